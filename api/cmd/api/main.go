@@ -2,16 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/bmachimbira/loyalty/api/internal/auth"
 	"github.com/bmachimbira/loyalty/api/internal/config"
+	"github.com/bmachimbira/loyalty/api/internal/dbtrace"
 	httputil "github.com/bmachimbira/loyalty/api/internal/http"
+	"github.com/bmachimbira/loyalty/api/internal/lifecycle"
 	"github.com/bmachimbira/loyalty/api/internal/logging"
+	"github.com/bmachimbira/loyalty/api/internal/pii"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -29,11 +35,13 @@ func main() {
 	logger.Info("Configuration loaded successfully",
 		"port", cfg.Port,
 		"log_level", os.Getenv("LOG_LEVEL"),
+		"slow_query_threshold", cfg.SlowQueryThreshold,
+		"pprof", cfg.PprofAddr != "",
 	)
 
 	// Initialize database connection pool
 	ctx := context.Background()
-	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	pool, err := newPool(ctx, cfg.DatabaseURL, cfg, logger)
 	if err != nil {
 		logger.Error("Unable to create database connection pool", "error", err)
 		os.Exit(1)
@@ -48,8 +56,59 @@ func main() {
 
 	logger.Info("Successfully connected to database")
 
+	// Reporting queries use the read replica when configured, otherwise the primary
+	readPool := pool
+	if cfg.ReadReplicaURL != "" {
+		replica, err := newPool(ctx, cfg.ReadReplicaURL, cfg, logger)
+		if err == nil {
+			err = replica.Ping(ctx)
+		}
+		if err != nil {
+			logger.Warn("Read replica unavailable, using primary for reads", "error", err)
+			if replica != nil {
+				replica.Close()
+			}
+		} else {
+			readPool = replica
+			defer replica.Close()
+			logger.Info("Successfully connected to read replica")
+		}
+	}
+
+	// Background workers are registered here and drained after the HTTP server
+	workers := lifecycle.NewManager(logger.Logger)
+
+	// JWT and HMAC keys can be rotated without dropping sessions: new key
+	// versions are loaded on SIGHUP while the old ones stay listed
+	keyring, err := auth.NewKeyring(cfg.JWTKeys, cfg.HMACKeys)
+	if err != nil {
+		logger.Error("Invalid JWT keys", "error", err)
+		os.Exit(1)
+	}
+	// PII is encrypted under the first PII key version and decrypted with any
+	// of them, so they're rotated the same way
+	if err := pii.Default().Set(cfg.PIIKeys); err != nil {
+		logger.Error("Invalid PII keys", "error", err)
+		os.Exit(1)
+	}
+	reloadSecrets := func() error {
+		return cfg.ReloadSecrets(keyring)
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := reloadSecrets(); err != nil {
+				logger.Error("Failed to reload secrets, keeping current keys", "error", err)
+				continue
+			}
+			logger.Info("Secrets reloaded", "jwt_kids", keyring.JWTKeyIDs(), "pii_kids", pii.Default().KeyIDs())
+		}
+	}()
+
 	// Set up router with all routes and middleware
-	router := httputil.SetupRouter(pool, cfg.JWTSecret, cfg.HMACKeys)
+	router := httputil.SetupRouter(pool, readPool, cfg.JWTSecret, keyring, reloadSecrets, workers)
 
 	// Start server
 	srv := &http.Server{
@@ -60,6 +119,19 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Profiling is served on its own listener so it is never exposed with
+	// the API
+	var pprofSrv *http.Server
+	if cfg.PprofAddr != "" {
+		pprofSrv = newPprofServer(cfg.PprofAddr)
+		go func() {
+			logger.Info("Starting pprof server", "addr", cfg.PprofAddr)
+			if err := pprofSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("pprof server failed", "error", err)
+			}
+		}()
+	}
+
 	// Graceful shutdown handler
 	go func() {
 		logger.Info("Starting HTTP server",
@@ -90,11 +162,70 @@ func main() {
 	// Attempt graceful shutdown
 	logger.Info("Shutting down server gracefully...")
 
+	exitCode := 0
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		logger.Error("Server forced to shutdown", "error", err)
-		os.Exit(1)
+		exitCode = 1
+	}
+
+	if pprofSrv != nil {
+		if err := pprofSrv.Shutdown(shutdownCtx); err != nil {
+			logger.Error("pprof server forced to shutdown", "error", err)
+		}
+	}
+
+	// Stop background workers once no new requests can enqueue work
+	logger.Info("Stopping background workers...")
+
+	if err := workers.Shutdown(shutdownCtx); err != nil {
+		var shutdownErr *lifecycle.ShutdownError
+		if errors.As(err, &shutdownErr) {
+			logger.Error("Background workers failed to drain",
+				"undrained", shutdownErr.Undrained,
+				"failed", len(shutdownErr.Failed),
+				"error", err,
+			)
+		}
+		exitCode = 1
+	}
+
+	if exitCode != 0 {
+		pool.Close()
+		os.Exit(exitCode)
 	}
 
 	logger.Info("Server shutdown complete")
 	slog.SetDefault(nil) // Flush any remaining logs
 }
+
+// newPool creates a connection pool, logging slow queries when a threshold
+// is configured
+func newPool(ctx context.Context, databaseURL string, cfg *config.Config, logger *logging.Logger) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SlowQueryThreshold > 0 {
+		poolConfig.ConnConfig.Tracer = dbtrace.NewSlowQueryTracer(cfg.SlowQueryThreshold, logger.Logger)
+	}
+	return pgxpool.NewWithConfig(ctx, poolConfig)
+}
+
+// newPprofServer creates a server for the net/http/pprof endpoints under
+// /debug/pprof/
+func newPprofServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	// CPU profiles and traces stream for their requested duration
+	return &http.Server{
+		Addr:        addr,
+		Handler:     mux,
+		ReadTimeout: 15 * time.Second,
+		IdleTimeout: 60 * time.Second,
+	}
+}
@@ -0,0 +1,1058 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bmachimbira/loyalty/api/internal/auth"
+	"github.com/bmachimbira/loyalty/api/internal/budget"
+	"github.com/bmachimbira/loyalty/api/internal/challenge"
+	"github.com/bmachimbira/loyalty/api/internal/channels"
+	"github.com/bmachimbira/loyalty/api/internal/currency"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/draw"
+	"github.com/bmachimbira/loyalty/api/internal/event"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/metering"
+	"github.com/bmachimbira/loyalty/api/internal/phone"
+	"github.com/bmachimbira/loyalty/api/internal/pii"
+	"github.com/bmachimbira/loyalty/api/internal/pii/rotation"
+	"github.com/bmachimbira/loyalty/api/internal/retention"
+	"github.com/bmachimbira/loyalty/api/internal/rules"
+	"github.com/bmachimbira/loyalty/api/internal/timezone"
+	"github.com/bmachimbira/loyalty/api/internal/webhooks"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// errAborted is returned when the operator declines a confirmation prompt
+var errAborted = errors.New("aborted by operator")
+
+// parseUUIDFlag parses a required UUID flag value
+func parseUUIDFlag(name, value string) (pgtype.UUID, error) {
+	var id pgtype.UUID
+	if value == "" {
+		return id, fmt.Errorf("--%s is required", name)
+	}
+	if err := httputil.ValidateUUID(value); err != nil {
+		return id, fmt.Errorf("invalid --%s: %w", name, err)
+	}
+	if err := id.Scan(value); err != nil {
+		return id, fmt.Errorf("invalid --%s: %w", name, err)
+	}
+	return id, nil
+}
+
+// runCreateTenant creates a tenant
+func runCreateTenant(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("create-tenant", flag.ExitOnError)
+	name := fs.String("name", "", "tenant name (required)")
+	country := fs.String("country", "ZW", "ISO country code")
+	currency := fs.String("currency", "USD", "default currency, an ISO 4217 code")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	if *name == "" {
+		return errors.New("--name is required")
+	}
+	*currency = strings.ToUpper(*currency)
+	if !budget.IsValidCurrency(*currency) {
+		return fmt.Errorf("unsupported currency %q", *currency)
+	}
+
+	if !a.confirm(*yes, "Create tenant %q (country=%s, currency=%s)", *name, *country, *currency) {
+		return errAborted
+	}
+
+	tenant, err := db.New(a.pool).CreateTenant(ctx, db.CreateTenantParams{
+		Name:        *name,
+		CountryCode: *country,
+		DefaultCcy:  *currency,
+		Theme:       []byte("{}"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create tenant: %w", err)
+	}
+
+	fmt.Printf("Created tenant %s (%s)\n", httputil.FormatUUID(tenant.ID.Bytes), tenant.Name)
+	return nil
+}
+
+// runCreateStaff creates a staff user for a tenant
+func runCreateStaff(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("create-staff", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	email := fs.String("email", "", "staff email (required)")
+	fullName := fs.String("name", "", "staff full name (required)")
+	role := fs.String("role", "staff", "role: owner, admin, manager, staff or viewer")
+	password := fs.String("password", "", "password (prompted if omitted)")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+	if *email == "" || *fullName == "" {
+		return errors.New("--email and --name are required")
+	}
+	switch *role {
+	case "owner", "admin", "manager", "staff", "viewer":
+	default:
+		return fmt.Errorf("invalid role %q", *role)
+	}
+
+	pwd := *password
+	if pwd == "" {
+		if pwd, err = a.prompt("Password"); err != nil {
+			return fmt.Errorf("failed to read password: %w", err)
+		}
+	}
+	if len(pwd) < 8 {
+		return errors.New("password must be at least 8 characters")
+	}
+
+	if !a.confirm(*yes, "Create %s user %s <%s> for tenant %s", *role, *fullName, *email, *tenant) {
+		return errAborted
+	}
+
+	hash, err := auth.HashPassword(pwd)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user, err := db.New(a.pool).CreateStaffUser(ctx, db.CreateStaffUserParams{
+		TenantID: tenantID,
+		Email:    strings.ToLower(*email),
+		FullName: *fullName,
+		Role:     *role,
+		PwdHash:  hash,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create staff user: %w", err)
+	}
+
+	fmt.Printf("Created staff user %s (%s)\n", httputil.FormatUUID(user.ID.Bytes), user.Email)
+	return nil
+}
+
+// runTopupBudget adds funds to a budget through the budget service
+func runTopupBudget(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("topup-budget", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	budgetFlag := fs.String("budget", "", "budget ID (required)")
+	amount := fs.String("amount", "", "amount to add, e.g. 500.00 (required)")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+	budgetID, err := parseUUIDFlag("budget", *budgetFlag)
+	if err != nil {
+		return err
+	}
+
+	b, err := db.New(a.pool).GetBudgetByID(ctx, db.GetBudgetByIDParams{
+		ID:       budgetID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get budget: %w", err)
+	}
+
+	if !a.confirm(*yes, "Top up budget %q by %s %s", b.Name, *amount, b.Currency) {
+		return errAborted
+	}
+
+	service := budget.NewService(a.pool, db.New(a.pool), a.logger.Logger)
+	result, err := service.TopupBudget(ctx, budget.TopupBudgetParams{
+		TenantID: tenantID,
+		BudgetID: budgetID,
+		Amount:   *amount,
+		Currency: b.Currency,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to top up budget: %w", err)
+	}
+
+	fmt.Printf("Budget %q topped up by %s %s (new balance %.2f)\n", b.Name, result.Amount, result.Currency, result.NewBalance)
+	return nil
+}
+
+// runUploadCodes loads voucher codes for a reward from the first column of a CSV file
+func runUploadCodes(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("upload-codes", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	reward := fs.String("reward", "", "reward ID (required)")
+	file := fs.String("file", "", "CSV file with one code per row (required)")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+	rewardID, err := parseUUIDFlag("reward", *reward)
+	if err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("--file is required")
+	}
+
+	queries := db.New(a.pool)
+	r, err := queries.GetRewardByID(ctx, db.GetRewardByIDParams{
+		ID:       rewardID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get reward: %w", err)
+	}
+	if r.Type != "voucher_code" {
+		return fmt.Errorf("reward %q is of type %s, expected voucher_code", r.Name, r.Type)
+	}
+
+	fh, err := os.Open(*file)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer fh.Close()
+
+	var codes []string
+	reader := csv.NewReader(bufio.NewReader(fh))
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse CSV file: %w", err)
+		}
+		if len(record) == 0 {
+			continue
+		}
+		if code := strings.TrimSpace(record[0]); code != "" {
+			codes = append(codes, code)
+		}
+	}
+
+	if !a.confirm(*yes, "Upload %d codes to reward %q", len(codes), r.Name) {
+		return errAborted
+	}
+
+	uploaded := 0
+	for _, code := range codes {
+		if _, err := queries.InsertVoucherCode(ctx, db.InsertVoucherCodeParams{
+			TenantID: tenantID,
+			RewardID: rewardID,
+			Code:     code,
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "skipped %s: %v\n", code, err)
+			continue
+		}
+		uploaded++
+	}
+
+	fmt.Printf("Uploaded %d of %d codes\n", uploaded, len(codes))
+	return nil
+}
+
+// runReconcile reconciles budgets against the ledger and optionally fixes discrepancies
+func runReconcile(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	fix := fs.Bool("fix", false, "correct budgets whose balance disagrees with the ledger")
+	notes := fs.String("notes", "reconciled via loyaltyctl", "notes recorded with fixes")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+
+	service := budget.NewService(a.pool, db.New(a.pool), a.logger.Logger)
+	report, err := service.ReconcileAllBudgets(ctx, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile budgets: %w", err)
+	}
+
+	for _, result := range report.Results {
+		status := "ok"
+		if result.HasDiscrepancy {
+			status = fmt.Sprintf("DISCREPANCY %.2f", result.Discrepancy)
+		}
+		fmt.Printf("%s  balance=%.2f ledger=%.2f  %s\n",
+			httputil.FormatUUID(result.BudgetID.Bytes), result.CurrentBalance, result.CalculatedBalance, status)
+	}
+	fmt.Printf("%d budgets, %d with discrepancies (total %.2f)\n",
+		report.Summary.TotalBudgets, report.Summary.BudgetsWithDiscrepancy, report.Summary.TotalDiscrepancy)
+
+	if !*fix || report.Summary.BudgetsWithDiscrepancy == 0 {
+		return nil
+	}
+
+	if !a.confirm(*yes, "Fix %d budget(s) to match the ledger", report.Summary.BudgetsWithDiscrepancy) {
+		return errAborted
+	}
+
+	for _, result := range report.Results {
+		if !result.HasDiscrepancy {
+			continue
+		}
+		if err := service.FixDiscrepancy(ctx, tenantID, result.BudgetID, *notes); err != nil {
+			return fmt.Errorf("failed to fix budget %s: %w", httputil.FormatUUID(result.BudgetID.Bytes), err)
+		}
+		fmt.Printf("Fixed %s\n", httputil.FormatUUID(result.BudgetID.Bytes))
+	}
+	return nil
+}
+
+// runReplayEvents re-runs rule processing for a tenant's events. Rules an
+// event already triggered return their existing issuances, which the engine
+// doesn't issue again.
+func runReplayEvents(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("replay-events", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	since := fs.String("since", "", "start of range, RFC3339 (required)")
+	until := fs.String("until", "", "end of range, RFC3339 (default now)")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+
+	var sinceTS, untilTS pgtype.Timestamptz
+	sinceTime, err := time.Parse(time.RFC3339, *since)
+	if err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	untilTime := time.Now()
+	if *until != "" {
+		if untilTime, err = time.Parse(time.RFC3339, *until); err != nil {
+			return fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+	sinceTS.Scan(sinceTime)
+	untilTS.Scan(untilTime)
+
+	events, err := db.New(a.pool).ListEventsByTenantRange(ctx, db.ListEventsByTenantRangeParams{
+		TenantID: tenantID,
+		Since:    sinceTS,
+		Until:    untilTS,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list events: %w", err)
+	}
+
+	if !a.confirm(*yes, "Replay %d events from %s to %s", len(events),
+		sinceTime.Format(time.RFC3339), untilTime.Format(time.RFC3339)) {
+		return errAborted
+	}
+
+	engine := rules.NewEngine(a.pool, a.logger)
+	queries := db.New(a.pool)
+	engine.SetChallengeTracker(challenge.NewTracker(a.pool, queries, engine, a.logger.Logger))
+	engine.SetDrawEntrant(draw.NewService(a.pool, queries, engine, a.logger.Logger))
+	issued, excluded, failed := 0, 0, 0
+	for _, event := range events {
+		// Rules an event already triggered return their issuances without
+		// issuing again, so only the ones the replay created are new
+		result, err := engine.Process(ctx, event)
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "event %s failed: %v\n", httputil.FormatUUID(event.ID.Bytes), err)
+			continue
+		}
+		issued += result.Created
+		excluded += len(result.Excluded())
+	}
+
+	fmt.Printf("Replayed %d events: %d new issuances, %d excluded, %d failures\n", len(events), issued, excluded, failed)
+	return nil
+}
+
+// runSetApproval turns maker-checker approval on or off for a tenant
+func runSetApproval(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("set-approval", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	required := fs.Bool("required", true, "require manager approval for campaign and rule changes")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+
+	if !a.confirm(*yes, "Set require_approval=%t for tenant %s", *required, *tenant) {
+		return errAborted
+	}
+
+	if err := db.New(a.pool).UpdateTenantRequireApproval(ctx, db.UpdateTenantRequireApprovalParams{
+		ID:              tenantID,
+		RequireApproval: *required,
+	}); err != nil {
+		return fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	fmt.Printf("Tenant %s require_approval=%t\n", *tenant, *required)
+	return nil
+}
+
+// runSetExport configures or disables nightly data exports for a tenant
+func runSetExport(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("set-export", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	bucket := fs.String("bucket", "", "bucket to write exports to; empty disables exports")
+	prefix := fs.String("prefix", "", "key prefix within the bucket")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+
+	if *bucket == "" {
+		if !a.confirm(*yes, "Disable data exports for tenant %s", *tenant) {
+			return errAborted
+		}
+	} else if !a.confirm(*yes, "Export data for tenant %s to s3://%s/%s", *tenant, *bucket, *prefix) {
+		return errAborted
+	}
+
+	if err := db.New(a.pool).UpdateTenantExport(ctx, db.UpdateTenantExportParams{
+		ID:           tenantID,
+		ExportBucket: pgtype.Text{String: *bucket, Valid: *bucket != ""},
+		ExportPrefix: strings.Trim(*prefix, "/"),
+	}); err != nil {
+		return fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	if *bucket == "" {
+		fmt.Printf("Tenant %s data exports disabled\n", *tenant)
+	} else {
+		fmt.Printf("Tenant %s exports to s3://%s/%s\n", *tenant, *bucket, *prefix)
+	}
+	return nil
+}
+
+// runSetEventBus turns domain event publication on or off for a tenant
+func runSetEventBus(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("set-event-bus", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	enabled := fs.Bool("enabled", true, "publish the tenant's domain events to the message bus")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+
+	if !a.confirm(*yes, "Set event_bus_enabled=%t for tenant %s", *enabled, *tenant) {
+		return errAborted
+	}
+
+	if err := db.New(a.pool).UpdateTenantEventBus(ctx, db.UpdateTenantEventBusParams{
+		ID:              tenantID,
+		EventBusEnabled: *enabled,
+	}); err != nil {
+		return fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	fmt.Printf("Tenant %s event_bus_enabled=%t\n", *tenant, *enabled)
+	return nil
+}
+
+// runSetReservationAge sets how long a tenant's issuances may stay reserved
+// before the reservation release worker cancels them and releases their budget
+func runSetReservationAge(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("set-reservation-age", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	hours := fs.Int("hours", 0, "max reservation age in hours; 0 keeps reservations until processed")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+	if *hours < 0 {
+		return fmt.Errorf("-hours must not be negative")
+	}
+
+	if *hours == 0 {
+		if !a.confirm(*yes, "Disable reservation ageing for tenant %s", *tenant) {
+			return errAborted
+		}
+	} else if !a.confirm(*yes, "Release reservations older than %d hours for tenant %s", *hours, *tenant) {
+		return errAborted
+	}
+
+	if err := db.New(a.pool).UpdateTenantMaxReservationAge(ctx, db.UpdateTenantMaxReservationAgeParams{
+		ID:                     tenantID,
+		MaxReservationAgeHours: pgtype.Int4{Int32: int32(*hours), Valid: *hours > 0},
+	}); err != nil {
+		return fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	if *hours == 0 {
+		fmt.Printf("Tenant %s reservation ageing disabled\n", *tenant)
+	} else {
+		fmt.Printf("Tenant %s max_reservation_age_hours=%d\n", *tenant, *hours)
+	}
+	return nil
+}
+
+// runSetWhatsAppRate sets how many queued WhatsApp messages are dispatched
+// for a tenant per minute
+func runSetWhatsAppRate(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("set-whatsapp-rate", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	perMinute := fs.Int("per-minute", 0, "queued messages dispatched per minute (required)")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+	if *perMinute <= 0 {
+		return fmt.Errorf("-per-minute must be positive")
+	}
+
+	if !a.confirm(*yes, "Dispatch up to %d queued WhatsApp messages per minute for tenant %s", *perMinute, *tenant) {
+		return errAborted
+	}
+
+	if err := db.New(a.pool).UpdateTenantWhatsAppRate(ctx, db.UpdateTenantWhatsAppRateParams{
+		ID:                    tenantID,
+		WhatsappRatePerMinute: int32(*perMinute),
+	}); err != nil {
+		return fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	fmt.Printf("Tenant %s whatsapp_rate_per_minute=%d\n", *tenant, *perMinute)
+	return nil
+}
+
+// runSetPhoneRegion sets the region a tenant's phone numbers without a
+// country code are read in
+func runSetPhoneRegion(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("set-phone-region", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	region := fs.String("region", "", "ISO 3166-1 region code, e.g. ZW (required)")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+	*region = strings.ToUpper(*region)
+	if !phone.ValidRegion(*region) {
+		return fmt.Errorf("-region must be one of %s", strings.Join(phone.Regions(), ", "))
+	}
+
+	if !a.confirm(*yes, "Read phone numbers without a country code as %s numbers for tenant %s", *region, *tenant) {
+		return errAborted
+	}
+
+	if err := db.New(a.pool).UpdateTenantPhoneRegion(ctx, db.UpdateTenantPhoneRegionParams{
+		ID:          tenantID,
+		PhoneRegion: *region,
+	}); err != nil {
+		return fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	fmt.Printf("Tenant %s phone_region=%s\n", *tenant, *region)
+	return nil
+}
+
+// runSetTimezone sets the time zone a tenant's local times, such as the hours
+// of time-of-day rules and report days, are read in. The tenant's analytics
+// rollups are rebuilt on the next refresh since their days move.
+func runSetTimezone(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("set-timezone", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	zone := fs.String("timezone", "", "IANA time zone, e.g. Africa/Harare (required)")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+	if !timezone.Valid(*zone) {
+		return fmt.Errorf("-timezone must be an IANA time zone, e.g. %s", timezone.Default)
+	}
+
+	if !a.confirm(*yes, "Read local times in %s for tenant %s", *zone, *tenant) {
+		return errAborted
+	}
+
+	queries := db.New(a.pool)
+	if err := queries.UpdateTenantTimezone(ctx, db.UpdateTenantTimezoneParams{
+		ID:       tenantID,
+		Timezone: *zone,
+	}); err != nil {
+		return fmt.Errorf("failed to update tenant: %w", err)
+	}
+	if err := queries.ResetRollupState(ctx, tenantID); err != nil {
+		return fmt.Errorf("failed to reset analytics rollups: %w", err)
+	}
+
+	fmt.Printf("Tenant %s timezone=%s\n", *tenant, *zone)
+	return nil
+}
+
+// runSetCurrencies sets the currencies a tenant may use besides its default
+// currency
+func runSetCurrencies(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("set-currencies", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	codes := fs.String("currencies", "", "comma separated ISO 4217 codes, e.g. ZAR,BWP; empty for the default currency only")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+
+	allowed := []string{}
+	for _, code := range strings.Split(*codes, ",") {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" {
+			continue
+		}
+		if !currency.Valid(code) {
+			return fmt.Errorf("unsupported currency %q", code)
+		}
+		if !slices.Contains(allowed, code) {
+			allowed = append(allowed, code)
+		}
+	}
+
+	if !a.confirm(*yes, "Allow currencies [%s] besides the default currency for tenant %s", strings.Join(allowed, ", "), *tenant) {
+		return errAborted
+	}
+
+	if err := db.New(a.pool).UpdateTenantAllowedCurrencies(ctx, db.UpdateTenantAllowedCurrenciesParams{
+		ID:                tenantID,
+		AllowedCurrencies: allowed,
+	}); err != nil {
+		return fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	fmt.Printf("Tenant %s allowed_currencies=%s\n", *tenant, strings.Join(allowed, ","))
+	return nil
+}
+
+// runSetRetention sets how many months a tenant's events, messages and
+// expired issuances are kept before the retention purge worker removes them
+func runSetRetention(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("set-retention", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	events := fs.Int("events", 0, "months events are kept; 0 keeps them forever")
+	messages := fs.Int("messages", 0, "months outbound messages and channel sessions are kept; 0 keeps them forever")
+	issuances := fs.Int("issuances", 0, "months expired issuances keep their codes; 0 keeps them forever")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+	if *events < 0 || *messages < 0 || *issuances < 0 {
+		return fmt.Errorf("retention months must not be negative")
+	}
+
+	if !a.confirm(*yes, "Keep events %s, messages %s and expired issuances %s for tenant %s",
+		describeRetention(*events), describeRetention(*messages), describeRetention(*issuances), *tenant) {
+		return errAborted
+	}
+
+	if err := db.New(a.pool).UpdateTenantRetention(ctx, db.UpdateTenantRetentionParams{
+		ID:                      tenantID,
+		EventRetentionMonths:    pgtype.Int4{Int32: int32(*events), Valid: *events > 0},
+		MessageRetentionMonths:  pgtype.Int4{Int32: int32(*messages), Valid: *messages > 0},
+		IssuanceRetentionMonths: pgtype.Int4{Int32: int32(*issuances), Valid: *issuances > 0},
+	}); err != nil {
+		return fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	fmt.Printf("Tenant %s event_retention_months=%d message_retention_months=%d issuance_retention_months=%d\n",
+		*tenant, *events, *messages, *issuances)
+	return nil
+}
+
+// describeRetention describes a retention in months for a confirmation prompt
+func describeRetention(months int) string {
+	if months == 0 {
+		return "forever"
+	}
+	return fmt.Sprintf("for %d months", months)
+}
+
+// runSetEventDedup sets whether events repeating an earlier event's content
+// within a window are flagged or suppressed
+func runSetEventDedup(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("set-event-dedup", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	mode := fs.String("mode", "", "flag, suppress or off (required)")
+	window := fs.Duration("window", 5*time.Minute, "how far apart near-duplicates may have occurred")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+	if *mode != "off" && !event.ValidDedupMode(*mode) {
+		return fmt.Errorf("-mode must be flag, suppress or off")
+	}
+	if *window < time.Second {
+		return fmt.Errorf("-window must be at least 1s")
+	}
+
+	if !a.confirm(*yes, "Set event deduplication to %s with a %s window for tenant %s", *mode, *window, *tenant) {
+		return errAborted
+	}
+
+	if err := db.New(a.pool).UpdateTenantEventDedup(ctx, db.UpdateTenantEventDedupParams{
+		ID:            tenantID,
+		Mode:          pgtype.Text{String: *mode, Valid: *mode != "off"},
+		WindowSeconds: int32(window.Seconds()),
+	}); err != nil {
+		return fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	fmt.Printf("Tenant %s event_dedup_mode=%s event_dedup_window_seconds=%d\n", *tenant, *mode, int(window.Seconds()))
+	return nil
+}
+
+// runSetWinback sets how many days without events make a tenant's customer
+// dormant, how long before a customer may be targeted again and the
+// WhatsApp template dormant customers are sent
+func runSetWinback(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("set-winback", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	days := fs.Int("days", 0, "days without events before a customer is dormant; 0 turns win-back off")
+	cooldown := fs.Int("cooldown", 90, "days before a targeted customer may be targeted again")
+	template := fs.String("template", "", "WhatsApp template sent to dormant customers; empty sends none")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+	if *days < 0 {
+		return fmt.Errorf("-days must not be negative")
+	}
+	if *cooldown < 1 {
+		return fmt.Errorf("-cooldown must be at least 1")
+	}
+
+	prompt := fmt.Sprintf("Turn win-back off for tenant %s", *tenant)
+	if *days > 0 {
+		prompt = fmt.Sprintf("Target customers after %d days without events, at most every %d days, for tenant %s", *days, *cooldown, *tenant)
+	}
+	if !a.confirm(*yes, "%s", prompt) {
+		return errAborted
+	}
+
+	if err := db.New(a.pool).UpdateTenantWinback(ctx, db.UpdateTenantWinbackParams{
+		ID:                  tenantID,
+		WinbackDormantDays:  pgtype.Int4{Int32: int32(*days), Valid: *days > 0},
+		WinbackCooldownDays: int32(*cooldown),
+		WinbackTemplate:     pgtype.Text{String: *template, Valid: *template != ""},
+	}); err != nil {
+		return fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	fmt.Printf("Tenant %s winback_dormant_days=%d winback_cooldown_days=%d winback_template=%s\n",
+		*tenant, *days, *cooldown, *template)
+	return nil
+}
+
+// runSetWelcomeSeries turns a tenant's welcome series on or off and sets
+// its WhatsApp templates, when the reminder is due and how long the first
+// purchase bonus window lasts
+func runSetWelcomeSeries(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("set-welcome-series", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	enabled := fs.Bool("enabled", true, "enroll new customers in the series; false turns it off")
+	template := fs.String("template", "", "WhatsApp template sent on enrollment; empty sends none")
+	reminderTemplate := fs.String("reminder-template", "", "WhatsApp template sent to customers without a purchase when the reminder is due; empty sends none")
+	reminderDays := fs.Int("reminder-days", 3, "days after enrollment the reminder is due")
+	bonusDays := fs.Int("bonus-days", 14, "days after enrollment rules using in_welcome_window apply")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+	if *reminderDays < 1 {
+		return fmt.Errorf("-reminder-days must be at least 1")
+	}
+	if *bonusDays < 1 {
+		return fmt.Errorf("-bonus-days must be at least 1")
+	}
+
+	prompt := fmt.Sprintf("Turn the welcome series off for tenant %s", *tenant)
+	if *enabled {
+		prompt = fmt.Sprintf("Enroll new customers of tenant %s in a welcome series with a reminder after %d days and a %d day bonus window", *tenant, *reminderDays, *bonusDays)
+	}
+	if !a.confirm(*yes, "%s", prompt) {
+		return errAborted
+	}
+
+	if err := db.New(a.pool).UpdateTenantWelcomeSeries(ctx, db.UpdateTenantWelcomeSeriesParams{
+		ID:                      tenantID,
+		WelcomeSeriesEnabled:    *enabled,
+		WelcomeTemplate:         pgtype.Text{String: *template, Valid: *template != ""},
+		WelcomeReminderTemplate: pgtype.Text{String: *reminderTemplate, Valid: *reminderTemplate != ""},
+		WelcomeReminderDays:     int32(*reminderDays),
+		WelcomeBonusDays:        int32(*bonusDays),
+	}); err != nil {
+		return fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	fmt.Printf("Tenant %s welcome_series_enabled=%t welcome_template=%s welcome_reminder_template=%s welcome_reminder_days=%d welcome_bonus_days=%d\n",
+		*tenant, *enabled, *template, *reminderTemplate, *reminderDays, *bonusDays)
+	return nil
+}
+
+// runSetBranding sets how a tenant's programme introduces itself in
+// WhatsApp and USSD messages. Empty flags fall back to the defaults.
+func runSetBranding(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("set-branding", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	programName := fs.String("program-name", "", "programme name used in welcome, menu and help text; empty uses "+channels.DefaultProgramName)
+	emoji := fs.String("emoji", "", "emoji shown with the programme name on WhatsApp; empty uses "+channels.DefaultBrandEmoji)
+	supportPhone := fs.String("support-phone", "", "number customers are told to call for help; empty tells them to contact support")
+	footer := fs.String("footer", "", "text appended to WhatsApp welcome and help messages; empty appends none")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+	for _, f := range []struct {
+		name  string
+		value string
+		max   int
+	}{
+		{"program-name", *programName, 60},
+		{"emoji", *emoji, 16},
+		{"support-phone", *supportPhone, 32},
+		{"footer", *footer, 200},
+	} {
+		if utf8.RuneCountInString(f.value) > f.max {
+			return fmt.Errorf("-%s must be at most %d characters", f.name, f.max)
+		}
+	}
+
+	if !a.confirm(*yes, "Set the channel branding of tenant %s", *tenant) {
+		return errAborted
+	}
+
+	if err := db.New(a.pool).UpdateTenantBranding(ctx, db.UpdateTenantBrandingParams{
+		ID:            tenantID,
+		ProgramName:   pgtype.Text{String: *programName, Valid: *programName != ""},
+		BrandEmoji:    pgtype.Text{String: *emoji, Valid: *emoji != ""},
+		SupportPhone:  pgtype.Text{String: *supportPhone, Valid: *supportPhone != ""},
+		MessageFooter: pgtype.Text{String: *footer, Valid: *footer != ""},
+	}); err != nil {
+		return fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	fmt.Printf("Tenant %s program_name=%s brand_emoji=%s support_phone=%s message_footer=%q\n",
+		*tenant, *programName, *emoji, *supportPhone, *footer)
+	return nil
+}
+
+// runRotateAlertSecret replaces the secret a tenant's budget alert webhooks
+// are signed with and prints the new one for the tenant's receivers
+func runRotateAlertSecret(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("rotate-alert-secret", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+
+	if !a.confirm(*yes, "Rotate the budget alert webhook secret of tenant %s; receivers must be updated to verify with the new one", *tenant) {
+		return errAborted
+	}
+
+	secret, err := webhooks.GenerateSecret()
+	if err != nil {
+		return err
+	}
+	if err := db.New(a.pool).UpdateTenantAlertWebhookSecret(ctx, db.UpdateTenantAlertWebhookSecretParams{
+		ID:                 tenantID,
+		AlertWebhookSecret: secret,
+	}); err != nil {
+		return fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	fmt.Printf("Tenant %s alert_webhook_secret=%s\n", *tenant, secret)
+	return nil
+}
+
+// runPurge purges a tenant's data past its retention now, or with --dry-run
+// reports what would be purged
+func runPurge(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (required)")
+	dryRun := fs.Bool("dry-run", false, "report what would be purged without removing it")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	tenantID, err := parseUUIDFlag("tenant", *tenant)
+	if err != nil {
+		return err
+	}
+
+	if !*dryRun && !a.confirm(*yes, "Purge data past retention for tenant %s", *tenant) {
+		return errAborted
+	}
+
+	run, err := retention.NewService(a.pool, db.New(a.pool), a.logger).Purge(ctx, tenantID, *dryRun, time.Now())
+	if err != nil {
+		return err
+	}
+
+	verb := "Purged"
+	if run.DryRun {
+		verb = "Would purge"
+	}
+	fmt.Printf("%s: %d events deleted, %d events anonymized, %d messages deleted, %d sessions deleted, %d issuances anonymized\n",
+		verb, run.EventsDeleted, run.EventsAnonymized, run.MessagesDeleted, run.SessionsDeleted, run.IssuancesAnonymized)
+	return nil
+}
+
+// runExportUsage writes every tenant's metered usage for one month as CSV,
+// for invoicing
+func runExportUsage(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("export-usage", flag.ExitOnError)
+	month := fs.String("month", "", "month to export, YYYY-MM (default last month)")
+	out := fs.String("out", "", "file to write (default stdout)")
+	fs.Parse(args)
+
+	period := metering.Period(time.Now()).AddDate(0, -1, 0)
+	if *month != "" {
+		p, err := metering.ParseMonth(*month)
+		if err != nil {
+			return fmt.Errorf("-month: %w", err)
+		}
+		period = p
+	}
+
+	rows, err := db.New(a.pool).ListUsageForPeriod(ctx, pgtype.Date{Time: period, Valid: true})
+	if err != nil {
+		return fmt.Errorf("failed to list usage: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		fh, err := os.Create(*out)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *out, err)
+		}
+		defer fh.Close()
+		w = fh
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"tenant_id", "tenant_name", "period", "metric", "quantity"})
+	for _, row := range rows {
+		writer.Write([]string{
+			httputil.FormatUUID(row.TenantID.Bytes),
+			row.TenantName,
+			period.Format(metering.MonthFormat),
+			row.Metric,
+			fmt.Sprintf("%d", row.Quantity),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write usage: %w", err)
+	}
+
+	if *out != "" {
+		fmt.Printf("Wrote %d usage records for %s to %s\n", len(rows), period.Format(metering.MonthFormat), *out)
+	}
+	return nil
+}
+
+// runRotatePII re-encrypts phone numbers not encrypted under the current
+// PII key, for one tenant or all of them, so older keys can be retired
+func runRotatePII(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("rotate-pii", flag.ExitOnError)
+	tenant := fs.String("tenant", "", "tenant ID (default all tenants)")
+	yes := fs.Bool("yes", false, "skip confirmation prompt")
+	fs.Parse(args)
+
+	kids := pii.Default().KeyIDs()
+	if len(kids) == 0 {
+		return rotation.ErrNoKey
+	}
+
+	queries := db.New(a.pool)
+	var tenants []pgtype.UUID
+	if *tenant != "" {
+		tenantID, err := parseUUIDFlag("tenant", *tenant)
+		if err != nil {
+			return err
+		}
+		tenants = append(tenants, tenantID)
+	} else {
+		all, err := queries.ListTenants(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list tenants: %w", err)
+		}
+		for _, t := range all {
+			tenants = append(tenants, t.ID)
+		}
+	}
+
+	if !a.confirm(*yes, "Re-encrypt PII of %d tenant(s) under key %s", len(tenants), kids[0]) {
+		return errAborted
+	}
+
+	service := rotation.NewService(a.pool, queries)
+	for _, tenantID := range tenants {
+		result, err := service.Rotate(ctx, tenantID)
+		if err != nil {
+			return fmt.Errorf("failed to rotate tenant %s: %w", httputil.FormatUUID(tenantID.Bytes), err)
+		}
+		fmt.Printf("%s  customers=%d wa_sessions=%d ussd_sessions=%d\n",
+			httputil.FormatUUID(tenantID.Bytes), result.Customers, result.WASessions, result.USSDSessions)
+	}
+	fmt.Printf("PII of %d tenant(s) is encrypted under key %s; keys other than %s can be removed\n", len(tenants), kids[0], kids[0])
+	return nil
+}
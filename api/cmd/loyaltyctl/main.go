@@ -0,0 +1,164 @@
+// Command loyaltyctl is an operator tool for common administrative tasks.
+//
+// It talks directly to the database and service layer so operators do not
+// have to write ad hoc SQL. Mutating commands ask for confirmation unless
+// --yes is passed.
+//
+// Usage:
+//
+//	loyaltyctl <command> [flags]
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/config"
+	"github.com/bmachimbira/loyalty/api/internal/logging"
+	"github.com/bmachimbira/loyalty/api/internal/pii"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+)
+
+// command is a loyaltyctl subcommand
+type command struct {
+	summary string
+	run     func(ctx context.Context, app *app, args []string) error
+}
+
+var commands = map[string]command{
+	"create-tenant":       {"Create a tenant", runCreateTenant},
+	"create-staff":        {"Create a staff user for a tenant", runCreateStaff},
+	"topup-budget":        {"Add funds to a budget", runTopupBudget},
+	"upload-codes":        {"Upload voucher codes for a reward from a CSV file", runUploadCodes},
+	"reconcile":           {"Reconcile budget balances against the ledger", runReconcile},
+	"replay-events":       {"Re-run rule processing for events in a time range", runReplayEvents},
+	"set-approval":        {"Turn maker-checker approval of campaign and rule changes on or off", runSetApproval},
+	"set-export":          {"Configure the bucket nightly data exports are written to", runSetExport},
+	"set-event-bus":       {"Turn publication of domain events to the message bus on or off", runSetEventBus},
+	"set-reservation-age": {"Set how long issuances may stay reserved before their budget is released", runSetReservationAge},
+	"set-whatsapp-rate":   {"Set how many queued WhatsApp messages are dispatched per minute for a tenant", runSetWhatsAppRate},
+	"set-phone-region":    {"Set the region phone numbers without a country code are read in for a tenant", runSetPhoneRegion},
+	"set-timezone":        {"Set the time zone a tenant's local times are read in", runSetTimezone},
+	"set-currencies":      {"Set the currencies a tenant may use besides its default currency", runSetCurrencies},
+	"set-retention":       {"Set how many months a tenant's events, messages and expired issuances are kept", runSetRetention},
+	"set-event-dedup":     {"Set whether near-duplicate events of a tenant are flagged or suppressed", runSetEventDedup},
+	"set-winback":         {"Set when a tenant's inactive customers are targeted with a win-back event and message", runSetWinback},
+	"set-welcome-series":  {"Set the welcome messages and first purchase bonus window of a tenant's new customers", runSetWelcomeSeries},
+	"set-branding":        {"Set the programme name, emoji, support number and footer of a tenant's channel messages", runSetBranding},
+	"rotate-alert-secret": {"Replace the secret a tenant's budget alert webhooks are signed with", runRotateAlertSecret},
+	"purge":               {"Purge a tenant's data past its retention, or report what would be purged", runPurge},
+	"export-usage":        {"Export every tenant's metered usage for a month as CSV for invoicing", runExportUsage},
+	"rotate-pii":          {"Re-encrypt customers' phone numbers under the current PII key", runRotatePII},
+}
+
+// app holds shared dependencies for commands
+type app struct {
+	pool   *pgxpool.Pool
+	logger *logging.Logger
+	in     *bufio.Reader
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	// Load .env file if it exists (same lookup as the API)
+	if err := godotenv.Load(); err != nil {
+		_ = godotenv.Load("../.env")
+	}
+
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		fmt.Fprintln(os.Stderr, "DATABASE_URL environment variable is required")
+		os.Exit(1)
+	}
+
+	// Phone numbers are read and written through the PII keys, as in the API
+	piiKeys, err := config.LoadPIIKeys(os.Getenv("SECRETS_FILE"))
+	if err == nil {
+		err = pii.Default().Set(piiKeys)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid PII keys: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to create database connection pool: %v\n", err)
+		os.Exit(1)
+	}
+
+	a := &app{
+		pool:   pool,
+		logger: logging.New(),
+		in:     bufio.NewReader(os.Stdin),
+	}
+
+	err = cmd.run(ctx, a, os.Args[2:])
+	pool.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: loyaltyctl <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-15s %s\n", name, commands[name].summary)
+	}
+
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'loyaltyctl <command> -h' for command flags.")
+}
+
+// confirm asks the operator to confirm an action. It returns true without
+// prompting when assumeYes is set.
+func (a *app) confirm(assumeYes bool, format string, args ...interface{}) bool {
+	fmt.Printf(format+"\n", args...)
+	if assumeYes {
+		return true
+	}
+
+	fmt.Print("Proceed? [y/N]: ")
+	answer, err := a.in.ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// prompt reads a line of input from the operator
+func (a *app) prompt(label string) (string, error) {
+	fmt.Printf("%s: ", label)
+	value, err := a.in.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(value), nil
+}
@@ -0,0 +1,233 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// FunnelChannelUSSD is the channel whose sessions are tracked through the
+// funnel
+const FunnelChannelUSSD = "ussd"
+
+// Funnel stages, in the order sessions pass through them
+const (
+	StageSessionsStarted     = "sessions_started"
+	StageMenuReached         = "menu_reached"
+	StageRedemptionAttempted = "redemption_attempted"
+	StageRedemptionCompleted = "redemption_completed"
+)
+
+// FunnelIdleTimeout is how long a session must be inactive before it counts
+// as abandoned. USSD gateways time sessions out well before this.
+const FunnelIdleTimeout = 5 * time.Minute
+
+// ErrInvalidChannel is returned for a channel whose sessions aren't tracked
+var ErrInvalidChannel = errors.New("channel must be ussd")
+
+// FunnelStep is how far a session has got, recorded as the customer moves
+// through the channel's menus
+type FunnelStep struct {
+	TenantID   pgtype.UUID
+	Channel    string
+	SessionRef string
+	// Step is the menu the session is now on
+	Step string
+	// Stage is the furthest funnel stage this step reaches; the stages
+	// before it are reached too
+	Stage string
+	// Ended is set when the channel ended the session
+	Ended bool
+}
+
+// FunnelRecorder records channel sessions' progress through the funnel
+type FunnelRecorder struct {
+	queries *db.Queries
+	logger  *slog.Logger
+}
+
+// NewFunnelRecorder creates a funnel recorder
+func NewFunnelRecorder(queries *db.Queries, logger *slog.Logger) *FunnelRecorder {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &FunnelRecorder{
+		queries: queries,
+		logger:  logger,
+	}
+}
+
+// Record saves a session's step. Failures are logged rather than returned so
+// analytics never gets in the way of serving the customer. A nil recorder
+// records nothing, so funnel tracking is optional wherever it is wired in.
+func (r *FunnelRecorder) Record(ctx context.Context, step FunnelStep) {
+	if r == nil {
+		return
+	}
+
+	reached := stageIndex(step.Stage)
+	err := r.queries.RecordFunnelStep(ctx, db.RecordFunnelStepParams{
+		TenantID:            step.TenantID,
+		Channel:             step.Channel,
+		SessionRef:          step.SessionRef,
+		LastStep:            step.Step,
+		MenuReached:         reached >= stageIndex(StageMenuReached),
+		RedemptionAttempted: reached >= stageIndex(StageRedemptionAttempted),
+		RedemptionCompleted: reached >= stageIndex(StageRedemptionCompleted),
+		Ended:               step.Ended,
+	})
+	if err != nil {
+		r.logger.Warn("failed to record funnel step",
+			"channel", step.Channel,
+			"session_ref", step.SessionRef,
+			"step", step.Step,
+			"error", err,
+		)
+	}
+}
+
+// funnelStages lists the stages in order
+var funnelStages = []string{
+	StageSessionsStarted,
+	StageMenuReached,
+	StageRedemptionAttempted,
+	StageRedemptionCompleted,
+}
+
+// stageIndex returns the position of stage in the funnel
+func stageIndex(stage string) int {
+	for i, s := range funnelStages {
+		if s == stage {
+			return i
+		}
+	}
+	return 0
+}
+
+// FunnelStage is how many sessions reached a stage. Conversion is the
+// percentage of the previous stage's sessions that reached it, and
+// OfStarted the percentage of all sessions.
+type FunnelStage struct {
+	Stage      string  `json:"stage"`
+	Sessions   int64   `json:"sessions"`
+	Conversion float64 `json:"conversion"`
+	OfStarted  float64 `json:"of_started"`
+}
+
+// FunnelDropOff is how many abandoned sessions were last on a step
+type FunnelDropOff struct {
+	Step     string  `json:"step"`
+	Sessions int64   `json:"sessions"`
+	Share    float64 `json:"share"`
+}
+
+// ChannelFunnel is how sessions started on a channel in a period progressed
+// and where those the customer abandoned dropped off
+type ChannelFunnel struct {
+	Channel   string          `json:"channel"`
+	From      time.Time       `json:"from"`
+	To        time.Time       `json:"to"`
+	Stages    []FunnelStage   `json:"stages"`
+	Abandoned int64           `json:"abandoned"`
+	DropOffs  []FunnelDropOff `json:"drop_offs"`
+}
+
+// GetChannelFunnel reports the funnel for sessions started on channel
+// between from and to
+func (s *Service) GetChannelFunnel(ctx context.Context, tenantID pgtype.UUID, channel string, from, to time.Time) (*ChannelFunnel, error) {
+	if channel != FunnelChannelUSSD {
+		return nil, ErrInvalidChannel
+	}
+	if !from.Before(to) {
+		return nil, ErrInvalidRange
+	}
+
+	startedFrom := pgtype.Timestamptz{Time: from, Valid: true}
+	startedTo := pgtype.Timestamptz{Time: to, Valid: true}
+	abandonedBefore := pgtype.Timestamptz{Time: time.Now().Add(-FunnelIdleTimeout), Valid: true}
+
+	counts, err := s.queries.GetChannelFunnel(ctx, db.GetChannelFunnelParams{
+		AbandonedBefore: abandonedBefore,
+		TenantID:        tenantID,
+		Channel:         channel,
+		StartedFrom:     startedFrom,
+		StartedTo:       startedTo,
+	})
+	if err != nil {
+		s.logger.Error("Failed to fetch channel funnel",
+			"tenant_id", tenantID,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	dropOffs, err := s.queries.ListChannelFunnelDropOffs(ctx, db.ListChannelFunnelDropOffsParams{
+		TenantID:        tenantID,
+		Channel:         channel,
+		StartedFrom:     startedFrom,
+		StartedTo:       startedTo,
+		AbandonedBefore: abandonedBefore,
+	})
+	if err != nil {
+		s.logger.Error("Failed to fetch channel funnel drop-offs",
+			"tenant_id", tenantID,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	funnel := buildFunnel(counts, dropOffs)
+	funnel.Channel = channel
+	funnel.From = from
+	funnel.To = to
+	return funnel, nil
+}
+
+// buildFunnel works out each stage's conversion and each drop-off's share of
+// the abandoned sessions
+func buildFunnel(counts db.GetChannelFunnelRow, dropOffs []db.ListChannelFunnelDropOffsRow) *ChannelFunnel {
+	sessions := []int64{
+		counts.SessionsStarted,
+		counts.MenuReached,
+		counts.RedemptionAttempted,
+		counts.RedemptionCompleted,
+	}
+
+	funnel := &ChannelFunnel{
+		Stages:    make([]FunnelStage, len(funnelStages)),
+		Abandoned: counts.Abandoned,
+		DropOffs:  make([]FunnelDropOff, len(dropOffs)),
+	}
+	for i, stage := range funnelStages {
+		previous := counts.SessionsStarted
+		if i > 0 {
+			previous = sessions[i-1]
+		}
+		funnel.Stages[i] = FunnelStage{
+			Stage:      stage,
+			Sessions:   sessions[i],
+			Conversion: percentage(sessions[i], previous),
+			OfStarted:  percentage(sessions[i], counts.SessionsStarted),
+		}
+	}
+	for i, d := range dropOffs {
+		funnel.DropOffs[i] = FunnelDropOff{
+			Step:     d.LastStep,
+			Sessions: d.Sessions,
+			Share:    percentage(d.Sessions, counts.Abandoned),
+		}
+	}
+	return funnel
+}
+
+// percentage returns n as a percentage of total, or 0 when total is 0
+func percentage(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
@@ -0,0 +1,55 @@
+package analytics
+
+import (
+	"testing"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildFunnel(t *testing.T) {
+	counts := db.GetChannelFunnelRow{
+		SessionsStarted:     200,
+		MenuReached:         150,
+		RedemptionAttempted: 30,
+		RedemptionCompleted: 24,
+		Abandoned:           40,
+	}
+	dropOffs := []db.ListChannelFunnelDropOffsRow{
+		{LastStep: "redeem", Sessions: 30},
+		{LastStep: "main", Sessions: 10},
+	}
+
+	funnel := buildFunnel(counts, dropOffs)
+
+	assert.Equal(t, []FunnelStage{
+		{Stage: StageSessionsStarted, Sessions: 200, Conversion: 100, OfStarted: 100},
+		{Stage: StageMenuReached, Sessions: 150, Conversion: 75, OfStarted: 75},
+		{Stage: StageRedemptionAttempted, Sessions: 30, Conversion: 20, OfStarted: 15},
+		{Stage: StageRedemptionCompleted, Sessions: 24, Conversion: 80, OfStarted: 12},
+	}, funnel.Stages)
+	assert.Equal(t, int64(40), funnel.Abandoned)
+	assert.Equal(t, []FunnelDropOff{
+		{Step: "redeem", Sessions: 30, Share: 75},
+		{Step: "main", Sessions: 10, Share: 25},
+	}, funnel.DropOffs)
+}
+
+func TestBuildFunnelNoSessions(t *testing.T) {
+	funnel := buildFunnel(db.GetChannelFunnelRow{}, []db.ListChannelFunnelDropOffsRow{})
+
+	assert.Len(t, funnel.Stages, 4)
+	for _, stage := range funnel.Stages {
+		assert.Zero(t, stage.Sessions)
+		assert.Zero(t, stage.Conversion)
+		assert.Zero(t, stage.OfStarted)
+	}
+	assert.Empty(t, funnel.DropOffs)
+}
+
+func TestStageIndexIsCumulative(t *testing.T) {
+	assert.Less(t, stageIndex(StageSessionsStarted), stageIndex(StageMenuReached))
+	assert.Less(t, stageIndex(StageMenuReached), stageIndex(StageRedemptionAttempted))
+	assert.Less(t, stageIndex(StageRedemptionAttempted), stageIndex(StageRedemptionCompleted))
+	assert.Equal(t, 0, stageIndex("unknown"))
+}
@@ -0,0 +1,166 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/retention"
+	"github.com/bmachimbira/loyalty/api/internal/timezone"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// rollupOverlap is how far before the last refresh each run starts looking
+// for new activity, covering transactions that committed late
+const rollupOverlap = 10 * time.Minute
+
+// RollupWorker maintains the daily rollup tables (events_daily,
+// issuances_daily, budget_spend_daily). Each run rebuilds, per tenant, every
+// day from the earliest one touched since the previous run up to today. Days
+// are dates in the tenant's time zone.
+type RollupWorker struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+	logger  *slog.Logger
+}
+
+// NewRollupWorker creates a rollup worker writing through pool
+func NewRollupWorker(pool *pgxpool.Pool, logger *slog.Logger) *RollupWorker {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &RollupWorker{
+		pool:    pool,
+		queries: db.New(pool),
+		logger:  logger,
+	}
+}
+
+// RefreshTenant brings the tenant's rollups up to date as of now. A tenant
+// without rollups is backfilled from its creation.
+func (w *RollupWorker) RefreshTenant(ctx context.Context, tenant db.Tenant, now time.Time) error {
+	tx, err := w.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Rollups are rebuilt outside a tenant request
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenant.ID.Bytes)); err != nil {
+		return fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	qtx := w.queries.WithTx(tx)
+
+	from := tenant.CreatedAt.Time
+	state, err := qtx.GetRollupState(ctx, tenant.ID)
+	switch {
+	case err == nil:
+		since := state.RefreshedAt.Time.Add(-rollupOverlap)
+		from = since
+
+		// Back-dated events reopen the days they fall in
+		earliest, err := qtx.GetEarliestEventSince(ctx, db.GetEarliestEventSinceParams{
+			TenantID:  tenant.ID,
+			CreatedAt: pgtype.Timestamptz{Time: since, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to find back-dated events: %w", err)
+		}
+		if earliest.Valid && earliest.Time.Before(from) {
+			from = earliest.Time
+		}
+
+		// Days past the tenant's event retention may have been purged, so
+		// they keep the counts they were rolled up with
+		if cutoff, ok := retention.EventCutoff(tenant, now); ok && from.Before(cutoff) {
+			from = cutoff
+		}
+	case !errors.Is(err, pgx.ErrNoRows):
+		return fmt.Errorf("failed to get rollup state: %w", err)
+	}
+
+	zone := timezone.Load(tenant.Timezone)
+	fromDay := pgtype.Date{Time: truncate(from, IntervalDay, zone), Valid: true}
+
+	if err := qtx.DeleteEventsDaily(ctx, db.DeleteEventsDailyParams{TenantID: tenant.ID, FromDay: fromDay}); err != nil {
+		return fmt.Errorf("failed to clear events rollup: %w", err)
+	}
+	if err := qtx.RefreshEventsDaily(ctx, db.RefreshEventsDailyParams{Timezone: zone.String(), TenantID: tenant.ID, FromDay: fromDay}); err != nil {
+		return fmt.Errorf("failed to refresh events rollup: %w", err)
+	}
+	if err := qtx.DeleteIssuancesDaily(ctx, db.DeleteIssuancesDailyParams{TenantID: tenant.ID, FromDay: fromDay}); err != nil {
+		return fmt.Errorf("failed to clear issuances rollup: %w", err)
+	}
+	if err := qtx.RefreshIssuancesDaily(ctx, db.RefreshIssuancesDailyParams{Timezone: zone.String(), TenantID: tenant.ID, FromDay: fromDay}); err != nil {
+		return fmt.Errorf("failed to refresh issuances rollup: %w", err)
+	}
+	if err := qtx.DeleteBudgetSpendDaily(ctx, db.DeleteBudgetSpendDailyParams{TenantID: tenant.ID, FromDay: fromDay}); err != nil {
+		return fmt.Errorf("failed to clear budget spend rollup: %w", err)
+	}
+	if err := qtx.RefreshBudgetSpendDaily(ctx, db.RefreshBudgetSpendDailyParams{Timezone: zone.String(), TenantID: tenant.ID, FromDay: fromDay}); err != nil {
+		return fmt.Errorf("failed to refresh budget spend rollup: %w", err)
+	}
+
+	if err := qtx.UpsertRollupState(ctx, db.UpsertRollupStateParams{
+		TenantID:    tenant.ID,
+		RefreshedAt: pgtype.Timestamptz{Time: now, Valid: true},
+	}); err != nil {
+		return fmt.Errorf("failed to record rollup state: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RefreshAll refreshes the rollups of every tenant. A failing tenant is
+// logged and skipped. It returns the number of tenants refreshed.
+func (w *RollupWorker) RefreshAll(ctx context.Context, now time.Time) (int, error) {
+	tenants, err := w.queries.ListTenants(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	refreshed := 0
+	for _, tenant := range tenants {
+		if err := w.RefreshTenant(ctx, tenant, now); err != nil {
+			w.logger.Error("failed to refresh analytics rollups",
+				"tenant_id", tenant.ID,
+				"error", err)
+			continue
+		}
+		refreshed++
+	}
+	return refreshed, nil
+}
+
+// Run refreshes the rollups on a schedule until ctx is cancelled
+func (w *RollupWorker) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		if n, err := w.RefreshAll(ctx, start); err != nil {
+			w.logger.Error("analytics rollup failed", "error", err)
+		} else {
+			w.logger.Debug("analytics rollups refreshed",
+				"tenants", n,
+				"duration_ms", time.Since(start).Milliseconds())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
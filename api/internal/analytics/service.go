@@ -2,16 +2,22 @@ package analytics
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"time"
 
+	"github.com/bmachimbira/loyalty/api/internal/budget"
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/timezone"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
 // Service handles analytics operations
 type Service struct {
 	queries *db.Queries
+	zones   *timezone.Resolver
 	logger  *slog.Logger
 }
 
@@ -22,10 +28,30 @@ func NewService(queries *db.Queries, logger *slog.Logger) *Service {
 	}
 	return &Service{
 		queries: queries,
+		zones:   timezone.NewResolver(queries),
 		logger:  logger,
 	}
 }
 
+// Figure sources reported in Freshness
+const (
+	// SourceRollup means figures come from the daily rollup tables
+	SourceRollup = "rollup"
+	// SourceLive means figures were aggregated from the source tables
+	SourceLive = "live"
+)
+
+// Freshness tells clients how current figures are. Rollup figures miss
+// activity after RefreshedAt.
+type Freshness struct {
+	Source      string     `json:"source"`
+	RefreshedAt *time.Time `json:"refreshed_at,omitempty"`
+}
+
+// DashboardCriticalAlerts is how many unacknowledged critical budget alerts
+// the dashboard lists
+const DashboardCriticalAlerts = 10
+
 // DashboardStats represents the dashboard statistics
 type DashboardStats struct {
 	ActiveCustomers      int64
@@ -33,22 +59,40 @@ type DashboardStats struct {
 	RewardsIssuedToday   int64
 	RewardsRedeemedToday int64
 	RedemptionRate       float64
+	Freshness            Freshness
+	// CriticalAlerts are the newest unacknowledged critical budget alerts,
+	// of UnacknowledgedCriticalAlerts in all
+	CriticalAlerts               []budget.AlertRecord
+	UnacknowledgedCriticalAlerts int64
 }
 
 // GetDashboardStats retrieves dashboard statistics for a tenant
 func (s *Service) GetDashboardStats(ctx context.Context, tenantID pgtype.UUID) (*DashboardStats, error) {
-	// Get start of today in UTC
-	now := time.Now().UTC()
-	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
-	
+	// Today starts at midnight in the tenant's time zone
+	loc, err := s.zones.Location(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	startOfDay := timezone.StartOfDay(time.Now(), loc)
+
 	var todayTimestamp pgtype.Timestamptz
 	todayTimestamp.Time = startOfDay
 	todayTimestamp.Valid = true
 
-	stats, err := s.queries.GetDashboardStats(ctx, db.GetDashboardStatsParams{
-		TenantID:   tenantID,
-		OccurredAt: todayTimestamp,
-	})
+	freshness, err := s.rollupFreshness(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats db.GetDashboardStatsRow
+	if freshness.Source == SourceRollup {
+		stats, err = s.dashboardFromRollups(ctx, tenantID, startOfDay)
+	} else {
+		stats, err = s.queries.GetDashboardStats(ctx, db.GetDashboardStatsParams{
+			TenantID:   tenantID,
+			OccurredAt: todayTimestamp,
+		})
+	}
 	if err != nil {
 		s.logger.Error("Failed to fetch dashboard stats",
 			"tenant_id", tenantID,
@@ -63,11 +107,75 @@ func (s *Service) GetDashboardStats(ctx context.Context, tenantID pgtype.UUID) (
 		redemptionRate = (float64(stats.RewardsRedeemedToday) / float64(stats.RewardsIssuedToday)) * 100
 	}
 
+	alerts, err := s.queries.ListBudgetAlerts(ctx, db.ListBudgetAlertsParams{
+		TenantID:     tenantID,
+		Level:        pgtype.Text{String: string(budget.AlertLevelCritical), Valid: true},
+		Acknowledged: pgtype.Bool{Bool: false, Valid: true},
+		RowLimit:     DashboardCriticalAlerts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list critical budget alerts: %w", err)
+	}
+	unacknowledged, err := s.queries.CountUnacknowledgedBudgetAlerts(ctx, db.CountUnacknowledgedBudgetAlertsParams{
+		TenantID: tenantID,
+		Level:    string(budget.AlertLevelCritical),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count critical budget alerts: %w", err)
+	}
+	criticalAlerts := make([]budget.AlertRecord, len(alerts))
+	for i, alert := range alerts {
+		criticalAlerts[i] = budget.NewAlertRecord(alert)
+	}
+
 	return &DashboardStats{
-		ActiveCustomers:      stats.ActiveCustomers,
-		EventsToday:          stats.EventsToday,
-		RewardsIssuedToday:   stats.RewardsIssuedToday,
-		RewardsRedeemedToday: stats.RewardsRedeemedToday,
-		RedemptionRate:       redemptionRate,
+		ActiveCustomers:              stats.ActiveCustomers,
+		EventsToday:                  stats.EventsToday,
+		RewardsIssuedToday:           stats.RewardsIssuedToday,
+		RewardsRedeemedToday:         stats.RewardsRedeemedToday,
+		RedemptionRate:               redemptionRate,
+		Freshness:                    freshness,
+		CriticalAlerts:               criticalAlerts,
+		UnacknowledgedCriticalAlerts: unacknowledged,
 	}, nil
 }
+
+// dashboardFromRollups reads today's dashboard counts from the daily rollups
+func (s *Service) dashboardFromRollups(ctx context.Context, tenantID pgtype.UUID, day time.Time) (db.GetDashboardStatsRow, error) {
+	active, err := s.queries.CountActiveCustomers(ctx, tenantID)
+	if err != nil {
+		return db.GetDashboardStatsRow{}, err
+	}
+	totals, err := s.queries.GetDailyTotals(ctx, db.GetDailyTotalsParams{
+		TenantID: tenantID,
+		Day:      pgtype.Date{Time: day, Valid: true},
+	})
+	if err != nil {
+		return db.GetDashboardStatsRow{}, err
+	}
+	return db.GetDashboardStatsRow{
+		ActiveCustomers:      active,
+		EventsToday:          totals.Events,
+		RewardsIssuedToday:   totals.Issued,
+		RewardsRedeemedToday: totals.Redeemed,
+	}, nil
+}
+
+// Location returns the time zone the tenant's figures are reported in
+func (s *Service) Location(ctx context.Context, tenantID pgtype.UUID) (*time.Location, error) {
+	return s.zones.Location(ctx, tenantID)
+}
+
+// rollupFreshness reports whether the tenant's rollups have been built and
+// when they were last refreshed
+func (s *Service) rollupFreshness(ctx context.Context, tenantID pgtype.UUID) (Freshness, error) {
+	state, err := s.queries.GetRollupState(ctx, tenantID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Freshness{Source: SourceLive}, nil
+	}
+	if err != nil {
+		return Freshness{}, fmt.Errorf("failed to get rollup state: %w", err)
+	}
+	refreshedAt := state.RefreshedAt.Time
+	return Freshness{Source: SourceRollup, RefreshedAt: &refreshedAt}, nil
+}
@@ -0,0 +1,387 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Time-series metrics
+const (
+	MetricIssuances   = "issuances"
+	MetricRedemptions = "redemptions"
+	MetricEvents      = "events"
+)
+
+// Time-series bucket intervals. Buckets are aligned in the tenant's time
+// zone; weeks start on Monday.
+const (
+	IntervalHour  = "hour"
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+// maxBuckets bounds the size of a single time-series response
+const maxBuckets = 1000
+
+var (
+	// ErrInvalidMetric is returned for an unknown metric
+	ErrInvalidMetric = errors.New("metric must be issuances, redemptions or events")
+
+	// ErrInvalidInterval is returned for an unknown bucket interval
+	ErrInvalidInterval = errors.New("interval must be hour, day, week or month")
+
+	// ErrInvalidRange is returned when from is not before to
+	ErrInvalidRange = errors.New("from must be before to")
+
+	// ErrTooManyBuckets is returned when the range holds more than maxBuckets buckets
+	ErrTooManyBuckets = fmt.Errorf("range spans more than %d buckets; use a larger interval", maxBuckets)
+
+	// ErrInvalidFilter is returned for a filter that doesn't apply to the metric
+	ErrInvalidFilter = errors.New("campaign_id applies to issuances and redemptions; event_type applies to events")
+)
+
+// TimeseriesParams selects a time series. CampaignID filters issuances and
+// redemptions; EventType filters events. Location is the time zone buckets
+// align in; GetTimeseries sets it to the tenant's.
+type TimeseriesParams struct {
+	TenantID   pgtype.UUID
+	Metric     string
+	Interval   string
+	From       time.Time
+	To         time.Time
+	CampaignID pgtype.UUID
+	EventType  string
+	Location   *time.Location
+}
+
+// Validate checks the parameters describe a bounded series
+func (p TimeseriesParams) Validate() error {
+	switch p.Metric {
+	case MetricIssuances, MetricRedemptions:
+		if p.EventType != "" {
+			return ErrInvalidFilter
+		}
+	case MetricEvents:
+		if p.CampaignID.Valid {
+			return ErrInvalidFilter
+		}
+	default:
+		return ErrInvalidMetric
+	}
+
+	if !validInterval(p.Interval) {
+		return ErrInvalidInterval
+	}
+	if !p.From.Before(p.To) {
+		return ErrInvalidRange
+	}
+	if len(bucketStarts(p.From, p.To, p.Interval, p.location())) > maxBuckets {
+		return ErrTooManyBuckets
+	}
+	return nil
+}
+
+// location returns the time zone buckets align in, UTC if unset
+func (p TimeseriesParams) location() *time.Location {
+	if p.Location == nil {
+		return time.UTC
+	}
+	return p.Location
+}
+
+// Bucket is one interval of a time series. Amounts holds the summed money
+// value per currency: cost for issuances, face value for redemptions.
+// UniqueCustomers is only set for events.
+type Bucket struct {
+	Start           time.Time         `json:"start"`
+	Count           int64             `json:"count"`
+	Amounts         map[string]string `json:"amounts,omitempty"`
+	UniqueCustomers *int64            `json:"unique_customers,omitempty"`
+}
+
+// Timeseries is a gap-free series of buckets covering [From, To). Series
+// served from the daily rollups widen From and To to whole days in the
+// tenant's time zone.
+type Timeseries struct {
+	Metric    string    `json:"metric"`
+	Interval  string    `json:"interval"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Buckets   []Bucket  `json:"buckets"`
+	Freshness Freshness `json:"freshness"`
+}
+
+// GetTimeseries returns bucketed counts and values for a metric. Buckets with
+// no activity are included with zero counts.
+func (s *Service) GetTimeseries(ctx context.Context, params TimeseriesParams) (*Timeseries, error) {
+	if params.Location == nil {
+		loc, err := s.zones.Location(ctx, params.TenantID)
+		if err != nil {
+			return nil, err
+		}
+		params.Location = loc
+	}
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	loc := params.Location
+	zone := loc.String()
+
+	freshness := Freshness{Source: SourceLive}
+	if rollupServes(params) {
+		var err error
+		if freshness, err = s.rollupFreshness(ctx, params.TenantID); err != nil {
+			return nil, err
+		}
+	}
+	if freshness.Source == SourceRollup {
+		params.From = truncate(params.From, IntervalDay, loc)
+		if to := truncate(params.To, IntervalDay, loc); to.Before(params.To) {
+			params.To = to.AddDate(0, 0, 1)
+		}
+	}
+
+	from := pgtype.Timestamptz{Time: params.From, Valid: true}
+	to := pgtype.Timestamptz{Time: params.To, Valid: true}
+
+	starts := bucketStarts(params.From, params.To, params.Interval, loc)
+	buckets := make([]Bucket, len(starts))
+	index := make(map[int64]int, len(starts))
+	for i, start := range starts {
+		buckets[i] = Bucket{Start: start}
+		index[start.Unix()] = i
+	}
+	bucketAt := func(ts pgtype.Timestamptz) *Bucket {
+		if i, ok := index[ts.Time.UTC().Unix()]; ok {
+			return &buckets[i]
+		}
+		return nil
+	}
+
+	// Rollup days are dates in the tenant's time zone
+	fromDay := pgtype.Date{Time: params.From.In(loc), Valid: true}
+	toDay := pgtype.Date{Time: params.To.In(loc), Valid: true}
+
+	switch {
+	case freshness.Source == SourceRollup && params.Metric == MetricIssuances:
+		rows, err := s.queries.GetIssuanceRollupTimeseries(ctx, db.GetIssuanceRollupTimeseriesParams{
+			BucketInterval: params.Interval,
+			Timezone:       zone,
+			TenantID:       params.TenantID,
+			FromDay:        fromDay,
+			ToDay:          toDay,
+			CampaignID:     params.CampaignID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get issuance rollup timeseries: %w", err)
+		}
+		for _, row := range rows {
+			addMoney(bucketAt(row.Bucket), row.Currency, row.Count, row.Amount)
+		}
+
+	case freshness.Source == SourceRollup && params.Metric == MetricRedemptions:
+		rows, err := s.queries.GetRedemptionRollupTimeseries(ctx, db.GetRedemptionRollupTimeseriesParams{
+			BucketInterval: params.Interval,
+			Timezone:       zone,
+			TenantID:       params.TenantID,
+			FromDay:        fromDay,
+			ToDay:          toDay,
+			CampaignID:     params.CampaignID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get redemption rollup timeseries: %w", err)
+		}
+		for _, row := range rows {
+			addMoney(bucketAt(row.Bucket), row.Currency, row.Count, row.Amount)
+		}
+
+	case freshness.Source == SourceRollup && params.Metric == MetricEvents:
+		rows, err := s.queries.GetEventRollupTimeseries(ctx, db.GetEventRollupTimeseriesParams{
+			Timezone:  zone,
+			TenantID:  params.TenantID,
+			FromDay:   fromDay,
+			ToDay:     toDay,
+			EventType: pgtype.Text{String: params.EventType, Valid: params.EventType != ""},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get event rollup timeseries: %w", err)
+		}
+		counts := make([]eventCount, len(rows))
+		for i, row := range rows {
+			counts[i] = eventCount{row.Bucket, row.Count, row.UniqueCustomers}
+		}
+		setEventCounts(buckets, bucketAt, counts)
+
+	case params.Metric == MetricIssuances:
+		rows, err := s.queries.GetIssuanceTimeseries(ctx, db.GetIssuanceTimeseriesParams{
+			BucketInterval: params.Interval,
+			Timezone:       zone,
+			TenantID:       params.TenantID,
+			FromTime:       from,
+			ToTime:         to,
+			CampaignID:     params.CampaignID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get issuance timeseries: %w", err)
+		}
+		for _, row := range rows {
+			addMoney(bucketAt(row.Bucket), row.Currency, row.Count, row.Amount)
+		}
+
+	case params.Metric == MetricRedemptions:
+		rows, err := s.queries.GetRedemptionTimeseries(ctx, db.GetRedemptionTimeseriesParams{
+			BucketInterval: params.Interval,
+			Timezone:       zone,
+			TenantID:       params.TenantID,
+			FromTime:       from,
+			ToTime:         to,
+			CampaignID:     params.CampaignID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get redemption timeseries: %w", err)
+		}
+		for _, row := range rows {
+			addMoney(bucketAt(row.Bucket), row.Currency, row.Count, row.Amount)
+		}
+
+	case params.Metric == MetricEvents:
+		eventType := pgtype.Text{String: params.EventType, Valid: params.EventType != ""}
+		rows, err := s.queries.GetEventTimeseries(ctx, db.GetEventTimeseriesParams{
+			BucketInterval: params.Interval,
+			Timezone:       zone,
+			TenantID:       params.TenantID,
+			FromTime:       from,
+			ToTime:         to,
+			EventType:      eventType,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get event timeseries: %w", err)
+		}
+		counts := make([]eventCount, len(rows))
+		for i, row := range rows {
+			counts[i] = eventCount{row.Bucket, row.Count, row.UniqueCustomers}
+		}
+		setEventCounts(buckets, bucketAt, counts)
+	}
+
+	return &Timeseries{
+		Metric:    params.Metric,
+		Interval:  params.Interval,
+		From:      params.From,
+		To:        params.To,
+		Buckets:   buckets,
+		Freshness: freshness,
+	}, nil
+}
+
+// eventCount is one bucket of an events query, live or rollup
+type eventCount struct {
+	bucket          pgtype.Timestamptz
+	count           int64
+	uniqueCustomers int64
+}
+
+// setEventCounts fills event buckets from counts, zeroing empty buckets
+func setEventCounts(buckets []Bucket, bucketAt func(pgtype.Timestamptz) *Bucket, counts []eventCount) {
+	for i := range buckets {
+		var zero int64
+		buckets[i].UniqueCustomers = &zero
+	}
+	for _, c := range counts {
+		if b := bucketAt(c.bucket); b != nil {
+			b.Count = c.count
+			*b.UniqueCustomers = c.uniqueCustomers
+		}
+	}
+}
+
+// rollupServes reports whether the daily rollups can answer the series.
+// Hourly series need raw rows, and unique customers can't be summed across
+// days, so events only come from the rollups by day.
+func rollupServes(params TimeseriesParams) bool {
+	switch params.Interval {
+	case IntervalHour:
+		return false
+	case IntervalDay:
+		return true
+	default:
+		return params.Metric != MetricEvents
+	}
+}
+
+// addMoney adds a per-currency row to its bucket
+func addMoney(b *Bucket, currency string, count int64, amount pgtype.Numeric) {
+	if b == nil {
+		return
+	}
+	b.Count += count
+	if currency == "" {
+		return
+	}
+	if b.Amounts == nil {
+		b.Amounts = make(map[string]string)
+	}
+	b.Amounts[currency] = httputil.FormatNumeric(amount)
+}
+
+// validInterval reports whether interval is a supported bucket interval
+func validInterval(interval string) bool {
+	switch interval {
+	case IntervalHour, IntervalDay, IntervalWeek, IntervalMonth:
+		return true
+	}
+	return false
+}
+
+// truncate aligns t to the start of its bucket in loc, matching Postgres
+// date_trunc with a time zone
+func truncate(t time.Time, interval string, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch interval {
+	case IntervalHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case IntervalWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+		return day.AddDate(0, 0, -offset)
+	case IntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+}
+
+// next returns the start of the bucket after start
+func next(start time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalHour:
+		return start.Add(time.Hour)
+	case IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	case IntervalMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// bucketStarts lists the start of every bucket overlapping [from, to),
+// aligned in loc. It stops one past maxBuckets so oversized ranges are cheap
+// to reject.
+func bucketStarts(from, to time.Time, interval string, loc *time.Location) []time.Time {
+	if !validInterval(interval) {
+		return nil
+	}
+	var starts []time.Time
+	for t := truncate(from, interval, loc); t.Before(to) && len(starts) <= maxBuckets; t = next(t, interval) {
+		starts = append(starts, t)
+	}
+	return starts
+}
@@ -0,0 +1,117 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucketStarts(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		interval string
+		want     []time.Time
+	}{
+		{
+			name:     "days align to midnight",
+			from:     time.Date(2025, 3, 1, 15, 30, 0, 0, time.UTC),
+			to:       day(2025, 3, 3),
+			interval: IntervalDay,
+			want:     []time.Time{day(2025, 3, 1), day(2025, 3, 2)},
+		},
+		{
+			name:     "weeks start on Monday",
+			from:     day(2025, 3, 5), // Wednesday
+			to:       day(2025, 3, 12),
+			interval: IntervalWeek,
+			want:     []time.Time{day(2025, 3, 3), day(2025, 3, 10)},
+		},
+		{
+			name:     "months",
+			from:     day(2025, 1, 31),
+			to:       day(2025, 3, 1),
+			interval: IntervalMonth,
+			want:     []time.Time{day(2025, 1, 1), day(2025, 2, 1)},
+		},
+		{
+			name:     "hours",
+			from:     time.Date(2025, 3, 1, 10, 15, 0, 0, time.UTC),
+			to:       time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+			interval: IntervalHour,
+			want: []time.Time{
+				time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC),
+				time.Date(2025, 3, 1, 11, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, bucketStarts(tt.from, tt.to, tt.interval, time.UTC))
+		})
+	}
+}
+
+func TestBucketStartsInTenantZone(t *testing.T) {
+	harare := time.FixedZone("CAT", 2*60*60)
+
+	// 23:00 UTC on the 1st is already the 2nd in Harare
+	starts := bucketStarts(
+		time.Date(2025, 3, 1, 23, 0, 0, 0, time.UTC),
+		time.Date(2025, 3, 3, 22, 0, 0, 0, time.UTC),
+		IntervalDay,
+		harare,
+	)
+	want := []time.Time{
+		time.Date(2025, 3, 2, 0, 0, 0, 0, harare),
+		time.Date(2025, 3, 3, 0, 0, 0, 0, harare),
+	}
+	assert.Len(t, starts, len(want))
+	for i := range want {
+		assert.True(t, want[i].Equal(starts[i]), "bucket %d = %v, want %v", i, starts[i], want[i])
+	}
+}
+
+func TestTimeseriesParamsValidate(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	valid := TimeseriesParams{
+		Metric:   MetricIssuances,
+		Interval: IntervalDay,
+		From:     from,
+		To:       from.AddDate(0, 0, 30),
+	}
+	assert.NoError(t, valid.Validate())
+
+	p := valid
+	p.Metric = "customers"
+	assert.ErrorIs(t, p.Validate(), ErrInvalidMetric)
+
+	p = valid
+	p.Interval = "minute"
+	assert.ErrorIs(t, p.Validate(), ErrInvalidInterval)
+
+	p = valid
+	p.To = p.From
+	assert.ErrorIs(t, p.Validate(), ErrInvalidRange)
+
+	p = valid
+	p.Interval = IntervalHour
+	p.To = from.AddDate(1, 0, 0)
+	assert.ErrorIs(t, p.Validate(), ErrTooManyBuckets)
+
+	p = valid
+	p.EventType = "purchase"
+	assert.ErrorIs(t, p.Validate(), ErrInvalidFilter)
+
+	p = valid
+	p.Metric = MetricEvents
+	p.CampaignID = pgtype.UUID{Valid: true}
+	assert.ErrorIs(t, p.Validate(), ErrInvalidFilter)
+}
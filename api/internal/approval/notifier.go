@@ -0,0 +1,60 @@
+package approval
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/budget"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+)
+
+// EmailNotifier emails pending approvals to approvers over SMTP
+type EmailNotifier struct {
+	config budget.SMTPConfig
+}
+
+// NewEmailNotifier creates a new email approval notifier
+func NewEmailNotifier(config budget.SMTPConfig) *EmailNotifier {
+	if config.Port == "" {
+		config.Port = "587"
+	}
+	return &EmailNotifier{config: config}
+}
+
+// Notify emails every approver about the pending approval
+func (n *EmailNotifier) Notify(ctx context.Context, approval db.Approval, approvers []db.StaffUser) error {
+	to := make([]string, len(approvers))
+	for i, a := range approvers {
+		to[i] = a.Email
+	}
+
+	subject := fmt.Sprintf("Approval needed: %s %s", approval.Action, approval.EntityType)
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	msg.WriteString("\r\n")
+	fmt.Fprintf(&msg, "A request to %s %s %s is waiting for your approval.\r\n\r\n",
+		approval.Action, approval.EntityType, httputil.FormatUUID(approval.EntityID.Bytes))
+	fmt.Fprintf(&msg, "Comment: %s\r\n", approval.RequestComment)
+	fmt.Fprintf(&msg, "Approval ID: %s\r\n", httputil.FormatUUID(approval.ID.Bytes))
+
+	var auth smtp.Auth
+	if n.config.Username != "" {
+		auth = smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)
+	}
+
+	addr := net.JoinHostPort(n.config.Host, n.config.Port)
+	if err := smtp.SendMail(addr, auth, n.config.From, to, []byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to send approval email: %w", err)
+	}
+
+	return nil
+}
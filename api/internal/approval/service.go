@@ -0,0 +1,552 @@
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/catalogcache"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Entity types that can require approval
+const (
+	EntityCampaign = "campaign"
+	EntityRule     = "rule"
+)
+
+// Approval actions
+const (
+	// ActionActivate moves a draft campaign or an inactive rule live
+	ActionActivate = "activate"
+	// ActionUpdate applies proposed fields to a live campaign
+	ActionUpdate = "update"
+)
+
+// Approval statuses
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+)
+
+// Campaign lifecycle statuses managed by approvals
+const (
+	CampaignDraft           = "draft"
+	CampaignPendingApproval = "pending_approval"
+	CampaignActive          = "active"
+)
+
+// notifyTimeout bounds a background approver notification
+const notifyTimeout = 30 * time.Second
+
+var (
+	// ErrApprovalNotFound is returned when the approval does not exist
+	ErrApprovalNotFound = errors.New("approval not found")
+
+	// ErrNotPending is returned when deciding an approval that was already decided
+	ErrNotPending = errors.New("approval is not pending")
+
+	// ErrAlreadyPending is returned when the entity already has an open approval
+	ErrAlreadyPending = errors.New("a change is already pending approval")
+
+	// ErrSelfApproval is returned when the requester tries to approve their own change
+	ErrSelfApproval = errors.New("changes must be approved by someone other than the requester")
+
+	// ErrCommentRequired is returned when a request or decision has no comment
+	ErrCommentRequired = errors.New("comment is required")
+
+	// ErrEntityNotFound is returned when the campaign or rule does not exist
+	ErrEntityNotFound = errors.New("campaign or rule not found")
+
+	// ErrInvalidState is returned when the entity cannot go through the requested change
+	ErrInvalidState = errors.New("entity is not in a state that allows this change")
+
+	// ErrInvalidRequest is returned for an unknown entity type or action
+	ErrInvalidRequest = errors.New("invalid entity type or action")
+)
+
+// CampaignChange is the proposed state of a campaign in an update approval
+type CampaignChange struct {
+	Name     string     `json:"name"`
+	StartAt  *time.Time `json:"start_at,omitempty"`
+	EndAt    *time.Time `json:"end_at,omitempty"`
+	BudgetID string     `json:"budget_id,omitempty"`
+	Status   string     `json:"status"`
+}
+
+// activatePayload records the draft rules activated with a campaign
+type activatePayload struct {
+	RuleIDs []string `json:"rule_ids,omitempty"`
+}
+
+// SubmitParams contains the fields of a change submitted for approval
+type SubmitParams struct {
+	TenantID    pgtype.UUID
+	EntityType  string
+	EntityID    pgtype.UUID
+	Action      string
+	Change      *CampaignChange
+	RequestedBy pgtype.UUID
+	Comment     string
+}
+
+// Validate validates the submission
+func (p SubmitParams) Validate() error {
+	if !p.TenantID.Valid || !p.EntityID.Valid || !p.RequestedBy.Valid {
+		return errors.New("tenant_id, entity_id and requested_by are required")
+	}
+	if strings.TrimSpace(p.Comment) == "" {
+		return ErrCommentRequired
+	}
+	switch {
+	case p.EntityType == EntityCampaign && p.Action == ActionActivate,
+		p.EntityType == EntityRule && p.Action == ActionActivate:
+		return nil
+	case p.EntityType == EntityCampaign && p.Action == ActionUpdate:
+		if p.Change == nil {
+			return errors.New("proposed campaign change is required")
+		}
+		if p.Change.Status == CampaignDraft || p.Change.Status == CampaignPendingApproval {
+			return ErrInvalidState
+		}
+		return nil
+	default:
+		return ErrInvalidRequest
+	}
+}
+
+// Notifier tells approvers that a change is waiting for them
+type Notifier interface {
+	Notify(ctx context.Context, approval db.Approval, approvers []db.StaffUser) error
+}
+
+// Service manages maker-checker approvals for campaigns and rules
+type Service struct {
+	pool          *pgxpool.Pool
+	queries       *db.Queries
+	catalog       *catalogcache.Cache
+	notifier      Notifier
+	logger        *slog.Logger
+	notifications sync.WaitGroup
+}
+
+// NewService creates a new approval service. Approved campaign changes
+// invalidate the campaign in catalog.
+func NewService(pool *pgxpool.Pool, queries *db.Queries, catalog *catalogcache.Cache, logger *slog.Logger) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Service{
+		pool:    pool,
+		queries: queries,
+		catalog: catalog,
+		logger:  logger,
+	}
+}
+
+// SetNotifier sets how approvers are told about new submissions.
+// Without a notifier submissions are only logged.
+func (s *Service) SetNotifier(notifier Notifier) {
+	s.notifier = notifier
+}
+
+// RequiresApproval reports whether the tenant has maker-checker enabled
+func (s *Service) RequiresApproval(ctx context.Context, tenantID pgtype.UUID) (bool, error) {
+	tenant, err := s.queries.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get tenant: %w", err)
+	}
+	return tenant.RequireApproval, nil
+}
+
+// Submit records a change for approval. Activating a draft campaign moves
+// it to pending_approval along with its inactive rules.
+func (s *Service) Submit(ctx context.Context, params SubmitParams) (db.Approval, error) {
+	if err := params.Validate(); err != nil {
+		return db.Approval{}, err
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.Approval{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	var payload interface{} = struct{}{}
+	switch params.EntityType {
+	case EntityCampaign:
+		campaign, err := qtx.GetCampaignForUpdate(ctx, db.GetCampaignForUpdateParams{
+			ID:       params.EntityID,
+			TenantID: params.TenantID,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return db.Approval{}, ErrEntityNotFound
+			}
+			return db.Approval{}, fmt.Errorf("failed to get campaign: %w", err)
+		}
+
+		if params.Action == ActionUpdate {
+			if campaign.Status == CampaignDraft || campaign.Status == CampaignPendingApproval {
+				return db.Approval{}, ErrInvalidState
+			}
+			payload = params.Change
+			break
+		}
+
+		if campaign.Status != CampaignDraft {
+			return db.Approval{}, ErrInvalidState
+		}
+		rules, err := qtx.ListRulesByCampaign(ctx, db.ListRulesByCampaignParams{
+			TenantID:   params.TenantID,
+			CampaignID: campaign.ID,
+		})
+		if err != nil {
+			return db.Approval{}, fmt.Errorf("failed to list campaign rules: %w", err)
+		}
+		activate := activatePayload{}
+		for _, r := range rules {
+			if !r.Active {
+				activate.RuleIDs = append(activate.RuleIDs, httputil.FormatUUID(r.ID.Bytes))
+			}
+		}
+		payload = activate
+
+		if err := qtx.UpdateCampaignStatus(ctx, db.UpdateCampaignStatusParams{
+			ID:       campaign.ID,
+			TenantID: params.TenantID,
+			Status:   CampaignPendingApproval,
+		}); err != nil {
+			return db.Approval{}, fmt.Errorf("failed to update campaign status: %w", err)
+		}
+
+	case EntityRule:
+		rule, err := qtx.GetRuleForUpdate(ctx, db.GetRuleForUpdateParams{
+			ID:       params.EntityID,
+			TenantID: params.TenantID,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return db.Approval{}, ErrEntityNotFound
+			}
+			return db.Approval{}, fmt.Errorf("failed to get rule: %w", err)
+		}
+		if rule.Active {
+			return db.Approval{}, ErrInvalidState
+		}
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return db.Approval{}, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	approval, err := qtx.CreateApproval(ctx, db.CreateApprovalParams{
+		TenantID:       params.TenantID,
+		EntityType:     params.EntityType,
+		EntityID:       params.EntityID,
+		Action:         params.Action,
+		Payload:        payloadJSON,
+		RequestedBy:    params.RequestedBy,
+		RequestComment: strings.TrimSpace(params.Comment),
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return db.Approval{}, ErrAlreadyPending
+		}
+		return db.Approval{}, fmt.Errorf("failed to create approval: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.Approval{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if params.EntityType == EntityCampaign {
+		s.catalog.InvalidateCampaign(params.TenantID, params.EntityID)
+	}
+	s.notifyApprovers(approval)
+
+	return approval, nil
+}
+
+// notifyApprovers tells the tenant's approvers about a new submission in
+// the background so submitting is not slowed down by delivery
+func (s *Service) notifyApprovers(approval db.Approval) {
+	s.notifications.Add(1)
+	go func() {
+		defer s.notifications.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+
+		approvers, err := s.queries.ListApprovers(ctx, approval.TenantID)
+		if err != nil {
+			s.logger.Error("failed to list approvers", "approval_id", approval.ID, "error", err)
+			return
+		}
+
+		// The requester cannot approve their own change
+		recipients := approvers[:0]
+		for _, a := range approvers {
+			if a.ID != approval.RequestedBy {
+				recipients = append(recipients, a)
+			}
+		}
+
+		if s.notifier == nil || len(recipients) == 0 {
+			s.logger.Info("approval pending",
+				"approval_id", approval.ID,
+				"entity_type", approval.EntityType,
+				"approvers", len(recipients),
+			)
+			return
+		}
+
+		if err := s.notifier.Notify(ctx, approval, recipients); err != nil {
+			s.logger.Error("failed to notify approvers", "approval_id", approval.ID, "error", err)
+		}
+	}()
+}
+
+// WaitForNotifications blocks until in-flight approver notifications finish
+func (s *Service) WaitForNotifications() {
+	s.notifications.Wait()
+}
+
+// Approve applies a pending change. The approver must not be the requester.
+func (s *Service) Approve(ctx context.Context, tenantID, approvalID, approverID pgtype.UUID, comment string) (db.Approval, error) {
+	return s.decide(ctx, tenantID, approvalID, approverID, comment, StatusApproved)
+}
+
+// Reject discards a pending change. Rejected campaign activations return
+// the campaign to draft.
+func (s *Service) Reject(ctx context.Context, tenantID, approvalID, approverID pgtype.UUID, comment string) (db.Approval, error) {
+	return s.decide(ctx, tenantID, approvalID, approverID, comment, StatusRejected)
+}
+
+// decide records a decision and applies its effect in one transaction
+func (s *Service) decide(ctx context.Context, tenantID, approvalID, approverID pgtype.UUID, comment, status string) (db.Approval, error) {
+	comment = strings.TrimSpace(comment)
+	if comment == "" {
+		return db.Approval{}, ErrCommentRequired
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.Approval{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	approval, err := qtx.GetApprovalForUpdate(ctx, db.GetApprovalForUpdateParams{
+		ID:       approvalID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Approval{}, ErrApprovalNotFound
+		}
+		return db.Approval{}, fmt.Errorf("failed to get approval: %w", err)
+	}
+	if approval.Status != StatusPending {
+		return db.Approval{}, ErrNotPending
+	}
+	if approval.RequestedBy == approverID {
+		return db.Approval{}, ErrSelfApproval
+	}
+
+	if status == StatusApproved {
+		err = s.apply(ctx, qtx, approval)
+	} else {
+		err = s.revert(ctx, qtx, approval)
+	}
+	if err != nil {
+		return db.Approval{}, err
+	}
+
+	decided, err := qtx.DecideApproval(ctx, db.DecideApprovalParams{
+		ID:              approval.ID,
+		TenantID:        tenantID,
+		Status:          status,
+		DecidedBy:       approverID,
+		DecisionComment: pgtype.Text{String: comment, Valid: true},
+	})
+	if err != nil {
+		return db.Approval{}, fmt.Errorf("failed to record decision: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.Approval{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if approval.EntityType == EntityCampaign {
+		s.catalog.InvalidateCampaign(tenantID, approval.EntityID)
+	}
+
+	return decided, nil
+}
+
+// apply makes an approved change take effect
+func (s *Service) apply(ctx context.Context, qtx *db.Queries, approval db.Approval) error {
+	switch {
+	case approval.EntityType == EntityRule:
+		if err := qtx.UpdateRuleStatus(ctx, db.UpdateRuleStatusParams{
+			ID:       approval.EntityID,
+			TenantID: approval.TenantID,
+			Active:   true,
+		}); err != nil {
+			return fmt.Errorf("failed to activate rule: %w", err)
+		}
+
+	case approval.Action == ActionActivate:
+		var payload activatePayload
+		if err := json.Unmarshal(approval.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to parse approval payload: %w", err)
+		}
+		if err := qtx.UpdateCampaignStatus(ctx, db.UpdateCampaignStatusParams{
+			ID:       approval.EntityID,
+			TenantID: approval.TenantID,
+			Status:   CampaignActive,
+		}); err != nil {
+			return fmt.Errorf("failed to activate campaign: %w", err)
+		}
+		for _, id := range payload.RuleIDs {
+			var ruleID pgtype.UUID
+			if err := ruleID.Scan(id); err != nil {
+				return fmt.Errorf("invalid rule id in approval payload: %w", err)
+			}
+			if err := qtx.UpdateRuleStatus(ctx, db.UpdateRuleStatusParams{
+				ID:       ruleID,
+				TenantID: approval.TenantID,
+				Active:   true,
+			}); err != nil {
+				return fmt.Errorf("failed to activate rule: %w", err)
+			}
+		}
+
+	default:
+		var change CampaignChange
+		if err := json.Unmarshal(approval.Payload, &change); err != nil {
+			return fmt.Errorf("failed to parse approval payload: %w", err)
+		}
+		params := db.UpdateCampaignParams{
+			ID:       approval.EntityID,
+			TenantID: approval.TenantID,
+			Name:     change.Name,
+			Status:   change.Status,
+		}
+		if change.StartAt != nil {
+			params.StartAt = pgtype.Timestamptz{Time: *change.StartAt, Valid: true}
+		}
+		if change.EndAt != nil {
+			params.EndAt = pgtype.Timestamptz{Time: *change.EndAt, Valid: true}
+		}
+		if change.BudgetID != "" {
+			if err := params.BudgetID.Scan(change.BudgetID); err != nil {
+				return fmt.Errorf("invalid budget id in approval payload: %w", err)
+			}
+		}
+		if err := qtx.UpdateCampaign(ctx, params); err != nil {
+			return fmt.Errorf("failed to update campaign: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// revert undoes the state change made when a change was submitted
+func (s *Service) revert(ctx context.Context, qtx *db.Queries, approval db.Approval) error {
+	if approval.EntityType != EntityCampaign || approval.Action != ActionActivate {
+		return nil
+	}
+	if err := qtx.UpdateCampaignStatus(ctx, db.UpdateCampaignStatusParams{
+		ID:       approval.EntityID,
+		TenantID: approval.TenantID,
+		Status:   CampaignDraft,
+	}); err != nil {
+		return fmt.Errorf("failed to return campaign to draft: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves an approval by ID
+func (s *Service) Get(ctx context.Context, tenantID, approvalID pgtype.UUID) (db.Approval, error) {
+	approval, err := s.queries.GetApprovalByID(ctx, db.GetApprovalByIDParams{
+		ID:       approvalID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Approval{}, ErrApprovalNotFound
+		}
+		return db.Approval{}, fmt.Errorf("failed to get approval: %w", err)
+	}
+	return approval, nil
+}
+
+// List lists approvals, newest first, optionally filtered by status and entity type
+func (s *Service) List(ctx context.Context, tenantID pgtype.UUID, status, entityType, limit, offset string) ([]db.Approval, int64, error) {
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil || limitInt < 1 {
+		limitInt = 50
+	}
+	if limitInt > 100 {
+		limitInt = 100
+	}
+
+	offsetInt, err := strconv.Atoi(offset)
+	if err != nil || offsetInt < 0 {
+		offsetInt = 0
+	}
+
+	var statusFilter, entityFilter pgtype.Text
+	if status != "" {
+		if status != StatusPending && status != StatusApproved && status != StatusRejected {
+			return nil, 0, ErrInvalidRequest
+		}
+		statusFilter = pgtype.Text{String: status, Valid: true}
+	}
+	if entityType != "" {
+		if entityType != EntityCampaign && entityType != EntityRule {
+			return nil, 0, ErrInvalidRequest
+		}
+		entityFilter = pgtype.Text{String: entityType, Valid: true}
+	}
+
+	approvals, err := s.queries.ListApprovals(ctx, db.ListApprovalsParams{
+		TenantID:   tenantID,
+		Status:     statusFilter,
+		EntityType: entityFilter,
+		Limit:      int32(limitInt),
+		Offset:     int32(offsetInt),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list approvals: %w", err)
+	}
+
+	total, err := s.queries.CountApprovals(ctx, db.CountApprovalsParams{
+		TenantID:   tenantID,
+		Status:     statusFilter,
+		EntityType: entityFilter,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count approvals: %w", err)
+	}
+
+	return approvals, total, nil
+}
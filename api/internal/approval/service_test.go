@@ -0,0 +1,40 @@
+package approval
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmitParamsValidate(t *testing.T) {
+	id := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	base := SubmitParams{
+		TenantID:    id,
+		EntityID:    id,
+		RequestedBy: id,
+		EntityType:  EntityCampaign,
+		Action:      ActionActivate,
+		Comment:     "launch for the holidays",
+	}
+	assert.NoError(t, base.Validate())
+
+	p := base
+	p.Comment = "  "
+	assert.ErrorIs(t, p.Validate(), ErrCommentRequired)
+
+	p = base
+	p.EntityType = EntityRule
+	p.Action = ActionUpdate
+	assert.ErrorIs(t, p.Validate(), ErrInvalidRequest)
+
+	p = base
+	p.Action = ActionUpdate
+	assert.Error(t, p.Validate())
+
+	p.Change = &CampaignChange{Name: "Holiday", Status: CampaignDraft}
+	assert.ErrorIs(t, p.Validate(), ErrInvalidState)
+
+	p.Change.Status = CampaignActive
+	assert.NoError(t, p.Validate())
+}
@@ -23,12 +23,20 @@ type HMACKey struct {
 	Secret string `json:"secret"`
 }
 
-// HMACKeys holds the map of API keys
-type HMACKeys map[string]string // key -> secret
+// HMACKeys holds the active secrets of each API key. A key has more than
+// one while its secret is rotated, so clients can switch over without
+// changing their key.
+type HMACKeys map[string][]string // key -> secrets
 
 // LoadHMACKeys loads API keys from the HMAC_KEYS_JSON environment variable
 func LoadHMACKeys() (HMACKeys, error) {
-	keysJSON := os.Getenv("HMAC_KEYS_JSON")
+	return ParseHMACKeys(os.Getenv("HMAC_KEYS_JSON"))
+}
+
+// ParseHMACKeys parses API keys in the HMAC_KEYS_JSON format, e.g.
+// [{"key":"pos","secret":"new"},{"key":"pos","secret":"old"}]. Listing a key
+// again with another secret keeps both secrets active.
+func ParseHMACKeys(keysJSON string) (HMACKeys, error) {
 	if keysJSON == "" {
 		return HMACKeys{}, nil // No keys configured
 	}
@@ -40,7 +48,7 @@ func LoadHMACKeys() (HMACKeys, error) {
 
 	hmacKeys := make(HMACKeys)
 	for _, k := range keys {
-		hmacKeys[k.Key] = k.Secret
+		hmacKeys[k.Key] = append(hmacKeys[k.Key], k.Secret)
 	}
 
 	return hmacKeys, nil
@@ -67,21 +75,21 @@ func ValidateHMAC(apiKey, timestamp, signature, body string, keys HMACKeys) erro
 		return ErrInvalidTimestamp
 	}
 
-	// Look up the secret for this key
-	secret, ok := keys[apiKey]
+	// Look up the secrets for this key
+	secrets, ok := keys[apiKey]
 	if !ok {
 		return ErrKeyNotFound
 	}
 
-	// Compute HMAC signature
-	computed := ComputeHMAC(secret, timestamp, body)
-
-	// Compare signatures securely
-	if !hmac.Equal([]byte(signature), []byte(computed)) {
-		return ErrInvalidSignature
+	// Compare signatures securely against each active secret
+	for _, secret := range secrets {
+		computed := ComputeHMAC(secret, timestamp, body)
+		if hmac.Equal([]byte(signature), []byte(computed)) {
+			return nil
+		}
 	}
 
-	return nil
+	return ErrInvalidSignature
 }
 
 // ComputeHMAC computes the HMAC-SHA256 signature
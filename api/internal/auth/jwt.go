@@ -2,6 +2,7 @@ package auth
 
 import (
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -29,7 +30,7 @@ type TokenPair struct {
 }
 
 // GenerateToken creates a JWT access token for a staff user
-func GenerateToken(userID, tenantID, email, role, secret string) (string, error) {
+func GenerateToken(userID, tenantID, email, role string, keys Keys) (string, error) {
 	claims := Claims{
 		UserID:   userID,
 		TenantID: tenantID,
@@ -42,12 +43,11 @@ func GenerateToken(userID, tenantID, email, role, secret string) (string, error)
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return sign(claims, keys.SigningKey())
 }
 
 // CreateRefreshToken creates a long-lived refresh token
-func CreateRefreshToken(userID, tenantID, email, role, secret string) (string, error) {
+func CreateRefreshToken(userID, tenantID, email, role string, keys Keys) (string, error) {
 	claims := Claims{
 		UserID:   userID,
 		TenantID: tenantID,
@@ -60,18 +60,12 @@ func CreateRefreshToken(userID, tenantID, email, role, secret string) (string, e
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return sign(claims, keys.SigningKey())
 }
 
 // ValidateToken verifies and parses a JWT token
-func ValidateToken(tokenString, secret string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
-		}
-		return []byte(secret), nil
-	})
+func ValidateToken(tokenString string, keys Keys) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keyFunc(keys))
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -80,7 +74,9 @@ func ValidateToken(tokenString, secret string) (*Claims, error) {
 		return nil, ErrInvalidToken
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	// Customer tokens are signed with the same keys but never grant staff
+	// access
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && !slices.Contains(claims.Audience, CustomerAudience) {
 		return claims, nil
 	}
 
@@ -88,20 +84,20 @@ func ValidateToken(tokenString, secret string) (*Claims, error) {
 }
 
 // RefreshAccessToken exchanges a refresh token for a new access token
-func RefreshAccessToken(refreshToken, secret string) (*TokenPair, error) {
-	claims, err := ValidateToken(refreshToken, secret)
+func RefreshAccessToken(refreshToken string, keys Keys) (*TokenPair, error) {
+	claims, err := ValidateToken(refreshToken, keys)
 	if err != nil {
 		return nil, err
 	}
 
 	// Generate new access token
-	accessToken, err := GenerateToken(claims.UserID, claims.TenantID, claims.Email, claims.Role, secret)
+	accessToken, err := GenerateToken(claims.UserID, claims.TenantID, claims.Email, claims.Role, keys)
 	if err != nil {
 		return nil, err
 	}
 
 	// Generate new refresh token
-	newRefreshToken, err := CreateRefreshToken(claims.UserID, claims.TenantID, claims.Email, claims.Role, secret)
+	newRefreshToken, err := CreateRefreshToken(claims.UserID, claims.TenantID, claims.Email, claims.Role, keys)
 	if err != nil {
 		return nil, err
 	}
@@ -112,3 +108,108 @@ func RefreshAccessToken(refreshToken, secret string) (*TokenPair, error) {
 		ExpiresIn:    900, // 15 minutes in seconds
 	}, nil
 }
+
+// CustomerAudience is the audience of tokens issued to customers, which are
+// only accepted by the customer portal API
+const CustomerAudience = "customer"
+
+// CustomerTokenTTL is how long a customer token is valid. Customers sign in
+// again with a new one-time code rather than refreshing.
+const CustomerTokenTTL = 30 * time.Minute
+
+// CustomerClaims represents the JWT claims for customers signed in to the
+// customer portal
+type CustomerClaims struct {
+	CustomerID string `json:"customer_id"`
+	TenantID   string `json:"tenant_id"`
+	// ImpersonationID is set on tokens issued to staff impersonating the
+	// customer, which only grant read access
+	ImpersonationID string `json:"impersonation_id,omitempty"`
+	// ImpersonatedBy is the staff user impersonating the customer
+	ImpersonatedBy string `json:"impersonated_by,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Impersonating reports whether the token was issued to staff impersonating
+// the customer
+func (c *CustomerClaims) Impersonating() bool {
+	return c.ImpersonationID != ""
+}
+
+// GenerateCustomerToken creates a JWT access token for a customer
+func GenerateCustomerToken(customerID, tenantID string, keys Keys) (string, error) {
+	claims := CustomerClaims{
+		CustomerID: customerID,
+		TenantID:   tenantID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{CustomerAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(CustomerTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return sign(claims, keys.SigningKey())
+}
+
+// GenerateImpersonationToken creates a customer token for a staff user
+// impersonating the customer, valid until expiresAt
+func GenerateImpersonationToken(customerID, tenantID, impersonationID, staffUserID string, expiresAt time.Time, keys Keys) (string, error) {
+	claims := CustomerClaims{
+		CustomerID:      customerID,
+		TenantID:        tenantID,
+		ImpersonationID: impersonationID,
+		ImpersonatedBy:  staffUserID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{CustomerAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return sign(claims, keys.SigningKey())
+}
+
+// ValidateCustomerToken verifies and parses a customer JWT token. Staff
+// tokens are rejected.
+func ValidateCustomerToken(tokenString string, keys Keys) (*CustomerClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &CustomerClaims{}, keyFunc(keys), jwt.WithAudience(CustomerAudience))
+
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	if claims, ok := token.Claims.(*CustomerClaims); ok && token.Valid && claims.CustomerID != "" {
+		return claims, nil
+	}
+
+	return nil, ErrInvalidToken
+}
+
+// sign signs claims with key, naming it in the token's kid header
+func sign(claims jwt.Claims, key JWTKey) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString([]byte(key.Secret))
+}
+
+// keyFunc verifies tokens with the active key named in their kid header
+func keyFunc(keys Keys) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidToken
+		}
+		kid, _ := token.Header["kid"].(string)
+		secret, ok := keys.VerificationSecret(kid)
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		return []byte(secret), nil
+	}
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testKeys = StaticKey("test-secret")
+
+func TestCustomerToken_RoundTrip(t *testing.T) {
+	token, err := GenerateCustomerToken("customer-1", "tenant-1", testKeys)
+	require.NoError(t, err)
+
+	claims, err := ValidateCustomerToken(token, testKeys)
+	require.NoError(t, err)
+	assert.Equal(t, "customer-1", claims.CustomerID)
+	assert.Equal(t, "tenant-1", claims.TenantID)
+}
+
+func TestCustomerToken_NotAcceptedAsStaffToken(t *testing.T) {
+	token, err := GenerateCustomerToken("customer-1", "tenant-1", testKeys)
+	require.NoError(t, err)
+
+	_, err = ValidateToken(token, testKeys)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestStaffToken_NotAcceptedAsCustomerToken(t *testing.T) {
+	token, err := GenerateToken("user-1", "tenant-1", "owner@example.com", "owner", testKeys)
+	require.NoError(t, err)
+
+	_, err = ValidateCustomerToken(token, testKeys)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestImpersonationToken_RoundTrip(t *testing.T) {
+	token, err := GenerateImpersonationToken("customer-1", "tenant-1", "imp-1", "user-1", time.Now().Add(10*time.Minute), testKeys)
+	require.NoError(t, err)
+
+	claims, err := ValidateCustomerToken(token, testKeys)
+	require.NoError(t, err)
+	assert.Equal(t, "customer-1", claims.CustomerID)
+	assert.True(t, claims.Impersonating())
+	assert.Equal(t, "imp-1", claims.ImpersonationID)
+	assert.Equal(t, "user-1", claims.ImpersonatedBy)
+
+	_, err = ValidateToken(token, testKeys)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestImpersonationToken_Expired(t *testing.T) {
+	token, err := GenerateImpersonationToken("customer-1", "tenant-1", "imp-1", "user-1", time.Now().Add(-time.Minute), testKeys)
+	require.NoError(t, err)
+
+	_, err = ValidateCustomerToken(token, testKeys)
+	assert.ErrorIs(t, err, ErrExpiredToken)
+}
+
+func TestCustomerToken_NotImpersonating(t *testing.T) {
+	token, err := GenerateCustomerToken("customer-1", "tenant-1", testKeys)
+	require.NoError(t, err)
+
+	claims, err := ValidateCustomerToken(token, testKeys)
+	require.NoError(t, err)
+	assert.False(t, claims.Impersonating())
+}
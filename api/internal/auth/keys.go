@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrNoJWTKeys     = errors.New("at least one JWT key is required")
+	ErrInvalidJWTKey = errors.New("JWT keys need a secret and a kid unique among the keys")
+	ErrUnknownKey    = errors.New("token signed with an unknown key")
+)
+
+// JWTKey is a version of the secret tokens are signed with. Tokens name the
+// key that signed them in their kid header; the key with an empty ID
+// verifies tokens without one, such as those signed before keys were
+// versioned.
+type JWTKey struct {
+	ID     string `json:"kid"`
+	Secret string `json:"secret"`
+}
+
+// Keys looks up the secrets tokens are signed and verified with
+type Keys interface {
+	// SigningKey is the key new tokens are signed with
+	SigningKey() JWTKey
+	// VerificationSecret returns the secret of the active key with the
+	// given kid
+	VerificationSecret(kid string) (string, bool)
+}
+
+// StaticKey is a single unversioned secret
+type StaticKey string
+
+// SigningKey implements Keys
+func (k StaticKey) SigningKey() JWTKey {
+	return JWTKey{Secret: string(k)}
+}
+
+// VerificationSecret implements Keys
+func (k StaticKey) VerificationSecret(kid string) (string, bool) {
+	return string(k), kid == ""
+}
+
+// ParseJWTKeys parses the JWT key versions of JWT_KEYS_JSON, e.g.
+// [{"kid":"2026-10","secret":"..."},{"kid":"2026-04","secret":"..."}]. The
+// first key signs new tokens and the others only verify tokens they signed
+// until they are removed. Without keysJSON, secret is the only key.
+func ParseJWTKeys(keysJSON, secret string) ([]JWTKey, error) {
+	if keysJSON == "" {
+		if secret == "" {
+			return nil, ErrNoJWTKeys
+		}
+		return []JWTKey{{Secret: secret}}, nil
+	}
+
+	var keys []JWTKey
+	if err := json.Unmarshal([]byte(keysJSON), &keys); err != nil {
+		return nil, fmt.Errorf("invalid JWT_KEYS_JSON: %w", err)
+	}
+	if err := validateJWTKeys(keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func validateJWTKeys(keys []JWTKey) error {
+	if len(keys) == 0 {
+		return ErrNoJWTKeys
+	}
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key.Secret == "" || seen[key.ID] {
+			return fmt.Errorf("%w: %q", ErrInvalidJWTKey, key.ID)
+		}
+		seen[key.ID] = true
+	}
+	return nil
+}
+
+// Keyring holds the active JWT and HMAC key versions. Secrets are rotated
+// without a restart by setting new versions, which take effect for the next
+// request; tokens and signatures made with a version still on the keyring
+// stay valid.
+type Keyring struct {
+	mu       sync.RWMutex
+	jwtKeys  []JWTKey
+	hmacKeys HMACKeys
+}
+
+// NewKeyring creates a keyring holding the given key versions
+func NewKeyring(jwtKeys []JWTKey, hmacKeys HMACKeys) (*Keyring, error) {
+	k := &Keyring{}
+	if err := k.Set(jwtKeys, hmacKeys); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Set replaces the keyring's key versions. The keyring is left unchanged
+// if the JWT keys are invalid.
+func (k *Keyring) Set(jwtKeys []JWTKey, hmacKeys HMACKeys) error {
+	if err := validateJWTKeys(jwtKeys); err != nil {
+		return err
+	}
+	if hmacKeys == nil {
+		hmacKeys = HMACKeys{}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.jwtKeys = jwtKeys
+	k.hmacKeys = hmacKeys
+	return nil
+}
+
+// SigningKey implements Keys
+func (k *Keyring) SigningKey() JWTKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.jwtKeys[0]
+}
+
+// VerificationSecret implements Keys
+func (k *Keyring) VerificationSecret(kid string) (string, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, key := range k.jwtKeys {
+		if key.ID == kid {
+			return key.Secret, true
+		}
+	}
+	return "", false
+}
+
+// JWTKeyIDs returns the kids of the active JWT keys, the signing key first
+func (k *Keyring) JWTKeyIDs() []string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	ids := make([]string, len(k.jwtKeys))
+	for i, key := range k.jwtKeys {
+		ids[i] = key.ID
+	}
+	return ids
+}
+
+// HMACKeys returns the active HMAC API keys
+func (k *Keyring) HMACKeys() HMACKeys {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.hmacKeys
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJWTKeys(t *testing.T) {
+	keys, err := ParseJWTKeys("", "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, []JWTKey{{Secret: "legacy"}}, keys)
+
+	keys, err = ParseJWTKeys(`[{"kid":"v2","secret":"new"},{"kid":"","secret":"legacy"}]`, "ignored")
+	require.NoError(t, err)
+	assert.Equal(t, []JWTKey{{ID: "v2", Secret: "new"}, {Secret: "legacy"}}, keys)
+
+	_, err = ParseJWTKeys("", "")
+	assert.ErrorIs(t, err, ErrNoJWTKeys)
+	_, err = ParseJWTKeys(`[{"kid":"v1","secret":"a"},{"kid":"v1","secret":"b"}]`, "")
+	assert.ErrorIs(t, err, ErrInvalidJWTKey)
+	_, err = ParseJWTKeys(`[{"kid":"v1"}]`, "")
+	assert.ErrorIs(t, err, ErrInvalidJWTKey)
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	keyring, err := NewKeyring([]JWTKey{{Secret: "legacy"}}, nil)
+	require.NoError(t, err)
+
+	legacyToken, err := GenerateToken("user-1", "tenant-1", "owner@example.com", "owner", keyring)
+	require.NoError(t, err)
+
+	// A new key signs, the legacy key still verifies the tokens it signed
+	require.NoError(t, keyring.Set([]JWTKey{{ID: "v2", Secret: "new"}, {Secret: "legacy"}}, nil))
+	_, err = ValidateToken(legacyToken, keyring)
+	require.NoError(t, err)
+
+	token, err := GenerateCustomerToken("customer-1", "tenant-1", keyring)
+	require.NoError(t, err)
+	claims, err := ValidateCustomerToken(token, keyring)
+	require.NoError(t, err)
+	assert.Equal(t, "customer-1", claims.CustomerID)
+	_, err = ValidateCustomerToken(token, StaticKey("new"))
+	assert.ErrorIs(t, err, ErrInvalidToken, "token must name its key")
+
+	// Retiring the legacy key ends its sessions
+	require.NoError(t, keyring.Set([]JWTKey{{ID: "v2", Secret: "new"}}, nil))
+	_, err = ValidateToken(legacyToken, keyring)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = ValidateCustomerToken(token, keyring)
+	require.NoError(t, err)
+
+	// Invalid keys leave the keyring as it was
+	assert.Error(t, keyring.Set(nil, nil))
+	assert.Equal(t, []string{"v2"}, keyring.JWTKeyIDs())
+}
+
+func TestValidateHMAC_RotatedSecret(t *testing.T) {
+	keys, err := ParseHMACKeys(`[{"key":"pos","secret":"new"},{"key":"pos","secret":"old"}]`)
+	require.NoError(t, err)
+	assert.Equal(t, HMACKeys{"pos": {"new", "old"}}, keys)
+
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	body := `{"event_type":"purchase"}`
+	for _, secret := range []string{"new", "old"} {
+		assert.NoError(t, ValidateHMAC("pos", timestamp, ComputeHMAC(secret, timestamp, body), body, keys), secret)
+	}
+	assert.ErrorIs(t, ValidateHMAC("pos", timestamp, ComputeHMAC("retired", timestamp, body), body, keys), ErrInvalidSignature)
+}
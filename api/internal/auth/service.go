@@ -15,15 +15,15 @@ var (
 
 // Service handles authentication business logic
 type Service struct {
-	queries   *db.Queries
-	jwtSecret string
+	queries *db.Queries
+	keys    Keys
 }
 
 // NewService creates a new auth service
-func NewService(queries *db.Queries, jwtSecret string) *Service {
+func NewService(queries *db.Queries, keys Keys) *Service {
 	return &Service{
-		queries:   queries,
-		jwtSecret: jwtSecret,
+		queries: queries,
+		keys:    keys,
 	}
 }
 
@@ -63,7 +63,7 @@ func (s *Service) Login(ctx context.Context, email, password string) (*LoginResu
 		user.TenantID.String(),
 		user.Email,
 		user.Role,
-		s.jwtSecret,
+		s.keys,
 	)
 	if err != nil {
 		return nil, err
@@ -74,7 +74,7 @@ func (s *Service) Login(ctx context.Context, email, password string) (*LoginResu
 		user.TenantID.String(),
 		user.Email,
 		user.Role,
-		s.jwtSecret,
+		s.keys,
 	)
 	if err != nil {
 		return nil, err
@@ -96,7 +96,7 @@ func (s *Service) Login(ctx context.Context, email, password string) (*LoginResu
 
 // RefreshToken exchanges a refresh token for new tokens
 func (s *Service) RefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error) {
-	return RefreshAccessToken(refreshToken, s.jwtSecret)
+	return RefreshAccessToken(refreshToken, s.keys)
 }
 
 // GetUserInfo retrieves user information from token claims
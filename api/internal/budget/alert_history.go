@@ -0,0 +1,157 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+)
+
+var (
+	// ErrAlertNotFound is returned when a budget alert doesn't exist
+	ErrAlertNotFound = errors.New("budget alert not found")
+
+	// ErrAlertAcknowledged is returned when acknowledging an alert that was
+	// already acknowledged
+	ErrAlertAcknowledged = errors.New("budget alert already acknowledged")
+)
+
+// AlertRecord is an alert in a budget's alert history. Alerts stay
+// unacknowledged until a staff user confirms the incident was handled;
+// info notices, such as an alert resolving, are recorded acknowledged.
+type AlertRecord struct {
+	ID                  string     `json:"id"`
+	BudgetID            string     `json:"budget_id"`
+	Type                AlertType  `json:"type"`
+	Level               AlertLevel `json:"level"`
+	Message             string     `json:"message"`
+	Currency            string     `json:"currency"`
+	Balance             string     `json:"balance"`
+	Utilization         string     `json:"utilization"`
+	CreatedAt           time.Time  `json:"created_at"`
+	Acknowledged        bool       `json:"acknowledged"`
+	AcknowledgedAt      *time.Time `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy      string     `json:"acknowledged_by,omitempty"`
+	AcknowledgementNote string     `json:"acknowledgement_note,omitempty"`
+}
+
+// ListAlertsParams filters a tenant's alert history
+type ListAlertsParams struct {
+	TenantID pgtype.UUID
+	// BudgetID optionally limits alerts to one budget
+	BudgetID pgtype.UUID
+	// Level optionally limits alerts to one level
+	Level string
+	// Acknowledged optionally limits alerts to acknowledged or
+	// unacknowledged ones
+	Acknowledged pgtype.Bool
+	Limit        int32
+	Offset       int32
+}
+
+// IsValidAlertLevel reports whether level is an alert level
+func IsValidAlertLevel(level string) bool {
+	switch AlertLevel(level) {
+	case AlertLevelInfo, AlertLevelWarning, AlertLevelCritical:
+		return true
+	}
+	return false
+}
+
+// ListAlerts returns a page of a tenant's alerts, newest first, and the
+// total number of alerts matching the filters
+func (s *Service) ListAlerts(ctx context.Context, params ListAlertsParams) ([]AlertRecord, int64, error) {
+	level := pgtype.Text{String: params.Level, Valid: params.Level != ""}
+	rows, err := s.queries.ListBudgetAlerts(ctx, db.ListBudgetAlertsParams{
+		TenantID:     params.TenantID,
+		BudgetID:     params.BudgetID,
+		Level:        level,
+		Acknowledged: params.Acknowledged,
+		RowLimit:     params.Limit,
+		RowOffset:    params.Offset,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list alerts: %w", err)
+	}
+
+	total, err := s.queries.CountBudgetAlerts(ctx, db.CountBudgetAlertsParams{
+		TenantID:     params.TenantID,
+		BudgetID:     params.BudgetID,
+		Level:        level,
+		Acknowledged: params.Acknowledged,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count alerts: %w", err)
+	}
+
+	alerts := make([]AlertRecord, 0, len(rows))
+	for _, row := range rows {
+		alerts = append(alerts, NewAlertRecord(row))
+	}
+	return alerts, total, nil
+}
+
+// AcknowledgeAlert records that a staff user handled an alert, with an
+// optional note on what was done about it
+func (s *Service) AcknowledgeAlert(ctx context.Context, tenantID, alertID, acknowledgedBy pgtype.UUID, note string) (*AlertRecord, error) {
+	row, err := s.queries.AcknowledgeBudgetAlert(ctx, db.AcknowledgeBudgetAlertParams{
+		AcknowledgedBy: acknowledgedBy,
+		Note:           pgtype.Text{String: note, Valid: note != ""},
+		TenantID:       tenantID,
+		ID:             alertID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Either the alert doesn't exist or it was acknowledged already
+		if _, err := s.queries.GetBudgetAlert(ctx, db.GetBudgetAlertParams{
+			TenantID: tenantID,
+			ID:       alertID,
+		}); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrAlertNotFound
+			}
+			return nil, fmt.Errorf("failed to get alert: %w", err)
+		}
+		return nil, ErrAlertAcknowledged
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to acknowledge alert: %w", err)
+	}
+
+	s.log(ctx).Info("budget alert acknowledged",
+		"alert_id", alertID,
+		"budget_id", row.BudgetID,
+		"acknowledged_by", acknowledgedBy)
+
+	record := NewAlertRecord(row)
+	return &record, nil
+}
+
+// NewAlertRecord converts a stored alert for API responses
+func NewAlertRecord(a db.BudgetAlert) AlertRecord {
+	record := AlertRecord{
+		ID:                  httputil.FormatUUID(a.ID.Bytes),
+		BudgetID:            httputil.FormatUUID(a.BudgetID.Bytes),
+		Type:                AlertType(a.AlertType),
+		Level:               AlertLevel(a.Level),
+		Message:             a.Message,
+		Currency:            a.Currency,
+		Balance:             httputil.FormatNumeric(a.Balance),
+		Utilization:         httputil.FormatNumeric(a.Utilization),
+		CreatedAt:           a.CreatedAt.Time,
+		Acknowledged:        a.AcknowledgedAt.Valid,
+		AcknowledgementNote: a.AcknowledgementNote.String,
+	}
+	if a.AcknowledgedAt.Valid {
+		record.AcknowledgedAt = &a.AcknowledgedAt.Time
+	}
+	if a.AcknowledgedBy.Valid {
+		record.AcknowledgedBy = httputil.FormatUUID(a.AcknowledgedBy.Bytes)
+	}
+	return record
+}
@@ -0,0 +1,311 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+)
+
+// DefaultAlertRepeatInterval is how long an alert that stays active waits
+// before it is sent again, for budgets without their own interval
+const DefaultAlertRepeatInterval = time.Hour
+
+// resolvedAlertTypes maps the threshold alerts whose state is tracked to the
+// alert sent when they resolve
+var resolvedAlertTypes = map[AlertType]AlertType{
+	AlertTypeSoftCap:        AlertTypeSoftCapResolved,
+	AlertTypeHardCap:        AlertTypeHardCapResolved,
+	AlertTypeHardCapReached: AlertTypeHardCapReachedResolved,
+	AlertTypeOverdraft:      AlertTypeOverdraftResolved,
+}
+
+// RepeatIntervalForBudget returns how long an active alert on the budget
+// waits before it is sent again
+func RepeatIntervalForBudget(budget db.Budget) time.Duration {
+	if budget.AlertRepeatMinutes.Valid && budget.AlertRepeatMinutes.Int32 > 0 {
+		return time.Duration(budget.AlertRepeatMinutes.Int32) * time.Minute
+	}
+	return DefaultAlertRepeatInterval
+}
+
+// thresholdCrossed reports whether the budget is still past the threshold of
+// a tracked alert. A hard cap reached alert stays active while the budget is
+// at its hard cap threshold, an overdraft alert while the balance is past the
+// hard cap.
+func thresholdCrossed(budget db.Budget, alertType AlertType) bool {
+	thresholds := ThresholdsForBudget(budget)
+	balance := numericToFloat(budget.Balance)
+	hardCap := numericToFloat(budget.HardCap)
+
+	utilization := 0.0
+	if hardCap > 0 {
+		utilization = (balance / hardCap) * 100
+	}
+
+	switch alertType {
+	case AlertTypeSoftCap:
+		_, crossed := softCapBreach(budget, balance, numericToFloat(budget.SoftCap), utilization)
+		return crossed
+	case AlertTypeHardCap, AlertTypeHardCapReached:
+		return hardCap > 0 && utilization >= thresholds.HardCapPercent
+	case AlertTypeOverdraft:
+		return balance > hardCap
+	}
+	return false
+}
+
+// inTenant runs fn in a transaction scoped to the tenant. Alerts are raised
+// and resolved in the background, outside the tenant request, and the
+// budget and alert tables force row level security.
+func (s *Service) inTenant(ctx context.Context, tenantID pgtype.UUID, fn func(q *db.Queries) error) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenantID.Bytes)); err != nil {
+		return fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	if err := fn(s.queries.WithTx(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// alertBudget gets the budget an alert check is for
+func (s *Service) alertBudget(ctx context.Context, tenantID, budgetID pgtype.UUID) (db.Budget, error) {
+	var budget db.Budget
+	err := s.inTenant(ctx, tenantID, func(q *db.Queries) error {
+		var err error
+		budget, err = q.GetBudgetByID(ctx, db.GetBudgetByIDParams{
+			ID:       budgetID,
+			TenantID: tenantID,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrBudgetNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get budget: %w", err)
+		}
+		return nil
+	})
+	return budget, err
+}
+
+// raiseAlert delivers a tracked alert unless it is already active
+// and was sent within the budget's repeat interval. Busy budgets cross their
+// thresholds on every reservation, so without this an alert would be sent
+// for each of them.
+func (s *Service) raiseAlert(ctx context.Context, budget db.Budget, alert Alert) error {
+	var claimed int64
+	err := s.inTenant(ctx, budget.TenantID, func(q *db.Queries) error {
+		var err error
+		claimed, err = q.ClaimBudgetAlert(ctx, db.ClaimBudgetAlertParams{
+			BudgetID:      budget.ID,
+			AlertType:     string(alert.Type),
+			TenantID:      budget.TenantID,
+			Now:           pgtype.Timestamptz{Time: time.Now(), Valid: true},
+			RepeatMinutes: int32(RepeatIntervalForBudget(budget) / time.Minute),
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to claim budget alert: %w", err)
+	}
+	if claimed == 0 {
+		s.logger.Debug("budget alert already sent within repeat interval",
+			"type", alert.Type,
+			"budget_id", alert.BudgetID)
+		return nil
+	}
+	return s.deliverAlert(ctx, alert)
+}
+
+// resolveAlert resolves the budget's active alert of the type and sends
+// an alert resolved notification. It reports whether an alert was active.
+func (s *Service) resolveAlert(ctx context.Context, budget db.Budget, alertType AlertType, now time.Time) (bool, error) {
+	var resolved bool
+	err := s.inTenant(ctx, budget.TenantID, func(q *db.Queries) error {
+		var err error
+		resolved, err = markResolved(ctx, q, budget, alertType, now)
+		return err
+	})
+	if err != nil || !resolved {
+		return false, err
+	}
+	return true, s.deliverAlert(ctx, resolvedAlert(budget, alertType))
+}
+
+// markResolved marks the budget's alert of the type resolved and
+// reports whether it was active
+func markResolved(ctx context.Context, q *db.Queries, budget db.Budget, alertType AlertType, now time.Time) (bool, error) {
+	if _, ok := resolvedAlertTypes[alertType]; !ok {
+		return false, nil
+	}
+
+	resolved, err := q.ResolveBudgetAlert(ctx, db.ResolveBudgetAlertParams{
+		ResolvedAt: pgtype.Timestamptz{Time: now, Valid: true},
+		TenantID:   budget.TenantID,
+		BudgetID:   budget.ID,
+		AlertType:  string(alertType),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve budget alert: %w", err)
+	}
+	return resolved > 0, nil
+}
+
+// resolvedAlert is the notification sent when the budget's alert of the type
+// resolves
+func resolvedAlert(budget db.Budget, alertType AlertType) Alert {
+	balance := numericToFloat(budget.Balance)
+	softCap := numericToFloat(budget.SoftCap)
+	hardCap := numericToFloat(budget.HardCap)
+	utilization := 0.0
+	if hardCap > 0 {
+		utilization = (balance / hardCap) * 100
+	}
+
+	thresholds := ThresholdsForBudget(budget)
+	below := fmt.Sprintf("its soft cap of %.2f %s", softCap, budget.Currency)
+	switch {
+	case alertType == AlertTypeHardCap, alertType == AlertTypeHardCapReached:
+		below = fmt.Sprintf("its hard cap alert threshold (%.0f%%)", thresholds.HardCapPercent)
+	case alertType == AlertTypeOverdraft:
+		below = fmt.Sprintf("its hard cap of %.2f %s", hardCap, budget.Currency)
+	case budget.AlertSoftPercent.Valid:
+		below = fmt.Sprintf("its soft cap and soft alert threshold (%.0f%%)", thresholds.SoftCapPercent)
+	}
+
+	return Alert{
+		Type:        resolvedAlertTypes[alertType],
+		Level:       AlertLevelInfo,
+		BudgetID:    budget.ID,
+		BudgetName:  budget.Name,
+		TenantID:    budget.TenantID,
+		Balance:     balance,
+		SoftCap:     softCap,
+		HardCap:     hardCap,
+		Currency:    budget.Currency,
+		Utilization: utilization,
+		Routes:      RoutesForBudget(budget),
+		Message: fmt.Sprintf(
+			"Resolved: Budget '%s' is back below %s. Balance: %.2f %s (%.1f%% utilized)",
+			budget.Name, below, balance, budget.Currency, utilization,
+		),
+	}
+}
+
+// ResolveAlerts resolves active alerts across all tenants
+// whose budgets have dropped back below the threshold, e.g. after releases,
+// top-ups or a period reset, and returns how many were resolved. A failing
+// tenant is logged and does not stop the others.
+func (s *Service) ResolveAlerts(ctx context.Context, now time.Time) (int, error) {
+	tenants, err := s.queries.ListTenants(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	resolved := 0
+	for _, tenant := range tenants {
+		n, err := s.resolveTenantAlerts(ctx, tenant.ID, now)
+		if err != nil {
+			s.logger.Error("failed to resolve budget alerts",
+				"tenant_id", tenant.ID,
+				"error", err)
+			continue
+		}
+		resolved += n
+	}
+	return resolved, nil
+}
+
+// resolveTenantAlerts resolves a tenant's active alerts whose budgets are
+// back below the threshold. The resolved notifications are sent once the
+// alerts are marked resolved.
+func (s *Service) resolveTenantAlerts(ctx context.Context, tenantID pgtype.UUID, now time.Time) (int, error) {
+	var alerts []Alert
+	err := s.inTenant(ctx, tenantID, func(q *db.Queries) error {
+		states, err := q.ListActiveBudgetAlerts(ctx, tenantID)
+		if err != nil {
+			return fmt.Errorf("failed to list active budget alerts: %w", err)
+		}
+
+		budgets := make(map[pgtype.UUID]db.Budget)
+		for _, state := range states {
+			budget, ok := budgets[state.BudgetID]
+			if !ok {
+				budget, err = q.GetBudgetByID(ctx, db.GetBudgetByIDParams{
+					ID:       state.BudgetID,
+					TenantID: tenantID,
+				})
+				if errors.Is(err, pgx.ErrNoRows) {
+					continue
+				}
+				if err != nil {
+					return fmt.Errorf("failed to get budget: %w", err)
+				}
+				budgets[state.BudgetID] = budget
+			}
+
+			alertType := AlertType(state.AlertType)
+			if thresholdCrossed(budget, alertType) {
+				continue
+			}
+			ok, err := markResolved(ctx, q, budget, alertType, now)
+			if err != nil {
+				return err
+			}
+			if ok {
+				alerts = append(alerts, resolvedAlert(budget, alertType))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, alert := range alerts {
+		if err := s.deliverAlert(ctx, alert); err != nil {
+			s.logger.Error("failed to send budget alert resolved",
+				"type", alert.Type,
+				"budget_id", alert.BudgetID,
+				"error", err)
+		}
+	}
+	return len(alerts), nil
+}
+
+// RunAlertResolveWorker resolves alerts on a schedule until ctx is cancelled
+func (s *Service) RunAlertResolveWorker(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.ResolveAlerts(ctx, time.Now()); err != nil {
+			s.logger.Error("budget alert resolve failed", "error", err)
+		} else if n > 0 {
+			s.logger.Info("resolved budget alerts", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -2,13 +2,18 @@ package budget
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/eventbus"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
 )
 
 // AlertType represents the type of budget alert
@@ -23,6 +28,26 @@ const (
 
 	// AlertTypeHardCapReached is triggered when a reservation is rejected due to hard cap
 	AlertTypeHardCapReached AlertType = "hard_cap_reached"
+
+	// AlertTypeOverdraft is triggered when a reservation takes a budget past
+	// its hard cap into its overdraft allowance
+	AlertTypeOverdraft AlertType = "overdraft_used"
+
+	// AlertTypeSoftCapResolved is sent when utilization drops back below the
+	// soft cap threshold after a soft cap alert
+	AlertTypeSoftCapResolved AlertType = "soft_cap_resolved"
+
+	// AlertTypeHardCapResolved is sent when utilization drops back below the
+	// hard cap threshold after a hard cap alert
+	AlertTypeHardCapResolved AlertType = "hard_cap_resolved"
+
+	// AlertTypeHardCapReachedResolved is sent when utilization drops back
+	// below the hard cap threshold after a hard cap reached alert
+	AlertTypeHardCapReachedResolved AlertType = "hard_cap_reached_resolved"
+
+	// AlertTypeOverdraftResolved is sent when the balance drops back to the
+	// hard cap after an overdraft alert
+	AlertTypeOverdraftResolved AlertType = "overdraft_resolved"
 )
 
 // AlertLevel represents the severity of an alert
@@ -34,6 +59,9 @@ const (
 
 	// AlertLevelCritical is for hard cap alerts
 	AlertLevelCritical AlertLevel = "critical"
+
+	// AlertLevelInfo is for resolved alerts
+	AlertLevelInfo AlertLevel = "info"
 )
 
 // Alert represents a budget alert
@@ -46,14 +74,17 @@ type Alert struct {
 	Balance         float64
 	SoftCap         float64
 	HardCap         float64
+	OverdraftLimit  float64
+	Currency        string
 	Utilization     float64 // Percentage (0-100)
 	Message         string
 	Timestamp       string
+	Routes          AlertRoutes
 }
 
 // AlertThresholds contains configurable thresholds for alerts
 type AlertThresholds struct {
-	SoftCapPercent float64 // Default: 80% - trigger when balance > soft_cap
+	SoftCapPercent float64 // Default: 80% - also trigger the soft cap alert at this utilization, when the budget sets it
 	HardCapPercent float64 // Default: 95% - trigger warning when balance > 95% of hard_cap
 }
 
@@ -65,22 +96,111 @@ func DefaultAlertThresholds() AlertThresholds {
 	}
 }
 
-// CheckSoftCapAlert checks if a budget has exceeded its soft cap and triggers an alert
+// ThresholdsForBudget returns the alert thresholds configured on a budget,
+// falling back to the defaults for any threshold that is not set
+func ThresholdsForBudget(budget db.Budget) AlertThresholds {
+	thresholds := DefaultAlertThresholds()
+
+	if budget.AlertSoftPercent.Valid {
+		if v, err := budget.AlertSoftPercent.Float64Value(); err == nil && v.Valid {
+			thresholds.SoftCapPercent = v.Float64
+		}
+	}
+	if budget.AlertHardPercent.Valid {
+		if v, err := budget.AlertHardPercent.Float64Value(); err == nil && v.Valid {
+			thresholds.HardCapPercent = v.Float64
+		}
+	}
+
+	return thresholds
+}
+
+// softCapBreach reports whether a soft cap alert fires for the budget and
+// describes the condition that fired: the balance past the soft cap or, for
+// budgets that set alert_soft_percent, utilization at that threshold.
+// Budgets without a soft percent alert on their soft cap alone, as they did
+// before per-budget thresholds.
+func softCapBreach(budget db.Budget, balance, softCap, utilization float64) (string, bool) {
+	if balance > softCap {
+		return "has exceeded soft cap", true
+	}
+	if budget.AlertSoftPercent.Valid {
+		threshold := ThresholdsForBudget(budget).SoftCapPercent
+		if utilization >= threshold {
+			return fmt.Sprintf("has reached its soft alert threshold of %.0f%% of hard cap", threshold), true
+		}
+	}
+	return "", false
+}
+
+// AlertChannel identifies a destination type for budget alerts
+type AlertChannel string
+
+const (
+	// AlertChannelEmail delivers alerts by email
+	AlertChannelEmail AlertChannel = "email"
+
+	// AlertChannelWebhook delivers alerts as a JSON POST to a URL
+	AlertChannelWebhook AlertChannel = "webhook"
+
+	// AlertChannelWhatsApp delivers alerts as a WhatsApp text to an admin number
+	AlertChannelWhatsApp AlertChannel = "whatsapp"
+)
+
+// AlertRoutes contains the per-budget destinations for alerts.
+// Empty values mean the channel is not configured for the budget.
+type AlertRoutes struct {
+	Email          string
+	WebhookURL     string
+	WhatsAppNumber string
+}
+
+// RoutesForBudget returns the alert routing configured on a budget
+func RoutesForBudget(budget db.Budget) AlertRoutes {
+	return AlertRoutes{
+		Email:          budget.AlertEmail.String,
+		WebhookURL:     budget.AlertWebhookUrl.String,
+		WhatsAppNumber: budget.AlertWhatsappNumber.String,
+	}
+}
+
+// destinations returns the configured destination for each channel
+func (r AlertRoutes) destinations() map[AlertChannel]string {
+	dest := make(map[AlertChannel]string)
+	if r.Email != "" {
+		dest[AlertChannelEmail] = r.Email
+	}
+	if r.WebhookURL != "" {
+		dest[AlertChannelWebhook] = r.WebhookURL
+	}
+	if r.WhatsAppNumber != "" {
+		dest[AlertChannelWhatsApp] = r.WhatsAppNumber
+	}
+	return dest
+}
+
+// AlertNotifier delivers an alert to a single destination on one channel
+type AlertNotifier interface {
+	Notify(ctx context.Context, destination string, alert Alert) error
+}
+
+// RegisterAlertNotifier registers the notifier used for a channel.
+// Alerts routed to a channel without a notifier are only logged.
+func (s *Service) RegisterAlertNotifier(channel AlertChannel, notifier AlertNotifier) {
+	s.notifiers[channel] = notifier
+}
+
+// CheckSoftCapAlert checks if a budget has exceeded its soft cap and triggers an alert.
+// An active alert is sent again only after the budget's repeat interval, and
+// resolved once the budget is back below the threshold.
 func (s *Service) CheckSoftCapAlert(ctx context.Context, tenantID, budgetID pgtype.UUID) error {
 	if !tenantID.Valid || !budgetID.Valid {
 		return errors.New("tenant_id and budget_id are required")
 	}
 
-	// Get budget
-	budget, err := s.queries.GetBudgetByID(ctx, db.GetBudgetByIDParams{
-		ID:       budgetID,
-		TenantID: tenantID,
-	})
+	budget, err := s.alertBudget(ctx, tenantID, budgetID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return ErrBudgetNotFound
-		}
-		return fmt.Errorf("failed to get budget: %w", err)
+		return err
 	}
 
 	// Convert numeric values
@@ -102,10 +222,12 @@ func (s *Service) CheckSoftCapAlert(ctx context.Context, tenantID, budgetID pgty
 	}
 	hardCap := hardCapVal.Float64
 
-	// Check if soft cap exceeded
-	if balance > softCap {
-		utilization := (balance / hardCap) * 100
+	utilization := 0.0
+	if hardCap > 0 {
+		utilization = (balance / hardCap) * 100
+	}
 
+	if condition, ok := softCapBreach(budget, balance, softCap, utilization); ok {
 		alert := Alert{
 			Type:        AlertTypeSoftCap,
 			Level:       AlertLevelWarning,
@@ -115,39 +237,36 @@ func (s *Service) CheckSoftCapAlert(ctx context.Context, tenantID, budgetID pgty
 			Balance:     balance,
 			SoftCap:     softCap,
 			HardCap:     hardCap,
+			Currency:    budget.Currency,
 			Utilization: utilization,
+			Routes:      RoutesForBudget(budget),
 			Message: fmt.Sprintf(
-				"Budget '%s' has exceeded soft cap. Balance: %.2f %s, Soft Cap: %.2f %s (%.1f%% utilized)",
-				budget.Name, balance, budget.Currency, softCap, budget.Currency, utilization,
+				"Budget '%s' %s. Balance: %.2f %s, Soft Cap: %.2f %s (%.1f%% utilized)",
+				budget.Name, condition, balance, budget.Currency, softCap, budget.Currency, utilization,
 			),
 		}
 
-		return s.deliverAlert(ctx, alert)
+		return s.raiseAlert(ctx, budget, alert)
 	}
 
-	return nil
+	_, err = s.resolveAlert(ctx, budget, AlertTypeSoftCap, time.Now())
+	return err
 }
 
-// CheckHardCapAlert checks if a budget is approaching its hard cap
+// CheckHardCapAlert checks if a budget is approaching its hard cap. Like soft
+// cap alerts, an active alert is repeated only after the repeat interval.
 func (s *Service) CheckHardCapAlert(ctx context.Context, tenantID, budgetID pgtype.UUID) error {
 	if !tenantID.Valid || !budgetID.Valid {
 		return errors.New("tenant_id and budget_id are required")
 	}
 
-	thresholds := DefaultAlertThresholds()
-
-	// Get budget
-	budget, err := s.queries.GetBudgetByID(ctx, db.GetBudgetByIDParams{
-		ID:       budgetID,
-		TenantID: tenantID,
-	})
+	budget, err := s.alertBudget(ctx, tenantID, budgetID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return ErrBudgetNotFound
-		}
-		return fmt.Errorf("failed to get budget: %w", err)
+		return err
 	}
 
+	thresholds := ThresholdsForBudget(budget)
+
 	// Convert numeric values
 	balanceVal, err := budget.Balance.Float64Value()
 	if err != nil {
@@ -161,6 +280,9 @@ func (s *Service) CheckHardCapAlert(ctx context.Context, tenantID, budgetID pgty
 	}
 	hardCap := hardCapVal.Float64
 
+	if hardCap <= 0 {
+		return nil
+	}
 	utilization := (balance / hardCap) * 100
 
 	// Check if approaching hard cap (>95% by default)
@@ -180,35 +302,33 @@ func (s *Service) CheckHardCapAlert(ctx context.Context, tenantID, budgetID pgty
 			Balance:     balance,
 			SoftCap:     softCap,
 			HardCap:     hardCap,
+			Currency:    budget.Currency,
 			Utilization: utilization,
+			Routes:      RoutesForBudget(budget),
 			Message: fmt.Sprintf(
 				"CRITICAL: Budget '%s' is approaching hard cap. Balance: %.2f %s, Hard Cap: %.2f %s (%.1f%% utilized)",
 				budget.Name, balance, budget.Currency, hardCap, budget.Currency, utilization,
 			),
 		}
 
-		return s.deliverAlert(ctx, alert)
+		return s.raiseAlert(ctx, budget, alert)
 	}
 
-	return nil
+	_, err = s.resolveAlert(ctx, budget, AlertTypeHardCap, time.Now())
+	return err
 }
 
-// TriggerHardCapReachedAlert is called when a reservation is rejected due to hard cap
+// TriggerHardCapReachedAlert is called when a reservation is rejected due to hard cap.
+// Like the threshold alerts it is repeated only after the repeat interval,
+// and resolved once the budget drops back below its hard cap threshold.
 func (s *Service) TriggerHardCapReachedAlert(ctx context.Context, tenantID, budgetID pgtype.UUID, attemptedAmount float64) error {
 	if !tenantID.Valid || !budgetID.Valid {
 		return errors.New("tenant_id and budget_id are required")
 	}
 
-	// Get budget
-	budget, err := s.queries.GetBudgetByID(ctx, db.GetBudgetByIDParams{
-		ID:       budgetID,
-		TenantID: tenantID,
-	})
+	budget, err := s.alertBudget(ctx, tenantID, budgetID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return ErrBudgetNotFound
-		}
-		return fmt.Errorf("failed to get budget: %w", err)
+		return err
 	}
 
 	// Convert numeric values
@@ -230,7 +350,10 @@ func (s *Service) TriggerHardCapReachedAlert(ctx context.Context, tenantID, budg
 	}
 	hardCap := hardCapVal.Float64
 
-	utilization := (balance / hardCap) * 100
+	utilization := 0.0
+	if hardCap > 0 {
+		utilization = (balance / hardCap) * 100
+	}
 
 	alert := Alert{
 		Type:        AlertTypeHardCapReached,
@@ -241,7 +364,9 @@ func (s *Service) TriggerHardCapReachedAlert(ctx context.Context, tenantID, budg
 		Balance:     balance,
 		SoftCap:     softCap,
 		HardCap:     hardCap,
+		Currency:    budget.Currency,
 		Utilization: utilization,
+		Routes:      RoutesForBudget(budget),
 		Message: fmt.Sprintf(
 			"CRITICAL: Budget '%s' hard cap reached. Reservation rejected. "+
 				"Attempted: %.2f %s, Balance: %.2f %s, Hard Cap: %.2f %s (%.1f%% utilized)",
@@ -250,16 +375,123 @@ func (s *Service) TriggerHardCapReachedAlert(ctx context.Context, tenantID, budg
 		),
 	}
 
-	return s.deliverAlert(ctx, alert)
+	return s.raiseAlert(ctx, budget, alert)
+}
+
+// CheckOverdraftAlert checks if a budget is drawing on its overdraft, i.e.
+// its balance is past its hard cap, and triggers an alert. The alert is
+// resolved once the balance is back within the hard cap.
+func (s *Service) CheckOverdraftAlert(ctx context.Context, tenantID, budgetID pgtype.UUID) error {
+	if !tenantID.Valid || !budgetID.Valid {
+		return errors.New("tenant_id and budget_id are required")
+	}
+
+	budget, err := s.alertBudget(ctx, tenantID, budgetID)
+	if err != nil {
+		return err
+	}
+
+	// Convert numeric values
+	balanceVal, err := budget.Balance.Float64Value()
+	if err != nil {
+		return fmt.Errorf("invalid balance value: %w", err)
+	}
+	balance := balanceVal.Float64
+
+	hardCapVal, err := budget.HardCap.Float64Value()
+	if err != nil {
+		return fmt.Errorf("invalid hard cap value: %w", err)
+	}
+	hardCap := hardCapVal.Float64
+
+	if balance <= hardCap {
+		_, err = s.resolveAlert(ctx, budget, AlertTypeOverdraft, time.Now())
+		return err
+	}
+
+	softCapVal, err := budget.SoftCap.Float64Value()
+	if err != nil {
+		return fmt.Errorf("invalid soft cap value: %w", err)
+	}
+	softCap := softCapVal.Float64
+
+	overdraftVal, err := budget.OverdraftLimit.Float64Value()
+	if err != nil {
+		return fmt.Errorf("invalid overdraft limit value: %w", err)
+	}
+	overdraftLimit := overdraftVal.Float64
+
+	var utilization float64
+	if hardCap > 0 {
+		utilization = (balance / hardCap) * 100
+	}
+
+	alert := Alert{
+		Type:           AlertTypeOverdraft,
+		Level:          AlertLevelCritical,
+		BudgetID:       budgetID,
+		BudgetName:     budget.Name,
+		TenantID:       tenantID,
+		Balance:        balance,
+		SoftCap:        softCap,
+		HardCap:        hardCap,
+		OverdraftLimit: overdraftLimit,
+		Currency:       budget.Currency,
+		Utilization:    utilization,
+		Routes:         RoutesForBudget(budget),
+		Message: fmt.Sprintf(
+			"CRITICAL: Budget '%s' is in overdraft. Balance: %.2f %s, Hard Cap: %.2f %s, Overdraft used: %.2f of %.2f %s",
+			budget.Name, balance, budget.Currency, hardCap, budget.Currency,
+			balance-hardCap, overdraftLimit, budget.Currency,
+		),
+	}
+
+	return s.raiseAlert(ctx, budget, alert)
 }
 
-// deliverAlert delivers an alert through configured channels
-// For Phase 2, this logs the alert. Phase 4 will add webhook delivery.
+// CheckReservationAlerts checks a budget that was reserved from for soft
+// cap, hard cap and overdraft alerts. Each check runs even if another fails.
+func (s *Service) CheckReservationAlerts(ctx context.Context, tenantID, budgetID pgtype.UUID) error {
+	var errs []error
+	if err := s.CheckSoftCapAlert(ctx, tenantID, budgetID); err != nil {
+		errs = append(errs, fmt.Errorf("soft cap alert: %w", err))
+	}
+	if err := s.CheckHardCapAlert(ctx, tenantID, budgetID); err != nil {
+		errs = append(errs, fmt.Errorf("hard cap alert: %w", err))
+	}
+	if err := s.CheckOverdraftAlert(ctx, tenantID, budgetID); err != nil {
+		errs = append(errs, fmt.Errorf("overdraft alert: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// AlertReservation checks in the background whether a budget reserved from
+// has crossed its alert thresholds or is drawing on its overdraft. Call it
+// once the reservation is committed, so the checks see the new balance; the
+// rules engine calls it for each budget its issuances reserved from.
+func (s *Service) AlertReservation(tenantID, budgetID pgtype.UUID) {
+	s.alerts.Add(1)
+	go func() {
+		defer s.alerts.Done()
+		if err := s.CheckReservationAlerts(context.Background(), tenantID, budgetID); err != nil {
+			s.logger.Error("failed to check budget alerts",
+				"error", err,
+				"budget_id", budgetID,
+				"tenant_id", tenantID)
+		}
+	}()
+}
+
+// deliverAlert logs an alert and delivers it to every channel configured
+// on the budget that has a registered notifier
 func (s *Service) deliverAlert(ctx context.Context, alert Alert) error {
 	// Log the alert
 	logFunc := s.logger.Warn
-	if alert.Level == AlertLevelCritical {
+	switch alert.Level {
+	case AlertLevelCritical:
 		logFunc = s.logger.Error
+	case AlertLevelInfo:
+		logFunc = s.logger.Info
 	}
 
 	logFunc("budget alert triggered",
@@ -274,11 +506,98 @@ func (s *Service) deliverAlert(ctx context.Context, alert Alert) error {
 		"utilization", alert.Utilization,
 		"message", alert.Message)
 
-	// TODO: Phase 4 - Send webhook notification to tenant
-	// This would call the webhook service to notify the tenant
-	// about budget alerts via their configured webhook endpoints
+	// Route to the budget's configured channels. A failing channel must
+	// not prevent delivery to the others, so errors are logged and joined.
+	var errs []error
+	if err := s.recordAlert(ctx, alert); err != nil {
+		s.logger.Error("failed to record budget alert",
+			"budget_id", alert.BudgetID,
+			"error", err)
+		errs = append(errs, err)
+	}
+	for channel, destination := range alert.Routes.destinations() {
+		notifier, ok := s.notifiers[channel]
+		if !ok {
+			s.logger.Warn("no notifier registered for alert channel",
+				"channel", channel,
+				"budget_id", alert.BudgetID)
+			continue
+		}
+
+		if err := notifier.Notify(ctx, destination, alert); err != nil {
+			s.logger.Error("failed to deliver budget alert",
+				"channel", channel,
+				"budget_id", alert.BudgetID,
+				"error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", channel, err))
+		}
+	}
 
-	return nil
+	return errors.Join(errs...)
+}
+
+// recordAlert adds an alert to the budget's alert history, where it waits to
+// be acknowledged unless it is an info notice, and writes a budget.alert domain event to the outbox. The
+// event is a no-op for tenants without the event bus enabled.
+func (s *Service) recordAlert(ctx context.Context, alert Alert) error {
+	var balance, utilization pgtype.Numeric
+	if err := balance.Scan(strconv.FormatFloat(alert.Balance, 'f', 2, 64)); err != nil {
+		return fmt.Errorf("invalid alert balance: %w", err)
+	}
+	if err := utilization.Scan(strconv.FormatFloat(alert.Utilization, 'f', 2, 64)); err != nil {
+		return fmt.Errorf("invalid alert utilization: %w", err)
+	}
+
+	// Info notices, such as an alert resolving, need no action
+	var acknowledgedAt pgtype.Timestamptz
+	if alert.Level == AlertLevelInfo {
+		acknowledgedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+
+	return s.inTenant(ctx, alert.TenantID, func(q *db.Queries) error {
+		record, err := q.CreateBudgetAlert(ctx, db.CreateBudgetAlertParams{
+			TenantID:    alert.TenantID,
+			BudgetID:    alert.BudgetID,
+			AlertType:   string(alert.Type),
+			Level:       string(alert.Level),
+			Message:     alert.Message,
+			Currency:    alert.Currency,
+			Balance:        balance,
+			Utilization:    utilization,
+			AcknowledgedAt: acknowledgedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record alert: %w", err)
+		}
+
+		payload, err := json.Marshal(map[string]any{
+			"alert_id":        httputil.FormatUUID(record.ID.Bytes),
+			"budget_id":       httputil.FormatUUID(alert.BudgetID.Bytes),
+			"budget_name":     alert.BudgetName,
+			"type":            alert.Type,
+			"level":           alert.Level,
+			"balance":         alert.Balance,
+			"soft_cap":        alert.SoftCap,
+			"hard_cap":        alert.HardCap,
+			"overdraft_limit": alert.OverdraftLimit,
+			"currency":        alert.Currency,
+			"utilization":     alert.Utilization,
+			"message":         alert.Message,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode alert event: %w", err)
+		}
+
+		if err := q.EnqueueOutboxEvent(ctx, db.EnqueueOutboxEventParams{
+			TenantID:    alert.TenantID,
+			EventType:   eventbus.EventBudgetAlert,
+			AggregateID: alert.BudgetID,
+			Payload:     payload,
+		}); err != nil {
+			return fmt.Errorf("failed to enqueue alert event: %w", err)
+		}
+		return nil
+	})
 }
 
 // GetBudgetUtilization returns the current utilization of a budget
@@ -318,6 +637,8 @@ func (s *Service) GetBudgetUtilization(ctx context.Context, tenantID, budgetID p
 	}
 	hardCap := hardCapVal.Float64
 
+	thresholds := ThresholdsForBudget(budget)
+
 	available := hardCap - balance
 	utilization := 0.0
 	if hardCap > 0 {
@@ -334,7 +655,7 @@ func (s *Service) GetBudgetUtilization(ctx context.Context, tenantID, budgetID p
 		HardCap:            hardCap,
 		Utilization:        utilization,
 		SoftCapExceeded:    balance > softCap,
-		HardCapApproaching: utilization >= 95.0,
+		HardCapApproaching: utilization >= thresholds.HardCapPercent,
 	}, nil
 }
 
@@ -351,3 +672,8 @@ type BudgetUtilization struct {
 	SoftCapExceeded    bool
 	HardCapApproaching bool
 }
+
+// WaitForAlerts blocks until alert checks triggered by reservations have finished
+func (s *Service) WaitForAlerts() {
+	s.alerts.Wait()
+}
@@ -0,0 +1,137 @@
+package budget
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+)
+
+func testNumeric(t *testing.T, s string) pgtype.Numeric {
+	t.Helper()
+	var n pgtype.Numeric
+	require.NoError(t, n.Scan(s))
+	return n
+}
+
+func TestSoftCapBreach(t *testing.T) {
+	tests := []struct {
+		name        string
+		balance     string
+		softPercent string
+		want        string
+	}{
+		{"below soft cap without percent", "850", "", ""},
+		{"past soft cap without percent", "901", "", "has exceeded soft cap"},
+		{"below percent", "700", "80", ""},
+		{"at percent below soft cap", "800", "80", "has reached its soft alert threshold of 80% of hard cap"},
+		{"past soft cap with percent", "950", "80", "has exceeded soft cap"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := db.Budget{
+				Balance: testNumeric(t, tt.balance),
+				SoftCap: testNumeric(t, "900"),
+				HardCap: testNumeric(t, "1000"),
+			}
+			if tt.softPercent != "" {
+				b.AlertSoftPercent = testNumeric(t, tt.softPercent)
+			}
+
+			balance := numericToFloat(b.Balance)
+			condition, ok := softCapBreach(b, balance, 900, balance/10)
+			assert.Equal(t, tt.want, condition)
+			assert.Equal(t, tt.want != "", ok)
+			assert.Equal(t, ok, thresholdCrossed(b, AlertTypeSoftCap))
+		})
+	}
+}
+
+func TestThresholdCrossedHardCap(t *testing.T) {
+	tests := []struct {
+		name        string
+		balance     string
+		hardCap     string
+		hardPercent string
+		want        bool
+	}{
+		{"below default", "940", "1000", "", false},
+		{"at default", "950", "1000", "", true},
+		{"at budget percent", "900", "1000", "90", true},
+		{"no hard cap", "100", "0", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := db.Budget{
+				Balance: testNumeric(t, tt.balance),
+				SoftCap: testNumeric(t, "0"),
+				HardCap: testNumeric(t, tt.hardCap),
+			}
+			if tt.hardPercent != "" {
+				b.AlertHardPercent = testNumeric(t, tt.hardPercent)
+			}
+			assert.Equal(t, tt.want, thresholdCrossed(b, AlertTypeHardCap))
+		})
+	}
+}
+
+func TestThresholdCrossedOverdraft(t *testing.T) {
+	tests := []struct {
+		name    string
+		balance string
+		want    bool
+	}{
+		{"within hard cap", "90", false},
+		{"at hard cap", "100", false},
+		{"past hard cap", "100.01", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := db.Budget{
+				Balance: testNumeric(t, tt.balance),
+				SoftCap: testNumeric(t, "100"),
+				HardCap: testNumeric(t, "100"),
+			}
+			assert.Equal(t, tt.want, thresholdCrossed(b, AlertTypeOverdraft))
+		})
+	}
+}
+
+func TestNewAlertRecord(t *testing.T) {
+	tests := []struct {
+		name            string
+		balance         string
+		utilization     string
+		acknowledged    bool
+		wantBalance     string
+		wantUtilization string
+	}{
+		{"unacknowledged warning", "901.50", "90.15", false, "901.50", "90.15"},
+		{"acknowledged notice", "100", "10", true, "100", "10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert := db.BudgetAlert{
+				Balance:     testNumeric(t, tt.balance),
+				Utilization: testNumeric(t, tt.utilization),
+			}
+			if tt.acknowledged {
+				alert.AcknowledgedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+			}
+
+			record := NewAlertRecord(alert)
+			assert.Equal(t, tt.wantBalance, record.Balance)
+			assert.Equal(t, tt.wantUtilization, record.Utilization)
+			assert.Equal(t, tt.acknowledged, record.Acknowledged)
+			assert.Equal(t, tt.acknowledged, record.AcknowledgedAt != nil)
+		})
+	}
+}
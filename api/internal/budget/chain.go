@@ -0,0 +1,129 @@
+package budget
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+)
+
+// chainBatchSize is the number of ledger entries verified per query
+const chainBatchSize = 1000
+
+// ChainVerification is the result of recomputing a budget's ledger hash
+// chain. When the chain is broken, BrokenEntryID is the first entry that
+// does not match and Reason says why; every entry after it is suspect too.
+type ChainVerification struct {
+	BudgetID       string  `json:"budget_id"`
+	EntriesChecked int64   `json:"entries_checked"`
+	Valid          bool    `json:"valid"`
+	BrokenEntryID  *int64  `json:"broken_entry_id,omitempty"`
+	Reason         string  `json:"reason,omitempty"`
+	HeadHash       *string `json:"head_hash"`
+}
+
+// EntryHash computes the hash of a ledger entry given the hash of the
+// budget's previous entry, as the ledger_entry_hash database function does.
+// The two must hash the same fields in the same order.
+func EntryHash(entry db.ListLedgerChainRow, prevHash string) string {
+	fields := []string{
+		strconv.FormatInt(entry.ID, 10),
+		httputil.FormatUUID(entry.TenantID.Bytes),
+		httputil.FormatUUID(entry.BudgetID.Bytes),
+		entry.EntryType,
+		entry.Currency,
+		entry.AmountText,
+		entry.RefType.String,
+		optionalUUID(entry.RefID),
+		optionalUUID(entry.FallbackFrom),
+		strconv.FormatInt(entry.CreatedMicros, 10),
+		prevHash,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyLedgerChain recomputes the hash chain of a budget's ledger entries,
+// reporting the first entry that was changed, or that follows a removed one
+func (s *Service) VerifyLedgerChain(ctx context.Context, tenantID, budgetID pgtype.UUID) (*ChainVerification, error) {
+	_, err := s.queries.GetBudgetByID(ctx, db.GetBudgetByIDParams{
+		ID:       budgetID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrBudgetNotFound
+		}
+		return nil, fmt.Errorf("failed to get budget: %w", err)
+	}
+
+	result := &ChainVerification{
+		BudgetID: httputil.FormatUUID(budgetID.Bytes),
+		Valid:    true,
+	}
+
+	prevHash := ""
+	var afterID int64
+	for {
+		entries, err := s.queries.ListLedgerChain(ctx, db.ListLedgerChainParams{
+			TenantID: tenantID,
+			BudgetID: budgetID,
+			AfterID:  afterID,
+			RowLimit: chainBatchSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list ledger entries: %w", err)
+		}
+
+		for _, entry := range entries {
+			result.EntriesChecked++
+			if reason := verifyChainLink(entry, prevHash); reason != "" {
+				id := entry.ID
+				result.Valid = false
+				result.BrokenEntryID = &id
+				result.Reason = reason
+				return result, nil
+			}
+			prevHash = entry.EntryHash
+			afterID = entry.ID
+		}
+
+		if len(entries) < chainBatchSize {
+			break
+		}
+	}
+
+	if result.EntriesChecked > 0 {
+		result.HeadHash = &prevHash
+	}
+	return result, nil
+}
+
+// verifyChainLink checks an entry against the hash of the entry before it,
+// returning why it does not match or "" if it does
+func verifyChainLink(entry db.ListLedgerChainRow, prevHash string) string {
+	if entry.PrevHash != prevHash {
+		return "previous hash does not match the preceding entry; an entry was removed or reordered"
+	}
+	if EntryHash(entry, prevHash) != entry.EntryHash {
+		return "entry hash does not match its contents; the entry was modified"
+	}
+	return ""
+}
+
+// optionalUUID formats a nullable UUID, empty when null
+func optionalUUID(id pgtype.UUID) string {
+	if !id.Valid {
+		return ""
+	}
+	return httputil.FormatUUID(id.Bytes)
+}
@@ -0,0 +1,86 @@
+package budget
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+)
+
+func testChain() []db.ListLedgerChainRow {
+	tenantID := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	budgetID := pgtype.UUID{Bytes: [16]byte{2}, Valid: true}
+	entries := []db.ListLedgerChainRow{
+		{ID: 1, EntryType: "fund", AmountText: "1000.00", CreatedMicros: 1765000000000000},
+		{ID: 4, EntryType: "reserve", AmountText: "25.00", RefType: pgtype.Text{String: "issuance", Valid: true}, RefID: pgtype.UUID{Bytes: [16]byte{3}, Valid: true}, CreatedMicros: 1765000001000000},
+		{ID: 9, EntryType: "charge", AmountText: "25.00", RefType: pgtype.Text{String: "issuance", Valid: true}, RefID: pgtype.UUID{Bytes: [16]byte{3}, Valid: true}, CreatedMicros: 1765000002000000},
+	}
+
+	prevHash := ""
+	for i := range entries {
+		entries[i].TenantID = tenantID
+		entries[i].BudgetID = budgetID
+		entries[i].Currency = "USD"
+		entries[i].PrevHash = prevHash
+		entries[i].EntryHash = EntryHash(entries[i], prevHash)
+		prevHash = entries[i].EntryHash
+	}
+	return entries
+}
+
+func verifyChain(entries []db.ListLedgerChainRow) (int64, string) {
+	prevHash := ""
+	for _, entry := range entries {
+		if reason := verifyChainLink(entry, prevHash); reason != "" {
+			return entry.ID, reason
+		}
+		prevHash = entry.EntryHash
+	}
+	return 0, ""
+}
+
+func TestEntryHash(t *testing.T) {
+	entry := testChain()[0]
+
+	hash := EntryHash(entry, "")
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, EntryHash(entry, ""))
+	assert.NotEqual(t, hash, EntryHash(entry, "abc"))
+
+	entry.AmountText = "1000.01"
+	assert.NotEqual(t, hash, EntryHash(entry, ""))
+}
+
+func TestVerifyChainLink(t *testing.T) {
+	t.Run("intact", func(t *testing.T) {
+		id, reason := verifyChain(testChain())
+		assert.Zero(t, id)
+		assert.Empty(t, reason)
+	})
+
+	t.Run("modified entry", func(t *testing.T) {
+		entries := testChain()
+		entries[1].AmountText = "2.50"
+		id, reason := verifyChain(entries)
+		assert.Equal(t, int64(4), id)
+		assert.Contains(t, reason, "modified")
+	})
+
+	t.Run("removed entry", func(t *testing.T) {
+		entries := testChain()
+		entries = append(entries[:1], entries[2:]...)
+		id, reason := verifyChain(entries)
+		assert.Equal(t, int64(9), id)
+		assert.Contains(t, reason, "removed")
+	})
+
+	t.Run("rehashed entry", func(t *testing.T) {
+		entries := testChain()
+		entries[1].AmountText = "2.50"
+		entries[1].EntryHash = EntryHash(entries[1], entries[1].PrevHash)
+		id, _ := verifyChain(entries)
+		assert.Equal(t, int64(9), id)
+	})
+}
@@ -0,0 +1,310 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+)
+
+// Commitment statuses
+const (
+	CommitmentOpen      = "open"
+	CommitmentConverted = "converted"
+	CommitmentReleased  = "released"
+)
+
+var (
+	// ErrCommitmentNotFound is returned when a budget commitment doesn't exist
+	ErrCommitmentNotFound = errors.New("budget commitment not found")
+
+	// ErrCommitmentClosed is returned when releasing a commitment that was
+	// already released or fully converted into reservations
+	ErrCommitmentClosed = errors.New("budget commitment is not open")
+
+	// ErrCampaignNotOnBudget is returned when committing funds to a campaign
+	// that doesn't reserve from the budget, so could never draw them down
+	ErrCampaignNotOnBudget = errors.New("campaign does not reserve from this budget")
+)
+
+// CommitBudgetParams contains parameters for committing budget to a campaign
+type CommitBudgetParams struct {
+	TenantID   pgtype.UUID
+	BudgetID   pgtype.UUID
+	CampaignID pgtype.UUID
+	Amount     string // String to avoid floating point precision issues
+	Note       string
+	CreatedBy  pgtype.UUID
+}
+
+// Validate validates the commit budget parameters
+func (p CommitBudgetParams) Validate() error {
+	if !p.TenantID.Valid {
+		return errors.New("tenant_id is required")
+	}
+	if !p.BudgetID.Valid {
+		return errors.New("budget_id is required")
+	}
+	if !p.CampaignID.Valid {
+		return errors.New("campaign_id is required")
+	}
+	if p.Amount == "" || p.Amount == "0" {
+		return ErrInvalidAmount
+	}
+	return nil
+}
+
+// Commitment is an amount of a budget earmarked for a campaign. Converted is
+// how much of it the campaign's reservations have drawn down and Released
+// how much went back to the budget when it was released. Amounts are
+// decimal strings.
+type Commitment struct {
+	ID         string     `json:"id"`
+	BudgetID   string     `json:"budget_id"`
+	CampaignID string     `json:"campaign_id"`
+	Currency   string     `json:"currency"`
+	Amount     string     `json:"amount"`
+	Remaining  string     `json:"remaining"`
+	Converted  string     `json:"converted"`
+	Released   string     `json:"released"`
+	Status     string     `json:"status"`
+	Note       string     `json:"note,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+}
+
+// CommitBudget ring-fences an amount of a budget for a campaign without
+// issuing anything. The amount counts towards the budget's balance until
+// the campaign's reservations draw it down or it is released.
+func (s *Service) CommitBudget(ctx context.Context, params CommitBudgetParams) (*Commitment, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	var amount pgtype.Numeric
+	if err := amount.Scan(params.Amount); err != nil {
+		return nil, ErrInvalidAmount
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	budget, err := qtx.GetBudgetByID(ctx, db.GetBudgetByIDParams{
+		ID:       params.BudgetID,
+		TenantID: params.TenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrBudgetNotFound
+		}
+		return nil, fmt.Errorf("failed to get budget: %w", err)
+	}
+
+	draws, err := qtx.CampaignDrawsFromBudget(ctx, db.CampaignDrawsFromBudgetParams{
+		TenantID:   params.TenantID,
+		CampaignID: params.CampaignID,
+		BudgetID:   params.BudgetID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check campaign budgets: %w", err)
+	}
+	if !draws.Bool {
+		return nil, ErrCampaignNotOnBudget
+	}
+
+	commitment, err := qtx.CreateBudgetCommitment(ctx, db.CreateBudgetCommitmentParams{
+		TenantID:   params.TenantID,
+		BudgetID:   params.BudgetID,
+		CampaignID: params.CampaignID,
+		Currency:   budget.Currency,
+		Amount:     amount,
+		Note:       pgtype.Text{String: params.Note, Valid: params.Note != ""},
+		CreatedBy:  params.CreatedBy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create commitment: %w", err)
+	}
+
+	// commit_budget checks capacity and posts the commit entry atomically
+	var success bool
+	if err := tx.QueryRow(ctx, "SELECT commit_budget($1, $2)", params.TenantID, commitment.ID).Scan(&success); err != nil {
+		return nil, fmt.Errorf("failed to commit budget: %w", err)
+	}
+	if !success {
+		return nil, ErrInsufficientFunds
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.log(ctx).Info("budget committed",
+		"budget_id", params.BudgetID,
+		"campaign_id", params.CampaignID,
+		"commitment_id", commitment.ID,
+		"amount", params.Amount,
+		"currency", budget.Currency)
+
+	return formatCommitment(commitment), nil
+}
+
+// ReleaseCommitment returns what is left of an open commitment to its
+// budget, e.g. when its campaign is cancelled. Reservations already drawn
+// from it are unaffected.
+func (s *Service) ReleaseCommitment(ctx context.Context, tenantID, budgetID, commitmentID, releasedBy pgtype.UUID) (*Commitment, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	params := db.GetBudgetCommitmentParams{
+		TenantID: tenantID,
+		BudgetID: budgetID,
+		ID:       commitmentID,
+	}
+	if _, err := qtx.GetBudgetCommitment(ctx, params); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCommitmentNotFound
+		}
+		return nil, fmt.Errorf("failed to get commitment: %w", err)
+	}
+
+	var released pgtype.Numeric
+	if err := tx.QueryRow(ctx, "SELECT release_budget_commitment($1, $2, $3)",
+		tenantID, commitmentID, releasedBy).Scan(&released); err != nil {
+		return nil, fmt.Errorf("failed to release commitment: %w", err)
+	}
+	if !released.Valid {
+		return nil, ErrCommitmentClosed
+	}
+
+	commitment, err := qtx.GetBudgetCommitment(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get released commitment: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.log(ctx).Info("budget commitment released",
+		"budget_id", budgetID,
+		"commitment_id", commitmentID,
+		"amount", numericToFloat(released))
+
+	return formatCommitment(commitment), nil
+}
+
+// ListCommitments returns a page of a budget's commitments, newest first,
+// and the total number of them. status optionally limits them to one status.
+func (s *Service) ListCommitments(ctx context.Context, tenantID, budgetID pgtype.UUID, status string, limit, offset int32) ([]Commitment, int64, error) {
+	statusFilter := pgtype.Text{String: status, Valid: status != ""}
+	rows, err := s.queries.ListBudgetCommitments(ctx, db.ListBudgetCommitmentsParams{
+		TenantID:  tenantID,
+		BudgetID:  budgetID,
+		Status:    statusFilter,
+		RowLimit:  limit,
+		RowOffset: offset,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list commitments: %w", err)
+	}
+
+	total, err := s.queries.CountBudgetCommitments(ctx, db.CountBudgetCommitmentsParams{
+		TenantID: tenantID,
+		BudgetID: budgetID,
+		Status:   statusFilter,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count commitments: %w", err)
+	}
+
+	commitments := make([]Commitment, 0, len(rows))
+	for _, row := range rows {
+		commitments = append(commitments, *formatCommitment(row))
+	}
+	return commitments, total, nil
+}
+
+// IsValidCommitmentStatus reports whether status is a commitment status
+func IsValidCommitmentStatus(status string) bool {
+	switch status {
+	case CommitmentOpen, CommitmentConverted, CommitmentReleased:
+		return true
+	}
+	return false
+}
+
+func formatCommitment(c db.BudgetCommitment) *Commitment {
+	remaining := c.Remaining
+	converted := subtractNumeric(c.Amount, c.Remaining)
+	released := zeroNumeric(c.Amount.Exp)
+	if c.Status == CommitmentReleased {
+		// A released commitment's remainder is what it released
+		released, remaining = remaining, zeroNumeric(c.Amount.Exp)
+	}
+
+	commitment := &Commitment{
+		ID:         httputil.FormatUUID(c.ID.Bytes),
+		BudgetID:   httputil.FormatUUID(c.BudgetID.Bytes),
+		CampaignID: httputil.FormatUUID(c.CampaignID.Bytes),
+		Currency:   c.Currency,
+		Amount:     httputil.FormatNumeric(c.Amount),
+		Remaining:  httputil.FormatNumeric(remaining),
+		Converted:  httputil.FormatNumeric(converted),
+		Released:   httputil.FormatNumeric(released),
+		Status:     c.Status,
+		Note:       c.Note.String,
+		CreatedAt:  c.CreatedAt.Time,
+	}
+	if c.ReleasedAt.Valid {
+		commitment.ReleasedAt = &c.ReleasedAt.Time
+	}
+	return commitment
+}
+
+// addNumeric returns a + b exactly, at the finer of their scales. Invalid
+// values count as zero.
+func addNumeric(a, b pgtype.Numeric) pgtype.Numeric {
+	exp := min(a.Exp, b.Exp)
+	sum := new(big.Int).Add(scaleNumeric(a, exp), scaleNumeric(b, exp))
+	return pgtype.Numeric{Int: sum, Exp: exp, Valid: true}
+}
+
+// subtractNumeric returns a - b exactly, at the finer of their scales.
+// Invalid values count as zero.
+func subtractNumeric(a, b pgtype.Numeric) pgtype.Numeric {
+	exp := min(a.Exp, b.Exp)
+	diff := new(big.Int).Sub(scaleNumeric(a, exp), scaleNumeric(b, exp))
+	return pgtype.Numeric{Int: diff, Exp: exp, Valid: true}
+}
+
+// scaleNumeric returns n's digits at exponent exp, which must not be above
+// n's own
+func scaleNumeric(n pgtype.Numeric, exp int32) *big.Int {
+	if !n.Valid || n.Int == nil {
+		return new(big.Int)
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n.Exp-exp)), nil)
+	return scale.Mul(scale, n.Int)
+}
+
+// zeroNumeric is zero at exponent exp, so it formats like amounts of that
+// scale
+func zeroNumeric(exp int32) pgtype.Numeric {
+	return pgtype.Numeric{Int: new(big.Int), Exp: exp, Valid: true}
+}
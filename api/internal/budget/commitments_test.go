@@ -0,0 +1,288 @@
+package budget
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+)
+
+func TestFormatCommitmentAmounts(t *testing.T) {
+	tests := []struct {
+		name          string
+		amount        string
+		remaining     string
+		status        string
+		wantRemaining string
+		wantConverted string
+		wantReleased  string
+	}{
+		{"untouched", "500.00", "500.00", CommitmentOpen, "500.00", "0.00", "0.00"},
+		{"partly drawn down", "500.00", "120.35", CommitmentOpen, "120.35", "379.65", "0.00"},
+		{"converted", "500.00", "0.00", CommitmentConverted, "0.00", "500.00", "0.00"},
+		{"released", "500.00", "120.35", CommitmentReleased, "0.00", "379.65", "120.35"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := formatCommitment(db.BudgetCommitment{
+				Amount:    testNumeric(t, tt.amount),
+				Remaining: testNumeric(t, tt.remaining),
+				Status:    tt.status,
+			})
+			assert.Equal(t, tt.amount, c.Amount)
+			assert.Equal(t, tt.wantRemaining, c.Remaining)
+			assert.Equal(t, tt.wantConverted, c.Converted)
+			assert.Equal(t, tt.wantReleased, c.Released)
+		})
+	}
+}
+
+// commitmentFixture is a tenant with a budget, a campaign reserving from it
+// and a campaign that doesn't
+type commitmentFixture struct {
+	tenantID   pgtype.UUID
+	budgetID   pgtype.UUID
+	campaignID pgtype.UUID
+	otherID    pgtype.UUID
+}
+
+func createCommitmentFixture(t *testing.T, queries *db.Queries, hardCap string) commitmentFixture {
+	ctx := context.Background()
+
+	tenant, err := queries.CreateTenant(ctx, db.CreateTenantParams{
+		Name:        "Commitments " + t.Name(),
+		CountryCode: "ZW",
+		DefaultCcy:  CurrencyUSD,
+		Theme:       []byte(`{}`),
+	})
+	require.NoError(t, err)
+
+	budget := createTestBudget(t, queries, tenant.ID, hardCap, hardCap, "0.00")
+
+	campaign := func(name string, budgetID pgtype.UUID) pgtype.UUID {
+		c, err := queries.CreateCampaign(ctx, db.CreateCampaignParams{
+			TenantID: tenant.ID,
+			Name:     name,
+			StartAt:  pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true},
+			BudgetID: budgetID,
+			Status:   "active",
+		})
+		require.NoError(t, err)
+		return c.ID
+	}
+
+	return commitmentFixture{
+		tenantID:   tenant.ID,
+		budgetID:   budget.ID,
+		campaignID: campaign("Committed Campaign", budget.ID),
+		otherID:    campaign("Other Campaign", pgtype.UUID{}),
+	}
+}
+
+// budgetBalance reads a budget's balance as a float
+func budgetBalance(t *testing.T, queries *db.Queries, f commitmentFixture) float64 {
+	b, err := queries.GetBudgetByID(context.Background(), db.GetBudgetByIDParams{
+		ID:       f.budgetID,
+		TenantID: f.tenantID,
+	})
+	require.NoError(t, err)
+	return numericToFloat(b.Balance)
+}
+
+func TestCommitBudget(t *testing.T) {
+	pool, queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	service := NewService(pool, queries, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	tests := []struct {
+		name        string
+		amount      string
+		onBudget    bool
+		wantErr     error
+		wantBalance float64
+	}{
+		{"within hard cap", "400.00", true, nil, 400},
+		{"up to hard cap", "1000.00", true, nil, 1000},
+		{"over hard cap", "1000.01", true, ErrInsufficientFunds, 0},
+		{"campaign not on budget", "400.00", false, ErrCampaignNotOnBudget, 0},
+		{"zero amount", "0", true, ErrInvalidAmount, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := createCommitmentFixture(t, queries, "1000.00")
+			campaignID := f.campaignID
+			if !tt.onBudget {
+				campaignID = f.otherID
+			}
+
+			commitment, err := service.CommitBudget(context.Background(), CommitBudgetParams{
+				TenantID:   f.tenantID,
+				BudgetID:   f.budgetID,
+				CampaignID: campaignID,
+				Amount:     tt.amount,
+			})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, CommitmentOpen, commitment.Status)
+				assert.Equal(t, tt.amount, commitment.Amount)
+				assert.Equal(t, tt.amount, commitment.Remaining)
+				assert.Equal(t, "0.00", commitment.Converted)
+			}
+			assert.Equal(t, tt.wantBalance, budgetBalance(t, queries, f))
+		})
+	}
+}
+
+func TestReleaseCommitment(t *testing.T) {
+	pool, queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	service := NewService(pool, queries, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		drawn        string
+		releaseTwice bool
+		unknown      bool
+		wantErr      error
+		wantReleased string
+		wantBalance  float64
+	}{
+		{"untouched", "", false, false, nil, "500.00", 0},
+		{"partly drawn down", "200.00", false, false, nil, "300.00", 200},
+		{"already released", "", true, false, ErrCommitmentClosed, "", 0},
+		{"unknown commitment", "", false, true, ErrCommitmentNotFound, "", 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := createCommitmentFixture(t, queries, "1000.00")
+			commitment, err := service.CommitBudget(ctx, CommitBudgetParams{
+				TenantID:   f.tenantID,
+				BudgetID:   f.budgetID,
+				CampaignID: f.campaignID,
+				Amount:     "500.00",
+			})
+			require.NoError(t, err)
+
+			if tt.drawn != "" {
+				reserveForCampaign(t, pool, f, tt.drawn)
+			}
+
+			var commitmentID pgtype.UUID
+			require.NoError(t, commitmentID.Scan(commitment.ID))
+			if tt.unknown {
+				commitmentID = pgtype.UUID{Bytes: uuid.New(), Valid: true}
+			}
+			if tt.releaseTwice {
+				_, err := service.ReleaseCommitment(ctx, f.tenantID, f.budgetID, commitmentID, pgtype.UUID{})
+				require.NoError(t, err)
+			}
+
+			released, err := service.ReleaseCommitment(ctx, f.tenantID, f.budgetID, commitmentID, pgtype.UUID{})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, CommitmentReleased, released.Status)
+				assert.Equal(t, tt.wantReleased, released.Released)
+				assert.Equal(t, "0.00", released.Remaining)
+				assert.NotNil(t, released.ReleasedAt)
+			}
+			assert.Equal(t, tt.wantBalance, budgetBalance(t, queries, f))
+		})
+	}
+}
+
+// TestCommitmentConversion checks that a campaign's reservations draw its
+// commitment down instead of the budget's free capacity. Commitments don't
+// lapse on their own; what isn't converted stays ring-fenced until released.
+func TestCommitmentConversion(t *testing.T) {
+	pool, queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	service := NewService(pool, queries, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		reservations  []string
+		wantStatus    string
+		wantRemaining string
+		wantBalance   float64
+	}{
+		{"partly drawn down", []string{"200.00"}, CommitmentOpen, "300.00", 500},
+		{"fully drawn down", []string{"200.00", "300.00"}, CommitmentConverted, "0.00", 500},
+		{"drawn past commitment", []string{"450.00", "150.00"}, CommitmentConverted, "0.00", 600},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := createCommitmentFixture(t, queries, "1000.00")
+			_, err := service.CommitBudget(ctx, CommitBudgetParams{
+				TenantID:   f.tenantID,
+				BudgetID:   f.budgetID,
+				CampaignID: f.campaignID,
+				Amount:     "500.00",
+			})
+			require.NoError(t, err)
+
+			for _, amount := range tt.reservations {
+				reserveForCampaign(t, pool, f, amount)
+			}
+
+			commitments, total, err := service.ListCommitments(ctx, f.tenantID, f.budgetID, "", 10, 0)
+			require.NoError(t, err)
+			require.Equal(t, int64(1), total)
+			assert.Equal(t, tt.wantStatus, commitments[0].Status)
+			assert.Equal(t, tt.wantRemaining, commitments[0].Remaining)
+			assert.Equal(t, tt.wantBalance, budgetBalance(t, queries, f))
+		})
+	}
+}
+
+// reserveForCampaign reserves an amount for one of the fixture campaign's
+// issuances the way the rules engine does
+func reserveForCampaign(t *testing.T, pool *pgxpool.Pool, f commitmentFixture, amount string) {
+	var budgetID pgtype.UUID
+	err := pool.QueryRow(context.Background(), "SELECT reserve_campaign_budget($1, $2, $3, $4, $5)",
+		f.tenantID, f.campaignID, testNumeric(t, amount), CurrencyUSD, pgtype.UUID{Bytes: uuid.New(), Valid: true}).Scan(&budgetID)
+	require.NoError(t, err)
+	require.Equal(t, f.budgetID, budgetID)
+}
+
+func TestAddNumeric(t *testing.T) {
+	tests := []struct {
+		name string
+		a    pgtype.Numeric
+		b    pgtype.Numeric
+		want string
+	}{
+		{"same scale", testNumeric(t, "10.25"), testNumeric(t, "0.75"), "11.00"},
+		{"finer scale kept", testNumeric(t, "10.5"), testNumeric(t, "0.125"), "10.625"},
+		{"invalid counts as zero", pgtype.Numeric{}, testNumeric(t, "20.00"), "20.00"},
+		{"negative", testNumeric(t, "5.00"), testNumeric(t, "-7.50"), "-2.50"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, httputil.FormatNumeric(addNumeric(tt.a, tt.b)))
+		})
+	}
+}
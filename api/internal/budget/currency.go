@@ -3,10 +3,11 @@ package budget
 import (
 	"errors"
 
+	"github.com/bmachimbira/loyalty/api/internal/currency"
 	"github.com/bmachimbira/loyalty/api/internal/db"
 )
 
-// Supported currencies
+// Common currencies
 const (
 	CurrencyZWG = "ZWG"
 	CurrencyUSD = "USD"
@@ -17,15 +18,9 @@ var (
 	ErrUnsupportedCurrency = errors.New("unsupported currency")
 )
 
-// ValidCurrencies is a map of all supported currencies
-var ValidCurrencies = map[string]bool{
-	CurrencyZWG: true,
-	CurrencyUSD: true,
-}
-
-// IsValidCurrency checks if a currency code is supported
-func IsValidCurrency(currency string) bool {
-	return ValidCurrencies[currency]
+// IsValidCurrency checks if a currency code is an ISO 4217 currency code
+func IsValidCurrency(code string) bool {
+	return currency.Valid(code)
 }
 
 // ValidateCurrency validates that a currency matches the budget's currency
@@ -43,5 +38,5 @@ func ValidateCurrency(budget *db.Budget, currency string) error {
 
 // GetSupportedCurrencies returns a list of all supported currencies
 func GetSupportedCurrencies() []string {
-	return []string{CurrencyZWG, CurrencyUSD}
+	return currency.Codes()
 }
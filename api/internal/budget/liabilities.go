@@ -0,0 +1,75 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+)
+
+// LiabilityLine is the reward liability for one reward type and currency
+type LiabilityLine struct {
+	RewardType       string `json:"reward_type"`
+	Currency         string `json:"currency"`
+	Outstanding      string `json:"outstanding"`
+	OutstandingCount int64  `json:"outstanding_count"`
+	Issued           string `json:"issued"`
+	Redeemed         string `json:"redeemed"`
+	Expired          string `json:"expired"`
+	Cancelled        string `json:"cancelled"`
+}
+
+// LiabilityReport is the face value of unredeemed rewards owed by a tenant.
+// Entries are posted to the liability ledger as issuances change status.
+// Amounts are decimal strings, and Total is outstanding per currency.
+type LiabilityReport struct {
+	AsOf  time.Time         `json:"as_of"`
+	Lines []LiabilityLine   `json:"lines"`
+	Total map[string]string `json:"total_outstanding"`
+}
+
+// GetLiabilityReport summarises outstanding reward liabilities by reward type
+// as of the given time
+func (s *Service) GetLiabilityReport(ctx context.Context, tenantID pgtype.UUID, asOf time.Time) (*LiabilityReport, error) {
+	if !tenantID.Valid {
+		return nil, errors.New("tenant_id is required")
+	}
+
+	rows, err := s.queries.GetLiabilitiesByRewardType(ctx, db.GetLiabilitiesByRewardTypeParams{
+		TenantID: tenantID,
+		AsOf:     pgtype.Timestamptz{Time: asOf, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get liabilities: %w", err)
+	}
+
+	report := &LiabilityReport{
+		AsOf:  asOf,
+		Lines: make([]LiabilityLine, len(rows)),
+		Total: make(map[string]string),
+	}
+	totals := make(map[string]pgtype.Numeric)
+	for i, row := range rows {
+		report.Lines[i] = LiabilityLine{
+			RewardType:       row.RewardType,
+			Currency:         row.Currency,
+			Outstanding:      httputil.FormatNumeric(row.Outstanding),
+			OutstandingCount: row.OutstandingCount,
+			Issued:           httputil.FormatNumeric(row.Issued),
+			Redeemed:         httputil.FormatNumeric(row.Redeemed),
+			Expired:          httputil.FormatNumeric(row.Expired),
+			Cancelled:        httputil.FormatNumeric(row.Cancelled),
+		}
+		totals[row.Currency] = addNumeric(totals[row.Currency], row.Outstanding)
+	}
+	for currency, total := range totals {
+		report.Total[currency] = httputil.FormatNumeric(total)
+	}
+
+	return report, nil
+}
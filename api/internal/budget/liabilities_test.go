@@ -0,0 +1,145 @@
+package budget
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+)
+
+// createLiabilityIssuance issues a reward and moves it to status, posting
+// liability entries as the issuance triggers do
+func createLiabilityIssuance(t *testing.T, pool *pgxpool.Pool, tenantID, customerID, rewardID pgtype.UUID, status string) {
+	ctx := context.Background()
+
+	initial := "issued"
+	if status == "reserved" {
+		initial = status
+	}
+
+	var id pgtype.UUID
+	err := pool.QueryRow(ctx, `
+		INSERT INTO issuances (tenant_id, customer_id, reward_id, status, code, issued_at)
+		VALUES ($1, $2, $3, $4, $5, now())
+		RETURNING id
+	`, tenantID, customerID, rewardID, initial, uuid.NewString()).Scan(&id)
+	require.NoError(t, err)
+
+	if status != initial {
+		_, err = pool.Exec(ctx, "UPDATE issuances SET status = $2 WHERE id = $1", id, status)
+		require.NoError(t, err)
+	}
+}
+
+func TestGetLiabilityReport(t *testing.T) {
+	pool, queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	service := NewService(pool, queries, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		faceValue string
+		statuses  []string
+		want      []LiabilityLine
+	}{
+		{
+			name:      "outstanding",
+			faceValue: "10.00",
+			statuses:  []string{"issued", "issued"},
+			want: []LiabilityLine{
+				{RewardType: "discount", Currency: CurrencyUSD, Outstanding: "20.00", OutstandingCount: 2, Issued: "20.00",
+					Redeemed: "0.00", Expired: "0.00", Cancelled: "0.00"},
+			},
+		},
+		{
+			name:      "settled",
+			faceValue: "10.00",
+			statuses:  []string{"issued", "redeemed", "expired", "cancelled"},
+			want: []LiabilityLine{
+				{RewardType: "discount", Currency: CurrencyUSD, Outstanding: "10.00", OutstandingCount: 1, Issued: "40.00",
+					Redeemed: "10.00", Expired: "10.00", Cancelled: "10.00"},
+			},
+		},
+		{
+			name:      "reserved is not yet owed",
+			faceValue: "10.00",
+			statuses:  []string{"reserved"},
+			want:      []LiabilityLine{},
+		},
+		{
+			name:      "no face value",
+			faceValue: "",
+			statuses:  []string{"issued"},
+			want:      []LiabilityLine{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant, err := queries.CreateTenant(ctx, db.CreateTenantParams{
+				Name:        "Liabilities " + tt.name,
+				CountryCode: "ZW",
+				DefaultCcy:  CurrencyUSD,
+				Theme:       []byte(`{}`),
+			})
+			require.NoError(t, err)
+
+			customer, err := queries.CreateCustomer(ctx, db.CreateCustomerParams{
+				TenantID:    tenant.ID,
+				ExternalRef: pgtype.Text{String: "liabilities-customer", Valid: true},
+			})
+			require.NoError(t, err)
+
+			var faceValue pgtype.Numeric
+			if tt.faceValue != "" {
+				faceValue = testNumeric(t, tt.faceValue)
+			}
+			reward, err := queries.CreateReward(ctx, db.CreateRewardParams{
+				TenantID:  tenant.ID,
+				Name:      "$10 Off",
+				Type:      "discount",
+				FaceValue: faceValue,
+				Currency:  pgtype.Text{String: CurrencyUSD, Valid: true},
+				Inventory: "none",
+				Metadata:  []byte(`{}`),
+				Active:    true,
+			})
+			require.NoError(t, err)
+
+			before := time.Now().Add(-time.Minute)
+			for _, status := range tt.statuses {
+				createLiabilityIssuance(t, pool, tenant.ID, customer.ID, reward.ID, status)
+			}
+
+			report, err := service.GetLiabilityReport(ctx, tenant.ID, time.Now())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, report.Lines)
+			for _, line := range tt.want {
+				assert.Equal(t, line.Outstanding, report.Total[line.Currency])
+			}
+
+			// Nothing was owed before the rewards were issued
+			earlier, err := service.GetLiabilityReport(ctx, tenant.ID, before)
+			require.NoError(t, err)
+			assert.Empty(t, earlier.Lines)
+		})
+	}
+}
+
+func TestGetLiabilityReportRequiresTenant(t *testing.T) {
+	service := NewService(nil, nil, nil)
+
+	_, err := service.GetLiabilityReport(context.Background(), pgtype.UUID{}, time.Now())
+	assert.EqualError(t, err, "tenant_id is required")
+}
@@ -0,0 +1,169 @@
+package budget
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/bmachimbira/loyalty/api/internal/webhooks"
+)
+
+// AlertSecrets looks up the secret a tenant's budget alert webhooks are
+// signed with (satisfied by *db.Queries)
+type AlertSecrets interface {
+	GetTenantAlertWebhookSecret(ctx context.Context, id pgtype.UUID) (string, error)
+}
+
+// WebhookAlertNotifier posts budget alerts as budget.threshold webhook
+// events, signed with the tenant's alert webhook secret the same way webhook
+// endpoint deliveries are
+type WebhookAlertNotifier struct {
+	client  *http.Client
+	secrets AlertSecrets
+}
+
+// NewWebhookAlertNotifier creates a new webhook alert notifier
+func NewWebhookAlertNotifier(secrets AlertSecrets) *WebhookAlertNotifier {
+	return &WebhookAlertNotifier{
+		client:  &http.Client{Timeout: 10 * time.Second},
+		secrets: secrets,
+	}
+}
+
+// Notify sends the alert to the given URL
+func (n *WebhookAlertNotifier) Notify(ctx context.Context, destination string, alert Alert) error {
+	threshold := "hard_cap"
+	switch alert.Type {
+	case AlertTypeSoftCap, AlertTypeSoftCapResolved:
+		threshold = "soft_cap"
+	case AlertTypeOverdraft, AlertTypeOverdraftResolved:
+		threshold = "overdraft"
+	}
+
+	payload := webhooks.NewBudgetThresholdEvent(uuid.UUID(alert.TenantID.Bytes), webhooks.BudgetThresholdData{
+		BudgetID:    uuid.UUID(alert.BudgetID.Bytes).String(),
+		BudgetName:  alert.BudgetName,
+		Threshold:   threshold,
+		Balance:     alert.Balance,
+		SoftCap:     alert.SoftCap,
+		HardCap:     alert.HardCap,
+		Currency:    alert.Currency,
+		Utilization: alert.Utilization,
+		Resolved:    alert.Level == AlertLevelInfo,
+	})
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert payload: %w", err)
+	}
+
+	secret, err := n.secrets.GetTenantAlertWebhookSecret(ctx, alert.TenantID)
+	if err != nil {
+		return fmt.Errorf("failed to get alert webhook secret: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", destination, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event", webhooks.EventBudgetThreshold)
+	req.Header.Set("X-Signature", webhooks.GenerateSignature(secret, body))
+	req.Header.Set("User-Agent", "ZW-Loyalty-Platform/1.0")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("non-2xx status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// TextSender sends a plain text message to a phone number
+// (satisfied by whatsapp.MessageSender)
+type TextSender interface {
+	SendText(ctx context.Context, to, text string) error
+}
+
+// WhatsAppAlertNotifier sends budget alerts as WhatsApp text messages
+type WhatsAppAlertNotifier struct {
+	sender TextSender
+}
+
+// NewWhatsAppAlertNotifier creates a new WhatsApp alert notifier
+func NewWhatsAppAlertNotifier(sender TextSender) *WhatsAppAlertNotifier {
+	return &WhatsAppAlertNotifier{sender: sender}
+}
+
+// Notify sends the alert message to the given admin number
+func (n *WhatsAppAlertNotifier) Notify(ctx context.Context, destination string, alert Alert) error {
+	// WhatsApp API expects the number without the + prefix
+	to := strings.TrimPrefix(destination, "+")
+	return n.sender.SendText(ctx, to, alert.Message)
+}
+
+// SMTPConfig holds the settings for sending alert emails
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// EmailAlertNotifier sends budget alerts by email over SMTP
+type EmailAlertNotifier struct {
+	config SMTPConfig
+}
+
+// NewEmailAlertNotifier creates a new email alert notifier
+func NewEmailAlertNotifier(config SMTPConfig) *EmailAlertNotifier {
+	if config.Port == "" {
+		config.Port = "587"
+	}
+	return &EmailAlertNotifier{config: config}
+}
+
+// Notify emails the alert to the given address
+func (n *EmailAlertNotifier) Notify(ctx context.Context, destination string, alert Alert) error {
+	subject := fmt.Sprintf("[%s] Budget alert: %s", strings.ToUpper(string(alert.Level)), alert.BudgetName)
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", destination)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(alert.Message)
+	msg.WriteString("\r\n")
+
+	var auth smtp.Auth
+	if n.config.Username != "" {
+		auth = smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)
+	}
+
+	addr := net.JoinHostPort(n.config.Host, n.config.Port)
+	if err := smtp.SendMail(addr, auth, n.config.From, []string{destination}, []byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to send alert email: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmachimbira/loyalty/api/internal/webhooks"
+)
+
+// stubAlertSecrets returns a fixed alert webhook secret
+type stubAlertSecrets struct {
+	secret string
+	err    error
+}
+
+func (s stubAlertSecrets) GetTenantAlertWebhookSecret(ctx context.Context, id pgtype.UUID) (string, error) {
+	return s.secret, s.err
+}
+
+func TestWebhookAlertNotifierSigns(t *testing.T) {
+	tests := []struct {
+		name      string
+		secrets   stubAlertSecrets
+		wantErr   bool
+		wantPosts int
+	}{
+		{"signed with tenant secret", stubAlertSecrets{secret: "whsec_test"}, false, 1},
+		{"secret lookup fails", stubAlertSecrets{err: errors.New("tenant not found")}, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				posts++
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				assert.Equal(t, webhooks.EventBudgetThreshold, r.Header.Get("X-Event"))
+				assert.True(t, webhooks.VerifySignature(tt.secrets.secret, body, r.Header.Get("X-Signature")))
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer server.Close()
+
+			err := NewWebhookAlertNotifier(tt.secrets).Notify(context.Background(), server.URL, Alert{
+				Type:       AlertTypeSoftCap,
+				Level:      AlertLevelWarning,
+				TenantID:   pgtype.UUID{Bytes: [16]byte{1}, Valid: true},
+				BudgetID:   pgtype.UUID{Bytes: [16]byte{2}, Valid: true},
+				BudgetName: "Signed Budget",
+				Currency:   CurrencyUSD,
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantPosts, posts)
+		})
+	}
+}
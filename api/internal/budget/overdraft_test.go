@@ -0,0 +1,305 @@
+package budget
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+)
+
+// recordingNotifier collects the alerts delivered to it
+type recordingNotifier struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, destination string, alert Alert) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+// overdraftAlerts returns the overdraft alerts delivered so far
+func (n *recordingNotifier) overdraftAlerts() []Alert {
+	return n.alertsOfType(AlertTypeOverdraft)
+}
+
+// alertsOfType returns the alerts of one type delivered so far
+func (n *recordingNotifier) alertsOfType(alertType AlertType) []Alert {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var alerts []Alert
+	for _, alert := range n.alerts {
+		if alert.Type == alertType {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+func createOverdraftTenant(t *testing.T, queries *db.Queries) pgtype.UUID {
+	tenant, err := queries.CreateTenant(context.Background(), db.CreateTenantParams{
+		Name:        "Overdraft " + t.Name(),
+		CountryCode: "ZW",
+		DefaultCcy:  CurrencyUSD,
+		Theme:       []byte(`{}`),
+	})
+	require.NoError(t, err)
+	return tenant.ID
+}
+
+// createOverdraftBudget creates a budget alerting by webhook with the given
+// overdraft limit
+func createOverdraftBudget(t *testing.T, queries *db.Queries, tenantID pgtype.UUID, hardCap, balance, overdraft string) db.Budget {
+	ctx := context.Background()
+
+	budget, err := queries.CreateBudget(ctx, db.CreateBudgetParams{
+		TenantID:        tenantID,
+		Name:            "Overdraft Budget",
+		Currency:        CurrencyUSD,
+		SoftCap:         testNumeric(t, hardCap),
+		HardCap:         testNumeric(t, hardCap),
+		Balance:         testNumeric(t, balance),
+		Period:          "rolling",
+		AlertWebhookUrl: pgtype.Text{String: "https://alerts.example.com", Valid: true},
+	})
+	require.NoError(t, err)
+
+	budget, err = queries.UpdateBudgetOverdraft(ctx, db.UpdateBudgetOverdraftParams{
+		ID:             budget.ID,
+		TenantID:       tenantID,
+		OverdraftLimit: testNumeric(t, overdraft),
+	})
+	require.NoError(t, err)
+	return budget
+}
+
+// overdraftDrawn sums a budget's overdraft ledger entries
+func overdraftDrawn(t *testing.T, pool *pgxpool.Pool, budgetID pgtype.UUID) float64 {
+	var drawn pgtype.Numeric
+	err := pool.QueryRow(context.Background(),
+		"SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE budget_id = $1 AND entry_type = 'overdraft'",
+		budgetID).Scan(&drawn)
+	require.NoError(t, err)
+	return numericToFloat(drawn)
+}
+
+func TestCheckOverdraftAlertRequiresIDs(t *testing.T) {
+	service := NewService(nil, nil, nil)
+	valid := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+
+	tests := []struct {
+		name     string
+		tenantID pgtype.UUID
+		budgetID pgtype.UUID
+	}{
+		{"no tenant", pgtype.UUID{}, valid},
+		{"no budget", valid, pgtype.UUID{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.CheckOverdraftAlert(context.Background(), tt.tenantID, tt.budgetID)
+			assert.EqualError(t, err, "tenant_id and budget_id are required")
+		})
+	}
+}
+
+// TestReserveBudgetOverdraft checks that reservations may go past the hard
+// cap by up to the overdraft limit, recording and alerting on the overdraft
+func TestReserveBudgetOverdraft(t *testing.T) {
+	pool, queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tests := []struct {
+		name          string
+		overdraft     string
+		amount        string
+		wantErr       error
+		wantBalance   float64
+		wantOverdraft float64
+	}{
+		{"no overdraft past hard cap", "0", "30.00", ErrInsufficientFunds, 90, 0},
+		{"within hard cap", "50", "10.00", nil, 100, 0},
+		{"into overdraft", "50", "30.00", nil, 120, 20},
+		{"up to overdraft limit", "50", "60.00", nil, 150, 50},
+		{"past overdraft limit", "50", "60.01", ErrInsufficientFunds, 90, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenantID := createOverdraftTenant(t, queries)
+			budget := createOverdraftBudget(t, queries, tenantID, "100.00", "90.00", tt.overdraft)
+
+			notifier := &recordingNotifier{}
+			service := NewService(pool, queries, nil)
+			service.RegisterAlertNotifier(AlertChannelWebhook, notifier)
+
+			result, err := service.ReserveBudget(context.Background(), ReserveBudgetParams{
+				TenantID: tenantID,
+				BudgetID: budget.ID,
+				Amount:   tt.amount,
+				Currency: CurrencyUSD,
+				RefID:    pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			})
+			service.WaitForAlerts()
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.InDelta(t, tt.wantBalance, result.NewBalance, 0.001)
+			}
+
+			updated, err := queries.GetBudgetByID(context.Background(), db.GetBudgetByIDParams{
+				ID:       budget.ID,
+				TenantID: tenantID,
+			})
+			require.NoError(t, err)
+			assert.InDelta(t, tt.wantBalance, numericToFloat(updated.Balance), 0.001)
+			assert.InDelta(t, tt.wantOverdraft, overdraftDrawn(t, pool, budget.ID), 0.001)
+
+			alerts := notifier.overdraftAlerts()
+			if tt.wantOverdraft == 0 {
+				assert.Empty(t, alerts)
+				return
+			}
+			require.Len(t, alerts, 1)
+			assert.Equal(t, AlertLevelCritical, alerts[0].Level)
+			assert.InDelta(t, 50, alerts[0].OverdraftLimit, 0.001)
+		})
+	}
+}
+
+// TestOverdraftAlertState checks that an overdraft alert is sent once while
+// the budget stays in overdraft and resolved once it is back within its cap
+func TestOverdraftAlertState(t *testing.T) {
+	pool, queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	tenantID := createOverdraftTenant(t, queries)
+	budget := createOverdraftBudget(t, queries, tenantID, "100.00", "120.00", "50")
+
+	notifier := &recordingNotifier{}
+	service := NewService(pool, queries, nil)
+	service.RegisterAlertNotifier(AlertChannelWebhook, notifier)
+
+	require.NoError(t, service.CheckOverdraftAlert(ctx, tenantID, budget.ID))
+	require.NoError(t, service.CheckOverdraftAlert(ctx, tenantID, budget.ID))
+	assert.Len(t, notifier.overdraftAlerts(), 1, "Expected the repeat to be suppressed")
+
+	_, err := pool.Exec(ctx, "UPDATE budgets SET balance = 90 WHERE id = $1", budget.ID)
+	require.NoError(t, err)
+	require.NoError(t, service.CheckOverdraftAlert(ctx, tenantID, budget.ID))
+
+	resolved := notifier.alertsOfType(AlertTypeOverdraftResolved)
+	require.Len(t, resolved, 1)
+	assert.Equal(t, AlertLevelInfo, resolved[0].Level)
+
+	// Back in overdraft the alert is sent again straight away
+	_, err = pool.Exec(ctx, "UPDATE budgets SET balance = 110 WHERE id = $1", budget.ID)
+	require.NoError(t, err)
+	require.NoError(t, service.CheckOverdraftAlert(ctx, tenantID, budget.ID))
+	assert.Len(t, notifier.overdraftAlerts(), 2)
+}
+
+// TestHardCapReachedAlertState checks that rejected reservations alert once
+// per repeat interval, and that a budget without a hard cap reports no
+// utilization
+func TestHardCapReachedAlertState(t *testing.T) {
+	pool, queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	tenantID := createOverdraftTenant(t, queries)
+
+	notifier := &recordingNotifier{}
+	service := NewService(pool, queries, nil)
+	service.RegisterAlertNotifier(AlertChannelWebhook, notifier)
+
+	budget := createOverdraftBudget(t, queries, tenantID, "100.00", "98.00", "0")
+	require.NoError(t, service.TriggerHardCapReachedAlert(ctx, tenantID, budget.ID, 5))
+	require.NoError(t, service.TriggerHardCapReachedAlert(ctx, tenantID, budget.ID, 5))
+	require.Len(t, notifier.alertsOfType(AlertTypeHardCapReached), 1, "Expected the repeat to be suppressed")
+
+	uncapped := createOverdraftBudget(t, queries, tenantID, "0", "0", "0")
+	require.NoError(t, service.TriggerHardCapReachedAlert(ctx, tenantID, uncapped.ID, 5))
+	alerts := notifier.alertsOfType(AlertTypeHardCapReached)
+	require.Len(t, alerts, 2)
+	assert.Zero(t, alerts[1].Utilization)
+}
+
+// TestReserveCampaignBudgetOverdraft checks that a campaign only overdraws
+// its budgets once every one of them is at its hard cap
+func TestReserveCampaignBudgetOverdraft(t *testing.T) {
+	pool, queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name            string
+		fallbackBalance string
+		wantFallback    bool
+		wantNone        bool
+		wantOverdraft   float64
+	}{
+		{"fallback has room", "0.00", true, false, 0},
+		{"fallback full", "100.00", false, false, 20},
+		{"fallback full past overdraft", "100.00", false, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenantID := createOverdraftTenant(t, queries)
+			primaryOverdraft := "50"
+			if tt.wantNone {
+				primaryOverdraft = "10"
+			}
+			primary := createOverdraftBudget(t, queries, tenantID, "100.00", "90.00", primaryOverdraft)
+			fallback := createOverdraftBudget(t, queries, tenantID, "100.00", tt.fallbackBalance, "0")
+
+			campaign, err := queries.CreateCampaign(ctx, db.CreateCampaignParams{
+				TenantID: tenantID,
+				Name:     "Overdraft Campaign",
+				StartAt:  pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true},
+				BudgetID: primary.ID,
+				Status:   "active",
+			})
+			require.NoError(t, err)
+			require.NoError(t, queries.AddCampaignFallbackBudget(ctx, db.AddCampaignFallbackBudgetParams{
+				TenantID:   tenantID,
+				CampaignID: campaign.ID,
+				BudgetID:   fallback.ID,
+				Position:   1,
+			}))
+
+			var reservedFrom pgtype.UUID
+			err = pool.QueryRow(ctx, "SELECT reserve_campaign_budget($1, $2, $3, $4, $5)",
+				tenantID, campaign.ID, testNumeric(t, "30.00"), CurrencyUSD,
+				pgtype.UUID{Bytes: uuid.New(), Valid: true}).Scan(&reservedFrom)
+			require.NoError(t, err)
+
+			switch {
+			case tt.wantNone:
+				assert.False(t, reservedFrom.Valid)
+			case tt.wantFallback:
+				assert.Equal(t, fallback.ID, reservedFrom)
+			default:
+				assert.Equal(t, primary.ID, reservedFrom)
+			}
+			assert.InDelta(t, tt.wantOverdraft, overdraftDrawn(t, pool, primary.ID), 0.001)
+			assert.Zero(t, overdraftDrawn(t, pool, fallback.ID))
+		})
+	}
+}
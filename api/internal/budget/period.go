@@ -8,6 +8,7 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/enums"
 )
 
 // PeriodType represents the budget period type
@@ -75,7 +76,7 @@ func (s *Service) ResetBudget(ctx context.Context, tenantID, budgetID pgtype.UUI
 		_, err = qtx.InsertLedgerEntry(ctx, db.InsertLedgerEntryParams{
 			TenantID:  tenantID,
 			BudgetID:  budgetID,
-			EntryType: "release",
+			EntryType: string(enums.LedgerRelease),
 			Currency:  budget.Currency,
 			Amount:    releaseAmount,
 			RefType:   pgtype.Text{String: "period_reset", Valid: true},
@@ -9,6 +9,7 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/enums"
 )
 
 // ReconciliationResult contains the result of a budget reconciliation
@@ -23,6 +24,7 @@ type ReconciliationResult struct {
 	TotalReserved      float64
 	TotalCharged       float64
 	TotalReleased      float64
+	TotalCommitted     float64
 	ExpectedReserved   float64
 }
 
@@ -82,7 +84,7 @@ func (s *Service) ReconcileBudget(ctx context.Context, tenantID, budgetID pgtype
 	}
 
 	// Calculate totals by entry type
-	var totalFunded, totalReserved, totalCharged, totalReleased float64
+	var totalFunded, totalReserved, totalCharged, totalReleased, totalCommitted float64
 	for _, entry := range entries {
 		amountVal, err := entry.Amount.Float64Value()
 		if err != nil {
@@ -90,22 +92,25 @@ func (s *Service) ReconcileBudget(ctx context.Context, tenantID, budgetID pgtype
 		}
 		amount := amountVal.Float64
 
-		switch entry.EntryType {
-		case "fund":
+		switch enums.LedgerEntryType(entry.EntryType) {
+		case enums.LedgerFund:
 			totalFunded += amount
-		case "reserve":
+		case enums.LedgerReserve:
 			totalReserved += amount
-		case "charge":
+		case enums.LedgerCharge:
 			totalCharged += amount
-		case "release":
+		case enums.LedgerRelease:
 			// Release entries are stored as negative amounts
 			totalReleased += -amount
+		case enums.LedgerCommit:
+			// Commitments are ring-fenced until drawn down or released
+			totalCommitted += amount
 		}
 	}
 
-	// Expected reserved = funded + reserved - released
+	// Expected reserved = funded + reserved - released + committed
 	// (charged entries don't affect balance, just record the charge)
-	expectedReserved := totalFunded + totalReserved + totalReleased // totalReleased is already negative
+	expectedReserved := totalFunded + totalReserved + totalReleased + totalCommitted // totalReleased is already negative
 
 	result := &ReconciliationResult{
 		BudgetID:           budgetID,
@@ -118,6 +123,7 @@ func (s *Service) ReconcileBudget(ctx context.Context, tenantID, budgetID pgtype
 		TotalReserved:      totalReserved,
 		TotalCharged:       totalCharged,
 		TotalReleased:      totalReleased,
+		TotalCommitted:     totalCommitted,
 		ExpectedReserved:   expectedReserved,
 	}
 
@@ -232,7 +238,7 @@ func (s *Service) FixDiscrepancy(ctx context.Context, tenantID, budgetID pgtype.
 	_, err = qtx.InsertLedgerEntry(ctx, db.InsertLedgerEntryParams{
 		TenantID:  tenantID,
 		BudgetID:  budgetID,
-		EntryType: "reverse",
+		EntryType: string(enums.LedgerReverse),
 		Currency:  budget.Currency,
 		Amount:    discrepancyNumeric,
 		RefType:   pgtype.Text{String: "reconciliation", Valid: true},
@@ -12,6 +12,7 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/enums"
 )
 
 // BudgetReport contains comprehensive budget statistics
@@ -28,6 +29,7 @@ type BudgetReport struct {
 	TotalReserved   float64                `json:"total_reserved"`
 	TotalCharged    float64                `json:"total_charged"`
 	TotalReleased   float64                `json:"total_released"`
+	TotalCommitted  float64                `json:"total_committed"`
 	NetCharged      float64                `json:"net_charged"`
 	Available       float64                `json:"available"`
 	EntryCount      map[string]int64       `json:"entry_count"`
@@ -131,7 +133,7 @@ func (s *Service) GenerateBudgetReport(ctx context.Context, tenantID, budgetID p
 	}
 
 	entryCount := make(map[string]int64)
-	var totalFunded, totalReserved, totalCharged, totalReleased float64
+	var totalFunded, totalReserved, totalCharged, totalReleased, totalCommitted float64
 
 	for _, row := range summaryRows {
 		// Convert amount to float64
@@ -148,15 +150,17 @@ func (s *Service) GenerateBudgetReport(ctx context.Context, tenantID, budgetID p
 		entryCount[row.EntryType] = row.EntryCount
 
 		// Accumulate totals
-		switch row.EntryType {
-		case "fund":
+		switch enums.LedgerEntryType(row.EntryType) {
+		case enums.LedgerFund:
 			totalFunded += amount
-		case "reserve":
+		case enums.LedgerReserve:
 			totalReserved += amount
-		case "charge":
+		case enums.LedgerCharge:
 			totalCharged += amount
-		case "release":
+		case enums.LedgerRelease:
 			totalReleased += -amount // Release amounts are negative
+		case enums.LedgerCommit:
+			totalCommitted += amount // Outstanding commitments
 		}
 	}
 
@@ -179,6 +183,7 @@ func (s *Service) GenerateBudgetReport(ctx context.Context, tenantID, budgetID p
 		TotalReserved:  totalReserved,
 		TotalCharged:   totalCharged,
 		TotalReleased:  totalReleased,
+		TotalCommitted: totalCommitted,
 		NetCharged:     netCharged,
 		Available:      available,
 		EntryCount:     entryCount,
@@ -382,14 +387,15 @@ func (p GetLedgerEntriesParams) Validate() error {
 	return nil
 }
 
-// NewDateRange creates a date range for common periods
-func NewDateRange(period string) DateRange {
-	now := time.Now()
+// NewDateRange creates a date range for common periods. Calendar periods
+// (today, month, year) start at midnight in loc, the tenant's time zone.
+func NewDateRange(period string, loc *time.Location) DateRange {
+	now := time.Now().In(loc)
 	var from, to time.Time
 
 	switch period {
 	case "today":
-		from = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		from = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
 		to = now
 
 	case "week":
@@ -397,7 +403,7 @@ func NewDateRange(period string) DateRange {
 		to = now
 
 	case "month":
-		from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
 		to = now
 
 	case "quarter":
@@ -405,12 +411,12 @@ func NewDateRange(period string) DateRange {
 		to = now
 
 	case "year":
-		from = time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+		from = time.Date(now.Year(), 1, 1, 0, 0, 0, 0, loc)
 		to = now
 
 	default:
 		// Default to current month
-		from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
 		to = now
 	}
 
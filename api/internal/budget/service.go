@@ -5,16 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/enums"
+	"github.com/bmachimbira/loyalty/api/internal/logging"
 )
 
 var (
-	// ErrInsufficientFunds is returned when a reservation would exceed the hard cap
+	// ErrInsufficientFunds is returned when a reservation would exceed the
+	// hard cap plus the budget's overdraft limit
 	ErrInsufficientFunds = errors.New("insufficient budget funds")
 
 	// ErrBudgetNotFound is returned when a budget doesn't exist
@@ -35,9 +39,11 @@ var (
 
 // Service handles budget operations
 type Service struct {
-	queries *db.Queries
-	pool    DBTX
-	logger  *slog.Logger
+	queries   *db.Queries
+	pool      DBTX
+	logger    *slog.Logger
+	notifiers map[AlertChannel]AlertNotifier
+	alerts    sync.WaitGroup // in-flight alert checks
 }
 
 // DBTX interface for database operations (matches sqlc's interface)
@@ -54,12 +60,18 @@ func NewService(pool DBTX, queries *db.Queries, logger *slog.Logger) *Service {
 		logger = slog.Default()
 	}
 	return &Service{
-		queries: queries,
-		pool:    pool,
-		logger:  logger,
+		queries:   queries,
+		pool:      pool,
+		logger:    logger,
+		notifiers: make(map[AlertChannel]AlertNotifier),
 	}
 }
 
+// log returns the request-scoped logger carried by ctx, or the service logger
+func (s *Service) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
 // ReserveBudget reserves an amount from a budget for a future charge
 // This is called when a reward is issued (reserved state)
 func (s *Service) ReserveBudget(ctx context.Context, params ReserveBudgetParams) (*ReservationResult, error) {
@@ -144,18 +156,10 @@ func (s *Service) ReserveBudget(ctx context.Context, params ReserveBudgetParams)
 	}
 	hardCap := hardCapVal.Float64
 
+	utilization := (balance / hardCap) * 100
+
 	if balance > softCap {
 		softCapExceeded = true
-		// Trigger soft cap alert (non-blocking)
-		go func() {
-			alertCtx := context.Background()
-			if err := s.CheckSoftCapAlert(alertCtx, params.TenantID, params.BudgetID); err != nil {
-				s.logger.Error("failed to trigger soft cap alert",
-					"error", err,
-					"budget_id", params.BudgetID,
-					"tenant_id", params.TenantID)
-			}
-		}()
 	}
 
 	// Commit transaction
@@ -163,6 +167,9 @@ func (s *Service) ReserveBudget(ctx context.Context, params ReserveBudgetParams)
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	// Alerts read the committed balance, so are checked after commit
+	s.AlertReservation(params.TenantID, params.BudgetID)
+
 	result := &ReservationResult{
 		ReservationID:   params.RefID,
 		BudgetID:        params.BudgetID,
@@ -170,10 +177,10 @@ func (s *Service) ReserveBudget(ctx context.Context, params ReserveBudgetParams)
 		Currency:        params.Currency,
 		NewBalance:      balance,
 		SoftCapExceeded: softCapExceeded,
-		Utilization:     (balance / hardCap) * 100,
+		Utilization:     utilization,
 	}
 
-	s.logger.Info("budget reserved",
+	s.log(ctx).Info("budget reserved",
 		"budget_id", params.BudgetID,
 		"amount", params.Amount,
 		"currency", params.Currency,
@@ -212,7 +219,7 @@ func (s *Service) ChargeReservation(ctx context.Context, params ChargeReservatio
 
 	// Check if already charged
 	for _, entry := range entries {
-		if entry.EntryType == "charge" {
+		if enums.LedgerEntryType(entry.EntryType) == enums.LedgerCharge {
 			return ErrAlreadyCharged
 		}
 	}
@@ -241,7 +248,7 @@ func (s *Service) ChargeReservation(ctx context.Context, params ChargeReservatio
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	s.logger.Info("budget charged",
+	s.log(ctx).Info("budget charged",
 		"budget_id", params.BudgetID,
 		"amount", params.Amount,
 		"currency", params.Currency,
@@ -282,10 +289,10 @@ func (s *Service) ReleaseReservation(ctx context.Context, params ReleaseReservat
 
 	// Check if already released or charged
 	for _, entry := range entries {
-		if entry.EntryType == "release" {
+		if enums.LedgerEntryType(entry.EntryType) == enums.LedgerRelease {
 			return errors.New("reservation already released")
 		}
-		if entry.EntryType == "charge" {
+		if enums.LedgerEntryType(entry.EntryType) == enums.LedgerCharge {
 			return errors.New("cannot release charged reservation")
 		}
 	}
@@ -314,7 +321,7 @@ func (s *Service) ReleaseReservation(ctx context.Context, params ReleaseReservat
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	s.logger.Info("budget released",
+	s.log(ctx).Info("budget released",
 		"budget_id", params.BudgetID,
 		"amount", params.Amount,
 		"currency", params.Currency,
@@ -0,0 +1,133 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+)
+
+// Spend breakdown groupings
+const (
+	SpendByCampaign = "campaign"
+	SpendByRule     = "rule"
+	SpendByReward   = "reward"
+)
+
+// ErrInvalidSpendGroup is returned for an unknown spend breakdown grouping
+var ErrInvalidSpendGroup = errors.New("group_by must be one of campaign, rule, reward")
+
+// SpendLine is a budget's spend attributed to one campaign, rule or reward.
+// ID is nil for spend whose issuance, campaign or rule is unknown. Amounts
+// are decimal strings.
+type SpendLine struct {
+	ID            *string `json:"id"`
+	Name          string  `json:"name"`
+	Reserved      string  `json:"reserved"`
+	Released      string  `json:"released"`
+	Charged       string  `json:"charged"`
+	Reversed      string  `json:"reversed"`
+	Net           string  `json:"net"`
+	IssuanceCount int64   `json:"issuance_count"`
+}
+
+// SpendBreakdown is a budget's issuance spend grouped by campaign, rule or
+// reward. Net is what the group contributes to the budget balance:
+// reservations less releases and reversals.
+type SpendBreakdown struct {
+	BudgetID string      `json:"budget_id"`
+	Currency string      `json:"currency"`
+	GroupBy  string      `json:"group_by"`
+	From     *time.Time  `json:"from,omitempty"`
+	To       *time.Time  `json:"to,omitempty"`
+	Lines    []SpendLine `json:"lines"`
+	TotalNet string      `json:"total_net"`
+}
+
+// GetSpendBreakdown attributes a budget's ledger spend to the campaigns,
+// rules or rewards of the issuances it was spent on. from and to optionally
+// limit the ledger entries counted.
+func (s *Service) GetSpendBreakdown(ctx context.Context, tenantID, budgetID pgtype.UUID, groupBy string, from, to *time.Time) (*SpendBreakdown, error) {
+	budget, err := s.queries.GetBudgetByID(ctx, db.GetBudgetByIDParams{
+		ID:       budgetID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrBudgetNotFound
+		}
+		return nil, fmt.Errorf("failed to get budget: %w", err)
+	}
+
+	params := db.GetBudgetSpendByCampaignParams{
+		TenantID: tenantID,
+		BudgetID: budgetID,
+	}
+	if from != nil {
+		params.FromTime = pgtype.Timestamptz{Time: *from, Valid: true}
+	}
+	if to != nil {
+		params.ToTime = pgtype.Timestamptz{Time: *to, Valid: true}
+	}
+
+	// The three queries return identical rows, so they convert to one type
+	var rows []db.GetBudgetSpendByCampaignRow
+	switch groupBy {
+	case SpendByCampaign:
+		rows, err = s.queries.GetBudgetSpendByCampaign(ctx, params)
+	case SpendByRule:
+		var ruleRows []db.GetBudgetSpendByRuleRow
+		ruleRows, err = s.queries.GetBudgetSpendByRule(ctx, db.GetBudgetSpendByRuleParams(params))
+		for _, row := range ruleRows {
+			rows = append(rows, db.GetBudgetSpendByCampaignRow(row))
+		}
+	case SpendByReward:
+		var rewardRows []db.GetBudgetSpendByRewardRow
+		rewardRows, err = s.queries.GetBudgetSpendByReward(ctx, db.GetBudgetSpendByRewardParams(params))
+		for _, row := range rewardRows {
+			rows = append(rows, db.GetBudgetSpendByCampaignRow(row))
+		}
+	default:
+		return nil, ErrInvalidSpendGroup
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spend breakdown: %w", err)
+	}
+
+	breakdown := &SpendBreakdown{
+		BudgetID: httputil.FormatUUID(budget.ID.Bytes),
+		Currency: budget.Currency,
+		GroupBy:  groupBy,
+		From:     from,
+		To:       to,
+		Lines:    make([]SpendLine, len(rows)),
+	}
+	totalNet := zeroNumeric(-2)
+	for i, row := range rows {
+		line := SpendLine{
+			Name:          "Unattributed",
+			Reserved:      httputil.FormatNumeric(row.Reserved),
+			Released:      httputil.FormatNumeric(row.Released),
+			Charged:       httputil.FormatNumeric(row.Charged),
+			Reversed:      httputil.FormatNumeric(row.Reversed),
+			Net:           httputil.FormatNumeric(row.Net),
+			IssuanceCount: row.IssuanceCount,
+		}
+		if row.GroupID.Valid {
+			id := httputil.FormatUUID(row.GroupID.Bytes)
+			line.ID = &id
+			line.Name = row.GroupName.String
+		}
+		breakdown.Lines[i] = line
+		totalNet = addNumeric(totalNet, row.Net)
+	}
+	breakdown.TotalNet = httputil.FormatNumeric(totalNet)
+
+	return breakdown, nil
+}
@@ -0,0 +1,169 @@
+package budget
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+)
+
+func TestGetSpendBreakdown(t *testing.T) {
+	pool, queries, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	service := NewService(pool, queries, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	ctx := context.Background()
+
+	tenant, err := queries.CreateTenant(ctx, db.CreateTenantParams{
+		Name:        "Spend Breakdown",
+		CountryCode: "ZW",
+		DefaultCcy:  CurrencyUSD,
+		Theme:       []byte(`{}`),
+	})
+	require.NoError(t, err)
+	budget := createTestBudget(t, queries, tenant.ID, "1000.00", "1000.00", "0.00")
+
+	customer, err := queries.CreateCustomer(ctx, db.CreateCustomerParams{
+		TenantID:    tenant.ID,
+		ExternalRef: pgtype.Text{String: "spend-customer", Valid: true},
+	})
+	require.NoError(t, err)
+
+	reward, err := queries.CreateReward(ctx, db.CreateRewardParams{
+		TenantID:  tenant.ID,
+		Name:      "Voucher",
+		Type:      "discount",
+		FaceValue: testNumeric(t, "10"),
+		Currency:  pgtype.Text{String: CurrencyUSD, Valid: true},
+		Inventory: "none",
+		Metadata:  []byte(`{}`),
+		Active:    true,
+	})
+	require.NoError(t, err)
+
+	// campaignRule creates a campaign on the budget with one rule
+	campaignRule := func(campaignName, ruleName string) (pgtype.UUID, pgtype.UUID) {
+		campaign, err := queries.CreateCampaign(ctx, db.CreateCampaignParams{
+			TenantID: tenant.ID,
+			Name:     campaignName,
+			StartAt:  pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true},
+			BudgetID: budget.ID,
+			Status:   "active",
+		})
+		require.NoError(t, err)
+		rule, err := queries.CreateRule(ctx, db.CreateRuleParams{
+			TenantID:   tenant.ID,
+			CampaignID: campaign.ID,
+			Name:       ruleName,
+			EventType:  "purchase",
+			Conditions: []byte(`{}`),
+			RewardID:   reward.ID,
+			PerUserCap: 10,
+			Active:     true,
+			Quantity:   1,
+		})
+		require.NoError(t, err)
+		return campaign.ID, rule.ID
+	}
+	campaignA, ruleX := campaignRule("Campaign A", "Rule X")
+	campaignB, ruleY := campaignRule("Campaign B", "Rule Y")
+
+	// spend reserves amount for a new issuance of a campaign's rule, then
+	// charges or releases it
+	spend := func(campaignID, ruleID pgtype.UUID, amount, outcome string) {
+		refID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+		if campaignID.Valid {
+			err := pool.QueryRow(ctx, `
+				INSERT INTO issuances (tenant_id, customer_id, campaign_id, rule_id, reward_id, status, currency, cost_amount)
+				VALUES ($1, $2, $3, $4, $5, 'reserved', $6, $7)
+				RETURNING id
+			`, tenant.ID, customer.ID, campaignID, ruleID, reward.ID, CurrencyUSD, testNumeric(t, amount)).Scan(&refID)
+			require.NoError(t, err)
+		}
+
+		_, err := service.ReserveBudget(ctx, ReserveBudgetParams{
+			TenantID: tenant.ID, BudgetID: budget.ID, Amount: amount, Currency: CurrencyUSD, RefID: refID,
+		})
+		require.NoError(t, err)
+
+		switch outcome {
+		case "charge":
+			require.NoError(t, service.ChargeReservation(ctx, ChargeReservationParams{
+				TenantID: tenant.ID, BudgetID: budget.ID, Amount: amount, Currency: CurrencyUSD, RefID: refID,
+			}))
+		case "release":
+			require.NoError(t, service.ReleaseReservation(ctx, ReleaseReservationParams{
+				TenantID: tenant.ID, BudgetID: budget.ID, Amount: amount, Currency: CurrencyUSD, RefID: refID,
+			}))
+		}
+	}
+	spend(campaignA, ruleX, "10.00", "charge")
+	spend(campaignA, ruleX, "10.00", "release")
+	spend(campaignB, ruleY, "25.00", "")
+	// A reservation whose issuance is unknown
+	spend(pgtype.UUID{}, pgtype.UUID{}, "5.00", "")
+
+	unattributed := SpendLine{Name: "Unattributed", Reserved: "5.00", Released: "0.00", Charged: "0.00", Reversed: "0.00", Net: "5.00"}
+
+	tests := []struct {
+		groupBy string
+		want    []SpendLine
+	}{
+		{SpendByCampaign, []SpendLine{
+			{Name: "Campaign B", Reserved: "25.00", Released: "0.00", Charged: "0.00", Reversed: "0.00", Net: "25.00", IssuanceCount: 1},
+			{Name: "Campaign A", Reserved: "20.00", Released: "10.00", Charged: "10.00", Reversed: "0.00", Net: "10.00", IssuanceCount: 2},
+			unattributed,
+		}},
+		{SpendByRule, []SpendLine{
+			{Name: "Rule Y", Reserved: "25.00", Released: "0.00", Charged: "0.00", Reversed: "0.00", Net: "25.00", IssuanceCount: 1},
+			{Name: "Rule X", Reserved: "20.00", Released: "10.00", Charged: "10.00", Reversed: "0.00", Net: "10.00", IssuanceCount: 2},
+			unattributed,
+		}},
+		{SpendByReward, []SpendLine{
+			{Name: "Voucher", Reserved: "45.00", Released: "10.00", Charged: "10.00", Reversed: "0.00", Net: "35.00", IssuanceCount: 3},
+			unattributed,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.groupBy, func(t *testing.T) {
+			breakdown, err := service.GetSpendBreakdown(ctx, tenant.ID, budget.ID, tt.groupBy, nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, CurrencyUSD, breakdown.Currency)
+			assert.Equal(t, "40.00", breakdown.TotalNet)
+
+			require.Len(t, breakdown.Lines, len(tt.want))
+			for i, line := range breakdown.Lines {
+				assert.Equal(t, tt.want[i].Name != "Unattributed", line.ID != nil, "line %d id", i)
+				line.ID = nil
+				assert.Equal(t, tt.want[i], line)
+			}
+		})
+	}
+
+	t.Run("outside window", func(t *testing.T) {
+		from := time.Now().Add(time.Hour)
+		breakdown, err := service.GetSpendBreakdown(ctx, tenant.ID, budget.ID, SpendByCampaign, &from, nil)
+		require.NoError(t, err)
+		assert.Empty(t, breakdown.Lines)
+		assert.Equal(t, "0.00", breakdown.TotalNet)
+	})
+
+	t.Run("unknown grouping", func(t *testing.T) {
+		_, err := service.GetSpendBreakdown(ctx, tenant.ID, budget.ID, "customer", nil, nil)
+		assert.ErrorIs(t, err, ErrInvalidSpendGroup)
+	})
+
+	t.Run("unknown budget", func(t *testing.T) {
+		_, err := service.GetSpendBreakdown(ctx, tenant.ID, pgtype.UUID{Bytes: uuid.New(), Valid: true}, SpendByCampaign, nil, nil)
+		assert.ErrorIs(t, err, ErrBudgetNotFound)
+	})
+}
@@ -0,0 +1,436 @@
+package budget
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/enums"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/timezone"
+)
+
+var (
+	// ErrStatementNotFound is returned when a budget statement does not exist
+	ErrStatementNotFound = errors.New("budget statement not found")
+
+	// ErrInvalidPeriod is returned when a period end is not after the open
+	// period's start or lies in the future
+	ErrInvalidPeriod = errors.New("invalid period end")
+
+	// ErrPeriodAlreadyClosed is returned when another close won the race for the period
+	ErrPeriodAlreadyClosed = errors.New("budget period already closed")
+)
+
+// discrepancyTolerance ignores rounding differences below a cent
+const discrepancyTolerance = 0.005
+
+// Statement is the immutable record of a closed budget period
+type Statement struct {
+	ID                 string                 `json:"id"`
+	TenantID           string                 `json:"tenant_id"`
+	BudgetID           string                 `json:"budget_id"`
+	Currency           string                 `json:"currency"`
+	PeriodStart        time.Time              `json:"period_start"`
+	PeriodEnd          time.Time              `json:"period_end"`
+	OpeningBalance     string                 `json:"opening_balance"`
+	ClosingBalance     string                 `json:"closing_balance"`
+	Totals             map[string]TypeSummary `json:"totals"`
+	EntryCount         int64                  `json:"entry_count"`
+	LedgerDiscrepancy  string                 `json:"ledger_discrepancy"`
+	BalanceDiscrepancy string                 `json:"balance_discrepancy"`
+	HasDiscrepancy     bool                   `json:"has_discrepancy"`
+	ClosedBy           string                 `json:"closed_by,omitempty"`
+	CreatedAt          time.Time              `json:"created_at"`
+}
+
+// ClosePeriodParams contains the fields needed to close a budget period
+type ClosePeriodParams struct {
+	TenantID  pgtype.UUID
+	BudgetID  pgtype.UUID
+	PeriodEnd time.Time
+	// ClosedBy is the staff user closing the period; unset for scheduled closes
+	ClosedBy pgtype.UUID
+}
+
+// Validate validates the close period parameters
+func (p ClosePeriodParams) Validate() error {
+	if !p.TenantID.Valid || !p.BudgetID.Valid {
+		return errors.New("tenant_id and budget_id are required")
+	}
+	if p.PeriodEnd.IsZero() {
+		return ErrInvalidPeriod
+	}
+	return nil
+}
+
+// NextPeriodEnd returns the end of the budget period that contains from.
+// Periods are calendar months, quarters and years in loc, the tenant's time
+// zone. Rolling budgets have no period end.
+func NextPeriodEnd(period PeriodType, from time.Time, loc *time.Location) (time.Time, bool) {
+	from = from.In(loc)
+	switch period {
+	case PeriodMonthly:
+		return time.Date(from.Year(), from.Month()+1, 1, 0, 0, 0, 0, loc), true
+	case PeriodQuarterly:
+		quarterStart := time.Month((int(from.Month())-1)/3*3 + 1)
+		return time.Date(from.Year(), quarterStart+3, 1, 0, 0, 0, 0, loc), true
+	case PeriodYearly:
+		return time.Date(from.Year()+1, time.January, 1, 0, 0, 0, 0, loc), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// ClosePeriod closes the budget's open period at PeriodEnd and stores an
+// immutable statement. The open period starts where the previous statement
+// ended, or when the budget was created. Once closed, ledger entries in the
+// period can no longer be changed.
+func (s *Service) ClosePeriod(ctx context.Context, params ClosePeriodParams) (*Statement, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Scheduled closes run outside a tenant request
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(params.TenantID.Bytes)); err != nil {
+		return nil, fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	qtx := s.queries.WithTx(tx)
+
+	budget, err := qtx.GetBudgetByID(ctx, db.GetBudgetByIDParams{
+		ID:       params.BudgetID,
+		TenantID: params.TenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrBudgetNotFound
+		}
+		return nil, fmt.Errorf("failed to get budget: %w", err)
+	}
+
+	periodStart := budget.CreatedAt.Time
+	var opening float64
+	latest, err := qtx.GetLatestBudgetStatement(ctx, db.GetLatestBudgetStatementParams{
+		TenantID: params.TenantID,
+		BudgetID: params.BudgetID,
+	})
+	switch {
+	case err == nil:
+		periodStart = latest.PeriodEnd.Time
+		opening = numericToFloat(latest.ClosingBalance)
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, fmt.Errorf("failed to get latest statement: %w", err)
+	}
+
+	periodEnd := params.PeriodEnd
+	if !periodEnd.After(periodStart) || periodEnd.After(time.Now()) {
+		return nil, ErrInvalidPeriod
+	}
+
+	rows, err := qtx.GetLedgerTotalsForPeriod(ctx, db.GetLedgerTotalsForPeriodParams{
+		TenantID:    params.TenantID,
+		BudgetID:    params.BudgetID,
+		PeriodStart: pgtype.Timestamptz{Time: periodStart, Valid: true},
+		PeriodEnd:   pgtype.Timestamptz{Time: periodEnd, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger totals: %w", err)
+	}
+
+	totals := make(map[string]TypeSummary, len(rows))
+	var entryCount int64
+	closing := opening
+	for _, row := range rows {
+		amount := numericToFloat(row.TotalAmount)
+		totals[row.EntryType] = TypeSummary{Count: row.EntryCount, Amount: amount}
+		entryCount += row.EntryCount
+		// Same movements reconcile_budget counts towards the balance
+		if enums.LedgerEntryType(row.EntryType).CountsTowardsBalance() {
+			closing += amount
+		}
+	}
+
+	ledgerAtEnd, err := qtx.GetLedgerBalanceAt(ctx, db.GetLedgerBalanceAtParams{
+		TenantID: params.TenantID,
+		BudgetID: params.BudgetID,
+		Before:   pgtype.Timestamptz{Time: periodEnd, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger balance: %w", err)
+	}
+	ledgerNow, err := qtx.GetLedgerBalanceAt(ctx, db.GetLedgerBalanceAtParams{
+		TenantID: params.TenantID,
+		BudgetID: params.BudgetID,
+		Before:   pgtype.Timestamptz{InfinityModifier: pgtype.Infinity, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger balance: %w", err)
+	}
+
+	ledgerDiscrepancy := closing - numericToFloat(ledgerAtEnd)
+	balanceDiscrepancy := numericToFloat(budget.Balance) - numericToFloat(ledgerNow)
+
+	totalsJSON, err := json.Marshal(totals)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal totals: %w", err)
+	}
+
+	var numbers [4]pgtype.Numeric
+	for i, v := range []float64{opening, closing, ledgerDiscrepancy, balanceDiscrepancy} {
+		if err := numbers[i].Scan(fmt.Sprintf("%.2f", v)); err != nil {
+			return nil, fmt.Errorf("failed to convert amount: %w", err)
+		}
+	}
+
+	created, err := qtx.CreateBudgetStatement(ctx, db.CreateBudgetStatementParams{
+		TenantID:           params.TenantID,
+		BudgetID:           params.BudgetID,
+		Currency:           budget.Currency,
+		PeriodStart:        pgtype.Timestamptz{Time: periodStart, Valid: true},
+		PeriodEnd:          pgtype.Timestamptz{Time: periodEnd, Valid: true},
+		OpeningBalance:     numbers[0],
+		ClosingBalance:     numbers[1],
+		Totals:             totalsJSON,
+		EntryCount:         entryCount,
+		LedgerDiscrepancy:  numbers[2],
+		BalanceDiscrepancy: numbers[3],
+		ClosedBy:           params.ClosedBy,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrPeriodAlreadyClosed
+		}
+		return nil, fmt.Errorf("failed to create statement: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	statement := newStatement(created)
+
+	s.logger.Info("budget period closed",
+		"budget_id", params.BudgetID,
+		"period_start", periodStart,
+		"period_end", periodEnd,
+		"closing_balance", statement.ClosingBalance,
+		"has_discrepancy", statement.HasDiscrepancy)
+
+	return statement, nil
+}
+
+// CloseDuePeriods closes every elapsed period of monthly, quarterly and
+// yearly budgets across all tenants, catching up on missed periods.
+// It returns the number of statements created.
+func (s *Service) CloseDuePeriods(ctx context.Context, now time.Time) (int, error) {
+	budgets, err := s.queries.ListPeriodicBudgets(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list periodic budgets: %w", err)
+	}
+
+	zones := make(map[pgtype.UUID]*time.Location)
+	closed := 0
+	for _, b := range budgets {
+		loc, ok := zones[b.TenantID]
+		if !ok {
+			name, err := s.queries.GetTenantTimezone(ctx, b.TenantID)
+			if err != nil {
+				s.logger.Error("failed to get tenant timezone", "tenant_id", b.TenantID, "error", err)
+				continue
+			}
+			loc = timezone.Load(name)
+			zones[b.TenantID] = loc
+		}
+
+		start := b.CreatedAt.Time
+		latest, err := s.queries.GetLatestBudgetStatement(ctx, db.GetLatestBudgetStatementParams{
+			TenantID: b.TenantID,
+			BudgetID: b.ID,
+		})
+		if err == nil {
+			start = latest.PeriodEnd.Time
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Error("failed to get latest statement", "budget_id", b.ID, "error", err)
+			continue
+		}
+
+		for {
+			end, ok := NextPeriodEnd(PeriodType(b.Period), start, loc)
+			if !ok || end.After(now) {
+				break
+			}
+			if _, err := s.ClosePeriod(ctx, ClosePeriodParams{
+				TenantID:  b.TenantID,
+				BudgetID:  b.ID,
+				PeriodEnd: end,
+			}); err != nil && !errors.Is(err, ErrPeriodAlreadyClosed) {
+				s.logger.Error("failed to close budget period",
+					"budget_id", b.ID,
+					"period_end", end,
+					"error", err)
+				break
+			}
+			closed++
+			start = end
+		}
+	}
+
+	return closed, nil
+}
+
+// RunPeriodCloseWorker closes due budget periods on a schedule until ctx is cancelled
+func (s *Service) RunPeriodCloseWorker(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.CloseDuePeriods(ctx, time.Now()); err != nil {
+			s.logger.Error("budget period close failed", "error", err)
+		} else if n > 0 {
+			s.logger.Info("closed budget periods", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetStatement retrieves a budget statement
+func (s *Service) GetStatement(ctx context.Context, tenantID, budgetID, statementID pgtype.UUID) (*Statement, error) {
+	row, err := s.queries.GetBudgetStatement(ctx, db.GetBudgetStatementParams{
+		ID:       statementID,
+		TenantID: tenantID,
+		BudgetID: budgetID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrStatementNotFound
+		}
+		return nil, fmt.Errorf("failed to get statement: %w", err)
+	}
+	return newStatement(row), nil
+}
+
+// ListStatements lists a budget's statements, most recent period first
+func (s *Service) ListStatements(ctx context.Context, tenantID, budgetID pgtype.UUID, limit, offset int32) ([]Statement, int64, error) {
+	rows, err := s.queries.ListBudgetStatements(ctx, db.ListBudgetStatementsParams{
+		TenantID: tenantID,
+		BudgetID: budgetID,
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list statements: %w", err)
+	}
+
+	total, err := s.queries.CountBudgetStatements(ctx, db.CountBudgetStatementsParams{
+		TenantID: tenantID,
+		BudgetID: budgetID,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count statements: %w", err)
+	}
+
+	statements := make([]Statement, len(rows))
+	for i, row := range rows {
+		statements[i] = *newStatement(row)
+	}
+	return statements, total, nil
+}
+
+// ExportStatementCSV writes a statement as CSV with one row per entry type
+func (s *Service) ExportStatementCSV(statement *Statement, w io.Writer) error {
+	csvWriter := csv.NewWriter(w)
+	defer csvWriter.Flush()
+
+	summary := [][]string{
+		{"Statement ID", statement.ID},
+		{"Budget ID", statement.BudgetID},
+		{"Currency", statement.Currency},
+		{"Period Start", statement.PeriodStart.Format(time.RFC3339)},
+		{"Period End", statement.PeriodEnd.Format(time.RFC3339)},
+		{"Opening Balance", statement.OpeningBalance},
+		{"Closing Balance", statement.ClosingBalance},
+		{"Ledger Discrepancy", statement.LedgerDiscrepancy},
+		{"Balance Discrepancy", statement.BalanceDiscrepancy},
+		{},
+		{"Entry Type", "Count", "Amount"},
+	}
+	if err := csvWriter.WriteAll(summary); err != nil {
+		return err
+	}
+
+	types := make([]string, 0, len(statement.Totals))
+	for t := range statement.Totals {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	for _, t := range types {
+		total := statement.Totals[t]
+		if err := csvWriter.Write([]string{t, fmt.Sprintf("%d", total.Count), fmt.Sprintf("%.2f", total.Amount)}); err != nil {
+			return err
+		}
+	}
+
+	return csvWriter.Error()
+}
+
+// newStatement converts a stored statement row
+func newStatement(row db.BudgetStatement) *Statement {
+	totals := map[string]TypeSummary{}
+	json.Unmarshal(row.Totals, &totals)
+
+	statement := &Statement{
+		ID:                 httputil.FormatUUID(row.ID.Bytes),
+		TenantID:           httputil.FormatUUID(row.TenantID.Bytes),
+		BudgetID:           httputil.FormatUUID(row.BudgetID.Bytes),
+		Currency:           row.Currency,
+		PeriodStart:        row.PeriodStart.Time,
+		PeriodEnd:          row.PeriodEnd.Time,
+		OpeningBalance:     httputil.FormatNumeric(row.OpeningBalance),
+		ClosingBalance:     httputil.FormatNumeric(row.ClosingBalance),
+		Totals:             totals,
+		EntryCount:         row.EntryCount,
+		LedgerDiscrepancy:  httputil.FormatNumeric(row.LedgerDiscrepancy),
+		BalanceDiscrepancy: httputil.FormatNumeric(row.BalanceDiscrepancy),
+		CreatedAt:          row.CreatedAt.Time,
+	}
+	statement.HasDiscrepancy = math.Abs(numericToFloat(row.LedgerDiscrepancy)) > discrepancyTolerance ||
+		math.Abs(numericToFloat(row.BalanceDiscrepancy)) > discrepancyTolerance
+	if row.ClosedBy.Valid {
+		statement.ClosedBy = httputil.FormatUUID(row.ClosedBy.Bytes)
+	}
+	return statement
+}
+
+// numericToFloat converts a numeric value, treating invalid values as zero
+func numericToFloat(n pgtype.Numeric) float64 {
+	f, err := n.Float64Value()
+	if err != nil {
+		return 0
+	}
+	return f.Float64
+}
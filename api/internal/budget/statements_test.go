@@ -0,0 +1,67 @@
+package budget
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+)
+
+func TestNextPeriodEnd(t *testing.T) {
+	from := time.Date(2025, time.November, 15, 10, 30, 0, 0, time.UTC)
+
+	end, ok := NextPeriodEnd(PeriodMonthly, from, time.UTC)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC), end)
+
+	end, ok = NextPeriodEnd(PeriodQuarterly, from, time.UTC)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC), end)
+
+	end, _ = NextPeriodEnd(PeriodQuarterly, time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC), time.UTC)
+	assert.Equal(t, time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC), end)
+
+	// A period starting exactly on a boundary ends at the next one
+	end, _ = NextPeriodEnd(PeriodYearly, time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), time.UTC)
+	assert.Equal(t, time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC), end)
+
+	_, ok = NextPeriodEnd(PeriodRolling, from, time.UTC)
+	assert.False(t, ok)
+
+	// Months end at midnight in the tenant's time zone
+	harare := time.FixedZone("CAT", 2*60*60)
+	end, _ = NextPeriodEnd(PeriodMonthly, time.Date(2025, time.November, 30, 23, 0, 0, 0, time.UTC), harare)
+	assert.True(t, time.Date(2025, time.December, 31, 22, 0, 0, 0, time.UTC).Equal(end))
+}
+
+func TestNewStatementAmounts(t *testing.T) {
+	tests := []struct {
+		name            string
+		ledger          string
+		balance         string
+		wantDiscrepancy bool
+	}{
+		{"balanced", "0.00", "0.00", false},
+		{"within tolerance", "0.00", "0.004", false},
+		{"ledger discrepancy", "-12.50", "0.00", true},
+		{"balance discrepancy", "0.00", "3.10", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statement := newStatement(db.BudgetStatement{
+				OpeningBalance:     testNumeric(t, "1000.00"),
+				ClosingBalance:     testNumeric(t, "987.50"),
+				LedgerDiscrepancy:  testNumeric(t, tt.ledger),
+				BalanceDiscrepancy: testNumeric(t, tt.balance),
+			})
+			assert.Equal(t, "1000.00", statement.OpeningBalance)
+			assert.Equal(t, "987.50", statement.ClosingBalance)
+			assert.Equal(t, tt.ledger, statement.LedgerDiscrepancy)
+			assert.Equal(t, tt.balance, statement.BalanceDiscrepancy)
+			assert.Equal(t, tt.wantDiscrepancy, statement.HasDiscrepancy)
+		})
+	}
+}
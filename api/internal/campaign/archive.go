@@ -0,0 +1,72 @@
+package campaign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	// ErrCampaignActive is returned when archiving a campaign that is still
+	// active
+	ErrCampaignActive = errors.New("campaign is active; pause or complete it before archiving")
+
+	// ErrCampaignArchived is returned when changing an archived campaign
+	ErrCampaignArchived = errors.New("campaign is archived")
+)
+
+// ArchiveCampaign hides a campaign from campaign lists. Active campaigns must
+// be paused or completed first. Archiving an archived campaign is a no-op.
+func (s *Service) ArchiveCampaign(ctx context.Context, id, tenantID pgtype.UUID) (db.Campaign, error) {
+	campaign, err := s.getCampaign(ctx, id, tenantID)
+	if err != nil {
+		return db.Campaign{}, err
+	}
+	if campaign.ArchivedAt.Valid {
+		return campaign, nil
+	}
+	if campaign.Status == "active" {
+		return db.Campaign{}, ErrCampaignActive
+	}
+
+	if _, err := s.queries.ArchiveCampaign(ctx, db.ArchiveCampaignParams{
+		ID:       id,
+		TenantID: tenantID,
+	}); err != nil {
+		return db.Campaign{}, fmt.Errorf("failed to archive campaign: %w", err)
+	}
+	s.catalog.InvalidateCampaign(tenantID, id)
+	return s.getCampaign(ctx, id, tenantID)
+}
+
+// RestoreCampaign makes an archived campaign visible again with the status it
+// was archived with
+func (s *Service) RestoreCampaign(ctx context.Context, id, tenantID pgtype.UUID) (db.Campaign, error) {
+	if _, err := s.queries.RestoreCampaign(ctx, db.RestoreCampaignParams{
+		ID:       id,
+		TenantID: tenantID,
+	}); err != nil {
+		return db.Campaign{}, fmt.Errorf("failed to restore campaign: %w", err)
+	}
+	s.catalog.InvalidateCampaign(tenantID, id)
+	return s.getCampaign(ctx, id, tenantID)
+}
+
+// getCampaign reads a campaign from the database, bypassing the cache
+func (s *Service) getCampaign(ctx context.Context, id, tenantID pgtype.UUID) (db.Campaign, error) {
+	campaign, err := s.queries.GetCampaignByID(ctx, db.GetCampaignByIDParams{
+		ID:       id,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Campaign{}, ErrCampaignNotFound
+		}
+		return db.Campaign{}, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	return campaign, nil
+}
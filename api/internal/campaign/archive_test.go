@@ -0,0 +1,109 @@
+package campaign
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/catalogcache"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveCampaign(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set, skipping integration tests")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	require.NoError(t, err)
+	defer pool.Close()
+	queries := db.New(pool)
+	service := NewService(pool, queries, catalogcache.New(queries, time.Minute))
+
+	tests := []struct {
+		name          string
+		status        string
+		archiveTwice  bool
+		unknown       bool
+		wantErr       error
+		wantListedNow bool
+	}{
+		{"paused", "paused", false, false, nil, false},
+		{"completed", "completed", false, false, nil, false},
+		{"already archived", "paused", true, false, nil, false},
+		{"active", "active", false, false, ErrCampaignActive, true},
+		{"unknown", "paused", false, true, ErrCampaignNotFound, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant, err := queries.CreateTenant(ctx, db.CreateTenantParams{
+				Name:        "Archive Campaigns " + tt.name,
+				CountryCode: "ZW",
+				DefaultCcy:  "USD",
+				Theme:       []byte(`{}`),
+			})
+			require.NoError(t, err)
+
+			campaign, err := queries.CreateCampaign(ctx, db.CreateCampaignParams{
+				TenantID: tenant.ID,
+				Name:     "Winter Promo",
+				StartAt:  pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true},
+				Status:   tt.status,
+			})
+			require.NoError(t, err)
+
+			id := campaign.ID
+			if tt.unknown {
+				id = pgtype.UUID{Bytes: uuid.New(), Valid: true}
+			}
+
+			var first db.Campaign
+			if tt.archiveTwice {
+				first, err = service.ArchiveCampaign(ctx, id, tenant.ID)
+				require.NoError(t, err)
+			}
+
+			archived, err := service.ArchiveCampaign(ctx, id, tenant.ID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.True(t, archived.ArchivedAt.Valid)
+				assert.Equal(t, tt.status, archived.Status)
+				if tt.archiveTwice {
+					assert.Equal(t, first.ArchivedAt, archived.ArchivedAt)
+				}
+			}
+
+			listed, _, err := service.ListCampaigns(ctx, tenant.ID, "", "", "", false)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantListedNow, len(listed) == 1)
+
+			withArchived, _, err := service.ListCampaigns(ctx, tenant.ID, "", "", "", true)
+			require.NoError(t, err)
+			assert.Len(t, withArchived, 1)
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			restored, err := service.RestoreCampaign(ctx, id, tenant.ID)
+			require.NoError(t, err)
+			assert.False(t, restored.ArchivedAt.Valid)
+			assert.Equal(t, tt.status, restored.Status)
+
+			listed, _, err = service.ListCampaigns(ctx, tenant.ID, "", "", "", false)
+			require.NoError(t, err)
+			assert.Len(t, listed, 1)
+		})
+	}
+}
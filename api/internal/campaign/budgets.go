@@ -0,0 +1,132 @@
+package campaign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxFallbackBudgets bounds how many budgets a campaign falls back through
+const maxFallbackBudgets = 5
+
+var (
+	// ErrNoPrimaryBudget is returned when fallbacks are set on a campaign
+	// without a budget of its own
+	ErrNoPrimaryBudget = errors.New("campaign has no budget to fall back from")
+
+	// ErrInvalidFallbackBudgets is returned for duplicate fallbacks, the
+	// primary budget listed as a fallback, or too many fallbacks
+	ErrInvalidFallbackBudgets = errors.New("fallback budgets must be distinct from each other and from the primary budget")
+
+	// ErrFallbackCurrencyMismatch is returned when a fallback budget's
+	// currency differs from the primary budget's
+	ErrFallbackCurrencyMismatch = errors.New("fallback budgets must use the primary budget's currency")
+)
+
+// ListFallbackBudgets returns the budgets a campaign falls back to, in order
+func (s *Service) ListFallbackBudgets(ctx context.Context, tenantID, campaignID pgtype.UUID) ([]db.ListCampaignFallbackBudgetsRow, error) {
+	budgets, err := s.queries.ListCampaignFallbackBudgets(ctx, db.ListCampaignFallbackBudgetsParams{
+		TenantID:   tenantID,
+		CampaignID: campaignID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fallback budgets: %w", err)
+	}
+	return budgets, nil
+}
+
+// SetFallbackBudgets replaces the ordered budgets a campaign falls back to
+// when its primary budget reaches its hard cap. An empty list removes them.
+func (s *Service) SetFallbackBudgets(ctx context.Context, tenantID, campaignID pgtype.UUID, budgetIDs []pgtype.UUID) ([]db.ListCampaignFallbackBudgetsRow, error) {
+	if len(budgetIDs) > maxFallbackBudgets {
+		return nil, ErrInvalidFallbackBudgets
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	campaign, err := qtx.GetCampaignByID(ctx, db.GetCampaignByIDParams{
+		ID:       campaignID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCampaignNotFound
+		}
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	if len(budgetIDs) > 0 && !campaign.BudgetID.Valid {
+		return nil, ErrNoPrimaryBudget
+	}
+
+	if err := qtx.DeleteCampaignFallbackBudgets(ctx, db.DeleteCampaignFallbackBudgetsParams{
+		TenantID:   tenantID,
+		CampaignID: campaignID,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to clear fallback budgets: %w", err)
+	}
+
+	if len(budgetIDs) > 0 {
+		primary, err := qtx.GetBudgetByID(ctx, db.GetBudgetByIDParams{
+			ID:       campaign.BudgetID,
+			TenantID: tenantID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get primary budget: %w", err)
+		}
+
+		seen := map[[16]byte]bool{primary.ID.Bytes: true}
+		for i, budgetID := range budgetIDs {
+			if seen[budgetID.Bytes] {
+				return nil, ErrInvalidFallbackBudgets
+			}
+			seen[budgetID.Bytes] = true
+
+			budget, err := qtx.GetBudgetByID(ctx, db.GetBudgetByIDParams{
+				ID:       budgetID,
+				TenantID: tenantID,
+			})
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					return nil, ErrBudgetNotFound
+				}
+				return nil, fmt.Errorf("failed to get budget: %w", err)
+			}
+			if budget.Currency != primary.Currency {
+				return nil, ErrFallbackCurrencyMismatch
+			}
+
+			if err := qtx.AddCampaignFallbackBudget(ctx, db.AddCampaignFallbackBudgetParams{
+				TenantID:   tenantID,
+				CampaignID: campaignID,
+				BudgetID:   budgetID,
+				Position:   int32(i),
+			}); err != nil {
+				return nil, fmt.Errorf("failed to add fallback budget: %w", err)
+			}
+		}
+	}
+
+	budgets, err := qtx.ListCampaignFallbackBudgets(ctx, db.ListCampaignFallbackBudgetsParams{
+		TenantID:   tenantID,
+		CampaignID: campaignID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fallback budgets: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return budgets, nil
+}
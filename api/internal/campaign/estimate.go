@@ -0,0 +1,196 @@
+package campaign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// EstimateParams is the expected response to a campaign
+type EstimateParams struct {
+	// ExpectedAudience is the number of customers the campaign reaches
+	ExpectedAudience int
+	// ExpectedConversion is the fraction of the audience expected to earn
+	// the campaign's rewards, between 0 and 1
+	ExpectedConversion float64
+}
+
+// Validate validates the estimate parameters
+func (p EstimateParams) Validate() error {
+	if p.ExpectedAudience < 1 {
+		return errors.New("expected_audience must be at least 1")
+	}
+	if p.ExpectedConversion <= 0 || p.ExpectedConversion > 1 {
+		return errors.New("expected_conversion must be greater than 0 and at most 1")
+	}
+	return nil
+}
+
+// RuleEstimate is the expected cost of one of the campaign's rules
+type RuleEstimate struct {
+	RuleID     pgtype.UUID
+	RuleName   string
+	RewardName string
+	Currency   string
+	UnitCost   float64
+	Issuances  int
+	Cost       float64
+}
+
+// BudgetEstimate is the budget a campaign is expected to need
+type BudgetEstimate struct {
+	ExpectedAudience   int
+	ExpectedConversion float64
+	// Currency is the budget's currency, or the rewards' when the campaign
+	// has no budget
+	Currency string
+	Rules    []RuleEstimate
+	// EstimatedCost totals the rules' costs in Currency
+	EstimatedCost float64
+	// BudgetID and Headroom are set when the campaign has a budget;
+	// Headroom is what it can still reserve before its hard cap
+	BudgetID pgtype.UUID
+	Headroom *float64
+	// Warnings explain why the estimate may not be covered or complete
+	Warnings []string
+}
+
+// Sufficient reports whether the budget's headroom covers the estimate
+func (e *BudgetEstimate) Sufficient() bool {
+	return e.Headroom != nil && *e.Headroom >= e.EstimatedCost
+}
+
+// EstimateBudget estimates the budget a campaign needs from its rules'
+// reward costs: each customer of the expected audience who converts is
+// expected to earn each rule's reward once, up to the rule's global cap.
+// The estimate is compared with the headroom of the campaign's budget.
+func (s *Service) EstimateBudget(ctx context.Context, tenantID pgtype.UUID, campaign db.Campaign, params EstimateParams) (*BudgetEstimate, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	rules, err := s.queries.ListRulesByCampaign(ctx, db.ListRulesByCampaignParams{
+		TenantID:   tenantID,
+		CampaignID: campaign.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaign rules: %w", err)
+	}
+
+	estimates := make([]RuleEstimate, 0, len(rules))
+	for _, rule := range rules {
+		rewardItem, err := s.queries.GetRewardByID(ctx, db.GetRewardByIDParams{
+			TenantID: tenantID,
+			ID:       rule.RewardID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get reward: %w", err)
+		}
+
+		estimate := RuleEstimate{
+			RuleID:     rule.ID,
+			RuleName:   rule.Name,
+			RewardName: rewardItem.Name,
+			Currency:   "USD",
+			Issuances:  expectedIssuances(params, rule.GlobalCap),
+		}
+		if rewardItem.Currency.Valid {
+			estimate.Currency = rewardItem.Currency.String
+		}
+		if v, err := rewardItem.FaceValue.Float64Value(); err == nil && v.Valid {
+			estimate.UnitCost = v.Float64
+		}
+		estimates = append(estimates, estimate)
+	}
+
+	var budget *db.Budget
+	if campaign.BudgetID.Valid {
+		b, err := s.queries.GetBudgetByID(ctx, db.GetBudgetByIDParams{
+			ID:       campaign.BudgetID,
+			TenantID: tenantID,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrBudgetNotFound
+			}
+			return nil, fmt.Errorf("failed to get budget: %w", err)
+		}
+		budget = &b
+	}
+
+	return estimateBudget(params, estimates, budget), nil
+}
+
+// estimateBudget totals the rule estimates in the budget's currency and
+// checks them against its headroom
+func estimateBudget(params EstimateParams, rules []RuleEstimate, budget *db.Budget) *BudgetEstimate {
+	result := &BudgetEstimate{
+		ExpectedAudience:   params.ExpectedAudience,
+		ExpectedConversion: params.ExpectedConversion,
+		Rules:              rules,
+		Warnings:           []string{},
+	}
+
+	switch {
+	case budget != nil:
+		result.Currency = budget.Currency
+	case len(rules) > 0:
+		result.Currency = rules[0].Currency
+	}
+
+	for i := range rules {
+		rules[i].Cost = httputil.RoundCents(rules[i].UnitCost * float64(rules[i].Issuances))
+		if rules[i].Currency != result.Currency {
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				"rule %q issues rewards in %s, which is not included in the %s estimate",
+				rules[i].RuleName, rules[i].Currency, result.Currency))
+			continue
+		}
+		result.EstimatedCost += rules[i].Cost
+	}
+	result.EstimatedCost = httputil.RoundCents(result.EstimatedCost)
+
+	if len(rules) == 0 {
+		result.Warnings = append(result.Warnings, "campaign has no rules yet; add rules to estimate their reward costs")
+	}
+
+	if budget == nil {
+		result.Warnings = append(result.Warnings, "campaign has no budget to cover the estimate")
+		return result
+	}
+
+	result.BudgetID = budget.ID
+	headroom := httputil.RoundCents(numericFloat(budget.HardCap) - numericFloat(budget.Balance))
+	result.Headroom = &headroom
+	if !result.Sufficient() {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"budget headroom of %.2f %s is below the estimated %.2f %s",
+			headroom, budget.Currency, result.EstimatedCost, result.Currency))
+	}
+	return result
+}
+
+// expectedIssuances is the number of customers expected to convert, capped
+// at the rule's global cap
+func expectedIssuances(params EstimateParams, globalCap pgtype.Int4) int {
+	issuances := int(math.Round(float64(params.ExpectedAudience) * params.ExpectedConversion))
+	if globalCap.Valid && globalCap.Int32 > 0 && issuances > int(globalCap.Int32) {
+		issuances = int(globalCap.Int32)
+	}
+	return issuances
+}
+
+// numericFloat converts a numeric to a float, treating NULL as zero
+func numericFloat(n pgtype.Numeric) float64 {
+	v, err := n.Float64Value()
+	if err != nil || !v.Valid {
+		return 0
+	}
+	return v.Float64
+}
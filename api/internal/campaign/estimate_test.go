@@ -0,0 +1,74 @@
+package campaign
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func numeric(v int64) pgtype.Numeric {
+	return pgtype.Numeric{Int: big.NewInt(v), Valid: true}
+}
+
+func TestEstimateParamsValidate(t *testing.T) {
+	assert.NoError(t, EstimateParams{ExpectedAudience: 1000, ExpectedConversion: 0.05}.Validate())
+	assert.NoError(t, EstimateParams{ExpectedAudience: 1, ExpectedConversion: 1}.Validate())
+	assert.Error(t, EstimateParams{ExpectedAudience: 0, ExpectedConversion: 0.05}.Validate())
+	assert.Error(t, EstimateParams{ExpectedAudience: 1000, ExpectedConversion: 0}.Validate())
+	assert.Error(t, EstimateParams{ExpectedAudience: 1000, ExpectedConversion: 1.5}.Validate())
+}
+
+func TestExpectedIssuances(t *testing.T) {
+	params := EstimateParams{ExpectedAudience: 1000, ExpectedConversion: 0.05}
+	assert.Equal(t, 50, expectedIssuances(params, pgtype.Int4{}))
+	assert.Equal(t, 20, expectedIssuances(params, pgtype.Int4{Int32: 20, Valid: true}))
+	assert.Equal(t, 50, expectedIssuances(params, pgtype.Int4{Int32: 100, Valid: true}))
+}
+
+func TestEstimateBudget(t *testing.T) {
+	params := EstimateParams{ExpectedAudience: 1000, ExpectedConversion: 0.1}
+	rules := func() []RuleEstimate {
+		return []RuleEstimate{
+			{RuleName: "Spend $50", Currency: "USD", UnitCost: 2.5, Issuances: 100},
+			{RuleName: "Welcome", Currency: "USD", UnitCost: 1, Issuances: 100},
+		}
+	}
+
+	t.Run("sufficient budget", func(t *testing.T) {
+		budget := &db.Budget{Currency: "USD", HardCap: numeric(1000), Balance: numeric(200)}
+		estimate := estimateBudget(params, rules(), budget)
+		assert.Equal(t, 350.0, estimate.EstimatedCost)
+		assert.Equal(t, 250.0, estimate.Rules[0].Cost)
+		require.NotNil(t, estimate.Headroom)
+		assert.Equal(t, 800.0, *estimate.Headroom)
+		assert.True(t, estimate.Sufficient())
+		assert.Empty(t, estimate.Warnings)
+	})
+
+	t.Run("insufficient headroom", func(t *testing.T) {
+		budget := &db.Budget{Currency: "USD", HardCap: numeric(500), Balance: numeric(300)}
+		estimate := estimateBudget(params, rules(), budget)
+		assert.False(t, estimate.Sufficient())
+		require.Len(t, estimate.Warnings, 1)
+		assert.Contains(t, estimate.Warnings[0], "headroom of 200.00 USD is below the estimated 350.00 USD")
+	})
+
+	t.Run("other currencies are excluded", func(t *testing.T) {
+		budget := &db.Budget{Currency: "ZWG", HardCap: numeric(1000), Balance: numeric(0)}
+		estimate := estimateBudget(params, rules(), budget)
+		assert.Equal(t, "ZWG", estimate.Currency)
+		assert.Equal(t, 0.0, estimate.EstimatedCost)
+		assert.Len(t, estimate.Warnings, 2)
+	})
+
+	t.Run("no budget and no rules", func(t *testing.T) {
+		estimate := estimateBudget(params, []RuleEstimate{}, nil)
+		assert.Nil(t, estimate.Headroom)
+		assert.False(t, estimate.Sufficient())
+		assert.Len(t, estimate.Warnings, 2)
+	})
+}
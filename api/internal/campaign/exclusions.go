@@ -0,0 +1,210 @@
+package campaign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/phone"
+	"github.com/bmachimbira/loyalty/api/internal/pii"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// MaxExclusionRows bounds how many customers one exclusion upload may list
+const MaxExclusionRows = 10000
+
+var (
+	// ErrExclusionNotFound is returned when removing a customer the campaign
+	// does not exclude
+	ErrExclusionNotFound = errors.New("customer is not excluded from the campaign")
+
+	// ErrInvalidExclusion is returned for an upload entry that doesn't
+	// identify a customer by exactly one of customer ID, phone or external ref
+	ErrInvalidExclusion = errors.New("each excluded customer needs exactly one of customer_id, phone or external_ref")
+)
+
+// ExclusionEntry identifies a customer to exclude by exactly one of their ID,
+// phone number or external reference
+type ExclusionEntry struct {
+	CustomerID  pgtype.UUID
+	Phone       string
+	ExternalRef string
+}
+
+// String returns how the entry identifies its customer
+func (e ExclusionEntry) String() string {
+	switch {
+	case e.CustomerID.Valid:
+		return httputil.FormatUUID(e.CustomerID.Bytes)
+	case e.Phone != "":
+		return e.Phone
+	default:
+		return e.ExternalRef
+	}
+}
+
+func (e ExclusionEntry) valid() bool {
+	n := 0
+	if e.CustomerID.Valid {
+		n++
+	}
+	if strings.TrimSpace(e.Phone) != "" {
+		n++
+	}
+	if strings.TrimSpace(e.ExternalRef) != "" {
+		n++
+	}
+	return n == 1
+}
+
+// ExclusionResult reports an exclusion upload
+type ExclusionResult struct {
+	// Excluded is the number of customers now excluded by the upload,
+	// including ones already excluded
+	Excluded int
+	// Unmatched lists the entries that matched no customer, as given
+	Unmatched []string
+}
+
+// AddExclusions excludes the listed customers from a campaign, so its rules
+// no longer reward them. Entries that match no customer are reported rather
+// than failing the upload; customers already excluded get the new reason.
+func (s *Service) AddExclusions(ctx context.Context, tenantID, campaignID pgtype.UUID, entries []ExclusionEntry, reason string) (*ExclusionResult, error) {
+	for _, entry := range entries {
+		if !entry.valid() {
+			return nil, ErrInvalidExclusion
+		}
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	if _, err := qtx.GetCampaignByID(ctx, db.GetCampaignByIDParams{
+		ID:       campaignID,
+		TenantID: tenantID,
+	}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCampaignNotFound
+		}
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+
+	var region string
+	result := &ExclusionResult{Unmatched: []string{}}
+	for _, entry := range entries {
+		var customer db.Customer
+		switch {
+		case entry.CustomerID.Valid:
+			customer, err = qtx.GetCustomerByID(ctx, db.GetCustomerByIDParams{
+				ID:       entry.CustomerID,
+				TenantID: tenantID,
+			})
+		case strings.TrimSpace(entry.Phone) != "":
+			if region == "" {
+				if region, err = qtx.GetTenantPhoneRegion(ctx, tenantID); err != nil {
+					return nil, fmt.Errorf("failed to get tenant phone region: %w", err)
+				}
+			}
+			e164, normErr := phone.Normalize(entry.Phone, region)
+			if normErr != nil {
+				result.Unmatched = append(result.Unmatched, entry.String())
+				continue
+			}
+			customer, err = qtx.GetCustomerByPhone(ctx, db.GetCustomerByPhoneParams{
+				TenantID:   tenantID,
+				PhoneBidxs: pii.Default().BlindIndexes(e164),
+			})
+		default:
+			customer, err = qtx.GetCustomerByExternalRef(ctx, db.GetCustomerByExternalRefParams{
+				TenantID:    tenantID,
+				ExternalRef: pgtype.Text{String: strings.TrimSpace(entry.ExternalRef), Valid: true},
+			})
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			result.Unmatched = append(result.Unmatched, entry.String())
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get customer: %w", err)
+		}
+
+		if err := qtx.AddCampaignExclusion(ctx, db.AddCampaignExclusionParams{
+			TenantID:   tenantID,
+			CampaignID: campaignID,
+			CustomerID: customer.ID,
+			Reason:     pgtype.Text{String: reason, Valid: reason != ""},
+		}); err != nil {
+			return nil, fmt.Errorf("failed to add exclusion: %w", err)
+		}
+		result.Excluded++
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// ListExclusions retrieves a paginated list of the customers a campaign
+// excludes, most recently excluded first
+func (s *Service) ListExclusions(ctx context.Context, tenantID, campaignID pgtype.UUID, limit, offset string) ([]db.ListCampaignExclusionsRow, int64, error) {
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil || limitInt < 1 {
+		limitInt = 50
+	}
+	if limitInt > 100 {
+		limitInt = 100
+	}
+
+	offsetInt, err := strconv.Atoi(offset)
+	if err != nil || offsetInt < 0 {
+		offsetInt = 0
+	}
+
+	exclusions, err := s.queries.ListCampaignExclusions(ctx, db.ListCampaignExclusionsParams{
+		TenantID:   tenantID,
+		CampaignID: campaignID,
+		Limit:      int32(limitInt),
+		Offset:     int32(offsetInt),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list exclusions: %w", err)
+	}
+
+	total, err := s.queries.CountCampaignExclusions(ctx, db.CountCampaignExclusionsParams{
+		TenantID:   tenantID,
+		CampaignID: campaignID,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count exclusions: %w", err)
+	}
+
+	return exclusions, total, nil
+}
+
+// RemoveExclusion lets a campaign reward a customer it excluded again
+func (s *Service) RemoveExclusion(ctx context.Context, tenantID, campaignID, customerID pgtype.UUID) error {
+	removed, err := s.queries.DeleteCampaignExclusion(ctx, db.DeleteCampaignExclusionParams{
+		TenantID:   tenantID,
+		CampaignID: campaignID,
+		CustomerID: customerID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove exclusion: %w", err)
+	}
+	if removed == 0 {
+		return ErrExclusionNotFound
+	}
+	return nil
+}
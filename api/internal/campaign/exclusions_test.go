@@ -0,0 +1,21 @@
+package campaign
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExclusionEntryValid(t *testing.T) {
+	id := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+
+	assert.True(t, ExclusionEntry{CustomerID: id}.valid())
+	assert.True(t, ExclusionEntry{Phone: "0771234567"}.valid())
+	assert.True(t, ExclusionEntry{ExternalRef: "staff-42"}.valid())
+
+	assert.False(t, ExclusionEntry{}.valid())
+	assert.False(t, ExclusionEntry{Phone: "  "}.valid())
+	assert.False(t, ExclusionEntry{CustomerID: id, Phone: "0771234567"}.valid())
+	assert.False(t, ExclusionEntry{Phone: "0771234567", ExternalRef: "staff-42"}.valid())
+}
@@ -0,0 +1,198 @@
+package campaign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/timezone"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Pacing statuses
+const (
+	// PacingUnlimited is a campaign without spend limits
+	PacingUnlimited = "unlimited"
+	// PacingOnTrack is a campaign with room left in every period
+	PacingOnTrack = "on_track"
+	// PacingDayLimitReached is a campaign that has spent its daily limit
+	PacingDayLimitReached = "day_limit_reached"
+	// PacingWeekLimitReached is a campaign that has spent its weekly limit
+	PacingWeekLimitReached = "week_limit_reached"
+)
+
+// ErrInvalidPacing is returned for a spend limit that isn't positive
+var ErrInvalidPacing = errors.New("spend limits must be greater than zero")
+
+// PacingLimits are the most a campaign may spend per day and per week
+type PacingLimits struct {
+	MaxSpendPerDay  pgtype.Numeric
+	MaxSpendPerWeek pgtype.Numeric
+}
+
+// Validate checks the limits that are set are positive
+func (l PacingLimits) Validate() error {
+	for _, limit := range []pgtype.Numeric{l.MaxSpendPerDay, l.MaxSpendPerWeek} {
+		if limit.Valid && numericFloat(limit) <= 0 {
+			return ErrInvalidPacing
+		}
+	}
+	return nil
+}
+
+// HasPacing reports whether the campaign limits its spend in any period
+func HasPacing(c db.Campaign) bool {
+	return c.MaxSpendPerDay.Valid || c.MaxSpendPerWeek.Valid
+}
+
+// PacingPeriods returns the start of the day and of the week containing now
+// in loc, the tenant's time zone. Weeks start on Monday.
+func PacingPeriods(now time.Time, loc *time.Location) (dayStart, weekStart time.Time) {
+	dayStart = timezone.StartOfDay(now, loc)
+	weekStart = dayStart.AddDate(0, 0, -((int(dayStart.Weekday()) + 6) % 7))
+	return dayStart, weekStart
+}
+
+// Pacing is a campaign's spend in its current day and week against its
+// limits, as decimal strings. A nil limit is unlimited.
+type Pacing struct {
+	Status    string    `json:"status"`
+	DayStart  time.Time `json:"day_start"`
+	DaySpend  string    `json:"day_spend"`
+	DayLimit  *string   `json:"day_limit"`
+	WeekStart time.Time `json:"week_start"`
+	WeekSpend string    `json:"week_spend"`
+	WeekLimit *string   `json:"week_limit"`
+
+	exceeded bool
+}
+
+// NewPacing works out a campaign's pacing from its spend in the periods
+// starting at dayStart and weekStart
+func NewPacing(c db.Campaign, spend db.GetCampaignPacingSpendRow, dayStart, weekStart time.Time) Pacing {
+	p := Pacing{
+		Status:    PacingUnlimited,
+		DayStart:  dayStart,
+		DaySpend:  httputil.FormatNumeric(spend.DaySpend),
+		WeekStart: weekStart,
+		WeekSpend: httputil.FormatNumeric(spend.WeekSpend),
+	}
+	if c.MaxSpendPerDay.Valid {
+		limit := httputil.FormatNumeric(c.MaxSpendPerDay)
+		p.DayLimit = &limit
+	}
+	if c.MaxSpendPerWeek.Valid {
+		limit := httputil.FormatNumeric(c.MaxSpendPerWeek)
+		p.WeekLimit = &limit
+	}
+
+	daySpend, weekSpend := numericFloat(spend.DaySpend), numericFloat(spend.WeekSpend)
+	dayLimit, weekLimit := numericFloat(c.MaxSpendPerDay), numericFloat(c.MaxSpendPerWeek)
+	switch {
+	case p.WeekLimit != nil && weekSpend >= weekLimit:
+		p.Status = PacingWeekLimitReached
+	case p.DayLimit != nil && daySpend >= dayLimit:
+		p.Status = PacingDayLimitReached
+	case p.DayLimit != nil || p.WeekLimit != nil:
+		p.Status = PacingOnTrack
+	}
+	p.exceeded = (p.DayLimit != nil && daySpend > dayLimit) ||
+		(p.WeekLimit != nil && weekSpend > weekLimit)
+	return p
+}
+
+// Exceeded reports whether spend has gone past either limit
+func (p Pacing) Exceeded() bool {
+	return p.exceeded
+}
+
+// CampaignPacing returns the campaign's pacing as of now. q may be a
+// transaction, so an issuance can check the spend it has just reserved.
+func CampaignPacing(ctx context.Context, q *db.Queries, c db.Campaign, now time.Time) (Pacing, error) {
+	zone, err := q.GetTenantTimezone(ctx, c.TenantID)
+	if err != nil {
+		return Pacing{}, fmt.Errorf("failed to get tenant timezone: %w", err)
+	}
+	dayStart, weekStart := PacingPeriods(now, timezone.Load(zone))
+
+	spend, err := q.GetCampaignPacingSpend(ctx, db.GetCampaignPacingSpendParams{
+		DayStart:   pgtype.Timestamptz{Time: dayStart, Valid: true},
+		TenantID:   c.TenantID,
+		CampaignID: c.ID,
+		WeekStart:  pgtype.Timestamptz{Time: weekStart, Valid: true},
+	})
+	if err != nil {
+		return Pacing{}, fmt.Errorf("failed to get campaign spend: %w", err)
+	}
+	return NewPacing(c, spend, dayStart, weekStart), nil
+}
+
+// SetPacing sets the campaign's spend limits. Limits left unset remove the
+// limit for that period.
+func (s *Service) SetPacing(ctx context.Context, tenantID, campaignID pgtype.UUID, limits PacingLimits) (db.Campaign, error) {
+	if err := limits.Validate(); err != nil {
+		return db.Campaign{}, err
+	}
+
+	updated, err := s.queries.SetCampaignPacing(ctx, db.SetCampaignPacingParams{
+		ID:              campaignID,
+		TenantID:        tenantID,
+		MaxSpendPerDay:  limits.MaxSpendPerDay,
+		MaxSpendPerWeek: limits.MaxSpendPerWeek,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Campaign{}, ErrCampaignNotFound
+		}
+		return db.Campaign{}, fmt.Errorf("failed to set campaign pacing: %w", err)
+	}
+	s.catalog.InvalidateCampaign(tenantID, campaignID)
+	return updated, nil
+}
+
+// Stats is a campaign's lifetime issuances, redemptions and spend, and its
+// pacing in the current day and week
+type Stats struct {
+	Issued   int64  `json:"issued"`
+	Redeemed int64  `json:"redeemed"`
+	Spend    string `json:"spend"`
+	Pacing   Pacing `json:"pacing"`
+}
+
+// GetStats returns the campaign's stats as of now
+func (s *Service) GetStats(ctx context.Context, tenantID, campaignID pgtype.UUID, now time.Time) (*Stats, error) {
+	c, err := s.queries.GetCampaignByID(ctx, db.GetCampaignByIDParams{
+		ID:       campaignID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCampaignNotFound
+		}
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+
+	totals, err := s.queries.GetCampaignStats(ctx, db.GetCampaignStatsParams{
+		TenantID:   tenantID,
+		CampaignID: campaignID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign stats: %w", err)
+	}
+
+	pacing, err := CampaignPacing(ctx, s.queries, c, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stats{
+		Issued:   totals.Issued,
+		Redeemed: totals.Redeemed,
+		Spend:    httputil.FormatNumeric(totals.Spend),
+		Pacing:   pacing,
+	}, nil
+}
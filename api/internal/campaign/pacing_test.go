@@ -0,0 +1,86 @@
+package campaign
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPacingPeriods(t *testing.T) {
+	harare, err := time.LoadLocation("Africa/Harare")
+	require.NoError(t, err)
+
+	// Wednesday 31 December 2025, 23:30 in Harare is already Thursday
+	// 1 January 2026 there
+	now := time.Date(2025, 12, 31, 22, 30, 0, 0, time.UTC)
+	dayStart, weekStart := PacingPeriods(now, harare)
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, harare), dayStart)
+	assert.Equal(t, time.Date(2025, 12, 29, 0, 0, 0, 0, harare), weekStart)
+
+	// A Monday starts its own week
+	monday := time.Date(2025, 12, 29, 10, 0, 0, 0, time.UTC)
+	dayStart, weekStart = PacingPeriods(monday, time.UTC)
+	assert.Equal(t, dayStart, weekStart)
+}
+
+func TestNewPacing(t *testing.T) {
+	dayStart := time.Date(2025, 12, 30, 0, 0, 0, 0, time.UTC)
+	weekStart := time.Date(2025, 12, 29, 0, 0, 0, 0, time.UTC)
+	spend := func(day, week int64) db.GetCampaignPacingSpendRow {
+		return db.GetCampaignPacingSpendRow{DaySpend: numeric(day), WeekSpend: numeric(week)}
+	}
+	limited := db.Campaign{
+		MaxSpendPerDay:  numeric(100),
+		MaxSpendPerWeek: numeric(500),
+	}
+
+	tests := []struct {
+		name     string
+		campaign db.Campaign
+		spend    db.GetCampaignPacingSpendRow
+		status   string
+		exceeded bool
+	}{
+		{"no limits", db.Campaign{}, spend(1000, 5000), PacingUnlimited, false},
+		{"on track", limited, spend(40, 200), PacingOnTrack, false},
+		{"day limit spent exactly", limited, spend(100, 300), PacingDayLimitReached, false},
+		{"past day limit", limited, spend(120, 300), PacingDayLimitReached, true},
+		{"week limit spent", limited, spend(50, 500), PacingWeekLimitReached, false},
+		{"past both limits", limited, spend(150, 550), PacingWeekLimitReached, true},
+		{"week limit only", db.Campaign{MaxSpendPerWeek: numeric(500)}, spend(400, 450), PacingOnTrack, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPacing(tt.campaign, tt.spend, dayStart, weekStart)
+			assert.Equal(t, tt.status, p.Status)
+			assert.Equal(t, tt.exceeded, p.Exceeded())
+			assert.Equal(t, dayStart, p.DayStart)
+			assert.Equal(t, weekStart, p.WeekStart)
+		})
+	}
+
+	p := NewPacing(limited, spend(40, 200), dayStart, weekStart)
+	require.NotNil(t, p.DayLimit)
+	require.NotNil(t, p.WeekLimit)
+	assert.Equal(t, "100", *p.DayLimit)
+	assert.Equal(t, "500", *p.WeekLimit)
+	assert.Equal(t, "40", p.DaySpend)
+	assert.Equal(t, "200", p.WeekSpend)
+	assert.Nil(t, NewPacing(db.Campaign{}, spend(0, 0), dayStart, weekStart).DayLimit)
+}
+
+func TestPacingLimitsValidate(t *testing.T) {
+	assert.NoError(t, PacingLimits{}.Validate())
+	assert.NoError(t, PacingLimits{MaxSpendPerDay: numeric(50)}.Validate())
+	assert.ErrorIs(t, PacingLimits{MaxSpendPerDay: numeric(0)}.Validate(), ErrInvalidPacing)
+	assert.ErrorIs(t, PacingLimits{MaxSpendPerWeek: numeric(-5)}.Validate(), ErrInvalidPacing)
+}
+
+func TestHasPacing(t *testing.T) {
+	assert.False(t, HasPacing(db.Campaign{}))
+	assert.True(t, HasPacing(db.Campaign{MaxSpendPerDay: numeric(10)}))
+	assert.True(t, HasPacing(db.Campaign{MaxSpendPerWeek: numeric(10)}))
+}
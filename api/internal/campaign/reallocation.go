@@ -0,0 +1,175 @@
+package campaign
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// MinIssuedForComparison is how many issuances a campaign needs before
+	// its redemption rate is compared with other campaigns'
+	MinIssuedForComparison = 20
+	// HighRedemptionFactor and LowRedemptionFactor are how far above or
+	// below the average redemption rate of the campaigns in its currency a
+	// campaign must be to receive or give up budget
+	HighRedemptionFactor = 1.25
+	LowRedemptionFactor  = 0.75
+	// ReallocationShare is the share of a budget's headroom suggested for
+	// moving
+	ReallocationShare = 0.5
+)
+
+// BudgetPerformance is a running campaign's budget utilisation and
+// redemption rate
+type BudgetPerformance struct {
+	CampaignID   pgtype.UUID
+	CampaignName string
+	BudgetID     pgtype.UUID
+	BudgetName   string
+	Currency     string
+	HardCap      float64
+	Spent        float64
+	Issued       int64
+	Redeemed     int64
+}
+
+// Burn is the fraction of the budget's hard cap spent
+func (p BudgetPerformance) Burn() float64 {
+	if p.HardCap <= 0 {
+		return 0
+	}
+	return p.Spent / p.HardCap
+}
+
+// Redemption is the fraction of the campaign's issuances redeemed
+func (p BudgetPerformance) Redemption() float64 {
+	if p.Issued == 0 {
+		return 0
+	}
+	return float64(p.Redeemed) / float64(p.Issued)
+}
+
+// Headroom is what the budget can still spend before its hard cap
+func (p BudgetPerformance) Headroom() float64 {
+	return math.Max(p.HardCap-p.Spent, 0)
+}
+
+// Reallocation suggests moving budget from a campaign whose rewards are
+// rarely redeemed to one whose rewards are. It is advisory: staff move the
+// amount between the budgets themselves.
+type Reallocation struct {
+	From     BudgetPerformance
+	To       BudgetPerformance
+	Amount   float64
+	Currency string
+	Reason   string
+}
+
+// SuggestReallocations compares the campaigns' redemption rates with the
+// average of the campaigns in the same currency. Campaigns well below it
+// that have budget left are paired with campaigns well above it, those
+// spending their budget fastest first, and half the headroom of each is
+// suggested for moving. Campaigns with too few issuances to compare, and
+// budgets that also fund a campaign well above average, are left alone.
+func SuggestReallocations(campaigns []BudgetPerformance) []Reallocation {
+	byCurrency := make(map[string][]BudgetPerformance)
+	var currencies []string
+	for _, c := range campaigns {
+		if c.HardCap <= 0 || c.Issued < MinIssuedForComparison {
+			continue
+		}
+		if _, ok := byCurrency[c.Currency]; !ok {
+			currencies = append(currencies, c.Currency)
+		}
+		byCurrency[c.Currency] = append(byCurrency[c.Currency], c)
+	}
+	sort.Strings(currencies)
+
+	var suggestions []Reallocation
+	for _, currency := range currencies {
+		suggestions = append(suggestions, suggestForCurrency(byCurrency[currency])...)
+	}
+	return suggestions
+}
+
+func suggestForCurrency(campaigns []BudgetPerformance) []Reallocation {
+	var issued, redeemed int64
+	for _, c := range campaigns {
+		issued += c.Issued
+		redeemed += c.Redeemed
+	}
+	if len(campaigns) < 2 || redeemed == 0 {
+		return nil
+	}
+	average := float64(redeemed) / float64(issued)
+
+	var recipients, donors []BudgetPerformance
+	recipientBudgets := make(map[pgtype.UUID]bool)
+	for _, c := range campaigns {
+		if c.Redemption() >= average*HighRedemptionFactor {
+			recipients = append(recipients, c)
+			recipientBudgets[c.BudgetID] = true
+		}
+	}
+	for _, c := range campaigns {
+		if c.Redemption() <= average*LowRedemptionFactor && c.Headroom() > 0 && !recipientBudgets[c.BudgetID] {
+			donors = append(donors, c)
+		}
+	}
+	if len(recipients) == 0 || len(donors) == 0 {
+		return nil
+	}
+
+	// The recipients closest to their cap, and the donors redeemed least,
+	// come first
+	sort.SliceStable(recipients, func(i, j int) bool { return recipients[i].Burn() > recipients[j].Burn() })
+	sort.SliceStable(donors, func(i, j int) bool { return donors[i].Redemption() < donors[j].Redemption() })
+
+	suggestions := make([]Reallocation, 0, len(donors))
+	for i, from := range donors {
+		to := recipients[i%len(recipients)]
+		amount := math.Floor(from.Headroom()*ReallocationShare*100) / 100
+		if amount <= 0 {
+			continue
+		}
+		suggestions = append(suggestions, Reallocation{
+			From:     from,
+			To:       to,
+			Amount:   amount,
+			Currency: from.Currency,
+			Reason: fmt.Sprintf("%s is at %.0f%% burn with %.0f%% redemption, %s at %.0f%% burn with %.0f%% redemption (average %.0f%%)",
+				to.CampaignName, to.Burn()*100, to.Redemption()*100,
+				from.CampaignName, from.Burn()*100, from.Redemption()*100, average*100),
+		})
+	}
+	return suggestions
+}
+
+// BudgetReallocations returns the tenant's running campaigns' budget
+// performance and the reallocations suggested between them
+func (s *Service) BudgetReallocations(ctx context.Context, tenantID pgtype.UUID) ([]BudgetPerformance, []Reallocation, error) {
+	rows, err := s.queries.ListCampaignBudgetPerformance(ctx, tenantID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list campaign budget performance: %w", err)
+	}
+
+	campaigns := make([]BudgetPerformance, len(rows))
+	for i, row := range rows {
+		campaigns[i] = BudgetPerformance{
+			CampaignID:   row.CampaignID,
+			CampaignName: row.CampaignName,
+			BudgetID:     row.BudgetID,
+			BudgetName:   row.BudgetName,
+			Currency:     row.Currency,
+			HardCap:      numericFloat(row.HardCap),
+			Spent:        numericFloat(row.Balance),
+			Issued:       row.Issued,
+			Redeemed:     row.Redeemed,
+		}
+	}
+	return campaigns, SuggestReallocations(campaigns), nil
+}
@@ -0,0 +1,78 @@
+package campaign
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func performance(name string, budget byte, currency string, hardCap, spent float64, issued, redeemed int64) BudgetPerformance {
+	return BudgetPerformance{
+		CampaignName: name,
+		BudgetID:     pgtype.UUID{Bytes: [16]byte{budget}, Valid: true},
+		Currency:     currency,
+		HardCap:      hardCap,
+		Spent:        spent,
+		Issued:       issued,
+		Redeemed:     redeemed,
+	}
+}
+
+func TestSuggestReallocations(t *testing.T) {
+	a := performance("Campaign A", 1, "USD", 1000, 200, 100, 60)
+	b := performance("Campaign B", 2, "USD", 1000, 900, 100, 5)
+	steady := performance("Steady", 3, "USD", 1000, 500, 100, 33)
+
+	suggestions := SuggestReallocations([]BudgetPerformance{a, b, steady})
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, "Campaign B", suggestions[0].From.CampaignName)
+	assert.Equal(t, "Campaign A", suggestions[0].To.CampaignName)
+	assert.Equal(t, 50.0, suggestions[0].Amount)
+	assert.Equal(t, "USD", suggestions[0].Currency)
+	assert.Equal(t, "Campaign A is at 20% burn with 60% redemption, Campaign B at 90% burn with 5% redemption (average 33%)", suggestions[0].Reason)
+}
+
+func TestSuggestReallocations_LeavesAlone(t *testing.T) {
+	// Too few issuances to compare
+	assert.Empty(t, SuggestReallocations([]BudgetPerformance{
+		performance("A", 1, "USD", 1000, 200, 100, 60),
+		performance("B", 2, "USD", 1000, 900, 10, 0),
+	}))
+
+	// Budgets aren't moved between currencies
+	assert.Empty(t, SuggestReallocations([]BudgetPerformance{
+		performance("A", 1, "USD", 1000, 200, 100, 60),
+		performance("B", 2, "ZWG", 1000, 900, 100, 5),
+	}))
+
+	// A budget funding a well redeemed campaign isn't taken from
+	assert.Empty(t, SuggestReallocations([]BudgetPerformance{
+		performance("A", 1, "USD", 1000, 200, 100, 60),
+		performance("B", 1, "USD", 1000, 200, 100, 5),
+	}))
+
+	// Nothing left to move
+	assert.Empty(t, SuggestReallocations([]BudgetPerformance{
+		performance("A", 1, "USD", 1000, 200, 100, 60),
+		performance("B", 2, "USD", 1000, 1000, 100, 5),
+	}))
+}
+
+func TestSuggestReallocations_FastestBurningRecipientFirst(t *testing.T) {
+	slow := performance("Slow", 1, "USD", 1000, 100, 100, 70)
+	fast := performance("Fast", 2, "USD", 1000, 950, 100, 70)
+	worst := performance("Worst", 3, "USD", 1000, 0, 100, 0)
+	poor := performance("Poor", 4, "USD", 1000, 600, 100, 5)
+	middling := performance("Middling", 5, "USD", 1000, 500, 200, 80)
+
+	suggestions := SuggestReallocations([]BudgetPerformance{slow, fast, worst, poor, middling})
+	require.Len(t, suggestions, 2)
+	assert.Equal(t, "Worst", suggestions[0].From.CampaignName)
+	assert.Equal(t, "Fast", suggestions[0].To.CampaignName)
+	assert.Equal(t, 500.0, suggestions[0].Amount)
+	assert.Equal(t, "Poor", suggestions[1].From.CampaignName)
+	assert.Equal(t, "Slow", suggestions[1].To.CampaignName)
+	assert.Equal(t, 200.0, suggestions[1].Amount)
+}
@@ -0,0 +1,279 @@
+package campaign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/timezone"
+	"github.com/bmachimbira/loyalty/api/internal/wallet"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// ReportTopRewards is how many rewards a report lists
+	ReportTopRewards = 10
+	// reportDefaultDays is the period reported for campaigns without a start
+	reportDefaultDays = 30
+)
+
+// Report is a printable summary of a campaign's performance: its lifetime
+// stats, its budget, its issuances, redemptions and spend over time and its
+// most issued rewards
+type Report struct {
+	TenantName   string
+	Branding     wallet.Branding
+	CampaignName string
+	Status       string
+	StartAt      *time.Time
+	EndAt        *time.Time
+	Currency     string
+	Stats        Stats
+	Budget       *ReportBudget
+	// Series covers From to To in buckets of Bucket, a date_trunc field
+	From       time.Time
+	To         time.Time
+	Bucket     string
+	Series     []ReportPoint
+	TopRewards []ReportReward
+	Location   *time.Location
+	CreatedAt  time.Time
+}
+
+// ReportBudget is the campaign's budget's cap and spend
+type ReportBudget struct {
+	Name     string
+	Currency string
+	SoftCap  float64
+	HardCap  float64
+	Spent    float64
+}
+
+// Burn is the fraction of the budget's hard cap spent
+func (b ReportBudget) Burn() float64 {
+	if b.HardCap <= 0 {
+		return 0
+	}
+	return b.Spent / b.HardCap
+}
+
+// ReportPoint is a bucket of the campaign's activity. Spend is a decimal
+// string counting issuances in the report's currency only.
+type ReportPoint struct {
+	Start    time.Time `json:"start"`
+	Issued   int64     `json:"issued"`
+	Redeemed int64     `json:"redeemed"`
+	Spend    string    `json:"spend"`
+}
+
+// ReportReward is one of the campaign's most issued rewards, with its cost
+// as a decimal string
+type ReportReward struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Issued   int64  `json:"issued"`
+	Redeemed int64  `json:"redeemed"`
+	Cost     string `json:"cost"`
+}
+
+// RedemptionRate is the fraction of the campaign's issuances redeemed
+func (r *Report) RedemptionRate() float64 {
+	if r.Stats.Issued == 0 {
+		return 0
+	}
+	return float64(r.Stats.Redeemed) / float64(r.Stats.Issued)
+}
+
+// Filename names the report for downloads and email attachments
+func (r *Report) Filename() string {
+	return fmt.Sprintf("campaign-report-%s-%s.html", slug(r.CampaignName), r.CreatedAt.In(r.Location).Format("2006-01-02"))
+}
+
+// ReportPeriod returns the period a campaign's report covers: from its start
+// to its end, or to now while it runs. Campaigns without a start cover the
+// last 30 days.
+func ReportPeriod(c db.Campaign, now time.Time) (from, to time.Time) {
+	to = now
+	if c.EndAt.Valid && c.EndAt.Time.Before(now) {
+		to = c.EndAt.Time
+	}
+	from = to.AddDate(0, 0, -reportDefaultDays)
+	if c.StartAt.Valid {
+		from = c.StartAt.Time
+	}
+	return from, to
+}
+
+// ReportBucket returns the date_trunc field bucketing a report period, so
+// its charts have at most a few months of bars
+func ReportBucket(from, to time.Time) string {
+	days := to.Sub(from).Hours() / 24
+	switch {
+	case days <= 92:
+		return "day"
+	case days <= 730:
+		return "week"
+	default:
+		return "month"
+	}
+}
+
+// Report builds the campaign's report as of now
+func (s *Service) Report(ctx context.Context, tenantID, campaignID pgtype.UUID, now time.Time) (*Report, error) {
+	c, err := s.queries.GetCampaignByID(ctx, db.GetCampaignByIDParams{
+		ID:       campaignID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCampaignNotFound
+		}
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+
+	tenant, err := s.queries.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+
+	stats, err := s.GetStats(ctx, tenantID, campaignID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := ReportPeriod(c, now)
+	report := &Report{
+		TenantName:   tenant.Name,
+		Branding:     wallet.BrandingFromTenant(tenant),
+		CampaignName: c.Name,
+		Status:       c.Status,
+		StartAt:      timestampPtr(c.StartAt),
+		EndAt:        timestampPtr(c.EndAt),
+		Currency:     tenant.DefaultCcy,
+		Stats:        *stats,
+		From:         from,
+		To:           to,
+		Bucket:       ReportBucket(from, to),
+		Location:     timezone.Load(tenant.Timezone),
+		CreatedAt:    now,
+	}
+
+	if c.BudgetID.Valid {
+		b, err := s.queries.GetBudgetByID(ctx, db.GetBudgetByIDParams{
+			ID:       c.BudgetID,
+			TenantID: tenantID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get budget: %w", err)
+		}
+		report.Currency = b.Currency
+		report.Budget = &ReportBudget{
+			Name:     b.Name,
+			Currency: b.Currency,
+			SoftCap:  numericFloat(b.SoftCap),
+			HardCap:  numericFloat(b.HardCap),
+			Spent:    numericFloat(b.Balance),
+		}
+	}
+
+	if report.Series, err = s.reportSeries(ctx, c, tenant.Timezone, report); err != nil {
+		return nil, err
+	}
+
+	rewards, err := s.queries.ListCampaignTopRewards(ctx, db.ListCampaignTopRewardsParams{
+		TenantID:   tenantID,
+		CampaignID: campaignID,
+		RowLimit:   ReportTopRewards,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list top rewards: %w", err)
+	}
+	report.TopRewards = make([]ReportReward, len(rewards))
+	for i, row := range rewards {
+		report.TopRewards[i] = ReportReward{
+			Name:     row.RewardName,
+			Type:     row.RewardType,
+			Issued:   row.Issued,
+			Redeemed: row.Redeemed,
+			Cost:     httputil.FormatNumeric(row.Cost),
+		}
+	}
+	return report, nil
+}
+
+// reportSeries returns the campaign's issuances, redemptions and spend in
+// the report's buckets
+func (s *Service) reportSeries(ctx context.Context, c db.Campaign, zone string, report *Report) ([]ReportPoint, error) {
+	if !report.From.Before(report.To) {
+		return []ReportPoint{}, nil
+	}
+	fromTS := pgtype.Timestamptz{Time: report.From, Valid: true}
+	toTS := pgtype.Timestamptz{Time: report.To, Valid: true}
+
+	issued, err := s.queries.GetIssuanceTimeseries(ctx, db.GetIssuanceTimeseriesParams{
+		BucketInterval: report.Bucket,
+		Timezone:       zone,
+		TenantID:       c.TenantID,
+		FromTime:       fromTS,
+		ToTime:         toTS,
+		CampaignID:     c.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issuance timeseries: %w", err)
+	}
+	redeemed, err := s.queries.GetRedemptionTimeseries(ctx, db.GetRedemptionTimeseriesParams{
+		BucketInterval: report.Bucket,
+		Timezone:       zone,
+		TenantID:       c.TenantID,
+		FromTime:       fromTS,
+		ToTime:         toTS,
+		CampaignID:     c.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redemption timeseries: %w", err)
+	}
+
+	points := make(map[time.Time]*ReportPoint)
+	point := func(bucket time.Time) *ReportPoint {
+		p, ok := points[bucket]
+		if !ok {
+			p = &ReportPoint{Start: bucket, Spend: "0"}
+			points[bucket] = p
+		}
+		return p
+	}
+	for _, row := range issued {
+		// Rows are per bucket and currency, so a bucket has at most one row
+		// in the report's currency
+		p := point(row.Bucket.Time)
+		p.Issued += row.Count
+		if row.Currency == report.Currency {
+			p.Spend = httputil.FormatNumeric(row.Amount)
+		}
+	}
+	for _, row := range redeemed {
+		point(row.Bucket.Time).Redeemed += row.Count
+	}
+
+	series := make([]ReportPoint, 0, len(points))
+	for _, p := range points {
+		series = append(series, *p)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		return series[i].Start.Before(series[j].Start)
+	})
+	return series, nil
+}
+
+// timestampPtr returns the timestamp's time, or nil when it is unset
+func timestampPtr(ts pgtype.Timestamptz) *time.Time {
+	if !ts.Valid {
+		return nil
+	}
+	return &ts.Time
+}
@@ -0,0 +1,267 @@
+package campaign
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reportTemplate lays a report out for screens and for printing to PDF on
+// A4. Charts are plain HTML so they print without scripts; their data is
+// embedded as JSON for clients that draw their own.
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"amount":  formatAmount,
+	"percent": formatPercent,
+	"date":    formatReportDate,
+	"height":  barHeight,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.CampaignName}} · Campaign report</title>
+<style>
+  @page { size: A4; margin: 16mm; }
+  * { box-sizing: border-box; }
+  body { margin: 0; font: 13px/1.45 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2933; -webkit-print-color-adjust: exact; print-color-adjust: exact; }
+  main { max-width: 190mm; margin: 0 auto; padding: 24px 0; }
+  header { display: flex; align-items: center; gap: 16px; padding: 20px 24px; border-radius: 8px; background: {{.Branding.PrimaryColor.Hex}}; color: {{.Branding.ForegroundColor.Hex}}; }
+  header img { max-height: 40px; }
+  header h1 { margin: 0; font-size: 22px; }
+  header p { margin: 2px 0 0; opacity: .85; }
+  section { margin-top: 24px; page-break-inside: avoid; }
+  h2 { margin: 0 0 10px; font-size: 15px; text-transform: uppercase; letter-spacing: .04em; color: #52606d; }
+  .tiles { display: grid; grid-template-columns: repeat(4, 1fr); gap: 12px; }
+  .tile { padding: 12px 14px; border: 1px solid #e4e7eb; border-radius: 6px; }
+  .tile span { display: block; color: #7b8794; font-size: 11px; text-transform: uppercase; }
+  .tile strong { font-size: 20px; }
+  .meter { height: 12px; border-radius: 6px; background: #e4e7eb; overflow: hidden; }
+  .meter div { height: 100%; background: {{.Branding.PrimaryColor.Hex}}; }
+  .chart { display: flex; align-items: flex-end; gap: 2px; height: 140px; padding-top: 8px; border-bottom: 1px solid #cbd2d9; }
+  .chart div { flex: 1; min-width: 1px; background: {{.Branding.PrimaryColor.Hex}}; }
+  .axis { display: flex; justify-content: space-between; color: #7b8794; font-size: 11px; }
+  table { width: 100%; border-collapse: collapse; }
+  th, td { padding: 6px 8px; border-bottom: 1px solid #e4e7eb; text-align: left; }
+  th { color: #7b8794; font-size: 11px; text-transform: uppercase; }
+  td.num, th.num { text-align: right; font-variant-numeric: tabular-nums; }
+  footer { margin-top: 32px; color: #9aa5b1; font-size: 11px; }
+</style>
+</head>
+<body>
+<main>
+<header>
+  {{if .Branding.LogoURL}}<img src="{{.Branding.LogoURL}}" alt="{{.TenantName}}">{{end}}
+  <div>
+    <h1>{{.CampaignName}}</h1>
+    <p>{{.TenantName}} · {{.Status}} · {{if .StartAt}}{{date .StartAt $.Location}}{{else}}No start date{{end}} – {{if .EndAt}}{{date .EndAt $.Location}}{{else}}ongoing{{end}}</p>
+  </div>
+</header>
+
+<section>
+  <h2>Performance</h2>
+  <div class="tiles">
+    <div class="tile"><span>Issued</span><strong>{{.Stats.Issued}}</strong></div>
+    <div class="tile"><span>Redeemed</span><strong>{{.Stats.Redeemed}}</strong></div>
+    <div class="tile"><span>Redemption rate</span><strong>{{percent .RedemptionRate}}</strong></div>
+    <div class="tile"><span>Spend</span><strong>{{.Currency}} {{amount .Stats.Spend}}</strong></div>
+  </div>
+</section>
+
+{{with .Budget}}
+<section>
+  <h2>Budget · {{.Name}}</h2>
+  <div class="meter"><div style="width: {{height .Spent .HardCap}}%"></div></div>
+  <table>
+    <tr><th>Spent</th><th class="num">Soft cap</th><th class="num">Hard cap</th><th class="num">Used</th></tr>
+    <tr><td>{{.Currency}} {{amount .Spent}}</td><td class="num">{{.Currency}} {{amount .SoftCap}}</td><td class="num">{{.Currency}} {{amount .HardCap}}</td><td class="num">{{percent .Burn}}</td></tr>
+  </table>
+</section>
+{{end}}
+
+{{if or .Stats.Pacing.DayLimit .Stats.Pacing.WeekLimit}}{{with .Stats.Pacing}}
+<section>
+  <h2>Pacing</h2>
+  <table>
+    <tr><th>Today</th><th class="num">Daily limit</th><th class="num">This week</th><th class="num">Weekly limit</th></tr>
+    <tr><td>{{amount .DaySpend}}</td><td class="num">{{amount .DayLimit}}</td><td class="num">{{amount .WeekSpend}}</td><td class="num">{{amount .WeekLimit}}</td></tr>
+  </table>
+</section>
+{{end}}{{end}}
+
+<section>
+  <h2>Spend by {{.Bucket}} ({{.Currency}})</h2>
+  {{if .Series}}
+  {{$max := .MaxSpend}}
+  <div class="chart">{{range .Series}}<div style="height: {{height .Spend $max}}%" title="{{date .Start $.Location}}: {{amount .Spend}}"></div>{{end}}</div>
+  <div class="axis"><span>{{date .From .Location}}</span><span>{{date .To .Location}}</span></div>
+  <table>
+    <tr><th>{{.Bucket}} of</th><th class="num">Issued</th><th class="num">Redeemed</th><th class="num">Spend</th></tr>
+    {{range .Series}}<tr><td>{{date .Start $.Location}}</td><td class="num">{{.Issued}}</td><td class="num">{{.Redeemed}}</td><td class="num">{{amount .Spend}}</td></tr>
+    {{end}}
+  </table>
+  {{else}}
+  <p>No issuances between {{date .From .Location}} and {{date .To .Location}}.</p>
+  {{end}}
+</section>
+
+<section>
+  <h2>Top rewards</h2>
+  {{if .TopRewards}}
+  <table>
+    <tr><th>Reward</th><th>Type</th><th class="num">Issued</th><th class="num">Redeemed</th><th class="num">Cost</th></tr>
+    {{range .TopRewards}}<tr><td>{{.Name}}</td><td>{{.Type}}</td><td class="num">{{.Issued}}</td><td class="num">{{.Redeemed}}</td><td class="num">{{amount .Cost}}</td></tr>
+    {{end}}
+  </table>
+  {{else}}
+  <p>No rewards issued yet.</p>
+  {{end}}
+</section>
+
+<footer>Generated {{(.CreatedAt.In .Location).Format "2 Jan 2006 15:04 MST"}}</footer>
+</main>
+<script type="application/json" id="report-data">{{.ChartData}}</script>
+</body>
+</html>
+`))
+
+// ReportChartData is the data behind a report's charts
+type ReportChartData struct {
+	Currency   string         `json:"currency"`
+	Bucket     string         `json:"bucket"`
+	Series     []ReportPoint  `json:"series"`
+	TopRewards []ReportReward `json:"top_rewards"`
+	Budget     *ReportBudget  `json:"budget,omitempty"`
+}
+
+// ChartData returns the data behind the report's charts
+func (r *Report) ChartData() ReportChartData {
+	return ReportChartData{
+		Currency:   r.Currency,
+		Bucket:     r.Bucket,
+		Series:     r.Series,
+		TopRewards: r.TopRewards,
+		Budget:     r.Budget,
+	}
+}
+
+// MaxSpend is the largest spend in the report's series, which the spend
+// chart is scaled to
+func (r *Report) MaxSpend() float64 {
+	var max float64
+	for _, p := range r.Series {
+		max = math.Max(max, parseAmount(p.Spend))
+	}
+	return max
+}
+
+// RenderReportHTML writes the report as a styled HTML page that prints to
+// PDF, for GET /campaigns/:id/report.html and email attachments
+func RenderReportHTML(w io.Writer, r *Report) error {
+	if err := reportTemplate.Execute(w, r); err != nil {
+		return fmt.Errorf("failed to render campaign report: %w", err)
+	}
+	return nil
+}
+
+// formatAmount formats an amount with two decimals and thousands separators.
+// Amounts are floats or decimal strings.
+func formatAmount(amount interface{}) string {
+	var v float64
+	switch a := amount.(type) {
+	case float64:
+		v = a
+	case *float64:
+		if a == nil {
+			return "–"
+		}
+		v = *a
+	case string:
+		v = parseAmount(a)
+	case *string:
+		if a == nil {
+			return "–"
+		}
+		v = parseAmount(*a)
+	}
+
+	s := fmt.Sprintf("%.2f", math.Abs(v))
+	whole, cents, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	if v < 0 && s != "0.00" {
+		b.WriteByte('-')
+	}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return b.String() + "." + cents
+}
+
+// parseAmount reads a decimal string amount, treating invalid ones as zero
+func parseAmount(amount string) float64 {
+	v, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// formatPercent formats a fraction as a percentage
+func formatPercent(fraction float64) string {
+	return fmt.Sprintf("%.1f%%", fraction*100)
+}
+
+// formatReportDate formats a date in the tenant's time zone
+func formatReportDate(t interface{}, loc *time.Location) string {
+	switch v := t.(type) {
+	case time.Time:
+		return v.In(loc).Format("2 Jan 2006")
+	case *time.Time:
+		if v != nil {
+			return v.In(loc).Format("2 Jan 2006")
+		}
+	}
+	return ""
+}
+
+// barHeight is value's share of max as a percentage, for bars and meters.
+// Values are floats or decimal strings; values above max fill the bar.
+func barHeight(amount interface{}, max float64) string {
+	var value float64
+	switch a := amount.(type) {
+	case float64:
+		value = a
+	case string:
+		value = parseAmount(a)
+	}
+	if max <= 0 || value <= 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%.1f", math.Min(value/max, 1)*100)
+}
+
+// slug lowercases a name and joins its words with hyphens, for filenames
+func slug(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	if b.Len() == 0 {
+		return "campaign"
+	}
+	return b.String()
+}
@@ -0,0 +1,101 @@
+package campaign
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/wallet"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportPeriod(t *testing.T) {
+	now := time.Date(2025, 12, 30, 9, 0, 0, 0, time.UTC)
+	start := time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	ts := func(t time.Time) pgtype.Timestamptz { return pgtype.Timestamptz{Time: t, Valid: true} }
+
+	from, to := ReportPeriod(db.Campaign{StartAt: ts(start)}, now)
+	assert.Equal(t, start, from)
+	assert.Equal(t, now, to)
+
+	// Ended campaigns are reported to their end
+	from, to = ReportPeriod(db.Campaign{StartAt: ts(start), EndAt: ts(end)}, now)
+	assert.Equal(t, start, from)
+	assert.Equal(t, end, to)
+
+	from, to = ReportPeriod(db.Campaign{}, now)
+	assert.Equal(t, now.AddDate(0, 0, -30), from)
+	assert.Equal(t, now, to)
+}
+
+func TestReportBucket(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, "day", ReportBucket(from, from.AddDate(0, 0, 30)))
+	assert.Equal(t, "week", ReportBucket(from, from.AddDate(0, 6, 0)))
+	assert.Equal(t, "month", ReportBucket(from, from.AddDate(3, 0, 0)))
+}
+
+func TestFormatAmount(t *testing.T) {
+	limit := 2500.0
+	assert.Equal(t, "0.00", formatAmount(0.0))
+	assert.Equal(t, "999.50", formatAmount(999.5))
+	assert.Equal(t, "1,234,567.89", formatAmount(1234567.891))
+	assert.Equal(t, "-1,000.00", formatAmount(-1000.0))
+	assert.Equal(t, "2,500.00", formatAmount(&limit))
+	assert.Equal(t, "–", formatAmount((*float64)(nil)))
+	assert.Equal(t, "1,250.00", formatAmount("1250.00"))
+	assert.Equal(t, "–", formatAmount((*string)(nil)))
+}
+
+func TestRenderReportHTML(t *testing.T) {
+	created := time.Date(2025, 12, 30, 9, 0, 0, 0, time.UTC)
+	start := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	dayLimit := "100.00"
+	report := &Report{
+		TenantName:   "Acme <Stores>",
+		Branding:     wallet.BrandingFromTenant(db.Tenant{Name: "Acme", Theme: []byte(`{"primary_color": "#0a7d4f"}`)}),
+		CampaignName: "Festive Cashback",
+		Status:       "active",
+		StartAt:      &start,
+		Currency:     "USD",
+		Stats: Stats{
+			Issued:   40,
+			Redeemed: 10,
+			Spend:    "1250.00",
+			Pacing:   Pacing{DayLimit: &dayLimit, DaySpend: "20.00"},
+		},
+		Budget: &ReportBudget{Name: "Q4", Currency: "USD", HardCap: 5000, Spent: 1250},
+		From:   start,
+		To:     created,
+		Bucket: "day",
+		Series: []ReportPoint{
+			{Start: start, Issued: 30, Redeemed: 5, Spend: "1000.00"},
+			{Start: start.AddDate(0, 0, 1), Issued: 10, Redeemed: 5, Spend: "250.00"},
+		},
+		TopRewards: []ReportReward{{Name: "$5 voucher", Type: "discount", Issued: 40, Redeemed: 10, Cost: "1250.00"}},
+		Location:   time.UTC,
+		CreatedAt:  created,
+	}
+
+	var b strings.Builder
+	require.NoError(t, RenderReportHTML(&b, report))
+	html := b.String()
+
+	assert.Contains(t, html, "<h1>Festive Cashback</h1>")
+	assert.Contains(t, html, "Acme &lt;Stores&gt;")
+	assert.Contains(t, html, "background: #0a7d4f")
+	assert.Contains(t, html, "25.0%", "redemption rate and budget burn")
+	assert.Contains(t, html, "USD 1,250.00")
+	assert.Contains(t, html, "Daily limit")
+	assert.Contains(t, html, `style="height: 100.0%"`, "the largest bucket fills the chart")
+	assert.Contains(t, html, `style="height: 25.0%"`)
+	assert.Contains(t, html, `"top_rewards":[{"name":"$5 voucher"`)
+	assert.Contains(t, html, `"spend":"250.00"`, "chart data keeps amounts as decimal strings")
+	assert.NotContains(t, html, "ZgotmplZ")
+
+	assert.Equal(t, "campaign-report-festive-cashback-2025-12-30.html", report.Filename())
+}
@@ -2,23 +2,46 @@ package campaign
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
+	"github.com/bmachimbira/loyalty/api/internal/catalogcache"
 	"github.com/bmachimbira/loyalty/api/internal/db"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrCampaignNotFound is returned when the campaign does not exist
+	ErrCampaignNotFound = errors.New("campaign not found")
+
+	// ErrBudgetNotFound is returned when the campaign's budget does not exist
+	ErrBudgetNotFound = errors.New("budget not found")
+
+	// ErrRewardNotFound is returned when a template references an unknown reward
+	ErrRewardNotFound = errors.New("reward not found")
+
+	// ErrInvalidDates is returned when a campaign ends before it starts
+	ErrInvalidDates = errors.New("end_at must be after start_at")
 )
 
 // Service handles campaign-related business logic
 type Service struct {
+	pool    *pgxpool.Pool
 	queries *db.Queries
+	catalog *catalogcache.Cache
 }
 
-// NewService creates a new campaign service
-func NewService(queries *db.Queries) *Service {
+// NewService creates a new campaign service. Campaign lookups go through
+// catalog, which is invalidated on every update.
+func NewService(pool *pgxpool.Pool, queries *db.Queries, catalog *catalogcache.Cache) *Service {
 	return &Service{
+		pool:    pool,
 		queries: queries,
+		catalog: catalog,
 	}
 }
 
@@ -29,14 +52,15 @@ func (s *Service) CreateCampaign(ctx context.Context, params db.CreateCampaignPa
 
 // GetCampaignByID retrieves a campaign by ID
 func (s *Service) GetCampaignByID(ctx context.Context, id, tenantID pgtype.UUID) (db.Campaign, error) {
-	return s.queries.GetCampaignByID(ctx, db.GetCampaignByIDParams{
+	return s.catalog.GetCampaignByID(ctx, db.GetCampaignByIDParams{
 		ID:       id,
 		TenantID: tenantID,
 	})
 }
 
-// ListCampaigns retrieves a paginated list of campaigns
-func (s *Service) ListCampaigns(ctx context.Context, tenantID pgtype.UUID, limit, offset string, status string) ([]db.Campaign, int, error) {
+// ListCampaigns retrieves a paginated list of campaigns. Archived campaigns
+// are only listed with includeArchived.
+func (s *Service) ListCampaigns(ctx context.Context, tenantID pgtype.UUID, limit, offset string, status string, includeArchived bool) ([]db.Campaign, int, error) {
 	// Parse limit and offset
 	limitInt, err := strconv.Atoi(limit)
 	if err != nil || limitInt < 1 {
@@ -56,17 +80,19 @@ func (s *Service) ListCampaigns(ctx context.Context, tenantID pgtype.UUID, limit
 	// If status filter is provided, use GetCampaignsByStatus
 	if status != "" {
 		campaigns, err = s.queries.GetCampaignsByStatus(ctx, db.GetCampaignsByStatusParams{
-			TenantID: tenantID,
-			Status:   status,
-			Limit:    int32(limitInt),
-			Offset:   int32(offsetInt),
+			TenantID:        tenantID,
+			Status:          status,
+			Limit:           int32(limitInt),
+			Offset:          int32(offsetInt),
+			IncludeArchived: includeArchived,
 		})
 	} else {
 		// Otherwise use ListCampaigns
 		campaigns, err = s.queries.ListCampaigns(ctx, db.ListCampaignsParams{
-			TenantID: tenantID,
-			Limit:    int32(limitInt),
-			Offset:   int32(offsetInt),
+			TenantID:        tenantID,
+			Limit:           int32(limitInt),
+			Offset:          int32(offsetInt),
+			IncludeArchived: includeArchived,
 		})
 	}
 
@@ -86,6 +112,7 @@ func (s *Service) UpdateCampaign(ctx context.Context, params db.UpdateCampaignPa
 		}
 		return fmt.Errorf("failed to update campaign: %w", err)
 	}
+	s.catalog.InvalidateCampaign(params.TenantID, params.ID)
 	return nil
 }
 
@@ -102,6 +129,7 @@ func (s *Service) UpdateCampaignStatus(ctx context.Context, id, tenantID pgtype.
 		}
 		return fmt.Errorf("failed to update campaign status: %w", err)
 	}
+	s.catalog.InvalidateCampaign(tenantID, id)
 	return nil
 }
 
@@ -109,3 +137,206 @@ func (s *Service) UpdateCampaignStatus(ctx context.Context, id, tenantID pgtype.
 func (s *Service) ListActiveCampaigns(ctx context.Context, tenantID pgtype.UUID) ([]db.Campaign, error) {
 	return s.queries.ListActiveCampaigns(ctx, tenantID)
 }
+
+// CloneParams describes how to copy a campaign
+type CloneParams struct {
+	TenantID   pgtype.UUID
+	CampaignID pgtype.UUID
+	// Name defaults to the original name with a " (copy)" suffix
+	Name string
+	// StartAt and EndAt default to the original dates. When only StartAt
+	// is given the clone keeps the original campaign's duration.
+	StartAt *time.Time
+	EndAt   *time.Time
+	// Status defaults to paused so the copy can be reviewed before going live
+	Status string
+	// FreshBudget creates an unfunded budget with the original budget's caps
+	// and alert settings instead of sharing the original budget
+	FreshBudget bool
+}
+
+// CloneResult is a cloned campaign with its copied rules
+type CloneResult struct {
+	Campaign db.Campaign
+	Rules    []db.Rule
+	Budget   *db.Budget
+}
+
+// Clone copies a campaign and its rules in a single transaction
+func (s *Service) Clone(ctx context.Context, params CloneParams) (*CloneResult, error) {
+	original, err := s.queries.GetCampaignByID(ctx, db.GetCampaignByIDParams{
+		ID:       params.CampaignID,
+		TenantID: params.TenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCampaignNotFound
+		}
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+
+	name := params.Name
+	if name == "" {
+		name = original.Name + " (copy)"
+	}
+	status := params.Status
+	if status == "" {
+		status = "paused"
+	}
+	startAt, endAt := cloneDates(original, params.StartAt, params.EndAt)
+	if startAt.Valid && endAt.Valid && !endAt.Time.After(startAt.Time) {
+		return nil, ErrInvalidDates
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	result := &CloneResult{}
+
+	budgetID := original.BudgetID
+	if params.FreshBudget && original.BudgetID.Valid {
+		budget, err := qtx.GetBudgetByID(ctx, db.GetBudgetByIDParams{
+			ID:       original.BudgetID,
+			TenantID: params.TenantID,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrBudgetNotFound
+			}
+			return nil, fmt.Errorf("failed to get budget: %w", err)
+		}
+
+		var balance pgtype.Numeric
+		balance.Scan("0")
+		fresh, err := qtx.CreateBudget(ctx, db.CreateBudgetParams{
+			TenantID:            params.TenantID,
+			Name:                name,
+			Currency:            budget.Currency,
+			SoftCap:             budget.SoftCap,
+			HardCap:             budget.HardCap,
+			Balance:             balance,
+			Period:              budget.Period,
+			AlertSoftPercent:    budget.AlertSoftPercent,
+			AlertHardPercent:    budget.AlertHardPercent,
+			AlertEmail:          budget.AlertEmail,
+			AlertWebhookUrl:     budget.AlertWebhookUrl,
+			AlertWhatsappNumber: budget.AlertWhatsappNumber,
+			AlertRepeatMinutes:  budget.AlertRepeatMinutes,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create budget: %w", err)
+		}
+		budgetID = fresh.ID
+		result.Budget = &fresh
+	}
+
+	result.Campaign, err = qtx.CreateCampaign(ctx, db.CreateCampaignParams{
+		TenantID: params.TenantID,
+		Name:     name,
+		StartAt:  startAt,
+		EndAt:    endAt,
+		BudgetID: budgetID,
+		Status:   status,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create campaign: %w", err)
+	}
+
+	if HasPacing(original) {
+		result.Campaign, err = qtx.SetCampaignPacing(ctx, db.SetCampaignPacingParams{
+			ID:              result.Campaign.ID,
+			TenantID:        params.TenantID,
+			MaxSpendPerDay:  original.MaxSpendPerDay,
+			MaxSpendPerWeek: original.MaxSpendPerWeek,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy campaign pacing: %w", err)
+		}
+	}
+
+	// A clone sharing the original budget shares its fallbacks too
+	if !params.FreshBudget {
+		fallbacks, err := qtx.ListCampaignFallbackBudgets(ctx, db.ListCampaignFallbackBudgetsParams{
+			TenantID:   params.TenantID,
+			CampaignID: original.ID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list fallback budgets: %w", err)
+		}
+		for _, fb := range fallbacks {
+			if err := qtx.AddCampaignFallbackBudget(ctx, db.AddCampaignFallbackBudgetParams{
+				TenantID:   params.TenantID,
+				CampaignID: result.Campaign.ID,
+				BudgetID:   fb.BudgetID,
+				Position:   fb.Position,
+			}); err != nil {
+				return nil, fmt.Errorf("failed to copy fallback budget: %w", err)
+			}
+		}
+	}
+
+	rules, err := qtx.ListRulesByCampaign(ctx, db.ListRulesByCampaignParams{
+		TenantID:   params.TenantID,
+		CampaignID: original.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaign rules: %w", err)
+	}
+
+	result.Rules = make([]db.Rule, 0, len(rules))
+	for _, r := range rules {
+		copied, err := qtx.CreateRule(ctx, db.CreateRuleParams{
+			TenantID:    params.TenantID,
+			CampaignID:  result.Campaign.ID,
+			Name:        r.Name,
+			EventType:   r.EventType,
+			Conditions:  r.Conditions,
+			RewardID:    r.RewardID,
+			PerUserCap:  r.PerUserCap,
+			GlobalCap:   r.GlobalCap,
+			CoolDownSec: r.CoolDownSec,
+			// Rules of a draft go live when the campaign is approved
+			Active:   r.Active && status != "draft",
+			Quantity: r.Quantity,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy rule %q: %w", r.Name, err)
+		}
+		if err := qtx.CopyRuleRewards(ctx, db.CopyRuleRewardsParams{
+			TenantID:   params.TenantID,
+			FromRuleID: r.ID,
+			ToRuleID:   copied.ID,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to copy rewards of rule %q: %w", r.Name, err)
+		}
+		result.Rules = append(result.Rules, copied)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// cloneDates resolves the dates of a cloned campaign
+func cloneDates(original db.Campaign, startAt, endAt *time.Time) (pgtype.Timestamptz, pgtype.Timestamptz) {
+	start, end := original.StartAt, original.EndAt
+
+	if startAt != nil {
+		start = pgtype.Timestamptz{Time: *startAt, Valid: true}
+		if endAt == nil && original.StartAt.Valid && original.EndAt.Valid {
+			duration := original.EndAt.Time.Sub(original.StartAt.Time)
+			end = pgtype.Timestamptz{Time: startAt.Add(duration), Valid: true}
+		}
+	}
+	if endAt != nil {
+		end = pgtype.Timestamptz{Time: *endAt, Valid: true}
+	}
+
+	return start, end
+}
@@ -0,0 +1,280 @@
+package campaign
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrTemplateNotFound is returned for an unknown campaign template
+var ErrTemplateNotFound = errors.New("campaign template not found")
+
+// ParamError is returned when template params are missing or invalid
+type ParamError struct {
+	Param  string
+	Reason string
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("param %s %s", e.Param, e.Reason)
+}
+
+// TemplateParam describes a numeric setting of a campaign template
+type TemplateParam struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Default     *float64 `json:"default,omitempty"`
+	Min         float64  `json:"min"`
+}
+
+// Template is a predefined campaign with a single rule
+type Template struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	EventType   string          `json:"event_type"`
+	Params      []TemplateParam `json:"params"`
+
+	// build returns the rule produced by the template for resolved params
+	build func(values map[string]float64) TemplateRule
+}
+
+// TemplateRule is the rule a template instantiates
+type TemplateRule struct {
+	Conditions  map[string]interface{}
+	PerUserCap  int32
+	CoolDownSec int32
+}
+
+func float(v float64) *float64 { return &v }
+
+// templates is the library of predefined campaigns
+var templates = map[string]Template{
+	"welcome_bonus": {
+		ID:          "welcome_bonus",
+		Name:        "Welcome bonus",
+		Description: "Reward customers once when they sign up.",
+		EventType:   "signup",
+		build: func(values map[string]float64) TemplateRule {
+			return TemplateRule{
+				Conditions: map[string]interface{}{
+					"==": []interface{}{map[string]interface{}{"var": "event_type"}, "signup"},
+				},
+				PerUserCap: 1,
+			}
+		},
+	},
+	"first_purchase_bonus": {
+		ID:          "first_purchase_bonus",
+		Name:        "First purchase bonus",
+		Description: "Reward a customer's first purchase within the bonus window of the welcome series.",
+		EventType:   "purchase",
+		build: func(values map[string]float64) TemplateRule {
+			return TemplateRule{
+				Conditions: map[string]interface{}{
+					"in_welcome_window": []interface{}{},
+				},
+				PerUserCap: 1,
+			}
+		},
+	},
+	"spend_threshold": {
+		ID:          "spend_threshold",
+		Name:        "Spend threshold",
+		Description: "Reward purchases at or above a minimum amount.",
+		EventType:   "purchase",
+		Params: []TemplateParam{
+			{Name: "min_amount", Description: "Minimum purchase amount", Min: 0.01},
+			{Name: "per_user_cap", Description: "Rewards per customer (0 for unlimited)", Default: float(1), Min: 0},
+			{Name: "cooldown_hours", Description: "Hours between rewards for a customer", Default: float(0), Min: 0},
+		},
+		build: func(values map[string]float64) TemplateRule {
+			return TemplateRule{
+				Conditions: map[string]interface{}{
+					">=": []interface{}{map[string]interface{}{"var": "amount"}, values["min_amount"]},
+				},
+				PerUserCap:  int32(values["per_user_cap"]),
+				CoolDownSec: int32(values["cooldown_hours"] * 3600),
+			}
+		},
+	},
+	"visit_frequency": {
+		ID:          "visit_frequency",
+		Name:        "Visit frequency",
+		Description: "Reward the Nth visit within a rolling window of days.",
+		EventType:   "visit",
+		Params: []TemplateParam{
+			{Name: "visits", Description: "Visits needed to earn the reward", Default: float(5), Min: 1},
+			{Name: "days", Description: "Length of the window in days", Default: float(30), Min: 1},
+		},
+		build: func(values map[string]float64) TemplateRule {
+			days := values["days"]
+			return TemplateRule{
+				Conditions: map[string]interface{}{
+					"nth_event_in_period": []interface{}{"visit", values["visits"], days},
+				},
+				// One reward per window
+				CoolDownSec: int32(days * 86400),
+			}
+		},
+	},
+}
+
+// Templates returns the campaign template library sorted by ID
+func Templates() []Template {
+	list := make([]Template, 0, len(templates))
+	for _, t := range templates {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// GetTemplate returns a campaign template by ID
+func GetTemplate(id string) (Template, error) {
+	t, ok := templates[id]
+	if !ok {
+		return Template{}, ErrTemplateNotFound
+	}
+	return t, nil
+}
+
+// Rule resolves template params, applying defaults, and returns the rule
+// the template produces
+func (t Template) Rule(values map[string]float64) (TemplateRule, error) {
+	resolved := make(map[string]float64, len(t.Params))
+	for _, p := range t.Params {
+		value, ok := values[p.Name]
+		if !ok {
+			if p.Default == nil {
+				return TemplateRule{}, &ParamError{Param: p.Name, Reason: "is required"}
+			}
+			value = *p.Default
+		}
+		if value < p.Min {
+			return TemplateRule{}, &ParamError{Param: p.Name, Reason: fmt.Sprintf("must be at least %g", p.Min)}
+		}
+		resolved[p.Name] = value
+	}
+	for name := range values {
+		if _, ok := resolved[name]; !ok {
+			return TemplateRule{}, &ParamError{Param: name, Reason: "is not supported by this template"}
+		}
+	}
+	return t.build(resolved), nil
+}
+
+// FromTemplateParams contains the fields needed to instantiate a template
+type FromTemplateParams struct {
+	TenantID   pgtype.UUID
+	TemplateID string
+	Name       string
+	RewardID   pgtype.UUID
+	BudgetID   pgtype.UUID
+	StartAt    pgtype.Timestamptz
+	EndAt      pgtype.Timestamptz
+	Status     string
+	Values     map[string]float64
+}
+
+// FromTemplate creates a campaign and its rule from a template
+func (s *Service) FromTemplate(ctx context.Context, params FromTemplateParams) (*CloneResult, error) {
+	template, err := GetTemplate(params.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	rule, err := template.Rule(params.Values)
+	if err != nil {
+		return nil, err
+	}
+	if params.StartAt.Valid && params.EndAt.Valid && !params.EndAt.Time.After(params.StartAt.Time) {
+		return nil, ErrInvalidDates
+	}
+
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		name = template.Name
+	}
+	status := params.Status
+	if status == "" {
+		status = "active"
+	}
+
+	conditions, err := json.Marshal(rule.Conditions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal conditions: %w", err)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	if _, err := qtx.GetRewardByID(ctx, db.GetRewardByIDParams{
+		ID:       params.RewardID,
+		TenantID: params.TenantID,
+	}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRewardNotFound
+		}
+		return nil, fmt.Errorf("failed to get reward: %w", err)
+	}
+
+	if params.BudgetID.Valid {
+		if _, err := qtx.GetBudgetByID(ctx, db.GetBudgetByIDParams{
+			ID:       params.BudgetID,
+			TenantID: params.TenantID,
+		}); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrBudgetNotFound
+			}
+			return nil, fmt.Errorf("failed to get budget: %w", err)
+		}
+	}
+
+	result := &CloneResult{}
+	result.Campaign, err = qtx.CreateCampaign(ctx, db.CreateCampaignParams{
+		TenantID: params.TenantID,
+		Name:     name,
+		StartAt:  params.StartAt,
+		EndAt:    params.EndAt,
+		BudgetID: params.BudgetID,
+		Status:   status,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create campaign: %w", err)
+	}
+
+	created, err := qtx.CreateRule(ctx, db.CreateRuleParams{
+		TenantID:    params.TenantID,
+		CampaignID:  result.Campaign.ID,
+		Name:        name,
+		EventType:   template.EventType,
+		Conditions:  conditions,
+		RewardID:    params.RewardID,
+		PerUserCap:  rule.PerUserCap,
+		CoolDownSec: rule.CoolDownSec,
+		Active:      status != "draft",
+		Quantity:    1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rule: %w", err)
+	}
+	result.Rules = []db.Rule{created}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
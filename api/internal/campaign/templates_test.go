@@ -0,0 +1,78 @@
+package campaign
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplatesSorted(t *testing.T) {
+	list := Templates()
+	require.Len(t, list, 4)
+	assert.Equal(t, "first_purchase_bonus", list[0].ID)
+	assert.Equal(t, "spend_threshold", list[1].ID)
+	assert.Equal(t, "visit_frequency", list[2].ID)
+	assert.Equal(t, "welcome_bonus", list[3].ID)
+
+	_, err := GetTemplate("double_points")
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
+
+func TestTemplateRule(t *testing.T) {
+	spend, err := GetTemplate("spend_threshold")
+	require.NoError(t, err)
+
+	_, err = spend.Rule(nil)
+	assert.EqualError(t, err, "param min_amount is required")
+
+	_, err = spend.Rule(map[string]float64{"min_amount": 0})
+	assert.Error(t, err)
+
+	_, err = spend.Rule(map[string]float64{"min_amount": 50, "bonus": 1})
+	assert.EqualError(t, err, "param bonus is not supported by this template")
+
+	rule, err := spend.Rule(map[string]float64{"min_amount": 50, "cooldown_hours": 24})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), rule.PerUserCap)
+	assert.Equal(t, int32(86400), rule.CoolDownSec)
+	assert.Equal(t, []interface{}{map[string]interface{}{"var": "amount"}, 50.0}, rule.Conditions[">="])
+
+	visits, err := GetTemplate("visit_frequency")
+	require.NoError(t, err)
+	rule, err = visits.Rule(map[string]float64{"visits": 3})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"visit", 3.0, 30.0}, rule.Conditions["nth_event_in_period"])
+	assert.Equal(t, int32(30*86400), rule.CoolDownSec)
+
+	firstPurchase, err := GetTemplate("first_purchase_bonus")
+	require.NoError(t, err)
+	rule, err = firstPurchase.Rule(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{}, rule.Conditions["in_welcome_window"])
+	assert.Equal(t, int32(1), rule.PerUserCap)
+}
+
+func TestCloneDates(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	original := db.Campaign{
+		StartAt: pgtype.Timestamptz{Time: start, Valid: true},
+		EndAt:   pgtype.Timestamptz{Time: start.AddDate(0, 0, 14), Valid: true},
+	}
+
+	s, e := cloneDates(original, nil, nil)
+	assert.Equal(t, original.StartAt, s)
+	assert.Equal(t, original.EndAt, e)
+
+	newStart := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	s, e = cloneDates(original, &newStart, nil)
+	assert.Equal(t, newStart, s.Time)
+	assert.Equal(t, newStart.AddDate(0, 0, 14), e.Time)
+
+	newEnd := newStart.AddDate(0, 1, 0)
+	_, e = cloneDates(original, &newStart, &newEnd)
+	assert.Equal(t, newEnd, e.Time)
+}
@@ -0,0 +1,236 @@
+package catalogcache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/metrics"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Cache names used for hit/miss metrics
+const (
+	metricRewards     = "rewards"
+	metricRewardLists = "reward_lists"
+	metricCampaigns   = "campaigns"
+)
+
+// Cache is a read-through TTL cache for reward catalog and campaign reads.
+// Its read methods mirror the db.Queries signatures so it can stand in for
+// them on hot paths. Mutations must call the Invalidate methods.
+type Cache struct {
+	queries     *db.Queries
+	rewards     *ttlMap[db.RewardCatalog]
+	rewardLists *ttlMap[[]db.RewardCatalog]
+	campaigns   *ttlMap[db.Campaign]
+	hits        atomic.Int64
+	misses      atomic.Int64
+}
+
+// Stats reports cache effectiveness
+type Stats struct {
+	Hits    int64
+	Misses  int64
+	Entries int
+}
+
+// New creates a new catalog cache with the specified TTL
+func New(queries *db.Queries, ttl time.Duration) *Cache {
+	return &Cache{
+		queries:     queries,
+		rewards:     newTTLMap[db.RewardCatalog](ttl),
+		rewardLists: newTTLMap[[]db.RewardCatalog](ttl),
+		campaigns:   newTTLMap[db.Campaign](ttl),
+	}
+}
+
+// GetRewardByID returns a reward, loading it from the database on a miss
+func (c *Cache) GetRewardByID(ctx context.Context, arg db.GetRewardByIDParams) (db.RewardCatalog, error) {
+	key := itemKey(arg.TenantID, arg.ID)
+	if reward, ok := c.rewards.get(key); ok {
+		c.hit(metricRewards)
+		return reward, nil
+	}
+	c.miss(metricRewards)
+
+	reward, err := c.queries.GetRewardByID(ctx, arg)
+	if err != nil {
+		return db.RewardCatalog{}, err
+	}
+	c.rewards.set(key, reward)
+	return reward, nil
+}
+
+// ListRewards returns all rewards for a tenant
+func (c *Cache) ListRewards(ctx context.Context, tenantID pgtype.UUID) ([]db.RewardCatalog, error) {
+	key := listKey(tenantID, "all")
+	if rewards, ok := c.rewardLists.get(key); ok {
+		c.hit(metricRewardLists)
+		return rewards, nil
+	}
+	c.miss(metricRewardLists)
+
+	rewards, err := c.queries.ListRewards(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	c.rewardLists.set(key, rewards)
+	return rewards, nil
+}
+
+// ListActiveRewards returns the active rewards for a tenant
+func (c *Cache) ListActiveRewards(ctx context.Context, tenantID pgtype.UUID) ([]db.RewardCatalog, error) {
+	key := listKey(tenantID, "active")
+	if rewards, ok := c.rewardLists.get(key); ok {
+		c.hit(metricRewardLists)
+		return rewards, nil
+	}
+	c.miss(metricRewardLists)
+
+	rewards, err := c.queries.ListActiveRewards(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	c.rewardLists.set(key, rewards)
+	return rewards, nil
+}
+
+// GetCampaignByID returns a campaign, loading it from the database on a miss
+func (c *Cache) GetCampaignByID(ctx context.Context, arg db.GetCampaignByIDParams) (db.Campaign, error) {
+	key := itemKey(arg.TenantID, arg.ID)
+	if campaign, ok := c.campaigns.get(key); ok {
+		c.hit(metricCampaigns)
+		return campaign, nil
+	}
+	c.miss(metricCampaigns)
+
+	campaign, err := c.queries.GetCampaignByID(ctx, arg)
+	if err != nil {
+		return db.Campaign{}, err
+	}
+	c.campaigns.set(key, campaign)
+	return campaign, nil
+}
+
+// InvalidateReward drops a reward and the tenant's reward lists
+func (c *Cache) InvalidateReward(tenantID, rewardID pgtype.UUID) {
+	c.rewards.delete(itemKey(tenantID, rewardID))
+	c.InvalidateRewardLists(tenantID)
+}
+
+// InvalidateRewardLists drops the tenant's cached reward lists, e.g. after a
+// reward is created
+func (c *Cache) InvalidateRewardLists(tenantID pgtype.UUID) {
+	c.rewardLists.delete(listKey(tenantID, "all"))
+	c.rewardLists.delete(listKey(tenantID, "active"))
+}
+
+// InvalidateCampaign drops a cached campaign
+func (c *Cache) InvalidateCampaign(tenantID, campaignID pgtype.UUID) {
+	c.campaigns.delete(itemKey(tenantID, campaignID))
+}
+
+// Clear removes all entries from the cache
+func (c *Cache) Clear() {
+	c.rewards.clear()
+	c.rewardLists.clear()
+	c.campaigns.clear()
+}
+
+// Stats returns hit/miss counts and the current number of entries
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+		Entries: c.rewards.size() + c.rewardLists.size() + c.campaigns.size(),
+	}
+}
+
+func (c *Cache) hit(name string) {
+	c.hits.Add(1)
+	metrics.RecordCacheHit(name)
+}
+
+func (c *Cache) miss(name string) {
+	c.misses.Add(1)
+	metrics.RecordCacheMiss(name)
+}
+
+// itemKey builds the cache key for a tenant-scoped record
+func itemKey(tenantID, id pgtype.UUID) string {
+	return httputil.FormatUUID(tenantID.Bytes) + ":" + httputil.FormatUUID(id.Bytes)
+}
+
+// listKey builds the cache key for a tenant-scoped list
+func listKey(tenantID pgtype.UUID, variant string) string {
+	return httputil.FormatUUID(tenantID.Bytes) + ":" + variant
+}
+
+// ttlMap is a thread-safe map whose entries expire after a fixed TTL
+type ttlMap[V any] struct {
+	mu    sync.RWMutex
+	items map[string]ttlEntry[V]
+	ttl   time.Duration
+}
+
+// ttlEntry represents a cached value
+type ttlEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+func newTTLMap[V any](ttl time.Duration) *ttlMap[V] {
+	return &ttlMap[V]{
+		items: make(map[string]ttlEntry[V]),
+		ttl:   ttl,
+	}
+}
+
+func (m *ttlMap[V]) get(key string) (V, bool) {
+	m.mu.RLock()
+	entry, ok := m.items[key]
+	m.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
+
+func (m *ttlMap[V]) set(key string, value V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Sweep expired entries opportunistically so the map stays bounded
+	now := time.Now()
+	for k, entry := range m.items {
+		if now.After(entry.expiresAt) {
+			delete(m.items, k)
+		}
+	}
+
+	m.items[key] = ttlEntry[V]{value: value, expiresAt: now.Add(m.ttl)}
+}
+
+func (m *ttlMap[V]) delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.items, key)
+}
+
+func (m *ttlMap[V]) clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items = make(map[string]ttlEntry[V])
+}
+
+func (m *ttlMap[V]) size() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.items)
+}
@@ -0,0 +1,48 @@
+package catalogcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTLMapExpiry(t *testing.T) {
+	m := newTTLMap[string](20 * time.Millisecond)
+
+	m.set("a", "reward")
+	value, ok := m.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "reward", value)
+
+	time.Sleep(30 * time.Millisecond)
+	_, ok = m.get("a")
+	assert.False(t, ok)
+
+	// Expired entries are swept on the next write
+	m.set("b", "campaign")
+	assert.Equal(t, 1, m.size())
+}
+
+func TestInvalidateRewardDropsTenantLists(t *testing.T) {
+	c := New(nil, time.Minute)
+
+	var tenantID, otherTenantID, rewardID pgtype.UUID
+	tenantID.Scan("11111111-1111-1111-1111-111111111111")
+	otherTenantID.Scan("22222222-2222-2222-2222-222222222222")
+	rewardID.Scan("33333333-3333-3333-3333-333333333333")
+
+	c.rewardLists.set(listKey(tenantID, "all"), nil)
+	c.rewardLists.set(listKey(tenantID, "active"), nil)
+	c.rewardLists.set(listKey(otherTenantID, "all"), nil)
+
+	c.InvalidateReward(tenantID, rewardID)
+
+	_, ok := c.rewardLists.get(listKey(tenantID, "all"))
+	assert.False(t, ok)
+	_, ok = c.rewardLists.get(listKey(tenantID, "active"))
+	assert.False(t, ok)
+	_, ok = c.rewardLists.get(listKey(otherTenantID, "all"))
+	assert.True(t, ok)
+}
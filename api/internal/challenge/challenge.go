@@ -0,0 +1,207 @@
+// Package challenge runs gamified challenges: goals such as "visit 3 times
+// this week" or "shop every week for 4 weeks" that customers work towards
+// with their events. Progress is tracked as events are processed by the
+// rules engine, and completing a challenge emits a challenge_completed event
+// rules can reward.
+package challenge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Challenge kinds
+const (
+	// KindCount is met by target qualifying events within one period
+	KindCount = "count"
+	// KindStreak is met by qualifying events in target consecutive periods
+	KindStreak = "streak"
+)
+
+// Challenge periods. Weeks start on Monday; periods are in UTC.
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+	// PeriodNone counts events over the whole challenge, for count
+	// challenges only
+	PeriodNone = "none"
+)
+
+// EventTypeChallengeCompleted is emitted when a customer completes a
+// challenge
+const EventTypeChallengeCompleted = "challenge_completed"
+
+// Params contains the fields of a challenge
+type Params struct {
+	TenantID    pgtype.UUID
+	Name        string
+	Description string
+	Kind        string
+	EventType   string
+	Criteria    json.RawMessage
+	Target      int32
+	Period      string
+	StartsAt    *time.Time
+	EndsAt      *time.Time
+	Active      bool
+}
+
+// Validate validates the challenge parameters. Whether the event type
+// exists for the tenant is checked by the service.
+func (p Params) Validate() error {
+	if !p.TenantID.Valid {
+		return errors.New("tenant_id is required")
+	}
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("name is required")
+	}
+	if p.Kind != KindCount && p.Kind != KindStreak {
+		return fmt.Errorf("kind must be %s or %s", KindCount, KindStreak)
+	}
+	if p.EventType == "" {
+		return errors.New("event_type is required")
+	}
+	if p.EventType == EventTypeChallengeCompleted {
+		return fmt.Errorf("event_type cannot be %s", EventTypeChallengeCompleted)
+	}
+	if len(p.Criteria) > 0 {
+		var criteria map[string]interface{}
+		if err := json.Unmarshal(p.Criteria, &criteria); err != nil {
+			return errors.New("criteria must be a JSON Logic object")
+		}
+	}
+	if p.Target < 1 {
+		return errors.New("target must be at least 1")
+	}
+	switch p.Period {
+	case PeriodDay, PeriodWeek, PeriodMonth:
+	case PeriodNone:
+		if p.Kind == KindStreak {
+			return errors.New("streak challenges need a period of day, week or month")
+		}
+	default:
+		return fmt.Errorf("period must be one of %s, %s, %s, %s", PeriodDay, PeriodWeek, PeriodMonth, PeriodNone)
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// PeriodStart returns the start of the period containing t, in loc, the
+// tenant's time zone. For PeriodNone it returns origin, the start of the
+// challenge.
+func PeriodStart(period string, t, origin time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	switch period {
+	case PeriodDay:
+		return day
+	case PeriodWeek:
+		// Days since Monday
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case PeriodMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	default:
+		return origin.In(loc)
+	}
+}
+
+// NextPeriod returns the start of the period after the one starting at start
+func NextPeriod(period string, start time.Time) time.Time {
+	switch period {
+	case PeriodDay:
+		return start.AddDate(0, 0, 1)
+	case PeriodWeek:
+		return start.AddDate(0, 0, 7)
+	case PeriodMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start
+	}
+}
+
+// State is a customer's progress on one attempt at a challenge
+type State struct {
+	// PeriodStart is the period counted, or the first period of a streak
+	PeriodStart time.Time
+	// LastPeriod is the latest period with a qualifying event
+	LastPeriod time.Time
+	Progress   int32
+	Completed  bool
+}
+
+// Advance applies a qualifying event in period to a customer's progress.
+// current is the attempt the event belongs to: the progress for the
+// period of a count challenge, or the latest streak; nil if there is none.
+// It returns the new state and whether it differs from current. A new
+// attempt is started when the state's PeriodStart differs from current's.
+func Advance(kind string, period string, target int32, current *State, eventPeriod time.Time) (State, bool) {
+	start := func() (State, bool) {
+		return State{PeriodStart: eventPeriod, LastPeriod: eventPeriod, Progress: 1, Completed: target <= 1}, true
+	}
+
+	if current == nil {
+		return start()
+	}
+	next := *current
+
+	switch kind {
+	case KindStreak:
+		switch {
+		case !eventPeriod.After(current.LastPeriod):
+			// Already counted this period, or a late event for a past one
+			return next, false
+		case !current.Completed && NextPeriod(period, current.LastPeriod).Equal(eventPeriod):
+			next.LastPeriod = eventPeriod
+			next.Progress++
+		default:
+			// The streak was completed or broken; a new one starts
+			return start()
+		}
+
+	default:
+		if current.Completed {
+			return next, false
+		}
+		next.Progress++
+	}
+
+	next.Completed = next.Progress >= target
+	return next, true
+}
+
+// Current returns the progress shown for a customer's latest attempt at a
+// challenge as of now: count progress resets each period and streaks are
+// broken by a period without a qualifying event. Periods are read in loc.
+func Current(kind string, period string, latest *State, now, origin time.Time, loc *time.Location) State {
+	nowPeriod := PeriodStart(period, now, origin, loc)
+	empty := State{PeriodStart: nowPeriod, LastPeriod: nowPeriod}
+	if latest == nil {
+		return empty
+	}
+
+	switch kind {
+	case KindStreak:
+		// A streak stays alive until a period passes without an event
+		if latest.LastPeriod.Equal(nowPeriod) || NextPeriod(period, latest.LastPeriod).Equal(nowPeriod) {
+			if latest.Completed && !latest.LastPeriod.Equal(nowPeriod) {
+				return empty
+			}
+			return *latest
+		}
+		return empty
+
+	default:
+		if latest.PeriodStart.Equal(nowPeriod) {
+			return *latest
+		}
+		return empty
+	}
+}
@@ -0,0 +1,180 @@
+package challenge
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestPeriodStart(t *testing.T) {
+	// Wednesday afternoon
+	at := time.Date(2025, 12, 17, 15, 30, 0, 0, time.UTC)
+	origin := date(2025, 12, 1)
+
+	assert.Equal(t, date(2025, 12, 17), PeriodStart(PeriodDay, at, origin, time.UTC))
+	assert.Equal(t, date(2025, 12, 15), PeriodStart(PeriodWeek, at, origin, time.UTC))
+	assert.Equal(t, date(2025, 12, 1), PeriodStart(PeriodMonth, at, origin, time.UTC))
+	assert.Equal(t, origin, PeriodStart(PeriodNone, at, origin, time.UTC))
+
+	// Sunday belongs to the week that started on Monday
+	assert.Equal(t, date(2025, 12, 15), PeriodStart(PeriodWeek, date(2025, 12, 21), origin, time.UTC))
+	assert.Equal(t, date(2025, 12, 22), PeriodStart(PeriodWeek, date(2025, 12, 22), origin, time.UTC))
+
+	// Times in other zones are placed in UTC periods
+	harare := time.FixedZone("CAT", 2*60*60)
+	assert.Equal(t, date(2025, 12, 16), PeriodStart(PeriodDay, time.Date(2025, 12, 17, 1, 0, 0, 0, harare), origin, time.UTC))
+
+	// Periods follow the tenant's time zone: 23:00 UTC is already the next
+	// day in Harare
+	lateUTC := time.Date(2025, 12, 17, 23, 0, 0, 0, time.UTC)
+	assert.True(t, time.Date(2025, 12, 18, 0, 0, 0, 0, harare).Equal(PeriodStart(PeriodDay, lateUTC, origin, harare)))
+}
+
+func TestAdvanceCount(t *testing.T) {
+	week := date(2025, 12, 15)
+
+	state, changed := Advance(KindCount, PeriodWeek, 3, nil, week)
+	require.True(t, changed)
+	assert.Equal(t, State{PeriodStart: week, LastPeriod: week, Progress: 1}, state)
+
+	state, changed = Advance(KindCount, PeriodWeek, 3, &state, week)
+	require.True(t, changed)
+	assert.Equal(t, int32(2), state.Progress)
+	assert.False(t, state.Completed)
+
+	state, changed = Advance(KindCount, PeriodWeek, 3, &state, week)
+	require.True(t, changed)
+	assert.Equal(t, int32(3), state.Progress)
+	assert.True(t, state.Completed)
+
+	// Completed challenges stop counting for the period
+	_, changed = Advance(KindCount, PeriodWeek, 3, &state, week)
+	assert.False(t, changed)
+}
+
+func TestAdvanceStreak(t *testing.T) {
+	week1 := date(2025, 12, 1)
+	week2 := date(2025, 12, 8)
+	week3 := date(2025, 12, 15)
+	week5 := date(2025, 12, 29)
+
+	state, changed := Advance(KindStreak, PeriodWeek, 3, nil, week1)
+	require.True(t, changed)
+	assert.Equal(t, int32(1), state.Progress)
+
+	// A second event in the same week does not extend the streak
+	_, changed = Advance(KindStreak, PeriodWeek, 3, &state, week1)
+	assert.False(t, changed)
+
+	state, changed = Advance(KindStreak, PeriodWeek, 3, &state, week2)
+	require.True(t, changed)
+	assert.Equal(t, State{PeriodStart: week1, LastPeriod: week2, Progress: 2}, state)
+
+	// A late event for a past week is ignored
+	_, changed = Advance(KindStreak, PeriodWeek, 3, &state, week1)
+	assert.False(t, changed)
+
+	state, changed = Advance(KindStreak, PeriodWeek, 3, &state, week3)
+	require.True(t, changed)
+	assert.Equal(t, int32(3), state.Progress)
+	assert.True(t, state.Completed)
+
+	// After a completed or broken streak a new one starts
+	state, changed = Advance(KindStreak, PeriodWeek, 3, &state, week5)
+	require.True(t, changed)
+	assert.Equal(t, State{PeriodStart: week5, LastPeriod: week5, Progress: 1}, state)
+
+	broken := State{PeriodStart: week1, LastPeriod: week1, Progress: 1}
+	state, changed = Advance(KindStreak, PeriodWeek, 3, &broken, week3)
+	require.True(t, changed)
+	assert.Equal(t, week3, state.PeriodStart)
+	assert.Equal(t, int32(1), state.Progress)
+}
+
+func TestAdvanceTargetOfOne(t *testing.T) {
+	state, changed := Advance(KindCount, PeriodDay, 1, nil, date(2025, 12, 17))
+	require.True(t, changed)
+	assert.True(t, state.Completed)
+}
+
+func TestCurrent(t *testing.T) {
+	origin := date(2025, 11, 1)
+	now := time.Date(2025, 12, 17, 12, 0, 0, 0, time.UTC)
+	thisWeek := date(2025, 12, 15)
+	lastWeek := date(2025, 12, 8)
+
+	t.Run("no progress", func(t *testing.T) {
+		current := Current(KindCount, PeriodWeek, nil, now, origin, time.UTC)
+		assert.Equal(t, int32(0), current.Progress)
+		assert.Equal(t, thisWeek, current.PeriodStart)
+	})
+
+	t.Run("count resets each period", func(t *testing.T) {
+		latest := &State{PeriodStart: lastWeek, LastPeriod: lastWeek, Progress: 2}
+		assert.Equal(t, int32(0), Current(KindCount, PeriodWeek, latest, now, origin, time.UTC).Progress)
+
+		latest = &State{PeriodStart: thisWeek, LastPeriod: thisWeek, Progress: 2}
+		assert.Equal(t, int32(2), Current(KindCount, PeriodWeek, latest, now, origin, time.UTC).Progress)
+	})
+
+	t.Run("streak continues from last period", func(t *testing.T) {
+		latest := &State{PeriodStart: date(2025, 12, 1), LastPeriod: lastWeek, Progress: 2}
+		assert.Equal(t, int32(2), Current(KindStreak, PeriodWeek, latest, now, origin, time.UTC).Progress)
+	})
+
+	t.Run("streak broken by a missed period", func(t *testing.T) {
+		latest := &State{PeriodStart: date(2025, 11, 24), LastPeriod: date(2025, 12, 1), Progress: 2}
+		assert.Equal(t, int32(0), Current(KindStreak, PeriodWeek, latest, now, origin, time.UTC).Progress)
+	})
+
+	t.Run("completed streak shown until the period ends", func(t *testing.T) {
+		latest := &State{PeriodStart: lastWeek, LastPeriod: thisWeek, Progress: 2, Completed: true}
+		assert.True(t, Current(KindStreak, PeriodWeek, latest, now, origin, time.UTC).Completed)
+
+		latest = &State{PeriodStart: date(2025, 12, 1), LastPeriod: lastWeek, Progress: 2, Completed: true}
+		assert.False(t, Current(KindStreak, PeriodWeek, latest, now, origin, time.UTC).Completed)
+	})
+}
+
+func TestParamsValidate(t *testing.T) {
+	valid := Params{
+		TenantID:  pgtype.UUID{Bytes: [16]byte{1}, Valid: true},
+		Name:      "Visit 3 times this week",
+		Kind:      KindCount,
+		EventType: "visit",
+		Target:    3,
+		Period:    PeriodWeek,
+	}
+	require.NoError(t, valid.Validate())
+
+	start := date(2025, 12, 1)
+	tests := []struct {
+		name   string
+		modify func(p *Params)
+	}{
+		{"missing name", func(p *Params) { p.Name = " " }},
+		{"unknown kind", func(p *Params) { p.Kind = "race" }},
+		{"missing event type", func(p *Params) { p.EventType = "" }},
+		{"completion event type", func(p *Params) { p.EventType = EventTypeChallengeCompleted }},
+		{"criteria not an object", func(p *Params) { p.Criteria = json.RawMessage(`[1]`) }},
+		{"zero target", func(p *Params) { p.Target = 0 }},
+		{"unknown period", func(p *Params) { p.Period = "year" }},
+		{"streak without period", func(p *Params) { p.Kind, p.Period = KindStreak, PeriodNone }},
+		{"ends before start", func(p *Params) { end := start.Add(-time.Hour); p.StartsAt, p.EndsAt = &start, &end }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := valid
+			tt.modify(&p)
+			assert.Error(t, p.Validate())
+		})
+	}
+}
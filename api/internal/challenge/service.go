@@ -0,0 +1,204 @@
+package challenge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/event"
+	"github.com/bmachimbira/loyalty/api/internal/timezone"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	// ErrChallengeNotFound is returned when the challenge does not exist
+	ErrChallengeNotFound = errors.New("challenge not found")
+
+	// ErrUnknownEventType is returned for a challenge on an event type that
+	// is neither built in nor registered by the tenant
+	ErrUnknownEventType = errors.New("unknown event type")
+)
+
+// Progress is a customer's current progress on a challenge
+type Progress struct {
+	Challenge db.Challenge
+	State
+	CompletedAt pgtype.Timestamptz
+}
+
+// Service manages challenges
+type Service struct {
+	queries *db.Queries
+	events  *event.Service
+}
+
+// NewService creates a new challenge service
+func NewService(queries *db.Queries) *Service {
+	return &Service{
+		queries: queries,
+		events:  event.NewService(queries),
+	}
+}
+
+// Create creates a new challenge
+func (s *Service) Create(ctx context.Context, params Params) (db.Challenge, error) {
+	if err := s.validate(ctx, params); err != nil {
+		return db.Challenge{}, err
+	}
+
+	challenge, err := s.queries.CreateChallenge(ctx, db.CreateChallengeParams{
+		TenantID:    params.TenantID,
+		Name:        strings.TrimSpace(params.Name),
+		Description: params.Description,
+		Kind:        params.Kind,
+		EventType:   params.EventType,
+		Criteria:    params.Criteria,
+		Target:      params.Target,
+		Period:      params.Period,
+		StartsAt:    timestamptz(params.StartsAt),
+		EndsAt:      timestamptz(params.EndsAt),
+		Active:      params.Active,
+	})
+	if err != nil {
+		return db.Challenge{}, fmt.Errorf("failed to create challenge: %w", err)
+	}
+	return challenge, nil
+}
+
+// Get retrieves a challenge by ID
+func (s *Service) Get(ctx context.Context, tenantID, challengeID pgtype.UUID) (db.Challenge, error) {
+	challenge, err := s.queries.GetChallengeByID(ctx, db.GetChallengeByIDParams{
+		ID:       challengeID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Challenge{}, ErrChallengeNotFound
+		}
+		return db.Challenge{}, fmt.Errorf("failed to get challenge: %w", err)
+	}
+	return challenge, nil
+}
+
+// List lists the tenant's challenges
+func (s *Service) List(ctx context.Context, tenantID pgtype.UUID) ([]db.Challenge, error) {
+	challenges, err := s.queries.ListChallenges(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list challenges: %w", err)
+	}
+	return challenges, nil
+}
+
+// Update replaces the editable fields of a challenge. The kind, event type
+// and period cannot change, as progress already made is counted by them.
+func (s *Service) Update(ctx context.Context, challengeID pgtype.UUID, params Params) (db.Challenge, error) {
+	if err := params.Validate(); err != nil {
+		return db.Challenge{}, err
+	}
+
+	challenge, err := s.queries.UpdateChallenge(ctx, db.UpdateChallengeParams{
+		ID:          challengeID,
+		TenantID:    params.TenantID,
+		Name:        strings.TrimSpace(params.Name),
+		Description: params.Description,
+		Criteria:    params.Criteria,
+		Target:      params.Target,
+		StartsAt:    timestamptz(params.StartsAt),
+		EndsAt:      timestamptz(params.EndsAt),
+		Active:      params.Active,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Challenge{}, ErrChallengeNotFound
+		}
+		return db.Challenge{}, fmt.Errorf("failed to update challenge: %w", err)
+	}
+	return challenge, nil
+}
+
+// CustomerProgress returns a customer's current progress on each of the
+// tenant's active challenges
+func (s *Service) CustomerProgress(ctx context.Context, tenantID, customerID pgtype.UUID, now time.Time) ([]Progress, error) {
+	challenges, err := s.queries.ListActiveChallenges(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list challenges: %w", err)
+	}
+
+	zone, err := s.queries.GetTenantTimezone(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant timezone: %w", err)
+	}
+	loc := timezone.Load(zone)
+
+	rows, err := s.queries.ListLatestChallengeProgressForCustomer(ctx, db.ListLatestChallengeProgressForCustomerParams{
+		TenantID:   tenantID,
+		CustomerID: customerID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list challenge progress: %w", err)
+	}
+	latest := make(map[[16]byte]db.ChallengeProgress, len(rows))
+	for _, row := range rows {
+		latest[row.ChallengeID.Bytes] = row
+	}
+
+	progress := make([]Progress, len(challenges))
+	for i, challenge := range challenges {
+		var state *State
+		row, ok := latest[challenge.ID.Bytes]
+		if ok {
+			state = stateOf(row, loc)
+		}
+
+		current := Current(challenge.Kind, challenge.Period, state, now, origin(challenge), loc)
+		progress[i] = Progress{Challenge: challenge, State: current}
+		if ok && current.Completed {
+			progress[i].CompletedAt = row.CompletedAt
+		}
+	}
+	return progress, nil
+}
+
+// validate validates a new challenge, including its event type
+func (s *Service) validate(ctx context.Context, params Params) error {
+	if err := params.Validate(); err != nil {
+		return err
+	}
+	if err := s.events.ValidateEventType(ctx, params.TenantID, params.EventType); err != nil {
+		if errors.Is(err, event.ErrUnknownEventType) {
+			return ErrUnknownEventType
+		}
+		return err
+	}
+	return nil
+}
+
+// origin is the start of a challenge, which PeriodNone counts from
+func origin(challenge db.Challenge) time.Time {
+	if challenge.StartsAt.Valid {
+		return challenge.StartsAt.Time
+	}
+	return challenge.CreatedAt.Time
+}
+
+// stateOf returns the state of a progress row, with its periods in loc
+func stateOf(row db.ChallengeProgress, loc *time.Location) *State {
+	return &State{
+		PeriodStart: row.PeriodStart.Time.In(loc),
+		LastPeriod:  row.LastPeriod.Time.In(loc),
+		Progress:    row.Progress,
+		Completed:   row.CompletedAt.Valid,
+	}
+}
+
+// timestamptz converts an optional time
+func timestamptz(t *time.Time) pgtype.Timestamptz {
+	if t == nil {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: *t, Valid: true}
+}
@@ -0,0 +1,275 @@
+package challenge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/deadletter"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/rules"
+	"github.com/bmachimbira/loyalty/api/internal/timezone"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Tracker updates customers' challenge progress as their events are
+// processed. It is set on the rules engine with SetChallengeTracker.
+type Tracker struct {
+	pool        *pgxpool.Pool
+	queries     *db.Queries
+	evaluator   *rules.Evaluator
+	processor   deadletter.EventProcessor
+	deadLetters *deadletter.Service
+	logger      *slog.Logger
+}
+
+// NewTracker creates a new challenge tracker. challenge_completed events are
+// run through processor so rules can reward them.
+func NewTracker(pool *pgxpool.Pool, queries *db.Queries, processor deadletter.EventProcessor, logger *slog.Logger) *Tracker {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Tracker{
+		pool:        pool,
+		queries:     queries,
+		evaluator:   rules.NewEvaluator(rules.NewCustomOperators(pool)),
+		processor:   processor,
+		deadLetters: deadletter.NewService(queries, processor),
+		logger:      logger,
+	}
+}
+
+// Track counts an event towards the customer's active challenges on its
+// event type. Each event counts once per challenge, however often it is
+// processed. It returns the rewards granted for the challenges completed.
+func (t *Tracker) Track(ctx context.Context, event db.Event) ([]db.Issuance, error) {
+	if !event.CustomerID.Valid || event.EventType == EventTypeChallengeCompleted {
+		return nil, nil
+	}
+
+	challenges, err := t.queries.ListActiveChallengesForEvent(ctx, db.ListActiveChallengesForEventParams{
+		TenantID:  event.TenantID,
+		EventType: event.EventType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list challenges: %w", err)
+	}
+	if len(challenges) == 0 {
+		return nil, nil
+	}
+
+	data, err := rules.EventData(event)
+	if err != nil {
+		return nil, err
+	}
+
+	// Periods are days, weeks and months in the tenant's time zone
+	zone, err := t.queries.GetTenantTimezone(ctx, event.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant timezone: %w", err)
+	}
+	loc := timezone.Load(zone)
+
+	var completions []db.Event
+	for _, challenge := range challenges {
+		if !inWindow(challenge, event.OccurredAt.Time) {
+			continue
+		}
+		if len(challenge.Criteria) > 0 {
+			matched, err := t.evaluator.Evaluate(ctx, challenge.Criteria, data)
+			if err != nil {
+				t.logger.Warn("challenge criteria evaluation error",
+					"challenge_id", httputil.FormatUUID(challenge.ID.Bytes),
+					"error", err,
+				)
+				continue
+			}
+			if !matched {
+				continue
+			}
+		}
+
+		completion, err := t.advance(ctx, challenge, event, loc)
+		if err != nil {
+			return nil, err
+		}
+		if completion != nil {
+			completions = append(completions, *completion)
+		}
+	}
+
+	var issuances []db.Issuance
+	for _, completion := range completions {
+		granted, err := t.processor.ProcessEvent(ctx, completion)
+		if err != nil {
+			// The challenge is complete; park the event so the reward can
+			// be retried
+			t.logger.Error("rules engine processing failed",
+				"event_id", completion.ID,
+				"error", err,
+			)
+			if _, dlqErr := t.deadLetters.Record(ctx, completion, err); dlqErr != nil {
+				t.logger.Error("failed to record dead letter", "event_id", completion.ID, "error", dlqErr)
+			}
+			continue
+		}
+		issuances = append(issuances, granted...)
+	}
+	return issuances, nil
+}
+
+// advance counts an event towards one challenge, returning the
+// challenge_completed event if it completed the challenge
+func (t *Tracker) advance(ctx context.Context, challenge db.Challenge, event db.Event, loc *time.Location) (*db.Event, error) {
+	tx, err := t.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := t.queries.WithTx(tx)
+
+	recorded, err := qtx.RecordChallengeEvent(ctx, db.RecordChallengeEventParams{
+		ChallengeID: challenge.ID,
+		EventID:     event.ID,
+		TenantID:    event.TenantID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record challenge event: %w", err)
+	}
+	if recorded == 0 {
+		return nil, nil
+	}
+
+	if err := qtx.LockChallengeProgress(ctx, db.LockChallengeProgressParams{
+		ChallengeID: challenge.ID,
+		CustomerID:  event.CustomerID,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to lock challenge progress: %w", err)
+	}
+
+	eventPeriod := PeriodStart(challenge.Period, event.OccurredAt.Time, origin(challenge), loc)
+
+	// Count challenges progress per period; streaks continue the latest one
+	var row db.ChallengeProgress
+	if challenge.Kind == KindStreak {
+		row, err = qtx.GetLatestChallengeProgress(ctx, db.GetLatestChallengeProgressParams{
+			TenantID:    event.TenantID,
+			ChallengeID: challenge.ID,
+			CustomerID:  event.CustomerID,
+		})
+	} else {
+		row, err = qtx.GetChallengeProgress(ctx, db.GetChallengeProgressParams{
+			TenantID:    event.TenantID,
+			ChallengeID: challenge.ID,
+			CustomerID:  event.CustomerID,
+			PeriodStart: pgtype.Timestamptz{Time: eventPeriod, Valid: true},
+		})
+	}
+	var current *State
+	switch {
+	case err == nil:
+		current = stateOf(row, loc)
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, fmt.Errorf("failed to get challenge progress: %w", err)
+	}
+
+	next, changed := Advance(challenge.Kind, challenge.Period, challenge.Target, current, eventPeriod)
+	if !changed {
+		if err := tx.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil, nil
+	}
+
+	if current == nil || !next.PeriodStart.Equal(current.PeriodStart) {
+		row, err = qtx.InsertChallengeProgress(ctx, db.InsertChallengeProgressParams{
+			TenantID:    event.TenantID,
+			ChallengeID: challenge.ID,
+			CustomerID:  event.CustomerID,
+			PeriodStart: pgtype.Timestamptz{Time: next.PeriodStart, Valid: true},
+			LastPeriod:  pgtype.Timestamptz{Time: next.LastPeriod, Valid: true},
+			Progress:    next.Progress,
+		})
+	} else {
+		row, err = qtx.UpdateChallengeProgress(ctx, db.UpdateChallengeProgressParams{
+			ID:         row.ID,
+			TenantID:   event.TenantID,
+			LastPeriod: pgtype.Timestamptz{Time: next.LastPeriod, Valid: true},
+			Progress:   next.Progress,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save challenge progress: %w", err)
+	}
+
+	var completion *db.Event
+	if next.Completed {
+		completed, err := insertCompletedEvent(ctx, qtx, challenge, row, event)
+		if err != nil {
+			return nil, err
+		}
+		if err := qtx.CompleteChallengeProgress(ctx, db.CompleteChallengeProgressParams{
+			ID:       row.ID,
+			TenantID: event.TenantID,
+			EventID:  completed.ID,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to complete challenge progress: %w", err)
+		}
+		completion = &completed
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return completion, nil
+}
+
+// insertCompletedEvent records the challenge_completed event for a
+// customer's completed attempt at a challenge
+func insertCompletedEvent(ctx context.Context, qtx *db.Queries, challenge db.Challenge, progress db.ChallengeProgress, event db.Event) (db.Event, error) {
+	properties, err := json.Marshal(map[string]interface{}{
+		"challenge_id":          httputil.FormatUUID(challenge.ID.Bytes),
+		"challenge_name":        challenge.Name,
+		"challenge_kind":        challenge.Kind,
+		"challenge_progress_id": httputil.FormatUUID(progress.ID.Bytes),
+		"period_start":          progress.PeriodStart.Time.UTC().Format(time.RFC3339),
+		"progress":              progress.Progress,
+		"event_id":              httputil.FormatUUID(event.ID.Bytes),
+	})
+	if err != nil {
+		return db.Event{}, fmt.Errorf("failed to marshal event properties: %w", err)
+	}
+
+	completed, err := qtx.InsertEvent(ctx, db.InsertEventParams{
+		TenantID:       event.TenantID,
+		CustomerID:     event.CustomerID,
+		EventType:      EventTypeChallengeCompleted,
+		Properties:     properties,
+		OccurredAt:     event.OccurredAt,
+		Source:         "challenge",
+		IdempotencyKey: "challenge:" + httputil.FormatUUID(progress.ID.Bytes),
+	})
+	if err != nil {
+		return db.Event{}, fmt.Errorf("failed to create event: %w", err)
+	}
+	return completed, nil
+}
+
+// inWindow reports whether an event at t falls within the challenge's
+// start and end
+func inWindow(challenge db.Challenge, t time.Time) bool {
+	if challenge.StartsAt.Valid && t.Before(challenge.StartsAt.Time) {
+		return false
+	}
+	if challenge.EndsAt.Valid && !t.Before(challenge.EndsAt.Time) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,252 @@
+// Package changes serves the change feed: records of changes to customers
+// and issuances, and of redemptions, that integrations such as Zapier or
+// Make poll with a cursor instead of diffing lists. The records are written
+// by database triggers (migration 066).
+package changes
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/pii"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Entity types in the feed
+const (
+	EntityCustomer   = "customer"
+	EntityIssuance   = "issuance"
+	EntityRedemption = "redemption"
+)
+
+// EntityTypes lists the entity types in the feed
+var EntityTypes = []string{EntityCustomer, EntityIssuance, EntityRedemption}
+
+const (
+	// DefaultLimit is how many changes are listed per poll by default
+	DefaultLimit = 100
+	// MaxLimit is the most changes listed per poll
+	MaxLimit = 500
+	// Retention is how long changes stay in the feed
+	Retention = 30 * 24 * time.Hour
+)
+
+// ErrInvalidCursor is returned for a cursor the feed didn't return
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a position in a tenant's feed. The zero cursor is the start of
+// the retained changes.
+type Cursor struct {
+	Txid int64
+	ID   int64
+}
+
+// String encodes the cursor for the API. Cursors are opaque to clients.
+func (c Cursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.Txid, 10) + "." + strconv.FormatInt(c.ID, 10)))
+}
+
+// ParseCursor decodes a cursor String returned. An empty cursor is the zero
+// cursor.
+func ParseCursor(s string) (Cursor, error) {
+	if s == "" {
+		return Cursor{}, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	txid, id, ok := strings.Cut(string(b), ".")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var c Cursor
+	if c.Txid, err = strconv.ParseInt(txid, 10, 64); err != nil || c.Txid < 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	if c.ID, err = strconv.ParseInt(id, 10, 64); err != nil || c.ID < 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// Change is a change in the feed
+type Change struct {
+	ID         int64
+	Cursor     Cursor
+	EntityType string
+	EntityID   pgtype.UUID
+	Operation  string
+	Data       map[string]interface{}
+	ChangedAt  pgtype.Timestamptz
+}
+
+// Feed is a page of changes and the cursor to poll from next
+type Feed struct {
+	Changes    []Change
+	NextCursor Cursor
+	HasMore    bool
+}
+
+// Service reads and prunes the change feed
+type Service struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+	logger  *slog.Logger
+}
+
+// NewService creates a new change feed service
+func NewService(pool *pgxpool.Pool, queries *db.Queries, logger *slog.Logger) *Service {
+	return &Service{
+		pool:    pool,
+		queries: queries,
+		logger:  logger,
+	}
+}
+
+// List returns up to limit of the tenant's changes after the cursor, of the
+// given entity types (all when empty). Changes are listed once the
+// transactions that made them, and every transaction older than those,
+// have finished, so a change may be listed shortly after it is made.
+func (s *Service) List(ctx context.Context, tenantID pgtype.UUID, after Cursor, entityTypes []string, limit int) (Feed, error) {
+	if len(entityTypes) == 0 {
+		entityTypes = EntityTypes
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	// One extra row tells whether there are more
+	rows, err := s.queries.ListEntityChanges(ctx, db.ListEntityChangesParams{
+		TenantID:    tenantID,
+		AfterTxid:   after.Txid,
+		AfterID:     after.ID,
+		EntityTypes: entityTypes,
+		RowLimit:    int32(limit + 1),
+	})
+	if err != nil {
+		return Feed{}, fmt.Errorf("failed to list changes: %w", err)
+	}
+
+	feed := Feed{NextCursor: after, HasMore: len(rows) > limit}
+	if feed.HasMore {
+		rows = rows[:limit]
+	}
+	feed.Changes = make([]Change, len(rows))
+	for i, row := range rows {
+		change, err := s.change(row)
+		if err != nil {
+			return Feed{}, err
+		}
+		feed.Changes[i] = change
+		feed.NextCursor = change.Cursor
+	}
+	return feed, nil
+}
+
+func (s *Service) change(row db.EntityChange) (Change, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal(row.Data, &data); err != nil {
+		return Change{}, fmt.Errorf("failed to read change %d: %w", row.ID, err)
+	}
+
+	// Customer phone numbers are recorded as stored
+	if phone, ok := data["phone_e164"].(string); ok {
+		plaintext, err := pii.Default().Decrypt(phone)
+		if err != nil {
+			// Leave the number out rather than stall the feed on a change
+			// encrypted under a retired key
+			s.logger.Warn("failed to decrypt phone number in change", "change_id", row.ID, "error", err)
+			data["phone_e164"] = nil
+		} else {
+			data["phone_e164"] = plaintext
+		}
+	}
+
+	return Change{
+		ID:         row.ID,
+		Cursor:     Cursor{Txid: row.Txid, ID: row.ID},
+		EntityType: row.EntityType,
+		EntityID:   row.EntityID,
+		Operation:  row.Operation,
+		Data:       data,
+		ChangedAt:  row.ChangedAt,
+	}, nil
+}
+
+// Prune deletes every tenant's changes older than Retention
+func (s *Service) Prune(ctx context.Context, now time.Time) (int64, error) {
+	tenants, err := s.queries.ListTenants(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	var total int64
+	for _, tenant := range tenants {
+		n, err := s.pruneTenant(ctx, tenant.ID, now.Add(-Retention))
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func (s *Service) pruneTenant(ctx context.Context, tenantID pgtype.UUID, before time.Time) (int64, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Pruning runs outside a tenant request
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenantID.Bytes)); err != nil {
+		return 0, fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	n, err := s.queries.WithTx(tx).DeleteEntityChangesBefore(ctx, db.DeleteEntityChangesBeforeParams{
+		TenantID: tenantID,
+		Before:   pgtype.Timestamptz{Time: before, Valid: true},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune changes: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return n, nil
+}
+
+// Run prunes the feed on a schedule until ctx is cancelled
+func (s *Service) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := s.Prune(ctx, time.Now())
+		if err != nil {
+			s.logger.Error("change feed prune failed", "error", err)
+		} else if n > 0 {
+			s.logger.Info("pruned change feed", "changes_deleted", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package changes
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/pii"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	c, err := ParseCursor("")
+	require.NoError(t, err)
+	assert.Equal(t, Cursor{}, c)
+
+	want := Cursor{Txid: 81234, ID: 567}
+	c, err = ParseCursor(want.String())
+	require.NoError(t, err)
+	assert.Equal(t, want, c)
+
+	for _, s := range []string{"not base64!", "MTIz", "YS5i", "LTEuMg"} {
+		_, err := ParseCursor(s)
+		assert.ErrorIs(t, err, ErrInvalidCursor, s)
+	}
+}
+
+func TestChange_DecryptsPhone(t *testing.T) {
+	require.NoError(t, pii.Default().Set([]pii.Key{{ID: "v1", Key: bytes.Repeat([]byte{1}, pii.KeySize)}}))
+	defer pii.Default().Set(nil)
+
+	stored, err := pii.Default().Encrypt("+263771234567")
+	require.NoError(t, err)
+
+	s := &Service{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	change, err := s.change(db.EntityChange{
+		ID:         7,
+		EntityType: EntityCustomer,
+		Operation:  "created",
+		Data:       []byte(`{"phone_e164": "` + stored + `", "status": "active"}`),
+		Txid:       42,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Cursor{Txid: 42, ID: 7}, change.Cursor)
+	assert.Equal(t, "+263771234567", change.Data["phone_e164"])
+	assert.Equal(t, "active", change.Data["status"])
+
+	// Numbers encrypted under a retired key are left out
+	require.NoError(t, pii.Default().Set([]pii.Key{{ID: "v2", Key: bytes.Repeat([]byte{2}, pii.KeySize)}}))
+	change, err = s.change(db.EntityChange{
+		ID:   8,
+		Data: []byte(`{"phone_e164": "` + stored + `"}`),
+	})
+	require.NoError(t, err)
+	assert.Nil(t, change.Data["phone_e164"])
+}
@@ -0,0 +1,101 @@
+package channels
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// DefaultProgramName names the programme for tenants that haven't set one
+	DefaultProgramName = "Loyalty Program"
+
+	// DefaultBrandEmoji is shown for tenants that haven't set an emoji
+	DefaultBrandEmoji = "🎉"
+)
+
+// Branding is how a tenant's programme introduces itself in channel
+// messages. Templates reference it with placeholders:
+//
+//	{program}  the programme name
+//	{emoji}    the brand emoji
+//	{support}  how to reach support: "call <phone>" or "contact support"
+//
+// so the same wording works for every tenant and channel, SMS included once
+// it's added. Branding is operator-only: it's set with
+// `loyaltyctl set-branding`, and there is no tenant API for it.
+type Branding struct {
+	ProgramName  string
+	Emoji        string
+	SupportPhone string
+	// Footer is appended to push channel messages by WithFooter. Session
+	// channels such as USSD have no room for it.
+	Footer string
+}
+
+// DefaultBranding is the branding of tenants with no settings
+func DefaultBranding() Branding {
+	return Branding{
+		ProgramName: DefaultProgramName,
+		Emoji:       DefaultBrandEmoji,
+	}
+}
+
+// BrandingFromTenant reads a tenant's branding settings, falling back to the
+// defaults for any that are unset
+func BrandingFromTenant(t db.Tenant) Branding {
+	b := DefaultBranding()
+	if t.ProgramName.Valid {
+		b.ProgramName = t.ProgramName.String
+	}
+	if t.BrandEmoji.Valid {
+		b.Emoji = t.BrandEmoji.String
+	}
+	b.SupportPhone = t.SupportPhone.String
+	b.Footer = t.MessageFooter.String
+	return b
+}
+
+// LoadBranding returns a tenant's branding. Messages still go out with the
+// default branding when the tenant can't be read.
+func LoadBranding(ctx context.Context, queries *db.Queries, tenantID pgtype.UUID) Branding {
+	tenant, err := queries.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		slog.Warn("Failed to load tenant branding", "tenant_id", tenantID, "error", err)
+		return DefaultBranding()
+	}
+	return BrandingFromTenant(tenant)
+}
+
+// Support returns how customers reach support, for "Please {support}."
+func (b Branding) Support() string {
+	if b.SupportPhone != "" {
+		return "call " + b.SupportPhone
+	}
+	return "contact support"
+}
+
+// Render fills a template's placeholders in
+func (b Branding) Render(template string) string {
+	if b.Emoji == "" {
+		// Drop the space around the placeholder along with it
+		template = strings.NewReplacer(" {emoji}", "", "{emoji} ", "").Replace(template)
+	}
+	return strings.NewReplacer(
+		"{program}", b.ProgramName,
+		"{emoji}", b.Emoji,
+		"{support}", b.Support(),
+	).Replace(template)
+}
+
+// WithFooter renders a template and appends the tenant's footer, if any
+func (b Branding) WithFooter(template string) string {
+	text := b.Render(template)
+	if b.Footer == "" {
+		return text
+	}
+	return text + "\n\n" + b.Footer
+}
@@ -0,0 +1,45 @@
+package channels
+
+import (
+	"testing"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBrandingFromTenant(t *testing.T) {
+	assert.Equal(t, DefaultBranding(), BrandingFromTenant(db.Tenant{}))
+
+	branding := BrandingFromTenant(db.Tenant{
+		ProgramName:   pgtype.Text{String: "OK Rewards", Valid: true},
+		BrandEmoji:    pgtype.Text{String: "🛒", Valid: true},
+		SupportPhone:  pgtype.Text{String: "+263 242 757 311", Valid: true},
+		MessageFooter: pgtype.Text{String: "OK Zimbabwe - Best for less", Valid: true},
+	})
+	assert.Equal(t, Branding{
+		ProgramName:  "OK Rewards",
+		Emoji:        "🛒",
+		SupportPhone: "+263 242 757 311",
+		Footer:       "OK Zimbabwe - Best for less",
+	}, branding)
+}
+
+func TestBrandingRender(t *testing.T) {
+	template := "Welcome to {program}! {emoji}\n\nFor help, please {support}."
+
+	assert.Equal(t, "Welcome to Loyalty Program! 🎉\n\nFor help, please contact support.",
+		DefaultBranding().Render(template))
+
+	branding := Branding{ProgramName: "OK Rewards", SupportPhone: "0242 757 311"}
+	assert.Equal(t, "Welcome to OK Rewards!\n\nFor help, please call 0242 757 311.",
+		branding.Render(template))
+}
+
+func TestBrandingWithFooter(t *testing.T) {
+	assert.Equal(t, "Welcome to Loyalty Program", DefaultBranding().WithFooter("Welcome to {program}"))
+
+	branding := DefaultBranding()
+	branding.Footer = "Reply STOP to opt out"
+	assert.Equal(t, "Welcome to Loyalty Program\n\nReply STOP to opt out", branding.WithFooter("Welcome to {program}"))
+}
@@ -0,0 +1,53 @@
+// Package channels holds what the customer messaging channels have in
+// common: the Channel interface a push channel (WhatsApp, and later
+// Telegram or SMS) implements, and the enrollment and redemption flows every
+// channel runs, so a new channel only has to add its transport and wording.
+//
+// Session channels such as USSD answer within the gateway's request rather
+// than sending messages, so they use the flows but render their own replies.
+package channels
+
+import "context"
+
+// Capabilities describes what a channel can send, so flows and handlers can
+// fall back to plain text where a channel has no richer option
+type Capabilities struct {
+	// Interactive channels render menus as tappable options; others need
+	// the options numbered in the text
+	Interactive bool
+	// MaxMenuOptions is the most options a single menu can show
+	MaxMenuOptions int
+	// MaxTextLength is the longest text message the channel accepts
+	MaxTextLength int
+	// Push channels can message customers outside a reply, e.g. surveys
+	// and reward notifications
+	Push bool
+}
+
+// MenuOption is one choice in a menu. ID is sent back as the customer's
+// reply when they pick it.
+type MenuOption struct {
+	ID          string
+	Title       string
+	Description string
+}
+
+// Menu asks the customer to pick one of a list of options
+type Menu struct {
+	Title   string
+	Body    string
+	Options []MenuOption
+}
+
+// Channel sends messages to customers over one messaging service
+type Channel interface {
+	// Name identifies the channel in consent records and issuance history,
+	// e.g. reward.ChannelWhatsApp
+	Name() string
+	// Capabilities reports what the channel can send
+	Capabilities() Capabilities
+	// SendText sends a plain text message
+	SendText(ctx context.Context, to, text string) error
+	// SendMenu sends a menu, showing at most MaxMenuOptions options
+	SendMenu(ctx context.Context, to string, menu Menu) error
+}
@@ -0,0 +1,227 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/catalogcache"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/issuance"
+	"github.com/bmachimbira/loyalty/api/internal/pii"
+	"github.com/bmachimbira/loyalty/api/internal/reward"
+	"github.com/bmachimbira/loyalty/api/internal/reward/window"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	// ErrNotEnrolled is returned when no customer has the phone number
+	ErrNotEnrolled = errors.New("customer not enrolled")
+	// ErrCodeNotFound is returned when none of the customer's rewards that
+	// could still be redeemed has the redemption code
+	ErrCodeNotFound = errors.New("invalid or expired redemption code")
+	// ErrAlreadyRedeemed is returned when the reward with the redemption code
+	// was already redeemed
+	ErrAlreadyRedeemed = errors.New("reward already redeemed")
+)
+
+// EnrollmentFlow finds and enrolls customers by phone number
+type EnrollmentFlow struct {
+	queries *db.Queries
+}
+
+// NewEnrollmentFlow creates a new enrollment flow
+func NewEnrollmentFlow(queries *db.Queries) *EnrollmentFlow {
+	return &EnrollmentFlow{queries: queries}
+}
+
+// Enrollment is the outcome of enrolling a customer
+type Enrollment struct {
+	Customer db.Customer
+	// Created is false when the phone number was already enrolled
+	Created bool
+}
+
+// Find returns the customer enrolled with the phone number, or
+// ErrNotEnrolled
+func (f *EnrollmentFlow) Find(ctx context.Context, tenantID pgtype.UUID, phoneE164 string) (db.Customer, error) {
+	customer, err := f.queries.GetCustomerByPhone(ctx, db.GetCustomerByPhoneParams{
+		TenantID:   tenantID,
+		PhoneBidxs: pii.Default().BlindIndexes(phoneE164),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Customer{}, ErrNotEnrolled
+	}
+	if err != nil {
+		return db.Customer{}, fmt.Errorf("failed to check customer: %w", err)
+	}
+	return customer, nil
+}
+
+// Enroll returns the customer enrolled with the phone number, creating them
+// if there is none. New customers have their loyalty consent recorded
+// against the channel they enrolled through.
+func (f *EnrollmentFlow) Enroll(ctx context.Context, tenantID pgtype.UUID, phoneE164, channel string) (Enrollment, error) {
+	customer, err := f.Find(ctx, tenantID, phoneE164)
+	if err == nil {
+		return Enrollment{Customer: customer}, nil
+	}
+	if !errors.Is(err, ErrNotEnrolled) {
+		return Enrollment{}, err
+	}
+
+	customer, err = f.queries.CreateCustomer(ctx, db.CreateCustomerParams{
+		TenantID:    tenantID,
+		PhoneE164:   pii.Text{String: phoneE164, Valid: true},
+		PhoneBidx:   pii.Default().BlindIndex(phoneE164),
+		ExternalRef: pgtype.Text{Valid: false},
+	})
+	if err != nil {
+		return Enrollment{}, fmt.Errorf("failed to create customer: %w", err)
+	}
+
+	_, err = f.queries.RecordConsent(ctx, db.RecordConsentParams{
+		TenantID:   tenantID,
+		CustomerID: customer.ID,
+		Channel:    channel,
+		Purpose:    "loyalty",
+		Granted:    true,
+		OccurredAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		slog.Error("Failed to record consent", "channel", channel, "error", err)
+	}
+
+	return Enrollment{Customer: customer, Created: true}, nil
+}
+
+// RedemptionFlow lists and redeems a customer's rewards
+type RedemptionFlow struct {
+	queries *db.Queries
+	catalog *catalogcache.Cache
+	rewards *reward.Service
+}
+
+// NewRedemptionFlow creates a new redemption flow
+func NewRedemptionFlow(queries *db.Queries, catalog *catalogcache.Cache, rewards *reward.Service) *RedemptionFlow {
+	return &RedemptionFlow{
+		queries: queries,
+		catalog: catalog,
+		rewards: rewards,
+	}
+}
+
+// ActiveReward is one of a customer's active issuances with its reward.
+// Reward is nil if the reward could not be loaded.
+type ActiveReward struct {
+	Issuance db.Issuance
+	Reward   *db.RewardCatalog
+}
+
+// Name returns the reward's name, or fallback if it could not be loaded
+func (a ActiveReward) Name(fallback string) string {
+	if a.Reward == nil {
+		return fallback
+	}
+	return a.Reward.Name
+}
+
+// RedemptionWindow returns the days and hours the reward can be redeemed,
+// or nil if it can be redeemed at any time or could not be loaded
+func (a ActiveReward) RedemptionWindow() *window.Window {
+	if a.Reward == nil {
+		return nil
+	}
+	// Windows are validated when rewards are created
+	w, _ := window.FromMetadata(a.Reward.Metadata)
+	return w
+}
+
+// ActiveRewards lists the customer's active rewards. Channels number them in
+// this order, so a number picked on one message refers to the same reward
+// on the next.
+func (f *RedemptionFlow) ActiveRewards(ctx context.Context, tenantID, customerID pgtype.UUID) ([]ActiveReward, error) {
+	issuances, err := f.queries.ListActiveIssuances(ctx, db.ListActiveIssuancesParams{
+		TenantID:   tenantID,
+		CustomerID: customerID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list issuances: %w", err)
+	}
+
+	active := make([]ActiveReward, len(issuances))
+	for i, issuance := range issuances {
+		active[i].Issuance = issuance
+
+		item, err := f.catalog.GetRewardByID(ctx, db.GetRewardByIDParams{
+			ID:       issuance.RewardID,
+			TenantID: tenantID,
+		})
+		if err != nil {
+			slog.Error("Failed to get reward details", "error", err, "reward_id", issuance.RewardID)
+			continue
+		}
+		active[i].Reward = &item
+	}
+
+	return active, nil
+}
+
+// Redeem redeems the customer's reward with the code, matched case
+// insensitively, on their behalf through the channel. It returns
+// ErrCodeNotFound if they have no usable reward with the code,
+// ErrAlreadyRedeemed if it was redeemed before, reward.ErrRewardExpired or
+// reward.ErrNotRedeemable if it can't be redeemed now, a *window.Error if
+// it is outside its redemption window, and a *basket.Error or
+// *locations.Error if it can only be redeemed in store.
+func (f *RedemptionFlow) Redeem(ctx context.Context, tenantID, customerID pgtype.UUID, code, channel string) (ActiveReward, error) {
+	normalized := issuance.NormalizeCode(code, false)
+	if normalized == "" {
+		return ActiveReward{}, ErrCodeNotFound
+	}
+
+	found, err := f.queries.GetCustomerIssuanceByCode(ctx, db.GetCustomerIssuanceByCodeParams{
+		TenantID:   tenantID,
+		CustomerID: customerID,
+		Code:       normalized,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ActiveReward{}, ErrCodeNotFound
+	}
+	if err != nil {
+		return ActiveReward{}, fmt.Errorf("failed to get issuance by code: %w", err)
+	}
+
+	switch reward.State(found.Status) {
+	case reward.StateRedeemed:
+		return ActiveReward{}, ErrAlreadyRedeemed
+	case reward.StateExpired:
+		return ActiveReward{}, reward.ErrRewardExpired
+	case reward.StateCancelled, reward.StateFailed:
+		return ActiveReward{}, ErrCodeNotFound
+	}
+
+	// The reward service validates state and expiry and charges the budget
+	err = f.rewards.RedeemIssuance(ctx, found.ID, tenantID, normalized, reward.Checkout{}, reward.Origin{
+		Actor:   reward.CustomerActor(customerID),
+		Channel: channel,
+	})
+	if err != nil {
+		return ActiveReward{}, fmt.Errorf("failed to redeem reward: %w", err)
+	}
+
+	redeemed := ActiveReward{Issuance: found}
+	item, err := f.catalog.GetRewardByID(ctx, db.GetRewardByIDParams{
+		ID:       found.RewardID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		slog.Error("Failed to get reward details", "error", err, "reward_id", found.RewardID)
+	} else {
+		redeemed.Reward = &item
+	}
+	return redeemed, nil
+}
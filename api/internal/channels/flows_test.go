@@ -0,0 +1,29 @@
+package channels
+
+import (
+	"testing"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActiveRewardName(t *testing.T) {
+	loaded := ActiveReward{Reward: &db.RewardCatalog{Name: "Free Coffee"}}
+	assert.Equal(t, "Free Coffee", loaded.Name("Your reward"))
+
+	missing := ActiveReward{}
+	assert.Equal(t, "Your reward", missing.Name("Your reward"))
+}
+
+func TestActiveRewardRedemptionWindow(t *testing.T) {
+	lunch := ActiveReward{Reward: &db.RewardCatalog{
+		Metadata: []byte(`{"redemption_window": {"start": "11:00", "end": "14:00"}}`),
+	}}
+	require.NotNil(t, lunch.RedemptionWindow())
+	assert.Equal(t, "daily 11:00-14:00", lunch.RedemptionWindow().String())
+
+	anytime := ActiveReward{Reward: &db.RewardCatalog{Metadata: []byte(`{}`)}}
+	assert.Nil(t, anytime.RedemptionWindow())
+	assert.Nil(t, ActiveReward{}.RedemptionWindow())
+}
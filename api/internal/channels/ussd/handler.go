@@ -5,9 +5,19 @@ import (
 	"log/slog"
 	"strings"
 
+	"github.com/bmachimbira/loyalty/api/internal/analytics"
+	"github.com/bmachimbira/loyalty/api/internal/catalogcache"
+	"github.com/bmachimbira/loyalty/api/internal/channels"
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/featureflag"
+	"github.com/bmachimbira/loyalty/api/internal/metering"
+	"github.com/bmachimbira/loyalty/api/internal/phone"
+	"github.com/bmachimbira/loyalty/api/internal/promo"
+	"github.com/bmachimbira/loyalty/api/internal/reward"
+	"github.com/bmachimbira/loyalty/api/internal/webhooks"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -15,21 +25,68 @@ import (
 type Handler struct {
 	pool           *pgxpool.Pool
 	queries        *db.Queries
+	catalog        *catalogcache.Cache
 	sessionManager *SessionManager
 	menuSystem     *MenuSystem
+	rewards        *reward.Service
+	enrollment     *channels.EnrollmentFlow
+	redemption     *channels.RedemptionFlow
+	promos         *promo.Service
+	phones         *phone.Normalizer
+	meter          *metering.Meter
+	features       *featureflag.Service
+	funnel         *analytics.FunnelRecorder
+}
+
+// menuFeatures maps menus to the feature flag a tenant can turn them off with
+var menuFeatures = map[string]string{
+	"balance": featureflag.FeaturePointsDisplay,
 }
 
 // NewHandler creates a new USSD handler
-func NewHandler(pool *pgxpool.Pool) *Handler {
+func NewHandler(pool *pgxpool.Pool, catalog *catalogcache.Cache) *Handler {
 	queries := db.New(pool)
+	rewards := reward.NewService(pool, queries)
 	return &Handler{
 		pool:           pool,
 		queries:        queries,
+		catalog:        catalog,
 		sessionManager: NewSessionManager(queries),
 		menuSystem:     NewMenuSystem(queries),
+		rewards:        rewards,
+		enrollment:     channels.NewEnrollmentFlow(queries),
+		redemption:     channels.NewRedemptionFlow(queries, catalog, rewards),
+		phones:         phone.NewNormalizer(queries),
 	}
 }
 
+// SetWebhookService enables webhook notifications for redemptions
+func (h *Handler) SetWebhookService(webhooks *webhooks.DeliveryService) {
+	h.rewards.SetWebhookService(webhooks)
+}
+
+// SetMeter meters USSD responses to customers for tenant billing
+func (h *Handler) SetMeter(meter *metering.Meter) {
+	h.meter = meter
+}
+
+// SetPromoService enables promo code entry from the main menu
+func (h *Handler) SetPromoService(promos *promo.Service) {
+	h.promos = promos
+}
+
+// SetFeatureFlags lets tenants turn menus such as the points balance off
+// for USSD
+func (h *Handler) SetFeatureFlags(features *featureflag.Service) {
+	h.features = features
+}
+
+// SetFunnelRecorder tracks how far sessions get through the menus, for the
+// channel funnel report
+func (h *Handler) SetFunnelRecorder(funnel *analytics.FunnelRecorder) {
+	h.funnel = funnel
+}
+
 // HandleCallback handles the USSD callback request
 func (h *Handler) HandleCallback(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -66,6 +123,7 @@ func (h *Handler) HandleCallback(c *gin.Context) {
 		c.String(200, "END System error. Please try again.")
 		return
 	}
+	sessionData.Branding = channels.LoadBranding(ctx, h.queries, session.TenantID)
 
 	// Try to link customer if not already linked
 	if !session.CustomerID.Valid {
@@ -75,6 +133,14 @@ func (h *Handler) HandleCallback(c *gin.Context) {
 	// Process the request
 	response := h.processRequest(ctx, session, sessionData, req)
 
+	// Sessions reach the menu stage of the funnel once the customer chooses
+	// an option from the main menu
+	stage := analytics.StageSessionsStarted
+	if sessionData.CurrentMenu != "main" {
+		stage = analytics.StageMenuReached
+	}
+	h.recordFunnel(ctx, session, sessionData.CurrentMenu, stage, response.Type == End)
+
 	// Update session state if continuing
 	if response.Type == Continue {
 		if err := h.sessionManager.UpdateSession(ctx, req.SessionID, sessionData); err != nil {
@@ -96,6 +162,7 @@ func (h *Handler) HandleCallback(c *gin.Context) {
 	)
 
 	c.String(200, responseText)
+	h.meter.Add(pgtype.UUID{Bytes: tenantID, Valid: true}, metering.MessagesSent(metering.ChannelUSSD), 1)
 }
 
 // processRequest processes the USSD request and returns a response
@@ -135,6 +202,18 @@ func (h *Handler) processRequest(ctx context.Context, session *db.UssdSession, d
 	// Handle input with current menu
 	nextMenuName, response := currentMenu.Handle(lastInput, data)
 
+	// Menus for features the tenant has turned off end the session
+	if feature, ok := menuFeatures[nextMenuName]; ok && !h.featureEnabled(ctx, session.TenantID, feature) {
+		data.CurrentMenu = "main"
+		return FormatEnd("Sorry, this service is\nnot available right now.")
+	}
+
+	// Menus that prompt for more input, like the redemption confirmation,
+	// move the session on to the menu that handles the answer
+	if response.Type == Continue && response.Message != "" && nextMenuName != data.CurrentMenu {
+		data.PushMenu(nextMenuName)
+	}
+
 	// If response is empty, we need to render the next menu
 	if response.Message == "" {
 		// Save current menu to stack if changing menus
@@ -155,9 +234,30 @@ func (h *Handler) processRequest(ctx context.Context, session *db.UssdSession, d
 	return response
 }
 
+// featureEnabled reports whether the tenant has a feature on for USSD.
+// Without a feature flag service every feature is on.
+func (h *Handler) featureEnabled(ctx context.Context, tenantID pgtype.UUID, feature string) bool {
+	if h.features == nil {
+		return true
+	}
+	return h.features.Enabled(ctx, tenantID, featureflag.ChannelUSSD, feature)
+}
+
+// recordFunnel records the session's step for the channel funnel
+func (h *Handler) recordFunnel(ctx context.Context, session *db.UssdSession, step, stage string, ended bool) {
+	h.funnel.Record(ctx, analytics.FunnelStep{
+		TenantID:   session.TenantID,
+		Channel:    analytics.FunnelChannelUSSD,
+		SessionRef: session.SessionID,
+		Step:       step,
+		Stage:      stage,
+		Ended:      ended,
+	})
+}
+
 // handleContextualMenu handles menus that need database access
 func (h *Handler) handleContextualMenu(ctx context.Context, session *db.UssdSession, data *SessionData, input string) USSDResponse {
-	menuCtx := NewMenuWithContext(ctx, h.queries, session)
+	menuCtx := NewMenuWithContext(ctx, h.redemption, h.promos, session)
 
 	switch data.CurrentMenu {
 	case "myrewards":
@@ -182,7 +282,24 @@ func (h *Handler) handleContextualMenu(ctx context.Context, session *db.UssdSess
 		}
 
 		// Process redemption
-		response := menuCtx.RenderRedeemWithCode(code)
+		response, redeemed := menuCtx.RenderRedeemWithCode(code)
+		stage := analytics.StageRedemptionAttempted
+		if redeemed {
+			stage = analytics.StageRedemptionCompleted
+		}
+		h.recordFunnel(ctx, session, data.CurrentMenu, stage, false)
+
+		data.CurrentMenu = "main"
+		return response
+
+	case "promo_submit":
+		code, ok := data.GetDataString("promo_code")
+		if !ok {
+			data.CurrentMenu = "main"
+			return FormatEnd("Error: Code not found.")
+		}
+
+		response := menuCtx.RenderPromo(code)
 		data.CurrentMenu = "main"
 		return response
 
@@ -196,12 +313,18 @@ func (h *Handler) handleContextualMenu(ctx context.Context, session *db.UssdSess
 
 // tryLinkCustomer attempts to link a customer to the session
 func (h *Handler) tryLinkCustomer(ctx context.Context, session *db.UssdSession, phoneNumber string, data *SessionData) {
-	// Normalize phone number to E.164 format
-	phoneE164 := h.normalizePhoneNumber(phoneNumber)
+	// Normalize phone number to E.164 in the tenant's region
+	phoneE164, err := h.phones.Normalize(ctx, session.TenantID, phoneNumber)
+	if err != nil {
+		slog.Warn("Invalid phone number for USSD session",
+			"error", err,
+			"session_id", session.SessionID,
+		)
+		return
+	}
 
 	// Try to find customer
-	menuCtx := NewMenuWithContext(ctx, h.queries, session)
-	customerID, err := menuCtx.GetCustomerByPhone(phoneE164)
+	customer, err := h.enrollment.Find(ctx, session.TenantID, phoneE164)
 	if err != nil {
 		// Customer not found, that's okay
 		slog.Info("Customer not found for USSD session",
@@ -212,6 +335,7 @@ func (h *Handler) tryLinkCustomer(ctx context.Context, session *db.UssdSession,
 	}
 
 	// Link customer to session
+	customerID := uuid.UUID(customer.ID.Bytes)
 	if err := h.sessionManager.LinkCustomer(ctx, session.SessionID, customerID); err != nil {
 		slog.Error("Failed to link customer to USSD session", "error", err)
 		return
@@ -226,28 +350,6 @@ func (h *Handler) tryLinkCustomer(ctx context.Context, session *db.UssdSession,
 	)
 }
 
-// normalizePhoneNumber normalizes a phone number to E.164 format
-func (h *Handler) normalizePhoneNumber(phone string) string {
-	// Remove whitespace and special characters
-	phone = strings.ReplaceAll(phone, " ", "")
-	phone = strings.ReplaceAll(phone, "-", "")
-	phone = strings.ReplaceAll(phone, "(", "")
-	phone = strings.ReplaceAll(phone, ")", "")
-
-	// Add country code if missing (Zimbabwe = +263)
-	if !strings.HasPrefix(phone, "+") {
-		if strings.HasPrefix(phone, "0") {
-			phone = "+263" + phone[1:] // Remove leading 0 and add +263
-		} else if strings.HasPrefix(phone, "263") {
-			phone = "+" + phone
-		} else {
-			phone = "+263" + phone
-		}
-	}
-
-	return phone
-}
-
 // getTenantIDFromServiceCode determines tenant from USSD service code
 func (h *Handler) getTenantIDFromServiceCode(serviceCode string) uuid.UUID {
 	// For Phase 3, return a default tenant ID
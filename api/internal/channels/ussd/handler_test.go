@@ -1,8 +1,10 @@
 package ussd
 
 import (
+	"context"
 	"testing"
 
+	"github.com/bmachimbira/loyalty/api/internal/db"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -250,54 +252,6 @@ func TestFormatMenu(t *testing.T) {
 	assert.Contains(t, response.Message, "2. Option 2")
 }
 
-func TestPhoneNumberNormalization(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    string
-		expected string
-	}{
-		{
-			name:     "local format with leading zero",
-			input:    "0771234567",
-			expected: "+263771234567",
-		},
-		{
-			name:     "without country code",
-			input:    "771234567",
-			expected: "+263771234567",
-		},
-		{
-			name:     "with country code no plus",
-			input:    "263771234567",
-			expected: "+263771234567",
-		},
-		{
-			name:     "already E.164",
-			input:    "+263771234567",
-			expected: "+263771234567",
-		},
-		{
-			name:     "with spaces",
-			input:    "077 123 4567",
-			expected: "+263771234567",
-		},
-		{
-			name:     "with dashes",
-			input:    "077-123-4567",
-			expected: "+263771234567",
-		},
-	}
-
-	handler := &Handler{}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := handler.normalizePhoneNumber(tt.input)
-			assert.Equal(t, tt.expected, result)
-		})
-	}
-}
-
 func TestTruncateText(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -390,3 +344,56 @@ func splitByAsterisk(text string) []string {
 
 	return parts
 }
+
+func TestPromoMenu(t *testing.T) {
+	ms := NewMenuSystem(nil)
+
+	t.Run("main menu option 4 opens promo", func(t *testing.T) {
+		next, response := ms.GetMenu("main").Handle("4", NewSessionData())
+		assert.Equal(t, "promo", next)
+		assert.Empty(t, response.Message)
+	})
+
+	t.Run("unlinked customer", func(t *testing.T) {
+		next, response := ms.GetMenu("promo").Handle("SUMMER25", NewSessionData())
+		assert.Equal(t, "main", next)
+		assert.Equal(t, End, response.Type)
+	})
+
+	t.Run("code is normalized and submitted", func(t *testing.T) {
+		data := NewSessionData()
+		data.CustomerID = "6f1c2a52-7a51-4b8e-9a36-2f0f3f3c9d10"
+
+		next, response := ms.GetMenu("promo").Handle("summer-25", data)
+		assert.Equal(t, "promo_submit", next)
+		assert.Empty(t, response.Message)
+
+		code, ok := data.GetDataString("promo_code")
+		assert.True(t, ok)
+		assert.Equal(t, "SUMMER25", code)
+		assert.Empty(t, ms.GetMenu("promo_submit").Render(data).Message)
+	})
+}
+
+func TestRedeemFlowReachesConfirmation(t *testing.T) {
+	h := &Handler{menuSystem: NewMenuSystem(nil)}
+	session := &db.UssdSession{SessionID: "ATUid_1"}
+	data := NewSessionData()
+	data.CustomerID = "6f1c2a52-7a51-4b8e-9a36-2f0f3f3c9d10"
+
+	response := h.processRequest(context.Background(), session, data, USSDRequest{Text: "3"})
+	assert.Equal(t, Continue, response.Type)
+	assert.Equal(t, "redeem", data.CurrentMenu)
+
+	response = h.processRequest(context.Background(), session, data, USSDRequest{Text: "3*abc123"})
+	assert.Equal(t, Continue, response.Type)
+	assert.Contains(t, response.Message, "Redeem code: ABC123")
+	assert.Equal(t, "redeem_confirm", data.CurrentMenu)
+
+	// The confirmation answer goes to the confirmation menu rather than
+	// being taken as another code
+	response = h.processRequest(context.Background(), session, data, USSDRequest{Text: "3*abc123*2"})
+	assert.Equal(t, End, response.Type)
+	assert.Equal(t, "Redemption cancelled.", response.Message)
+	assert.Equal(t, "main", data.CurrentMenu)
+}
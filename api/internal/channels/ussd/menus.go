@@ -2,13 +2,18 @@ package ussd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/bmachimbira/loyalty/api/internal/channels"
 	"github.com/bmachimbira/loyalty/api/internal/db"
-	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/bmachimbira/loyalty/api/internal/promo"
+	"github.com/bmachimbira/loyalty/api/internal/reward"
+	"github.com/bmachimbira/loyalty/api/internal/reward/basket"
+	"github.com/bmachimbira/loyalty/api/internal/reward/locations"
+	"github.com/bmachimbira/loyalty/api/internal/reward/window"
 )
 
 // MenuSystem manages all USSD menus
@@ -30,6 +35,9 @@ func NewMenuSystem(queries *db.Queries) *MenuSystem {
 	ms.menus["rewards"] = &RewardsMenu{queries: queries}
 	ms.menus["myrewards"] = &MyRewardsMenu{queries: queries}
 	ms.menus["redeem"] = &RedeemMenu{queries: queries}
+	ms.menus["redeem_confirm"] = &RedeemConfirmMenu{}
+	ms.menus["promo"] = &PromoMenu{queries: queries}
+	ms.menus["promo_submit"] = &PromoSubmitMenu{}
 	ms.menus["help"] = &HelpMenu{queries: queries}
 
 	return ms
@@ -50,16 +58,17 @@ type MainMenu struct {
 }
 
 func (m *MainMenu) Render(session *SessionData) USSDResponse {
-	return FormatMenu("Welcome to Loyalty", []MenuOption{
+	return FormatMenu(session.Branding.Render("Welcome to {program}"), []MenuOption{
 		{Key: "1", Label: "My Rewards"},
 		{Key: "2", Label: "Check Balance"},
 		{Key: "3", Label: "Redeem Reward"},
-		{Key: "4", Label: "Help"},
+		{Key: "4", Label: "Promo Code"},
+		{Key: "5", Label: "Help"},
 	})
 }
 
 func (m *MainMenu) Handle(input string, session *SessionData) (string, USSDResponse) {
-	choice, ok := ParseMenuChoice(input, 4)
+	choice, ok := ParseMenuChoice(input, 5)
 	if !ok {
 		return "main", FormatContinue("Invalid option. Please try again.\n\n" + m.Render(session).Message)
 	}
@@ -72,6 +81,8 @@ func (m *MainMenu) Handle(input string, session *SessionData) (string, USSDRespo
 	case 3:
 		return "redeem", USSDResponse{}
 	case 4:
+		return "promo", USSDResponse{}
+	case 5:
 		return "help", USSDResponse{}
 	default:
 		return "main", FormatContinue("Invalid option. Please try again.\n\n" + m.Render(session).Message)
@@ -164,35 +175,54 @@ func (m *RedeemMenu) Handle(input string, session *SessionData) (string, USSDRes
 	return "redeem_confirm", FormatContinue(fmt.Sprintf("Redeem code: %s\n\n1. Confirm\n2. Cancel", code))
 }
 
-// RedeemConfirmMenu confirms redemption
-type RedeemConfirmMenu struct {
-	queries *db.Queries
-}
+// RedeemConfirmMenu confirms and redeems the entered code. It needs
+// database access, so it renders nothing and is handled as a contextual menu.
+type RedeemConfirmMenu struct{}
 
 func (m *RedeemConfirmMenu) Render(session *SessionData) USSDResponse {
-	code, _ := session.GetDataString("redeem_code")
-	return FormatConfirmation(fmt.Sprintf("Redeem code: %s", code))
+	return USSDResponse{}
 }
 
 func (m *RedeemConfirmMenu) Handle(input string, session *SessionData) (string, USSDResponse) {
-	choice, ok := ParseMenuChoice(input, 2)
-	if !ok {
-		return "redeem_confirm", FormatContinue("Invalid option.\n\n" + m.Render(session).Message)
-	}
+	return "redeem_confirm", USSDResponse{}
+}
 
-	if choice == 2 {
-		return "main", FormatEnd("Redemption cancelled.")
+// PromoMenu asks for a promo code
+type PromoMenu struct {
+	queries *db.Queries
+}
+
+func (m *PromoMenu) Render(session *SessionData) USSDResponse {
+	return FormatContinue("Enter your promo code:")
+}
+
+func (m *PromoMenu) Handle(input string, session *SessionData) (string, USSDResponse) {
+	// Check if customer is linked
+	if session.CustomerID == "" {
+		return "main", FormatEnd("Please register first.\n\nContact customer support.")
 	}
 
-	// Get the code
-	code, ok := session.GetDataString("redeem_code")
-	if !ok {
-		return "main", FormatEnd("Error: Code not found.\nPlease try again.")
+	code := promo.Normalize(input)
+	if code == "" {
+		return "promo", FormatContinue("Please enter a promo code:")
 	}
 
-	// In production, this would process the redemption
-	// For Phase 3, we return a success message
-	return "main", FormatEnd(fmt.Sprintf("Success!\n\nCode %s redeemed.\n\nThank you!", code))
+	// Store the code for processing
+	session.SetData("promo_code", code)
+
+	return "promo_submit", USSDResponse{}
+}
+
+// PromoSubmitMenu redeems the entered promo code. It needs database access,
+// so it renders nothing and is handled as a contextual menu.
+type PromoSubmitMenu struct{}
+
+func (m *PromoSubmitMenu) Render(session *SessionData) USSDResponse {
+	return USSDResponse{}
+}
+
+func (m *PromoSubmitMenu) Handle(input string, session *SessionData) (string, USSDResponse) {
+	return "main", USSDResponse{}
 }
 
 // HelpMenu shows help information
@@ -202,7 +232,7 @@ type HelpMenu struct {
 
 func (m *HelpMenu) Render(session *SessionData) USSDResponse {
 	rb := NewResponseBuilder()
-	rb.AddLine("Loyalty Program Help")
+	rb.AddLine(session.Branding.Render("{program} Help"))
 	rb.AddBlankLine()
 	rb.AddLine("1. My Rewards - View your")
 	rb.AddLine("   active rewards")
@@ -213,8 +243,11 @@ func (m *HelpMenu) Render(session *SessionData) USSDResponse {
 	rb.AddLine("3. Redeem - Use a reward")
 	rb.AddLine("   code")
 	rb.AddBlankLine()
-	rb.AddLine("For more info, contact")
-	rb.AddLine("customer support.")
+	rb.AddLine("4. Promo Code - Enter a")
+	rb.AddLine("   promo code")
+	rb.AddBlankLine()
+	rb.AddLine("For more info, please")
+	rb.AddLine(session.Branding.Render("{support}."))
 
 	return rb.End()
 }
@@ -225,17 +258,20 @@ func (m *HelpMenu) Handle(input string, session *SessionData) (string, USSDRespo
 
 // MenuWithContext is a menu that needs database access
 type MenuWithContext struct {
-	ctx     context.Context
-	queries *db.Queries
-	session *db.UssdSession
+	ctx        context.Context
+	redemption *channels.RedemptionFlow
+	promos     *promo.Service
+	session    *db.UssdSession
 }
 
-// NewMenuWithContext creates a menu with context
-func NewMenuWithContext(ctx context.Context, queries *db.Queries, session *db.UssdSession) *MenuWithContext {
+// NewMenuWithContext creates a menu with context. promos may be nil when
+// promo codes are not enabled.
+func NewMenuWithContext(ctx context.Context, redemption *channels.RedemptionFlow, promos *promo.Service, session *db.UssdSession) *MenuWithContext {
 	return &MenuWithContext{
-		ctx:     ctx,
-		queries: queries,
-		session: session,
+		ctx:        ctx,
+		redemption: redemption,
+		promos:     promos,
+		session:    session,
 	}
 }
 
@@ -245,16 +281,13 @@ func (m *MenuWithContext) RenderMyRewards() USSDResponse {
 		return FormatEnd("Please register first.\n\nContact customer support\nor use WhatsApp to enroll.")
 	}
 
-	// Get customer's active issuances
-	issuances, err := m.queries.ListActiveIssuances(m.ctx, db.ListActiveIssuancesParams{
-		TenantID:   m.session.TenantID,
-		CustomerID: m.session.CustomerID,
-	})
+	// Get customer's active rewards
+	active, err := m.redemption.ActiveRewards(m.ctx, m.session.TenantID, m.session.CustomerID)
 	if err != nil {
 		return FormatError("Failed to load rewards")
 	}
 
-	if len(issuances) == 0 {
+	if len(active) == 0 {
 		return FormatEnd("You have no active rewards.\n\nKeep shopping to earn rewards!")
 	}
 
@@ -263,27 +296,25 @@ func (m *MenuWithContext) RenderMyRewards() USSDResponse {
 	rb.AddBlankLine()
 
 	// Show up to 3 rewards on USSD (character limit)
-	count := len(issuances)
+	count := len(active)
 	if count > 3 {
 		count = 3
 	}
 
 	for i := 0; i < count; i++ {
-		iss := issuances[i]
-
-		// Get reward details
-		reward, err := m.queries.GetRewardByID(m.ctx, db.GetRewardByIDParams{
-			ID:       iss.RewardID,
-			TenantID: m.session.TenantID,
-		})
-		if err != nil {
+		a := active[i]
+		if a.Reward == nil {
 			continue
 		}
+		iss := a.Issuance
 
-		rb.AddLine(fmt.Sprintf("%d. %s", i+1, reward.Name))
+		rb.AddLine(fmt.Sprintf("%d. %s", i+1, a.Reward.Name))
 		if iss.Code.Valid {
 			rb.AddLine(fmt.Sprintf("   Code: %s", iss.Code.String))
 		}
+		if w := a.RedemptionWindow(); w != nil {
+			rb.AddLine(fmt.Sprintf("   Use: %s", w))
+		}
 
 		if iss.ExpiresAt.Valid {
 			daysLeft := int(time.Until(iss.ExpiresAt.Time).Hours() / 24)
@@ -294,8 +325,8 @@ func (m *MenuWithContext) RenderMyRewards() USSDResponse {
 		rb.AddBlankLine()
 	}
 
-	if len(issuances) > 3 {
-		rb.AddLine(fmt.Sprintf("+ %d more rewards", len(issuances)-3))
+	if len(active) > 3 {
+		rb.AddLine(fmt.Sprintf("+ %d more rewards", len(active)-3))
 		rb.AddBlankLine()
 	}
 
@@ -305,80 +336,63 @@ func (m *MenuWithContext) RenderMyRewards() USSDResponse {
 	return rb.End()
 }
 
-// RenderRedeemWithCode handles redemption with database access
-func (m *MenuWithContext) RenderRedeemWithCode(code string) USSDResponse {
+// RenderRedeemWithCode handles redemption with database access, reporting
+// whether the reward was redeemed
+func (m *MenuWithContext) RenderRedeemWithCode(code string) (USSDResponse, bool) {
 	if !m.session.CustomerID.Valid {
-		return FormatEnd("Please register first.\n\nContact customer support.")
+		return FormatEnd("Please register first.\n\nContact customer support."), false
 	}
 
-	// Find issuance by code
-	issuances, err := m.queries.ListActiveIssuances(m.ctx, db.ListActiveIssuancesParams{
-		TenantID:   m.session.TenantID,
-		CustomerID: m.session.CustomerID,
-	})
-	if err != nil {
-		return FormatError("Failed to process redemption")
+	redeemed, err := m.redemption.Redeem(m.ctx, m.session.TenantID, m.session.CustomerID, code, reward.ChannelUSSD)
+	var locationErr *locations.Error
+	var windowErr *window.Error
+	var basketErr *basket.Error
+	switch {
+	case errors.As(err, &basketErr):
+		return FormatEnd(basketErr.Message()), false
+	case errors.As(err, &windowErr):
+		return FormatEnd(windowErr.Message()), false
+	case errors.As(err, &locationErr):
+		return FormatEnd(locationErr.Message()), false
+	case errors.Is(err, channels.ErrCodeNotFound):
+		return FormatEnd("Invalid or expired code.\n\nPlease check and try again."), false
+	case errors.Is(err, channels.ErrAlreadyRedeemed):
+		return FormatEnd("This reward has already\nbeen redeemed."), false
+	case errors.Is(err, reward.ErrRewardExpired):
+		return FormatEnd("This reward has expired."), false
+	case errors.Is(err, reward.ErrNotRedeemable):
+		return FormatEnd("This reward can't be\nredeemed yet."), false
+	case err != nil:
+		return FormatError("Redemption failed"), false
 	}
 
-	var targetIssuance *db.Issuance
-	for _, iss := range issuances {
-		if iss.Code.Valid && strings.EqualFold(iss.Code.String, code) {
-			targetIssuance = &iss
-			break
-		}
-	}
-
-	if targetIssuance == nil {
-		return FormatEnd("Invalid or expired code.\n\nPlease check and try again.")
-	}
-
-	// Check if already redeemed
-	if targetIssuance.Status == "redeemed" {
-		return FormatEnd("This reward has already\nbeen redeemed.")
-	}
-
-	// Check expiry
-	if targetIssuance.ExpiresAt.Valid && time.Now().After(targetIssuance.ExpiresAt.Time) {
-		return FormatEnd("This reward has expired.")
-	}
+	return FormatEnd(fmt.Sprintf("Success!\n\n%s redeemed.\n\nThank you for your loyalty!", redeemed.Name("Your reward"))), true
+}
 
-	// Update status to redeemed
-	err = m.queries.UpdateIssuanceStatus(m.ctx, db.UpdateIssuanceStatusParams{
-		ID:       targetIssuance.ID,
-		TenantID: m.session.TenantID,
-		Status:   "issued", // Old status
-		Status_2: "redeemed",   // New status
-	})
-	if err != nil {
-		return FormatError("Redemption failed")
+// RenderPromo redeems a promo code with database access
+func (m *MenuWithContext) RenderPromo(code string) USSDResponse {
+	if !m.session.CustomerID.Valid {
+		return FormatEnd("Please register first.\n\nContact customer support.")
 	}
-
-	// Get reward details
-	reward, err := m.queries.GetRewardByID(m.ctx, db.GetRewardByIDParams{
-		ID:       targetIssuance.RewardID,
-		TenantID: m.session.TenantID,
-	})
-
-	rewardName := "Your reward"
-	if err == nil {
-		rewardName = reward.Name
+	if m.promos == nil {
+		return FormatEnd("Promo codes are not\navailable right now.")
 	}
 
-	return FormatEnd(fmt.Sprintf("Success!\n\n%s redeemed.\n\nThank you for your loyalty!", rewardName))
-}
-
-// GetCustomerByPhone finds a customer by phone number
-func (m *MenuWithContext) GetCustomerByPhone(phoneE164 string) (uuid.UUID, error) {
-	customer, err := m.queries.GetCustomerByPhone(m.ctx, db.GetCustomerByPhoneParams{
-		TenantID: m.session.TenantID,
-		PhoneE164: pgtype.Text{
-			String: phoneE164,
-			Valid:  true,
-		},
-	})
-	if err != nil {
-		return uuid.UUID{}, err
+	_, err := m.promos.Redeem(m.ctx, m.session.TenantID, m.session.CustomerID, code, reward.ChannelUSSD, time.Now())
+	switch {
+	case errors.Is(err, promo.ErrInvalidCode):
+		return FormatEnd("Invalid promo code.\n\nPlease check and try again.")
+	case errors.Is(err, promo.ErrCodeNotActive):
+		return FormatEnd("This promo code is not\nactive right now.")
+	case errors.Is(err, promo.ErrCodeUsedUp):
+		return FormatEnd("This promo code has\nalready been used.")
+	case errors.Is(err, promo.ErrAlreadyRedeemed):
+		return FormatEnd("You have already used\nthis promo.")
+	case errors.Is(err, promo.ErrTooManyAttempts):
+		return FormatEnd("Too many invalid codes.\n\nPlease try again later.")
+	case err != nil:
+		return FormatError("Promo code failed")
 	}
 
-	return uuid.UUID(customer.ID.Bytes), nil
+	return FormatEnd("Promo code accepted!\n\nChoose My Rewards to see\nany reward you earned.")
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/pii"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -56,7 +57,7 @@ func (sm *SessionManager) GetOrCreateSession(ctx context.Context, sessionID, pho
 		},
 		CustomerID: pgtype.UUID{Valid: false}, // Will be set when customer is identified
 		SessionID:  sessionID,
-		PhoneE164:  phoneNumber,
+		PhoneE164:  pii.Text{String: phoneNumber, Valid: true},
 		State:      stateJSON,
 	})
 	if err != nil {
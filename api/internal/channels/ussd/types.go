@@ -1,5 +1,7 @@
 package ussd
 
+import "github.com/bmachimbira/loyalty/api/internal/channels"
+
 // USSDRequest represents an incoming USSD request
 // This follows the Africa's Talking USSD API format
 type USSDRequest struct {
@@ -64,6 +66,8 @@ type SessionData struct {
 	Data        map[string]interface{} `json:"data"`
 	CustomerID  string                 `json:"customer_id,omitempty"`
 	TenantID    string                 `json:"tenant_id,omitempty"`
+	// Branding is the tenant's, loaded for each request rather than stored
+	Branding channels.Branding `json:"-"`
 }
 
 // NewSessionData creates a new session data instance
@@ -73,6 +77,7 @@ func NewSessionData() *SessionData {
 		MenuStack:   []string{},
 		PageNumber:  1,
 		Data:        make(map[string]interface{}),
+		Branding:    channels.DefaultBranding(),
 	}
 }
 
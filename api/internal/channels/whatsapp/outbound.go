@@ -0,0 +1,367 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/connectors"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/metering"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Outbound message statuses
+const (
+	OutboundPending = "pending"
+	OutboundSent    = "sent"
+	OutboundDead    = "dead"
+)
+
+const (
+	// maxOutboundAttempts is how many times a message is tried, including
+	// the failed send that queued it, before it is dead
+	maxOutboundAttempts = 8
+
+	// maxOutboundRetryDelay caps the backoff between attempts
+	maxOutboundRetryDelay = time.Hour
+
+	// outboundBatchSize is the most messages claimed per tenant per pass,
+	// on top of the tenant's per-minute rate
+	outboundBatchSize = 100
+
+	// sentRetention is how long sent messages are kept for inspection
+	sentRetention = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrOutboundNotFound is returned when the outbound message does not exist
+	ErrOutboundNotFound = errors.New("outbound message not found")
+
+	// ErrOutboundNotDead is returned when retrying a message that is not dead
+	ErrOutboundNotDead = errors.New("outbound message is not dead")
+
+	// ErrInvalidOutboundStatus is returned when filtering by an unknown status
+	ErrInvalidOutboundStatus = errors.New("invalid outbound message status")
+)
+
+// OutboundQueue durably queues WhatsApp messages that could not be sent
+// straight away and dispatches them with exponential backoff. Messages that
+// fail permanently, or are still failing after maxOutboundAttempts, are
+// dead: they stay in the queue until retried by hand. Rows are claimed with
+// FOR UPDATE SKIP LOCKED, so several API instances can dispatch side by side.
+type OutboundQueue struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+	sender  *MessageSender
+	logger  *slog.Logger
+}
+
+// NewOutboundQueue creates a queue that dispatches through sender
+func NewOutboundQueue(pool *pgxpool.Pool, sender *MessageSender, logger *slog.Logger) *OutboundQueue {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &OutboundQueue{
+		pool:    pool,
+		queries: db.New(pool),
+		sender:  sender,
+		logger:  logger,
+	}
+}
+
+// Enqueue queues a message whose send failed with sendErr. The failed send
+// counts as its first attempt.
+func (q *OutboundQueue) Enqueue(ctx context.Context, tenantID pgtype.UUID, payload SendMessageRequest, sendErr error) (db.OutboundMessage, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return db.OutboundMessage{}, fmt.Errorf("failed to marshal message payload: %w", err)
+	}
+
+	var msg db.OutboundMessage
+	err = q.withTenant(ctx, tenantID, func(qtx *db.Queries) error {
+		var err error
+		msg, err = qtx.EnqueueOutboundMessage(ctx, db.EnqueueOutboundMessageParams{
+			TenantID:      tenantID,
+			Recipient:     payload.To,
+			Payload:       body,
+			Attempts:      1,
+			NextAttemptAt: pgtype.Timestamptz{Time: time.Now().Add(outboundRetryDelay(1)), Valid: true},
+			LastError:     pgtype.Text{String: sendErr.Error(), Valid: true},
+		})
+		return err
+	})
+	if err != nil {
+		return db.OutboundMessage{}, fmt.Errorf("failed to enqueue outbound message: %w", err)
+	}
+	return msg, nil
+}
+
+// DispatchTenant sends the tenant's due messages, at most its per-minute
+// rate less what was sent in the last minute, and purges old sent ones. It
+// returns the number sent. The pass stops early while the WhatsApp API
+// circuit is open; attempts rejected by it are not counted.
+func (q *OutboundQueue) DispatchTenant(ctx context.Context, tenant db.Tenant, now time.Time) (int, error) {
+	sent := 0
+	err := q.withTenant(ctx, tenant.ID, func(qtx *db.Queries) error {
+		recent, err := qtx.CountOutboundSentSince(ctx, db.CountOutboundSentSinceParams{
+			TenantID: tenant.ID,
+			Since:    pgtype.Timestamptz{Time: now.Add(-time.Minute), Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to count sent messages: %w", err)
+		}
+		limit := min(int64(tenant.WhatsappRatePerMinute)-recent, outboundBatchSize)
+
+		if limit > 0 {
+			messages, err := qtx.ClaimOutboundMessages(ctx, db.ClaimOutboundMessagesParams{
+				TenantID:   tenant.ID,
+				Now:        pgtype.Timestamptz{Time: now, Valid: true},
+				LimitCount: int32(limit),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to claim outbound messages: %w", err)
+			}
+
+			for _, msg := range messages {
+				ok, err := q.dispatch(ctx, qtx, msg, now)
+				if errors.Is(err, connectors.ErrCircuitOpen) {
+					break
+				}
+				if err != nil {
+					return err
+				}
+				if ok {
+					sent++
+				}
+			}
+		}
+
+		if _, err := qtx.DeleteSentOutboundMessages(ctx, db.DeleteSentOutboundMessagesParams{
+			TenantID: tenant.ID,
+			Before:   pgtype.Timestamptz{Time: now.Add(-sentRetention), Valid: true},
+		}); err != nil {
+			return fmt.Errorf("failed to purge sent outbound messages: %w", err)
+		}
+		return nil
+	})
+	return sent, err
+}
+
+// dispatch makes one attempt at sending a queued message and records the
+// outcome, reporting whether it was sent. While the WhatsApp API circuit is
+// open the message is left untouched and ErrCircuitOpen is returned.
+func (q *OutboundQueue) dispatch(ctx context.Context, qtx *db.Queries, msg db.OutboundMessage, now time.Time) (bool, error) {
+	var payload SendMessageRequest
+	var sendErr error
+	permanent := false
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		sendErr = fmt.Errorf("invalid message payload: %w", err)
+		permanent = true
+	} else {
+		sendErr = q.sender.deliver(metering.WithTenant(ctx, msg.TenantID), payload, 1)
+	}
+	if sendErr == nil {
+		if err := qtx.MarkOutboundMessageSent(ctx, msg.ID); err != nil {
+			return false, fmt.Errorf("failed to mark outbound message sent: %w", err)
+		}
+		return true, nil
+	}
+
+	if errors.Is(sendErr, connectors.ErrCircuitOpen) {
+		return false, sendErr
+	}
+
+	lastError := pgtype.Text{String: sendErr.Error(), Valid: true}
+	if permanent || !connectors.IsRetriable(sendErr) || msg.Attempts+1 >= maxOutboundAttempts {
+		q.logger.Error("outbound WhatsApp message failed permanently",
+			"outbound_id", msg.ID,
+			"tenant_id", httputil.FormatUUID(msg.TenantID.Bytes),
+			"attempts", msg.Attempts+1,
+			"error", sendErr)
+		if err := qtx.MarkOutboundMessageDead(ctx, db.MarkOutboundMessageDeadParams{
+			ID:        msg.ID,
+			LastError: lastError,
+		}); err != nil {
+			return false, fmt.Errorf("failed to mark outbound message dead: %w", err)
+		}
+		return false, nil
+	}
+
+	q.logger.Warn("outbound WhatsApp message failed",
+		"outbound_id", msg.ID,
+		"attempts", msg.Attempts+1,
+		"error", sendErr)
+	if err := qtx.MarkOutboundMessageFailed(ctx, db.MarkOutboundMessageFailedParams{
+		ID:            msg.ID,
+		NextAttemptAt: pgtype.Timestamptz{Time: now.Add(outboundRetryDelay(msg.Attempts + 1)), Valid: true},
+		LastError:     lastError,
+	}); err != nil {
+		return false, fmt.Errorf("failed to record outbound message failure: %w", err)
+	}
+	return false, nil
+}
+
+// DispatchAll dispatches every tenant's due messages. A failing tenant is
+// logged and skipped. It returns the number of messages sent.
+func (q *OutboundQueue) DispatchAll(ctx context.Context, now time.Time) (int, error) {
+	tenants, err := q.queries.ListTenants(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	total := 0
+	for _, tenant := range tenants {
+		n, err := q.DispatchTenant(ctx, tenant, now)
+		total += n
+		if err != nil {
+			q.logger.Error("failed to dispatch outbound messages",
+				"tenant_id", httputil.FormatUUID(tenant.ID.Bytes),
+				"error", err)
+		}
+	}
+	return total, nil
+}
+
+// Run dispatches queued messages on a schedule until ctx is cancelled
+func (q *OutboundQueue) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := q.DispatchAll(ctx, time.Now()); err != nil {
+			q.logger.Error("outbound message dispatch failed", "error", err)
+		} else if n > 0 {
+			q.logger.Debug("outbound messages sent", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Get retrieves a queued message by ID
+func (q *OutboundQueue) Get(ctx context.Context, tenantID pgtype.UUID, id int64) (db.OutboundMessage, error) {
+	msg, err := q.queries.GetOutboundMessageByID(ctx, db.GetOutboundMessageByIDParams{
+		ID:       id,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.OutboundMessage{}, ErrOutboundNotFound
+		}
+		return db.OutboundMessage{}, fmt.Errorf("failed to get outbound message: %w", err)
+	}
+	return msg, nil
+}
+
+// List retrieves a paginated list of queued messages, optionally filtered by
+// status, and the total number of messages matching the filter
+func (q *OutboundQueue) List(ctx context.Context, tenantID pgtype.UUID, status, limit, offset string) ([]db.OutboundMessage, int64, error) {
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil || limitInt < 1 {
+		limitInt = 50
+	}
+	if limitInt > 100 {
+		limitInt = 100
+	}
+
+	offsetInt, err := strconv.Atoi(offset)
+	if err != nil || offsetInt < 0 {
+		offsetInt = 0
+	}
+
+	var statusFilter pgtype.Text
+	if status != "" {
+		switch status {
+		case OutboundPending, OutboundSent, OutboundDead:
+		default:
+			return nil, 0, ErrInvalidOutboundStatus
+		}
+		statusFilter = pgtype.Text{String: status, Valid: true}
+	}
+
+	messages, err := q.queries.ListOutboundMessages(ctx, db.ListOutboundMessagesParams{
+		TenantID: tenantID,
+		Status:   statusFilter,
+		Limit:    int32(limitInt),
+		Offset:   int32(offsetInt),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list outbound messages: %w", err)
+	}
+
+	total, err := q.queries.CountOutboundMessages(ctx, db.CountOutboundMessagesParams{
+		TenantID: tenantID,
+		Status:   statusFilter,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count outbound messages: %w", err)
+	}
+	return messages, total, nil
+}
+
+// Retry returns a dead message to the queue with a fresh set of attempts
+func (q *OutboundQueue) Retry(ctx context.Context, tenantID pgtype.UUID, id int64) (db.OutboundMessage, error) {
+	msg, err := q.queries.RetryOutboundMessage(ctx, db.RetryOutboundMessageParams{
+		ID:       id,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Distinguish missing from not dead
+			if _, getErr := q.Get(ctx, tenantID, id); getErr != nil {
+				return db.OutboundMessage{}, getErr
+			}
+			return db.OutboundMessage{}, ErrOutboundNotDead
+		}
+		return db.OutboundMessage{}, fmt.Errorf("failed to retry outbound message: %w", err)
+	}
+	return msg, nil
+}
+
+// withTenant runs fn in a transaction scoped to the tenant. Enqueueing and
+// dispatch run outside a tenant request.
+func (q *OutboundQueue) withTenant(ctx context.Context, tenantID pgtype.UUID, fn func(qtx *db.Queries) error) error {
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenantID.Bytes)); err != nil {
+		return fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	if err := fn(q.queries.WithTx(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// outboundRetryDelay is the exponential backoff after the given number of
+// failed attempts: 30s, 1m, 2m, ... up to maxOutboundRetryDelay
+func outboundRetryDelay(attempts int32) time.Duration {
+	delay := 30 * time.Second
+	for i := int32(1); i < attempts && delay < maxOutboundRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxOutboundRetryDelay {
+		delay = maxOutboundRetryDelay
+	}
+	return delay
+}
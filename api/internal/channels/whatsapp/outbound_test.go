@@ -0,0 +1,245 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+)
+
+func TestOutboundRetryDelay(t *testing.T) {
+	assert.Equal(t, 30*time.Second, outboundRetryDelay(1))
+	assert.Equal(t, time.Minute, outboundRetryDelay(2))
+	assert.Equal(t, 2*time.Minute, outboundRetryDelay(3))
+	assert.Equal(t, 32*time.Minute, outboundRetryDelay(7))
+	assert.Equal(t, maxOutboundRetryDelay, outboundRetryDelay(8))
+	assert.Equal(t, maxOutboundRetryDelay, outboundRetryDelay(50))
+}
+
+// fakeAPI stands in for the WhatsApp API, answering every send with status
+type fakeAPI struct {
+	status atomic.Int32
+	sends  atomic.Int32
+}
+
+// redirectTransport sends every request to the fake API's server
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (rt redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = rt.target.Scheme
+	req.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// newFakeAPI returns a sender whose requests reach a fake WhatsApp API
+// answering 200 until told otherwise
+func newFakeAPI(t *testing.T) (*MessageSender, *fakeAPI) {
+	api := &fakeAPI{}
+	api.status.Store(http.StatusOK)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.sends.Add(1)
+		status := int(api.status.Load())
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			w.Write([]byte(`{"messaging_product": "whatsapp", "messages": [{"id": "wamid.test"}]}`))
+			return
+		}
+		w.Write([]byte(`{"error": {"message": "unavailable", "code": 131000}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	target, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	sender := NewMessageSender("phone", "token")
+	sender.client = &http.Client{Transport: redirectTransport{target: target}, Timeout: 5 * time.Second}
+	return sender, api
+}
+
+func TestOutboundQueue(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set, skipping integration tests")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	require.NoError(t, err)
+	defer pool.Close()
+	queries := db.New(pool)
+
+	// setup creates a tenant allowed ratePerMinute messages a minute and a
+	// queue sending to a fake WhatsApp API
+	setup := func(t *testing.T, ratePerMinute int32) (db.Tenant, *OutboundQueue, *fakeAPI) {
+		tenant, err := queries.CreateTenant(ctx, db.CreateTenantParams{
+			Name:        "Outbound " + t.Name(),
+			CountryCode: "ZW",
+			DefaultCcy:  "USD",
+			Theme:       []byte(`{}`),
+		})
+		require.NoError(t, err)
+		require.NoError(t, queries.UpdateTenantWhatsAppRate(ctx, db.UpdateTenantWhatsAppRateParams{
+			ID:                    tenant.ID,
+			WhatsappRatePerMinute: ratePerMinute,
+		}))
+		tenant.WhatsappRatePerMinute = ratePerMinute
+
+		sender, api := newFakeAPI(t)
+		return tenant, NewOutboundQueue(pool, sender, nil), api
+	}
+	message := SendMessageRequest{
+		MessagingProduct: "whatsapp",
+		To:               "263771234567",
+		Type:             "text",
+		Text:             &TextPayload{Body: "Your reward is ready"},
+	}
+	sendErr := errors.New("WhatsApp API error (code 131000): unavailable")
+
+	t.Run("enqueue", func(t *testing.T) {
+		tenant, queue, _ := setup(t, 60)
+		before := time.Now()
+
+		msg, err := queue.Enqueue(ctx, tenant.ID, message, sendErr)
+		require.NoError(t, err)
+
+		// The failed send counts as the first attempt
+		assert.Equal(t, OutboundPending, msg.Status)
+		assert.Equal(t, int32(1), msg.Attempts)
+		assert.Equal(t, message.To, msg.Recipient)
+		assert.Equal(t, sendErr.Error(), msg.LastError.String)
+		assert.WithinDuration(t, before.Add(outboundRetryDelay(1)), msg.NextAttemptAt.Time, 5*time.Second)
+
+		var payload SendMessageRequest
+		require.NoError(t, json.Unmarshal(msg.Payload, &payload))
+		assert.Equal(t, message, payload)
+	})
+
+	t.Run("dispatch", func(t *testing.T) {
+		tenant, queue, api := setup(t, 60)
+		msg, err := queue.Enqueue(ctx, tenant.ID, message, sendErr)
+		require.NoError(t, err)
+
+		// Nothing is sent before the message is due
+		sent, err := queue.DispatchTenant(ctx, tenant, time.Now())
+		require.NoError(t, err)
+		assert.Zero(t, sent)
+		assert.Zero(t, api.sends.Load())
+
+		sent, err = queue.DispatchTenant(ctx, tenant, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, 1, sent)
+		assert.Equal(t, int32(1), api.sends.Load())
+
+		got, err := queue.Get(ctx, tenant.ID, msg.ID)
+		require.NoError(t, err)
+		assert.Equal(t, OutboundSent, got.Status)
+		assert.Equal(t, int32(2), got.Attempts)
+		assert.True(t, got.SentAt.Valid)
+		assert.False(t, got.LastError.Valid)
+	})
+
+	t.Run("failed attempt is retried later", func(t *testing.T) {
+		tenant, queue, api := setup(t, 60)
+		api.status.Store(http.StatusServiceUnavailable)
+		msg, err := queue.Enqueue(ctx, tenant.ID, message, sendErr)
+		require.NoError(t, err)
+
+		now := time.Now().Add(time.Minute)
+		sent, err := queue.DispatchTenant(ctx, tenant, now)
+		require.NoError(t, err)
+		assert.Zero(t, sent)
+
+		got, err := queue.Get(ctx, tenant.ID, msg.ID)
+		require.NoError(t, err)
+		assert.Equal(t, OutboundPending, got.Status)
+		assert.Equal(t, int32(2), got.Attempts)
+		assert.WithinDuration(t, now.Add(outboundRetryDelay(2)), got.NextAttemptAt.Time, time.Second)
+	})
+
+	t.Run("dead after last attempt", func(t *testing.T) {
+		tenant, queue, api := setup(t, 60)
+		api.status.Store(http.StatusServiceUnavailable)
+		msg, err := queue.Enqueue(ctx, tenant.ID, message, sendErr)
+		require.NoError(t, err)
+		_, err = pool.Exec(ctx, "UPDATE outbound_messages SET attempts = $2 WHERE id = $1", msg.ID, maxOutboundAttempts-1)
+		require.NoError(t, err)
+
+		sent, err := queue.DispatchTenant(ctx, tenant, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.Zero(t, sent)
+
+		got, err := queue.Get(ctx, tenant.ID, msg.ID)
+		require.NoError(t, err)
+		assert.Equal(t, OutboundDead, got.Status)
+		assert.Equal(t, int32(maxOutboundAttempts), got.Attempts)
+		assert.True(t, got.DeadAt.Valid)
+		assert.Contains(t, got.LastError.String, "unavailable")
+
+		dead, total, err := queue.List(ctx, tenant.ID, OutboundDead, "50", "0")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		require.Len(t, dead, 1)
+		assert.Equal(t, msg.ID, dead[0].ID)
+
+		// Dead messages are left alone until retried by hand
+		sent, err = queue.DispatchTenant(ctx, tenant, time.Now().Add(2*time.Hour))
+		require.NoError(t, err)
+		assert.Zero(t, sent)
+		assert.Equal(t, int32(1), api.sends.Load())
+
+		retried, err := queue.Retry(ctx, tenant.ID, msg.ID)
+		require.NoError(t, err)
+		assert.Equal(t, OutboundPending, retried.Status)
+		assert.Zero(t, retried.Attempts)
+
+		api.status.Store(http.StatusOK)
+		sent, err = queue.DispatchTenant(ctx, tenant, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, 1, sent)
+	})
+
+	t.Run("rate cap", func(t *testing.T) {
+		tenant, queue, api := setup(t, 2)
+		for i := 0; i < 5; i++ {
+			_, err := queue.Enqueue(ctx, tenant.ID, message, sendErr)
+			require.NoError(t, err)
+		}
+
+		// At most the tenant's rate is sent a minute
+		now := time.Now().Add(time.Minute)
+		sent, err := queue.DispatchTenant(ctx, tenant, now)
+		require.NoError(t, err)
+		assert.Equal(t, 2, sent)
+
+		sent, err = queue.DispatchTenant(ctx, tenant, now)
+		require.NoError(t, err)
+		assert.Zero(t, sent, "Expected no sends until the minute is up")
+		assert.Equal(t, int32(2), api.sends.Load())
+
+		// A minute later the next messages go out
+		sent, err = queue.DispatchTenant(ctx, tenant, now.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, 2, sent)
+
+		pending, total, err := queue.List(ctx, tenant.ID, OutboundPending, "50", "0")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		assert.Len(t, pending, 1)
+	})
+}
@@ -2,14 +2,30 @@ package whatsapp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/bmachimbira/loyalty/api/internal/catalogcache"
+	"github.com/bmachimbira/loyalty/api/internal/channels"
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/featureflag"
+	"github.com/bmachimbira/loyalty/api/internal/fulfilment"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/metering"
+	"github.com/bmachimbira/loyalty/api/internal/phone"
+	"github.com/bmachimbira/loyalty/api/internal/promo"
+	"github.com/bmachimbira/loyalty/api/internal/receipt"
+	"github.com/bmachimbira/loyalty/api/internal/reward"
+	"github.com/bmachimbira/loyalty/api/internal/reward/basket"
+	"github.com/bmachimbira/loyalty/api/internal/reward/locations"
+	"github.com/bmachimbira/loyalty/api/internal/reward/window"
+	"github.com/bmachimbira/loyalty/api/internal/survey"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -18,17 +34,31 @@ import (
 type MessageProcessor struct {
 	pool           *pgxpool.Pool
 	queries        *db.Queries
+	catalog        *catalogcache.Cache
 	sender         *MessageSender
 	sessionManager *SessionManager
+	rewards        *reward.Service
+	enrollment     *channels.EnrollmentFlow
+	redemption     *channels.RedemptionFlow
+	receipts       *receipt.Service
+	surveys        *survey.Service
+	promos         *promo.Service
+	fulfilments    *fulfilment.Service
+	features       *featureflag.Service
 }
 
 // NewMessageProcessor creates a new message processor
-func NewMessageProcessor(pool *pgxpool.Pool, queries *db.Queries, sender *MessageSender) *MessageProcessor {
+func NewMessageProcessor(pool *pgxpool.Pool, queries *db.Queries, catalog *catalogcache.Cache, sender *MessageSender) *MessageProcessor {
+	rewards := reward.NewService(pool, queries)
 	return &MessageProcessor{
 		pool:           pool,
 		queries:        queries,
+		catalog:        catalog,
 		sender:         sender,
 		sessionManager: NewSessionManager(queries),
+		rewards:        rewards,
+		enrollment:     channels.NewEnrollmentFlow(queries),
+		redemption:     channels.NewRedemptionFlow(queries, catalog, rewards),
 	}
 }
 
@@ -37,18 +67,30 @@ func (p *MessageProcessor) ProcessMessage(ctx context.Context, msg Message) erro
 	// For now, use a default tenant ID (in production, this would be determined by phone number routing)
 	// TODO: Implement tenant resolution based on phone number
 	tenantID := p.getTenantIDFromPhoneNumber(msg.From)
+	ctx = metering.WithTenant(ctx, pgtype.UUID{Bytes: tenantID, Valid: true})
 
 	// Set tenant context for RLS
 	if _, err := p.pool.Exec(ctx, "SET LOCAL app.tenant_id = $1", tenantID); err != nil {
 		return fmt.Errorf("failed to set tenant context: %w", err)
 	}
 
+	// WhatsApp IDs are the sender's number in international format
+	phoneE164, err := phone.FromWhatsAppID(msg.From)
+	if err != nil {
+		return fmt.Errorf("invalid WhatsApp sender %q: %w", msg.From, err)
+	}
+
 	// Get or create session
-	session, err := p.sessionManager.GetOrCreateSession(ctx, msg.From, msg.From, tenantID)
+	session, err := p.sessionManager.GetOrCreateSession(ctx, msg.From, phoneE164, tenantID)
 	if err != nil {
 		return fmt.Errorf("failed to get session: %w", err)
 	}
 
+	// Photos are treated as receipt submissions
+	if msg.Image != nil {
+		return p.handleReceiptImage(ctx, session, msg.Image)
+	}
+
 	// Parse message text
 	text := p.getMessageText(msg)
 	if text == "" {
@@ -87,6 +129,8 @@ func (p *MessageProcessor) ProcessMessage(ctx context.Context, msg Message) erro
 		return p.handleEnrollmentFlow(ctx, session, state, text)
 	case "redemption":
 		return p.handleRedemptionFlow(ctx, session, state, text)
+	case "survey":
+		return p.handleSurveyFlow(ctx, session, state, text)
 	default:
 		// Unknown flow, reset
 		p.sessionManager.ResetSessionState(ctx, session.WaID)
@@ -115,8 +159,14 @@ func (p *MessageProcessor) handleCommand(ctx context.Context, session *db.WaSess
 		return p.handleRewards(ctx, session)
 	case "/myrewards":
 		return p.handleMyRewards(ctx, session)
+	case "/reward":
+		return p.handleRewardDetails(ctx, session, args)
 	case "/redeem":
 		return p.handleRedeem(ctx, session, args)
+	case "/promo":
+		return p.handlePromo(ctx, session, args)
+	case "/track":
+		return p.handleTrack(ctx, session)
 	case "/refer":
 		return p.handleReferral(ctx, session)
 	case "/help":
@@ -128,44 +178,22 @@ func (p *MessageProcessor) handleCommand(ctx context.Context, session *db.WaSess
 
 // handleEnroll handles customer enrollment
 func (p *MessageProcessor) handleEnroll(ctx context.Context, session *db.WaSession) error {
-	// Check if customer already exists
-	customer, err := p.queries.GetCustomerByPhone(ctx, db.GetCustomerByPhoneParams{
-		TenantID: session.TenantID,
-		PhoneE164: pgtype.Text{
-			String: session.PhoneE164,
-			Valid:  true,
-		},
-	})
-
-	if err == nil {
-		// Customer already exists
-		if session.CustomerID.Valid && session.CustomerID.Bytes == customer.ID.Bytes {
-			return p.sender.SendText(ctx, session.WaID, "You're already enrolled in our loyalty program! Send /help to see what you can do.")
-		}
-
-		// Link existing customer to session
-		if err := p.sessionManager.LinkCustomer(ctx, session.WaID, uuid.UUID(customer.ID.Bytes)); err != nil {
-			slog.Error("Failed to link customer to session", "error", err)
-		}
-
-		return p.sender.SendText(ctx, session.WaID, "Welcome back! You're now connected via WhatsApp. Send /help to see available commands.")
+	// Sessions created before numbers were normalized stored the bare
+	// WhatsApp ID, so the number is derived from it again
+	phoneE164, err := phone.FromWhatsAppID(session.WaID)
+	if err != nil {
+		return fmt.Errorf("invalid WhatsApp ID %q: %w", session.WaID, err)
 	}
 
-	if err != pgx.ErrNoRows {
-		return fmt.Errorf("failed to check customer: %w", err)
+	enrollment, err := p.enrollment.Enroll(ctx, session.TenantID, phoneE164, p.sender.Name())
+	if err != nil {
+		return err
 	}
 
-	// Create new customer
-	customer, err = p.queries.CreateCustomer(ctx, db.CreateCustomerParams{
-		TenantID: session.TenantID,
-		PhoneE164: pgtype.Text{
-			String: session.PhoneE164,
-			Valid:  true,
-		},
-		ExternalRef: pgtype.Text{Valid: false},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create customer: %w", err)
+	customer := enrollment.Customer
+	if !enrollment.Created && session.CustomerID.Valid && session.CustomerID.Bytes == customer.ID.Bytes {
+		branding := channels.LoadBranding(ctx, p.queries, session.TenantID)
+		return p.sender.SendText(ctx, session.WaID, branding.Render(AlreadyEnrolledMessage))
 	}
 
 	// Link customer to session
@@ -173,24 +201,13 @@ func (p *MessageProcessor) handleEnroll(ctx context.Context, session *db.WaSessi
 		slog.Error("Failed to link customer to session", "error", err)
 	}
 
-	// Record consent
-	_, err = p.queries.RecordConsent(ctx, db.RecordConsentParams{
-		TenantID:   session.TenantID,
-		CustomerID: customer.ID,
-		Channel:    "whatsapp",
-		Purpose:    "loyalty",
-		Granted:    true,
-		OccurredAt: pgtype.Timestamptz{
-			Time:  time.Now(),
-			Valid: true,
-		},
-	})
-	if err != nil {
-		slog.Error("Failed to record consent", "error", err)
+	if !enrollment.Created {
+		return p.sender.SendText(ctx, session.WaID, "Welcome back! You're now connected via WhatsApp. Send /help to see available commands.")
 	}
 
 	// Send welcome message
-	return p.sender.SendText(ctx, session.WaID, WelcomeMessage)
+	branding := channels.LoadBranding(ctx, p.queries, session.TenantID)
+	return p.sender.SendText(ctx, session.WaID, branding.WithFooter(WelcomeMessage))
 }
 
 // handleBalance shows the customer's points balance
@@ -198,6 +215,9 @@ func (p *MessageProcessor) handleBalance(ctx context.Context, session *db.WaSess
 	if !session.CustomerID.Valid {
 		return p.sender.SendText(ctx, session.WaID, "Please enroll first using /enroll")
 	}
+	if !p.featureEnabled(ctx, session.TenantID, featureflag.FeaturePointsDisplay) {
+		return p.sender.SendText(ctx, session.WaID, FeatureUnavailableMessage)
+	}
 
 	// For Phase 3, we don't have a points system yet
 	// This would be implemented in the future
@@ -211,7 +231,7 @@ func (p *MessageProcessor) handleRewards(ctx context.Context, session *db.WaSess
 	}
 
 	// Get active rewards from catalog
-	rewards, err := p.queries.ListRewards(ctx, session.TenantID)
+	rewards, err := p.catalog.ListRewards(ctx, session.TenantID)
 	if err != nil {
 		return fmt.Errorf("failed to list rewards: %w", err)
 	}
@@ -228,7 +248,7 @@ func (p *MessageProcessor) handleRewards(ctx context.Context, session *db.WaSess
 		msg.WriteString(fmt.Sprintf("%d. *%s*\n", i+1, reward.Name))
 		msg.WriteString(fmt.Sprintf("   Type: %s\n", reward.Type))
 		if reward.Currency.Valid && reward.FaceValue.Valid {
-			msg.WriteString(fmt.Sprintf("   Value: %s %.2f\n", reward.Currency.String, reward.FaceValue))
+			msg.WriteString(fmt.Sprintf("   Value: %s %s\n", reward.Currency.String, httputil.FormatNumeric(reward.FaceValue)))
 		}
 		msg.WriteString("\n")
 	}
@@ -244,16 +264,12 @@ func (p *MessageProcessor) handleMyRewards(ctx context.Context, session *db.WaSe
 		return p.sender.SendText(ctx, session.WaID, "Please enroll first using /enroll")
 	}
 
-	// Get customer's active issuances
-	issuances, err := p.queries.ListActiveIssuances(ctx, db.ListActiveIssuancesParams{
-		TenantID:   session.TenantID,
-		CustomerID: session.CustomerID,
-	})
+	active, err := p.redemption.ActiveRewards(ctx, session.TenantID, session.CustomerID)
 	if err != nil {
-		return fmt.Errorf("failed to list issuances: %w", err)
+		return err
 	}
 
-	if len(issuances) == 0 {
+	if len(active) == 0 {
 		return p.sender.SendText(ctx, session.WaID, NoRewardsMessage)
 	}
 
@@ -261,24 +277,23 @@ func (p *MessageProcessor) handleMyRewards(ctx context.Context, session *db.WaSe
 	var msg strings.Builder
 	msg.WriteString("*Your Active Rewards:*\n\n")
 
-	for i, issuance := range issuances {
-		// Get reward details
-		reward, err := p.queries.GetRewardByID(ctx, db.GetRewardByIDParams{
-			ID:       issuance.RewardID,
-			TenantID: session.TenantID,
-		})
-		if err != nil {
-			slog.Error("Failed to get reward details", "error", err, "reward_id", issuance.RewardID)
+	for i, a := range active {
+		if a.Reward == nil {
 			continue
 		}
+		issuance := a.Issuance
 
-		msg.WriteString(fmt.Sprintf("%d. *%s*\n", i+1, reward.Name))
+		msg.WriteString(fmt.Sprintf("%d. *%s*\n", i+1, a.Reward.Name))
 		msg.WriteString(fmt.Sprintf("   Status: %s\n", issuance.Status))
 
 		if issuance.Code.Valid {
 			msg.WriteString(fmt.Sprintf("   Code: %s\n", issuance.Code.String))
 		}
 
+		if w := a.RedemptionWindow(); w != nil {
+			msg.WriteString(fmt.Sprintf("   Redeem: %s\n", w))
+		}
+
 		if issuance.ExpiresAt.Valid {
 			expiry := issuance.ExpiresAt.Time
 			daysLeft := int(time.Until(expiry).Hours() / 24)
@@ -292,80 +307,160 @@ func (p *MessageProcessor) handleMyRewards(ctx context.Context, session *db.WaSe
 		msg.WriteString("\n")
 	}
 
-	msg.WriteString("Use /redeem [code] to redeem a reward.")
+	msg.WriteString("Use /reward [number] to see full details and terms.\nUse /redeem [code] to redeem a reward.")
 
 	return p.sender.SendText(ctx, session.WaID, msg.String())
 }
 
-// handleRedeem handles reward redemption
-func (p *MessageProcessor) handleRedeem(ctx context.Context, session *db.WaSession, args []string) error {
+// handleRewardDetails shows the full terms of one of the customer's active
+// rewards. Without a number it sends a list to pick from; list selections
+// arrive back as "/reward [number]".
+func (p *MessageProcessor) handleRewardDetails(ctx context.Context, session *db.WaSession, args []string) error {
 	if !session.CustomerID.Valid {
 		return p.sender.SendText(ctx, session.WaID, "Please enroll first using /enroll")
 	}
 
+	// Numbering matches /myrewards
+	active, err := p.redemption.ActiveRewards(ctx, session.TenantID, session.CustomerID)
+	if err != nil {
+		return err
+	}
+
+	if len(active) == 0 {
+		return p.sender.SendText(ctx, session.WaID, NoRewardsMessage)
+	}
+
 	if len(args) == 0 {
-		return p.sender.SendText(ctx, session.WaID, "Please provide a redemption code.\n\nUsage: /redeem [code]\nExample: /redeem ABC123")
+		return p.sendRewardPicker(ctx, session, active)
 	}
 
-	code := strings.ToUpper(args[0])
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 || n > len(active) {
+		return p.sender.SendText(ctx, session.WaID, fmt.Sprintf("Please choose a reward number between 1 and %d.\n\nUsage: /reward [number]\nExample: /reward 1", len(active)))
+	}
 
-	// Find issuance by code
-	issuances, err := p.queries.ListActiveIssuances(ctx, db.ListActiveIssuancesParams{
-		TenantID:   session.TenantID,
-		CustomerID: session.CustomerID,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to list issuances: %w", err)
+	a := active[n-1]
+	if a.Reward == nil {
+		return fmt.Errorf("failed to get reward %s", httputil.FormatUUID(a.Issuance.RewardID.Bytes))
 	}
 
-	var targetIssuance *db.Issuance
-	for _, iss := range issuances {
-		if iss.Code.Valid && strings.ToUpper(iss.Code.String) == code {
-			targetIssuance = &iss
-			break
+	return p.sender.SendText(ctx, session.WaID, FormatRewardDetails(*a.Reward, a.Issuance, time.Now()))
+}
+
+// sendRewardPicker sends the customer's active rewards as a menu
+func (p *MessageProcessor) sendRewardPicker(ctx context.Context, session *db.WaSession, active []channels.ActiveReward) error {
+	menu := channels.Menu{
+		Title:   "My rewards",
+		Body:    "Choose a reward to see its full details and terms.",
+		Options: make([]channels.MenuOption, len(active)),
+	}
+	for i, a := range active {
+		menu.Options[i] = channels.MenuOption{
+			ID:    fmt.Sprintf("/reward %d", i+1),
+			Title: a.Name(fmt.Sprintf("Reward %d", i+1)),
+		}
+		if a.Issuance.ExpiresAt.Valid {
+			menu.Options[i].Description = "Expires " + a.Issuance.ExpiresAt.Time.Format("2 Jan 2006")
 		}
 	}
 
-	if targetIssuance == nil {
-		return p.sender.SendText(ctx, session.WaID, "Invalid or expired redemption code. Use /myrewards to see your active rewards.")
+	if shown := p.sender.Capabilities().MaxMenuOptions; len(active) > shown {
+		menu.Body += fmt.Sprintf("\n\nShowing %d of %d. Send /reward [number] for the others.", shown, len(active))
 	}
 
-	// Check if already redeemed
-	if targetIssuance.Status == "redeemed" {
-		return p.sender.SendText(ctx, session.WaID, "This reward has already been redeemed.")
+	return p.sender.SendMenu(ctx, session.WaID, menu)
+}
+
+// handleRedeem handles reward redemption
+func (p *MessageProcessor) handleRedeem(ctx context.Context, session *db.WaSession, args []string) error {
+	if !session.CustomerID.Valid {
+		return p.sender.SendText(ctx, session.WaID, "Please enroll first using /enroll")
+	}
+
+	if len(args) == 0 {
+		return p.sender.SendText(ctx, session.WaID, "Please provide a redemption code.\n\nUsage: /redeem [code]\nExample: /redeem ABC123")
 	}
 
-	// Check expiry
-	if targetIssuance.ExpiresAt.Valid && time.Now().After(targetIssuance.ExpiresAt.Time) {
+	redeemed, err := p.redemption.Redeem(ctx, session.TenantID, session.CustomerID, args[0], p.sender.Name())
+	var locationErr *locations.Error
+	var windowErr *window.Error
+	var basketErr *basket.Error
+	switch {
+	case errors.As(err, &basketErr):
+		return p.sender.SendText(ctx, session.WaID, basketErr.Message()+" Show your code to the cashier when you pay.")
+	case errors.As(err, &windowErr):
+		return p.sender.SendText(ctx, session.WaID, windowErr.Message()+" Please try again then.")
+	case errors.As(err, &locationErr):
+		return p.sender.SendText(ctx, session.WaID, locationErr.Message()+" Show your code to the cashier there.")
+	case errors.Is(err, channels.ErrCodeNotFound):
+		return p.sender.SendText(ctx, session.WaID, "Invalid or expired redemption code. Use /myrewards to see your active rewards.")
+	case errors.Is(err, channels.ErrAlreadyRedeemed):
+		return p.sender.SendText(ctx, session.WaID, "This reward has already been redeemed.")
+	case errors.Is(err, reward.ErrRewardExpired):
 		return p.sender.SendText(ctx, session.WaID, "This reward has expired.")
+	case errors.Is(err, reward.ErrNotRedeemable):
+		return p.sender.SendText(ctx, session.WaID, "This reward can't be redeemed yet. Please try again later.")
+	case err != nil:
+		return err
 	}
 
-	// Update status to redeemed
-	err = p.queries.UpdateIssuanceStatus(ctx, db.UpdateIssuanceStatusParams{
-		ID:       targetIssuance.ID,
-		TenantID: session.TenantID,
-		Status:   "reserved", // Old status
-		Status_2: "redeemed", // New status
-	})
-	if err != nil {
-		return fmt.Errorf("failed to redeem reward: %w", err)
+	if p.surveys != nil {
+		p.surveys.TriggerAfterRedemption(session.TenantID, session.CustomerID, redeemed.Issuance.ID)
 	}
 
-	// Get reward details
-	reward, err := p.queries.GetRewardByID(ctx, db.GetRewardByIDParams{
-		ID:       targetIssuance.RewardID,
-		TenantID: session.TenantID,
-	})
-	if err != nil {
-		slog.Error("Failed to get reward details", "error", err)
+	return p.sender.SendText(ctx, session.WaID, fmt.Sprintf("✅ Success!\n\n*%s* has been redeemed.\n\nThank you for being a loyal customer!", redeemed.Name("Your reward")))
+}
+
+// handlePromo redeems a promo code
+func (p *MessageProcessor) handlePromo(ctx context.Context, session *db.WaSession, args []string) error {
+	if !session.CustomerID.Valid {
+		return p.sender.SendText(ctx, session.WaID, "Please enroll first using /enroll")
+	}
+	if p.promos == nil {
+		return p.sender.SendText(ctx, session.WaID, PromosUnavailableMessage)
+	}
+	if len(args) == 0 {
+		return p.sender.SendText(ctx, session.WaID, PromoUsageMessage)
+	}
+
+	_, err := p.promos.Redeem(ctx, session.TenantID, session.CustomerID, strings.Join(args, ""), reward.ChannelWhatsApp, time.Now())
+	switch {
+	case err == nil:
+		return p.sender.SendText(ctx, session.WaID, PromoAcceptedMessage)
+	case errors.Is(err, promo.ErrInvalidCode):
+		return p.sender.SendText(ctx, session.WaID, PromoInvalidMessage)
+	case errors.Is(err, promo.ErrCodeNotActive):
+		return p.sender.SendText(ctx, session.WaID, PromoNotActiveMessage)
+	case errors.Is(err, promo.ErrCodeUsedUp):
+		return p.sender.SendText(ctx, session.WaID, PromoUsedUpMessage)
+	case errors.Is(err, promo.ErrAlreadyRedeemed):
+		return p.sender.SendText(ctx, session.WaID, PromoAlreadyRedeemedMessage)
+	case errors.Is(err, promo.ErrTooManyAttempts):
+		return p.sender.SendText(ctx, session.WaID, PromoTooManyAttemptsMessage)
+	default:
+		slog.Error("Failed to redeem promo code", "wa_id", session.WaID, "error", err)
+		return p.sender.SendText(ctx, session.WaID, ErrorMessage)
+	}
+}
+
+// handleTrack shows the delivery status of the customer's physical rewards
+func (p *MessageProcessor) handleTrack(ctx context.Context, session *db.WaSession) error {
+	if !session.CustomerID.Valid {
+		return p.sender.SendText(ctx, session.WaID, "Please enroll first using /enroll")
+	}
+	if p.fulfilments == nil {
+		return p.sender.SendText(ctx, session.WaID, NoDeliveriesMessage)
 	}
 
-	rewardName := "Your reward"
-	if err == nil {
-		rewardName = reward.Name
+	items, err := p.fulfilments.CustomerFulfilments(ctx, session.TenantID, session.CustomerID, maxTrackedItems)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return p.sender.SendText(ctx, session.WaID, NoDeliveriesMessage)
 	}
 
-	return p.sender.SendText(ctx, session.WaID, fmt.Sprintf("✅ Success!\n\n*%s* has been redeemed.\n\nThank you for being a loyal customer!", rewardName))
+	return p.sender.SendText(ctx, session.WaID, FormatTracking(items))
 }
 
 // handleReferral provides referral information
@@ -373,14 +468,28 @@ func (p *MessageProcessor) handleReferral(ctx context.Context, session *db.WaSes
 	if !session.CustomerID.Valid {
 		return p.sender.SendText(ctx, session.WaID, "Please enroll first using /enroll")
 	}
+	if !p.featureEnabled(ctx, session.TenantID, featureflag.FeatureReferrals) {
+		return p.sender.SendText(ctx, session.WaID, FeatureUnavailableMessage)
+	}
 
 	// For Phase 3, referral system is not implemented yet
-	return p.sender.SendText(ctx, session.WaID, "Referral program coming soon!\n\nShare our loyalty program with friends and earn bonus rewards.")
+	branding := channels.LoadBranding(ctx, p.queries, session.TenantID)
+	return p.sender.SendText(ctx, session.WaID, branding.Render(ReferralMessage))
+}
+
+// featureEnabled reports whether the tenant has a feature on for WhatsApp.
+// Without a feature flag service every feature is on.
+func (p *MessageProcessor) featureEnabled(ctx context.Context, tenantID pgtype.UUID, feature string) bool {
+	if p.features == nil {
+		return true
+	}
+	return p.features.Enabled(ctx, tenantID, featureflag.ChannelWhatsApp, feature)
 }
 
 // handleHelp shows help message
 func (p *MessageProcessor) handleHelp(ctx context.Context, session *db.WaSession) error {
-	return p.sender.SendText(ctx, session.WaID, HelpMessage)
+	branding := channels.LoadBranding(ctx, p.queries, session.TenantID)
+	return p.sender.SendText(ctx, session.WaID, branding.WithFooter(HelpMessage))
 }
 
 // handleEnrollmentFlow handles multi-step enrollment flow
@@ -397,6 +506,100 @@ func (p *MessageProcessor) handleRedemptionFlow(ctx context.Context, session *db
 	return p.sender.SendText(ctx, session.WaID, "Please use /redeem [code] to redeem a reward.")
 }
 
+// handleReceiptImage submits a photo of a receipt for staff review
+func (p *MessageProcessor) handleReceiptImage(ctx context.Context, session *db.WaSession, image *MediaMsg) error {
+	if !session.CustomerID.Valid {
+		return p.sender.SendText(ctx, session.WaID, "Please enroll first using /enroll")
+	}
+	if p.receipts == nil {
+		return p.sender.SendText(ctx, session.WaID, ReceiptsUnavailableMessage)
+	}
+
+	data, _, err := p.sender.DownloadMedia(ctx, image.ID, receipt.MaxImageBytes)
+	if err != nil {
+		slog.Error("Failed to download receipt image", "media_id", image.ID, "error", err)
+		return p.sender.SendText(ctx, session.WaID, ErrorMessage)
+	}
+
+	_, err = p.receipts.Submit(ctx, receipt.SubmitParams{
+		TenantID:   session.TenantID,
+		CustomerID: session.CustomerID,
+		Source:     receipt.SourceWhatsApp,
+		MimeType:   http.DetectContentType(data),
+		Image:      data,
+	})
+	switch {
+	case err == nil:
+		return p.sender.SendText(ctx, session.WaID, ReceiptReceivedMessage)
+	case errors.Is(err, receipt.ErrDuplicateReceipt):
+		return p.sender.SendText(ctx, session.WaID, ReceiptDuplicateMessage)
+	case errors.Is(err, receipt.ErrUnsupportedImage), errors.Is(err, receipt.ErrImageTooLarge):
+		return p.sender.SendText(ctx, session.WaID, ReceiptUnsupportedMessage)
+	default:
+		slog.Error("Failed to submit receipt", "wa_id", session.WaID, "error", err)
+		return p.sender.SendText(ctx, session.WaID, ErrorMessage)
+	}
+}
+
+// handleSurveyFlow records a reply to the current survey question
+func (p *MessageProcessor) handleSurveyFlow(ctx context.Context, session *db.WaSession, state *SessionState, text string) error {
+	responseID, _ := state.GetFlowDataString("response_id")
+
+	var responseUUID pgtype.UUID
+	if p.surveys == nil || responseUUID.Scan(responseID) != nil {
+		p.sessionManager.ResetSessionState(ctx, session.WaID)
+		return p.sender.SendText(ctx, session.WaID, InvalidCommandMessage)
+	}
+
+	result, err := p.surveys.Answer(ctx, session.TenantID, responseUUID, text)
+	if err != nil {
+		p.sessionManager.ResetSessionState(ctx, session.WaID)
+		if errors.Is(err, survey.ErrResponseClosed) || errors.Is(err, survey.ErrResponseNotFound) {
+			return p.sender.SendText(ctx, session.WaID, SurveyClosedMessage)
+		}
+		slog.Error("Failed to record survey answer", "wa_id", session.WaID, "error", err)
+		return p.sender.SendText(ctx, session.WaID, ErrorMessage)
+	}
+
+	switch {
+	case result.Invalid != "":
+		return p.sender.SendText(ctx, session.WaID, fmt.Sprintf("Sorry, %s.\n\n%s", result.Invalid, result.Next))
+	case result.Completed:
+		if err := p.sessionManager.ResetSessionState(ctx, session.WaID); err != nil {
+			slog.Warn("Failed to reset session after survey", "error", err)
+		}
+		return p.sender.SendText(ctx, session.WaID, SurveyCompleteMessage)
+	default:
+		state.NextStep()
+		if err := p.sessionManager.UpdateSessionState(ctx, session.WaID, state); err != nil {
+			return err
+		}
+		return p.sender.SendText(ctx, session.WaID, result.Next)
+	}
+}
+
+// deliverSurveyQuestion puts the customer's session into the survey flow
+// and sends the first question
+func (p *MessageProcessor) deliverSurveyQuestion(ctx context.Context, tenantID, customerID, responseID pgtype.UUID, prompt string) error {
+	ctx = metering.WithTenant(ctx, tenantID)
+	session, err := p.sessionManager.GetSessionByCustomer(ctx, tenantID.Bytes, customerID.Bytes)
+	if err != nil {
+		return err
+	}
+
+	state, err := p.sessionManager.GetSessionState(session)
+	if err != nil {
+		return fmt.Errorf("failed to get session state: %w", err)
+	}
+	state.StartFlow("survey")
+	state.SetFlowData("response_id", uuid.UUID(responseID.Bytes).String())
+	if err := p.sessionManager.UpdateSessionState(ctx, session.WaID, state); err != nil {
+		return err
+	}
+
+	return p.sender.SendText(ctx, session.WaID, SurveyIntroMessage+"\n\n"+prompt)
+}
+
 // getMessageText extracts text from a message
 func (p *MessageProcessor) getMessageText(msg Message) string {
 	if msg.Text != nil {
@@ -405,6 +608,15 @@ func (p *MessageProcessor) getMessageText(msg Message) string {
 	if msg.Button != nil {
 		return msg.Button.Text
 	}
+	if msg.Interactive != nil {
+		// Reply IDs carry the command to run, e.g. "/reward 2"
+		if msg.Interactive.ListReply != nil {
+			return msg.Interactive.ListReply.ID
+		}
+		if msg.Interactive.ButtonReply != nil {
+			return msg.Interactive.ButtonReply.ID
+		}
+	}
 	return ""
 }
 
@@ -0,0 +1,97 @@
+package whatsapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/reward/window"
+	"github.com/bmachimbira/loyalty/api/internal/rewardtypes"
+)
+
+const (
+	// maxListRows is the most rows WhatsApp allows in a list message
+	maxListRows = 10
+	// maxRowTitleLen is the longest list row title WhatsApp accepts
+	maxRowTitleLen = 24
+)
+
+// rewardDetailsMetadata is the part of reward metadata shown to customers
+type rewardDetailsMetadata struct {
+	rewardtypes.TermsMetadata
+	PickupLocations []string `json:"pickup_locations,omitempty"`
+}
+
+// FormatRewardDetails renders the full details and terms of an issued reward
+// from its catalog entry and metadata
+func FormatRewardDetails(reward db.RewardCatalog, issuance db.Issuance, now time.Time) string {
+	var meta rewardDetailsMetadata
+	if len(reward.Metadata) > 0 {
+		// Unknown or malformed metadata just leaves the terms out
+		_ = json.Unmarshal(reward.Metadata, &meta)
+	}
+
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("*%s*\n", reward.Name))
+	if meta.Description != "" {
+		msg.WriteString(meta.Description + "\n")
+	}
+	msg.WriteString("\n")
+
+	msg.WriteString(fmt.Sprintf("Status: %s\n", issuance.Status))
+	if issuance.Currency.Valid && issuance.FaceAmount.Valid {
+		msg.WriteString(fmt.Sprintf("Value: %s %s\n", issuance.Currency.String, httputil.FormatNumeric(issuance.FaceAmount)))
+	} else if reward.Currency.Valid && reward.FaceValue.Valid {
+		msg.WriteString(fmt.Sprintf("Value: %s %s\n", reward.Currency.String, httputil.FormatNumeric(reward.FaceValue)))
+	}
+	if issuance.Code.Valid {
+		msg.WriteString(fmt.Sprintf("Code: %s\n", issuance.Code.String))
+	}
+	if issuance.ExpiresAt.Valid {
+		expiry := issuance.ExpiresAt.Time
+		msg.WriteString(fmt.Sprintf("Expires: %s", expiry.Format("2 Jan 2006")))
+		if daysLeft := int(expiry.Sub(now).Hours() / 24); daysLeft > 0 {
+			msg.WriteString(fmt.Sprintf(" (in %d days)", daysLeft))
+		}
+		msg.WriteString("\n")
+	}
+	// Windows are validated when rewards are created
+	if w, _ := window.FromMetadata(reward.Metadata); w != nil {
+		msg.WriteString(fmt.Sprintf("Redeemable: %s\n", w))
+	}
+	if meta.MinBasket > 0 {
+		msg.WriteString(fmt.Sprintf("Minimum spend: %.2f\n", meta.MinBasket))
+	}
+
+	if meta.Instructions != "" {
+		msg.WriteString("\n*How to redeem:*\n" + meta.Instructions + "\n")
+	} else if issuance.Code.Valid {
+		msg.WriteString(fmt.Sprintf("\n*How to redeem:*\nSend /redeem %s or show the code in store.\n", issuance.Code.String))
+	}
+
+	stores := append(meta.StoreRestrictions, meta.PickupLocations...)
+	if len(stores) > 0 {
+		msg.WriteString("\n*Valid at:*\n")
+		for _, store := range stores {
+			msg.WriteString("• " + store + "\n")
+		}
+	}
+
+	if meta.Terms != "" {
+		msg.WriteString("\n*Terms:*\n" + meta.Terms + "\n")
+	}
+
+	return strings.TrimRight(msg.String(), "\n")
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,71 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatRewardDetails(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var face pgtype.Numeric
+	require.NoError(t, face.Scan("3.50"))
+
+	reward := db.RewardCatalog{
+		Name:      "Free Coffee Voucher",
+		Type:      "voucher_code",
+		Currency:  pgtype.Text{String: "USD", Valid: true},
+		FaceValue: face,
+		Metadata: []byte(`{
+			"description": "Any regular coffee",
+			"instructions": "Present code at counter",
+			"store_restrictions": ["Sam Levy's Village", "Avondale"],
+			"terms": "One per visit. Not exchangeable for cash.",
+			"min_basket": 5,
+			"redemption_window": {"days": ["mon", "tue", "wed", "thu", "fri"], "start": "11:00", "end": "14:00"}
+		}`),
+	}
+	issuance := db.Issuance{
+		Status:    "issued",
+		Code:      pgtype.Text{String: "ABC123", Valid: true},
+		ExpiresAt: pgtype.Timestamptz{Time: now.Add(5*24*time.Hour + time.Hour), Valid: true},
+	}
+
+	got := FormatRewardDetails(reward, issuance, now)
+
+	assert.Contains(t, got, "*Free Coffee Voucher*\nAny regular coffee")
+	assert.Contains(t, got, "Value: USD 3.50")
+	assert.Contains(t, got, "Code: ABC123")
+	assert.Contains(t, got, "Expires: 6 Jun 2025 (in 5 days)")
+	assert.Contains(t, got, "Redeemable: Mon-Fri 11:00-14:00")
+	assert.Contains(t, got, "Minimum spend: 5.00")
+	assert.Contains(t, got, "*How to redeem:*\nPresent code at counter")
+	assert.Contains(t, got, "• Sam Levy's Village\n• Avondale")
+	assert.Contains(t, got, "*Terms:*\nOne per visit. Not exchangeable for cash.")
+}
+
+func TestFormatRewardDetails_NoMetadata(t *testing.T) {
+	reward := db.RewardCatalog{Name: "Mystery Gift", Metadata: []byte(`{}`)}
+	issuance := db.Issuance{
+		Status: "issued",
+		Code:   pgtype.Text{String: "XYZ", Valid: true},
+	}
+
+	got := FormatRewardDetails(reward, issuance, time.Now())
+
+	assert.Contains(t, got, "Send /redeem XYZ")
+	assert.NotContains(t, got, "*Terms:*")
+	assert.NotContains(t, got, "Redeemable:")
+	assert.NotContains(t, got, "*Valid at:*")
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", truncate("short", 24))
+	assert.Equal(t, "Zimbabwe Dollar Voucher…", truncate("Zimbabwe Dollar Voucher Bundle", 24))
+	assert.Len(t, []rune(truncate("Zimbabwe Dollar Voucher Bundle", 24)), 24)
+}
@@ -4,11 +4,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"sort"
+	"strconv"
 	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/channels"
+	"github.com/bmachimbira/loyalty/api/internal/connectors"
+	"github.com/bmachimbira/loyalty/api/internal/metering"
+	"github.com/bmachimbira/loyalty/api/internal/phone"
+	"github.com/bmachimbira/loyalty/api/internal/reward"
 )
 
 const (
@@ -17,24 +26,67 @@ const (
 	retryDelay         = time.Second * 2
 )
 
-// MessageSender handles sending messages via WhatsApp Business API
+// maxTextLength is the longest text message body WhatsApp accepts
+const maxTextLength = 4096
+
+// MessageSender handles sending messages via WhatsApp Business API. It is
+// the WhatsApp channels.Channel.
 type MessageSender struct {
 	client      *http.Client
+	breaker     *connectors.CircuitBreaker
 	phoneID     string
 	accessToken string
+	meter       *metering.Meter
+	queue       *OutboundQueue
 }
 
-// NewMessageSender creates a new message sender
+// NewMessageSender creates a new message sender. Sends fail fast once the
+// WhatsApp API has failed 5 times in a row, until a probe 30 seconds later
+// succeeds.
 func NewMessageSender(phoneID, accessToken string) *MessageSender {
+	breaker := connectors.NewCircuitBreaker(5, 30*time.Second)
+	breaker.SetName("whatsapp")
+
 	return &MessageSender{
 		client: &http.Client{
 			Timeout: time.Second * 30,
 		},
+		breaker:     breaker,
 		phoneID:     phoneID,
 		accessToken: accessToken,
 	}
 }
 
+// SetQueue queues messages that fail to send for a retriable reason, or
+// while the WhatsApp API circuit is open, instead of failing them. Only
+// messages sent with a context tagged with their tenant (metering.WithTenant)
+// can be queued.
+func (s *MessageSender) SetQueue(queue *OutboundQueue) {
+	s.queue = queue
+}
+
+// SetMeter meters sent messages to the tenant their context is tagged with,
+// see metering.WithTenant
+func (s *MessageSender) SetMeter(meter *metering.Meter) {
+	s.meter = meter
+}
+
+// Name returns the channel's name
+func (s *MessageSender) Name() string {
+	return reward.ChannelWhatsApp
+}
+
+// Capabilities reports that WhatsApp sends interactive lists and can message
+// customers outside a reply
+func (s *MessageSender) Capabilities() channels.Capabilities {
+	return channels.Capabilities{
+		Interactive:    true,
+		MaxMenuOptions: maxListRows,
+		MaxTextLength:  maxTextLength,
+		Push:           true,
+	}
+}
+
 // SendText sends a text message
 func (s *MessageSender) SendText(ctx context.Context, to, text string) error {
 	req := SendMessageRequest{
@@ -56,17 +108,9 @@ func (s *MessageSender) SendTemplate(ctx context.Context, to, templateName strin
 	// Build template parameters
 	var components []TemplateComponentPayload
 	if len(params) > 0 {
-		parameters := make([]TemplateParameterPayload, 0, len(params))
-		for _, value := range params {
-			parameters = append(parameters, TemplateParameterPayload{
-				Type: "text",
-				Text: value,
-			})
-		}
-
 		components = append(components, TemplateComponentPayload{
 			Type:       "body",
-			Parameters: parameters,
+			Parameters: templateParameters(params),
 		})
 	}
 
@@ -87,6 +131,32 @@ func (s *MessageSender) SendTemplate(ctx context.Context, to, templateName strin
 	return s.send(ctx, req)
 }
 
+// templateParameters orders params by their placeholder, so "2" fills {{2}}
+// whatever order the map iterates in
+func templateParameters(params map[string]string) []TemplateParameterPayload {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, errA := strconv.Atoi(keys[i])
+		b, errB := strconv.Atoi(keys[j])
+		if errA != nil || errB != nil {
+			return keys[i] < keys[j]
+		}
+		return a < b
+	})
+
+	parameters := make([]TemplateParameterPayload, 0, len(params))
+	for _, key := range keys {
+		parameters = append(parameters, TemplateParameterPayload{
+			Type: "text",
+			Text: params[key],
+		})
+	}
+	return parameters
+}
+
 // SendInteractive sends an interactive message with buttons
 func (s *MessageSender) SendInteractive(ctx context.Context, to, bodyText string, buttons []ButtonPayload) error {
 	req := SendMessageRequest{
@@ -130,12 +200,70 @@ func (s *MessageSender) SendList(ctx context.Context, to, bodyText, buttonText s
 	return s.send(ctx, req)
 }
 
-// send sends a message request to WhatsApp API with retry logic
+// SendMenu sends a menu as an interactive list with a single section. Only
+// the first maxListRows options are shown, with titles truncated to fit.
+func (s *MessageSender) SendMenu(ctx context.Context, to string, menu channels.Menu) error {
+	rows := make([]RowPayload, 0, min(len(menu.Options), maxListRows))
+	for i, option := range menu.Options {
+		if i == maxListRows {
+			break
+		}
+		rows = append(rows, RowPayload{
+			ID:          option.ID,
+			Title:       truncate(option.Title, maxRowTitleLen),
+			Description: option.Description,
+		})
+	}
+
+	return s.SendList(ctx, to, menu.Body, menu.Title, []SectionPayload{
+		{Title: menu.Title, Rows: rows},
+	})
+}
+
+// send sends a message request to WhatsApp API, queueing it for later
+// delivery if it fails for a reason worth retrying and a queue is set
 func (s *MessageSender) send(ctx context.Context, payload SendMessageRequest) error {
+	// Customers are addressed by E.164 number elsewhere; WhatsApp wants the
+	// number without the +
+	payload.To = phone.WhatsAppID(payload.To)
+
+	err := s.deliver(ctx, payload, maxRetries)
+	if err == nil || s.queue == nil || !queueable(err) {
+		return err
+	}
+
+	tenantID, ok := metering.TenantFromContext(ctx)
+	if !ok {
+		return err
+	}
+	if _, qErr := s.queue.Enqueue(context.WithoutCancel(ctx), tenantID, payload, err); qErr != nil {
+		slog.Error("failed to queue WhatsApp message",
+			"to", payload.To,
+			"error", qErr,
+		)
+		return err
+	}
+
+	slog.Warn("WhatsApp message send failed, queued for retry",
+		"to", payload.To,
+		"type", payload.Type,
+		"error", err,
+	)
+	return nil
+}
+
+// queueable reports whether a failed send may succeed if retried later
+func queueable(err error) bool {
+	return errors.Is(err, connectors.ErrCircuitOpen) || connectors.IsRetriable(err)
+}
+
+// deliver sends a message request to WhatsApp API, trying up to attempts
+// times
+func (s *MessageSender) deliver(ctx context.Context, payload SendMessageRequest, attempts int) error {
 	url := fmt.Sprintf("%s/%s/messages", whatsappAPIBaseURL, s.phoneID)
 
 	var lastErr error
-	for attempt := 1; attempt <= maxRetries; attempt++ {
+	for attempt := 1; attempt <= attempts; attempt++ {
 		if attempt > 1 {
 			// Wait before retry
 			select {
@@ -160,9 +288,12 @@ func (s *MessageSender) send(ctx context.Context, payload SendMessageRequest) er
 		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("Authorization", "Bearer "+s.accessToken)
 
-		// Send request
-		resp, err := s.client.Do(req)
-		if err != nil {
+		// Send request. Once the circuit opens, fail fast instead of retrying.
+		resp, err := s.do(req)
+		if errors.Is(err, connectors.ErrCircuitOpen) {
+			return err
+		}
+		if resp == nil {
 			lastErr = fmt.Errorf("failed to send request: %w", err)
 			slog.Warn("WhatsApp API request failed, retrying",
 				"attempt", attempt,
@@ -196,15 +327,17 @@ func (s *MessageSender) send(ctx context.Context, payload SendMessageRequest) er
 					"msg_id", result.Messages[0].ID,
 				)
 			}
+			s.meter.AddForContext(ctx, metering.MessagesSent(metering.ChannelWhatsApp), 1)
 			return nil
 		}
 
 		// Handle error response
 		var errResp ErrorResponse
 		if err := json.Unmarshal(respBody, &errResp); err != nil {
-			lastErr = fmt.Errorf("WhatsApp API error (status %d): %s", resp.StatusCode, string(respBody))
+			lastErr = connectors.NewHTTPError(resp.StatusCode, "WhatsApp API error: "+string(respBody))
 		} else {
-			lastErr = fmt.Errorf("WhatsApp API error (code %d): %s", errResp.Error.Code, errResp.Error.Message)
+			lastErr = connectors.NewHTTPError(resp.StatusCode,
+				fmt.Sprintf("WhatsApp API error (code %d): %s", errResp.Error.Code, errResp.Error.Message))
 		}
 
 		// Check if error is retryable
@@ -222,12 +355,93 @@ func (s *MessageSender) send(ctx context.Context, payload SendMessageRequest) er
 	}
 
 	slog.Error("WhatsApp message send failed after all retries",
-		"attempts", maxRetries,
+		"attempts", attempts,
 		"error", lastErr,
 	)
 	return lastErr
 }
 
+// do sends a request through the circuit breaker. Server errors and rate
+// limiting count as failures; their response is returned along with the
+// error so the caller can read the error body.
+func (s *MessageSender) do(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	err := s.breaker.Execute(func() error {
+		var reqErr error
+		resp, reqErr = s.client.Do(req)
+		if reqErr != nil {
+			return reqErr
+		}
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return connectors.NewHTTPError(resp.StatusCode, "server error or rate limit")
+		}
+		return nil
+	})
+	return resp, err
+}
+
+// mediaInfo is the metadata returned for an uploaded media object
+type mediaInfo struct {
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type"`
+}
+
+// DownloadMedia downloads a media object sent by a customer. Media is
+// fetched in two steps: the media ID resolves to a short-lived URL, which
+// is then downloaded with the same access token. Downloads larger than
+// maxBytes are rejected.
+func (s *MessageSender) DownloadMedia(ctx context.Context, mediaID string, maxBytes int64) ([]byte, string, error) {
+	infoURL := fmt.Sprintf("%s/%s", whatsappAPIBaseURL, mediaID)
+
+	infoBody, err := s.get(ctx, infoURL, 1<<16)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get media info: %w", err)
+	}
+
+	var info mediaInfo
+	if err := json.Unmarshal(infoBody, &info); err != nil {
+		return nil, "", fmt.Errorf("failed to parse media info: %w", err)
+	}
+	if info.URL == "" {
+		return nil, "", fmt.Errorf("media info has no download URL")
+	}
+
+	data, err := s.get(ctx, info.URL, maxBytes+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download media: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, "", fmt.Errorf("media exceeds %d bytes", maxBytes)
+	}
+
+	return data, info.MimeType, nil
+}
+
+// get performs an authenticated GET request and reads at most limit bytes
+func (s *MessageSender) get(ctx context.Context, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.accessToken)
+
+	resp, err := s.do(req)
+	if resp == nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("request failed (status %d)", resp.StatusCode)
+	}
+
+	return body, nil
+}
+
 // MarkAsRead marks a message as read
 func (s *MessageSender) MarkAsRead(ctx context.Context, messageID string) error {
 	url := fmt.Sprintf("%s/%s/messages", whatsappAPIBaseURL, s.phoneID)
@@ -251,8 +465,8 @@ func (s *MessageSender) MarkAsRead(ctx context.Context, messageID string) error
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+s.accessToken)
 
-	resp, err := s.client.Do(req)
-	if err != nil {
+	resp, err := s.do(req)
+	if resp == nil {
 		return fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
@@ -0,0 +1,82 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/bmachimbira/loyalty/api/internal/channels"
+	"github.com/bmachimbira/loyalty/api/internal/connectors"
+	"github.com/stretchr/testify/assert"
+)
+
+// openCircuit trips the sender's circuit breaker so sends fail fast
+// without reaching the WhatsApp API
+func openCircuit(s *MessageSender) {
+	for s.breaker.State() != connectors.StateOpen {
+		s.breaker.Execute(func() error {
+			return errors.New("unavailable")
+		})
+	}
+}
+
+func TestSend_CircuitOpenFailsFast(t *testing.T) {
+	s := NewMessageSender("phone", "token")
+	openCircuit(s)
+
+	err := s.SendText(context.Background(), "+263771234567", "hello")
+	assert.ErrorIs(t, err, connectors.ErrCircuitOpen)
+}
+
+func TestSend_UntaggedContextIsNotQueued(t *testing.T) {
+	s := NewMessageSender("phone", "token")
+	s.SetQueue(&OutboundQueue{})
+	openCircuit(s)
+
+	// Without a tenant the message cannot be queued, so the error is returned
+	err := s.SendText(context.Background(), "+263771234567", "hello")
+	assert.ErrorIs(t, err, connectors.ErrCircuitOpen)
+}
+
+func TestQueueable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"circuit open", connectors.ErrCircuitOpen, true},
+		{"server error", connectors.NewHTTPError(503, "unavailable"), true},
+		{"rate limited", connectors.NewHTTPError(429, "too many requests"), true},
+		{"invalid recipient", connectors.NewHTTPError(400, "invalid parameter"), false},
+		{"wrapped server error", fmt.Errorf("send: %w", connectors.NewHTTPError(500, "oops")), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, queueable(tt.err))
+		})
+	}
+}
+
+func TestMessageSender_IsChannel(t *testing.T) {
+	var ch channels.Channel = NewMessageSender("phone", "token")
+
+	assert.Equal(t, "whatsapp", ch.Name())
+	assert.True(t, ch.Capabilities().Interactive)
+	assert.Equal(t, maxListRows, ch.Capabilities().MaxMenuOptions)
+}
+
+func TestTemplateParameters_OrderedByPlaceholder(t *testing.T) {
+	params := map[string]string{}
+	for i := 1; i <= 12; i++ {
+		params[fmt.Sprint(i)] = fmt.Sprintf("value %d", i)
+	}
+
+	parameters := templateParameters(params)
+	assert.Len(t, parameters, 12)
+	for i, p := range parameters {
+		assert.Equal(t, fmt.Sprintf("value %d", i+1), p.Text)
+		assert.Equal(t, "text", p.Type)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/pii"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -61,7 +62,7 @@ func (sm *SessionManager) GetOrCreateSession(ctx context.Context, waID, phoneE16
 		},
 		CustomerID: pgtype.UUID{Valid: false}, // Will be set during enrollment
 		WaID:       waID,
-		PhoneE164:  phoneE164,
+		PhoneE164:  pii.Text{String: phoneE164, Valid: true},
 		State:      stateJSON,
 	})
 	if err != nil {
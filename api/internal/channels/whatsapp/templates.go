@@ -79,22 +79,31 @@ func FormatRewardRedeemedParams(rewardName, location string) map[string]string {
 }
 
 // Help messages (sent as regular text messages)
+// HelpMessage and WelcomeMessage name the tenant's programme, so are sent
+// through channels.Branding.WithFooter to fill in {program}, {emoji} and
+// {support}. AlreadyEnrolledMessage and ReferralMessage are short replies
+// sent through channels.Branding.Render.
 
 const (
-	HelpMessage = `*Zimbabwe Loyalty Program Help*
+	HelpMessage = `*{program} Help*
 
 Available commands:
-• /enroll - Join the loyalty program
+• /enroll - Join {program}
 • /balance - Check your points balance
 • /rewards - View available rewards
 • /myrewards - See your active rewards
+• /reward [number] - See a reward's details and terms
 • /redeem [code] - Redeem a reward
+• /promo [code] - Enter a promo code
+• /track - Track delivery of your physical rewards
 • /refer - Get your referral link
 • /help - Show this help message
 
-Simply send a command to get started!`
+You can also send a photo of your till slip to earn rewards on purchases.
 
-	WelcomeMessage = `Welcome to the Zimbabwe Loyalty Program! 🎉
+Simply send a command to get started! For anything else, please {support}.`
+
+	WelcomeMessage = `Welcome to {program}! {emoji}
 
 You've successfully enrolled. Start earning rewards with every purchase!
 
@@ -117,4 +126,55 @@ Send /rewards to see what's available.`
 Send /help to see available commands.`
 
 	ErrorMessage = `Sorry, something went wrong. Please try again later or contact support.`
+
+	AlreadyEnrolledMessage = `You're already enrolled in {program}! Send /help to see what you can do.`
+
+	ReferralMessage = `Referral program coming soon!
+
+Share {program} with friends and earn bonus rewards.`
+
+	ReceiptReceivedMessage = `Thanks! We've received your receipt. 🧾
+
+Our team will review it shortly and any rewards you earn will be sent to you here.`
+
+	ReceiptDuplicateMessage = `We've already received this receipt. Each receipt can only be submitted once.`
+
+	ReceiptUnsupportedMessage = `Sorry, we couldn't read that file. Please send a clear photo of your receipt.`
+
+	ReceiptsUnavailableMessage = `Sorry, receipt uploads aren't available right now.`
+
+	SurveyIntroMessage = `We'd love your feedback! Please answer a few quick questions.`
+
+	SurveyCompleteMessage = `Thank you for your feedback! 🙏`
+
+	SurveyClosedMessage = `This survey has already closed. Thank you!`
+
+	PromoUsageMessage = `Please provide a promo code.
+
+Usage: /promo [code]
+Example: /promo SUMMER25`
+
+	PromoAcceptedMessage = `✅ Promo code accepted!
+
+Any reward you've earned will be sent to you here. Use /myrewards to see your active rewards.`
+
+	PromoInvalidMessage = `Sorry, that promo code isn't valid. Please check it and try again.`
+
+	PromoNotActiveMessage = `Sorry, that promo code isn't active right now.`
+
+	PromoUsedUpMessage = `Sorry, that promo code has already been used.`
+
+	PromoAlreadyRedeemedMessage = `You've already used this promo.`
+
+	PromoTooManyAttemptsMessage = `Too many invalid promo codes. Please try again in 15 minutes.`
+
+	PromosUnavailableMessage = `Sorry, promo codes aren't available right now.`
+
+	FeatureUnavailableMessage = `Sorry, this feature isn't available right now.
+
+Send /help to see what you can do.`
+
+	NoDeliveriesMessage = `You don't have any rewards being delivered.
+
+Use /myrewards to see your active rewards.`
 )
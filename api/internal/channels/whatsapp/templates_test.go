@@ -0,0 +1,30 @@
+package whatsapp
+
+import (
+	"testing"
+
+	"github.com/bmachimbira/loyalty/api/internal/channels"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplatesNameProgram(t *testing.T) {
+	branding := channels.Branding{ProgramName: "OK Rewards"}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"help", HelpMessage, "• /enroll - Join OK Rewards"},
+		{"already enrolled", AlreadyEnrolledMessage, "You're already enrolled in OK Rewards!"},
+		{"referral", ReferralMessage, "Share OK Rewards with friends"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text := branding.Render(tt.template)
+			assert.Contains(t, text, tt.want)
+			assert.NotContains(t, text, "loyalty program")
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package whatsapp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/fulfilment"
+)
+
+// maxTrackedItems is how many of a customer's deliveries /track shows
+const maxTrackedItems = 5
+
+// trackingStatus is how each fulfilment status reads to a customer
+var trackingStatus = map[string]string{
+	fulfilment.StatusPending:    "📦 Being prepared",
+	fulfilment.StatusPicked:     "📦 Packed and ready to ship",
+	fulfilment.StatusDispatched: "🚚 On its way",
+	fulfilment.StatusDelivered:  "✅ Delivered",
+}
+
+// FormatTracking renders the status of a customer's physical reward deliveries
+func FormatTracking(items []db.ListCustomerFulfilmentsRow) string {
+	var msg strings.Builder
+	msg.WriteString("*Your Deliveries:*\n\n")
+
+	for i, item := range items {
+		f := item.Fulfilment
+		msg.WriteString(fmt.Sprintf("%d. *%s*\n", i+1, item.RewardName))
+		msg.WriteString(fmt.Sprintf("   Status: %s\n", trackingStatus[f.Status]))
+
+		switch {
+		case f.Status == fulfilment.StatusDelivered && f.DeliveredAt.Valid:
+			msg.WriteString(fmt.Sprintf("   Delivered: %s\n", f.DeliveredAt.Time.Format("2 Jan 2006")))
+		case f.Status != fulfilment.StatusDelivered:
+			msg.WriteString(fmt.Sprintf("   Expected by: %s\n", f.DueAt.Time.Format("2 Jan 2006")))
+		}
+		if f.Carrier.Valid {
+			msg.WriteString(fmt.Sprintf("   Carrier: %s\n", f.Carrier.String))
+		}
+		if f.TrackingRef.Valid {
+			msg.WriteString(fmt.Sprintf("   Tracking: %s\n", f.TrackingRef.String))
+		}
+		msg.WriteString("\n")
+	}
+
+	msg.WriteString("If something hasn't arrived by the expected date, please contact support.")
+	return msg.String()
+}
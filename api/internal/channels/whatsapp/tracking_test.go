@@ -0,0 +1,44 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatTracking(t *testing.T) {
+	due := time.Date(2025, 12, 8, 10, 0, 0, 0, time.UTC)
+	delivered := time.Date(2025, 12, 3, 15, 0, 0, 0, time.UTC)
+
+	msg := FormatTracking([]db.ListCustomerFulfilmentsRow{
+		{
+			RewardName: "Branded Mug",
+			Fulfilment: db.Fulfilment{
+				Status:      "dispatched",
+				Carrier:     pgtype.Text{String: "Zimpost", Valid: true},
+				TrackingRef: pgtype.Text{String: "ZP123", Valid: true},
+				DueAt:       pgtype.Timestamptz{Time: due, Valid: true},
+			},
+		},
+		{
+			RewardName: "T-Shirt",
+			Fulfilment: db.Fulfilment{
+				Status:      "delivered",
+				DueAt:       pgtype.Timestamptz{Time: due, Valid: true},
+				DeliveredAt: pgtype.Timestamptz{Time: delivered, Valid: true},
+			},
+		},
+	})
+
+	assert.Contains(t, msg, "1. *Branded Mug*")
+	assert.Contains(t, msg, "Status: 🚚 On its way")
+	assert.Contains(t, msg, "Expected by: 8 Dec 2025")
+	assert.Contains(t, msg, "Carrier: Zimpost")
+	assert.Contains(t, msg, "Tracking: ZP123")
+	assert.Contains(t, msg, "2. *T-Shirt*")
+	assert.Contains(t, msg, "Status: ✅ Delivered")
+	assert.Contains(t, msg, "Delivered: 3 Dec 2025")
+}
@@ -35,14 +35,15 @@ type Metadata struct {
 
 // Message represents an incoming WhatsApp message
 type Message struct {
-	From      string      `json:"from"`
-	ID        string      `json:"id"`
-	Timestamp string      `json:"timestamp"`
-	Type      string      `json:"type"`
-	Text      *TextMsg    `json:"text,omitempty"`
-	Button    *ButtonMsg  `json:"button,omitempty"`
-	Image     *MediaMsg   `json:"image,omitempty"`
-	Document  *MediaMsg   `json:"document,omitempty"`
+	From        string          `json:"from"`
+	ID          string          `json:"id"`
+	Timestamp   string          `json:"timestamp"`
+	Type        string          `json:"type"`
+	Text        *TextMsg        `json:"text,omitempty"`
+	Button      *ButtonMsg      `json:"button,omitempty"`
+	Interactive *InteractiveMsg `json:"interactive,omitempty"`
+	Image       *MediaMsg       `json:"image,omitempty"`
+	Document    *MediaMsg       `json:"document,omitempty"`
 }
 
 // TextMsg represents a text message
@@ -56,6 +57,13 @@ type ButtonMsg struct {
 	Text    string `json:"text"`
 }
 
+// InteractiveMsg represents a reply to an interactive list or button message
+type InteractiveMsg struct {
+	Type        string        `json:"type"`
+	ListReply   *RowPayload   `json:"list_reply,omitempty"`
+	ButtonReply *ReplyPayload `json:"button_reply,omitempty"`
+}
+
 // MediaMsg represents a media message
 type MediaMsg struct {
 	ID       string `json:"id"`
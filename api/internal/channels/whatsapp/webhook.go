@@ -1,6 +1,7 @@
 package whatsapp
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -9,8 +10,17 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/bmachimbira/loyalty/api/internal/catalogcache"
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/featureflag"
+	"github.com/bmachimbira/loyalty/api/internal/fulfilment"
+	"github.com/bmachimbira/loyalty/api/internal/metering"
+	"github.com/bmachimbira/loyalty/api/internal/promo"
+	"github.com/bmachimbira/loyalty/api/internal/receipt"
+	"github.com/bmachimbira/loyalty/api/internal/survey"
+	"github.com/bmachimbira/loyalty/api/internal/webhooks"
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -21,13 +31,16 @@ type Handler struct {
 	verifyToken string
 	appSecret   string
 	processor   *MessageProcessor
+	queue       *OutboundQueue
 }
 
 // NewHandler creates a new WhatsApp webhook handler
-func NewHandler(pool *pgxpool.Pool, verifyToken, appSecret, phoneNumberID, accessToken string) *Handler {
+func NewHandler(pool *pgxpool.Pool, catalog *catalogcache.Cache, verifyToken, appSecret, phoneNumberID, accessToken string) *Handler {
 	queries := db.New(pool)
 	sender := NewMessageSender(phoneNumberID, accessToken)
-	processor := NewMessageProcessor(pool, queries, sender)
+	queue := NewOutboundQueue(pool, sender, nil)
+	sender.SetQueue(queue)
+	processor := NewMessageProcessor(pool, queries, catalog, sender)
 
 	return &Handler{
 		pool:        pool,
@@ -35,9 +48,63 @@ func NewHandler(pool *pgxpool.Pool, verifyToken, appSecret, phoneNumberID, acces
 		verifyToken: verifyToken,
 		appSecret:   appSecret,
 		processor:   processor,
+		queue:       queue,
 	}
 }
 
+// SetMeter meters messages sent to customers for tenant billing
+func (h *Handler) SetMeter(meter *metering.Meter) {
+	h.processor.sender.SetMeter(meter)
+}
+
+// Sender returns the sender replies are sent with, for other features that
+// message customers over WhatsApp
+func (h *Handler) Sender() *MessageSender {
+	return h.processor.sender
+}
+
+// Queue returns the queue replies that failed to send are retried from
+func (h *Handler) Queue() *OutboundQueue {
+	return h.queue
+}
+
+// SetReceiptService enables receipt submission by sending a photo
+func (h *Handler) SetReceiptService(receipts *receipt.Service) {
+	h.processor.receipts = receipts
+}
+
+// SetWebhookService enables webhook notifications for redemptions
+func (h *Handler) SetWebhookService(webhooks *webhooks.DeliveryService) {
+	h.processor.rewards.SetWebhookService(webhooks)
+}
+
+// SetSurveyService enables survey replies and post-redemption surveys
+func (h *Handler) SetSurveyService(surveys *survey.Service) {
+	h.processor.surveys = surveys
+}
+
+// SetPromoService enables promo codes with /promo
+func (h *Handler) SetPromoService(promos *promo.Service) {
+	h.processor.promos = promos
+}
+
+// SetFulfilmentService enables delivery tracking with /track
+func (h *Handler) SetFulfilmentService(fulfilments *fulfilment.Service) {
+	h.processor.fulfilments = fulfilments
+}
+
+// SetFeatureFlags lets tenants turn features such as referrals and the
+// points balance off for WhatsApp
+func (h *Handler) SetFeatureFlags(features *featureflag.Service) {
+	h.processor.features = features
+}
+
+// DeliverSurveyQuestion sends a survey question to a customer and routes
+// their next replies to the survey
+func (h *Handler) DeliverSurveyQuestion(ctx context.Context, tenantID, customerID, responseID pgtype.UUID, prompt string) error {
+	return h.processor.deliverSurveyQuestion(ctx, tenantID, customerID, responseID, prompt)
+}
+
 // Verify handles GET request for webhook verification
 // This is called by WhatsApp to verify the webhook endpoint
 func (h *Handler) Verify(c *gin.Context) {
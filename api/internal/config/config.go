@@ -3,18 +3,44 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/bmachimbira/loyalty/api/internal/auth"
+	"github.com/bmachimbira/loyalty/api/internal/pii"
 	"github.com/joho/godotenv"
 )
 
 // Config holds all application configuration
 type Config struct {
 	DatabaseURL string
-	JWTSecret   string
-	HMACKeys    auth.HMACKeys
+	// ReadReplicaURL is an optional DSN for read-only reporting queries
+	ReadReplicaURL string
+	// JWTSecret keys sign-in code hashes and wallet pass authentication
+	// tokens, and signs JWTs unless JWTKeys are configured
+	JWTSecret string
+	// JWTKeys and HMACKeys are the rotating secrets, reloaded from the
+	// environment and SecretsFile on SIGHUP
+	JWTKeys  []auth.JWTKey
+	HMACKeys auth.HMACKeys
+	// PIIKeys are the key versions customers' phone numbers are encrypted
+	// with, reloaded with the other rotating secrets. Without them PII is
+	// stored unencrypted.
+	PIIKeys []pii.Key
+	// SecretsFile is an optional env file, e.g. a mounted secret, whose
+	// JWT_KEYS_JSON, HMAC_KEYS_JSON and PII_KEYS_JSON take precedence over
+	// the environment
+	SecretsFile string
 	Port        string
 
+	// PprofAddr is the address of a separate listener serving the
+	// net/http/pprof endpoints, e.g. localhost:6060; empty disables profiling.
+	// It has no authentication, so bind it to a private interface.
+	PprofAddr string
+	// SlowQueryThreshold is the latency at and above which database queries
+	// are logged; zero disables slow query logging
+	SlowQueryThreshold time.Duration
+
 	// WhatsApp Business API configuration
 	WhatsAppVerifyToken   string
 	WhatsAppAppSecret     string
@@ -32,8 +58,11 @@ func Load() (*Config, error) {
 
 	cfg := &Config{
 		DatabaseURL:           os.Getenv("DATABASE_URL"),
+		ReadReplicaURL:        os.Getenv("DATABASE_READ_URL"),
 		JWTSecret:             os.Getenv("JWT_SECRET"),
+		SecretsFile:           os.Getenv("SECRETS_FILE"),
 		Port:                  getEnvOrDefault("PORT", "8080"),
+		PprofAddr:             os.Getenv("PPROF_ADDR"),
 		WhatsAppVerifyToken:   os.Getenv("WHATSAPP_VERIFY_TOKEN"),
 		WhatsAppAppSecret:     os.Getenv("WHATSAPP_APP_SECRET"),
 		WhatsAppPhoneNumberID: os.Getenv("WHATSAPP_PHONE_NUMBER_ID"),
@@ -49,13 +78,28 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("JWT_SECRET environment variable is required")
 	}
 
-	// Load HMAC keys (optional)
-	hmacKeys, err := auth.LoadHMACKeys()
+	if ms := os.Getenv("SLOW_QUERY_THRESHOLD_MS"); ms != "" {
+		threshold, err := strconv.Atoi(ms)
+		if err != nil || threshold < 0 {
+			return nil, fmt.Errorf("SLOW_QUERY_THRESHOLD_MS must be a non-negative number of milliseconds")
+		}
+		cfg.SlowQueryThreshold = time.Duration(threshold) * time.Millisecond
+	}
+
+	// Load JWT key versions and HMAC keys (optional)
+	jwtKeys, hmacKeys, err := LoadSecrets(cfg.SecretsFile, cfg.JWTSecret)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load HMAC keys: %w", err)
+		return nil, err
 	}
+	cfg.JWTKeys = jwtKeys
 	cfg.HMACKeys = hmacKeys
 
+	piiKeys, err := LoadPIIKeys(cfg.SecretsFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg.PIIKeys = piiKeys
+
 	// WhatsApp configuration is optional (not all deployments will use it)
 	// Log warning if not configured
 	if cfg.WhatsAppPhoneNumberID == "" || cfg.WhatsAppAccessToken == "" {
@@ -65,6 +109,79 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// LoadSecrets reads the secrets that can be rotated without a restart: the
+// JWT key versions of JWT_KEYS_JSON, or jwtSecret without them, and the
+// HMAC keys of HMAC_KEYS_JSON. Values in secretsFile, when set, take
+// precedence over the environment, which is fixed for the process.
+func LoadSecrets(secretsFile, jwtSecret string) ([]auth.JWTKey, auth.HMACKeys, error) {
+	values, err := readSecrets(secretsFile, "JWT_KEYS_JSON", "HMAC_KEYS_JSON")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	jwtKeys, err := auth.ParseJWTKeys(values["JWT_KEYS_JSON"], jwtSecret)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load JWT keys: %w", err)
+	}
+	hmacKeys, err := auth.ParseHMACKeys(values["HMAC_KEYS_JSON"])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load HMAC keys: %w", err)
+	}
+	return jwtKeys, hmacKeys, nil
+}
+
+// LoadPIIKeys reads the PII key versions of PII_KEYS_JSON, from
+// secretsFile when set there, like LoadSecrets
+func LoadPIIKeys(secretsFile string) ([]pii.Key, error) {
+	values, err := readSecrets(secretsFile, "PII_KEYS_JSON")
+	if err != nil {
+		return nil, err
+	}
+	keys, err := pii.ParseKeys(values["PII_KEYS_JSON"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to load PII keys: %w", err)
+	}
+	return keys, nil
+}
+
+// readSecrets reads the named secrets from the environment, overridden by
+// secretsFile when set
+func readSecrets(secretsFile string, names ...string) (map[string]string, error) {
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		values[name] = os.Getenv(name)
+	}
+	if secretsFile != "" {
+		file, err := godotenv.Read(secretsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SECRETS_FILE: %w", err)
+		}
+		for name := range values {
+			if value, ok := file[name]; ok {
+				values[name] = value
+			}
+		}
+	}
+	return values, nil
+}
+
+// ReloadSecrets reloads the rotating secrets into keyring and the default
+// PII keyring. Neither changes if any secret is invalid.
+func (c *Config) ReloadSecrets(keyring *auth.Keyring) error {
+	jwtKeys, hmacKeys, err := LoadSecrets(c.SecretsFile, c.JWTSecret)
+	if err != nil {
+		return err
+	}
+	piiKeys, err := LoadPIIKeys(c.SecretsFile)
+	if err != nil {
+		return err
+	}
+	if err := keyring.Set(jwtKeys, hmacKeys); err != nil {
+		return err
+	}
+	return pii.Default().Set(piiKeys)
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
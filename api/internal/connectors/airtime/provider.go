@@ -18,6 +18,7 @@ import (
 // Provider implements the Connector interface for airtime/data providers
 type Provider struct {
 	client  *http.Client
+	breaker *connectors.CircuitBreaker
 	baseURL string
 	apiKey  string
 	secret  string
@@ -26,27 +27,29 @@ type Provider struct {
 
 // New creates a new airtime provider
 func New(baseURL, apiKey, secret string, timeout time.Duration) *Provider {
-	return &Provider{
-		client: &http.Client{
-			Timeout: timeout,
-		},
-		baseURL: baseURL,
-		apiKey:  apiKey,
-		secret:  secret,
-		name:    "airtime_provider",
-	}
+	return newProvider("airtime_provider", baseURL, apiKey, secret, timeout)
 }
 
 // NewDataProvider creates a new data provider (similar to airtime)
 func NewDataProvider(baseURL, apiKey, secret string, timeout time.Duration) *Provider {
+	return newProvider("data_provider", baseURL, apiKey, secret, timeout)
+}
+
+// newProvider creates a provider whose requests fail fast while it is down:
+// 5 consecutive failures open its circuit for 60 seconds
+func newProvider(name, baseURL, apiKey, secret string, timeout time.Duration) *Provider {
+	breaker := connectors.NewCircuitBreaker(5, 60*time.Second)
+	breaker.SetName(name)
+
 	return &Provider{
 		client: &http.Client{
 			Timeout: timeout,
 		},
+		breaker: breaker,
 		baseURL: baseURL,
 		apiKey:  apiKey,
 		secret:  secret,
-		name:    "data_provider",
+		name:    name,
 	}
 }
 
@@ -89,18 +92,8 @@ func (p *Provider) IssueVoucher(ctx context.Context, params connectors.IssuePara
 
 	err = connectors.RetryWithBackoff(ctx, retryConfig, func() error {
 		var reqErr error
-		resp, reqErr = p.client.Do(req)
-		if reqErr != nil {
-			return reqErr
-		}
-
-		// Check for retriable HTTP status codes
-		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
-			resp.Body.Close()
-			return connectors.NewHTTPError(resp.StatusCode, "server error or rate limit")
-		}
-
-		return nil
+		resp, reqErr = p.do(req)
+		return reqErr
 	})
 
 	if err != nil {
@@ -160,17 +153,8 @@ func (p *Provider) CheckStatus(ctx context.Context, transactionID string) (*conn
 
 	err = connectors.RetryWithBackoff(ctx, retryConfig, func() error {
 		var reqErr error
-		resp, reqErr = p.client.Do(req)
-		if reqErr != nil {
-			return reqErr
-		}
-
-		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
-			resp.Body.Close()
-			return connectors.NewHTTPError(resp.StatusCode, "server error or rate limit")
-		}
-
-		return nil
+		resp, reqErr = p.do(req)
+		return reqErr
 	})
 
 	if err != nil {
@@ -231,7 +215,7 @@ func (p *Provider) CancelVoucher(ctx context.Context, externalRef string) error
 	req.Header.Set("X-Signature", p.sign(body))
 
 	// Send request
-	resp, err := p.client.Do(req)
+	resp, err := p.do(req)
 	if err != nil {
 		return fmt.Errorf("cancel request failed: %w", err)
 	}
@@ -245,6 +229,31 @@ func (p *Provider) CancelVoucher(ctx context.Context, externalRef string) error
 	return nil
 }
 
+// do sends a request through the provider's circuit breaker. Server errors
+// and rate limiting are returned as HTTP errors so they count as failures.
+func (p *Provider) do(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	err := p.breaker.Execute(func() error {
+		var reqErr error
+		resp, reqErr = p.client.Do(req)
+		if reqErr != nil {
+			return reqErr
+		}
+
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			resp.Body.Close()
+			return connectors.NewHTTPError(resp.StatusCode, "server error or rate limit")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
 // sign generates an HMAC signature for the request
 func (p *Provider) sign(body []byte) string {
 	mac := hmac.New(sha256.New, []byte(p.secret))
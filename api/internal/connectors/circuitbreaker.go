@@ -4,6 +4,8 @@ import (
 	"errors"
 	"sync"
 	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/metrics"
 )
 
 // CircuitState represents the state of the circuit breaker
@@ -30,7 +32,9 @@ type CircuitBreaker struct {
 	timeout       time.Duration // Time before transitioning from open to half-open
 	state         CircuitState
 	lastFailTime  time.Time
-	halfOpenMax   int // Max successful calls in half-open before closing
+	halfOpenMax   int    // Max successful calls in half-open before closing
+	probing       bool   // A half-open probe call is in flight
+	name          string // Service name metrics are reported under
 }
 
 // NewCircuitBreaker creates a new circuit breaker
@@ -43,22 +47,50 @@ func NewCircuitBreaker(threshold int, timeout time.Duration) *CircuitBreaker {
 	}
 }
 
-// Execute wraps a function call with circuit breaker logic
+// SetName reports the breaker's state and rejected calls to metrics under
+// the given service name
+func (cb *CircuitBreaker) SetName(name string) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.name = name
+	cb.setState(cb.state)
+}
+
+// Execute wraps a function call with circuit breaker logic. While the
+// circuit is open calls are rejected with ErrCircuitOpen without running fn.
+// Once the timeout has passed the circuit is half-open and lets one probe
+// call through at a time; the rest are still rejected until enough probes
+// succeed to close it.
+//
+// Only errors IsRetriable reports count as failures: a rejected request
+// (4xx) or a cancelled caller says nothing about the service's health.
 func (cb *CircuitBreaker) Execute(fn func() error) error {
 	cb.mu.Lock()
 
 	// Check if circuit should transition from open to half-open
 	if cb.state == StateOpen {
 		if time.Since(cb.lastFailTime) > cb.timeout {
-			cb.state = StateHalfOpen
+			cb.setState(StateHalfOpen)
 			cb.failureCount = 0
 			cb.successCount = 0
 		} else {
+			cb.reject()
 			cb.mu.Unlock()
 			return ErrCircuitOpen
 		}
 	}
 
+	probe := cb.state == StateHalfOpen
+	if probe {
+		if cb.probing {
+			cb.reject()
+			cb.mu.Unlock()
+			return ErrCircuitOpen
+		}
+		cb.probing = true
+	}
+
 	cb.mu.Unlock()
 
 	// Execute the function
@@ -67,8 +99,14 @@ func (cb *CircuitBreaker) Execute(fn func() error) error {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
+	if probe {
+		cb.probing = false
+	}
+
 	if err != nil {
-		cb.recordFailure()
+		if IsRetriable(err) {
+			cb.recordFailure()
+		}
 		return err
 	}
 
@@ -76,19 +114,39 @@ func (cb *CircuitBreaker) Execute(fn func() error) error {
 	return nil
 }
 
+// reject records a call rejected without running
+func (cb *CircuitBreaker) reject() {
+	if cb.name != "" {
+		metrics.RecordCircuitBreakerRejected(cb.name)
+	}
+}
+
+// setState changes the circuit state and reports it to metrics
+func (cb *CircuitBreaker) setState(state CircuitState) {
+	cb.state = state
+	if cb.name == "" {
+		return
+	}
+
+	var value int64
+	switch state {
+	case StateOpen:
+		value = 1
+	case StateHalfOpen:
+		value = 2
+	}
+	metrics.RecordCircuitBreakerState(cb.name, value)
+}
+
 // recordFailure records a failed call and potentially opens the circuit
 func (cb *CircuitBreaker) recordFailure() {
 	cb.failureCount++
 	cb.lastFailTime = time.Now()
 	cb.successCount = 0
 
-	if cb.failureCount >= cb.threshold {
-		cb.state = StateOpen
-	}
-
 	// If in half-open state, one failure reopens the circuit
-	if cb.state == StateHalfOpen {
-		cb.state = StateOpen
+	if cb.failureCount >= cb.threshold || cb.state == StateHalfOpen {
+		cb.setState(StateOpen)
 	}
 }
 
@@ -99,7 +157,7 @@ func (cb *CircuitBreaker) recordSuccess() {
 	if cb.state == StateHalfOpen {
 		cb.successCount++
 		if cb.successCount >= cb.halfOpenMax {
-			cb.state = StateClosed
+			cb.setState(StateClosed)
 			cb.successCount = 0
 		}
 	}
@@ -110,9 +168,10 @@ func (cb *CircuitBreaker) Reset() {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
-	cb.state = StateClosed
+	cb.setState(StateClosed)
 	cb.failureCount = 0
 	cb.successCount = 0
+	cb.probing = false
 }
 
 // State returns the current state of the circuit breaker
@@ -224,3 +224,67 @@ func TestCircuitBreaker_ConcurrentCalls(t *testing.T) {
 		connectors.StateHalfOpen,
 	}, state)
 }
+
+func TestCircuitBreaker_HalfOpenAllowsOneProbe(t *testing.T) {
+	cb := connectors.NewCircuitBreaker(1, 50*time.Millisecond)
+
+	cb.Execute(func() error {
+		return errors.New("test error")
+	})
+	time.Sleep(100 * time.Millisecond)
+
+	// While the probe is in flight other calls are rejected
+	probing := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- cb.Execute(func() error {
+			close(probing)
+			<-release
+			return nil
+		})
+	}()
+	<-probing
+
+	callCount := 0
+	err := cb.Execute(func() error {
+		callCount++
+		return nil
+	})
+	assert.Equal(t, connectors.ErrCircuitOpen, err)
+	assert.Equal(t, 0, callCount)
+
+	close(release)
+	assert.NoError(t, <-done)
+	assert.Equal(t, connectors.StateHalfOpen, cb.State())
+
+	// The next call probes again
+	err = cb.Execute(func() error {
+		callCount++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, callCount)
+}
+
+func TestCircuitBreaker_ClientErrorsDoNotCount(t *testing.T) {
+	cb := connectors.NewCircuitBreaker(2, 1*time.Second)
+
+	for i := 0; i < 5; i++ {
+		err := cb.Execute(func() error {
+			return connectors.NewHTTPError(400, "bad request")
+		})
+		assert.Error(t, err)
+	}
+
+	assert.Equal(t, connectors.StateClosed, cb.State())
+	assert.Equal(t, 0, cb.FailureCount())
+
+	// Server errors do
+	for i := 0; i < 2; i++ {
+		cb.Execute(func() error {
+			return connectors.NewHTTPError(503, "unavailable")
+		})
+	}
+	assert.Equal(t, connectors.StateOpen, cb.State())
+}
@@ -33,6 +33,7 @@ func (r *Registry) Register(connector Connector) {
 	// Create circuit breaker for this connector
 	// 5 failures before opening, 60 second timeout
 	cb := NewCircuitBreaker(5, 60*time.Second)
+	cb.SetName(connector.Name())
 
 	r.connectors[connector.Name()] = &ConnectorWrapper{
 		Connector:      connector,
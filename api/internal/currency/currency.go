@@ -0,0 +1,252 @@
+// Package currency validates ISO 4217 currency codes and the currencies each
+// tenant may use. A tenant may create budgets and rewards in its default
+// currency and in its allowed currencies (tenants.allowed_currencies).
+package currency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	// ErrUnknownCurrency is returned for a code that is not an active ISO 4217
+	// currency
+	ErrUnknownCurrency = errors.New("currency must be an ISO 4217 currency code")
+
+	// ErrNotAllowed is returned for a currency the tenant has not enabled
+	ErrNotAllowed = errors.New("currency is not enabled for this tenant")
+)
+
+// Currency is an ISO 4217 currency
+type Currency struct {
+	Code string
+	Name string
+	// MinorUnits is the number of decimal places amounts are given in
+	MinorUnits int
+}
+
+// currencies are the active ISO 4217 currencies, by code. Funds and precious
+// metals are left out.
+var currencies = map[string]Currency{
+	"AED": {"AED", "UAE Dirham", 2},
+	"AFN": {"AFN", "Afghani", 2},
+	"ALL": {"ALL", "Lek", 2},
+	"AMD": {"AMD", "Armenian Dram", 2},
+	"AOA": {"AOA", "Kwanza", 2},
+	"ARS": {"ARS", "Argentine Peso", 2},
+	"AUD": {"AUD", "Australian Dollar", 2},
+	"AWG": {"AWG", "Aruban Florin", 2},
+	"AZN": {"AZN", "Azerbaijan Manat", 2},
+	"BAM": {"BAM", "Convertible Mark", 2},
+	"BBD": {"BBD", "Barbados Dollar", 2},
+	"BDT": {"BDT", "Taka", 2},
+	"BGN": {"BGN", "Bulgarian Lev", 2},
+	"BHD": {"BHD", "Bahraini Dinar", 3},
+	"BIF": {"BIF", "Burundi Franc", 0},
+	"BMD": {"BMD", "Bermudian Dollar", 2},
+	"BND": {"BND", "Brunei Dollar", 2},
+	"BOB": {"BOB", "Boliviano", 2},
+	"BRL": {"BRL", "Brazilian Real", 2},
+	"BSD": {"BSD", "Bahamian Dollar", 2},
+	"BTN": {"BTN", "Ngultrum", 2},
+	"BWP": {"BWP", "Pula", 2},
+	"BYN": {"BYN", "Belarusian Ruble", 2},
+	"BZD": {"BZD", "Belize Dollar", 2},
+	"CAD": {"CAD", "Canadian Dollar", 2},
+	"CDF": {"CDF", "Congolese Franc", 2},
+	"CHF": {"CHF", "Swiss Franc", 2},
+	"CLP": {"CLP", "Chilean Peso", 0},
+	"CNY": {"CNY", "Yuan Renminbi", 2},
+	"COP": {"COP", "Colombian Peso", 2},
+	"CRC": {"CRC", "Costa Rican Colon", 2},
+	"CUP": {"CUP", "Cuban Peso", 2},
+	"CVE": {"CVE", "Cabo Verde Escudo", 2},
+	"CZK": {"CZK", "Czech Koruna", 2},
+	"DJF": {"DJF", "Djibouti Franc", 0},
+	"DKK": {"DKK", "Danish Krone", 2},
+	"DOP": {"DOP", "Dominican Peso", 2},
+	"DZD": {"DZD", "Algerian Dinar", 2},
+	"EGP": {"EGP", "Egyptian Pound", 2},
+	"ERN": {"ERN", "Nakfa", 2},
+	"ETB": {"ETB", "Ethiopian Birr", 2},
+	"EUR": {"EUR", "Euro", 2},
+	"FJD": {"FJD", "Fiji Dollar", 2},
+	"FKP": {"FKP", "Falkland Islands Pound", 2},
+	"GBP": {"GBP", "Pound Sterling", 2},
+	"GEL": {"GEL", "Lari", 2},
+	"GHS": {"GHS", "Ghana Cedi", 2},
+	"GIP": {"GIP", "Gibraltar Pound", 2},
+	"GMD": {"GMD", "Dalasi", 2},
+	"GNF": {"GNF", "Guinean Franc", 0},
+	"GTQ": {"GTQ", "Quetzal", 2},
+	"GYD": {"GYD", "Guyana Dollar", 2},
+	"HKD": {"HKD", "Hong Kong Dollar", 2},
+	"HNL": {"HNL", "Lempira", 2},
+	"HTG": {"HTG", "Gourde", 2},
+	"HUF": {"HUF", "Forint", 2},
+	"IDR": {"IDR", "Rupiah", 2},
+	"ILS": {"ILS", "New Israeli Sheqel", 2},
+	"INR": {"INR", "Indian Rupee", 2},
+	"IQD": {"IQD", "Iraqi Dinar", 3},
+	"IRR": {"IRR", "Iranian Rial", 2},
+	"ISK": {"ISK", "Iceland Krona", 0},
+	"JMD": {"JMD", "Jamaican Dollar", 2},
+	"JOD": {"JOD", "Jordanian Dinar", 3},
+	"JPY": {"JPY", "Yen", 0},
+	"KES": {"KES", "Kenyan Shilling", 2},
+	"KGS": {"KGS", "Som", 2},
+	"KHR": {"KHR", "Riel", 2},
+	"KMF": {"KMF", "Comorian Franc", 0},
+	"KPW": {"KPW", "North Korean Won", 2},
+	"KRW": {"KRW", "Won", 0},
+	"KWD": {"KWD", "Kuwaiti Dinar", 3},
+	"KYD": {"KYD", "Cayman Islands Dollar", 2},
+	"KZT": {"KZT", "Tenge", 2},
+	"LAK": {"LAK", "Lao Kip", 2},
+	"LBP": {"LBP", "Lebanese Pound", 2},
+	"LKR": {"LKR", "Sri Lanka Rupee", 2},
+	"LRD": {"LRD", "Liberian Dollar", 2},
+	"LSL": {"LSL", "Loti", 2},
+	"LYD": {"LYD", "Libyan Dinar", 3},
+	"MAD": {"MAD", "Moroccan Dirham", 2},
+	"MDL": {"MDL", "Moldovan Leu", 2},
+	"MGA": {"MGA", "Malagasy Ariary", 2},
+	"MKD": {"MKD", "Denar", 2},
+	"MMK": {"MMK", "Kyat", 2},
+	"MNT": {"MNT", "Tugrik", 2},
+	"MOP": {"MOP", "Pataca", 2},
+	"MRU": {"MRU", "Ouguiya", 2},
+	"MUR": {"MUR", "Mauritius Rupee", 2},
+	"MVR": {"MVR", "Rufiyaa", 2},
+	"MWK": {"MWK", "Malawi Kwacha", 2},
+	"MXN": {"MXN", "Mexican Peso", 2},
+	"MYR": {"MYR", "Malaysian Ringgit", 2},
+	"MZN": {"MZN", "Mozambique Metical", 2},
+	"NAD": {"NAD", "Namibia Dollar", 2},
+	"NGN": {"NGN", "Naira", 2},
+	"NIO": {"NIO", "Cordoba Oro", 2},
+	"NOK": {"NOK", "Norwegian Krone", 2},
+	"NPR": {"NPR", "Nepalese Rupee", 2},
+	"NZD": {"NZD", "New Zealand Dollar", 2},
+	"OMR": {"OMR", "Rial Omani", 3},
+	"PAB": {"PAB", "Balboa", 2},
+	"PEN": {"PEN", "Sol", 2},
+	"PGK": {"PGK", "Kina", 2},
+	"PHP": {"PHP", "Philippine Peso", 2},
+	"PKR": {"PKR", "Pakistan Rupee", 2},
+	"PLN": {"PLN", "Zloty", 2},
+	"PYG": {"PYG", "Guarani", 0},
+	"QAR": {"QAR", "Qatari Rial", 2},
+	"RON": {"RON", "Romanian Leu", 2},
+	"RSD": {"RSD", "Serbian Dinar", 2},
+	"RUB": {"RUB", "Russian Ruble", 2},
+	"RWF": {"RWF", "Rwanda Franc", 0},
+	"SAR": {"SAR", "Saudi Riyal", 2},
+	"SBD": {"SBD", "Solomon Islands Dollar", 2},
+	"SCR": {"SCR", "Seychelles Rupee", 2},
+	"SDG": {"SDG", "Sudanese Pound", 2},
+	"SEK": {"SEK", "Swedish Krona", 2},
+	"SGD": {"SGD", "Singapore Dollar", 2},
+	"SHP": {"SHP", "Saint Helena Pound", 2},
+	"SLE": {"SLE", "Leone", 2},
+	"SOS": {"SOS", "Somali Shilling", 2},
+	"SRD": {"SRD", "Surinam Dollar", 2},
+	"SSP": {"SSP", "South Sudanese Pound", 2},
+	"STN": {"STN", "Dobra", 2},
+	"SVC": {"SVC", "El Salvador Colon", 2},
+	"SYP": {"SYP", "Syrian Pound", 2},
+	"SZL": {"SZL", "Lilangeni", 2},
+	"THB": {"THB", "Baht", 2},
+	"TJS": {"TJS", "Somoni", 2},
+	"TMT": {"TMT", "Turkmenistan New Manat", 2},
+	"TND": {"TND", "Tunisian Dinar", 3},
+	"TOP": {"TOP", "Pa'anga", 2},
+	"TRY": {"TRY", "Turkish Lira", 2},
+	"TTD": {"TTD", "Trinidad and Tobago Dollar", 2},
+	"TWD": {"TWD", "New Taiwan Dollar", 2},
+	"TZS": {"TZS", "Tanzanian Shilling", 2},
+	"UAH": {"UAH", "Hryvnia", 2},
+	"UGX": {"UGX", "Uganda Shilling", 0},
+	"USD": {"USD", "US Dollar", 2},
+	"UYU": {"UYU", "Peso Uruguayo", 2},
+	"UZS": {"UZS", "Uzbekistan Sum", 2},
+	"VES": {"VES", "Bolivar Soberano", 2},
+	"VND": {"VND", "Dong", 0},
+	"VUV": {"VUV", "Vatu", 0},
+	"WST": {"WST", "Tala", 2},
+	"XAF": {"XAF", "CFA Franc BEAC", 0},
+	"XCD": {"XCD", "East Caribbean Dollar", 2},
+	"XOF": {"XOF", "CFA Franc BCEAO", 0},
+	"XPF": {"XPF", "CFP Franc", 0},
+	"YER": {"YER", "Yemeni Rial", 2},
+	"ZAR": {"ZAR", "Rand", 2},
+	"ZMW": {"ZMW", "Zambian Kwacha", 2},
+	"ZWG": {"ZWG", "Zimbabwe Gold", 2},
+}
+
+// Lookup returns the ISO 4217 currency with the code
+func Lookup(code string) (Currency, bool) {
+	c, ok := currencies[code]
+	return c, ok
+}
+
+// Valid reports whether code is an active ISO 4217 currency code
+func Valid(code string) bool {
+	_, ok := currencies[code]
+	return ok
+}
+
+// Codes returns all known currency codes, sorted
+func Codes() []string {
+	codes := make([]string, 0, len(currencies))
+	for code := range currencies {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Allowed returns the currencies a tenant may use: its default currency
+// followed by its other allowed currencies
+func Allowed(defaultCurrency string, allowed []string) []string {
+	codes := []string{defaultCurrency}
+	for _, code := range allowed {
+		if !slices.Contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// Checker checks currencies against each tenant's allowed currencies
+type Checker struct {
+	queries *db.Queries
+}
+
+// NewChecker creates a new currency checker
+func NewChecker(queries *db.Queries) *Checker {
+	return &Checker{queries: queries}
+}
+
+// Check returns ErrUnknownCurrency if code is not an ISO 4217 currency and
+// ErrNotAllowed if the tenant may not use it
+func (c *Checker) Check(ctx context.Context, tenantID pgtype.UUID, code string) error {
+	if !Valid(code) {
+		return ErrUnknownCurrency
+	}
+
+	tenant, err := c.queries.GetTenantCurrencies(ctx, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to get tenant currencies: %w", err)
+	}
+	if !slices.Contains(Allowed(tenant.DefaultCcy, tenant.AllowedCurrencies), code) {
+		return fmt.Errorf("%w: %s", ErrNotAllowed, code)
+	}
+	return nil
+}
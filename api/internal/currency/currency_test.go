@@ -0,0 +1,30 @@
+package currency
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValid(t *testing.T) {
+	for _, code := range []string{"USD", "ZWG", "ZAR", "BWP", "KES"} {
+		assert.True(t, Valid(code), code)
+	}
+	for _, code := range []string{"", "usd", "ZWL", "XAU", "ABC", "USDT"} {
+		assert.False(t, Valid(code), code)
+	}
+}
+
+func TestTableIsConsistent(t *testing.T) {
+	for code, c := range currencies {
+		assert.Equal(t, code, c.Code)
+		assert.NotEmpty(t, c.Name, code)
+		assert.Contains(t, []int{0, 2, 3}, c.MinorUnits, code)
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	assert.Equal(t, []string{"ZAR"}, Allowed("ZAR", nil))
+	assert.Equal(t, []string{"USD", "ZWG"}, Allowed("USD", []string{"ZWG", "USD"}))
+	assert.Equal(t, []string{"KES", "USD", "UGX"}, Allowed("KES", []string{"USD", "UGX", "USD"}))
+}
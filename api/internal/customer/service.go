@@ -2,14 +2,46 @@ package customer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/pii"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+var (
+	// ErrSearchQueryTooShort is returned for search queries too short to
+	// match on meaningfully
+	ErrSearchQueryTooShort = errors.New("search query must be at least 3 characters")
+
+	// ErrInvalidActivityKind is returned when filtering the activity feed
+	// by an unknown kind of activity
+	ErrInvalidActivityKind = errors.New("activity kind must be one of event, issuance, redemption, message, consent")
+
+	// ErrIdentifierTaken is returned when upserting a customer with an
+	// external reference that belongs to another customer
+	ErrIdentifierTaken = errors.New("external_ref belongs to another customer")
+)
+
+// Kinds of customer activity
+const (
+	ActivityEvent      = "event"
+	ActivityIssuance   = "issuance"
+	ActivityRedemption = "redemption"
+	ActivityMessage    = "message"
+	ActivityConsent    = "consent"
+)
+
+// minSearchQuery is the shortest search query; trigram matching needs three
+// characters
+const minSearchQuery = 3
+
 // Service handles customer-related business logic
 type Service struct {
 	queries *db.Queries
@@ -22,11 +54,81 @@ func NewService(queries *db.Queries) *Service {
 	}
 }
 
-// CreateCustomer creates a new customer
+// CreateCustomer creates a new customer. Their phone number is indexed
+// under the current PII key, and a customer with the same number indexed
+// under an older key is moved onto it first so the number stays unique.
 func (s *Service) CreateCustomer(ctx context.Context, params db.CreateCustomerParams) (db.Customer, error) {
+	if params.PhoneE164.Valid {
+		if err := s.reindexPhone(ctx, params.TenantID, params.PhoneE164.String); err != nil {
+			return db.Customer{}, err
+		}
+		params.PhoneBidx = pii.Default().BlindIndex(params.PhoneE164.String)
+	}
 	return s.queries.CreateCustomer(ctx, params)
 }
 
+// reindexPhone moves a customer whose phone number is indexed under an
+// older PII key, or isn't encrypted yet, onto the current key, so creating
+// or upserting by the current blind index finds them
+func (s *Service) reindexPhone(ctx context.Context, tenantID pgtype.UUID, phoneE164 string) error {
+	previous := pii.Default().BlindIndexes(phoneE164)[1:]
+	if len(previous) == 0 {
+		return nil
+	}
+	if err := s.queries.ReindexCustomerPhone(ctx, db.ReindexCustomerPhoneParams{
+		PhoneE164:     pii.Text{String: phoneE164, Valid: true},
+		PhoneBidx:     pii.Default().BlindIndex(phoneE164),
+		TenantID:      tenantID,
+		PreviousBidxs: previous,
+	}); err != nil {
+		return fmt.Errorf("failed to reindex customer phone: %w", err)
+	}
+	return nil
+}
+
+// UpsertCustomer creates the customer with the phone number, or with the
+// external reference when there is no phone number, or updates the existing
+// one's details. It is atomic, so concurrent enrollments of the same
+// customer cannot create duplicates. It reports whether the customer was
+// created.
+func (s *Service) UpsertCustomer(ctx context.Context, tenantID pgtype.UUID, phoneE164, externalRef, name string) (db.Customer, bool, error) {
+	var (
+		customer db.Customer
+		created  bool
+		err      error
+	)
+	if phoneE164 != "" {
+		if err := s.reindexPhone(ctx, tenantID, phoneE164); err != nil {
+			return db.Customer{}, false, err
+		}
+		var row db.UpsertCustomerByPhoneRow
+		row, err = s.queries.UpsertCustomerByPhone(ctx, db.UpsertCustomerByPhoneParams{
+			TenantID:    tenantID,
+			PhoneE164:   pii.Text{String: phoneE164, Valid: true},
+			PhoneBidx:   pii.Default().BlindIndex(phoneE164),
+			ExternalRef: pgtype.Text{String: externalRef, Valid: externalRef != ""},
+			Name:        pgtype.Text{String: name, Valid: name != ""},
+		})
+		customer, created = row.Customer, row.Created
+	} else {
+		var row db.UpsertCustomerByExternalRefRow
+		row, err = s.queries.UpsertCustomerByExternalRef(ctx, db.UpsertCustomerByExternalRefParams{
+			TenantID:    tenantID,
+			ExternalRef: pgtype.Text{String: externalRef, Valid: true},
+			Name:        pgtype.Text{String: name, Valid: name != ""},
+		})
+		customer, created = row.Customer, row.Created
+	}
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return db.Customer{}, false, ErrIdentifierTaken
+		}
+		return db.Customer{}, false, fmt.Errorf("failed to upsert customer: %w", err)
+	}
+	return customer, created, nil
+}
+
 // GetCustomerByID retrieves a customer by ID
 func (s *Service) GetCustomerByID(ctx context.Context, id, tenantID pgtype.UUID) (db.Customer, error) {
 	return s.queries.GetCustomerByID(ctx, db.GetCustomerByIDParams{
@@ -35,11 +137,27 @@ func (s *Service) GetCustomerByID(ctx context.Context, id, tenantID pgtype.UUID)
 	})
 }
 
+// WelcomeSeries returns the customer's welcome series. ok is false when they
+// were enrolled while their tenant had the series off.
+func (s *Service) WelcomeSeries(ctx context.Context, customer db.Customer) (series db.WelcomeSeries, ok bool, err error) {
+	series, err = s.queries.GetCustomerWelcomeSeries(ctx, db.GetCustomerWelcomeSeriesParams{
+		TenantID:   customer.TenantID,
+		CustomerID: customer.ID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.WelcomeSeries{}, false, nil
+	}
+	if err != nil {
+		return db.WelcomeSeries{}, false, fmt.Errorf("failed to get welcome series: %w", err)
+	}
+	return series, true, nil
+}
+
 // GetCustomerByPhone retrieves a customer by phone number
 func (s *Service) GetCustomerByPhone(ctx context.Context, tenantID pgtype.UUID, phoneE164 string) (db.Customer, error) {
 	return s.queries.GetCustomerByPhone(ctx, db.GetCustomerByPhoneParams{
-		TenantID:  tenantID,
-		PhoneE164: pgtype.Text{String: phoneE164, Valid: true},
+		TenantID:   tenantID,
+		PhoneBidxs: pii.Default().BlindIndexes(phoneE164),
 	})
 }
 
@@ -79,6 +197,111 @@ func (s *Service) ListCustomers(ctx context.Context, tenantID pgtype.UUID, limit
 	return customers, len(customers), nil
 }
 
+// SearchCustomers finds customers whose phone number, external reference or
+// name partially or approximately matches the query, best matches first.
+// Phone numbers match on their digits, so "077 123 4567" finds +263771234567.
+func (s *Service) SearchCustomers(ctx context.Context, tenantID pgtype.UUID, query string, limit int) ([]db.SearchCustomersRow, error) {
+	query = strings.TrimSpace(query)
+	if len([]rune(query)) < minSearchQuery {
+		return nil, ErrSearchQueryTooShort
+	}
+	if limit < 1 || limit > 50 {
+		limit = 20
+	}
+
+	params := db.SearchCustomersParams{
+		TenantID: tenantID,
+		Query:    query,
+		Pattern:  "%" + escapeLike(query) + "%",
+		RowLimit: int32(limit),
+	}
+	if digits := phoneDigits(query); len(digits) >= minSearchQuery {
+		params.PhoneBidxs = pii.Default().BlindIndexes("+" + digits)
+		params.PhonePattern = pgtype.Text{String: "%" + digits + "%", Valid: true}
+	}
+
+	rows, err := s.queries.SearchCustomers(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search customers: %w", err)
+	}
+	return rows, nil
+}
+
+// phoneDigits returns the digits of a query that looks like a phone number,
+// without the trunk prefix 0 of local numbers, or "" for other queries
+func phoneDigits(query string) string {
+	var digits strings.Builder
+	for _, r := range query {
+		switch {
+		case unicode.IsDigit(r):
+			digits.WriteRune(r)
+		case r == '+' || r == ' ' || r == '-' || r == '(' || r == ')':
+		default:
+			return ""
+		}
+	}
+	return strings.TrimLeft(digits.String(), "0")
+}
+
+// escapeLike escapes the LIKE wildcards in s
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Activity returns a page of the customer's activity, newest first: events,
+// issuance status changes, redemptions, queued WhatsApp messages and consent
+// changes, and the total amount of activity. kinds limits the feed to those
+// kinds of activity; empty means all.
+func (s *Service) Activity(ctx context.Context, customer db.Customer, kinds []string, limit, offset string) ([]db.ListCustomerActivityRow, int64, error) {
+	for _, kind := range kinds {
+		switch kind {
+		case ActivityEvent, ActivityIssuance, ActivityRedemption, ActivityMessage, ActivityConsent:
+		default:
+			return nil, 0, ErrInvalidActivityKind
+		}
+	}
+
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil || limitInt < 1 {
+		limitInt = 50
+	}
+	if limitInt > 100 {
+		limitInt = 100
+	}
+
+	offsetInt, err := strconv.Atoi(offset)
+	if err != nil || offsetInt < 0 {
+		offsetInt = 0
+	}
+
+	params := db.ListCustomerActivityParams{
+		TenantID:   customer.TenantID,
+		CustomerID: customer.ID,
+		Phone:      customer.PhoneE164.String,
+		RowLimit:   int32(limitInt),
+		RowOffset:  int32(offsetInt),
+	}
+	if len(kinds) > 0 {
+		params.Kinds = kinds
+	}
+
+	activity, err := s.queries.ListCustomerActivity(ctx, params)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list customer activity: %w", err)
+	}
+
+	total, err := s.queries.CountCustomerActivity(ctx, db.CountCustomerActivityParams{
+		TenantID:   params.TenantID,
+		CustomerID: params.CustomerID,
+		Phone:      params.Phone,
+		Kinds:      params.Kinds,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count customer activity: %w", err)
+	}
+	return activity, total, nil
+}
+
 // UpdateCustomerStatus updates the status of a customer
 func (s *Service) UpdateCustomerStatus(ctx context.Context, id, tenantID pgtype.UUID, status string) error {
 	err := s.queries.UpdateCustomerStatus(ctx, db.UpdateCustomerStatusParams{
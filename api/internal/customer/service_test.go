@@ -0,0 +1,132 @@
+package customer
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneDigits(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"+263771234567", "263771234567"},
+		{"077 123 4567", "771234567"},
+		{"(077) 123-4567", "771234567"},
+		{"4567", "4567"},
+		{"CUST-4567", ""},
+		{"Tendai", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, phoneDigits(tt.query), tt.query)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, `50\% off\_now \\ ok`, escapeLike(`50% off_now \ ok`))
+}
+
+func TestActivityRejectsUnknownKind(t *testing.T) {
+	s := NewService(nil)
+	_, _, err := s.Activity(context.Background(), db.Customer{}, []string{ActivityEvent, "login"}, "50", "0")
+	assert.ErrorIs(t, err, ErrInvalidActivityKind)
+}
+
+func TestUpsertCustomer(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set, skipping integration tests")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	require.NoError(t, err)
+	defer pool.Close()
+	queries := db.New(pool)
+	s := NewService(queries)
+
+	tenant, err := queries.CreateTenant(ctx, db.CreateTenantParams{
+		Name:        "Customer Upsert",
+		CountryCode: "ZW",
+		DefaultCcy:  "USD",
+		Theme:       []byte(`{}`),
+	})
+	require.NoError(t, err)
+
+	// Each step runs against the customers left by the ones before it.
+	// sameAs is the index of the step whose customer is expected back, or -1
+	// for a new one.
+	steps := []struct {
+		name        string
+		phone       string
+		externalRef string
+		fullName    string
+		wantCreated bool
+		sameAs      int
+		wantRef     string
+		wantName    string
+		wantErr     error
+	}{
+		{"new by phone", "+263771000001", "", "Tendai", true, -1, "", "Tendai", nil},
+		{"same phone adds reference", "+263771000001", "POS-1", "", false, 0, "POS-1", "Tendai", nil},
+		{"new by reference", "", "POS-2", "", true, -1, "POS-2", "", nil},
+		{"same reference renames", "", "POS-2", "Rudo", false, 2, "POS-2", "Rudo", nil},
+		{"new phone with taken reference", "+263771000002", "POS-2", "", false, -1, "", "", ErrIdentifierTaken},
+	}
+
+	customers := make([]db.Customer, len(steps))
+	for i, tt := range steps {
+		t.Run(tt.name, func(t *testing.T) {
+			customer, created, err := s.UpsertCustomer(ctx, tenant.ID, tt.phone, tt.externalRef, tt.fullName)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			customers[i] = customer
+
+			assert.Equal(t, tt.wantCreated, created)
+			if tt.sameAs >= 0 {
+				assert.Equal(t, customers[tt.sameAs].ID, customer.ID)
+			}
+			assert.Equal(t, tt.wantRef, customer.ExternalRef.String)
+			assert.Equal(t, tt.wantName, customer.Name.String)
+		})
+	}
+
+	t.Run("concurrent enrollments", func(t *testing.T) {
+		const attempts = 8
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			created int
+			ids     = make(map[[16]byte]bool)
+		)
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				customer, isNew, err := s.UpsertCustomer(ctx, tenant.ID, "+263771000003", "", "")
+				assert.NoError(t, err)
+				mu.Lock()
+				defer mu.Unlock()
+				ids[customer.ID.Bytes] = true
+				if isNew {
+					created++
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 1, created)
+		assert.Len(t, ids, 1)
+	})
+}
@@ -0,0 +1,113 @@
+// Package dbtrace logs slow database queries.
+//
+// A SlowQueryTracer is set as the pgx tracer of a connection pool. Queries
+// taking longer than its threshold are logged with the request-scoped logger
+// of the query's context, so the log line carries the request ID and route
+// of the request that ran it. Query arguments are never logged as they may
+// hold customer data.
+package dbtrace
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/logging"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxLoggedSQL bounds the length of the SQL logged for a slow query
+const maxLoggedSQL = 1000
+
+// queryNamePattern matches the name sqlc puts in a comment before its queries
+var queryNamePattern = regexp.MustCompile(`^--\s*name:\s*(\w+)`)
+
+// traceKey is the context key for the start of a traced query
+type traceKey struct{}
+
+// queryTrace is what a query's start leaves for its end
+type queryTrace struct {
+	sql   string
+	start time.Time
+}
+
+// SlowQueryTracer logs queries that take at least its threshold
+type SlowQueryTracer struct {
+	threshold time.Duration
+	logger    *slog.Logger
+	now       func() time.Time
+}
+
+// NewSlowQueryTracer creates a tracer logging queries that take at least
+// threshold. logger is used for queries run outside a request.
+func NewSlowQueryTracer(threshold time.Duration, logger *slog.Logger) *SlowQueryTracer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlowQueryTracer{
+		threshold: threshold,
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+// TraceQueryStart implements pgx.QueryTracer
+func (t *SlowQueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, traceKey{}, queryTrace{sql: data.SQL, start: t.now()})
+}
+
+// TraceQueryEnd implements pgx.QueryTracer
+func (t *SlowQueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	trace, ok := ctx.Value(traceKey{}).(queryTrace)
+	if !ok {
+		return
+	}
+
+	elapsed := t.now().Sub(trace.start)
+	if elapsed < t.threshold {
+		return
+	}
+
+	attrs := []any{
+		"duration_ms", elapsed.Milliseconds(),
+		"threshold_ms", t.threshold.Milliseconds(),
+		"rows", data.CommandTag.RowsAffected(),
+		"sql", compactSQL(trace.sql),
+	}
+	if name := queryName(trace.sql); name != "" {
+		attrs = append(attrs, "query", name)
+	}
+	if data.Err != nil {
+		attrs = append(attrs, "error", data.Err)
+	}
+	logging.FromContext(ctx, t.logger).Warn("slow query", attrs...)
+}
+
+// queryName returns the sqlc name of a query, or "" for queries written by
+// hand
+func queryName(sql string) string {
+	if m := queryNamePattern.FindStringSubmatch(strings.TrimSpace(sql)); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// compactSQL drops the sqlc name comment of a query, collapses its
+// whitespace onto one line and truncates it to maxLoggedSQL
+func compactSQL(sql string) string {
+	sql = strings.TrimSpace(sql)
+	if queryNamePattern.MatchString(sql) {
+		if i := strings.IndexByte(sql, '\n'); i >= 0 {
+			sql = sql[i+1:]
+		} else {
+			sql = ""
+		}
+	}
+	compact := strings.Join(strings.Fields(sql), " ")
+	if len(compact) > maxLoggedSQL {
+		compact = compact[:maxLoggedSQL] + "..."
+	}
+	return compact
+}
@@ -0,0 +1,71 @@
+package dbtrace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/logging"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const namedQuery = `-- name: GetTenantByID :one
+SELECT * FROM tenants
+WHERE id = $1`
+
+// tracerWithClock returns a tracer whose clock advances by step on each read
+func tracerWithClock(threshold, step time.Duration, buf *bytes.Buffer) *SlowQueryTracer {
+	tracer := NewSlowQueryTracer(threshold, logging.NewWithWriter(buf, slog.LevelDebug).Logger)
+	now := time.Date(2025, 12, 25, 9, 0, 0, 0, time.UTC)
+	tracer.now = func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+	return tracer
+}
+
+func TestSlowQueryTracerLogsSlowQueries(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := tracerWithClock(100*time.Millisecond, 150*time.Millisecond, &buf)
+
+	ctx := context.WithValue(context.Background(), logging.RequestIDKey, "req-1")
+	ctx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: namedQuery, Args: []any{"secret"}})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "slow query", entry["msg"])
+	assert.Equal(t, "GetTenantByID", entry["query"])
+	assert.Equal(t, "SELECT * FROM tenants WHERE id = $1", entry["sql"])
+	assert.Equal(t, float64(150), entry["duration_ms"])
+	assert.Equal(t, "req-1", entry["request_id"])
+	assert.NotContains(t, buf.String(), "secret")
+}
+
+func TestSlowQueryTracerIgnoresFastQueries(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := tracerWithClock(100*time.Millisecond, 10*time.Millisecond, &buf)
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: namedQuery})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	assert.Empty(t, buf.String())
+}
+
+func TestQueryName(t *testing.T) {
+	assert.Equal(t, "GetTenantByID", queryName(namedQuery))
+	assert.Empty(t, queryName("SELECT 1"))
+}
+
+func TestCompactSQL(t *testing.T) {
+	assert.Equal(t, "SELECT * FROM tenants WHERE id = $1", compactSQL(namedQuery))
+	assert.Equal(t, "SELECT 1", compactSQL("  SELECT\n\t1 "))
+
+	long := compactSQL("SELECT " + string(bytes.Repeat([]byte("x"), 2*maxLoggedSQL)))
+	assert.Len(t, long, maxLoggedSQL+len("..."))
+}
@@ -0,0 +1,185 @@
+package deadletter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Dead letter statuses
+const (
+	StatusPending  = "pending"
+	StatusRetrying = "retrying"
+	StatusResolved = "resolved"
+)
+
+var (
+	// ErrNotFound is returned when the dead letter does not exist
+	ErrNotFound = errors.New("dead letter not found")
+
+	// ErrNotRetryable is returned when the dead letter is already resolved or being retried
+	ErrNotRetryable = errors.New("dead letter is not pending")
+
+	// ErrInvalidStatus is returned when filtering by an unknown status
+	ErrInvalidStatus = errors.New("invalid dead letter status")
+)
+
+// EventProcessor runs an event through the rules engine
+type EventProcessor interface {
+	ProcessEvent(ctx context.Context, event db.Event) ([]db.Issuance, error)
+}
+
+// Service persists and retries events whose rule processing failed
+type Service struct {
+	queries   *db.Queries
+	processor EventProcessor
+}
+
+// NewService creates a new dead letter service
+func NewService(queries *db.Queries, processor EventProcessor) *Service {
+	return &Service{
+		queries:   queries,
+		processor: processor,
+	}
+}
+
+// Record stores a processing failure for an event. Recording the same event
+// again increments its attempt count and returns it to pending.
+func (s *Service) Record(ctx context.Context, event db.Event, procErr error) (db.DeadLetter, error) {
+	deadLetter, err := s.queries.RecordDeadLetter(ctx, db.RecordDeadLetterParams{
+		TenantID: event.TenantID,
+		EventID:  event.ID,
+		Error:    procErr.Error(),
+	})
+	if err != nil {
+		return db.DeadLetter{}, fmt.Errorf("failed to record dead letter: %w", err)
+	}
+	return deadLetter, nil
+}
+
+// Get retrieves a dead letter by ID
+func (s *Service) Get(ctx context.Context, tenantID, id pgtype.UUID) (db.DeadLetter, error) {
+	deadLetter, err := s.queries.GetDeadLetterByID(ctx, db.GetDeadLetterByIDParams{
+		ID:       id,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.DeadLetter{}, ErrNotFound
+		}
+		return db.DeadLetter{}, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+	return deadLetter, nil
+}
+
+// List retrieves a paginated list of dead letters, optionally filtered by
+// status, and the total number of dead letters matching the filter
+func (s *Service) List(ctx context.Context, tenantID pgtype.UUID, status, limit, offset string) ([]db.DeadLetter, int64, error) {
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil || limitInt < 1 {
+		limitInt = 50
+	}
+	if limitInt > 100 {
+		limitInt = 100
+	}
+
+	offsetInt, err := strconv.Atoi(offset)
+	if err != nil || offsetInt < 0 {
+		offsetInt = 0
+	}
+
+	var statusFilter pgtype.Text
+	if status != "" {
+		switch status {
+		case StatusPending, StatusRetrying, StatusResolved:
+		default:
+			return nil, 0, ErrInvalidStatus
+		}
+		statusFilter = pgtype.Text{String: status, Valid: true}
+	}
+
+	deadLetters, err := s.queries.ListDeadLetters(ctx, db.ListDeadLettersParams{
+		TenantID: tenantID,
+		Status:   statusFilter,
+		Limit:    int32(limitInt),
+		Offset:   int32(offsetInt),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	total, err := s.queries.CountDeadLetters(ctx, db.CountDeadLettersParams{
+		TenantID: tenantID,
+		Status:   statusFilter,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count dead letters: %w", err)
+	}
+	return deadLetters, total, nil
+}
+
+// Retry re-runs rule processing for a pending dead letter. The dead letter is
+// claimed first so concurrent retries cannot process the same event twice, and
+// the rules engine skips rewards already issued for the event.
+func (s *Service) Retry(ctx context.Context, tenantID, id pgtype.UUID) (db.DeadLetter, []db.Issuance, error) {
+	deadLetter, err := s.queries.ClaimDeadLetterForRetry(ctx, db.ClaimDeadLetterForRetryParams{
+		ID:       id,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Distinguish missing from not-pending
+			if _, getErr := s.Get(ctx, tenantID, id); getErr != nil {
+				return db.DeadLetter{}, nil, getErr
+			}
+			return db.DeadLetter{}, nil, ErrNotRetryable
+		}
+		return db.DeadLetter{}, nil, fmt.Errorf("failed to claim dead letter: %w", err)
+	}
+
+	event, err := s.queries.GetEventByID(ctx, db.GetEventByIDParams{
+		ID:       deadLetter.EventID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		s.release(ctx, deadLetter, err)
+		return db.DeadLetter{}, nil, fmt.Errorf("failed to get event: %w", err)
+	}
+
+	issuances, procErr := s.processor.ProcessEvent(ctx, event)
+	if procErr != nil {
+		s.release(ctx, deadLetter, procErr)
+		deadLetter, err = s.Get(ctx, tenantID, id)
+		if err != nil {
+			return db.DeadLetter{}, nil, err
+		}
+		return deadLetter, nil, nil
+	}
+
+	if err := s.queries.ResolveDeadLetter(ctx, db.ResolveDeadLetterParams{
+		ID:       id,
+		TenantID: tenantID,
+	}); err != nil {
+		return db.DeadLetter{}, nil, fmt.Errorf("failed to resolve dead letter: %w", err)
+	}
+
+	deadLetter, err = s.Get(ctx, tenantID, id)
+	if err != nil {
+		return db.DeadLetter{}, nil, err
+	}
+	return deadLetter, issuances, nil
+}
+
+// release returns a claimed dead letter to pending with the latest error
+func (s *Service) release(ctx context.Context, deadLetter db.DeadLetter, cause error) {
+	_ = s.queries.FailDeadLetterRetry(ctx, db.FailDeadLetterRetryParams{
+		ID:       deadLetter.ID,
+		TenantID: deadLetter.TenantID,
+		Error:    cause.Error(),
+	})
+}
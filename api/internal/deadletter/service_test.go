@@ -0,0 +1,187 @@
+package deadletter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+)
+
+// stubProcessor fails with err, or returns issuances when err is nil
+type stubProcessor struct {
+	issuances []db.Issuance
+	err       error
+}
+
+func (p stubProcessor) ProcessEvent(ctx context.Context, event db.Event) ([]db.Issuance, error) {
+	return p.issuances, p.err
+}
+
+func TestListInvalidStatus(t *testing.T) {
+	service := NewService(nil, nil)
+
+	_, _, err := service.List(context.Background(), pgtype.UUID{}, "failed", "", "")
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+}
+
+func setupTestDB(t *testing.T) (*db.Queries, db.Tenant) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set, skipping integration tests")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	queries := db.New(pool)
+
+	tenant, err := queries.CreateTenant(ctx, db.CreateTenantParams{
+		Name:        "Dead Letters " + t.Name(),
+		CountryCode: "ZW",
+		DefaultCcy:  "USD",
+		Theme:       []byte(`{}`),
+	})
+	require.NoError(t, err)
+	return queries, tenant
+}
+
+// createFailedEvent records an event as failed to process
+func createFailedEvent(t *testing.T, service *Service, queries *db.Queries, tenantID pgtype.UUID) db.DeadLetter {
+	ctx := context.Background()
+
+	customer, err := queries.CreateCustomer(ctx, db.CreateCustomerParams{
+		TenantID:    tenantID,
+		ExternalRef: pgtype.Text{String: uuid.NewString(), Valid: true},
+	})
+	require.NoError(t, err)
+
+	event, err := queries.InsertEvent(ctx, db.InsertEventParams{
+		TenantID:       tenantID,
+		CustomerID:     customer.ID,
+		EventType:      "purchase",
+		Properties:     []byte(`{"amount": 10}`),
+		OccurredAt:     pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Source:         "api",
+		IdempotencyKey: uuid.NewString(),
+	})
+	require.NoError(t, err)
+
+	deadLetter, err := service.Record(ctx, event, errors.New("budget service unavailable"))
+	require.NoError(t, err)
+	return deadLetter
+}
+
+func TestRecordAgain(t *testing.T) {
+	queries, tenant := setupTestDB(t)
+	service := NewService(queries, nil)
+	ctx := context.Background()
+
+	first := createFailedEvent(t, service, queries, tenant.ID)
+	assert.Equal(t, StatusPending, first.Status)
+	assert.Equal(t, int32(1), first.Attempts)
+
+	event, err := queries.GetEventByID(ctx, db.GetEventByIDParams{ID: first.EventID, TenantID: tenant.ID})
+	require.NoError(t, err)
+	again, err := service.Record(ctx, event, errors.New("rules engine timeout"))
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, again.ID)
+	assert.Equal(t, int32(2), again.Attempts)
+	assert.Equal(t, "rules engine timeout", again.Error)
+}
+
+func TestRetry(t *testing.T) {
+	queries, tenant := setupTestDB(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		processErr   error
+		resolveFirst bool
+		unknown      bool
+		wantErr      error
+		wantStatus   string
+		wantAttempts int32
+		wantIssued   int
+	}{
+		{"processed", nil, false, false, nil, StatusResolved, 1, 1},
+		{"fails again", errors.New("budget exhausted"), false, false, nil, StatusPending, 2, 0},
+		{"already resolved", nil, true, false, ErrNotRetryable, "", 0, 0},
+		{"unknown", nil, false, true, ErrNotFound, "", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := stubProcessor{issuances: []db.Issuance{{}}, err: tt.processErr}
+			service := NewService(queries, processor)
+
+			deadLetter := createFailedEvent(t, service, queries, tenant.ID)
+			id := deadLetter.ID
+			if tt.unknown {
+				id = pgtype.UUID{Bytes: uuid.New(), Valid: true}
+			}
+			if tt.resolveFirst {
+				_, _, err := NewService(queries, stubProcessor{}).Retry(ctx, tenant.ID, id)
+				require.NoError(t, err)
+			}
+
+			retried, issuances, err := service.Retry(ctx, tenant.ID, id)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, retried.Status)
+			assert.Equal(t, tt.wantAttempts, retried.Attempts)
+			assert.Len(t, issuances, tt.wantIssued)
+			if tt.processErr != nil {
+				assert.Equal(t, tt.processErr.Error(), retried.Error)
+			}
+		})
+	}
+}
+
+func TestListTotal(t *testing.T) {
+	queries, tenant := setupTestDB(t)
+	service := NewService(queries, stubProcessor{})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		createFailedEvent(t, service, queries, tenant.ID)
+	}
+	resolved := createFailedEvent(t, service, queries, tenant.ID)
+	_, _, err := service.Retry(ctx, tenant.ID, resolved.ID)
+	require.NoError(t, err)
+
+	tests := []struct {
+		status    string
+		limit     string
+		wantRows  int
+		wantTotal int64
+	}{
+		{"", "", 4, 4},
+		{"", "2", 2, 4},
+		{StatusPending, "", 3, 3},
+		{StatusResolved, "", 1, 1},
+		{StatusRetrying, "", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("status %q limit %q", tt.status, tt.limit), func(t *testing.T) {
+			deadLetters, total, err := service.List(ctx, tenant.ID, tt.status, tt.limit, "")
+			require.NoError(t, err)
+			assert.Len(t, deadLetters, tt.wantRows)
+			assert.Equal(t, tt.wantTotal, total)
+		})
+	}
+}
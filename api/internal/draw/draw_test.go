@@ -0,0 +1,121 @@
+package draw
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func id(b byte) pgtype.UUID {
+	return pgtype.UUID{Bytes: [16]byte{b}, Valid: true}
+}
+
+func TestSelectWinnersDeterministic(t *testing.T) {
+	entries := []Entry{
+		{CustomerID: id(1), EventID: id(11), Tickets: 1},
+		{CustomerID: id(2), EventID: id(12), Tickets: 3},
+		{CustomerID: id(3), EventID: id(13), Tickets: 1},
+		{CustomerID: id(1), EventID: id(14), Tickets: 2},
+		{CustomerID: id(4), EventID: id(15), Tickets: 1},
+	}
+
+	first := SelectWinners("audit-seed", entries, 3)
+	require.Len(t, first, 3)
+	assert.Equal(t, first, SelectWinners("audit-seed", entries, 3))
+
+	// A customer wins at most once
+	seen := map[pgtype.UUID]bool{}
+	for _, winner := range first {
+		assert.False(t, seen[winner], "duplicate winner")
+		seen[winner] = true
+	}
+}
+
+func TestSelectWinnersFewerEntrantsThanPrizes(t *testing.T) {
+	entries := []Entry{
+		{CustomerID: id(1), EventID: id(11), Tickets: 1},
+		{CustomerID: id(1), EventID: id(12), Tickets: 1},
+		{CustomerID: id(2), EventID: id(13), Tickets: 1},
+	}
+
+	winners := SelectWinners("seed", entries, 5)
+	assert.ElementsMatch(t, []pgtype.UUID{id(1), id(2)}, winners)
+
+	assert.Empty(t, SelectWinners("seed", nil, 3))
+}
+
+func TestSelectWinnersWeightedByTickets(t *testing.T) {
+	entries := []Entry{
+		{CustomerID: id(1), EventID: id(11), Tickets: 9},
+		{CustomerID: id(2), EventID: id(12), Tickets: 1},
+	}
+
+	wins := 0
+	for i := 0; i < 1000; i++ {
+		if SelectWinners(time.Duration(i).String(), entries, 1)[0] == id(1) {
+			wins++
+		}
+	}
+	assert.InDelta(t, 900, wins, 60)
+}
+
+func TestEntriesHash(t *testing.T) {
+	entries := []Entry{
+		{CustomerID: id(1), EventID: id(11), Tickets: 1},
+		{CustomerID: id(2), EventID: id(12), Tickets: 2},
+	}
+
+	hash := EntriesHash(entries)
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, EntriesHash(entries))
+
+	// Tickets and order are part of the hash
+	changed := []Entry{entries[0], {CustomerID: id(2), EventID: id(12), Tickets: 3}}
+	assert.NotEqual(t, hash, EntriesHash(changed))
+	assert.NotEqual(t, hash, EntriesHash([]Entry{entries[1], entries[0]}))
+}
+
+func TestNewSeed(t *testing.T) {
+	a, err := NewSeed()
+	require.NoError(t, err)
+	b, err := NewSeed()
+	require.NoError(t, err)
+	assert.Len(t, a, 64)
+	assert.NotEqual(t, a, b)
+}
+
+func TestParamsValidate(t *testing.T) {
+	valid := Params{
+		TenantID:        id(1),
+		CampaignID:      id(2),
+		Name:            "December draw",
+		EventType:       "purchase",
+		EntriesPerEvent: 1,
+		Winners:         3,
+	}
+	require.NoError(t, valid.Validate())
+
+	tests := []struct {
+		name   string
+		modify func(p *Params)
+	}{
+		{"missing campaign", func(p *Params) { p.CampaignID = pgtype.UUID{} }},
+		{"missing name", func(p *Params) { p.Name = " " }},
+		{"missing event type", func(p *Params) { p.EventType = "" }},
+		{"draw_won event type", func(p *Params) { p.EventType = EventTypeDrawWon }},
+		{"criteria not an object", func(p *Params) { p.Criteria = json.RawMessage(`"x"`) }},
+		{"zero entries per event", func(p *Params) { p.EntriesPerEvent = 0 }},
+		{"zero winners", func(p *Params) { p.Winners = 0 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := valid
+			tt.modify(&p)
+			assert.Error(t, p.Validate())
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package draw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// RunDue runs every tenant's draws that are due as of now and returns the
+// number run. A failing draw is logged and left open for the next run.
+func (s *Service) RunDue(ctx context.Context, now time.Time) (int, error) {
+	tenants, err := s.queries.ListTenants(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	run := 0
+	for _, tenant := range tenants {
+		due, err := s.dueDraws(ctx, tenant.ID, now)
+		if err != nil {
+			s.logger.Error("failed to list due draws",
+				"tenant_id", tenant.ID,
+				"error", err)
+			continue
+		}
+
+		for _, draw := range due {
+			result, err := s.Draw(ctx, tenant.ID, draw.ID, "", now)
+			if err != nil {
+				if !errors.Is(err, ErrDrawNotOpen) {
+					s.logger.Error("failed to run draw",
+						"draw_id", draw.ID,
+						"error", err)
+				}
+				continue
+			}
+			s.logger.Info("draw run",
+				"draw_id", draw.ID,
+				"winners", len(result.Winners),
+				"issuances", len(result.Issuances))
+			run++
+		}
+	}
+	return run, nil
+}
+
+// dueDraws lists a tenant's open draws scheduled at or before now
+func (s *Service) dueDraws(ctx context.Context, tenantID pgtype.UUID, now time.Time) ([]db.Draw, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenantID.Bytes)); err != nil {
+		return nil, fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	draws, err := s.queries.WithTx(tx).ListDueDraws(ctx, db.ListDueDrawsParams{
+		TenantID: tenantID,
+		Now:      pgtype.Timestamptz{Time: now, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return draws, tx.Commit(ctx)
+}
+
+// RunScheduler runs due draws on a schedule until ctx is cancelled
+func (s *Service) RunScheduler(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunDue(ctx, time.Now()); err != nil {
+			s.logger.Error("scheduled draws failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
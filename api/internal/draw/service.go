@@ -0,0 +1,437 @@
+package draw
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/deadletter"
+	"github.com/bmachimbira/loyalty/api/internal/event"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/rules"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrDrawNotFound is returned when the draw does not exist
+	ErrDrawNotFound = errors.New("draw not found")
+
+	// ErrCampaignNotFound is returned when the draw's campaign does not exist
+	ErrCampaignNotFound = errors.New("campaign not found")
+
+	// ErrUnknownEventType is returned for a draw on an event type that is
+	// neither built in nor registered by the tenant
+	ErrUnknownEventType = errors.New("unknown event type")
+
+	// ErrDrawNotOpen is returned when drawing or cancelling a draw that has
+	// already been drawn or cancelled
+	ErrDrawNotOpen = errors.New("draw is not open")
+)
+
+// Result is the outcome of running a draw
+type Result struct {
+	Draw db.Draw
+	// Winners are the winning customers in order of position
+	Winners []pgtype.UUID
+	// Issuances are prizes granted by rules for the draw_won events
+	Issuances []db.Issuance
+}
+
+// Winner is a row of a draw's winners report
+type Winner struct {
+	Position    int32
+	CustomerID  pgtype.UUID
+	EventID     pgtype.UUID
+	PhoneE164   string
+	ExternalRef string
+	Prizes      []Prize
+}
+
+// Prize is a reward issued to a winner
+type Prize struct {
+	IssuanceID pgtype.UUID
+	Status     string
+	RewardName string
+	FaceAmount pgtype.Numeric
+	Currency   string
+}
+
+// Service manages prize draws
+type Service struct {
+	pool        *pgxpool.Pool
+	queries     *db.Queries
+	events      *event.Service
+	evaluator   *rules.Evaluator
+	processor   deadletter.EventProcessor
+	deadLetters *deadletter.Service
+	logger      *slog.Logger
+}
+
+// NewService creates a new draw service. draw_won events are run through
+// processor so rules can grant prizes.
+func NewService(pool *pgxpool.Pool, queries *db.Queries, processor deadletter.EventProcessor, logger *slog.Logger) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Service{
+		pool:        pool,
+		queries:     queries,
+		events:      event.NewService(queries),
+		evaluator:   rules.NewEvaluator(rules.NewCustomOperators(pool)),
+		processor:   processor,
+		deadLetters: deadletter.NewService(queries, processor),
+		logger:      logger,
+	}
+}
+
+// Create creates a new open draw
+func (s *Service) Create(ctx context.Context, params Params) (db.Draw, error) {
+	if err := params.Validate(); err != nil {
+		return db.Draw{}, err
+	}
+	if _, err := s.queries.GetCampaignByID(ctx, db.GetCampaignByIDParams{
+		ID:       params.CampaignID,
+		TenantID: params.TenantID,
+	}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Draw{}, ErrCampaignNotFound
+		}
+		return db.Draw{}, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	if err := s.events.ValidateEventType(ctx, params.TenantID, params.EventType); err != nil {
+		if errors.Is(err, event.ErrUnknownEventType) {
+			return db.Draw{}, ErrUnknownEventType
+		}
+		return db.Draw{}, err
+	}
+
+	var drawAt pgtype.Timestamptz
+	if params.DrawAt != nil {
+		drawAt = pgtype.Timestamptz{Time: *params.DrawAt, Valid: true}
+	}
+
+	draw, err := s.queries.CreateDraw(ctx, db.CreateDrawParams{
+		TenantID:        params.TenantID,
+		CampaignID:      params.CampaignID,
+		Name:            strings.TrimSpace(params.Name),
+		EventType:       params.EventType,
+		Criteria:        params.Criteria,
+		EntriesPerEvent: params.EntriesPerEvent,
+		Winners:         params.Winners,
+		DrawAt:          drawAt,
+	})
+	if err != nil {
+		return db.Draw{}, fmt.Errorf("failed to create draw: %w", err)
+	}
+	return draw, nil
+}
+
+// Get retrieves a draw by ID
+func (s *Service) Get(ctx context.Context, tenantID, drawID pgtype.UUID) (db.Draw, error) {
+	draw, err := s.queries.GetDrawByID(ctx, db.GetDrawByIDParams{
+		ID:       drawID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Draw{}, ErrDrawNotFound
+		}
+		return db.Draw{}, fmt.Errorf("failed to get draw: %w", err)
+	}
+	return draw, nil
+}
+
+// List lists the tenant's draws, optionally for one campaign
+func (s *Service) List(ctx context.Context, tenantID, campaignID pgtype.UUID) ([]db.Draw, error) {
+	draws, err := s.queries.ListDraws(ctx, db.ListDrawsParams{
+		TenantID:   tenantID,
+		CampaignID: campaignID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list draws: %w", err)
+	}
+	return draws, nil
+}
+
+// Cancel cancels an open draw; its entries are kept
+func (s *Service) Cancel(ctx context.Context, tenantID, drawID pgtype.UUID) (db.Draw, error) {
+	draw, err := s.queries.CancelDraw(ctx, db.CancelDrawParams{
+		ID:       drawID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			if _, err := s.Get(ctx, tenantID, drawID); err != nil {
+				return db.Draw{}, err
+			}
+			return db.Draw{}, ErrDrawNotOpen
+		}
+		return db.Draw{}, fmt.Errorf("failed to cancel draw: %w", err)
+	}
+	return draw, nil
+}
+
+// Enter enters an event's customer into the open draws it qualifies for.
+// Each event enters a draw once, however often it is processed. It is set on
+// the rules engine with SetDrawEntrant.
+func (s *Service) Enter(ctx context.Context, evt db.Event) error {
+	if !evt.CustomerID.Valid || evt.EventType == EventTypeDrawWon {
+		return nil
+	}
+
+	draws, err := s.queries.ListOpenDrawsForEvent(ctx, db.ListOpenDrawsForEventParams{
+		TenantID:   evt.TenantID,
+		EventType:  evt.EventType,
+		OccurredAt: evt.OccurredAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list draws: %w", err)
+	}
+	if len(draws) == 0 {
+		return nil
+	}
+
+	data, err := rules.EventData(evt)
+	if err != nil {
+		return err
+	}
+
+	for _, draw := range draws {
+		if len(draw.Criteria) > 0 {
+			matched, err := s.evaluator.Evaluate(ctx, draw.Criteria, data)
+			if err != nil {
+				s.logger.Warn("draw criteria evaluation error",
+					"draw_id", httputil.FormatUUID(draw.ID.Bytes),
+					"error", err,
+				)
+				continue
+			}
+			if !matched {
+				continue
+			}
+		}
+
+		if err := s.enter(ctx, draw, evt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enter records an event's entry into one draw unless the draw has closed
+func (s *Service) enter(ctx context.Context, draw db.Draw, evt db.Event) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	// The lock keeps the draw from being run until the entry is committed
+	if _, err := qtx.LockOpenDraw(ctx, db.LockOpenDrawParams{
+		ID:       draw.ID,
+		TenantID: draw.TenantID,
+	}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to lock draw: %w", err)
+	}
+
+	if _, err := qtx.InsertDrawEntry(ctx, db.InsertDrawEntryParams{
+		TenantID:   draw.TenantID,
+		DrawID:     draw.ID,
+		CustomerID: evt.CustomerID,
+		EventID:    evt.ID,
+		Tickets:    draw.EntriesPerEvent,
+	}); err != nil {
+		return fmt.Errorf("failed to enter draw: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Draw runs an open draw: it picks the winners with seed, or a new random
+// seed when empty, emits their draw_won events and runs them through the
+// rules engine. A prize that fails to issue is parked as a dead letter.
+func (s *Service) Draw(ctx context.Context, tenantID, drawID pgtype.UUID, seed string, now time.Time) (*Result, error) {
+	if seed == "" {
+		var err error
+		if seed, err = NewSeed(); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Scheduled draws run outside a tenant request
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenantID.Bytes)); err != nil {
+		return nil, fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	qtx := s.queries.WithTx(tx)
+
+	draw, err := qtx.GetDrawForUpdate(ctx, db.GetDrawForUpdateParams{
+		ID:       drawID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDrawNotFound
+		}
+		return nil, fmt.Errorf("failed to get draw: %w", err)
+	}
+	if draw.Status != StatusOpen {
+		return nil, ErrDrawNotOpen
+	}
+
+	rows, err := qtx.ListDrawEntries(ctx, db.ListDrawEntriesParams{
+		TenantID: tenantID,
+		DrawID:   drawID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list draw entries: %w", err)
+	}
+	entries := make([]Entry, len(rows))
+	for i, row := range rows {
+		entries[i] = Entry{CustomerID: row.CustomerID, EventID: row.EventID, Tickets: row.Tickets}
+	}
+
+	winners := SelectWinners(seed, entries, int(draw.Winners))
+
+	won := make([]db.Event, len(winners))
+	for i, customerID := range winners {
+		position := int32(i + 1)
+		won[i], err = insertWonEvent(ctx, qtx, draw, customerID, position, len(winners), now)
+		if err != nil {
+			return nil, err
+		}
+		if err := qtx.InsertDrawWinner(ctx, db.InsertDrawWinnerParams{
+			DrawID:     draw.ID,
+			TenantID:   tenantID,
+			Position:   position,
+			CustomerID: customerID,
+			EventID:    won[i].ID,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to record draw winner: %w", err)
+		}
+	}
+
+	draw, err = qtx.CompleteDraw(ctx, db.CompleteDrawParams{
+		ID:          drawID,
+		TenantID:    tenantID,
+		Seed:        pgtype.Text{String: seed, Valid: true},
+		EntriesHash: pgtype.Text{String: EntriesHash(entries), Valid: true},
+		EntryCount:  pgtype.Int4{Int32: int32(len(entries)), Valid: true},
+		DrawnAt:     pgtype.Timestamptz{Time: now, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete draw: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result := &Result{Draw: draw, Winners: winners}
+	for _, evt := range won {
+		issuances, err := s.processor.ProcessEvent(ctx, evt)
+		if err != nil {
+			// The winner is recorded; park the event so the prize can be
+			// retried
+			s.logger.Error("rules engine processing failed",
+				"event_id", evt.ID,
+				"draw_id", draw.ID,
+				"error", err,
+			)
+			if _, dlqErr := s.deadLetters.Record(ctx, evt, err); dlqErr != nil {
+				s.logger.Error("failed to record dead letter", "event_id", evt.ID, "error", dlqErr)
+			}
+			continue
+		}
+		result.Issuances = append(result.Issuances, issuances...)
+	}
+	return result, nil
+}
+
+// Winners returns the winners report of a draw: each winner with the prizes
+// issued for their draw_won event
+func (s *Service) Winners(ctx context.Context, tenantID, drawID pgtype.UUID) ([]Winner, error) {
+	rows, err := s.queries.ListDrawWinners(ctx, db.ListDrawWinnersParams{
+		TenantID: tenantID,
+		DrawID:   drawID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list draw winners: %w", err)
+	}
+
+	var winners []Winner
+	for _, row := range rows {
+		if len(winners) == 0 || winners[len(winners)-1].Position != row.Position {
+			winners = append(winners, Winner{
+				Position:    row.Position,
+				CustomerID:  row.CustomerID,
+				EventID:     row.EventID,
+				PhoneE164:   row.PhoneE164.String,
+				ExternalRef: row.ExternalRef.String,
+				Prizes:      []Prize{},
+			})
+		}
+		if row.IssuanceID.Valid {
+			winner := &winners[len(winners)-1]
+			winner.Prizes = append(winner.Prizes, Prize{
+				IssuanceID: row.IssuanceID,
+				Status:     row.IssuanceStatus.String,
+				RewardName: row.RewardName.String,
+				FaceAmount: row.FaceAmount,
+				Currency:   row.Currency.String,
+			})
+		}
+	}
+	return winners, nil
+}
+
+// insertWonEvent records the draw_won event for a winner. Rules can target
+// a position, e.g. the first prize.
+func insertWonEvent(ctx context.Context, qtx *db.Queries, draw db.Draw, customerID pgtype.UUID, position int32, winners int, now time.Time) (db.Event, error) {
+	properties, err := json.Marshal(map[string]interface{}{
+		"draw_id":     httputil.FormatUUID(draw.ID.Bytes),
+		"draw_name":   draw.Name,
+		"campaign_id": httputil.FormatUUID(draw.CampaignID.Bytes),
+		"position":    position,
+		"winners":     winners,
+	})
+	if err != nil {
+		return db.Event{}, fmt.Errorf("failed to marshal event properties: %w", err)
+	}
+
+	won, err := qtx.InsertEvent(ctx, db.InsertEventParams{
+		TenantID:       draw.TenantID,
+		CustomerID:     customerID,
+		EventType:      EventTypeDrawWon,
+		Properties:     properties,
+		OccurredAt:     pgtype.Timestamptz{Time: now, Valid: true},
+		Source:         "draw",
+		IdempotencyKey: "draw:" + httputil.FormatUUID(draw.ID.Bytes) + ":" + strconv.Itoa(int(position)),
+	})
+	if err != nil {
+		return db.Event{}, fmt.Errorf("failed to create event: %w", err)
+	}
+	return won, nil
+}
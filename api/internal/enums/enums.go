@@ -0,0 +1,112 @@
+// Package enums holds the values of text columns that act as enums and are
+// shared across services, handlers and channels: budget ledger entry types
+// and issuance statuses. Code compares against these typed constants rather
+// than string literals, so a misspelt or retired value fails to compile.
+//
+// Database columns are plain strings, so convert at the boundary:
+//
+//	if enums.LedgerEntryType(entry.EntryType) == enums.LedgerCharge { ... }
+//	params.EntryType = string(enums.LedgerRelease)
+package enums
+
+// LedgerEntryType is the entry_type of a budget ledger entry
+type LedgerEntryType string
+
+const (
+	LedgerFund      LedgerEntryType = "fund"      // Budget topped up
+	LedgerReserve   LedgerEntryType = "reserve"   // Amount held for an issuance
+	LedgerRelease   LedgerEntryType = "release"   // Held amount returned, negative
+	LedgerCharge    LedgerEntryType = "charge"    // Held amount spent by an issuance
+	LedgerExpire    LedgerEntryType = "expire"    // Held amount expired
+	LedgerReverse   LedgerEntryType = "reverse"   // Correction or clawback, negative
+	LedgerCommit    LedgerEntryType = "commit"    // Amount ring-fenced for a campaign
+	LedgerOverdraft LedgerEntryType = "overdraft" // Part of a reservation past the hard cap
+)
+
+// LedgerEntryTypes returns every ledger entry type, in the order of the
+// ledger_entries entry_type check constraint
+func LedgerEntryTypes() []LedgerEntryType {
+	return []LedgerEntryType{
+		LedgerFund,
+		LedgerReserve,
+		LedgerRelease,
+		LedgerCharge,
+		LedgerExpire,
+		LedgerReverse,
+		LedgerCommit,
+		LedgerOverdraft,
+	}
+}
+
+// IsValid reports whether t is a ledger entry type
+func (t LedgerEntryType) IsValid() bool {
+	for _, v := range LedgerEntryTypes() {
+		if t == v {
+			return true
+		}
+	}
+	return false
+}
+
+// CountsTowardsBalance reports whether entries of type t move a budget's
+// balance. Charges, expiries and overdrafts only record what happened to
+// an amount already counted by its reservation.
+func (t LedgerEntryType) CountsTowardsBalance() bool {
+	switch t {
+	case LedgerFund, LedgerReserve, LedgerRelease, LedgerCommit:
+		return true
+	}
+	return false
+}
+
+// String returns the entry type as stored
+func (t LedgerEntryType) String() string {
+	return string(t)
+}
+
+// IssuanceStatus is the status of a reward issuance. The reward package's
+// State adds the transitions between them.
+type IssuanceStatus string
+
+const (
+	IssuanceReserved  IssuanceStatus = "reserved"  // Budget reserved, awaiting processing
+	IssuanceIssued    IssuanceStatus = "issued"    // Successfully issued to customer
+	IssuanceRedeemed  IssuanceStatus = "redeemed"  // Customer has redeemed the reward
+	IssuanceExpired   IssuanceStatus = "expired"   // Reward expired before redemption
+	IssuanceCancelled IssuanceStatus = "cancelled" // Cancelled by staff, a reversal or a reissue
+	IssuanceFailed    IssuanceStatus = "failed"    // Processing failed
+)
+
+// IssuanceStatuses returns every issuance status, in the order of the
+// issuances status check constraint
+func IssuanceStatuses() []IssuanceStatus {
+	return []IssuanceStatus{
+		IssuanceReserved,
+		IssuanceIssued,
+		IssuanceRedeemed,
+		IssuanceExpired,
+		IssuanceCancelled,
+		IssuanceFailed,
+	}
+}
+
+// IsValid reports whether s is an issuance status
+func (s IssuanceStatus) IsValid() bool {
+	for _, v := range IssuanceStatuses() {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOutstanding reports whether an issuance with status s may still be
+// redeemed: it is reserved or issued
+func (s IssuanceStatus) IsOutstanding() bool {
+	return s == IssuanceReserved || s == IssuanceIssued
+}
+
+// String returns the status as stored
+func (s IssuanceStatus) String() string {
+	return string(s)
+}
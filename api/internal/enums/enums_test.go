@@ -0,0 +1,209 @@
+package enums_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmachimbira/loyalty/api/internal/enums"
+)
+
+var (
+	ledgerCheck   = regexp.MustCompile(`(?s)ADD CONSTRAINT ledger_entries_entry_type_check\s+CHECK \(entry_type IN \(([^)]*)\)\)`)
+	issuanceCheck = regexp.MustCompile(`(?s)CREATE TABLE issuances \(.*?status\s+text NOT NULL CHECK \(status IN \(([^)]*)\)\)`)
+)
+
+// lastCheckValues returns the values of the last check constraint matched
+// across the migrations, in migration order
+func lastCheckValues(t *testing.T, re *regexp.Regexp) []string {
+	t.Helper()
+	files, err := filepath.Glob("../../../migrations/*.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+	sort.Strings(files)
+
+	var values []string
+	for _, file := range files {
+		sql, err := os.ReadFile(file)
+		require.NoError(t, err)
+		for _, m := range re.FindAllSubmatch(sql, -1) {
+			values = values[:0]
+			for _, v := range strings.Split(string(m[1]), ",") {
+				values = append(values, strings.Trim(strings.TrimSpace(v), "'"))
+			}
+		}
+	}
+	require.NotEmpty(t, values, "no check constraint found")
+	return values
+}
+
+func TestLedgerEntryTypesMatchMigrations(t *testing.T) {
+	var types []string
+	for _, v := range enums.LedgerEntryTypes() {
+		types = append(types, string(v))
+	}
+	assert.Equal(t, lastCheckValues(t, ledgerCheck), types)
+}
+
+func TestIssuanceStatusesMatchMigrations(t *testing.T) {
+	var statuses []string
+	for _, v := range enums.IssuanceStatuses() {
+		statuses = append(statuses, string(v))
+	}
+	assert.Equal(t, lastCheckValues(t, issuanceCheck), statuses)
+}
+
+func TestLedgerEntryType(t *testing.T) {
+	assert.True(t, enums.LedgerOverdraft.IsValid())
+	assert.False(t, enums.LedgerEntryType("refund").IsValid())
+
+	assert.True(t, enums.LedgerCommit.CountsTowardsBalance())
+	assert.False(t, enums.LedgerCharge.CountsTowardsBalance())
+	assert.False(t, enums.LedgerOverdraft.CountsTowardsBalance())
+}
+
+func TestIssuanceStatus(t *testing.T) {
+	assert.True(t, enums.IssuanceFailed.IsValid())
+	assert.False(t, enums.IssuanceStatus("pending").IsValid())
+
+	assert.True(t, enums.IssuanceReserved.IsOutstanding())
+	assert.False(t, enums.IssuanceRedeemed.IsOutstanding())
+}
+
+// enumFields maps the struct fields holding each enum to its values
+var enumFields = map[string]map[string]bool{
+	"EntryType": {},
+	"Status":    {},
+}
+
+func init() {
+	for _, v := range enums.LedgerEntryTypes() {
+		enumFields["EntryType"][string(v)] = true
+	}
+	for _, v := range enums.IssuanceStatuses() {
+		enumFields["Status"][string(v)] = true
+	}
+}
+
+// enumField returns the enum field expr reads, seeing through conversions
+// such as reward.State(issuance.Status)
+func enumField(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.CallExpr:
+			if len(e.Args) != 1 {
+				return ""
+			}
+			expr = e.Args[0]
+		case *ast.SelectorExpr:
+			if _, ok := enumFields[e.Sel.Name]; ok {
+				return e.Sel.Name
+			}
+			return ""
+		case *ast.Ident:
+			if _, ok := enumFields[e.Name]; ok {
+				return e.Name
+			}
+			return ""
+		default:
+			return ""
+		}
+	}
+}
+
+// enumLiteral reports whether expr is a string literal holding one of the
+// field's enum values
+func enumLiteral(field string, expr ast.Expr) bool {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return false
+	}
+	v, err := strconv.Unquote(lit.Value)
+	return err == nil && enumFields[field][v]
+}
+
+// TestNoRawEnumLiterals fails on ledger entry types and issuance statuses
+// written as string literals where they are compared with, switched on or
+// assigned to an EntryType or Status field. Use the enums constants instead.
+func TestNoRawEnumLiterals(t *testing.T) {
+	fset := token.NewFileSet()
+	var found []string
+	report := func(node ast.Node, field string) {
+		found = append(found, fset.Position(node.Pos()).String()+": raw "+field+" literal")
+	}
+
+	for _, root := range []string{"..", "../../cmd"} {
+		err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				// sqlc output is generated from the queries
+				if path == "../db" || path == "../enums" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+				return nil
+			}
+
+			file, err := parser.ParseFile(fset, path, nil, 0)
+			if err != nil {
+				return err
+			}
+			ast.Inspect(file, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.BinaryExpr:
+					if n.Op != token.EQL && n.Op != token.NEQ {
+						break
+					}
+					if field := enumField(n.X); field != "" && enumLiteral(field, n.Y) {
+						report(n, field)
+					} else if field := enumField(n.Y); field != "" && enumLiteral(field, n.X) {
+						report(n, field)
+					}
+				case *ast.SwitchStmt:
+					field := enumField(n.Tag)
+					if field == "" {
+						break
+					}
+					for _, stmt := range n.Body.List {
+						for _, expr := range stmt.(*ast.CaseClause).List {
+							if enumLiteral(field, expr) {
+								report(expr, field)
+							}
+						}
+					}
+				case *ast.AssignStmt:
+					for i, lhs := range n.Lhs {
+						if field := enumField(lhs); field != "" && i < len(n.Rhs) && enumLiteral(field, n.Rhs[i]) {
+							report(n, field)
+						}
+					}
+				case *ast.KeyValueExpr:
+					if key, ok := n.Key.(*ast.Ident); ok && enumLiteral(key.Name, n.Value) {
+						report(n, key.Name)
+					}
+				}
+				return true
+			})
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	assert.Empty(t, found, "use the enums package's constants")
+}
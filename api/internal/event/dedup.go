@@ -0,0 +1,138 @@
+package event
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Event deduplication modes (tenants.event_dedup_mode)
+const (
+	// DedupFlag accepts near-duplicate events but points them at the event
+	// they repeat
+	DedupFlag = "flag"
+	// DedupSuppress drops near-duplicate events
+	DedupSuppress = "suppress"
+)
+
+// ValidDedupMode reports whether mode is a deduplication mode
+func ValidDedupMode(mode string) bool {
+	return mode == DedupFlag || mode == DedupSuppress
+}
+
+// DedupCheck is the outcome of checking an event for near-duplicates
+type DedupCheck struct {
+	// Mode is the tenant's deduplication mode, "" when it is off
+	Mode string
+	// Hash is the content hash to store with the event; unset when
+	// deduplication is off
+	Hash pgtype.Text
+	// Original is the earlier event this one repeats, if any
+	Original *db.Event
+}
+
+// ContentHash hashes what makes two events of a tenant the same: the
+// customer, the event type and the properties. Properties are hashed in
+// canonical form, so key order and formatting do not matter.
+func ContentHash(customerID pgtype.UUID, eventType string, properties []byte) (string, error) {
+	var decoded interface{}
+	if err := json.Unmarshal(properties, &decoded); err != nil {
+		return "", fmt.Errorf("invalid properties: %w", err)
+	}
+	canonical, err := json.Marshal(decoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid properties: %w", err)
+	}
+
+	h := sha256.New()
+	h.Write(customerID.Bytes[:])
+	h.Write([]byte{0})
+	h.Write([]byte(eventType))
+	h.Write([]byte{0})
+	h.Write(canonical)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// CheckDuplicate looks for an earlier event with the same content that
+// occurred within the tenant's deduplication window of occurredAt. Events
+// that arrive at the same time may both pass the check; deduplication is a
+// safety net for retries, not a uniqueness guarantee.
+func (s *Service) CheckDuplicate(ctx context.Context, tenantID, customerID pgtype.UUID, eventType string, properties []byte, occurredAt time.Time) (*DedupCheck, error) {
+	tenant, err := s.queries.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+	if !tenant.EventDedupMode.Valid {
+		return &DedupCheck{}, nil
+	}
+
+	hash, err := ContentHash(customerID, eventType, properties)
+	if err != nil {
+		return nil, err
+	}
+	check := &DedupCheck{
+		Mode: tenant.EventDedupMode.String,
+		Hash: pgtype.Text{String: hash, Valid: true},
+	}
+
+	original, err := s.queries.FindNearDuplicateEvent(ctx, db.FindNearDuplicateEventParams{
+		TenantID:      tenantID,
+		ContentHash:   check.Hash,
+		OccurredAt:    pgtype.Timestamptz{Time: occurredAt, Valid: true},
+		WindowSeconds: tenant.EventDedupWindowSeconds,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return check, nil
+		}
+		return nil, fmt.Errorf("failed to find duplicate events: %w", err)
+	}
+	check.Original = &original
+	return check, nil
+}
+
+// RecordDuplicate counts a near-duplicate caught at the given time
+func (s *Service) RecordDuplicate(ctx context.Context, tenantID pgtype.UUID, eventType, mode string, at time.Time) error {
+	params := db.IncrementEventDedupStatsParams{
+		TenantID:  tenantID,
+		Day:       pgtype.Date{Time: dayOf(at), Valid: true},
+		EventType: eventType,
+	}
+	if mode == DedupSuppress {
+		params.Suppressed = 1
+	} else {
+		params.Flagged = 1
+	}
+	if err := s.queries.IncrementEventDedupStats(ctx, params); err != nil {
+		return fmt.Errorf("failed to record duplicate event: %w", err)
+	}
+	return nil
+}
+
+// DedupStats returns the near-duplicates caught per day and event type
+// between from and to, inclusive
+func (s *Service) DedupStats(ctx context.Context, tenantID pgtype.UUID, from, to time.Time) ([]db.EventDedupStat, error) {
+	stats, err := s.queries.ListEventDedupStats(ctx, db.ListEventDedupStatsParams{
+		TenantID: tenantID,
+		FromDay:  pgtype.Date{Time: dayOf(from), Valid: true},
+		ToDay:    pgtype.Date{Time: dayOf(to), Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list duplicate event stats: %w", err)
+	}
+	return stats, nil
+}
+
+// dayOf returns the UTC day of t
+func dayOf(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,55 @@
+package event
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentHash(t *testing.T) {
+	customer := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	other := pgtype.UUID{Bytes: [16]byte{2}, Valid: true}
+
+	hash, err := ContentHash(customer, "purchase", []byte(`{"amount": 20, "sku": "A1"}`))
+	require.NoError(t, err)
+
+	// Key order and formatting do not matter
+	reordered, err := ContentHash(customer, "purchase", []byte(`{"sku":"A1","amount":20}`))
+	require.NoError(t, err)
+	assert.Equal(t, hash, reordered)
+
+	differs := []struct {
+		name       string
+		customer   pgtype.UUID
+		eventType  string
+		properties string
+	}{
+		{"customer", other, "purchase", `{"amount": 20, "sku": "A1"}`},
+		{"event type", customer, "visit", `{"amount": 20, "sku": "A1"}`},
+		{"properties", customer, "purchase", `{"amount": 21, "sku": "A1"}`},
+	}
+	for _, tt := range differs {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := ContentHash(tt.customer, tt.eventType, []byte(tt.properties))
+			require.NoError(t, err)
+			assert.NotEqual(t, hash, h)
+		})
+	}
+
+	_, err = ContentHash(customer, "purchase", []byte("{"))
+	assert.Error(t, err)
+}
+
+func TestValidDedupMode(t *testing.T) {
+	assert.True(t, ValidDedupMode(DedupFlag))
+	assert.True(t, ValidDedupMode(DedupSuppress))
+	assert.False(t, ValidDedupMode("off"))
+}
+
+func TestDayOf(t *testing.T) {
+	at := time.Date(2025, 12, 26, 1, 30, 0, 0, time.FixedZone("CAT", 2*60*60))
+	assert.Equal(t, time.Date(2025, 12, 25, 0, 0, 0, 0, time.UTC), dayOf(at))
+}
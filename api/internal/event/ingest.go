@@ -0,0 +1,50 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// InsertOnce stores an event unless the tenant already has one with its
+// idempotency key, in which case that event is returned and created is false.
+// The insert and the fallback lookup share a transaction, so concurrent
+// requests with the same key resolve to a single event instead of one of
+// them failing on the unique constraint.
+func InsertOnce(ctx context.Context, pool *pgxpool.Pool, arg db.InsertEventIfNewParams) (event db.Event, created bool, err error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return db.Event{}, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := db.New(pool).WithTx(tx)
+
+	event, err = qtx.InsertEventIfNew(ctx, arg)
+	switch {
+	case err == nil:
+		created = true
+	case errors.Is(err, pgx.ErrNoRows):
+		// Another request holds the key; the conflict only resolves once it
+		// has committed, so its event is visible here
+		event, err = qtx.GetEventByIdemKey(ctx, db.GetEventByIdemKeyParams{
+			TenantID:       arg.TenantID,
+			IdempotencyKey: arg.IdempotencyKey,
+		})
+		if err != nil {
+			return db.Event{}, false, fmt.Errorf("failed to get existing event: %w", err)
+		}
+	default:
+		return db.Event{}, false, fmt.Errorf("failed to insert event: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.Event{}, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return event, created, nil
+}
@@ -0,0 +1,116 @@
+package event
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+)
+
+func TestInsertOnce(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set, skipping integration tests")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	require.NoError(t, err)
+	defer pool.Close()
+	queries := db.New(pool)
+
+	tenant, err := queries.CreateTenant(ctx, db.CreateTenantParams{
+		Name:        "Insert Once",
+		CountryCode: "ZW",
+		DefaultCcy:  "USD",
+		Theme:       []byte(`{}`),
+	})
+	require.NoError(t, err)
+
+	customer, err := queries.CreateCustomer(ctx, db.CreateCustomerParams{
+		TenantID:    tenant.ID,
+		ExternalRef: pgtype.Text{String: "insert-once-customer", Valid: true},
+	})
+	require.NoError(t, err)
+
+	params := func(key, properties string) db.InsertEventIfNewParams {
+		return db.InsertEventIfNewParams{
+			TenantID:       tenant.ID,
+			CustomerID:     customer.ID,
+			EventType:      "purchase",
+			Properties:     []byte(properties),
+			OccurredAt:     pgtype.Timestamptz{Time: time.Now(), Valid: true},
+			Source:         "api",
+			IdempotencyKey: key,
+		}
+	}
+
+	// The steps run in order, so later ones see the events earlier ones stored
+	ids := map[string]pgtype.UUID{}
+	steps := []struct {
+		name        string
+		key         string
+		properties  string
+		wantCreated bool
+		wantSameAs  string
+	}{
+		{"new key", "order-1", `{"amount": 10}`, true, ""},
+		{"repeated key", "order-1", `{"amount": 99}`, false, "order-1"},
+		{"another key", "order-2", `{"amount": 20}`, true, ""},
+		{"first key again", "order-1", `{"amount": 10}`, false, "order-1"},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			ev, created, err := InsertOnce(ctx, pool, params(step.key, step.properties))
+			require.NoError(t, err)
+			assert.Equal(t, step.wantCreated, created)
+			assert.Equal(t, step.key, ev.IdempotencyKey)
+
+			if step.wantSameAs != "" {
+				assert.Equal(t, ids[step.wantSameAs], ev.ID)
+				assert.JSONEq(t, `{"amount": 10}`, string(ev.Properties))
+				return
+			}
+			for key, id := range ids {
+				assert.NotEqual(t, id, ev.ID, "event for %s reused", key)
+			}
+			ids[step.key] = ev.ID
+		})
+	}
+
+	t.Run("concurrent requests", func(t *testing.T) {
+		const requests = 10
+
+		var wg sync.WaitGroup
+		events := make([]db.Event, requests)
+		created := make([]bool, requests)
+		errs := make([]error, requests)
+		for i := 0; i < requests; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				events[i], created[i], errs[i] = InsertOnce(ctx, pool, params("order-concurrent", `{"amount": 5}`))
+			}(i)
+		}
+		wg.Wait()
+
+		createdCount := 0
+		for i := 0; i < requests; i++ {
+			require.NoError(t, errs[i])
+			assert.Equal(t, events[0].ID, events[i].ID)
+			if created[i] {
+				createdCount++
+			}
+		}
+		assert.Equal(t, 1, createdCount)
+	})
+}
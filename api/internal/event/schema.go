@@ -0,0 +1,324 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema is a compiled JSON Schema used to validate event properties.
+//
+// Supported keywords (a practical subset of JSON Schema draft-07):
+// type, properties, required, additionalProperties, enum, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, minLength, maxLength, pattern,
+// items, minItems, maxItems.
+type Schema struct {
+	root     map[string]interface{}
+	patterns map[string]*regexp.Regexp
+}
+
+// SchemaError describes a single validation failure
+type SchemaError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// validSchemaTypes are the JSON Schema primitive types
+var validSchemaTypes = map[string]bool{
+	"object":  true,
+	"array":   true,
+	"string":  true,
+	"number":  true,
+	"integer": true,
+	"boolean": true,
+	"null":    true,
+}
+
+// CompileSchema parses and checks a JSON Schema document
+func CompileSchema(raw json.RawMessage) (*Schema, error) {
+	var root map[string]interface{}
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return nil, fmt.Errorf("schema must be a JSON object: %w", err)
+	}
+
+	s := &Schema{
+		root:     root,
+		patterns: make(map[string]*regexp.Regexp),
+	}
+	if err := s.compile(root, "$"); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// compile validates keyword values and precompiles patterns
+func (s *Schema) compile(node map[string]interface{}, path string) error {
+	if t, ok := node["type"]; ok {
+		for _, name := range schemaTypes(t) {
+			if !validSchemaTypes[name] {
+				return fmt.Errorf("%s: unknown type %q", path, name)
+			}
+		}
+		if len(schemaTypes(t)) == 0 {
+			return fmt.Errorf("%s: type must be a string or array of strings", path)
+		}
+	}
+
+	if p, ok := node["pattern"]; ok {
+		pattern, ok := p.(string)
+		if !ok {
+			return fmt.Errorf("%s: pattern must be a string", path)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern: %w", path, err)
+		}
+		s.patterns[pattern] = re
+	}
+
+	if req, ok := node["required"]; ok {
+		list, ok := req.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: required must be an array", path)
+		}
+		for _, r := range list {
+			if _, ok := r.(string); !ok {
+				return fmt.Errorf("%s: required entries must be strings", path)
+			}
+		}
+	}
+
+	if props, ok := node["properties"]; ok {
+		propMap, ok := props.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: properties must be an object", path)
+		}
+		for name, sub := range propMap {
+			subNode, ok := sub.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s.%s: schema must be an object", path, name)
+			}
+			if err := s.compile(subNode, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if items, ok := node["items"]; ok {
+		itemNode, ok := items.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: items must be an object", path)
+		}
+		if err := s.compile(itemNode, path+"[]"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validate validates a value (usually the event properties) against the schema.
+// It returns all validation failures, ordered by path.
+func (s *Schema) Validate(value interface{}) []SchemaError {
+	errs := s.validate(s.root, value, "$")
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Path < errs[j].Path
+	})
+	return errs
+}
+
+// validate recursively validates a value against a schema node
+func (s *Schema) validate(node map[string]interface{}, value interface{}, path string) []SchemaError {
+	var errs []SchemaError
+
+	if t, ok := node["type"]; ok {
+		types := schemaTypes(t)
+		if !matchesAnyType(value, types) {
+			return []SchemaError{{
+				Path:    path,
+				Message: fmt.Sprintf("expected %s, got %s", strings.Join(types, " or "), jsonType(value)),
+			}}
+		}
+	}
+
+	if enum, ok := node["enum"].([]interface{}); ok {
+		found := false
+		for _, candidate := range enum {
+			if jsonEqual(candidate, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, SchemaError{Path: path, Message: "value is not one of the allowed values"})
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		errs = append(errs, s.validateObject(node, v, path)...)
+	case []interface{}:
+		errs = append(errs, s.validateArray(node, v, path)...)
+	case string:
+		errs = append(errs, s.validateString(node, v, path)...)
+	case float64:
+		errs = append(errs, validateNumber(node, v, path)...)
+	}
+
+	return errs
+}
+
+// validateObject applies object keywords
+func (s *Schema) validateObject(node map[string]interface{}, obj map[string]interface{}, path string) []SchemaError {
+	var errs []SchemaError
+
+	if req, ok := node["required"].([]interface{}); ok {
+		for _, r := range req {
+			name := r.(string)
+			if _, present := obj[name]; !present {
+				errs = append(errs, SchemaError{Path: path + "." + name, Message: "is required"})
+			}
+		}
+	}
+
+	props, _ := node["properties"].(map[string]interface{})
+	for name, val := range obj {
+		sub, ok := props[name].(map[string]interface{})
+		if !ok {
+			if allowed, ok := node["additionalProperties"].(bool); ok && !allowed {
+				errs = append(errs, SchemaError{Path: path + "." + name, Message: "additional property is not allowed"})
+			}
+			continue
+		}
+		errs = append(errs, s.validate(sub, val, path+"."+name)...)
+	}
+
+	return errs
+}
+
+// validateArray applies array keywords
+func (s *Schema) validateArray(node map[string]interface{}, arr []interface{}, path string) []SchemaError {
+	var errs []SchemaError
+
+	if min, ok := node["minItems"].(float64); ok && float64(len(arr)) < min {
+		errs = append(errs, SchemaError{Path: path, Message: fmt.Sprintf("must have at least %d items", int(min))})
+	}
+	if max, ok := node["maxItems"].(float64); ok && float64(len(arr)) > max {
+		errs = append(errs, SchemaError{Path: path, Message: fmt.Sprintf("must have at most %d items", int(max))})
+	}
+
+	if items, ok := node["items"].(map[string]interface{}); ok {
+		for i, item := range arr {
+			errs = append(errs, s.validate(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+
+	return errs
+}
+
+// validateString applies string keywords
+func (s *Schema) validateString(node map[string]interface{}, str string, path string) []SchemaError {
+	var errs []SchemaError
+	length := float64(len([]rune(str)))
+
+	if min, ok := node["minLength"].(float64); ok && length < min {
+		errs = append(errs, SchemaError{Path: path, Message: fmt.Sprintf("must be at least %d characters", int(min))})
+	}
+	if max, ok := node["maxLength"].(float64); ok && length > max {
+		errs = append(errs, SchemaError{Path: path, Message: fmt.Sprintf("must be at most %d characters", int(max))})
+	}
+	if pattern, ok := node["pattern"].(string); ok {
+		if re := s.patterns[pattern]; re != nil && !re.MatchString(str) {
+			errs = append(errs, SchemaError{Path: path, Message: fmt.Sprintf("does not match pattern %q", pattern)})
+		}
+	}
+
+	return errs
+}
+
+// validateNumber applies numeric keywords
+func validateNumber(node map[string]interface{}, n float64, path string) []SchemaError {
+	var errs []SchemaError
+
+	if min, ok := node["minimum"].(float64); ok && n < min {
+		errs = append(errs, SchemaError{Path: path, Message: fmt.Sprintf("must be >= %v", min)})
+	}
+	if max, ok := node["maximum"].(float64); ok && n > max {
+		errs = append(errs, SchemaError{Path: path, Message: fmt.Sprintf("must be <= %v", max)})
+	}
+	if min, ok := node["exclusiveMinimum"].(float64); ok && n <= min {
+		errs = append(errs, SchemaError{Path: path, Message: fmt.Sprintf("must be > %v", min)})
+	}
+	if max, ok := node["exclusiveMaximum"].(float64); ok && n >= max {
+		errs = append(errs, SchemaError{Path: path, Message: fmt.Sprintf("must be < %v", max)})
+	}
+
+	return errs
+}
+
+// schemaTypes normalizes the "type" keyword to a list of type names
+func schemaTypes(t interface{}) []string {
+	switch v := t.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		types := make([]string, 0, len(v))
+		for _, item := range v {
+			name, ok := item.(string)
+			if !ok {
+				return nil
+			}
+			types = append(types, name)
+		}
+		return types
+	}
+	return nil
+}
+
+// matchesAnyType reports whether a decoded JSON value matches one of the types
+func matchesAnyType(value interface{}, types []string) bool {
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual {
+			return true
+		}
+		// Whole numbers satisfy "integer", and integers are also numbers
+		if t == "integer" && actual == "number" {
+			if n := value.(float64); n == math.Trunc(n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON Schema type name of a decoded JSON value
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return "unknown"
+	}
+}
+
+// jsonEqual compares two decoded JSON values
+func jsonEqual(a, b interface{}) bool {
+	aj, errA := json.Marshal(a)
+	bj, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aj) == string(bj)
+}
@@ -0,0 +1,96 @@
+package event
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const purchaseSchema = `{
+	"type": "object",
+	"required": ["amount", "currency"],
+	"properties": {
+		"amount":   {"type": "number", "minimum": 0},
+		"currency": {"type": "string", "enum": ["USD", "ZWG"]},
+		"items":    {"type": "integer", "minimum": 1},
+		"sku":      {"type": "string", "pattern": "^[A-Z0-9-]+$"},
+		"tags":     {"type": "array", "items": {"type": "string"}, "maxItems": 2}
+	}
+}`
+
+func TestSchemaValidate(t *testing.T) {
+	schema, err := CompileSchema(json.RawMessage(purchaseSchema))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		properties string
+		wantPaths  []string
+	}{
+		{"valid purchase", `{"amount": 25.5, "currency": "USD"}`, nil},
+		{"amount as string", `{"amount": "25.50", "currency": "USD"}`, []string{"$.amount"}},
+		{"missing currency", `{"amount": 10}`, []string{"$.currency"}},
+		{"unknown currency", `{"amount": 10, "currency": "EUR"}`, []string{"$.currency"}},
+		{"negative amount", `{"amount": -1, "currency": "USD"}`, []string{"$.amount"}},
+		{"fractional integer", `{"amount": 1, "currency": "USD", "items": 1.5}`, []string{"$.items"}},
+		{"whole number integer", `{"amount": 1, "currency": "USD", "items": 3}`, nil},
+		{"pattern mismatch", `{"amount": 1, "currency": "USD", "sku": "abc"}`, []string{"$.sku"}},
+		{"array item type", `{"amount": 1, "currency": "USD", "tags": ["a", 2]}`, []string{"$.tags[1]"}},
+		{"too many items", `{"amount": 1, "currency": "USD", "tags": ["a", "b", "c"]}`, []string{"$.tags"}},
+		{"multiple errors", `{"amount": "x"}`, []string{"$.amount", "$.currency"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var props map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(tt.properties), &props))
+
+			errs := schema.Validate(props)
+
+			paths := make([]string, 0, len(errs))
+			for _, e := range errs {
+				paths = append(paths, e.Path)
+			}
+			if tt.wantPaths == nil {
+				assert.Empty(t, errs)
+			} else {
+				assert.Equal(t, tt.wantPaths, paths)
+			}
+		})
+	}
+}
+
+func TestSchemaAdditionalProperties(t *testing.T) {
+	schema, err := CompileSchema(json.RawMessage(`{
+		"type": "object",
+		"properties": {"amount": {"type": "number"}},
+		"additionalProperties": false
+	}`))
+	require.NoError(t, err)
+
+	errs := schema.Validate(map[string]interface{}{"amount": 1.0, "extra": true})
+	require.Len(t, errs, 1)
+	assert.Equal(t, "$.extra", errs[0].Path)
+}
+
+func TestCompileSchemaErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{"not an object", `[1, 2]`},
+		{"unknown type", `{"type": "money"}`},
+		{"bad pattern", `{"type": "string", "pattern": "("}`},
+		{"required not array", `{"required": "amount"}`},
+		{"nested unknown type", `{"properties": {"amount": {"type": "decimal"}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileSchema(json.RawMessage(tt.schema))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -2,6 +2,8 @@ package event
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 
@@ -10,6 +12,14 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+var (
+	// ErrInvalidSchema is returned when an event schema document cannot be compiled
+	ErrInvalidSchema = errors.New("invalid event schema")
+
+	// ErrSchemaNotFound is returned when no schema is registered for an event type
+	ErrSchemaNotFound = errors.New("event schema not found")
+)
+
 // Service handles event-related business logic
 type Service struct {
 	queries *db.Queries
@@ -78,3 +88,97 @@ func (s *Service) GetActiveRulesForEvent(ctx context.Context, tenantID pgtype.UU
 		EventType: eventType,
 	})
 }
+
+// ValidationResult is the outcome of validating event properties against
+// the schema registered for the event type
+type ValidationResult struct {
+	Errors []SchemaError
+	Strict bool
+}
+
+// Valid reports whether the properties passed validation
+func (r *ValidationResult) Valid() bool {
+	return r == nil || len(r.Errors) == 0
+}
+
+// ValidateProperties validates event properties against the tenant's schema for
+// the event type. It returns a nil result when no schema is registered.
+func (s *Service) ValidateProperties(ctx context.Context, tenantID pgtype.UUID, eventType string, properties map[string]interface{}) (*ValidationResult, error) {
+	registered, err := s.queries.GetEventSchema(ctx, db.GetEventSchemaParams{
+		TenantID:  tenantID,
+		EventType: eventType,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get event schema: %w", err)
+	}
+
+	schema, err := CompileSchema(registered.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+
+	if properties == nil {
+		properties = map[string]interface{}{}
+	}
+
+	return &ValidationResult{
+		Errors: schema.Validate(properties),
+		Strict: registered.Strict,
+	}, nil
+}
+
+// UpsertSchema registers or replaces the schema for an event type
+func (s *Service) UpsertSchema(ctx context.Context, tenantID pgtype.UUID, eventType string, schema json.RawMessage, strict bool) (db.EventSchema, error) {
+	if _, err := CompileSchema(schema); err != nil {
+		return db.EventSchema{}, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+
+	registered, err := s.queries.UpsertEventSchema(ctx, db.UpsertEventSchemaParams{
+		TenantID:  tenantID,
+		EventType: eventType,
+		Schema:    schema,
+		Strict:    strict,
+	})
+	if err != nil {
+		return db.EventSchema{}, fmt.Errorf("failed to save event schema: %w", err)
+	}
+	return registered, nil
+}
+
+// GetSchema retrieves the schema registered for an event type
+func (s *Service) GetSchema(ctx context.Context, tenantID pgtype.UUID, eventType string) (db.EventSchema, error) {
+	registered, err := s.queries.GetEventSchema(ctx, db.GetEventSchemaParams{
+		TenantID:  tenantID,
+		EventType: eventType,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.EventSchema{}, ErrSchemaNotFound
+		}
+		return db.EventSchema{}, fmt.Errorf("failed to get event schema: %w", err)
+	}
+	return registered, nil
+}
+
+// ListSchemas lists all schemas registered for a tenant
+func (s *Service) ListSchemas(ctx context.Context, tenantID pgtype.UUID) ([]db.EventSchema, error) {
+	schemas, err := s.queries.ListEventSchemas(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list event schemas: %w", err)
+	}
+	return schemas, nil
+}
+
+// DeleteSchema removes the schema for an event type
+func (s *Service) DeleteSchema(ctx context.Context, tenantID pgtype.UUID, eventType string) error {
+	if err := s.queries.DeleteEventSchema(ctx, db.DeleteEventSchemaParams{
+		TenantID:  tenantID,
+		EventType: eventType,
+	}); err != nil {
+		return fmt.Errorf("failed to delete event schema: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	// ErrUnknownEventType is returned when an event type is neither built-in nor registered
+	ErrUnknownEventType = errors.New("invalid event type")
+
+	// ErrEventTypeNotFound is returned when a tenant-defined event type doesn't exist
+	ErrEventTypeNotFound = errors.New("event type not found")
+
+	// ErrEventTypeExists is returned when creating an event type that already exists
+	ErrEventTypeExists = errors.New("event type already exists")
+
+	// ErrInvalidEventTypeName is returned when an event type name is malformed
+	ErrInvalidEventTypeName = errors.New("event type name must be lowercase letters, digits and underscores, starting with a letter")
+)
+
+// eventTypeNameRegex mirrors the CHECK constraint on event_types.name
+var eventTypeNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// ExpectedProperty documents a property producers should send with an event type
+type ExpectedProperty struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required"`
+}
+
+// EventTypeParams contains the fields of a tenant-defined event type
+type EventTypeParams struct {
+	TenantID           pgtype.UUID
+	Name               string
+	Description        string
+	ExpectedProperties []ExpectedProperty
+	Active             bool
+}
+
+// Validate validates the event type parameters
+func (p EventTypeParams) Validate() error {
+	if !p.TenantID.Valid {
+		return errors.New("tenant_id is required")
+	}
+	if !eventTypeNameRegex.MatchString(p.Name) {
+		return ErrInvalidEventTypeName
+	}
+	seen := make(map[string]bool)
+	for _, prop := range p.ExpectedProperties {
+		if prop.Name == "" {
+			return errors.New("expected property name is required")
+		}
+		if seen[prop.Name] {
+			return fmt.Errorf("duplicate expected property %q", prop.Name)
+		}
+		seen[prop.Name] = true
+		if prop.Type != "" && !validSchemaTypes[prop.Type] {
+			return fmt.Errorf("expected property %q has unknown type %q", prop.Name, prop.Type)
+		}
+	}
+	return nil
+}
+
+// ValidateEventType checks that an event type is built-in or registered
+// and active for the tenant
+func (s *Service) ValidateEventType(ctx context.Context, tenantID pgtype.UUID, eventType string) error {
+	if httputil.ValidateEventType(eventType) == nil {
+		return nil
+	}
+
+	registered, err := s.queries.GetEventTypeByName(ctx, db.GetEventTypeByNameParams{
+		TenantID: tenantID,
+		Name:     eventType,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUnknownEventType
+		}
+		return fmt.Errorf("failed to get event type: %w", err)
+	}
+	if !registered.Active {
+		return ErrUnknownEventType
+	}
+
+	return nil
+}
+
+// CreateEventType registers a tenant-defined event type
+func (s *Service) CreateEventType(ctx context.Context, params EventTypeParams) (db.EventType, error) {
+	if err := params.Validate(); err != nil {
+		return db.EventType{}, err
+	}
+	if httputil.ValidateEventType(params.Name) == nil {
+		return db.EventType{}, ErrEventTypeExists
+	}
+
+	props, err := marshalExpectedProperties(params.ExpectedProperties)
+	if err != nil {
+		return db.EventType{}, err
+	}
+
+	created, err := s.queries.CreateEventType(ctx, db.CreateEventTypeParams{
+		TenantID:           params.TenantID,
+		Name:               params.Name,
+		Description:        pgtype.Text{String: params.Description, Valid: params.Description != ""},
+		ExpectedProperties: props,
+		Active:             params.Active,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return db.EventType{}, ErrEventTypeExists
+		}
+		return db.EventType{}, fmt.Errorf("failed to create event type: %w", err)
+	}
+
+	return created, nil
+}
+
+// GetEventType retrieves a tenant-defined event type by name
+func (s *Service) GetEventType(ctx context.Context, tenantID pgtype.UUID, name string) (db.EventType, error) {
+	eventType, err := s.queries.GetEventTypeByName(ctx, db.GetEventTypeByNameParams{
+		TenantID: tenantID,
+		Name:     name,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.EventType{}, ErrEventTypeNotFound
+		}
+		return db.EventType{}, fmt.Errorf("failed to get event type: %w", err)
+	}
+	return eventType, nil
+}
+
+// ListEventTypes lists the tenant-defined event types
+func (s *Service) ListEventTypes(ctx context.Context, tenantID pgtype.UUID) ([]db.EventType, error) {
+	eventTypes, err := s.queries.ListEventTypes(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list event types: %w", err)
+	}
+	return eventTypes, nil
+}
+
+// UpdateEventType replaces the description, expected properties and status of an event type
+func (s *Service) UpdateEventType(ctx context.Context, params EventTypeParams) (db.EventType, error) {
+	if err := params.Validate(); err != nil {
+		return db.EventType{}, err
+	}
+
+	props, err := marshalExpectedProperties(params.ExpectedProperties)
+	if err != nil {
+		return db.EventType{}, err
+	}
+
+	updated, err := s.queries.UpdateEventType(ctx, db.UpdateEventTypeParams{
+		TenantID:           params.TenantID,
+		Name:               params.Name,
+		Description:        pgtype.Text{String: params.Description, Valid: params.Description != ""},
+		ExpectedProperties: props,
+		Active:             params.Active,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.EventType{}, ErrEventTypeNotFound
+		}
+		return db.EventType{}, fmt.Errorf("failed to update event type: %w", err)
+	}
+	return updated, nil
+}
+
+// DeactivateEventType stops a tenant-defined event type from being accepted
+func (s *Service) DeactivateEventType(ctx context.Context, tenantID pgtype.UUID, name string) error {
+	if err := s.queries.DeactivateEventType(ctx, db.DeactivateEventTypeParams{
+		TenantID: tenantID,
+		Name:     name,
+	}); err != nil {
+		return fmt.Errorf("failed to deactivate event type: %w", err)
+	}
+	return nil
+}
+
+// ParseExpectedProperties decodes the stored expected properties of an event type
+func ParseExpectedProperties(raw []byte) []ExpectedProperty {
+	props := []ExpectedProperty{}
+	if len(raw) > 0 {
+		json.Unmarshal(raw, &props)
+	}
+	return props
+}
+
+func marshalExpectedProperties(props []ExpectedProperty) ([]byte, error) {
+	if props == nil {
+		props = []ExpectedProperty{}
+	}
+	data, err := json.Marshal(props)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal expected properties: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,121 @@
+package event
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+)
+
+func TestEventTypeParamsValidate(t *testing.T) {
+	tenant := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+
+	tests := []struct {
+		name    string
+		params  EventTypeParams
+		wantErr string
+	}{
+		{"valid", EventTypeParams{TenantID: tenant, Name: "store_visit"}, ""},
+		{"with properties", EventTypeParams{TenantID: tenant, Name: "fuel_purchase", ExpectedProperties: []ExpectedProperty{
+			{Name: "litres", Type: "number", Required: true},
+			{Name: "station"},
+		}}, ""},
+		{"no tenant", EventTypeParams{Name: "store_visit"}, "tenant_id is required"},
+		{"uppercase name", EventTypeParams{TenantID: tenant, Name: "StoreVisit"}, ErrInvalidEventTypeName.Error()},
+		{"leading digit", EventTypeParams{TenantID: tenant, Name: "1st_visit"}, ErrInvalidEventTypeName.Error()},
+		{"empty name", EventTypeParams{TenantID: tenant}, ErrInvalidEventTypeName.Error()},
+		{"unnamed property", EventTypeParams{TenantID: tenant, Name: "store_visit", ExpectedProperties: []ExpectedProperty{
+			{Type: "string"},
+		}}, "expected property name is required"},
+		{"duplicate property", EventTypeParams{TenantID: tenant, Name: "store_visit", ExpectedProperties: []ExpectedProperty{
+			{Name: "store"}, {Name: "store"},
+		}}, `duplicate expected property "store"`},
+		{"unknown property type", EventTypeParams{TenantID: tenant, Name: "store_visit", ExpectedProperties: []ExpectedProperty{
+			{Name: "store", Type: "text"},
+		}}, `expected property "store" has unknown type "text"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestParseExpectedProperties(t *testing.T) {
+	props := []ExpectedProperty{{Name: "litres", Type: "number", Required: true}}
+	raw, err := marshalExpectedProperties(props)
+	require.NoError(t, err)
+	assert.Equal(t, props, ParseExpectedProperties(raw))
+
+	raw, err = marshalExpectedProperties(nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[]`, string(raw))
+	assert.Empty(t, ParseExpectedProperties(nil))
+}
+
+func TestValidateEventType(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set, skipping integration tests")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	require.NoError(t, err)
+	defer pool.Close()
+	queries := db.New(pool)
+	service := NewService(queries)
+
+	tenant, err := queries.CreateTenant(ctx, db.CreateTenantParams{
+		Name:        "Event Types",
+		CountryCode: "ZW",
+		DefaultCcy:  "USD",
+		Theme:       []byte(`{}`),
+	})
+	require.NoError(t, err)
+
+	_, err = service.CreateEventType(ctx, EventTypeParams{TenantID: tenant.ID, Name: "store_visit", Active: true})
+	require.NoError(t, err)
+	_, err = service.CreateEventType(ctx, EventTypeParams{TenantID: tenant.ID, Name: "fuel_purchase", Active: true})
+	require.NoError(t, err)
+	require.NoError(t, service.DeactivateEventType(ctx, tenant.ID, "fuel_purchase"))
+
+	_, err = service.CreateEventType(ctx, EventTypeParams{TenantID: tenant.ID, Name: "store_visit", Active: true})
+	assert.ErrorIs(t, err, ErrEventTypeExists)
+	_, err = service.CreateEventType(ctx, EventTypeParams{TenantID: tenant.ID, Name: "purchase", Active: true})
+	assert.ErrorIs(t, err, ErrEventTypeExists)
+
+	tests := []struct {
+		name      string
+		eventType string
+		wantErr   error
+	}{
+		{"built-in", "purchase", nil},
+		{"registered", "store_visit", nil},
+		{"deactivated", "fuel_purchase", ErrUnknownEventType},
+		{"unknown", "spaceship_launch", ErrUnknownEventType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.ValidateEventType(ctx, tenant.ID, tt.eventType)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
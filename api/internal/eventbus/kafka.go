@@ -0,0 +1,105 @@
+package eventbus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// KafkaRESTPublisher publishes to Kafka through a Confluent-compatible REST
+// proxy (v2 API), keeping a Kafka client library out of the API. The message
+// key is the aggregate ID, so events for one issuance land on one partition
+// in order.
+type KafkaRESTPublisher struct {
+	client   *http.Client
+	endpoint string
+}
+
+// kafkaRecords is the v2 produce request body
+type kafkaRecords struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+type kafkaRecord struct {
+	Key   string          `json:"key,omitempty"`
+	Value json.RawMessage `json:"value"`
+}
+
+// kafkaProduceResponse is the v2 produce response. A record the broker
+// rejected has error_code set.
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+// NewKafkaRESTPublisher creates a publisher for a REST proxy base URL such
+// as http://kafka-rest:8082
+func NewKafkaRESTPublisher(endpoint string) *KafkaRESTPublisher {
+	return &KafkaRESTPublisher{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		endpoint: strings.TrimRight(endpoint, "/"),
+	}
+}
+
+// Name returns the publisher name
+func (p *KafkaRESTPublisher) Name() string {
+	return "kafka"
+}
+
+// Publish produces msg to the topic named by its subject
+func (p *KafkaRESTPublisher) Publish(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(kafkaRecords{
+		Records: []kafkaRecord{{Key: msg.Key, Value: msg.Body}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode Kafka record: %w", err)
+	}
+
+	topicURL := p.endpoint + "/topics/" + url.PathEscape(msg.Subject)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, topicURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to publish to Kafka: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return fmt.Errorf("failed to read Kafka response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Kafka REST proxy returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var produced kafkaProduceResponse
+	if err := json.Unmarshal(respBody, &produced); err != nil {
+		return fmt.Errorf("failed to parse Kafka response: %w", err)
+	}
+	for _, offset := range produced.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("Kafka rejected record: %s (code %d)", offset.Error, *offset.ErrorCode)
+		}
+	}
+	return nil
+}
+
+// Close is a no-op; the REST proxy holds the Kafka connections
+func (p *KafkaRESTPublisher) Close() error {
+	return nil
+}
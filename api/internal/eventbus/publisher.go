@@ -0,0 +1,67 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Domain event types published on the bus
+const (
+	EventIngested         = "event.ingested"
+	EventIssuanceCreated  = "issuance.created"
+	EventIssuanceRedeemed = "issuance.redeemed"
+	EventBudgetAlert      = "budget.alert"
+)
+
+// Message is a domain event ready to publish. Subject is the NATS subject or
+// Kafka topic; Key orders related messages (Kafka partition key); ID is
+// stable across retries so consumers can drop duplicates.
+type Message struct {
+	ID      string
+	Subject string
+	Key     string
+	Body    []byte
+}
+
+// Envelope is the JSON body of every published message
+type Envelope struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	TenantID   string    `json:"tenant_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+// Publisher delivers messages to a message bus
+type Publisher interface {
+	// Name returns the publisher's unique identifier
+	Name() string
+
+	// Publish delivers msg, returning once the bus has accepted it
+	Publish(ctx context.Context, msg Message) error
+
+	// Close releases the publisher's connections
+	Close() error
+}
+
+// NewPublisher creates the publisher with the given name. An empty name
+// disables publication; outbox events then wait until a bus is configured.
+func NewPublisher(name, url string) (Publisher, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case "nats":
+		if url == "" {
+			return nil, fmt.Errorf("nats publisher requires a URL")
+		}
+		return NewNATSPublisher(url)
+	case "kafka":
+		if url == "" {
+			return nil, fmt.Errorf("kafka publisher requires a REST proxy URL")
+		}
+		return NewKafkaRESTPublisher(url), nil
+	default:
+		return nil, fmt.Errorf("unknown event bus: %s", name)
+	}
+}
@@ -0,0 +1,208 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// relayBatchSize is the most outbox rows claimed per tenant per pass
+	relayBatchSize = 100
+
+	// publishedRetention is how long published rows are kept for inspection
+	publishedRetention = 7 * 24 * time.Hour
+
+	// maxRetryDelay caps the backoff between publish attempts
+	maxRetryDelay = time.Hour
+)
+
+// Relay publishes outbox events to the message bus. Rows are claimed with
+// FOR UPDATE SKIP LOCKED, so several API instances can relay side by side.
+// Each tenant's events are published in order: a failure stops that
+// tenant's pass and the event is retried with backoff before any later one.
+type Relay struct {
+	pool      *pgxpool.Pool
+	queries   *db.Queries
+	publisher Publisher
+	prefix    string
+	logger    *slog.Logger
+}
+
+// NewRelay creates a relay. prefix is prepended to event types to form the
+// subject or topic, e.g. "loyalty" publishes to loyalty.issuance.created.
+func NewRelay(pool *pgxpool.Pool, publisher Publisher, prefix string, logger *slog.Logger) *Relay {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Relay{
+		pool:      pool,
+		queries:   db.New(pool),
+		publisher: publisher,
+		prefix:    prefix,
+		logger:    logger,
+	}
+}
+
+// Subject returns the subject or topic an event type is published to
+func (r *Relay) Subject(eventType string) string {
+	if r.prefix == "" {
+		return eventType
+	}
+	return r.prefix + "." + eventType
+}
+
+// RelayTenant publishes the tenant's pending outbox events and purges old
+// published ones. It returns the number published.
+func (r *Relay) RelayTenant(ctx context.Context, tenantID pgtype.UUID, now time.Time) (int, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The relay runs outside a tenant request
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenantID.Bytes)); err != nil {
+		return 0, fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	qtx := r.queries.WithTx(tx)
+
+	events, err := qtx.ClaimOutboxEvents(ctx, db.ClaimOutboxEventsParams{
+		TenantID:   tenantID,
+		LimitCount: relayBatchSize,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+
+	published := 0
+	for _, event := range events {
+		// Later events wait behind one that is backing off
+		if event.NextAttemptAt.Time.After(now) {
+			break
+		}
+
+		msg, err := r.message(event)
+		if err == nil {
+			err = r.publisher.Publish(ctx, msg)
+		}
+		if err != nil {
+			r.logger.Warn("failed to publish outbox event",
+				"outbox_id", event.ID,
+				"event_type", event.EventType,
+				"attempts", event.Attempts+1,
+				"error", err)
+			if markErr := qtx.MarkOutboxEventFailed(ctx, db.MarkOutboxEventFailedParams{
+				ID:            event.ID,
+				NextAttemptAt: pgtype.Timestamptz{Time: now.Add(retryDelay(event.Attempts + 1)), Valid: true},
+				LastError:     pgtype.Text{String: err.Error(), Valid: true},
+			}); markErr != nil {
+				return published, fmt.Errorf("failed to record publish failure: %w", markErr)
+			}
+			break
+		}
+
+		if err := qtx.MarkOutboxEventPublished(ctx, event.ID); err != nil {
+			return published, fmt.Errorf("failed to mark outbox event published: %w", err)
+		}
+		published++
+	}
+
+	if _, err := qtx.DeletePublishedOutboxEvents(ctx, db.DeletePublishedOutboxEventsParams{
+		TenantID: tenantID,
+		Before:   pgtype.Timestamptz{Time: now.Add(-publishedRetention), Valid: true},
+	}); err != nil {
+		return published, fmt.Errorf("failed to purge published outbox events: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return published, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return published, nil
+}
+
+// RelayAll relays every tenant with the event bus enabled. A failing tenant
+// is logged and skipped. It returns the number of events published.
+func (r *Relay) RelayAll(ctx context.Context, now time.Time) (int, error) {
+	tenants, err := r.queries.ListEventBusTenants(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list event bus tenants: %w", err)
+	}
+
+	total := 0
+	for _, tenant := range tenants {
+		n, err := r.RelayTenant(ctx, tenant.ID, now)
+		total += n
+		if err != nil {
+			r.logger.Error("failed to relay outbox events",
+				"tenant_id", tenant.ID,
+				"error", err)
+		}
+	}
+	return total, nil
+}
+
+// Run relays outbox events on a schedule until ctx is cancelled, then closes
+// the publisher
+func (r *Relay) Run(ctx context.Context, interval time.Duration) error {
+	defer r.publisher.Close()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := r.RelayAll(ctx, time.Now()); err != nil {
+			r.logger.Error("outbox relay failed", "error", err)
+		} else if n > 0 {
+			r.logger.Debug("outbox events published", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// message builds the bus message for an outbox row
+func (r *Relay) message(event db.OutboxEvent) (Message, error) {
+	id := httputil.FormatUUID(event.MessageID.Bytes)
+	body, err := json.Marshal(Envelope{
+		ID:         id,
+		Type:       event.EventType,
+		TenantID:   httputil.FormatUUID(event.TenantID.Bytes),
+		OccurredAt: event.CreatedAt.Time.UTC(),
+		Data:       json.RawMessage(event.Payload),
+	})
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to encode envelope: %w", err)
+	}
+	return Message{
+		ID:      id,
+		Subject: r.Subject(event.EventType),
+		Key:     httputil.FormatUUID(event.AggregateID.Bytes),
+		Body:    body,
+	}, nil
+}
+
+// retryDelay is the exponential backoff after the given number of failed
+// attempts: 5s, 10s, 20s, ... up to maxRetryDelay
+func retryDelay(attempts int32) time.Duration {
+	delay := 5 * time.Second
+	for i := int32(1); i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,135 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Column headers of each dataset's CSV file
+var (
+	customerColumns = []string{"id", "phone_e164", "external_ref", "status", "created_at"}
+	eventColumns    = []string{"id", "customer_id", "event_type", "properties", "occurred_at", "source", "location_id", "created_at"}
+	issuanceColumns = []string{"id", "customer_id", "campaign_id", "reward_id", "event_id", "status", "currency",
+		"cost_amount", "face_amount", "issued_at", "expires_at", "redeemed_at", "redemption_source"}
+	ledgerColumns = []string{"id", "budget_id", "entry_type", "currency", "amount", "ref_type", "ref_id", "created_at"}
+)
+
+// csvFile accumulates rows of a CSV file in memory
+type csvFile struct {
+	buf  bytes.Buffer
+	w    *csv.Writer
+	rows int64
+}
+
+func newCSVFile(header []string) *csvFile {
+	f := &csvFile{}
+	f.w = csv.NewWriter(&f.buf)
+	f.w.Write(header)
+	return f
+}
+
+func (f *csvFile) write(record ...string) {
+	f.w.Write(record)
+	f.rows++
+}
+
+// bytes flushes and returns the file contents
+func (f *csvFile) bytes() ([]byte, error) {
+	f.w.Flush()
+	if err := f.w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return f.buf.Bytes(), nil
+}
+
+func customersCSV(rows []db.ExportCustomersRow) *csvFile {
+	f := newCSVFile(customerColumns)
+	for _, r := range rows {
+		f.write(
+			uuidString(r.ID),
+			r.PhoneE164.String,
+			r.ExternalRef.String,
+			r.Status,
+			timeString(r.CreatedAt),
+		)
+	}
+	return f
+}
+
+func eventsCSV(rows []db.ExportEventsRow) *csvFile {
+	f := newCSVFile(eventColumns)
+	for _, r := range rows {
+		f.write(
+			uuidString(r.ID),
+			uuidString(r.CustomerID),
+			r.EventType,
+			string(r.Properties),
+			timeString(r.OccurredAt),
+			r.Source,
+			uuidString(r.LocationID),
+			timeString(r.CreatedAt),
+		)
+	}
+	return f
+}
+
+func issuancesCSV(rows []db.ExportIssuancesRow) *csvFile {
+	f := newCSVFile(issuanceColumns)
+	for _, r := range rows {
+		f.write(
+			uuidString(r.ID),
+			uuidString(r.CustomerID),
+			uuidString(r.CampaignID),
+			uuidString(r.RewardID),
+			uuidString(r.EventID),
+			r.Status,
+			r.Currency.String,
+			httputil.FormatNumeric(r.CostAmount),
+			httputil.FormatNumeric(r.FaceAmount),
+			timeString(r.IssuedAt),
+			timeString(r.ExpiresAt),
+			timeString(r.RedeemedAt),
+			r.RedemptionSource.String,
+		)
+	}
+	return f
+}
+
+func ledgerCSV(rows []db.ExportLedgerEntriesRow) *csvFile {
+	f := newCSVFile(ledgerColumns)
+	for _, r := range rows {
+		f.write(
+			fmt.Sprint(r.ID),
+			uuidString(r.BudgetID),
+			r.EntryType,
+			r.Currency,
+			httputil.FormatNumeric(r.Amount),
+			r.RefType.String,
+			uuidString(r.RefID),
+			timeString(r.CreatedAt),
+		)
+	}
+	return f
+}
+
+// uuidString formats a UUID, leaving NULL empty
+func uuidString(id pgtype.UUID) string {
+	if !id.Valid {
+		return ""
+	}
+	return httputil.FormatUUID(id.Bytes)
+}
+
+// timeString formats a timestamp in UTC, leaving NULL empty
+func timeString(ts pgtype.Timestamptz) string {
+	if !ts.Valid {
+		return ""
+	}
+	return ts.Time.UTC().Format(time.RFC3339)
+}
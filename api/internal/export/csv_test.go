@@ -0,0 +1,56 @@
+package export
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssuancesCSV(t *testing.T) {
+	id := pgtype.UUID{Bytes: [16]byte{0x12, 0x34, 15: 0x01}, Valid: true}
+	issued := time.Date(2025, 12, 4, 9, 30, 0, 0, time.FixedZone("CAT", 2*60*60))
+
+	f := issuancesCSV([]db.ExportIssuancesRow{{
+		ID:         id,
+		CustomerID: id,
+		RewardID:   id,
+		Status:     "issued",
+		Currency:   pgtype.Text{String: "USD", Valid: true},
+		CostAmount: pgtype.Numeric{Int: big.NewInt(250), Exp: -2, Valid: true},
+		IssuedAt:   pgtype.Timestamptz{Time: issued, Valid: true},
+	}})
+
+	body, err := f.bytes()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), f.rows)
+	assert.Equal(t,
+		"id,customer_id,campaign_id,reward_id,event_id,status,currency,cost_amount,face_amount,issued_at,expires_at,redeemed_at,redemption_source\n"+
+			"12340000-0000-0000-0000-000000000001,12340000-0000-0000-0000-000000000001,,12340000-0000-0000-0000-000000000001,,issued,USD,2.50,,2025-12-04T07:30:00Z,,,\n",
+		string(body))
+}
+
+func TestEventsCSVQuotesProperties(t *testing.T) {
+	f := eventsCSV([]db.ExportEventsRow{{
+		EventType:  "purchase",
+		Properties: []byte(`{"amount":10,"sku":"A1"}`),
+	}})
+
+	body, err := f.bytes()
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `,purchase,"{""amount"":10,""sku"":""A1""}",`)
+}
+
+func TestObjectKey(t *testing.T) {
+	tenantID := pgtype.UUID{Bytes: [16]byte{15: 0x0a}, Valid: true}
+	day := time.Date(2025, 12, 4, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "tenant=00000000-0000-0000-0000-00000000000a/date=2025-12-04/ledger.csv",
+		ObjectKey("", tenantID, DatasetLedger, day))
+	assert.Equal(t, "bi/loyalty/tenant=00000000-0000-0000-0000-00000000000a/date=2025-12-04/customers.csv",
+		ObjectKey("bi/loyalty", tenantID, DatasetCustomers, day))
+}
@@ -0,0 +1,222 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrObjectNotFound is returned when downloading an object that doesn't exist
+var ErrObjectNotFound = errors.New("object not found")
+
+// emptyPayloadHash is the SHA-256 of an empty request body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// Uploader writes objects to a bucket
+type Uploader interface {
+	Put(ctx context.Context, bucket, key string, body []byte, contentType string) error
+}
+
+// S3Config configures an S3-compatible object store. Endpoint is the base
+// URL, e.g. https://s3.af-south-1.amazonaws.com or http://minio:9000.
+// PathStyle addresses buckets as endpoint/bucket rather than as a
+// subdomain, which MinIO and most self-hosted stores need.
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PathStyle       bool
+}
+
+// S3Uploader uploads and downloads objects with AWS Signature Version 4
+type S3Uploader struct {
+	client *http.Client
+	config S3Config
+	now    func() time.Time
+}
+
+// NewS3Uploader creates an uploader for an S3-compatible store
+func NewS3Uploader(config S3Config) (*S3Uploader, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("S3 endpoint is required")
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 access key ID and secret access key are required")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	return &S3Uploader{
+		client: &http.Client{
+			Timeout: 5 * time.Minute,
+		},
+		config: config,
+		now:    time.Now,
+	}, nil
+}
+
+// Put uploads body to bucket/key, replacing any existing object
+func (u *S3Uploader) Put(ctx context.Context, bucket, key string, body []byte, contentType string) error {
+	objectURL, err := u.objectURL(bucket, key)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	sum := sha256.Sum256(body)
+	signRequest(req, hex.EncodeToString(sum[:]), u.config, u.now())
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("upload of %s/%s failed with status %d: %s", bucket, key, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Get downloads the object at bucket/key
+func (u *S3Uploader) Get(ctx context.Context, bucket, key string) ([]byte, error) {
+	objectURL, err := u.objectURL(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, objectURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	signRequest(req, emptyPayloadHash, u.config, u.now())
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrObjectNotFound
+	default:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("download of %s/%s failed with status %d: %s", bucket, key, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	return body, nil
+}
+
+// objectURL builds the URL of an object in path or virtual-hosted style
+func (u *S3Uploader) objectURL(bucket, key string) (string, error) {
+	base, err := url.Parse(u.config.Endpoint)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return "", fmt.Errorf("invalid S3 endpoint %q", u.config.Endpoint)
+	}
+
+	path := "/" + key
+	if u.config.PathStyle {
+		path = "/" + bucket + path
+	} else {
+		base.Host = bucket + "." + base.Host
+	}
+	base.Path = path
+	base.RawPath = escapePath(path)
+	return base.String(), nil
+}
+
+// signRequest adds SigV4 authentication headers to req. Every header already
+// set on req is signed, along with host, x-amz-date and
+// x-amz-content-sha256.
+func signRequest(req *http.Request, payloadHash string, config S3Config, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + config.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+config.SecretAccessKey), date)
+	key = hmacSHA256(key, config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// escapePath URI-encodes each segment of an object path as SigV4 requires:
+// everything but unreserved characters is percent-encoded
+func escapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '/', c == '-', c == '_', c == '.', c == '~',
+			'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,294 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Exported datasets
+const (
+	DatasetCustomers = "customers"
+	DatasetEvents    = "events"
+	DatasetIssuances = "issuances"
+	DatasetLedger    = "ledger"
+)
+
+// Datasets lists every dataset in the order they are exported
+var Datasets = []string{DatasetCustomers, DatasetEvents, DatasetIssuances, DatasetLedger}
+
+// FormatCSV is the only export format
+const FormatCSV = "csv"
+
+var (
+	// ErrExportNotFound is returned when an export doesn't exist for the tenant
+	ErrExportNotFound = errors.New("export not found")
+
+	// ErrInvalidDataset is returned for an unknown dataset
+	ErrInvalidDataset = errors.New("dataset must be customers, events, issuances or ledger")
+)
+
+// Service writes nightly dataset snapshots to each tenant's export bucket
+// and records the completed files in data_exports
+type Service struct {
+	pool     *pgxpool.Pool
+	queries  *db.Queries
+	uploader Uploader
+	logger   *slog.Logger
+}
+
+// NewService creates an export service. uploader may be nil when no object
+// store is configured, in which case exports can be listed but not run.
+func NewService(pool *pgxpool.Pool, uploader Uploader, logger *slog.Logger) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Service{
+		pool:     pool,
+		queries:  db.New(pool),
+		uploader: uploader,
+		logger:   logger,
+	}
+}
+
+// ValidDataset reports whether dataset can be exported
+func ValidDataset(dataset string) bool {
+	for _, d := range Datasets {
+		if d == dataset {
+			return true
+		}
+	}
+	return false
+}
+
+// ObjectKey is where a dataset snapshot is written. The tenant=/date=
+// segments let BI tools treat the bucket as a partitioned table.
+func ObjectKey(prefix string, tenantID pgtype.UUID, dataset string, day time.Time) string {
+	return path.Join(prefix,
+		"tenant="+httputil.FormatUUID(tenantID.Bytes),
+		"date="+day.Format("2006-01-02"),
+		dataset+"."+FormatCSV)
+}
+
+// ExportTenant writes every dataset of the tenant for the UTC day containing
+// day. Datasets already exported for that day are skipped, so a failed run
+// can simply be repeated. It returns the exports created.
+func (s *Service) ExportTenant(ctx context.Context, tenant db.Tenant, day time.Time) ([]db.DataExport, error) {
+	if s.uploader == nil {
+		return nil, fmt.Errorf("no object store configured")
+	}
+	if !tenant.ExportBucket.Valid {
+		return nil, fmt.Errorf("tenant has no export bucket")
+	}
+
+	day = day.UTC()
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+	var created []db.DataExport
+	for _, dataset := range Datasets {
+		export, err := s.exportDataset(ctx, tenant, dataset, day)
+		if err != nil {
+			return created, fmt.Errorf("failed to export %s: %w", dataset, err)
+		}
+		if export != nil {
+			created = append(created, *export)
+		}
+	}
+	return created, nil
+}
+
+// exportDataset writes one dataset, returning nil if it was already exported
+func (s *Service) exportDataset(ctx context.Context, tenant db.Tenant, dataset string, day time.Time) (*db.DataExport, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Exports run outside a tenant request
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenant.ID.Bytes)); err != nil {
+		return nil, fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	qtx := s.queries.WithTx(tx)
+	snapshotDate := pgtype.Date{Time: day, Valid: true}
+
+	exists, err := qtx.DataExportExists(ctx, db.DataExportExistsParams{
+		TenantID:     tenant.ID,
+		Dataset:      dataset,
+		SnapshotDate: snapshotDate,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for existing export: %w", err)
+	}
+	if exists {
+		return nil, nil
+	}
+
+	file, err := s.buildCSV(ctx, qtx, tenant.ID, dataset, day)
+	if err != nil {
+		return nil, err
+	}
+	body, err := file.bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	key := ObjectKey(tenant.ExportPrefix, tenant.ID, dataset, day)
+	if err := s.uploader.Put(ctx, tenant.ExportBucket.String, key, body, "text/csv"); err != nil {
+		return nil, err
+	}
+
+	export, err := qtx.CreateDataExport(ctx, db.CreateDataExportParams{
+		TenantID:     tenant.ID,
+		Dataset:      dataset,
+		SnapshotDate: snapshotDate,
+		Format:       FormatCSV,
+		Bucket:       tenant.ExportBucket.String,
+		ObjectKey:    key,
+		RowCount:     file.rows,
+		SizeBytes:    int64(len(body)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record export: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &export, nil
+}
+
+// buildCSV queries a dataset for the day and renders it as CSV
+func (s *Service) buildCSV(ctx context.Context, q *db.Queries, tenantID pgtype.UUID, dataset string, day time.Time) (*csvFile, error) {
+	from := pgtype.Timestamptz{Time: day, Valid: true}
+	to := pgtype.Timestamptz{Time: day.AddDate(0, 0, 1), Valid: true}
+
+	switch dataset {
+	case DatasetCustomers:
+		rows, err := q.ExportCustomers(ctx, tenantID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query customers: %w", err)
+		}
+		return customersCSV(rows), nil
+
+	case DatasetEvents:
+		rows, err := q.ExportEvents(ctx, db.ExportEventsParams{TenantID: tenantID, FromTime: from, ToTime: to})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query events: %w", err)
+		}
+		return eventsCSV(rows), nil
+
+	case DatasetIssuances:
+		rows, err := q.ExportIssuances(ctx, db.ExportIssuancesParams{TenantID: tenantID, FromTime: from, ToTime: to})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query issuances: %w", err)
+		}
+		return issuancesCSV(rows), nil
+
+	case DatasetLedger:
+		rows, err := q.ExportLedgerEntries(ctx, db.ExportLedgerEntriesParams{TenantID: tenantID, FromTime: from, ToTime: to})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query ledger entries: %w", err)
+		}
+		return ledgerCSV(rows), nil
+	}
+	return nil, ErrInvalidDataset
+}
+
+// ExportDue exports the previous UTC day for every tenant with an export
+// bucket. A failing tenant is logged and skipped. It returns the number of
+// files written.
+func (s *Service) ExportDue(ctx context.Context, now time.Time) (int, error) {
+	tenants, err := s.queries.ListExportTenants(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list export tenants: %w", err)
+	}
+
+	day := now.UTC().AddDate(0, 0, -1)
+	written := 0
+	for _, tenant := range tenants {
+		created, err := s.ExportTenant(ctx, tenant, day)
+		written += len(created)
+		if err != nil {
+			s.logger.Error("failed to export tenant data",
+				"tenant_id", tenant.ID,
+				"error", err)
+		}
+	}
+	return written, nil
+}
+
+// Run exports due snapshots on a schedule until ctx is cancelled. Each
+// snapshot is written once, so the interval only bounds how soon after
+// midnight UTC the previous day appears.
+func (s *Service) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		if n, err := s.ExportDue(ctx, start); err != nil {
+			s.logger.Error("data export failed", "error", err)
+		} else if n > 0 {
+			s.logger.Info("data exports written",
+				"files", n,
+				"duration_ms", time.Since(start).Milliseconds())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// List returns a page of the tenant's completed exports, newest first, and
+// the total number matching. An empty dataset lists every dataset.
+func (s *Service) List(ctx context.Context, tenantID pgtype.UUID, dataset string, limit, offset int32) ([]db.DataExport, int64, error) {
+	if dataset != "" && !ValidDataset(dataset) {
+		return nil, 0, ErrInvalidDataset
+	}
+	filter := pgtype.Text{String: dataset, Valid: dataset != ""}
+
+	exports, err := s.queries.ListDataExports(ctx, db.ListDataExportsParams{
+		TenantID:    tenantID,
+		Dataset:     filter,
+		LimitCount:  limit,
+		OffsetCount: offset,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list exports: %w", err)
+	}
+
+	total, err := s.queries.CountDataExports(ctx, db.CountDataExportsParams{
+		TenantID: tenantID,
+		Dataset:  filter,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count exports: %w", err)
+	}
+	return exports, total, nil
+}
+
+// Get returns one of the tenant's exports
+func (s *Service) Get(ctx context.Context, tenantID, id pgtype.UUID) (*db.DataExport, error) {
+	export, err := s.queries.GetDataExport(ctx, db.GetDataExportParams{ID: id, TenantID: tenantID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrExportNotFound
+		}
+		return nil, fmt.Errorf("failed to get export: %w", err)
+	}
+	return &export, nil
+}
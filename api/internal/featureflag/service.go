@@ -0,0 +1,212 @@
+// Package featureflag lets tenants turn customer-facing features on and off
+// per messaging channel, so a feature can be rolled out tenant by tenant
+// without a deployment. Features a tenant hasn't set use a built-in default.
+package featureflag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Channels a feature can be controlled on
+const (
+	ChannelWhatsApp = "whatsapp"
+	ChannelUSSD     = "ussd"
+)
+
+// Features tenants can control
+const (
+	// FeatureReferrals offers the referral program
+	FeatureReferrals = "referrals"
+	// FeaturePointsDisplay shows customers their points balance
+	FeaturePointsDisplay = "points_display"
+	// FeatureGifting lets customers give rewards to other customers
+	FeatureGifting = "gifting"
+	// FeatureSurveys sends surveys and accepts replies to them
+	FeatureSurveys = "surveys"
+)
+
+var (
+	// ErrUnknownChannel is returned for a channel features can't be set on
+	ErrUnknownChannel = errors.New("unknown channel")
+
+	// ErrUnknownFeature is returned for a feature that doesn't exist
+	ErrUnknownFeature = errors.New("unknown feature")
+
+	// ErrFlagNotSet is returned when resetting a feature the tenant hasn't set
+	ErrFlagNotSet = errors.New("feature flag not set")
+)
+
+// channels lists the channels in the order flags are reported
+var channels = []string{ChannelWhatsApp, ChannelUSSD}
+
+// defaults holds whether each feature is on for tenants that haven't set it.
+// Features that were live before flags existed default to on; new ones start
+// off until a tenant opts in.
+var defaults = map[string]bool{
+	FeatureReferrals:     true,
+	FeaturePointsDisplay: true,
+	FeatureGifting:       false,
+	FeatureSurveys:       true,
+}
+
+// Flag is whether a feature is on for a tenant on one channel
+type Flag struct {
+	Channel string
+	Feature string
+	Enabled bool
+	// Default reports that the tenant hasn't set the feature, so Enabled is
+	// the built-in default
+	Default   bool
+	UpdatedAt pgtype.Timestamptz
+}
+
+// Features returns the features tenants can control, sorted by name
+func Features() []string {
+	features := make([]string, 0, len(defaults))
+	for feature := range defaults {
+		features = append(features, feature)
+	}
+	slices.Sort(features)
+	return features
+}
+
+// Validate checks that a feature can be set on a channel
+func Validate(channel, feature string) error {
+	if !slices.Contains(channels, channel) {
+		return fmt.Errorf("%w: %q", ErrUnknownChannel, channel)
+	}
+	if _, ok := defaults[feature]; !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownFeature, feature)
+	}
+	return nil
+}
+
+// Service reads and sets tenants' feature flags
+type Service struct {
+	queries *db.Queries
+	logger  *slog.Logger
+}
+
+// NewService creates a new feature flag service
+func NewService(queries *db.Queries, logger *slog.Logger) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Service{
+		queries: queries,
+		logger:  logger,
+	}
+}
+
+// Enabled reports whether a feature is on for the tenant on a channel.
+// Channels consult it while replying to customers, so a failed lookup is
+// logged and the feature's default used rather than failing the reply.
+func (s *Service) Enabled(ctx context.Context, tenantID pgtype.UUID, channel, feature string) bool {
+	enabled, err := s.queries.GetFeatureFlag(ctx, db.GetFeatureFlagParams{
+		TenantID: tenantID,
+		Channel:  channel,
+		Feature:  feature,
+	})
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Error("failed to get feature flag",
+				"tenant_id", tenantID,
+				"channel", channel,
+				"feature", feature,
+				"error", err,
+			)
+		}
+		return defaults[feature]
+	}
+	return enabled
+}
+
+// List returns every feature on every channel for the tenant, with the
+// tenant's setting where it has one and the default otherwise
+func (s *Service) List(ctx context.Context, tenantID pgtype.UUID) ([]Flag, error) {
+	rows, err := s.queries.ListFeatureFlags(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feature flags: %w", err)
+	}
+
+	set := make(map[[2]string]db.FeatureFlag, len(rows))
+	for _, row := range rows {
+		set[[2]string{row.Channel, row.Feature}] = row
+	}
+
+	features := Features()
+	flags := make([]Flag, 0, len(channels)*len(features))
+	for _, channel := range channels {
+		for _, feature := range features {
+			flag := Flag{Channel: channel, Feature: feature}
+			if row, ok := set[[2]string{channel, feature}]; ok {
+				flag.Enabled = row.Enabled
+				flag.UpdatedAt = row.UpdatedAt
+			} else {
+				flag.Enabled = defaults[feature]
+				flag.Default = true
+			}
+			flags = append(flags, flag)
+		}
+	}
+	return flags, nil
+}
+
+// Set turns a feature on or off for the tenant on a channel
+func (s *Service) Set(ctx context.Context, tenantID pgtype.UUID, channel, feature string, enabled bool) (Flag, error) {
+	if err := Validate(channel, feature); err != nil {
+		return Flag{}, err
+	}
+
+	row, err := s.queries.SetFeatureFlag(ctx, db.SetFeatureFlagParams{
+		TenantID: tenantID,
+		Channel:  channel,
+		Feature:  feature,
+		Enabled:  enabled,
+	})
+	if err != nil {
+		return Flag{}, fmt.Errorf("failed to set feature flag: %w", err)
+	}
+
+	return Flag{
+		Channel:   row.Channel,
+		Feature:   row.Feature,
+		Enabled:   row.Enabled,
+		UpdatedAt: row.UpdatedAt,
+	}, nil
+}
+
+// Reset removes the tenant's setting for a feature on a channel, so the
+// default applies again
+func (s *Service) Reset(ctx context.Context, tenantID pgtype.UUID, channel, feature string) (Flag, error) {
+	if err := Validate(channel, feature); err != nil {
+		return Flag{}, err
+	}
+
+	removed, err := s.queries.DeleteFeatureFlag(ctx, db.DeleteFeatureFlagParams{
+		TenantID: tenantID,
+		Channel:  channel,
+		Feature:  feature,
+	})
+	if err != nil {
+		return Flag{}, fmt.Errorf("failed to reset feature flag: %w", err)
+	}
+	if removed == 0 {
+		return Flag{}, ErrFlagNotSet
+	}
+
+	return Flag{
+		Channel: channel,
+		Feature: feature,
+		Enabled: defaults[feature],
+		Default: true,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package featureflag
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel string
+		feature string
+		wantErr error
+	}{
+		{"whatsapp referrals", ChannelWhatsApp, FeatureReferrals, nil},
+		{"ussd points", ChannelUSSD, FeaturePointsDisplay, nil},
+		{"unknown channel", "sms", FeatureSurveys, ErrUnknownChannel},
+		{"unknown feature", ChannelWhatsApp, "loyalty_tiers", ErrUnknownFeature},
+		{"empty", "", "", ErrUnknownChannel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.channel, tt.feature)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	got := Features()
+	want := []string{FeatureGifting, FeaturePointsDisplay, FeatureReferrals, FeatureSurveys}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Features() = %v, want %v", got, want)
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	// Features that predate flags must stay on for tenants that haven't set them
+	for _, feature := range []string{FeatureReferrals, FeaturePointsDisplay, FeatureSurveys} {
+		if !defaults[feature] {
+			t.Errorf("%s should default to on", feature)
+		}
+	}
+	if defaults[FeatureGifting] {
+		t.Error("gifting should default to off")
+	}
+}
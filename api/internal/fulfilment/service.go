@@ -0,0 +1,336 @@
+package fulfilment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/rewardtypes"
+	"github.com/bmachimbira/loyalty/api/internal/webhooks"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Fulfilment statuses, in the order a delivery moves through them
+const (
+	StatusPending    = "pending"
+	StatusPicked     = "picked"
+	StatusDispatched = "dispatched"
+	StatusDelivered  = "delivered"
+)
+
+const (
+	// DefaultDeliveryDays is how long a physical item has to be delivered when
+	// its reward doesn't set delivery_days
+	DefaultDeliveryDays = 7
+
+	// escalationBatchSize bounds how many of one tenant's overdue fulfilments a
+	// pass escalates; the rest are picked up by the next pass
+	escalationBatchSize = 100
+)
+
+var (
+	// ErrFulfilmentNotFound is returned when the fulfilment does not exist for the tenant
+	ErrFulfilmentNotFound = errors.New("fulfilment not found")
+
+	// ErrInvalidStatus is returned for a status that isn't a fulfilment status
+	ErrInvalidStatus = errors.New("status must be one of pending, picked, dispatched, delivered")
+
+	// ErrInvalidTransition is returned when a fulfilment would move backwards
+	// or stay where it is
+	ErrInvalidTransition = errors.New("fulfilment status can only move forward")
+)
+
+var statusOrder = map[string]int{
+	StatusPending:    0,
+	StatusPicked:     1,
+	StatusDispatched: 2,
+	StatusDelivered:  3,
+}
+
+// IsValidStatus reports whether status is a fulfilment status
+func IsValidStatus(status string) bool {
+	_, ok := statusOrder[status]
+	return ok
+}
+
+// CanAdvance reports whether a fulfilment can move from one status to
+// another. Steps may be skipped, e.g. an item handed over in store goes
+// straight from pending to delivered.
+func CanAdvance(from, to string) bool {
+	fromOrder, ok := statusOrder[from]
+	if !ok {
+		return false
+	}
+	toOrder, ok := statusOrder[to]
+	return ok && toOrder > fromOrder
+}
+
+// DueAt is when an item issued at issuedAt must be delivered by, from its
+// reward's delivery_days
+func DueAt(metadata []byte, issuedAt time.Time) time.Time {
+	var meta rewardtypes.PhysicalItemMetadata
+	if len(metadata) > 0 {
+		// Malformed metadata falls back to the default
+		_ = json.Unmarshal(metadata, &meta)
+	}
+	days := meta.DeliveryDays
+	if days <= 0 {
+		days = DefaultDeliveryDays
+	}
+	return issuedAt.AddDate(0, 0, days)
+}
+
+// Open starts the fulfilment of an issued physical item. It runs in the
+// issuing transaction; opening one twice is a no-op.
+func Open(ctx context.Context, q *db.Queries, issuance db.Issuance, reward db.RewardCatalog, now time.Time) error {
+	_, err := q.CreateFulfilment(ctx, db.CreateFulfilmentParams{
+		TenantID:   issuance.TenantID,
+		IssuanceID: issuance.ID,
+		CustomerID: issuance.CustomerID,
+		DueAt:      pgtype.Timestamptz{Time: DueAt(reward.Metadata, now), Valid: true},
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to open fulfilment: %w", err)
+	}
+	return nil
+}
+
+// AdvanceParams contains the parameters for moving a fulfilment on
+type AdvanceParams struct {
+	TenantID     pgtype.UUID
+	FulfilmentID pgtype.UUID
+	Status       string
+	Carrier      string
+	TrackingRef  string
+	Note         string
+	UpdatedBy    pgtype.UUID
+}
+
+// Service handles physical item fulfilment
+type Service struct {
+	pool     *pgxpool.Pool
+	queries  *db.Queries
+	webhooks *webhooks.DeliveryService
+}
+
+// NewService creates a new fulfilment service
+func NewService(pool *pgxpool.Pool, queries *db.Queries) *Service {
+	return &Service{
+		pool:    pool,
+		queries: queries,
+	}
+}
+
+// SetWebhookService enables fulfilment.overdue escalations
+func (s *Service) SetWebhookService(webhooks *webhooks.DeliveryService) {
+	s.webhooks = webhooks
+}
+
+// Get retrieves a fulfilment by ID
+func (s *Service) Get(ctx context.Context, tenantID, id pgtype.UUID) (db.Fulfilment, error) {
+	fulfilment, err := s.queries.GetFulfilment(ctx, db.GetFulfilmentParams{
+		ID:       id,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Fulfilment{}, ErrFulfilmentNotFound
+		}
+		return db.Fulfilment{}, fmt.Errorf("failed to get fulfilment: %w", err)
+	}
+	return fulfilment, nil
+}
+
+// List returns a tenant's fulfilments, open ones due soonest first, with the
+// total count. status optionally limits them to one status and overdue to
+// undelivered ones past their due date.
+func (s *Service) List(ctx context.Context, tenantID pgtype.UUID, status string, overdue bool, now time.Time, limit, offset int32) ([]db.Fulfilment, int64, error) {
+	statusText := pgtype.Text{String: status, Valid: status != ""}
+	due := pgtype.Timestamptz{Time: now, Valid: true}
+
+	fulfilments, err := s.queries.ListFulfilments(ctx, db.ListFulfilmentsParams{
+		TenantID:  tenantID,
+		Status:    statusText,
+		Overdue:   overdue,
+		Now:       due,
+		RowLimit:  limit,
+		RowOffset: offset,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list fulfilments: %w", err)
+	}
+	total, err := s.queries.CountFulfilments(ctx, db.CountFulfilmentsParams{
+		TenantID: tenantID,
+		Status:   statusText,
+		Overdue:  overdue,
+		Now:      due,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count fulfilments: %w", err)
+	}
+	return fulfilments, total, nil
+}
+
+// Advance moves a fulfilment forward to a new status, recording the carrier,
+// tracking reference and note when given
+func (s *Service) Advance(ctx context.Context, params AdvanceParams) (db.Fulfilment, error) {
+	if !IsValidStatus(params.Status) {
+		return db.Fulfilment{}, ErrInvalidStatus
+	}
+
+	current, err := s.Get(ctx, params.TenantID, params.FulfilmentID)
+	if err != nil {
+		return db.Fulfilment{}, err
+	}
+	if !CanAdvance(current.Status, params.Status) {
+		return db.Fulfilment{}, ErrInvalidTransition
+	}
+
+	updated, err := s.queries.AdvanceFulfilment(ctx, db.AdvanceFulfilmentParams{
+		ID:          params.FulfilmentID,
+		TenantID:    params.TenantID,
+		FromStatus:  current.Status,
+		Status:      params.Status,
+		Carrier:     optionalText(params.Carrier),
+		TrackingRef: optionalText(params.TrackingRef),
+		Note:        optionalText(params.Note),
+		UpdatedBy:   params.UpdatedBy,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Someone else moved it on first
+			return db.Fulfilment{}, ErrInvalidTransition
+		}
+		return db.Fulfilment{}, fmt.Errorf("failed to update fulfilment: %w", err)
+	}
+	return updated, nil
+}
+
+// CustomerFulfilments returns a customer's most recent deliveries
+func (s *Service) CustomerFulfilments(ctx context.Context, tenantID, customerID pgtype.UUID, limit int32) ([]db.ListCustomerFulfilmentsRow, error) {
+	fulfilments, err := s.queries.ListCustomerFulfilments(ctx, db.ListCustomerFulfilmentsParams{
+		TenantID:   tenantID,
+		CustomerID: customerID,
+		RowLimit:   limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list customer fulfilments: %w", err)
+	}
+	return fulfilments, nil
+}
+
+// EscalateOverdue escalates every tenant's undelivered fulfilments past their
+// due date with a fulfilment.overdue webhook, once per fulfilment, and returns
+// how many were escalated. A failing tenant is logged and does not stop the
+// others.
+func (s *Service) EscalateOverdue(ctx context.Context, now time.Time) (int, error) {
+	tenants, err := s.queries.ListTenants(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	escalated := 0
+	for _, tenant := range tenants {
+		n, err := s.escalateTenant(ctx, tenant.ID, now)
+		if err != nil {
+			log.Printf("Failed to escalate overdue fulfilments for tenant %s: %v", httputil.FormatUUID(tenant.ID.Bytes), err)
+			continue
+		}
+		escalated += n
+	}
+	return escalated, nil
+}
+
+func (s *Service) escalateTenant(ctx context.Context, tenantID pgtype.UUID, now time.Time) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Escalation runs outside a tenant request
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenantID.Bytes)); err != nil {
+		return 0, fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	qtx := s.queries.WithTx(tx)
+	overdue, err := qtx.ListOverdueFulfilments(ctx, db.ListOverdueFulfilmentsParams{
+		TenantID: tenantID,
+		Now:      pgtype.Timestamptz{Time: now, Valid: true},
+		RowLimit: escalationBatchSize,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list overdue fulfilments: %w", err)
+	}
+
+	for _, row := range overdue {
+		if err := qtx.MarkFulfilmentEscalated(ctx, db.MarkFulfilmentEscalatedParams{
+			ID:       row.Fulfilment.ID,
+			TenantID: tenantID,
+		}); err != nil {
+			return 0, fmt.Errorf("failed to mark fulfilment escalated: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Webhooks are queued after commit so a rolled back pass doesn't alert
+	if s.webhooks != nil {
+		for _, row := range overdue {
+			f := row.Fulfilment
+			data := webhooks.FulfilmentOverdueData{
+				FulfilmentID: httputil.FormatUUID(f.ID.Bytes),
+				IssuanceID:   httputil.FormatUUID(f.IssuanceID.Bytes),
+				CustomerID:   httputil.FormatUUID(f.CustomerID.Bytes),
+				RewardName:   row.RewardName,
+				Status:       f.Status,
+				Carrier:      f.Carrier.String,
+				TrackingRef:  f.TrackingRef.String,
+				DueAt:        f.DueAt.Time.UTC().Format(time.RFC3339),
+			}
+			if err := s.webhooks.NotifyFulfilmentOverdue(ctx, uuid.UUID(tenantID.Bytes), data); err != nil {
+				log.Printf("Failed to send fulfilment overdue webhook for %s: %v", data.FulfilmentID, err)
+			}
+		}
+	}
+
+	return len(overdue), nil
+}
+
+// RunEscalationWorker escalates overdue fulfilments on a schedule until ctx
+// is cancelled
+func (s *Service) RunEscalationWorker(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		escalated, err := s.EscalateOverdue(ctx, time.Now())
+		if err != nil {
+			log.Printf("Fulfilment escalation error: %v", err)
+		} else if escalated > 0 {
+			log.Printf("Fulfilment escalation: %d overdue fulfilments escalated", escalated)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func optionalText(value string) pgtype.Text {
+	value = strings.TrimSpace(value)
+	return pgtype.Text{String: value, Valid: value != ""}
+}
@@ -0,0 +1,50 @@
+package fulfilment
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCanAdvance(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{StatusPending, StatusPicked, true},
+		{StatusPicked, StatusDispatched, true},
+		{StatusDispatched, StatusDelivered, true},
+		{StatusPending, StatusDelivered, true},
+		{StatusPicked, StatusPicked, false},
+		{StatusDispatched, StatusPicked, false},
+		{StatusDelivered, StatusPending, false},
+		{StatusPending, "lost", false},
+		{"lost", StatusDelivered, false},
+	}
+
+	for _, tt := range tests {
+		if got := CanAdvance(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanAdvance(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestDueAt(t *testing.T) {
+	issuedAt := time.Date(2025, 12, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		metadata string
+		want     time.Time
+	}{
+		{"delivery days", `{"item_name": "Mug", "delivery_days": 3}`, issuedAt.AddDate(0, 0, 3)},
+		{"default", `{"item_name": "Mug"}`, issuedAt.AddDate(0, 0, DefaultDeliveryDays)},
+		{"no metadata", ``, issuedAt.AddDate(0, 0, DefaultDeliveryDays)},
+		{"malformed", `{"delivery_days": "soon"}`, issuedAt.AddDate(0, 0, DefaultDeliveryDays)},
+	}
+
+	for _, tt := range tests {
+		if got := DueAt([]byte(tt.metadata), issuedAt); !got.Equal(tt.want) {
+			t.Errorf("%s: DueAt() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
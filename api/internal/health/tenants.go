@@ -0,0 +1,249 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Thresholds a tenant is reported degraded, or unhealthy, at. Rates are only
+// judged once there is MinSample traffic to judge them on.
+const (
+	MinSample = 20
+
+	DegradedErrorRate  = 0.02
+	UnhealthyErrorRate = 0.10
+
+	DegradedWebhookFailureRate  = 0.10
+	UnhealthyWebhookFailureRate = 0.50
+
+	DegradedDeliveryRate  = 0.90
+	UnhealthyDeliveryRate = 0.50
+
+	DegradedBacklog  = 100
+	UnhealthyBacklog = 1000
+)
+
+// TenantHealth summarizes how a tenant's platform usage has gone since a
+// point in time, so operators can spot a tenant in trouble
+type TenantHealth struct {
+	TenantID   string   `json:"tenant_id"`
+	TenantName string   `json:"tenant_name"`
+	Status     Status   `json:"status"`
+	Problems   []string `json:"problems"`
+
+	Events    EventHealth    `json:"events"`
+	Backlogs  Backlogs       `json:"backlogs"`
+	Budgets   BudgetAlerts   `json:"budgets"`
+	Webhooks  WebhookHealth  `json:"webhooks"`
+	Messaging DeliveryHealth `json:"messaging"`
+}
+
+// EventHealth is a tenant's event throughput and processing errors. Rates
+// are nil without traffic to compute them from.
+type EventHealth struct {
+	Received        int64    `json:"received"`
+	DeadLettered    int64    `json:"dead_lettered"`
+	FailedIssuances int64    `json:"failed_issuances"`
+	ErrorRate       *float64 `json:"error_rate"`
+}
+
+// Backlogs is the work queued for a tenant right now
+type Backlogs struct {
+	DeadLetters       int64 `json:"dead_letters"`
+	ReservedIssuances int64 `json:"reserved_issuances"`
+	OutboundMessages  int64 `json:"outbound_messages"`
+	OutboxEvents      int64 `json:"outbox_events"`
+	WebhookDeliveries int64 `json:"webhook_deliveries"`
+}
+
+// BudgetAlerts counts a tenant's budgets currently past their caps
+type BudgetAlerts struct {
+	OverSoftCap int64 `json:"over_soft_cap"`
+	AtHardCap   int64 `json:"at_hard_cap"`
+}
+
+// WebhookHealth is how a tenant's webhook deliveries went
+type WebhookHealth struct {
+	Delivered   int64    `json:"delivered"`
+	Failed      int64    `json:"failed"`
+	FailureRate *float64 `json:"failure_rate"`
+}
+
+// DeliveryHealth is how messages queued for a tenant's customers on the
+// WhatsApp channel went
+type DeliveryHealth struct {
+	Sent         int64    `json:"sent"`
+	Dead         int64    `json:"dead"`
+	DeliveryRate *float64 `json:"delivery_rate"`
+}
+
+// TenantReporter reports the health of every tenant
+type TenantReporter struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+}
+
+// NewTenantReporter creates a tenant health reporter. pool may be a read
+// replica.
+func NewTenantReporter(pool *pgxpool.Pool) *TenantReporter {
+	return &TenantReporter{pool: pool, queries: db.New(pool)}
+}
+
+// Report returns the health of every tenant since the given time, those in
+// the most trouble first
+func (r *TenantReporter) Report(ctx context.Context, since time.Time) ([]TenantHealth, error) {
+	tenants, err := r.queries.ListTenants(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	report := make([]TenantHealth, 0, len(tenants))
+	for _, tenant := range tenants {
+		row, err := r.tenantHealth(ctx, tenant.ID, since)
+		if err != nil {
+			return nil, err
+		}
+		health := NewTenantHealth(row)
+		health.TenantID = httputil.FormatUUID(tenant.ID.Bytes)
+		health.TenantName = tenant.Name
+		report = append(report, health)
+	}
+
+	sort.SliceStable(report, func(i, j int) bool {
+		return severity(report[i].Status) > severity(report[j].Status)
+	})
+	return report, nil
+}
+
+// tenantHealth gets a tenant's counts in a transaction scoped to the tenant
+func (r *TenantReporter) tenantHealth(ctx context.Context, tenantID pgtype.UUID, since time.Time) (db.GetTenantHealthRow, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return db.GetTenantHealthRow{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenantID.Bytes)); err != nil {
+		return db.GetTenantHealthRow{}, fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	row, err := r.queries.WithTx(tx).GetTenantHealth(ctx, db.GetTenantHealthParams{
+		TenantID: tenantID,
+		Since:    pgtype.Timestamptz{Time: since, Valid: true},
+	})
+	if err != nil {
+		return db.GetTenantHealthRow{}, fmt.Errorf("failed to get tenant health: %w", err)
+	}
+	return row, nil
+}
+
+// NewTenantHealth builds a tenant's health from its counts and judges its
+// status, listing the problems that made it degraded or unhealthy
+func NewTenantHealth(row db.GetTenantHealthRow) TenantHealth {
+	h := TenantHealth{
+		Status:   StatusHealthy,
+		Problems: []string{},
+		Events: EventHealth{
+			Received:        row.Events,
+			DeadLettered:    row.DeadLetters,
+			FailedIssuances: row.FailedIssuances,
+			ErrorRate:       rate(row.DeadLetters, row.Events),
+		},
+		Backlogs: Backlogs{
+			DeadLetters:       row.DeadLetterBacklog,
+			ReservedIssuances: row.ReservedIssuances,
+			OutboundMessages:  row.MessageBacklog,
+			OutboxEvents:      row.OutboxBacklog,
+			WebhookDeliveries: row.WebhookBacklog,
+		},
+		Budgets: BudgetAlerts{
+			OverSoftCap: row.BudgetsOverSoftCap,
+			AtHardCap:   row.BudgetsAtHardCap,
+		},
+		Webhooks: WebhookHealth{
+			Delivered:   row.WebhooksDelivered,
+			Failed:      row.WebhooksFailed,
+			FailureRate: rate(row.WebhooksFailed, row.WebhooksDelivered+row.WebhooksFailed),
+		},
+		Messaging: DeliveryHealth{
+			Sent:         row.MessagesSent,
+			Dead:         row.MessagesDead,
+			DeliveryRate: rate(row.MessagesSent, row.MessagesSent+row.MessagesDead),
+		},
+	}
+
+	if row.Events >= MinSample {
+		h.judge(*h.Events.ErrorRate >= UnhealthyErrorRate, *h.Events.ErrorRate >= DegradedErrorRate,
+			fmt.Sprintf("%.1f%% of events dead-lettered", *h.Events.ErrorRate*100))
+	}
+	if h.Events.FailedIssuances > 0 {
+		h.judge(false, true, fmt.Sprintf("%d issuances failed", h.Events.FailedIssuances))
+	}
+	for _, backlog := range []struct {
+		name  string
+		count int64
+	}{
+		{"dead letters", h.Backlogs.DeadLetters},
+		{"reserved issuances", h.Backlogs.ReservedIssuances},
+		{"outbound messages", h.Backlogs.OutboundMessages},
+		{"outbox events", h.Backlogs.OutboxEvents},
+		{"webhook deliveries", h.Backlogs.WebhookDeliveries},
+	} {
+		h.judge(backlog.count >= UnhealthyBacklog, backlog.count >= DegradedBacklog,
+			fmt.Sprintf("%d %s queued", backlog.count, backlog.name))
+	}
+	h.judge(h.Budgets.AtHardCap > 0, h.Budgets.OverSoftCap > 0,
+		fmt.Sprintf("%d budgets at hard cap, %d over soft cap", h.Budgets.AtHardCap, h.Budgets.OverSoftCap))
+	if row.WebhooksDelivered+row.WebhooksFailed >= MinSample {
+		h.judge(*h.Webhooks.FailureRate >= UnhealthyWebhookFailureRate, *h.Webhooks.FailureRate >= DegradedWebhookFailureRate,
+			fmt.Sprintf("%.1f%% of webhook deliveries failed", *h.Webhooks.FailureRate*100))
+	}
+	if row.MessagesSent+row.MessagesDead >= MinSample {
+		h.judge(*h.Messaging.DeliveryRate < UnhealthyDeliveryRate, *h.Messaging.DeliveryRate < DegradedDeliveryRate,
+			fmt.Sprintf("%.1f%% of WhatsApp messages delivered", *h.Messaging.DeliveryRate*100))
+	}
+	return h
+}
+
+// judge records problem, worsening the status, when the tenant is
+// unhealthy or degraded by it
+func (h *TenantHealth) judge(unhealthy, degraded bool, problem string) {
+	switch {
+	case unhealthy:
+		h.Status = StatusUnhealthy
+	case degraded:
+		if h.Status == StatusHealthy {
+			h.Status = StatusDegraded
+		}
+	default:
+		return
+	}
+	h.Problems = append(h.Problems, problem)
+}
+
+// rate is part over whole, nil when whole is zero
+func rate(part, whole int64) *float64 {
+	if whole == 0 {
+		return nil
+	}
+	r := float64(part) / float64(whole)
+	return &r
+}
+
+// severity orders statuses from healthy to unhealthy
+func severity(status Status) int {
+	switch status {
+	case StatusUnhealthy:
+		return 2
+	case StatusDegraded:
+		return 1
+	}
+	return 0
+}
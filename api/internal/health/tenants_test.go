@@ -0,0 +1,41 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTenantHealth(t *testing.T) {
+	h := NewTenantHealth(db.GetTenantHealthRow{Events: 500, DeadLetters: 2, MessagesSent: 10})
+	assert.Equal(t, StatusHealthy, h.Status)
+	assert.Empty(t, h.Problems)
+	require.NotNil(t, h.Events.ErrorRate)
+	assert.InDelta(t, 0.004, *h.Events.ErrorRate, 1e-9)
+	assert.Nil(t, h.Webhooks.FailureRate)
+
+	// Too little traffic to judge the rate by
+	h = NewTenantHealth(db.GetTenantHealthRow{Events: 5, DeadLetters: 5})
+	assert.Equal(t, StatusHealthy, h.Status)
+
+	h = NewTenantHealth(db.GetTenantHealthRow{Events: 100, DeadLetters: 5, BudgetsOverSoftCap: 1})
+	assert.Equal(t, StatusDegraded, h.Status)
+	assert.Equal(t, []string{
+		"5.0% of events dead-lettered",
+		"0 budgets at hard cap, 1 over soft cap",
+	}, h.Problems)
+
+	h = NewTenantHealth(db.GetTenantHealthRow{MessageBacklog: 150, WebhooksDelivered: 10, WebhooksFailed: 30})
+	assert.Equal(t, StatusUnhealthy, h.Status)
+	assert.Equal(t, []string{
+		"150 outbound messages queued",
+		"75.0% of webhook deliveries failed",
+	}, h.Problems)
+}
+
+func TestSeverity(t *testing.T) {
+	assert.Less(t, severity(StatusHealthy), severity(StatusDegraded))
+	assert.Less(t, severity(StatusDegraded), severity(StatusUnhealthy))
+}
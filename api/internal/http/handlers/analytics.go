@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"errors"
+	"time"
+
 	"github.com/bmachimbira/loyalty/api/internal/analytics"
+	"github.com/bmachimbira/loyalty/api/internal/budget"
 	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/timezone"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgtype"
 )
@@ -17,12 +22,16 @@ func NewAnalyticsHandler(service *analytics.Service) *AnalyticsHandler {
 	return &AnalyticsHandler{service: service}
 }
 
-// DashboardStatsResponse represents the dashboard statistics response
+// DashboardStatsResponse represents the dashboard statistics response.
+// CriticalAlerts lists the newest unacknowledged critical budget alerts.
 type DashboardStatsResponse struct {
-	ActiveCustomers    int64   `json:"active_customers"`
-	EventsToday        int64   `json:"events_today"`
-	RewardsIssuedToday int64   `json:"rewards_issued_today"`
-	RedemptionRate     float64 `json:"redemption_rate"`
+	ActiveCustomers              int64                `json:"active_customers"`
+	EventsToday                  int64                `json:"events_today"`
+	RewardsIssuedToday           int64                `json:"rewards_issued_today"`
+	RedemptionRate               float64              `json:"redemption_rate"`
+	Freshness                    analytics.Freshness  `json:"freshness"`
+	CriticalAlerts               []budget.AlertRecord `json:"critical_alerts"`
+	UnacknowledgedCriticalAlerts int64                `json:"unacknowledged_critical_alerts"`
 }
 
 // GetDashboardStats handles GET /v1/tenants/:tid/analytics/dashboard
@@ -47,11 +56,148 @@ func (h *AnalyticsHandler) GetDashboardStats(c *gin.Context) {
 	}
 
 	response := DashboardStatsResponse{
-		ActiveCustomers:    stats.ActiveCustomers,
-		EventsToday:        stats.EventsToday,
-		RewardsIssuedToday: stats.RewardsIssuedToday,
-		RedemptionRate:     stats.RedemptionRate,
+		ActiveCustomers:              stats.ActiveCustomers,
+		EventsToday:                  stats.EventsToday,
+		RewardsIssuedToday:           stats.RewardsIssuedToday,
+		RedemptionRate:               stats.RedemptionRate,
+		Freshness:                    stats.Freshness,
+		CriticalAlerts:               stats.CriticalAlerts,
+		UnacknowledgedCriticalAlerts: stats.UnacknowledgedCriticalAlerts,
+	}
+
+	httputil.Respond(c, 200, response)
+}
+
+// GetTimeseries handles GET /v1/tenants/:tid/analytics/timeseries
+// Query: metric (issuances|redemptions|events), interval (hour|day|week|month,
+// default day), from and to (RFC3339 or a date in the tenant's time zone,
+// default the last 30 days), campaign_id and event_type filters.
+func (h *AnalyticsHandler) GetTimeseries(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	params := analytics.TimeseriesParams{
+		Metric:    c.Query("metric"),
+		Interval:  c.DefaultQuery("interval", analytics.IntervalDay),
+		To:        time.Now().UTC(),
+		EventType: c.Query("event_type"),
+	}
+	if err := params.TenantID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	loc, err := h.service.Location(c.Request.Context(), params.TenantID)
+	if err != nil {
+		httputil.InternalError(c, "Failed to fetch timeseries")
+		return
+	}
+	params.Location = loc
+
+	if v := c.Query("to"); v != "" {
+		t, err := timezone.ParseEnd(v, loc)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid to format. Use RFC3339 or YYYY-MM-DD", nil)
+			return
+		}
+		params.To = t
+	}
+	params.From = params.To.AddDate(0, 0, -30)
+	if v := c.Query("from"); v != "" {
+		t, err := timezone.ParseStart(v, loc)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid from format. Use RFC3339 or YYYY-MM-DD", nil)
+			return
+		}
+		params.From = t
+	}
+
+	if campaignID := c.Query("campaign_id"); campaignID != "" {
+		if err := httputil.ValidateUUID(campaignID); err != nil {
+			httputil.BadRequest(c, "Invalid campaign ID", nil)
+			return
+		}
+		if err := params.CampaignID.Scan(campaignID); err != nil {
+			httputil.BadRequest(c, "Invalid campaign ID format", nil)
+			return
+		}
+	}
+
+	series, err := h.service.GetTimeseries(c.Request.Context(), params)
+	if err != nil {
+		switch {
+		case errors.Is(err, analytics.ErrInvalidMetric),
+			errors.Is(err, analytics.ErrInvalidInterval),
+			errors.Is(err, analytics.ErrInvalidRange),
+			errors.Is(err, analytics.ErrTooManyBuckets),
+			errors.Is(err, analytics.ErrInvalidFilter):
+			httputil.BadRequest(c, err.Error(), nil)
+		default:
+			httputil.InternalError(c, "Failed to fetch timeseries")
+		}
+		return
+	}
+
+	httputil.Respond(c, 200, series)
+}
+
+// GetChannelFunnel handles GET /v1/tenants/:tid/analytics/channel-funnel
+// Query: channel (default ussd), from and to (RFC3339 or a date in the
+// tenant's time zone, default the last 30 days). Reports how many sessions
+// started in the period reached each stage and where abandoned sessions
+// dropped off.
+func (h *AnalyticsHandler) GetChannelFunnel(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	loc, err := h.service.Location(c.Request.Context(), tenantUUID)
+	if err != nil {
+		httputil.InternalError(c, "Failed to fetch channel funnel")
+		return
+	}
+
+	to := time.Now().UTC()
+	if v := c.Query("to"); v != "" {
+		t, err := timezone.ParseEnd(v, loc)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid to format. Use RFC3339 or YYYY-MM-DD", nil)
+			return
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -30)
+	if v := c.Query("from"); v != "" {
+		t, err := timezone.ParseStart(v, loc)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid from format. Use RFC3339 or YYYY-MM-DD", nil)
+			return
+		}
+		from = t
+	}
+
+	funnel, err := h.service.GetChannelFunnel(c.Request.Context(), tenantUUID, c.DefaultQuery("channel", analytics.FunnelChannelUSSD), from, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, analytics.ErrInvalidChannel),
+			errors.Is(err, analytics.ErrInvalidRange):
+			httputil.BadRequest(c, err.Error(), nil)
+		default:
+			httputil.InternalError(c, "Failed to fetch channel funnel")
+		}
+		return
 	}
 
-	c.JSON(200, response)
+	httputil.Respond(c, 200, funnel)
 }
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/bmachimbira/loyalty/api/internal/approval"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ApprovalsHandler handles maker-checker approval endpoints
+type ApprovalsHandler struct {
+	pool    *pgxpool.Pool
+	service *approval.Service
+}
+
+// NewApprovalsHandler creates a new approvals handler
+func NewApprovalsHandler(pool *pgxpool.Pool, service *approval.Service) *ApprovalsHandler {
+	return &ApprovalsHandler{
+		pool:    pool,
+		service: service,
+	}
+}
+
+// SubmitApprovalRequest represents the request to submit a campaign or rule
+// for activation
+type SubmitApprovalRequest struct {
+	EntityType string `json:"entity_type" binding:"required"`
+	EntityID   string `json:"entity_id" binding:"required"`
+	Comment    string `json:"comment" binding:"required"`
+}
+
+// DecideApprovalRequest represents the request to approve or reject a change
+type DecideApprovalRequest struct {
+	Comment string `json:"comment" binding:"required"`
+}
+
+// ApprovalResponse is an approval in API responses. The decision fields are
+// set once the approval is decided.
+type ApprovalResponse struct {
+	ID              string                 `json:"id"`
+	TenantID        string                 `json:"tenant_id"`
+	EntityType      string                 `json:"entity_type"`
+	EntityID        string                 `json:"entity_id"`
+	Action          string                 `json:"action"`
+	Payload         map[string]interface{} `json:"payload"`
+	Status          string                 `json:"status"`
+	RequestedBy     string                 `json:"requested_by"`
+	RequestComment  string                 `json:"request_comment"`
+	DecidedBy       string                 `json:"decided_by,omitempty"`
+	DecisionComment string                 `json:"decision_comment,omitempty"`
+	DecidedAt       string                 `json:"decided_at,omitempty"`
+	CreatedAt       string                 `json:"created_at"`
+}
+
+// Submit handles POST /v1/tenants/:tid/approvals
+// Submits a draft campaign or an inactive rule for activation.
+func (h *ApprovalsHandler) Submit(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var req SubmitApprovalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.EntityType != approval.EntityCampaign && req.EntityType != approval.EntityRule {
+		httputil.BadRequest(c, "Invalid entity_type. Must be campaign or rule", nil)
+		return
+	}
+	if err := httputil.ValidateUUID(req.EntityID); err != nil {
+		httputil.BadRequest(c, "Invalid entity ID", nil)
+		return
+	}
+
+	userUUID, ok := reviewerID(c)
+	if !ok {
+		return
+	}
+
+	params := approval.SubmitParams{
+		EntityType:  req.EntityType,
+		Action:      approval.ActionActivate,
+		RequestedBy: userUUID,
+		Comment:     req.Comment,
+	}
+	if err := params.TenantID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+	if err := params.EntityID.Scan(req.EntityID); err != nil {
+		httputil.BadRequest(c, "Invalid entity ID format", nil)
+		return
+	}
+
+	created, err := h.service.Submit(c.Request.Context(), params)
+	if err != nil {
+		respondApprovalError(c, err, "Failed to submit for approval")
+		return
+	}
+
+	httputil.Respond(c, 201, formatApproval(created))
+}
+
+// List handles GET /v1/tenants/:tid/approvals
+func (h *ApprovalsHandler) List(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	approvals, total, err := h.service.List(
+		c.Request.Context(),
+		tenantUUID,
+		c.Query("status"),
+		c.Query("entity_type"),
+		c.DefaultQuery("limit", "50"),
+		c.DefaultQuery("offset", "0"),
+	)
+	if err != nil {
+		if errors.Is(err, approval.ErrInvalidRequest) {
+			httputil.BadRequest(c, "Invalid status or entity_type filter", nil)
+			return
+		}
+		httputil.InternalError(c, "Failed to list approvals")
+		return
+	}
+
+	approvalsList := make([]ApprovalResponse, len(approvals))
+	for i, a := range approvals {
+		approvalsList[i] = formatApproval(a)
+	}
+
+	httputil.RespondList(c, approvalsList, httputil.Page{Total: int64(total)})
+}
+
+// Get handles GET /v1/tenants/:tid/approvals/:id
+func (h *ApprovalsHandler) Get(c *gin.Context) {
+	tenantUUID, approvalUUID, ok := parseApprovalParams(c)
+	if !ok {
+		return
+	}
+
+	a, err := h.service.Get(c.Request.Context(), tenantUUID, approvalUUID)
+	if err != nil {
+		respondApprovalError(c, err, "Failed to get approval")
+		return
+	}
+
+	httputil.Respond(c, 200, formatApproval(a))
+}
+
+// Approve handles POST /v1/tenants/:tid/approvals/:id/approve
+func (h *ApprovalsHandler) Approve(c *gin.Context) {
+	h.decide(c, true)
+}
+
+// Reject handles POST /v1/tenants/:tid/approvals/:id/reject
+func (h *ApprovalsHandler) Reject(c *gin.Context) {
+	h.decide(c, false)
+}
+
+// decide records an approval decision by the authenticated staff user
+func (h *ApprovalsHandler) decide(c *gin.Context, approve bool) {
+	tenantUUID, approvalUUID, ok := parseApprovalParams(c)
+	if !ok {
+		return
+	}
+
+	var req DecideApprovalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	userUUID, ok := reviewerID(c)
+	if !ok {
+		return
+	}
+
+	var (
+		decided db.Approval
+		err     error
+	)
+	if approve {
+		decided, err = h.service.Approve(c.Request.Context(), tenantUUID, approvalUUID, userUUID, req.Comment)
+	} else {
+		decided, err = h.service.Reject(c.Request.Context(), tenantUUID, approvalUUID, userUUID, req.Comment)
+	}
+	if err != nil {
+		respondApprovalError(c, err, "Failed to record decision")
+		return
+	}
+
+	httputil.Respond(c, 200, formatApproval(decided))
+}
+
+// respondApprovalError maps approval service errors to HTTP responses
+func respondApprovalError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, approval.ErrApprovalNotFound):
+		httputil.NotFound(c, "Approval not found")
+	case errors.Is(err, approval.ErrEntityNotFound):
+		httputil.NotFound(c, "Campaign or rule not found")
+	case errors.Is(err, approval.ErrCommentRequired),
+		errors.Is(err, approval.ErrInvalidRequest):
+		httputil.BadRequest(c, err.Error(), nil)
+	case errors.Is(err, approval.ErrSelfApproval):
+		httputil.Forbidden(c, err.Error())
+	case errors.Is(err, approval.ErrNotPending),
+		errors.Is(err, approval.ErrAlreadyPending),
+		errors.Is(err, approval.ErrInvalidState):
+		httputil.Conflict(c, err.Error(), nil)
+	default:
+		httputil.InternalError(c, fallback)
+	}
+}
+
+// requiresApproval reports whether changes for the tenant must go through
+// approval. Writes an error response and returns ok=false on failure.
+func requiresApproval(c *gin.Context, service *approval.Service, tenantID pgtype.UUID) (required bool, ok bool) {
+	if service == nil {
+		return false, true
+	}
+	required, err := service.RequiresApproval(c.Request.Context(), tenantID)
+	if err != nil {
+		httputil.InternalError(c, "Failed to check approval settings")
+		return false, false
+	}
+	return required, true
+}
+
+// parseApprovalParams validates and parses the tenant and approval IDs from the path
+func parseApprovalParams(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, approvalUUID pgtype.UUID
+
+	tenantID := c.Param("tid")
+	approvalID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, approvalUUID, false
+	}
+	if err := httputil.ValidateUUID(approvalID); err != nil {
+		httputil.BadRequest(c, "Invalid approval ID", nil)
+		return tenantUUID, approvalUUID, false
+	}
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, approvalUUID, false
+	}
+	if err := approvalUUID.Scan(approvalID); err != nil {
+		httputil.BadRequest(c, "Invalid approval ID format", nil)
+		return tenantUUID, approvalUUID, false
+	}
+
+	return tenantUUID, approvalUUID, true
+}
+
+// formatApproval formats an approval for the API response
+func formatApproval(a db.Approval) ApprovalResponse {
+	var payload map[string]interface{}
+	json.Unmarshal(a.Payload, &payload)
+
+	response := ApprovalResponse{
+		ID:             formatUUID(a.ID),
+		TenantID:       formatUUID(a.TenantID),
+		EntityType:     a.EntityType,
+		EntityID:       formatUUID(a.EntityID),
+		Action:         a.Action,
+		Payload:        payload,
+		Status:         a.Status,
+		RequestedBy:    formatUUID(a.RequestedBy),
+		RequestComment: a.RequestComment,
+		CreatedAt:      formatTimestamp(a.CreatedAt),
+	}
+
+	if a.DecidedBy.Valid {
+		response.DecidedBy = formatUUID(a.DecidedBy)
+		response.DecisionComment = a.DecisionComment.String
+		response.DecidedAt = formatTimestamp(a.DecidedAt)
+	}
+
+	return response
+}
@@ -67,7 +67,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, LoginResponse{
+	httputil.Respond(c, 200, LoginResponse{
 		AccessToken:  result.AccessToken,
 		RefreshToken: result.RefreshToken,
 		ExpiresIn:    result.ExpiresIn,
@@ -96,7 +96,7 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, tokenPair)
+	httputil.Respond(c, 200, tokenPair)
 }
 
 // Me handles GET /v1/auth/me (requires authentication)
@@ -112,7 +112,7 @@ func (h *AuthHandler) Me(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, UserInfo{
+	httputil.Respond(c, 200, UserInfo{
 		ID:       userInfo.ID,
 		Email:    userInfo.Email,
 		FullName: userInfo.FullName,
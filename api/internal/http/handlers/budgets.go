@@ -1,13 +1,21 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/mail"
 	"strconv"
 	"time"
 
 	"github.com/bmachimbira/loyalty/api/internal/budget"
+	"github.com/bmachimbira/loyalty/api/internal/currency"
 	"github.com/bmachimbira/loyalty/api/internal/db"
 	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/phone"
+	"github.com/bmachimbira/loyalty/api/internal/timezone"
+	"github.com/bmachimbira/loyalty/api/internal/webhooks"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -15,28 +23,66 @@ import (
 
 // BudgetsHandler handles budget-related API endpoints
 type BudgetsHandler struct {
-	pool    *pgxpool.Pool
-	service *budget.Service
-	queries *db.Queries
+	pool        *pgxpool.Pool
+	service     *budget.Service
+	queries     *db.Queries
+	readQueries *db.Queries // listings and ledger reads, possibly on a replica
+	currencies  *currency.Checker
+	zones       *timezone.Resolver
 }
 
-// NewBudgetsHandler creates a new budgets handler
-func NewBudgetsHandler(pool *pgxpool.Pool, logger *slog.Logger) *BudgetsHandler {
+// NewBudgetsHandler creates a new budgets handler. readPool serves budget
+// listings and ledger reads and may be the same as pool.
+func NewBudgetsHandler(pool, readPool *pgxpool.Pool, logger *slog.Logger) *BudgetsHandler {
 	queries := db.New(pool)
 	return &BudgetsHandler{
-		pool:    pool,
-		service: budget.NewService(pool, queries, logger),
-		queries: queries,
+		pool:        pool,
+		service:     budget.NewService(pool, queries, logger),
+		queries:     queries,
+		readQueries: db.New(readPool),
+		currencies:  currency.NewChecker(queries),
+		zones:       timezone.NewResolver(queries),
 	}
 }
 
+// AlertReservation checks in the background whether a budget crossed its
+// alert thresholds or is drawing on its overdraft; the rules engine calls it
+// after reserving from campaign budgets
+func (h *BudgetsHandler) AlertReservation(tenantID, budgetID pgtype.UUID) {
+	h.service.AlertReservation(tenantID, budgetID)
+}
+
+// WaitForAlerts blocks until in-flight budget alert checks have finished
+func (h *BudgetsHandler) WaitForAlerts() {
+	h.service.WaitForAlerts()
+}
+
+// RegisterAlertNotifier registers the notifier used to deliver budget alerts on a channel
+func (h *BudgetsHandler) RegisterAlertNotifier(channel budget.AlertChannel, notifier budget.AlertNotifier) {
+	h.service.RegisterAlertNotifier(channel, notifier)
+}
+
 // CreateBudgetRequest represents the request to create a budget
 type CreateBudgetRequest struct {
-	Name     string  `json:"name" binding:"required"`
-	Currency string  `json:"currency" binding:"required"`
-	SoftCap  float64 `json:"soft_cap"`
-	HardCap  float64 `json:"hard_cap" binding:"required"`
-	Period   string  `json:"period"`
+	Name     string                      `json:"name" binding:"required"`
+	Currency string                      `json:"currency" binding:"required"`
+	SoftCap  float64                     `json:"soft_cap"`
+	HardCap  float64                     `json:"hard_cap" binding:"required"`
+	Period   string                      `json:"period"`
+	Alerts   *BudgetAlertSettingsRequest `json:"alerts"`
+}
+
+// BudgetAlertSettingsRequest represents per-budget alert thresholds and routing.
+// Omitted thresholds fall back to the platform defaults (80% soft, 95% hard).
+// RepeatMinutes is how long an alert that stays active waits before it is
+// sent again, 60 minutes when omitted.
+type BudgetAlertSettingsRequest struct {
+	SoftPercent    *float64 `json:"soft_percent"`
+	HardPercent    *float64 `json:"hard_percent"`
+	Email          string   `json:"email"`
+	WebhookURL     string   `json:"webhook_url"`
+	WhatsAppNumber string   `json:"whatsapp_number"`
+	RepeatMinutes  *int     `json:"repeat_minutes"`
 }
 
 // TopupBudgetRequest represents the request to topup a budget
@@ -45,6 +91,68 @@ type TopupBudgetRequest struct {
 	Description string  `json:"description"`
 }
 
+// BudgetResponse is a budget in API responses
+type BudgetResponse struct {
+	ID             string               `json:"id"`
+	TenantID       string               `json:"tenant_id"`
+	Name           string               `json:"name"`
+	Currency       string               `json:"currency"`
+	SoftCap        string               `json:"soft_cap"`
+	HardCap        string               `json:"hard_cap"`
+	OverdraftLimit string               `json:"overdraft_limit"`
+	Balance        string               `json:"balance"`
+	Period         string               `json:"period"`
+	Alerts         BudgetAlertsResponse `json:"alerts"`
+	CreatedAt      string               `json:"created_at"`
+}
+
+// BudgetAlertsResponse is a budget's effective alert thresholds and routing.
+// SoftPercent is null for budgets that alert on their soft cap alone.
+type BudgetAlertsResponse struct {
+	SoftPercent    *float64 `json:"soft_percent"`
+	HardPercent    float64  `json:"hard_percent"`
+	Email          string   `json:"email"`
+	WebhookURL     string   `json:"webhook_url"`
+	WhatsAppNumber string   `json:"whatsapp_number"`
+	RepeatMinutes  int      `json:"repeat_minutes"`
+}
+
+// BudgetAlertSettingsResponse is a budget's alert settings after an update
+type BudgetAlertSettingsResponse struct {
+	ID     string               `json:"id"`
+	Alerts BudgetAlertsResponse `json:"alerts"`
+}
+
+// BudgetOverdraftResponse is a budget's overdraft after an update
+type BudgetOverdraftResponse struct {
+	ID             string `json:"id"`
+	HardCap        string `json:"hard_cap"`
+	OverdraftLimit string `json:"overdraft_limit"`
+	Balance        string `json:"balance"`
+}
+
+// TopupBudgetResponse is the result of topping up a budget
+type TopupBudgetResponse struct {
+	BudgetID   string  `json:"budget_id"`
+	Amount     string  `json:"amount"`
+	Currency   string  `json:"currency"`
+	NewBalance float64 `json:"new_balance"`
+}
+
+// LedgerEntryResponse is a budget ledger entry in API responses
+type LedgerEntryResponse struct {
+	ID           int64  `json:"id"`
+	TenantID     string `json:"tenant_id"`
+	BudgetID     string `json:"budget_id"`
+	EntryType    string `json:"entry_type"`
+	Currency     string `json:"currency"`
+	Amount       string `json:"amount"`
+	RefType      string `json:"ref_type"`
+	RefID        string `json:"ref_id"`
+	FallbackFrom string `json:"fallback_from"`
+	CreatedAt    string `json:"created_at"`
+}
+
 // Create handles POST /v1/tenants/:tid/budgets
 func (h *BudgetsHandler) Create(c *gin.Context) {
 	tenantID := c.Param("tid")
@@ -59,12 +167,6 @@ func (h *BudgetsHandler) Create(c *gin.Context) {
 		return
 	}
 
-	// Validate currency
-	if err := httputil.ValidateCurrency(req.Currency); err != nil {
-		httputil.BadRequest(c, err.Error(), nil)
-		return
-	}
-
 	// Validate period
 	validPeriods := map[string]bool{
 		"rolling": true,
@@ -95,6 +197,11 @@ func (h *BudgetsHandler) Create(c *gin.Context) {
 		return
 	}
 
+	// Validate currency
+	if !checkCurrency(c, h.currencies, tenantUUID, req.Currency) {
+		return
+	}
+
 	// Prepare numeric values
 	var softCap, hardCap, balance pgtype.Numeric
 	if err := softCap.Scan(req.SoftCap); err != nil {
@@ -110,32 +217,39 @@ func (h *BudgetsHandler) Create(c *gin.Context) {
 		return
 	}
 
+	// Validate alert settings
+	var alerts budgetAlertSettings
+	if req.Alerts != nil {
+		var msg string
+		alerts, msg = parseBudgetAlertSettings(*req.Alerts)
+		if msg != "" {
+			httputil.BadRequest(c, msg, nil)
+			return
+		}
+	}
+
 	// Create budget using queries
 	budget, err := h.queries.CreateBudget(c.Request.Context(), db.CreateBudgetParams{
-		TenantID: tenantUUID,
-		Name:     req.Name,
-		Currency: req.Currency,
-		SoftCap:  softCap,
-		HardCap:  hardCap,
-		Balance:  balance,
-		Period:   req.Period,
+		TenantID:            tenantUUID,
+		Name:                req.Name,
+		Currency:            req.Currency,
+		SoftCap:             softCap,
+		HardCap:             hardCap,
+		Balance:             balance,
+		Period:              req.Period,
+		AlertSoftPercent:    alerts.softPercent,
+		AlertHardPercent:    alerts.hardPercent,
+		AlertEmail:          alerts.email,
+		AlertWebhookUrl:     alerts.webhookURL,
+		AlertWhatsappNumber: alerts.whatsAppNumber,
+		AlertRepeatMinutes:  alerts.repeatMinutes,
 	})
 	if err != nil {
 		httputil.InternalError(c, "Failed to create budget")
 		return
 	}
 
-	c.JSON(201, gin.H{
-		"id":         formatUUID(budget.ID),
-		"tenant_id":  formatUUID(budget.TenantID),
-		"name":       budget.Name,
-		"currency":   budget.Currency,
-		"soft_cap":   budget.SoftCap.Int.String(),
-		"hard_cap":   budget.HardCap.Int.String(),
-		"balance":    budget.Balance.Int.String(),
-		"period":     budget.Period,
-		"created_at": formatTimestamp(budget.CreatedAt),
-	})
+	httputil.Respond(c, 201, formatBudget(budget))
 }
 
 // List handles GET /v1/tenants/:tid/budgets
@@ -154,34 +268,19 @@ func (h *BudgetsHandler) List(c *gin.Context) {
 	}
 
 	// List budgets using queries
-	budgets, err := h.queries.ListBudgets(c.Request.Context(), tenantUUID)
+	budgets, err := h.readQueries.ListBudgets(c.Request.Context(), tenantUUID)
 	if err != nil {
 		httputil.InternalError(c, "Failed to list budgets")
 		return
 	}
 
 	// Format response
-	budgetsList := make([]gin.H, len(budgets))
+	budgetsList := make([]BudgetResponse, len(budgets))
 	for i, budget := range budgets {
-		budgetsList[i] = gin.H{
-			"id":         formatUUID(budget.ID),
-			"tenant_id":  formatUUID(budget.TenantID),
-			"name":       budget.Name,
-			"currency":   budget.Currency,
-			"soft_cap":   budget.SoftCap.Int.String(),
-			"hard_cap":   budget.HardCap.Int.String(),
-			"balance":    budget.Balance.Int.String(),
-			"period":     budget.Period,
-			"created_at": formatTimestamp(budget.CreatedAt),
-		}
+		budgetsList[i] = formatBudget(budget)
 	}
 
-	c.JSON(200, gin.H{
-		"data":  budgetsList,
-		"total": len(budgets),
-		"limit": 0,
-		"page":  0,
-	})
+	httputil.RespondList(c, budgetsList, httputil.Page{Total: int64(len(budgets))})
 }
 
 // Get handles GET /v1/tenants/:tid/budgets/:id
@@ -220,16 +319,113 @@ func (h *BudgetsHandler) Get(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, gin.H{
-		"id":         formatUUID(budget.ID),
-		"tenant_id":  formatUUID(budget.TenantID),
-		"name":       budget.Name,
-		"currency":   budget.Currency,
-		"soft_cap":   budget.SoftCap.Int.String(),
-		"hard_cap":   budget.HardCap.Int.String(),
-		"balance":    budget.Balance.Int.String(),
-		"period":     budget.Period,
-		"created_at": formatTimestamp(budget.CreatedAt),
+	httputil.Respond(c, 200, formatBudget(budget))
+}
+
+// UpdateAlerts handles PUT /v1/tenants/:tid/budgets/:id/alerts
+func (h *BudgetsHandler) UpdateAlerts(c *gin.Context) {
+	tenantID := c.Param("tid")
+	budgetID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	if err := httputil.ValidateUUID(budgetID); err != nil {
+		httputil.BadRequest(c, "Invalid budget ID", nil)
+		return
+	}
+
+	var req BudgetAlertSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	alerts, msg := parseBudgetAlertSettings(req)
+	if msg != "" {
+		httputil.BadRequest(c, msg, nil)
+		return
+	}
+
+	// Parse UUIDs
+	var tenantUUID, budgetUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+	if err := budgetUUID.Scan(budgetID); err != nil {
+		httputil.BadRequest(c, "Invalid budget ID format", nil)
+		return
+	}
+
+	budget, err := h.queries.UpdateBudgetAlertSettings(c.Request.Context(), db.UpdateBudgetAlertSettingsParams{
+		ID:                  budgetUUID,
+		TenantID:            tenantUUID,
+		AlertSoftPercent:    alerts.softPercent,
+		AlertHardPercent:    alerts.hardPercent,
+		AlertEmail:          alerts.email,
+		AlertWebhookUrl:     alerts.webhookURL,
+		AlertWhatsappNumber: alerts.whatsAppNumber,
+		AlertRepeatMinutes:  alerts.repeatMinutes,
+	})
+	if err != nil {
+		httputil.NotFound(c, "Budget not found")
+		return
+	}
+
+	httputil.Respond(c, 200, BudgetAlertSettingsResponse{
+		ID:     formatUUID(budget.ID),
+		Alerts: formatBudgetAlerts(budget),
+	})
+}
+
+// UpdateOverdraftRequest represents the request to set a budget's overdraft
+type UpdateOverdraftRequest struct {
+	OverdraftLimit *float64 `json:"overdraft_limit" binding:"required"`
+}
+
+// UpdateOverdraft handles PUT /v1/tenants/:tid/budgets/:id/overdraft
+// Sets how far reservations for triggered rewards may take the budget past
+// its hard cap; 0 makes the hard cap strict.
+func (h *BudgetsHandler) UpdateOverdraft(c *gin.Context) {
+	tenantUUID, budgetUUID, ok := parseBudgetParams(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateOverdraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+	if *req.OverdraftLimit < 0 {
+		httputil.BadRequest(c, "Overdraft limit cannot be negative", nil)
+		return
+	}
+
+	var limit pgtype.Numeric
+	if err := limit.Scan(strconv.FormatFloat(*req.OverdraftLimit, 'f', 2, 64)); err != nil {
+		httputil.BadRequest(c, "Invalid overdraft limit value", nil)
+		return
+	}
+
+	budget, err := h.queries.UpdateBudgetOverdraft(c.Request.Context(), db.UpdateBudgetOverdraftParams{
+		ID:             budgetUUID,
+		TenantID:       tenantUUID,
+		OverdraftLimit: limit,
+	})
+	if err != nil {
+		httputil.NotFound(c, "Budget not found")
+		return
+	}
+
+	httputil.Respond(c, 200, BudgetOverdraftResponse{
+		ID:             formatUUID(budget.ID),
+		HardCap:        formatNumeric(budget.HardCap),
+		OverdraftLimit: formatNumeric(budget.OverdraftLimit),
+		Balance:        formatNumeric(budget.Balance),
 	})
 }
 
@@ -292,11 +488,11 @@ func (h *BudgetsHandler) Topup(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, gin.H{
-		"budget_id":   formatUUID(result.BudgetID),
-		"amount":      result.Amount,
-		"currency":    result.Currency,
-		"new_balance": result.NewBalance,
+	httputil.Respond(c, 200, TopupBudgetResponse{
+		BudgetID:   formatUUID(result.BudgetID),
+		Amount:     result.Amount,
+		Currency:   result.Currency,
+		NewBalance: result.NewBalance,
 	})
 }
 
@@ -366,7 +562,7 @@ func (h *BudgetsHandler) ListLedger(c *gin.Context) {
 			return
 		}
 
-		entries, err = h.queries.GetLedgerEntries(c.Request.Context(), db.GetLedgerEntriesParams{
+		entries, err = h.readQueries.GetLedgerEntries(c.Request.Context(), db.GetLedgerEntriesParams{
 			TenantID:    tenantUUID,
 			BudgetID:    budgetUUID,
 			CreatedAt:   pgtype.Timestamptz{Time: fromTime, Valid: true},
@@ -376,7 +572,7 @@ func (h *BudgetsHandler) ListLedger(c *gin.Context) {
 		})
 	} else {
 		// Otherwise use GetLedgerEntriesByDateRangeOnly (no date filter)
-		entries, err = h.queries.GetLedgerEntriesByDateRangeOnly(c.Request.Context(), db.GetLedgerEntriesByDateRangeOnlyParams{
+		entries, err = h.readQueries.GetLedgerEntriesByDateRangeOnly(c.Request.Context(), db.GetLedgerEntriesByDateRangeOnlyParams{
 			TenantID: tenantUUID,
 			BudgetID: budgetUUID,
 			Limit:    int32(limitInt),
@@ -390,30 +586,672 @@ func (h *BudgetsHandler) ListLedger(c *gin.Context) {
 	}
 
 	// Format response
-	entriesList := make([]gin.H, len(entries))
+	entriesList := make([]LedgerEntryResponse, len(entries))
 	for i, entry := range entries {
-		entriesList[i] = gin.H{
-			"id":          entry.ID,
-			"tenant_id":   formatUUID(entry.TenantID),
-			"budget_id":   formatUUID(entry.BudgetID),
-			"entry_type":  entry.EntryType,
-			"currency":    entry.Currency,
-			"amount":      entry.Amount.Int.String(),
-			"ref_type":    entry.RefType.String,
-			"ref_id":      formatUUID(entry.RefID),
-			"created_at":  formatTimestamp(entry.CreatedAt),
+		entriesList[i] = LedgerEntryResponse{
+			ID:           entry.ID,
+			TenantID:     formatUUID(entry.TenantID),
+			BudgetID:     formatUUID(entry.BudgetID),
+			EntryType:    entry.EntryType,
+			Currency:     entry.Currency,
+			Amount:       formatNumeric(entry.Amount),
+			RefType:      entry.RefType.String,
+			RefID:        formatUUID(entry.RefID),
+			FallbackFrom: formatUUID(entry.FallbackFrom),
+			CreatedAt:    formatTimestamp(entry.CreatedAt),
 		}
 	}
 
-	c.JSON(200, gin.H{
-		"entries": entriesList,
-		"total":   len(entries),
-		"limit":   limit,
-		"offset":  offset,
-		"filters": gin.H{
-			"budget_id": budgetID,
-			"from":      fromDate,
-			"to":        toDate,
-		},
-	})
+	httputil.RespondList(c, entriesList, httputil.NewPage(int64(len(entries)), limit, offset))
+}
+
+// budgetAlertSettings holds validated alert settings ready for the database
+type budgetAlertSettings struct {
+	softPercent    pgtype.Numeric
+	hardPercent    pgtype.Numeric
+	email          pgtype.Text
+	webhookURL     pgtype.Text
+	whatsAppNumber pgtype.Text
+	repeatMinutes  pgtype.Int4
+}
+
+// parseBudgetAlertSettings validates an alert settings request.
+// It returns a non-empty message describing the first validation failure.
+func parseBudgetAlertSettings(req BudgetAlertSettingsRequest) (budgetAlertSettings, string) {
+	var settings budgetAlertSettings
+
+	if req.SoftPercent != nil {
+		if *req.SoftPercent <= 0 || *req.SoftPercent > 100 {
+			return settings, "Soft alert percent must be between 0 and 100"
+		}
+		if err := settings.softPercent.Scan(strconv.FormatFloat(*req.SoftPercent, 'f', 2, 64)); err != nil {
+			return settings, "Invalid soft alert percent"
+		}
+	}
+	if req.HardPercent != nil {
+		if *req.HardPercent <= 0 || *req.HardPercent > 100 {
+			return settings, "Hard alert percent must be between 0 and 100"
+		}
+		if err := settings.hardPercent.Scan(strconv.FormatFloat(*req.HardPercent, 'f', 2, 64)); err != nil {
+			return settings, "Invalid hard alert percent"
+		}
+	}
+
+	// Compare against defaults when only one threshold is supplied
+	soft, hard := budget.DefaultAlertThresholds().SoftCapPercent, budget.DefaultAlertThresholds().HardCapPercent
+	if req.SoftPercent != nil {
+		soft = *req.SoftPercent
+	}
+	if req.HardPercent != nil {
+		hard = *req.HardPercent
+	}
+	if soft > hard {
+		return settings, "Soft alert percent cannot exceed hard alert percent"
+	}
+
+	if req.Email != "" {
+		if _, err := mail.ParseAddress(req.Email); err != nil {
+			return settings, "Invalid alert email address"
+		}
+		settings.email = pgtype.Text{String: req.Email, Valid: true}
+	}
+
+	if req.WebhookURL != "" {
+		switch err := webhooks.ValidateURL(req.WebhookURL); {
+		case errors.Is(err, webhooks.ErrPrivateURL):
+			return settings, "Alert webhook URL must not point at a local or private host"
+		case err != nil:
+			return settings, "Alert webhook URL must be an absolute http(s) URL"
+		}
+		settings.webhookURL = pgtype.Text{String: req.WebhookURL, Valid: true}
+	}
+
+	if req.WhatsAppNumber != "" {
+		// Alert numbers are read in international format, + optional
+		number, err := phone.FromWhatsAppID(req.WhatsAppNumber)
+		if err != nil {
+			return settings, "Alert WhatsApp number must be a phone number with its country code"
+		}
+		settings.whatsAppNumber = pgtype.Text{String: number, Valid: true}
+	}
+
+	if req.RepeatMinutes != nil {
+		if *req.RepeatMinutes <= 0 || *req.RepeatMinutes > 7*24*60 {
+			return settings, "Alert repeat minutes must be between 1 and 10080"
+		}
+		settings.repeatMinutes = pgtype.Int4{Int32: int32(*req.RepeatMinutes), Valid: true}
+	}
+
+	return settings, ""
+}
+
+// formatBudget formats a budget for the API response
+func formatBudget(b db.Budget) BudgetResponse {
+	return BudgetResponse{
+		ID:             formatUUID(b.ID),
+		TenantID:       formatUUID(b.TenantID),
+		Name:           b.Name,
+		Currency:       b.Currency,
+		SoftCap:        formatNumeric(b.SoftCap),
+		HardCap:        formatNumeric(b.HardCap),
+		OverdraftLimit: formatNumeric(b.OverdraftLimit),
+		Balance:        formatNumeric(b.Balance),
+		Period:         b.Period,
+		Alerts:         formatBudgetAlerts(b),
+		CreatedAt:      formatTimestamp(b.CreatedAt),
+	}
+}
+
+// formatBudgetAlerts formats the effective alert thresholds and routing of a
+// budget
+func formatBudgetAlerts(b db.Budget) BudgetAlertsResponse {
+	thresholds := budget.ThresholdsForBudget(b)
+	var softPercent *float64
+	if b.AlertSoftPercent.Valid {
+		softPercent = &thresholds.SoftCapPercent
+	}
+	return BudgetAlertsResponse{
+		SoftPercent:    softPercent,
+		HardPercent:    thresholds.HardCapPercent,
+		Email:          b.AlertEmail.String,
+		WebhookURL:     b.AlertWebhookUrl.String,
+		WhatsAppNumber: b.AlertWhatsappNumber.String,
+		RepeatMinutes:  int(budget.RepeatIntervalForBudget(b) / time.Minute),
+	}
+}
+
+// CloseStatementRequest represents the request to close a budget period
+type CloseStatementRequest struct {
+	// PeriodEnd defaults to now. A date ends the period at midnight in the
+	// tenant's time zone.
+	PeriodEnd *string `json:"period_end"`
+}
+
+// RunPeriodCloseWorker closes elapsed monthly, quarterly and yearly budget
+// periods on a schedule until ctx is cancelled
+func (h *BudgetsHandler) RunPeriodCloseWorker(ctx context.Context) error {
+	return h.service.RunPeriodCloseWorker(ctx, time.Hour)
+}
+
+// RunAlertResolveWorker resolves budget alerts whose budgets have dropped
+// back below their thresholds on a schedule until ctx is cancelled
+func (h *BudgetsHandler) RunAlertResolveWorker(ctx context.Context) error {
+	return h.service.RunAlertResolveWorker(ctx, 5*time.Minute)
+}
+
+// CloseStatement handles POST /v1/tenants/:tid/budgets/:id/statements
+// Closes the open period and generates its statement.
+func (h *BudgetsHandler) CloseStatement(c *gin.Context) {
+	tenantUUID, budgetUUID, ok := parseBudgetParams(c)
+	if !ok {
+		return
+	}
+
+	var req CloseStatementRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httputil.BadRequest(c, "Invalid request body", err.Error())
+			return
+		}
+	}
+
+	periodEnd := time.Now()
+	if req.PeriodEnd != nil {
+		loc, err := h.zones.Location(c.Request.Context(), tenantUUID)
+		if err != nil {
+			httputil.InternalError(c, "Failed to get tenant timezone")
+			return
+		}
+		t, err := timezone.ParseStart(*req.PeriodEnd, loc)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid period_end format", nil)
+			return
+		}
+		periodEnd = t
+	}
+
+	params := budget.ClosePeriodParams{
+		TenantID:  tenantUUID,
+		BudgetID:  budgetUUID,
+		PeriodEnd: periodEnd,
+	}
+	if userID, exists := c.Get("user_id"); exists {
+		params.ClosedBy.Scan(userID.(string))
+	}
+
+	statement, err := h.service.ClosePeriod(c.Request.Context(), params)
+	if err != nil {
+		switch {
+		case errors.Is(err, budget.ErrBudgetNotFound):
+			httputil.NotFound(c, "Budget not found")
+		case errors.Is(err, budget.ErrInvalidPeriod):
+			httputil.BadRequest(c, "period_end must be after the open period's start and not in the future", nil)
+		case errors.Is(err, budget.ErrPeriodAlreadyClosed):
+			httputil.Conflict(c, "Budget period already closed", nil)
+		default:
+			httputil.InternalError(c, "Failed to close budget period")
+		}
+		return
+	}
+
+	httputil.Respond(c, 201, statement)
+}
+
+// ListStatements handles GET /v1/tenants/:tid/budgets/:id/statements
+func (h *BudgetsHandler) ListStatements(c *gin.Context) {
+	tenantUUID, budgetUUID, ok := parseBudgetParams(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	statements, total, err := h.service.ListStatements(c.Request.Context(), tenantUUID, budgetUUID, int32(limit), int32(offset))
+	if err != nil {
+		httputil.InternalError(c, "Failed to list budget statements")
+		return
+	}
+
+	httputil.RespondList(c, statements, httputil.Page{Total: int64(total)})
+}
+
+// GetStatement handles GET /v1/tenants/:tid/budgets/:id/statements/:sid
+// Returns the statement as JSON, or as a CSV download with ?format=csv.
+func (h *BudgetsHandler) GetStatement(c *gin.Context) {
+	tenantUUID, budgetUUID, ok := parseBudgetParams(c)
+	if !ok {
+		return
+	}
+
+	statementID := c.Param("sid")
+	if err := httputil.ValidateUUID(statementID); err != nil {
+		httputil.BadRequest(c, "Invalid statement ID", nil)
+		return
+	}
+	var statementUUID pgtype.UUID
+	if err := statementUUID.Scan(statementID); err != nil {
+		httputil.BadRequest(c, "Invalid statement ID format", nil)
+		return
+	}
+
+	statement, err := h.service.GetStatement(c.Request.Context(), tenantUUID, budgetUUID, statementUUID)
+	if err != nil {
+		if errors.Is(err, budget.ErrStatementNotFound) {
+			httputil.NotFound(c, "Budget statement not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get budget statement")
+		return
+	}
+
+	switch budget.ReportFormat(c.DefaultQuery("format", string(budget.FormatJSON))) {
+	case budget.FormatJSON:
+		httputil.Respond(c, 200, statement)
+	case budget.FormatCSV:
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=statement-%s.csv", statement.ID))
+		if err := h.service.ExportStatementCSV(statement, c.Writer); err != nil {
+			c.Error(err)
+		}
+	default:
+		httputil.BadRequest(c, "Invalid format. Must be json or csv", nil)
+	}
+}
+
+// SpendBreakdown handles GET /v1/tenants/:tid/budgets/:id/spend-breakdown
+// Attributes the budget's spend to campaigns, rules or rewards
+// (?group_by=campaign|rule|reward), optionally within ?from= and ?to= (RFC3339).
+func (h *BudgetsHandler) SpendBreakdown(c *gin.Context) {
+	tenantUUID, budgetUUID, ok := parseBudgetParams(c)
+	if !ok {
+		return
+	}
+
+	var from, to *time.Time
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid from date format", nil)
+			return
+		}
+		from = &t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid to date format", nil)
+			return
+		}
+		to = &t
+	}
+
+	groupBy := c.DefaultQuery("group_by", budget.SpendByCampaign)
+	breakdown, err := h.service.GetSpendBreakdown(c.Request.Context(), tenantUUID, budgetUUID, groupBy, from, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, budget.ErrInvalidSpendGroup):
+			httputil.BadRequest(c, err.Error(), nil)
+		case errors.Is(err, budget.ErrBudgetNotFound):
+			httputil.NotFound(c, "Budget not found")
+		default:
+			httputil.InternalError(c, "Failed to get spend breakdown")
+		}
+		return
+	}
+
+	httputil.Respond(c, 200, breakdown)
+}
+
+// VerifyLedger handles GET /v1/tenants/:tid/budgets/:id/ledger/verify
+// Recomputes the hash chain of the budget's ledger entries and reports
+// whether any entry was changed or removed.
+func (h *BudgetsHandler) VerifyLedger(c *gin.Context) {
+	tenantUUID, budgetUUID, ok := parseBudgetParams(c)
+	if !ok {
+		return
+	}
+
+	verification, err := h.service.VerifyLedgerChain(c.Request.Context(), tenantUUID, budgetUUID)
+	if err != nil {
+		if errors.Is(err, budget.ErrBudgetNotFound) {
+			httputil.NotFound(c, "Budget not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to verify ledger")
+		return
+	}
+
+	httputil.Respond(c, 200, verification)
+}
+
+// parseBudgetParams validates and parses the tenant and budget IDs from the path
+func parseBudgetParams(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, budgetUUID pgtype.UUID
+
+	tenantID := c.Param("tid")
+	budgetID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, budgetUUID, false
+	}
+	if err := httputil.ValidateUUID(budgetID); err != nil {
+		httputil.BadRequest(c, "Invalid budget ID", nil)
+		return tenantUUID, budgetUUID, false
+	}
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, budgetUUID, false
+	}
+	if err := budgetUUID.Scan(budgetID); err != nil {
+		httputil.BadRequest(c, "Invalid budget ID format", nil)
+		return tenantUUID, budgetUUID, false
+	}
+
+	return tenantUUID, budgetUUID, true
+}
+
+// Liabilities handles GET /v1/tenants/:tid/liabilities
+// Reports the face value of outstanding rewards by reward type, optionally
+// as of a past time (?as_of=RFC3339).
+func (h *BudgetsHandler) Liabilities(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	asOf := time.Now()
+	if v := c.Query("as_of"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid as_of format", nil)
+			return
+		}
+		asOf = t
+	}
+
+	report, err := h.service.GetLiabilityReport(c.Request.Context(), tenantUUID, asOf)
+	if err != nil {
+		httputil.InternalError(c, "Failed to get liability report")
+		return
+	}
+
+	httputil.Respond(c, 200, report)
+}
+
+// CommitBudgetRequest represents the request to earmark budget for a campaign
+type CommitBudgetRequest struct {
+	CampaignID string  `json:"campaign_id" binding:"required"`
+	Amount     float64 `json:"amount" binding:"required"`
+	Note       string  `json:"note"`
+}
+
+// CreateCommitment handles POST /v1/tenants/:tid/budgets/:id/commitments
+// Ring-fences an amount of the budget for a scheduled campaign. The
+// campaign's reservations against the budget draw the commitment down.
+func (h *BudgetsHandler) CreateCommitment(c *gin.Context) {
+	tenantUUID, budgetUUID, ok := parseBudgetParams(c)
+	if !ok {
+		return
+	}
+
+	var req CommitBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.Amount <= 0 {
+		httputil.BadRequest(c, "Amount must be greater than 0", nil)
+		return
+	}
+
+	if err := httputil.ValidateUUID(req.CampaignID); err != nil {
+		httputil.BadRequest(c, "Invalid campaign ID", nil)
+		return
+	}
+	var campaignUUID pgtype.UUID
+	if err := campaignUUID.Scan(req.CampaignID); err != nil {
+		httputil.BadRequest(c, "Invalid campaign ID format", nil)
+		return
+	}
+
+	params := budget.CommitBudgetParams{
+		TenantID:   tenantUUID,
+		BudgetID:   budgetUUID,
+		CampaignID: campaignUUID,
+		Amount:     strconv.FormatFloat(req.Amount, 'f', 2, 64),
+		Note:       req.Note,
+	}
+	if userID, exists := c.Get("user_id"); exists {
+		params.CreatedBy.Scan(userID.(string))
+	}
+
+	commitment, err := h.service.CommitBudget(c.Request.Context(), params)
+	if err != nil {
+		switch {
+		case errors.Is(err, budget.ErrBudgetNotFound):
+			httputil.NotFound(c, "Budget not found")
+		case errors.Is(err, budget.ErrCampaignNotOnBudget):
+			httputil.BadRequest(c, "Campaign does not reserve from this budget", nil)
+		case errors.Is(err, budget.ErrInsufficientFunds):
+			httputil.Conflict(c, "Commitment would exceed the budget's hard cap", nil)
+		default:
+			httputil.InternalError(c, "Failed to commit budget")
+		}
+		return
+	}
+
+	httputil.Respond(c, 201, commitment)
+}
+
+// ListCommitments handles GET /v1/tenants/:tid/budgets/:id/commitments
+// Optionally filtered by ?status=open|converted|released.
+func (h *BudgetsHandler) ListCommitments(c *gin.Context) {
+	tenantUUID, budgetUUID, ok := parseBudgetParams(c)
+	if !ok {
+		return
+	}
+
+	status := c.Query("status")
+	if status != "" && !budget.IsValidCommitmentStatus(status) {
+		httputil.BadRequest(c, "Invalid status. Must be open, converted or released", nil)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	commitments, total, err := h.service.ListCommitments(c.Request.Context(), tenantUUID, budgetUUID, status, int32(limit), int32(offset))
+	if err != nil {
+		httputil.InternalError(c, "Failed to list budget commitments")
+		return
+	}
+
+	httputil.RespondList(c, commitments, httputil.Page{Total: total, Limit: limit, Offset: offset})
+}
+
+// ReleaseCommitment handles POST /v1/tenants/:tid/budgets/:id/commitments/:cid/release
+// Returns what is left of the commitment to the budget, e.g. when its
+// campaign is cancelled.
+func (h *BudgetsHandler) ReleaseCommitment(c *gin.Context) {
+	tenantUUID, budgetUUID, ok := parseBudgetParams(c)
+	if !ok {
+		return
+	}
+
+	commitmentID := c.Param("cid")
+	if err := httputil.ValidateUUID(commitmentID); err != nil {
+		httputil.BadRequest(c, "Invalid commitment ID", nil)
+		return
+	}
+	var commitmentUUID pgtype.UUID
+	if err := commitmentUUID.Scan(commitmentID); err != nil {
+		httputil.BadRequest(c, "Invalid commitment ID format", nil)
+		return
+	}
+
+	var releasedBy pgtype.UUID
+	if userID, exists := c.Get("user_id"); exists {
+		releasedBy.Scan(userID.(string))
+	}
+
+	commitment, err := h.service.ReleaseCommitment(c.Request.Context(), tenantUUID, budgetUUID, commitmentUUID, releasedBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, budget.ErrCommitmentNotFound):
+			httputil.NotFound(c, "Budget commitment not found")
+		case errors.Is(err, budget.ErrCommitmentClosed):
+			httputil.Conflict(c, "Budget commitment is not open", nil)
+		default:
+			httputil.InternalError(c, "Failed to release budget commitment")
+		}
+		return
+	}
+
+	httputil.Respond(c, 200, commitment)
+}
+
+// AcknowledgeBudgetAlertRequest represents the request to acknowledge a
+// budget alert
+type AcknowledgeBudgetAlertRequest struct {
+	Note string `json:"note"`
+}
+
+// ListAlerts handles GET /v1/tenants/:tid/budget-alerts
+// Lists sent budget alerts, newest first. Optionally filtered by
+// ?budget_id=, ?level=info|warning|critical and ?acknowledged=true|false.
+func (h *BudgetsHandler) ListAlerts(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var params budget.ListAlertsParams
+	if err := params.TenantID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	if budgetID := c.Query("budget_id"); budgetID != "" {
+		if err := httputil.ValidateUUID(budgetID); err != nil {
+			httputil.BadRequest(c, "Invalid budget ID", nil)
+			return
+		}
+		if err := params.BudgetID.Scan(budgetID); err != nil {
+			httputil.BadRequest(c, "Invalid budget ID format", nil)
+			return
+		}
+	}
+
+	params.Level = c.Query("level")
+	if params.Level != "" && !budget.IsValidAlertLevel(params.Level) {
+		httputil.BadRequest(c, "Invalid level. Must be info, warning or critical", nil)
+		return
+	}
+
+	if v := c.Query("acknowledged"); v != "" {
+		acknowledged, err := strconv.ParseBool(v)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid acknowledged filter. Must be true or false", nil)
+			return
+		}
+		params.Acknowledged = pgtype.Bool{Bool: acknowledged, Valid: true}
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	params.Limit, params.Offset = int32(limit), int32(offset)
+
+	alerts, total, err := h.service.ListAlerts(c.Request.Context(), params)
+	if err != nil {
+		httputil.InternalError(c, "Failed to list budget alerts")
+		return
+	}
+
+	httputil.RespondList(c, alerts, httputil.Page{Total: total, Limit: limit, Offset: offset})
+}
+
+// AcknowledgeAlert handles POST /v1/tenants/:tid/budget-alerts/:id/acknowledge
+// Records that the authenticated staff user handled the alert, with an
+// optional note.
+func (h *BudgetsHandler) AcknowledgeAlert(c *gin.Context) {
+	tenantID := c.Param("tid")
+	alertID := c.Param("id")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+	if err := httputil.ValidateUUID(alertID); err != nil {
+		httputil.BadRequest(c, "Invalid alert ID", nil)
+		return
+	}
+	var tenantUUID, alertUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+	if err := alertUUID.Scan(alertID); err != nil {
+		httputil.BadRequest(c, "Invalid alert ID format", nil)
+		return
+	}
+
+	var req AcknowledgeBudgetAlertRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httputil.BadRequest(c, "Invalid request body", err.Error())
+			return
+		}
+	}
+	if len(req.Note) > 2000 {
+		httputil.BadRequest(c, "Note must be at most 2000 characters", nil)
+		return
+	}
+
+	userUUID, ok := reviewerID(c)
+	if !ok {
+		return
+	}
+
+	alert, err := h.service.AcknowledgeAlert(c.Request.Context(), tenantUUID, alertUUID, userUUID, req.Note)
+	if err != nil {
+		switch {
+		case errors.Is(err, budget.ErrAlertNotFound):
+			httputil.NotFound(c, "Budget alert not found")
+		case errors.Is(err, budget.ErrAlertAcknowledged):
+			httputil.Conflict(c, "Budget alert already acknowledged", nil)
+		default:
+			httputil.InternalError(c, "Failed to acknowledge budget alert")
+		}
+		return
+	}
+
+	httputil.Respond(c, 200, alert)
 }
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/campaign"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ExclusionUploadItem is a single customer in a JSON exclusion upload,
+// identified by exactly one of its fields
+type ExclusionUploadItem struct {
+	CustomerID  string `json:"customer_id"`
+	Phone       string `json:"phone"`
+	ExternalRef string `json:"external_ref"`
+}
+
+// UploadExclusionsRequest represents a JSON exclusion upload
+type UploadExclusionsRequest struct {
+	Customers []ExclusionUploadItem `json:"customers" binding:"required"`
+	Reason    string                `json:"reason"`
+}
+
+// ExclusionUploadResponse is the result of an exclusion upload
+type ExclusionUploadResponse struct {
+	Excluded  int      `json:"excluded"`
+	Unmatched []string `json:"unmatched"`
+}
+
+// ExclusionResponse is a customer excluded from a campaign
+type ExclusionResponse struct {
+	CustomerID  string `json:"customer_id"`
+	PhoneE164   string `json:"phone_e164"`
+	ExternalRef string `json:"external_ref"`
+	Reason      string `json:"reason"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// ExclusionRemovedResponse confirms that an exclusion was removed
+type ExclusionRemovedResponse struct {
+	Message string `json:"message"`
+}
+
+// UploadExclusions handles POST /v1/tenants/:tid/campaigns/:id/exclusions
+// Accepts either a JSON batch or a multipart CSV file ("file", with an
+// optional "reason" field) with the columns customer_id, phone, external_ref,
+// one of which is set per row. Customers that match nothing are reported
+// back rather than failing the upload.
+func (h *CampaignsHandler) UploadExclusions(c *gin.Context) {
+	tenantUUID, campaignUUID, ok := parseCampaignParams(c)
+	if !ok {
+		return
+	}
+
+	var items []ExclusionUploadItem
+	var reason string
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			httputil.BadRequest(c, "CSV file is required", nil)
+			return
+		}
+
+		fileHandle, err := file.Open()
+		if err != nil {
+			httputil.InternalError(c, "Failed to open file")
+			return
+		}
+		defer fileHandle.Close()
+
+		items, err = parseExclusionCSV(fileHandle)
+		if err != nil {
+			httputil.BadRequest(c, "Failed to parse CSV file", err.Error())
+			return
+		}
+		reason = c.PostForm("reason")
+	} else {
+		var req UploadExclusionsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httputil.BadRequest(c, "Invalid request body", err.Error())
+			return
+		}
+		items = req.Customers
+		reason = req.Reason
+	}
+
+	if len(items) == 0 {
+		httputil.BadRequest(c, "No customers to exclude", nil)
+		return
+	}
+	if len(items) > campaign.MaxExclusionRows {
+		httputil.BadRequest(c, fmt.Sprintf("Batch exceeds %d rows", campaign.MaxExclusionRows), nil)
+		return
+	}
+
+	entries := make([]campaign.ExclusionEntry, len(items))
+	for i, item := range items {
+		entries[i] = campaign.ExclusionEntry{
+			Phone:       strings.TrimSpace(item.Phone),
+			ExternalRef: strings.TrimSpace(item.ExternalRef),
+		}
+		if id := strings.TrimSpace(item.CustomerID); id != "" {
+			if err := httputil.ValidateUUID(id); err != nil {
+				httputil.BadRequest(c, "Invalid customer ID", gin.H{"row": i + 1})
+				return
+			}
+			if err := entries[i].CustomerID.Scan(id); err != nil {
+				httputil.BadRequest(c, "Invalid customer ID format", gin.H{"row": i + 1})
+				return
+			}
+		}
+	}
+
+	result, err := h.service.AddExclusions(c.Request.Context(), tenantUUID, campaignUUID, entries, strings.TrimSpace(reason))
+	if err != nil {
+		switch {
+		case errors.Is(err, campaign.ErrCampaignNotFound):
+			httputil.NotFound(c, "Campaign not found")
+		case errors.Is(err, campaign.ErrInvalidExclusion):
+			httputil.BadRequest(c, err.Error(), nil)
+		default:
+			httputil.InternalError(c, "Failed to exclude customers")
+		}
+		return
+	}
+
+	httputil.Respond(c, 200, ExclusionUploadResponse{
+		Excluded:  result.Excluded,
+		Unmatched: result.Unmatched,
+	})
+}
+
+// ListExclusions handles GET /v1/tenants/:tid/campaigns/:id/exclusions
+func (h *CampaignsHandler) ListExclusions(c *gin.Context) {
+	tenantUUID, campaignUUID, ok := parseCampaignParams(c)
+	if !ok {
+		return
+	}
+
+	limit := c.DefaultQuery("limit", "50")
+	offset := c.DefaultQuery("offset", "0")
+
+	exclusions, total, err := h.service.ListExclusions(c.Request.Context(), tenantUUID, campaignUUID, limit, offset)
+	if err != nil {
+		httputil.InternalError(c, "Failed to list exclusions")
+		return
+	}
+
+	exclusionsList := make([]ExclusionResponse, len(exclusions))
+	for i, e := range exclusions {
+		exclusionsList[i] = formatExclusion(e)
+	}
+
+	httputil.RespondList(c, exclusionsList, httputil.NewPage(total, limit, offset))
+}
+
+// RemoveExclusion handles DELETE /v1/tenants/:tid/campaigns/:id/exclusions/:cid
+func (h *CampaignsHandler) RemoveExclusion(c *gin.Context) {
+	tenantUUID, campaignUUID, ok := parseCampaignParams(c)
+	if !ok {
+		return
+	}
+
+	customerID := c.Param("cid")
+	if err := httputil.ValidateUUID(customerID); err != nil {
+		httputil.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+	var customerUUID pgtype.UUID
+	if err := customerUUID.Scan(customerID); err != nil {
+		httputil.BadRequest(c, "Invalid customer ID format", nil)
+		return
+	}
+
+	if err := h.service.RemoveExclusion(c.Request.Context(), tenantUUID, campaignUUID, customerUUID); err != nil {
+		if errors.Is(err, campaign.ErrExclusionNotFound) {
+			httputil.NotFound(c, "Exclusion not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to remove exclusion")
+		return
+	}
+
+	httputil.Respond(c, 200, ExclusionRemovedResponse{Message: "Exclusion removed"})
+}
+
+// parseExclusionCSV parses an exclusion CSV with the columns customer_id,
+// phone, external_ref. A header row is optional.
+func parseExclusionCSV(r io.Reader) ([]ExclusionUploadItem, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var items []ExclusionUploadItem
+	line := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line++
+
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "customer_id") {
+			continue
+		}
+
+		var item ExclusionUploadItem
+		if len(record) > 0 {
+			item.CustomerID = strings.TrimSpace(record[0])
+		}
+		if len(record) > 1 {
+			item.Phone = strings.TrimSpace(record[1])
+		}
+		if len(record) > 2 {
+			item.ExternalRef = strings.TrimSpace(record[2])
+		}
+		if item == (ExclusionUploadItem{}) {
+			continue
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func formatExclusion(e db.ListCampaignExclusionsRow) ExclusionResponse {
+	return ExclusionResponse{
+		CustomerID:  formatUUID(e.CustomerID),
+		PhoneE164:   e.PhoneE164.String,
+		ExternalRef: e.ExternalRef.String,
+		Reason:      e.Reason.String,
+		CreatedAt:   formatTimestamp(e.CreatedAt),
+	}
+}
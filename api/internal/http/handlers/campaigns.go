@@ -1,11 +1,19 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"strconv"
 	"time"
 
+	"github.com/bmachimbira/loyalty/api/internal/approval"
 	"github.com/bmachimbira/loyalty/api/internal/campaign"
+	"github.com/bmachimbira/loyalty/api/internal/catalogcache"
 	"github.com/bmachimbira/loyalty/api/internal/db"
 	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/timezone"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -13,19 +21,62 @@ import (
 
 // CampaignsHandler handles campaign-related API endpoints
 type CampaignsHandler struct {
-	pool    *pgxpool.Pool
-	service *campaign.Service
+	pool      *pgxpool.Pool
+	service   *campaign.Service
+	zones     *timezone.Resolver
+	approvals *approval.Service
 }
 
 // NewCampaignsHandler creates a new campaigns handler
-func NewCampaignsHandler(pool *pgxpool.Pool) *CampaignsHandler {
+func NewCampaignsHandler(pool *pgxpool.Pool, catalog *catalogcache.Cache) *CampaignsHandler {
 	queries := db.New(pool)
 	return &CampaignsHandler{
 		pool:    pool,
-		service: campaign.NewService(queries),
+		service: campaign.NewService(pool, queries, catalog),
+		zones:   timezone.NewResolver(queries),
 	}
 }
 
+// parseWindow parses a campaign's start_at and end_at. Either may be an
+// RFC 3339 time or a date in the tenant's time zone: a start date begins at
+// local midnight and an end date runs to the end of that day. It writes the
+// error response and returns false on failure.
+func (h *CampaignsHandler) parseWindow(c *gin.Context, tenantID pgtype.UUID, start, end *string) (startAt, endAt pgtype.Timestamptz, ok bool) {
+	if start == nil && end == nil {
+		return startAt, endAt, true
+	}
+
+	loc, err := h.zones.Location(c.Request.Context(), tenantID)
+	if err != nil {
+		httputil.InternalError(c, "Failed to get tenant timezone")
+		return startAt, endAt, false
+	}
+
+	if start != nil {
+		t, err := timezone.ParseStart(*start, loc)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid start_at format", err.Error())
+			return startAt, endAt, false
+		}
+		startAt = pgtype.Timestamptz{Time: t, Valid: true}
+	}
+	if end != nil {
+		t, err := timezone.ParseEnd(*end, loc)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid end_at format", err.Error())
+			return startAt, endAt, false
+		}
+		endAt = pgtype.Timestamptz{Time: t, Valid: true}
+	}
+	return startAt, endAt, true
+}
+
+// SetApprovalService enables maker-checker controls for tenants that
+// require approval
+func (h *CampaignsHandler) SetApprovalService(approvals *approval.Service) {
+	h.approvals = approvals
+}
+
 // CreateCampaignRequest represents the request to create a campaign
 type CreateCampaignRequest struct {
 	Name     string  `json:"name" binding:"required"`
@@ -33,6 +84,10 @@ type CreateCampaignRequest struct {
 	EndAt    *string `json:"end_at"`
 	BudgetID *string `json:"budget_id"`
 	Status   string  `json:"status"`
+	// ExpectedAudience and ExpectedConversion, when given, return an
+	// estimate of the budget the campaign's rules need
+	ExpectedAudience   *int     `json:"expected_audience"`
+	ExpectedConversion *float64 `json:"expected_conversion"`
 }
 
 // UpdateCampaignRequest represents the request to update a campaign
@@ -42,6 +97,175 @@ type UpdateCampaignRequest struct {
 	EndAt    *string `json:"end_at"`
 	BudgetID *string `json:"budget_id"`
 	Status   *string `json:"status"`
+	// Comment explains the change when it has to be approved
+	Comment string `json:"comment"`
+}
+
+// CloneCampaignRequest represents the request to clone a campaign
+type CloneCampaignRequest struct {
+	Name        string  `json:"name"`
+	StartAt     *string `json:"start_at"`
+	EndAt       *string `json:"end_at"`
+	Status      string  `json:"status"`
+	FreshBudget bool    `json:"fresh_budget"`
+}
+
+// FromTemplateRequest represents the request to create a campaign from a template
+type FromTemplateRequest struct {
+	Template string             `json:"template" binding:"required"`
+	Name     string             `json:"name"`
+	RewardID string             `json:"reward_id" binding:"required"`
+	BudgetID *string            `json:"budget_id"`
+	StartAt  *string            `json:"start_at"`
+	EndAt    *string            `json:"end_at"`
+	Status   string             `json:"status"`
+	Params   map[string]float64 `json:"params"`
+	// ExpectedAudience and ExpectedConversion, when given, return an
+	// estimate of the budget the template's rule needs
+	ExpectedAudience   *int     `json:"expected_audience"`
+	ExpectedConversion *float64 `json:"expected_conversion"`
+}
+
+// CampaignResponse is a campaign in API responses
+type CampaignResponse struct {
+	ID                string `json:"id"`
+	TenantID          string `json:"tenant_id"`
+	Name              string `json:"name"`
+	StartAt           string `json:"start_at"`
+	EndAt             string `json:"end_at"`
+	BudgetID          string `json:"budget_id"`
+	Status            string `json:"status"`
+	ArchivedAt        string `json:"archived_at"`
+	LeaderboardMetric string `json:"leaderboard_metric"`
+	MaxSpendPerDay    string `json:"max_spend_per_day"`
+	MaxSpendPerWeek   string `json:"max_spend_per_week"`
+}
+
+// CreatedCampaignResponse is a newly created campaign, with the estimate of
+// its budget when one was asked for
+type CreatedCampaignResponse struct {
+	CampaignResponse
+	BudgetEstimate *BudgetEstimateResult `json:"budget_estimate,omitempty"`
+}
+
+// CampaignWithRulesResponse is a campaign created with its rules, and the
+// budget created for it if any
+type CampaignWithRulesResponse struct {
+	ID             string                  `json:"id"`
+	TenantID       string                  `json:"tenant_id"`
+	Name           string                  `json:"name"`
+	StartAt        string                  `json:"start_at"`
+	EndAt          string                  `json:"end_at"`
+	BudgetID       string                  `json:"budget_id"`
+	Status         string                  `json:"status"`
+	Rules          []CampaignRuleResponse  `json:"rules"`
+	Budget         *CampaignBudgetResponse `json:"budget,omitempty"`
+	BudgetEstimate *BudgetEstimateResult   `json:"budget_estimate,omitempty"`
+}
+
+// CampaignRuleResponse is a rule created with a campaign
+type CampaignRuleResponse struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	EventType   string                 `json:"event_type"`
+	Conditions  map[string]interface{} `json:"conditions"`
+	RewardID    string                 `json:"reward_id"`
+	PerUserCap  int32                  `json:"per_user_cap"`
+	GlobalCap   int32                  `json:"global_cap"`
+	CoolDownSec int32                  `json:"cool_down_sec"`
+	Active      bool                   `json:"active"`
+}
+
+// CampaignBudgetResponse is a budget created with a campaign
+type CampaignBudgetResponse struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Currency string `json:"currency"`
+	SoftCap  string `json:"soft_cap"`
+	HardCap  string `json:"hard_cap"`
+	Balance  string `json:"balance"`
+}
+
+// FallbackBudgetsResponse is a campaign's fallback budgets in the order
+// they are tried
+type FallbackBudgetsResponse struct {
+	FallbackBudgets []FallbackBudgetResponse `json:"fallback_budgets"`
+}
+
+// FallbackBudgetResponse is one of a campaign's fallback budgets
+type FallbackBudgetResponse struct {
+	BudgetID string `json:"budget_id"`
+	Position int32  `json:"position"`
+	Name     string `json:"name"`
+	Currency string `json:"currency"`
+	Balance  string `json:"balance"`
+	HardCap  string `json:"hard_cap"`
+}
+
+// BudgetEstimateResponse is the budget a campaign's rules are expected to
+// need. BudgetID and Headroom are only set when the campaign has a budget.
+type BudgetEstimateResponse struct {
+	ExpectedAudience   int                    `json:"expected_audience"`
+	ExpectedConversion float64                `json:"expected_conversion"`
+	Currency           string                 `json:"currency"`
+	EstimatedCost      float64                `json:"estimated_cost"`
+	Rules              []RuleEstimateResponse `json:"rules"`
+	Sufficient         bool                   `json:"sufficient"`
+	Warnings           []string               `json:"warnings"`
+	BudgetID           *string                `json:"budget_id,omitempty"`
+	Headroom           *float64               `json:"headroom,omitempty"`
+}
+
+// BudgetEstimateResult is the estimate returned when creating a campaign.
+// The campaign is created either way, so a failed estimate is reported as
+// just an error.
+type BudgetEstimateResult struct {
+	*BudgetEstimateResponse
+	Error string `json:"error,omitempty"`
+}
+
+// RuleEstimateResponse is the expected cost of one of a campaign's rules
+type RuleEstimateResponse struct {
+	RuleID     string  `json:"rule_id"`
+	RuleName   string  `json:"rule_name"`
+	RewardName string  `json:"reward_name"`
+	Currency   string  `json:"currency"`
+	UnitCost   float64 `json:"unit_cost"`
+	Issuances  int     `json:"issuances"`
+	Cost       float64 `json:"cost"`
+}
+
+// BudgetPerformanceResponse is a running campaign's budget utilisation and
+// redemption rate
+type BudgetPerformanceResponse struct {
+	CampaignID   string  `json:"campaign_id"`
+	CampaignName string  `json:"campaign_name"`
+	BudgetID     string  `json:"budget_id"`
+	BudgetName   string  `json:"budget_name"`
+	Currency     string  `json:"currency"`
+	HardCap      float64 `json:"hard_cap"`
+	Spent        float64 `json:"spent"`
+	Headroom     float64 `json:"headroom"`
+	Burn         float64 `json:"burn"`
+	Issued       int64   `json:"issued"`
+	Redeemed     int64   `json:"redeemed"`
+	Redemption   float64 `json:"redemption"`
+}
+
+// ReallocationResponse suggests moving budget between two campaigns
+type ReallocationResponse struct {
+	From     BudgetPerformanceResponse `json:"from"`
+	To       BudgetPerformanceResponse `json:"to"`
+	Amount   float64                   `json:"amount"`
+	Currency string                    `json:"currency"`
+	Reason   string                    `json:"reason"`
+}
+
+// BudgetReallocationsResponse is running campaigns' budget performance and
+// the reallocations suggested between them
+type BudgetReallocationsResponse struct {
+	Campaigns   []BudgetPerformanceResponse `json:"campaigns"`
+	Suggestions []ReallocationResponse      `json:"suggestions"`
 }
 
 // Create handles POST /v1/tenants/:tid/campaigns
@@ -70,13 +294,13 @@ func (h *CampaignsHandler) Create(c *gin.Context) {
 	if req.Status == "" {
 		req.Status = "active"
 	}
-	validStatuses := map[string]bool{
-		"active":    true,
-		"paused":    true,
-		"completed": true,
+	if !validCampaignStatus(req.Status) {
+		httputil.BadRequest(c, "Invalid status. Must be draft, active, paused, or completed", nil)
+		return
 	}
-	if !validStatuses[req.Status] {
-		httputil.BadRequest(c, "Invalid status. Must be active, paused, or completed", nil)
+
+	estimate, ok := parseEstimateParams(c, req.ExpectedAudience, req.ExpectedConversion)
+	if !ok {
 		return
 	}
 
@@ -87,23 +311,19 @@ func (h *CampaignsHandler) Create(c *gin.Context) {
 		return
 	}
 
-	// Prepare parameters
-	var startAt, endAt pgtype.Timestamptz
-	if req.StartAt != nil {
-		startTime, err := time.Parse(time.RFC3339, *req.StartAt)
-		if err != nil {
-			httputil.BadRequest(c, "Invalid start_at format", nil)
-			return
-		}
-		startAt = pgtype.Timestamptz{Time: startTime, Valid: true}
+	// Campaigns start as drafts when the tenant requires approval
+	required, ok := requiresApproval(c, h.approvals, tenantUUID)
+	if !ok {
+		return
 	}
-	if req.EndAt != nil {
-		endTime, err := time.Parse(time.RFC3339, *req.EndAt)
-		if err != nil {
-			httputil.BadRequest(c, "Invalid end_at format", nil)
-			return
-		}
-		endAt = pgtype.Timestamptz{Time: endTime, Valid: true}
+	if required {
+		req.Status = approval.CampaignDraft
+	}
+
+	// Prepare parameters
+	startAt, endAt, ok := h.parseWindow(c, tenantUUID, req.StartAt, req.EndAt)
+	if !ok {
+		return
 	}
 
 	var budgetID pgtype.UUID
@@ -128,15 +348,11 @@ func (h *CampaignsHandler) Create(c *gin.Context) {
 		return
 	}
 
-	c.JSON(201, gin.H{
-		"id":         formatUUID(campaign.ID),
-		"tenant_id":  formatUUID(campaign.TenantID),
-		"name":       campaign.Name,
-		"start_at":   formatTimestamp(campaign.StartAt),
-		"end_at":     formatTimestamp(campaign.EndAt),
-		"budget_id":  formatUUID(campaign.BudgetID),
-		"status":     campaign.Status,
-	})
+	response := CreatedCampaignResponse{CampaignResponse: formatCampaign(campaign)}
+	if estimate != nil {
+		response.BudgetEstimate = h.budgetEstimate(c, tenantUUID, campaign, *estimate)
+	}
+	httputil.Respond(c, 201, response)
 }
 
 // List handles GET /v1/tenants/:tid/campaigns
@@ -149,14 +365,10 @@ func (h *CampaignsHandler) List(c *gin.Context) {
 
 	// Get filter params
 	status := c.Query("status")
+	includeArchived := c.DefaultQuery("include_archived", "false")
 
 	if status != "" {
-		validStatuses := map[string]bool{
-			"active":    true,
-			"paused":    true,
-			"completed": true,
-		}
-		if !validStatuses[status] {
+		if !validCampaignStatus(status) && status != approval.CampaignPendingApproval {
 			httputil.BadRequest(c, "Invalid status", nil)
 			return
 		}
@@ -170,32 +382,19 @@ func (h *CampaignsHandler) List(c *gin.Context) {
 	}
 
 	// List campaigns using service
-	campaigns, total, err := h.service.ListCampaigns(c.Request.Context(), tenantUUID, "50", "0", status)
+	campaigns, total, err := h.service.ListCampaigns(c.Request.Context(), tenantUUID, "50", "0", status, includeArchived == "true")
 	if err != nil {
 		httputil.InternalError(c, "Failed to list campaigns")
 		return
 	}
 
 	// Format response
-	campaignsList := make([]gin.H, len(campaigns))
+	campaignsList := make([]CampaignResponse, len(campaigns))
 	for i, campaign := range campaigns {
-		campaignsList[i] = gin.H{
-			"id":         formatUUID(campaign.ID),
-			"tenant_id":  formatUUID(campaign.TenantID),
-			"name":       campaign.Name,
-			"start_at":   formatTimestamp(campaign.StartAt),
-			"end_at":     formatTimestamp(campaign.EndAt),
-			"budget_id":  formatUUID(campaign.BudgetID),
-			"status":     campaign.Status,
-		}
+		campaignsList[i] = formatCampaign(campaign)
 	}
 
-	c.JSON(200, gin.H{
-		"data":  campaignsList,
-		"total": total,
-		"limit": 50,
-		"page":  0,
-	})
+	httputil.RespondList(c, campaignsList, httputil.Page{Total: int64(total)})
 }
 
 // Get handles GET /v1/tenants/:tid/campaigns/:id
@@ -231,15 +430,7 @@ func (h *CampaignsHandler) Get(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, gin.H{
-		"id":         formatUUID(campaign.ID),
-		"tenant_id":  formatUUID(campaign.TenantID),
-		"name":       campaign.Name,
-		"start_at":   formatTimestamp(campaign.StartAt),
-		"end_at":     formatTimestamp(campaign.EndAt),
-		"budget_id":  formatUUID(campaign.BudgetID),
-		"status":     campaign.Status,
-	})
+	httputil.Respond(c, 200, formatCampaign(campaign))
 }
 
 // Update handles PATCH /v1/tenants/:tid/campaigns/:id
@@ -273,12 +464,7 @@ func (h *CampaignsHandler) Update(c *gin.Context) {
 
 	// Validate status if provided
 	if req.Status != nil {
-		validStatuses := map[string]bool{
-			"active":    true,
-			"paused":    true,
-			"completed": true,
-		}
-		if !validStatuses[*req.Status] {
+		if !validCampaignStatus(*req.Status) {
 			httputil.BadRequest(c, "Invalid status", nil)
 			return
 		}
@@ -301,6 +487,10 @@ func (h *CampaignsHandler) Update(c *gin.Context) {
 		httputil.NotFound(c, "Campaign not found")
 		return
 	}
+	if currentCampaign.ArchivedAt.Valid {
+		httputil.Conflict(c, "Restore the campaign before changing it", nil)
+		return
+	}
 
 	// Prepare update parameters
 	name := currentCampaign.Name
@@ -308,24 +498,17 @@ func (h *CampaignsHandler) Update(c *gin.Context) {
 		name = *req.Name
 	}
 
+	newStart, newEnd, ok := h.parseWindow(c, tenantUUID, req.StartAt, req.EndAt)
+	if !ok {
+		return
+	}
 	startAt := currentCampaign.StartAt
 	if req.StartAt != nil {
-		startTime, err := time.Parse(time.RFC3339, *req.StartAt)
-		if err != nil {
-			httputil.BadRequest(c, "Invalid start_at format", nil)
-			return
-		}
-		startAt = pgtype.Timestamptz{Time: startTime, Valid: true}
+		startAt = newStart
 	}
-
 	endAt := currentCampaign.EndAt
 	if req.EndAt != nil {
-		endTime, err := time.Parse(time.RFC3339, *req.EndAt)
-		if err != nil {
-			httputil.BadRequest(c, "Invalid end_at format", nil)
-			return
-		}
-		endAt = pgtype.Timestamptz{Time: endTime, Valid: true}
+		endAt = newEnd
 	}
 
 	budgetID := currentCampaign.BudgetID
@@ -341,6 +524,37 @@ func (h *CampaignsHandler) Update(c *gin.Context) {
 		status = *req.Status
 	}
 
+	required, ok := requiresApproval(c, h.approvals, tenantUUID)
+	if !ok {
+		return
+	}
+	if required {
+		switch currentCampaign.Status {
+		case approval.CampaignPendingApproval:
+			httputil.Conflict(c, "Campaign is pending approval", nil)
+			return
+		case approval.CampaignDraft:
+			// Drafts are edited freely but only go live through approval
+			if status != approval.CampaignDraft {
+				httputil.Conflict(c, "Submit the campaign for approval to change its status", nil)
+				return
+			}
+		default:
+			if status == approval.CampaignDraft {
+				httputil.BadRequest(c, "Invalid status", nil)
+				return
+			}
+			h.submitUpdate(c, tenantUUID, campaignUUID, req.Comment, approval.CampaignChange{
+				Name:     name,
+				StartAt:  timestampPtr(startAt),
+				EndAt:    timestampPtr(endAt),
+				BudgetID: formatUUID(budgetID),
+				Status:   status,
+			})
+			return
+		}
+	}
+
 	// Update campaign using service
 	err = h.service.UpdateCampaign(c.Request.Context(), db.UpdateCampaignParams{
 		ID:       campaignUUID,
@@ -363,13 +577,718 @@ func (h *CampaignsHandler) Update(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, gin.H{
-		"id":         formatUUID(campaign.ID),
-		"tenant_id":  formatUUID(campaign.TenantID),
-		"name":       campaign.Name,
-		"start_at":   formatTimestamp(campaign.StartAt),
-		"end_at":     formatTimestamp(campaign.EndAt),
-		"budget_id":  formatUUID(campaign.BudgetID),
-		"status":     campaign.Status,
+	httputil.Respond(c, 200, formatCampaign(campaign))
+}
+
+// Clone handles POST /v1/tenants/:tid/campaigns/:id/clone
+// Copies the campaign and its rules. The copy is paused unless a status is given.
+func (h *CampaignsHandler) Clone(c *gin.Context) {
+	tenantID := c.Param("tid")
+	campaignID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	if err := httputil.ValidateUUID(campaignID); err != nil {
+		httputil.BadRequest(c, "Invalid campaign ID", nil)
+		return
+	}
+
+	var req CloneCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.Status != "" && !validCampaignStatus(req.Status) {
+		httputil.BadRequest(c, "Invalid status. Must be draft, active, paused, or completed", nil)
+		return
+	}
+
+	// Parse UUIDs
+	var tenantUUID, campaignUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+	if err := campaignUUID.Scan(campaignID); err != nil {
+		httputil.BadRequest(c, "Invalid campaign ID format", nil)
+		return
+	}
+
+	params := campaign.CloneParams{
+		TenantID:    tenantUUID,
+		CampaignID:  campaignUUID,
+		Name:        req.Name,
+		Status:      req.Status,
+		FreshBudget: req.FreshBudget,
+	}
+	startAt, endAt, ok := h.parseWindow(c, tenantUUID, req.StartAt, req.EndAt)
+	if !ok {
+		return
+	}
+	if startAt.Valid {
+		params.StartAt = &startAt.Time
+	}
+	if endAt.Valid {
+		params.EndAt = &endAt.Time
+	}
+
+	required, ok := requiresApproval(c, h.approvals, tenantUUID)
+	if !ok {
+		return
+	}
+	if required {
+		params.Status = approval.CampaignDraft
+	}
+
+	result, err := h.service.Clone(c.Request.Context(), params)
+	if err != nil {
+		switch {
+		case errors.Is(err, campaign.ErrCampaignNotFound):
+			httputil.NotFound(c, "Campaign not found")
+		case errors.Is(err, campaign.ErrBudgetNotFound):
+			httputil.NotFound(c, "Budget not found")
+		case errors.Is(err, campaign.ErrInvalidDates):
+			httputil.BadRequest(c, err.Error(), nil)
+		default:
+			httputil.InternalError(c, "Failed to clone campaign")
+		}
+		return
+	}
+
+	httputil.Respond(c, 201, formatCampaignWithRules(result))
+}
+
+// SetFallbackBudgetsRequest represents the ordered budgets a campaign falls
+// back to when its primary budget reaches its hard cap
+type SetFallbackBudgetsRequest struct {
+	BudgetIDs []string `json:"budget_ids"`
+}
+
+// FallbackBudgets handles GET /v1/tenants/:tid/campaigns/:id/fallback-budgets
+func (h *CampaignsHandler) FallbackBudgets(c *gin.Context) {
+	tenantUUID, campaignUUID, ok := parseCampaignParams(c)
+	if !ok {
+		return
+	}
+
+	budgets, err := h.service.ListFallbackBudgets(c.Request.Context(), tenantUUID, campaignUUID)
+	if err != nil {
+		httputil.InternalError(c, "Failed to list fallback budgets")
+		return
+	}
+
+	httputil.Respond(c, 200, FallbackBudgetsResponse{FallbackBudgets: formatFallbackBudgets(budgets)})
+}
+
+// SetFallbackBudgets handles PUT /v1/tenants/:tid/campaigns/:id/fallback-budgets
+// Replaces the campaign's fallback budgets; they are tried in the order given.
+func (h *CampaignsHandler) SetFallbackBudgets(c *gin.Context) {
+	tenantUUID, campaignUUID, ok := parseCampaignParams(c)
+	if !ok {
+		return
+	}
+
+	var req SetFallbackBudgetsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	budgetIDs := make([]pgtype.UUID, len(req.BudgetIDs))
+	for i, id := range req.BudgetIDs {
+		if err := httputil.ValidateUUID(id); err != nil {
+			httputil.BadRequest(c, "Invalid budget ID", nil)
+			return
+		}
+		if err := budgetIDs[i].Scan(id); err != nil {
+			httputil.BadRequest(c, "Invalid budget ID format", nil)
+			return
+		}
+	}
+
+	budgets, err := h.service.SetFallbackBudgets(c.Request.Context(), tenantUUID, campaignUUID, budgetIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, campaign.ErrCampaignNotFound):
+			httputil.NotFound(c, "Campaign not found")
+		case errors.Is(err, campaign.ErrBudgetNotFound):
+			httputil.NotFound(c, "Budget not found")
+		case errors.Is(err, campaign.ErrNoPrimaryBudget),
+			errors.Is(err, campaign.ErrInvalidFallbackBudgets),
+			errors.Is(err, campaign.ErrFallbackCurrencyMismatch):
+			httputil.BadRequest(c, err.Error(), nil)
+		default:
+			httputil.InternalError(c, "Failed to set fallback budgets")
+		}
+		return
+	}
+
+	httputil.Respond(c, 200, FallbackBudgetsResponse{FallbackBudgets: formatFallbackBudgets(budgets)})
+}
+
+// SetPacingRequest represents a campaign's spend limits. An omitted or null
+// limit leaves that period unlimited.
+type SetPacingRequest struct {
+	MaxSpendPerDay  *float64 `json:"max_spend_per_day"`
+	MaxSpendPerWeek *float64 `json:"max_spend_per_week"`
+}
+
+// SetPacing handles PUT /v1/tenants/:tid/campaigns/:id/pacing
+// Sets the most the campaign's rules may spend per day and per week in the
+// tenant's time zone.
+func (h *CampaignsHandler) SetPacing(c *gin.Context) {
+	tenantUUID, campaignUUID, ok := parseCampaignParams(c)
+	if !ok {
+		return
+	}
+
+	var req SetPacingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	var limits campaign.PacingLimits
+	if req.MaxSpendPerDay != nil {
+		if err := limits.MaxSpendPerDay.Scan(strconv.FormatFloat(*req.MaxSpendPerDay, 'f', 2, 64)); err != nil {
+			httputil.BadRequest(c, "Invalid max_spend_per_day", nil)
+			return
+		}
+	}
+	if req.MaxSpendPerWeek != nil {
+		if err := limits.MaxSpendPerWeek.Scan(strconv.FormatFloat(*req.MaxSpendPerWeek, 'f', 2, 64)); err != nil {
+			httputil.BadRequest(c, "Invalid max_spend_per_week", nil)
+			return
+		}
+	}
+
+	updated, err := h.service.SetPacing(c.Request.Context(), tenantUUID, campaignUUID, limits)
+	if err != nil {
+		switch {
+		case errors.Is(err, campaign.ErrCampaignNotFound):
+			httputil.NotFound(c, "Campaign not found")
+		case errors.Is(err, campaign.ErrInvalidPacing):
+			httputil.BadRequest(c, err.Error(), nil)
+		default:
+			httputil.InternalError(c, "Failed to set campaign pacing")
+		}
+		return
+	}
+
+	httputil.Respond(c, 200, formatCampaign(updated))
+}
+
+// Stats handles GET /v1/tenants/:tid/campaigns/:id/stats
+// Returns the campaign's lifetime issuances, redemptions and spend, and its
+// spend against its limits in the current day and week.
+func (h *CampaignsHandler) Stats(c *gin.Context) {
+	tenantUUID, campaignUUID, ok := parseCampaignParams(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.GetStats(c.Request.Context(), tenantUUID, campaignUUID, time.Now())
+	if err != nil {
+		if errors.Is(err, campaign.ErrCampaignNotFound) {
+			httputil.NotFound(c, "Campaign not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to fetch campaign stats")
+		return
+	}
+
+	httputil.Respond(c, 200, stats)
+}
+
+// Report handles GET /v1/tenants/:tid/campaigns/:id/report.html
+// Renders the campaign's report as a printable HTML page, to print or
+// convert to PDF.
+func (h *CampaignsHandler) Report(c *gin.Context) {
+	tenantUUID, campaignUUID, ok := parseCampaignParams(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.Report(c.Request.Context(), tenantUUID, campaignUUID, time.Now())
+	if err != nil {
+		if errors.Is(err, campaign.ErrCampaignNotFound) {
+			httputil.NotFound(c, "Campaign not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to build campaign report")
+		return
+	}
+
+	var page bytes.Buffer
+	if err := campaign.RenderReportHTML(&page, report); err != nil {
+		httputil.InternalError(c, "Failed to render campaign report")
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": report.Filename()}))
+	c.Data(200, "text/html; charset=utf-8", page.Bytes())
+}
+
+// Delete handles DELETE /v1/tenants/:tid/campaigns/:id
+// Archives the campaign, which must not be active. Restore brings it back.
+func (h *CampaignsHandler) Delete(c *gin.Context) {
+	tenantUUID, campaignUUID, ok := parseCampaignParams(c)
+	if !ok {
+		return
+	}
+
+	archived, err := h.service.ArchiveCampaign(c.Request.Context(), campaignUUID, tenantUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, campaign.ErrCampaignNotFound):
+			httputil.NotFound(c, "Campaign not found")
+		case errors.Is(err, campaign.ErrCampaignActive):
+			httputil.Conflict(c, "Pause or complete the campaign before archiving it", nil)
+		default:
+			httputil.InternalError(c, "Failed to archive campaign")
+		}
+		return
+	}
+
+	httputil.Respond(c, 200, formatCampaign(archived))
+}
+
+// Restore handles POST /v1/tenants/:tid/campaigns/:id/restore
+// The campaign keeps the status it was archived with.
+func (h *CampaignsHandler) Restore(c *gin.Context) {
+	tenantUUID, campaignUUID, ok := parseCampaignParams(c)
+	if !ok {
+		return
+	}
+
+	restored, err := h.service.RestoreCampaign(c.Request.Context(), campaignUUID, tenantUUID)
+	if err != nil {
+		if errors.Is(err, campaign.ErrCampaignNotFound) {
+			httputil.NotFound(c, "Campaign not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to restore campaign")
+		return
+	}
+
+	httputil.Respond(c, 200, formatCampaign(restored))
+}
+
+// Templates handles GET /v1/tenants/:tid/campaigns/templates
+func (h *CampaignsHandler) Templates(c *gin.Context) {
+	templates := campaign.Templates()
+	httputil.RespondList(c, templates, httputil.Page{Total: int64(len(templates))})
+}
+
+// FromTemplate handles POST /v1/tenants/:tid/campaigns/from-template
+// Creates a campaign with the template's rule granting the given reward.
+func (h *CampaignsHandler) FromTemplate(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var req FromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := httputil.ValidateUUID(req.RewardID); err != nil {
+		httputil.BadRequest(c, "Invalid reward ID", nil)
+		return
+	}
+	if req.BudgetID != nil {
+		if err := httputil.ValidateUUID(*req.BudgetID); err != nil {
+			httputil.BadRequest(c, "Invalid budget ID", nil)
+			return
+		}
+	}
+	if req.Status != "" && !validCampaignStatus(req.Status) {
+		httputil.BadRequest(c, "Invalid status. Must be draft, active, paused, or completed", nil)
+		return
+	}
+	estimate, ok := parseEstimateParams(c, req.ExpectedAudience, req.ExpectedConversion)
+	if !ok {
+		return
+	}
+
+	params := campaign.FromTemplateParams{
+		TemplateID: req.Template,
+		Name:       req.Name,
+		Status:     req.Status,
+		Values:     req.Params,
+	}
+	if err := params.TenantID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+	if err := params.RewardID.Scan(req.RewardID); err != nil {
+		httputil.BadRequest(c, "Invalid reward ID format", nil)
+		return
+	}
+	if req.BudgetID != nil {
+		if err := params.BudgetID.Scan(*req.BudgetID); err != nil {
+			httputil.BadRequest(c, "Invalid budget ID format", nil)
+			return
+		}
+	}
+	params.StartAt, params.EndAt, ok = h.parseWindow(c, params.TenantID, req.StartAt, req.EndAt)
+	if !ok {
+		return
+	}
+
+	required, ok := requiresApproval(c, h.approvals, params.TenantID)
+	if !ok {
+		return
+	}
+	if required {
+		params.Status = approval.CampaignDraft
+	}
+
+	result, err := h.service.FromTemplate(c.Request.Context(), params)
+	if err != nil {
+		switch {
+		case errors.Is(err, campaign.ErrTemplateNotFound):
+			httputil.NotFound(c, "Campaign template not found")
+		case errors.Is(err, campaign.ErrRewardNotFound):
+			httputil.NotFound(c, "Reward not found")
+		case errors.Is(err, campaign.ErrBudgetNotFound):
+			httputil.NotFound(c, "Budget not found")
+		case errors.Is(err, campaign.ErrInvalidDates):
+			httputil.BadRequest(c, err.Error(), nil)
+		default:
+			var pe *campaign.ParamError
+			if errors.As(err, &pe) {
+				httputil.BadRequest(c, "Invalid template params", pe.Error())
+				return
+			}
+			httputil.InternalError(c, "Failed to create campaign from template")
+		}
+		return
+	}
+
+	response := formatCampaignWithRules(result)
+	if estimate != nil {
+		response.BudgetEstimate = h.budgetEstimate(c, params.TenantID, result.Campaign, *estimate)
+	}
+	httputil.Respond(c, 201, response)
+}
+
+// BudgetEstimate handles GET /v1/tenants/:tid/campaigns/:id/budget-estimate
+// Estimates the budget the campaign's rules need for
+// ?expected_audience=&expected_conversion= and checks the headroom of the
+// campaign's budget.
+func (h *CampaignsHandler) BudgetEstimate(c *gin.Context) {
+	tenantUUID, campaignUUID, ok := parseCampaignParams(c)
+	if !ok {
+		return
+	}
+
+	audience, err := strconv.Atoi(c.Query("expected_audience"))
+	if err != nil {
+		httputil.BadRequest(c, "expected_audience must be a whole number", nil)
+		return
+	}
+	conversion, err := strconv.ParseFloat(c.Query("expected_conversion"), 64)
+	if err != nil {
+		httputil.BadRequest(c, "expected_conversion must be a number", nil)
+		return
+	}
+	params := campaign.EstimateParams{ExpectedAudience: audience, ExpectedConversion: conversion}
+	if err := params.Validate(); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	found, err := h.service.GetCampaignByID(c.Request.Context(), campaignUUID, tenantUUID)
+	if err != nil {
+		httputil.NotFound(c, "Campaign not found")
+		return
+	}
+
+	estimate, err := h.service.EstimateBudget(c.Request.Context(), tenantUUID, found, params)
+	if err != nil {
+		if errors.Is(err, campaign.ErrBudgetNotFound) {
+			httputil.NotFound(c, "Budget not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to estimate budget")
+		return
+	}
+
+	httputil.Respond(c, 200, formatBudgetEstimate(estimate))
+}
+
+// BudgetReallocations handles GET /v1/tenants/:tid/campaigns/budget-reallocations
+// Compares running campaigns' budget burn and redemption rates and suggests
+// moving budget from rarely redeemed campaigns to well redeemed ones. The
+// suggestions are advisory; nothing is moved.
+func (h *CampaignsHandler) BudgetReallocations(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	campaigns, suggestions, err := h.service.BudgetReallocations(c.Request.Context(), tenantUUID)
+	if err != nil {
+		httputil.InternalError(c, "Failed to suggest budget reallocations")
+		return
+	}
+
+	campaignsList := make([]BudgetPerformanceResponse, len(campaigns))
+	for i, p := range campaigns {
+		campaignsList[i] = formatBudgetPerformance(p)
+	}
+	suggestionsList := make([]ReallocationResponse, len(suggestions))
+	for i, r := range suggestions {
+		suggestionsList[i] = ReallocationResponse{
+			From:     formatBudgetPerformance(r.From),
+			To:       formatBudgetPerformance(r.To),
+			Amount:   r.Amount,
+			Currency: r.Currency,
+			Reason:   r.Reason,
+		}
+	}
+
+	httputil.Respond(c, 200, BudgetReallocationsResponse{
+		Campaigns:   campaignsList,
+		Suggestions: suggestionsList,
+	})
+}
+
+// parseEstimateParams returns the expected response given when creating a
+// campaign, or nil when none is given
+func parseEstimateParams(c *gin.Context, audience *int, conversion *float64) (*campaign.EstimateParams, bool) {
+	if audience == nil && conversion == nil {
+		return nil, true
+	}
+	if audience == nil || conversion == nil {
+		httputil.BadRequest(c, "expected_audience and expected_conversion must be given together", nil)
+		return nil, false
+	}
+
+	params := campaign.EstimateParams{ExpectedAudience: *audience, ExpectedConversion: *conversion}
+	if err := params.Validate(); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return nil, false
+	}
+	return &params, true
+}
+
+// budgetEstimate estimates a newly created campaign's budget. The campaign
+// is already created, so a failed estimate is reported in the response
+// rather than failing the request.
+func (h *CampaignsHandler) budgetEstimate(c *gin.Context, tenantUUID pgtype.UUID, created db.Campaign, params campaign.EstimateParams) *BudgetEstimateResult {
+	estimate, err := h.service.EstimateBudget(c.Request.Context(), tenantUUID, created, params)
+	if err != nil {
+		return &BudgetEstimateResult{Error: "Failed to estimate budget"}
+	}
+	response := formatBudgetEstimate(estimate)
+	return &BudgetEstimateResult{BudgetEstimateResponse: &response}
+}
+
+// formatBudgetEstimate formats a budget estimate for a response
+func formatBudgetEstimate(e *campaign.BudgetEstimate) BudgetEstimateResponse {
+	rules := make([]RuleEstimateResponse, len(e.Rules))
+	for i, r := range e.Rules {
+		rules[i] = RuleEstimateResponse{
+			RuleID:     formatUUID(r.RuleID),
+			RuleName:   r.RuleName,
+			RewardName: r.RewardName,
+			Currency:   r.Currency,
+			UnitCost:   r.UnitCost,
+			Issuances:  r.Issuances,
+			Cost:       r.Cost,
+		}
+	}
+
+	response := BudgetEstimateResponse{
+		ExpectedAudience:   e.ExpectedAudience,
+		ExpectedConversion: e.ExpectedConversion,
+		Currency:           e.Currency,
+		EstimatedCost:      e.EstimatedCost,
+		Rules:              rules,
+		Sufficient:         e.Sufficient(),
+		Warnings:           e.Warnings,
+	}
+	if e.Headroom != nil {
+		budgetID := formatUUID(e.BudgetID)
+		response.BudgetID = &budgetID
+		response.Headroom = e.Headroom
+	}
+	return response
+}
+
+// formatBudgetPerformance formats a campaign's budget performance for the API response
+func formatBudgetPerformance(p campaign.BudgetPerformance) BudgetPerformanceResponse {
+	return BudgetPerformanceResponse{
+		CampaignID:   formatUUID(p.CampaignID),
+		CampaignName: p.CampaignName,
+		BudgetID:     formatUUID(p.BudgetID),
+		BudgetName:   p.BudgetName,
+		Currency:     p.Currency,
+		HardCap:      p.HardCap,
+		Spent:        p.Spent,
+		Headroom:     p.Headroom(),
+		Burn:         p.Burn(),
+		Issued:       p.Issued,
+		Redeemed:     p.Redeemed,
+		Redemption:   p.Redemption(),
+	}
+}
+
+// submitUpdate records a change to a live campaign for approval instead of
+// applying it
+func (h *CampaignsHandler) submitUpdate(c *gin.Context, tenantUUID, campaignUUID pgtype.UUID, comment string, change approval.CampaignChange) {
+	userUUID, ok := reviewerID(c)
+	if !ok {
+		return
+	}
+
+	submitted, err := h.approvals.Submit(c.Request.Context(), approval.SubmitParams{
+		TenantID:    tenantUUID,
+		EntityType:  approval.EntityCampaign,
+		EntityID:    campaignUUID,
+		Action:      approval.ActionUpdate,
+		Change:      &change,
+		RequestedBy: userUUID,
+		Comment:     comment,
 	})
+	if err != nil {
+		respondApprovalError(c, err, "Failed to submit campaign change for approval")
+		return
+	}
+
+	httputil.Respond(c, 202, formatApproval(submitted))
+}
+
+// validCampaignStatus reports whether status is a campaign status that can be set directly
+func validCampaignStatus(status string) bool {
+	return status == "draft" || status == "active" || status == "paused" || status == "completed"
+}
+
+// timestampPtr returns the time of a timestamp, or nil when it is unset
+func timestampPtr(ts pgtype.Timestamptz) *time.Time {
+	if !ts.Valid {
+		return nil
+	}
+	return &ts.Time
+}
+
+// formatCampaignWithRules formats a created campaign with its rules
+func formatCampaignWithRules(result *campaign.CloneResult) CampaignWithRulesResponse {
+	rules := make([]CampaignRuleResponse, len(result.Rules))
+	for i, r := range result.Rules {
+		var conditions map[string]interface{}
+		json.Unmarshal(r.Conditions, &conditions)
+
+		rules[i] = CampaignRuleResponse{
+			ID:          formatUUID(r.ID),
+			Name:        r.Name,
+			EventType:   r.EventType,
+			Conditions:  conditions,
+			RewardID:    formatUUID(r.RewardID),
+			PerUserCap:  r.PerUserCap,
+			GlobalCap:   r.GlobalCap.Int32,
+			CoolDownSec: r.CoolDownSec,
+			Active:      r.Active,
+		}
+	}
+
+	response := CampaignWithRulesResponse{
+		ID:       formatUUID(result.Campaign.ID),
+		TenantID: formatUUID(result.Campaign.TenantID),
+		Name:     result.Campaign.Name,
+		StartAt:  formatTimestamp(result.Campaign.StartAt),
+		EndAt:    formatTimestamp(result.Campaign.EndAt),
+		BudgetID: formatUUID(result.Campaign.BudgetID),
+		Status:   result.Campaign.Status,
+		Rules:    rules,
+	}
+
+	if result.Budget != nil {
+		response.Budget = &CampaignBudgetResponse{
+			ID:       formatUUID(result.Budget.ID),
+			Name:     result.Budget.Name,
+			Currency: result.Budget.Currency,
+			SoftCap:  formatNumeric(result.Budget.SoftCap),
+			HardCap:  formatNumeric(result.Budget.HardCap),
+			Balance:  formatNumeric(result.Budget.Balance),
+		}
+	}
+
+	return response
+}
+
+// formatCampaign formats a campaign for a response
+func formatCampaign(c db.Campaign) CampaignResponse {
+	return CampaignResponse{
+		ID:                formatUUID(c.ID),
+		TenantID:          formatUUID(c.TenantID),
+		Name:              c.Name,
+		StartAt:           formatTimestamp(c.StartAt),
+		EndAt:             formatTimestamp(c.EndAt),
+		BudgetID:          formatUUID(c.BudgetID),
+		Status:            c.Status,
+		ArchivedAt:        formatTimestamp(c.ArchivedAt),
+		LeaderboardMetric: c.LeaderboardMetric.String,
+		MaxSpendPerDay:    formatNumeric(c.MaxSpendPerDay),
+		MaxSpendPerWeek:   formatNumeric(c.MaxSpendPerWeek),
+	}
+}
+
+// parseCampaignParams validates and parses the tenant and campaign IDs from the path
+func parseCampaignParams(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, campaignUUID pgtype.UUID
+
+	tenantID := c.Param("tid")
+	campaignID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, campaignUUID, false
+	}
+	if err := httputil.ValidateUUID(campaignID); err != nil {
+		httputil.BadRequest(c, "Invalid campaign ID", nil)
+		return tenantUUID, campaignUUID, false
+	}
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, campaignUUID, false
+	}
+	if err := campaignUUID.Scan(campaignID); err != nil {
+		httputil.BadRequest(c, "Invalid campaign ID format", nil)
+		return tenantUUID, campaignUUID, false
+	}
+
+	return tenantUUID, campaignUUID, true
+}
+
+// formatFallbackBudgets formats a campaign's fallback budgets for a response
+func formatFallbackBudgets(budgets []db.ListCampaignFallbackBudgetsRow) []FallbackBudgetResponse {
+	result := make([]FallbackBudgetResponse, len(budgets))
+	for i, b := range budgets {
+		result[i] = FallbackBudgetResponse{
+			BudgetID: formatUUID(b.BudgetID),
+			Position: b.Position,
+			Name:     b.BudgetName,
+			Currency: b.Currency,
+			Balance:  formatNumeric(b.Balance),
+			HardCap:  formatNumeric(b.HardCap),
+		}
+	}
+	return result
 }
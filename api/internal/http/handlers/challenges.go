@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/challenge"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ChallengesHandler handles challenge definition and progress endpoints
+type ChallengesHandler struct {
+	service *challenge.Service
+}
+
+// NewChallengesHandler creates a new challenges handler
+func NewChallengesHandler(service *challenge.Service) *ChallengesHandler {
+	return &ChallengesHandler{
+		service: service,
+	}
+}
+
+// CreateChallengeRequest represents the request to create a challenge
+type CreateChallengeRequest struct {
+	Name        string                 `json:"name" binding:"required"`
+	Description string                 `json:"description"`
+	Kind        string                 `json:"kind" binding:"required"`
+	EventType   string                 `json:"event_type" binding:"required"`
+	Criteria    map[string]interface{} `json:"criteria"`
+	Target      int32                  `json:"target" binding:"required"`
+	Period      string                 `json:"period" binding:"required"`
+	StartsAt    *time.Time             `json:"starts_at"`
+	EndsAt      *time.Time             `json:"ends_at"`
+	Active      *bool                  `json:"active"`
+}
+
+// UpdateChallengeRequest represents the request to update a challenge. The
+// kind, event type and period are fixed once created.
+type UpdateChallengeRequest struct {
+	Name        *string                 `json:"name"`
+	Description *string                 `json:"description"`
+	Criteria    *map[string]interface{} `json:"criteria"`
+	Target      *int32                  `json:"target"`
+	StartsAt    *time.Time              `json:"starts_at"`
+	EndsAt      *time.Time              `json:"ends_at"`
+	Active      *bool                   `json:"active"`
+}
+
+// ChallengeResponse is a challenge in API responses
+type ChallengeResponse struct {
+	ID          string                 `json:"id"`
+	TenantID    string                 `json:"tenant_id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Kind        string                 `json:"kind"`
+	EventType   string                 `json:"event_type"`
+	Criteria    map[string]interface{} `json:"criteria"`
+	Target      int32                  `json:"target"`
+	Period      string                 `json:"period"`
+	StartsAt    string                 `json:"starts_at"`
+	EndsAt      string                 `json:"ends_at"`
+	Active      bool                   `json:"active"`
+	CreatedAt   string                 `json:"created_at"`
+	UpdatedAt   string                 `json:"updated_at"`
+}
+
+// ChallengeProgressResponse is a customer's progress on a challenge in the
+// current period
+type ChallengeProgressResponse struct {
+	ChallengeID string `json:"challenge_id"`
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	EventType   string `json:"event_type"`
+	Period      string `json:"period"`
+	Target      int32  `json:"target"`
+	Progress    int32  `json:"progress"`
+	Completed   bool   `json:"completed"`
+	PeriodStart string `json:"period_start"`
+	CompletedAt string `json:"completed_at"`
+}
+
+// Create handles POST /v1/tenants/:tid/challenges
+func (h *ChallengesHandler) Create(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var req CreateChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	params := challenge.Params{
+		TenantID:    tenantUUID,
+		Name:        req.Name,
+		Description: req.Description,
+		Kind:        req.Kind,
+		EventType:   req.EventType,
+		Target:      req.Target,
+		Period:      req.Period,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+		Active:      active,
+	}
+	if req.Criteria != nil {
+		criteria, err := json.Marshal(req.Criteria)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid criteria format", nil)
+			return
+		}
+		params.Criteria = criteria
+	}
+	if err := params.Validate(); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	created, err := h.service.Create(c.Request.Context(), params)
+	if err != nil {
+		if errors.Is(err, challenge.ErrUnknownEventType) {
+			httputil.BadRequest(c, "Unknown event type", nil)
+			return
+		}
+		httputil.InternalError(c, "Failed to create challenge")
+		return
+	}
+
+	httputil.Respond(c, 201, formatChallenge(created))
+}
+
+// List handles GET /v1/tenants/:tid/challenges
+func (h *ChallengesHandler) List(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	challenges, err := h.service.List(c.Request.Context(), tenantUUID)
+	if err != nil {
+		httputil.InternalError(c, "Failed to list challenges")
+		return
+	}
+
+	challengesList := make([]ChallengeResponse, len(challenges))
+	for i, ch := range challenges {
+		challengesList[i] = formatChallenge(ch)
+	}
+
+	httputil.RespondList(c, challengesList, httputil.Page{Total: int64(len(challengesList))})
+}
+
+// Get handles GET /v1/tenants/:tid/challenges/:id
+func (h *ChallengesHandler) Get(c *gin.Context) {
+	tenantUUID, challengeUUID, ok := parseChallengeParams(c)
+	if !ok {
+		return
+	}
+
+	ch, err := h.service.Get(c.Request.Context(), tenantUUID, challengeUUID)
+	if err != nil {
+		if errors.Is(err, challenge.ErrChallengeNotFound) {
+			httputil.NotFound(c, "Challenge not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get challenge")
+		return
+	}
+
+	httputil.Respond(c, 200, formatChallenge(ch))
+}
+
+// Update handles PATCH /v1/tenants/:tid/challenges/:id
+func (h *ChallengesHandler) Update(c *gin.Context) {
+	tenantUUID, challengeUUID, ok := parseChallengeParams(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	// Get current challenge so omitted fields are preserved
+	existing, err := h.service.Get(c.Request.Context(), tenantUUID, challengeUUID)
+	if err != nil {
+		if errors.Is(err, challenge.ErrChallengeNotFound) {
+			httputil.NotFound(c, "Challenge not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get challenge")
+		return
+	}
+
+	params := challenge.Params{
+		TenantID:    tenantUUID,
+		Name:        existing.Name,
+		Description: existing.Description,
+		Kind:        existing.Kind,
+		EventType:   existing.EventType,
+		Criteria:    existing.Criteria,
+		Target:      existing.Target,
+		Period:      existing.Period,
+		Active:      existing.Active,
+	}
+	if existing.StartsAt.Valid {
+		params.StartsAt = &existing.StartsAt.Time
+	}
+	if existing.EndsAt.Valid {
+		params.EndsAt = &existing.EndsAt.Time
+	}
+	if req.Name != nil {
+		params.Name = *req.Name
+	}
+	if req.Description != nil {
+		params.Description = *req.Description
+	}
+	if req.Criteria != nil {
+		criteria, err := json.Marshal(*req.Criteria)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid criteria format", nil)
+			return
+		}
+		params.Criteria = criteria
+	}
+	if req.Target != nil {
+		params.Target = *req.Target
+	}
+	if req.StartsAt != nil {
+		params.StartsAt = req.StartsAt
+	}
+	if req.EndsAt != nil {
+		params.EndsAt = req.EndsAt
+	}
+	if req.Active != nil {
+		params.Active = *req.Active
+	}
+
+	if err := params.Validate(); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	updated, err := h.service.Update(c.Request.Context(), challengeUUID, params)
+	if err != nil {
+		if errors.Is(err, challenge.ErrChallengeNotFound) {
+			httputil.NotFound(c, "Challenge not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to update challenge")
+		return
+	}
+
+	httputil.Respond(c, 200, formatChallenge(updated))
+}
+
+// CustomerProgress handles GET /v1/tenants/:tid/customers/:id/challenges
+// Lists the customer's current progress on each active challenge.
+func (h *ChallengesHandler) CustomerProgress(c *gin.Context) {
+	tenantID := c.Param("tid")
+	customerID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+	if err := httputil.ValidateUUID(customerID); err != nil {
+		httputil.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+
+	var tenantUUID, customerUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+	if err := customerUUID.Scan(customerID); err != nil {
+		httputil.BadRequest(c, "Invalid customer ID format", nil)
+		return
+	}
+
+	progress, err := h.service.CustomerProgress(c.Request.Context(), tenantUUID, customerUUID, time.Now())
+	if err != nil {
+		httputil.InternalError(c, "Failed to get challenge progress")
+		return
+	}
+
+	progressList := make([]ChallengeProgressResponse, len(progress))
+	for i, p := range progress {
+		progressList[i] = ChallengeProgressResponse{
+			ChallengeID: formatUUID(p.Challenge.ID),
+			Name:        p.Challenge.Name,
+			Kind:        p.Challenge.Kind,
+			EventType:   p.Challenge.EventType,
+			Period:      p.Challenge.Period,
+			Target:      p.Challenge.Target,
+			Progress:    p.Progress,
+			Completed:   p.Completed,
+			PeriodStart: p.PeriodStart.Format(time.RFC3339),
+			CompletedAt: formatTimestamp(p.CompletedAt),
+		}
+	}
+
+	httputil.RespondList(c, progressList, httputil.Page{Total: int64(len(progressList))})
+}
+
+// parseChallengeParams validates and parses the tenant and challenge IDs
+// from the path
+func parseChallengeParams(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, challengeUUID pgtype.UUID
+
+	tenantID := c.Param("tid")
+	challengeID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, challengeUUID, false
+	}
+	if err := httputil.ValidateUUID(challengeID); err != nil {
+		httputil.BadRequest(c, "Invalid challenge ID", nil)
+		return tenantUUID, challengeUUID, false
+	}
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, challengeUUID, false
+	}
+	if err := challengeUUID.Scan(challengeID); err != nil {
+		httputil.BadRequest(c, "Invalid challenge ID format", nil)
+		return tenantUUID, challengeUUID, false
+	}
+
+	return tenantUUID, challengeUUID, true
+}
+
+// formatChallenge formats a challenge for the API response
+func formatChallenge(ch db.Challenge) ChallengeResponse {
+	var criteria map[string]interface{}
+	if len(ch.Criteria) > 0 {
+		json.Unmarshal(ch.Criteria, &criteria)
+	}
+
+	return ChallengeResponse{
+		ID:          formatUUID(ch.ID),
+		TenantID:    formatUUID(ch.TenantID),
+		Name:        ch.Name,
+		Description: ch.Description,
+		Kind:        ch.Kind,
+		EventType:   ch.EventType,
+		Criteria:    criteria,
+		Target:      ch.Target,
+		Period:      ch.Period,
+		StartsAt:    formatTimestamp(ch.StartsAt),
+		EndsAt:      formatTimestamp(ch.EndsAt),
+		Active:      ch.Active,
+		CreatedAt:   formatTimestamp(ch.CreatedAt),
+		UpdatedAt:   formatTimestamp(ch.UpdatedAt),
+	}
+}
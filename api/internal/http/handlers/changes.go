@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/changes"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ChangesHandler handles the change feed endpoint
+type ChangesHandler struct {
+	service *changes.Service
+}
+
+// NewChangesHandler creates a new changes handler
+func NewChangesHandler(service *changes.Service) *ChangesHandler {
+	return &ChangesHandler{service: service}
+}
+
+// ChangeFeedResponse is a page of the change feed and the cursor to poll
+// from next
+type ChangeFeedResponse struct {
+	Changes    []ChangeResponse `json:"changes"`
+	NextCursor string           `json:"next_cursor"`
+	HasMore    bool             `json:"has_more"`
+}
+
+// ChangeResponse is a change to a customer or issuance, or a redemption
+type ChangeResponse struct {
+	ID         string                 `json:"id"`
+	Cursor     string                 `json:"cursor"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   string                 `json:"entity_id"`
+	Operation  string                 `json:"operation"`
+	Data       map[string]interface{} `json:"data"`
+	ChangedAt  string                 `json:"changed_at"`
+}
+
+// List handles GET /v1/tenants/:tid/changes
+// Lists changes to customers and issuances, and redemptions, after
+// ?cursor= (from the start of the retained changes when omitted), oldest
+// first. ?entity_types= is a comma-separated subset of customer, issuance
+// and redemption. Poll again with next_cursor; it is returned unchanged when
+// there is nothing new.
+func (h *ChangesHandler) List(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	cursor, err := changes.ParseCursor(c.Query("cursor"))
+	if err != nil {
+		httputil.BadRequest(c, "Invalid cursor", nil)
+		return
+	}
+
+	var entityTypes []string
+	if v := c.Query("entity_types"); v != "" {
+		for _, entityType := range strings.Split(v, ",") {
+			entityType = strings.TrimSpace(entityType)
+			if entityType != changes.EntityCustomer && entityType != changes.EntityIssuance && entityType != changes.EntityRedemption {
+				httputil.BadRequest(c, "Invalid entity_types. Use customer, issuance or redemption", nil)
+				return
+			}
+			entityTypes = append(entityTypes, entityType)
+		}
+	}
+
+	limit := changes.DefaultLimit
+	if v := c.Query("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > changes.MaxLimit {
+			httputil.BadRequest(c, "limit must be between 1 and "+strconv.Itoa(changes.MaxLimit), nil)
+			return
+		}
+	}
+
+	feed, err := h.service.List(c.Request.Context(), tenantUUID, cursor, entityTypes, limit)
+	if err != nil {
+		httputil.InternalError(c, "Failed to list changes")
+		return
+	}
+
+	changesList := make([]ChangeResponse, len(feed.Changes))
+	for i, change := range feed.Changes {
+		changesList[i] = ChangeResponse{
+			ID:         strconv.FormatInt(change.ID, 10),
+			Cursor:     change.Cursor.String(),
+			EntityType: change.EntityType,
+			EntityID:   formatUUID(change.EntityID),
+			Operation:  change.Operation,
+			Data:       change.Data,
+			ChangedAt:  formatTimestamp(change.ChangedAt),
+		}
+	}
+
+	httputil.Respond(c, 200, ChangeFeedResponse{
+		Changes:    changesList,
+		NextCursor: feed.NextCursor.String(),
+		HasMore:    feed.HasMore,
+	})
+}
@@ -1,9 +1,18 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/bmachimbira/loyalty/api/internal/customer"
 	"github.com/bmachimbira/loyalty/api/internal/db"
 	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/phone"
+	"github.com/bmachimbira/loyalty/api/internal/pii"
+	"github.com/bmachimbira/loyalty/api/internal/welcome"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -13,6 +22,7 @@ import (
 type CustomersHandler struct {
 	pool    *pgxpool.Pool
 	service *customer.Service
+	phones  *phone.Normalizer
 }
 
 // NewCustomersHandler creates a new customers handler
@@ -21,6 +31,7 @@ func NewCustomersHandler(pool *pgxpool.Pool) *CustomersHandler {
 	return &CustomersHandler{
 		pool:    pool,
 		service: customer.NewService(queries),
+		phones:  phone.NewNormalizer(queries),
 	}
 }
 
@@ -28,14 +39,105 @@ func NewCustomersHandler(pool *pgxpool.Pool) *CustomersHandler {
 type CreateCustomerRequest struct {
 	PhoneE164   string            `json:"phone_e164"`
 	ExternalRef string            `json:"external_ref"`
+	Name        string            `json:"name"`
 	Metadata    map[string]string `json:"metadata"`
 }
 
+// UpsertCustomerRequest represents the request to create or update a
+// customer by phone number or external reference
+type UpsertCustomerRequest struct {
+	PhoneE164   string `json:"phone_e164"`
+	ExternalRef string `json:"external_ref"`
+	Name        string `json:"name"`
+}
+
 // UpdateCustomerStatusRequest represents the request to update customer status
 type UpdateCustomerStatusRequest struct {
 	Status string `json:"status"`
 }
 
+// NewCustomerResponse is a customer just created
+type NewCustomerResponse struct {
+	ID          string `json:"id"`
+	TenantID    string `json:"tenant_id"`
+	PhoneE164   string `json:"phone_e164"`
+	ExternalRef string `json:"external_ref"`
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// UpsertCustomerResponse is a customer created or updated by an upsert
+type UpsertCustomerResponse struct {
+	NewCustomerResponse
+	Created bool `json:"created"`
+}
+
+// CustomerResponse is a customer in API responses
+type CustomerResponse struct {
+	ID          string `json:"id"`
+	TenantID    string `json:"tenant_id"`
+	PhoneE164   string `json:"phone_e164"`
+	ExternalRef string `json:"external_ref"`
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	FlaggedAt   string `json:"flagged_at"`
+	FlagReason  string `json:"flag_reason"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// CustomerProfileResponse is a customer with their welcome series, which is
+// null when they weren't enrolled in one
+type CustomerProfileResponse struct {
+	CustomerResponse
+	WelcomeSeries *WelcomeSeriesResponse `json:"welcome_series"`
+}
+
+// WelcomeSeriesResponse is a customer's welcome series. Message statuses
+// are empty until the message is done.
+type WelcomeSeriesResponse struct {
+	Status          string `json:"status"`
+	EnrolledAt      string `json:"enrolled_at"`
+	WelcomeStatus   string `json:"welcome_status"`
+	WelcomeSentAt   string `json:"welcome_sent_at"`
+	ReminderDueAt   string `json:"reminder_due_at"`
+	ReminderStatus  string `json:"reminder_status"`
+	ReminderSentAt  string `json:"reminder_sent_at"`
+	BonusEndsAt     string `json:"bonus_ends_at"`
+	FirstPurchaseAt string `json:"first_purchase_at"`
+	CompletedAt     string `json:"completed_at"`
+}
+
+// CustomerMatchResponse is a customer found by a search and how well they
+// matched
+type CustomerMatchResponse struct {
+	ID          string  `json:"id"`
+	PhoneE164   string  `json:"phone_e164"`
+	ExternalRef string  `json:"external_ref"`
+	Name        string  `json:"name"`
+	Status      string  `json:"status"`
+	FlaggedAt   string  `json:"flagged_at"`
+	CreatedAt   string  `json:"created_at"`
+	Score       float32 `json:"score"`
+}
+
+// ActivityEntryResponse is an entry in a customer's activity feed
+type ActivityEntryResponse struct {
+	Kind       string          `json:"kind"`
+	RefID      string          `json:"ref_id"`
+	OccurredAt string          `json:"occurred_at"`
+	Summary    string          `json:"summary"`
+	Details    json.RawMessage `json:"details"`
+}
+
+// CustomerStatusResponse is a customer's status after it was changed
+type CustomerStatusResponse struct {
+	ID        string `json:"id"`
+	TenantID  string `json:"tenant_id"`
+	Status    string `json:"status"`
+	UpdatedAt string `json:"updated_at"`
+}
+
 // Create handles POST /v1/tenants/:tid/customers
 func (h *CustomersHandler) Create(c *gin.Context) {
 	var req CreateCustomerRequest
@@ -50,15 +152,6 @@ func (h *CustomersHandler) Create(c *gin.Context) {
 		return
 	}
 
-	// Validate E.164 phone format if provided
-	if req.PhoneE164 != "" {
-		if err := httputil.ValidateE164Phone(req.PhoneE164); err != nil {
-			httputil.BadRequest(c, err.Error(), nil)
-			return
-		}
-		req.PhoneE164 = httputil.NormalizeE164Phone(req.PhoneE164)
-	}
-
 	tenantID := c.Param("tid")
 	if err := httputil.ValidateUUID(tenantID); err != nil {
 		httputil.BadRequest(c, "Invalid tenant ID", nil)
@@ -72,28 +165,103 @@ func (h *CustomersHandler) Create(c *gin.Context) {
 		return
 	}
 
+	// Normalize the phone number to E.164 in the tenant's region
+	if req.PhoneE164 != "" {
+		var ok bool
+		if req.PhoneE164, ok = normalizePhone(c, h.phones, tenantUUID, req.PhoneE164); !ok {
+			return
+		}
+	}
+
 	// Create customer using service
 	customer, err := h.service.CreateCustomer(c.Request.Context(), db.CreateCustomerParams{
 		TenantID:    tenantUUID,
-		PhoneE164:   pgtype.Text{String: req.PhoneE164, Valid: req.PhoneE164 != ""},
+		PhoneE164:   pii.Text{String: req.PhoneE164, Valid: req.PhoneE164 != ""},
 		ExternalRef: pgtype.Text{String: req.ExternalRef, Valid: req.ExternalRef != ""},
+		Name:        pgtype.Text{String: req.Name, Valid: req.Name != ""},
 	})
 	if err != nil {
 		httputil.InternalError(c, "Failed to create customer")
 		return
 	}
 
-	c.JSON(201, gin.H{
-		"id":           formatUUID(customer.ID),
-		"tenant_id":    formatUUID(customer.TenantID),
-		"phone_e164":   customer.PhoneE164.String,
-		"external_ref": customer.ExternalRef.String,
-		"status":       customer.Status,
-		"created_at":   formatTimestamp(customer.CreatedAt),
+	httputil.Respond(c, 201, NewCustomerResponse{
+		ID:          formatUUID(customer.ID),
+		TenantID:    formatUUID(customer.TenantID),
+		PhoneE164:   customer.PhoneE164.String,
+		ExternalRef: customer.ExternalRef.String,
+		Name:        customer.Name.String,
+		Status:      customer.Status,
+		CreatedAt:   formatTimestamp(customer.CreatedAt),
+	})
+}
+
+// Upsert handles PUT /v1/tenants/:tid/customers/upsert
+// Creates the customer, keyed on phone_e164 or else external_ref, or updates
+// the existing one. Safe to repeat and to call concurrently; the response
+// says whether the customer was created.
+func (h *CustomersHandler) Upsert(c *gin.Context) {
+	var req UpsertCustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.PhoneE164 == "" && req.ExternalRef == "" {
+		httputil.BadRequest(c, "Either phone_e164 or external_ref is required", nil)
+		return
+	}
+
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	if req.PhoneE164 != "" {
+		var ok bool
+		if req.PhoneE164, ok = normalizePhone(c, h.phones, tenantUUID, req.PhoneE164); !ok {
+			return
+		}
+	}
+
+	cust, created, err := h.service.UpsertCustomer(c.Request.Context(), tenantUUID, req.PhoneE164, req.ExternalRef, req.Name)
+	if err != nil {
+		if errors.Is(err, customer.ErrIdentifierTaken) {
+			httputil.Conflict(c, err.Error(), nil)
+			return
+		}
+		httputil.InternalError(c, "Failed to upsert customer")
+		return
+	}
+
+	status := 200
+	if created {
+		status = 201
+	}
+	httputil.Respond(c, status, UpsertCustomerResponse{
+		NewCustomerResponse: NewCustomerResponse{
+			ID:          formatUUID(cust.ID),
+			TenantID:    formatUUID(cust.TenantID),
+			PhoneE164:   cust.PhoneE164.String,
+			ExternalRef: cust.ExternalRef.String,
+			Name:        cust.Name.String,
+			Status:      cust.Status,
+			CreatedAt:   formatTimestamp(cust.CreatedAt),
+		},
+		Created: created,
 	})
 }
 
 // Get handles GET /v1/tenants/:tid/customers/:id
+// The profile includes the customer's welcome series, or null when they
+// weren't enrolled in one.
 func (h *CustomersHandler) Get(c *gin.Context) {
 	tenantID := c.Param("tid")
 	customerID := c.Param("id")
@@ -126,14 +294,50 @@ func (h *CustomersHandler) Get(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, gin.H{
-		"id":           formatUUID(customer.ID),
-		"tenant_id":    formatUUID(customer.TenantID),
-		"phone_e164":   customer.PhoneE164.String,
-		"external_ref": customer.ExternalRef.String,
-		"status":       customer.Status,
-		"created_at":   formatTimestamp(customer.CreatedAt),
-	})
+	series, enrolled, err := h.service.WelcomeSeries(c.Request.Context(), customer)
+	if err != nil {
+		httputil.InternalError(c, "Failed to get welcome series")
+		return
+	}
+	response := CustomerProfileResponse{CustomerResponse: formatCustomer(customer)}
+	if enrolled {
+		welcomeSeries := formatWelcomeSeries(series, time.Now())
+		response.WelcomeSeries = &welcomeSeries
+	}
+
+	httputil.Respond(c, 200, response)
+}
+
+// formatWelcomeSeries formats a customer's welcome series for their profile.
+// Message statuses are empty until the message is done.
+func formatWelcomeSeries(series db.WelcomeSeries, now time.Time) WelcomeSeriesResponse {
+	return WelcomeSeriesResponse{
+		Status:          welcome.Status(series, now),
+		EnrolledAt:      formatTimestamp(series.EnrolledAt),
+		WelcomeStatus:   series.WelcomeStatus.String,
+		WelcomeSentAt:   formatTimestamp(series.WelcomeSentAt),
+		ReminderDueAt:   formatTimestamp(series.ReminderDueAt),
+		ReminderStatus:  series.ReminderStatus.String,
+		ReminderSentAt:  formatTimestamp(series.ReminderSentAt),
+		BonusEndsAt:     formatTimestamp(series.BonusEndsAt),
+		FirstPurchaseAt: formatTimestamp(series.FirstPurchaseAt),
+		CompletedAt:     formatTimestamp(series.CompletedAt),
+	}
+}
+
+// formatCustomer formats a customer for the API response
+func formatCustomer(customer db.Customer) CustomerResponse {
+	return CustomerResponse{
+		ID:          formatUUID(customer.ID),
+		TenantID:    formatUUID(customer.TenantID),
+		PhoneE164:   customer.PhoneE164.String,
+		ExternalRef: customer.ExternalRef.String,
+		Name:        customer.Name.String,
+		Status:      customer.Status,
+		FlaggedAt:   formatTimestamp(customer.FlaggedAt),
+		FlagReason:  customer.FlagReason.String,
+		CreatedAt:   formatTimestamp(customer.CreatedAt),
+	}
 }
 
 // List handles GET /v1/tenants/:tid/customers
@@ -163,24 +367,126 @@ func (h *CustomersHandler) List(c *gin.Context) {
 	}
 
 	// Format response
-	customersList := make([]gin.H, len(customers))
+	customersList := make([]CustomerResponse, len(customers))
 	for i, customer := range customers {
-		customersList[i] = gin.H{
-			"id":           formatUUID(customer.ID),
-			"tenant_id":    formatUUID(customer.TenantID),
-			"phone_e164":   customer.PhoneE164.String,
-			"external_ref": customer.ExternalRef.String,
-			"status":       customer.Status,
-			"created_at":   formatTimestamp(customer.CreatedAt),
+		customersList[i] = formatCustomer(customer)
+	}
+
+	httputil.RespondList(c, customersList, httputil.NewPage(int64(total), limit, offset))
+}
+
+// Search handles GET /v1/tenants/:tid/customers/search?q=
+// Finds customers by partial phone number, external reference or name,
+// best matches first, for agents who only have part of a customer's details.
+func (h *CustomersHandler) Search(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		limit = 20
+	}
+
+	matches, err := h.service.SearchCustomers(c.Request.Context(), tenantUUID, c.Query("q"), limit)
+	if err != nil {
+		if errors.Is(err, customer.ErrSearchQueryTooShort) {
+			httputil.BadRequest(c, err.Error(), nil)
+			return
 		}
+		httputil.InternalError(c, "Failed to search customers")
+		return
 	}
 
-	c.JSON(200, gin.H{
-		"data":   customersList,
-		"total":  total,
-		"limit":  limit,
-		"offset": offset,
-	})
+	results := make([]CustomerMatchResponse, len(matches))
+	for i, match := range matches {
+		results[i] = CustomerMatchResponse{
+			ID:          formatUUID(match.Customer.ID),
+			PhoneE164:   match.Customer.PhoneE164.String,
+			ExternalRef: match.Customer.ExternalRef.String,
+			Name:        match.Customer.Name.String,
+			Status:      match.Customer.Status,
+			FlaggedAt:   formatTimestamp(match.Customer.FlaggedAt),
+			CreatedAt:   formatTimestamp(match.Customer.CreatedAt),
+			Score:       match.Score,
+		}
+	}
+
+	httputil.Respond(c, 200, results)
+}
+
+// Activity handles GET /v1/tenants/:tid/customers/:id/activity
+// Returns the customer's events, issuances, redemptions, messages and consent
+// changes as one feed, newest first. ?kind=event,redemption limits the feed
+// to those kinds.
+func (h *CustomersHandler) Activity(c *gin.Context) {
+	tenantID := c.Param("tid")
+	customerID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	if err := httputil.ValidateUUID(customerID); err != nil {
+		httputil.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+
+	var tenantUUID, customerUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+	if err := customerUUID.Scan(customerID); err != nil {
+		httputil.BadRequest(c, "Invalid customer ID format", nil)
+		return
+	}
+
+	cust, err := h.service.GetCustomerByID(c.Request.Context(), customerUUID, tenantUUID)
+	if err != nil {
+		httputil.NotFound(c, "Customer not found")
+		return
+	}
+
+	var kinds []string
+	if kind := c.Query("kind"); kind != "" {
+		kinds = strings.Split(kind, ",")
+	}
+
+	limit := c.DefaultQuery("limit", "50")
+	offset := c.DefaultQuery("offset", "0")
+
+	activity, total, err := h.service.Activity(c.Request.Context(), cust, kinds, limit, offset)
+	if err != nil {
+		if errors.Is(err, customer.ErrInvalidActivityKind) {
+			httputil.BadRequest(c, err.Error(), nil)
+			return
+		}
+		httputil.InternalError(c, "Failed to get customer activity")
+		return
+	}
+
+	activityList := make([]ActivityEntryResponse, len(activity))
+	for i, entry := range activity {
+		activityList[i] = ActivityEntryResponse{
+			Kind:       entry.Kind,
+			RefID:      entry.RefID,
+			OccurredAt: formatTimestamp(entry.OccurredAt),
+			Summary:    entry.Summary,
+			Details:    json.RawMessage(entry.Details),
+		}
+	}
+
+	httputil.RespondList(c, activityList, httputil.NewPage(total, limit, offset))
 }
 
 // UpdateStatus handles PATCH /v1/tenants/:tid/customers/:id/status
@@ -241,10 +547,10 @@ func (h *CustomersHandler) UpdateStatus(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, gin.H{
-		"id":         formatUUID(customer.ID),
-		"tenant_id":  formatUUID(customer.TenantID),
-		"status":     customer.Status,
-		"updated_at": formatTimestamp(customer.CreatedAt),
+	httputil.Respond(c, 200, CustomerStatusResponse{
+		ID:        formatUUID(customer.ID),
+		TenantID:  formatUUID(customer.TenantID),
+		Status:    customer.Status,
+		UpdatedAt: formatTimestamp(customer.CreatedAt),
 	})
 }
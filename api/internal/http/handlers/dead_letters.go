@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/deadletter"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/logging"
+	"github.com/bmachimbira/loyalty/api/internal/rules"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DeadLettersHandler handles inspection and retry of failed rule processing
+type DeadLettersHandler struct {
+	pool    *pgxpool.Pool
+	service *deadletter.Service
+	logger  *logging.Logger
+}
+
+// NewDeadLettersHandler creates a new dead letters handler
+func NewDeadLettersHandler(pool *pgxpool.Pool, rulesEngine *rules.Engine, logger *logging.Logger) *DeadLettersHandler {
+	return &DeadLettersHandler{
+		pool:    pool,
+		service: deadletter.NewService(db.New(pool), rulesEngine),
+		logger:  logger,
+	}
+}
+
+// DeadLetterResponse is an event whose rule processing failed
+type DeadLetterResponse struct {
+	ID            string `json:"id"`
+	TenantID      string `json:"tenant_id"`
+	EventID       string `json:"event_id"`
+	Error         string `json:"error"`
+	Attempts      int32  `json:"attempts"`
+	Status        string `json:"status"`
+	LastAttemptAt string `json:"last_attempt_at"`
+	ResolvedAt    string `json:"resolved_at"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// RetriedDeadLetterResponse is a retried dead letter with the issuances the
+// retry created
+type RetriedDeadLetterResponse struct {
+	DeadLetterResponse
+	Issuances []RetriedIssuanceResponse `json:"issuances"`
+}
+
+// RetriedIssuanceResponse is an issuance created by retrying a dead letter
+type RetriedIssuanceResponse struct {
+	ID         string `json:"id"`
+	RewardID   string `json:"reward_id"`
+	CampaignID string `json:"campaign_id"`
+	Status     string `json:"status"`
+	IssuedAt   string `json:"issued_at"`
+}
+
+// List handles GET /v1/tenants/:tid/dead-letters
+func (h *DeadLettersHandler) List(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	limit := c.DefaultQuery("limit", "50")
+	offset := c.DefaultQuery("offset", "0")
+	status := c.Query("status")
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	deadLetters, total, err := h.service.List(c.Request.Context(), tenantUUID, status, limit, offset)
+	if err != nil {
+		if errors.Is(err, deadletter.ErrInvalidStatus) {
+			httputil.BadRequest(c, "Invalid status filter", nil)
+			return
+		}
+		httputil.InternalError(c, "Failed to list dead letters")
+		return
+	}
+
+	deadLettersList := make([]DeadLetterResponse, len(deadLetters))
+	for i, deadLetter := range deadLetters {
+		deadLettersList[i] = formatDeadLetter(deadLetter)
+	}
+
+	httputil.RespondList(c, deadLettersList, httputil.NewPage(total, limit, offset))
+}
+
+// Get handles GET /v1/tenants/:tid/dead-letters/:id
+func (h *DeadLettersHandler) Get(c *gin.Context) {
+	tenantUUID, idUUID, ok := parseDeadLetterParams(c)
+	if !ok {
+		return
+	}
+
+	deadLetter, err := h.service.Get(c.Request.Context(), tenantUUID, idUUID)
+	if err != nil {
+		if errors.Is(err, deadletter.ErrNotFound) {
+			httputil.NotFound(c, "Dead letter not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get dead letter")
+		return
+	}
+
+	httputil.Respond(c, 200, formatDeadLetter(deadLetter))
+}
+
+// Retry handles POST /v1/tenants/:tid/dead-letters/:id/retry
+func (h *DeadLettersHandler) Retry(c *gin.Context) {
+	tenantUUID, idUUID, ok := parseDeadLetterParams(c)
+	if !ok {
+		return
+	}
+
+	deadLetter, issuances, err := h.service.Retry(c.Request.Context(), tenantUUID, idUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, deadletter.ErrNotFound):
+			httputil.NotFound(c, "Dead letter not found")
+		case errors.Is(err, deadletter.ErrNotRetryable):
+			httputil.Conflict(c, "Dead letter is not pending", nil)
+		default:
+			h.logger.Error("dead letter retry failed", "dead_letter_id", formatUUID(idUUID), "error", err)
+			httputil.InternalError(c, "Failed to retry dead letter")
+		}
+		return
+	}
+
+	issuancesList := make([]RetriedIssuanceResponse, len(issuances))
+	for i, issuance := range issuances {
+		issuancesList[i] = RetriedIssuanceResponse{
+			ID:         formatUUID(issuance.ID),
+			RewardID:   formatUUID(issuance.RewardID),
+			CampaignID: formatUUID(issuance.CampaignID),
+			Status:     issuance.Status,
+			IssuedAt:   formatTimestamp(issuance.IssuedAt),
+		}
+	}
+
+	httputil.Respond(c, 200, RetriedDeadLetterResponse{
+		DeadLetterResponse: formatDeadLetter(deadLetter),
+		Issuances:          issuancesList,
+	})
+}
+
+// parseDeadLetterParams validates and parses the tenant and dead letter IDs
+func parseDeadLetterParams(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, idUUID pgtype.UUID
+
+	tenantID := c.Param("tid")
+	id := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, idUUID, false
+	}
+	if err := httputil.ValidateUUID(id); err != nil {
+		httputil.BadRequest(c, "Invalid dead letter ID", nil)
+		return tenantUUID, idUUID, false
+	}
+
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, idUUID, false
+	}
+	if err := idUUID.Scan(id); err != nil {
+		httputil.BadRequest(c, "Invalid dead letter ID format", nil)
+		return tenantUUID, idUUID, false
+	}
+
+	return tenantUUID, idUUID, true
+}
+
+// formatDeadLetter formats a dead letter for the API response
+func formatDeadLetter(deadLetter db.DeadLetter) DeadLetterResponse {
+	return DeadLetterResponse{
+		ID:            formatUUID(deadLetter.ID),
+		TenantID:      formatUUID(deadLetter.TenantID),
+		EventID:       formatUUID(deadLetter.EventID),
+		Error:         deadLetter.Error,
+		Attempts:      deadLetter.Attempts,
+		Status:        deadLetter.Status,
+		LastAttemptAt: formatTimestamp(deadLetter.LastAttemptAt),
+		ResolvedAt:    formatTimestamp(deadLetter.ResolvedAt),
+		CreatedAt:     formatTimestamp(deadLetter.CreatedAt),
+	}
+}
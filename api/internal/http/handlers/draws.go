@@ -0,0 +1,404 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/draw"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DrawsHandler handles prize draw endpoints
+type DrawsHandler struct {
+	service *draw.Service
+}
+
+// NewDrawsHandler creates a new draws handler
+func NewDrawsHandler(service *draw.Service) *DrawsHandler {
+	return &DrawsHandler{
+		service: service,
+	}
+}
+
+// CreateDrawRequest represents the request to create a draw
+type CreateDrawRequest struct {
+	CampaignID      string                 `json:"campaign_id" binding:"required"`
+	Name            string                 `json:"name" binding:"required"`
+	EventType       string                 `json:"event_type" binding:"required"`
+	Criteria        map[string]interface{} `json:"criteria"`
+	EntriesPerEvent *int32                 `json:"entries_per_event"`
+	Winners         int32                  `json:"winners" binding:"required"`
+	DrawAt          *time.Time             `json:"draw_at"`
+}
+
+// RunDrawRequest represents the request to run a draw
+type RunDrawRequest struct {
+	// Seed is the random seed to draw with; a random one is used if empty
+	Seed string `json:"seed"`
+}
+
+// DrawResponse is a draw in API responses. EntryCount is set once the draw
+// has run.
+type DrawResponse struct {
+	ID              string                 `json:"id"`
+	TenantID        string                 `json:"tenant_id"`
+	CampaignID      string                 `json:"campaign_id"`
+	Name            string                 `json:"name"`
+	EventType       string                 `json:"event_type"`
+	Criteria        map[string]interface{} `json:"criteria"`
+	EntriesPerEvent int32                  `json:"entries_per_event"`
+	Winners         int32                  `json:"winners"`
+	DrawAt          string                 `json:"draw_at"`
+	Status          string                 `json:"status"`
+	Seed            string                 `json:"seed"`
+	EntriesHash     string                 `json:"entries_hash"`
+	EntryCount      *int32                 `json:"entry_count,omitempty"`
+	DrawnAt         string                 `json:"drawn_at"`
+	CreatedAt       string                 `json:"created_at"`
+	UpdatedAt       string                 `json:"updated_at"`
+}
+
+// DrawResultResponse is a draw that has just run, with its winners and the
+// issuances of their prizes
+type DrawResultResponse struct {
+	DrawResponse
+	WinnerCustomerIDs []string `json:"winner_customer_ids"`
+	IssuanceIDs       []string `json:"issuance_ids"`
+}
+
+// DrawWinnersResponse is a draw's winners report
+type DrawWinnersResponse struct {
+	DrawResponse
+	WinnersReport []DrawWinnerResponse `json:"winners_report"`
+}
+
+// DrawWinnerResponse is a winner in a draw's winners report
+type DrawWinnerResponse struct {
+	Position    int32               `json:"position"`
+	CustomerID  string              `json:"customer_id"`
+	PhoneE164   string              `json:"phone_e164"`
+	ExternalRef string              `json:"external_ref"`
+	EventID     string              `json:"event_id"`
+	Prizes      []DrawPrizeResponse `json:"prizes"`
+}
+
+// DrawPrizeResponse is a prize issued to a draw winner
+type DrawPrizeResponse struct {
+	IssuanceID string `json:"issuance_id"`
+	Status     string `json:"status"`
+	RewardName string `json:"reward_name"`
+	FaceAmount string `json:"face_amount"`
+	Currency   string `json:"currency"`
+}
+
+// Create handles POST /v1/tenants/:tid/draws
+func (h *DrawsHandler) Create(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var req CreateDrawRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := httputil.ValidateUUID(req.CampaignID); err != nil {
+		httputil.BadRequest(c, "Invalid campaign ID", nil)
+		return
+	}
+
+	var tenantUUID, campaignUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+	if err := campaignUUID.Scan(req.CampaignID); err != nil {
+		httputil.BadRequest(c, "Invalid campaign ID format", nil)
+		return
+	}
+
+	params := draw.Params{
+		TenantID:        tenantUUID,
+		CampaignID:      campaignUUID,
+		Name:            req.Name,
+		EventType:       req.EventType,
+		EntriesPerEvent: 1,
+		Winners:         req.Winners,
+		DrawAt:          req.DrawAt,
+	}
+	if req.EntriesPerEvent != nil {
+		params.EntriesPerEvent = *req.EntriesPerEvent
+	}
+	if req.Criteria != nil {
+		criteria, err := json.Marshal(req.Criteria)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid criteria format", nil)
+			return
+		}
+		params.Criteria = criteria
+	}
+	if err := params.Validate(); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	created, err := h.service.Create(c.Request.Context(), params)
+	if err != nil {
+		switch {
+		case errors.Is(err, draw.ErrCampaignNotFound):
+			httputil.NotFound(c, "Campaign not found")
+		case errors.Is(err, draw.ErrUnknownEventType):
+			httputil.BadRequest(c, "Unknown event type", nil)
+		default:
+			httputil.InternalError(c, "Failed to create draw")
+		}
+		return
+	}
+
+	httputil.Respond(c, 201, formatDraw(created))
+}
+
+// List handles GET /v1/tenants/:tid/draws
+// Filter to one campaign with ?campaign_id=.
+func (h *DrawsHandler) List(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID, campaignUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+	if campaignID := c.Query("campaign_id"); campaignID != "" {
+		if err := httputil.ValidateUUID(campaignID); err != nil {
+			httputil.BadRequest(c, "Invalid campaign ID", nil)
+			return
+		}
+		if err := campaignUUID.Scan(campaignID); err != nil {
+			httputil.BadRequest(c, "Invalid campaign ID format", nil)
+			return
+		}
+	}
+
+	draws, err := h.service.List(c.Request.Context(), tenantUUID, campaignUUID)
+	if err != nil {
+		httputil.InternalError(c, "Failed to list draws")
+		return
+	}
+
+	drawsList := make([]DrawResponse, len(draws))
+	for i, d := range draws {
+		drawsList[i] = formatDraw(d)
+	}
+
+	httputil.RespondList(c, drawsList, httputil.Page{Total: int64(len(drawsList))})
+}
+
+// Get handles GET /v1/tenants/:tid/draws/:id
+func (h *DrawsHandler) Get(c *gin.Context) {
+	tenantUUID, drawUUID, ok := parseDrawParams(c)
+	if !ok {
+		return
+	}
+
+	d, err := h.service.Get(c.Request.Context(), tenantUUID, drawUUID)
+	if err != nil {
+		if errors.Is(err, draw.ErrDrawNotFound) {
+			httputil.NotFound(c, "Draw not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get draw")
+		return
+	}
+
+	httputil.Respond(c, 200, formatDraw(d))
+}
+
+// Cancel handles POST /v1/tenants/:tid/draws/:id/cancel
+func (h *DrawsHandler) Cancel(c *gin.Context) {
+	tenantUUID, drawUUID, ok := parseDrawParams(c)
+	if !ok {
+		return
+	}
+
+	cancelled, err := h.service.Cancel(c.Request.Context(), tenantUUID, drawUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, draw.ErrDrawNotFound):
+			httputil.NotFound(c, "Draw not found")
+		case errors.Is(err, draw.ErrDrawNotOpen):
+			httputil.Conflict(c, "Draw is not open", nil)
+		default:
+			httputil.InternalError(c, "Failed to cancel draw")
+		}
+		return
+	}
+
+	httputil.Respond(c, 200, formatDraw(cancelled))
+}
+
+// Run handles POST /v1/tenants/:tid/draws/:id/draw
+// Picks the winners now instead of waiting for draw_at. Prizes are granted
+// by rules on the draw_won events.
+func (h *DrawsHandler) Run(c *gin.Context) {
+	tenantUUID, drawUUID, ok := parseDrawParams(c)
+	if !ok {
+		return
+	}
+
+	var req RunDrawRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httputil.BadRequest(c, "Invalid request body", err.Error())
+			return
+		}
+	}
+
+	result, err := h.service.Draw(c.Request.Context(), tenantUUID, drawUUID, req.Seed, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, draw.ErrDrawNotFound):
+			httputil.NotFound(c, "Draw not found")
+		case errors.Is(err, draw.ErrDrawNotOpen):
+			httputil.Conflict(c, "Draw is not open", nil)
+		default:
+			httputil.InternalError(c, "Failed to run draw")
+		}
+		return
+	}
+
+	winners := make([]string, len(result.Winners))
+	for i, w := range result.Winners {
+		winners[i] = formatUUID(w)
+	}
+	issuances := make([]string, len(result.Issuances))
+	for i, issuance := range result.Issuances {
+		issuances[i] = formatUUID(issuance.ID)
+	}
+
+	httputil.Respond(c, 200, DrawResultResponse{
+		DrawResponse:      formatDraw(result.Draw),
+		WinnerCustomerIDs: winners,
+		IssuanceIDs:       issuances,
+	})
+}
+
+// Winners handles GET /v1/tenants/:tid/draws/:id/winners
+// The winners report: the draw's seed and entries hash for audit, and each
+// winner with the prizes issued to them.
+func (h *DrawsHandler) Winners(c *gin.Context) {
+	tenantUUID, drawUUID, ok := parseDrawParams(c)
+	if !ok {
+		return
+	}
+
+	d, err := h.service.Get(c.Request.Context(), tenantUUID, drawUUID)
+	if err != nil {
+		if errors.Is(err, draw.ErrDrawNotFound) {
+			httputil.NotFound(c, "Draw not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get draw")
+		return
+	}
+
+	winners, err := h.service.Winners(c.Request.Context(), tenantUUID, drawUUID)
+	if err != nil {
+		httputil.InternalError(c, "Failed to list draw winners")
+		return
+	}
+
+	winnersList := make([]DrawWinnerResponse, len(winners))
+	for i, w := range winners {
+		prizes := make([]DrawPrizeResponse, len(w.Prizes))
+		for j, p := range w.Prizes {
+			prizes[j] = DrawPrizeResponse{
+				IssuanceID: formatUUID(p.IssuanceID),
+				Status:     p.Status,
+				RewardName: p.RewardName,
+				FaceAmount: formatNumeric(p.FaceAmount),
+				Currency:   p.Currency,
+			}
+		}
+		winnersList[i] = DrawWinnerResponse{
+			Position:    w.Position,
+			CustomerID:  formatUUID(w.CustomerID),
+			PhoneE164:   w.PhoneE164,
+			ExternalRef: w.ExternalRef,
+			EventID:     formatUUID(w.EventID),
+			Prizes:      prizes,
+		}
+	}
+
+	httputil.Respond(c, 200, DrawWinnersResponse{
+		DrawResponse:  formatDraw(d),
+		WinnersReport: winnersList,
+	})
+}
+
+// parseDrawParams validates and parses the tenant and draw IDs from the path
+func parseDrawParams(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, drawUUID pgtype.UUID
+
+	tenantID := c.Param("tid")
+	drawID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, drawUUID, false
+	}
+	if err := httputil.ValidateUUID(drawID); err != nil {
+		httputil.BadRequest(c, "Invalid draw ID", nil)
+		return tenantUUID, drawUUID, false
+	}
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, drawUUID, false
+	}
+	if err := drawUUID.Scan(drawID); err != nil {
+		httputil.BadRequest(c, "Invalid draw ID format", nil)
+		return tenantUUID, drawUUID, false
+	}
+
+	return tenantUUID, drawUUID, true
+}
+
+// formatDraw formats a draw for the API response
+func formatDraw(d db.Draw) DrawResponse {
+	var criteria map[string]interface{}
+	if len(d.Criteria) > 0 {
+		json.Unmarshal(d.Criteria, &criteria)
+	}
+
+	response := DrawResponse{
+		ID:              formatUUID(d.ID),
+		TenantID:        formatUUID(d.TenantID),
+		CampaignID:      formatUUID(d.CampaignID),
+		Name:            d.Name,
+		EventType:       d.EventType,
+		Criteria:        criteria,
+		EntriesPerEvent: d.EntriesPerEvent,
+		Winners:         d.Winners,
+		DrawAt:          formatTimestamp(d.DrawAt),
+		Status:          d.Status,
+		Seed:            d.Seed.String,
+		EntriesHash:     d.EntriesHash.String,
+		DrawnAt:         formatTimestamp(d.DrawnAt),
+		CreatedAt:       formatTimestamp(d.CreatedAt),
+		UpdatedAt:       formatTimestamp(d.UpdatedAt),
+	}
+	if d.EntryCount.Valid {
+		response.EntryCount = &d.EntryCount.Int32
+	}
+	return response
+}
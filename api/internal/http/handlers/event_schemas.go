@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/event"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EventSchemasHandler handles the per-tenant event schema registry
+type EventSchemasHandler struct {
+	pool    *pgxpool.Pool
+	service *event.Service
+}
+
+// NewEventSchemasHandler creates a new event schemas handler
+func NewEventSchemasHandler(pool *pgxpool.Pool) *EventSchemasHandler {
+	return &EventSchemasHandler{
+		pool:    pool,
+		service: event.NewService(db.New(pool)),
+	}
+}
+
+// PutEventSchemaRequest represents the request to register an event schema
+type PutEventSchemaRequest struct {
+	Schema json.RawMessage `json:"schema" binding:"required"`
+	Strict bool            `json:"strict"`
+}
+
+// EventSchemaResponse is an event schema in API responses
+type EventSchemaResponse struct {
+	ID        string          `json:"id"`
+	TenantID  string          `json:"tenant_id"`
+	EventType string          `json:"event_type"`
+	Schema    json.RawMessage `json:"schema"`
+	Strict    bool            `json:"strict"`
+	CreatedAt string          `json:"created_at"`
+	UpdatedAt string          `json:"updated_at"`
+}
+
+// EventSchemaDeletedResponse confirms that an event schema was deleted
+type EventSchemaDeletedResponse struct {
+	EventType string `json:"event_type"`
+	Message   string `json:"message"`
+}
+
+// Put handles PUT /v1/tenants/:tid/event-schemas/:event_type
+func (h *EventSchemasHandler) Put(c *gin.Context) {
+	tenantID := c.Param("tid")
+	eventType := c.Param("event_type")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var req PutEventSchemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	if err := h.service.ValidateEventType(c.Request.Context(), tenantUUID, eventType); err != nil {
+		if errors.Is(err, event.ErrUnknownEventType) {
+			httputil.BadRequest(c, err.Error(), nil)
+			return
+		}
+		httputil.InternalError(c, "Failed to validate event type")
+		return
+	}
+
+	registered, err := h.service.UpsertSchema(c.Request.Context(), tenantUUID, eventType, req.Schema, req.Strict)
+	if err != nil {
+		if errors.Is(err, event.ErrInvalidSchema) {
+			httputil.BadRequest(c, err.Error(), nil)
+			return
+		}
+		httputil.InternalError(c, "Failed to save event schema")
+		return
+	}
+
+	httputil.Respond(c, 200, formatEventSchema(registered))
+}
+
+// List handles GET /v1/tenants/:tid/event-schemas
+func (h *EventSchemasHandler) List(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	schemas, err := h.service.ListSchemas(c.Request.Context(), tenantUUID)
+	if err != nil {
+		httputil.InternalError(c, "Failed to list event schemas")
+		return
+	}
+
+	schemasList := make([]EventSchemaResponse, len(schemas))
+	for i, schema := range schemas {
+		schemasList[i] = formatEventSchema(schema)
+	}
+
+	httputil.RespondList(c, schemasList, httputil.Page{Total: int64(len(schemas))})
+}
+
+// Get handles GET /v1/tenants/:tid/event-schemas/:event_type
+func (h *EventSchemasHandler) Get(c *gin.Context) {
+	tenantID := c.Param("tid")
+	eventType := c.Param("event_type")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	registered, err := h.service.GetSchema(c.Request.Context(), tenantUUID, eventType)
+	if err != nil {
+		if errors.Is(err, event.ErrSchemaNotFound) {
+			httputil.NotFound(c, "Event schema not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get event schema")
+		return
+	}
+
+	httputil.Respond(c, 200, formatEventSchema(registered))
+}
+
+// Delete handles DELETE /v1/tenants/:tid/event-schemas/:event_type
+func (h *EventSchemasHandler) Delete(c *gin.Context) {
+	tenantID := c.Param("tid")
+	eventType := c.Param("event_type")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	if err := h.service.DeleteSchema(c.Request.Context(), tenantUUID, eventType); err != nil {
+		httputil.InternalError(c, "Failed to delete event schema")
+		return
+	}
+
+	httputil.Respond(c, 200, EventSchemaDeletedResponse{
+		EventType: eventType,
+		Message:   "Event schema deleted successfully",
+	})
+}
+
+// formatEventSchema formats an event schema for the API response
+func formatEventSchema(schema db.EventSchema) EventSchemaResponse {
+	return EventSchemaResponse{
+		ID:        formatUUID(schema.ID),
+		TenantID:  formatUUID(schema.TenantID),
+		EventType: schema.EventType,
+		Schema:    json.RawMessage(schema.Schema),
+		Strict:    schema.Strict,
+		CreatedAt: formatTimestamp(schema.CreatedAt),
+		UpdatedAt: formatTimestamp(schema.UpdatedAt),
+	}
+}
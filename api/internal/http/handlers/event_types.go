@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/event"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EventTypesHandler handles tenant-defined event type endpoints
+type EventTypesHandler struct {
+	pool    *pgxpool.Pool
+	service *event.Service
+}
+
+// NewEventTypesHandler creates a new event types handler
+func NewEventTypesHandler(pool *pgxpool.Pool) *EventTypesHandler {
+	return &EventTypesHandler{
+		pool:    pool,
+		service: event.NewService(db.New(pool)),
+	}
+}
+
+// CreateEventTypeRequest represents the request to create an event type
+type CreateEventTypeRequest struct {
+	Name               string                   `json:"name" binding:"required"`
+	Description        string                   `json:"description"`
+	ExpectedProperties []event.ExpectedProperty `json:"expected_properties"`
+	Active             *bool                    `json:"active"`
+}
+
+// UpdateEventTypeRequest represents the request to update an event type
+type UpdateEventTypeRequest struct {
+	Description        *string                   `json:"description"`
+	ExpectedProperties *[]event.ExpectedProperty `json:"expected_properties"`
+	Active             *bool                     `json:"active"`
+}
+
+// EventTypeResponse is an event type in API responses. Builtin types have
+// only a name.
+type EventTypeResponse struct {
+	ID                 string                   `json:"id,omitempty"`
+	TenantID           string                   `json:"tenant_id,omitempty"`
+	Name               string                   `json:"name"`
+	Description        string                   `json:"description,omitempty"`
+	ExpectedProperties []event.ExpectedProperty `json:"expected_properties,omitempty"`
+	Builtin            bool                     `json:"builtin"`
+	Active             bool                     `json:"active"`
+	CreatedAt          string                   `json:"created_at,omitempty"`
+	UpdatedAt          string                   `json:"updated_at,omitempty"`
+}
+
+// EventTypeDeactivatedResponse confirms that an event type was deactivated
+type EventTypeDeactivatedResponse struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+// Create handles POST /v1/tenants/:tid/event-types
+func (h *EventTypesHandler) Create(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var req CreateEventTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	params := event.EventTypeParams{
+		TenantID:           tenantUUID,
+		Name:               req.Name,
+		Description:        req.Description,
+		ExpectedProperties: req.ExpectedProperties,
+		Active:             active,
+	}
+	if err := params.Validate(); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	created, err := h.service.CreateEventType(c.Request.Context(), params)
+	if err != nil {
+		if errors.Is(err, event.ErrEventTypeExists) {
+			httputil.Conflict(c, "Event type already exists", nil)
+			return
+		}
+		httputil.InternalError(c, "Failed to create event type")
+		return
+	}
+
+	httputil.Respond(c, 201, formatEventType(created))
+}
+
+// List handles GET /v1/tenants/:tid/event-types
+// Built-in event types are listed alongside tenant-defined ones.
+func (h *EventTypesHandler) List(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	eventTypes, err := h.service.ListEventTypes(c.Request.Context(), tenantUUID)
+	if err != nil {
+		httputil.InternalError(c, "Failed to list event types")
+		return
+	}
+
+	builtin := httputil.BuiltinEventTypes()
+	typesList := make([]EventTypeResponse, 0, len(builtin)+len(eventTypes))
+	for _, name := range builtin {
+		typesList = append(typesList, EventTypeResponse{
+			Name:    name,
+			Builtin: true,
+			Active:  true,
+		})
+	}
+	for _, eventType := range eventTypes {
+		typesList = append(typesList, formatEventType(eventType))
+	}
+
+	httputil.RespondList(c, typesList, httputil.Page{Total: int64(len(typesList))})
+}
+
+// Get handles GET /v1/tenants/:tid/event-types/:name
+func (h *EventTypesHandler) Get(c *gin.Context) {
+	tenantID := c.Param("tid")
+	name := c.Param("name")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	eventType, err := h.service.GetEventType(c.Request.Context(), tenantUUID, name)
+	if err != nil {
+		if errors.Is(err, event.ErrEventTypeNotFound) {
+			httputil.NotFound(c, "Event type not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get event type")
+		return
+	}
+
+	httputil.Respond(c, 200, formatEventType(eventType))
+}
+
+// Update handles PATCH /v1/tenants/:tid/event-types/:name
+func (h *EventTypesHandler) Update(c *gin.Context) {
+	tenantID := c.Param("tid")
+	name := c.Param("name")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var req UpdateEventTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	// Get current event type so omitted fields are preserved
+	existing, err := h.service.GetEventType(c.Request.Context(), tenantUUID, name)
+	if err != nil {
+		if errors.Is(err, event.ErrEventTypeNotFound) {
+			httputil.NotFound(c, "Event type not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get event type")
+		return
+	}
+
+	params := event.EventTypeParams{
+		TenantID:           tenantUUID,
+		Name:               existing.Name,
+		Description:        existing.Description.String,
+		ExpectedProperties: event.ParseExpectedProperties(existing.ExpectedProperties),
+		Active:             existing.Active,
+	}
+	if req.Description != nil {
+		params.Description = *req.Description
+	}
+	if req.ExpectedProperties != nil {
+		params.ExpectedProperties = *req.ExpectedProperties
+	}
+	if req.Active != nil {
+		params.Active = *req.Active
+	}
+
+	if err := params.Validate(); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	updated, err := h.service.UpdateEventType(c.Request.Context(), params)
+	if err != nil {
+		if errors.Is(err, event.ErrEventTypeNotFound) {
+			httputil.NotFound(c, "Event type not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to update event type")
+		return
+	}
+
+	httputil.Respond(c, 200, formatEventType(updated))
+}
+
+// Delete handles DELETE /v1/tenants/:tid/event-types/:name
+// Event types are deactivated rather than deleted so historical events keep their meaning.
+func (h *EventTypesHandler) Delete(c *gin.Context) {
+	tenantID := c.Param("tid")
+	name := c.Param("name")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	if err := h.service.DeactivateEventType(c.Request.Context(), tenantUUID, name); err != nil {
+		httputil.InternalError(c, "Failed to deactivate event type")
+		return
+	}
+
+	httputil.Respond(c, 200, EventTypeDeactivatedResponse{
+		Name:    name,
+		Message: "Event type deactivated successfully",
+	})
+}
+
+// formatEventType formats a tenant-defined event type for the API response
+func formatEventType(eventType db.EventType) EventTypeResponse {
+	return EventTypeResponse{
+		ID:                 formatUUID(eventType.ID),
+		TenantID:           formatUUID(eventType.TenantID),
+		Name:               eventType.Name,
+		Description:        eventType.Description.String,
+		ExpectedProperties: event.ParseExpectedProperties(eventType.ExpectedProperties),
+		Builtin:            false,
+		Active:             eventType.Active,
+		CreatedAt:          formatTimestamp(eventType.CreatedAt),
+		UpdatedAt:          formatTimestamp(eventType.UpdatedAt),
+	}
+}
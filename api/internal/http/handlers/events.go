@@ -2,13 +2,18 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/deadletter"
 	"github.com/bmachimbira/loyalty/api/internal/event"
 	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/location"
 	"github.com/bmachimbira/loyalty/api/internal/logging"
+	"github.com/bmachimbira/loyalty/api/internal/metering"
 	"github.com/bmachimbira/loyalty/api/internal/rules"
+	"github.com/bmachimbira/loyalty/api/internal/winback"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -21,6 +26,9 @@ type EventsHandler struct {
 	queries     *db.Queries
 	service     *event.Service
 	rulesEngine *rules.Engine
+	deadLetters *deadletter.Service
+	locations   *location.Service
+	meter       *metering.Meter
 	logger      *logging.Logger
 }
 
@@ -32,10 +40,17 @@ func NewEventsHandler(pool *pgxpool.Pool, rulesEngine *rules.Engine, logger *log
 		queries:     queries,
 		service:     event.NewService(queries),
 		rulesEngine: rulesEngine,
+		deadLetters: deadletter.NewService(queries, rulesEngine),
+		locations:   location.NewService(queries),
 		logger:      logger,
 	}
 }
 
+// SetMeter meters ingested events for tenant billing
+func (h *EventsHandler) SetMeter(meter *metering.Meter) {
+	h.meter = meter
+}
+
 // CreateEventRequest represents the request to create an event
 type CreateEventRequest struct {
 	CustomerID string                 `json:"customer_id" binding:"required"`
@@ -43,6 +58,87 @@ type CreateEventRequest struct {
 	Properties map[string]interface{} `json:"properties"`
 	OccurredAt *time.Time             `json:"occurred_at"`
 	Source     string                 `json:"source"`
+	LocationID string                 `json:"location_id"`
+	Latitude   *float64               `json:"latitude"`
+	Longitude  *float64               `json:"longitude"`
+}
+
+// EventResponse is an event in API responses. DuplicateOf is set for
+// near-duplicates of an earlier event.
+type EventResponse struct {
+	ID             string                 `json:"id"`
+	TenantID       string                 `json:"tenant_id"`
+	CustomerID     string                 `json:"customer_id"`
+	EventType      string                 `json:"event_type"`
+	Properties     map[string]interface{} `json:"properties"`
+	OccurredAt     string                 `json:"occurred_at"`
+	Source         string                 `json:"source"`
+	IdempotencyKey string                 `json:"idempotency_key"`
+	CreatedAt      string                 `json:"created_at"`
+	DuplicateOf    string                 `json:"duplicate_of,omitempty"`
+}
+
+// EventDetailResponse is an event with where it happened and the issuances
+// it triggered. SchemaErrors is set for events accepted despite failing
+// schema validation, and Suppressed for near-duplicates that weren't stored.
+type EventDetailResponse struct {
+	EventResponse
+	LocationID   string                  `json:"location_id,omitempty"`
+	Latitude     *float64                `json:"latitude,omitempty"`
+	Longitude    *float64                `json:"longitude,omitempty"`
+	SchemaErrors []interface{}           `json:"schema_errors,omitempty"`
+	Suppressed   bool                    `json:"suppressed,omitempty"`
+	Issuances    []EventIssuanceResponse `json:"issuances"`
+}
+
+// EventIssuanceResponse is an issuance triggered by an event
+type EventIssuanceResponse struct {
+	ID         string `json:"id"`
+	RewardID   string `json:"reward_id"`
+	CampaignID string `json:"campaign_id"`
+	Status     string `json:"status"`
+	Currency   string `json:"currency"`
+	FaceAmount string `json:"face_amount"`
+	IssuedAt   string `json:"issued_at"`
+}
+
+// ReversalResponse is the reversal of an event and what happened to each of
+// its issuances
+type ReversalResponse struct {
+	ID              string                     `json:"id"`
+	ReversalEventID string                     `json:"reversal_event_id"`
+	OriginalEventID string                     `json:"original_event_id"`
+	Reason          string                     `json:"reason"`
+	CreatedAt       string                     `json:"created_at"`
+	Issuances       []ReversedIssuanceResponse `json:"issuances"`
+}
+
+// ReversedIssuanceResponse is an issuance undone by a reversal
+type ReversedIssuanceResponse struct {
+	IssuanceID     string `json:"issuance_id"`
+	PreviousStatus string `json:"previous_status"`
+	Action         string `json:"action"`
+	ReleasedAmount string `json:"released_amount"`
+	Currency       string `json:"currency"`
+}
+
+// DuplicateStatsResponse is the near-duplicate events flagged and
+// suppressed over a period
+type DuplicateStatsResponse struct {
+	From       string                      `json:"from"`
+	To         string                      `json:"to"`
+	Flagged    int64                       `json:"flagged"`
+	Suppressed int64                       `json:"suppressed"`
+	Days       []DuplicateDayStatsResponse `json:"days"`
+}
+
+// DuplicateDayStatsResponse is the near-duplicates of an event type flagged
+// and suppressed on a day
+type DuplicateDayStatsResponse struct {
+	Day        string `json:"day"`
+	EventType  string `json:"event_type"`
+	Flagged    int64  `json:"flagged"`
+	Suppressed int64  `json:"suppressed"`
 }
 
 // Create handles POST /v1/tenants/:tid/events
@@ -66,11 +162,25 @@ func (h *EventsHandler) Create(c *gin.Context) {
 		return
 	}
 
-	// Validate event type
-	if err := httputil.ValidateEventType(req.EventType); err != nil {
-		httputil.BadRequest(c, err.Error(), nil)
+	// Validate location ID if provided
+	if req.LocationID != "" {
+		if err := httputil.ValidateUUID(req.LocationID); err != nil {
+			httputil.BadRequest(c, "Invalid location ID", nil)
+			return
+		}
+	}
+
+	// Validate coordinates if provided; they only make sense as a pair
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		httputil.BadRequest(c, "latitude and longitude must be provided together", nil)
 		return
 	}
+	if req.Latitude != nil {
+		if *req.Latitude < -90 || *req.Latitude > 90 || *req.Longitude < -180 || *req.Longitude > 180 {
+			httputil.BadRequest(c, "Invalid coordinates", nil)
+			return
+		}
+	}
 
 	// Check for idempotency key
 	idempotencyKey := c.GetHeader("Idempotency-Key")
@@ -90,6 +200,35 @@ func (h *EventsHandler) Create(c *gin.Context) {
 		return
 	}
 
+	// Events may only reference an active location of the tenant
+	var locationUUID pgtype.UUID
+	if req.LocationID != "" {
+		if err := locationUUID.Scan(req.LocationID); err != nil {
+			httputil.BadRequest(c, "Invalid location ID format", nil)
+			return
+		}
+		if err := h.locations.ValidateLocation(c.Request.Context(), tenantUUID, locationUUID); err != nil {
+			if errors.Is(err, location.ErrLocationNotFound) || errors.Is(err, location.ErrLocationInactive) {
+				httputil.BadRequest(c, "Unknown or inactive location", nil)
+				return
+			}
+			h.logger.Error("failed to validate location", "error", err)
+			httputil.InternalError(c, "Failed to validate location")
+			return
+		}
+	}
+
+	// Validate event type (built-in or registered for the tenant)
+	if err := h.service.ValidateEventType(c.Request.Context(), tenantUUID, req.EventType); err != nil {
+		if errors.Is(err, event.ErrUnknownEventType) {
+			httputil.BadRequest(c, err.Error(), nil)
+			return
+		}
+		h.logger.Error("failed to validate event type", "error", err)
+		httputil.InternalError(c, "Failed to validate event type")
+		return
+	}
+
 	// Check if event with this idempotency key already exists
 	existingEvent, err := h.queries.GetEventByIdemKey(c.Request.Context(), db.GetEventByIdemKeyParams{
 		TenantID:       tenantUUID,
@@ -101,7 +240,7 @@ func (h *EventsHandler) Create(c *gin.Context) {
 			"idempotency_key", idempotencyKey,
 			"event_id", existingEvent.ID,
 		)
-		c.JSON(200, formatEventResponse(existingEvent, nil))
+		httputil.Respond(c, 200, formatEventResponse(existingEvent, nil))
 		return
 	} else if err != pgx.ErrNoRows {
 		// Unexpected error
@@ -110,6 +249,33 @@ func (h *EventsHandler) Create(c *gin.Context) {
 		return
 	}
 
+	// Validate properties against the event schema registered for this type
+	validation, err := h.service.ValidateProperties(c.Request.Context(), tenantUUID, req.EventType, req.Properties)
+	if err != nil {
+		h.logger.Error("failed to validate event properties", "event_type", req.EventType, "error", err)
+		httputil.InternalError(c, "Failed to validate event properties")
+		return
+	}
+
+	var schemaErrors []byte
+	if !validation.Valid() {
+		if validation.Strict {
+			httputil.ValidationError(c, validation.Errors)
+			return
+		}
+
+		// Non-strict schema: accept the event but flag it
+		schemaErrors, err = json.Marshal(validation.Errors)
+		if err != nil {
+			httputil.InternalError(c, "Failed to record schema errors")
+			return
+		}
+		h.logger.Warn("event properties failed schema validation",
+			"event_type", req.EventType,
+			"errors", len(validation.Errors),
+		)
+	}
+
 	// Serialize properties
 	var propertiesJSON []byte
 	if req.Properties != nil {
@@ -122,6 +288,37 @@ func (h *EventsHandler) Create(c *gin.Context) {
 		propertiesJSON = []byte("{}")
 	}
 
+	// Promo code events come only from codes accepted by the promo service
+	if req.EventType == rules.EventTypePromoCode {
+		httputil.BadRequest(c, "promo_code events are created by entering a promo code", nil)
+		return
+	}
+
+	// Dormant events come only from the win-back sweep, which records the
+	// contact that stops a customer being targeted again
+	if req.EventType == winback.EventTypeCustomerDormant {
+		httputil.BadRequest(c, "customer_dormant events are created by the win-back automation", nil)
+		return
+	}
+
+	// A reversal must name an event of the same customer that can still be
+	// reversed
+	if req.EventType == rules.EventTypeReversal {
+		if _, err := h.rulesEngine.FindReversalTarget(c.Request.Context(), tenantUUID, customerUUID, propertiesJSON); err != nil {
+			switch {
+			case errors.Is(err, rules.ErrReversalTargetRequired),
+				errors.Is(err, rules.ErrOriginalEventNotFound),
+				errors.Is(err, rules.ErrReversalOfReversal),
+				errors.Is(err, rules.ErrReversalCustomerMismatch):
+				httputil.BadRequest(c, err.Error(), nil)
+			default:
+				h.logger.Error("failed to find reversal target", "error", err)
+				httputil.InternalError(c, "Failed to validate reversal")
+			}
+			return
+		}
+	}
+
 	// Set occurred_at (default to now if not provided)
 	var occurredAt pgtype.Timestamptz
 	if req.OccurredAt != nil {
@@ -142,8 +339,35 @@ func (h *EventsHandler) Create(c *gin.Context) {
 		source = "api"
 	}
 
-	// Create event
-	event, err := h.queries.InsertEvent(c.Request.Context(), db.InsertEventParams{
+	// Catch near-duplicates sent again under a fresh idempotency key
+	dedup, err := h.service.CheckDuplicate(c.Request.Context(), tenantUUID, customerUUID, req.EventType, propertiesJSON, occurredAt.Time)
+	if err != nil {
+		h.logger.Error("failed to check for duplicate events", "error", err)
+		httputil.InternalError(c, "Failed to check for duplicate events")
+		return
+	}
+	var duplicateOf pgtype.UUID
+	if dedup.Original != nil {
+		if err := h.service.RecordDuplicate(c.Request.Context(), tenantUUID, req.EventType, dedup.Mode, time.Now()); err != nil {
+			h.logger.Error("failed to record duplicate event", "error", err)
+		}
+		if dedup.Mode == event.DedupSuppress {
+			h.logger.Info("suppressed near-duplicate event",
+				"idempotency_key", idempotencyKey,
+				"duplicate_of", dedup.Original.ID,
+			)
+			response := formatEventResponse(*dedup.Original, nil)
+			response.Suppressed = true
+			httputil.Respond(c, 200, response)
+			return
+		}
+		duplicateOf = dedup.Original.ID
+	}
+
+	// Create event; a concurrent request with the same idempotency key may
+	// have won the race since the check above, in which case its event is
+	// returned as if this request had been a retry
+	ev, created, err := event.InsertOnce(c.Request.Context(), h.pool, db.InsertEventIfNewParams{
 		TenantID:       tenantUUID,
 		CustomerID:     customerUUID,
 		EventType:      req.EventType,
@@ -151,34 +375,56 @@ func (h *EventsHandler) Create(c *gin.Context) {
 		OccurredAt:     occurredAt,
 		Source:         source,
 		IdempotencyKey: idempotencyKey,
+		SchemaErrors:   schemaErrors,
+		LocationID:     locationUUID,
+		ContentHash:    dedup.Hash,
+		DuplicateOf:    duplicateOf,
+		Latitude:       optionalFloat8(req.Latitude),
+		Longitude:      optionalFloat8(req.Longitude),
 	})
 	if err != nil {
 		h.logger.Error("failed to create event", "error", err)
 		httputil.InternalError(c, "Failed to create event")
 		return
 	}
+	if !created {
+		h.logger.Info("returning existing event for idempotency key",
+			"idempotency_key", idempotencyKey,
+			"event_id", ev.ID,
+		)
+		httputil.Respond(c, 200, formatEventResponse(ev, nil))
+		return
+	}
 
 	h.logger.Info("event created",
-		"event_id", event.ID,
-		"event_type", event.EventType,
-		"customer_id", event.CustomerID,
+		"event_id", ev.ID,
+		"event_type", ev.EventType,
+		"customer_id", ev.CustomerID,
 	)
+	h.meter.Add(tenantUUID, metering.MetricEventsIngested, 1)
 
 	// Process event through rules engine
-	issuances, err := h.rulesEngine.ProcessEvent(c.Request.Context(), event)
+	issuances, err := h.rulesEngine.ProcessEvent(c.Request.Context(), ev)
 	if err != nil {
 		// Log error but don't fail the request - event was created successfully
 		h.logger.Error("rules engine processing failed",
-			"event_id", event.ID,
+			"event_id", ev.ID,
 			"error", err,
 		)
+		// Park the event in the dead letter queue so it can be retried
+		if _, dlqErr := h.deadLetters.Record(c.Request.Context(), ev, err); dlqErr != nil {
+			h.logger.Error("failed to record dead letter",
+				"event_id", ev.ID,
+				"error", dlqErr,
+			)
+		}
 		// Return event without issuances
-		c.JSON(201, formatEventResponse(event, nil))
+		httputil.Respond(c, 201, formatEventResponse(ev, nil))
 		return
 	}
 
 	// Return event with issuances
-	c.JSON(201, formatEventResponse(event, issuances))
+	httputil.Respond(c, 201, formatEventResponse(ev, issuances))
 }
 
 // Get handles GET /v1/tenants/:tid/events/:id
@@ -202,6 +448,63 @@ func (h *EventsHandler) Get(c *gin.Context) {
 	return
 }
 
+// GetReversal handles GET /v1/tenants/:tid/events/:id/reversal. The event
+// may be either the reversed event or the reversal itself.
+func (h *EventsHandler) GetReversal(c *gin.Context) {
+	tenantID := c.Param("tid")
+	eventID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+	if err := httputil.ValidateUUID(eventID); err != nil {
+		httputil.BadRequest(c, "Invalid event ID", nil)
+		return
+	}
+
+	var tenantUUID, eventUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+	if err := eventUUID.Scan(eventID); err != nil {
+		httputil.BadRequest(c, "Invalid event ID format", nil)
+		return
+	}
+
+	reversal, err := h.rulesEngine.GetReversal(c.Request.Context(), tenantUUID, eventUUID)
+	if err != nil {
+		if errors.Is(err, rules.ErrReversalNotFound) {
+			httputil.NotFound(c, "Event has not been reversed")
+			return
+		}
+		h.logger.Error("failed to get reversal", "error", err)
+		httputil.InternalError(c, "Failed to get reversal")
+		return
+	}
+
+	issuances := make([]ReversedIssuanceResponse, len(reversal.Issuances))
+	for i, item := range reversal.Issuances {
+		issuances[i] = ReversedIssuanceResponse{
+			IssuanceID:     formatUUID(item.IssuanceID),
+			PreviousStatus: item.PreviousStatus,
+			Action:         item.Action,
+			ReleasedAmount: formatNumeric(item.ReleasedAmount),
+			Currency:       item.Currency.String,
+		}
+	}
+
+	httputil.Respond(c, 200, ReversalResponse{
+		ID:              formatUUID(reversal.ID),
+		ReversalEventID: formatUUID(reversal.ReversalEventID),
+		OriginalEventID: formatUUID(reversal.OriginalEventID),
+		Reason:          reversal.Reason.String,
+		CreatedAt:       formatTimestamp(reversal.CreatedAt),
+		Issuances:       issuances,
+	})
+}
+
 // List handles GET /v1/tenants/:tid/events
 func (h *EventsHandler) List(c *gin.Context) {
 	tenantID := c.Param("tid")
@@ -245,76 +548,138 @@ func (h *EventsHandler) List(c *gin.Context) {
 	}
 
 	// Format response
-	eventsList := make([]gin.H, len(events))
+	eventsList := make([]EventResponse, len(events))
 	for i, event := range events {
-		var properties map[string]interface{}
-		if len(event.Properties) > 0 {
-			json.Unmarshal(event.Properties, &properties)
+		eventsList[i] = formatEvent(event)
+	}
+
+	httputil.RespondList(c, eventsList, httputil.NewPage(int64(total), limit, offset))
+}
+
+// Duplicates handles GET /v1/tenants/:tid/events/duplicates
+// Reports the near-duplicate events flagged and suppressed per day and event
+// type, over the last 30 days unless from and to are given.
+func (h *EventsHandler) Duplicates(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	to := time.Now()
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid to format. Use RFC3339", nil)
+			return
 		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -30)
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid from format. Use RFC3339", nil)
+			return
+		}
+		from = t
+	}
+	if from.After(to) {
+		httputil.BadRequest(c, "from must not be after to", nil)
+		return
+	}
 
-		eventsList[i] = gin.H{
-			"id":              formatUUID(event.ID),
-			"tenant_id":       formatUUID(event.TenantID),
-			"customer_id":     formatUUID(event.CustomerID),
-			"event_type":      event.EventType,
-			"properties":      properties,
-			"occurred_at":     formatTimestamp(event.OccurredAt),
-			"source":          event.Source,
-			"idempotency_key": event.IdempotencyKey,
-			"created_at":      formatTimestamp(event.CreatedAt),
+	stats, err := h.service.DedupStats(c.Request.Context(), tenantUUID, from, to)
+	if err != nil {
+		h.logger.Error("failed to list duplicate event stats", "error", err)
+		httputil.InternalError(c, "Failed to list duplicate events")
+		return
+	}
+
+	var flagged, suppressed int64
+	days := make([]DuplicateDayStatsResponse, len(stats))
+	for i, s := range stats {
+		flagged += s.Flagged
+		suppressed += s.Suppressed
+		days[i] = DuplicateDayStatsResponse{
+			Day:        s.Day.Time.Format("2006-01-02"),
+			EventType:  s.EventType,
+			Flagged:    s.Flagged,
+			Suppressed: s.Suppressed,
 		}
 	}
 
-	c.JSON(200, gin.H{
-		"events": eventsList,
-		"total":  total,
-		"limit":  limit,
-		"offset": offset,
-		"filters": gin.H{
-			"customer_id": customerID,
-		},
+	httputil.Respond(c, 200, DuplicateStatsResponse{
+		From:       from.UTC().Format("2006-01-02"),
+		To:         to.UTC().Format("2006-01-02"),
+		Flagged:    flagged,
+		Suppressed: suppressed,
+		Days:       days,
 	})
 }
 
 // Helper functions
 
-// formatEventResponse formats an event and its issuances for the API response
-func formatEventResponse(event db.Event, issuances []db.Issuance) gin.H {
-	// Parse properties
+// formatEvent formats an event for the API response
+func formatEvent(event db.Event) EventResponse {
 	var properties map[string]interface{}
 	if len(event.Properties) > 0 {
 		json.Unmarshal(event.Properties, &properties)
 	}
 
-	response := gin.H{
-		"id":              formatUUID(event.ID),
-		"tenant_id":       formatUUID(event.TenantID),
-		"customer_id":     formatUUID(event.CustomerID),
-		"event_type":      event.EventType,
-		"properties":      properties,
-		"occurred_at":     formatTimestamp(event.OccurredAt),
-		"source":          event.Source,
-		"idempotency_key": event.IdempotencyKey,
-		"created_at":      formatTimestamp(event.CreatedAt),
-	}
-
-	// Add issuances if any
-	if len(issuances) > 0 {
-		issuancesList := make([]gin.H, len(issuances))
-		for i, issuance := range issuances {
-			issuancesList[i] = gin.H{
-				"id":          formatUUID(issuance.ID),
-				"reward_id":   formatUUID(issuance.RewardID),
-				"campaign_id": formatUUID(issuance.CampaignID),
-				"status":      issuance.Status,
-				"currency":    issuance.Currency.String,
-				"face_amount": issuance.FaceAmount.Int.String(),
-				"issued_at":   formatTimestamp(issuance.IssuedAt),
-			}
+	response := EventResponse{
+		ID:             formatUUID(event.ID),
+		TenantID:       formatUUID(event.TenantID),
+		CustomerID:     formatUUID(event.CustomerID),
+		EventType:      event.EventType,
+		Properties:     properties,
+		OccurredAt:     formatTimestamp(event.OccurredAt),
+		Source:         event.Source,
+		IdempotencyKey: event.IdempotencyKey,
+		CreatedAt:      formatTimestamp(event.CreatedAt),
+	}
+
+	// Flag near-duplicates of an earlier event
+	if event.DuplicateOf.Valid {
+		response.DuplicateOf = formatUUID(event.DuplicateOf)
+	}
+	return response
+}
+
+// formatEventResponse formats an event and its issuances for the API response
+func formatEventResponse(event db.Event, issuances []db.Issuance) EventDetailResponse {
+	response := EventDetailResponse{EventResponse: formatEvent(event)}
+
+	if event.LocationID.Valid {
+		response.LocationID = formatUUID(event.LocationID)
+	}
+	if event.Latitude.Valid && event.Longitude.Valid {
+		response.Latitude = &event.Latitude.Float64
+		response.Longitude = &event.Longitude.Float64
+	}
+
+	// Flag events accepted despite failing schema validation
+	if len(event.SchemaErrors) > 0 {
+		json.Unmarshal(event.SchemaErrors, &response.SchemaErrors)
+	}
+
+	response.Issuances = make([]EventIssuanceResponse, len(issuances))
+	for i, issuance := range issuances {
+		response.Issuances[i] = EventIssuanceResponse{
+			ID:         formatUUID(issuance.ID),
+			RewardID:   formatUUID(issuance.RewardID),
+			CampaignID: formatUUID(issuance.CampaignID),
+			Status:     issuance.Status,
+			Currency:   issuance.Currency.String,
+			FaceAmount: formatNumeric(issuance.FaceAmount),
+			IssuedAt:   formatTimestamp(issuance.IssuedAt),
 		}
-		response["issuances"] = issuancesList
-	} else {
-		response["issuances"] = []gin.H{}
 	}
 
 	return response
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/export"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ExportsHandler lists the data export files written for BI tooling
+type ExportsHandler struct {
+	service *export.Service
+}
+
+// NewExportsHandler creates a new exports handler
+func NewExportsHandler(service *export.Service) *ExportsHandler {
+	return &ExportsHandler{service: service}
+}
+
+// DataExportResponse is a warehouse export in API responses
+type DataExportResponse struct {
+	ID           string `json:"id"`
+	Dataset      string `json:"dataset"`
+	SnapshotDate string `json:"snapshot_date"`
+	Format       string `json:"format"`
+	Bucket       string `json:"bucket"`
+	ObjectKey    string `json:"object_key"`
+	RowCount     int64  `json:"row_count"`
+	SizeBytes    int64  `json:"size_bytes"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// List handles GET /v1/tenants/:tid/exports
+func (h *ExportsHandler) List(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	limit := c.DefaultQuery("limit", "50")
+	offset := c.DefaultQuery("offset", "0")
+
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil || limitInt < 1 {
+		limitInt = 50
+	}
+	if limitInt > 500 {
+		limitInt = 500
+	}
+	offsetInt, err := strconv.Atoi(offset)
+	if err != nil || offsetInt < 0 {
+		offsetInt = 0
+	}
+
+	exports, total, err := h.service.List(c.Request.Context(), tenantUUID, c.Query("dataset"), int32(limitInt), int32(offsetInt))
+	if err != nil {
+		if errors.Is(err, export.ErrInvalidDataset) {
+			httputil.BadRequest(c, err.Error(), nil)
+			return
+		}
+		httputil.InternalError(c, "Failed to list exports")
+		return
+	}
+
+	exportsList := make([]DataExportResponse, len(exports))
+	for i, e := range exports {
+		exportsList[i] = formatDataExport(e)
+	}
+
+	httputil.RespondList(c, exportsList, httputil.Page{Total: total, Limit: limitInt, Offset: offsetInt})
+}
+
+// Get handles GET /v1/tenants/:tid/exports/:id
+func (h *ExportsHandler) Get(c *gin.Context) {
+	tenantID := c.Param("tid")
+	exportID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+	if err := httputil.ValidateUUID(exportID); err != nil {
+		httputil.BadRequest(c, "Invalid export ID", nil)
+		return
+	}
+
+	var tenantUUID, exportUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+	if err := exportUUID.Scan(exportID); err != nil {
+		httputil.BadRequest(c, "Invalid export ID format", nil)
+		return
+	}
+
+	e, err := h.service.Get(c.Request.Context(), tenantUUID, exportUUID)
+	if err != nil {
+		if errors.Is(err, export.ErrExportNotFound) {
+			httputil.NotFound(c, "Export not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get export")
+		return
+	}
+
+	httputil.Respond(c, 200, formatDataExport(*e))
+}
+
+func formatDataExport(e db.DataExport) DataExportResponse {
+	return DataExportResponse{
+		ID:           formatUUID(e.ID),
+		Dataset:      e.Dataset,
+		SnapshotDate: e.SnapshotDate.Time.Format("2006-01-02"),
+		Format:       e.Format,
+		Bucket:       e.Bucket,
+		ObjectKey:    e.ObjectKey,
+		RowCount:     e.RowCount,
+		SizeBytes:    e.SizeBytes,
+		CreatedAt:    formatTimestamp(e.CreatedAt),
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/bmachimbira/loyalty/api/internal/featureflag"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// FeatureFlagsHandler handles per-channel feature flag endpoints
+type FeatureFlagsHandler struct {
+	service *featureflag.Service
+}
+
+// NewFeatureFlagsHandler creates a new feature flags handler
+func NewFeatureFlagsHandler(service *featureflag.Service) *FeatureFlagsHandler {
+	return &FeatureFlagsHandler{
+		service: service,
+	}
+}
+
+// SetFeatureFlagRequest represents the request to turn a feature on or off
+type SetFeatureFlagRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// FeatureFlagResponse is a channel feature flag. UpdatedAt is only set once
+// the tenant has set the flag.
+type FeatureFlagResponse struct {
+	Channel   string `json:"channel"`
+	Feature   string `json:"feature"`
+	Enabled   bool   `json:"enabled"`
+	Default   bool   `json:"default"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+// List handles GET /v1/tenants/:tid/feature-flags
+// Every feature is listed for every channel, with "default" set for
+// features the tenant hasn't set.
+func (h *FeatureFlagsHandler) List(c *gin.Context) {
+	tenantUUID, ok := parseFeatureFlagTenant(c)
+	if !ok {
+		return
+	}
+
+	flags, err := h.service.List(c.Request.Context(), tenantUUID)
+	if err != nil {
+		httputil.InternalError(c, "Failed to list feature flags")
+		return
+	}
+
+	flagsList := make([]FeatureFlagResponse, len(flags))
+	for i, flag := range flags {
+		flagsList[i] = formatFeatureFlag(flag)
+	}
+
+	httputil.RespondList(c, flagsList, httputil.Page{Total: int64(len(flagsList))})
+}
+
+// Set handles PUT /v1/tenants/:tid/feature-flags/:channel/:feature
+func (h *FeatureFlagsHandler) Set(c *gin.Context) {
+	tenantUUID, ok := parseFeatureFlagTenant(c)
+	if !ok {
+		return
+	}
+
+	var req SetFeatureFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	flag, err := h.service.Set(c.Request.Context(), tenantUUID, c.Param("channel"), c.Param("feature"), *req.Enabled)
+	if err != nil {
+		if errors.Is(err, featureflag.ErrUnknownChannel) || errors.Is(err, featureflag.ErrUnknownFeature) {
+			httputil.BadRequest(c, err.Error(), nil)
+			return
+		}
+		httputil.InternalError(c, "Failed to set feature flag")
+		return
+	}
+
+	httputil.Respond(c, 200, formatFeatureFlag(flag))
+}
+
+// Reset handles DELETE /v1/tenants/:tid/feature-flags/:channel/:feature
+// The feature goes back to its default for the channel.
+func (h *FeatureFlagsHandler) Reset(c *gin.Context) {
+	tenantUUID, ok := parseFeatureFlagTenant(c)
+	if !ok {
+		return
+	}
+
+	flag, err := h.service.Reset(c.Request.Context(), tenantUUID, c.Param("channel"), c.Param("feature"))
+	if err != nil {
+		switch {
+		case errors.Is(err, featureflag.ErrUnknownChannel), errors.Is(err, featureflag.ErrUnknownFeature):
+			httputil.BadRequest(c, err.Error(), nil)
+		case errors.Is(err, featureflag.ErrFlagNotSet):
+			httputil.NotFound(c, "Feature flag not set")
+		default:
+			httputil.InternalError(c, "Failed to reset feature flag")
+		}
+		return
+	}
+
+	httputil.Respond(c, 200, formatFeatureFlag(flag))
+}
+
+func parseFeatureFlagTenant(c *gin.Context) (pgtype.UUID, bool) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return pgtype.UUID{}, false
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return pgtype.UUID{}, false
+	}
+	return tenantUUID, true
+}
+
+func formatFeatureFlag(flag featureflag.Flag) FeatureFlagResponse {
+	result := FeatureFlagResponse{
+		Channel: flag.Channel,
+		Feature: flag.Feature,
+		Enabled: flag.Enabled,
+		Default: flag.Default,
+	}
+	if flag.UpdatedAt.Valid {
+		result.UpdatedAt = formatTimestamp(flag.UpdatedAt)
+	}
+	return result
+}
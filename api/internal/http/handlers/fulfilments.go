@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/fulfilment"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// FulfilmentsHandler handles physical item fulfilment endpoints
+type FulfilmentsHandler struct {
+	service *fulfilment.Service
+	logger  *slog.Logger
+}
+
+// NewFulfilmentsHandler creates a new fulfilments handler
+func NewFulfilmentsHandler(service *fulfilment.Service, logger *slog.Logger) *FulfilmentsHandler {
+	return &FulfilmentsHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// UpdateFulfilmentStatusRequest represents the request to move a fulfilment on
+type UpdateFulfilmentStatusRequest struct {
+	Status      string `json:"status" binding:"required"`
+	Carrier     string `json:"carrier"`
+	TrackingRef string `json:"tracking_ref"`
+	Note        string `json:"note"`
+}
+
+// FulfilmentResponse is a physical item fulfilment in API responses
+type FulfilmentResponse struct {
+	ID           string `json:"id"`
+	IssuanceID   string `json:"issuance_id"`
+	CustomerID   string `json:"customer_id"`
+	Status       string `json:"status"`
+	Carrier      string `json:"carrier"`
+	TrackingRef  string `json:"tracking_ref"`
+	Note         string `json:"note"`
+	DueAt        string `json:"due_at"`
+	PickedAt     string `json:"picked_at"`
+	DispatchedAt string `json:"dispatched_at"`
+	DeliveredAt  string `json:"delivered_at"`
+	EscalatedAt  string `json:"escalated_at"`
+	CreatedAt    string `json:"created_at"`
+	UpdatedAt    string `json:"updated_at"`
+}
+
+// List handles GET /v1/tenants/:tid/fulfilments
+// Supports ?status= and ?overdue=true for undelivered items past their due date.
+func (h *FulfilmentsHandler) List(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	status := c.Query("status")
+	if status != "" && !fulfilment.IsValidStatus(status) {
+		httputil.BadRequest(c, fulfilment.ErrInvalidStatus.Error(), nil)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	fulfilments, total, err := h.service.List(c.Request.Context(), tenantUUID, status, c.Query("overdue") == "true", time.Now(), int32(limit), int32(offset))
+	if err != nil {
+		h.logger.Error("failed to list fulfilments", "error", err)
+		httputil.InternalError(c, "Failed to list fulfilments")
+		return
+	}
+
+	fulfilmentsList := make([]FulfilmentResponse, len(fulfilments))
+	for i, f := range fulfilments {
+		fulfilmentsList[i] = formatFulfilment(f)
+	}
+
+	httputil.RespondList(c, fulfilmentsList, httputil.Page{Total: total, Limit: limit, Offset: offset})
+}
+
+// Get handles GET /v1/tenants/:tid/fulfilments/:id
+func (h *FulfilmentsHandler) Get(c *gin.Context) {
+	tenantUUID, fulfilmentUUID, ok := parseFulfilmentParams(c)
+	if !ok {
+		return
+	}
+
+	f, err := h.service.Get(c.Request.Context(), tenantUUID, fulfilmentUUID)
+	if err != nil {
+		if errors.Is(err, fulfilment.ErrFulfilmentNotFound) {
+			httputil.NotFound(c, "Fulfilment not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get fulfilment")
+		return
+	}
+
+	httputil.Respond(c, 200, formatFulfilment(f))
+}
+
+// UpdateStatus handles POST /v1/tenants/:tid/fulfilments/:id/status
+// Moves a fulfilment forward, e.g. to dispatched with the carrier and tracking reference.
+func (h *FulfilmentsHandler) UpdateStatus(c *gin.Context) {
+	tenantUUID, fulfilmentUUID, ok := parseFulfilmentParams(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateFulfilmentStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+	if !fulfilment.IsValidStatus(req.Status) {
+		httputil.BadRequest(c, fulfilment.ErrInvalidStatus.Error(), nil)
+		return
+	}
+
+	params := fulfilment.AdvanceParams{
+		TenantID:     tenantUUID,
+		FulfilmentID: fulfilmentUUID,
+		Status:       req.Status,
+		Carrier:      req.Carrier,
+		TrackingRef:  req.TrackingRef,
+		Note:         req.Note,
+	}
+	if userID, exists := c.Get("user_id"); exists {
+		params.UpdatedBy.Scan(userID.(string))
+	}
+
+	updated, err := h.service.Advance(c.Request.Context(), params)
+	if err != nil {
+		switch {
+		case errors.Is(err, fulfilment.ErrFulfilmentNotFound):
+			httputil.NotFound(c, "Fulfilment not found")
+		case errors.Is(err, fulfilment.ErrInvalidTransition):
+			httputil.Conflict(c, err.Error(), nil)
+		default:
+			h.logger.Error("failed to update fulfilment", "error", err)
+			httputil.InternalError(c, "Failed to update fulfilment")
+		}
+		return
+	}
+
+	httputil.Respond(c, 200, formatFulfilment(updated))
+}
+
+// parseFulfilmentParams validates and parses the tenant and fulfilment IDs from the path
+func parseFulfilmentParams(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, fulfilmentUUID pgtype.UUID
+
+	tenantID := c.Param("tid")
+	fulfilmentID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, fulfilmentUUID, false
+	}
+	if err := httputil.ValidateUUID(fulfilmentID); err != nil {
+		httputil.BadRequest(c, "Invalid fulfilment ID", nil)
+		return tenantUUID, fulfilmentUUID, false
+	}
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, fulfilmentUUID, false
+	}
+	if err := fulfilmentUUID.Scan(fulfilmentID); err != nil {
+		httputil.BadRequest(c, "Invalid fulfilment ID format", nil)
+		return tenantUUID, fulfilmentUUID, false
+	}
+
+	return tenantUUID, fulfilmentUUID, true
+}
+
+// formatFulfilment formats a fulfilment for the API response
+func formatFulfilment(f db.Fulfilment) FulfilmentResponse {
+	return FulfilmentResponse{
+		ID:           formatUUID(f.ID),
+		IssuanceID:   formatUUID(f.IssuanceID),
+		CustomerID:   formatUUID(f.CustomerID),
+		Status:       f.Status,
+		Carrier:      f.Carrier.String,
+		TrackingRef:  f.TrackingRef.String,
+		Note:         f.Note.String,
+		DueAt:        formatTimestamp(f.DueAt),
+		PickedAt:     formatTimestamp(f.PickedAt),
+		DispatchedAt: formatTimestamp(f.DispatchedAt),
+		DeliveredAt:  formatTimestamp(f.DeliveredAt),
+		EscalatedAt:  formatTimestamp(f.EscalatedAt),
+		CreatedAt:    formatTimestamp(f.CreatedAt),
+		UpdatedAt:    formatTimestamp(f.UpdatedAt),
+	}
+}
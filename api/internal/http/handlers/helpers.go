@@ -1,10 +1,22 @@
 package handlers
 
 import (
+	"errors"
+
+	"github.com/bmachimbira/loyalty/api/internal/currency"
 	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/phone"
+	"github.com/bmachimbira/loyalty/api/internal/reward"
+	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+// DeactivatedResponse confirms that a resource was deactivated
+type DeactivatedResponse struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
 // formatUUID converts pgtype.UUID to string
 func formatUUID(uuid pgtype.UUID) string {
 	if !uuid.Valid {
@@ -18,3 +30,58 @@ func formatUUID(uuid pgtype.UUID) string {
 func formatTimestamp(ts pgtype.Timestamptz) string {
 	return httputil.FormatTimestamp(ts)
 }
+
+// formatNumeric converts pgtype.Numeric to a decimal string
+func formatNumeric(n pgtype.Numeric) string {
+	return httputil.FormatNumeric(n)
+}
+
+// optionalFloat8 converts an optional request number to pgtype.Float8
+func optionalFloat8(v *float64) pgtype.Float8 {
+	if v == nil {
+		return pgtype.Float8{}
+	}
+	return pgtype.Float8{Float64: *v, Valid: true}
+}
+
+// staffOrigin returns the origin of an issuance status change made by the
+// authenticated staff user over the given channel
+func staffOrigin(c *gin.Context, channel string) reward.Origin {
+	origin := reward.Origin{Actor: reward.ActorSystem, Channel: channel}
+	if userID, ok := c.Get("user_id"); ok {
+		var userUUID pgtype.UUID
+		if userUUID.Scan(userID.(string)) == nil {
+			origin.Actor = reward.StaffActor(userUUID)
+		}
+	}
+	return origin
+}
+
+// normalizePhone returns the E.164 form of a phone number entered for the
+// tenant, responding with an error if it is invalid
+func normalizePhone(c *gin.Context, phones *phone.Normalizer, tenantID pgtype.UUID, raw string) (string, bool) {
+	normalized, err := phones.Normalize(c.Request.Context(), tenantID, raw)
+	if err != nil {
+		if errors.Is(err, phone.ErrInvalidNumber) {
+			httputil.BadRequest(c, err.Error(), nil)
+			return "", false
+		}
+		httputil.InternalError(c, "Failed to validate phone number")
+		return "", false
+	}
+	return normalized, true
+}
+
+// checkCurrency checks that the tenant may use a currency, responding with
+// an error if not
+func checkCurrency(c *gin.Context, currencies *currency.Checker, tenantID pgtype.UUID, code string) bool {
+	if err := currencies.Check(c.Request.Context(), tenantID, code); err != nil {
+		if errors.Is(err, currency.ErrUnknownCurrency) || errors.Is(err, currency.ErrNotAllowed) {
+			httputil.BadRequest(c, err.Error(), nil)
+			return false
+		}
+		httputil.InternalError(c, "Failed to validate currency")
+		return false
+	}
+	return true
+}
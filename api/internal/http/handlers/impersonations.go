@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"errors"
+	"net/netip"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/http/middleware"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/portal"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ImpersonateRequest represents a staff request to see the portal as a
+// customer
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"required"`
+	// DurationMinutes defaults to 15 and can be at most 60
+	DurationMinutes int `json:"duration_minutes"`
+}
+
+// ImpersonationResponse is a staff user's impersonation of a customer.
+// EndedAt is only set once it was ended early.
+type ImpersonationResponse struct {
+	ID          string `json:"id"`
+	CustomerID  string `json:"customer_id"`
+	StaffUserID string `json:"staff_user_id"`
+	Reason      string `json:"reason"`
+	ExpiresAt   string `json:"expires_at"`
+	CreatedAt   string `json:"created_at"`
+	EndedAt     string `json:"ended_at,omitempty"`
+}
+
+// ImpersonationSessionResponse is a new impersonation with the portal token
+// the staff user acts as the customer with
+type ImpersonationSessionResponse struct {
+	ImpersonationResponse
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// Impersonate handles POST /v1/tenants/:tid/customers/:id/impersonations
+// Returns a read-only customer portal token for the customer. The reason is
+// mandatory and, like every request made with the token, audit logged.
+func (h *PortalHandler) Impersonate(c *gin.Context) {
+	tenantUUID, customerUUID, staffUUID, ok := parseImpersonationParams(c)
+	if !ok {
+		return
+	}
+
+	var req ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	impersonation, session, err := h.portal.Impersonate(c.Request.Context(), portal.ImpersonationParams{
+		TenantID:    tenantUUID,
+		CustomerID:  customerUUID,
+		StaffUserID: staffUUID,
+		Reason:      req.Reason,
+		Duration:    time.Duration(req.DurationMinutes) * time.Minute,
+		IPAddress:   clientIP(c),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, portal.ErrReasonRequired), errors.Is(err, portal.ErrInvalidDuration):
+			httputil.BadRequest(c, err.Error(), nil)
+		case errors.Is(err, portal.ErrCustomerNotFound):
+			httputil.NotFound(c, "Customer not found")
+		default:
+			httputil.InternalError(c, "Failed to impersonate customer")
+		}
+		return
+	}
+
+	httputil.Respond(c, 201, ImpersonationSessionResponse{
+		ImpersonationResponse: formatImpersonation(impersonation),
+		AccessToken:           session.AccessToken,
+		TokenType:             "Bearer",
+		ExpiresIn:             session.ExpiresIn,
+	})
+}
+
+// ListImpersonations handles GET /v1/tenants/:tid/customers/:id/impersonations
+func (h *PortalHandler) ListImpersonations(c *gin.Context) {
+	tenantUUID, customerUUID, _, ok := parseImpersonationParams(c)
+	if !ok {
+		return
+	}
+
+	limit := c.DefaultQuery("limit", "50")
+	offset := c.DefaultQuery("offset", "0")
+
+	impersonations, total, err := h.portal.ListImpersonations(c.Request.Context(), tenantUUID, customerUUID, limit, offset)
+	if err != nil {
+		httputil.InternalError(c, "Failed to list impersonations")
+		return
+	}
+
+	impersonationsList := make([]ImpersonationResponse, len(impersonations))
+	for i, imp := range impersonations {
+		impersonationsList[i] = formatImpersonation(imp)
+	}
+
+	httputil.RespondList(c, impersonationsList, httputil.NewPage(total, limit, offset))
+}
+
+// EndImpersonation handles DELETE /v1/tenants/:tid/customers/:id/impersonations/:iid
+// The impersonation's token is rejected from then on.
+func (h *PortalHandler) EndImpersonation(c *gin.Context) {
+	tenantUUID, _, staffUUID, ok := parseImpersonationParams(c)
+	if !ok {
+		return
+	}
+
+	impersonationID := c.Param("iid")
+	if err := httputil.ValidateUUID(impersonationID); err != nil {
+		httputil.BadRequest(c, "Invalid impersonation ID", nil)
+		return
+	}
+	var impersonationUUID pgtype.UUID
+	if err := impersonationUUID.Scan(impersonationID); err != nil {
+		httputil.BadRequest(c, "Invalid impersonation ID format", nil)
+		return
+	}
+
+	impersonation, err := h.portal.EndImpersonation(c.Request.Context(), tenantUUID, impersonationUUID, staffUUID, clientIP(c))
+	if err != nil {
+		if errors.Is(err, portal.ErrImpersonationNotActive) {
+			httputil.NotFound(c, "Active impersonation not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to end impersonation")
+		return
+	}
+
+	httputil.Respond(c, 200, formatImpersonation(impersonation))
+}
+
+// parseImpersonationParams returns the tenant and customer from the path
+// and the authenticated staff user, who must belong to the tenant
+func parseImpersonationParams(c *gin.Context) (pgtype.UUID, pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, customerUUID, staffUUID pgtype.UUID
+
+	tenantID := c.Param("tid")
+	customerID := c.Param("id")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, customerUUID, staffUUID, false
+	}
+	if err := httputil.ValidateUUID(customerID); err != nil {
+		httputil.BadRequest(c, "Invalid customer ID", nil)
+		return tenantUUID, customerUUID, staffUUID, false
+	}
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, customerUUID, staffUUID, false
+	}
+	if err := customerUUID.Scan(customerID); err != nil {
+		httputil.BadRequest(c, "Invalid customer ID format", nil)
+		return tenantUUID, customerUUID, staffUUID, false
+	}
+
+	// Impersonations are attributed to the staff user, so only staff of
+	// the customer's own tenant can start or end them
+	if c.GetString(middleware.TenantIDKey) != tenantID || staffUUID.Scan(c.GetString(middleware.UserIDKey)) != nil {
+		httputil.Forbidden(c, "Staff user cannot impersonate customers of this tenant")
+		return tenantUUID, customerUUID, staffUUID, false
+	}
+
+	return tenantUUID, customerUUID, staffUUID, true
+}
+
+// clientIP returns the request's client address for the audit log
+func clientIP(c *gin.Context) *netip.Addr {
+	addr, err := netip.ParseAddr(c.ClientIP())
+	if err != nil {
+		return nil
+	}
+	return &addr
+}
+
+func formatImpersonation(imp db.CustomerImpersonation) ImpersonationResponse {
+	result := ImpersonationResponse{
+		ID:          formatUUID(imp.ID),
+		CustomerID:  formatUUID(imp.CustomerID),
+		StaffUserID: formatUUID(imp.StaffUserID),
+		Reason:      imp.Reason,
+		ExpiresAt:   formatTimestamp(imp.ExpiresAt),
+		CreatedAt:   formatTimestamp(imp.CreatedAt),
+	}
+	if imp.EndedAt.Valid {
+		result.EndedAt = formatTimestamp(imp.EndedAt)
+	}
+	return result
+}
@@ -1,15 +1,25 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"log/slog"
-	"strings"
+	"net/http"
+	"strconv"
 
 	"github.com/bmachimbira/loyalty/api/internal/auth"
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/enums"
 	"github.com/bmachimbira/loyalty/api/internal/httputil"
 	"github.com/bmachimbira/loyalty/api/internal/issuance"
 	"github.com/bmachimbira/loyalty/api/internal/reward"
+	"github.com/bmachimbira/loyalty/api/internal/reward/basket"
+	"github.com/bmachimbira/loyalty/api/internal/reward/locations"
+	"github.com/bmachimbira/loyalty/api/internal/reward/window"
+	"github.com/bmachimbira/loyalty/api/internal/survey"
+	"github.com/bmachimbira/loyalty/api/internal/webhooks"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -20,6 +30,9 @@ type IssuancesHandler struct {
 	queries       *db.Queries
 	service       *issuance.Service
 	rewardService *reward.Service
+	surveys       *survey.Service
+	webhooks      *webhooks.DeliveryService
+	logger        *slog.Logger
 }
 
 // NewIssuancesHandler creates a new issuances handler
@@ -30,13 +43,210 @@ func NewIssuancesHandler(pool *pgxpool.Pool, logger *slog.Logger) *IssuancesHand
 		queries:       queries,
 		service:       issuance.NewService(queries),
 		rewardService: reward.NewService(pool, queries),
+		logger:        logger,
 	}
 }
 
-// RedeemIssuanceRequest represents the request to redeem an issuance
+// SetSurveyService enables post-redemption surveys
+func (h *IssuancesHandler) SetSurveyService(surveys *survey.Service) {
+	h.surveys = surveys
+}
+
+// SetTemplateSender enables confirming redemptions and notifying reissued
+// rewards to customers over WhatsApp
+func (h *IssuancesHandler) SetTemplateSender(sender reward.TemplateSender) {
+	h.rewardService.SetTemplateSender(sender)
+}
+
+// WaitForNotifications blocks until in-flight customer notifications finish
+func (h *IssuancesHandler) WaitForNotifications() {
+	h.rewardService.WaitForNotifications()
+}
+
+// SetWebhookService enables webhook notifications for redemptions and
+// clawbacks
+func (h *IssuancesHandler) SetWebhookService(webhooks *webhooks.DeliveryService) {
+	h.webhooks = webhooks
+	h.rewardService.SetWebhookService(webhooks)
+}
+
+// RedeemIssuanceRequest represents the request to redeem an issuance.
+// StoreID is the code of the location redeeming it and BasketAmount the
+// purchase it is redeemed with. A reward restricted to other locations can
+// still be redeemed there with a manager's email and PIN.
 type RedeemIssuanceRequest struct {
-	OTP      string `json:"otp"`
-	StaffPIN string `json:"staff_pin"`
+	OTP          string   `json:"otp"`
+	StaffPIN     string   `json:"staff_pin"`
+	StoreID      string   `json:"store_id"`
+	BasketAmount *float64 `json:"basket_amount"`
+	ManagerEmail string   `json:"manager_email"`
+	ManagerPIN   string   `json:"manager_pin"`
+}
+
+// overrideRoles are the staff roles that may override a reward's location
+// restriction
+var overrideRoles = map[string]bool{"owner": true, "manager": true}
+
+// ClawbackIssuanceRequest represents the request to claw back a redeemed issuance
+type ClawbackIssuanceRequest struct {
+	ReasonCode string `json:"reason_code" binding:"required"`
+	Note       string `json:"note"`
+}
+
+// ReissueIssuanceRequest represents the request to reissue a failed or lost
+// reward
+type ReissueIssuanceRequest struct {
+	ReasonCode string `json:"reason_code" binding:"required"`
+	Note       string `json:"note"`
+}
+
+// IssuanceResponse is an issuance in API responses
+type IssuanceResponse struct {
+	ID          string                 `json:"id"`
+	TenantID    string                 `json:"tenant_id"`
+	CustomerID  string                 `json:"customer_id"`
+	CampaignID  string                 `json:"campaign_id"`
+	RewardID    string                 `json:"reward_id"`
+	Status      string                 `json:"status"`
+	Code        string                 `json:"code"`
+	ExternalRef string                 `json:"external_ref"`
+	Currency    string                 `json:"currency"`
+	CostAmount  string                 `json:"cost_amount"`
+	FaceAmount  string                 `json:"face_amount"`
+	ValueInputs map[string]interface{} `json:"value_inputs"`
+	IssuedAt    string                 `json:"issued_at"`
+	ExpiresAt   string                 `json:"expires_at"`
+	RedeemedAt  string                 `json:"redeemed_at"`
+}
+
+// IssuanceDetailResponse is an issuance with the issuances it replaced or
+// was replaced by, if it was reissued
+type IssuanceDetailResponse struct {
+	IssuanceResponse
+	ReissuedFrom string `json:"reissued_from"`
+	ReissuedAs   string `json:"reissued_as"`
+}
+
+// IssuanceByCodeResponse is an issuance looked up by its code, with its
+// reward and customer for the cashier redeeming it
+type IssuanceByCodeResponse struct {
+	IssuanceResponse
+	Reward   IssuanceRewardResponse   `json:"reward"`
+	Customer IssuanceCustomerResponse `json:"customer"`
+}
+
+// IssuanceRewardResponse is the reward of an issuance looked up by its code
+type IssuanceRewardResponse struct {
+	ID           string                         `json:"id"`
+	Name         string                         `json:"name"`
+	Type         string                         `json:"type"`
+	Requirements RedemptionRequirementsResponse `json:"requirements"`
+}
+
+// RedemptionRequirementsResponse is what the cashier must check before
+// redeeming a reward. Unset requirements are null.
+type RedemptionRequirementsResponse struct {
+	MinBasket        *float64 `json:"min_basket"`
+	RedemptionWindow *string  `json:"redemption_window"`
+	AllowedLocations []string `json:"allowed_locations"`
+}
+
+// IssuanceCustomerResponse is the customer of an issuance looked up by its
+// code
+type IssuanceCustomerResponse struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Phone       string `json:"phone"`
+	ExternalRef string `json:"external_ref"`
+	Status      string `json:"status"`
+}
+
+// FailedIssuanceResponse is a failed issuance with the error that failed it
+type FailedIssuanceResponse struct {
+	IssuanceResponse
+	RewardName       string `json:"reward_name"`
+	RewardType       string `json:"reward_type"`
+	ProcessAttempts  int32  `json:"process_attempts"`
+	LastProcessError string `json:"last_process_error"`
+	FailedAt         string `json:"failed_at"`
+}
+
+// RedeemedIssuanceResponse is an issuance that was just redeemed
+type RedeemedIssuanceResponse struct {
+	ID         string `json:"id"`
+	Status     string `json:"status"`
+	RedeemedAt string `json:"redeemed_at"`
+}
+
+// IssuanceStatusResponse is the status an issuance was moved to
+type IssuanceStatusResponse struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+// RedemptionReceiptResponse is the receipt of a redemption. StaffUserID is
+// null for redemptions not made by a staff user.
+type RedemptionReceiptResponse struct {
+	ID                 string  `json:"id"`
+	ReceiptNumber      string  `json:"receipt_number"`
+	IssuanceID         string  `json:"issuance_id"`
+	CustomerID         string  `json:"customer_id"`
+	RewardID           string  `json:"reward_id"`
+	RewardName         string  `json:"reward_name"`
+	Value              string  `json:"value"`
+	Currency           string  `json:"currency"`
+	StoreID            string  `json:"store_id"`
+	StaffUserID        *string `json:"staff_user_id"`
+	StaffRef           string  `json:"staff_ref"`
+	RedeemedBy         string  `json:"redeemed_by"`
+	Channel            string  `json:"channel"`
+	RedeemedAt         string  `json:"redeemed_at"`
+	ConfirmationStatus string  `json:"confirmation_status"`
+	ConfirmedAt        string  `json:"confirmed_at"`
+}
+
+// IssuanceHistoryResponse is an issuance's status changes, oldest first
+type IssuanceHistoryResponse struct {
+	IssuanceID string                 `json:"issuance_id"`
+	History    []StatusChangeResponse `json:"history"`
+}
+
+// StatusChangeResponse is a status change of an issuance. OldStatus is empty
+// for the issuance's creation.
+type StatusChangeResponse struct {
+	OldStatus string `json:"old_status"`
+	NewStatus string `json:"new_status"`
+	Actor     string `json:"actor"`
+	Channel   string `json:"channel"`
+	CreatedAt string `json:"created_at"`
+}
+
+// ClawbackResponse is a clawback of a redeemed issuance. BudgetID, Amount
+// and Currency are empty when nothing was credited back to a budget.
+type ClawbackResponse struct {
+	ID         string `json:"id"`
+	IssuanceID string `json:"issuance_id"`
+	CustomerID string `json:"customer_id"`
+	ReasonCode string `json:"reason_code"`
+	Note       string `json:"note"`
+	BudgetID   string `json:"budget_id"`
+	Amount     string `json:"amount"`
+	Currency   string `json:"currency"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// ReissueResponse is a reissue of a failed or lost issuance, with the
+// cancelled original and its replacement
+type ReissueResponse struct {
+	ID            string           `json:"id"`
+	OriginalID    string           `json:"original_id"`
+	ReplacementID string           `json:"replacement_id"`
+	CustomerID    string           `json:"customer_id"`
+	ReasonCode    string           `json:"reason_code"`
+	Note          string           `json:"note"`
+	CreatedAt     string           `json:"created_at"`
+	Original      IssuanceResponse `json:"original"`
+	Replacement   IssuanceResponse `json:"replacement"`
 }
 
 // List handles GET /v1/tenants/:tid/issuances
@@ -66,15 +276,7 @@ func (h *IssuancesHandler) List(c *gin.Context) {
 
 	// Validate status if provided
 	if status != "" {
-		validStatuses := map[string]bool{
-			"reserved":  true,
-			"issued":    true,
-			"redeemed":  true,
-			"expired":   true,
-			"cancelled": true,
-			"failed":    true,
-		}
-		if !validStatuses[status] {
+		if !enums.IssuanceStatus(status).IsValid() {
 			httputil.BadRequest(c, "Invalid status", nil)
 			return
 		}
@@ -99,36 +301,12 @@ func (h *IssuancesHandler) List(c *gin.Context) {
 	}
 
 	// Format response
-	issuancesList := make([]gin.H, len(issuances))
+	issuancesList := make([]IssuanceResponse, len(issuances))
 	for i, issuance := range issuances {
-		issuancesList[i] = gin.H{
-			"id":           formatUUID(issuance.ID),
-			"tenant_id":    formatUUID(issuance.TenantID),
-			"customer_id":  formatUUID(issuance.CustomerID),
-			"campaign_id":  formatUUID(issuance.CampaignID),
-			"reward_id":    formatUUID(issuance.RewardID),
-			"status":       issuance.Status,
-			"code":         issuance.Code.String,
-			"external_ref": issuance.ExternalRef.String,
-			"currency":     issuance.Currency.String,
-			"cost_amount":  issuance.CostAmount.Int.String(),
-			"face_amount":  issuance.FaceAmount.Int.String(),
-			"issued_at":    formatTimestamp(issuance.IssuedAt),
-			"expires_at":   formatTimestamp(issuance.ExpiresAt),
-			"redeemed_at":  formatTimestamp(issuance.RedeemedAt),
-		}
+		issuancesList[i] = formatIssuance(issuance)
 	}
 
-	c.JSON(200, gin.H{
-		"issuances": issuancesList,
-		"total":     total,
-		"limit":     limit,
-		"offset":    offset,
-		"filters": gin.H{
-			"customer_id": customerID,
-			"status":      status,
-		},
-	})
+	httputil.RespondList(c, issuancesList, httputil.NewPage(int64(total), limit, offset))
 }
 
 // Get handles GET /v1/tenants/:tid/issuances/:id
@@ -164,21 +342,146 @@ func (h *IssuancesHandler) Get(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, gin.H{
-		"id":           formatUUID(issuance.ID),
-		"tenant_id":    formatUUID(issuance.TenantID),
-		"customer_id":  formatUUID(issuance.CustomerID),
-		"campaign_id":  formatUUID(issuance.CampaignID),
-		"reward_id":    formatUUID(issuance.RewardID),
-		"status":       issuance.Status,
-		"code":         issuance.Code.String,
-		"external_ref": issuance.ExternalRef.String,
-		"currency":     issuance.Currency.String,
-		"cost_amount":  issuance.CostAmount.Int.String(),
-		"face_amount":  issuance.FaceAmount.Int.String(),
-		"issued_at":    formatTimestamp(issuance.IssuedAt),
-		"expires_at":   formatTimestamp(issuance.ExpiresAt),
-		"redeemed_at":  formatTimestamp(issuance.RedeemedAt),
+	reissuedFrom, reissuedAs, err := h.rewardService.ReissueLinks(c.Request.Context(), tenantUUID, issuanceUUID)
+	if err != nil {
+		h.logger.Error("failed to get reissue links", "issuance_id", issuanceID, "error", err)
+		httputil.InternalError(c, "Failed to get issuance")
+		return
+	}
+
+	httputil.Respond(c, 200, IssuanceDetailResponse{
+		IssuanceResponse: formatIssuance(issuance),
+		ReissuedFrom:     formatUUID(reissuedFrom),
+		ReissuedAs:       formatUUID(reissuedAs),
+	})
+}
+
+// ByCode handles GET /v1/tenants/:tid/issuances/by-code/:code
+// Codes match regardless of case; with ?fuzzy=true spaces and dashes are
+// ignored too. The issuance is returned with its reward, including what
+// the cashier must check to redeem it, and customer.
+func (h *IssuancesHandler) ByCode(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	row, err := h.service.GetIssuanceByCode(c.Request.Context(), tenantUUID, c.Param("code"), c.Query("fuzzy") == "true")
+	if err != nil {
+		if errors.Is(err, issuance.ErrCodeNotFound) {
+			httputil.NotFound(c, "Issuance not found")
+			return
+		}
+		h.logger.Error("failed to get issuance by code", "error", err)
+		httputil.InternalError(c, "Failed to get issuance")
+		return
+	}
+
+	item, err := h.queries.GetRewardByID(c.Request.Context(), db.GetRewardByIDParams{
+		ID:       row.Issuance.RewardID,
+		TenantID: tenantUUID,
+	})
+	if err != nil {
+		h.logger.Error("failed to get reward of issuance", "error", err)
+		httputil.InternalError(c, "Failed to get issuance")
+		return
+	}
+
+	httputil.Respond(c, 200, IssuanceByCodeResponse{
+		IssuanceResponse: formatIssuance(row.Issuance),
+		Reward: IssuanceRewardResponse{
+			ID:           formatUUID(row.Issuance.RewardID),
+			Name:         row.RewardName,
+			Type:         row.RewardType,
+			Requirements: redemptionRequirements(item),
+		},
+		Customer: IssuanceCustomerResponse{
+			ID:          formatUUID(row.Issuance.CustomerID),
+			Name:        row.CustomerName.String,
+			Phone:       row.CustomerPhone.String,
+			ExternalRef: row.CustomerExternalRef.String,
+			Status:      row.CustomerStatus,
+		},
+	})
+}
+
+// redemptionRequirements lists what the cashier must check before redeeming
+// a reward: the minimum basket, redemption window and allowed locations.
+// Metadata is validated when rewards are created, so errors are ignored.
+func redemptionRequirements(item db.RewardCatalog) RedemptionRequirementsResponse {
+	requirements := RedemptionRequirementsResponse{AllowedLocations: []string{}}
+	if minimum, _ := basket.FromMetadata(item.Metadata); minimum > 0 {
+		requirements.MinBasket = &minimum
+	}
+	if w, _ := window.FromMetadata(item.Metadata); w != nil {
+		redemptionWindow := w.String()
+		requirements.RedemptionWindow = &redemptionWindow
+	}
+	if allowed, _ := locations.FromMetadata(item.Metadata); len(allowed) > 0 {
+		requirements.AllowedLocations = allowed
+	}
+	return requirements
+}
+
+// History handles GET /v1/tenants/:tid/issuances/:id/history
+func (h *IssuancesHandler) History(c *gin.Context) {
+	tenantID := c.Param("tid")
+	issuanceID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	if err := httputil.ValidateUUID(issuanceID); err != nil {
+		httputil.BadRequest(c, "Invalid issuance ID", nil)
+		return
+	}
+
+	// Parse UUIDs
+	var tenantUUID, issuanceUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+	if err := issuanceUUID.Scan(issuanceID); err != nil {
+		httputil.BadRequest(c, "Invalid issuance ID format", nil)
+		return
+	}
+
+	if _, err := h.service.GetIssuanceByID(c.Request.Context(), issuanceUUID, tenantUUID); err != nil {
+		httputil.NotFound(c, "Issuance not found")
+		return
+	}
+
+	history, err := h.rewardService.GetStatusHistory(c.Request.Context(), issuanceUUID, tenantUUID)
+	if err != nil {
+		h.logger.Error("failed to get issuance history", "error", err)
+		httputil.InternalError(c, "Failed to get issuance history")
+		return
+	}
+
+	historyList := make([]StatusChangeResponse, len(history))
+	for i, entry := range history {
+		historyList[i] = StatusChangeResponse{
+			OldStatus: entry.OldStatus.String,
+			NewStatus: entry.NewStatus,
+			Actor:     entry.Actor,
+			Channel:   entry.Channel,
+			CreatedAt: formatTimestamp(entry.CreatedAt),
+		}
+	}
+
+	httputil.Respond(c, 200, IssuanceHistoryResponse{
+		IssuanceID: issuanceID,
+		History:    historyList,
 	})
 }
 
@@ -253,30 +556,72 @@ func (h *IssuancesHandler) Redeem(c *gin.Context) {
 		}
 	}
 
+	if req.BasketAmount != nil && *req.BasketAmount < 0 {
+		httputil.BadRequest(c, "basket_amount must not be negative", nil)
+		return
+	}
+
+	checkout := reward.Checkout{StoreID: req.StoreID, BasketAmount: req.BasketAmount}
+	if req.ManagerPIN != "" {
+		if req.StoreID == "" || req.ManagerEmail == "" {
+			httputil.BadRequest(c, "store_id and manager_email are required with a manager PIN", nil)
+			return
+		}
+
+		manager, err := h.queries.GetStaffUserByEmail(c.Request.Context(), db.GetStaffUserByEmailParams{
+			TenantID: tenantUUID,
+			Email:    req.ManagerEmail,
+		})
+		if err != nil || auth.ComparePassword(manager.PwdHash, req.ManagerPIN) != nil {
+			httputil.Unauthorized(c, "Invalid manager PIN")
+			return
+		}
+		if !overrideRoles[manager.Role] {
+			httputil.Forbidden(c, "Only a manager can override a location restriction")
+			return
+		}
+		checkout.OverrideBy = manager.ID
+		checkout.IPAddress = clientIP(c)
+	}
+
 	// Use the reward service to redeem the issuance
 	// This handles:
 	// - State validation (must be "issued")
 	// - OTP/code validation (if OTP is provided)
 	// - Expiry checking
+	// - Minimum basket
+	// - Location restrictions
 	// - Budget charging
 	code := req.OTP
-	err := h.rewardService.RedeemIssuance(c.Request.Context(), issuanceUUID, tenantUUID, code)
+	err := h.rewardService.RedeemIssuance(c.Request.Context(), issuanceUUID, tenantUUID, code, checkout, staffOrigin(c, reward.ChannelAPI))
 	if err != nil {
 		// Check for specific error types to provide better error messages
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "cannot redeem") || strings.Contains(errMsg, "must be issued") {
-			httputil.BadRequest(c, errMsg, nil)
-			return
-		}
-		if strings.Contains(errMsg, "invalid redemption code") {
+		var locationErr *locations.Error
+		var windowErr *window.Error
+		var basketErr *basket.Error
+		switch {
+		case errors.As(err, &basketErr):
+			httputil.BadRequest(c, basketErr.Message(), gin.H{
+				"min_basket":    basketErr.Minimum,
+				"basket_amount": basketErr.Amount,
+			})
+		case errors.As(err, &windowErr):
+			httputil.Conflict(c, windowErr.Message(), gin.H{"redemption_window": windowErr.Window.String()})
+		case errors.As(err, &locationErr):
+			httputil.RespondError(c, http.StatusForbidden, httputil.ErrCodeLocationDenied, locationErr.Message(), gin.H{
+				"store_id":         locationErr.StoreID,
+				"allowed_stores":   locationErr.Stores,
+				"override_allowed": locationErr.StoreID != "",
+			})
+		case errors.Is(err, reward.ErrNotRedeemable):
+			httputil.BadRequest(c, err.Error(), nil)
+		case errors.Is(err, reward.ErrInvalidRedemptionCode):
 			httputil.BadRequest(c, "Invalid OTP code", nil)
-			return
-		}
-		if strings.Contains(errMsg, "expired") {
+		case errors.Is(err, reward.ErrRewardExpired):
 			httputil.BadRequest(c, "Reward has expired", nil)
-			return
+		default:
+			httputil.InternalError(c, "Failed to redeem issuance")
 		}
-		httputil.InternalError(c, "Failed to redeem issuance")
 		return
 	}
 
@@ -287,10 +632,14 @@ func (h *IssuancesHandler) Redeem(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, gin.H{
-		"id":          formatUUID(updatedIss.ID),
-		"status":      updatedIss.Status,
-		"redeemed_at": formatTimestamp(updatedIss.RedeemedAt),
+	if h.surveys != nil {
+		h.surveys.TriggerAfterRedemption(tenantUUID, updatedIss.CustomerID, updatedIss.ID)
+	}
+
+	httputil.Respond(c, 200, RedeemedIssuanceResponse{
+		ID:         formatUUID(updatedIss.ID),
+		Status:     updatedIss.Status,
+		RedeemedAt: formatTimestamp(updatedIss.RedeemedAt),
 	})
 }
 
@@ -321,7 +670,7 @@ func (h *IssuancesHandler) Cancel(c *gin.Context) {
 	}
 
 	// Use reward service to cancel issuance (it handles budget release)
-	err := h.rewardService.CancelIssuance(c.Request.Context(), issuanceUUID, tenantUUID)
+	err := h.rewardService.CancelIssuance(c.Request.Context(), issuanceUUID, tenantUUID, staffOrigin(c, reward.ChannelAPI))
 	if err != nil {
 		httputil.InternalError(c, "Failed to cancel issuance")
 		return
@@ -334,8 +683,375 @@ func (h *IssuancesHandler) Cancel(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, gin.H{
-		"id":     formatUUID(updatedIss.ID),
-		"status": updatedIss.Status,
+	httputil.Respond(c, 200, IssuanceStatusResponse{
+		ID:     formatUUID(updatedIss.ID),
+		Status: updatedIss.Status,
 	})
 }
+
+// Clawback handles POST /v1/tenants/:tid/issuances/:id/clawback
+func (h *IssuancesHandler) Clawback(c *gin.Context) {
+	tenantID := c.Param("tid")
+	issuanceID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	if err := httputil.ValidateUUID(issuanceID); err != nil {
+		httputil.BadRequest(c, "Invalid issuance ID", nil)
+		return
+	}
+
+	var req ClawbackIssuanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	if !reward.ValidClawbackReason(req.ReasonCode) {
+		httputil.BadRequest(c, reward.ErrInvalidClawbackReason.Error(), nil)
+		return
+	}
+
+	// Parse UUIDs
+	var tenantUUID, issuanceUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+	if err := issuanceUUID.Scan(issuanceID); err != nil {
+		httputil.BadRequest(c, "Invalid issuance ID format", nil)
+		return
+	}
+
+	staffID, ok := reviewerID(c)
+	if !ok {
+		return
+	}
+
+	clawback, iss, err := h.rewardService.ClawbackIssuance(c.Request.Context(), reward.ClawbackRequest{
+		TenantID:   tenantUUID,
+		IssuanceID: issuanceUUID,
+		StaffID:    staffID,
+		ReasonCode: req.ReasonCode,
+		Note:       req.Note,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, reward.ErrIssuanceNotFound):
+			httputil.NotFound(c, "Issuance not found")
+		case errors.Is(err, reward.ErrClawbackNotRedeemed), errors.Is(err, reward.ErrAlreadyClawedBack):
+			httputil.Conflict(c, err.Error(), nil)
+		default:
+			h.logger.Error("failed to claw back issuance", "error", err)
+			httputil.InternalError(c, "Failed to claw back issuance")
+		}
+		return
+	}
+
+	if h.webhooks != nil {
+		amount, _ := clawback.Amount.Float64Value()
+		data := webhooks.RewardClawedBackData{
+			ClawbackID:   formatUUID(clawback.ID),
+			IssuanceID:   formatUUID(iss.ID),
+			CustomerID:   formatUUID(iss.CustomerID),
+			RewardID:     formatUUID(iss.RewardID),
+			Code:         iss.Code.String,
+			ExternalRef:  iss.ExternalRef.String,
+			Amount:       amount.Float64,
+			Currency:     clawback.Currency.String,
+			ReasonCode:   clawback.ReasonCode,
+			Note:         clawback.Note.String,
+			RedeemedAt:   formatTimestamp(iss.RedeemedAt),
+			ClawedBackAt: formatTimestamp(clawback.CreatedAt),
+		}
+		if err := h.webhooks.NotifyRewardClawedBack(c.Request.Context(), uuid.UUID(tenantUUID.Bytes), data); err != nil {
+			h.logger.Error("failed to send clawback webhook", "issuance_id", issuanceID, "error", err)
+		}
+	}
+
+	httputil.Respond(c, 200, ClawbackResponse{
+		ID:         formatUUID(clawback.ID),
+		IssuanceID: formatUUID(clawback.IssuanceID),
+		CustomerID: formatUUID(clawback.CustomerID),
+		ReasonCode: clawback.ReasonCode,
+		Note:       clawback.Note.String,
+		BudgetID:   formatUUID(clawback.BudgetID),
+		Amount:     formatNumeric(clawback.Amount),
+		Currency:   clawback.Currency.String,
+		CreatedAt:  formatTimestamp(clawback.CreatedAt),
+	})
+}
+
+// Reissue handles POST /v1/tenants/:tid/issuances/:id/reissue
+// Replaces an issued or failed reward the customer can't use, e.g. a lost
+// code or a failed voucher, with a new issuance carrying a fresh code. The
+// original is cancelled and the replacement sent to the customer.
+func (h *IssuancesHandler) Reissue(c *gin.Context) {
+	tenantID := c.Param("tid")
+	issuanceID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	if err := httputil.ValidateUUID(issuanceID); err != nil {
+		httputil.BadRequest(c, "Invalid issuance ID", nil)
+		return
+	}
+
+	var req ReissueIssuanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	if !reward.ValidReissueReason(req.ReasonCode) {
+		httputil.BadRequest(c, reward.ErrInvalidReissueReason.Error(), nil)
+		return
+	}
+
+	// Parse UUIDs
+	var tenantUUID, issuanceUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+	if err := issuanceUUID.Scan(issuanceID); err != nil {
+		httputil.BadRequest(c, "Invalid issuance ID format", nil)
+		return
+	}
+
+	staffID, ok := reviewerID(c)
+	if !ok {
+		return
+	}
+
+	reissue, original, replacement, err := h.rewardService.ReissueIssuance(c.Request.Context(), reward.ReissueRequest{
+		TenantID:   tenantUUID,
+		IssuanceID: issuanceUUID,
+		StaffID:    staffID,
+		ReasonCode: req.ReasonCode,
+		Note:       req.Note,
+	}, staffOrigin(c, reward.ChannelAPI))
+	if err != nil {
+		switch {
+		case errors.Is(err, reward.ErrIssuanceNotFound):
+			httputil.NotFound(c, "Issuance not found")
+		case errors.Is(err, reward.ErrNotReissuable), errors.Is(err, reward.ErrReissueBudget):
+			httputil.Conflict(c, err.Error(), nil)
+		default:
+			h.logger.Error("failed to reissue issuance", "issuance_id", issuanceID, "error", err)
+			httputil.InternalError(c, "Failed to reissue issuance")
+		}
+		return
+	}
+
+	httputil.Respond(c, 201, ReissueResponse{
+		ID:            formatUUID(reissue.ID),
+		OriginalID:    formatUUID(reissue.OriginalID),
+		ReplacementID: formatUUID(reissue.ReplacementID),
+		CustomerID:    formatUUID(reissue.CustomerID),
+		ReasonCode:    reissue.ReasonCode,
+		Note:          reissue.Note.String,
+		CreatedAt:     formatTimestamp(reissue.CreatedAt),
+		Original:      formatIssuance(original),
+		Replacement:   formatIssuance(replacement),
+	})
+}
+
+// Failed handles GET /v1/tenants/:tid/issuances/failed
+// Lists failed issuances for review with the error that failed them
+func (h *IssuancesHandler) Failed(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	rows, total, err := h.rewardService.ListFailedIssuances(c.Request.Context(), tenantUUID, int32(limit), int32(offset))
+	if err != nil {
+		h.logger.Error("failed to list failed issuances", "error", err)
+		httputil.InternalError(c, "Failed to list failed issuances")
+		return
+	}
+
+	failed := make([]FailedIssuanceResponse, len(rows))
+	for i, row := range rows {
+		failed[i] = FailedIssuanceResponse{
+			IssuanceResponse: formatIssuance(row.Issuance),
+			RewardName:       row.RewardName,
+			RewardType:       row.RewardType,
+			ProcessAttempts:  row.Issuance.ProcessAttempts,
+			LastProcessError: row.Issuance.LastProcessError.String,
+			FailedAt:         formatTimestamp(row.FailedAt),
+		}
+	}
+
+	httputil.RespondList(c, failed, httputil.Page{Total: total, Limit: limit, Offset: offset})
+}
+
+// RedemptionReceipts handles GET /v1/tenants/:tid/customers/:id/redemption-receipts
+// Lists the receipts of a customer's redemptions for settling disputes
+func (h *IssuancesHandler) RedemptionReceipts(c *gin.Context) {
+	tenantID := c.Param("tid")
+	customerID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	if err := httputil.ValidateUUID(customerID); err != nil {
+		httputil.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+
+	var tenantUUID, customerUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+	if err := customerUUID.Scan(customerID); err != nil {
+		httputil.BadRequest(c, "Invalid customer ID format", nil)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	receipts, total, err := h.rewardService.ListRedemptionReceipts(c.Request.Context(), tenantUUID, customerUUID, int32(limit), int32(offset))
+	if err != nil {
+		h.logger.Error("failed to list redemption receipts", "customer_id", customerID, "error", err)
+		httputil.InternalError(c, "Failed to list redemption receipts")
+		return
+	}
+
+	receiptsList := make([]RedemptionReceiptResponse, len(receipts))
+	for i, receipt := range receipts {
+		receiptsList[i] = formatRedemptionReceipt(receipt)
+	}
+
+	httputil.RespondList(c, receiptsList, httputil.Page{Total: total, Limit: limit, Offset: offset})
+}
+
+// Retry handles POST /v1/tenants/:tid/issuances/:id/retry
+// Reserves a failed issuance's budget again and queues it for processing
+func (h *IssuancesHandler) Retry(c *gin.Context) {
+	tenantID := c.Param("tid")
+	issuanceID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	if err := httputil.ValidateUUID(issuanceID); err != nil {
+		httputil.BadRequest(c, "Invalid issuance ID", nil)
+		return
+	}
+
+	var tenantUUID, issuanceUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+	if err := issuanceUUID.Scan(issuanceID); err != nil {
+		httputil.BadRequest(c, "Invalid issuance ID format", nil)
+		return
+	}
+
+	issuance, err := h.rewardService.RetryIssuance(c.Request.Context(), issuanceUUID, tenantUUID, staffOrigin(c, reward.ChannelAPI))
+	if err != nil {
+		switch {
+		case errors.Is(err, reward.ErrIssuanceNotFound):
+			httputil.NotFound(c, "Issuance not found")
+		case errors.Is(err, reward.ErrNotRetryable), errors.Is(err, reward.ErrRetryBudget):
+			httputil.Conflict(c, err.Error(), nil)
+		default:
+			h.logger.Error("failed to retry issuance", "issuance_id", issuanceID, "error", err)
+			httputil.InternalError(c, "Failed to retry issuance")
+		}
+		return
+	}
+
+	httputil.Respond(c, 200, formatIssuance(issuance))
+}
+
+// formatIssuance formats an issuance for the API response
+func formatIssuance(issuance db.Issuance) IssuanceResponse {
+	var valueInputs map[string]interface{}
+	if len(issuance.ValueInputs) > 0 {
+		json.Unmarshal(issuance.ValueInputs, &valueInputs)
+	}
+
+	return IssuanceResponse{
+		ID:          formatUUID(issuance.ID),
+		TenantID:    formatUUID(issuance.TenantID),
+		CustomerID:  formatUUID(issuance.CustomerID),
+		CampaignID:  formatUUID(issuance.CampaignID),
+		RewardID:    formatUUID(issuance.RewardID),
+		Status:      issuance.Status,
+		Code:        issuance.Code.String,
+		ExternalRef: issuance.ExternalRef.String,
+		Currency:    issuance.Currency.String,
+		CostAmount:  formatNumeric(issuance.CostAmount),
+		FaceAmount:  formatNumeric(issuance.FaceAmount),
+		ValueInputs: valueInputs,
+		IssuedAt:    formatTimestamp(issuance.IssuedAt),
+		ExpiresAt:   formatTimestamp(issuance.ExpiresAt),
+		RedeemedAt:  formatTimestamp(issuance.RedeemedAt),
+	}
+}
+
+// formatRedemptionReceipt formats a redemption receipt for the API response
+func formatRedemptionReceipt(receipt db.RedemptionReceipt) RedemptionReceiptResponse {
+	var staffUserID *string
+	if receipt.StaffUserID.Valid {
+		id := formatUUID(receipt.StaffUserID)
+		staffUserID = &id
+	}
+
+	return RedemptionReceiptResponse{
+		ID:                 formatUUID(receipt.ID),
+		ReceiptNumber:      reward.ReceiptNumber(receipt.ID),
+		IssuanceID:         formatUUID(receipt.IssuanceID),
+		CustomerID:         formatUUID(receipt.CustomerID),
+		RewardID:           formatUUID(receipt.RewardID),
+		RewardName:         receipt.RewardName,
+		Value:              formatNumeric(receipt.Value),
+		Currency:           receipt.Currency.String,
+		StoreID:            receipt.StoreID.String,
+		StaffUserID:        staffUserID,
+		StaffRef:           receipt.StaffRef.String,
+		RedeemedBy:         receipt.RedeemedBy,
+		Channel:            receipt.Channel,
+		RedeemedAt:         formatTimestamp(receipt.RedeemedAt),
+		ConfirmationStatus: receipt.ConfirmationStatus.String,
+		ConfirmedAt:        formatTimestamp(receipt.ConfirmedAt),
+	}
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/leaderboard"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LeaderboardsHandler handles campaign leaderboard endpoints
+type LeaderboardsHandler struct {
+	service *leaderboard.Service
+}
+
+// NewLeaderboardsHandler creates a new leaderboards handler
+func NewLeaderboardsHandler(pool *pgxpool.Pool) *LeaderboardsHandler {
+	return &LeaderboardsHandler{
+		service: leaderboard.NewService(pool, db.New(pool)),
+	}
+}
+
+// SetLeaderboardRequest represents the request to enable or disable a
+// campaign's leaderboard
+type SetLeaderboardRequest struct {
+	// Metric is points, spend or visits; null disables the leaderboard
+	Metric *string `json:"metric"`
+}
+
+// LeaderboardEntryResponse is a customer's place on a campaign's leaderboard
+type LeaderboardEntryResponse struct {
+	CustomerID  string `json:"customer_id"`
+	Rank        int32  `json:"rank"`
+	DisplayName string `json:"display_name"`
+	Score       string `json:"score"`
+	ComputedAt  string `json:"computed_at"`
+}
+
+// CustomerRankResponse is a customer's place on a campaign's leaderboard
+// and how many customers are ranked
+type CustomerRankResponse struct {
+	LeaderboardEntryResponse
+	TotalRanked int64 `json:"total_ranked"`
+}
+
+// Set handles PUT /v1/tenants/:tid/campaigns/:id/leaderboard
+// The ranking is rebuilt straight away, then kept fresh by the leaderboard
+// worker.
+func (h *LeaderboardsHandler) Set(c *gin.Context) {
+	tenantUUID, campaignUUID, ok := parseCampaignParams(c)
+	if !ok {
+		return
+	}
+
+	var req SetLeaderboardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	metric := ""
+	if req.Metric != nil {
+		metric = *req.Metric
+		if !leaderboard.ValidMetric(metric) {
+			httputil.BadRequest(c, leaderboard.ErrInvalidMetric.Error(), nil)
+			return
+		}
+	}
+
+	updated, err := h.service.SetMetric(c.Request.Context(), tenantUUID, campaignUUID, metric, time.Now())
+	if err != nil {
+		if errors.Is(err, leaderboard.ErrCampaignNotFound) {
+			httputil.NotFound(c, "Campaign not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to set leaderboard")
+		return
+	}
+
+	httputil.Respond(c, 200, formatCampaign(updated))
+}
+
+// List handles GET /v1/tenants/:tid/campaigns/:id/leaderboard
+func (h *LeaderboardsHandler) List(c *gin.Context) {
+	tenantUUID, campaignUUID, ok := parseCampaignParams(c)
+	if !ok {
+		return
+	}
+
+	limit := c.DefaultQuery("limit", "50")
+	offset := c.DefaultQuery("offset", "0")
+
+	entries, total, err := h.service.List(c.Request.Context(), tenantUUID, campaignUUID, limit, offset)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	entriesList := make([]LeaderboardEntryResponse, len(entries))
+	for i, entry := range entries {
+		entriesList[i] = formatLeaderboardEntry(entry)
+	}
+
+	httputil.RespondList(c, entriesList, httputil.NewPage(total, limit, offset))
+}
+
+// CustomerRank handles GET /v1/tenants/:tid/campaigns/:id/leaderboard/customers/:cid
+func (h *LeaderboardsHandler) CustomerRank(c *gin.Context) {
+	tenantUUID, campaignUUID, ok := parseCampaignParams(c)
+	if !ok {
+		return
+	}
+
+	customerID := c.Param("cid")
+	if err := httputil.ValidateUUID(customerID); err != nil {
+		httputil.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+	var customerUUID pgtype.UUID
+	if err := customerUUID.Scan(customerID); err != nil {
+		httputil.BadRequest(c, "Invalid customer ID format", nil)
+		return
+	}
+
+	entry, total, err := h.service.CustomerRank(c.Request.Context(), tenantUUID, campaignUUID, customerUUID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	httputil.Respond(c, 200, CustomerRankResponse{
+		LeaderboardEntryResponse: formatLeaderboardEntry(entry),
+		TotalRanked:              total,
+	})
+}
+
+// respondError maps leaderboard lookup errors to responses
+func (h *LeaderboardsHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, leaderboard.ErrCampaignNotFound):
+		httputil.NotFound(c, "Campaign not found")
+	case errors.Is(err, leaderboard.ErrNotEnabled):
+		httputil.NotFound(c, "Campaign has no leaderboard")
+	case errors.Is(err, leaderboard.ErrNotRanked):
+		httputil.NotFound(c, "Customer is not on the leaderboard")
+	default:
+		httputil.InternalError(c, "Failed to get leaderboard")
+	}
+}
+
+// formatLeaderboardEntry formats a leaderboard entry for the API response
+func formatLeaderboardEntry(entry leaderboard.Entry) LeaderboardEntryResponse {
+	return LeaderboardEntryResponse{
+		CustomerID:  formatUUID(entry.CustomerID),
+		Rank:        entry.Rank,
+		DisplayName: entry.DisplayName,
+		Score:       formatNumeric(entry.Score),
+		ComputedAt:  formatTimestamp(entry.ComputedAt),
+	}
+}
@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/location"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LocationsHandler handles store/location endpoints
+type LocationsHandler struct {
+	pool    *pgxpool.Pool
+	service *location.Service
+}
+
+// NewLocationsHandler creates a new locations handler
+func NewLocationsHandler(pool *pgxpool.Pool) *LocationsHandler {
+	return &LocationsHandler{
+		pool:    pool,
+		service: location.NewService(db.New(pool)),
+	}
+}
+
+// CreateLocationRequest represents the request to create a location
+type CreateLocationRequest struct {
+	Name      string   `json:"name" binding:"required"`
+	Code      string   `json:"code" binding:"required"`
+	Region    string   `json:"region"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Active    *bool    `json:"active"`
+}
+
+// UpdateLocationRequest represents the request to update a location
+type UpdateLocationRequest struct {
+	Name      *string  `json:"name"`
+	Code      *string  `json:"code"`
+	Region    *string  `json:"region"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Active    *bool    `json:"active"`
+}
+
+// LocationResponse is a store or location in API responses
+type LocationResponse struct {
+	ID        string   `json:"id"`
+	TenantID  string   `json:"tenant_id"`
+	Name      string   `json:"name"`
+	Code      string   `json:"code"`
+	Region    string   `json:"region"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Active    bool     `json:"active"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+}
+
+// Create handles POST /v1/tenants/:tid/locations
+func (h *LocationsHandler) Create(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var req CreateLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	params := location.Params{
+		TenantID:  tenantUUID,
+		Name:      req.Name,
+		Code:      req.Code,
+		Region:    req.Region,
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		Active:    active,
+	}
+	if err := params.Validate(); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	created, err := h.service.Create(c.Request.Context(), params)
+	if err != nil {
+		if errors.Is(err, location.ErrLocationExists) {
+			httputil.Conflict(c, "Location code already exists", nil)
+			return
+		}
+		httputil.InternalError(c, "Failed to create location")
+		return
+	}
+
+	httputil.Respond(c, 201, formatLocation(created))
+}
+
+// List handles GET /v1/tenants/:tid/locations
+// Supports an optional case-insensitive ?region= filter.
+func (h *LocationsHandler) List(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	locations, err := h.service.List(c.Request.Context(), tenantUUID, c.Query("region"))
+	if err != nil {
+		httputil.InternalError(c, "Failed to list locations")
+		return
+	}
+
+	locationsList := make([]LocationResponse, len(locations))
+	for i, loc := range locations {
+		locationsList[i] = formatLocation(loc)
+	}
+
+	httputil.RespondList(c, locationsList, httputil.Page{Total: int64(len(locationsList))})
+}
+
+// Get handles GET /v1/tenants/:tid/locations/:id
+func (h *LocationsHandler) Get(c *gin.Context) {
+	tenantUUID, locationUUID, ok := parseLocationParams(c)
+	if !ok {
+		return
+	}
+
+	loc, err := h.service.Get(c.Request.Context(), tenantUUID, locationUUID)
+	if err != nil {
+		if errors.Is(err, location.ErrLocationNotFound) {
+			httputil.NotFound(c, "Location not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get location")
+		return
+	}
+
+	httputil.Respond(c, 200, formatLocation(loc))
+}
+
+// Update handles PATCH /v1/tenants/:tid/locations/:id
+func (h *LocationsHandler) Update(c *gin.Context) {
+	tenantUUID, locationUUID, ok := parseLocationParams(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	// Get current location so omitted fields are preserved
+	existing, err := h.service.Get(c.Request.Context(), tenantUUID, locationUUID)
+	if err != nil {
+		if errors.Is(err, location.ErrLocationNotFound) {
+			httputil.NotFound(c, "Location not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get location")
+		return
+	}
+
+	params := location.Params{
+		TenantID:  tenantUUID,
+		Name:      existing.Name,
+		Code:      existing.Code,
+		Region:    existing.Region.String,
+		Latitude:  location.Coordinate(existing.Latitude),
+		Longitude: location.Coordinate(existing.Longitude),
+		Active:    existing.Active,
+	}
+	if req.Name != nil {
+		params.Name = *req.Name
+	}
+	if req.Code != nil {
+		params.Code = *req.Code
+	}
+	if req.Region != nil {
+		params.Region = *req.Region
+	}
+	if req.Latitude != nil {
+		params.Latitude = req.Latitude
+	}
+	if req.Longitude != nil {
+		params.Longitude = req.Longitude
+	}
+	if req.Active != nil {
+		params.Active = *req.Active
+	}
+
+	if err := params.Validate(); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	updated, err := h.service.Update(c.Request.Context(), locationUUID, params)
+	if err != nil {
+		switch {
+		case errors.Is(err, location.ErrLocationNotFound):
+			httputil.NotFound(c, "Location not found")
+		case errors.Is(err, location.ErrLocationExists):
+			httputil.Conflict(c, "Location code already exists", nil)
+		default:
+			httputil.InternalError(c, "Failed to update location")
+		}
+		return
+	}
+
+	httputil.Respond(c, 200, formatLocation(updated))
+}
+
+// Delete handles DELETE /v1/tenants/:tid/locations/:id
+// Locations are deactivated rather than deleted so historical events keep their attribution.
+func (h *LocationsHandler) Delete(c *gin.Context) {
+	tenantUUID, locationUUID, ok := parseLocationParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Deactivate(c.Request.Context(), tenantUUID, locationUUID); err != nil {
+		httputil.InternalError(c, "Failed to deactivate location")
+		return
+	}
+
+	httputil.Respond(c, 200, DeactivatedResponse{
+		ID:      c.Param("id"),
+		Message: "Location deactivated successfully",
+	})
+}
+
+// parseLocationParams validates and parses the tenant and location IDs from the path
+func parseLocationParams(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, locationUUID pgtype.UUID
+
+	tenantID := c.Param("tid")
+	locationID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, locationUUID, false
+	}
+	if err := httputil.ValidateUUID(locationID); err != nil {
+		httputil.BadRequest(c, "Invalid location ID", nil)
+		return tenantUUID, locationUUID, false
+	}
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, locationUUID, false
+	}
+	if err := locationUUID.Scan(locationID); err != nil {
+		httputil.BadRequest(c, "Invalid location ID format", nil)
+		return tenantUUID, locationUUID, false
+	}
+
+	return tenantUUID, locationUUID, true
+}
+
+// formatLocation formats a location for the API response
+func formatLocation(loc db.Location) LocationResponse {
+	return LocationResponse{
+		ID:        formatUUID(loc.ID),
+		TenantID:  formatUUID(loc.TenantID),
+		Name:      loc.Name,
+		Code:      loc.Code,
+		Region:    loc.Region.String,
+		Latitude:  location.Coordinate(loc.Latitude),
+		Longitude: location.Coordinate(loc.Longitude),
+		Active:    loc.Active,
+		CreatedAt: formatTimestamp(loc.CreatedAt),
+		UpdatedAt: formatTimestamp(loc.UpdatedAt),
+	}
+}
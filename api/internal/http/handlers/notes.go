@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/http/middleware"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/note"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// NotesHandler handles the internal notes staff attach to customers and
+// issuances. Notes are only served here, never to customers.
+type NotesHandler struct {
+	pool    *pgxpool.Pool
+	service *note.Service
+}
+
+// NewNotesHandler creates a new notes handler
+func NewNotesHandler(pool *pgxpool.Pool) *NotesHandler {
+	return &NotesHandler{
+		pool:    pool,
+		service: note.NewService(db.New(pool)),
+	}
+}
+
+// CreateNoteRequest represents the request to add a note
+type CreateNoteRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// NoteResponse is a staff note in API responses. IssuanceID is null for
+// notes on the customer rather than one of their issuances.
+type NoteResponse struct {
+	ID         string  `json:"id"`
+	CustomerID string  `json:"customer_id"`
+	IssuanceID *string `json:"issuance_id"`
+	AuthorID   string  `json:"author_id"`
+	Body       string  `json:"body"`
+	CreatedAt  string  `json:"created_at"`
+}
+
+// NoteWithAuthorResponse is a listed note with its author's name and email
+type NoteWithAuthorResponse struct {
+	NoteResponse
+	AuthorName  string `json:"author_name"`
+	AuthorEmail string `json:"author_email"`
+}
+
+// IssuanceNotesResponse is the notes on an issuance
+type IssuanceNotesResponse struct {
+	Notes []NoteWithAuthorResponse `json:"notes"`
+}
+
+// CreateForCustomer handles POST /v1/tenants/:tid/customers/:id/notes
+func (h *NotesHandler) CreateForCustomer(c *gin.Context) {
+	tenantUUID, customerUUID, ok := parseNoteParams(c, "customer")
+	if !ok {
+		return
+	}
+	authorUUID, ok := noteAuthor(c)
+	if !ok {
+		return
+	}
+
+	var req CreateNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	created, err := h.service.AddToCustomer(c.Request.Context(), tenantUUID, customerUUID, authorUUID, req.Body)
+	if err != nil {
+		switch {
+		case errors.Is(err, note.ErrInvalidBody):
+			httputil.BadRequest(c, err.Error(), nil)
+		case errors.Is(err, note.ErrCustomerNotFound):
+			httputil.NotFound(c, "Customer not found")
+		default:
+			httputil.InternalError(c, "Failed to add note")
+		}
+		return
+	}
+
+	httputil.Respond(c, 201, formatNote(created))
+}
+
+// ListForCustomer handles GET /v1/tenants/:tid/customers/:id/notes
+// Lists the customer's notes, including those on their issuances
+func (h *NotesHandler) ListForCustomer(c *gin.Context) {
+	tenantUUID, customerUUID, ok := parseNoteParams(c, "customer")
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	rows, total, err := h.service.ListForCustomer(c.Request.Context(), tenantUUID, customerUUID, int32(limit), int32(offset))
+	if err != nil {
+		httputil.InternalError(c, "Failed to list notes")
+		return
+	}
+
+	notes := make([]NoteWithAuthorResponse, len(rows))
+	for i, row := range rows {
+		notes[i] = formatNoteWithAuthor(db.StaffNote{
+			ID:         row.ID,
+			TenantID:   row.TenantID,
+			CustomerID: row.CustomerID,
+			IssuanceID: row.IssuanceID,
+			AuthorID:   row.AuthorID,
+			Body:       row.Body,
+			CreatedAt:  row.CreatedAt,
+		}, row.AuthorName, row.AuthorEmail)
+	}
+
+	httputil.RespondList(c, notes, httputil.Page{Total: total, Limit: limit, Offset: offset})
+}
+
+// CreateForIssuance handles POST /v1/tenants/:tid/issuances/:id/notes
+func (h *NotesHandler) CreateForIssuance(c *gin.Context) {
+	tenantUUID, issuanceUUID, ok := parseNoteParams(c, "issuance")
+	if !ok {
+		return
+	}
+	authorUUID, ok := noteAuthor(c)
+	if !ok {
+		return
+	}
+
+	var req CreateNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	created, err := h.service.AddToIssuance(c.Request.Context(), tenantUUID, issuanceUUID, authorUUID, req.Body)
+	if err != nil {
+		switch {
+		case errors.Is(err, note.ErrInvalidBody):
+			httputil.BadRequest(c, err.Error(), nil)
+		case errors.Is(err, note.ErrIssuanceNotFound):
+			httputil.NotFound(c, "Issuance not found")
+		default:
+			httputil.InternalError(c, "Failed to add note")
+		}
+		return
+	}
+
+	httputil.Respond(c, 201, formatNote(created))
+}
+
+// ListForIssuance handles GET /v1/tenants/:tid/issuances/:id/notes
+func (h *NotesHandler) ListForIssuance(c *gin.Context) {
+	tenantUUID, issuanceUUID, ok := parseNoteParams(c, "issuance")
+	if !ok {
+		return
+	}
+
+	rows, err := h.service.ListForIssuance(c.Request.Context(), tenantUUID, issuanceUUID)
+	if err != nil {
+		httputil.InternalError(c, "Failed to list notes")
+		return
+	}
+
+	notes := make([]NoteWithAuthorResponse, len(rows))
+	for i, row := range rows {
+		notes[i] = formatNoteWithAuthor(db.StaffNote{
+			ID:         row.ID,
+			TenantID:   row.TenantID,
+			CustomerID: row.CustomerID,
+			IssuanceID: row.IssuanceID,
+			AuthorID:   row.AuthorID,
+			Body:       row.Body,
+			CreatedAt:  row.CreatedAt,
+		}, row.AuthorName, row.AuthorEmail)
+	}
+
+	httputil.Respond(c, 200, IssuanceNotesResponse{Notes: notes})
+}
+
+// parseNoteParams parses the tenant and the customer or issuance a note is
+// on from the path
+func parseNoteParams(c *gin.Context, resource string) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, resourceUUID pgtype.UUID
+
+	tenantID := c.Param("tid")
+	resourceID := c.Param("id")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, resourceUUID, false
+	}
+	if err := httputil.ValidateUUID(resourceID); err != nil {
+		httputil.BadRequest(c, "Invalid "+resource+" ID", nil)
+		return tenantUUID, resourceUUID, false
+	}
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, resourceUUID, false
+	}
+	if err := resourceUUID.Scan(resourceID); err != nil {
+		httputil.BadRequest(c, "Invalid "+resource+" ID format", nil)
+		return tenantUUID, resourceUUID, false
+	}
+
+	return tenantUUID, resourceUUID, true
+}
+
+// noteAuthor returns the staff user adding a note. Notes are attributed to
+// their author, so only staff of the tenant itself can add them.
+func noteAuthor(c *gin.Context) (pgtype.UUID, bool) {
+	var authorUUID pgtype.UUID
+	if c.GetString(middleware.TenantIDKey) != c.Param("tid") || authorUUID.Scan(c.GetString(middleware.UserIDKey)) != nil {
+		httputil.Forbidden(c, "Only staff of this tenant can add notes")
+		return authorUUID, false
+	}
+	return authorUUID, true
+}
+
+// formatNote formats a note for the API response
+func formatNote(n db.StaffNote) NoteResponse {
+	var issuanceID *string
+	if n.IssuanceID.Valid {
+		id := formatUUID(n.IssuanceID)
+		issuanceID = &id
+	}
+
+	return NoteResponse{
+		ID:         formatUUID(n.ID),
+		CustomerID: formatUUID(n.CustomerID),
+		IssuanceID: issuanceID,
+		AuthorID:   formatUUID(n.AuthorID),
+		Body:       n.Body,
+		CreatedAt:  formatTimestamp(n.CreatedAt),
+	}
+}
+
+// formatNoteWithAuthor formats a listed note with its author's name and email
+func formatNoteWithAuthor(n db.StaffNote, authorName, authorEmail string) NoteWithAuthorResponse {
+	return NoteWithAuthorResponse{
+		NoteResponse: formatNote(n),
+		AuthorName:   authorName,
+		AuthorEmail:  authorEmail,
+	}
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/bmachimbira/loyalty/api/internal/channels/whatsapp"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// OutboundMessagesHandler handles inspection and retry of queued WhatsApp
+// messages
+type OutboundMessagesHandler struct {
+	queue *whatsapp.OutboundQueue
+}
+
+// NewOutboundMessagesHandler creates a new outbound messages handler
+func NewOutboundMessagesHandler(queue *whatsapp.OutboundQueue) *OutboundMessagesHandler {
+	return &OutboundMessagesHandler{queue: queue}
+}
+
+// OutboundMessageResponse is a queued WhatsApp message in API responses.
+// LastError is only set once an attempt to send it failed.
+type OutboundMessageResponse struct {
+	ID            int64           `json:"id"`
+	TenantID      string          `json:"tenant_id"`
+	Recipient     string          `json:"recipient"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int32           `json:"attempts"`
+	NextAttemptAt string          `json:"next_attempt_at"`
+	CreatedAt     string          `json:"created_at"`
+	SentAt        string          `json:"sent_at"`
+	DeadAt        string          `json:"dead_at"`
+	LastError     string          `json:"last_error,omitempty"`
+}
+
+// List handles GET /v1/tenants/:tid/outbound-messages
+// Filter with ?status=dead to see messages that failed permanently.
+func (h *OutboundMessagesHandler) List(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	limit := c.DefaultQuery("limit", "50")
+	offset := c.DefaultQuery("offset", "0")
+	status := c.Query("status")
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	messages, total, err := h.queue.List(c.Request.Context(), tenantUUID, status, limit, offset)
+	if err != nil {
+		if errors.Is(err, whatsapp.ErrInvalidOutboundStatus) {
+			httputil.BadRequest(c, "Invalid status filter", nil)
+			return
+		}
+		httputil.InternalError(c, "Failed to list outbound messages")
+		return
+	}
+
+	messagesList := make([]OutboundMessageResponse, len(messages))
+	for i, msg := range messages {
+		messagesList[i] = formatOutboundMessage(msg)
+	}
+
+	httputil.RespondList(c, messagesList, httputil.NewPage(total, limit, offset))
+}
+
+// Get handles GET /v1/tenants/:tid/outbound-messages/:id
+func (h *OutboundMessagesHandler) Get(c *gin.Context) {
+	tenantUUID, id, ok := parseOutboundMessageParams(c)
+	if !ok {
+		return
+	}
+
+	msg, err := h.queue.Get(c.Request.Context(), tenantUUID, id)
+	if err != nil {
+		if errors.Is(err, whatsapp.ErrOutboundNotFound) {
+			httputil.NotFound(c, "Outbound message not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get outbound message")
+		return
+	}
+
+	httputil.Respond(c, 200, formatOutboundMessage(msg))
+}
+
+// Retry handles POST /v1/tenants/:tid/outbound-messages/:id/retry
+// Returns a dead message to the queue for the dispatcher to send.
+func (h *OutboundMessagesHandler) Retry(c *gin.Context) {
+	tenantUUID, id, ok := parseOutboundMessageParams(c)
+	if !ok {
+		return
+	}
+
+	msg, err := h.queue.Retry(c.Request.Context(), tenantUUID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, whatsapp.ErrOutboundNotFound):
+			httputil.NotFound(c, "Outbound message not found")
+		case errors.Is(err, whatsapp.ErrOutboundNotDead):
+			httputil.Conflict(c, "Outbound message is not dead", nil)
+		default:
+			httputil.InternalError(c, "Failed to retry outbound message")
+		}
+		return
+	}
+
+	httputil.Respond(c, 200, formatOutboundMessage(msg))
+}
+
+// parseOutboundMessageParams validates and parses the tenant and message IDs
+func parseOutboundMessageParams(c *gin.Context) (pgtype.UUID, int64, bool) {
+	var tenantUUID pgtype.UUID
+
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, 0, false
+	}
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, 0, false
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		httputil.BadRequest(c, "Invalid outbound message ID", nil)
+		return tenantUUID, 0, false
+	}
+
+	return tenantUUID, id, true
+}
+
+// formatOutboundMessage formats a queued message for the API response
+func formatOutboundMessage(msg db.OutboundMessage) OutboundMessageResponse {
+	response := OutboundMessageResponse{
+		ID:            msg.ID,
+		TenantID:      formatUUID(msg.TenantID),
+		Recipient:     msg.Recipient,
+		Payload:       json.RawMessage(msg.Payload),
+		Status:        msg.Status,
+		Attempts:      msg.Attempts,
+		NextAttemptAt: formatTimestamp(msg.NextAttemptAt),
+		CreatedAt:     formatTimestamp(msg.CreatedAt),
+		SentAt:        formatTimestamp(msg.SentAt),
+		DeadAt:        formatTimestamp(msg.DeadAt),
+	}
+	if msg.LastError.Valid {
+		response.LastError = msg.LastError.String
+	}
+	return response
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/auth"
+	"github.com/bmachimbira/loyalty/api/internal/health"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/pii"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxHealthWindowHours bounds how far back the tenant health report looks
+const maxHealthWindowHours = 7 * 24
+
+// PlatformHandler serves platform operators across all tenants
+type PlatformHandler struct {
+	reporter      *health.TenantReporter
+	keyring       *auth.Keyring
+	reloadSecrets func() error
+	logger        *slog.Logger
+}
+
+// NewPlatformHandler creates a new platform handler. reloadSecrets rotates
+// keyring's keys.
+func NewPlatformHandler(pool *pgxpool.Pool, keyring *auth.Keyring, reloadSecrets func() error, logger *slog.Logger) *PlatformHandler {
+	return &PlatformHandler{
+		reporter:      health.NewTenantReporter(pool),
+		keyring:       keyring,
+		reloadSecrets: reloadSecrets,
+		logger:        logger,
+	}
+}
+
+// TenantHealthResponse is every tenant's health over a window, with how
+// many tenants are in each status
+type TenantHealthResponse struct {
+	Since   string                `json:"since"`
+	Hours   int                   `json:"hours"`
+	Summary map[health.Status]int `json:"summary"`
+	Tenants []health.TenantHealth `json:"tenants"`
+}
+
+// ReloadedSecretsResponse is the key versions active after a reload
+type ReloadedSecretsResponse struct {
+	JWTKids     []string `json:"jwt_kids"`
+	SigningKid  string   `json:"signing_kid"`
+	HMACAPIKeys int      `json:"hmac_api_keys"`
+	PIIKids     []string `json:"pii_kids"`
+}
+
+// TenantHealth handles GET /v1/platform/tenant-health
+// Reports every tenant's event throughput, error rates, backlogs, budget
+// alerts, webhook failures and WhatsApp delivery over the last ?hours
+// (default 24), tenants in the most trouble first.
+func (h *PlatformHandler) TenantHealth(c *gin.Context) {
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours < 1 || hours > maxHealthWindowHours {
+		httputil.BadRequest(c, "hours must be between 1 and "+strconv.Itoa(maxHealthWindowHours), nil)
+		return
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	report, err := h.reporter.Report(c.Request.Context(), since)
+	if err != nil {
+		h.logger.Error("failed to report tenant health", "error", err)
+		httputil.InternalError(c, "Failed to report tenant health")
+		return
+	}
+
+	counts := map[health.Status]int{
+		health.StatusHealthy:   0,
+		health.StatusDegraded:  0,
+		health.StatusUnhealthy: 0,
+	}
+	for _, tenant := range report {
+		counts[tenant.Status]++
+	}
+
+	httputil.Respond(c, 200, TenantHealthResponse{
+		Since:   since.UTC().Format(time.RFC3339),
+		Hours:   hours,
+		Summary: counts,
+		Tenants: report,
+	})
+}
+
+// ReloadSecrets handles POST /v1/platform/secrets/reload
+// Reloads the JWT, HMAC and PII key versions, as SIGHUP does, and reports
+// the active kids. Secrets are never returned.
+func (h *PlatformHandler) ReloadSecrets(c *gin.Context) {
+	if err := h.reloadSecrets(); err != nil {
+		h.logger.Error("failed to reload secrets", "error", err)
+		httputil.InternalError(c, "Failed to reload secrets, the current keys stay active: "+err.Error())
+		return
+	}
+
+	kids := h.keyring.JWTKeyIDs()
+	piiKids := pii.Default().KeyIDs()
+	h.logger.Info("secrets reloaded", "jwt_kids", kids, "pii_kids", piiKids)
+	httputil.Respond(c, 200, ReloadedSecretsResponse{
+		JWTKids:     kids,
+		SigningKid:  kids[0],
+		HMACAPIKeys: len(h.keyring.HMACKeys()),
+		PIIKids:     piiKids,
+	})
+}
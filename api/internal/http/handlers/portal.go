@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bmachimbira/loyalty/api/internal/catalogcache"
+	"github.com/bmachimbira/loyalty/api/internal/channels"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/enums"
+	"github.com/bmachimbira/loyalty/api/internal/http/middleware"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/phone"
+	"github.com/bmachimbira/loyalty/api/internal/portal"
+	"github.com/bmachimbira/loyalty/api/internal/reward"
+	"github.com/bmachimbira/loyalty/api/internal/reward/basket"
+	"github.com/bmachimbira/loyalty/api/internal/reward/locations"
+	"github.com/bmachimbira/loyalty/api/internal/reward/window"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PortalHandler handles the customer-facing portal API used by the hosted
+// customer portal and embedded "My Rewards" widgets
+type PortalHandler struct {
+	queries    *db.Queries
+	phones     *phone.Normalizer
+	portal     *portal.Service
+	redemption *channels.RedemptionFlow
+}
+
+// NewPortalHandler creates a new portal handler
+func NewPortalHandler(pool *pgxpool.Pool, catalog *catalogcache.Cache, portalService *portal.Service, rewards *reward.Service) *PortalHandler {
+	queries := db.New(pool)
+	return &PortalHandler{
+		queries:    queries,
+		phones:     phone.NewNormalizer(queries),
+		portal:     portalService,
+		redemption: channels.NewRedemptionFlow(queries, catalog, rewards),
+	}
+}
+
+// RequestCodeRequest represents the request for a sign-in code
+type RequestCodeRequest struct {
+	Phone   string `json:"phone" binding:"required"`
+	Channel string `json:"channel"`
+}
+
+// VerifyCodeRequest represents the request to exchange a sign-in code for a
+// customer token
+type VerifyCodeRequest struct {
+	Phone string `json:"phone" binding:"required"`
+	Code  string `json:"code" binding:"required"`
+}
+
+// PortalRedeemRequest represents a customer's request to redeem a reward
+type PortalRedeemRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// CodeSentResponse confirms that a sign-in code was sent
+type CodeSentResponse struct {
+	Status string `json:"status"`
+}
+
+// PortalTokenResponse is the token a customer signed in with a code uses
+type PortalTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	CustomerID  string `json:"customer_id"`
+}
+
+// PortalCustomerResponse is the signed-in customer
+type PortalCustomerResponse struct {
+	ID        string `json:"id"`
+	PhoneE164 string `json:"phone_e164"`
+	Status    string `json:"status"`
+	CreatedAt string `json:"created_at"`
+}
+
+// PortalRewardResponse is one of the customer's active rewards. Internal
+// fields such as cost and budget are left out, as are unset ones.
+type PortalRewardResponse struct {
+	IssuanceID string `json:"issuance_id"`
+	RewardID   string `json:"reward_id"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	IssuedAt   string `json:"issued_at"`
+	Type       string `json:"type,omitempty"`
+	Code       string `json:"code,omitempty"`
+	Currency   string `json:"currency,omitempty"`
+	FaceAmount string `json:"face_amount,omitempty"`
+	ExpiresAt  string `json:"expires_at,omitempty"`
+}
+
+// PointsResponse is the customer's points balance
+type PointsResponse struct {
+	Points int64 `json:"points"`
+}
+
+// PortalRedemptionResponse is a reward the customer just redeemed
+type PortalRedemptionResponse struct {
+	IssuanceID string `json:"issuance_id"`
+	RewardName string `json:"reward_name"`
+	Status     string `json:"status"`
+}
+
+// RequestCode handles POST /v1/portal/tenants/:tid/otp
+// Sends a sign-in code over the channel (default whatsapp). The response is
+// the same whether or not the phone number is enrolled.
+func (h *PortalHandler) RequestCode(c *gin.Context) {
+	tenantUUID, ok := parsePortalTenant(c)
+	if !ok {
+		return
+	}
+
+	var req RequestCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+	phoneE164, ok := normalizePhone(c, h.phones, tenantUUID, req.Phone)
+	if !ok {
+		return
+	}
+	if req.Channel == "" {
+		req.Channel = reward.ChannelWhatsApp
+	}
+
+	err := h.portal.RequestCode(c.Request.Context(), tenantUUID, phoneE164, req.Channel)
+	switch {
+	case errors.Is(err, portal.ErrChannelUnavailable):
+		httputil.BadRequest(c, "Sign-in codes can't be sent over "+req.Channel, nil)
+		return
+	case errors.Is(err, portal.ErrTooManyCodes):
+		httputil.RateLimited(c, "Too many sign-in codes requested. Please try again later.")
+		return
+	case err != nil:
+		httputil.InternalError(c, "Failed to send sign-in code")
+		return
+	}
+
+	httputil.Respond(c, http.StatusAccepted, CodeSentResponse{Status: "sent"})
+}
+
+// VerifyCode handles POST /v1/portal/tenants/:tid/token
+func (h *PortalHandler) VerifyCode(c *gin.Context) {
+	tenantUUID, ok := parsePortalTenant(c)
+	if !ok {
+		return
+	}
+
+	var req VerifyCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+	phoneE164, ok := normalizePhone(c, h.phones, tenantUUID, req.Phone)
+	if !ok {
+		return
+	}
+
+	session, err := h.portal.VerifyCode(c.Request.Context(), tenantUUID, phoneE164, req.Code)
+	if err != nil {
+		if errors.Is(err, portal.ErrInvalidCode) {
+			httputil.Unauthorized(c, "Invalid or expired sign-in code")
+			return
+		}
+		httputil.InternalError(c, "Failed to verify sign-in code")
+		return
+	}
+
+	httputil.Respond(c, 200, PortalTokenResponse{
+		AccessToken: session.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   session.ExpiresIn,
+		CustomerID:  formatUUID(session.CustomerID),
+	})
+}
+
+// Me handles GET /v1/portal/me
+func (h *PortalHandler) Me(c *gin.Context) {
+	tenantUUID, customerUUID, ok := portalCustomer(c)
+	if !ok {
+		return
+	}
+
+	customer, err := h.queries.GetCustomerByID(c.Request.Context(), db.GetCustomerByIDParams{
+		ID:       customerUUID,
+		TenantID: tenantUUID,
+	})
+	if err != nil {
+		httputil.NotFound(c, "Customer not found")
+		return
+	}
+
+	httputil.Respond(c, 200, PortalCustomerResponse{
+		ID:        formatUUID(customer.ID),
+		PhoneE164: customer.PhoneE164.String,
+		Status:    customer.Status,
+		CreatedAt: formatTimestamp(customer.CreatedAt),
+	})
+}
+
+// Rewards handles GET /v1/portal/me/rewards
+// Lists the customer's active rewards.
+func (h *PortalHandler) Rewards(c *gin.Context) {
+	tenantUUID, customerUUID, ok := portalCustomer(c)
+	if !ok {
+		return
+	}
+
+	active, err := h.redemption.ActiveRewards(c.Request.Context(), tenantUUID, customerUUID)
+	if err != nil {
+		httputil.InternalError(c, "Failed to list rewards")
+		return
+	}
+
+	rewardsList := make([]PortalRewardResponse, len(active))
+	for i, a := range active {
+		rewardsList[i] = formatPortalReward(a)
+	}
+
+	httputil.Respond(c, 200, rewardsList)
+}
+
+// Points handles GET /v1/portal/me/points
+func (h *PortalHandler) Points(c *gin.Context) {
+	tenantUUID, customerUUID, ok := portalCustomer(c)
+	if !ok {
+		return
+	}
+
+	points, err := h.queries.GetCustomerPointsBalance(c.Request.Context(), db.GetCustomerPointsBalanceParams{
+		TenantID:   tenantUUID,
+		CustomerID: customerUUID,
+	})
+	if err != nil {
+		httputil.InternalError(c, "Failed to get points balance")
+		return
+	}
+
+	httputil.Respond(c, 200, PointsResponse{Points: points})
+}
+
+// Redeem handles POST /v1/portal/me/redemptions
+// Redeems one of the customer's active rewards by its code.
+func (h *PortalHandler) Redeem(c *gin.Context) {
+	tenantUUID, customerUUID, ok := portalCustomer(c)
+	if !ok {
+		return
+	}
+
+	var req PortalRedeemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	redeemed, err := h.redemption.Redeem(c.Request.Context(), tenantUUID, customerUUID, req.Code, reward.ChannelPortal)
+	var locationErr *locations.Error
+	var windowErr *window.Error
+	var basketErr *basket.Error
+	switch {
+	case errors.As(err, &basketErr):
+		httputil.Conflict(c, basketErr.Message(), gin.H{"min_basket": basketErr.Minimum})
+		return
+	case errors.As(err, &windowErr):
+		httputil.Conflict(c, windowErr.Message(), gin.H{"redemption_window": windowErr.Window.String()})
+		return
+	case errors.As(err, &locationErr):
+		httputil.RespondError(c, http.StatusForbidden, httputil.ErrCodeLocationDenied, locationErr.Message(), gin.H{
+			"allowed_stores": locationErr.Stores,
+		})
+		return
+	case errors.Is(err, channels.ErrCodeNotFound):
+		httputil.NotFound(c, "No active reward with this code")
+		return
+	case errors.Is(err, channels.ErrAlreadyRedeemed):
+		httputil.Conflict(c, "Reward has already been redeemed", nil)
+		return
+	case errors.Is(err, reward.ErrRewardExpired):
+		httputil.Conflict(c, "Reward has expired", nil)
+		return
+	case errors.Is(err, reward.ErrNotRedeemable):
+		httputil.Conflict(c, "Reward can't be redeemed yet", nil)
+		return
+	case err != nil:
+		httputil.InternalError(c, "Failed to redeem reward")
+		return
+	}
+
+	httputil.Respond(c, 200, PortalRedemptionResponse{
+		IssuanceID: formatUUID(redeemed.Issuance.ID),
+		RewardName: redeemed.Name(""),
+		Status:     string(enums.IssuanceRedeemed),
+	})
+}
+
+// parsePortalTenant parses the tenant of a sign-in request
+func parsePortalTenant(c *gin.Context) (pgtype.UUID, bool) {
+	var tenantUUID pgtype.UUID
+	if err := httputil.ValidateUUID(c.Param("tid")); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, false
+	}
+	if err := tenantUUID.Scan(c.Param("tid")); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, false
+	}
+	return tenantUUID, true
+}
+
+// portalCustomer returns the tenant and customer of the signed-in customer,
+// set by middleware.RequireCustomerAuth
+func portalCustomer(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, customerUUID pgtype.UUID
+	if tenantUUID.Scan(c.GetString(middleware.TenantIDKey)) != nil || customerUUID.Scan(c.GetString(middleware.CustomerIDKey)) != nil {
+		httputil.Unauthorized(c, "Customer not authenticated")
+		return tenantUUID, customerUUID, false
+	}
+	return tenantUUID, customerUUID, true
+}
+
+// formatPortalReward formats one of the customer's active rewards
+func formatPortalReward(a channels.ActiveReward) PortalRewardResponse {
+	iss := a.Issuance
+	result := PortalRewardResponse{
+		IssuanceID: formatUUID(iss.ID),
+		RewardID:   formatUUID(iss.RewardID),
+		Name:       a.Name(""),
+		Status:     iss.Status,
+		IssuedAt:   formatTimestamp(iss.IssuedAt),
+	}
+	if a.Reward != nil {
+		result.Type = a.Reward.Type
+	}
+	if iss.Code.Valid {
+		result.Code = iss.Code.String
+	}
+	if iss.Currency.Valid {
+		result.Currency = iss.Currency.String
+	}
+	if iss.FaceAmount.Valid {
+		result.FaceAmount = formatNumeric(iss.FaceAmount)
+	}
+	if iss.ExpiresAt.Valid {
+		result.ExpiresAt = formatTimestamp(iss.ExpiresAt)
+	}
+	return result
+}
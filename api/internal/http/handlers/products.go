@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/product"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ProductsHandler handles product reference endpoints
+type ProductsHandler struct {
+	pool    *pgxpool.Pool
+	service *product.Service
+}
+
+// NewProductsHandler creates a new products handler
+func NewProductsHandler(pool *pgxpool.Pool) *ProductsHandler {
+	return &ProductsHandler{
+		pool:    pool,
+		service: product.NewService(pool, db.New(pool)),
+	}
+}
+
+// ProductUploadItem is a single product in a JSON upload batch
+type ProductUploadItem struct {
+	SKU      string `json:"sku"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Active   *bool  `json:"active"`
+}
+
+// UploadProductsRequest represents a JSON product upload batch
+type UploadProductsRequest struct {
+	Products []ProductUploadItem `json:"products" binding:"required"`
+}
+
+// ProductsUploadedResponse is the result of a product upload
+type ProductsUploadedResponse struct {
+	ProductsUploaded int    `json:"products_uploaded"`
+	Message          string `json:"message"`
+}
+
+// ProductResponse is a product in API responses
+type ProductResponse struct {
+	ID        string `json:"id"`
+	TenantID  string `json:"tenant_id"`
+	SKU       string `json:"sku"`
+	Name      string `json:"name"`
+	Category  string `json:"category"`
+	Active    bool   `json:"active"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// Upload handles POST /v1/tenants/:tid/products/bulk
+// Accepts either a JSON batch or a multipart CSV file ("file") with the
+// columns sku, category, name, active. Products are upserted by SKU and the
+// whole batch is rejected if any row is invalid.
+func (h *ProductsHandler) Upload(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	var products []product.Params
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			httputil.BadRequest(c, "CSV file is required", nil)
+			return
+		}
+
+		fileHandle, err := file.Open()
+		if err != nil {
+			httputil.InternalError(c, "Failed to open file")
+			return
+		}
+		defer fileHandle.Close()
+
+		products, err = parseProductCSV(fileHandle)
+		if err != nil {
+			httputil.BadRequest(c, "Failed to parse CSV file", err.Error())
+			return
+		}
+	} else {
+		var req UploadProductsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httputil.BadRequest(c, "Invalid request body", err.Error())
+			return
+		}
+		products = make([]product.Params, len(req.Products))
+		for i, item := range req.Products {
+			active := true
+			if item.Active != nil {
+				active = *item.Active
+			}
+			products[i] = product.Params{
+				SKU:      item.SKU,
+				Name:     item.Name,
+				Category: item.Category,
+				Active:   active,
+			}
+		}
+	}
+
+	if len(products) == 0 {
+		httputil.BadRequest(c, "No products to upload", nil)
+		return
+	}
+	if len(products) > product.MaxUploadRows {
+		httputil.BadRequest(c, fmt.Sprintf("Batch exceeds %d rows", product.MaxUploadRows), nil)
+		return
+	}
+
+	if rowErrors := product.ValidateBatch(products); len(rowErrors) > 0 {
+		httputil.BadRequest(c, "Invalid products in batch", rowErrors)
+		return
+	}
+
+	count, err := h.service.BulkUpsert(c.Request.Context(), tenantUUID, products)
+	if err != nil {
+		httputil.InternalError(c, "Failed to upload products")
+		return
+	}
+
+	httputil.Respond(c, 200, ProductsUploadedResponse{
+		ProductsUploaded: count,
+		Message:          "Products uploaded successfully",
+	})
+}
+
+// List handles GET /v1/tenants/:tid/products
+// Supports an optional case-insensitive ?category= filter.
+func (h *ProductsHandler) List(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	limit := c.DefaultQuery("limit", "50")
+	offset := c.DefaultQuery("offset", "0")
+	category := c.Query("category")
+
+	products, total, err := h.service.List(c.Request.Context(), tenantUUID, category, limit, offset)
+	if err != nil {
+		httputil.InternalError(c, "Failed to list products")
+		return
+	}
+
+	productsList := make([]ProductResponse, len(products))
+	for i, p := range products {
+		productsList[i] = formatProduct(p)
+	}
+
+	httputil.RespondList(c, productsList, httputil.NewPage(int64(total), limit, offset))
+}
+
+// Get handles GET /v1/tenants/:tid/products/:sku
+func (h *ProductsHandler) Get(c *gin.Context) {
+	tenantID := c.Param("tid")
+	sku := c.Param("sku")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	p, err := h.service.GetBySKU(c.Request.Context(), tenantUUID, sku)
+	if err != nil {
+		if errors.Is(err, product.ErrProductNotFound) {
+			httputil.NotFound(c, "Product not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get product")
+		return
+	}
+
+	httputil.Respond(c, 200, formatProduct(p))
+}
+
+// parseProductCSV parses a product CSV. A header row is optional.
+func parseProductCSV(r io.Reader) ([]product.Params, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var products []product.Params
+	line := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line++
+
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "sku") {
+			continue
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: expected at least sku and category", line)
+		}
+
+		p := product.Params{
+			SKU:      strings.TrimSpace(record[0]),
+			Category: strings.TrimSpace(record[1]),
+			Active:   true,
+		}
+		if len(record) > 2 {
+			p.Name = strings.TrimSpace(record[2])
+		}
+		if len(record) > 3 && strings.TrimSpace(record[3]) != "" {
+			active, err := strconv.ParseBool(strings.TrimSpace(record[3]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid active value", line)
+			}
+			p.Active = active
+		}
+		products = append(products, p)
+	}
+
+	return products, nil
+}
+
+// formatProduct formats a product for the API response
+func formatProduct(p db.Product) ProductResponse {
+	return ProductResponse{
+		ID:        formatUUID(p.ID),
+		TenantID:  formatUUID(p.TenantID),
+		SKU:       p.Sku,
+		Name:      p.Name.String,
+		Category:  p.Category,
+		Active:    p.Active,
+		CreatedAt: formatTimestamp(p.CreatedAt),
+		UpdatedAt: formatTimestamp(p.UpdatedAt),
+	}
+}
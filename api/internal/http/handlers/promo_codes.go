@@ -0,0 +1,416 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/promo"
+	"github.com/bmachimbira/loyalty/api/internal/reward"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// PromoCodesHandler handles promo batch and promo code endpoints
+type PromoCodesHandler struct {
+	service *promo.Service
+}
+
+// NewPromoCodesHandler creates a new promo codes handler
+func NewPromoCodesHandler(service *promo.Service) *PromoCodesHandler {
+	return &PromoCodesHandler{
+		service: service,
+	}
+}
+
+// CreatePromoBatchRequest represents the request to create a promo batch
+type CreatePromoBatchRequest struct {
+	Name   string `json:"name" binding:"required"`
+	RuleID string `json:"rule_id" binding:"required"`
+	Kind   string `json:"kind" binding:"required"`
+	// Count and Prefix generate the codes of a unique batch
+	Count  int    `json:"count"`
+	Prefix string `json:"prefix"`
+	// Code and MaxUses set up a shared batch
+	Code             string     `json:"code"`
+	MaxUses          *int32     `json:"max_uses"`
+	PerCustomerLimit *int32     `json:"per_customer_limit"`
+	StartsAt         *time.Time `json:"starts_at"`
+	EndsAt           *time.Time `json:"ends_at"`
+}
+
+// UpdatePromoBatchRequest represents the request to activate or deactivate
+// a promo batch
+type UpdatePromoBatchRequest struct {
+	Active *bool `json:"active" binding:"required"`
+}
+
+// RedeemPromoCodeRequest represents a promo code entered by a customer
+type RedeemPromoCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// PromoBatchResponse is a promo batch in API responses. MaxUses is only set
+// for batches with a limit.
+type PromoBatchResponse struct {
+	ID               string `json:"id"`
+	TenantID         string `json:"tenant_id"`
+	Name             string `json:"name"`
+	RuleID           string `json:"rule_id"`
+	Kind             string `json:"kind"`
+	PerCustomerLimit int32  `json:"per_customer_limit"`
+	StartsAt         string `json:"starts_at"`
+	EndsAt           string `json:"ends_at"`
+	Active           bool   `json:"active"`
+	CreatedAt        string `json:"created_at"`
+	UpdatedAt        string `json:"updated_at"`
+	MaxUses          *int32 `json:"max_uses,omitempty"`
+}
+
+// PromoBatchSummaryResponse is a listed promo batch with how many codes it
+// has and how often they were redeemed
+type PromoBatchSummaryResponse struct {
+	PromoBatchResponse
+	CodeCount       int32 `json:"code_count"`
+	RedemptionCount int32 `json:"redemption_count"`
+}
+
+// PromoCodeResponse is a code in a promo batch
+type PromoCodeResponse struct {
+	ID        string `json:"id"`
+	Code      string `json:"code"`
+	Uses      int32  `json:"uses"`
+	CreatedAt string `json:"created_at"`
+}
+
+// PromoRedemptionResponse is a redeemed promo code and the issuances it
+// triggered
+type PromoRedemptionResponse struct {
+	RedemptionID string   `json:"redemption_id"`
+	PromoBatchID string   `json:"promo_batch_id"`
+	EventID      string   `json:"event_id"`
+	IssuanceIDs  []string `json:"issuance_ids"`
+}
+
+// CreateBatch handles POST /v1/tenants/:tid/promo-batches
+func (h *PromoCodesHandler) CreateBatch(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var req CreatePromoBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := httputil.ValidateUUID(req.RuleID); err != nil {
+		httputil.BadRequest(c, "Invalid rule ID", nil)
+		return
+	}
+
+	var tenantUUID, ruleUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+	if err := ruleUUID.Scan(req.RuleID); err != nil {
+		httputil.BadRequest(c, "Invalid rule ID format", nil)
+		return
+	}
+
+	params := promo.BatchParams{
+		TenantID:         tenantUUID,
+		Name:             req.Name,
+		RuleID:           ruleUUID,
+		Kind:             req.Kind,
+		Count:            req.Count,
+		Prefix:           req.Prefix,
+		Code:             req.Code,
+		MaxUses:          req.MaxUses,
+		PerCustomerLimit: 1,
+		StartsAt:         req.StartsAt,
+		EndsAt:           req.EndsAt,
+	}
+	if req.PerCustomerLimit != nil {
+		params.PerCustomerLimit = *req.PerCustomerLimit
+	}
+	if err := params.Validate(); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	batch, err := h.service.CreateBatch(c.Request.Context(), params)
+	if err != nil {
+		switch {
+		case errors.Is(err, promo.ErrRuleNotFound):
+			httputil.NotFound(c, "Rule not found")
+		case errors.Is(err, promo.ErrRuleNotPromo):
+			httputil.BadRequest(c, "Rule must have event_type promo_code", nil)
+		case errors.Is(err, promo.ErrCodeTaken):
+			httputil.Conflict(c, "Promo code already exists", nil)
+		default:
+			httputil.InternalError(c, "Failed to create promo batch")
+		}
+		return
+	}
+
+	httputil.Respond(c, 201, formatPromoBatch(batch))
+}
+
+// ListBatches handles GET /v1/tenants/:tid/promo-batches
+func (h *PromoCodesHandler) ListBatches(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	batches, err := h.service.ListBatches(c.Request.Context(), tenantUUID)
+	if err != nil {
+		httputil.InternalError(c, "Failed to list promo batches")
+		return
+	}
+
+	batchesList := make([]PromoBatchSummaryResponse, len(batches))
+	for i, b := range batches {
+		batchesList[i].PromoBatchResponse = formatPromoBatch(db.PromoBatch{
+			ID:               b.ID,
+			TenantID:         b.TenantID,
+			Name:             b.Name,
+			RuleID:           b.RuleID,
+			Kind:             b.Kind,
+			MaxUses:          b.MaxUses,
+			PerCustomerLimit: b.PerCustomerLimit,
+			StartsAt:         b.StartsAt,
+			EndsAt:           b.EndsAt,
+			Active:           b.Active,
+			CreatedAt:        b.CreatedAt,
+			UpdatedAt:        b.UpdatedAt,
+		})
+		batchesList[i].CodeCount = b.CodeCount
+		batchesList[i].RedemptionCount = b.RedemptionCount
+	}
+
+	httputil.RespondList(c, batchesList, httputil.Page{Total: int64(len(batchesList))})
+}
+
+// GetBatch handles GET /v1/tenants/:tid/promo-batches/:id
+func (h *PromoCodesHandler) GetBatch(c *gin.Context) {
+	tenantUUID, batchUUID, ok := parsePromoBatchParams(c)
+	if !ok {
+		return
+	}
+
+	batch, err := h.service.GetBatch(c.Request.Context(), tenantUUID, batchUUID)
+	if err != nil {
+		if errors.Is(err, promo.ErrBatchNotFound) {
+			httputil.NotFound(c, "Promo batch not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get promo batch")
+		return
+	}
+
+	httputil.Respond(c, 200, formatPromoBatch(batch))
+}
+
+// UpdateBatch handles PATCH /v1/tenants/:tid/promo-batches/:id
+// A deactivated batch's codes are rejected until it is activated again.
+func (h *PromoCodesHandler) UpdateBatch(c *gin.Context) {
+	tenantUUID, batchUUID, ok := parsePromoBatchParams(c)
+	if !ok {
+		return
+	}
+
+	var req UpdatePromoBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	batch, err := h.service.SetActive(c.Request.Context(), tenantUUID, batchUUID, *req.Active)
+	if err != nil {
+		if errors.Is(err, promo.ErrBatchNotFound) {
+			httputil.NotFound(c, "Promo batch not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to update promo batch")
+		return
+	}
+
+	httputil.Respond(c, 200, formatPromoBatch(batch))
+}
+
+// ListCodes handles GET /v1/tenants/:tid/promo-batches/:id/codes
+// Pages through a batch's codes, e.g. to print them, up to 1000 at a time.
+func (h *PromoCodesHandler) ListCodes(c *gin.Context) {
+	tenantUUID, batchUUID, ok := parsePromoBatchParams(c)
+	if !ok {
+		return
+	}
+
+	limitStr := c.DefaultQuery("limit", "100")
+	offsetStr := c.DefaultQuery("offset", "0")
+
+	promoCodes, total, err := h.service.ListCodes(c.Request.Context(), tenantUUID, batchUUID, limitStr, offsetStr)
+	if err != nil {
+		if errors.Is(err, promo.ErrBatchNotFound) {
+			httputil.NotFound(c, "Promo batch not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to list promo codes")
+		return
+	}
+
+	codesList := make([]PromoCodeResponse, len(promoCodes))
+	for i, pc := range promoCodes {
+		codesList[i] = PromoCodeResponse{
+			ID:        formatUUID(pc.ID),
+			Code:      pc.Code,
+			Uses:      pc.Uses,
+			CreatedAt: formatTimestamp(pc.CreatedAt),
+		}
+	}
+
+	httputil.RespondList(c, codesList, httputil.NewPage(total, limitStr, offsetStr))
+}
+
+// RedeemForCustomer handles POST /v1/tenants/:tid/customers/:id/promo-codes
+// Staff enter a code on a customer's behalf, e.g. at the till.
+func (h *PromoCodesHandler) RedeemForCustomer(c *gin.Context) {
+	tenantID := c.Param("tid")
+	customerID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+	if err := httputil.ValidateUUID(customerID); err != nil {
+		httputil.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+
+	var tenantUUID, customerUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+	if err := customerUUID.Scan(customerID); err != nil {
+		httputil.BadRequest(c, "Invalid customer ID format", nil)
+		return
+	}
+
+	h.redeem(c, tenantUUID, customerUUID, reward.ChannelAPI)
+}
+
+// PortalRedeem handles POST /v1/portal/me/promo-codes
+func (h *PromoCodesHandler) PortalRedeem(c *gin.Context) {
+	tenantUUID, customerUUID, ok := portalCustomer(c)
+	if !ok {
+		return
+	}
+
+	h.redeem(c, tenantUUID, customerUUID, reward.ChannelPortal)
+}
+
+// redeem redeems the code in the request body for a customer
+func (h *PromoCodesHandler) redeem(c *gin.Context, tenantUUID, customerUUID pgtype.UUID, channel string) {
+	var req RedeemPromoCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	result, err := h.service.Redeem(c.Request.Context(), tenantUUID, customerUUID, req.Code, channel, time.Now())
+	switch {
+	case errors.Is(err, promo.ErrInvalidCode):
+		httputil.NotFound(c, "Invalid promo code")
+		return
+	case errors.Is(err, promo.ErrCodeNotActive):
+		httputil.Conflict(c, "Promo code is not active", nil)
+		return
+	case errors.Is(err, promo.ErrCodeUsedUp):
+		httputil.Conflict(c, "Promo code has been used up", nil)
+		return
+	case errors.Is(err, promo.ErrAlreadyRedeemed):
+		httputil.Conflict(c, "Promo code already redeemed", nil)
+		return
+	case errors.Is(err, promo.ErrTooManyAttempts):
+		httputil.RateLimited(c, "Too many promo code attempts, try again later")
+		return
+	case err != nil:
+		httputil.InternalError(c, "Failed to redeem promo code")
+		return
+	}
+
+	issuances := make([]string, len(result.Issuances))
+	for i, issuance := range result.Issuances {
+		issuances[i] = formatUUID(issuance.ID)
+	}
+
+	httputil.Respond(c, 200, PromoRedemptionResponse{
+		RedemptionID: formatUUID(result.Redemption.ID),
+		PromoBatchID: formatUUID(result.Batch.ID),
+		EventID:      formatUUID(result.Event.ID),
+		IssuanceIDs:  issuances,
+	})
+}
+
+// parsePromoBatchParams validates and parses the tenant and batch IDs from
+// the path
+func parsePromoBatchParams(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, batchUUID pgtype.UUID
+
+	tenantID := c.Param("tid")
+	batchID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, batchUUID, false
+	}
+	if err := httputil.ValidateUUID(batchID); err != nil {
+		httputil.BadRequest(c, "Invalid promo batch ID", nil)
+		return tenantUUID, batchUUID, false
+	}
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, batchUUID, false
+	}
+	if err := batchUUID.Scan(batchID); err != nil {
+		httputil.BadRequest(c, "Invalid promo batch ID format", nil)
+		return tenantUUID, batchUUID, false
+	}
+
+	return tenantUUID, batchUUID, true
+}
+
+// formatPromoBatch formats a promo batch for the API response
+func formatPromoBatch(b db.PromoBatch) PromoBatchResponse {
+	response := PromoBatchResponse{
+		ID:               formatUUID(b.ID),
+		TenantID:         formatUUID(b.TenantID),
+		Name:             b.Name,
+		RuleID:           formatUUID(b.RuleID),
+		Kind:             b.Kind,
+		PerCustomerLimit: b.PerCustomerLimit,
+		StartsAt:         formatTimestamp(b.StartsAt),
+		EndsAt:           formatTimestamp(b.EndsAt),
+		Active:           b.Active,
+		CreatedAt:        formatTimestamp(b.CreatedAt),
+		UpdatedAt:        formatTimestamp(b.UpdatedAt),
+	}
+	if b.MaxUses.Valid {
+		response.MaxUses = &b.MaxUses.Int32
+	}
+	return response
+}
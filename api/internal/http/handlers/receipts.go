@@ -0,0 +1,437 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/deadletter"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/logging"
+	"github.com/bmachimbira/loyalty/api/internal/receipt"
+	"github.com/bmachimbira/loyalty/api/internal/rules"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReceiptsHandler handles receipt ingestion and review endpoints
+type ReceiptsHandler struct {
+	pool        *pgxpool.Pool
+	service     *receipt.Service
+	rulesEngine *rules.Engine
+	deadLetters *deadletter.Service
+	logger      *logging.Logger
+}
+
+// NewReceiptsHandler creates a new receipts handler
+func NewReceiptsHandler(pool *pgxpool.Pool, service *receipt.Service, rulesEngine *rules.Engine, logger *logging.Logger) *ReceiptsHandler {
+	return &ReceiptsHandler{
+		pool:        pool,
+		service:     service,
+		rulesEngine: rulesEngine,
+		deadLetters: deadletter.NewService(db.New(pool), rulesEngine),
+		logger:      logger,
+	}
+}
+
+// ApproveReceiptRequest represents the reviewed values for a receipt.
+// Omitted fields keep the values extracted by OCR.
+type ApproveReceiptRequest struct {
+	Amount      string `json:"amount"`
+	Currency    string `json:"currency"`
+	ReceiptDate string `json:"receipt_date"` // YYYY-MM-DD
+	LocationID  string `json:"location_id"`
+}
+
+// RejectReceiptRequest represents the request to reject a receipt
+type RejectReceiptRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ReceiptResponse is a receipt in API responses. Fields set by OCR or
+// review are only present once known.
+type ReceiptResponse struct {
+	ID              string `json:"id"`
+	TenantID        string `json:"tenant_id"`
+	CustomerID      string `json:"customer_id"`
+	Source          string `json:"source"`
+	Status          string `json:"status"`
+	MimeType        string `json:"mime_type"`
+	OcrProvider     string `json:"ocr_provider"`
+	OcrText         string `json:"ocr_text"`
+	Currency        string `json:"currency"`
+	StoreName       string `json:"store_name"`
+	CreatedAt       string `json:"created_at"`
+	OcrError        string `json:"ocr_error,omitempty"`
+	Amount          string `json:"amount,omitempty"`
+	ReceiptDate     string `json:"receipt_date,omitempty"`
+	LocationID      string `json:"location_id,omitempty"`
+	EventID         string `json:"event_id,omitempty"`
+	ReviewedBy      string `json:"reviewed_by,omitempty"`
+	ReviewedAt      string `json:"reviewed_at,omitempty"`
+	RejectionReason string `json:"rejection_reason,omitempty"`
+}
+
+// ApprovedReceiptResponse is an approved receipt and the event recorded for
+// it, with the issuances it triggered
+type ApprovedReceiptResponse struct {
+	Receipt ReceiptResponse     `json:"receipt"`
+	Event   EventDetailResponse `json:"event"`
+}
+
+// Submit handles POST /v1/tenants/:tid/receipts
+// Accepts a multipart form with the receipt image ("file") and customer_id.
+func (h *ReceiptsHandler) Submit(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	customerID := c.PostForm("customer_id")
+	if err := httputil.ValidateUUID(customerID); err != nil {
+		httputil.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		httputil.BadRequest(c, "Receipt image is required", nil)
+		return
+	}
+	if file.Size > receipt.MaxImageBytes {
+		httputil.BadRequest(c, receipt.ErrImageTooLarge.Error(), nil)
+		return
+	}
+
+	var tenantUUID, customerUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+	if err := customerUUID.Scan(customerID); err != nil {
+		httputil.BadRequest(c, "Invalid customer ID format", nil)
+		return
+	}
+
+	fileHandle, err := file.Open()
+	if err != nil {
+		httputil.InternalError(c, "Failed to open file")
+		return
+	}
+	defer fileHandle.Close()
+
+	image, err := io.ReadAll(io.LimitReader(fileHandle, receipt.MaxImageBytes+1))
+	if err != nil {
+		httputil.InternalError(c, "Failed to read file")
+		return
+	}
+
+	created, err := h.service.Submit(c.Request.Context(), receipt.SubmitParams{
+		TenantID:   tenantUUID,
+		CustomerID: customerUUID,
+		Source:     receipt.SourceUpload,
+		MimeType:   http.DetectContentType(image),
+		Image:      image,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, receipt.ErrDuplicateReceipt):
+			httputil.Conflict(c, "Receipt already submitted", nil)
+		case errors.Is(err, receipt.ErrCustomerNotFound):
+			httputil.NotFound(c, "Customer not found")
+		case errors.Is(err, receipt.ErrUnsupportedImage), errors.Is(err, receipt.ErrImageTooLarge):
+			httputil.BadRequest(c, err.Error(), nil)
+		default:
+			h.logger.Error("failed to submit receipt", "error", err)
+			httputil.InternalError(c, "Failed to submit receipt")
+		}
+		return
+	}
+
+	httputil.Respond(c, 201, formatReceipt(created))
+}
+
+// List handles GET /v1/tenants/:tid/receipts
+// Supports an optional ?status= filter (pending, approved, rejected).
+func (h *ReceiptsHandler) List(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	limit := c.DefaultQuery("limit", "50")
+	offset := c.DefaultQuery("offset", "0")
+	status := c.Query("status")
+
+	receipts, total, err := h.service.List(c.Request.Context(), tenantUUID, status, limit, offset)
+	if err != nil {
+		if errors.Is(err, receipt.ErrInvalidStatus) {
+			httputil.BadRequest(c, "Invalid status filter", nil)
+			return
+		}
+		httputil.InternalError(c, "Failed to list receipts")
+		return
+	}
+
+	receiptsList := make([]ReceiptResponse, len(receipts))
+	for i, r := range receipts {
+		receiptsList[i] = formatReceipt(r)
+	}
+
+	httputil.RespondList(c, receiptsList, httputil.NewPage(int64(total), limit, offset))
+}
+
+// Get handles GET /v1/tenants/:tid/receipts/:id
+func (h *ReceiptsHandler) Get(c *gin.Context) {
+	tenantUUID, receiptUUID, ok := parseReceiptParams(c)
+	if !ok {
+		return
+	}
+
+	r, err := h.service.Get(c.Request.Context(), tenantUUID, receiptUUID)
+	if err != nil {
+		if errors.Is(err, receipt.ErrReceiptNotFound) {
+			httputil.NotFound(c, "Receipt not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get receipt")
+		return
+	}
+
+	httputil.Respond(c, 200, formatReceipt(r))
+}
+
+// Image handles GET /v1/tenants/:tid/receipts/:id/image
+func (h *ReceiptsHandler) Image(c *gin.Context) {
+	tenantUUID, receiptUUID, ok := parseReceiptParams(c)
+	if !ok {
+		return
+	}
+
+	image, mimeType, err := h.service.Image(c.Request.Context(), tenantUUID, receiptUUID)
+	if err != nil {
+		if errors.Is(err, receipt.ErrReceiptNotFound) {
+			httputil.NotFound(c, "Receipt not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get receipt image")
+		return
+	}
+
+	c.Data(200, mimeType, image)
+}
+
+// Approve handles POST /v1/tenants/:tid/receipts/:id/approve
+// Records a purchase event for the receipt and runs it through the rules engine.
+func (h *ReceiptsHandler) Approve(c *gin.Context) {
+	tenantUUID, receiptUUID, ok := parseReceiptParams(c)
+	if !ok {
+		return
+	}
+
+	var req ApproveReceiptRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	reviewerUUID, ok := reviewerID(c)
+	if !ok {
+		return
+	}
+
+	params := receipt.ApproveParams{
+		TenantID:   tenantUUID,
+		ReceiptID:  receiptUUID,
+		ReviewerID: reviewerUUID,
+		Amount:     req.Amount,
+		Currency:   req.Currency,
+	}
+	if req.ReceiptDate != "" {
+		date, err := time.Parse("2006-01-02", req.ReceiptDate)
+		if err != nil {
+			httputil.BadRequest(c, "receipt_date must be in YYYY-MM-DD format", nil)
+			return
+		}
+		params.Date = &date
+	}
+	if req.LocationID != "" {
+		if err := httputil.ValidateUUID(req.LocationID); err != nil {
+			httputil.BadRequest(c, "Invalid location ID", nil)
+			return
+		}
+		if err := params.LocationID.Scan(req.LocationID); err != nil {
+			httputil.BadRequest(c, "Invalid location ID format", nil)
+			return
+		}
+	}
+
+	approved, event, err := h.service.Approve(c.Request.Context(), params)
+	if err != nil {
+		switch {
+		case errors.Is(err, receipt.ErrReceiptNotFound):
+			httputil.NotFound(c, "Receipt not found")
+		case errors.Is(err, receipt.ErrNotPending):
+			httputil.Conflict(c, "Receipt is not pending review", nil)
+		case errors.Is(err, receipt.ErrInvalidAmount), errors.Is(err, receipt.ErrInvalidCurrency), errors.Is(err, receipt.ErrInvalidLocation):
+			httputil.BadRequest(c, err.Error(), nil)
+		default:
+			h.logger.Error("failed to approve receipt", "receipt_id", formatUUID(receiptUUID), "error", err)
+			httputil.InternalError(c, "Failed to approve receipt")
+		}
+		return
+	}
+
+	issuances, err := h.rulesEngine.ProcessEvent(c.Request.Context(), event)
+	if err != nil {
+		// The receipt is approved; park the event so it can be retried
+		h.logger.Error("rules engine processing failed",
+			"event_id", event.ID,
+			"receipt_id", formatUUID(receiptUUID),
+			"error", err,
+		)
+		if _, dlqErr := h.deadLetters.Record(c.Request.Context(), event, err); dlqErr != nil {
+			h.logger.Error("failed to record dead letter",
+				"event_id", event.ID,
+				"error", dlqErr,
+			)
+		}
+		issuances = nil
+	}
+
+	httputil.Respond(c, 200, ApprovedReceiptResponse{
+		Receipt: formatReceipt(approved),
+		Event:   formatEventResponse(event, issuances),
+	})
+}
+
+// Reject handles POST /v1/tenants/:tid/receipts/:id/reject
+func (h *ReceiptsHandler) Reject(c *gin.Context) {
+	tenantUUID, receiptUUID, ok := parseReceiptParams(c)
+	if !ok {
+		return
+	}
+
+	var req RejectReceiptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	reviewerUUID, ok := reviewerID(c)
+	if !ok {
+		return
+	}
+
+	rejected, err := h.service.Reject(c.Request.Context(), tenantUUID, receiptUUID, reviewerUUID, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, receipt.ErrReceiptNotFound):
+			httputil.NotFound(c, "Receipt not found")
+		case errors.Is(err, receipt.ErrNotPending):
+			httputil.Conflict(c, "Receipt is not pending review", nil)
+		default:
+			httputil.InternalError(c, "Failed to reject receipt")
+		}
+		return
+	}
+
+	httputil.Respond(c, 200, formatReceipt(rejected))
+}
+
+// parseReceiptParams validates and parses the tenant and receipt IDs from the path
+func parseReceiptParams(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, receiptUUID pgtype.UUID
+
+	tenantID := c.Param("tid")
+	receiptID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, receiptUUID, false
+	}
+	if err := httputil.ValidateUUID(receiptID); err != nil {
+		httputil.BadRequest(c, "Invalid receipt ID", nil)
+		return tenantUUID, receiptUUID, false
+	}
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, receiptUUID, false
+	}
+	if err := receiptUUID.Scan(receiptID); err != nil {
+		httputil.BadRequest(c, "Invalid receipt ID format", nil)
+		return tenantUUID, receiptUUID, false
+	}
+
+	return tenantUUID, receiptUUID, true
+}
+
+// reviewerID returns the authenticated staff user performing a review
+func reviewerID(c *gin.Context) (pgtype.UUID, bool) {
+	var userUUID pgtype.UUID
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		httputil.Unauthorized(c, "User not authenticated")
+		return userUUID, false
+	}
+	if err := userUUID.Scan(userID.(string)); err != nil {
+		httputil.InternalError(c, "Invalid user ID")
+		return userUUID, false
+	}
+
+	return userUUID, true
+}
+
+// formatReceipt formats a receipt for the API response
+func formatReceipt(r db.Receipt) ReceiptResponse {
+	response := ReceiptResponse{
+		ID:          formatUUID(r.ID),
+		TenantID:    formatUUID(r.TenantID),
+		CustomerID:  formatUUID(r.CustomerID),
+		Source:      r.Source,
+		Status:      r.Status,
+		MimeType:    r.MimeType,
+		OcrProvider: r.OcrProvider.String,
+		OcrText:     r.OcrText.String,
+		Currency:    r.Currency.String,
+		StoreName:   r.StoreName.String,
+		CreatedAt:   formatTimestamp(r.CreatedAt),
+	}
+
+	if r.OcrError.Valid {
+		response.OcrError = r.OcrError.String
+	}
+	if r.Amount.Valid {
+		response.Amount = formatNumeric(r.Amount)
+	}
+	if r.ReceiptDate.Valid {
+		response.ReceiptDate = r.ReceiptDate.Time.Format("2006-01-02")
+	}
+	if r.LocationID.Valid {
+		response.LocationID = formatUUID(r.LocationID)
+	}
+	if r.EventID.Valid {
+		response.EventID = formatUUID(r.EventID)
+	}
+	if r.ReviewedBy.Valid {
+		response.ReviewedBy = formatUUID(r.ReviewedBy)
+		response.ReviewedAt = formatTimestamp(r.ReviewedAt)
+	}
+	if r.RejectionReason.Valid {
+		response.RejectionReason = r.RejectionReason.String
+	}
+
+	return response
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/reward"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RedemptionsHandler handles bulk redemption endpoints
+type RedemptionsHandler struct {
+	pool          *pgxpool.Pool
+	rewardService *reward.Service
+	logger        *slog.Logger
+}
+
+// NewRedemptionsHandler creates a new redemptions handler
+func NewRedemptionsHandler(pool *pgxpool.Pool, logger *slog.Logger) *RedemptionsHandler {
+	return &RedemptionsHandler{
+		pool:          pool,
+		rewardService: reward.NewService(pool, db.New(pool)),
+		logger:        logger,
+	}
+}
+
+// RedemptionImportItem is a single redemption in a JSON import batch
+type RedemptionImportItem struct {
+	Code       string    `json:"code"`
+	RedeemedAt time.Time `json:"redeemed_at"`
+	StoreID    string    `json:"store_id"`
+	StaffRef   string    `json:"staff_ref"`
+}
+
+// ImportRedemptionsRequest represents a JSON redemption import batch
+type ImportRedemptionsRequest struct {
+	Redemptions []RedemptionImportItem `json:"redemptions" binding:"required"`
+}
+
+// RedemptionImportResponse is the result of a redemption import, with how
+// many rows ended in each status
+type RedemptionImportResponse struct {
+	Total   int                              `json:"total"`
+	Summary map[string]int                   `json:"summary"`
+	Results []RedemptionImportResultResponse `json:"results"`
+}
+
+// RedemptionImportResultResponse is the result of one imported row.
+// IssuanceID is set when the code matched an issuance, Error when the row
+// failed.
+type RedemptionImportResultResponse struct {
+	Row        int    `json:"row"`
+	Code       string `json:"code"`
+	Status     string `json:"status"`
+	IssuanceID string `json:"issuance_id,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Import handles POST /v1/tenants/:tid/redemptions/import
+// Accepts either a JSON batch or a multipart CSV file ("file") with the
+// columns code, redeemed_at (RFC3339), store_id, staff_ref.
+func (h *RedemptionsHandler) Import(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	var rows []reward.RedemptionImportRow
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			httputil.BadRequest(c, "CSV file is required", nil)
+			return
+		}
+
+		fileHandle, err := file.Open()
+		if err != nil {
+			httputil.InternalError(c, "Failed to open file")
+			return
+		}
+		defer fileHandle.Close()
+
+		rows, err = parseRedemptionCSV(fileHandle)
+		if err != nil {
+			httputil.BadRequest(c, "Failed to parse CSV file", err.Error())
+			return
+		}
+	} else {
+		var req ImportRedemptionsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httputil.BadRequest(c, "Invalid request body", err.Error())
+			return
+		}
+		rows = make([]reward.RedemptionImportRow, len(req.Redemptions))
+		for i, item := range req.Redemptions {
+			rows[i] = reward.RedemptionImportRow{
+				Code:       item.Code,
+				RedeemedAt: item.RedeemedAt,
+				StoreID:    item.StoreID,
+				StaffRef:   item.StaffRef,
+			}
+		}
+	}
+
+	if len(rows) == 0 {
+		httputil.BadRequest(c, "No redemptions to import", nil)
+		return
+	}
+	if len(rows) > reward.MaxImportRows {
+		httputil.BadRequest(c, fmt.Sprintf("Batch exceeds %d rows", reward.MaxImportRows), nil)
+		return
+	}
+
+	results := h.rewardService.ImportRedemptions(c.Request.Context(), tenantUUID, rows, staffOrigin(c, reward.ChannelPOSImport))
+
+	summary := map[string]int{
+		reward.ImportStatusRedeemed:        0,
+		reward.ImportStatusAlreadyRedeemed: 0,
+		reward.ImportStatusFailed:          0,
+	}
+	resultsList := make([]RedemptionImportResultResponse, len(results))
+	for i, result := range results {
+		summary[result.Status]++
+		resultsList[i] = RedemptionImportResultResponse{
+			Row:        result.Row,
+			Code:       result.Code,
+			Status:     result.Status,
+			IssuanceID: formatUUID(result.IssuanceID),
+			Error:      result.Error,
+		}
+	}
+
+	h.logger.Info("redemption import processed",
+		"tenant_id", tenantID,
+		"rows", len(rows),
+		"redeemed", summary[reward.ImportStatusRedeemed],
+		"failed", summary[reward.ImportStatusFailed],
+	)
+
+	httputil.Respond(c, 200, RedemptionImportResponse{
+		Total:   len(results),
+		Summary: summary,
+		Results: resultsList,
+	})
+}
+
+// parseRedemptionCSV parses a redemption CSV. A header row is optional.
+func parseRedemptionCSV(r io.Reader) ([]reward.RedemptionImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var rows []reward.RedemptionImportRow
+	line := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line++
+
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "code") {
+			continue
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: expected at least code and redeemed_at", line)
+		}
+
+		redeemedAt, err := time.Parse(time.RFC3339, strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid redeemed_at: %w", line, err)
+		}
+
+		row := reward.RedemptionImportRow{
+			Code:       strings.TrimSpace(record[0]),
+			RedeemedAt: redeemedAt,
+		}
+		if len(record) > 2 {
+			row.StoreID = strings.TrimSpace(record[2])
+		}
+		if len(record) > 3 {
+			row.StaffRef = strings.TrimSpace(record[3])
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
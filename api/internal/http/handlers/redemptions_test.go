@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmachimbira/loyalty/api/internal/reward"
+)
+
+func TestParseRedemptionCSV(t *testing.T) {
+	redeemedAt := time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		csv     string
+		want    []reward.RedemptionImportRow
+		wantErr string
+	}{
+		{
+			name: "with header",
+			csv:  "code,redeemed_at,store_id,staff_ref\nABC123,2025-03-01T09:30:00Z,HRE01,till-4\n",
+			want: []reward.RedemptionImportRow{
+				{Code: "ABC123", RedeemedAt: redeemedAt, StoreID: "HRE01", StaffRef: "till-4"},
+			},
+		},
+		{
+			name: "without header, blank lines and optional columns",
+			csv:  "ABC123, 2025-03-01T09:30:00Z\n\nDEF456,2025-03-01T09:30:00Z,BYO02\n",
+			want: []reward.RedemptionImportRow{
+				{Code: "ABC123", RedeemedAt: redeemedAt},
+				{Code: "DEF456", RedeemedAt: redeemedAt, StoreID: "BYO02"},
+			},
+		},
+		{
+			name:    "missing redeemed_at",
+			csv:     "code,redeemed_at\nABC123\n",
+			wantErr: "line 2: expected at least code and redeemed_at",
+		},
+		{
+			name:    "bad time",
+			csv:     "ABC123,01/03/2025\n",
+			wantErr: "line 1: invalid redeemed_at",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := parseRedemptionCSV(strings.NewReader(tt.csv))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, rows)
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/logging"
+	"github.com/bmachimbira/loyalty/api/internal/retention"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// RetentionHandler handles the tenant's data retention policy and purges
+type RetentionHandler struct {
+	service *retention.Service
+	logger  *logging.Logger
+}
+
+// NewRetentionHandler creates a new retention handler
+func NewRetentionHandler(service *retention.Service, logger *logging.Logger) *RetentionHandler {
+	return &RetentionHandler{service: service, logger: logger}
+}
+
+// PurgeRequest represents the request to purge data past retention
+type PurgeRequest struct {
+	// DryRun defaults to true, so a purge must be asked for explicitly
+	DryRun *bool `json:"dry_run"`
+}
+
+// RetentionPolicyResponse is the tenant's retention policy. A retention is
+// null when that data is kept forever.
+type RetentionPolicyResponse struct {
+	EventRetentionMonths    *int `json:"event_retention_months"`
+	MessageRetentionMonths  *int `json:"message_retention_months"`
+	IssuanceRetentionMonths *int `json:"issuance_retention_months"`
+}
+
+// PurgeRunResponse is a purge run in API responses
+type PurgeRunResponse struct {
+	ID                  string `json:"id"`
+	DryRun              bool   `json:"dry_run"`
+	EventCutoff         string `json:"event_cutoff"`
+	MessageCutoff       string `json:"message_cutoff"`
+	IssuanceCutoff      string `json:"issuance_cutoff"`
+	EventsDeleted       int64  `json:"events_deleted"`
+	EventsAnonymized    int64  `json:"events_anonymized"`
+	MessagesDeleted     int64  `json:"messages_deleted"`
+	SessionsDeleted     int64  `json:"sessions_deleted"`
+	IssuancesAnonymized int64  `json:"issuances_anonymized"`
+	CreatedAt           string `json:"created_at"`
+}
+
+// GetPolicy handles GET /v1/tenants/:tid/retention
+// Retention is configured with `loyaltyctl set-retention`.
+func (h *RetentionHandler) GetPolicy(c *gin.Context) {
+	tenantUUID, ok := parseRetentionTenant(c)
+	if !ok {
+		return
+	}
+
+	policy, err := h.service.GetPolicy(c.Request.Context(), tenantUUID)
+	if err != nil {
+		if errors.Is(err, retention.ErrTenantNotFound) {
+			httputil.NotFound(c, "Tenant not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get retention policy")
+		return
+	}
+
+	httputil.Respond(c, 200, RetentionPolicyResponse{
+		EventRetentionMonths:    retentionMonths(policy.EventMonths),
+		MessageRetentionMonths:  retentionMonths(policy.MessageMonths),
+		IssuanceRetentionMonths: retentionMonths(policy.IssuanceMonths),
+	})
+}
+
+// Purge handles POST /v1/tenants/:tid/retention/purges
+// Without "dry_run": false it only reports what would be purged.
+func (h *RetentionHandler) Purge(c *gin.Context) {
+	tenantUUID, ok := parseRetentionTenant(c)
+	if !ok {
+		return
+	}
+
+	var req PurgeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httputil.BadRequest(c, "Invalid request body", err.Error())
+			return
+		}
+	}
+	dryRun := req.DryRun == nil || *req.DryRun
+
+	run, err := h.service.Purge(c.Request.Context(), tenantUUID, dryRun, time.Now())
+	if err != nil {
+		if errors.Is(err, retention.ErrTenantNotFound) {
+			httputil.NotFound(c, "Tenant not found")
+			return
+		}
+		h.logger.Error("failed to purge data past retention", "error", err)
+		httputil.InternalError(c, "Failed to purge data")
+		return
+	}
+
+	httputil.Respond(c, 201, formatPurgeRun(*run))
+}
+
+// ListPurges handles GET /v1/tenants/:tid/retention/purges
+func (h *RetentionHandler) ListPurges(c *gin.Context) {
+	tenantUUID, ok := parseRetentionTenant(c)
+	if !ok {
+		return
+	}
+
+	limit := c.DefaultQuery("limit", "50")
+	offset := c.DefaultQuery("offset", "0")
+
+	runs, total, err := h.service.ListRuns(c.Request.Context(), tenantUUID, limit, offset)
+	if err != nil {
+		httputil.InternalError(c, "Failed to list purges")
+		return
+	}
+
+	runsList := make([]PurgeRunResponse, len(runs))
+	for i, run := range runs {
+		runsList[i] = formatPurgeRun(run)
+	}
+
+	httputil.RespondList(c, runsList, httputil.NewPage(total, limit, offset))
+}
+
+// parseRetentionTenant parses the tenant ID, responding with an error if it
+// is invalid
+func parseRetentionTenant(c *gin.Context) (pgtype.UUID, bool) {
+	var tenantUUID pgtype.UUID
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, false
+	}
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, false
+	}
+	return tenantUUID, true
+}
+
+// retentionMonths formats a retention, which is null when data is kept
+// forever
+func retentionMonths(months int) *int {
+	if months <= 0 {
+		return nil
+	}
+	return &months
+}
+
+// formatPurgeRun formats a purge run for the API response
+func formatPurgeRun(run db.PurgeRun) PurgeRunResponse {
+	return PurgeRunResponse{
+		ID:                  formatUUID(run.ID),
+		DryRun:              run.DryRun,
+		EventCutoff:         formatTimestamp(run.EventCutoff),
+		MessageCutoff:       formatTimestamp(run.MessageCutoff),
+		IssuanceCutoff:      formatTimestamp(run.IssuanceCutoff),
+		EventsDeleted:       run.EventsDeleted,
+		EventsAnonymized:    run.EventsAnonymized,
+		MessagesDeleted:     run.MessagesDeleted,
+		SessionsDeleted:     run.SessionsDeleted,
+		IssuancesAnonymized: run.IssuancesAnonymized,
+		CreatedAt:           formatTimestamp(run.CreatedAt),
+	}
+}
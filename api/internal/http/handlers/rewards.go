@@ -1,15 +1,25 @@
 package handlers
 
 import (
-	"bufio"
-	"encoding/csv"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
-	"strings"
 
+	"github.com/bmachimbira/loyalty/api/internal/catalogcache"
+	"github.com/bmachimbira/loyalty/api/internal/currency"
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/http/middleware"
 	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/logging"
+	"github.com/bmachimbira/loyalty/api/internal/reward/basket"
+	"github.com/bmachimbira/loyalty/api/internal/reward/codes"
+	"github.com/bmachimbira/loyalty/api/internal/reward/locations"
+	"github.com/bmachimbira/loyalty/api/internal/reward/value"
+	"github.com/bmachimbira/loyalty/api/internal/reward/window"
 	"github.com/bmachimbira/loyalty/api/internal/rewardcatalog"
+	"github.com/bmachimbira/loyalty/api/internal/storage"
+	"github.com/bmachimbira/loyalty/api/internal/supplier"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -17,21 +27,31 @@ import (
 
 // RewardsHandler handles reward catalog API endpoints
 type RewardsHandler struct {
-	pool    *pgxpool.Pool
-	service *rewardcatalog.Service
-	queries *db.Queries
+	pool       *pgxpool.Pool
+	service    *rewardcatalog.Service
+	queries    *db.Queries
+	currencies *currency.Checker
+	suppliers  *supplier.Service
+	uploads    *storage.Service
 }
 
 // NewRewardsHandler creates a new rewards handler
-func NewRewardsHandler(pool *pgxpool.Pool) *RewardsHandler {
+func NewRewardsHandler(pool *pgxpool.Pool, catalog *catalogcache.Cache) *RewardsHandler {
 	queries := db.New(pool)
 	return &RewardsHandler{
-		pool:    pool,
-		service: rewardcatalog.NewService(queries),
-		queries: queries,
+		pool:       pool,
+		service:    rewardcatalog.NewService(queries, catalog),
+		queries:    queries,
+		currencies: currency.NewChecker(queries),
+		suppliers:  supplier.NewService(queries),
 	}
 }
 
+// SetUploadService keeps voucher code CSVs in upload storage
+func (h *RewardsHandler) SetUploadService(uploads *storage.Service) {
+	h.uploads = uploads
+}
+
 // CreateRewardRequest represents the request to create a reward
 type CreateRewardRequest struct {
 	Name       string                 `json:"name" binding:"required"`
@@ -52,6 +72,31 @@ type UpdateRewardRequest struct {
 	Active    *bool                   `json:"active"`
 }
 
+// RewardResponse is a catalog reward in API responses
+type RewardResponse struct {
+	ID         string                 `json:"id"`
+	TenantID   string                 `json:"tenant_id"`
+	Name       string                 `json:"name"`
+	Type       string                 `json:"type"`
+	FaceValue  string                 `json:"face_value"`
+	Currency   string                 `json:"currency"`
+	Inventory  string                 `json:"inventory"`
+	SupplierID string                 `json:"supplier_id"`
+	Metadata   map[string]interface{} `json:"metadata"`
+	Active     bool                   `json:"active"`
+	ArchivedAt string                 `json:"archived_at"`
+}
+
+// VoucherCodesUploadedResponse is the result of a voucher code upload.
+// UploadID is only set when the file was kept.
+type VoucherCodesUploadedResponse struct {
+	RewardID      string `json:"reward_id"`
+	CodesUploaded int    `json:"codes_uploaded"`
+	FileName      string `json:"file_name"`
+	TotalCodes    int    `json:"total_codes"`
+	UploadID      string `json:"upload_id,omitempty"`
+}
+
 // Create handles POST /v1/tenants/:tid/reward-catalog
 func (h *RewardsHandler) Create(c *gin.Context) {
 	tenantID := c.Param("tid")
@@ -78,14 +123,6 @@ func (h *RewardsHandler) Create(c *gin.Context) {
 		return
 	}
 
-	// Validate currency if face value is provided
-	if req.FaceValue != nil && req.Currency != "" {
-		if err := httputil.ValidateCurrency(req.Currency); err != nil {
-			httputil.BadRequest(c, err.Error(), nil)
-			return
-		}
-	}
-
 	// Validate supplier ID if provided
 	if req.SupplierID != nil {
 		if err := httputil.ValidateUUID(*req.SupplierID); err != nil {
@@ -101,6 +138,11 @@ func (h *RewardsHandler) Create(c *gin.Context) {
 		return
 	}
 
+	// Validate currency if provided
+	if req.Currency != "" && !checkCurrency(c, h.currencies, tenantUUID, req.Currency) {
+		return
+	}
+
 	// Prepare parameters
 	var faceValue pgtype.Numeric
 	if req.FaceValue != nil {
@@ -121,6 +163,15 @@ func (h *RewardsHandler) Create(c *gin.Context) {
 			httputil.BadRequest(c, "Invalid supplier ID format", nil)
 			return
 		}
+		if err := h.suppliers.ValidateSupplier(c.Request.Context(), tenantUUID, supplierID); err != nil {
+			switch {
+			case errors.Is(err, supplier.ErrSupplierNotFound), errors.Is(err, supplier.ErrSupplierInactive):
+				httputil.BadRequest(c, err.Error(), nil)
+			default:
+				httputil.InternalError(c, "Failed to validate supplier")
+			}
+			return
+		}
 	}
 
 	// Serialize metadata
@@ -136,6 +187,36 @@ func (h *RewardsHandler) Create(c *gin.Context) {
 		metadataJSON = []byte("{}")
 	}
 
+	// Validate issuance code settings
+	if _, err := codes.FromMetadata(metadataJSON, codes.DefaultAlphanumeric); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	// Validate the value formula of rewards valued from their event
+	if _, err := value.FromMetadata(metadataJSON); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	// Validate the purchase a reward must be redeemed with
+	if _, err := basket.FromMetadata(metadataJSON); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	// Validate the days and hours a reward may be redeemed
+	if _, err := window.FromMetadata(metadataJSON); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	// Validate the locations a restricted reward may be redeemed at
+	if _, err := locations.FromMetadata(metadataJSON); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
 	// Create reward using service
 	reward, err := h.service.CreateReward(c.Request.Context(), db.CreateRewardParams{
 		TenantID:   tenantUUID,
@@ -153,24 +234,7 @@ func (h *RewardsHandler) Create(c *gin.Context) {
 		return
 	}
 
-	// Parse metadata back
-	var metadata map[string]interface{}
-	if len(reward.Metadata) > 0 {
-		json.Unmarshal(reward.Metadata, &metadata)
-	}
-
-	c.JSON(201, gin.H{
-		"id":          formatUUID(reward.ID),
-		"tenant_id":   formatUUID(reward.TenantID),
-		"name":        reward.Name,
-		"type":        reward.Type,
-		"face_value":  reward.FaceValue.Int.String(),
-		"currency":    reward.Currency.String,
-		"inventory":   reward.Inventory,
-		"supplier_id": formatUUID(reward.SupplierID),
-		"metadata":    metadata,
-		"active":      reward.Active,
-	})
+	httputil.Respond(c, 201, formatReward(reward))
 }
 
 // List handles GET /v1/tenants/:tid/reward-catalog
@@ -183,6 +247,7 @@ func (h *RewardsHandler) List(c *gin.Context) {
 
 	// Get filter params
 	activeOnly := c.DefaultQuery("active_only", "true")
+	includeArchived := c.DefaultQuery("include_archived", "false")
 
 	// Parse tenant UUID
 	var tenantUUID pgtype.UUID
@@ -192,40 +257,19 @@ func (h *RewardsHandler) List(c *gin.Context) {
 	}
 
 	// List rewards using service
-	rewards, err := h.service.ListRewards(c.Request.Context(), tenantUUID, activeOnly == "true")
+	rewards, err := h.service.ListRewards(c.Request.Context(), tenantUUID, activeOnly == "true", includeArchived == "true")
 	if err != nil {
 		httputil.InternalError(c, "Failed to list rewards")
 		return
 	}
 
 	// Format response
-	rewardsList := make([]gin.H, len(rewards))
+	rewardsList := make([]RewardResponse, len(rewards))
 	for i, reward := range rewards {
-		var metadata map[string]interface{}
-		if len(reward.Metadata) > 0 {
-			json.Unmarshal(reward.Metadata, &metadata)
-		}
-
-		rewardsList[i] = gin.H{
-			"id":          formatUUID(reward.ID),
-			"tenant_id":   formatUUID(reward.TenantID),
-			"name":        reward.Name,
-			"type":        reward.Type,
-			"face_value":  reward.FaceValue.Int.String(),
-			"currency":    reward.Currency.String,
-			"inventory":   reward.Inventory,
-			"supplier_id": formatUUID(reward.SupplierID),
-			"metadata":    metadata,
-			"active":      reward.Active,
-		}
+		rewardsList[i] = formatReward(reward)
 	}
 
-	c.JSON(200, gin.H{
-		"data":  rewardsList,
-		"total": len(rewards),
-		"limit": 0,
-		"page":  0,
-	})
+	httputil.RespondList(c, rewardsList, httputil.Page{Total: int64(len(rewards))})
 }
 
 // Get handles GET /v1/tenants/:tid/reward-catalog/:id
@@ -261,24 +305,7 @@ func (h *RewardsHandler) Get(c *gin.Context) {
 		return
 	}
 
-	// Parse metadata
-	var metadata map[string]interface{}
-	if len(reward.Metadata) > 0 {
-		json.Unmarshal(reward.Metadata, &metadata)
-	}
-
-	c.JSON(200, gin.H{
-		"id":          formatUUID(reward.ID),
-		"tenant_id":   formatUUID(reward.TenantID),
-		"name":        reward.Name,
-		"type":        reward.Type,
-		"face_value":  reward.FaceValue.Int.String(),
-		"currency":    reward.Currency.String,
-		"inventory":   reward.Inventory,
-		"supplier_id": formatUUID(reward.SupplierID),
-		"metadata":    metadata,
-		"active":      reward.Active,
-	})
+	httputil.Respond(c, 200, formatReward(reward))
 }
 
 // Update handles PATCH /v1/tenants/:tid/reward-catalog/:id
@@ -317,7 +344,14 @@ func (h *RewardsHandler) Update(c *gin.Context) {
 	if req.Active != nil {
 		err := h.service.UpdateRewardStatus(c.Request.Context(), rewardUUID, tenantUUID, *req.Active)
 		if err != nil {
-			httputil.InternalError(c, "Failed to update reward")
+			switch {
+			case errors.Is(err, rewardcatalog.ErrRewardNotFound):
+				httputil.NotFound(c, "Reward not found")
+			case errors.Is(err, rewardcatalog.ErrRewardArchived):
+				httputil.Conflict(c, "Restore the reward before activating it", nil)
+			default:
+				httputil.InternalError(c, "Failed to update reward")
+			}
 			return
 		}
 	}
@@ -329,56 +363,67 @@ func (h *RewardsHandler) Update(c *gin.Context) {
 		return
 	}
 
-	// Parse metadata
-	var metadata map[string]interface{}
-	if len(reward.Metadata) > 0 {
-		json.Unmarshal(reward.Metadata, &metadata)
-	}
-
-	c.JSON(200, gin.H{
-		"id":         formatUUID(reward.ID),
-		"tenant_id":  formatUUID(reward.TenantID),
-		"name":       reward.Name,
-		"type":       reward.Type,
-		"face_value": reward.FaceValue.Int.String(),
-		"currency":   reward.Currency.String,
-		"inventory":  reward.Inventory,
-		"metadata":   metadata,
-		"active":     reward.Active,
-	})
+	httputil.Respond(c, 200, formatReward(reward))
 }
 
-// UploadCodes handles POST /v1/tenants/:tid/reward-catalog/:id/upload-codes
-// For voucher_code type rewards
-func (h *RewardsHandler) UploadCodes(c *gin.Context) {
-	tenantID := c.Param("tid")
-	rewardID := c.Param("id")
+// Delete handles DELETE /v1/tenants/:tid/reward-catalog/:id
+// Archives the reward, which also deactivates it. Restore brings it back.
+func (h *RewardsHandler) Delete(c *gin.Context) {
+	tenantUUID, rewardUUID, ok := parseRewardParams(c)
+	if !ok {
+		return
+	}
 
-	if err := httputil.ValidateUUID(tenantID); err != nil {
-		httputil.BadRequest(c, "Invalid tenant ID", nil)
+	reward, err := h.service.ArchiveReward(c.Request.Context(), rewardUUID, tenantUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, rewardcatalog.ErrRewardNotFound):
+			httputil.NotFound(c, "Reward not found")
+		case errors.Is(err, rewardcatalog.ErrRewardInUse):
+			httputil.Conflict(c, "Reward is issued by active rules of active campaigns", nil)
+		default:
+			httputil.InternalError(c, "Failed to archive reward")
+		}
 		return
 	}
 
-	if err := httputil.ValidateUUID(rewardID); err != nil {
-		httputil.BadRequest(c, "Invalid reward ID", nil)
+	httputil.Respond(c, 200, formatReward(reward))
+}
+
+// Restore handles POST /v1/tenants/:tid/reward-catalog/:id/restore
+// The restored reward stays inactive until it is activated.
+func (h *RewardsHandler) Restore(c *gin.Context) {
+	tenantUUID, rewardUUID, ok := parseRewardParams(c)
+	if !ok {
 		return
 	}
 
-	// Parse multipart form for CSV file
-	file, err := c.FormFile("file")
+	reward, err := h.service.RestoreReward(c.Request.Context(), rewardUUID, tenantUUID)
 	if err != nil {
-		httputil.BadRequest(c, "CSV file is required", nil)
+		if errors.Is(err, rewardcatalog.ErrRewardNotFound) {
+			httputil.NotFound(c, "Reward not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to restore reward")
 		return
 	}
 
-	// Parse UUIDs
-	var tenantUUID, rewardUUID pgtype.UUID
-	if err := tenantUUID.Scan(tenantID); err != nil {
-		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+	httputil.Respond(c, 200, formatReward(reward))
+}
+
+// UploadCodes handles POST /v1/tenants/:tid/reward-catalog/:id/upload-codes
+// For voucher_code type rewards. The CSV is kept in upload storage, when
+// configured, so it can be audited and imported again.
+func (h *RewardsHandler) UploadCodes(c *gin.Context) {
+	tenantUUID, rewardUUID, ok := parseRewardParams(c)
+	if !ok {
 		return
 	}
-	if err := rewardUUID.Scan(rewardID); err != nil {
-		httputil.BadRequest(c, "Invalid reward ID format", nil)
+
+	// Parse multipart form for CSV file
+	file, err := c.FormFile("file")
+	if err != nil {
+		httputil.BadRequest(c, "CSV file is required", nil)
 		return
 	}
 
@@ -394,7 +439,7 @@ func (h *RewardsHandler) UploadCodes(c *gin.Context) {
 		return
 	}
 
-	// Open the uploaded file
+	// Read the uploaded file
 	fileHandle, err := file.Open()
 	if err != nil {
 		httputil.InternalError(c, "Failed to open file")
@@ -402,53 +447,104 @@ func (h *RewardsHandler) UploadCodes(c *gin.Context) {
 	}
 	defer fileHandle.Close()
 
-	// Parse CSV file
-	reader := csv.NewReader(bufio.NewReader(fileHandle))
-	var codes []string
-	var codesUploaded int
+	body, err := io.ReadAll(fileHandle)
+	if err != nil {
+		httputil.InternalError(c, "Failed to read file")
+		return
+	}
 
-	// Read CSV line by line
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
+	// Keep the file before importing it so a failed import can be retried.
+	// Losing the copy isn't worth failing the upload over.
+	var uploadID string
+	if h.uploads != nil && h.uploads.Enabled() {
+		var staffUUID pgtype.UUID
+		staffUUID.Scan(c.GetString(middleware.UserIDKey))
+
+		upload, err := h.uploads.Save(c.Request.Context(), storage.SaveParams{
+			TenantID:    tenantUUID,
+			Kind:        storage.KindVoucherCodes,
+			Filename:    file.Filename,
+			ContentType: file.Header.Get("Content-Type"),
+			Body:        body,
+			EntityType:  "reward",
+			EntityID:    rewardUUID,
+			UploadedBy:  staffUUID,
+		})
 		if err != nil {
-			httputil.BadRequest(c, "Failed to parse CSV file", err.Error())
-			return
+			logging.FromContext(c.Request.Context(), nil).Error("failed to store voucher code upload", "reward_id", formatUUID(rewardUUID), "error", err)
+		} else {
+			uploadID = formatUUID(upload.ID)
 		}
+	}
 
-		// Skip empty lines
-		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
-			continue
+	result, err := h.service.ImportVoucherCodes(c.Request.Context(), tenantUUID, rewardUUID, bytes.NewReader(body))
+	if err != nil {
+		if errors.Is(err, rewardcatalog.ErrRewardNotFound) {
+			httputil.NotFound(c, "Reward not found")
+			return
 		}
-
-		// Get code from first column and trim whitespace
-		code := strings.TrimSpace(record[0])
-		if code != "" {
-			codes = append(codes, code)
+		if errors.Is(err, rewardcatalog.ErrNotVoucherReward) {
+			httputil.BadRequest(c, "Reward must be of type voucher_code", nil)
+			return
 		}
+		httputil.BadRequest(c, "Failed to parse CSV file", err.Error())
+		return
 	}
 
-	// Insert voucher codes
-	for _, code := range codes {
-		_, err := h.queries.InsertVoucherCode(c.Request.Context(), db.InsertVoucherCodeParams{
-			TenantID: tenantUUID,
-			RewardID: rewardUUID,
-			Code:     code,
-		})
-		if err != nil {
-			// Log error but continue with other codes
-			// In production, you might want to collect errors and return them
-			continue
-		}
-		codesUploaded++
+	httputil.Respond(c, 200, VoucherCodesUploadedResponse{
+		RewardID:      formatUUID(rewardUUID),
+		CodesUploaded: result.Imported,
+		FileName:      file.Filename,
+		TotalCodes:    result.Total,
+		UploadID:      uploadID,
+	})
+}
+
+// parseRewardParams validates and parses the tenant and reward IDs from the path
+func parseRewardParams(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, rewardUUID pgtype.UUID
+
+	tenantID := c.Param("tid")
+	rewardID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, rewardUUID, false
+	}
+	if err := httputil.ValidateUUID(rewardID); err != nil {
+		httputil.BadRequest(c, "Invalid reward ID", nil)
+		return tenantUUID, rewardUUID, false
+	}
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, rewardUUID, false
+	}
+	if err := rewardUUID.Scan(rewardID); err != nil {
+		httputil.BadRequest(c, "Invalid reward ID format", nil)
+		return tenantUUID, rewardUUID, false
 	}
 
-	c.JSON(200, gin.H{
-		"reward_id":      formatUUID(rewardUUID),
-		"codes_uploaded": codesUploaded,
-		"file_name":      file.Filename,
-		"total_codes":    len(codes),
-	})
+	return tenantUUID, rewardUUID, true
+}
+
+// formatReward formats a catalog reward for a response
+func formatReward(reward db.RewardCatalog) RewardResponse {
+	var metadata map[string]interface{}
+	if len(reward.Metadata) > 0 {
+		json.Unmarshal(reward.Metadata, &metadata)
+	}
+
+	return RewardResponse{
+		ID:         formatUUID(reward.ID),
+		TenantID:   formatUUID(reward.TenantID),
+		Name:       reward.Name,
+		Type:       reward.Type,
+		FaceValue:  formatNumeric(reward.FaceValue),
+		Currency:   reward.Currency.String,
+		Inventory:  reward.Inventory,
+		SupplierID: formatUUID(reward.SupplierID),
+		Metadata:   metadata,
+		Active:     reward.Active,
+		ArchivedAt: formatTimestamp(reward.ArchivedAt),
+	}
 }
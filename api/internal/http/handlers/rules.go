@@ -2,10 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
 
+	"github.com/bmachimbira/loyalty/api/internal/approval"
 	"github.com/bmachimbira/loyalty/api/internal/db"
 	"github.com/bmachimbira/loyalty/api/internal/httputil"
 	"github.com/bmachimbira/loyalty/api/internal/rule"
+	"github.com/bmachimbira/loyalty/api/internal/rules"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -13,32 +19,53 @@ import (
 
 // RulesHandler handles rule-related API endpoints
 type RulesHandler struct {
-	pool    *pgxpool.Pool
-	service *rule.Service
+	pool       *pgxpool.Pool
+	service    *rule.Service
+	backtester *rule.Backtester
+	bundler    *rule.Bundler
+	approvals  *approval.Service
 }
 
 // NewRulesHandler creates a new rules handler
 func NewRulesHandler(pool *pgxpool.Pool) *RulesHandler {
 	queries := db.New(pool)
 	return &RulesHandler{
-		pool:    pool,
-		service: rule.NewService(queries),
+		pool:       pool,
+		service:    rule.NewService(pool, queries),
+		backtester: rule.NewBacktester(pool, queries),
+		bundler:    rule.NewBundler(pool, queries),
 	}
 }
 
-// CreateRuleRequest represents the request to create a rule
+// SetApprovalService enables maker-checker controls for tenants that
+// require approval
+func (h *RulesHandler) SetApprovalService(approvals *approval.Service) {
+	h.approvals = approvals
+}
+
+// CreateRuleRequest represents the request to create a rule. Each trigger
+// issues Quantity of the reward and the AdditionalRewards; an omitted
+// quantity means 1.
 type CreateRuleRequest struct {
-	Name         string                 `json:"name" binding:"required"`
-	Description  string                 `json:"description"`
-	EventType    string                 `json:"event_type" binding:"required"`
-	Conditions   map[string]interface{} `json:"conditions" binding:"required"`
-	RewardID     string                 `json:"reward_id" binding:"required"`
-	CampaignID   *string                `json:"campaign_id"`
-	Priority     int                    `json:"priority"`
-	CapPerUser   int                    `json:"cap_per_user"`
-	CapGlobal    *int                   `json:"cap_global"`
-	CooldownSecs int                    `json:"cooldown_secs"`
-	Active       bool                   `json:"active"`
+	Name              string                 `json:"name" binding:"required"`
+	Description       string                 `json:"description"`
+	EventType         string                 `json:"event_type" binding:"required"`
+	Conditions        map[string]interface{} `json:"conditions" binding:"required"`
+	RewardID          string                 `json:"reward_id" binding:"required"`
+	Quantity          int                    `json:"quantity"`
+	AdditionalRewards []RuleRewardRequest    `json:"additional_rewards"`
+	CampaignID        *string                `json:"campaign_id"`
+	Priority          int                    `json:"priority"`
+	CapPerUser        int                    `json:"cap_per_user"`
+	CapGlobal         *int                   `json:"cap_global"`
+	CooldownSecs      int                    `json:"cooldown_secs"`
+	Active            bool                   `json:"active"`
+}
+
+// RuleRewardRequest is a reward a rule issues on top of its own
+type RuleRewardRequest struct {
+	RewardID string `json:"reward_id"`
+	Quantity int    `json:"quantity"`
 }
 
 // UpdateRuleRequest represents the request to update a rule
@@ -53,6 +80,73 @@ type UpdateRuleRequest struct {
 	Active       *bool                   `json:"active"`
 }
 
+// BacktestRuleRequest represents the request to backtest a rule
+type BacktestRuleRequest struct {
+	Days int `json:"days"`
+	// Conditions, when set, are backtested instead of the saved conditions
+	Conditions map[string]interface{} `json:"conditions"`
+}
+
+// RuleResponse is a rule in API responses
+type RuleResponse struct {
+	ID                string                 `json:"id"`
+	TenantID          string                 `json:"tenant_id"`
+	CampaignID        string                 `json:"campaign_id"`
+	Name              string                 `json:"name"`
+	EventType         string                 `json:"event_type"`
+	Conditions        map[string]interface{} `json:"conditions"`
+	RewardID          string                 `json:"reward_id"`
+	Quantity          int32                  `json:"quantity"`
+	AdditionalRewards []RuleRewardResponse   `json:"additional_rewards"`
+	PerUserCap        int32                  `json:"per_user_cap"`
+	GlobalCap         int32                  `json:"global_cap"`
+	CoolDownSec       int32                  `json:"cool_down_sec"`
+	Active            bool                   `json:"active"`
+	ArchivedAt        string                 `json:"archived_at"`
+}
+
+// RuleRewardResponse is a reward a rule issues on top of its own
+type RuleRewardResponse struct {
+	RewardID string `json:"reward_id"`
+	Quantity int32  `json:"quantity"`
+}
+
+// BacktestResponse is what a rule would have done against past events
+type BacktestResponse struct {
+	RuleID             string                  `json:"rule_id"`
+	Since              string                  `json:"since"`
+	Until              string                  `json:"until"`
+	EventsEvaluated    int                     `json:"events_evaluated"`
+	Truncated          bool                    `json:"truncated"`
+	Matched            int                     `json:"matched"`
+	EvaluationErrors   int                     `json:"evaluation_errors"`
+	Skipped            BacktestSkippedResponse `json:"skipped"`
+	Triggered          int                     `json:"triggered"`
+	Issuances          int                     `json:"issuances"`
+	Customers          int                     `json:"customers"`
+	Currency           string                  `json:"currency"`
+	UnitCost           float64                 `json:"unit_cost"`
+	EstimatedCost      float64                 `json:"estimated_cost"`
+	Projected30DayCost float64                 `json:"projected_30_day_cost"`
+}
+
+// BacktestSkippedResponse is how many matched events a backtest skipped,
+// by reason
+type BacktestSkippedResponse struct {
+	Excluded   int `json:"excluded"`
+	PerUserCap int `json:"per_user_cap"`
+	GlobalCap  int `json:"global_cap"`
+	Cooldown   int `json:"cooldown"`
+	NoValue    int `json:"no_value"`
+}
+
+// ImportedRulesResponse is the rules imported into a campaign
+type ImportedRulesResponse struct {
+	CampaignID string         `json:"campaign_id"`
+	Imported   int            `json:"imported"`
+	Rules      []RuleResponse `json:"rules"`
+}
+
 // Create handles POST /v1/tenants/:tid/rules
 func (h *RulesHandler) Create(c *gin.Context) {
 	tenantID := c.Param("tid")
@@ -100,6 +194,25 @@ func (h *RulesHandler) Create(c *gin.Context) {
 		}
 	}
 
+	additional := make([]rules.RewardAction, len(req.AdditionalRewards))
+	for i, a := range req.AdditionalRewards {
+		if err := httputil.ValidateUUID(a.RewardID); err != nil {
+			httputil.BadRequest(c, "Invalid additional reward ID", nil)
+			return
+		}
+		additional[i].RewardID.Scan(a.RewardID)
+		additional[i].Quantity = rule.DefaultQuantity(a.Quantity)
+	}
+
+	// Rules start inactive when the tenant requires approval
+	required, ok := requiresApproval(c, h.approvals, tenantUUID)
+	if !ok {
+		return
+	}
+	if required {
+		req.Active = false
+	}
+
 	// Serialize conditions to JSON
 	conditionsJSON, err := json.Marshal(req.Conditions)
 	if err != nil {
@@ -125,29 +238,25 @@ func (h *RulesHandler) Create(c *gin.Context) {
 		GlobalCap:   globalCap,
 		CoolDownSec: int32(req.CooldownSecs),
 		Active:      req.Active,
-	})
+		Quantity:    rule.DefaultQuantity(req.Quantity),
+	}, additional)
 	if err != nil {
-		httputil.InternalError(c, "Failed to create rule")
+		switch {
+		case errors.Is(err, rule.ErrInvalidRewardActions):
+			httputil.BadRequest(c, err.Error(), nil)
+		case errors.Is(err, rule.ErrRewardNotFound):
+			httputil.BadRequest(c, "Reward not found", nil)
+		default:
+			httputil.InternalError(c, "Failed to create rule")
+		}
 		return
 	}
 
-	// Parse conditions back for response
-	var conditions map[string]interface{}
-	json.Unmarshal(createdRule.Conditions, &conditions)
-
-	c.JSON(201, gin.H{
-		"id":           formatUUID(createdRule.ID),
-		"tenant_id":    formatUUID(createdRule.TenantID),
-		"campaign_id":  formatUUID(createdRule.CampaignID),
-		"name":         createdRule.Name,
-		"event_type":   createdRule.EventType,
-		"conditions":   conditions,
-		"reward_id":    formatUUID(createdRule.RewardID),
-		"per_user_cap": createdRule.PerUserCap,
-		"global_cap":   createdRule.GlobalCap.Int32,
-		"cool_down_sec": createdRule.CoolDownSec,
-		"active":       createdRule.Active,
-	})
+	formatted, ok := h.formatRules(c, tenantUUID, createdRule)
+	if !ok {
+		return
+	}
+	httputil.Respond(c, 201, formatted[0])
 }
 
 // List handles GET /v1/tenants/:tid/rules
@@ -160,6 +269,7 @@ func (h *RulesHandler) List(c *gin.Context) {
 
 	// Get filter params
 	activeOnly := c.DefaultQuery("active_only", "false")
+	includeArchived := c.DefaultQuery("include_archived", "false")
 
 	// Parse tenant UUID
 	var tenantUUID pgtype.UUID
@@ -169,44 +279,19 @@ func (h *RulesHandler) List(c *gin.Context) {
 	}
 
 	// List rules using service
-	rules, err := h.service.ListRules(c.Request.Context(), tenantUUID, activeOnly == "true")
+	rules, err := h.service.ListRules(c.Request.Context(), tenantUUID, activeOnly == "true", includeArchived == "true")
 	if err != nil {
 		httputil.InternalError(c, "Failed to list rules")
 		return
 	}
 
 	// Format response
-	rulesList := make([]gin.H, len(rules))
-	for i, rule := range rules {
-		var conditions map[string]interface{}
-		if len(rule.Conditions) > 0 {
-			json.Unmarshal(rule.Conditions, &conditions)
-		}
-
-		rulesList[i] = gin.H{
-			"id":            formatUUID(rule.ID),
-			"tenant_id":     formatUUID(rule.TenantID),
-			"campaign_id":   formatUUID(rule.CampaignID),
-			"name":          rule.Name,
-			"event_type":    rule.EventType,
-			"conditions":    conditions,
-			"reward_id":     formatUUID(rule.RewardID),
-			"per_user_cap":  rule.PerUserCap,
-			"global_cap":    rule.GlobalCap.Int32,
-			"cool_down_sec": rule.CoolDownSec,
-			"active":        rule.Active,
-		}
+	rulesList, ok := h.formatRules(c, tenantUUID, rules...)
+	if !ok {
+		return
 	}
 
-	c.JSON(200, gin.H{
-		"data":  rulesList,
-		"total": len(rules),
-		"limit": 0,
-		"page":  0,
-		"filters": gin.H{
-			"active_only": activeOnly,
-		},
-	})
+	httputil.RespondList(c, rulesList, httputil.Page{Total: int64(len(rules))})
 }
 
 // Get handles GET /v1/tenants/:tid/rules/:id
@@ -242,25 +327,11 @@ func (h *RulesHandler) Get(c *gin.Context) {
 		return
 	}
 
-	// Parse conditions
-	var conditions map[string]interface{}
-	if len(rule.Conditions) > 0 {
-		json.Unmarshal(rule.Conditions, &conditions)
-	}
-
-	c.JSON(200, gin.H{
-		"id":            formatUUID(rule.ID),
-		"tenant_id":     formatUUID(rule.TenantID),
-		"campaign_id":   formatUUID(rule.CampaignID),
-		"name":          rule.Name,
-		"event_type":    rule.EventType,
-		"conditions":    conditions,
-		"reward_id":     formatUUID(rule.RewardID),
-		"per_user_cap":  rule.PerUserCap,
-		"global_cap":    rule.GlobalCap.Int32,
-		"cool_down_sec": rule.CoolDownSec,
-		"active":        rule.Active,
-	})
+	formatted, ok := h.formatRules(c, tenantUUID, rule)
+	if !ok {
+		return
+	}
+	httputil.Respond(c, 200, formatted[0])
 }
 
 // Update handles PATCH /v1/tenants/:tid/rules/:id
@@ -297,10 +368,35 @@ func (h *RulesHandler) Update(c *gin.Context) {
 
 	// For now, we only support updating the active status
 	// Full update would require a new UpdateRule query
+	if req.Active != nil && *req.Active {
+		required, ok := requiresApproval(c, h.approvals, tenantUUID)
+		if !ok {
+			return
+		}
+		if required {
+			current, err := h.service.GetRuleByID(c.Request.Context(), ruleUUID, tenantUUID)
+			if err != nil {
+				httputil.NotFound(c, "Rule not found")
+				return
+			}
+			if !current.Active {
+				httputil.Conflict(c, "Submit the rule for approval to activate it", nil)
+				return
+			}
+		}
+	}
+
 	if req.Active != nil {
 		err := h.service.UpdateRuleStatus(c.Request.Context(), ruleUUID, tenantUUID, *req.Active)
 		if err != nil {
-			httputil.InternalError(c, "Failed to update rule")
+			switch {
+			case errors.Is(err, rule.ErrRuleNotFound):
+				httputil.NotFound(c, "Rule not found")
+			case errors.Is(err, rule.ErrRuleArchived):
+				httputil.Conflict(c, "Restore the rule before activating it", nil)
+			default:
+				httputil.InternalError(c, "Failed to update rule")
+			}
 			return
 		}
 	}
@@ -312,63 +408,365 @@ func (h *RulesHandler) Update(c *gin.Context) {
 		return
 	}
 
-	// Parse conditions
-	var conditions map[string]interface{}
-	if len(rule.Conditions) > 0 {
-		json.Unmarshal(rule.Conditions, &conditions)
-	}
-
-	c.JSON(200, gin.H{
-		"id":            formatUUID(rule.ID),
-		"tenant_id":     formatUUID(rule.TenantID),
-		"campaign_id":   formatUUID(rule.CampaignID),
-		"name":          rule.Name,
-		"event_type":    rule.EventType,
-		"conditions":    conditions,
-		"reward_id":     formatUUID(rule.RewardID),
-		"per_user_cap":  rule.PerUserCap,
-		"global_cap":    rule.GlobalCap.Int32,
-		"cool_down_sec": rule.CoolDownSec,
-		"active":        rule.Active,
-	})
+	formatted, ok := h.formatRules(c, tenantUUID, rule)
+	if !ok {
+		return
+	}
+	httputil.Respond(c, 200, formatted[0])
 }
 
 // Delete handles DELETE /v1/tenants/:tid/rules/:id
-// This is a soft delete (sets active=false)
+// Archives the rule, which also deactivates it. Restore brings it back.
 func (h *RulesHandler) Delete(c *gin.Context) {
+	tenantUUID, ruleUUID, ok := parseRuleParams(c)
+	if !ok {
+		return
+	}
+
+	archived, err := h.service.ArchiveRule(c.Request.Context(), ruleUUID, tenantUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, rule.ErrRuleNotFound):
+			httputil.NotFound(c, "Rule not found")
+		case errors.Is(err, rule.ErrRuleInActiveCampaign):
+			httputil.Conflict(c, err.Error(), nil)
+		default:
+			httputil.InternalError(c, "Failed to archive rule")
+		}
+		return
+	}
+
+	formatted, ok := h.formatRules(c, tenantUUID, archived)
+	if !ok {
+		return
+	}
+	httputil.Respond(c, 200, formatted[0])
+}
+
+// Restore handles POST /v1/tenants/:tid/rules/:id/restore
+// The restored rule stays inactive until it is activated.
+func (h *RulesHandler) Restore(c *gin.Context) {
+	tenantUUID, ruleUUID, ok := parseRuleParams(c)
+	if !ok {
+		return
+	}
+
+	restored, err := h.service.RestoreRule(c.Request.Context(), ruleUUID, tenantUUID)
+	if err != nil {
+		if errors.Is(err, rule.ErrRuleNotFound) {
+			httputil.NotFound(c, "Rule not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to restore rule")
+		return
+	}
+
+	formatted, ok := h.formatRules(c, tenantUUID, restored)
+	if !ok {
+		return
+	}
+	httputil.Respond(c, 200, formatted[0])
+}
+
+// Backtest handles POST /v1/tenants/:tid/rules/:id/backtest
+// Replays the last days (default 30) of the rule's events through its
+// conditions and caps and estimates what it would have issued and cost,
+// without issuing anything.
+func (h *RulesHandler) Backtest(c *gin.Context) {
+	tenantUUID, ruleUUID, ok := parseRuleParams(c)
+	if !ok {
+		return
+	}
+
+	var req BacktestRuleRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httputil.BadRequest(c, "Invalid request body", err.Error())
+			return
+		}
+	}
+
+	params := rule.BacktestParams{Days: req.Days}
+	if req.Conditions != nil {
+		conditionsJSON, err := json.Marshal(req.Conditions)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid conditions format", nil)
+			return
+		}
+		params.Conditions = conditionsJSON
+	}
+
+	result, err := h.backtester.Backtest(c.Request.Context(), tenantUUID, ruleUUID, params, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, rule.ErrRuleNotFound):
+			httputil.NotFound(c, "Rule not found")
+		case errors.Is(err, rule.ErrInvalidBacktestDays):
+			httputil.BadRequest(c, err.Error(), nil)
+		default:
+			httputil.InternalError(c, "Failed to backtest rule")
+		}
+		return
+	}
+
+	httputil.Respond(c, 200, BacktestResponse{
+		RuleID:           formatUUID(ruleUUID),
+		Since:            result.Since.Format(time.RFC3339),
+		Until:            result.Until.Format(time.RFC3339),
+		EventsEvaluated:  result.EventsEvaluated,
+		Truncated:        result.Truncated,
+		Matched:          result.Matched,
+		EvaluationErrors: result.EvaluationErrors,
+		Skipped: BacktestSkippedResponse{
+			Excluded:   result.SkippedExcluded,
+			PerUserCap: result.SkippedPerUserCap,
+			GlobalCap:  result.SkippedGlobalCap,
+			Cooldown:   result.SkippedCooldown,
+			NoValue:    result.SkippedNoValue,
+		},
+		Triggered:          result.Triggered,
+		Issuances:          result.Issuances,
+		Customers:          result.Customers,
+		Currency:           result.Currency,
+		UnitCost:           result.UnitCost,
+		EstimatedCost:      result.EstimatedCost,
+		Projected30DayCost: result.Projected30DayCost,
+	})
+}
+
+// Export handles GET /v1/tenants/:tid/rules/export?campaign_id=...
+// Downloads the campaign's rules as a bundle, in JSON or with format=yaml in
+// YAML, for import into another tenant.
+func (h *RulesHandler) Export(c *gin.Context) {
+	tenantUUID, campaignUUID, ok := parseBundleParams(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", rule.FormatJSON)
+	if format != rule.FormatJSON && format != rule.FormatYAML {
+		httputil.BadRequest(c, "format must be json or yaml", nil)
+		return
+	}
+
+	bundle, err := h.bundler.Export(c.Request.Context(), tenantUUID, campaignUUID)
+	if err != nil {
+		if errors.Is(err, rule.ErrCampaignNotFound) {
+			httputil.NotFound(c, "Campaign not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to export rules")
+		return
+	}
+
+	data, err := rule.MarshalBundle(bundle, format)
+	if err != nil {
+		httputil.InternalError(c, "Failed to encode rule bundle")
+		return
+	}
+
+	contentType := "application/json"
+	if format == rule.FormatYAML {
+		contentType = "application/yaml"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=rules-%s.%s", formatUUID(campaignUUID), format))
+	c.Data(200, contentType, data)
+}
+
+// Import handles POST /v1/tenants/:tid/rules/import?campaign_id=...
+// Creates the rules of a bundle in the campaign. The body is read as YAML
+// when sent as application/yaml (or format=yaml), otherwise as JSON.
+// Rewards are matched by name. Imported rules start inactive when the tenant
+// requires approval.
+func (h *RulesHandler) Import(c *gin.Context) {
+	tenantUUID, campaignUUID, ok := parseBundleParams(c)
+	if !ok {
+		return
+	}
+
+	data, format, ok := readBundleBody(c)
+	if !ok {
+		return
+	}
+
+	bundle, err := rule.UnmarshalBundle(data, format)
+	if err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	required, ok := requiresApproval(c, h.approvals, tenantUUID)
+	if !ok {
+		return
+	}
+
+	created, err := h.bundler.Import(c.Request.Context(), tenantUUID, campaignUUID, bundle, required)
+	if err != nil {
+		switch {
+		case errors.Is(err, rule.ErrCampaignNotFound):
+			httputil.NotFound(c, "Campaign not found")
+		case errors.Is(err, rule.ErrInvalidBundle), errors.Is(err, rule.ErrUnknownRewards):
+			httputil.BadRequest(c, err.Error(), nil)
+		case errors.Is(err, rule.ErrRuleNamesTaken):
+			httputil.Conflict(c, err.Error(), nil)
+		default:
+			httputil.InternalError(c, "Failed to import rules")
+		}
+		return
+	}
+
+	rulesList, ok := h.formatRules(c, tenantUUID, created...)
+	if !ok {
+		return
+	}
+
+	httputil.Respond(c, 201, ImportedRulesResponse{
+		CampaignID: formatUUID(campaignUUID),
+		Imported:   len(created),
+		Rules:      rulesList,
+	})
+}
+
+// maxBundleSize bounds the size of an imported rule bundle or tenant
+// configuration
+const maxBundleSize = 1 << 20
+
+// readBundleBody reads a JSON or YAML request body. The format is taken from
+// the format query parameter, else from the content type.
+func readBundleBody(c *gin.Context) ([]byte, string, bool) {
+	format := c.Query("format")
+	if format == "" {
+		format = rule.FormatJSON
+		switch c.ContentType() {
+		case "application/yaml", "application/x-yaml", "text/yaml":
+			format = rule.FormatYAML
+		}
+	}
+	if format != rule.FormatJSON && format != rule.FormatYAML {
+		httputil.BadRequest(c, "format must be json or yaml", nil)
+		return nil, "", false
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBundleSize+1))
+	if err != nil {
+		httputil.BadRequest(c, "Failed to read request body", nil)
+		return nil, "", false
+	}
+	if len(data) > maxBundleSize {
+		httputil.BadRequest(c, "Request body is too large", nil)
+		return nil, "", false
+	}
+	return data, format, true
+}
+
+// parseBundleParams validates and parses the tenant ID from the path and the
+// campaign ID from the query
+func parseBundleParams(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, campaignUUID pgtype.UUID
+
 	tenantID := c.Param("tid")
-	ruleID := c.Param("id")
+	campaignID := c.Query("campaign_id")
 
 	if err := httputil.ValidateUUID(tenantID); err != nil {
 		httputil.BadRequest(c, "Invalid tenant ID", nil)
-		return
+		return tenantUUID, campaignUUID, false
+	}
+	if campaignID == "" {
+		httputil.BadRequest(c, "campaign_id query parameter is required", nil)
+		return tenantUUID, campaignUUID, false
+	}
+	if err := httputil.ValidateUUID(campaignID); err != nil {
+		httputil.BadRequest(c, "Invalid campaign ID", nil)
+		return tenantUUID, campaignUUID, false
+	}
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, campaignUUID, false
+	}
+	if err := campaignUUID.Scan(campaignID); err != nil {
+		httputil.BadRequest(c, "Invalid campaign ID format", nil)
+		return tenantUUID, campaignUUID, false
 	}
 
+	return tenantUUID, campaignUUID, true
+}
+
+// parseRuleParams validates and parses the tenant and rule IDs from the path
+func parseRuleParams(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, ruleUUID pgtype.UUID
+
+	tenantID := c.Param("tid")
+	ruleID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, ruleUUID, false
+	}
 	if err := httputil.ValidateUUID(ruleID); err != nil {
 		httputil.BadRequest(c, "Invalid rule ID", nil)
-		return
+		return tenantUUID, ruleUUID, false
 	}
-
-	// Parse UUIDs
-	var tenantUUID, ruleUUID pgtype.UUID
 	if err := tenantUUID.Scan(tenantID); err != nil {
 		httputil.BadRequest(c, "Invalid tenant ID format", nil)
-		return
+		return tenantUUID, ruleUUID, false
 	}
 	if err := ruleUUID.Scan(ruleID); err != nil {
 		httputil.BadRequest(c, "Invalid rule ID format", nil)
-		return
+		return tenantUUID, ruleUUID, false
 	}
 
-	// Deactivate rule using service
-	err := h.service.DeactivateRule(c.Request.Context(), ruleUUID, tenantUUID)
+	return tenantUUID, ruleUUID, true
+}
+
+// formatRules formats rules with their additional rewards for a response
+func (h *RulesHandler) formatRules(c *gin.Context, tenantID pgtype.UUID, rules ...db.Rule) ([]RuleResponse, bool) {
+	ruleIDs := make([]pgtype.UUID, len(rules))
+	for i, r := range rules {
+		ruleIDs[i] = r.ID
+	}
+	additional, err := h.service.AdditionalRewards(c.Request.Context(), tenantID, ruleIDs)
 	if err != nil {
-		httputil.InternalError(c, "Failed to deactivate rule")
-		return
+		httputil.InternalError(c, "Failed to get rule rewards")
+		return nil, false
 	}
 
-	c.JSON(200, gin.H{
-		"id":      formatUUID(ruleUUID),
-		"message": "Rule deactivated successfully",
-	})
+	formatted := make([]RuleResponse, len(rules))
+	for i, r := range rules {
+		formatted[i] = formatRule(r, additional[r.ID])
+	}
+	return formatted, true
+}
+
+// formatRule formats a rule and the rewards it issues on top of its own
+// for a response
+func formatRule(r db.Rule, additional []db.RuleReward) RuleResponse {
+	var conditions map[string]interface{}
+	if len(r.Conditions) > 0 {
+		json.Unmarshal(r.Conditions, &conditions)
+	}
+
+	additionalRewards := make([]RuleRewardResponse, len(additional))
+	for i, a := range additional {
+		additionalRewards[i] = RuleRewardResponse{
+			RewardID: formatUUID(a.RewardID),
+			Quantity: a.Quantity,
+		}
+	}
+
+	return RuleResponse{
+		ID:                formatUUID(r.ID),
+		TenantID:          formatUUID(r.TenantID),
+		CampaignID:        formatUUID(r.CampaignID),
+		Name:              r.Name,
+		EventType:         r.EventType,
+		Conditions:        conditions,
+		RewardID:          formatUUID(r.RewardID),
+		Quantity:          r.Quantity,
+		AdditionalRewards: additionalRewards,
+		PerUserCap:        r.PerUserCap,
+		GlobalCap:         r.GlobalCap.Int32,
+		CoolDownSec:       r.CoolDownSec,
+		Active:            r.Active,
+		ArchivedAt:        formatTimestamp(r.ArchivedAt),
+	}
 }
@@ -0,0 +1,422 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/supplier"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SuppliersHandler handles supplier endpoints
+type SuppliersHandler struct {
+	pool    *pgxpool.Pool
+	service *supplier.Service
+	logger  *slog.Logger
+}
+
+// NewSuppliersHandler creates a new suppliers handler
+func NewSuppliersHandler(pool *pgxpool.Pool, logger *slog.Logger) *SuppliersHandler {
+	return &SuppliersHandler{
+		pool:    pool,
+		service: supplier.NewService(db.New(pool)),
+		logger:  logger,
+	}
+}
+
+// CreateSupplierRequest represents the request to create a supplier
+type CreateSupplierRequest struct {
+	Name         string                 `json:"name" binding:"required"`
+	ContactName  string                 `json:"contact_name"`
+	ContactEmail string                 `json:"contact_email"`
+	ContactPhone string                 `json:"contact_phone"`
+	Integration  map[string]interface{} `json:"integration"`
+	Active       *bool                  `json:"active"`
+}
+
+// UpdateSupplierRequest represents the request to update a supplier
+type UpdateSupplierRequest struct {
+	Name         *string                 `json:"name"`
+	ContactName  *string                 `json:"contact_name"`
+	ContactEmail *string                 `json:"contact_email"`
+	ContactPhone *string                 `json:"contact_phone"`
+	Integration  *map[string]interface{} `json:"integration"`
+	Active       *bool                   `json:"active"`
+}
+
+// SupplierResponse is a supplier in API responses
+type SupplierResponse struct {
+	ID           string                 `json:"id"`
+	TenantID     string                 `json:"tenant_id"`
+	Name         string                 `json:"name"`
+	ContactName  string                 `json:"contact_name"`
+	ContactEmail string                 `json:"contact_email"`
+	ContactPhone string                 `json:"contact_phone"`
+	Integration  map[string]interface{} `json:"integration"`
+	Active       bool                   `json:"active"`
+	CreatedAt    string                 `json:"created_at"`
+	UpdatedAt    string                 `json:"updated_at"`
+}
+
+// SupplierPerformanceReportResponse is suppliers' fulfilment performance
+// over a period
+type SupplierPerformanceReportResponse struct {
+	From      string                        `json:"from"`
+	To        string                        `json:"to"`
+	Suppliers []SupplierPerformanceResponse `json:"suppliers"`
+}
+
+// SupplierPerformanceResponse is a supplier's fulfilment counts, success
+// rate and latency
+type SupplierPerformanceResponse struct {
+	SupplierID        string  `json:"supplier_id"`
+	Name              string  `json:"name"`
+	Total             int64   `json:"total"`
+	Fulfilled         int64   `json:"fulfilled"`
+	Failed            int64   `json:"failed"`
+	Pending           int64   `json:"pending"`
+	SuccessRate       float64 `json:"success_rate"`
+	AvgLatencySeconds float64 `json:"avg_latency_seconds"`
+	P95LatencySeconds float64 `json:"p95_latency_seconds"`
+}
+
+// Create handles POST /v1/tenants/:tid/suppliers
+func (h *SuppliersHandler) Create(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var req CreateSupplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	params := supplier.Params{
+		TenantID:     tenantUUID,
+		Name:         req.Name,
+		ContactName:  req.ContactName,
+		ContactEmail: req.ContactEmail,
+		ContactPhone: req.ContactPhone,
+		Integration:  req.Integration,
+		Active:       active,
+	}
+	if err := params.Validate(); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	created, err := h.service.Create(c.Request.Context(), params)
+	if err != nil {
+		if errors.Is(err, supplier.ErrSupplierExists) {
+			httputil.Conflict(c, "Supplier name already exists", nil)
+			return
+		}
+		h.logger.Error("failed to create supplier", "error", err)
+		httputil.InternalError(c, "Failed to create supplier")
+		return
+	}
+
+	httputil.Respond(c, 201, formatSupplier(created))
+}
+
+// List handles GET /v1/tenants/:tid/suppliers
+// Deactivated suppliers are included unless ?active_only=true.
+func (h *SuppliersHandler) List(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	suppliers, err := h.service.List(c.Request.Context(), tenantUUID, c.Query("active_only") == "true")
+	if err != nil {
+		h.logger.Error("failed to list suppliers", "error", err)
+		httputil.InternalError(c, "Failed to list suppliers")
+		return
+	}
+
+	suppliersList := make([]SupplierResponse, len(suppliers))
+	for i, s := range suppliers {
+		suppliersList[i] = formatSupplier(s)
+	}
+
+	httputil.RespondList(c, suppliersList, httputil.Page{Total: int64(len(suppliersList))})
+}
+
+// Get handles GET /v1/tenants/:tid/suppliers/:id
+func (h *SuppliersHandler) Get(c *gin.Context) {
+	tenantUUID, supplierUUID, ok := parseSupplierParams(c)
+	if !ok {
+		return
+	}
+
+	s, err := h.service.Get(c.Request.Context(), tenantUUID, supplierUUID)
+	if err != nil {
+		if errors.Is(err, supplier.ErrSupplierNotFound) {
+			httputil.NotFound(c, "Supplier not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get supplier")
+		return
+	}
+
+	httputil.Respond(c, 200, formatSupplier(s))
+}
+
+// Update handles PATCH /v1/tenants/:tid/suppliers/:id
+func (h *SuppliersHandler) Update(c *gin.Context) {
+	tenantUUID, supplierUUID, ok := parseSupplierParams(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateSupplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	// Get current supplier so omitted fields are preserved
+	existing, err := h.service.Get(c.Request.Context(), tenantUUID, supplierUUID)
+	if err != nil {
+		if errors.Is(err, supplier.ErrSupplierNotFound) {
+			httputil.NotFound(c, "Supplier not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get supplier")
+		return
+	}
+
+	params := supplier.Params{
+		TenantID:     tenantUUID,
+		Name:         existing.Name,
+		ContactName:  existing.ContactName.String,
+		ContactEmail: existing.ContactEmail.String,
+		ContactPhone: existing.ContactPhone.String,
+		Active:       existing.Active,
+	}
+	if err := json.Unmarshal(existing.Integration, &params.Integration); err != nil {
+		httputil.InternalError(c, "Failed to read supplier integration")
+		return
+	}
+	if req.Name != nil {
+		params.Name = *req.Name
+	}
+	if req.ContactName != nil {
+		params.ContactName = *req.ContactName
+	}
+	if req.ContactEmail != nil {
+		params.ContactEmail = *req.ContactEmail
+	}
+	if req.ContactPhone != nil {
+		params.ContactPhone = *req.ContactPhone
+	}
+	if req.Integration != nil {
+		params.Integration = *req.Integration
+	}
+	if req.Active != nil {
+		params.Active = *req.Active
+	}
+	if err := params.Validate(); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	updated, err := h.service.Update(c.Request.Context(), supplierUUID, params)
+	if err != nil {
+		switch {
+		case errors.Is(err, supplier.ErrSupplierNotFound):
+			httputil.NotFound(c, "Supplier not found")
+		case errors.Is(err, supplier.ErrSupplierExists):
+			httputil.Conflict(c, "Supplier name already exists", nil)
+		default:
+			h.logger.Error("failed to update supplier", "error", err)
+			httputil.InternalError(c, "Failed to update supplier")
+		}
+		return
+	}
+
+	httputil.Respond(c, 200, formatSupplier(updated))
+}
+
+// Delete handles DELETE /v1/tenants/:tid/suppliers/:id
+// Suppliers are deactivated rather than deleted so historical issuances keep their attribution.
+func (h *SuppliersHandler) Delete(c *gin.Context) {
+	tenantUUID, supplierUUID, ok := parseSupplierParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Deactivate(c.Request.Context(), tenantUUID, supplierUUID); err != nil {
+		switch {
+		case errors.Is(err, supplier.ErrSupplierNotFound):
+			httputil.NotFound(c, "Supplier not found")
+		case errors.Is(err, supplier.ErrSupplierInUse):
+			httputil.Conflict(c, "Supplier has active rewards; archive or reassign them first", nil)
+		default:
+			h.logger.Error("failed to deactivate supplier", "error", err)
+			httputil.InternalError(c, "Failed to deactivate supplier")
+		}
+		return
+	}
+
+	httputil.Respond(c, 200, DeactivatedResponse{
+		ID:      c.Param("id"),
+		Message: "Supplier deactivated successfully",
+	})
+}
+
+// Performance handles GET /v1/tenants/:tid/suppliers/performance
+// Reports fulfilment success rates and latency per supplier for the issuances
+// reserved between ?from= and ?to= (RFC3339, default the last 30 days).
+// ?supplier_id= limits the report to one supplier.
+func (h *SuppliersHandler) Performance(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	var supplierUUID pgtype.UUID
+	if v := c.Query("supplier_id"); v != "" {
+		if err := httputil.ValidateUUID(v); err != nil {
+			httputil.BadRequest(c, "Invalid supplier ID", nil)
+			return
+		}
+		if err := supplierUUID.Scan(v); err != nil {
+			httputil.BadRequest(c, "Invalid supplier ID format", nil)
+			return
+		}
+	}
+
+	to := time.Now()
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid to format. Use RFC3339", nil)
+			return
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -30)
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid from format. Use RFC3339", nil)
+			return
+		}
+		from = t
+	}
+	if from.After(to) {
+		httputil.BadRequest(c, "from must not be after to", nil)
+		return
+	}
+
+	report, err := h.service.Performance(c.Request.Context(), tenantUUID, supplierUUID, from, to)
+	if err != nil {
+		h.logger.Error("failed to report supplier performance", "error", err)
+		httputil.InternalError(c, "Failed to report supplier performance")
+		return
+	}
+
+	suppliers := make([]SupplierPerformanceResponse, len(report))
+	for i, p := range report {
+		suppliers[i] = SupplierPerformanceResponse{
+			SupplierID:        formatUUID(p.SupplierID),
+			Name:              p.Name,
+			Total:             p.Total,
+			Fulfilled:         p.Fulfilled,
+			Failed:            p.Failed,
+			Pending:           p.Pending,
+			SuccessRate:       p.SuccessRate,
+			AvgLatencySeconds: p.AvgLatencySeconds,
+			P95LatencySeconds: p.P95LatencySeconds,
+		}
+	}
+
+	httputil.Respond(c, 200, SupplierPerformanceReportResponse{
+		From:      from.UTC().Format(time.RFC3339),
+		To:        to.UTC().Format(time.RFC3339),
+		Suppliers: suppliers,
+	})
+}
+
+// parseSupplierParams validates and parses the tenant and supplier IDs from the path
+func parseSupplierParams(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, supplierUUID pgtype.UUID
+
+	tenantID := c.Param("tid")
+	supplierID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, supplierUUID, false
+	}
+	if err := httputil.ValidateUUID(supplierID); err != nil {
+		httputil.BadRequest(c, "Invalid supplier ID", nil)
+		return tenantUUID, supplierUUID, false
+	}
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, supplierUUID, false
+	}
+	if err := supplierUUID.Scan(supplierID); err != nil {
+		httputil.BadRequest(c, "Invalid supplier ID format", nil)
+		return tenantUUID, supplierUUID, false
+	}
+
+	return tenantUUID, supplierUUID, true
+}
+
+// formatSupplier formats a supplier for the API response
+func formatSupplier(s db.Supplier) SupplierResponse {
+	var integration map[string]interface{}
+	if len(s.Integration) > 0 {
+		json.Unmarshal(s.Integration, &integration)
+	}
+
+	return SupplierResponse{
+		ID:           formatUUID(s.ID),
+		TenantID:     formatUUID(s.TenantID),
+		Name:         s.Name,
+		ContactName:  s.ContactName.String,
+		ContactEmail: s.ContactEmail.String,
+		ContactPhone: s.ContactPhone.String,
+		Integration:  integration,
+		Active:       s.Active,
+		CreatedAt:    formatTimestamp(s.CreatedAt),
+		UpdatedAt:    formatTimestamp(s.UpdatedAt),
+	}
+}
@@ -0,0 +1,387 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/survey"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SurveysHandler handles survey definition and results endpoints
+type SurveysHandler struct {
+	pool    *pgxpool.Pool
+	service *survey.Service
+}
+
+// NewSurveysHandler creates a new surveys handler
+func NewSurveysHandler(pool *pgxpool.Pool, service *survey.Service) *SurveysHandler {
+	return &SurveysHandler{
+		pool:    pool,
+		service: service,
+	}
+}
+
+// CreateSurveyRequest represents the request to create a survey
+type CreateSurveyRequest struct {
+	Name      string            `json:"name" binding:"required"`
+	Trigger   string            `json:"trigger" binding:"required"`
+	Questions []survey.Question `json:"questions" binding:"required"`
+	Active    *bool             `json:"active"`
+}
+
+// UpdateSurveyRequest represents the request to update a survey
+type UpdateSurveyRequest struct {
+	Name      *string           `json:"name"`
+	Trigger   *string           `json:"trigger"`
+	Questions []survey.Question `json:"questions"`
+	Active    *bool             `json:"active"`
+}
+
+// SendSurveyRequest represents the request to send a survey to a customer
+type SendSurveyRequest struct {
+	CustomerID string `json:"customer_id" binding:"required"`
+}
+
+// SurveyResponse is a survey in API responses
+type SurveyResponse struct {
+	ID        string            `json:"id"`
+	TenantID  string            `json:"tenant_id"`
+	Name      string            `json:"name"`
+	Trigger   string            `json:"trigger"`
+	Questions []survey.Question `json:"questions"`
+	Active    bool              `json:"active"`
+	CreatedAt string            `json:"created_at"`
+	UpdatedAt string            `json:"updated_at"`
+}
+
+// CustomerSurveyResponse is a customer's response to a survey. IssuanceID
+// is only set once completing it issued a reward.
+type CustomerSurveyResponse struct {
+	ID              string `json:"id"`
+	SurveyID        string `json:"survey_id"`
+	CustomerID      string `json:"customer_id"`
+	Status          string `json:"status"`
+	CurrentQuestion int32  `json:"current_question"`
+	StartedAt       string `json:"started_at"`
+	IssuanceID      string `json:"issuance_id,omitempty"`
+}
+
+// SurveyResultsResponse is how many customers started, completed and
+// abandoned a survey, and the answers to each question
+type SurveyResultsResponse struct {
+	SurveyID       string                  `json:"survey_id"`
+	Started        int64                   `json:"started"`
+	Completed      int64                   `json:"completed"`
+	Abandoned      int64                   `json:"abandoned"`
+	CompletionRate float64                 `json:"completion_rate"`
+	Questions      []survey.QuestionResult `json:"questions"`
+}
+
+// Create handles POST /v1/tenants/:tid/surveys
+func (h *SurveysHandler) Create(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var req CreateSurveyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	params := survey.Params{
+		TenantID:  tenantUUID,
+		Name:      req.Name,
+		Trigger:   req.Trigger,
+		Questions: req.Questions,
+		Active:    active,
+	}
+	if err := params.Validate(); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	created, err := h.service.Create(c.Request.Context(), params)
+	if err != nil {
+		httputil.InternalError(c, "Failed to create survey")
+		return
+	}
+
+	httputil.Respond(c, 201, formatSurvey(created))
+}
+
+// List handles GET /v1/tenants/:tid/surveys
+func (h *SurveysHandler) List(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	surveys, err := h.service.List(c.Request.Context(), tenantUUID)
+	if err != nil {
+		httputil.InternalError(c, "Failed to list surveys")
+		return
+	}
+
+	surveysList := make([]SurveyResponse, len(surveys))
+	for i, s := range surveys {
+		surveysList[i] = formatSurvey(s)
+	}
+
+	httputil.RespondList(c, surveysList, httputil.Page{Total: int64(len(surveysList))})
+}
+
+// Get handles GET /v1/tenants/:tid/surveys/:id
+func (h *SurveysHandler) Get(c *gin.Context) {
+	tenantUUID, surveyUUID, ok := parseSurveyParams(c)
+	if !ok {
+		return
+	}
+
+	s, err := h.service.Get(c.Request.Context(), tenantUUID, surveyUUID)
+	if err != nil {
+		if errors.Is(err, survey.ErrSurveyNotFound) {
+			httputil.NotFound(c, "Survey not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get survey")
+		return
+	}
+
+	httputil.Respond(c, 200, formatSurvey(s))
+}
+
+// Update handles PATCH /v1/tenants/:tid/surveys/:id
+func (h *SurveysHandler) Update(c *gin.Context) {
+	tenantUUID, surveyUUID, ok := parseSurveyParams(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateSurveyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	// Get current survey so omitted fields are preserved
+	existing, err := h.service.Get(c.Request.Context(), tenantUUID, surveyUUID)
+	if err != nil {
+		if errors.Is(err, survey.ErrSurveyNotFound) {
+			httputil.NotFound(c, "Survey not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get survey")
+		return
+	}
+
+	params := survey.Params{
+		TenantID:  tenantUUID,
+		Name:      existing.Name,
+		Trigger:   existing.Trigger,
+		Questions: survey.ParseQuestions(existing.Questions),
+		Active:    existing.Active,
+	}
+	if req.Name != nil {
+		params.Name = *req.Name
+	}
+	if req.Trigger != nil {
+		params.Trigger = *req.Trigger
+	}
+	if req.Questions != nil {
+		params.Questions = req.Questions
+	}
+	if req.Active != nil {
+		params.Active = *req.Active
+	}
+
+	if err := params.Validate(); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	updated, err := h.service.Update(c.Request.Context(), surveyUUID, params)
+	if err != nil {
+		if errors.Is(err, survey.ErrSurveyNotFound) {
+			httputil.NotFound(c, "Survey not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to update survey")
+		return
+	}
+
+	httputil.Respond(c, 200, formatSurvey(updated))
+}
+
+// Send handles POST /v1/tenants/:tid/surveys/:id/send
+// Starts the survey for a customer over WhatsApp.
+func (h *SurveysHandler) Send(c *gin.Context) {
+	tenantUUID, surveyUUID, ok := parseSurveyParams(c)
+	if !ok {
+		return
+	}
+
+	var req SendSurveyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+	if err := httputil.ValidateUUID(req.CustomerID); err != nil {
+		httputil.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+
+	var customerUUID pgtype.UUID
+	if err := customerUUID.Scan(req.CustomerID); err != nil {
+		httputil.BadRequest(c, "Invalid customer ID format", nil)
+		return
+	}
+
+	s, err := h.service.Get(c.Request.Context(), tenantUUID, surveyUUID)
+	if err != nil {
+		if errors.Is(err, survey.ErrSurveyNotFound) {
+			httputil.NotFound(c, "Survey not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get survey")
+		return
+	}
+
+	response, err := h.service.Send(c.Request.Context(), s, customerUUID, pgtype.UUID{})
+	if err != nil {
+		switch {
+		case errors.Is(err, survey.ErrSurveyInactive):
+			httputil.Conflict(c, "Survey is inactive", nil)
+		case errors.Is(err, survey.ErrCustomerNotFound):
+			httputil.NotFound(c, "Customer not found")
+		case errors.Is(err, survey.ErrNoDeliverer):
+			httputil.BadRequest(c, "WhatsApp is not configured for surveys", nil)
+		case errors.Is(err, survey.ErrSurveysDisabled):
+			httputil.Conflict(c, "Surveys are disabled for WhatsApp", nil)
+		default:
+			if response.ID.Valid {
+				httputil.InternalError(c, "Survey started but the first question could not be delivered")
+				return
+			}
+			httputil.InternalError(c, "Failed to send survey")
+		}
+		return
+	}
+
+	httputil.Respond(c, 201, formatSurveyResponse(response))
+}
+
+// Results handles GET /v1/tenants/:tid/surveys/:id/results
+func (h *SurveysHandler) Results(c *gin.Context) {
+	tenantUUID, surveyUUID, ok := parseSurveyParams(c)
+	if !ok {
+		return
+	}
+
+	results, err := h.service.Results(c.Request.Context(), tenantUUID, surveyUUID)
+	if err != nil {
+		if errors.Is(err, survey.ErrSurveyNotFound) {
+			httputil.NotFound(c, "Survey not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get survey results")
+		return
+	}
+
+	var completionRate float64
+	if results.Started > 0 {
+		completionRate = float64(results.Completed) / float64(results.Started)
+	}
+
+	httputil.Respond(c, 200, SurveyResultsResponse{
+		SurveyID:       formatUUID(results.SurveyID),
+		Started:        results.Started,
+		Completed:      results.Completed,
+		Abandoned:      results.Abandoned,
+		CompletionRate: completionRate,
+		Questions:      results.Questions,
+	})
+}
+
+// parseSurveyParams validates and parses the tenant and survey IDs from the path
+func parseSurveyParams(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, surveyUUID pgtype.UUID
+
+	tenantID := c.Param("tid")
+	surveyID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, surveyUUID, false
+	}
+	if err := httputil.ValidateUUID(surveyID); err != nil {
+		httputil.BadRequest(c, "Invalid survey ID", nil)
+		return tenantUUID, surveyUUID, false
+	}
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, surveyUUID, false
+	}
+	if err := surveyUUID.Scan(surveyID); err != nil {
+		httputil.BadRequest(c, "Invalid survey ID format", nil)
+		return tenantUUID, surveyUUID, false
+	}
+
+	return tenantUUID, surveyUUID, true
+}
+
+// formatSurvey formats a survey for the API response
+func formatSurvey(s db.Survey) SurveyResponse {
+	return SurveyResponse{
+		ID:        formatUUID(s.ID),
+		TenantID:  formatUUID(s.TenantID),
+		Name:      s.Name,
+		Trigger:   s.Trigger,
+		Questions: survey.ParseQuestions(s.Questions),
+		Active:    s.Active,
+		CreatedAt: formatTimestamp(s.CreatedAt),
+		UpdatedAt: formatTimestamp(s.UpdatedAt),
+	}
+}
+
+// formatSurveyResponse formats a survey response for the API response
+func formatSurveyResponse(r db.SurveyResponse) CustomerSurveyResponse {
+	response := CustomerSurveyResponse{
+		ID:              formatUUID(r.ID),
+		SurveyID:        formatUUID(r.SurveyID),
+		CustomerID:      formatUUID(r.CustomerID),
+		Status:          r.Status,
+		CurrentQuestion: r.CurrentQuestion,
+		StartedAt:       formatTimestamp(r.StartedAt),
+	}
+
+	if r.IssuanceID.Valid {
+		response.IssuanceID = formatUUID(r.IssuanceID)
+	}
+
+	return response
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/bmachimbira/loyalty/api/internal/approval"
+	"github.com/bmachimbira/loyalty/api/internal/catalogcache"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/tenantconfig"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TenantConfigHandler handles declarative tenant configuration
+type TenantConfigHandler struct {
+	applier   *tenantconfig.Applier
+	approvals *approval.Service
+}
+
+// NewTenantConfigHandler creates a new tenant configuration handler
+func NewTenantConfigHandler(pool *pgxpool.Pool, catalog *catalogcache.Cache) *TenantConfigHandler {
+	return &TenantConfigHandler{
+		applier: tenantconfig.NewApplier(pool, db.New(pool), catalog),
+	}
+}
+
+// SetApprovalService enables maker-checker controls for tenants that
+// require approval
+func (h *TenantConfigHandler) SetApprovalService(approvals *approval.Service) {
+	h.approvals = approvals
+}
+
+// ConfigPlanResponse is the changes applying a tenant configuration makes,
+// or would make when only planned
+type ConfigPlanResponse struct {
+	Applied bool                      `json:"applied"`
+	Summary ConfigPlanSummaryResponse `json:"summary"`
+	Changes []tenantconfig.Change     `json:"changes"`
+}
+
+// ConfigPlanSummaryResponse counts a plan's changes by action
+type ConfigPlanSummaryResponse struct {
+	Create    int `json:"create"`
+	Update    int `json:"update"`
+	Unchanged int `json:"unchanged"`
+}
+
+// Apply handles POST /v1/tenants/:tid/config/apply
+// The body is a tenant configuration document in JSON or YAML. With
+// ?plan=true the changes are only previewed.
+func (h *TenantConfigHandler) Apply(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	data, format, ok := readBundleBody(c)
+	if !ok {
+		return
+	}
+
+	doc, err := tenantconfig.Unmarshal(data, format)
+	if err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	required, ok := requiresApproval(c, h.approvals, tenantUUID)
+	if !ok {
+		return
+	}
+
+	plan, err := h.applier.Apply(c.Request.Context(), tenantUUID, doc, tenantconfig.Options{
+		Apply:           c.Query("plan") != "true",
+		RequireApproval: required,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, tenantconfig.ErrInvalidDocument), errors.Is(err, tenantconfig.ErrUnknownReferences):
+			httputil.BadRequest(c, err.Error(), nil)
+		case errors.Is(err, tenantconfig.ErrAmbiguousName), errors.Is(err, tenantconfig.ErrImmutableChange),
+			errors.Is(err, tenantconfig.ErrArchivedReward), errors.Is(err, tenantconfig.ErrApprovalRequired):
+			httputil.Conflict(c, err.Error(), nil)
+		default:
+			httputil.InternalError(c, "Failed to apply tenant configuration")
+		}
+		return
+	}
+
+	httputil.Respond(c, 200, ConfigPlanResponse{
+		Applied: plan.Applied,
+		Summary: ConfigPlanSummaryResponse{
+			Create:    plan.Count(tenantconfig.ActionCreate),
+			Update:    plan.Count(tenantconfig.ActionUpdate),
+			Unchanged: plan.Count(tenantconfig.ActionNone),
+		},
+		Changes: plan.Changes,
+	})
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/rewardcatalog"
+	"github.com/bmachimbira/loyalty/api/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// UploadsHandler lists the files staff have uploaded and processes them
+// again
+type UploadsHandler struct {
+	service *storage.Service
+	rewards *rewardcatalog.Service
+}
+
+// NewUploadsHandler creates a new uploads handler
+func NewUploadsHandler(service *storage.Service, rewards *rewardcatalog.Service) *UploadsHandler {
+	return &UploadsHandler{
+		service: service,
+		rewards: rewards,
+	}
+}
+
+// UploadResponse is a stored upload in API responses
+type UploadResponse struct {
+	ID          string `json:"id"`
+	Kind        string `json:"kind"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	SizeBytes   int64  `json:"size_bytes"`
+	SHA256      string `json:"sha256"`
+	EntityType  string `json:"entity_type"`
+	EntityID    string `json:"entity_id"`
+	UploadedBy  string `json:"uploaded_by"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// List handles GET /v1/tenants/:tid/uploads
+// Filters by kind, entity_type and entity_id when given.
+func (h *UploadsHandler) List(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	filter := storage.ListFilter{
+		Kind:       c.Query("kind"),
+		EntityType: c.Query("entity_type"),
+	}
+	if entityID := c.Query("entity_id"); entityID != "" {
+		if err := httputil.ValidateUUID(entityID); err != nil {
+			httputil.BadRequest(c, "Invalid entity ID", nil)
+			return
+		}
+		if err := filter.EntityID.Scan(entityID); err != nil {
+			httputil.BadRequest(c, "Invalid entity ID format", nil)
+			return
+		}
+	}
+
+	limit := c.DefaultQuery("limit", "50")
+	offset := c.DefaultQuery("offset", "0")
+
+	uploads, total, err := h.service.List(c.Request.Context(), tenantUUID, filter, limit, offset)
+	if err != nil {
+		httputil.InternalError(c, "Failed to list uploads")
+		return
+	}
+
+	uploadsList := make([]UploadResponse, len(uploads))
+	for i, u := range uploads {
+		uploadsList[i] = formatUpload(u)
+	}
+
+	httputil.RespondList(c, uploadsList, httputil.NewPage(total, limit, offset))
+}
+
+// Get handles GET /v1/tenants/:tid/uploads/:id
+func (h *UploadsHandler) Get(c *gin.Context) {
+	tenantUUID, uploadUUID, ok := parseUploadParams(c)
+	if !ok {
+		return
+	}
+
+	upload, err := h.service.Get(c.Request.Context(), tenantUUID, uploadUUID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUploadNotFound) {
+			httputil.NotFound(c, "Upload not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get upload")
+		return
+	}
+
+	httputil.Respond(c, 200, formatUpload(upload))
+}
+
+// Content handles GET /v1/tenants/:tid/uploads/:id/content
+// Responds with the file as it was uploaded.
+func (h *UploadsHandler) Content(c *gin.Context) {
+	tenantUUID, uploadUUID, ok := parseUploadParams(c)
+	if !ok {
+		return
+	}
+
+	upload, body, ok := h.open(c, tenantUUID, uploadUUID)
+	if !ok {
+		return
+	}
+
+	// Always a download, never rendered, whatever the uploader claimed it was
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": upload.Filename}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(200, upload.ContentType, body)
+}
+
+// Reprocess handles POST /v1/tenants/:tid/uploads/:id/reprocess
+// Processes a stored upload again as if it had just been uploaded. Voucher
+// codes already loaded are skipped.
+func (h *UploadsHandler) Reprocess(c *gin.Context) {
+	tenantUUID, uploadUUID, ok := parseUploadParams(c)
+	if !ok {
+		return
+	}
+
+	upload, body, ok := h.open(c, tenantUUID, uploadUUID)
+	if !ok {
+		return
+	}
+
+	if upload.Kind != storage.KindVoucherCodes || upload.EntityType.String != "reward" || !upload.EntityID.Valid {
+		httputil.BadRequest(c, "Uploads of kind "+upload.Kind+" cannot be reprocessed", nil)
+		return
+	}
+
+	result, err := h.rewards.ImportVoucherCodes(c.Request.Context(), tenantUUID, upload.EntityID, bytes.NewReader(body))
+	if err != nil {
+		if errors.Is(err, rewardcatalog.ErrRewardNotFound) {
+			httputil.NotFound(c, "Reward not found")
+			return
+		}
+		if errors.Is(err, rewardcatalog.ErrNotVoucherReward) {
+			httputil.BadRequest(c, "Reward must be of type voucher_code", nil)
+			return
+		}
+		httputil.BadRequest(c, "Failed to parse CSV file", err.Error())
+		return
+	}
+
+	httputil.Respond(c, 200, VoucherCodesUploadedResponse{
+		UploadID:      formatUUID(upload.ID),
+		RewardID:      formatUUID(upload.EntityID),
+		CodesUploaded: result.Imported,
+		FileName:      upload.Filename,
+		TotalCodes:    result.Total,
+	})
+}
+
+// open retrieves an upload and its contents, responding with the error if
+// that fails
+func (h *UploadsHandler) open(c *gin.Context, tenantUUID, uploadUUID pgtype.UUID) (db.Upload, []byte, bool) {
+	upload, body, err := h.service.Open(c.Request.Context(), tenantUUID, uploadUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrUploadNotFound):
+			httputil.NotFound(c, "Upload not found")
+		case errors.Is(err, storage.ErrObjectNotFound):
+			httputil.NotFound(c, "Upload content not found in storage")
+		case errors.Is(err, storage.ErrStorageDisabled):
+			httputil.Conflict(c, "Upload storage is not configured", nil)
+		default:
+			httputil.InternalError(c, "Failed to read upload")
+		}
+		return db.Upload{}, nil, false
+	}
+	return upload, body, true
+}
+
+// parseUploadParams validates and parses the tenant and upload IDs from the
+// path
+func parseUploadParams(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, uploadUUID pgtype.UUID
+
+	tenantID := c.Param("tid")
+	uploadID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, uploadUUID, false
+	}
+	if err := httputil.ValidateUUID(uploadID); err != nil {
+		httputil.BadRequest(c, "Invalid upload ID", nil)
+		return tenantUUID, uploadUUID, false
+	}
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, uploadUUID, false
+	}
+	if err := uploadUUID.Scan(uploadID); err != nil {
+		httputil.BadRequest(c, "Invalid upload ID format", nil)
+		return tenantUUID, uploadUUID, false
+	}
+
+	return tenantUUID, uploadUUID, true
+}
+
+func formatUpload(u db.Upload) UploadResponse {
+	return UploadResponse{
+		ID:          formatUUID(u.ID),
+		Kind:        u.Kind,
+		Filename:    u.Filename,
+		ContentType: u.ContentType,
+		SizeBytes:   u.SizeBytes,
+		SHA256:      u.Sha256,
+		EntityType:  u.EntityType.String,
+		EntityID:    formatUUID(u.EntityID),
+		UploadedBy:  formatUUID(u.UploadedBy),
+		CreatedAt:   formatTimestamp(u.CreatedAt),
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/metering"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// UsageHandler reports a tenant's metered, billable usage
+type UsageHandler struct {
+	queries *db.Queries
+}
+
+// NewUsageHandler creates a new usage handler
+func NewUsageHandler(pool *pgxpool.Pool) *UsageHandler {
+	return &UsageHandler{queries: db.New(pool)}
+}
+
+// UsageResponse is a tenant's metered usage per month
+type UsageResponse struct {
+	TenantID string                  `json:"tenant_id"`
+	From     string                  `json:"from"`
+	To       string                  `json:"to"`
+	Months   []metering.MonthlyUsage `json:"months"`
+}
+
+// Get handles GET /v1/tenants/:tid/usage
+// Optional from and to (YYYY-MM) default to the last 12 months.
+func (h *UsageHandler) Get(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	to := metering.Period(time.Now())
+	if s := c.Query("to"); s != "" {
+		t, err := metering.ParseMonth(s)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid to: "+err.Error(), nil)
+			return
+		}
+		to = t
+	}
+	from := to.AddDate(0, -11, 0)
+	if s := c.Query("from"); s != "" {
+		t, err := metering.ParseMonth(s)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid from: "+err.Error(), nil)
+			return
+		}
+		from = t
+	}
+	if from.After(to) {
+		httputil.BadRequest(c, "from must not be after to", nil)
+		return
+	}
+
+	usage, err := metering.GetUsage(c.Request.Context(), h.queries, tenantUUID, from, to)
+	if err != nil {
+		httputil.InternalError(c, "Failed to get usage")
+		return
+	}
+
+	httputil.Respond(c, 200, UsageResponse{
+		TenantID: tenantID,
+		From:     from.Format(metering.MonthFormat),
+		To:       to.Format(metering.MonthFormat),
+		Months:   usage,
+	})
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/wallet"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Wallet pass formats
+const (
+	walletFormatApple  = "apple"
+	walletFormatGoogle = "google"
+)
+
+// WalletPassesHandler serves Apple Wallet and Google Wallet passes of issued
+// rewards. Either wallet is optional; requests for one that is not
+// configured are rejected.
+type WalletPassesHandler struct {
+	service *wallet.Service
+	apple   *wallet.AppleSigner
+	google  *wallet.GoogleIssuer
+}
+
+// NewWalletPassesHandler creates a new wallet passes handler. apple and
+// google may be nil.
+func NewWalletPassesHandler(service *wallet.Service, apple *wallet.AppleSigner, google *wallet.GoogleIssuer) *WalletPassesHandler {
+	return &WalletPassesHandler{
+		service: service,
+		apple:   apple,
+		google:  google,
+	}
+}
+
+// WalletPassStatusResponse is the status a Google Wallet pass was updated to
+type WalletPassStatusResponse struct {
+	IssuanceID string `json:"issuance_id"`
+	Status     string `json:"status"`
+}
+
+// WalletPassSaveResponse is the link that saves a pass to Google Wallet
+type WalletPassSaveResponse struct {
+	IssuanceID string `json:"issuance_id"`
+	SaveURL    string `json:"save_url"`
+}
+
+// Get handles GET /v1/tenants/:tid/issuances/:id/wallet-pass?format=apple|google
+// Apple passes are returned as a .pkpass file; Google passes as a "Save to
+// Google Wallet" link.
+func (h *WalletPassesHandler) Get(c *gin.Context) {
+	tenantUUID, issuanceUUID, ok := parseWalletPassParams(c)
+	if !ok {
+		return
+	}
+
+	pass, ok := h.issuedPass(c, tenantUUID, issuanceUUID)
+	if !ok {
+		return
+	}
+	h.respondPass(c, pass)
+}
+
+// Update handles PUT /v1/tenants/:tid/issuances/:id/wallet-pass
+// Pushes the issuance's current state to its saved Google Wallet pass, e.g.
+// after it was redeemed. Apple Wallet passes are fetched by the wallet
+// itself from the pass web service.
+func (h *WalletPassesHandler) Update(c *gin.Context) {
+	tenantUUID, issuanceUUID, ok := parseWalletPassParams(c)
+	if !ok {
+		return
+	}
+	if h.google == nil {
+		httputil.BadRequest(c, "Google Wallet passes are not configured", nil)
+		return
+	}
+
+	pass, err := h.service.Pass(c.Request.Context(), tenantUUID, issuanceUUID)
+	if !h.handlePassError(c, err) {
+		return
+	}
+
+	if err := h.google.Update(c.Request.Context(), pass); err != nil {
+		if errors.Is(err, wallet.ErrNotSaved) {
+			httputil.NotFound(c, "Pass has not been saved to Google Wallet")
+			return
+		}
+		httputil.InternalError(c, "Failed to update Google Wallet pass")
+		return
+	}
+
+	httputil.Respond(c, 200, WalletPassStatusResponse{
+		IssuanceID: formatUUID(issuanceUUID),
+		Status:     pass.Status,
+	})
+}
+
+// CustomerGet handles GET /v1/portal/me/rewards/:id/wallet-pass?format=apple|google
+// The signed-in customer's own rewards only.
+func (h *WalletPassesHandler) CustomerGet(c *gin.Context) {
+	tenantUUID, customerUUID, ok := portalCustomer(c)
+	if !ok {
+		return
+	}
+
+	var issuanceUUID pgtype.UUID
+	if err := issuanceUUID.Scan(c.Param("id")); err != nil {
+		httputil.BadRequest(c, "Invalid issuance ID format", nil)
+		return
+	}
+
+	pass, ok := h.issuedPass(c, tenantUUID, issuanceUUID)
+	if !ok {
+		return
+	}
+	if pass.CustomerID != customerUUID {
+		httputil.NotFound(c, "Reward not found")
+		return
+	}
+	h.respondPass(c, pass)
+}
+
+// LatestApplePass handles GET /public/wallet/v1/passes/:passTypeId/:serial
+// This is the Apple Wallet web service endpoint wallets fetch updated passes
+// from, authenticated with the pass's own token.
+func (h *WalletPassesHandler) LatestApplePass(c *gin.Context) {
+	if h.apple == nil || c.Param("passTypeId") != h.apple.PassTypeID() {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	serial := c.Param("serial")
+	token, found := strings.CutPrefix(c.GetHeader("Authorization"), "ApplePass ")
+	if !found || !h.apple.ValidAuthToken(serial, token) {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	tenantUUID, issuanceUUID, err := wallet.ParseSerialNumber(serial)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	pass, err := h.service.Pass(c.Request.Context(), tenantUUID, issuanceUUID)
+	if err != nil {
+		if errors.Is(err, wallet.ErrPassNotFound) || errors.Is(err, wallet.ErrNoCode) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !pass.LastModified.After(since) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	data, err := h.apple.Build(pass)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("Last-Modified", pass.LastModified.UTC().Format(http.TimeFormat))
+	c.Data(http.StatusOK, wallet.PKPassContentType, data)
+}
+
+// issuedPass loads the pass of an issued reward, responding with an error if
+// there is none
+func (h *WalletPassesHandler) issuedPass(c *gin.Context, tenantUUID, issuanceUUID pgtype.UUID) (wallet.Pass, bool) {
+	pass, err := h.service.IssuedPass(c.Request.Context(), tenantUUID, issuanceUUID)
+	return pass, h.handlePassError(c, err)
+}
+
+// handlePassError responds to an error loading a pass, reporting whether
+// there was none
+func (h *WalletPassesHandler) handlePassError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, wallet.ErrPassNotFound):
+		httputil.NotFound(c, "Issuance not found")
+	case errors.Is(err, wallet.ErrNoCode):
+		httputil.Conflict(c, "Reward has no redemption code to add to a wallet", nil)
+	case errors.Is(err, wallet.ErrNotIssued):
+		httputil.Conflict(c, "Reward is no longer active", nil)
+	default:
+		httputil.InternalError(c, "Failed to load wallet pass")
+	}
+	return false
+}
+
+// respondPass responds with the pass in the requested format
+func (h *WalletPassesHandler) respondPass(c *gin.Context, pass wallet.Pass) {
+	switch c.DefaultQuery("format", walletFormatApple) {
+	case walletFormatApple:
+		if h.apple == nil {
+			httputil.BadRequest(c, "Apple Wallet passes are not configured", nil)
+			return
+		}
+		data, err := h.apple.Build(pass)
+		if err != nil {
+			httputil.InternalError(c, "Failed to create Apple Wallet pass")
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="reward.pkpass"`)
+		c.Header("Last-Modified", pass.LastModified.UTC().Format(http.TimeFormat))
+		c.Data(http.StatusOK, wallet.PKPassContentType, data)
+
+	case walletFormatGoogle:
+		if h.google == nil {
+			httputil.BadRequest(c, "Google Wallet passes are not configured", nil)
+			return
+		}
+		saveURL, err := h.google.SaveURL(pass)
+		if err != nil {
+			httputil.InternalError(c, "Failed to create Google Wallet pass")
+			return
+		}
+		httputil.Respond(c, 200, WalletPassSaveResponse{
+			IssuanceID: formatUUID(pass.IssuanceID),
+			SaveURL:    saveURL,
+		})
+
+	default:
+		httputil.BadRequest(c, "format must be apple or google", nil)
+	}
+}
+
+// parseWalletPassParams parses the tenant and issuance of a wallet pass
+// request
+func parseWalletPassParams(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, issuanceUUID pgtype.UUID
+	if err := httputil.ValidateUUID(c.Param("tid")); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, issuanceUUID, false
+	}
+	if err := httputil.ValidateUUID(c.Param("id")); err != nil {
+		httputil.BadRequest(c, "Invalid issuance ID", nil)
+		return tenantUUID, issuanceUUID, false
+	}
+	if err := tenantUUID.Scan(c.Param("tid")); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, issuanceUUID, false
+	}
+	if err := issuanceUUID.Scan(c.Param("id")); err != nil {
+		httputil.BadRequest(c, "Invalid issuance ID format", nil)
+		return tenantUUID, issuanceUUID, false
+	}
+	return tenantUUID, issuanceUUID, true
+}
@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/webhooks"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WebhooksHandler handles the tenant's outbound webhook endpoints
+type WebhooksHandler struct {
+	service *webhooks.Service
+	logger  *slog.Logger
+}
+
+// NewWebhooksHandler creates a new webhooks handler
+func NewWebhooksHandler(pool *pgxpool.Pool, logger *slog.Logger) *WebhooksHandler {
+	return &WebhooksHandler{
+		service: webhooks.NewService(pool),
+		logger:  logger,
+	}
+}
+
+// CreateWebhookRequest represents the request to create a webhook endpoint
+type CreateWebhookRequest struct {
+	Name   string          `json:"name" binding:"required"`
+	URL    string          `json:"url" binding:"required"`
+	Events []string        `json:"events" binding:"required"`
+	Filter json.RawMessage `json:"filter"`
+	Active *bool           `json:"active"`
+}
+
+// UpdateWebhookRequest represents the request to update a webhook endpoint.
+// An omitted filter is kept and a null filter is removed.
+type UpdateWebhookRequest struct {
+	Name   *string         `json:"name"`
+	URL    *string         `json:"url"`
+	Events *[]string       `json:"events"`
+	Filter json.RawMessage `json:"filter"`
+	Active *bool           `json:"active"`
+}
+
+// WebhookResponse is a webhook endpoint in API responses. Its secret is
+// left out.
+type WebhookResponse struct {
+	ID        string      `json:"id"`
+	TenantID  string      `json:"tenant_id"`
+	Name      string      `json:"name"`
+	URL       string      `json:"url"`
+	Events    []string    `json:"events"`
+	Filter    interface{} `json:"filter"`
+	Active    bool        `json:"active"`
+	CreatedAt string      `json:"created_at"`
+}
+
+// CreatedWebhookResponse is a new webhook endpoint with the secret its
+// deliveries are signed with, which is only returned once
+type CreatedWebhookResponse struct {
+	WebhookResponse
+	Secret string `json:"secret"`
+}
+
+// Create handles POST /v1/tenants/:tid/webhooks
+// The endpoint receives the events it subscribes to that pass its optional
+// JsonLogic filter, evaluated against the event's data. The response holds
+// the secret deliveries are signed with; it isn't returned again.
+func (h *WebhooksHandler) Create(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	params := webhooks.Params{
+		Name:   req.Name,
+		URL:    req.URL,
+		Events: req.Events,
+		Filter: req.Filter,
+		Active: active,
+	}
+	if err := params.Validate(); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	created, err := h.service.Create(c.Request.Context(), tenantUUID, params)
+	if err != nil {
+		h.logger.Error("failed to create webhook", "error", err)
+		httputil.InternalError(c, "Failed to create webhook")
+		return
+	}
+
+	httputil.Respond(c, 201, CreatedWebhookResponse{
+		WebhookResponse: formatWebhook(created),
+		Secret:          created.Secret,
+	})
+}
+
+// List handles GET /v1/tenants/:tid/webhooks
+func (h *WebhooksHandler) List(c *gin.Context) {
+	tenantID := c.Param("tid")
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return
+	}
+
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return
+	}
+
+	list, err := h.service.List(c.Request.Context(), tenantUUID)
+	if err != nil {
+		h.logger.Error("failed to list webhooks", "error", err)
+		httputil.InternalError(c, "Failed to list webhooks")
+		return
+	}
+
+	webhooksList := make([]WebhookResponse, len(list))
+	for i, w := range list {
+		webhooksList[i] = formatWebhook(w)
+	}
+
+	httputil.RespondList(c, webhooksList, httputil.Page{Total: int64(len(webhooksList))})
+}
+
+// Get handles GET /v1/tenants/:tid/webhooks/:id
+func (h *WebhooksHandler) Get(c *gin.Context) {
+	tenantUUID, webhookUUID, ok := parseWebhookParams(c)
+	if !ok {
+		return
+	}
+
+	w, err := h.service.Get(c.Request.Context(), tenantUUID, webhookUUID)
+	if err != nil {
+		if errors.Is(err, webhooks.ErrWebhookNotFound) {
+			httputil.NotFound(c, "Webhook not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get webhook")
+		return
+	}
+
+	httputil.Respond(c, 200, formatWebhook(w))
+}
+
+// Update handles PATCH /v1/tenants/:tid/webhooks/:id
+func (h *WebhooksHandler) Update(c *gin.Context) {
+	tenantUUID, webhookUUID, ok := parseWebhookParams(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	// Get current webhook so omitted fields are preserved
+	existing, err := h.service.Get(c.Request.Context(), tenantUUID, webhookUUID)
+	if err != nil {
+		if errors.Is(err, webhooks.ErrWebhookNotFound) {
+			httputil.NotFound(c, "Webhook not found")
+			return
+		}
+		httputil.InternalError(c, "Failed to get webhook")
+		return
+	}
+
+	params := webhooks.Params{
+		Name:   existing.Name,
+		URL:    existing.Url,
+		Events: existing.Events,
+		Filter: existing.Filter,
+		Active: existing.Active,
+	}
+	if req.Name != nil {
+		params.Name = *req.Name
+	}
+	if req.URL != nil {
+		params.URL = *req.URL
+	}
+	if req.Events != nil {
+		params.Events = *req.Events
+	}
+	if req.Filter != nil {
+		params.Filter = req.Filter
+	}
+	if req.Active != nil {
+		params.Active = *req.Active
+	}
+	if err := params.Validate(); err != nil {
+		httputil.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	updated, err := h.service.Update(c.Request.Context(), tenantUUID, webhookUUID, params)
+	if err != nil {
+		if errors.Is(err, webhooks.ErrWebhookNotFound) {
+			httputil.NotFound(c, "Webhook not found")
+			return
+		}
+		h.logger.Error("failed to update webhook", "error", err)
+		httputil.InternalError(c, "Failed to update webhook")
+		return
+	}
+
+	httputil.Respond(c, 200, formatWebhook(updated))
+}
+
+// Delete handles DELETE /v1/tenants/:tid/webhooks/:id
+// Webhooks are deactivated rather than deleted so their delivery history is kept.
+func (h *WebhooksHandler) Delete(c *gin.Context) {
+	tenantUUID, webhookUUID, ok := parseWebhookParams(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.service.Deactivate(c.Request.Context(), tenantUUID, webhookUUID); err != nil {
+		if errors.Is(err, webhooks.ErrWebhookNotFound) {
+			httputil.NotFound(c, "Webhook not found")
+			return
+		}
+		h.logger.Error("failed to deactivate webhook", "error", err)
+		httputil.InternalError(c, "Failed to deactivate webhook")
+		return
+	}
+
+	httputil.Respond(c, 200, DeactivatedResponse{
+		ID:      c.Param("id"),
+		Message: "Webhook deactivated successfully",
+	})
+}
+
+// parseWebhookParams validates and parses the tenant and webhook IDs from the path
+func parseWebhookParams(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var tenantUUID, webhookUUID pgtype.UUID
+
+	tenantID := c.Param("tid")
+	webhookID := c.Param("id")
+
+	if err := httputil.ValidateUUID(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID", nil)
+		return tenantUUID, webhookUUID, false
+	}
+	if err := httputil.ValidateUUID(webhookID); err != nil {
+		httputil.BadRequest(c, "Invalid webhook ID", nil)
+		return tenantUUID, webhookUUID, false
+	}
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		httputil.BadRequest(c, "Invalid tenant ID format", nil)
+		return tenantUUID, webhookUUID, false
+	}
+	if err := webhookUUID.Scan(webhookID); err != nil {
+		httputil.BadRequest(c, "Invalid webhook ID format", nil)
+		return tenantUUID, webhookUUID, false
+	}
+
+	return tenantUUID, webhookUUID, true
+}
+
+// formatWebhook formats a webhook endpoint for the API response, without its
+// secret
+func formatWebhook(w db.Webhook) WebhookResponse {
+	var filter interface{}
+	if len(w.Filter) > 0 {
+		json.Unmarshal(w.Filter, &filter)
+	}
+
+	return WebhookResponse{
+		ID:        formatUUID(w.ID),
+		TenantID:  formatUUID(w.TenantID),
+		Name:      w.Name,
+		URL:       w.Url,
+		Events:    w.Events,
+		Filter:    filter,
+		Active:    w.Active,
+		CreatedAt: formatTimestamp(w.CreatedAt),
+	}
+}
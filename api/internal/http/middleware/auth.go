@@ -1,11 +1,15 @@
 package middleware
 
 import (
+	"context"
+	"crypto/subtle"
 	"io"
+	"net/http"
 	"strings"
 
 	"github.com/bmachimbira/loyalty/api/internal/auth"
 	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/logging"
 	"github.com/gin-gonic/gin"
 )
 
@@ -14,10 +18,17 @@ const (
 	TenantIDKey = "tenant_id"
 	EmailKey    = "email"
 	RoleKey     = "role"
+
+	// CustomerIDKey holds the signed-in customer on customer portal routes
+	CustomerIDKey = "customer_id"
+
+	// ImpersonationIDKey holds the impersonation on customer portal routes
+	// requested by staff impersonating the customer
+	ImpersonationIDKey = "impersonation_id"
 )
 
 // RequireAuth validates JWT token and extracts claims
-func RequireAuth(jwtSecret string) gin.HandlerFunc {
+func RequireAuth(keys auth.Keys) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -37,7 +48,7 @@ func RequireAuth(jwtSecret string) gin.HandlerFunc {
 		token := parts[1]
 
 		// Validate token
-		claims, err := auth.ValidateToken(token, jwtSecret)
+		claims, err := auth.ValidateToken(token, keys)
 		if err != nil {
 			httputil.Unauthorized(c, "Invalid or expired token")
 			c.Abort()
@@ -50,6 +61,95 @@ func RequireAuth(jwtSecret string) gin.HandlerFunc {
 		c.Set(EmailKey, claims.Email)
 		c.Set(RoleKey, claims.Role)
 
+		// Tag the request's log lines with who made it
+		ctx := c.Request.Context()
+		logger := logging.FromContext(ctx, nil).With("tenant_id", claims.TenantID, "user_id", claims.UserID)
+		c.Request = c.Request.WithContext(logging.NewContext(ctx, logger))
+
+		c.Next()
+	}
+}
+
+// RequireCustomerAuth validates a customer portal JWT token and extracts its
+// claims. Staff tokens are rejected.
+func RequireCustomerAuth(keys auth.Keys) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			httputil.Unauthorized(c, "Missing authorization header")
+			c.Abort()
+			return
+		}
+
+		// Extract token from "Bearer <token>"
+		parts := strings.Split(authHeader, " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			httputil.Unauthorized(c, "Invalid authorization header format")
+			c.Abort()
+			return
+		}
+
+		claims, err := auth.ValidateCustomerToken(parts[1], keys)
+		if err != nil {
+			httputil.Unauthorized(c, "Invalid or expired token")
+			c.Abort()
+			return
+		}
+
+		// Staff impersonating a customer can see what they see but not act
+		// for them
+		if claims.Impersonating() && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			httputil.Forbidden(c, "Impersonation sessions are read-only")
+			c.Abort()
+			return
+		}
+
+		c.Set(CustomerIDKey, claims.CustomerID)
+		c.Set(TenantIDKey, claims.TenantID)
+
+		ctx := c.Request.Context()
+		logger := logging.FromContext(ctx, nil).With("tenant_id", claims.TenantID, "customer_id", claims.CustomerID)
+		if claims.Impersonating() {
+			c.Set(ImpersonationIDKey, claims.ImpersonationID)
+			logger = logger.With("impersonation_id", claims.ImpersonationID, "user_id", claims.ImpersonatedBy)
+		}
+		c.Request = c.Request.WithContext(logging.NewContext(ctx, logger))
+
+		c.Next()
+	}
+}
+
+// ImpersonationAuditor records customer portal requests made by staff
+// impersonating a customer
+type ImpersonationAuditor interface {
+	// RecordImpersonatedRequest audit logs the request, reporting false when
+	// the impersonation has ended and the request must be refused
+	RecordImpersonatedRequest(ctx context.Context, tenantID, impersonationID, method, path, ip string) (bool, error)
+}
+
+// AuditImpersonation audit logs every request made with an impersonation
+// token and refuses those whose impersonation was ended early. It runs after
+// RequireCustomerAuth; customers' own requests pass through untouched.
+func AuditImpersonation(auditor ImpersonationAuditor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		impersonationID := c.GetString(ImpersonationIDKey)
+		if impersonationID == "" {
+			c.Next()
+			return
+		}
+
+		active, err := auditor.RecordImpersonatedRequest(c.Request.Context(), c.GetString(TenantIDKey), impersonationID, c.Request.Method, c.Request.URL.Path, c.ClientIP())
+		if err != nil {
+			httputil.InternalError(c, "Failed to record impersonated request")
+			c.Abort()
+			return
+		}
+		if !active {
+			httputil.Unauthorized(c, "Impersonation session has ended")
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -78,7 +178,8 @@ func RequireRole(roles ...string) gin.HandlerFunc {
 }
 
 // RequireHMAC validates HMAC signature for server-to-server requests
-func RequireHMAC(keys auth.HMACKeys) gin.HandlerFunc {
+// against the keyring's current API keys
+func RequireHMAC(keyring *auth.Keyring) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		apiKey := c.GetHeader("X-Key")
 		timestamp := c.GetHeader("X-Timestamp")
@@ -93,7 +194,7 @@ func RequireHMAC(keys auth.HMACKeys) gin.HandlerFunc {
 		}
 
 		// Validate HMAC
-		if err := auth.ValidateHMAC(apiKey, timestamp, signature, string(body), keys); err != nil {
+		if err := auth.ValidateHMAC(apiKey, timestamp, signature, string(body), keyring.HMACKeys()); err != nil {
 			httputil.Unauthorized(c, "Invalid HMAC signature: "+err.Error())
 			c.Abort()
 			return
@@ -102,3 +203,18 @@ func RequireHMAC(keys auth.HMACKeys) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequirePlatformToken admits platform operators, who authenticate with the
+// token configured for the deployment rather than a tenant's staff login
+func RequirePlatformToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(parts) != 2 || parts[0] != "Bearer" || subtle.ConstantTimeCompare([]byte(parts[1]), []byte(token)) != 1 {
+			httputil.Unauthorized(c, "Invalid platform token")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -1,34 +1,34 @@
 package middleware
 
 import (
-	"log"
 	"time"
 
+	"github.com/bmachimbira/loyalty/api/internal/logging"
 	"github.com/gin-gonic/gin"
 )
 
-// Logger middleware logs HTTP requests
-func Logger() gin.HandlerFunc {
+// Logger middleware attaches a request-scoped logger carrying the request and
+// correlation IDs and the matched route to the request context, then logs the
+// request once handled. Must run after RequestID.
+func Logger(logger *logging.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
-		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
+		ctx := c.Request.Context()
+		requestLogger := logger.WithContext(ctx)
+		if route := c.FullPath(); route != "" {
+			requestLogger = requestLogger.With("route", route)
+		}
+		c.Request = c.Request.WithContext(logging.NewContext(ctx, requestLogger))
 
 		c.Next()
 
-		end := time.Now()
-		latency := end.Sub(start)
+		fields := map[string]interface{}{
+			"client_ip": c.ClientIP(),
+		}
+		if query := c.Request.URL.RawQuery; query != "" {
+			fields["query"] = query
+		}
 
-		requestID, _ := c.Get(RequestIDKey)
-
-		log.Printf("[%s] %s %s %s %d %s %s",
-			requestID,
-			c.Request.Method,
-			path,
-			query,
-			c.Writer.Status(),
-			latency,
-			c.ClientIP(),
-		)
+		logger.LogRequest(c.Request.Context(), c.Request.Method, c.Request.URL.Path, c.Writer.Status(), time.Since(start), fields)
 	}
 }
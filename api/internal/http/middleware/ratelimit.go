@@ -5,6 +5,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
 	"github.com/gin-gonic/gin"
 )
 
@@ -99,11 +100,8 @@ func RateLimit(rate int, window time.Duration) gin.HandlerFunc {
 
 		// Check if request is allowed
 		if !limiter.Allow(key) {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":   "Rate limit exceeded",
-				"message": "Too many requests. Please try again later.",
-				"retry_after": window.Seconds(),
-			})
+			httputil.RespondError(c, http.StatusTooManyRequests, httputil.ErrCodeRateLimited,
+				"Too many requests. Please try again later.", gin.H{"retry_after": window.Seconds()})
 			c.Abort()
 			return
 		}
@@ -128,11 +126,8 @@ func RateLimitByTenant(rate int, window time.Duration) gin.HandlerFunc {
 
 		// Check if request is allowed
 		if !limiter.Allow(key) {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":   "Rate limit exceeded",
-				"message": "Too many requests for this tenant. Please try again later.",
-				"retry_after": window.Seconds(),
-			})
+			httputil.RespondError(c, http.StatusTooManyRequests, httputil.ErrCodeRateLimited,
+				"Too many requests for this tenant. Please try again later.", gin.H{"retry_after": window.Seconds()})
 			c.Abort()
 			return
 		}
@@ -166,9 +161,7 @@ func RateLimitByEndpoint(limits map[string]*RateLimiter) gin.HandlerFunc {
 
 		// Check if request is allowed
 		if !limiter.Allow(key) {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "Rate limit exceeded",
-			})
+			httputil.RateLimited(c, "Rate limit exceeded")
 			c.Abort()
 			return
 		}
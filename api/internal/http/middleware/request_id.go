@@ -1,13 +1,24 @@
 package middleware
 
 import (
+	"context"
+
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/logging"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-const RequestIDKey = "request_id"
+// RequestIDKey is the gin context key holding the request ID
+const RequestIDKey = httputil.RequestIDKey
+
+// CorrelationIDKey is the gin context key holding the correlation ID
+const CorrelationIDKey = "correlation_id"
 
-// RequestID middleware generates a unique request ID for each request
+// RequestID middleware generates a unique request ID for each request. The
+// caller's X-Correlation-ID is propagated so a flow spanning several requests
+// can be traced; it defaults to the request ID. Both IDs are echoed in the
+// response headers and stored in the request context for logging.
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		requestID := c.GetHeader("X-Request-ID")
@@ -15,8 +26,20 @@ func RequestID() gin.HandlerFunc {
 			requestID = uuid.New().String()
 		}
 
+		correlationID := c.GetHeader("X-Correlation-ID")
+		if correlationID == "" {
+			correlationID = requestID
+		}
+
 		c.Set(RequestIDKey, requestID)
+		c.Set(CorrelationIDKey, correlationID)
 		c.Writer.Header().Set("X-Request-ID", requestID)
+		c.Writer.Header().Set("X-Correlation-ID", correlationID)
+
+		ctx := context.WithValue(c.Request.Context(), logging.RequestIDKey, requestID)
+		ctx = context.WithValue(ctx, logging.CorrelationIDKey, correlationID)
+		c.Request = c.Request.WithContext(ctx)
+
 		c.Next()
 	}
 }
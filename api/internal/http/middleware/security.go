@@ -3,6 +3,7 @@ package middleware
 import (
 	"net/http"
 
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
 	"github.com/gin-gonic/gin"
 )
 
@@ -49,9 +50,7 @@ func RequestSizeLimiter(maxBytes int64) gin.HandlerFunc {
 
 		// Check if request body was too large
 		if err := c.Request.Body.Close(); err != nil {
-			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
-				"error": "Request body too large",
-			})
+			httputil.RespondError(c, http.StatusRequestEntityTooLarge, httputil.ErrCodePayloadTooLarge, "Request body too large", nil)
 			c.Abort()
 			return
 		}
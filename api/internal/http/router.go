@@ -1,70 +1,461 @@
 package http
 
 import (
+	"context"
 	"os"
 	"time"
 
 	"github.com/bmachimbira/loyalty/api/internal/analytics"
+	"github.com/bmachimbira/loyalty/api/internal/approval"
 	"github.com/bmachimbira/loyalty/api/internal/auth"
+	"github.com/bmachimbira/loyalty/api/internal/budget"
+	"github.com/bmachimbira/loyalty/api/internal/catalogcache"
+	"github.com/bmachimbira/loyalty/api/internal/challenge"
+	"github.com/bmachimbira/loyalty/api/internal/changes"
 	"github.com/bmachimbira/loyalty/api/internal/channels/ussd"
 	"github.com/bmachimbira/loyalty/api/internal/channels/whatsapp"
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/draw"
+	"github.com/bmachimbira/loyalty/api/internal/eventbus"
+	"github.com/bmachimbira/loyalty/api/internal/export"
+	"github.com/bmachimbira/loyalty/api/internal/featureflag"
+	"github.com/bmachimbira/loyalty/api/internal/fulfilment"
 	"github.com/bmachimbira/loyalty/api/internal/http/handlers"
 	"github.com/bmachimbira/loyalty/api/internal/http/middleware"
+	"github.com/bmachimbira/loyalty/api/internal/leaderboard"
+	"github.com/bmachimbira/loyalty/api/internal/lifecycle"
 	"github.com/bmachimbira/loyalty/api/internal/logging"
+	"github.com/bmachimbira/loyalty/api/internal/metering"
+	"github.com/bmachimbira/loyalty/api/internal/portal"
+	"github.com/bmachimbira/loyalty/api/internal/promo"
+	"github.com/bmachimbira/loyalty/api/internal/receipt"
+	"github.com/bmachimbira/loyalty/api/internal/retention"
+	"github.com/bmachimbira/loyalty/api/internal/reward"
+	"github.com/bmachimbira/loyalty/api/internal/rewardcatalog"
 	"github.com/bmachimbira/loyalty/api/internal/rules"
+	"github.com/bmachimbira/loyalty/api/internal/storage"
+	"github.com/bmachimbira/loyalty/api/internal/survey"
+	"github.com/bmachimbira/loyalty/api/internal/wallet"
+	"github.com/bmachimbira/loyalty/api/internal/webhooks"
+	"github.com/bmachimbira/loyalty/api/internal/welcome"
+	"github.com/bmachimbira/loyalty/api/internal/winback"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// SetupRouter configures all routes and middleware
-func SetupRouter(pool *pgxpool.Pool, jwtSecret string, hmacKeys auth.HMACKeys) *gin.Engine {
+// SetupRouter configures all routes and middleware. Background workers owned
+// by the handlers are registered with workers so they drain on shutdown.
+// Read-only reporting queries use readPool, which may be the primary pool.
+// Tokens are signed and verified with keyring's keys, which reloadSecrets
+// rotates.
+func SetupRouter(pool, readPool *pgxpool.Pool, jwtSecret string, keyring *auth.Keyring, reloadSecrets func() error, workers *lifecycle.Manager) *gin.Engine {
 	// Set Gin mode based on environment
 	// gin.SetMode(gin.ReleaseMode) // Uncomment for production
 
 	r := gin.New()
 
+	// Initialize logger
+	logger := logging.New()
+
 	// Global middleware
 	r.Use(gin.Recovery()) // Recover from panics
 	r.Use(middleware.CORS())
 	r.Use(middleware.RequestID())
-	r.Use(middleware.Logger())
+	r.Use(middleware.Logger(logger))
 
 	// Health check endpoint (no auth required)
 	r.GET("/health", HealthCheck)
 	r.GET("/ready", ReadyCheck(pool))
 
-	// Initialize logger and rules engine
-	logger := logging.New()
+	// Initialize rules engine
 	rulesEngine := rules.NewEngine(pool, logger)
 
 	// Initialize database queries
 	queries := db.New(pool)
 
+	// Shared reward catalog and campaign cache, invalidated by catalog mutations
+	catalog := catalogcache.New(queries, 5*time.Minute)
+	rulesEngine.SetCatalogCache(catalog)
+
+	// Billable usage is buffered and flushed to monthly usage records
+	meter := metering.NewMeter(pool, logger.Logger)
+	rulesEngine.SetMeter(meter)
+	if err := workers.Register("usage-metering", func(ctx context.Context) error {
+		return meter.Run(ctx, 30*time.Second)
+	}); err != nil {
+		logger.Error("failed to register usage metering worker", "error", err)
+	}
+
 	// Initialize services
-	authService := auth.NewService(queries, jwtSecret)
-	analyticsService := analytics.NewService(queries, logger.Logger)
+	authService := auth.NewService(queries, keyring)
+	analyticsService := analytics.NewService(db.New(readPool), logger.Logger)
+
+	// Receipt OCR is optional; without a provider receipts are reviewed by hand
+	ocrProvider, err := receipt.NewProvider(os.Getenv("RECEIPT_OCR_PROVIDER"), os.Getenv("RECEIPT_OCR_URL"), os.Getenv("RECEIPT_OCR_API_KEY"))
+	if err != nil {
+		logger.Error("invalid receipt OCR configuration, OCR disabled", "error", err)
+	}
+	receiptService := receipt.NewService(pool, queries, ocrProvider)
+	surveyService := survey.NewService(pool, queries, rulesEngine, logger.Logger)
+
+	// Tenants turn customer-facing features on and off per channel
+	featureFlags := featureflag.NewService(queries, logger.Logger)
+	surveyService.SetFeatureFlags(featureFlags)
+
+	// Challenge progress is tracked as the rules engine processes events
+	challengeService := challenge.NewService(queries)
+	rulesEngine.SetChallengeTracker(challenge.NewTracker(pool, queries, rulesEngine, logger.Logger))
+
+	// Events enter customers into prize draws; winners' prizes come from rules
+	drawService := draw.NewService(pool, queries, rulesEngine, logger.Logger)
+	rulesEngine.SetDrawEntrant(drawService)
+	promoService := promo.NewService(pool, queries, rulesEngine, logger.Logger)
+	approvalService := approval.NewService(pool, queries, catalog, logger.Logger)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService)
 	customersHandler := handlers.NewCustomersHandler(pool)
+	notesHandler := handlers.NewNotesHandler(pool)
 	eventsHandler := handlers.NewEventsHandler(pool, rulesEngine, logger)
+	eventsHandler.SetMeter(meter)
+	deadLettersHandler := handlers.NewDeadLettersHandler(pool, rulesEngine, logger)
+	eventSchemasHandler := handlers.NewEventSchemasHandler(pool)
+	eventTypesHandler := handlers.NewEventTypesHandler(pool)
+	locationsHandler := handlers.NewLocationsHandler(pool)
+	suppliersHandler := handlers.NewSuppliersHandler(pool, logger.Logger)
+	productsHandler := handlers.NewProductsHandler(pool)
+	receiptsHandler := handlers.NewReceiptsHandler(pool, receiptService, rulesEngine, logger)
+	surveysHandler := handlers.NewSurveysHandler(pool, surveyService)
+	challengesHandler := handlers.NewChallengesHandler(challengeService)
+	drawsHandler := handlers.NewDrawsHandler(drawService)
+	promoCodesHandler := handlers.NewPromoCodesHandler(promoService)
 	rulesHandler := handlers.NewRulesHandler(pool)
-	rewardsHandler := handlers.NewRewardsHandler(pool)
+	rulesHandler.SetApprovalService(approvalService)
+	rewardsHandler := handlers.NewRewardsHandler(pool, catalog)
+
+	// Uploaded files such as voucher code CSVs are kept, when a store is
+	// configured, so they can be audited and processed again
+	uploadStore, err := storage.NewStore(storage.Config{
+		Backend: os.Getenv("UPLOAD_STORAGE"),
+		Dir:     os.Getenv("UPLOAD_STORAGE_DIR"),
+		Bucket:  os.Getenv("UPLOAD_S3_BUCKET"),
+		S3: export.S3Config{
+			Endpoint:        os.Getenv("UPLOAD_S3_ENDPOINT"),
+			Region:          os.Getenv("UPLOAD_S3_REGION"),
+			AccessKeyID:     os.Getenv("UPLOAD_S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("UPLOAD_S3_SECRET_ACCESS_KEY"),
+			PathStyle:       os.Getenv("UPLOAD_S3_PATH_STYLE") == "true",
+		},
+	})
+	if err != nil {
+		logger.Error("invalid upload storage configuration, uploads will not be kept", "error", err)
+	}
+	uploadService := storage.NewService(queries, uploadStore)
+	rewardsHandler.SetUploadService(uploadService)
+	uploadsHandler := handlers.NewUploadsHandler(uploadService, rewardcatalog.NewService(queries, catalog))
+
 	issuancesHandler := handlers.NewIssuancesHandler(pool, logger.Logger)
-	budgetsHandler := handlers.NewBudgetsHandler(pool, logger.Logger)
-	campaignsHandler := handlers.NewCampaignsHandler(pool)
+	issuancesHandler.SetSurveyService(surveyService)
+	redemptionsHandler := handlers.NewRedemptionsHandler(pool, logger.Logger)
+	budgetsHandler := handlers.NewBudgetsHandler(pool, readPool, logger.Logger)
+	campaignsHandler := handlers.NewCampaignsHandler(pool, catalog)
+	campaignsHandler.SetApprovalService(approvalService)
+	tenantConfigHandler := handlers.NewTenantConfigHandler(pool, catalog)
+	tenantConfigHandler.SetApprovalService(approvalService)
+	leaderboardsHandler := handlers.NewLeaderboardsHandler(pool)
+	approvalsHandler := handlers.NewApprovalsHandler(pool, approvalService)
 	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
 
+	// Register budget alert channels (routing is configured per budget)
+	budgetsHandler.RegisterAlertNotifier(budget.AlertChannelWebhook, budget.NewWebhookAlertNotifier(queries))
+	rulesEngine.SetBudgetAlerter(budgetsHandler)
+	if phoneID, token := os.Getenv("WHATSAPP_PHONE_NUMBER_ID"), os.Getenv("WHATSAPP_ACCESS_TOKEN"); phoneID != "" && token != "" {
+		budgetsHandler.RegisterAlertNotifier(budget.AlertChannelWhatsApp,
+			budget.NewWhatsAppAlertNotifier(whatsapp.NewMessageSender(phoneID, token)))
+	}
+	if smtpHost := os.Getenv("SMTP_HOST"); smtpHost != "" {
+		smtpConfig := budget.SMTPConfig{
+			Host:     smtpHost,
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("ALERT_EMAIL_FROM"),
+		}
+		budgetsHandler.RegisterAlertNotifier(budget.AlertChannelEmail, budget.NewEmailAlertNotifier(smtpConfig))
+		approvalService.SetNotifier(approval.NewEmailNotifier(smtpConfig))
+	}
+
+	// Let in-flight budget alerts finish before exit
+	if err := workers.Register("budget-alerts", lifecycle.OnShutdown(budgetsHandler.WaitForAlerts)); err != nil {
+		logger.Error("failed to register budget alerts worker", "error", err)
+	}
+
+	if err := workers.Register("budget-period-close", budgetsHandler.RunPeriodCloseWorker); err != nil {
+		logger.Error("failed to register budget period close worker", "error", err)
+	}
+	if err := workers.Register("budget-alert-resolve", budgetsHandler.RunAlertResolveWorker); err != nil {
+		logger.Error("failed to register budget alert resolve worker", "error", err)
+	}
+	if err := workers.Register("approval-notifications", lifecycle.OnShutdown(approvalService.WaitForNotifications)); err != nil {
+		logger.Error("failed to register approval notifications worker", "error", err)
+	}
+
+	// Tenant webhooks subscribed to reward events
+	webhookService := webhooks.NewDeliveryService(pool, logger.Logger)
+	// Endpoint filters only read the event's data, so need no custom operators
+	webhookService.SetFilterEvaluator(rules.NewEvaluator(nil))
+	webhooksHandler := handlers.NewWebhooksHandler(pool, logger.Logger)
+	issuancesHandler.SetWebhookService(webhookService)
+	if err := workers.Register("webhook-delivery", func(ctx context.Context) error {
+		webhookService.StartWorkers()
+		<-ctx.Done()
+		webhookService.Stop()
+		return nil
+	}); err != nil {
+		logger.Error("failed to register webhook delivery worker", "error", err)
+	}
+
+	// Physical items are tracked from issue to delivery; undelivered items past
+	// their due date are escalated with a fulfilment.overdue webhook
+	fulfilmentService := fulfilment.NewService(pool, queries)
+	fulfilmentService.SetWebhookService(webhookService)
+	fulfilmentsHandler := handlers.NewFulfilmentsHandler(fulfilmentService, logger.Logger)
+	if err := workers.Register("fulfilment-escalation", func(ctx context.Context) error {
+		return fulfilmentService.RunEscalationWorker(ctx, time.Hour)
+	}); err != nil {
+		logger.Error("failed to register fulfilment escalation worker", "error", err)
+	}
+
+	// Daily analytics rollups are written to the primary and read from readPool
+	rollupWorker := analytics.NewRollupWorker(pool, logger.Logger)
+	if err := workers.Register("analytics-rollups", func(ctx context.Context) error {
+		return rollupWorker.Run(ctx, 15*time.Minute)
+	}); err != nil {
+		logger.Error("failed to register analytics rollups worker", "error", err)
+	}
+
+	// Campaign leaderboards are ranked in the background and served from cache
+	leaderboardWorker := leaderboard.NewWorker(pool, logger.Logger)
+	if err := workers.Register("leaderboards", func(ctx context.Context) error {
+		return leaderboardWorker.Run(ctx, 10*time.Minute)
+	}); err != nil {
+		logger.Error("failed to register leaderboard worker", "error", err)
+	}
+
+	// Draws with a draw_at are run once it passes
+	if err := workers.Register("prize-draws", func(ctx context.Context) error {
+		return drawService.RunScheduler(ctx, time.Minute)
+	}); err != nil {
+		logger.Error("failed to register prize draws worker", "error", err)
+	}
+
+	// Reservations older than a tenant's max reservation age are cancelled and
+	// their budget released; tenants opt in with `loyaltyctl set-reservation-age`
+	reservationService := reward.NewService(pool, queries)
+	if err := workers.Register("reservation-release", func(ctx context.Context) error {
+		return reservationService.RunReservationReleaseWorker(ctx, 15*time.Minute)
+	}); err != nil {
+		logger.Error("failed to register reservation release worker", "error", err)
+	}
+
+	// Reserved issuances are issued through their reward type's handler;
+	// transient handler failures are retried with backoff, as many times as
+	// ISSUANCE_RETRY_ATTEMPTS allows per reward type
+	retryAttempts, err := reward.ParseRetryAttempts(os.Getenv("ISSUANCE_RETRY_ATTEMPTS"))
+	if err != nil {
+		logger.Error("invalid ISSUANCE_RETRY_ATTEMPTS", "error", err)
+	}
+	for rewardType, attempts := range retryAttempts {
+		if err := reservationService.SetRetryAttempts(rewardType, attempts); err != nil {
+			logger.Error("invalid retry attempts", "reward_type", rewardType, "attempts", attempts, "error", err)
+		}
+	}
+	if err := workers.Register("issuance-processor", func(ctx context.Context) error {
+		return reservationService.RunIssuanceProcessor(ctx, 10*time.Second, reward.DefaultProcessWorkers)
+	}); err != nil {
+		logger.Error("failed to register issuance processor worker", "error", err)
+	}
+
+	// Data past a tenant's retention is purged daily; tenants opt in with
+	// `loyaltyctl set-retention`
+	retentionService := retention.NewService(pool, queries, logger)
+	retentionHandler := handlers.NewRetentionHandler(retentionService, logger)
+	if err := workers.Register("retention-purge", func(ctx context.Context) error {
+		return retentionService.Run(ctx, 24*time.Hour)
+	}); err != nil {
+		logger.Error("failed to register retention purge worker", "error", err)
+	}
+
+	// The change feed integrations poll is written by database triggers;
+	// changes past its retention are pruned daily
+	changeService := changes.NewService(pool, queries, logger.Logger)
+	changesHandler := handlers.NewChangesHandler(changeService)
+	if err := workers.Register("change-feed-prune", func(ctx context.Context) error {
+		return changeService.Run(ctx, 24*time.Hour)
+	}); err != nil {
+		logger.Error("failed to register change feed prune worker", "error", err)
+	}
+
+	// Nightly data exports need an S3-compatible object store; without one the
+	// exports API still lists earlier files
+	var exportUploader export.Uploader
+	if endpoint := os.Getenv("EXPORT_S3_ENDPOINT"); endpoint != "" {
+		uploader, err := export.NewS3Uploader(export.S3Config{
+			Endpoint:        endpoint,
+			Region:          os.Getenv("EXPORT_S3_REGION"),
+			AccessKeyID:     os.Getenv("EXPORT_S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("EXPORT_S3_SECRET_ACCESS_KEY"),
+			PathStyle:       os.Getenv("EXPORT_S3_PATH_STYLE") == "true",
+		})
+		if err != nil {
+			logger.Error("invalid export storage configuration, data exports disabled", "error", err)
+		} else {
+			exportUploader = uploader
+		}
+	}
+	exportService := export.NewService(pool, exportUploader, logger.Logger)
+	exportsHandler := handlers.NewExportsHandler(exportService)
+	usageHandler := handlers.NewUsageHandler(readPool)
+	featureFlagsHandler := handlers.NewFeatureFlagsHandler(featureFlags)
+	if exportUploader != nil {
+		if err := workers.Register("data-exports", func(ctx context.Context) error {
+			return exportService.Run(ctx, time.Hour)
+		}); err != nil {
+			logger.Error("failed to register data exports worker", "error", err)
+		}
+	}
+
+	// Domain events are published from the outbox when a message bus is
+	// configured; tenants opt in with `loyaltyctl set-event-bus`
+	publisher, err := eventbus.NewPublisher(os.Getenv("EVENT_BUS"), os.Getenv("EVENT_BUS_URL"))
+	if err != nil {
+		logger.Error("invalid event bus configuration, event publication disabled", "error", err)
+	}
+	if publisher != nil {
+		prefix := os.Getenv("EVENT_BUS_PREFIX")
+		if prefix == "" {
+			prefix = "loyalty"
+		}
+		relay := eventbus.NewRelay(pool, publisher, prefix, logger.Logger)
+		if err := workers.Register("event-bus-relay", func(ctx context.Context) error {
+			return relay.Run(ctx, 2*time.Second)
+		}); err != nil {
+			logger.Error("failed to register event bus relay worker", "error", err)
+		}
+	}
+
 	// Initialize channel handlers
 	waHandler := whatsapp.NewHandler(
 		pool,
+		catalog,
 		os.Getenv("WHATSAPP_VERIFY_TOKEN"),
 		os.Getenv("WHATSAPP_APP_SECRET"),
 		os.Getenv("WHATSAPP_PHONE_NUMBER_ID"),
 		os.Getenv("WHATSAPP_ACCESS_TOKEN"),
 	)
-	ussdHandler := ussd.NewHandler(pool)
+	waHandler.SetReceiptService(receiptService)
+	waHandler.SetSurveyService(surveyService)
+	waHandler.SetPromoService(promoService)
+	waHandler.SetFulfilmentService(fulfilmentService)
+	waHandler.SetWebhookService(webhookService)
+	waHandler.SetMeter(meter)
+	waHandler.SetFeatureFlags(featureFlags)
+	outboundMessagesHandler := handlers.NewOutboundMessagesHandler(waHandler.Queue())
+	if os.Getenv("WHATSAPP_ACCESS_TOKEN") != "" {
+		if err := workers.Register("whatsapp-outbound", func(ctx context.Context) error {
+			return waHandler.Queue().Run(ctx, 10*time.Second)
+		}); err != nil {
+			logger.Error("failed to register WhatsApp outbound worker", "error", err)
+		}
+	}
+
+	// Surveys are answered over WhatsApp, so they need a configured sender
+	if os.Getenv("WHATSAPP_ACCESS_TOKEN") != "" {
+		surveyService.SetDeliverer(waHandler)
+	}
+	if err := workers.Register("survey-triggers", lifecycle.OnShutdown(surveyService.WaitForTriggers)); err != nil {
+		logger.Error("failed to register survey triggers worker", "error", err)
+	}
+	// Redemptions made at a till or through the API are confirmed to the
+	// customer with the reward_redeemed template, and reissued rewards sent
+	// with the reward_issued template
+	if os.Getenv("WHATSAPP_ACCESS_TOKEN") != "" {
+		issuancesHandler.SetTemplateSender(waHandler.Sender())
+	}
+	if err := workers.Register("issuance-notifications", lifecycle.OnShutdown(issuancesHandler.WaitForNotifications)); err != nil {
+		logger.Error("failed to register issuance notifications worker", "error", err)
+	}
+	// Customers inactive past a tenant's dormancy period get a customer_dormant
+	// event and, with WhatsApp configured, the tenant's win-back template;
+	// tenants opt in with `loyaltyctl set-winback`
+	winbackService := winback.NewService(pool, queries, rulesEngine, logger.Logger)
+	if os.Getenv("WHATSAPP_ACCESS_TOKEN") != "" {
+		winbackService.SetSender(waHandler.Sender())
+	}
+	if err := workers.Register("winback", func(ctx context.Context) error {
+		return winbackService.Run(ctx, time.Hour)
+	}); err != nil {
+		logger.Error("failed to register win-back worker", "error", err)
+	}
+	// New customers of tenants with the welcome series on are sent its
+	// templates as they come due; tenants opt in with
+	// `loyaltyctl set-welcome-series`
+	welcomeService := welcome.NewService(pool, queries, logger.Logger)
+	if os.Getenv("WHATSAPP_ACCESS_TOKEN") != "" {
+		welcomeService.SetSender(waHandler.Sender())
+	}
+	if err := workers.Register("welcome-series", func(ctx context.Context) error {
+		return welcomeService.Run(ctx, 5*time.Minute)
+	}); err != nil {
+		logger.Error("failed to register welcome series worker", "error", err)
+	}
+	ussdHandler := ussd.NewHandler(pool, catalog)
+	ussdHandler.SetMeter(meter)
+	ussdHandler.SetWebhookService(webhookService)
+	ussdHandler.SetPromoService(promoService)
+	ussdHandler.SetFeatureFlags(featureFlags)
+	ussdHandler.SetFunnelRecorder(analytics.NewFunnelRecorder(queries, logger.Logger))
+
+	// Customer portal sign-in codes are sent over the configured channels
+	portalService := portal.NewService(pool, queries, keyring, jwtSecret)
+	if os.Getenv("WHATSAPP_ACCESS_TOKEN") != "" {
+		portalService.RegisterChannel(waHandler.Sender())
+	}
+	portalRewards := reward.NewService(pool, queries)
+	portalRewards.SetWebhookService(webhookService)
+	portalHandler := handlers.NewPortalHandler(pool, catalog, portalService, portalRewards)
+
+	// Wallet passes need an Apple pass type certificate or a Google Wallet
+	// issuer account; either can be configured on its own
+	var appleSigner *wallet.AppleSigner
+	if passTypeID := os.Getenv("APPLE_WALLET_PASS_TYPE_ID"); passTypeID != "" {
+		signer, err := wallet.NewAppleSigner(wallet.AppleConfig{
+			PassTypeID:    passTypeID,
+			TeamID:        os.Getenv("APPLE_WALLET_TEAM_ID"),
+			CertFile:      os.Getenv("APPLE_WALLET_CERT_FILE"),
+			KeyFile:       os.Getenv("APPLE_WALLET_KEY_FILE"),
+			WWDRFile:      os.Getenv("APPLE_WALLET_WWDR_FILE"),
+			WebServiceURL: os.Getenv("APPLE_WALLET_WEB_SERVICE_URL"),
+		}, jwtSecret)
+		if err != nil {
+			logger.Error("invalid Apple Wallet configuration, Apple Wallet passes disabled", "error", err)
+		} else {
+			appleSigner = signer
+		}
+	}
+	var googleIssuer *wallet.GoogleIssuer
+	if issuerID := os.Getenv("GOOGLE_WALLET_ISSUER_ID"); issuerID != "" {
+		issuer, err := wallet.NewGoogleIssuer(wallet.GoogleConfig{
+			IssuerID:           issuerID,
+			ServiceAccountFile: os.Getenv("GOOGLE_WALLET_SERVICE_ACCOUNT_FILE"),
+		})
+		if err != nil {
+			logger.Error("invalid Google Wallet configuration, Google Wallet passes disabled", "error", err)
+		} else {
+			googleIssuer = issuer
+		}
+	}
+	walletPassesHandler := handlers.NewWalletPassesHandler(wallet.NewService(queries, catalog), appleSigner, googleIssuer)
 
 	// Public routes (no authentication)
 	public := r.Group("/public")
@@ -78,6 +469,9 @@ func SetupRouter(pool *pgxpool.Pool, jwtSecret string, hmacKeys auth.HMACKeys) *
 
 		// USSD callback endpoint
 		public.POST("/ussd/callback", ussdHandler.HandleCallback)
+
+		// Apple Wallet web service, where wallets fetch updated passes
+		public.GET("/wallet/v1/passes/:passTypeId/:serial", walletPassesHandler.LatestApplePass)
 	}
 
 	// V1 API routes
@@ -88,11 +482,43 @@ func SetupRouter(pool *pgxpool.Pool, jwtSecret string, hmacKeys auth.HMACKeys) *
 	{
 		auth.POST("/login", authHandler.Login)
 		auth.POST("/refresh", authHandler.Refresh)
-		auth.GET("/me", middleware.RequireAuth(jwtSecret), authHandler.Me)
+		auth.GET("/me", middleware.RequireAuth(keyring), authHandler.Me)
+	}
+
+	// Customer portal routes, authenticated with customer tokens rather than
+	// staff tokens
+	portalRoutes := v1.Group("/portal")
+	{
+		signIn := portalRoutes.Group("/tenants/:tid", middleware.RateLimit(10, time.Minute))
+		{
+			signIn.POST("/otp", portalHandler.RequestCode)
+			signIn.POST("/token", portalHandler.VerifyCode)
+		}
+
+		me := portalRoutes.Group("/me", middleware.RequireCustomerAuth(keyring), middleware.AuditImpersonation(portalService))
+		{
+			me.GET("", portalHandler.Me)
+			me.GET("/rewards", portalHandler.Rewards)
+			me.GET("/points", portalHandler.Points)
+			me.POST("/redemptions", portalHandler.Redeem)
+			me.POST("/promo-codes", promoCodesHandler.PortalRedeem)
+			me.GET("/rewards/:id/wallet-pass", walletPassesHandler.CustomerGet)
+		}
+	}
+
+	// Platform operator routes, across all tenants. They are only served
+	// when PLATFORM_ADMIN_TOKEN is set.
+	if platformToken := os.Getenv("PLATFORM_ADMIN_TOKEN"); platformToken != "" {
+		platformHandler := handlers.NewPlatformHandler(readPool, keyring, reloadSecrets, logger.Logger)
+		platform := v1.Group("/platform", middleware.RequirePlatformToken(platformToken))
+		{
+			platform.GET("/tenant-health", platformHandler.TenantHealth)
+			platform.POST("/secrets/reload", platformHandler.ReloadSecrets)
+		}
 	}
 
 	// Apply authentication middleware for all other v1 routes
-	v1.Use(middleware.RequireAuth(jwtSecret))
+	v1.Use(middleware.RequireAuth(keyring))
 	// TenantContext middleware disabled - handlers use tenant ID from URL path parameter
 	// The middleware was trying to set PostgreSQL session variable which isn't configured
 	// v1.Use(middleware.TenantContext(pool))
@@ -105,9 +531,20 @@ func SetupRouter(pool *pgxpool.Pool, jwtSecret string, hmacKeys auth.HMACKeys) *
 		customers := tenants.Group("/customers")
 		{
 			customers.POST("", customersHandler.Create)
+			customers.PUT("/upsert", customersHandler.Upsert)
 			customers.GET("", customersHandler.List)
+			customers.GET("/search", customersHandler.Search)
 			customers.GET("/:id", customersHandler.Get)
+			customers.GET("/:id/activity", customersHandler.Activity)
+			customers.GET("/:id/challenges", challengesHandler.CustomerProgress)
+			customers.GET("/:id/redemption-receipts", issuancesHandler.RedemptionReceipts)
+			customers.POST("/:id/notes", middleware.RequireRole("owner", "admin", "staff"), notesHandler.CreateForCustomer)
+			customers.GET("/:id/notes", notesHandler.ListForCustomer)
+			customers.POST("/:id/promo-codes", middleware.RequireRole("owner", "admin", "staff"), promoCodesHandler.RedeemForCustomer)
 			customers.PATCH("/:id/status", customersHandler.UpdateStatus)
+			customers.POST("/:id/impersonations", middleware.RequireRole("owner", "admin"), portalHandler.Impersonate)
+			customers.GET("/:id/impersonations", middleware.RequireRole("owner", "admin"), portalHandler.ListImpersonations)
+			customers.DELETE("/:id/impersonations/:iid", middleware.RequireRole("owner", "admin"), portalHandler.EndImpersonation)
 		}
 
 		// Events API
@@ -115,7 +552,145 @@ func SetupRouter(pool *pgxpool.Pool, jwtSecret string, hmacKeys auth.HMACKeys) *
 		{
 			events.POST("", eventsHandler.Create) // Requires Idempotency-Key
 			events.GET("", eventsHandler.List)
+			events.GET("/duplicates", middleware.RequireRole("owner", "admin"), eventsHandler.Duplicates)
 			events.GET("/:id", eventsHandler.Get)
+			events.GET("/:id/reversal", eventsHandler.GetReversal)
+		}
+
+		// Dead Letters API (failed rule processing)
+		deadLetters := tenants.Group("/dead-letters")
+		{
+			deadLetters.GET("", deadLettersHandler.List)
+			deadLetters.GET("/:id", deadLettersHandler.Get)
+			deadLetters.POST("/:id/retry", middleware.RequireRole("owner", "admin"), deadLettersHandler.Retry)
+		}
+
+		// Outbound Messages API (queued WhatsApp sends)
+		outboundMessages := tenants.Group("/outbound-messages")
+		{
+			outboundMessages.GET("", outboundMessagesHandler.List)
+			outboundMessages.GET("/:id", outboundMessagesHandler.Get)
+			outboundMessages.POST("/:id/retry", middleware.RequireRole("owner", "admin"), outboundMessagesHandler.Retry)
+		}
+
+		// Event Types API
+		eventTypes := tenants.Group("/event-types")
+		{
+			eventTypes.POST("", middleware.RequireRole("owner", "admin"), eventTypesHandler.Create)
+			eventTypes.GET("", eventTypesHandler.List)
+			eventTypes.GET("/:name", eventTypesHandler.Get)
+			eventTypes.PATCH("/:name", middleware.RequireRole("owner", "admin"), eventTypesHandler.Update)
+			eventTypes.DELETE("/:name", middleware.RequireRole("owner", "admin"), eventTypesHandler.Delete)
+		}
+
+		// Locations API
+		locations := tenants.Group("/locations")
+		{
+			locations.POST("", middleware.RequireRole("owner", "admin"), locationsHandler.Create)
+			locations.GET("", locationsHandler.List)
+			locations.GET("/:id", locationsHandler.Get)
+			locations.PATCH("/:id", middleware.RequireRole("owner", "admin"), locationsHandler.Update)
+			locations.DELETE("/:id", middleware.RequireRole("owner", "admin"), locationsHandler.Delete)
+		}
+
+		// Suppliers API
+		suppliers := tenants.Group("/suppliers")
+		{
+			suppliers.POST("", middleware.RequireRole("owner", "admin"), suppliersHandler.Create)
+			suppliers.GET("", suppliersHandler.List)
+			suppliers.GET("/performance", suppliersHandler.Performance)
+			suppliers.GET("/:id", suppliersHandler.Get)
+			suppliers.PATCH("/:id", middleware.RequireRole("owner", "admin"), suppliersHandler.Update)
+			suppliers.DELETE("/:id", middleware.RequireRole("owner", "admin"), suppliersHandler.Delete)
+		}
+
+		// Webhooks API
+		webhookEndpoints := tenants.Group("/webhooks")
+		{
+			webhookEndpoints.POST("", middleware.RequireRole("owner", "admin"), webhooksHandler.Create)
+			webhookEndpoints.GET("", webhooksHandler.List)
+			webhookEndpoints.GET("/:id", webhooksHandler.Get)
+			webhookEndpoints.PATCH("/:id", middleware.RequireRole("owner", "admin"), webhooksHandler.Update)
+			webhookEndpoints.DELETE("/:id", middleware.RequireRole("owner", "admin"), webhooksHandler.Delete)
+		}
+
+		// Products API
+		products := tenants.Group("/products")
+		{
+			products.POST("/bulk", middleware.RequireRole("owner", "admin"), productsHandler.Upload)
+			products.GET("", productsHandler.List)
+			products.GET("/:sku", productsHandler.Get)
+		}
+
+		// Receipts API
+		receipts := tenants.Group("/receipts")
+		{
+			receipts.POST("", receiptsHandler.Submit)
+			receipts.GET("", receiptsHandler.List)
+			receipts.GET("/:id", receiptsHandler.Get)
+			receipts.GET("/:id/image", receiptsHandler.Image)
+			receipts.POST("/:id/approve", middleware.RequireRole("owner", "admin", "staff"), receiptsHandler.Approve)
+			receipts.POST("/:id/reject", middleware.RequireRole("owner", "admin", "staff"), receiptsHandler.Reject)
+		}
+
+		// Surveys API
+		surveys := tenants.Group("/surveys")
+		{
+			surveys.POST("", middleware.RequireRole("owner", "admin"), surveysHandler.Create)
+			surveys.GET("", surveysHandler.List)
+			surveys.GET("/:id", surveysHandler.Get)
+			surveys.PATCH("/:id", middleware.RequireRole("owner", "admin"), surveysHandler.Update)
+			surveys.GET("/:id/results", surveysHandler.Results)
+			surveys.POST("/:id/send", middleware.RequireRole("owner", "admin", "staff"), surveysHandler.Send)
+		}
+
+		// Challenges API
+		challenges := tenants.Group("/challenges")
+		{
+			challenges.POST("", middleware.RequireRole("owner", "admin"), challengesHandler.Create)
+			challenges.GET("", challengesHandler.List)
+			challenges.GET("/:id", challengesHandler.Get)
+			challenges.PATCH("/:id", middleware.RequireRole("owner", "admin"), challengesHandler.Update)
+		}
+
+		// Prize draws API
+		draws := tenants.Group("/draws")
+		{
+			draws.POST("", middleware.RequireRole("owner", "admin"), drawsHandler.Create)
+			draws.GET("", drawsHandler.List)
+			draws.GET("/:id", drawsHandler.Get)
+			draws.POST("/:id/cancel", middleware.RequireRole("owner", "admin"), drawsHandler.Cancel)
+			draws.POST("/:id/draw", middleware.RequireRole("owner", "admin"), drawsHandler.Run)
+			draws.GET("/:id/winners", drawsHandler.Winners)
+		}
+
+		// Promo codes API
+		promoBatches := tenants.Group("/promo-batches")
+		{
+			promoBatches.POST("", middleware.RequireRole("owner", "admin"), promoCodesHandler.CreateBatch)
+			promoBatches.GET("", promoCodesHandler.ListBatches)
+			promoBatches.GET("/:id", promoCodesHandler.GetBatch)
+			promoBatches.PATCH("/:id", middleware.RequireRole("owner", "admin"), promoCodesHandler.UpdateBatch)
+			promoBatches.GET("/:id/codes", promoCodesHandler.ListCodes)
+		}
+
+		// Approvals API (maker-checker for campaigns and rules)
+		approvals := tenants.Group("/approvals")
+		{
+			approvals.POST("", middleware.RequireRole("owner", "admin", "manager"), approvalsHandler.Submit)
+			approvals.GET("", approvalsHandler.List)
+			approvals.GET("/:id", approvalsHandler.Get)
+			approvals.POST("/:id/approve", middleware.RequireRole("owner", "manager"), approvalsHandler.Approve)
+			approvals.POST("/:id/reject", middleware.RequireRole("owner", "manager"), approvalsHandler.Reject)
+		}
+
+		// Event Schema Registry API
+		eventSchemas := tenants.Group("/event-schemas")
+		{
+			eventSchemas.GET("", eventSchemasHandler.List)
+			eventSchemas.GET("/:event_type", eventSchemasHandler.Get)
+			eventSchemas.PUT("/:event_type", middleware.RequireRole("owner", "admin"), eventSchemasHandler.Put)
+			eventSchemas.DELETE("/:event_type", middleware.RequireRole("owner", "admin"), eventSchemasHandler.Delete)
 		}
 
 		// Rules API
@@ -123,9 +698,13 @@ func SetupRouter(pool *pgxpool.Pool, jwtSecret string, hmacKeys auth.HMACKeys) *
 		{
 			rules.POST("", middleware.RequireRole("owner", "admin"), rulesHandler.Create)
 			rules.GET("", rulesHandler.List)
+			rules.GET("/export", rulesHandler.Export)
+			rules.POST("/import", middleware.RequireRole("owner", "admin"), rulesHandler.Import)
 			rules.GET("/:id", rulesHandler.Get)
 			rules.PATCH("/:id", middleware.RequireRole("owner", "admin"), rulesHandler.Update)
 			rules.DELETE("/:id", middleware.RequireRole("owner", "admin"), rulesHandler.Delete)
+			rules.POST("/:id/restore", middleware.RequireRole("owner", "admin"), rulesHandler.Restore)
+			rules.POST("/:id/backtest", rulesHandler.Backtest)
 		}
 
 		// Rewards Catalog API
@@ -135,6 +714,8 @@ func SetupRouter(pool *pgxpool.Pool, jwtSecret string, hmacKeys auth.HMACKeys) *
 			rewards.GET("", rewardsHandler.List)
 			rewards.GET("/:id", rewardsHandler.Get)
 			rewards.PATCH("/:id", middleware.RequireRole("owner", "admin"), rewardsHandler.Update)
+			rewards.DELETE("/:id", middleware.RequireRole("owner", "admin"), rewardsHandler.Delete)
+			rewards.POST("/:id/restore", middleware.RequireRole("owner", "admin"), rewardsHandler.Restore)
 			rewards.POST("/:id/upload-codes", middleware.RequireRole("owner", "admin"), rewardsHandler.UploadCodes)
 		}
 
@@ -142,11 +723,32 @@ func SetupRouter(pool *pgxpool.Pool, jwtSecret string, hmacKeys auth.HMACKeys) *
 		issuances := tenants.Group("/issuances")
 		{
 			issuances.GET("", issuancesHandler.List)
+			issuances.GET("/by-code/:code", issuancesHandler.ByCode)
+			issuances.GET("/failed", issuancesHandler.Failed)
 			issuances.GET("/:id", issuancesHandler.Get)
+			issuances.GET("/:id/history", issuancesHandler.History)
+			issuances.POST("/:id/notes", middleware.RequireRole("owner", "admin", "staff"), notesHandler.CreateForIssuance)
+			issuances.GET("/:id/notes", notesHandler.ListForIssuance)
+			issuances.GET("/:id/wallet-pass", walletPassesHandler.Get)
+			issuances.PUT("/:id/wallet-pass", middleware.RequireRole("owner", "admin", "staff"), walletPassesHandler.Update)
 			issuances.POST("/:id/redeem", issuancesHandler.Redeem)
 			issuances.POST("/:id/cancel", middleware.RequireRole("owner", "admin", "staff"), issuancesHandler.Cancel)
+			issuances.POST("/:id/clawback", middleware.RequireRole("owner", "admin"), issuancesHandler.Clawback)
+			issuances.POST("/:id/retry", middleware.RequireRole("owner", "admin", "staff"), issuancesHandler.Retry)
+			issuances.POST("/:id/reissue", middleware.RequireRole("owner", "admin", "staff"), issuancesHandler.Reissue)
+		}
+
+		// Fulfilments API
+		fulfilments := tenants.Group("/fulfilments")
+		{
+			fulfilments.GET("", fulfilmentsHandler.List)
+			fulfilments.GET("/:id", fulfilmentsHandler.Get)
+			fulfilments.POST("/:id/status", middleware.RequireRole("owner", "admin", "staff"), fulfilmentsHandler.UpdateStatus)
 		}
 
+		// Redemptions API (offline POS batch sync)
+		tenants.POST("/redemptions/import", middleware.RequireRole("owner", "admin", "staff"), redemptionsHandler.Import)
+
 		// Budgets API
 		budgets := tenants.Group("/budgets")
 		{
@@ -154,24 +756,103 @@ func SetupRouter(pool *pgxpool.Pool, jwtSecret string, hmacKeys auth.HMACKeys) *
 			budgets.GET("", budgetsHandler.List)
 			budgets.GET("/:id", budgetsHandler.Get)
 			budgets.POST("/:id/topup", middleware.RequireRole("owner", "admin"), budgetsHandler.Topup)
+			budgets.PUT("/:id/alerts", middleware.RequireRole("owner", "admin"), budgetsHandler.UpdateAlerts)
+			budgets.PUT("/:id/overdraft", middleware.RequireRole("owner", "admin"), budgetsHandler.UpdateOverdraft)
+			budgets.POST("/:id/statements", middleware.RequireRole("owner", "admin"), budgetsHandler.CloseStatement)
+			budgets.GET("/:id/statements", budgetsHandler.ListStatements)
+			budgets.GET("/:id/statements/:sid", budgetsHandler.GetStatement)
+			budgets.GET("/:id/spend-breakdown", budgetsHandler.SpendBreakdown)
+			budgets.GET("/:id/ledger/verify", budgetsHandler.VerifyLedger)
+			budgets.POST("/:id/commitments", middleware.RequireRole("owner", "admin"), budgetsHandler.CreateCommitment)
+			budgets.GET("/:id/commitments", budgetsHandler.ListCommitments)
+			budgets.POST("/:id/commitments/:cid/release", middleware.RequireRole("owner", "admin"), budgetsHandler.ReleaseCommitment)
+		}
+
+		// Budget Alerts API (alert history and acknowledgement)
+		budgetAlerts := tenants.Group("/budget-alerts")
+		{
+			budgetAlerts.GET("", budgetsHandler.ListAlerts)
+			budgetAlerts.POST("/:id/acknowledge", middleware.RequireRole("owner", "admin", "staff"), budgetsHandler.AcknowledgeAlert)
 		}
 
 		// Ledger API
 		tenants.GET("/ledger", budgetsHandler.ListLedger)
+		tenants.GET("/liabilities", budgetsHandler.Liabilities)
 
 		// Campaigns API
 		campaigns := tenants.Group("/campaigns")
 		{
 			campaigns.POST("", middleware.RequireRole("owner", "admin"), campaignsHandler.Create)
 			campaigns.GET("", campaignsHandler.List)
+			campaigns.GET("/templates", campaignsHandler.Templates)
+			campaigns.GET("/budget-reallocations", campaignsHandler.BudgetReallocations)
+			campaigns.POST("/from-template", middleware.RequireRole("owner", "admin"), campaignsHandler.FromTemplate)
 			campaigns.GET("/:id", campaignsHandler.Get)
 			campaigns.PATCH("/:id", middleware.RequireRole("owner", "admin"), campaignsHandler.Update)
+			campaigns.DELETE("/:id", middleware.RequireRole("owner", "admin"), campaignsHandler.Delete)
+			campaigns.POST("/:id/restore", middleware.RequireRole("owner", "admin"), campaignsHandler.Restore)
+			campaigns.POST("/:id/clone", middleware.RequireRole("owner", "admin"), campaignsHandler.Clone)
+			campaigns.GET("/:id/budget-estimate", campaignsHandler.BudgetEstimate)
+			campaigns.GET("/:id/fallback-budgets", campaignsHandler.FallbackBudgets)
+			campaigns.PUT("/:id/fallback-budgets", middleware.RequireRole("owner", "admin"), campaignsHandler.SetFallbackBudgets)
+			campaigns.PUT("/:id/pacing", middleware.RequireRole("owner", "admin"), campaignsHandler.SetPacing)
+			campaigns.GET("/:id/stats", campaignsHandler.Stats)
+			campaigns.GET("/:id/report.html", campaignsHandler.Report)
+			campaigns.GET("/:id/exclusions", campaignsHandler.ListExclusions)
+			campaigns.POST("/:id/exclusions", middleware.RequireRole("owner", "admin"), campaignsHandler.UploadExclusions)
+			campaigns.DELETE("/:id/exclusions/:cid", middleware.RequireRole("owner", "admin"), campaignsHandler.RemoveExclusion)
+			campaigns.GET("/:id/leaderboard", leaderboardsHandler.List)
+			campaigns.PUT("/:id/leaderboard", middleware.RequireRole("owner", "admin"), leaderboardsHandler.Set)
+			campaigns.GET("/:id/leaderboard/customers/:cid", leaderboardsHandler.CustomerRank)
 		}
 
 		// Analytics API
 		analytics := tenants.Group("/analytics")
 		{
 			analytics.GET("/dashboard", analyticsHandler.GetDashboardStats)
+			analytics.GET("/timeseries", analyticsHandler.GetTimeseries)
+			analytics.GET("/channel-funnel", analyticsHandler.GetChannelFunnel)
+		}
+
+		// Change feed API
+		tenants.GET("/changes", changesHandler.List)
+
+		// Data exports API
+		exports := tenants.Group("/exports")
+		{
+			exports.GET("", exportsHandler.List)
+			exports.GET("/:id", exportsHandler.Get)
+		}
+
+		// Uploaded files API
+		uploads := tenants.Group("/uploads")
+		{
+			uploads.GET("", middleware.RequireRole("owner", "admin"), uploadsHandler.List)
+			uploads.GET("/:id", middleware.RequireRole("owner", "admin"), uploadsHandler.Get)
+			uploads.GET("/:id/content", middleware.RequireRole("owner", "admin"), uploadsHandler.Content)
+			uploads.POST("/:id/reprocess", middleware.RequireRole("owner", "admin"), uploadsHandler.Reprocess)
+		}
+
+		// Data retention API
+		retentionPolicy := tenants.Group("/retention")
+		{
+			retentionPolicy.GET("", middleware.RequireRole("owner", "admin"), retentionHandler.GetPolicy)
+			retentionPolicy.GET("/purges", middleware.RequireRole("owner", "admin"), retentionHandler.ListPurges)
+			retentionPolicy.POST("/purges", middleware.RequireRole("owner"), retentionHandler.Purge)
+		}
+
+		// Declarative configuration API
+		tenants.POST("/config/apply", middleware.RequireRole("owner", "admin"), tenantConfigHandler.Apply)
+
+		// Billable usage API
+		tenants.GET("/usage", middleware.RequireRole("owner", "admin"), usageHandler.Get)
+
+		// Feature flags API
+		featureFlagsGroup := tenants.Group("/feature-flags")
+		{
+			featureFlagsGroup.GET("", middleware.RequireRole("owner", "admin"), featureFlagsHandler.List)
+			featureFlagsGroup.PUT("/:channel/:feature", middleware.RequireRole("owner", "admin"), featureFlagsHandler.Set)
+			featureFlagsGroup.DELETE("/:channel/:feature", middleware.RequireRole("owner", "admin"), featureFlagsHandler.Reset)
 		}
 	}
 
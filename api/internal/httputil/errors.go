@@ -15,21 +15,19 @@ const (
 	ErrCodeRateLimited      = "rate_limited"
 	ErrCodeInternalError    = "internal_error"
 	ErrCodeValidationFailed = "validation_failed"
+	ErrCodePayloadTooLarge  = "payload_too_large"
+	ErrCodeLocationDenied   = "location_not_allowed"
 )
 
-// ErrorResponse represents a standardized error response
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Code    string `json:"code"`
-	Details any    `json:"details,omitempty"`
-}
-
-// RespondError sends a standardized error response
+// RespondError sends an error wrapped in the response envelope
 func RespondError(c *gin.Context, status int, code, message string, details any) {
-	c.JSON(status, ErrorResponse{
-		Error:   message,
-		Code:    code,
-		Details: details,
+	c.JSON(status, Envelope{
+		Meta: newMeta(c),
+		Error: &ErrorBody{
+			Code:    code,
+			Message: message,
+			Details: details,
+		},
 	})
 }
 
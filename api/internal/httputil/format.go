@@ -2,6 +2,8 @@ package httputil
 
 import (
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
@@ -20,3 +22,49 @@ func FormatTimestamp(ts pgtype.Timestamptz) string {
 	}
 	return ts.Time.Format(time.RFC3339)
 }
+
+// FormatNumeric converts pgtype.Numeric to a decimal string such as "10.00",
+// applying the exponent that Numeric.Int alone leaves out. Invalid (NULL)
+// values format as "".
+func FormatNumeric(n pgtype.Numeric) string {
+	if !n.Valid {
+		return ""
+	}
+	if n.NaN {
+		return "NaN"
+	}
+	switch n.InfinityModifier {
+	case pgtype.Infinity:
+		return "Infinity"
+	case pgtype.NegativeInfinity:
+		return "-Infinity"
+	}
+	if n.Int == nil {
+		return "0"
+	}
+
+	digits := n.Int.String()
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+
+	if n.Exp >= 0 {
+		if digits == "0" {
+			return "0"
+		}
+		return sign + digits + strings.Repeat("0", int(n.Exp))
+	}
+
+	scale := int(-n.Exp)
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	point := len(digits) - scale
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// RoundCents rounds an amount to two decimal places
+func RoundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,57 @@
+package httputil
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatNumeric(t *testing.T) {
+	scanned := func(s string) pgtype.Numeric {
+		var n pgtype.Numeric
+		require.NoError(t, n.Scan(s))
+		return n
+	}
+
+	tests := []struct {
+		name string
+		in   pgtype.Numeric
+		want string
+	}{
+		// Int alone is 1000 here; the exponent must be applied
+		{"scaled", scanned("10.00"), "10.00"},
+		{"fraction", scanned("0.05"), "0.05"},
+		{"negative", scanned("-12.50"), "-12.50"},
+		{"negative fraction", scanned("-0.50"), "-0.50"},
+		{"integer", scanned("250"), "250"},
+		{"positive exponent", pgtype.Numeric{Int: big.NewInt(15), Exp: 2, Valid: true}, "1500"},
+		{"zero", pgtype.Numeric{Int: big.NewInt(0), Exp: 0, Valid: true}, "0"},
+		{"null", pgtype.Numeric{}, ""},
+		{"nan", pgtype.Numeric{NaN: true, Valid: true}, "NaN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FormatNumeric(tt.in))
+		})
+	}
+}
+
+func TestRoundCents(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   float64
+	}{
+		{9.999, 10},
+		{1.234, 1.23},
+		{1.235, 1.24},
+		{0, 0},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, RoundCents(tt.amount), "RoundCents(%v)", tt.amount)
+	}
+}
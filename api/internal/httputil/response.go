@@ -0,0 +1,71 @@
+package httputil
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDKey is the gin context key the request ID middleware stores the ID under
+const RequestIDKey = "request_id"
+
+// Envelope is the shape of every API response. Successful responses carry
+// data; failed responses carry error. Meta is always present so clients can
+// quote the request ID when reporting problems.
+type Envelope struct {
+	Data  any        `json:"data,omitempty"`
+	Meta  Meta       `json:"meta"`
+	Error *ErrorBody `json:"error,omitempty"`
+}
+
+// Meta holds response metadata
+type Meta struct {
+	RequestID string `json:"request_id,omitempty"`
+	*Page
+}
+
+// Page describes a page of a list response
+type Page struct {
+	Total  int64 `json:"total"`
+	Limit  int   `json:"limit,omitempty"`
+	Offset int   `json:"offset,omitempty"`
+}
+
+// NewPage builds a Page from the raw limit and offset query values. Values that
+// don't parse are left out of the response.
+func NewPage(total int64, limit, offset string) Page {
+	page := Page{Total: total}
+	page.Limit, _ = strconv.Atoi(limit)
+	page.Offset, _ = strconv.Atoi(offset)
+	return page
+}
+
+// ErrorBody describes why a request failed
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+// Respond sends data wrapped in the response envelope
+func Respond(c *gin.Context, status int, data any) {
+	c.JSON(status, Envelope{
+		Data: data,
+		Meta: newMeta(c),
+	})
+}
+
+// RespondList sends a page of results wrapped in the response envelope
+func RespondList(c *gin.Context, data any, page Page) {
+	meta := newMeta(c)
+	meta.Page = &page
+	c.JSON(200, Envelope{
+		Data: data,
+		Meta: meta,
+	})
+}
+
+// newMeta builds the metadata common to all responses
+func newMeta(c *gin.Context) Meta {
+	return Meta{RequestID: c.GetString(RequestIDKey)}
+}
@@ -0,0 +1,67 @@
+package httputil
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestContext() (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(RequestIDKey, "req-123")
+	return c, w
+}
+
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body
+}
+
+func TestRespond(t *testing.T) {
+	c, w := newTestContext()
+
+	Respond(c, 201, gin.H{"id": "abc"})
+
+	assert.Equal(t, 201, w.Code)
+	body := decodeBody(t, w)
+	assert.Equal(t, map[string]any{"id": "abc"}, body["data"])
+	assert.Equal(t, map[string]any{"request_id": "req-123"}, body["meta"])
+	assert.NotContains(t, body, "error")
+}
+
+func TestRespondList(t *testing.T) {
+	c, w := newTestContext()
+
+	RespondList(c, []string{}, NewPage(12, "10", "bad"))
+
+	assert.Equal(t, 200, w.Code)
+	body := decodeBody(t, w)
+	assert.Equal(t, []any{}, body["data"])
+	assert.Equal(t, map[string]any{
+		"request_id": "req-123",
+		"total":      float64(12),
+		"limit":      float64(10),
+	}, body["meta"])
+}
+
+func TestRespondError(t *testing.T) {
+	c, w := newTestContext()
+
+	NotFound(c, "Customer not found")
+
+	assert.Equal(t, 404, w.Code)
+	body := decodeBody(t, w)
+	assert.NotContains(t, body, "data")
+	assert.Equal(t, map[string]any{"request_id": "req-123"}, body["meta"])
+	assert.Equal(t, map[string]any{
+		"code":    ErrCodeNotFound,
+		"message": "Customer not found",
+	}, body["error"])
+}
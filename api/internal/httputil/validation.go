@@ -2,32 +2,29 @@ package httputil
 
 import (
 	"errors"
-	"regexp"
-	"strings"
+	"sort"
 
+	"github.com/bmachimbira/loyalty/api/internal/currency"
 	"github.com/google/uuid"
 )
 
 var (
-	// E.164 phone number regex (with optional + prefix)
-	e164Regex = regexp.MustCompile(`^\+?[1-9]\d{1,14}$`)
-
-	// Valid currencies
-	validCurrencies = map[string]bool{
-		"ZWG": true,
-		"USD": true,
-	}
-
 	// Valid event types
 	validEventTypes = map[string]bool{
-		"purchase":       true,
-		"visit":          true,
-		"referral":       true,
-		"signup":         true,
-		"review":         true,
-		"share":          true,
-		"app_open":       true,
-		"custom":         true,
+		"purchase":            true,
+		"visit":               true,
+		"referral":            true,
+		"signup":              true,
+		"review":              true,
+		"share":               true,
+		"app_open":            true,
+		"custom":              true,
+		"survey_completed":    true,
+		"challenge_completed": true,
+		"draw_won":            true,
+		"promo_code":          true,
+		"reversal":            true,
+		"customer_dormant":    true,
 	}
 
 	// Valid reward types
@@ -49,31 +46,14 @@ var (
 
 	// Valid user roles
 	validRoles = map[string]bool{
-		"owner":  true,
-		"admin":  true,
-		"staff":  true,
-		"viewer": true,
+		"owner":   true,
+		"admin":   true,
+		"manager": true,
+		"staff":   true,
+		"viewer":  true,
 	}
 )
 
-// ValidateE164Phone validates phone number in E.164 format
-func ValidateE164Phone(phone string) error {
-	if phone == "" {
-		return nil // Empty is allowed in some contexts
-	}
-
-	// Ensure it starts with +
-	if !strings.HasPrefix(phone, "+") {
-		phone = "+" + phone
-	}
-
-	if !e164Regex.MatchString(phone) {
-		return errors.New("phone number must be in E.164 format (e.g., +263771234567)")
-	}
-
-	return nil
-}
-
 // ValidateUUID validates UUID format
 func ValidateUUID(id string) error {
 	if _, err := uuid.Parse(id); err != nil {
@@ -82,15 +62,17 @@ func ValidateUUID(id string) error {
 	return nil
 }
 
-// ValidateCurrency checks if currency is ZWG or USD
-func ValidateCurrency(currency string) error {
-	if !validCurrencies[currency] {
-		return errors.New("currency must be ZWG or USD")
+// ValidateCurrency checks if currency is an ISO 4217 currency code. Whether
+// a tenant may use it is checked by currency.Checker.
+func ValidateCurrency(code string) error {
+	if !currency.Valid(code) {
+		return currency.ErrUnknownCurrency
 	}
 	return nil
 }
 
-// ValidateEventType checks if event type is allowed
+// ValidateEventType checks if event type is one of the built-in event types.
+// Tenant-defined event types are validated by event.Service.ValidateEventType.
 func ValidateEventType(eventType string) error {
 	if !validEventTypes[eventType] {
 		return errors.New("invalid event type")
@@ -98,6 +80,16 @@ func ValidateEventType(eventType string) error {
 	return nil
 }
 
+// BuiltinEventTypes returns the built-in event types, sorted by name
+func BuiltinEventTypes() []string {
+	types := make([]string, 0, len(validEventTypes))
+	for t := range validEventTypes {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
 // ValidateRewardType checks if reward type is allowed
 func ValidateRewardType(rewardType string) error {
 	if !validRewardTypes[rewardType] {
@@ -121,14 +113,3 @@ func ValidateRole(role string) error {
 	}
 	return nil
 }
-
-// NormalizeE164Phone ensures phone number has + prefix
-func NormalizeE164Phone(phone string) string {
-	if phone == "" {
-		return ""
-	}
-	if !strings.HasPrefix(phone, "+") {
-		return "+" + phone
-	}
-	return phone
-}
@@ -0,0 +1,71 @@
+package issuance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrCodeNotFound is returned when no issuance has the code
+var ErrCodeNotFound = errors.New("no issuance found for code")
+
+// NormalizeCode returns a code as it is looked up: trimmed and upper case,
+// and for fuzzy lookups without spaces or dashes
+func NormalizeCode(code string, fuzzy bool) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !fuzzy {
+		return code
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '-':
+			return -1
+		}
+		return r
+	}, code)
+}
+
+// GetIssuanceByCode finds the issuance with a code, with its reward and
+// customer. Codes match regardless of case; fuzzy lookups that find no exact
+// match also ignore spaces and dashes. Both lookups are indexed.
+func (s *Service) GetIssuanceByCode(ctx context.Context, tenantID pgtype.UUID, code string, fuzzy bool) (db.GetIssuanceByCodeRow, error) {
+	exact := NormalizeCode(code, false)
+	if exact == "" {
+		return db.GetIssuanceByCodeRow{}, ErrCodeNotFound
+	}
+
+	row, err := s.queries.GetIssuanceByCode(ctx, db.GetIssuanceByCodeParams{
+		TenantID: tenantID,
+		Code:     exact,
+	})
+	if err == nil {
+		return row, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return db.GetIssuanceByCodeRow{}, fmt.Errorf("failed to get issuance by code: %w", err)
+	}
+	if !fuzzy {
+		return db.GetIssuanceByCodeRow{}, ErrCodeNotFound
+	}
+
+	normalized := NormalizeCode(code, true)
+	if normalized == "" {
+		return db.GetIssuanceByCodeRow{}, ErrCodeNotFound
+	}
+	fuzzyRow, err := s.queries.GetIssuanceByNormalizedCode(ctx, db.GetIssuanceByNormalizedCodeParams{
+		TenantID: tenantID,
+		Code:     normalized,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.GetIssuanceByCodeRow{}, ErrCodeNotFound
+		}
+		return db.GetIssuanceByCodeRow{}, fmt.Errorf("failed to get issuance by code: %w", err)
+	}
+	return db.GetIssuanceByCodeRow(fuzzyRow), nil
+}
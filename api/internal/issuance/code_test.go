@@ -0,0 +1,25 @@
+package issuance
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeCode(t *testing.T) {
+	tests := []struct {
+		code  string
+		fuzzy bool
+		want  string
+	}{
+		{" abc123 ", false, "ABC123"},
+		{"abc-123", false, "ABC-123"},
+		{"abc-123", true, "ABC123"},
+		{"ab c\t1-2 3", true, "ABC123"},
+		{"  ", true, ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, NormalizeCode(tt.code, tt.fuzzy), tt.code)
+	}
+}
@@ -79,23 +79,6 @@ func (s *Service) ReserveIssuance(ctx context.Context, params db.ReserveIssuance
 	return s.queries.ReserveIssuance(ctx, params)
 }
 
-// UpdateIssuanceStatus updates the status of an issuance
-func (s *Service) UpdateIssuanceStatus(ctx context.Context, id, tenantID pgtype.UUID, fromStatus, toStatus string) error {
-	err := s.queries.UpdateIssuanceStatus(ctx, db.UpdateIssuanceStatusParams{
-		ID:       id,
-		TenantID: tenantID,
-		Status:   fromStatus,
-		Status_2: toStatus,
-	})
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			return fmt.Errorf("issuance not found or status mismatch")
-		}
-		return fmt.Errorf("failed to update issuance status: %w", err)
-	}
-	return nil
-}
-
 // UpdateIssuanceDetails updates the details of an issuance
 func (s *Service) UpdateIssuanceDetails(ctx context.Context, params db.UpdateIssuanceDetailsParams) error {
 	err := s.queries.UpdateIssuanceDetails(ctx, params)
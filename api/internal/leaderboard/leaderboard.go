@@ -0,0 +1,261 @@
+// Package leaderboard ranks a campaign's customers by points earned, spend
+// or visits. Leaderboards are opt-in per campaign; the ranking is cached in
+// leaderboard_entries by a worker and served with privacy-safe display
+// names rather than customers' names or phone numbers.
+package leaderboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Leaderboard metrics
+const (
+	// MetricPoints ranks by the face value of the campaign's points rewards
+	MetricPoints = "points"
+	// MetricSpend ranks by purchase amounts during the campaign
+	MetricSpend = "spend"
+	// MetricVisits ranks by visits during the campaign
+	MetricVisits = "visits"
+)
+
+var (
+	// ErrCampaignNotFound is returned when the campaign does not exist
+	ErrCampaignNotFound = errors.New("campaign not found")
+
+	// ErrNotEnabled is returned for a campaign without a leaderboard
+	ErrNotEnabled = errors.New("campaign has no leaderboard")
+
+	// ErrNotRanked is returned when the customer is not on the leaderboard
+	ErrNotRanked = errors.New("customer is not ranked")
+
+	// ErrInvalidMetric is returned for an unknown leaderboard metric
+	ErrInvalidMetric = fmt.Errorf("metric must be one of %s, %s, %s", MetricPoints, MetricSpend, MetricVisits)
+)
+
+// ValidMetric reports whether metric is a leaderboard metric
+func ValidMetric(metric string) bool {
+	switch metric {
+	case MetricPoints, MetricSpend, MetricVisits:
+		return true
+	}
+	return false
+}
+
+// Entry is a customer's place on a leaderboard
+type Entry struct {
+	CustomerID  pgtype.UUID
+	Rank        int32
+	Score       pgtype.Numeric
+	DisplayName string
+	ComputedAt  pgtype.Timestamptz
+}
+
+// DisplayName is how a customer is shown on a leaderboard: their first name
+// and last initial ("Tendai M."), or the last digits of their phone number
+// when they have no name
+func DisplayName(name, phone string) string {
+	if parts := strings.Fields(name); len(parts) > 0 {
+		display := parts[0]
+		if len(parts) > 1 {
+			initial, _ := utf8.DecodeRuneInString(parts[len(parts)-1])
+			display += " " + string(unicode.ToUpper(initial)) + "."
+		}
+		return display
+	}
+
+	phone = strings.TrimSpace(phone)
+	if len(phone) >= 4 {
+		return "Customer ***" + phone[len(phone)-4:]
+	}
+	return "Customer"
+}
+
+// Service manages campaign leaderboards
+type Service struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+}
+
+// NewService creates a new leaderboard service
+func NewService(pool *pgxpool.Pool, queries *db.Queries) *Service {
+	return &Service{
+		pool:    pool,
+		queries: queries,
+	}
+}
+
+// SetMetric enables a campaign's leaderboard ranked by metric, or disables
+// it when metric is empty. The ranking is rebuilt straight away.
+func (s *Service) SetMetric(ctx context.Context, tenantID, campaignID pgtype.UUID, metric string, now time.Time) (db.Campaign, error) {
+	if metric != "" && !ValidMetric(metric) {
+		return db.Campaign{}, ErrInvalidMetric
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.Campaign{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	campaign, err := qtx.SetCampaignLeaderboardMetric(ctx, db.SetCampaignLeaderboardMetricParams{
+		ID:                campaignID,
+		TenantID:          tenantID,
+		LeaderboardMetric: pgtype.Text{String: metric, Valid: metric != ""},
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Campaign{}, ErrCampaignNotFound
+		}
+		return db.Campaign{}, fmt.Errorf("failed to set leaderboard metric: %w", err)
+	}
+
+	if _, err := rebuild(ctx, qtx, campaign, now); err != nil {
+		return db.Campaign{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.Campaign{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return campaign, nil
+}
+
+// List returns a page of a campaign's leaderboard and the number of
+// customers ranked
+func (s *Service) List(ctx context.Context, tenantID, campaignID pgtype.UUID, limit, offset string) ([]Entry, int64, error) {
+	if err := s.checkEnabled(ctx, tenantID, campaignID); err != nil {
+		return nil, 0, err
+	}
+
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil || limitInt < 1 {
+		limitInt = 50
+	}
+	if limitInt > 100 {
+		limitInt = 100
+	}
+	offsetInt, err := strconv.Atoi(offset)
+	if err != nil || offsetInt < 0 {
+		offsetInt = 0
+	}
+
+	rows, err := s.queries.ListLeaderboardEntries(ctx, db.ListLeaderboardEntriesParams{
+		TenantID:   tenantID,
+		CampaignID: campaignID,
+		Limit:      int32(limitInt),
+		Offset:     int32(offsetInt),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list leaderboard: %w", err)
+	}
+
+	total, err := s.queries.CountLeaderboardEntries(ctx, db.CountLeaderboardEntriesParams{
+		TenantID:   tenantID,
+		CampaignID: campaignID,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count leaderboard: %w", err)
+	}
+
+	entries := make([]Entry, len(rows))
+	for i, row := range rows {
+		entries[i] = Entry{
+			CustomerID:  row.CustomerID,
+			Rank:        row.Rank,
+			Score:       row.Score,
+			DisplayName: DisplayName(row.CustomerName.String, row.PhoneE164.String),
+			ComputedAt:  row.ComputedAt,
+		}
+	}
+	return entries, total, nil
+}
+
+// CustomerRank returns a customer's place on a campaign's leaderboard and
+// the number of customers ranked
+func (s *Service) CustomerRank(ctx context.Context, tenantID, campaignID, customerID pgtype.UUID) (Entry, int64, error) {
+	if err := s.checkEnabled(ctx, tenantID, campaignID); err != nil {
+		return Entry{}, 0, err
+	}
+
+	total, err := s.queries.CountLeaderboardEntries(ctx, db.CountLeaderboardEntriesParams{
+		TenantID:   tenantID,
+		CampaignID: campaignID,
+	})
+	if err != nil {
+		return Entry{}, 0, fmt.Errorf("failed to count leaderboard: %w", err)
+	}
+
+	row, err := s.queries.GetLeaderboardEntry(ctx, db.GetLeaderboardEntryParams{
+		TenantID:   tenantID,
+		CampaignID: campaignID,
+		CustomerID: customerID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Entry{}, total, ErrNotRanked
+		}
+		return Entry{}, 0, fmt.Errorf("failed to get leaderboard entry: %w", err)
+	}
+
+	return Entry{
+		CustomerID:  row.CustomerID,
+		Rank:        row.Rank,
+		Score:       row.Score,
+		DisplayName: DisplayName(row.CustomerName.String, row.PhoneE164.String),
+		ComputedAt:  row.ComputedAt,
+	}, total, nil
+}
+
+// checkEnabled checks the campaign exists and has a leaderboard
+func (s *Service) checkEnabled(ctx context.Context, tenantID, campaignID pgtype.UUID) error {
+	campaign, err := s.queries.GetCampaignByID(ctx, db.GetCampaignByIDParams{
+		ID:       campaignID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrCampaignNotFound
+		}
+		return fmt.Errorf("failed to get campaign: %w", err)
+	}
+	if !campaign.LeaderboardMetric.Valid {
+		return ErrNotEnabled
+	}
+	return nil
+}
+
+// rebuild replaces a campaign's cached ranking, returning the number of
+// customers ranked. A campaign without a leaderboard is left empty.
+func rebuild(ctx context.Context, qtx *db.Queries, campaign db.Campaign, now time.Time) (int64, error) {
+	if err := qtx.DeleteLeaderboardEntries(ctx, db.DeleteLeaderboardEntriesParams{
+		TenantID:   campaign.TenantID,
+		CampaignID: campaign.ID,
+	}); err != nil {
+		return 0, fmt.Errorf("failed to clear leaderboard: %w", err)
+	}
+	if !campaign.LeaderboardMetric.Valid {
+		return 0, nil
+	}
+
+	ranked, err := qtx.RefreshLeaderboardEntries(ctx, db.RefreshLeaderboardEntriesParams{
+		ComputedAt: pgtype.Timestamptz{Time: now, Valid: true},
+		CampaignID: campaign.ID,
+		TenantID:   campaign.TenantID,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to rank leaderboard: %w", err)
+	}
+	return ranked, nil
+}
@@ -0,0 +1,38 @@
+package leaderboard
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisplayName(t *testing.T) {
+	tests := []struct {
+		name     string
+		fullName string
+		phone    string
+		want     string
+	}{
+		{"first and last name", "Tendai Moyo", "+263771234567", "Tendai M."},
+		{"middle names dropped", "Rudo Chipo Ncube", "", "Rudo N."},
+		{"lower case surname", "tatenda  sibanda", "", "tatenda S."},
+		{"non-ASCII initial", "Zoë Émile", "", "Zoë É."},
+		{"first name only", "Farai", "+263771234567", "Farai"},
+		{"no name", " ", "+263771234567", "Customer ***4567"},
+		{"nothing known", "", "", "Customer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DisplayName(tt.fullName, tt.phone))
+		})
+	}
+}
+
+func TestValidMetric(t *testing.T) {
+	for _, metric := range []string{MetricPoints, MetricSpend, MetricVisits} {
+		assert.True(t, ValidMetric(metric), metric)
+	}
+	assert.False(t, ValidMetric(""))
+	assert.False(t, ValidMetric("referrals"))
+}
@@ -0,0 +1,109 @@
+package leaderboard
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Worker rebuilds the cached ranking of every campaign with a leaderboard
+type Worker struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+	logger  *slog.Logger
+}
+
+// NewWorker creates a leaderboard worker
+func NewWorker(pool *pgxpool.Pool, logger *slog.Logger) *Worker {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Worker{
+		pool:    pool,
+		queries: db.New(pool),
+		logger:  logger,
+	}
+}
+
+// RefreshTenant rebuilds the leaderboards of the tenant's campaigns,
+// returning the number of leaderboards rebuilt. Each campaign is rebuilt in
+// one transaction, so readers see either the old ranking or the new one.
+func (w *Worker) RefreshTenant(ctx context.Context, tenant db.Tenant, now time.Time) (int, error) {
+	tx, err := w.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Leaderboards are rebuilt outside a tenant request
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenant.ID.Bytes)); err != nil {
+		return 0, fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	qtx := w.queries.WithTx(tx)
+	campaigns, err := qtx.ListLeaderboardCampaigns(ctx, tenant.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list leaderboard campaigns: %w", err)
+	}
+
+	for _, campaign := range campaigns {
+		if _, err := rebuild(ctx, qtx, campaign, now); err != nil {
+			return 0, fmt.Errorf("campaign %s: %w", httputil.FormatUUID(campaign.ID.Bytes), err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(campaigns), nil
+}
+
+// RefreshAll rebuilds the leaderboards of every tenant. A failing tenant is
+// logged and skipped. It returns the number of leaderboards rebuilt.
+func (w *Worker) RefreshAll(ctx context.Context, now time.Time) (int, error) {
+	tenants, err := w.queries.ListTenants(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	refreshed := 0
+	for _, tenant := range tenants {
+		n, err := w.RefreshTenant(ctx, tenant, now)
+		if err != nil {
+			w.logger.Error("failed to refresh leaderboards",
+				"tenant_id", tenant.ID,
+				"error", err)
+			continue
+		}
+		refreshed += n
+	}
+	return refreshed, nil
+}
+
+// Run rebuilds the leaderboards on a schedule until ctx is cancelled
+func (w *Worker) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		if n, err := w.RefreshAll(ctx, start); err != nil {
+			w.logger.Error("leaderboard refresh failed", "error", err)
+		} else {
+			w.logger.Debug("leaderboards refreshed",
+				"leaderboards", n,
+				"duration_ms", time.Since(start).Milliseconds())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrDuplicateWorker is returned when registering a worker name twice
+var ErrDuplicateWorker = errors.New("worker already registered")
+
+// Worker is a background subsystem that runs until its context is cancelled.
+// It should return promptly once ctx is done, after draining in-flight work.
+type Worker func(ctx context.Context) error
+
+// OnShutdown adapts a blocking drain function into a Worker. The worker idles
+// until shutdown and then calls drain.
+func OnShutdown(drain func()) Worker {
+	return func(ctx context.Context) error {
+		<-ctx.Done()
+		drain()
+		return nil
+	}
+}
+
+// ShutdownError reports the workers that did not drain cleanly
+type ShutdownError struct {
+	// Undrained lists workers still running when the shutdown deadline passed
+	Undrained []string
+
+	// Failed maps workers that returned an error to that error
+	Failed map[string]error
+}
+
+func (e *ShutdownError) Error() string {
+	var parts []string
+	if len(e.Undrained) > 0 {
+		parts = append(parts, fmt.Sprintf("workers did not drain: %s", strings.Join(e.Undrained, ", ")))
+	}
+	if len(e.Failed) > 0 {
+		names := make([]string, 0, len(e.Failed))
+		for name := range e.Failed {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		failed := make([]string, len(names))
+		for i, name := range names {
+			failed[i] = fmt.Sprintf("%s (%v)", name, e.Failed[name])
+		}
+		parts = append(parts, fmt.Sprintf("workers failed: %s", strings.Join(failed, ", ")))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// worker tracks a running worker
+type worker struct {
+	name string
+	done chan struct{}
+	err  error
+}
+
+// Manager starts background workers and coordinates their shutdown
+type Manager struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	logger  *slog.Logger
+	mu      sync.Mutex
+	workers []*worker
+}
+
+// NewManager creates a new lifecycle manager
+func NewManager(logger *slog.Logger) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		ctx:    ctx,
+		cancel: cancel,
+		logger: logger,
+	}
+}
+
+// Register starts a worker under the given name. The worker's context is
+// cancelled when Shutdown is called.
+func (m *Manager) Register(name string, run Worker) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, w := range m.workers {
+		if w.name == name {
+			return fmt.Errorf("%w: %s", ErrDuplicateWorker, name)
+		}
+	}
+
+	w := &worker{name: name, done: make(chan struct{})}
+	m.workers = append(m.workers, w)
+
+	go func() {
+		defer close(w.done)
+		w.err = run(m.ctx)
+		if w.err != nil && m.ctx.Err() == nil {
+			m.logger.Error("worker stopped unexpectedly", "worker", name, "error", w.err)
+		}
+	}()
+
+	m.logger.Info("worker started", "worker", name)
+	return nil
+}
+
+// Shutdown cancels all workers and waits for them to return until ctx is
+// done. It returns a *ShutdownError naming workers that failed or did not
+// drain before the deadline.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.cancel()
+
+	m.mu.Lock()
+	workers := append([]*worker(nil), m.workers...)
+	m.mu.Unlock()
+
+	result := &ShutdownError{Failed: make(map[string]error)}
+	for _, w := range workers {
+		select {
+		case <-w.done:
+		case <-ctx.Done():
+		}
+
+		select {
+		case <-w.done:
+			if w.err != nil && !errors.Is(w.err, context.Canceled) {
+				result.Failed[w.name] = w.err
+			}
+		default:
+			result.Undrained = append(result.Undrained, w.name)
+		}
+	}
+
+	if len(result.Undrained) > 0 || len(result.Failed) > 0 {
+		return result
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestManager() *Manager {
+	return NewManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestShutdownDrainsWorkers(t *testing.T) {
+	m := newTestManager()
+
+	drained := false
+	require.NoError(t, m.Register("sweeper", func(ctx context.Context) error {
+		<-ctx.Done()
+		drained = true
+		return ctx.Err()
+	}))
+	require.NoError(t, m.Register("alerts", OnShutdown(func() {})))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	assert.NoError(t, m.Shutdown(ctx))
+	assert.True(t, drained)
+}
+
+func TestShutdownReportsUndrainedAndFailedWorkers(t *testing.T) {
+	m := newTestManager()
+
+	block := make(chan struct{})
+	defer close(block)
+
+	require.NoError(t, m.Register("stuck", func(ctx context.Context) error {
+		<-block
+		return nil
+	}))
+	require.NoError(t, m.Register("broken", func(ctx context.Context) error {
+		<-ctx.Done()
+		return errors.New("flush failed")
+	}))
+	require.NoError(t, m.Register("clean", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := m.Shutdown(ctx)
+	var shutdownErr *ShutdownError
+	require.ErrorAs(t, err, &shutdownErr)
+	assert.Equal(t, []string{"stuck"}, shutdownErr.Undrained)
+	assert.Contains(t, shutdownErr.Failed, "broken")
+	assert.NotContains(t, shutdownErr.Failed, "clean")
+}
+
+func TestRegisterRejectsDuplicateNames(t *testing.T) {
+	m := newTestManager()
+	defer m.Shutdown(context.Background())
+
+	require.NoError(t, m.Register("sweeper", OnShutdown(func() {})))
+	assert.ErrorIs(t, m.Register("sweeper", OnShutdown(func() {})), ErrDuplicateWorker)
+}
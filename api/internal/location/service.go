@@ -0,0 +1,217 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	// ErrLocationNotFound is returned when the location does not exist for the tenant
+	ErrLocationNotFound = errors.New("location not found")
+
+	// ErrLocationExists is returned when another location already uses the code
+	ErrLocationExists = errors.New("location code already exists")
+
+	// ErrLocationInactive is returned when an event references a deactivated location
+	ErrLocationInactive = errors.New("location is inactive")
+)
+
+// Params contains the fields of a location
+type Params struct {
+	TenantID  pgtype.UUID
+	Name      string
+	Code      string
+	Region    string
+	Latitude  *float64
+	Longitude *float64
+	Active    bool
+}
+
+// Validate validates the location parameters
+func (p Params) Validate() error {
+	if !p.TenantID.Valid {
+		return errors.New("tenant_id is required")
+	}
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("name is required")
+	}
+	if strings.TrimSpace(p.Code) == "" {
+		return errors.New("code is required")
+	}
+	if (p.Latitude == nil) != (p.Longitude == nil) {
+		return errors.New("latitude and longitude must be provided together")
+	}
+	if p.Latitude != nil && (*p.Latitude < -90 || *p.Latitude > 90) {
+		return errors.New("latitude must be between -90 and 90")
+	}
+	if p.Longitude != nil && (*p.Longitude < -180 || *p.Longitude > 180) {
+		return errors.New("longitude must be between -180 and 180")
+	}
+	return nil
+}
+
+// Service handles location business logic
+type Service struct {
+	queries *db.Queries
+}
+
+// NewService creates a new location service
+func NewService(queries *db.Queries) *Service {
+	return &Service{queries: queries}
+}
+
+// Create creates a new location
+func (s *Service) Create(ctx context.Context, params Params) (db.Location, error) {
+	if err := params.Validate(); err != nil {
+		return db.Location{}, err
+	}
+
+	latitude, longitude, err := coordinates(params)
+	if err != nil {
+		return db.Location{}, err
+	}
+
+	created, err := s.queries.CreateLocation(ctx, db.CreateLocationParams{
+		TenantID:  params.TenantID,
+		Name:      strings.TrimSpace(params.Name),
+		Code:      strings.TrimSpace(params.Code),
+		Region:    regionText(params.Region),
+		Latitude:  latitude,
+		Longitude: longitude,
+		Active:    params.Active,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return db.Location{}, ErrLocationExists
+		}
+		return db.Location{}, fmt.Errorf("failed to create location: %w", err)
+	}
+
+	return created, nil
+}
+
+// Get retrieves a location by ID
+func (s *Service) Get(ctx context.Context, tenantID, id pgtype.UUID) (db.Location, error) {
+	location, err := s.queries.GetLocationByID(ctx, db.GetLocationByIDParams{
+		ID:       id,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Location{}, ErrLocationNotFound
+		}
+		return db.Location{}, fmt.Errorf("failed to get location: %w", err)
+	}
+	return location, nil
+}
+
+// List lists the tenant's locations, optionally filtered by region
+func (s *Service) List(ctx context.Context, tenantID pgtype.UUID, region string) ([]db.Location, error) {
+	locations, err := s.queries.ListLocations(ctx, db.ListLocationsParams{
+		TenantID: tenantID,
+		Region:   regionText(region),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list locations: %w", err)
+	}
+	return locations, nil
+}
+
+// Update replaces the fields of a location
+func (s *Service) Update(ctx context.Context, id pgtype.UUID, params Params) (db.Location, error) {
+	if err := params.Validate(); err != nil {
+		return db.Location{}, err
+	}
+
+	latitude, longitude, err := coordinates(params)
+	if err != nil {
+		return db.Location{}, err
+	}
+
+	updated, err := s.queries.UpdateLocation(ctx, db.UpdateLocationParams{
+		ID:        id,
+		TenantID:  params.TenantID,
+		Name:      strings.TrimSpace(params.Name),
+		Code:      strings.TrimSpace(params.Code),
+		Region:    regionText(params.Region),
+		Latitude:  latitude,
+		Longitude: longitude,
+		Active:    params.Active,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Location{}, ErrLocationNotFound
+		}
+		if isUniqueViolation(err) {
+			return db.Location{}, ErrLocationExists
+		}
+		return db.Location{}, fmt.Errorf("failed to update location: %w", err)
+	}
+	return updated, nil
+}
+
+// Deactivate stops a location from being referenced by new events. Locations
+// are never deleted so historical events keep their attribution.
+func (s *Service) Deactivate(ctx context.Context, tenantID, id pgtype.UUID) error {
+	if err := s.queries.DeactivateLocation(ctx, db.DeactivateLocationParams{
+		ID:       id,
+		TenantID: tenantID,
+	}); err != nil {
+		return fmt.Errorf("failed to deactivate location: %w", err)
+	}
+	return nil
+}
+
+// ValidateLocation checks that a location exists and is active for the tenant
+func (s *Service) ValidateLocation(ctx context.Context, tenantID, id pgtype.UUID) error {
+	location, err := s.Get(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if !location.Active {
+		return ErrLocationInactive
+	}
+	return nil
+}
+
+// Coordinate returns a stored latitude or longitude as a float, or nil if unset
+func Coordinate(value pgtype.Numeric) *float64 {
+	f, err := value.Float64Value()
+	if err != nil || !f.Valid {
+		return nil
+	}
+	return &f.Float64
+}
+
+// coordinates converts the optional coordinates to numeric values
+func coordinates(params Params) (pgtype.Numeric, pgtype.Numeric, error) {
+	var latitude, longitude pgtype.Numeric
+	if params.Latitude == nil {
+		return latitude, longitude, nil
+	}
+	if err := latitude.Scan(strconv.FormatFloat(*params.Latitude, 'f', 6, 64)); err != nil {
+		return latitude, longitude, fmt.Errorf("invalid latitude: %w", err)
+	}
+	if err := longitude.Scan(strconv.FormatFloat(*params.Longitude, 'f', 6, 64)); err != nil {
+		return latitude, longitude, fmt.Errorf("invalid longitude: %w", err)
+	}
+	return latitude, longitude, nil
+}
+
+func regionText(region string) pgtype.Text {
+	region = strings.TrimSpace(region)
+	return pgtype.Text{String: region, Valid: region != ""}
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
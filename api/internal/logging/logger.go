@@ -14,10 +14,16 @@ type ContextKey string
 const (
 	// RequestIDKey is the context key for request ID
 	RequestIDKey ContextKey = "request_id"
+	// CorrelationIDKey is the context key for the correlation ID shared by
+	// every request in a caller's flow
+	CorrelationIDKey ContextKey = "correlation_id"
 	// TenantIDKey is the context key for tenant ID
 	TenantIDKey ContextKey = "tenant_id"
 	// UserIDKey is the context key for user ID
 	UserIDKey ContextKey = "user_id"
+
+	// loggerKey is the context key for a request-scoped logger
+	loggerKey ContextKey = "logger"
 )
 
 // Logger wraps slog.Logger with additional context handling
@@ -84,36 +90,48 @@ func getLogLevel() slog.Level {
 	}
 }
 
-// WithContext creates a logger with values from context
+// WithContext returns the request-scoped logger carried by ctx. Without one
+// it creates a logger with the IDs found in context.
 func (l *Logger) WithContext(ctx context.Context) *slog.Logger {
-	attrs := make([]slog.Attr, 0, 3)
-
-	// Add request ID if present
-	if requestID := ctx.Value(RequestIDKey); requestID != nil {
-		if id, ok := requestID.(string); ok && id != "" {
-			attrs = append(attrs, slog.String("request_id", id))
-		}
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return logger
 	}
+	return withContextIDs(l.Logger, ctx)
+}
 
-	// Add tenant ID if present
-	if tenantID := ctx.Value(TenantIDKey); tenantID != nil {
-		if id, ok := tenantID.(string); ok && id != "" {
-			attrs = append(attrs, slog.String("tenant_id", id))
-		}
+// NewContext returns a copy of ctx carrying logger. Services pick it up with
+// FromContext so their log lines share the request's IDs.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// FromContext returns the request-scoped logger carried by ctx, falling back
+// to fallback (or slog.Default() when nil) with the IDs found in context
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return logger
 	}
+	if fallback == nil {
+		fallback = slog.Default()
+	}
+	return withContextIDs(fallback, ctx)
+}
 
-	// Add user ID if present
-	if userID := ctx.Value(UserIDKey); userID != nil {
-		if id, ok := userID.(string); ok && id != "" {
-			attrs = append(attrs, slog.String("user_id", id))
+// withContextIDs adds the request, correlation, tenant and user IDs found in
+// ctx to logger
+func withContextIDs(logger *slog.Logger, ctx context.Context) *slog.Logger {
+	attrs := make([]any, 0, 4)
+	for _, key := range []ContextKey{RequestIDKey, CorrelationIDKey, TenantIDKey, UserIDKey} {
+		if id, ok := ctx.Value(key).(string); ok && id != "" {
+			attrs = append(attrs, slog.String(string(key), id))
 		}
 	}
 
 	if len(attrs) > 0 {
-		return l.Logger.With(attrs)
+		return logger.With(attrs...)
 	}
 
-	return l.Logger
+	return logger
 }
 
 // WithFields creates a logger with additional fields
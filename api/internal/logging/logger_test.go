@@ -0,0 +1,48 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	return line
+}
+
+func TestFromContextAddsIDs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWithWriter(&buf, slog.LevelInfo)
+
+	ctx := context.WithValue(context.Background(), RequestIDKey, "req-1")
+	ctx = context.WithValue(ctx, CorrelationIDKey, "corr-1")
+
+	FromContext(ctx, logger.Logger).Info("hello")
+
+	line := decodeLine(t, &buf)
+	assert.Equal(t, "req-1", line["request_id"])
+	assert.Equal(t, "corr-1", line["correlation_id"])
+}
+
+func TestFromContextPrefersRequestLogger(t *testing.T) {
+	var fallback, scoped bytes.Buffer
+	requestLogger := NewWithWriter(&scoped, slog.LevelInfo).With("request_id", "req-2")
+
+	ctx := NewContext(context.Background(), requestLogger)
+	FromContext(ctx, NewWithWriter(&fallback, slog.LevelInfo).Logger).Info("hello")
+
+	assert.Zero(t, fallback.Len())
+	assert.Equal(t, "req-2", decodeLine(t, &scoped)["request_id"])
+}
+
+func TestWithContextWithoutIDs(t *testing.T) {
+	logger := NewWithWriter(&bytes.Buffer{}, slog.LevelInfo)
+	assert.Same(t, logger.Logger, logger.WithContext(context.Background()))
+}
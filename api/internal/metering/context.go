@@ -0,0 +1,22 @@
+package metering
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type tenantKey struct{}
+
+// WithTenant tags ctx with the tenant that operations done with it are
+// metered to. Senders that don't know their tenant, like the WhatsApp
+// sender, meter through AddForContext.
+func WithTenant(ctx context.Context, tenantID pgtype.UUID) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant ctx was tagged with
+func TenantFromContext(ctx context.Context) (pgtype.UUID, bool) {
+	tenantID, ok := ctx.Value(tenantKey{}).(pgtype.UUID)
+	return tenantID, ok && tenantID.Valid
+}
@@ -0,0 +1,189 @@
+// Package metering counts billable operations per tenant into monthly usage
+// records that SaaS tenants are invoiced from.
+package metering
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Billable metrics. Messages are metered per channel, see MessagesSent.
+const (
+	MetricEventsIngested   = "events_ingested"
+	MetricIssuancesCreated = "issuances_created"
+)
+
+// Channels messages are sent to customers through
+const (
+	ChannelWhatsApp = "whatsapp"
+	ChannelUSSD     = "ussd"
+)
+
+// MessagesSent is the metric for messages sent to customers through channel
+func MessagesSent(channel string) string {
+	return "messages_sent_" + channel
+}
+
+// Period returns the first day of the UTC month containing t. Usage is
+// recorded per month.
+func Period(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// usageKey identifies one monthly usage record
+type usageKey struct {
+	tenantID [16]byte
+	period   time.Time
+	metric   string
+}
+
+// Meter buffers billable operation counts in memory and periodically adds
+// them to the tenants' usage records, so metering never slows down or fails
+// the operation it counts. Counts still buffered when the process dies are
+// lost; Run flushes them on shutdown.
+type Meter struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+	logger  *slog.Logger
+	now     func() time.Time
+
+	mu      sync.Mutex
+	pending map[usageKey]int64
+}
+
+// NewMeter creates a meter writing to pool
+func NewMeter(pool *pgxpool.Pool, logger *slog.Logger) *Meter {
+	return &Meter{
+		pool:    pool,
+		queries: db.New(pool),
+		logger:  logger,
+		now:     time.Now,
+		pending: make(map[usageKey]int64),
+	}
+}
+
+// Add counts n billable operations for a tenant in the current month. A nil
+// Meter counts nothing, so metering is optional wherever it is wired in.
+func (m *Meter) Add(tenantID pgtype.UUID, metric string, n int64) {
+	if m == nil || !tenantID.Valid || n <= 0 {
+		return
+	}
+
+	key := usageKey{tenantID: tenantID.Bytes, period: Period(m.now()), metric: metric}
+	m.mu.Lock()
+	m.pending[key] += n
+	m.mu.Unlock()
+}
+
+// AddForContext counts n operations for the tenant ctx was tagged with by
+// WithTenant. It counts nothing for untagged contexts.
+func (m *Meter) AddForContext(ctx context.Context, metric string, n int64) {
+	if tenantID, ok := TenantFromContext(ctx); ok {
+		m.Add(tenantID, metric, n)
+	}
+}
+
+// take removes and returns the buffered counts
+func (m *Meter) take() map[usageKey]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pending := m.pending
+	m.pending = make(map[usageKey]int64)
+	return pending
+}
+
+// restore puts counts that could not be written back into the buffer
+func (m *Meter) restore(counts map[usageKey]int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, n := range counts {
+		m.pending[key] += n
+	}
+}
+
+// Flush adds the buffered counts to the tenants' usage records. Counts of a
+// tenant whose write fails are kept for the next flush.
+func (m *Meter) Flush(ctx context.Context) error {
+	byTenant := make(map[[16]byte]map[usageKey]int64)
+	for key, n := range m.take() {
+		if byTenant[key.tenantID] == nil {
+			byTenant[key.tenantID] = make(map[usageKey]int64)
+		}
+		byTenant[key.tenantID][key] = n
+	}
+
+	var errs []error
+	for tenantID, counts := range byTenant {
+		if err := m.flushTenant(ctx, tenantID, counts); err != nil {
+			m.restore(counts)
+			errs = append(errs, fmt.Errorf("tenant %s: %w", httputil.FormatUUID(tenantID), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flushTenant writes one tenant's counts in a single transaction
+func (m *Meter) flushTenant(ctx context.Context, tenantID [16]byte, counts map[usageKey]int64) error {
+	tx, err := m.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Flushes run outside a tenant request
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenantID)); err != nil {
+		return fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	qtx := m.queries.WithTx(tx)
+	for key, n := range counts {
+		if err := qtx.AddUsage(ctx, db.AddUsageParams{
+			TenantID: pgtype.UUID{Bytes: key.tenantID, Valid: true},
+			Period:   pgtype.Date{Time: key.period, Valid: true},
+			Metric:   key.metric,
+			Quantity: n,
+		}); err != nil {
+			return fmt.Errorf("failed to add usage: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Run flushes buffered counts on a schedule until ctx is cancelled, then
+// flushes once more so counts are not lost on shutdown
+func (m *Meter) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := m.Flush(flushCtx); err != nil {
+				m.logger.Error("final usage flush failed", "error", err)
+			}
+			return nil
+		case <-ticker.C:
+			if err := m.Flush(ctx); err != nil {
+				m.logger.Error("usage flush failed", "error", err)
+			}
+		}
+	}
+}
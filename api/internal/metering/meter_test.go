@@ -0,0 +1,76 @@
+package metering
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func newTestMeter(now time.Time) *Meter {
+	return &Meter{
+		now:     func() time.Time { return now },
+		pending: make(map[usageKey]int64),
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	// Usage is bucketed by UTC month
+	harare := time.FixedZone("CAT", 2*60*60)
+	got := Period(time.Date(2025, 12, 1, 1, 0, 0, 0, harare))
+	want := time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("Expected period %v, got %v", want, got)
+	}
+}
+
+func TestMeterAdd(t *testing.T) {
+	now := time.Date(2025, 12, 13, 10, 0, 0, 0, time.UTC)
+	m := newTestMeter(now)
+	tenant := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+
+	m.Add(tenant, MetricEventsIngested, 1)
+	m.Add(tenant, MetricEventsIngested, 2)
+	m.Add(tenant, MessagesSent(ChannelWhatsApp), 1)
+	m.Add(pgtype.UUID{}, MetricEventsIngested, 1)
+	m.Add(tenant, MetricIssuancesCreated, 0)
+
+	pending := m.take()
+	if len(pending) != 2 {
+		t.Fatalf("Expected 2 usage records, got %d", len(pending))
+	}
+	key := usageKey{tenantID: tenant.Bytes, period: Period(now), metric: MetricEventsIngested}
+	if pending[key] != 3 {
+		t.Errorf("Expected 3 events ingested, got %d", pending[key])
+	}
+	if len(m.take()) != 0 {
+		t.Error("Expected take to empty the buffer")
+	}
+
+	// Counts that failed to flush are added to newer ones
+	m.Add(tenant, MetricEventsIngested, 1)
+	m.restore(pending)
+	if got := m.take()[key]; got != 4 {
+		t.Errorf("Expected 4 events ingested after restore, got %d", got)
+	}
+}
+
+func TestMeterAddForContext(t *testing.T) {
+	m := newTestMeter(time.Now())
+	tenant := pgtype.UUID{Bytes: [16]byte{2}, Valid: true}
+
+	m.AddForContext(context.Background(), MessagesSent(ChannelWhatsApp), 1)
+	if len(m.take()) != 0 {
+		t.Error("Expected untagged contexts not to be metered")
+	}
+
+	m.AddForContext(WithTenant(context.Background(), tenant), MessagesSent(ChannelWhatsApp), 1)
+	if len(m.take()) != 1 {
+		t.Error("Expected tagged context to be metered")
+	}
+
+	// A nil meter counts nothing
+	var nilMeter *Meter
+	nilMeter.Add(tenant, MetricEventsIngested, 1)
+}
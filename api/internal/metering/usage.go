@@ -0,0 +1,55 @@
+package metering
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// MonthFormat is how usage periods are written in requests and responses
+const MonthFormat = "2006-01"
+
+// ErrInvalidMonth is returned for a month not in YYYY-MM form
+var ErrInvalidMonth = errors.New("month must be in YYYY-MM format")
+
+// ParseMonth parses a YYYY-MM month into its usage period
+func ParseMonth(s string) (time.Time, error) {
+	t, err := time.Parse(MonthFormat, s)
+	if err != nil {
+		return time.Time{}, ErrInvalidMonth
+	}
+	return Period(t), nil
+}
+
+// MonthlyUsage is a tenant's metered usage for one month
+type MonthlyUsage struct {
+	Period  string           `json:"period"`
+	Metrics map[string]int64 `json:"metrics"`
+}
+
+// GetUsage returns a tenant's usage for the months from through to, oldest
+// first. Months without usage are omitted.
+func GetUsage(ctx context.Context, q *db.Queries, tenantID pgtype.UUID, from, to time.Time) ([]MonthlyUsage, error) {
+	records, err := q.ListTenantUsage(ctx, db.ListTenantUsageParams{
+		TenantID:   tenantID,
+		FromPeriod: pgtype.Date{Time: Period(from), Valid: true},
+		ToPeriod:   pgtype.Date{Time: Period(to), Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage: %w", err)
+	}
+
+	usage := []MonthlyUsage{}
+	for _, r := range records {
+		period := r.Period.Time.Format(MonthFormat)
+		if len(usage) == 0 || usage[len(usage)-1].Period != period {
+			usage = append(usage, MonthlyUsage{Period: period, Metrics: map[string]int64{}})
+		}
+		usage[len(usage)-1].Metrics[r.Metric] = r.Quantity
+	}
+	return usage, nil
+}
@@ -24,6 +24,16 @@ type Metrics struct {
 	BudgetUtilization     *GaugeVec
 	ExternalAPILatency    *Histogram
 	CircuitBreakerState   *GaugeVec
+	CircuitBreakerRejectedTotal *CounterVec
+
+	// Cache metrics, labelled by cache name
+	CacheHitsTotal        *CounterVec
+	CacheMissesTotal      *CounterVec
+
+	// Issuance processor metrics; processed issuances are labelled by
+	// outcome (issued, retried, failed)
+	IssuanceQueueDepth    *Gauge
+	IssuancesProcessedTotal *CounterVec
 
 	// Database metrics
 	DBConnectionsActive   *Gauge
@@ -119,6 +129,44 @@ func (gv *GaugeVec) WithLabels(labels ...string) *Gauge {
 	return gauge
 }
 
+// CounterVec is a collection of counters with labels
+type CounterVec struct {
+	counters map[string]*Counter
+	mu       sync.RWMutex
+}
+
+// NewCounterVec creates a new CounterVec
+func NewCounterVec() *CounterVec {
+	return &CounterVec{
+		counters: make(map[string]*Counter),
+	}
+}
+
+// WithLabels returns a counter for the given label values
+func (cv *CounterVec) WithLabels(labels ...string) *Counter {
+	key := joinLabels(labels...)
+
+	cv.mu.RLock()
+	counter, exists := cv.counters[key]
+	cv.mu.RUnlock()
+
+	if exists {
+		return counter
+	}
+
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+
+	// Double-check in case another goroutine created it
+	if counter, exists := cv.counters[key]; exists {
+		return counter
+	}
+
+	counter = &Counter{}
+	cv.counters[key] = counter
+	return counter
+}
+
 // Histogram tracks the distribution of values
 type Histogram struct {
 	observations []time.Duration
@@ -182,6 +230,15 @@ func Init() *Metrics {
 			BudgetUtilization:     NewGaugeVec(),
 			ExternalAPILatency:    &Histogram{observations: make([]time.Duration, 0, 1000)},
 			CircuitBreakerState:   NewGaugeVec(),
+			CircuitBreakerRejectedTotal: NewCounterVec(),
+
+			// Cache metrics
+			CacheHitsTotal:        NewCounterVec(),
+			CacheMissesTotal:      NewCounterVec(),
+
+			// Issuance processor metrics
+			IssuanceQueueDepth:    &Gauge{},
+			IssuancesProcessedTotal: NewCounterVec(),
 
 			// Database metrics
 			DBConnectionsActive:   &Gauge{},
@@ -235,6 +292,11 @@ func RecordCircuitBreakerState(service string, state int64) {
 	Get().CircuitBreakerState.WithLabels(service).Set(state)
 }
 
+// RecordCircuitBreakerRejected records a call rejected by an open circuit
+func RecordCircuitBreakerRejected(service string) {
+	Get().CircuitBreakerRejectedTotal.WithLabels(service).Inc()
+}
+
 // RecordHTTPRequest records HTTP request metrics
 func RecordHTTPRequest(duration time.Duration, isError bool) {
 	m := Get()
@@ -254,3 +316,23 @@ func RecordActiveHTTPRequest() {
 func RecordHTTPRequestComplete() {
 	Get().HTTPActiveRequests.Dec()
 }
+
+// RecordCacheHit records a cache hit for the named cache
+func RecordCacheHit(cache string) {
+	Get().CacheHitsTotal.WithLabels(cache).Inc()
+}
+
+// RecordCacheMiss records a cache miss for the named cache
+func RecordCacheMiss(cache string) {
+	Get().CacheMissesTotal.WithLabels(cache).Inc()
+}
+
+// RecordIssuanceQueueDepth records how many reserved issuances are due for processing
+func RecordIssuanceQueueDepth(depth int64) {
+	Get().IssuanceQueueDepth.Set(depth)
+}
+
+// RecordIssuanceProcessed records the outcome of processing a reserved issuance
+func RecordIssuanceProcessed(outcome string) {
+	Get().IssuancesProcessedTotal.WithLabels(outcome).Inc()
+}
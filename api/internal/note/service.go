@@ -0,0 +1,148 @@
+// Package note keeps the internal notes staff attach to customers and their
+// issuances, e.g. "customer says code didn't work, reissued manually", so
+// support interactions are traceable. Notes are never shown to customers.
+package note
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// MaxBodyLength is the longest note, in characters
+const MaxBodyLength = 2000
+
+var (
+	// ErrInvalidBody is returned for a note that is empty or too long
+	ErrInvalidBody = errors.New("invalid note")
+
+	// ErrCustomerNotFound is returned when noting an unknown customer
+	ErrCustomerNotFound = errors.New("customer not found")
+
+	// ErrIssuanceNotFound is returned when noting an unknown issuance
+	ErrIssuanceNotFound = errors.New("issuance not found")
+)
+
+// Service handles staff notes
+type Service struct {
+	queries *db.Queries
+}
+
+// NewService creates a new note service
+func NewService(queries *db.Queries) *Service {
+	return &Service{queries: queries}
+}
+
+// NormalizeBody trims a note and checks it is neither empty nor longer than
+// MaxBodyLength
+func NormalizeBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", fmt.Errorf("%w: body is required", ErrInvalidBody)
+	}
+	if utf8.RuneCountInString(body) > MaxBodyLength {
+		return "", fmt.Errorf("%w: body must be at most %d characters", ErrInvalidBody, MaxBodyLength)
+	}
+	return body, nil
+}
+
+// AddToCustomer attaches a note by author to a customer
+func (s *Service) AddToCustomer(ctx context.Context, tenantID, customerID, authorID pgtype.UUID, body string) (db.StaffNote, error) {
+	body, err := NormalizeBody(body)
+	if err != nil {
+		return db.StaffNote{}, err
+	}
+
+	if _, err := s.queries.GetCustomerByID(ctx, db.GetCustomerByIDParams{
+		ID:       customerID,
+		TenantID: tenantID,
+	}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.StaffNote{}, ErrCustomerNotFound
+		}
+		return db.StaffNote{}, fmt.Errorf("failed to get customer: %w", err)
+	}
+
+	return s.create(ctx, db.CreateStaffNoteParams{
+		TenantID:   tenantID,
+		CustomerID: customerID,
+		AuthorID:   authorID,
+		Body:       body,
+	})
+}
+
+// AddToIssuance attaches a note by author to an issuance. The note also
+// shows among the notes of the issuance's customer.
+func (s *Service) AddToIssuance(ctx context.Context, tenantID, issuanceID, authorID pgtype.UUID, body string) (db.StaffNote, error) {
+	body, err := NormalizeBody(body)
+	if err != nil {
+		return db.StaffNote{}, err
+	}
+
+	issuance, err := s.queries.GetIssuanceByID(ctx, db.GetIssuanceByIDParams{
+		ID:       issuanceID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.StaffNote{}, ErrIssuanceNotFound
+		}
+		return db.StaffNote{}, fmt.Errorf("failed to get issuance: %w", err)
+	}
+
+	return s.create(ctx, db.CreateStaffNoteParams{
+		TenantID:   tenantID,
+		CustomerID: issuance.CustomerID,
+		IssuanceID: issuance.ID,
+		AuthorID:   authorID,
+		Body:       body,
+	})
+}
+
+func (s *Service) create(ctx context.Context, params db.CreateStaffNoteParams) (db.StaffNote, error) {
+	note, err := s.queries.CreateStaffNote(ctx, params)
+	if err != nil {
+		return db.StaffNote{}, fmt.Errorf("failed to create note: %w", err)
+	}
+	return note, nil
+}
+
+// ListForCustomer returns a customer's notes, including those on their
+// issuances, newest first, and the total count
+func (s *Service) ListForCustomer(ctx context.Context, tenantID, customerID pgtype.UUID, limit, offset int32) ([]db.ListCustomerStaffNotesRow, int64, error) {
+	notes, err := s.queries.ListCustomerStaffNotes(ctx, db.ListCustomerStaffNotesParams{
+		TenantID:   tenantID,
+		CustomerID: customerID,
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list notes: %w", err)
+	}
+	total, err := s.queries.CountCustomerStaffNotes(ctx, db.CountCustomerStaffNotesParams{
+		TenantID:   tenantID,
+		CustomerID: customerID,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count notes: %w", err)
+	}
+	return notes, total, nil
+}
+
+// ListForIssuance returns an issuance's notes, newest first
+func (s *Service) ListForIssuance(ctx context.Context, tenantID, issuanceID pgtype.UUID) ([]db.ListIssuanceStaffNotesRow, error) {
+	notes, err := s.queries.ListIssuanceStaffNotes(ctx, db.ListIssuanceStaffNotesParams{
+		TenantID:   tenantID,
+		IssuanceID: issuanceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notes: %w", err)
+	}
+	return notes, nil
+}
@@ -0,0 +1,24 @@
+package note
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeBody(t *testing.T) {
+	body, err := NormalizeBody("  Customer says code didn't work, reissued manually \n")
+	require.NoError(t, err)
+	assert.Equal(t, "Customer says code didn't work, reissued manually", body)
+
+	body, err = NormalizeBody(strings.Repeat("é", MaxBodyLength))
+	require.NoError(t, err)
+	assert.Len(t, []rune(body), MaxBodyLength)
+
+	for _, invalid := range []string{"", " \t\n", strings.Repeat("a", MaxBodyLength+1)} {
+		_, err := NormalizeBody(invalid)
+		assert.ErrorIs(t, err, ErrInvalidBody)
+	}
+}
@@ -0,0 +1,209 @@
+// Package phone validates phone numbers and normalizes them to E.164, the
+// form customers are stored and looked up by. Numbers without a country code
+// are read in a default region, normally the tenant's (tenants.phone_region).
+//
+// Numbering plans are known for the regions the platform operates in and its
+// neighbours. Numbers of other countries are accepted in international
+// format with only a length check.
+package phone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	// ErrInvalidNumber is returned for input that is not a valid phone
+	// number
+	ErrInvalidNumber = errors.New("invalid phone number")
+
+	// ErrUnknownRegion is returned for a region without a known numbering
+	// plan
+	ErrUnknownRegion = errors.New("unknown phone region")
+)
+
+// DefaultRegion is the region of tenants that have not set one
+const DefaultRegion = "ZW"
+
+// E.164 numbers have at most 15 digits; shorter than 8 are not dialable
+// subscriber numbers anywhere
+const (
+	minDigits = 8
+	maxDigits = 15
+)
+
+// plan is a region's numbering plan
+type plan struct {
+	countryCode string
+	// trunkPrefix is dialled before national numbers within the region and
+	// dropped in international format
+	trunkPrefix string
+	// minLength and maxLength bound the national significant number
+	minLength int
+	maxLength int
+}
+
+// plans are the numbering plans of known regions, by ISO 3166-1 code
+var plans = map[string]plan{
+	"ZW": {countryCode: "263", trunkPrefix: "0", minLength: 8, maxLength: 9},
+	"ZA": {countryCode: "27", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"ZM": {countryCode: "260", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"BW": {countryCode: "267", minLength: 7, maxLength: 8},
+	"MZ": {countryCode: "258", minLength: 8, maxLength: 9},
+	"MW": {countryCode: "265", trunkPrefix: "0", minLength: 7, maxLength: 9},
+	"NA": {countryCode: "264", trunkPrefix: "0", minLength: 8, maxLength: 9},
+	"KE": {countryCode: "254", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"UG": {countryCode: "256", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"TZ": {countryCode: "255", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"NG": {countryCode: "234", trunkPrefix: "0", minLength: 8, maxLength: 10},
+	"GB": {countryCode: "44", trunkPrefix: "0", minLength: 9, maxLength: 10},
+	"US": {countryCode: "1", trunkPrefix: "1", minLength: 10, maxLength: 10},
+}
+
+// Regions returns the regions with a known numbering plan, sorted
+func Regions() []string {
+	regions := make([]string, 0, len(plans))
+	for region := range plans {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// ValidRegion reports whether region has a known numbering plan
+func ValidRegion(region string) bool {
+	_, ok := plans[region]
+	return ok
+}
+
+// Normalize returns the E.164 form of a phone number. The number may be in
+// international format (+263 77 123 4567 or 00263771234567) or, given a
+// region, in that region's national format (077 123 4567). Spaces, dashes,
+// dots and brackets are ignored.
+func Normalize(raw, region string) (string, error) {
+	digits, international, err := parse(raw)
+	if err != nil {
+		return "", err
+	}
+
+	if international {
+		return normalizeInternational(digits)
+	}
+
+	p, ok := plans[region]
+	if !ok {
+		if region == "" {
+			return "", fmt.Errorf("%w: country code is required", ErrInvalidNumber)
+		}
+		return "", ErrUnknownRegion
+	}
+
+	// International format without the +, e.g. 263771234567
+	if national, ok := strings.CutPrefix(digits, p.countryCode); ok && p.validLength(national) && !p.validLength(digits) {
+		return "+" + digits, nil
+	}
+
+	national := digits
+	if p.trunkPrefix != "" {
+		national = strings.TrimPrefix(national, p.trunkPrefix)
+	}
+	if !p.validLength(national) {
+		return "", fmt.Errorf("%w: wrong number of digits for %s", ErrInvalidNumber, region)
+	}
+	return "+" + p.countryCode + national, nil
+}
+
+// Valid reports whether s is a phone number in E.164 format
+func Valid(s string) bool {
+	normalized, err := Normalize(s, "")
+	return err == nil && normalized == s
+}
+
+// WhatsAppID returns the WhatsApp ID of an E.164 number, which is the
+// number without the +
+func WhatsAppID(e164 string) string {
+	return strings.TrimPrefix(e164, "+")
+}
+
+// FromWhatsAppID returns the E.164 number of a WhatsApp ID
+func FromWhatsAppID(waID string) (string, error) {
+	return Normalize("+"+strings.TrimPrefix(waID, "+"), "")
+}
+
+// parse strips formatting from a phone number, returning its digits and
+// whether it is in international format
+func parse(raw string) (string, bool, error) {
+	raw = strings.TrimSpace(raw)
+	international := strings.HasPrefix(raw, "+")
+	raw = strings.TrimPrefix(raw, "+")
+
+	var digits strings.Builder
+	for _, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", false, fmt.Errorf("%w: unexpected character %q", ErrInvalidNumber, r)
+		}
+	}
+
+	s := digits.String()
+	if !international {
+		// 00 is the international call prefix in most of the world
+		if rest, ok := strings.CutPrefix(s, "00"); ok {
+			s, international = rest, true
+		}
+	}
+	if s == "" {
+		return "", false, ErrInvalidNumber
+	}
+	return s, international, nil
+}
+
+// normalizeInternational validates the digits of an international number
+// against its country's numbering plan, if known
+func normalizeInternational(digits string) (string, error) {
+	if len(digits) < minDigits || len(digits) > maxDigits || digits[0] == '0' {
+		return "", ErrInvalidNumber
+	}
+	for _, p := range plans {
+		if national, ok := strings.CutPrefix(digits, p.countryCode); ok {
+			if !p.validLength(national) {
+				return "", fmt.Errorf("%w: wrong number of digits for +%s", ErrInvalidNumber, p.countryCode)
+			}
+			break
+		}
+	}
+	return "+" + digits, nil
+}
+
+func (p plan) validLength(national string) bool {
+	return len(national) >= p.minLength && len(national) <= p.maxLength
+}
+
+// Normalizer normalizes phone numbers in each tenant's default region
+type Normalizer struct {
+	queries *db.Queries
+}
+
+// NewNormalizer creates a new normalizer
+func NewNormalizer(queries *db.Queries) *Normalizer {
+	return &Normalizer{queries: queries}
+}
+
+// Normalize returns the E.164 form of a phone number entered for the
+// tenant, see Normalize
+func (n *Normalizer) Normalize(ctx context.Context, tenantID pgtype.UUID, raw string) (string, error) {
+	region, err := n.queries.GetTenantPhoneRegion(ctx, tenantID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get tenant phone region: %w", err)
+	}
+	return Normalize(raw, region)
+}
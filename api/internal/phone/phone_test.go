@@ -0,0 +1,85 @@
+package phone
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		region   string
+		expected string
+	}{
+		{"local format with leading zero", "0771234567", "ZW", "+263771234567"},
+		{"without country code", "771234567", "ZW", "+263771234567"},
+		{"with country code no plus", "263771234567", "ZW", "+263771234567"},
+		{"already E.164", "+263771234567", "ZW", "+263771234567"},
+		{"with spaces", "077 123 4567", "ZW", "+263771234567"},
+		{"with dashes", "077-123-4567", "ZW", "+263771234567"},
+		{"with brackets", "(077) 123.4567", "ZW", "+263771234567"},
+		{"international call prefix", "00263 77 123 4567", "ZW", "+263771234567"},
+		{"other region", "082 123 4567", "ZA", "+27821234567"},
+		{"foreign number in international format", "+27 82 123 4567", "ZW", "+27821234567"},
+		{"international format without region", "+263771234567", "", "+263771234567"},
+		{"US trunk prefix", "1 (202) 555-0123", "US", "+12025550123"},
+		{"unknown country in international format", "+33612345678", "ZW", "+33612345678"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Normalize(tt.input, tt.region)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestNormalizeInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		region string
+		err    error
+	}{
+		{"empty", "", "ZW", ErrInvalidNumber},
+		{"letters", "077 CALL ME", "ZW", ErrInvalidNumber},
+		{"too short", "07712", "ZW", ErrInvalidNumber},
+		{"too long", "07712345678901", "ZW", ErrInvalidNumber},
+		{"wrong length for country", "+2637712345", "ZW", ErrInvalidNumber},
+		{"national format without region", "0771234567", "", ErrInvalidNumber},
+		{"unknown region", "0771234567", "XX", ErrUnknownRegion},
+		{"international too long", "+1234567890123456", "", ErrInvalidNumber},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Normalize(tt.input, tt.region)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid("+263771234567"))
+	assert.False(t, Valid("263771234567"))
+	assert.False(t, Valid("+263 77 123 4567"))
+}
+
+func TestWhatsAppID(t *testing.T) {
+	assert.Equal(t, "263771234567", WhatsAppID("+263771234567"))
+
+	e164, err := FromWhatsAppID("263771234567")
+	require.NoError(t, err)
+	assert.Equal(t, "+263771234567", e164)
+}
+
+func TestRegions(t *testing.T) {
+	regions := Regions()
+	assert.Contains(t, regions, DefaultRegion)
+	assert.True(t, ValidRegion("ZA"))
+	assert.False(t, ValidRegion("za"))
+}
@@ -0,0 +1,275 @@
+// Package pii encrypts personal data, such as customers' phone numbers, at
+// rest. Values are encrypted with AES-GCM under a versioned key and stored
+// with the key's ID, so keys can be rotated with loyaltyctl rotate-pii while
+// values encrypted under older keys stay readable. Encrypted values can't
+// be compared in SQL, so they are looked up by a blind index: an HMAC of the
+// value under the same key version.
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Prefix marks encrypted values, which are stored as
+// "enc:<kid>:<base64 nonce and ciphertext>". Plaintext values, written
+// before encryption was configured, are read as they are.
+const Prefix = "enc:"
+
+// KeySize is the size of a PII key in bytes
+const KeySize = 32
+
+var (
+	// ErrInvalidKey is returned for a key without a kid, with a kid that
+	// isn't unique or contains a colon, or that isn't KeySize bytes
+	ErrInvalidKey = errors.New("PII keys need a 32 byte key and a kid, without colons, unique among the keys")
+
+	// ErrUnknownKey is returned when decrypting a value encrypted under a
+	// key that isn't on the keyring
+	ErrUnknownKey = errors.New("value encrypted with an unknown PII key")
+
+	// ErrMalformed is returned when decrypting a value that isn't in the
+	// encrypted format or fails authentication
+	ErrMalformed = errors.New("malformed encrypted PII value")
+)
+
+// Key is a version of the key PII is encrypted and blind-indexed with. Key
+// is the base64 encoded key in JSON.
+type Key struct {
+	ID  string `json:"kid"`
+	Key []byte `json:"key"`
+}
+
+// ParseKeys parses the PII key versions of PII_KEYS_JSON, e.g.
+// [{"kid":"2026-10","key":"<base64>"},{"kid":"2026-04","key":"<base64>"}].
+// The first key encrypts new values and the others only decrypt values they
+// encrypted until rotated away. Without keysJSON there are no keys and PII
+// is stored unencrypted.
+func ParseKeys(keysJSON string) ([]Key, error) {
+	if keysJSON == "" {
+		return nil, nil
+	}
+
+	var keys []Key
+	if err := json.Unmarshal([]byte(keysJSON), &keys); err != nil {
+		return nil, fmt.Errorf("invalid PII_KEYS_JSON: %w", err)
+	}
+	if err := validateKeys(keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func validateKeys(keys []Key) error {
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key.ID == "" || strings.Contains(key.ID, ":") || seen[key.ID] || len(key.Key) != KeySize {
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key.ID)
+		}
+		seen[key.ID] = true
+	}
+	return nil
+}
+
+// version is a key version's derived encryption and blind index keys
+type version struct {
+	id    string
+	aead  cipher.AEAD
+	index []byte
+}
+
+func newVersion(key Key) (version, error) {
+	block, err := aes.NewCipher(derive(key.Key, "encryption"))
+	if err != nil {
+		return version{}, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return version{}, err
+	}
+	return version{id: key.ID, aead: aead, index: derive(key.Key, "blind-index")}, nil
+}
+
+// derive derives a subkey for purpose, so a key version's encryption and
+// blind index keys are independent
+func derive(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// Keyring holds the active PII key versions. Keys are rotated without a
+// restart by setting new versions; values encrypted under a version still
+// on the keyring stay readable.
+type Keyring struct {
+	mu       sync.RWMutex
+	versions []version
+}
+
+var defaultKeyring = &Keyring{}
+
+// Default returns the keyring PII columns are encrypted and decrypted with
+func Default() *Keyring {
+	return defaultKeyring
+}
+
+// NewKeyring creates a keyring holding the given key versions
+func NewKeyring(keys []Key) (*Keyring, error) {
+	k := &Keyring{}
+	if err := k.Set(keys); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Set replaces the keyring's key versions. The keyring is left unchanged
+// if the keys are invalid.
+func (k *Keyring) Set(keys []Key) error {
+	if err := validateKeys(keys); err != nil {
+		return err
+	}
+	versions := make([]version, len(keys))
+	for i, key := range keys {
+		v, err := newVersion(key)
+		if err != nil {
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key.ID)
+		}
+		versions[i] = v
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.versions = versions
+	return nil
+}
+
+// Enabled reports whether the keyring has a key to encrypt with
+func (k *Keyring) Enabled() bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return len(k.versions) > 0
+}
+
+// KeyIDs returns the kids of the active keys, the encrypting key first
+func (k *Keyring) KeyIDs() []string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	ids := make([]string, len(k.versions))
+	for i, v := range k.versions {
+		ids[i] = v.id
+	}
+	return ids
+}
+
+// CurrentPrefix is the prefix of values encrypted under the current key,
+// empty when there is none
+func (k *Keyring) CurrentPrefix() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if len(k.versions) == 0 {
+		return ""
+	}
+	return Prefix + k.versions[0].id + ":"
+}
+
+// Encrypt encrypts plaintext under the current key. Without a key,
+// plaintext is returned as it is.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if len(k.versions) == 0 {
+		return plaintext, nil
+	}
+
+	v := k.versions[0]
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := v.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return Prefix + v.id + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value Encrypt returned. Values without Prefix were
+// stored unencrypted and are returned as they are.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, Prefix) {
+		return value, nil
+	}
+	kid, encoded, ok := strings.Cut(strings.TrimPrefix(value, Prefix), ":")
+	if !ok {
+		return "", ErrMalformed
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrMalformed
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, v := range k.versions {
+		if v.id != kid {
+			continue
+		}
+		if len(sealed) < v.aead.NonceSize() {
+			return "", ErrMalformed
+		}
+		nonce, ciphertext := sealed[:v.aead.NonceSize()], sealed[v.aead.NonceSize():]
+		plaintext, err := v.aead.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			return "", ErrMalformed
+		}
+		return string(plaintext), nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+}
+
+// BlindIndex returns the blind index of value under the current key, which
+// is stored alongside the encrypted value to look it up by. Without a key
+// it is the value's SHA-256 digest, as indexed before encryption was
+// configured.
+func (k *Keyring) BlindIndex(value string) []byte {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if len(k.versions) == 0 {
+		return legacyIndex(value)
+	}
+	return hmacIndex(k.versions[0].index, value)
+}
+
+// BlindIndexes returns the blind indexes of value under every key, the
+// current key's first, and its SHA-256 digest. Values are looked up by all
+// of them so they're found whether or not they've been rotated onto the
+// current key yet.
+func (k *Keyring) BlindIndexes(value string) [][]byte {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	indexes := make([][]byte, 0, len(k.versions)+1)
+	for _, v := range k.versions {
+		indexes = append(indexes, hmacIndex(v.index, value))
+	}
+	return append(indexes, legacyIndex(value))
+}
+
+func hmacIndex(key []byte, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// legacyIndex is the blind index of values stored before encryption was
+// configured. It is unkeyed, which is no weaker than the plaintext stored
+// beside it.
+func legacyIndex(value string) []byte {
+	sum := sha256.Sum256([]byte(value))
+	return sum[:]
+}
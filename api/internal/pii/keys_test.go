@@ -0,0 +1,129 @@
+package pii
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(id string, b byte) Key {
+	return Key{ID: id, Key: bytes.Repeat([]byte{b}, KeySize)}
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys("")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	encoded := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, KeySize))
+	keys, err = ParseKeys(`[{"kid":"v1","key":"` + encoded + `"}]`)
+	require.NoError(t, err)
+	assert.Equal(t, []Key{testKey("v1", 1)}, keys)
+
+	for _, keysJSON := range []string{
+		`[{"kid":"","key":"` + encoded + `"}]`,
+		`[{"kid":"v:1","key":"` + encoded + `"}]`,
+		`[{"kid":"v1","key":"` + encoded + `"},{"kid":"v1","key":"` + encoded + `"}]`,
+		`[{"kid":"v1","key":"c2hvcnQ="}]`,
+	} {
+		_, err = ParseKeys(keysJSON)
+		assert.ErrorIs(t, err, ErrInvalidKey, keysJSON)
+	}
+}
+
+func TestKeyring_EncryptDecrypt(t *testing.T) {
+	keyring, err := NewKeyring([]Key{testKey("v1", 1)})
+	require.NoError(t, err)
+
+	ciphertext, err := keyring.Encrypt("+263771234567")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(ciphertext, "enc:v1:"))
+	assert.NotContains(t, ciphertext, "263771234567")
+
+	again, err := keyring.Encrypt("+263771234567")
+	require.NoError(t, err)
+	assert.NotEqual(t, ciphertext, again, "encryption is randomized")
+
+	plaintext, err := keyring.Decrypt(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "+263771234567", plaintext)
+
+	// Values stored before encryption are read as they are
+	plaintext, err = keyring.Decrypt("+263771234567")
+	require.NoError(t, err)
+	assert.Equal(t, "+263771234567", plaintext)
+
+	_, err = keyring.Decrypt(ciphertext[:len(ciphertext)-2] + "AA")
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	keyring, err := NewKeyring([]Key{testKey("v1", 1)})
+	require.NoError(t, err)
+	old, err := keyring.Encrypt("+263771234567")
+	require.NoError(t, err)
+	oldIndex := keyring.BlindIndex("+263771234567")
+
+	// The new key encrypts, the old key still decrypts
+	require.NoError(t, keyring.Set([]Key{testKey("v2", 2), testKey("v1", 1)}))
+	assert.Equal(t, "enc:v2:", keyring.CurrentPrefix())
+	plaintext, err := keyring.Decrypt(old)
+	require.NoError(t, err)
+	assert.Equal(t, "+263771234567", plaintext)
+
+	indexes := keyring.BlindIndexes("+263771234567")
+	require.Len(t, indexes, 3)
+	assert.Equal(t, keyring.BlindIndex("+263771234567"), indexes[0])
+	assert.Equal(t, oldIndex, indexes[1])
+	assert.NotEqual(t, indexes[0], indexes[1])
+
+	// Once the old key is removed its values can't be read
+	require.NoError(t, keyring.Set([]Key{testKey("v2", 2)}))
+	_, err = keyring.Decrypt(old)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestKeyring_Disabled(t *testing.T) {
+	keyring, err := NewKeyring(nil)
+	require.NoError(t, err)
+	assert.False(t, keyring.Enabled())
+	assert.Empty(t, keyring.CurrentPrefix())
+
+	value, err := keyring.Encrypt("+263771234567")
+	require.NoError(t, err)
+	assert.Equal(t, "+263771234567", value)
+
+	// Matches the digest migration 064 indexed existing numbers with
+	sum := sha256.Sum256([]byte("+263771234567"))
+	assert.Equal(t, sum[:], keyring.BlindIndex("+263771234567"))
+	assert.Equal(t, [][]byte{sum[:]}, keyring.BlindIndexes("+263771234567"))
+}
+
+func TestText(t *testing.T) {
+	require.NoError(t, Default().Set([]Key{testKey("v1", 1)}))
+	defer Default().Set(nil)
+
+	stored, err := Text{String: "+263771234567", Valid: true}.TextValue()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(stored.String, "enc:v1:"))
+
+	var read Text
+	require.NoError(t, read.ScanText(stored))
+	assert.Equal(t, Text{String: "+263771234567", Valid: true}, read)
+
+	require.NoError(t, read.ScanText(pgtype.Text{}))
+	assert.False(t, read.Valid)
+
+	b, err := Text{String: "+263771234567", Valid: true}.MarshalJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `"+263771234567"`, string(b))
+	b, err = Text{}.MarshalJSON()
+	require.NoError(t, err)
+	assert.Equal(t, "null", string(b))
+}
@@ -0,0 +1,172 @@
+// Package rotation moves stored PII onto the current PII key: values
+// stored unencrypted, or encrypted under an older key, are re-encrypted
+// and reindexed so older keys can be retired.
+package rotation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/pii"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// BatchSize is how many values of a table are re-encrypted per transaction
+const BatchSize = 500
+
+// ErrNoKey is returned when rotating without a PII key to rotate onto
+var ErrNoKey = errors.New("no PII key configured; set PII_KEYS_JSON")
+
+// Result counts the values a rotation moved onto the current key
+type Result struct {
+	Customers    int
+	WASessions   int
+	USSDSessions int
+}
+
+// Service rotates tenants' PII onto the current key of the default PII
+// keyring
+type Service struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+}
+
+// NewService creates a new rotation service
+func NewService(pool *pgxpool.Pool, queries *db.Queries) *Service {
+	return &Service{
+		pool:    pool,
+		queries: queries,
+	}
+}
+
+// Rotate re-encrypts the tenant's customer and session phone numbers that
+// aren't encrypted under the current key, in batches. Values are decrypted
+// with whichever key encrypted them, so every key that did must still be on
+// the keyring. Rotating again after a partial run picks up where it
+// stopped.
+func (s *Service) Rotate(ctx context.Context, tenantID pgtype.UUID) (Result, error) {
+	prefix := pii.Default().CurrentPrefix()
+	if prefix == "" {
+		return Result{}, ErrNoKey
+	}
+
+	var result Result
+	for _, step := range []struct {
+		count  *int
+		rotate func(ctx context.Context, qtx *db.Queries) (int, error)
+	}{
+		{&result.Customers, func(ctx context.Context, qtx *db.Queries) (int, error) {
+			return rotateCustomers(ctx, qtx, tenantID, prefix)
+		}},
+		{&result.WASessions, func(ctx context.Context, qtx *db.Queries) (int, error) {
+			return rotateWASessions(ctx, qtx, tenantID, prefix)
+		}},
+		{&result.USSDSessions, func(ctx context.Context, qtx *db.Queries) (int, error) {
+			return rotateUSSDSessions(ctx, qtx, tenantID, prefix)
+		}},
+	} {
+		for {
+			n, err := s.batch(ctx, tenantID, step.rotate)
+			*step.count += n
+			if err != nil {
+				return result, err
+			}
+			if n < BatchSize {
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// batch runs one batch in a transaction scoped to the tenant
+func (s *Service) batch(ctx context.Context, tenantID pgtype.UUID, rotate func(ctx context.Context, qtx *db.Queries) (int, error)) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Rotations run outside a tenant request
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenantID.Bytes)); err != nil {
+		return 0, fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	n, err := rotate(ctx, s.queries.WithTx(tx))
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return n, nil
+}
+
+func rotateCustomers(ctx context.Context, qtx *db.Queries, tenantID pgtype.UUID, prefix string) (int, error) {
+	rows, err := qtx.ListCustomersToRotate(ctx, db.ListCustomersToRotateParams{
+		TenantID:      tenantID,
+		CurrentPrefix: prefix,
+		RowLimit:      BatchSize,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list customers: %w", err)
+	}
+	for _, row := range rows {
+		// Writing the decrypted number back encrypts it under the current key
+		if err := qtx.SetCustomerPhone(ctx, db.SetCustomerPhoneParams{
+			ID:        row.ID,
+			TenantID:  tenantID,
+			PhoneE164: row.PhoneE164,
+			PhoneBidx: pii.Default().BlindIndex(row.PhoneE164.String),
+		}); err != nil {
+			return 0, fmt.Errorf("failed to rotate customer %s: %w", httputil.FormatUUID(row.ID.Bytes), err)
+		}
+	}
+	return len(rows), nil
+}
+
+func rotateWASessions(ctx context.Context, qtx *db.Queries, tenantID pgtype.UUID, prefix string) (int, error) {
+	rows, err := qtx.ListWASessionsToRotate(ctx, db.ListWASessionsToRotateParams{
+		TenantID:      tenantID,
+		CurrentPrefix: prefix,
+		RowLimit:      BatchSize,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list WhatsApp sessions: %w", err)
+	}
+	for _, row := range rows {
+		if err := qtx.SetWASessionPhone(ctx, db.SetWASessionPhoneParams{
+			ID:        row.ID,
+			TenantID:  tenantID,
+			PhoneE164: row.PhoneE164,
+		}); err != nil {
+			return 0, fmt.Errorf("failed to rotate WhatsApp session: %w", err)
+		}
+	}
+	return len(rows), nil
+}
+
+func rotateUSSDSessions(ctx context.Context, qtx *db.Queries, tenantID pgtype.UUID, prefix string) (int, error) {
+	rows, err := qtx.ListUSSDSessionsToRotate(ctx, db.ListUSSDSessionsToRotateParams{
+		TenantID:      tenantID,
+		CurrentPrefix: prefix,
+		RowLimit:      BatchSize,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list USSD sessions: %w", err)
+	}
+	for _, row := range rows {
+		if err := qtx.SetUSSDSessionPhone(ctx, db.SetUSSDSessionPhoneParams{
+			ID:        row.ID,
+			TenantID:  tenantID,
+			PhoneE164: row.PhoneE164,
+		}); err != nil {
+			return 0, fmt.Errorf("failed to rotate USSD session: %w", err)
+		}
+	}
+	return len(rows), nil
+}
@@ -0,0 +1,58 @@
+package pii
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Text is a nullable text column holding PII. It is encrypted with the
+// default keyring as it's written and decrypted as it's read, so code using
+// the column sees plaintext, like a pgtype.Text. sqlc maps the PII columns
+// to it in sqlc.yaml.
+type Text struct {
+	String string
+	Valid  bool
+}
+
+// ScanText implements pgtype.TextScanner, decrypting the stored value
+func (t *Text) ScanText(v pgtype.Text) error {
+	if !v.Valid {
+		*t = Text{}
+		return nil
+	}
+	plaintext, err := Default().Decrypt(v.String)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt PII: %w", err)
+	}
+	*t = Text{String: plaintext, Valid: true}
+	return nil
+}
+
+// TextValue implements pgtype.TextValuer, encrypting the value to store
+func (t Text) TextValue() (pgtype.Text, error) {
+	if !t.Valid {
+		return pgtype.Text{}, nil
+	}
+	ciphertext, err := Default().Encrypt(t.String)
+	if err != nil {
+		return pgtype.Text{}, fmt.Errorf("failed to encrypt PII: %w", err)
+	}
+	return pgtype.Text{String: ciphertext, Valid: true}, nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (t Text) MarshalJSON() ([]byte, error) {
+	return pgtype.Text{String: t.String, Valid: t.Valid}.MarshalJSON()
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *Text) UnmarshalJSON(b []byte) error {
+	var v pgtype.Text
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*t = Text{String: v.String, Valid: v.Valid}
+	return nil
+}
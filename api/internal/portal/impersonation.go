@@ -0,0 +1,274 @@
+package portal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/auth"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// DefaultImpersonationTTL is how long an impersonation lasts when staff
+	// don't choose
+	DefaultImpersonationTTL = 15 * time.Minute
+	// MaxImpersonationTTL is the longest an impersonation can last
+	MaxImpersonationTTL = time.Hour
+)
+
+// Audit log actions recorded for impersonations
+const (
+	AuditImpersonationStart   = "impersonate_start"
+	AuditImpersonationEnd     = "impersonate_end"
+	AuditImpersonationRequest = "impersonate_request"
+)
+
+var (
+	// ErrReasonRequired is returned when an impersonation has no reason
+	ErrReasonRequired = errors.New("a reason is required to impersonate a customer")
+
+	// ErrInvalidDuration is returned for an impersonation that is not
+	// positive or longer than MaxImpersonationTTL
+	ErrInvalidDuration = fmt.Errorf("impersonation must last between 1 minute and %d minutes", int(MaxImpersonationTTL.Minutes()))
+
+	// ErrCustomerNotFound is returned when impersonating an unknown customer
+	ErrCustomerNotFound = errors.New("customer not found")
+
+	// ErrImpersonationNotActive is returned when ending an impersonation that
+	// doesn't exist, has ended or has expired
+	ErrImpersonationNotActive = errors.New("impersonation is not active")
+)
+
+// ImpersonationParams describe a staff user's request to see the portal as
+// a customer
+type ImpersonationParams struct {
+	TenantID    pgtype.UUID
+	CustomerID  pgtype.UUID
+	StaffUserID pgtype.UUID
+	Reason      string
+	// Duration defaults to DefaultImpersonationTTL
+	Duration  time.Duration
+	IPAddress *netip.Addr
+}
+
+// Impersonate issues a read-only customer token to a staff user so they can
+// see the portal as the customer does. The impersonation and its reason are
+// audit logged; the token stops working when it expires or is ended.
+func (s *Service) Impersonate(ctx context.Context, params ImpersonationParams) (db.CustomerImpersonation, *Session, error) {
+	reason := strings.TrimSpace(params.Reason)
+	if reason == "" {
+		return db.CustomerImpersonation{}, nil, ErrReasonRequired
+	}
+	duration := params.Duration
+	if duration == 0 {
+		duration = DefaultImpersonationTTL
+	}
+	if duration < time.Minute || duration > MaxImpersonationTTL {
+		return db.CustomerImpersonation{}, nil, ErrInvalidDuration
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.CustomerImpersonation{}, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	if _, err := qtx.GetCustomerByID(ctx, db.GetCustomerByIDParams{
+		ID:       params.CustomerID,
+		TenantID: params.TenantID,
+	}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.CustomerImpersonation{}, nil, ErrCustomerNotFound
+		}
+		return db.CustomerImpersonation{}, nil, fmt.Errorf("failed to get customer: %w", err)
+	}
+
+	expiresAt := time.Now().Add(duration)
+	impersonation, err := qtx.CreateCustomerImpersonation(ctx, db.CreateCustomerImpersonationParams{
+		TenantID:    params.TenantID,
+		CustomerID:  params.CustomerID,
+		StaffUserID: params.StaffUserID,
+		Reason:      reason,
+		ExpiresAt:   pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		return db.CustomerImpersonation{}, nil, fmt.Errorf("failed to create impersonation: %w", err)
+	}
+
+	if err := auditImpersonation(ctx, qtx, impersonation, AuditImpersonationStart, params.IPAddress, map[string]any{
+		"reason":     reason,
+		"expires_at": expiresAt.UTC().Format(time.RFC3339),
+	}); err != nil {
+		return db.CustomerImpersonation{}, nil, err
+	}
+
+	token, err := auth.GenerateImpersonationToken(
+		httputil.FormatUUID(params.CustomerID.Bytes),
+		httputil.FormatUUID(params.TenantID.Bytes),
+		httputil.FormatUUID(impersonation.ID.Bytes),
+		httputil.FormatUUID(params.StaffUserID.Bytes),
+		expiresAt,
+		s.keys,
+	)
+	if err != nil {
+		return db.CustomerImpersonation{}, nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.CustomerImpersonation{}, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return impersonation, &Session{
+		AccessToken: token,
+		ExpiresIn:   int64(duration.Seconds()),
+		CustomerID:  params.CustomerID,
+	}, nil
+}
+
+// EndImpersonation ends an impersonation before it expires, so its token is
+// rejected from then on. staffUserID is the staff user ending it, who need
+// not be the one who started it.
+func (s *Service) EndImpersonation(ctx context.Context, tenantID, impersonationID, staffUserID pgtype.UUID, ip *netip.Addr) (db.CustomerImpersonation, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.CustomerImpersonation{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	impersonation, err := qtx.EndCustomerImpersonation(ctx, db.EndCustomerImpersonationParams{
+		TenantID: tenantID,
+		ID:       impersonationID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.CustomerImpersonation{}, ErrImpersonationNotActive
+		}
+		return db.CustomerImpersonation{}, fmt.Errorf("failed to end impersonation: %w", err)
+	}
+
+	details := map[string]any{}
+	if staffUserID.Valid {
+		details["ended_by"] = httputil.FormatUUID(staffUserID.Bytes)
+	}
+	if err := auditImpersonation(ctx, qtx, impersonation, AuditImpersonationEnd, ip, details); err != nil {
+		return db.CustomerImpersonation{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.CustomerImpersonation{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return impersonation, nil
+}
+
+// RecordImpersonatedRequest audit logs a portal request made with an
+// impersonation token. It reports false, recording nothing, when the
+// impersonation has ended or expired and the request must be refused.
+func (s *Service) RecordImpersonatedRequest(ctx context.Context, tenantID, impersonationID, method, path, ip string) (bool, error) {
+	var tenantUUID, impersonationUUID pgtype.UUID
+	if tenantUUID.Scan(tenantID) != nil || impersonationUUID.Scan(impersonationID) != nil {
+		return false, nil
+	}
+
+	impersonation, err := s.queries.GetActiveCustomerImpersonation(ctx, db.GetActiveCustomerImpersonationParams{
+		TenantID: tenantUUID,
+		ID:       impersonationUUID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get impersonation: %w", err)
+	}
+
+	if err := auditImpersonation(ctx, s.queries, impersonation, AuditImpersonationRequest, parseIP(ip), map[string]any{
+		"method": method,
+		"path":   path,
+	}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListImpersonations retrieves a paginated list of a customer's
+// impersonations, most recent first
+func (s *Service) ListImpersonations(ctx context.Context, tenantID, customerID pgtype.UUID, limit, offset string) ([]db.CustomerImpersonation, int64, error) {
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil || limitInt < 1 {
+		limitInt = 50
+	}
+	if limitInt > 100 {
+		limitInt = 100
+	}
+
+	offsetInt, err := strconv.Atoi(offset)
+	if err != nil || offsetInt < 0 {
+		offsetInt = 0
+	}
+
+	impersonations, err := s.queries.ListCustomerImpersonations(ctx, db.ListCustomerImpersonationsParams{
+		TenantID:   tenantID,
+		CustomerID: customerID,
+		Limit:      int32(limitInt),
+		Offset:     int32(offsetInt),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list impersonations: %w", err)
+	}
+
+	total, err := s.queries.CountCustomerImpersonations(ctx, db.CountCustomerImpersonationsParams{
+		TenantID:   tenantID,
+		CustomerID: customerID,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count impersonations: %w", err)
+	}
+
+	return impersonations, total, nil
+}
+
+// auditImpersonation writes an audit log entry for an impersonation,
+// attributed to the staff user who started it
+func auditImpersonation(ctx context.Context, queries *db.Queries, impersonation db.CustomerImpersonation, action string, ip *netip.Addr, details map[string]any) error {
+	details["impersonation_id"] = httputil.FormatUUID(impersonation.ID.Bytes)
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit details: %w", err)
+	}
+
+	if _, err := queries.InsertAuditLog(ctx, db.InsertAuditLogParams{
+		TenantID:     impersonation.TenantID,
+		ActorType:    "staff",
+		ActorID:      impersonation.StaffUserID,
+		Action:       action,
+		ResourceType: pgtype.Text{String: "customer", Valid: true},
+		ResourceID:   impersonation.CustomerID,
+		Details:      detailsJSON,
+		IpAddress:    ip,
+	}); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// parseIP returns the address of a client IP, or nil if it isn't one
+func parseIP(ip string) *netip.Addr {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil
+	}
+	return &addr
+}
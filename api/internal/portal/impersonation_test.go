@@ -0,0 +1,42 @@
+package portal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImpersonate_Validation(t *testing.T) {
+	s := &Service{jwtSecret: "secret"}
+
+	tests := []struct {
+		name    string
+		params  ImpersonationParams
+		wantErr error
+	}{
+		{"missing reason", ImpersonationParams{}, ErrReasonRequired},
+		{"blank reason", ImpersonationParams{Reason: "   "}, ErrReasonRequired},
+		{"too short", ImpersonationParams{Reason: "ticket 42", Duration: 30 * time.Second}, ErrInvalidDuration},
+		{"too long", ImpersonationParams{Reason: "ticket 42", Duration: MaxImpersonationTTL + time.Minute}, ErrInvalidDuration},
+		{"negative", ImpersonationParams{Reason: "ticket 42", Duration: -time.Minute}, ErrInvalidDuration},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := s.Impersonate(context.Background(), tt.params)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestParseIP(t *testing.T) {
+	ip := parseIP("192.168.1.1")
+	if assert.NotNil(t, ip) {
+		assert.Equal(t, "192.168.1.1", ip.String())
+	}
+	assert.NotNil(t, parseIP("::1"))
+	assert.Nil(t, parseIP(""))
+	assert.Nil(t, parseIP("not-an-ip"))
+}
@@ -0,0 +1,238 @@
+// Package portal signs customers in to the hosted customer portal and
+// embeddable "My Rewards" widgets. Customers prove they own their phone
+// number with a one-time code sent over a messaging channel and get a
+// short-lived customer token for the portal API.
+package portal
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/auth"
+	"github.com/bmachimbira/loyalty/api/internal/channels"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/metering"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// codeDigits is the length of a sign-in code
+	codeDigits = 6
+	// codeTTL is how long a sign-in code can be used
+	codeTTL = 5 * time.Minute
+	// maxCodeAttempts is how many wrong guesses lock a code
+	maxCodeAttempts = 5
+	// maxCodesPerWindow is how many codes a customer can request per
+	// codeWindow, so the portal cannot be used to spam them
+	maxCodesPerWindow = 3
+	codeWindow        = 15 * time.Minute
+)
+
+// customerActive is the status of customers who can sign in
+const customerActive = "active"
+
+var (
+	// ErrChannelUnavailable is returned when codes cannot be sent over the
+	// requested channel
+	ErrChannelUnavailable = errors.New("sign-in channel not available")
+
+	// ErrTooManyCodes is returned when the customer requested too many codes
+	// recently
+	ErrTooManyCodes = errors.New("too many sign-in codes requested")
+
+	// ErrInvalidCode is returned when the code is wrong, expired, used or
+	// locked, or the phone number is not enrolled
+	ErrInvalidCode = errors.New("invalid or expired sign-in code")
+)
+
+// Service issues and verifies customer sign-in codes
+type Service struct {
+	pool       *pgxpool.Pool
+	queries    *db.Queries
+	enrollment *channels.EnrollmentFlow
+	channels   map[string]channels.Channel
+	// keys sign customer tokens; jwtSecret keys sign-in code hashes
+	keys      auth.Keys
+	jwtSecret string
+}
+
+// NewService creates a new portal service. Codes can only be sent once a
+// channel is registered.
+func NewService(pool *pgxpool.Pool, queries *db.Queries, keys auth.Keys, jwtSecret string) *Service {
+	return &Service{
+		pool:       pool,
+		queries:    queries,
+		enrollment: channels.NewEnrollmentFlow(queries),
+		channels:   make(map[string]channels.Channel),
+		keys:       keys,
+		jwtSecret:  jwtSecret,
+	}
+}
+
+// RegisterChannel lets customers request sign-in codes over ch, under
+// ch.Name()
+func (s *Service) RegisterChannel(ch channels.Channel) {
+	s.channels[ch.Name()] = ch
+}
+
+// Session is a signed-in customer's token
+type Session struct {
+	AccessToken string
+	ExpiresIn   int64
+	CustomerID  pgtype.UUID
+}
+
+// RequestCode sends a sign-in code to the customer enrolled with the phone
+// number over the channel. Phone numbers that are not enrolled, or whose
+// customer is suspended or deleted, get no code but no error either, so the
+// endpoint cannot be used to find customers.
+func (s *Service) RequestCode(ctx context.Context, tenantID pgtype.UUID, phoneE164, channel string) error {
+	ch, ok := s.channels[channel]
+	if !ok {
+		return ErrChannelUnavailable
+	}
+
+	customer, err := s.enrollment.Find(ctx, tenantID, phoneE164)
+	if errors.Is(err, channels.ErrNotEnrolled) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if customer.Status != customerActive {
+		return nil
+	}
+
+	recent, err := s.queries.CountCustomerOTPsSince(ctx, db.CountCustomerOTPsSinceParams{
+		TenantID:   tenantID,
+		CustomerID: customer.ID,
+		Since:      pgtype.Timestamptz{Time: time.Now().Add(-codeWindow), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count sign-in codes: %w", err)
+	}
+	if recent >= maxCodesPerWindow {
+		return ErrTooManyCodes
+	}
+
+	code, err := generateCode()
+	if err != nil {
+		return fmt.Errorf("failed to generate sign-in code: %w", err)
+	}
+
+	_, err = s.queries.CreateCustomerOTP(ctx, db.CreateCustomerOTPParams{
+		TenantID:   tenantID,
+		CustomerID: customer.ID,
+		Channel:    channel,
+		CodeHash:   s.hashCode(customer.ID, code),
+		ExpiresAt:  pgtype.Timestamptz{Time: time.Now().Add(codeTTL), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create sign-in code: %w", err)
+	}
+
+	msg := fmt.Sprintf("Your loyalty sign-in code is %s. It expires in %d minutes. Don't share it with anyone.", code, int(codeTTL.Minutes()))
+	if err := ch.SendText(metering.WithTenant(ctx, tenantID), phoneE164, msg); err != nil {
+		return fmt.Errorf("failed to send sign-in code: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyCode exchanges the latest sign-in code sent to the phone number for
+// a customer token. The code is consumed on success; wrong guesses count
+// towards locking it.
+func (s *Service) VerifyCode(ctx context.Context, tenantID pgtype.UUID, phoneE164, code string) (*Session, error) {
+	customer, err := s.enrollment.Find(ctx, tenantID, phoneE164)
+	if errors.Is(err, channels.ErrNotEnrolled) {
+		return nil, ErrInvalidCode
+	}
+	if err != nil {
+		return nil, err
+	}
+	if customer.Status != customerActive {
+		return nil, ErrInvalidCode
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	otp, err := qtx.GetLatestCustomerOTP(ctx, db.GetLatestCustomerOTPParams{
+		TenantID:   tenantID,
+		CustomerID: customer.ID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidCode
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sign-in code: %w", err)
+	}
+
+	if otp.ConsumedAt.Valid || time.Now().After(otp.ExpiresAt.Time) || otp.Attempts >= maxCodeAttempts {
+		return nil, ErrInvalidCode
+	}
+
+	if !hmac.Equal([]byte(otp.CodeHash), []byte(s.hashCode(customer.ID, code))) {
+		if err := qtx.IncrementCustomerOTPAttempts(ctx, otp.ID); err != nil {
+			return nil, fmt.Errorf("failed to record sign-in attempt: %w", err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil, ErrInvalidCode
+	}
+
+	if _, err := qtx.ConsumeCustomerOTP(ctx, otp.ID); err != nil {
+		return nil, fmt.Errorf("failed to consume sign-in code: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	token, err := auth.GenerateCustomerToken(
+		httputil.FormatUUID(customer.ID.Bytes),
+		httputil.FormatUUID(tenantID.Bytes),
+		s.keys,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return &Session{
+		AccessToken: token,
+		ExpiresIn:   int64(auth.CustomerTokenTTL.Seconds()),
+		CustomerID:  customer.ID,
+	}, nil
+}
+
+// hashCode keys the stored hash to the server secret and the customer, so a
+// leaked hash cannot be checked against the small code space offline
+func (s *Service) hashCode(customerID pgtype.UUID, code string) string {
+	mac := hmac.New(sha256.New, []byte(s.jwtSecret))
+	mac.Write(customerID.Bytes[:])
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// generateCode returns a random numeric sign-in code
+func generateCode() (string, error) {
+	n, err := rand.Int(rand.Reader, new(big.Int).Exp(big.NewInt(10), big.NewInt(codeDigits), nil))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", codeDigits, n.Int64()), nil
+}
@@ -0,0 +1,31 @@
+package portal
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateCode(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := generateCode()
+		require.NoError(t, err)
+		assert.Len(t, code, codeDigits)
+		assert.Regexp(t, `^[0-9]+$`, code)
+	}
+}
+
+func TestHashCode(t *testing.T) {
+	s := &Service{jwtSecret: "secret"}
+	alice := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	bob := pgtype.UUID{Bytes: [16]byte{2}, Valid: true}
+
+	assert.Equal(t, s.hashCode(alice, "123456"), s.hashCode(alice, "123456"))
+	assert.NotEqual(t, s.hashCode(alice, "123456"), s.hashCode(alice, "123457"))
+	assert.NotEqual(t, s.hashCode(alice, "123456"), s.hashCode(bob, "123456"))
+
+	other := &Service{jwtSecret: "other"}
+	assert.NotEqual(t, s.hashCode(alice, "123456"), other.hashCode(alice, "123456"))
+}
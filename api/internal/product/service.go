@@ -0,0 +1,171 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MaxUploadRows is the largest product batch accepted in one upload
+const MaxUploadRows = 5000
+
+var (
+	// ErrProductNotFound is returned when no product exists for the SKU
+	ErrProductNotFound = errors.New("product not found")
+)
+
+// Params contains the fields of a product reference
+type Params struct {
+	SKU      string
+	Name     string
+	Category string
+	Active   bool
+}
+
+// Validate validates the product parameters
+func (p Params) Validate() error {
+	if strings.TrimSpace(p.SKU) == "" {
+		return errors.New("sku is required")
+	}
+	if strings.TrimSpace(p.Category) == "" {
+		return errors.New("category is required")
+	}
+	return nil
+}
+
+// RowError describes an invalid row in a bulk upload
+type RowError struct {
+	Row   int    `json:"row"`
+	SKU   string `json:"sku"`
+	Error string `json:"error"`
+}
+
+// Service handles product reference business logic
+type Service struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+}
+
+// NewService creates a new product service
+func NewService(pool *pgxpool.Pool, queries *db.Queries) *Service {
+	return &Service{
+		pool:    pool,
+		queries: queries,
+	}
+}
+
+// ValidateBatch checks every row of a bulk upload and reports the invalid ones.
+// A SKU appearing twice in the batch is an error since the result would
+// depend on row order.
+func ValidateBatch(products []Params) []RowError {
+	var rowErrors []RowError
+	seen := make(map[string]int, len(products))
+	for i, p := range products {
+		sku := strings.TrimSpace(p.SKU)
+		if err := p.Validate(); err != nil {
+			rowErrors = append(rowErrors, RowError{Row: i + 1, SKU: sku, Error: err.Error()})
+			continue
+		}
+		if first, ok := seen[sku]; ok {
+			rowErrors = append(rowErrors, RowError{Row: i + 1, SKU: sku, Error: fmt.Sprintf("duplicate of row %d", first+1)})
+			continue
+		}
+		seen[sku] = i
+	}
+	return rowErrors
+}
+
+// BulkUpsert creates or updates products by SKU in a single transaction.
+// Callers should run ValidateBatch first; any invalid row aborts the upload.
+func (s *Service) BulkUpsert(ctx context.Context, tenantID pgtype.UUID, products []Params) (int, error) {
+	if rowErrors := ValidateBatch(products); len(rowErrors) > 0 {
+		return 0, fmt.Errorf("row %d: %s", rowErrors[0].Row, rowErrors[0].Error)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	for _, p := range products {
+		name := strings.TrimSpace(p.Name)
+		if _, err := qtx.UpsertProduct(ctx, db.UpsertProductParams{
+			TenantID: tenantID,
+			Sku:      strings.TrimSpace(p.SKU),
+			Name:     pgtype.Text{String: name, Valid: name != ""},
+			Category: strings.TrimSpace(p.Category),
+			Active:   p.Active,
+		}); err != nil {
+			return 0, fmt.Errorf("failed to upsert product %s: %w", p.SKU, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(products), nil
+}
+
+// GetBySKU retrieves a product by SKU
+func (s *Service) GetBySKU(ctx context.Context, tenantID pgtype.UUID, sku string) (db.Product, error) {
+	product, err := s.queries.GetProductBySKU(ctx, db.GetProductBySKUParams{
+		TenantID: tenantID,
+		Sku:      sku,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Product{}, ErrProductNotFound
+		}
+		return db.Product{}, fmt.Errorf("failed to get product: %w", err)
+	}
+	return product, nil
+}
+
+// List retrieves a paginated list of products, optionally filtered by category
+func (s *Service) List(ctx context.Context, tenantID pgtype.UUID, category, limit, offset string) ([]db.Product, int64, error) {
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil || limitInt < 1 {
+		limitInt = 50
+	}
+	if limitInt > 500 {
+		limitInt = 500
+	}
+
+	offsetInt, err := strconv.Atoi(offset)
+	if err != nil || offsetInt < 0 {
+		offsetInt = 0
+	}
+
+	categoryText := pgtype.Text{String: strings.TrimSpace(category), Valid: strings.TrimSpace(category) != ""}
+
+	products, err := s.queries.ListProducts(ctx, db.ListProductsParams{
+		TenantID: tenantID,
+		Category: categoryText,
+		Limit:    int32(limitInt),
+		Offset:   int32(offsetInt),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list products: %w", err)
+	}
+
+	total, err := s.queries.CountProducts(ctx, db.CountProductsParams{
+		TenantID: tenantID,
+		Category: categoryText,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count products: %w", err)
+	}
+
+	return products, total, nil
+}
@@ -0,0 +1,178 @@
+// Package promo runs customer-entered promo codes. Tenants create batches of
+// codes tied to a rule whose event type is promo_code; a customer enters a
+// code over WhatsApp, USSD or the API, and an accepted code emits a
+// promo_code event that runs only the batch's rule, so the rule's
+// conditions, caps and budget decide the reward.
+//
+// A unique batch has generated single-use codes; a shared batch has one code
+// with an optional cap on total uses. Customers who keep entering codes that
+// are rejected are throttled so codes can't be guessed.
+package promo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/reward/codes"
+	"github.com/bmachimbira/loyalty/api/internal/rewardtypes"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Batch kinds
+const (
+	// KindUnique batches have generated codes that can each be used once
+	KindUnique = "unique"
+	// KindShared batches have one code that many customers can use
+	KindShared = "shared"
+)
+
+const (
+	// uniqueCodeLength is the length of a generated code before its prefix
+	// and check character
+	uniqueCodeLength = 8
+
+	// MaxBatchSize is the most codes a unique batch can generate
+	MaxBatchSize = 10000
+
+	minSharedCodeLength = 4
+	maxSharedCodeLength = 20
+)
+
+// Throttling of customers entering codes that are rejected
+const (
+	// MaxFailedAttempts is how many rejected codes a customer can enter
+	// within AttemptWindow before further attempts are refused
+	MaxFailedAttempts = 5
+
+	// AttemptWindow is the period over which rejected codes are counted
+	AttemptWindow = 15 * time.Minute
+)
+
+// BatchParams contains the fields of a promo batch
+type BatchParams struct {
+	TenantID pgtype.UUID
+	Name     string
+	RuleID   pgtype.UUID
+	Kind     string
+	// Count is the number of codes to generate for a unique batch
+	Count int
+	// Prefix is prepended to a unique batch's generated codes
+	Prefix string
+	// Code is a shared batch's code
+	Code string
+	// MaxUses caps the total uses of a shared batch's code
+	MaxUses *int32
+	// PerCustomerLimit is how many times a customer can use the batch
+	PerCustomerLimit int32
+	StartsAt         *time.Time
+	EndsAt           *time.Time
+}
+
+// Validate validates the batch parameters. Whether the rule exists and
+// handles promo_code events is checked by the service.
+func (p BatchParams) Validate() error {
+	if !p.TenantID.Valid {
+		return errors.New("tenant_id is required")
+	}
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("name is required")
+	}
+	if !p.RuleID.Valid {
+		return errors.New("rule_id is required")
+	}
+
+	switch p.Kind {
+	case KindUnique:
+		if p.Count < 1 || p.Count > MaxBatchSize {
+			return fmt.Errorf("count must be between 1 and %d", MaxBatchSize)
+		}
+		if p.Code != "" {
+			return errors.New("code is only supported by shared batches")
+		}
+		if p.MaxUses != nil {
+			return errors.New("max_uses is only supported by shared batches")
+		}
+		if err := codes.Validate(uniqueCodeConfig(p.Prefix)); err != nil {
+			return errors.New("prefix must be at most 6 letters and digits")
+		}
+	case KindShared:
+		if err := ValidateSharedCode(Normalize(p.Code)); err != nil {
+			return err
+		}
+		if p.Count != 0 || p.Prefix != "" {
+			return errors.New("count and prefix are only supported by unique batches")
+		}
+		if p.MaxUses != nil && *p.MaxUses < 1 {
+			return errors.New("max_uses must be at least 1")
+		}
+	default:
+		return errors.New("kind must be unique or shared")
+	}
+
+	if p.PerCustomerLimit < 1 {
+		return errors.New("per_customer_limit must be at least 1")
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// ValidateSharedCode checks a normalized shared code
+func ValidateSharedCode(code string) error {
+	if len(code) < minSharedCodeLength || len(code) > maxSharedCodeLength {
+		return fmt.Errorf("code must be between %d and %d characters", minSharedCodeLength, maxSharedCodeLength)
+	}
+	for _, c := range code {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return errors.New("code must contain only letters and digits")
+		}
+	}
+	return nil
+}
+
+// Normalize returns a code as stored: upper case without spaces or dashes,
+// so "summer-25" and "SUMMER 25" both match SUMMER25
+func Normalize(code string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '-':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(code))
+}
+
+// uniqueCodeConfig is the code configuration of a unique batch. The check
+// character catches most mistyped codes.
+func uniqueCodeConfig(prefix string) rewardtypes.CodeConfig {
+	return rewardtypes.CodeConfig{
+		Strategy: codes.StrategyChecksum,
+		Length:   uniqueCodeLength,
+		Prefix:   strings.ToUpper(prefix),
+	}
+}
+
+// useLimitReached reports whether a code has no uses left
+func useLimitReached(kind string, uses int32, maxUses pgtype.Int4) bool {
+	if kind == KindUnique {
+		return uses >= 1
+	}
+	return maxUses.Valid && uses >= maxUses.Int32
+}
+
+// activeAt reports whether a batch accepts codes at now
+func activeAt(active bool, startsAt, endsAt pgtype.Timestamptz, now time.Time) bool {
+	if !active {
+		return false
+	}
+	if startsAt.Valid && now.Before(startsAt.Time) {
+		return false
+	}
+	if endsAt.Valid && !now.Before(endsAt.Time) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package promo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/reward/codes"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func id(b byte) pgtype.UUID {
+	return pgtype.UUID{Bytes: [16]byte{b}, Valid: true}
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "SUMMER25", Normalize("summer-25"))
+	assert.Equal(t, "SUMMER25", Normalize(" Summer 25 "))
+	assert.Equal(t, "", Normalize(" - "))
+}
+
+func TestBatchParamsValidate(t *testing.T) {
+	maxUses := int32(100)
+	zero := int32(0)
+	start := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(-time.Hour)
+
+	unique := BatchParams{
+		TenantID:         id(1),
+		Name:             "Flyer codes",
+		RuleID:           id(2),
+		Kind:             KindUnique,
+		Count:            500,
+		Prefix:           "fly",
+		PerCustomerLimit: 1,
+	}
+	require.NoError(t, unique.Validate())
+
+	shared := BatchParams{
+		TenantID:         id(1),
+		Name:             "Summer",
+		RuleID:           id(2),
+		Kind:             KindShared,
+		Code:             "summer-25",
+		MaxUses:          &maxUses,
+		PerCustomerLimit: 1,
+	}
+	require.NoError(t, shared.Validate())
+
+	tests := []struct {
+		name   string
+		params BatchParams
+		modify func(p *BatchParams)
+	}{
+		{"missing name", unique, func(p *BatchParams) { p.Name = " " }},
+		{"missing rule", unique, func(p *BatchParams) { p.RuleID = pgtype.UUID{} }},
+		{"unknown kind", unique, func(p *BatchParams) { p.Kind = "single" }},
+		{"zero count", unique, func(p *BatchParams) { p.Count = 0 }},
+		{"count too large", unique, func(p *BatchParams) { p.Count = MaxBatchSize + 1 }},
+		{"unique with code", unique, func(p *BatchParams) { p.Code = "SUMMER25" }},
+		{"unique with max uses", unique, func(p *BatchParams) { p.MaxUses = &maxUses }},
+		{"prefix too long", unique, func(p *BatchParams) { p.Prefix = "FLYERS1" }},
+		{"prefix not alphanumeric", unique, func(p *BatchParams) { p.Prefix = "FL_" }},
+		{"shared code too short", shared, func(p *BatchParams) { p.Code = "ab" }},
+		{"shared code not alphanumeric", shared, func(p *BatchParams) { p.Code = "SUMMER!" }},
+		{"shared with count", shared, func(p *BatchParams) { p.Count = 10 }},
+		{"zero max uses", shared, func(p *BatchParams) { p.MaxUses = &zero }},
+		{"zero per customer limit", shared, func(p *BatchParams) { p.PerCustomerLimit = 0 }},
+		{"ends before start", shared, func(p *BatchParams) { p.StartsAt, p.EndsAt = &start, &end }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.params
+			tt.modify(&p)
+			assert.Error(t, p.Validate())
+		})
+	}
+}
+
+func TestUniqueCodeConfig(t *testing.T) {
+	code, err := codes.New(uniqueCodeConfig("fly"))
+	require.NoError(t, err)
+	assert.Len(t, code, 3+uniqueCodeLength+1)
+	assert.True(t, codes.ValidChecksum(code, "FLY"))
+}
+
+func TestUseLimitReached(t *testing.T) {
+	assert.False(t, useLimitReached(KindUnique, 0, pgtype.Int4{}))
+	assert.True(t, useLimitReached(KindUnique, 1, pgtype.Int4{}))
+
+	assert.False(t, useLimitReached(KindShared, 1000, pgtype.Int4{}))
+	assert.False(t, useLimitReached(KindShared, 9, pgtype.Int4{Int32: 10, Valid: true}))
+	assert.True(t, useLimitReached(KindShared, 10, pgtype.Int4{Int32: 10, Valid: true}))
+}
+
+func TestActiveAt(t *testing.T) {
+	now := time.Date(2025, 12, 24, 12, 0, 0, 0, time.UTC)
+	at := func(t time.Time) pgtype.Timestamptz { return pgtype.Timestamptz{Time: t, Valid: true} }
+	none := pgtype.Timestamptz{}
+
+	assert.True(t, activeAt(true, none, none, now))
+	assert.False(t, activeAt(false, none, none, now))
+	assert.True(t, activeAt(true, at(now.Add(-time.Hour)), at(now.Add(time.Hour)), now))
+	assert.False(t, activeAt(true, at(now.Add(time.Hour)), none, now))
+	assert.False(t, activeAt(true, none, at(now), now))
+}
+
+func TestIsRejection(t *testing.T) {
+	assert.True(t, isRejection(ErrInvalidCode))
+	assert.True(t, isRejection(ErrAlreadyRedeemed))
+	assert.False(t, isRejection(ErrTooManyAttempts))
+	assert.False(t, isRejection(assert.AnError))
+}
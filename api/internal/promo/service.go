@@ -0,0 +1,464 @@
+package promo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/deadletter"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/reward/codes"
+	"github.com/bmachimbira/loyalty/api/internal/rules"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxGenerateRounds bounds how many times codes that collided with existing
+// ones are regenerated
+const maxGenerateRounds = 5
+
+var (
+	// ErrBatchNotFound is returned when the batch does not exist
+	ErrBatchNotFound = errors.New("promo batch not found")
+
+	// ErrRuleNotFound is returned when the batch's rule does not exist
+	ErrRuleNotFound = errors.New("rule not found")
+
+	// ErrRuleNotPromo is returned for a batch whose rule doesn't handle
+	// promo_code events
+	ErrRuleNotPromo = errors.New("rule event_type must be promo_code")
+
+	// ErrCodeTaken is returned when a shared batch's code is already in use
+	ErrCodeTaken = errors.New("promo code already exists")
+
+	// ErrNoUniqueCodes is returned when a unique batch could not generate
+	// enough codes that don't collide with existing ones
+	ErrNoUniqueCodes = errors.New("could not generate unique promo codes")
+
+	// ErrInvalidCode is returned for a code that doesn't exist
+	ErrInvalidCode = errors.New("invalid promo code")
+
+	// ErrCodeNotActive is returned for a code whose batch is deactivated or
+	// outside its validity window
+	ErrCodeNotActive = errors.New("promo code is not active")
+
+	// ErrCodeUsedUp is returned for a code with no uses left
+	ErrCodeUsedUp = errors.New("promo code has been used up")
+
+	// ErrAlreadyRedeemed is returned when the customer has used the batch's
+	// codes as many times as allowed
+	ErrAlreadyRedeemed = errors.New("promo code already redeemed")
+
+	// ErrTooManyAttempts is returned when the customer has entered too many
+	// rejected codes recently
+	ErrTooManyAttempts = errors.New("too many promo code attempts")
+)
+
+// Result is the outcome of a redeemed promo code
+type Result struct {
+	Batch      db.PromoBatch
+	Redemption db.PromoRedemption
+	Event      db.Event
+	// Issuances are the rewards granted by the batch's rule
+	Issuances []db.Issuance
+}
+
+// Service manages promo batches and redeems codes entered by customers
+type Service struct {
+	pool        *pgxpool.Pool
+	queries     *db.Queries
+	processor   deadletter.EventProcessor
+	deadLetters *deadletter.Service
+	logger      *slog.Logger
+}
+
+// NewService creates a new promo service. promo_code events are run through
+// processor so the batch's rule can grant the reward.
+func NewService(pool *pgxpool.Pool, queries *db.Queries, processor deadletter.EventProcessor, logger *slog.Logger) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Service{
+		pool:        pool,
+		queries:     queries,
+		processor:   processor,
+		deadLetters: deadletter.NewService(queries, processor),
+		logger:      logger,
+	}
+}
+
+// CreateBatch creates a batch with its codes: the generated codes of a
+// unique batch or the one code of a shared batch
+func (s *Service) CreateBatch(ctx context.Context, params BatchParams) (db.PromoBatch, error) {
+	if err := params.Validate(); err != nil {
+		return db.PromoBatch{}, err
+	}
+
+	rule, err := s.queries.GetRuleByID(ctx, db.GetRuleByIDParams{
+		ID:       params.RuleID,
+		TenantID: params.TenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.PromoBatch{}, ErrRuleNotFound
+		}
+		return db.PromoBatch{}, fmt.Errorf("failed to get rule: %w", err)
+	}
+	if rule.EventType != rules.EventTypePromoCode {
+		return db.PromoBatch{}, ErrRuleNotPromo
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.PromoBatch{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	createParams := db.CreatePromoBatchParams{
+		TenantID:         params.TenantID,
+		Name:             params.Name,
+		RuleID:           params.RuleID,
+		Kind:             params.Kind,
+		PerCustomerLimit: params.PerCustomerLimit,
+	}
+	if params.MaxUses != nil {
+		createParams.MaxUses = pgtype.Int4{Int32: *params.MaxUses, Valid: true}
+	}
+	if params.StartsAt != nil {
+		createParams.StartsAt = pgtype.Timestamptz{Time: *params.StartsAt, Valid: true}
+	}
+	if params.EndsAt != nil {
+		createParams.EndsAt = pgtype.Timestamptz{Time: *params.EndsAt, Valid: true}
+	}
+
+	batch, err := qtx.CreatePromoBatch(ctx, createParams)
+	if err != nil {
+		return db.PromoBatch{}, fmt.Errorf("failed to create promo batch: %w", err)
+	}
+
+	if params.Kind == KindShared {
+		inserted, err := qtx.InsertPromoCodes(ctx, db.InsertPromoCodesParams{
+			TenantID: batch.TenantID,
+			BatchID:  batch.ID,
+			Codes:    []string{Normalize(params.Code)},
+		})
+		if err != nil {
+			return db.PromoBatch{}, fmt.Errorf("failed to insert promo code: %w", err)
+		}
+		if inserted == 0 {
+			return db.PromoBatch{}, ErrCodeTaken
+		}
+	} else if err := generateCodes(ctx, qtx, batch, params.Count, params.Prefix); err != nil {
+		return db.PromoBatch{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.PromoBatch{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return batch, nil
+}
+
+// GetBatch returns a batch by ID
+func (s *Service) GetBatch(ctx context.Context, tenantID, batchID pgtype.UUID) (db.PromoBatch, error) {
+	batch, err := s.queries.GetPromoBatchByID(ctx, db.GetPromoBatchByIDParams{
+		ID:       batchID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.PromoBatch{}, ErrBatchNotFound
+		}
+		return db.PromoBatch{}, fmt.Errorf("failed to get promo batch: %w", err)
+	}
+	return batch, nil
+}
+
+// ListBatches returns a tenant's batches, newest first, with their code and
+// redemption counts
+func (s *Service) ListBatches(ctx context.Context, tenantID pgtype.UUID) ([]db.ListPromoBatchesRow, error) {
+	batches, err := s.queries.ListPromoBatches(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list promo batches: %w", err)
+	}
+	return batches, nil
+}
+
+// SetActive activates or deactivates a batch. A deactivated batch's codes
+// are rejected.
+func (s *Service) SetActive(ctx context.Context, tenantID, batchID pgtype.UUID, active bool) (db.PromoBatch, error) {
+	batch, err := s.queries.SetPromoBatchActive(ctx, db.SetPromoBatchActiveParams{
+		ID:       batchID,
+		TenantID: tenantID,
+		Active:   active,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.PromoBatch{}, ErrBatchNotFound
+		}
+		return db.PromoBatch{}, fmt.Errorf("failed to update promo batch: %w", err)
+	}
+	return batch, nil
+}
+
+// ListCodes returns a page of a batch's codes, e.g. to print or send out,
+// and the batch's number of codes. Pages are up to 1000 codes.
+func (s *Service) ListCodes(ctx context.Context, tenantID, batchID pgtype.UUID, limit, offset string) ([]db.PromoCode, int64, error) {
+	if _, err := s.GetBatch(ctx, tenantID, batchID); err != nil {
+		return nil, 0, err
+	}
+
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil || limitInt < 1 {
+		limitInt = 100
+	}
+	if limitInt > 1000 {
+		limitInt = 1000
+	}
+	offsetInt, err := strconv.Atoi(offset)
+	if err != nil || offsetInt < 0 {
+		offsetInt = 0
+	}
+
+	promoCodes, err := s.queries.ListPromoCodes(ctx, db.ListPromoCodesParams{
+		TenantID: tenantID,
+		BatchID:  batchID,
+		Limit:    int32(limitInt),
+		Offset:   int32(offsetInt),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list promo codes: %w", err)
+	}
+
+	total, err := s.queries.CountPromoCodes(ctx, db.CountPromoCodesParams{
+		TenantID: tenantID,
+		BatchID:  batchID,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count promo codes: %w", err)
+	}
+	return promoCodes, total, nil
+}
+
+// Redeem redeems a code entered by a customer on channel. An accepted code
+// emits a promo_code event that is run through the rules engine; a reward
+// that fails to issue is parked as a dead letter. A rejected code counts
+// towards the customer's throttle.
+func (s *Service) Redeem(ctx context.Context, tenantID, customerID pgtype.UUID, code, channel string, now time.Time) (*Result, error) {
+	code = Normalize(code)
+
+	failures, err := s.queries.CountRecentPromoFailures(ctx, db.CountRecentPromoFailuresParams{
+		TenantID:   tenantID,
+		CustomerID: customerID,
+		Since:      pgtype.Timestamptz{Time: now.Add(-AttemptWindow), Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count promo attempts: %w", err)
+	}
+	if failures >= MaxFailedAttempts {
+		return nil, ErrTooManyAttempts
+	}
+
+	result, err := s.redeem(ctx, tenantID, customerID, code, channel, now)
+	if err != nil {
+		if isRejection(err) {
+			if attemptErr := s.queries.InsertPromoAttempt(ctx, db.InsertPromoAttemptParams{
+				TenantID:   tenantID,
+				CustomerID: customerID,
+				Code:       code,
+				Channel:    channel,
+				Succeeded:  false,
+			}); attemptErr != nil {
+				s.logger.Error("failed to record promo attempt", "customer_id", customerID, "error", attemptErr)
+			}
+		}
+		return nil, err
+	}
+
+	issuances, err := s.processor.ProcessEvent(ctx, result.Event)
+	if err != nil {
+		// The code is used; park the event so the reward can be retried
+		s.logger.Error("rules engine processing failed",
+			"event_id", result.Event.ID,
+			"promo_batch_id", result.Batch.ID,
+			"error", err,
+		)
+		if _, dlqErr := s.deadLetters.Record(ctx, result.Event, err); dlqErr != nil {
+			s.logger.Error("failed to record dead letter", "event_id", result.Event.ID, "error", dlqErr)
+		}
+		return result, nil
+	}
+	result.Issuances = issuances
+	return result, nil
+}
+
+// redeem checks and uses a code and records its promo_code event
+func (s *Service) redeem(ctx context.Context, tenantID, customerID pgtype.UUID, code, channel string, now time.Time) (*Result, error) {
+	if code == "" {
+		return nil, ErrInvalidCode
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	row, err := qtx.GetPromoCodeForUpdate(ctx, db.GetPromoCodeForUpdateParams{
+		TenantID: tenantID,
+		Code:     code,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidCode
+		}
+		return nil, fmt.Errorf("failed to get promo code: %w", err)
+	}
+	promoCode, batch := row.PromoCode, row.PromoBatch
+
+	if !activeAt(batch.Active, batch.StartsAt, batch.EndsAt, now) {
+		return nil, ErrCodeNotActive
+	}
+	if useLimitReached(batch.Kind, promoCode.Uses, batch.MaxUses) {
+		return nil, ErrCodeUsedUp
+	}
+
+	if err := qtx.LockCustomerPromoBatch(ctx, db.LockCustomerPromoBatchParams{
+		BatchID:    batch.ID,
+		CustomerID: customerID,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to lock customer promo batch: %w", err)
+	}
+	redeemed, err := qtx.CountCustomerPromoRedemptions(ctx, db.CountCustomerPromoRedemptionsParams{
+		TenantID:   tenantID,
+		BatchID:    batch.ID,
+		CustomerID: customerID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count promo redemptions: %w", err)
+	}
+	if redeemed >= int64(batch.PerCustomerLimit) {
+		return nil, ErrAlreadyRedeemed
+	}
+
+	if err := qtx.IncrementPromoCodeUses(ctx, db.IncrementPromoCodeUsesParams{
+		ID:       promoCode.ID,
+		TenantID: tenantID,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to use promo code: %w", err)
+	}
+
+	evt, err := insertPromoEvent(ctx, qtx, batch, promoCode, customerID, channel, now)
+	if err != nil {
+		return nil, err
+	}
+
+	redemption, err := qtx.InsertPromoRedemption(ctx, db.InsertPromoRedemptionParams{
+		TenantID:   tenantID,
+		BatchID:    batch.ID,
+		CodeID:     promoCode.ID,
+		CustomerID: customerID,
+		EventID:    evt.ID,
+		Channel:    channel,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record promo redemption: %w", err)
+	}
+
+	if err := qtx.InsertPromoAttempt(ctx, db.InsertPromoAttemptParams{
+		TenantID:   tenantID,
+		CustomerID: customerID,
+		Code:       code,
+		Channel:    channel,
+		Succeeded:  true,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to record promo attempt: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &Result{Batch: batch, Redemption: redemption, Event: evt}, nil
+}
+
+// generateCodes generates count codes for a unique batch, regenerating any
+// that collide with codes already in the tenant
+func generateCodes(ctx context.Context, qtx *db.Queries, batch db.PromoBatch, count int, prefix string) error {
+	cfg := uniqueCodeConfig(prefix)
+	remaining := count
+	for round := 0; round < maxGenerateRounds && remaining > 0; round++ {
+		generated := make(map[string]bool, remaining)
+		batchCodes := make([]string, 0, remaining)
+		for len(batchCodes) < remaining {
+			code, err := codes.New(cfg)
+			if err != nil {
+				return fmt.Errorf("failed to generate promo code: %w", err)
+			}
+			if !generated[code] {
+				generated[code] = true
+				batchCodes = append(batchCodes, code)
+			}
+		}
+
+		inserted, err := qtx.InsertPromoCodes(ctx, db.InsertPromoCodesParams{
+			TenantID: batch.TenantID,
+			BatchID:  batch.ID,
+			Codes:    batchCodes,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to insert promo codes: %w", err)
+		}
+		remaining -= int(inserted)
+	}
+	if remaining > 0 {
+		return ErrNoUniqueCodes
+	}
+	return nil
+}
+
+// insertPromoEvent records the promo_code event for a used code. The key
+// is unique per use, so each use is one event.
+func insertPromoEvent(ctx context.Context, qtx *db.Queries, batch db.PromoBatch, promoCode db.PromoCode, customerID pgtype.UUID, channel string, now time.Time) (db.Event, error) {
+	properties, err := json.Marshal(map[string]interface{}{
+		"promo_batch_id": httputil.FormatUUID(batch.ID.Bytes),
+		"promo_batch":    batch.Name,
+		"rule_id":        httputil.FormatUUID(batch.RuleID.Bytes),
+		"code":           promoCode.Code,
+		"channel":        channel,
+	})
+	if err != nil {
+		return db.Event{}, fmt.Errorf("failed to marshal event properties: %w", err)
+	}
+
+	evt, err := qtx.InsertEvent(ctx, db.InsertEventParams{
+		TenantID:       batch.TenantID,
+		CustomerID:     customerID,
+		EventType:      rules.EventTypePromoCode,
+		Properties:     properties,
+		OccurredAt:     pgtype.Timestamptz{Time: now, Valid: true},
+		Source:         rules.PromoSource,
+		IdempotencyKey: "promo:" + httputil.FormatUUID(promoCode.ID.Bytes) + ":" + strconv.Itoa(int(promoCode.Uses+1)),
+	})
+	if err != nil {
+		return db.Event{}, fmt.Errorf("failed to create event: %w", err)
+	}
+	return evt, nil
+}
+
+// isRejection reports whether err rejected the code itself, as opposed to
+// failing to check it
+func isRejection(err error) bool {
+	return errors.Is(err, ErrInvalidCode) ||
+		errors.Is(err, ErrCodeNotActive) ||
+		errors.Is(err, ErrCodeUsedUp) ||
+		errors.Is(err, ErrAlreadyRedeemed)
+}
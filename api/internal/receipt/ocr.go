@@ -0,0 +1,224 @@
+package receipt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Extraction holds the fields read from a receipt image. Fields the provider
+// could not read are left empty for staff to fill in during review.
+type Extraction struct {
+	Text      string
+	Amount    string // Decimal string to avoid floating point precision issues
+	Currency  string
+	Date      *time.Time
+	StoreName string
+}
+
+// Provider extracts receipt fields from an image
+type Provider interface {
+	// Name returns the provider's unique identifier
+	Name() string
+
+	// Extract reads the receipt image and returns the extracted fields
+	Extract(ctx context.Context, image []byte, mimeType string) (*Extraction, error)
+}
+
+// NewProvider creates the OCR provider with the given name. An empty name
+// disables OCR; receipts are then reviewed entirely by hand.
+func NewProvider(name, endpoint, apiKey string) (Provider, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case "http":
+		if endpoint == "" {
+			return nil, fmt.Errorf("http OCR provider requires an endpoint")
+		}
+		return NewHTTPProvider(endpoint, apiKey), nil
+	default:
+		return nil, fmt.Errorf("unknown OCR provider: %s", name)
+	}
+}
+
+// HTTPProvider sends receipt images to an OCR service over HTTP. The service
+// receives the raw image as the request body and must respond with JSON
+// containing at least "text"; structured fields it returns take precedence
+// over those parsed from the text.
+type HTTPProvider struct {
+	client   *http.Client
+	endpoint string
+	apiKey   string
+}
+
+// httpOCRResponse is the response expected from an HTTP OCR service
+type httpOCRResponse struct {
+	Text      string `json:"text"`
+	Amount    string `json:"amount"`
+	Currency  string `json:"currency"`
+	Date      string `json:"date"`
+	StoreName string `json:"store_name"`
+}
+
+// NewHTTPProvider creates a new HTTP OCR provider
+func NewHTTPProvider(endpoint, apiKey string) *HTTPProvider {
+	return &HTTPProvider{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		endpoint: endpoint,
+		apiKey:   apiKey,
+	}
+}
+
+// Name returns the provider name
+func (p *HTTPProvider) Name() string {
+	return "http"
+}
+
+// Extract sends the image to the OCR service
+func (p *HTTPProvider) Extract(ctx context.Context, image []byte, mimeType string) (*Extraction, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", p.endpoint, bytes.NewReader(image))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", mimeType)
+	req.Header.Set("Accept", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("OCR service returned status %d", resp.StatusCode)
+	}
+
+	var result httpOCRResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	extraction := ParseText(result.Text)
+	if result.Amount != "" {
+		extraction.Amount = result.Amount
+	}
+	if result.Currency != "" {
+		extraction.Currency = strings.ToUpper(result.Currency)
+	}
+	if result.Date != "" {
+		if date, ok := parseDate(result.Date); ok {
+			extraction.Date = &date
+		}
+	}
+	if result.StoreName != "" {
+		extraction.StoreName = result.StoreName
+	}
+
+	return extraction, nil
+}
+
+var (
+	// amountRegex matches a money amount such as 12.50, 1,234.00 or 12,50
+	amountRegex = regexp.MustCompile(`\d{1,3}(?:[ ,]\d{3})*[.,]\d{2}\b|\d+[.,]\d{2}\b`)
+
+	// dateRegexes match the date layouts commonly printed on receipts
+	dateRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`),
+		regexp.MustCompile(`\b\d{1,2}[/.-]\d{1,2}[/.-]\d{4}\b`),
+		regexp.MustCompile(`(?i)\b\d{1,2} (?:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]* \d{4}\b`),
+	}
+
+	// dateLayouts are tried in order; day-first layouts are preferred
+	// because that is how local receipts print dates
+	dateLayouts = []string{
+		"2006-01-02",
+		"02/01/2006", "2/1/2006",
+		"02-01-2006", "2-1-2006",
+		"02.01.2006", "2.1.2006",
+		"02 Jan 2006", "2 Jan 2006",
+		"02 January 2006", "2 January 2006",
+	}
+)
+
+// ParseText extracts receipt fields from OCR text using simple heuristics:
+// the amount comes from the last "total" line that is not a subtotal, the
+// date from the first recognisable date, and the store name from the first
+// line containing letters.
+func ParseText(text string) *Extraction {
+	extraction := &Extraction{Text: text}
+
+	lines := strings.Split(text, "\n")
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if extraction.StoreName == "" && strings.IndexFunc(trimmed, isLetter) >= 0 {
+			extraction.StoreName = trimmed
+		}
+
+		upper := strings.ToUpper(trimmed)
+		if strings.Contains(upper, "TOTAL") && !strings.Contains(upper, "SUBTOTAL") && !strings.Contains(upper, "SUB TOTAL") {
+			if amounts := amountRegex.FindAllString(trimmed, -1); len(amounts) > 0 {
+				extraction.Amount = normalizeAmount(amounts[len(amounts)-1])
+			}
+		}
+
+		if extraction.Date == nil {
+			for _, re := range dateRegexes {
+				if match := re.FindString(trimmed); match != "" {
+					if date, ok := parseDate(match); ok {
+						extraction.Date = &date
+						break
+					}
+				}
+			}
+		}
+	}
+
+	upperText := strings.ToUpper(text)
+	switch {
+	case strings.Contains(upperText, "ZWG") || strings.Contains(upperText, "ZIG"):
+		extraction.Currency = "ZWG"
+	case strings.Contains(upperText, "USD") || strings.Contains(upperText, "US$") || strings.Contains(text, "$"):
+		extraction.Currency = "USD"
+	}
+
+	return extraction
+}
+
+// normalizeAmount converts a printed amount to a plain decimal string
+func normalizeAmount(amount string) string {
+	amount = strings.ReplaceAll(amount, " ", "")
+	// The last separator is the decimal point; any others group thousands
+	decimal := amount[len(amount)-3:]
+	whole := strings.NewReplacer(",", "", ".", "").Replace(amount[:len(amount)-3])
+	return whole + "." + decimal[1:]
+}
+
+// parseDate parses a date in any of the supported layouts
+func parseDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func isLetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
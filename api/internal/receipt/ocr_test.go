@@ -0,0 +1,63 @@
+package receipt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseText(t *testing.T) {
+	text := `OK MART BORROWDALE
+Harare
+Date: 14/11/2025 10:32
+Bread            1.20
+Milk             2,30
+SUBTOTAL         3.50
+VAT              0.00
+TOTAL USD        3.50
+Cash             5.00`
+
+	extraction := ParseText(text)
+
+	assert.Equal(t, "OK MART BORROWDALE", extraction.StoreName)
+	assert.Equal(t, "3.50", extraction.Amount)
+	assert.Equal(t, "USD", extraction.Currency)
+	require.NotNil(t, extraction.Date)
+	assert.Equal(t, time.Date(2025, 11, 14, 0, 0, 0, 0, time.UTC), *extraction.Date)
+}
+
+func TestParseText_ThousandsAndZWG(t *testing.T) {
+	extraction := ParseText("Spar\n2 Nov 2025\nGRAND TOTAL ZWG 1,234.50")
+
+	assert.Equal(t, "1234.50", extraction.Amount)
+	assert.Equal(t, "ZWG", extraction.Currency)
+	require.NotNil(t, extraction.Date)
+	assert.Equal(t, time.November, extraction.Date.Month())
+}
+
+func TestParseText_Unreadable(t *testing.T) {
+	extraction := ParseText("")
+
+	assert.Empty(t, extraction.Amount)
+	assert.Empty(t, extraction.Currency)
+	assert.Empty(t, extraction.StoreName)
+	assert.Nil(t, extraction.Date)
+}
+
+func TestNewProvider(t *testing.T) {
+	provider, err := NewProvider("", "", "")
+	require.NoError(t, err)
+	assert.Nil(t, provider)
+
+	_, err = NewProvider("http", "", "")
+	assert.Error(t, err)
+
+	provider, err = NewProvider("http", "http://ocr.local/extract", "key")
+	require.NoError(t, err)
+	assert.Equal(t, "http", provider.Name())
+
+	_, err = NewProvider("unknown", "", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,456 @@
+package receipt
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/location"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MaxImageBytes is the largest receipt image accepted
+const MaxImageBytes = 5 << 20
+
+// Receipt statuses
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+)
+
+// Receipt sources
+const (
+	SourceUpload   = "upload"
+	SourceWhatsApp = "whatsapp"
+)
+
+// ocrTimeout bounds how long a submission waits for the OCR provider
+const ocrTimeout = 30 * time.Second
+
+var (
+	// ErrReceiptNotFound is returned when the receipt does not exist
+	ErrReceiptNotFound = errors.New("receipt not found")
+
+	// ErrDuplicateReceipt is returned when the same image was already submitted
+	ErrDuplicateReceipt = errors.New("receipt already submitted")
+
+	// ErrNotPending is returned when reviewing a receipt that was already reviewed
+	ErrNotPending = errors.New("receipt is not pending review")
+
+	// ErrCustomerNotFound is returned when the customer does not belong to the tenant
+	ErrCustomerNotFound = errors.New("customer not found")
+
+	// ErrUnsupportedImage is returned for images that are not JPEG, PNG or WebP
+	ErrUnsupportedImage = errors.New("receipt must be a JPEG, PNG or WebP image")
+
+	// ErrImageTooLarge is returned for images over MaxImageBytes
+	ErrImageTooLarge = errors.New("receipt image is too large")
+
+	// ErrInvalidStatus is returned when filtering by an unknown status
+	ErrInvalidStatus = errors.New("invalid receipt status")
+
+	// ErrInvalidAmount is returned when approving without a positive amount
+	ErrInvalidAmount = errors.New("amount is required and must be greater than 0")
+
+	// ErrInvalidCurrency is returned when approving without a supported currency
+	ErrInvalidCurrency = errors.New("currency is required and must be an ISO 4217 currency code")
+
+	// ErrInvalidLocation is returned when approving with an unknown or inactive location
+	ErrInvalidLocation = errors.New("unknown or inactive location")
+)
+
+// supportedMimeTypes lists the accepted receipt image types
+var supportedMimeTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// SubmitParams contains parameters for submitting a receipt
+type SubmitParams struct {
+	TenantID   pgtype.UUID
+	CustomerID pgtype.UUID
+	Source     string
+	MimeType   string
+	Image      []byte
+}
+
+// Validate validates the submit parameters
+func (p SubmitParams) Validate() error {
+	if !p.TenantID.Valid {
+		return errors.New("tenant_id is required")
+	}
+	if !p.CustomerID.Valid {
+		return errors.New("customer_id is required")
+	}
+	if p.Source != SourceUpload && p.Source != SourceWhatsApp {
+		return fmt.Errorf("invalid source: %s", p.Source)
+	}
+	if len(p.Image) == 0 {
+		return errors.New("image is required")
+	}
+	if len(p.Image) > MaxImageBytes {
+		return ErrImageTooLarge
+	}
+	if !supportedMimeTypes[p.MimeType] {
+		return ErrUnsupportedImage
+	}
+	return nil
+}
+
+// ApproveParams contains the reviewed values for approving a receipt. Empty
+// fields fall back to the values extracted by OCR.
+type ApproveParams struct {
+	TenantID   pgtype.UUID
+	ReceiptID  pgtype.UUID
+	ReviewerID pgtype.UUID
+	Amount     string
+	Currency   string
+	Date       *time.Time
+	LocationID pgtype.UUID
+}
+
+// Service manages receipt submission and review
+type Service struct {
+	pool      *pgxpool.Pool
+	queries   *db.Queries
+	provider  Provider
+	locations *location.Service
+}
+
+// NewService creates a new receipt service. provider may be nil, in which
+// case receipts are stored without OCR and reviewed entirely by hand.
+func NewService(pool *pgxpool.Pool, queries *db.Queries, provider Provider) *Service {
+	return &Service{
+		pool:      pool,
+		queries:   queries,
+		provider:  provider,
+		locations: location.NewService(queries),
+	}
+}
+
+// Submit stores a receipt image for review. OCR failures do not fail the
+// submission; the error is kept on the receipt for the reviewer.
+func (s *Service) Submit(ctx context.Context, params SubmitParams) (db.Receipt, error) {
+	if err := params.Validate(); err != nil {
+		return db.Receipt{}, err
+	}
+
+	if _, err := s.queries.GetCustomerByID(ctx, db.GetCustomerByIDParams{
+		ID:       params.CustomerID,
+		TenantID: params.TenantID,
+	}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Receipt{}, ErrCustomerNotFound
+		}
+		return db.Receipt{}, fmt.Errorf("failed to get customer: %w", err)
+	}
+
+	sum := sha256.Sum256(params.Image)
+	hash := hex.EncodeToString(sum[:])
+
+	// Reject resubmissions before paying for OCR
+	if _, err := s.queries.GetReceiptByHash(ctx, db.GetReceiptByHashParams{
+		TenantID:    params.TenantID,
+		ImageSha256: hash,
+	}); err == nil {
+		return db.Receipt{}, ErrDuplicateReceipt
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return db.Receipt{}, fmt.Errorf("failed to check for duplicate receipt: %w", err)
+	}
+
+	createParams := db.CreateReceiptParams{
+		TenantID:    params.TenantID,
+		CustomerID:  params.CustomerID,
+		Source:      params.Source,
+		MimeType:    params.MimeType,
+		ImageSha256: hash,
+	}
+	if s.provider != nil {
+		createParams.OcrProvider = pgtype.Text{String: s.provider.Name(), Valid: true}
+		if err := s.applyOCR(ctx, params, &createParams); err != nil {
+			slog.Warn("receipt OCR failed", "provider", s.provider.Name(), "error", err)
+			createParams.OcrError = pgtype.Text{String: err.Error(), Valid: true}
+		}
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.Receipt{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	receipt, err := qtx.CreateReceipt(ctx, createParams)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return db.Receipt{}, ErrDuplicateReceipt
+		}
+		return db.Receipt{}, fmt.Errorf("failed to create receipt: %w", err)
+	}
+
+	if err := qtx.CreateReceiptImage(ctx, db.CreateReceiptImageParams{
+		ReceiptID: receipt.ID,
+		TenantID:  params.TenantID,
+		Image:     params.Image,
+	}); err != nil {
+		return db.Receipt{}, fmt.Errorf("failed to store receipt image: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.Receipt{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return receipt, nil
+}
+
+// applyOCR runs the OCR provider and copies the extracted fields
+func (s *Service) applyOCR(ctx context.Context, params SubmitParams, createParams *db.CreateReceiptParams) error {
+	ocrCtx, cancel := context.WithTimeout(ctx, ocrTimeout)
+	defer cancel()
+
+	extraction, err := s.provider.Extract(ocrCtx, params.Image, params.MimeType)
+	if err != nil {
+		return err
+	}
+
+	createParams.OcrText = pgtype.Text{String: extraction.Text, Valid: extraction.Text != ""}
+	createParams.StoreName = pgtype.Text{String: extraction.StoreName, Valid: extraction.StoreName != ""}
+	if extraction.Amount != "" {
+		// Unparseable amounts are left for the reviewer
+		if err := createParams.Amount.Scan(extraction.Amount); err != nil {
+			createParams.Amount = pgtype.Numeric{}
+		}
+	}
+	if httputil.ValidateCurrency(extraction.Currency) == nil {
+		createParams.Currency = pgtype.Text{String: extraction.Currency, Valid: true}
+	}
+	if extraction.Date != nil {
+		createParams.ReceiptDate = pgtype.Date{Time: *extraction.Date, Valid: true}
+	}
+	return nil
+}
+
+// Get retrieves a receipt by ID
+func (s *Service) Get(ctx context.Context, tenantID, receiptID pgtype.UUID) (db.Receipt, error) {
+	receipt, err := s.queries.GetReceiptByID(ctx, db.GetReceiptByIDParams{
+		ID:       receiptID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Receipt{}, ErrReceiptNotFound
+		}
+		return db.Receipt{}, fmt.Errorf("failed to get receipt: %w", err)
+	}
+	return receipt, nil
+}
+
+// Image retrieves a receipt's image and MIME type
+func (s *Service) Image(ctx context.Context, tenantID, receiptID pgtype.UUID) ([]byte, string, error) {
+	image, err := s.queries.GetReceiptImage(ctx, db.GetReceiptImageParams{
+		ID:       receiptID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, "", ErrReceiptNotFound
+		}
+		return nil, "", fmt.Errorf("failed to get receipt image: %w", err)
+	}
+	return image.Image, image.MimeType, nil
+}
+
+// List lists receipts, optionally filtered by status
+func (s *Service) List(ctx context.Context, tenantID pgtype.UUID, status, limit, offset string) ([]db.Receipt, int64, error) {
+	if status != "" && status != StatusPending && status != StatusApproved && status != StatusRejected {
+		return nil, 0, ErrInvalidStatus
+	}
+
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil || limitInt < 1 {
+		limitInt = 50
+	}
+	if limitInt > 100 {
+		limitInt = 100
+	}
+
+	offsetInt, err := strconv.Atoi(offset)
+	if err != nil || offsetInt < 0 {
+		offsetInt = 0
+	}
+
+	statusText := pgtype.Text{String: status, Valid: status != ""}
+
+	receipts, err := s.queries.ListReceipts(ctx, db.ListReceiptsParams{
+		TenantID: tenantID,
+		Status:   statusText,
+		Limit:    int32(limitInt),
+		Offset:   int32(offsetInt),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list receipts: %w", err)
+	}
+
+	total, err := s.queries.CountReceipts(ctx, db.CountReceiptsParams{
+		TenantID: tenantID,
+		Status:   statusText,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count receipts: %w", err)
+	}
+
+	return receipts, total, nil
+}
+
+// Approve marks a pending receipt as approved and records a purchase event
+// for it. The caller is responsible for running the returned event through
+// the rules engine once the transaction has committed.
+func (s *Service) Approve(ctx context.Context, params ApproveParams) (db.Receipt, db.Event, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.Receipt{}, db.Event{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	receipt, err := qtx.GetReceiptForReview(ctx, db.GetReceiptForReviewParams{
+		ID:       params.ReceiptID,
+		TenantID: params.TenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Receipt{}, db.Event{}, ErrReceiptNotFound
+		}
+		return db.Receipt{}, db.Event{}, fmt.Errorf("failed to get receipt: %w", err)
+	}
+	if receipt.Status != StatusPending {
+		return db.Receipt{}, db.Event{}, ErrNotPending
+	}
+
+	// Reviewed values override what OCR extracted
+	amount := receipt.Amount
+	if params.Amount != "" {
+		if err := amount.Scan(params.Amount); err != nil {
+			return db.Receipt{}, db.Event{}, ErrInvalidAmount
+		}
+	}
+	amountValue, err := amount.Float64Value()
+	if err != nil || !amountValue.Valid || amountValue.Float64 <= 0 {
+		return db.Receipt{}, db.Event{}, ErrInvalidAmount
+	}
+
+	currency := receipt.Currency
+	if params.Currency != "" {
+		currency = pgtype.Text{String: params.Currency, Valid: true}
+	}
+	if err := httputil.ValidateCurrency(currency.String); err != nil {
+		return db.Receipt{}, db.Event{}, ErrInvalidCurrency
+	}
+
+	receiptDate := receipt.ReceiptDate
+	if params.Date != nil {
+		receiptDate = pgtype.Date{Time: *params.Date, Valid: true}
+	}
+
+	locationID := receipt.LocationID
+	if params.LocationID.Valid {
+		if err := s.locations.ValidateLocation(ctx, params.TenantID, params.LocationID); err != nil {
+			if errors.Is(err, location.ErrLocationNotFound) || errors.Is(err, location.ErrLocationInactive) {
+				return db.Receipt{}, db.Event{}, ErrInvalidLocation
+			}
+			return db.Receipt{}, db.Event{}, err
+		}
+		locationID = params.LocationID
+	}
+
+	// The purchase happened on the receipt date if known, otherwise when it was submitted
+	occurredAt := receipt.CreatedAt
+	if receiptDate.Valid {
+		occurredAt = pgtype.Timestamptz{Time: receiptDate.Time, Valid: true}
+	}
+
+	properties, err := json.Marshal(map[string]interface{}{
+		"amount":     amountValue.Float64,
+		"currency":   currency.String,
+		"receipt_id": httputil.FormatUUID(receipt.ID.Bytes),
+		"store_name": receipt.StoreName.String,
+	})
+	if err != nil {
+		return db.Receipt{}, db.Event{}, fmt.Errorf("failed to marshal event properties: %w", err)
+	}
+
+	event, err := qtx.InsertEvent(ctx, db.InsertEventParams{
+		TenantID:       receipt.TenantID,
+		CustomerID:     receipt.CustomerID,
+		EventType:      "purchase",
+		Properties:     properties,
+		OccurredAt:     occurredAt,
+		Source:         "receipt",
+		IdempotencyKey: "receipt:" + httputil.FormatUUID(receipt.ID.Bytes),
+		LocationID:     locationID,
+	})
+	if err != nil {
+		return db.Receipt{}, db.Event{}, fmt.Errorf("failed to create event: %w", err)
+	}
+
+	if err := qtx.ApproveReceipt(ctx, db.ApproveReceiptParams{
+		ID:          receipt.ID,
+		TenantID:    receipt.TenantID,
+		Amount:      amount,
+		Currency:    currency,
+		ReceiptDate: receiptDate,
+		LocationID:  locationID,
+		EventID:     event.ID,
+		ReviewedBy:  params.ReviewerID,
+	}); err != nil {
+		return db.Receipt{}, db.Event{}, fmt.Errorf("failed to approve receipt: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.Receipt{}, db.Event{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	approved, err := s.Get(ctx, params.TenantID, params.ReceiptID)
+	if err != nil {
+		return db.Receipt{}, db.Event{}, err
+	}
+	return approved, event, nil
+}
+
+// Reject marks a pending receipt as rejected
+func (s *Service) Reject(ctx context.Context, tenantID, receiptID, reviewerID pgtype.UUID, reason string) (db.Receipt, error) {
+	rows, err := s.queries.RejectReceipt(ctx, db.RejectReceiptParams{
+		ID:              receiptID,
+		TenantID:        tenantID,
+		RejectionReason: pgtype.Text{String: reason, Valid: reason != ""},
+		ReviewedBy:      reviewerID,
+	})
+	if err != nil {
+		return db.Receipt{}, fmt.Errorf("failed to reject receipt: %w", err)
+	}
+
+	receipt, err := s.Get(ctx, tenantID, receiptID)
+	if err != nil {
+		return db.Receipt{}, err
+	}
+	if rows == 0 {
+		return db.Receipt{}, ErrNotPending
+	}
+	return receipt, nil
+}
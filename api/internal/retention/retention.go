@@ -0,0 +1,65 @@
+// Package retention purges tenant data that is older than the tenant's
+// retention settings.
+//
+// Events, outbound messages with channel sessions, and expired issuances each
+// have their own retention in months. Rows that nothing else needs are
+// deleted; rows that must stay, such as events referenced by issuances and
+// expired issuances that back the liability ledger, are anonymized instead.
+// Budget and liability ledger entries are never touched, and events are only
+// purged from days already in the analytics rollups, so reported totals do
+// not change.
+package retention
+
+import (
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+)
+
+// Policy is a tenant's retention in months for each kind of data; zero keeps
+// that data forever
+type Policy struct {
+	EventMonths    int
+	MessageMonths  int
+	IssuanceMonths int
+}
+
+// PolicyFor returns the tenant's retention policy
+func PolicyFor(tenant db.Tenant) Policy {
+	return Policy{
+		EventMonths:    int(tenant.EventRetentionMonths.Int32),
+		MessageMonths:  int(tenant.MessageRetentionMonths.Int32),
+		IssuanceMonths: int(tenant.IssuanceRetentionMonths.Int32),
+	}
+}
+
+// Enabled reports whether the policy purges anything
+func (p Policy) Enabled() bool {
+	return p.EventMonths > 0 || p.MessageMonths > 0 || p.IssuanceMonths > 0
+}
+
+// EventCutoff is the start of the UTC day before which the tenant's events
+// are purged, or false when the tenant keeps events forever. Purging whole
+// days lets the analytics rollups skip them.
+func EventCutoff(tenant db.Tenant, now time.Time) (time.Time, bool) {
+	cutoff, ok := cutoffBefore(PolicyFor(tenant).EventMonths, now)
+	if !ok {
+		return time.Time{}, false
+	}
+	return startOfDay(cutoff), true
+}
+
+// cutoffBefore is the time data older than the given number of months was
+// created before, or false when months is zero
+func cutoffBefore(months int, now time.Time) (time.Time, bool) {
+	if months <= 0 {
+		return time.Time{}, false
+	}
+	return now.AddDate(0, -months, 0), true
+}
+
+// startOfDay truncates t to the start of its UTC day
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,55 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyFor(t *testing.T) {
+	policy := PolicyFor(db.Tenant{
+		EventRetentionMonths:   pgtype.Int4{Int32: 24, Valid: true},
+		MessageRetentionMonths: pgtype.Int4{Int32: 6, Valid: true},
+	})
+
+	assert.Equal(t, Policy{EventMonths: 24, MessageMonths: 6}, policy)
+	assert.True(t, policy.Enabled())
+	assert.False(t, PolicyFor(db.Tenant{}).Enabled())
+}
+
+func TestEventCutoff(t *testing.T) {
+	now := time.Date(2025, 12, 25, 15, 30, 0, 0, time.UTC)
+
+	cutoff, ok := EventCutoff(db.Tenant{EventRetentionMonths: pgtype.Int4{Int32: 12, Valid: true}}, now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 12, 25, 0, 0, 0, 0, time.UTC), cutoff)
+
+	_, ok = EventCutoff(db.Tenant{}, now)
+	assert.False(t, ok)
+}
+
+func TestCutoffBefore(t *testing.T) {
+	now := time.Date(2025, 12, 25, 15, 30, 0, 0, time.UTC)
+
+	cutoff, ok := cutoffBefore(3, now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2025, 9, 25, 15, 30, 0, 0, time.UTC), cutoff)
+
+	_, ok = cutoffBefore(0, now)
+	assert.False(t, ok)
+}
+
+func TestRemoved(t *testing.T) {
+	run := db.PurgeRun{
+		EventsDeleted:       10,
+		EventsAnonymized:    2,
+		MessagesDeleted:     5,
+		SessionsDeleted:     1,
+		IssuancesAnonymized: 3,
+	}
+	assert.Equal(t, int64(21), Removed(run))
+	assert.Zero(t, Removed(db.PurgeRun{}))
+}
@@ -0,0 +1,284 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/logging"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrTenantNotFound is returned when purging an unknown tenant
+var ErrTenantNotFound = errors.New("tenant not found")
+
+// Service purges data past tenants' retention
+type Service struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+	logger  *logging.Logger
+}
+
+// NewService creates a new retention service
+func NewService(pool *pgxpool.Pool, queries *db.Queries, logger *logging.Logger) *Service {
+	return &Service{
+		pool:    pool,
+		queries: queries,
+		logger:  logger,
+	}
+}
+
+// Purge removes the tenant's data past its retention and records what was
+// removed as a purge run. A dry run makes the same changes in a savepoint
+// that is rolled back, so its counts are exactly what a purge would remove
+// at that moment, and records the run without removing anything.
+func (s *Service) Purge(ctx context.Context, tenantID pgtype.UUID, dryRun bool, now time.Time) (*db.PurgeRun, error) {
+	tenant, err := s.queries.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTenantNotFound
+		}
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+	return s.purgeTenant(ctx, tenant, dryRun, now)
+}
+
+func (s *Service) purgeTenant(ctx context.Context, tenant db.Tenant, dryRun bool, now time.Time) (*db.PurgeRun, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Purges run outside a tenant request
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenant.ID.Bytes)); err != nil {
+		return nil, fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	qtx := s.queries.WithTx(tx)
+	params, err := s.cutoffs(ctx, qtx, tenant, now)
+	if err != nil {
+		return nil, err
+	}
+	params.DryRun = dryRun
+
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin savepoint: %w", err)
+	}
+	defer savepoint.Rollback(ctx)
+
+	if err := purge(ctx, s.queries.WithTx(savepoint), &params); err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		if err := savepoint.Rollback(ctx); err != nil {
+			return nil, fmt.Errorf("failed to roll back dry run: %w", err)
+		}
+	} else if err := savepoint.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to release savepoint: %w", err)
+	}
+
+	run, err := qtx.InsertPurgeRun(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record purge run: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &run, nil
+}
+
+// cutoffs works out the tenant's cutoffs as of now. Events are only purged
+// from days the analytics rollups have already counted, and not at all for
+// a tenant without rollups yet.
+func (s *Service) cutoffs(ctx context.Context, qtx *db.Queries, tenant db.Tenant, now time.Time) (db.InsertPurgeRunParams, error) {
+	policy := PolicyFor(tenant)
+	params := db.InsertPurgeRunParams{TenantID: tenant.ID}
+
+	if cutoff, ok := EventCutoff(tenant, now); ok {
+		state, err := qtx.GetRollupState(ctx, tenant.ID)
+		switch {
+		case err == nil:
+			if rolledUp := startOfDay(state.RefreshedAt.Time); rolledUp.Before(cutoff) {
+				cutoff = rolledUp
+			}
+			params.EventCutoff = pgtype.Timestamptz{Time: cutoff, Valid: true}
+		case !errors.Is(err, pgx.ErrNoRows):
+			return params, fmt.Errorf("failed to get rollup state: %w", err)
+		}
+	}
+	if cutoff, ok := cutoffBefore(policy.MessageMonths, now); ok {
+		params.MessageCutoff = pgtype.Timestamptz{Time: cutoff, Valid: true}
+	}
+	if cutoff, ok := cutoffBefore(policy.IssuanceMonths, now); ok {
+		params.IssuanceCutoff = pgtype.Timestamptz{Time: cutoff, Valid: true}
+	}
+	return params, nil
+}
+
+// purge removes data before the run's cutoffs and counts it into the run
+func purge(ctx context.Context, qtx *db.Queries, run *db.InsertPurgeRunParams) error {
+	var err error
+
+	if run.EventCutoff.Valid {
+		run.EventsDeleted, err = qtx.DeleteExpiredEvents(ctx, db.DeleteExpiredEventsParams{
+			TenantID: run.TenantID,
+			Before:   run.EventCutoff,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete events: %w", err)
+		}
+		run.EventsAnonymized, err = qtx.AnonymizeExpiredEvents(ctx, db.AnonymizeExpiredEventsParams{
+			TenantID: run.TenantID,
+			Before:   run.EventCutoff,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to anonymize events: %w", err)
+		}
+	}
+
+	if run.MessageCutoff.Valid {
+		run.MessagesDeleted, err = qtx.DeleteExpiredOutboundMessages(ctx, db.DeleteExpiredOutboundMessagesParams{
+			TenantID: run.TenantID,
+			Before:   run.MessageCutoff,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete outbound messages: %w", err)
+		}
+		waSessions, err := qtx.DeleteExpiredWASessions(ctx, db.DeleteExpiredWASessionsParams{
+			TenantID: run.TenantID,
+			Before:   run.MessageCutoff,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete WhatsApp sessions: %w", err)
+		}
+		ussdSessions, err := qtx.DeleteExpiredUSSDSessions(ctx, db.DeleteExpiredUSSDSessionsParams{
+			TenantID: run.TenantID,
+			Before:   run.MessageCutoff,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete USSD sessions: %w", err)
+		}
+		run.SessionsDeleted = waSessions + ussdSessions
+	}
+
+	if run.IssuanceCutoff.Valid {
+		run.IssuancesAnonymized, err = qtx.AnonymizeExpiredIssuances(ctx, db.AnonymizeExpiredIssuancesParams{
+			TenantID: run.TenantID,
+			Before:   run.IssuanceCutoff,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to anonymize issuances: %w", err)
+		}
+	}
+	return nil
+}
+
+// PurgeAll purges every tenant with a retention policy and returns the runs
+// that removed anything. A failing tenant is logged and does not stop the
+// others.
+func (s *Service) PurgeAll(ctx context.Context, dryRun bool, now time.Time) ([]db.PurgeRun, error) {
+	tenants, err := s.queries.ListRetentionTenants(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	var runs []db.PurgeRun
+	for _, tenant := range tenants {
+		run, err := s.purgeTenant(ctx, tenant, dryRun, now)
+		if err != nil {
+			s.logger.Error("failed to purge tenant data",
+				"tenant_id", httputil.FormatUUID(tenant.ID.Bytes),
+				"error", err)
+			continue
+		}
+		if Removed(*run) > 0 {
+			runs = append(runs, *run)
+		}
+	}
+	return runs, nil
+}
+
+// Removed is the number of rows a purge run deleted or anonymized
+func Removed(run db.PurgeRun) int64 {
+	return run.EventsDeleted + run.EventsAnonymized + run.MessagesDeleted +
+		run.SessionsDeleted + run.IssuancesAnonymized
+}
+
+// GetPolicy returns the tenant's retention policy
+func (s *Service) GetPolicy(ctx context.Context, tenantID pgtype.UUID) (Policy, error) {
+	tenant, err := s.queries.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Policy{}, ErrTenantNotFound
+		}
+		return Policy{}, fmt.Errorf("failed to get tenant: %w", err)
+	}
+	return PolicyFor(tenant), nil
+}
+
+// ListRuns lists the tenant's purge runs, newest first
+func (s *Service) ListRuns(ctx context.Context, tenantID pgtype.UUID, limitStr, offsetStr string) ([]db.PurgeRun, int64, error) {
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	runs, err := s.queries.ListPurgeRuns(ctx, db.ListPurgeRunsParams{
+		TenantID: tenantID,
+		Limit:    int32(limit),
+		Offset:   int32(offset),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list purge runs: %w", err)
+	}
+	total, err := s.queries.CountPurgeRuns(ctx, tenantID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count purge runs: %w", err)
+	}
+	return runs, total, nil
+}
+
+// Run purges every tenant on a schedule until ctx is cancelled
+func (s *Service) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		runs, err := s.PurgeAll(ctx, false, time.Now())
+		if err != nil {
+			s.logger.Error("retention purge failed", "error", err)
+		}
+		for _, run := range runs {
+			s.logger.Info("purged data past retention",
+				"tenant_id", httputil.FormatUUID(run.TenantID.Bytes),
+				"events_deleted", run.EventsDeleted,
+				"events_anonymized", run.EventsAnonymized,
+				"messages_deleted", run.MessagesDeleted,
+				"sessions_deleted", run.SessionsDeleted,
+				"issuances_anonymized", run.IssuancesAnonymized)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package reward
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// staleReservationBatch bounds how many reservations one tenant has released
+// per sweep; the rest are picked up by the next sweep
+const staleReservationBatch = 500
+
+// ReservationRelease is what a sweep released for one tenant
+type ReservationRelease struct {
+	TenantID pgtype.UUID
+	Released int
+	// Recovered is the budget released, by currency
+	Recovered map[string]float64
+}
+
+// reservedBefore is the cutoff for a tenant's stale reservations, or false
+// when the tenant keeps reservations until they are processed
+func reservedBefore(tenant db.Tenant, now time.Time) (time.Time, bool) {
+	if !tenant.MaxReservationAgeHours.Valid || tenant.MaxReservationAgeHours.Int32 <= 0 {
+		return time.Time{}, false
+	}
+	return now.Add(-time.Duration(tenant.MaxReservationAgeHours.Int32) * time.Hour), true
+}
+
+// ReleaseStaleReservations cancels a tenant's issuances that have been
+// reserved for longer than its max reservation age and releases their
+// budget reservations
+func (s *Service) ReleaseStaleReservations(ctx context.Context, tenant db.Tenant, now time.Time) (*ReservationRelease, error) {
+	result := &ReservationRelease{TenantID: tenant.ID, Recovered: map[string]float64{}}
+
+	cutoff, ok := reservedBefore(tenant, now)
+	if !ok {
+		return result, nil
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Sweeps run outside a tenant request
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenant.ID.Bytes)); err != nil {
+		return nil, fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	qtx := s.queries.WithTx(tx)
+	issuances, err := qtx.ListStaleReservations(ctx, db.ListStaleReservationsParams{
+		TenantID:       tenant.ID,
+		ReservedBefore: pgtype.Timestamptz{Time: cutoff, Valid: true},
+		Limit:          staleReservationBatch,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stale reservations: %w", err)
+	}
+
+	for _, issuance := range issuances {
+		if err := TransitionIssuance(ctx, qtx, Transition{
+			IssuanceID: issuance.ID,
+			TenantID:   tenant.ID,
+			From:       StateReserved,
+			To:         StateCancelled,
+			Origin:     SystemOrigin,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to cancel issuance %s: %w", httputil.FormatUUID(issuance.ID.Bytes), err)
+		}
+
+		budgetID, err := IssuanceBudget(ctx, qtx, issuance)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.releaseBudget(ctx, tx, tenant.ID, budgetID, issuance.ID, issuance.CostAmount, issuance.Currency); err != nil {
+			return nil, fmt.Errorf("failed to release budget: %w", err)
+		}
+
+		result.Released++
+		if budgetID.Valid && issuance.Currency.Valid {
+			if amount, err := issuance.CostAmount.Float64Value(); err == nil && amount.Valid {
+				result.Recovered[issuance.Currency.String] += amount.Float64
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// ReleaseAllStaleReservations sweeps every tenant with a max reservation age
+// and returns what was released for the tenants that had stale reservations.
+// A failing tenant is logged and does not stop the others.
+func (s *Service) ReleaseAllStaleReservations(ctx context.Context, now time.Time) ([]ReservationRelease, error) {
+	tenants, err := s.queries.ListReservationAgeingTenants(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	var releases []ReservationRelease
+	for _, tenant := range tenants {
+		release, err := s.ReleaseStaleReservations(ctx, tenant, now)
+		if err != nil {
+			log.Printf("Failed to release stale reservations for tenant %s: %v", httputil.FormatUUID(tenant.ID.Bytes), err)
+			continue
+		}
+		if release.Released > 0 {
+			releases = append(releases, *release)
+		}
+	}
+	return releases, nil
+}
+
+// RunReservationReleaseWorker releases stale reservations on a schedule until
+// ctx is cancelled, logging the budget recovered for each tenant
+func (s *Service) RunReservationReleaseWorker(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		releases, err := s.ReleaseAllStaleReservations(ctx, time.Now())
+		if err != nil {
+			log.Printf("Reservation release worker error: %v", err)
+		}
+		for _, release := range releases {
+			log.Printf("Released %d stale reservations for tenant %s, recovered %v",
+				release.Released, httputil.FormatUUID(release.TenantID.Bytes), release.Recovered)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package reward
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestReservedBefore(t *testing.T) {
+	now := time.Date(2025, 12, 11, 12, 0, 0, 0, time.UTC)
+
+	// Tenants without a max age keep reservations until processed
+	if _, ok := reservedBefore(db.Tenant{}, now); ok {
+		t.Error("Expected no cutoff without a max reservation age")
+	}
+
+	cutoff, ok := reservedBefore(db.Tenant{MaxReservationAgeHours: pgtype.Int4{Int32: 48, Valid: true}}, now)
+	if !ok {
+		t.Fatal("Expected a cutoff with a max reservation age")
+	}
+	if want := time.Date(2025, 12, 9, 12, 0, 0, 0, time.UTC); !cutoff.Equal(want) {
+		t.Errorf("Expected cutoff %v, got %v", want, cutoff)
+	}
+}
+
+func TestReleaseStaleReservations(t *testing.T) {
+	f := setupTestFixture(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	// reservedAt backdates when an issuance was reserved
+	reservedAt := func(t *testing.T, issuance db.Issuance, at time.Time) {
+		t.Helper()
+		if _, err := f.pool.Exec(ctx, "UPDATE issuances SET issued_at = $2 WHERE id = $1", issuance.ID, at); err != nil {
+			t.Fatalf("failed to backdate issuance: %v", err)
+		}
+	}
+
+	stale := f.reserve(t)
+	reservedAt(t, stale, now.Add(-72*time.Hour))
+	fresh := f.reserve(t)
+	reservedAt(t, fresh, now.Add(-time.Hour))
+	// Only reservations are released, however old
+	issued := f.issue(t)
+	reservedAt(t, issued, now.Add(-72*time.Hour))
+
+	t.Run("tenant without a max age", func(t *testing.T) {
+		release, err := f.service.ReleaseStaleReservations(ctx, f.tenant, now)
+		if err != nil {
+			t.Fatalf("ReleaseStaleReservations failed: %v", err)
+		}
+		if release.Released != 0 {
+			t.Errorf("Expected nothing released, got %d", release.Released)
+		}
+		if got := f.get(t, stale.ID).Status; got != string(StateReserved) {
+			t.Errorf("Expected issuance to stay reserved, got %s", got)
+		}
+	})
+
+	t.Run("stale reservation is released", func(t *testing.T) {
+		maxAge := pgtype.Int4{Int32: 48, Valid: true}
+		if err := f.queries.UpdateTenantMaxReservationAge(ctx, db.UpdateTenantMaxReservationAgeParams{
+			ID:                     f.tenant.ID,
+			MaxReservationAgeHours: maxAge,
+		}); err != nil {
+			t.Fatalf("UpdateTenantMaxReservationAge failed: %v", err)
+		}
+		tenant := f.tenant
+		tenant.MaxReservationAgeHours = maxAge
+
+		release, err := f.service.ReleaseStaleReservations(ctx, tenant, now)
+		if err != nil {
+			t.Fatalf("ReleaseStaleReservations failed: %v", err)
+		}
+		if release.Released != 1 {
+			t.Errorf("Expected 1 reservation released, got %d", release.Released)
+		}
+		if got := release.Recovered["USD"]; got != 5 {
+			t.Errorf("Expected 5 USD recovered, got %v", release.Recovered)
+		}
+
+		if got := f.get(t, stale.ID).Status; got != string(StateCancelled) {
+			t.Errorf("Expected stale issuance to be cancelled, got %s", got)
+		}
+		if got := f.ledgerTotal(t, stale.ID, "release"); got != -5 {
+			t.Errorf("Expected a release ledger entry of -5, got %v", got)
+		}
+
+		if got := f.get(t, fresh.ID).Status; got != string(StateReserved) {
+			t.Errorf("Expected fresh issuance to stay reserved, got %s", got)
+		}
+		if got := f.get(t, issued.ID).Status; got != string(StateIssued) {
+			t.Errorf("Expected issued issuance to stay issued, got %s", got)
+		}
+		for _, issuance := range []db.Issuance{fresh, issued} {
+			if got := f.ledgerTotal(t, issuance.ID, "release"); got != 0 {
+				t.Errorf("Expected no release of %v, got %v", issuance.ID, got)
+			}
+		}
+
+		// Only the fresh and issued reservations are still held
+		budget, err := f.queries.GetBudgetByID(ctx, db.GetBudgetByIDParams{ID: f.budget.ID, TenantID: f.tenant.ID})
+		if err != nil {
+			t.Fatalf("GetBudgetByID failed: %v", err)
+		}
+		if got := numericToFloat(t, budget.Balance); got != 10 {
+			t.Errorf("Expected budget balance 10, got %v", got)
+		}
+
+		// A second sweep finds nothing left to release
+		again, err := f.service.ReleaseStaleReservations(ctx, tenant, now)
+		if err != nil {
+			t.Fatalf("ReleaseStaleReservations failed: %v", err)
+		}
+		if again.Released != 0 {
+			t.Errorf("Expected nothing released by the second sweep, got %d", again.Released)
+		}
+	})
+}
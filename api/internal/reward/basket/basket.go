@@ -0,0 +1,78 @@
+// Package basket enforces the qualifying purchase some rewards need at
+// redemption, e.g. a free coffee with any purchase over USD 5. A reward opts
+// in with a min_basket in its metadata; the till then reports the basket
+// the reward is redeemed with.
+package basket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/bmachimbira/loyalty/api/internal/rewardtypes"
+)
+
+var (
+	// ErrInvalidConfig is returned for a minimum basket that can't be used
+	ErrInvalidConfig = errors.New("invalid minimum basket")
+
+	// ErrBelowMinimum is returned when a reward is redeemed without a basket
+	// or with one smaller than its minimum
+	ErrBelowMinimum = errors.New("basket is below the reward's minimum")
+)
+
+// FromMetadata returns the minimum basket in a reward's metadata, or zero
+// when the reward needs no purchase
+func FromMetadata(metadata []byte) (float64, error) {
+	var meta rewardtypes.TermsMetadata
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &meta); err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+	}
+	if meta.MinBasket < 0 || math.IsInf(meta.MinBasket, 0) {
+		return 0, fmt.Errorf("%w: min_basket must not be negative", ErrInvalidConfig)
+	}
+	return meta.MinBasket, nil
+}
+
+// Check returns an *Error if amount, nil when no basket was reported, is
+// below minimum
+func Check(minimum float64, amount *float64, currency string) error {
+	if minimum <= 0 || (amount != nil && *amount >= minimum) {
+		return nil
+	}
+	return &Error{Minimum: minimum, Amount: amount, Currency: currency}
+}
+
+// Error is an ErrBelowMinimum carrying the minimum basket
+type Error struct {
+	Minimum float64
+	// Amount is the basket reported, nil if there was none
+	Amount   *float64
+	Currency string
+}
+
+func (e *Error) Error() string {
+	if e.Amount == nil {
+		return fmt.Sprintf("%v: no basket amount given, minimum %.2f", ErrBelowMinimum, e.Minimum)
+	}
+	return fmt.Sprintf("%v: basket %.2f, minimum %.2f", ErrBelowMinimum, *e.Amount, e.Minimum)
+}
+
+func (e *Error) Unwrap() error {
+	return ErrBelowMinimum
+}
+
+// Message is the explanation shown to the customer
+func (e *Error) Message() string {
+	minimum := fmt.Sprintf("%.2f", e.Minimum)
+	if e.Currency != "" {
+		minimum = e.Currency + " " + minimum
+	}
+	if e.Amount == nil {
+		return fmt.Sprintf("This reward needs a purchase of at least %s and can only be redeemed at the till.", minimum)
+	}
+	return fmt.Sprintf("This reward needs a purchase of at least %s.", minimum)
+}
@@ -0,0 +1,45 @@
+package basket
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromMetadata(t *testing.T) {
+	minimum, err := FromMetadata([]byte(`{"terms": "One per visit"}`))
+	require.NoError(t, err)
+	assert.Zero(t, minimum)
+
+	minimum, err = FromMetadata([]byte(`{"min_basket": 5}`))
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, minimum)
+
+	for _, metadata := range []string{
+		`{"min_basket": -1}`,
+		`{"min_basket": "5"}`,
+	} {
+		_, err := FromMetadata([]byte(metadata))
+		assert.ErrorIs(t, err, ErrInvalidConfig, metadata)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	amount := func(v float64) *float64 { return &v }
+
+	assert.NoError(t, Check(0, nil, "USD"))
+	assert.NoError(t, Check(5, amount(5), "USD"))
+	assert.NoError(t, Check(5, amount(12.5), "USD"))
+
+	err := Check(5, amount(4.99), "USD")
+	var basketErr *Error
+	require.True(t, errors.As(err, &basketErr))
+	assert.ErrorIs(t, err, ErrBelowMinimum)
+	assert.Equal(t, "This reward needs a purchase of at least USD 5.00.", basketErr.Message())
+
+	err = Check(5, nil, "")
+	require.True(t, errors.As(err, &basketErr))
+	assert.Equal(t, "This reward needs a purchase of at least 5.00 and can only be redeemed at the till.", basketErr.Message())
+}
@@ -0,0 +1,31 @@
+package reward
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// IssuanceBudget returns the budget an issuance's cost was reserved from:
+// the budget recorded when it was reserved, which may be one of the
+// campaign's fallback budgets, or for older issuances the campaign's
+// budget. It returns an invalid UUID when the issuance has no budget.
+func IssuanceBudget(ctx context.Context, q *db.Queries, issuance db.Issuance) (pgtype.UUID, error) {
+	if issuance.BudgetID.Valid {
+		return issuance.BudgetID, nil
+	}
+	if !issuance.CampaignID.Valid {
+		return pgtype.UUID{}, nil
+	}
+
+	campaign, err := q.GetCampaignByID(ctx, db.GetCampaignByIDParams{
+		TenantID: issuance.TenantID,
+		ID:       issuance.CampaignID,
+	})
+	if err != nil {
+		return pgtype.UUID{}, fmt.Errorf("failed to get campaign budget: %w", err)
+	}
+	return campaign.BudgetID, nil
+}
@@ -0,0 +1,34 @@
+package reward
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestIssuanceBudget(t *testing.T) {
+	budgetID := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+
+	// The budget recorded at reservation wins, without a campaign lookup
+	got, err := IssuanceBudget(context.Background(), nil, db.Issuance{
+		CampaignID: pgtype.UUID{Bytes: [16]byte{2}, Valid: true},
+		BudgetID:   budgetID,
+	})
+	if err != nil {
+		t.Fatalf("IssuanceBudget failed: %v", err)
+	}
+	if got != budgetID {
+		t.Errorf("Expected recorded budget %v, got %v", budgetID, got)
+	}
+
+	// Issuances outside a campaign have no budget
+	got, err = IssuanceBudget(context.Background(), nil, db.Issuance{})
+	if err != nil {
+		t.Fatalf("IssuanceBudget failed: %v", err)
+	}
+	if got.Valid {
+		t.Errorf("Expected no budget, got %v", got)
+	}
+}
@@ -0,0 +1,151 @@
+package reward
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Clawback reason codes
+const (
+	ClawbackReasonFraud      = "fraud"
+	ClawbackReasonRefund     = "refund"
+	ClawbackReasonChargeback = "chargeback"
+	ClawbackReasonDuplicate  = "duplicate"
+	ClawbackReasonOther      = "other"
+)
+
+var (
+	// ErrInvalidClawbackReason is returned for an unknown reason code
+	ErrInvalidClawbackReason = errors.New("reason_code must be one of fraud, refund, chargeback, duplicate, other")
+
+	// ErrClawbackNotRedeemed is returned when the issuance was never redeemed;
+	// unredeemed rewards are cancelled instead
+	ErrClawbackNotRedeemed = errors.New("only redeemed issuances can be clawed back")
+
+	// ErrAlreadyClawedBack is returned when the issuance was already clawed back
+	ErrAlreadyClawedBack = errors.New("issuance already clawed back")
+
+	// ErrIssuanceNotFound is returned when the issuance doesn't exist
+	ErrIssuanceNotFound = errors.New("issuance not found")
+)
+
+// ValidClawbackReason reports whether code is a known clawback reason code
+func ValidClawbackReason(code string) bool {
+	switch code {
+	case ClawbackReasonFraud, ClawbackReasonRefund, ClawbackReasonChargeback,
+		ClawbackReasonDuplicate, ClawbackReasonOther:
+		return true
+	}
+	return false
+}
+
+// ClawbackRequest describes a clawback of a redeemed issuance
+type ClawbackRequest struct {
+	TenantID   pgtype.UUID
+	IssuanceID pgtype.UUID
+	StaffID    pgtype.UUID
+	ReasonCode string
+	Note       string
+}
+
+// ClawbackIssuance claws back a redeemed issuance after a refund or fraud.
+// This function:
+// 1. Validates the issuance is in redeemed state and not yet clawed back
+// 2. Credits the charged cost back to the campaign budget ('reverse' entry)
+// 3. Flags the customer for review
+// 4. Records the clawback
+// It returns the clawback and the issuance it applies to.
+func (s *Service) ClawbackIssuance(ctx context.Context, req ClawbackRequest) (db.IssuanceClawback, db.Issuance, error) {
+	if !ValidClawbackReason(req.ReasonCode) {
+		return db.IssuanceClawback{}, db.Issuance{}, ErrInvalidClawbackReason
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.IssuanceClawback{}, db.Issuance{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	issuance, err := qtx.GetIssuanceForUpdate(ctx, db.GetIssuanceForUpdateParams{
+		ID:       req.IssuanceID,
+		TenantID: req.TenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.IssuanceClawback{}, db.Issuance{}, ErrIssuanceNotFound
+		}
+		return db.IssuanceClawback{}, db.Issuance{}, fmt.Errorf("failed to get issuance: %w", err)
+	}
+
+	if State(issuance.Status) != StateRedeemed {
+		return db.IssuanceClawback{}, issuance, ErrClawbackNotRedeemed
+	}
+
+	budgetID, err := s.reverseCharge(ctx, tx, qtx, issuance)
+	if err != nil {
+		return db.IssuanceClawback{}, issuance, err
+	}
+
+	params := db.CreateIssuanceClawbackParams{
+		TenantID:   issuance.TenantID,
+		IssuanceID: issuance.ID,
+		CustomerID: issuance.CustomerID,
+		ReasonCode: req.ReasonCode,
+		Note:       pgtype.Text{String: req.Note, Valid: req.Note != ""},
+		BudgetID:   budgetID,
+		CreatedBy:  req.StaffID,
+	}
+	if budgetID.Valid {
+		params.Amount = issuance.CostAmount
+		params.Currency = issuance.Currency
+	}
+
+	clawback, err := qtx.CreateIssuanceClawback(ctx, params)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.IssuanceClawback{}, issuance, ErrAlreadyClawedBack
+		}
+		return db.IssuanceClawback{}, issuance, fmt.Errorf("failed to record clawback: %w", err)
+	}
+
+	if err := qtx.FlagCustomer(ctx, db.FlagCustomerParams{
+		ID:         issuance.CustomerID,
+		TenantID:   issuance.TenantID,
+		FlagReason: pgtype.Text{String: "reward clawed back: " + req.ReasonCode, Valid: true},
+	}); err != nil {
+		return db.IssuanceClawback{}, issuance, fmt.Errorf("failed to flag customer: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.IssuanceClawback{}, issuance, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return clawback, issuance, nil
+}
+
+// reverseCharge credits a redeemed issuance's cost back to the budget it was
+// charged to using the reverse_budget database function. It returns the budget
+// credited, or an invalid UUID when the issuance has no budget or cost.
+func (s *Service) reverseCharge(ctx context.Context, tx pgx.Tx, qtx *db.Queries, issuance db.Issuance) (pgtype.UUID, error) {
+	if !issuance.CampaignID.Valid || !issuance.CostAmount.Valid || !issuance.Currency.Valid {
+		return pgtype.UUID{}, nil
+	}
+
+	budgetID, err := IssuanceBudget(ctx, qtx, issuance)
+	if err != nil || !budgetID.Valid {
+		return pgtype.UUID{}, err
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT reverse_budget($1, $2, $3, $4, $5)",
+		issuance.TenantID, budgetID, issuance.CostAmount, issuance.Currency.String, issuance.ID); err != nil {
+		return pgtype.UUID{}, fmt.Errorf("reverse_budget function failed: %w", err)
+	}
+	return budgetID, nil
+}
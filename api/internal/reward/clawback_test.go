@@ -0,0 +1,99 @@
+package reward
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+)
+
+func TestValidClawbackReason(t *testing.T) {
+	for _, code := range []string{"fraud", "refund", "chargeback", "duplicate", "other"} {
+		if !ValidClawbackReason(code) {
+			t.Errorf("expected %q to be a valid reason code", code)
+		}
+	}
+
+	for _, code := range []string{"", "Fraud", "expired"} {
+		if ValidClawbackReason(code) {
+			t.Errorf("expected %q to be rejected", code)
+		}
+	}
+}
+
+func TestClawbackIssuance(t *testing.T) {
+	f := setupTestFixture(t)
+	ctx := context.Background()
+
+	request := func(issuance db.Issuance) ClawbackRequest {
+		return ClawbackRequest{
+			TenantID:   f.tenant.ID,
+			IssuanceID: issuance.ID,
+			StaffID:    f.staff.ID,
+			ReasonCode: ClawbackReasonRefund,
+			Note:       "refunded at till 3",
+		}
+	}
+
+	t.Run("redeemed issuance is clawed back", func(t *testing.T) {
+		issuance := f.redeem(t)
+
+		clawback, clawedBack, err := f.service.ClawbackIssuance(ctx, request(issuance))
+		if err != nil {
+			t.Fatalf("ClawbackIssuance failed: %v", err)
+		}
+		if clawedBack.ID != issuance.ID {
+			t.Errorf("Expected issuance %v, got %v", issuance.ID, clawedBack.ID)
+		}
+		if clawback.BudgetID != f.budget.ID {
+			t.Errorf("Expected budget %v to be credited, got %v", f.budget.ID, clawback.BudgetID)
+		}
+		if got := numericToFloat(t, clawback.Amount); got != 5 {
+			t.Errorf("Expected clawback amount 5, got %v", got)
+		}
+
+		// The charge is credited back to the budget
+		if got := f.ledgerTotal(t, issuance.ID, "reverse"); got != -5 {
+			t.Errorf("Expected a reverse ledger entry of -5, got %v", got)
+		}
+
+		// The issuance stays redeemed
+		if got := f.get(t, issuance.ID).Status; got != string(StateRedeemed) {
+			t.Errorf("Expected issuance to stay redeemed, got %s", got)
+		}
+
+		customer, err := f.queries.GetCustomerByID(ctx, db.GetCustomerByIDParams{
+			ID:       issuance.CustomerID,
+			TenantID: f.tenant.ID,
+		})
+		if err != nil {
+			t.Fatalf("GetCustomerByID failed: %v", err)
+		}
+		if !customer.FlaggedAt.Valid {
+			t.Error("Expected customer to be flagged")
+		}
+		if want := "reward clawed back: refund"; customer.FlagReason.String != want {
+			t.Errorf("Expected flag reason %q, got %q", want, customer.FlagReason.String)
+		}
+
+		// A second clawback is refused and credits nothing more
+		if _, _, err := f.service.ClawbackIssuance(ctx, request(issuance)); !errors.Is(err, ErrAlreadyClawedBack) {
+			t.Errorf("Expected ErrAlreadyClawedBack, got %v", err)
+		}
+		if got := f.ledgerTotal(t, issuance.ID, "reverse"); got != -5 {
+			t.Errorf("Expected the reverse ledger entry to stay -5, got %v", got)
+		}
+	})
+
+	t.Run("unredeemed issuance is refused", func(t *testing.T) {
+		issuance := f.issue(t)
+
+		if _, _, err := f.service.ClawbackIssuance(ctx, request(issuance)); !errors.Is(err, ErrClawbackNotRedeemed) {
+			t.Errorf("Expected ErrClawbackNotRedeemed, got %v", err)
+		}
+		if got := f.ledgerTotal(t, issuance.ID, "reverse"); got != 0 {
+			t.Errorf("Expected no reverse ledger entry, got %v", got)
+		}
+	})
+}
@@ -0,0 +1,227 @@
+// Package codes generates the redemption codes customers present to redeem
+// an issuance. Each reward picks a strategy in its metadata; every strategy
+// avoids characters that are easily confused when read aloud or typed on a
+// USSD keypad.
+package codes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/rewardtypes"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Code generation strategies
+const (
+	// StrategyAlphanumeric generates human-friendly letters and digits
+	StrategyAlphanumeric = "alphanumeric"
+	// StrategyNumeric generates OTP-style digits only
+	StrategyNumeric = "numeric"
+	// StrategyChecksum generates an optional prefix, alphanumeric body and a
+	// trailing check character that catches single-character typos
+	StrategyChecksum = "checksum"
+)
+
+const (
+	// alphanumeric excludes 0, O, 1 and I, which are easily confused
+	alphanumeric = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	digits       = "0123456789"
+
+	minLength       = 4
+	maxLength       = 20
+	maxPrefixLength = 6
+
+	// maxAttempts bounds how many times a colliding code is regenerated
+	maxAttempts = 5
+)
+
+// Defaults used when a reward doesn't configure its codes
+var (
+	DefaultAlphanumeric = rewardtypes.CodeConfig{Strategy: StrategyAlphanumeric, Length: 8}
+	DefaultNumeric      = rewardtypes.CodeConfig{Strategy: StrategyNumeric, Length: 6}
+)
+
+var (
+	// ErrInvalidConfig is returned for an unknown strategy, length or prefix
+	ErrInvalidConfig = errors.New("invalid code configuration")
+
+	// ErrNoUniqueCode is returned when every attempt collided with an existing code
+	ErrNoUniqueCode = errors.New("could not generate a unique code")
+)
+
+// Generator generates codes that are unique within a tenant
+type Generator struct {
+	queries *db.Queries
+}
+
+// NewGenerator creates a generator that checks codes against existing
+// issuances. A nil Generator generates codes without collision checks.
+func NewGenerator(queries *db.Queries) *Generator {
+	return &Generator{queries: queries}
+}
+
+// Generate returns a new code for the tenant, regenerating it when it
+// collides with an existing issuance's code
+func (g *Generator) Generate(ctx context.Context, tenantID pgtype.UUID, cfg rewardtypes.CodeConfig) (string, error) {
+	if err := Validate(cfg); err != nil {
+		return "", err
+	}
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		code, err := New(cfg)
+		if err != nil {
+			return "", err
+		}
+		if g == nil || g.queries == nil {
+			return code, nil
+		}
+
+		taken, err := g.queries.IssuanceCodeExists(ctx, db.IssuanceCodeExistsParams{
+			TenantID: tenantID,
+			Code:     pgtype.Text{String: code, Valid: true},
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to check code: %w", err)
+		}
+		if !taken {
+			return code, nil
+		}
+	}
+	return "", ErrNoUniqueCode
+}
+
+// FromMetadata returns the code configuration in a reward's metadata, or def
+// when the reward doesn't configure one. A configured strategy without a
+// length gets the strategy's default length.
+func FromMetadata(metadata []byte, def rewardtypes.CodeConfig) (rewardtypes.CodeConfig, error) {
+	var meta rewardtypes.CodeMetadata
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &meta); err != nil {
+			return rewardtypes.CodeConfig{}, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+	}
+	if meta.Code == nil {
+		return def, nil
+	}
+
+	cfg := *meta.Code
+	cfg.Prefix = strings.ToUpper(cfg.Prefix)
+	if cfg.Length == 0 {
+		cfg.Length = defaultLength(cfg.Strategy)
+	}
+	if err := Validate(cfg); err != nil {
+		return rewardtypes.CodeConfig{}, err
+	}
+	return cfg, nil
+}
+
+// Validate checks a code configuration
+func Validate(cfg rewardtypes.CodeConfig) error {
+	switch cfg.Strategy {
+	case StrategyAlphanumeric, StrategyNumeric, StrategyChecksum:
+	default:
+		return fmt.Errorf("%w: strategy must be one of alphanumeric, numeric, checksum", ErrInvalidConfig)
+	}
+
+	if cfg.Length < minLength || cfg.Length > maxLength {
+		return fmt.Errorf("%w: length must be between %d and %d", ErrInvalidConfig, minLength, maxLength)
+	}
+
+	if cfg.Prefix != "" {
+		if cfg.Strategy != StrategyChecksum {
+			return fmt.Errorf("%w: prefix is only supported by the checksum strategy", ErrInvalidConfig)
+		}
+		if len(cfg.Prefix) > maxPrefixLength {
+			return fmt.Errorf("%w: prefix must be at most %d characters", ErrInvalidConfig, maxPrefixLength)
+		}
+		for _, c := range cfg.Prefix {
+			if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+				return fmt.Errorf("%w: prefix must contain only letters and digits", ErrInvalidConfig)
+			}
+		}
+	}
+	return nil
+}
+
+// New generates a code without checking for collisions
+func New(cfg rewardtypes.CodeConfig) (string, error) {
+	switch cfg.Strategy {
+	case StrategyNumeric:
+		return random(digits, cfg.Length)
+	case StrategyChecksum:
+		body, err := random(alphanumeric, cfg.Length)
+		if err != nil {
+			return "", err
+		}
+		return cfg.Prefix + body + string(checkChar(body)), nil
+	default:
+		return random(alphanumeric, cfg.Length)
+	}
+}
+
+// ValidChecksum reports whether a checksum-strategy code is well formed, so
+// mistyped codes can be rejected without a lookup
+func ValidChecksum(code, prefix string) bool {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !strings.HasPrefix(code, strings.ToUpper(prefix)) {
+		return false
+	}
+	rest := code[len(prefix):]
+	if len(rest) <= minLength {
+		return false
+	}
+	body, check := rest[:len(rest)-1], rest[len(rest)-1]
+	for i := 0; i < len(body); i++ {
+		if strings.IndexByte(alphanumeric, body[i]) < 0 {
+			return false
+		}
+	}
+	return checkChar(body) == check
+}
+
+// defaultLength returns the length used when a strategy is configured
+// without one
+func defaultLength(strategy string) int {
+	if strategy == StrategyNumeric {
+		return DefaultNumeric.Length
+	}
+	return DefaultAlphanumeric.Length
+}
+
+// random returns n characters drawn uniformly from chars using crypto/rand
+func random(chars string, n int) (string, error) {
+	max := big.NewInt(int64(len(chars)))
+	b := make([]byte, n)
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to read random bytes: %w", err)
+		}
+		b[i] = chars[idx.Int64()]
+	}
+	return string(b), nil
+}
+
+// checkChar computes the Luhn mod N check character of an alphanumeric body
+func checkChar(body string) byte {
+	n := len(alphanumeric)
+	factor := 2
+	sum := 0
+	for i := len(body) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(alphanumeric, body[i])
+		if factor == 2 {
+			factor = 1
+		} else {
+			factor = 2
+		}
+		sum += addend/n + addend%n
+	}
+	return alphanumeric[(n-sum%n)%n]
+}
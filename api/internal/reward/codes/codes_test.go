@@ -0,0 +1,168 @@
+package codes
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bmachimbira/loyalty/api/internal/rewardtypes"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestNew_Alphanumeric(t *testing.T) {
+	// Generate multiple codes to check uniqueness and format
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		code, err := New(DefaultAlphanumeric)
+		if err != nil {
+			t.Fatalf("Failed to generate code: %v", err)
+		}
+
+		if len(code) != 8 {
+			t.Errorf("Expected code length 8, got %d", len(code))
+		}
+
+		// Check for duplicates (very unlikely with crypto/rand)
+		if seen[code] {
+			t.Errorf("Duplicate code generated: %s", code)
+		}
+		seen[code] = true
+
+		// Ambiguous characters are never used
+		if strings.ContainsAny(code, "0O1I") {
+			t.Errorf("Ambiguous character in code: %s", code)
+		}
+	}
+}
+
+func TestNew_Numeric(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := New(rewardtypes.CodeConfig{Strategy: StrategyNumeric, Length: 8})
+		if err != nil {
+			t.Fatalf("Failed to generate code: %v", err)
+		}
+
+		if len(code) != 8 {
+			t.Errorf("Expected code length 8, got %d", len(code))
+		}
+
+		for _, c := range code {
+			if c < '0' || c > '9' {
+				t.Errorf("Invalid character in code: %c", c)
+			}
+		}
+	}
+}
+
+func TestNew_Checksum(t *testing.T) {
+	cfg := rewardtypes.CodeConfig{Strategy: StrategyChecksum, Length: 6, Prefix: "OK"}
+
+	for i := 0; i < 100; i++ {
+		code, err := New(cfg)
+		if err != nil {
+			t.Fatalf("Failed to generate code: %v", err)
+		}
+
+		if len(code) != 9 {
+			t.Errorf("Expected code length 9, got %d", len(code))
+		}
+		if !ValidChecksum(code, "OK") {
+			t.Errorf("Generated code failed its checksum: %s", code)
+		}
+		if !ValidChecksum(strings.ToLower(code), "ok") {
+			t.Errorf("Checksum should ignore case: %s", code)
+		}
+
+		// Changing any single body character must be detected
+		body := []byte(code)
+		for _, c := range []byte(alphanumeric) {
+			if c == body[4] {
+				continue
+			}
+			typo := append([]byte(nil), body...)
+			typo[4] = c
+			if ValidChecksum(string(typo), "OK") {
+				t.Errorf("Typo %s of %s passed the checksum", typo, code)
+			}
+		}
+	}
+
+	if ValidChecksum("XX", "") {
+		t.Error("Code without body should be invalid")
+	}
+	if ValidChecksum("AB234567", "ZZ") {
+		t.Error("Code with the wrong prefix should be invalid")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     rewardtypes.CodeConfig
+		wantErr bool
+	}{
+		{"alphanumeric", rewardtypes.CodeConfig{Strategy: StrategyAlphanumeric, Length: 8}, false},
+		{"numeric", rewardtypes.CodeConfig{Strategy: StrategyNumeric, Length: 4}, false},
+		{"checksum with prefix", rewardtypes.CodeConfig{Strategy: StrategyChecksum, Length: 8, Prefix: "SPAR"}, false},
+		{"unknown strategy", rewardtypes.CodeConfig{Strategy: "emoji", Length: 8}, true},
+		{"too short", rewardtypes.CodeConfig{Strategy: StrategyNumeric, Length: 3}, true},
+		{"too long", rewardtypes.CodeConfig{Strategy: StrategyAlphanumeric, Length: 21}, true},
+		{"prefix without checksum", rewardtypes.CodeConfig{Strategy: StrategyNumeric, Length: 6, Prefix: "AB"}, true},
+		{"long prefix", rewardtypes.CodeConfig{Strategy: StrategyChecksum, Length: 6, Prefix: "ABCDEFG"}, true},
+		{"prefix with symbol", rewardtypes.CodeConfig{Strategy: StrategyChecksum, Length: 6, Prefix: "A-B"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("Expected ErrInvalidConfig, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFromMetadata(t *testing.T) {
+	// No configuration falls back to the default
+	cfg, err := FromMetadata([]byte(`{"amount": 5}`), DefaultNumeric)
+	if err != nil {
+		t.Fatalf("FromMetadata failed: %v", err)
+	}
+	if cfg != DefaultNumeric {
+		t.Errorf("Expected default config, got %+v", cfg)
+	}
+
+	// A strategy without a length gets the strategy's default length
+	cfg, err = FromMetadata([]byte(`{"code": {"strategy": "checksum", "prefix": "ab"}}`), DefaultNumeric)
+	if err != nil {
+		t.Fatalf("FromMetadata failed: %v", err)
+	}
+	want := rewardtypes.CodeConfig{Strategy: StrategyChecksum, Length: 8, Prefix: "AB"}
+	if cfg != want {
+		t.Errorf("Expected %+v, got %+v", want, cfg)
+	}
+
+	if _, err := FromMetadata([]byte(`{"code": {"strategy": "numeric", "length": 50}}`), DefaultNumeric); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestGenerate_NilGenerator(t *testing.T) {
+	var g *Generator
+
+	code, err := g.Generate(context.Background(), pgtype.UUID{}, DefaultAlphanumeric)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(code) != 8 {
+		t.Errorf("Expected code length 8, got %d", len(code))
+	}
+
+	if _, err := g.Generate(context.Background(), pgtype.UUID{}, rewardtypes.CodeConfig{}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/bmachimbira/loyalty/api/internal/db"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
@@ -28,12 +29,13 @@ func (s *Service) ExpireOldIssuances(ctx context.Context) error {
 
 	// Query for expired issuances
 	rows, err := tx.Query(ctx, `
-		SELECT i.id, i.tenant_id, i.campaign_id, i.cost_amount
+		SELECT i.id, i.tenant_id, COALESCE(i.budget_id, c.budget_id), i.cost_amount, i.currency
 		FROM issuances i
+		LEFT JOIN campaigns c ON c.id = i.campaign_id
 		WHERE i.status = 'issued'
 		  AND i.expires_at IS NOT NULL
 		  AND i.expires_at < NOW()
-		FOR UPDATE SKIP LOCKED
+		FOR UPDATE OF i SKIP LOCKED
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to query expired issuances: %w", err)
@@ -44,17 +46,18 @@ func (s *Service) ExpireOldIssuances(ctx context.Context) error {
 	errorCount := 0
 
 	for rows.Next() {
-		var issuanceID, tenantID, campaignID pgtype.UUID
+		var issuanceID, tenantID, budgetID pgtype.UUID
 		var costAmount pgtype.Numeric
+		var currency pgtype.Text
 
-		if err := rows.Scan(&issuanceID, &tenantID, &campaignID, &costAmount); err != nil {
+		if err := rows.Scan(&issuanceID, &tenantID, &budgetID, &costAmount, &currency); err != nil {
 			log.Printf("Error scanning expired issuance: %v", err)
 			errorCount++
 			continue
 		}
 
 		// Transition to expired state
-		err = s.updateStateInTx(ctx, tx, issuanceID, tenantID, StateIssued, StateExpired)
+		err = s.updateStateInTx(ctx, tx, issuanceID, tenantID, StateIssued, StateExpired, SystemOrigin)
 		if err != nil {
 			log.Printf("Failed to expire issuance %s: %v", issuanceID, err)
 			errorCount++
@@ -62,7 +65,7 @@ func (s *Service) ExpireOldIssuances(ctx context.Context) error {
 		}
 
 		// Release the budget reservation
-		err = s.releaseBudget(ctx, tx, campaignID, issuanceID)
+		err = s.releaseBudget(ctx, tx, tenantID, budgetID, issuanceID, costAmount, currency)
 		if err != nil {
 			log.Printf("Failed to release budget for issuance %s: %v", issuanceID, err)
 			errorCount++
@@ -87,30 +90,18 @@ func (s *Service) ExpireOldIssuances(ctx context.Context) error {
 
 // releaseBudget releases a budget reservation for an expired or cancelled issuance
 // This uses the release_budget database function to atomically:
-// 1. Find the reserve ledger entry
-// 2. Create a release ledger entry
-// 3. Update the budget balance
-func (s *Service) releaseBudget(ctx context.Context, tx pgx.Tx, campaignID, issuanceID pgtype.UUID) error {
-	// Get the budget ID from the campaign
-	var budgetID pgtype.UUID
-	var tenantID pgtype.UUID
-
-	err := tx.QueryRow(ctx, `
-		SELECT budget_id, tenant_id
-		FROM campaigns
-		WHERE id = $1
-	`, campaignID).Scan(&budgetID, &tenantID)
-
-	if err != nil {
-		return fmt.Errorf("failed to get campaign budget: %w", err)
+// 1. Create a release ledger entry
+// 2. Update the budget balance
+// budgetID is the budget the issuance was reserved from; there is nothing to
+// release when it or the cost is unset.
+func (s *Service) releaseBudget(ctx context.Context, tx pgx.Tx, tenantID, budgetID, issuanceID pgtype.UUID, amount pgtype.Numeric, currency pgtype.Text) error {
+	if !budgetID.Valid || !amount.Valid || !currency.Valid {
+		return nil
 	}
 
 	// Call the release_budget function
-	// This function expects (p_tenant_id, p_budget_id, p_ref_type, p_ref_id)
-	_, err = tx.Exec(ctx, `
-		SELECT release_budget($1::uuid, $2::uuid, 'issuance'::text, $3::uuid)
-	`, tenantID, budgetID, issuanceID)
-
+	_, err := tx.Exec(ctx, "SELECT release_budget($1, $2, $3, $4, $5)",
+		tenantID, budgetID, amount, currency.String, issuanceID)
 	if err != nil {
 		return fmt.Errorf("release_budget function failed: %w", err)
 	}
@@ -155,19 +146,15 @@ func (s *Service) ExpireIssuance(ctx context.Context, issuanceID, tenantID pgtyp
 	defer tx.Rollback(ctx)
 
 	// Get issuance
-	var campaignID pgtype.UUID
-	var status string
-
-	err = tx.QueryRow(ctx, `
-		SELECT campaign_id, status
-		FROM issuances
-		WHERE id = $1 AND tenant_id = $2
-		FOR UPDATE
-	`, issuanceID, tenantID).Scan(&campaignID, &status)
-
+	qtx := s.queries.WithTx(tx)
+	issuance, err := qtx.GetIssuanceForUpdate(ctx, db.GetIssuanceForUpdateParams{
+		ID:       issuanceID,
+		TenantID: tenantID,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to get issuance: %w", err)
 	}
+	status := issuance.Status
 
 	// Can only expire issued issuances
 	if status != string(StateIssued) {
@@ -175,13 +162,17 @@ func (s *Service) ExpireIssuance(ctx context.Context, issuanceID, tenantID pgtyp
 	}
 
 	// Transition to expired
-	err = s.updateStateInTx(ctx, tx, issuanceID, tenantID, StateIssued, StateExpired)
+	err = s.updateStateInTx(ctx, tx, issuanceID, tenantID, StateIssued, StateExpired, SystemOrigin)
 	if err != nil {
 		return fmt.Errorf("failed to update state: %w", err)
 	}
 
 	// Release budget
-	err = s.releaseBudget(ctx, tx, campaignID, issuanceID)
+	budgetID, err := IssuanceBudget(ctx, qtx, issuance)
+	if err != nil {
+		return err
+	}
+	err = s.releaseBudget(ctx, tx, tenantID, budgetID, issuanceID, issuance.CostAmount, issuance.Currency)
 	if err != nil {
 		return fmt.Errorf("failed to release budget: %w", err)
 	}
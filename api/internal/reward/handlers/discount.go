@@ -2,32 +2,45 @@ package handlers
 
 import (
 	"context"
-	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/reward/codes"
 	"github.com/bmachimbira/loyalty/api/internal/rewardtypes"
 )
 
 // DiscountHandler handles discount code rewards
-type DiscountHandler struct{}
+type DiscountHandler struct {
+	codes *codes.Generator
+}
 
-// Process generates a unique discount code and sets expiry
-func (h *DiscountHandler) Process(ctx context.Context, issuance *db.Issuance, rewardCatalog *db.RewardCatalog) (*ProcessResult, error) {
-	// Generate unique discount code
-	code, err := generateDiscountCode()
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate discount code: %w", err)
+// NewDiscountHandler creates a new discount handler
+func NewDiscountHandler(codes *codes.Generator) *DiscountHandler {
+	return &DiscountHandler{
+		codes: codes,
 	}
+}
 
+// Process generates a unique discount code and sets expiry
+func (h *DiscountHandler) Process(ctx context.Context, issuance *db.Issuance, rewardCatalog *db.RewardCatalog) (*ProcessResult, error) {
 	// Parse metadata
 	var meta rewardtypes.DiscountMetadata
 	if err := json.Unmarshal(rewardCatalog.Metadata, &meta); err != nil {
 		return nil, fmt.Errorf("invalid discount metadata: %w", err)
 	}
 
+	// Generate unique discount code
+	codeConfig, err := codes.FromMetadata(rewardCatalog.Metadata, codes.DefaultAlphanumeric)
+	if err != nil {
+		return nil, err
+	}
+	code, err := h.codes.Generate(ctx, issuance.TenantID, codeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate discount code: %w", err)
+	}
+
 	// Validate discount metadata
 	if meta.DiscountType != "amount" && meta.DiscountType != "percent" {
 		return nil, fmt.Errorf("invalid discount type: %s", meta.DiscountType)
@@ -58,23 +71,3 @@ func (h *DiscountHandler) Process(ctx context.Context, issuance *db.Issuance, re
 		Metadata:  resultMeta,
 	}, nil
 }
-
-// generateDiscountCode generates a cryptographically secure alphanumeric code
-// Uses characters that are easy to read and type (excludes 0, O, 1, I, etc.)
-func generateDiscountCode() (string, error) {
-	const (
-		chars  = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
-		length = 8
-	)
-
-	b := make([]byte, length)
-	if _, err := rand.Read(b); err != nil {
-		return "", err
-	}
-
-	for i := range b {
-		b[i] = chars[int(b[i])%len(chars)]
-	}
-
-	return string(b), nil
-}
@@ -3,9 +3,11 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/bmachimbira/loyalty/api/internal/connectors"
 	"github.com/bmachimbira/loyalty/api/internal/db"
 	"github.com/bmachimbira/loyalty/api/internal/rewardtypes"
 )
@@ -38,18 +40,26 @@ type IssueResponse struct {
 // ExternalVoucherHandler handles external voucher provider integration
 type ExternalVoucherHandler struct {
 	connectors map[string]Connector
+	breakers   map[string]*connectors.CircuitBreaker
 }
 
 // NewExternalVoucherHandler creates a new external voucher handler
 func NewExternalVoucherHandler() *ExternalVoucherHandler {
 	return &ExternalVoucherHandler{
 		connectors: make(map[string]Connector),
+		breakers:   make(map[string]*connectors.CircuitBreaker),
 	}
 }
 
-// RegisterConnector registers an external provider connector
+// RegisterConnector registers an external provider connector. Each provider
+// gets its own circuit breaker, so issuing fails fast while it is down
+// instead of waiting out every retry.
 func (h *ExternalVoucherHandler) RegisterConnector(providerID string, connector Connector) {
+	breaker := connectors.NewCircuitBreaker(5, 60*time.Second)
+	breaker.SetName("supplier:" + providerID)
+
 	h.connectors[providerID] = connector
+	h.breakers[providerID] = breaker
 }
 
 // Process issues a voucher through an external provider
@@ -65,6 +75,7 @@ func (h *ExternalVoucherHandler) Process(ctx context.Context, issuance *db.Issua
 	if !ok {
 		return nil, fmt.Errorf("supplier not configured: %s", meta.SupplierID)
 	}
+	breaker := h.breakers[meta.SupplierID]
 
 	// Get face value as float64
 	faceValue, err := issuance.FaceAmount.Float64Value()
@@ -94,10 +105,17 @@ func (h *ExternalVoucherHandler) Process(ctx context.Context, issuance *db.Issua
 
 	// Retry up to 3 times with exponential backoff
 	for attempt := 1; attempt <= 3; attempt++ {
-		resp, lastErr = connector.IssueVoucher(ctx, params)
+		lastErr = breaker.Execute(func() error {
+			var err error
+			resp, err = connector.IssueVoucher(ctx, params)
+			return err
+		})
 		if lastErr == nil {
 			break
 		}
+		if errors.Is(lastErr, connectors.ErrCircuitOpen) {
+			return nil, fmt.Errorf("supplier %s unavailable: %w", meta.SupplierID, lastErr)
+		}
 
 		if attempt < 3 {
 			// Exponential backoff: 1s, 2s, 4s
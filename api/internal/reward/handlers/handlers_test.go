@@ -120,7 +120,7 @@ func TestPointsCreditHandler_Process(t *testing.T) {
 }
 
 func TestPhysicalItemHandler_Process(t *testing.T) {
-	handler := NewPhysicalItemHandler()
+	handler := NewPhysicalItemHandler(nil)
 	ctx := context.Background()
 
 	issuance := &db.Issuance{}
@@ -161,61 +161,45 @@ func TestPhysicalItemHandler_Process(t *testing.T) {
 	}
 }
 
-func TestGenerateDiscountCode(t *testing.T) {
-	// Generate multiple codes to check uniqueness and format
-	codes := make(map[string]bool)
-	for i := 0; i < 100; i++ {
-		code, err := generateDiscountCode()
-		if err != nil {
-			t.Fatalf("Failed to generate code: %v", err)
-		}
-
-		if len(code) != 8 {
-			t.Errorf("Expected code length 8, got %d", len(code))
-		}
-
-		// Check for duplicates (very unlikely with crypto/rand)
-		if codes[code] {
-			t.Errorf("Duplicate code generated: %s", code)
-		}
-		codes[code] = true
-
-		// Verify characters
-		for _, c := range code {
-			validChars := "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
-			if !contains(validChars, byte(c)) {
-				t.Errorf("Invalid character in code: %c", c)
-			}
-		}
+func TestDiscountHandler_ConfiguredCode(t *testing.T) {
+	handler := NewDiscountHandler(nil)
+	ctx := context.Background()
+
+	metaBytes := []byte(`{
+		"discount_type": "amount",
+		"amount": 5,
+		"valid_days": 7,
+		"code": {"strategy": "checksum", "length": 6, "prefix": "sp"}
+	}`)
+
+	rewardCatalog := &db.RewardCatalog{
+		Type:     "discount",
+		Metadata: metaBytes,
 	}
-}
 
-func TestGenerateClaimToken(t *testing.T) {
-	// Generate multiple tokens to check format
-	for i := 0; i < 100; i++ {
-		token, err := generateClaimToken()
-		if err != nil {
-			t.Fatalf("Failed to generate token: %v", err)
-		}
-
-		if len(token) != 6 {
-			t.Errorf("Expected token length 6, got %d", len(token))
-		}
-
-		// Verify all characters are digits
-		for _, c := range token {
-			if c < '0' || c > '9' {
-				t.Errorf("Invalid character in token: %c", c)
-			}
-		}
+	result, err := handler.Process(ctx, &db.Issuance{}, rewardCatalog)
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// Prefix + 6 body characters + check character
+	if len(result.Code) != 9 {
+		t.Errorf("Expected code length 9, got %d", len(result.Code))
+	}
+	if result.Code[:2] != "SP" {
+		t.Errorf("Expected prefix SP, got %s", result.Code)
 	}
 }
 
-func contains(s string, b byte) bool {
-	for i := 0; i < len(s); i++ {
-		if s[i] == b {
-			return true
-		}
+func TestDiscountHandler_InvalidCodeConfig(t *testing.T) {
+	handler := NewDiscountHandler(nil)
+
+	rewardCatalog := &db.RewardCatalog{
+		Type:     "discount",
+		Metadata: []byte(`{"discount_type": "amount", "amount": 5, "code": {"strategy": "emoji"}}`),
+	}
+
+	if _, err := handler.Process(context.Background(), &db.Issuance{}, rewardCatalog); err == nil {
+		t.Error("Expected error for unknown code strategy")
 	}
-	return false
 }
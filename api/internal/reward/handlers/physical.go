@@ -2,21 +2,25 @@ package handlers
 
 import (
 	"context"
-	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/reward/codes"
 	"github.com/bmachimbira/loyalty/api/internal/rewardtypes"
 )
 
 // PhysicalItemHandler handles physical item rewards
-type PhysicalItemHandler struct{}
+type PhysicalItemHandler struct {
+	codes *codes.Generator
+}
 
 // NewPhysicalItemHandler creates a new physical item handler
-func NewPhysicalItemHandler() *PhysicalItemHandler {
-	return &PhysicalItemHandler{}
+func NewPhysicalItemHandler(codes *codes.Generator) *PhysicalItemHandler {
+	return &PhysicalItemHandler{
+		codes: codes,
+	}
 }
 
 // Process generates a collection/claim token for physical items
@@ -38,8 +42,13 @@ func (h *PhysicalItemHandler) Process(ctx context.Context, issuance *db.Issuance
 		meta.CollectionPeriod = 30
 	}
 
-	// Generate unique collection token
-	token, err := generateClaimToken()
+	// Generate unique collection token; numeric by default so store staff
+	// can verify it easily
+	codeConfig, err := codes.FromMetadata(rewardCatalog.Metadata, codes.DefaultNumeric)
+	if err != nil {
+		return nil, err
+	}
+	token, err := h.codes.Generate(ctx, issuance.TenantID, codeConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate claim token: %w", err)
 	}
@@ -70,23 +79,3 @@ func (h *PhysicalItemHandler) Process(ctx context.Context, issuance *db.Issuance
 		Metadata:  resultMeta,
 	}, nil
 }
-
-// generateClaimToken generates a cryptographically secure numeric token
-// Format: 6 digits for easy verification by store staff
-func generateClaimToken() (string, error) {
-	const (
-		digits = "0123456789"
-		length = 6
-	)
-
-	b := make([]byte, length)
-	if _, err := rand.Read(b); err != nil {
-		return "", err
-	}
-
-	for i := range b {
-		b[i] = digits[int(b[i])%len(digits)]
-	}
-
-	return string(b), nil
-}
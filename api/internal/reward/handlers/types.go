@@ -2,11 +2,16 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/bmachimbira/loyalty/api/internal/db"
 )
 
+// ErrTemporary marks a handler failure worth retrying later, such as a
+// provider answering 5xx. Handlers wrap it with fmt.Errorf("%w: ...").
+var ErrTemporary = errors.New("temporary reward handler failure")
+
 // RewardHandler defines the interface that all reward type handlers must implement
 type RewardHandler interface {
 	// Process handles the issuance of a specific reward type
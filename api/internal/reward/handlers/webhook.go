@@ -120,6 +120,9 @@ func (h *WebhookHandler) Process(ctx context.Context, issuance *db.Issuance, rew
 	// Check response status
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return nil, fmt.Errorf("%w: webhook returned status %d: %s", ErrTemporary, resp.StatusCode, string(bodyBytes))
+		}
 		return nil, fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
@@ -0,0 +1,172 @@
+package reward
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// MaxImportRows is the largest redemption batch accepted in one import
+const MaxImportRows = 1000
+
+// Redemption import row outcomes
+const (
+	ImportStatusRedeemed        = "redeemed"
+	ImportStatusAlreadyRedeemed = "already_redeemed"
+	ImportStatusFailed          = "failed"
+)
+
+// RedemptionImportRow is a single offline redemption reported by a POS terminal
+type RedemptionImportRow struct {
+	Code       string
+	RedeemedAt time.Time
+	StoreID    string
+	StaffRef   string
+}
+
+// Validate validates the import row
+func (r RedemptionImportRow) Validate() error {
+	if strings.TrimSpace(r.Code) == "" {
+		return errors.New("code is required")
+	}
+	if r.RedeemedAt.IsZero() {
+		return errors.New("redeemed_at is required")
+	}
+	if r.RedeemedAt.After(time.Now().Add(5 * time.Minute)) {
+		return errors.New("redeemed_at is in the future")
+	}
+	return nil
+}
+
+// RedemptionImportResult is the outcome of importing one row
+type RedemptionImportResult struct {
+	Row        int
+	Code       string
+	Status     string
+	IssuanceID pgtype.UUID
+	Error      string
+}
+
+// ImportRedemptions applies a batch of offline redemptions. Each row is
+// processed in its own transaction so one bad row does not fail the batch.
+// Re-importing a row whose issuance is already redeemed reports
+// already_redeemed without charging the budget again.
+func (s *Service) ImportRedemptions(ctx context.Context, tenantID pgtype.UUID, rows []RedemptionImportRow, origin Origin) []RedemptionImportResult {
+	results := make([]RedemptionImportResult, len(rows))
+	seen := make(map[string]int, len(rows))
+
+	for i, row := range rows {
+		code := strings.ToUpper(strings.TrimSpace(row.Code))
+		result := RedemptionImportResult{Row: i + 1, Code: code}
+
+		if err := row.Validate(); err != nil {
+			result.Status = ImportStatusFailed
+			result.Error = err.Error()
+			results[i] = result
+			continue
+		}
+
+		// The same code twice in one batch is a duplicate of the earlier row
+		if first, ok := seen[code]; ok {
+			result.Status = ImportStatusAlreadyRedeemed
+			result.IssuanceID = results[first].IssuanceID
+			result.Error = fmt.Sprintf("duplicate of row %d", first+1)
+			results[i] = result
+			continue
+		}
+		seen[code] = i
+
+		issuanceID, status, err := s.importRedemption(ctx, tenantID, code, row, origin)
+		result.IssuanceID = issuanceID
+		result.Status = status
+		if err != nil {
+			result.Status = ImportStatusFailed
+			result.Error = err.Error()
+		}
+		results[i] = result
+	}
+
+	return results
+}
+
+// importRedemption redeems the issuance matching code at the reported time
+func (s *Service) importRedemption(ctx context.Context, tenantID pgtype.UUID, code string, row RedemptionImportRow, origin Origin) (pgtype.UUID, string, error) {
+	var issuanceID pgtype.UUID
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return issuanceID, "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		status    string
+		expiresAt pgtype.Timestamptz
+		charge    = db.Issuance{TenantID: tenantID}
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT id, status, campaign_id, budget_id, cost_amount, currency, expires_at
+		FROM issuances
+		WHERE tenant_id = $1 AND upper(code) = $2
+		FOR UPDATE
+	`, tenantID, code).Scan(&issuanceID, &status, &charge.CampaignID, &charge.BudgetID, &charge.CostAmount, &charge.Currency, &expiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return issuanceID, "", errors.New("no issuance found for code")
+		}
+		return issuanceID, "", fmt.Errorf("failed to get issuance: %w", err)
+	}
+
+	switch State(status) {
+	case StateRedeemed:
+		return issuanceID, ImportStatusAlreadyRedeemed, nil
+	case StateIssued:
+	default:
+		return issuanceID, "", fmt.Errorf("cannot redeem issuance in state: %s", status)
+	}
+
+	// Offline redemptions are valid if they happened before expiry, even if
+	// they are synced afterwards
+	if expiresAt.Valid && row.RedeemedAt.After(expiresAt.Time) {
+		return issuanceID, "", errors.New("reward had expired at redemption time")
+	}
+
+	if err := s.updateStateInTx(ctx, tx, issuanceID, tenantID, StateIssued, StateRedeemed, origin); err != nil {
+		return issuanceID, "", err
+	}
+
+	// Keep the redemption time and place reported by the POS
+	if _, err := tx.Exec(ctx, `
+		UPDATE issuances
+		SET redeemed_at = $3,
+		    redeemed_store_id = NULLIF($4, ''),
+		    redeemed_staff_ref = NULLIF($5, ''),
+		    redemption_source = 'pos_import'
+		WHERE id = $1 AND tenant_id = $2
+	`, issuanceID, tenantID, row.RedeemedAt, row.StoreID, row.StaffRef); err != nil {
+		return issuanceID, "", fmt.Errorf("failed to record redemption details: %w", err)
+	}
+
+	// Imported redemptions are synced after the fact, so their receipts are
+	// recorded but not confirmed to the customer
+	if _, err := createReceipt(ctx, s.queries.WithTx(tx), issuanceID, tenantID, origin); err != nil {
+		return issuanceID, "", err
+	}
+
+	charge.ID = issuanceID
+	if err := s.chargeBudget(ctx, tx, charge); err != nil {
+		return issuanceID, "", fmt.Errorf("failed to charge budget: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return issuanceID, "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return issuanceID, ImportStatusRedeemed, nil
+}
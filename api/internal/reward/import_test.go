@@ -0,0 +1,57 @@
+package reward
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestRedemptionImportRowValidate(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		row     RedemptionImportRow
+		wantErr string
+	}{
+		{"valid", RedemptionImportRow{Code: "ABC123", RedeemedAt: now.Add(-time.Hour)}, ""},
+		{"slightly ahead of server clock", RedemptionImportRow{Code: "ABC123", RedeemedAt: now.Add(time.Minute)}, ""},
+		{"missing code", RedemptionImportRow{Code: "  ", RedeemedAt: now}, "code is required"},
+		{"missing time", RedemptionImportRow{Code: "ABC123"}, "redeemed_at is required"},
+		{"future time", RedemptionImportRow{Code: "ABC123", RedeemedAt: now.Add(time.Hour)}, "redeemed_at is in the future"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.row.Validate()
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.wantErr {
+				t.Errorf("Validate() = %q, want %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestImportRedemptionsInvalidRows(t *testing.T) {
+	// Invalid rows fail on their own before touching the database
+	service := &Service{}
+	rows := []RedemptionImportRow{
+		{Code: " abc123 "},
+		{RedeemedAt: time.Now()},
+	}
+
+	got := service.ImportRedemptions(context.Background(), pgtype.UUID{}, rows, SystemOrigin)
+	want := []RedemptionImportResult{
+		{Row: 1, Code: "ABC123", Status: ImportStatusFailed, Error: "redeemed_at is required"},
+		{Row: 2, Code: "", Status: ImportStatusFailed, Error: "code is required"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ImportRedemptions() = %+v, want %+v", got, want)
+	}
+}
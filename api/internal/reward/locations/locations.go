@@ -0,0 +1,106 @@
+// Package locations restricts the stores a reward can be redeemed at. A
+// reward opts in by listing location codes in its metadata; a redemption
+// must then be made at one of them unless a manager overrides it.
+package locations
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/rewardtypes"
+)
+
+var (
+	// ErrInvalidConfig is returned for allowed locations that can't be used
+	ErrInvalidConfig = errors.New("invalid allowed locations")
+
+	// ErrNotAllowed is returned when a reward is redeemed away from the
+	// stores it is restricted to
+	ErrNotAllowed = errors.New("reward can't be redeemed at this store")
+)
+
+// FromMetadata returns the location codes in a reward's metadata, or nil
+// when the reward can be redeemed anywhere
+func FromMetadata(metadata []byte) ([]string, error) {
+	var meta rewardtypes.LocationMetadata
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &meta); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+	}
+
+	var allowed []string
+	for _, code := range meta.AllowedLocations {
+		code = strings.TrimSpace(code)
+		if code == "" {
+			return nil, fmt.Errorf("%w: location codes must not be blank", ErrInvalidConfig)
+		}
+		if !Allowed(allowed, code) {
+			allowed = append(allowed, code)
+		}
+	}
+	return allowed, nil
+}
+
+// Allowed reports whether a reward restricted to the allowed location codes
+// can be redeemed at storeID. Codes are matched case insensitively.
+func Allowed(allowed []string, storeID string) bool {
+	for _, code := range allowed {
+		if strings.EqualFold(code, strings.TrimSpace(storeID)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Names returns the names of the allowed locations, keeping the code of any
+// that isn't one of the tenant's active locations
+func Names(allowed []string, tenantLocations []db.Location) []string {
+	names := make([]string, len(allowed))
+	for i, code := range allowed {
+		names[i] = code
+		for _, location := range tenantLocations {
+			if location.Active && strings.EqualFold(location.Code, code) {
+				names[i] = location.Name
+				break
+			}
+		}
+	}
+	return names
+}
+
+// Error is an ErrNotAllowed naming the stores the reward can be redeemed at
+type Error struct {
+	StoreID string
+	Stores  []string
+}
+
+func (e *Error) Error() string {
+	if e.StoreID == "" {
+		return fmt.Sprintf("%v: no store given, allowed at %s", ErrNotAllowed, strings.Join(e.Stores, ", "))
+	}
+	return fmt.Sprintf("%v: store %q, allowed at %s", ErrNotAllowed, e.StoreID, strings.Join(e.Stores, ", "))
+}
+
+func (e *Error) Unwrap() error {
+	return ErrNotAllowed
+}
+
+// Message is the explanation shown to the customer
+func (e *Error) Message() string {
+	if e.StoreID == "" {
+		return fmt.Sprintf("This reward can only be redeemed in store at %s.", joinOr(e.Stores))
+	}
+	return fmt.Sprintf("This reward can't be redeemed at this store. It can be redeemed at %s.", joinOr(e.Stores))
+}
+
+// joinOr lists names as "A", "A or B" or "A, B or C"
+func joinOr(names []string) string {
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
@@ -0,0 +1,54 @@
+package locations
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromMetadata(t *testing.T) {
+	allowed, err := FromMetadata([]byte(`{"terms": "In store only"}`))
+	require.NoError(t, err)
+	assert.Nil(t, allowed)
+
+	allowed, err = FromMetadata([]byte(`{"allowed_locations": [" HRE-01", "byo-02", "hre-01"]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"HRE-01", "byo-02"}, allowed)
+
+	for _, metadata := range []string{
+		`{"allowed_locations": ["HRE-01", " "]}`,
+		`{"allowed_locations": "HRE-01"}`,
+	} {
+		_, err := FromMetadata([]byte(metadata))
+		assert.ErrorIs(t, err, ErrInvalidConfig, metadata)
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	allowed := []string{"HRE-01", "BYO-02"}
+	assert.True(t, Allowed(allowed, "hre-01"))
+	assert.True(t, Allowed(allowed, " BYO-02 "))
+	assert.False(t, Allowed(allowed, "MUT-03"))
+	assert.False(t, Allowed(allowed, ""))
+}
+
+func TestNames(t *testing.T) {
+	tenantLocations := []db.Location{
+		{Code: "HRE-01", Name: "Sam Levy's Village", Active: true},
+		{Code: "BYO-02", Name: "Bulawayo Centre", Active: false},
+	}
+	names := Names([]string{"hre-01", "BYO-02", "MUT-03"}, tenantLocations)
+	assert.Equal(t, []string{"Sam Levy's Village", "BYO-02", "MUT-03"}, names)
+}
+
+func TestErrorMessage(t *testing.T) {
+	err := &Error{StoreID: "MUT-03", Stores: []string{"Sam Levy's Village", "Avondale", "Borrowdale"}}
+	assert.True(t, errors.Is(err, ErrNotAllowed))
+	assert.Equal(t, "This reward can't be redeemed at this store. It can be redeemed at Sam Levy's Village, Avondale or Borrowdale.", err.Message())
+
+	err = &Error{Stores: []string{"Avondale"}}
+	assert.Equal(t, "This reward can only be redeemed in store at Avondale.", err.Message())
+}
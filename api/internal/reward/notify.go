@@ -0,0 +1,135 @@
+package reward
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/logging"
+	"github.com/bmachimbira/loyalty/api/internal/metering"
+	"github.com/bmachimbira/loyalty/api/internal/timezone"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// notifyTimeout bounds a background customer notification
+const notifyTimeout = 30 * time.Second
+
+// TemplateSender sends WhatsApp template messages
+type TemplateSender interface {
+	SendTemplate(ctx context.Context, to, templateName string, params map[string]string) error
+}
+
+// SetTemplateSender enables notifying customers over WhatsApp of
+// redemptions made at a till or through the API, and of reissued rewards
+func (s *Service) SetTemplateSender(sender TemplateSender) {
+	s.templates = sender
+}
+
+// WaitForNotifications blocks until in-flight background customer
+// notifications finish
+func (s *Service) WaitForNotifications() {
+	s.notifications.Wait()
+}
+
+// inBackground runs a customer notification in the background, so the
+// change it is about is not slowed down by delivery
+func (s *Service) inBackground(ctx context.Context, fn func(ctx context.Context, logger *slog.Logger)) {
+	logger := logging.FromContext(ctx, nil)
+
+	s.notifications.Add(1)
+	go func() {
+		defer s.notifications.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+		fn(ctx, logger)
+	}()
+}
+
+// whatsAppContact is how a customer can be messaged on WhatsApp. status is
+// set instead when they can't: they have no phone number or haven't
+// consented to loyalty messages on WhatsApp.
+type whatsAppContact struct {
+	phone  string
+	loc    *time.Location
+	status string
+}
+
+// whatsAppContact looks up how to message a customer on WhatsApp
+func (s *Service) whatsAppContact(ctx context.Context, tenantID, customerID pgtype.UUID) (whatsAppContact, error) {
+	var contact whatsAppContact
+	err := s.withTenant(ctx, tenantID, func(qtx *db.Queries) error {
+		customer, err := qtx.GetCustomerByID(ctx, db.GetCustomerByIDParams{
+			ID:       customerID,
+			TenantID: tenantID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get customer: %w", err)
+		}
+		if !customer.PhoneE164.Valid || customer.PhoneE164.String == "" {
+			contact.status = ConfirmationNoPhone
+			return nil
+		}
+		contact.phone = customer.PhoneE164.String
+
+		consent, err := qtx.GetLatestConsent(ctx, db.GetLatestConsentParams{
+			TenantID:   tenantID,
+			CustomerID: customerID,
+			Channel:    "whatsapp",
+			Purpose:    "loyalty",
+		})
+		switch {
+		case errors.Is(err, pgx.ErrNoRows), err == nil && !consent.Granted:
+			contact.status = ConfirmationNoConsent
+			return nil
+		case err != nil:
+			return fmt.Errorf("failed to get consent: %w", err)
+		}
+
+		contact.loc, err = timezone.NewResolver(qtx).Location(ctx, tenantID)
+		return err
+	})
+	return contact, err
+}
+
+// sendTemplate sends a template and returns ConfirmationSent or, if it
+// failed, ConfirmationFailed. Sends that fail for a reason worth retrying
+// are queued by the sender and count as sent.
+func (s *Service) sendTemplate(ctx context.Context, tenantID pgtype.UUID, phone, templateName string, params map[string]string, logger *slog.Logger) string {
+	if err := s.templates.SendTemplate(metering.WithTenant(ctx, tenantID), phone, templateName, params); err != nil {
+		logger.Warn("failed to send WhatsApp template",
+			"tenant_id", httputil.FormatUUID(tenantID.Bytes),
+			"template", templateName,
+			"error", err,
+		)
+		return ConfirmationFailed
+	}
+	return ConfirmationSent
+}
+
+// withTenant runs fn in a transaction scoped to the tenant. Notifications
+// run outside a tenant request.
+func (s *Service) withTenant(ctx context.Context, tenantID pgtype.UUID, fn func(qtx *db.Queries) error) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenantID.Bytes)); err != nil {
+		return fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	if err := fn(s.queries.WithTx(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+package reward
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/connectors"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/metrics"
+	"github.com/bmachimbira/loyalty/api/internal/reward/handlers"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Outcomes of processing a reserved issuance, as recorded in metrics
+const (
+	ProcessIssued  = "issued"
+	ProcessRetried = "retried"
+	ProcessFailed  = "failed"
+)
+
+const (
+	// DefaultProcessWorkers is how many issuances the processor handles at once
+	DefaultProcessWorkers = 8
+
+	// processBatchSize bounds how many of one tenant's issuances a pass
+	// processes; the rest are picked up by the next pass
+	processBatchSize = 200
+)
+
+var (
+	// ErrIssuanceBusy is returned when an issuance is no longer reserved or
+	// another worker is processing it
+	ErrIssuanceBusy = errors.New("issuance is not reserved or is being processed")
+
+	// ErrProcessingFailed is returned when a reward handler failed for good
+	// and the issuance was marked failed
+	ErrProcessingFailed = errors.New("reward processing failed")
+)
+
+// IsTransient reports whether a processing error is worth retrying: a
+// handler's temporary failure, an open circuit, a timeout or network error,
+// or a database conflict or lost connection
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, handlers.ErrTemporary) ||
+		errors.Is(err, connectors.ErrCircuitOpen) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// serialization_failure, deadlock_detected and connection exceptions
+		return pgErr.Code == "40001" || pgErr.Code == "40P01" || strings.HasPrefix(pgErr.Code, "08")
+	}
+	return false
+}
+
+// ProcessorPass is what one pass of the issuance processor did. Queued is
+// how many issuances were due when the pass started.
+type ProcessorPass struct {
+	Queued  int64
+	Issued  int
+	Retried int
+	Failed  int
+}
+
+// processJob is one reserved issuance handed to a processor worker
+type processJob struct {
+	tenantID   pgtype.UUID
+	issuanceID pgtype.UUID
+}
+
+// ProcessReserved runs one pass of the issuance processor. Every tenant's
+// reserved issuances that are due are processed, at most workers at a time.
+// Issuances are claimed with FOR UPDATE SKIP LOCKED, so several API
+// instances can process side by side. A failing tenant is logged and does
+// not stop the others.
+func (s *Service) ProcessReserved(ctx context.Context, workers int, now time.Time) (*ProcessorPass, error) {
+	if workers < 1 {
+		workers = DefaultProcessWorkers
+	}
+
+	tenants, err := s.queries.ListTenants(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	pass := &ProcessorPass{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan processJob)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				outcome := s.processJob(ctx, job, now)
+				if outcome == "" {
+					continue
+				}
+				metrics.RecordIssuanceProcessed(outcome)
+
+				mu.Lock()
+				switch outcome {
+				case ProcessIssued:
+					pass.Issued++
+				case ProcessRetried:
+					pass.Retried++
+				case ProcessFailed:
+					pass.Failed++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for _, tenant := range tenants {
+		ids, queued, err := s.processableIssuances(ctx, tenant.ID, now)
+		if err != nil {
+			log.Printf("Failed to list reserved issuances for tenant %s: %v", httputil.FormatUUID(tenant.ID.Bytes), err)
+			continue
+		}
+		pass.Queued += queued
+
+		for _, id := range ids {
+			select {
+			case jobs <- processJob{tenantID: tenant.ID, issuanceID: id}:
+			case <-ctx.Done():
+				break dispatch
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Retried issuances wait for their backoff, so they leave the queue
+	metrics.RecordIssuanceQueueDepth(max(pass.Queued-int64(pass.Issued+pass.Retried+pass.Failed), 0))
+	return pass, nil
+}
+
+// processableIssuances returns a batch of the tenant's reserved issuances
+// due for processing and how many are due in all
+func (s *Service) processableIssuances(ctx context.Context, tenantID pgtype.UUID, now time.Time) ([]pgtype.UUID, int64, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The processor runs outside a tenant request
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenantID.Bytes)); err != nil {
+		return nil, 0, fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	qtx := s.queries.WithTx(tx)
+	due := pgtype.Timestamptz{Time: now, Valid: true}
+	ids, err := qtx.ListProcessableIssuances(ctx, db.ListProcessableIssuancesParams{
+		TenantID: tenantID,
+		Now:      due,
+		RowLimit: processBatchSize,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list reserved issuances: %w", err)
+	}
+	queued, err := qtx.CountProcessableIssuances(ctx, db.CountProcessableIssuancesParams{
+		TenantID: tenantID,
+		Now:      due,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count reserved issuances: %w", err)
+	}
+
+	return ids, queued, tx.Commit(ctx)
+}
+
+// processJob processes one reserved issuance and returns the outcome, or ""
+// when there was nothing to do
+func (s *Service) processJob(ctx context.Context, job processJob, now time.Time) string {
+	err := s.ProcessIssuance(ctx, job.tenantID, job.issuanceID)
+	switch {
+	case err == nil:
+		return ProcessIssued
+	case errors.Is(err, ErrIssuanceBusy), ctx.Err() != nil:
+		return ""
+	case errors.Is(err, ErrProcessingFailed):
+		return ProcessFailed
+	}
+
+	// Transient handler failures, and failures around the handler, are
+	// retried with backoff
+	failed, deferErr := s.deferIssuance(ctx, job, err, now)
+	if deferErr != nil {
+		log.Printf("Failed to record processing failure for issuance %s: %v", httputil.FormatUUID(job.issuanceID.Bytes), deferErr)
+		return ""
+	}
+	if failed {
+		return ProcessFailed
+	}
+	return ProcessRetried
+}
+
+// deferIssuance records a failed attempt at processing an issuance and
+// schedules the next one by its reward type's retry policy. After the
+// policy's MaxAttempts the issuance is marked failed and its budget released
+// instead, which deferIssuance reports.
+func (s *Service) deferIssuance(ctx context.Context, job processJob, cause error, now time.Time) (bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(job.tenantID.Bytes)); err != nil {
+		return false, fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	qtx := s.queries.WithTx(tx)
+	issuance, err := qtx.GetIssuanceByID(ctx, db.GetIssuanceByIDParams{
+		ID:       job.issuanceID,
+		TenantID: job.tenantID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to get issuance: %w", err)
+	}
+	reward, err := qtx.GetRewardByID(ctx, db.GetRewardByIDParams{
+		ID:       issuance.RewardID,
+		TenantID: job.tenantID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to get reward: %w", err)
+	}
+	policy := s.RetryPolicy(reward.Type)
+
+	attempts, err := qtx.RecordIssuanceProcessFailure(ctx, db.RecordIssuanceProcessFailureParams{
+		ID:               job.issuanceID,
+		TenantID:         job.tenantID,
+		NextProcessAt:    pgtype.Timestamptz{Time: now.Add(policy.Delay(issuance.ProcessAttempts + 1)), Valid: true},
+		LastProcessError: pgtype.Text{String: cause.Error(), Valid: true},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to record processing failure: %w", err)
+	}
+
+	failed := attempts >= policy.MaxAttempts
+	if failed {
+		if err := TransitionIssuance(ctx, qtx, Transition{
+			IssuanceID: job.issuanceID,
+			TenantID:   job.tenantID,
+			From:       StateReserved,
+			To:         StateFailed,
+			Origin:     SystemOrigin,
+		}); err != nil {
+			return false, fmt.Errorf("failed to mark issuance failed: %w", err)
+		}
+		if err := s.releaseFailedBudget(ctx, tx, qtx, issuance); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return failed, nil
+}
+
+// RunIssuanceProcessor processes reserved issuances on a schedule until ctx
+// is cancelled, with at most workers in flight at once
+func (s *Service) RunIssuanceProcessor(ctx context.Context, interval time.Duration, workers int) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		pass, err := s.ProcessReserved(ctx, workers, time.Now())
+		if err != nil {
+			log.Printf("Issuance processor error: %v", err)
+		} else if pass.Issued+pass.Retried+pass.Failed > 0 {
+			log.Printf("Issuance processor: %d issued, %d retried, %d failed, %d queued",
+				pass.Issued, pass.Retried, pass.Failed, pass.Queued)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package reward
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/connectors"
+	"github.com/bmachimbira/loyalty/api/internal/reward/handlers"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"temporary handler failure", fmt.Errorf("%w: webhook returned status 503", handlers.ErrTemporary), true},
+		{"open circuit", fmt.Errorf("supplier acme unavailable: %w", connectors.ErrCircuitOpen), true},
+		{"timeout", fmt.Errorf("reward processing failed: %w", context.DeadlineExceeded), true},
+		{"network error", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, true},
+		{"lost connection", &pgconn.PgError{Code: "08006"}, true},
+		{"constraint violation", &pgconn.PgError{Code: "23505"}, false},
+		{"bad metadata", errors.New("invalid discount metadata"), false},
+		{"empty voucher pool", errors.New("no voucher codes available"), false},
+	}
+
+	for _, tt := range tests {
+		if got := IsTransient(tt.err); got != tt.want {
+			t.Errorf("%s: IsTransient() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	tests := []struct {
+		attempts int32
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{20, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := DefaultRetryPolicy.Delay(tt.attempts); got != tt.want {
+			t.Errorf("Delay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+
+	voucher := defaultRetryPolicies["external_voucher"]
+	if got := voucher.Delay(8); got != 2*time.Hour {
+		t.Errorf("external_voucher Delay(8) = %v, want 2h", got)
+	}
+}
+
+func TestParseRetryAttempts(t *testing.T) {
+	got, err := ParseRetryAttempts(" external_voucher=12, webhook_custom = 6,")
+	if err != nil {
+		t.Fatalf("ParseRetryAttempts() error = %v", err)
+	}
+	if got["external_voucher"] != 12 || got["webhook_custom"] != 6 || len(got) != 2 {
+		t.Errorf("ParseRetryAttempts() = %v", got)
+	}
+
+	if got, err := ParseRetryAttempts(""); err != nil || len(got) != 0 {
+		t.Errorf("ParseRetryAttempts(\"\") = %v, %v", got, err)
+	}
+
+	for _, value := range []string{"external_voucher", "external_voucher=many"} {
+		if _, err := ParseRetryAttempts(value); !errors.Is(err, ErrInvalidRetryPolicy) {
+			t.Errorf("ParseRetryAttempts(%q) error = %v, want ErrInvalidRetryPolicy", value, err)
+		}
+	}
+}
+
+func TestSetRetryAttempts(t *testing.T) {
+	s := NewService(nil, nil)
+
+	if err := s.SetRetryAttempts("webhook_custom", 3); err != nil {
+		t.Fatalf("SetRetryAttempts() error = %v", err)
+	}
+	policy := s.RetryPolicy("webhook_custom")
+	if policy.MaxAttempts != 3 || policy.BaseDelay != time.Minute {
+		t.Errorf("RetryPolicy(webhook_custom) = %+v", policy)
+	}
+	if defaultRetryPolicies["webhook_custom"].MaxAttempts != 8 {
+		t.Error("SetRetryAttempts changed the default policies")
+	}
+
+	if err := s.SetRetryAttempts("discount", 0); !errors.Is(err, ErrInvalidRetryPolicy) {
+		t.Errorf("SetRetryAttempts(discount, 0) error = %v", err)
+	}
+	if err := s.SetRetryAttempts("nonexistent", 3); !errors.Is(err, ErrInvalidRetryPolicy) {
+		t.Errorf("SetRetryAttempts(nonexistent, 3) error = %v", err)
+	}
+	if got := s.RetryPolicy("discount"); got != DefaultRetryPolicy {
+		t.Errorf("RetryPolicy(discount) = %+v, want default", got)
+	}
+}
@@ -0,0 +1,155 @@
+package reward
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// How the confirmation of a redemption receipt went
+const (
+	ConfirmationSent      = "sent"
+	ConfirmationFailed    = "failed"
+	ConfirmationNoConsent = "no_consent"
+	ConfirmationNoPhone   = "no_phone"
+	// ConfirmationInChannel is recorded when the customer redeemed through a
+	// channel, which confirms the redemption in its reply
+	ConfirmationInChannel = "in_channel"
+)
+
+// ReceiptTemplate is the WhatsApp template redemptions are confirmed with:
+// {{1}} is the reward and {{2}} where and when it was redeemed
+const ReceiptTemplate = "reward_redeemed"
+
+// ReceiptNumber is the short reference customers and staff quote for a
+// receipt, e.g. "RCP-1A2B3C4D"
+func ReceiptNumber(id pgtype.UUID) string {
+	return "RCP-" + strings.ToUpper(hex.EncodeToString(id.Bytes[:4]))
+}
+
+// createReceipt records the receipt of an issuance redeemed in qtx's
+// transaction. Customers redeeming through a channel have had it confirmed
+// in the channel's reply.
+func createReceipt(ctx context.Context, qtx *db.Queries, issuanceID, tenantID pgtype.UUID, origin Origin) (db.RedemptionReceipt, error) {
+	receipt, err := qtx.CreateRedemptionReceipt(ctx, db.CreateRedemptionReceiptParams{
+		StaffUserID: staffUserID(origin.Actor),
+		RedeemedBy:  origin.Actor,
+		Channel:     origin.Channel,
+		IssuanceID:  issuanceID,
+		TenantID:    tenantID,
+	})
+	if err != nil {
+		return db.RedemptionReceipt{}, fmt.Errorf("failed to create redemption receipt: %w", err)
+	}
+
+	if strings.HasPrefix(origin.Actor, "customer:") {
+		if err := qtx.SetRedemptionReceiptConfirmation(ctx, db.SetRedemptionReceiptConfirmationParams{
+			ConfirmationStatus: ConfirmationInChannel,
+			TenantID:           tenantID,
+			ID:                 receipt.ID,
+		}); err != nil {
+			return db.RedemptionReceipt{}, fmt.Errorf("failed to record receipt confirmation: %w", err)
+		}
+		receipt.ConfirmationStatus = pgtype.Text{String: ConfirmationInChannel, Valid: true}
+	}
+	return receipt, nil
+}
+
+// ListRedemptionReceipts returns a customer's redemption receipts, most
+// recent first, and the total count
+func (s *Service) ListRedemptionReceipts(ctx context.Context, tenantID, customerID pgtype.UUID, limit, offset int32) ([]db.RedemptionReceipt, int64, error) {
+	receipts, err := s.queries.ListCustomerRedemptionReceipts(ctx, db.ListCustomerRedemptionReceiptsParams{
+		TenantID:   tenantID,
+		CustomerID: customerID,
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list redemption receipts: %w", err)
+	}
+	total, err := s.queries.CountCustomerRedemptionReceipts(ctx, db.CountCustomerRedemptionReceiptsParams{
+		TenantID:   tenantID,
+		CustomerID: customerID,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count redemption receipts: %w", err)
+	}
+	return receipts, total, nil
+}
+
+// staffUserID returns the staff user of a StaffActor, if actor is one
+func staffUserID(actor string) pgtype.UUID {
+	id, err := uuid.Parse(strings.TrimPrefix(actor, "staff:"))
+	if err != nil || !strings.HasPrefix(actor, "staff:") {
+		return pgtype.UUID{}
+	}
+	return pgtype.UUID{Bytes: id, Valid: true}
+}
+
+// confirmReceipt sends the customer a WhatsApp confirmation of a committed
+// redemption in the background, so redemption is not slowed down by
+// delivery, and records how it went on the receipt
+func (s *Service) confirmReceipt(ctx context.Context, receipt db.RedemptionReceipt) {
+	if s.templates == nil || receipt.ConfirmationStatus.Valid {
+		return
+	}
+
+	s.inBackground(ctx, func(ctx context.Context, logger *slog.Logger) {
+		status, err := s.sendConfirmation(ctx, receipt, logger)
+		if err != nil {
+			logger.Error("failed to confirm redemption receipt",
+				"receipt_id", httputil.FormatUUID(receipt.ID.Bytes),
+				"error", err,
+			)
+			return
+		}
+
+		err = s.withTenant(ctx, receipt.TenantID, func(qtx *db.Queries) error {
+			return qtx.SetRedemptionReceiptConfirmation(ctx, db.SetRedemptionReceiptConfirmationParams{
+				ConfirmationStatus: status,
+				TenantID:           receipt.TenantID,
+				ID:                 receipt.ID,
+			})
+		})
+		if err != nil {
+			logger.Error("failed to record receipt confirmation",
+				"receipt_id", httputil.FormatUUID(receipt.ID.Bytes),
+				"error", err,
+			)
+		}
+	})
+}
+
+// sendConfirmation sends the receipt to the customer and returns the
+// confirmation status
+func (s *Service) sendConfirmation(ctx context.Context, receipt db.RedemptionReceipt, logger *slog.Logger) (string, error) {
+	contact, err := s.whatsAppContact(ctx, receipt.TenantID, receipt.CustomerID)
+	if err != nil || contact.status != "" {
+		return contact.status, err
+	}
+
+	params := map[string]string{
+		"1": receipt.RewardName,
+		"2": receiptPlace(receipt, contact.loc),
+	}
+	return s.sendTemplate(ctx, receipt.TenantID, contact.phone, ReceiptTemplate, params, logger), nil
+}
+
+// receiptPlace describes where and when a receipt's reward was redeemed,
+// e.g. "store HRE01 on 2 Jan 2026 15:04 (receipt RCP-1A2B3C4D)"
+func receiptPlace(receipt db.RedemptionReceipt, loc *time.Location) string {
+	when := receipt.RedeemedAt.Time.In(loc).Format("2 Jan 2006 15:04")
+	place := when
+	if receipt.StoreID.Valid && receipt.StoreID.String != "" {
+		place = fmt.Sprintf("store %s on %s", receipt.StoreID.String, when)
+	}
+	return fmt.Sprintf("%s (receipt %s)", place, ReceiptNumber(receipt.ID))
+}
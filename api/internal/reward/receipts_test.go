@@ -0,0 +1,49 @@
+package reward
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestReceiptNumber(t *testing.T) {
+	id := pgtype.UUID{Bytes: [16]byte{0x1a, 0x2b, 0x3c, 0x4d, 15: 1}, Valid: true}
+	if got := ReceiptNumber(id); got != "RCP-1A2B3C4D" {
+		t.Errorf("ReceiptNumber() = %q", got)
+	}
+}
+
+func TestStaffUserID(t *testing.T) {
+	id := pgtype.UUID{Bytes: [16]byte{15: 1}, Valid: true}
+
+	if got := staffUserID(StaffActor(id)); got != id {
+		t.Errorf("staffUserID(StaffActor) = %v, want %v", got, id)
+	}
+	for _, actor := range []string{CustomerActor(id), ActorSystem, "staff:", "staff:not-a-uuid"} {
+		if got := staffUserID(actor); got.Valid {
+			t.Errorf("staffUserID(%q) = %v, want invalid", actor, got)
+		}
+	}
+}
+
+func TestReceiptPlace(t *testing.T) {
+	harare, err := time.LoadLocation("Africa/Harare")
+	if err != nil {
+		t.Skip("time zone data unavailable")
+	}
+	receipt := db.RedemptionReceipt{
+		ID:         pgtype.UUID{Bytes: [16]byte{0x1a, 0x2b, 0x3c, 0x4d}, Valid: true},
+		RedeemedAt: pgtype.Timestamptz{Time: time.Date(2026, 1, 2, 13, 4, 0, 0, time.UTC), Valid: true},
+	}
+
+	if got := receiptPlace(receipt, harare); got != "2 Jan 2026 15:04 (receipt RCP-1A2B3C4D)" {
+		t.Errorf("receiptPlace() = %q", got)
+	}
+
+	receipt.StoreID = pgtype.Text{String: "HRE01", Valid: true}
+	if got := receiptPlace(receipt, harare); got != "store HRE01 on 2 Jan 2026 15:04 (receipt RCP-1A2B3C4D)" {
+		t.Errorf("receiptPlace() = %q", got)
+	}
+}
@@ -2,23 +2,69 @@ package reward
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/netip"
 	"strings"
 	"time"
 
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/logging"
+	"github.com/bmachimbira/loyalty/api/internal/reward/basket"
+	"github.com/bmachimbira/loyalty/api/internal/reward/locations"
+	"github.com/bmachimbira/loyalty/api/internal/reward/window"
+	"github.com/bmachimbira/loyalty/api/internal/timezone"
+	"github.com/bmachimbira/loyalty/api/internal/webhooks"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-// RedeemIssuance redeems an issued reward
+var (
+	// ErrNotRedeemable is returned when the issuance is not in issued state
+	ErrNotRedeemable = errors.New("issuance cannot be redeemed")
+
+	// ErrInvalidRedemptionCode is returned when the code doesn't match the issuance
+	ErrInvalidRedemptionCode = errors.New("invalid redemption code")
+
+	// ErrRewardExpired is returned when the reward expired before redemption
+	ErrRewardExpired = errors.New("reward has expired")
+)
+
+// AuditLocationOverride is the audit log action recorded when a manager
+// allows a reward to be redeemed at a store it isn't allowed at
+const AuditLocationOverride = "redemption_location_override"
+
+// Checkout is the till a reward is redeemed at. StoreID is a location code
+// and BasketAmount the purchase the reward is redeemed with; both are empty
+// when the customer redeems through a channel rather than at a till.
+type Checkout struct {
+	StoreID      string
+	BasketAmount *float64
+	// OverrideBy is the manager who verified their PIN to allow a reward
+	// restricted to other locations to be redeemed at StoreID
+	OverrideBy pgtype.UUID
+	// IPAddress is the client address recorded with an override
+	IPAddress *netip.Addr
+}
+
+// RedeemIssuance redeems an issued reward. Every channel (API, WhatsApp,
+// USSD) redeems through it.
 // This function:
 // 1. Validates the issuance is in issued state
 // 2. Verifies the OTP/code if provided
 // 3. Checks expiry
-// 4. Transitions to redeemed state
-// 5. Charges the budget (moves from reserved to charged in ledger)
-func (s *Service) RedeemIssuance(ctx context.Context, issuanceID, tenantID pgtype.UUID, code string) error {
+// 4. Checks the reward's redemption window in the tenant's time zone
+// 5. Checks the basket meets the reward's minimum
+// 6. Checks the store is one the reward may be redeemed at
+// 7. Transitions to redeemed state
+// 8. Records the redemption receipt
+// 9. Charges the budget (moves from reserved to charged in ledger)
+// 10. Sends the reward.redeemed webhook and confirms the receipt to the
+// customer
+func (s *Service) RedeemIssuance(ctx context.Context, issuanceID, tenantID pgtype.UUID, code string, checkout Checkout, origin Origin) error {
 	// Start transaction for atomic redemption
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
@@ -27,37 +73,10 @@ func (s *Service) RedeemIssuance(ctx context.Context, issuanceID, tenantID pgtyp
 	defer tx.Rollback(ctx)
 
 	// Get issuance
-	var issuance struct {
-		ID         pgtype.UUID
-		TenantID   pgtype.UUID
-		CustomerID pgtype.UUID
-		CampaignID pgtype.UUID
-		RewardID   pgtype.UUID
-		Status     string
-		Code       pgtype.Text
-		ExpiresAt  pgtype.Timestamptz
-		CostAmount pgtype.Numeric
-		Currency   pgtype.Text
-	}
-
-	err = tx.QueryRow(ctx, `
-		SELECT id, tenant_id, customer_id, campaign_id, reward_id, status,
-		       code, expires_at, cost_amount, currency
-		FROM issuances
-		WHERE id = $1 AND tenant_id = $2
-		FOR UPDATE
-	`, issuanceID, tenantID).Scan(
-		&issuance.ID,
-		&issuance.TenantID,
-		&issuance.CustomerID,
-		&issuance.CampaignID,
-		&issuance.RewardID,
-		&issuance.Status,
-		&issuance.Code,
-		&issuance.ExpiresAt,
-		&issuance.CostAmount,
-		&issuance.Currency,
-	)
+	issuance, err := s.queries.WithTx(tx).GetIssuanceForUpdate(ctx, db.GetIssuanceForUpdateParams{
+		ID:       issuanceID,
+		TenantID: tenantID,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to get issuance: %w", err)
 	}
@@ -65,7 +84,7 @@ func (s *Service) RedeemIssuance(ctx context.Context, issuanceID, tenantID pgtyp
 	// Validate state
 	currentState := State(issuance.Status)
 	if currentState != StateIssued {
-		return fmt.Errorf("cannot redeem issuance in state: %s (must be issued)", currentState)
+		return fmt.Errorf("%w: cannot redeem issuance in state: %s (must be issued)", ErrNotRedeemable, currentState)
 	}
 
 	// Verify code if provided and if issuance has a code
@@ -75,7 +94,7 @@ func (s *Service) RedeemIssuance(ctx context.Context, issuanceID, tenantID pgtyp
 		normalizedStored := strings.ToUpper(strings.TrimSpace(issuance.Code.String))
 
 		if normalizedProvided != normalizedStored {
-			return fmt.Errorf("invalid redemption code")
+			return ErrInvalidRedemptionCode
 		}
 	}
 
@@ -83,20 +102,57 @@ func (s *Service) RedeemIssuance(ctx context.Context, issuanceID, tenantID pgtyp
 	if issuance.ExpiresAt.Valid {
 		if time.Now().After(issuance.ExpiresAt.Time) {
 			// Mark as expired
-			_ = s.updateStateInTx(ctx, tx, issuanceID, tenantID, StateIssued, StateExpired)
-			return fmt.Errorf("reward has expired")
+			_ = s.updateStateInTx(ctx, tx, issuanceID, tenantID, StateIssued, StateExpired, SystemOrigin)
+			return ErrRewardExpired
 		}
 	}
 
+	qtx := s.queries.WithTx(tx)
+	item, err := qtx.GetRewardByID(ctx, db.GetRewardByIDParams{
+		ID:       issuance.RewardID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get reward: %w", err)
+	}
+	if err := checkWindow(ctx, qtx, item, time.Now()); err != nil {
+		return err
+	}
+	minimum, err := basket.FromMetadata(item.Metadata)
+	if err != nil {
+		return fmt.Errorf("reward %s: %w", httputil.FormatUUID(item.ID.Bytes), err)
+	}
+	if err := basket.Check(minimum, checkout.BasketAmount, rewardCurrency(issuance, item)); err != nil {
+		return err
+	}
+	if err := checkStore(ctx, qtx, issuance, item, checkout, origin); err != nil {
+		return err
+	}
+
 	// Transition to redeemed state
-	err = s.updateStateInTx(ctx, tx, issuanceID, tenantID, StateIssued, StateRedeemed)
+	err = s.updateStateInTx(ctx, tx, issuanceID, tenantID, StateIssued, StateRedeemed, origin)
 	if err != nil {
 		return fmt.Errorf("failed to update state: %w", err)
 	}
 
+	if checkout.StoreID != "" {
+		if _, err := tx.Exec(ctx, `
+			UPDATE issuances
+			SET redeemed_store_id = $3
+			WHERE id = $1 AND tenant_id = $2
+		`, issuanceID, tenantID, strings.TrimSpace(checkout.StoreID)); err != nil {
+			return fmt.Errorf("failed to record redemption store: %w", err)
+		}
+	}
+
+	receipt, err := createReceipt(ctx, qtx, issuanceID, tenantID, origin)
+	if err != nil {
+		return err
+	}
+
 	// Charge the budget by calling the charge_budget database function
 	// This moves the ledger entry from 'reserve' to 'charge'
-	err = s.chargeBudget(ctx, tx, issuance.CampaignID, issuance.ID, issuance.CostAmount)
+	err = s.chargeBudget(ctx, tx, issuance)
 	if err != nil {
 		return fmt.Errorf("failed to charge budget: %w", err)
 	}
@@ -106,35 +162,106 @@ func (s *Service) RedeemIssuance(ctx context.Context, issuanceID, tenantID pgtyp
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	s.notifyRedeemed(ctx, issuanceID, tenantID, origin)
+	s.confirmReceipt(ctx, receipt)
+
 	return nil
 }
 
-// chargeBudget charges the budget for a redeemed issuance
-// This uses the charge_budget database function to atomically:
-// 1. Find the reserve ledger entry
-// 2. Create a charge ledger entry
-// 3. Update the budget balance
-func (s *Service) chargeBudget(ctx context.Context, tx pgx.Tx, campaignID, issuanceID pgtype.UUID, amount pgtype.Numeric) error {
-	// Get the budget ID from the campaign
-	var budgetID pgtype.UUID
-	var tenantID pgtype.UUID
-
-	err := tx.QueryRow(ctx, `
-		SELECT budget_id, tenant_id
-		FROM campaigns
-		WHERE id = $1
-	`, campaignID).Scan(&budgetID, &tenantID)
+// checkWindow returns a *window.Error if now is outside the reward's
+// redemption window in the tenant's time zone
+func checkWindow(ctx context.Context, queries *db.Queries, item db.RewardCatalog, now time.Time) error {
+	w, err := window.FromMetadata(item.Metadata)
+	if err != nil {
+		return fmt.Errorf("reward %s: %w", httputil.FormatUUID(item.ID.Bytes), err)
+	}
+	if w == nil {
+		return nil
+	}
 
+	loc, err := timezone.NewResolver(queries).Location(ctx, item.TenantID)
 	if err != nil {
-		return fmt.Errorf("failed to get campaign budget: %w", err)
+		return err
 	}
+	if !w.Contains(now, loc) {
+		return &window.Error{Window: *w}
+	}
+	return nil
+}
 
-	// Call the charge_budget function
-	// This function expects (p_tenant_id, p_budget_id, p_ref_type, p_ref_id)
-	_, err = tx.Exec(ctx, `
-		SELECT charge_budget($1::uuid, $2::uuid, 'issuance'::text, $3::uuid)
-	`, tenantID, budgetID, issuanceID)
+// rewardCurrency returns the currency amounts of the issuance are shown in
+func rewardCurrency(issuance db.Issuance, item db.RewardCatalog) string {
+	if issuance.Currency.Valid {
+		return issuance.Currency.String
+	}
+	return item.Currency.String
+}
 
+// checkStore returns a *locations.Error if the issuance's reward is
+// restricted to other locations than the store. A manager override lets the
+// redemption through and is written to the audit log.
+func checkStore(ctx context.Context, queries *db.Queries, issuance db.Issuance, item db.RewardCatalog, checkout Checkout, origin Origin) error {
+	allowed, err := locations.FromMetadata(item.Metadata)
+	if err != nil {
+		return fmt.Errorf("reward %s: %w", httputil.FormatUUID(item.ID.Bytes), err)
+	}
+	if len(allowed) == 0 || locations.Allowed(allowed, checkout.StoreID) {
+		return nil
+	}
+
+	if checkout.OverrideBy.Valid && checkout.StoreID != "" {
+		details, err := json.Marshal(map[string]any{
+			"issuance_id":       httputil.FormatUUID(issuance.ID.Bytes),
+			"reward_id":         httputil.FormatUUID(item.ID.Bytes),
+			"store_id":          strings.TrimSpace(checkout.StoreID),
+			"allowed_locations": allowed,
+			"redeemed_by":       origin.Actor,
+			"channel":           origin.Channel,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit details: %w", err)
+		}
+		if _, err := queries.InsertAuditLog(ctx, db.InsertAuditLogParams{
+			TenantID:     issuance.TenantID,
+			ActorType:    "staff",
+			ActorID:      checkout.OverrideBy,
+			Action:       AuditLocationOverride,
+			ResourceType: pgtype.Text{String: "issuance", Valid: true},
+			ResourceID:   issuance.ID,
+			Details:      details,
+			IpAddress:    checkout.IPAddress,
+		}); err != nil {
+			return fmt.Errorf("failed to write audit log: %w", err)
+		}
+		return nil
+	}
+
+	tenantLocations, err := queries.ListLocations(ctx, db.ListLocationsParams{TenantID: issuance.TenantID})
+	if err != nil {
+		return fmt.Errorf("failed to list locations: %w", err)
+	}
+	return &locations.Error{
+		StoreID: strings.TrimSpace(checkout.StoreID),
+		Stores:  locations.Names(allowed, tenantLocations),
+	}
+}
+
+// chargeBudget charges the budget for a redeemed issuance
+// This uses the charge_budget database function to record the charge of the
+// reservation against the budget the issuance was reserved from
+func (s *Service) chargeBudget(ctx context.Context, tx pgx.Tx, issuance db.Issuance) error {
+	if !issuance.CostAmount.Valid || !issuance.Currency.Valid {
+		return nil
+	}
+
+	budgetID, err := IssuanceBudget(ctx, s.queries.WithTx(tx), issuance)
+	if err != nil || !budgetID.Valid {
+		return err
+	}
+
+	// Call the charge_budget function
+	_, err = tx.Exec(ctx, "SELECT charge_budget($1, $2, $3, $4, $5)",
+		issuance.TenantID, budgetID, issuance.CostAmount, issuance.Currency.String, issuance.ID)
 	if err != nil {
 		return fmt.Errorf("charge_budget function failed: %w", err)
 	}
@@ -142,6 +269,49 @@ func (s *Service) chargeBudget(ctx context.Context, tx pgx.Tx, campaignID, issua
 	return nil
 }
 
+// notifyRedeemed sends the reward.redeemed webhook for a committed
+// redemption. Failures are logged; the redemption stands.
+func (s *Service) notifyRedeemed(ctx context.Context, issuanceID, tenantID pgtype.UUID, origin Origin) {
+	if s.webhooks == nil {
+		return
+	}
+	logger := logging.FromContext(ctx, nil)
+
+	issuance, err := s.queries.GetIssuanceByID(ctx, db.GetIssuanceByIDParams{
+		ID:       issuanceID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		logger.Error("failed to get redeemed issuance for webhook", "issuance_id", issuanceID, "error", err)
+		return
+	}
+	reward, err := s.queries.GetRewardByID(ctx, db.GetRewardByIDParams{
+		ID:       issuance.RewardID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		logger.Error("failed to get reward for webhook", "issuance_id", issuanceID, "error", err)
+		return
+	}
+
+	faceAmount, _ := issuance.FaceAmount.Float64Value()
+	data := webhooks.RewardRedeemedData{
+		IssuanceID: httputil.FormatUUID(issuance.ID.Bytes),
+		CustomerID: httputil.FormatUUID(issuance.CustomerID.Bytes),
+		RewardID:   httputil.FormatUUID(issuance.RewardID.Bytes),
+		RewardName: reward.Name,
+		RewardType: reward.Type,
+		Code:       issuance.Code.String,
+		FaceAmount: faceAmount.Float64,
+		Currency:   issuance.Currency.String,
+		RedeemedAt: httputil.FormatTimestamp(issuance.RedeemedAt),
+		RedeemedBy: origin.Actor,
+	}
+	if err := s.webhooks.NotifyRewardRedeemed(ctx, uuid.UUID(tenantID.Bytes), data); err != nil {
+		logger.Error("failed to send redemption webhook", "issuance_id", issuanceID, "error", err)
+	}
+}
+
 // VerifyRedemptionCode checks if a code is valid for redemption without actually redeeming
 // Useful for preview/validation before final redemption
 func (s *Service) VerifyRedemptionCode(ctx context.Context, issuanceID, tenantID pgtype.UUID, code string) (bool, error) {
@@ -0,0 +1,282 @@
+package reward
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/logging"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Reissue reason codes
+const (
+	ReissueReasonFailed      = "failed"
+	ReissueReasonLost        = "lost"
+	ReissueReasonNotReceived = "not_received"
+	ReissueReasonOther       = "other"
+)
+
+// IssuedTemplate is the WhatsApp template reissued rewards are sent with:
+// {{1}} is the reward, {{2}} its code and {{3}} when it expires
+const IssuedTemplate = "reward_issued"
+
+var (
+	// ErrInvalidReissueReason is returned for an unknown reason code
+	ErrInvalidReissueReason = errors.New("reason_code must be one of failed, lost, not_received, other")
+
+	// ErrNotReissuable is returned when the issuance is neither issued nor
+	// failed, or was already reissued
+	ErrNotReissuable = errors.New("only issued or failed issuances that haven't been reissued can be reissued")
+
+	// ErrReissueBudget is returned when none of the campaign's budgets can
+	// cover the replacement
+	ErrReissueBudget = errors.New("no campaign budget can cover the replacement")
+)
+
+// ValidReissueReason reports whether code is a known reissue reason code
+func ValidReissueReason(code string) bool {
+	switch code {
+	case ReissueReasonFailed, ReissueReasonLost, ReissueReasonNotReceived, ReissueReasonOther:
+		return true
+	}
+	return false
+}
+
+// ReissueRequest describes a reissue of a failed or lost reward
+type ReissueRequest struct {
+	TenantID   pgtype.UUID
+	IssuanceID pgtype.UUID
+	StaffID    pgtype.UUID
+	ReasonCode string
+	Note       string
+}
+
+// ReissueIssuance replaces an issued or failed issuance with a new one.
+// This function:
+// 1. Cancels the original, releasing its budget if it still held one
+// 2. Reserves a replacement of the same reward, and its cost from the
+// campaign's budgets if the original was budgeted
+// 3. Records the reissue linking the two
+// 4. Processes the replacement so it gets a fresh code and sends it to the
+// customer
+// It returns the reissue, the cancelled original and the replacement. A
+// replacement that couldn't be processed straight away is left reserved
+// for the issuance processor and isn't sent.
+func (s *Service) ReissueIssuance(ctx context.Context, req ReissueRequest, origin Origin) (db.IssuanceReissue, db.Issuance, db.Issuance, error) {
+	if !ValidReissueReason(req.ReasonCode) {
+		return db.IssuanceReissue{}, db.Issuance{}, db.Issuance{}, ErrInvalidReissueReason
+	}
+
+	reissue, original, err := s.reissue(ctx, req, origin)
+	if err != nil {
+		return db.IssuanceReissue{}, original, db.Issuance{}, err
+	}
+
+	logger := logging.FromContext(ctx, nil)
+	if err := s.ProcessIssuance(ctx, req.TenantID, reissue.ReplacementID); err != nil {
+		logger.Warn("replacement issuance not processed, left for the processor",
+			"issuance_id", httputil.FormatUUID(reissue.ReplacementID.Bytes),
+			"error", err,
+		)
+	}
+
+	replacement, err := s.queries.GetIssuanceByID(ctx, db.GetIssuanceByIDParams{
+		ID:       reissue.ReplacementID,
+		TenantID: req.TenantID,
+	})
+	if err != nil {
+		return reissue, original, db.Issuance{}, fmt.Errorf("failed to get replacement issuance: %w", err)
+	}
+
+	if State(replacement.Status) == StateIssued {
+		s.notifyReissued(ctx, replacement)
+	}
+	return reissue, original, replacement, nil
+}
+
+// reissue cancels the original and reserves its replacement in one
+// transaction
+func (s *Service) reissue(ctx context.Context, req ReissueRequest, origin Origin) (db.IssuanceReissue, db.Issuance, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.IssuanceReissue{}, db.Issuance{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	original, err := qtx.GetIssuanceForUpdate(ctx, db.GetIssuanceForUpdateParams{
+		ID:       req.IssuanceID,
+		TenantID: req.TenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.IssuanceReissue{}, db.Issuance{}, ErrIssuanceNotFound
+		}
+		return db.IssuanceReissue{}, db.Issuance{}, fmt.Errorf("failed to get issuance: %w", err)
+	}
+
+	state := State(original.Status)
+	if state != StateIssued && state != StateFailed {
+		return db.IssuanceReissue{}, original, ErrNotReissuable
+	}
+
+	budgetID, err := IssuanceBudget(ctx, qtx, original)
+	if err != nil {
+		return db.IssuanceReissue{}, original, err
+	}
+
+	if err := TransitionIssuance(ctx, qtx, Transition{
+		IssuanceID: original.ID,
+		TenantID:   original.TenantID,
+		From:       state,
+		To:         StateCancelled,
+		Origin:     origin,
+	}); err != nil {
+		return db.IssuanceReissue{}, original, err
+	}
+	// Failed issuances released their budget when they failed
+	if state == StateIssued {
+		if err := s.releaseBudget(ctx, tx, original.TenantID, budgetID, original.ID, original.CostAmount, original.Currency); err != nil {
+			return db.IssuanceReissue{}, original, fmt.Errorf("failed to release budget: %w", err)
+		}
+	}
+	original.Status = string(StateCancelled)
+
+	replacement, err := qtx.ReserveIssuance(ctx, db.ReserveIssuanceParams{
+		TenantID:    original.TenantID,
+		CustomerID:  original.CustomerID,
+		CampaignID:  original.CampaignID,
+		RewardID:    original.RewardID,
+		Currency:    original.Currency,
+		FaceAmount:  original.FaceAmount,
+		CostAmount:  original.CostAmount,
+		EventID:     original.EventID,
+		RuleID:      original.RuleID,
+		ValueInputs: original.ValueInputs,
+	})
+	if err != nil {
+		return db.IssuanceReissue{}, original, fmt.Errorf("failed to create replacement issuance: %w", err)
+	}
+
+	if budgetID.Valid && original.CostAmount.Valid && original.Currency.Valid {
+		var replacementBudget pgtype.UUID
+		if err := tx.QueryRow(ctx, "SELECT reserve_campaign_budget($1, $2, $3, $4, $5)",
+			replacement.TenantID, replacement.CampaignID, replacement.CostAmount, replacement.Currency.String, replacement.ID).Scan(&replacementBudget); err != nil {
+			return db.IssuanceReissue{}, original, fmt.Errorf("reserve_campaign_budget function failed: %w", err)
+		}
+		if !replacementBudget.Valid {
+			return db.IssuanceReissue{}, original, ErrReissueBudget
+		}
+		if err := qtx.SetIssuanceBudget(ctx, db.SetIssuanceBudgetParams{
+			ID:       replacement.ID,
+			TenantID: replacement.TenantID,
+			BudgetID: replacementBudget,
+		}); err != nil {
+			return db.IssuanceReissue{}, original, fmt.Errorf("failed to record issuance budget: %w", err)
+		}
+	}
+
+	if err := RecordIssuanceCreated(ctx, qtx, replacement, origin); err != nil {
+		return db.IssuanceReissue{}, original, err
+	}
+
+	reissue, err := qtx.CreateIssuanceReissue(ctx, db.CreateIssuanceReissueParams{
+		TenantID:      original.TenantID,
+		OriginalID:    original.ID,
+		ReplacementID: replacement.ID,
+		CustomerID:    original.CustomerID,
+		ReasonCode:    req.ReasonCode,
+		Note:          pgtype.Text{String: req.Note, Valid: req.Note != ""},
+		CreatedBy:     req.StaffID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.IssuanceReissue{}, original, ErrNotReissuable
+		}
+		return db.IssuanceReissue{}, original, fmt.Errorf("failed to record reissue: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.IssuanceReissue{}, original, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return reissue, original, nil
+}
+
+// ReissueLinks returns the issuance an issuance replaced and the one that
+// replaced it, each an invalid UUID when there is none
+func (s *Service) ReissueLinks(ctx context.Context, tenantID, issuanceID pgtype.UUID) (reissuedFrom, reissuedAs pgtype.UUID, err error) {
+	from, err := s.queries.GetIssuanceReissueByReplacement(ctx, db.GetIssuanceReissueByReplacementParams{
+		TenantID:      tenantID,
+		ReplacementID: issuanceID,
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return pgtype.UUID{}, pgtype.UUID{}, fmt.Errorf("failed to get reissue: %w", err)
+	}
+	as, err := s.queries.GetIssuanceReissueByOriginal(ctx, db.GetIssuanceReissueByOriginalParams{
+		TenantID:   tenantID,
+		OriginalID: issuanceID,
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return pgtype.UUID{}, pgtype.UUID{}, fmt.Errorf("failed to get reissue: %w", err)
+	}
+	return from.OriginalID, as.ReplacementID, nil
+}
+
+// notifyReissued sends the customer their replacement reward over WhatsApp
+// in the background
+func (s *Service) notifyReissued(ctx context.Context, replacement db.Issuance) {
+	if s.templates == nil {
+		return
+	}
+
+	s.inBackground(ctx, func(ctx context.Context, logger *slog.Logger) {
+		contact, err := s.whatsAppContact(ctx, replacement.TenantID, replacement.CustomerID)
+		if err != nil {
+			logger.Error("failed to notify reissued reward",
+				"issuance_id", httputil.FormatUUID(replacement.ID.Bytes),
+				"error", err,
+			)
+			return
+		}
+		if contact.status != "" {
+			logger.Info("reissued reward not sent",
+				"issuance_id", httputil.FormatUUID(replacement.ID.Bytes),
+				"reason", contact.status,
+			)
+			return
+		}
+
+		var rewardName string
+		err = s.withTenant(ctx, replacement.TenantID, func(qtx *db.Queries) error {
+			item, err := qtx.GetRewardByID(ctx, db.GetRewardByIDParams{
+				ID:       replacement.RewardID,
+				TenantID: replacement.TenantID,
+			})
+			rewardName = item.Name
+			return err
+		})
+		if err != nil {
+			logger.Error("failed to get reissued reward",
+				"issuance_id", httputil.FormatUUID(replacement.ID.Bytes),
+				"error", err,
+			)
+			return
+		}
+
+		expiry := "no expiry"
+		if replacement.ExpiresAt.Valid {
+			expiry = replacement.ExpiresAt.Time.In(contact.loc).Format("2 Jan 2006 15:04")
+		}
+		s.sendTemplate(ctx, replacement.TenantID, contact.phone, IssuedTemplate, map[string]string{
+			"1": rewardName,
+			"2": replacement.Code.String,
+			"3": expiry,
+		}, logger)
+	})
+}
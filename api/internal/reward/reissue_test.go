@@ -0,0 +1,102 @@
+package reward
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestValidReissueReason(t *testing.T) {
+	for _, code := range []string{"failed", "lost", "not_received", "other"} {
+		if !ValidReissueReason(code) {
+			t.Errorf("expected %q to be a valid reason code", code)
+		}
+	}
+
+	for _, code := range []string{"", "Lost", "fraud"} {
+		if ValidReissueReason(code) {
+			t.Errorf("expected %q to be rejected", code)
+		}
+	}
+}
+
+func TestReissueIssuance(t *testing.T) {
+	f := setupTestFixture(t)
+	ctx := context.Background()
+
+	issuance := f.issue(t)
+	request := ReissueRequest{
+		TenantID:   f.tenant.ID,
+		IssuanceID: issuance.ID,
+		StaffID:    f.staff.ID,
+		ReasonCode: ReissueReasonLost,
+		Note:       "customer lost the message",
+	}
+	origin := Origin{Actor: StaffActor(f.staff.ID), Channel: ChannelAPI}
+
+	reissue, original, replacement, err := f.service.ReissueIssuance(ctx, request, origin)
+	if err != nil {
+		t.Fatalf("ReissueIssuance failed: %v", err)
+	}
+
+	// The original is cancelled and its reservation released
+	if original.ID != issuance.ID {
+		t.Errorf("Expected original %v, got %v", issuance.ID, original.ID)
+	}
+	if got := f.get(t, issuance.ID).Status; got != string(StateCancelled) {
+		t.Errorf("Expected original to be cancelled, got %s", got)
+	}
+	if got := f.ledgerTotal(t, issuance.ID, "release"); got != -5 {
+		t.Errorf("Expected a release ledger entry of -5, got %v", got)
+	}
+
+	// The replacement is reserved from the budget and issued with a new code
+	if replacement.ID == issuance.ID {
+		t.Fatal("Expected a new issuance as the replacement")
+	}
+	if replacement.Status != string(StateIssued) {
+		t.Errorf("Expected replacement to be issued, got %s", replacement.Status)
+	}
+	if replacement.BudgetID != f.budget.ID {
+		t.Errorf("Expected replacement reserved from budget %v, got %v", f.budget.ID, replacement.BudgetID)
+	}
+	if got := f.ledgerTotal(t, replacement.ID, "reserve"); got != 5 {
+		t.Errorf("Expected a reserve ledger entry of 5, got %v", got)
+	}
+	if !replacement.Code.Valid || replacement.Code.String == issuance.Code.String {
+		t.Errorf("Expected a new code, got %q (original %q)", replacement.Code.String, issuance.Code.String)
+	}
+	if replacement.CustomerID != issuance.CustomerID || replacement.RewardID != issuance.RewardID {
+		t.Error("Expected the replacement to be the same reward for the same customer")
+	}
+
+	// The reissue links the two both ways
+	if reissue.OriginalID != issuance.ID || reissue.ReplacementID != replacement.ID {
+		t.Errorf("Expected reissue of %v as %v, got %v as %v", issuance.ID, replacement.ID, reissue.OriginalID, reissue.ReplacementID)
+	}
+	reissuedFrom, reissuedAs, err := f.service.ReissueLinks(ctx, f.tenant.ID, issuance.ID)
+	if err != nil {
+		t.Fatalf("ReissueLinks failed: %v", err)
+	}
+	if reissuedFrom.Valid {
+		t.Errorf("Expected the original not to replace anything, got %v", reissuedFrom)
+	}
+	if reissuedAs != replacement.ID {
+		t.Errorf("Expected the original reissued as %v, got %v", replacement.ID, reissuedAs)
+	}
+	reissuedFrom, reissuedAs, err = f.service.ReissueLinks(ctx, f.tenant.ID, replacement.ID)
+	if err != nil {
+		t.Fatalf("ReissueLinks failed: %v", err)
+	}
+	if reissuedFrom != issuance.ID {
+		t.Errorf("Expected the replacement reissued from %v, got %v", issuance.ID, reissuedFrom)
+	}
+	if reissuedAs.Valid {
+		t.Errorf("Expected the replacement not to be reissued, got %v", reissuedAs)
+	}
+
+	// The cancelled original can't be reissued again
+	if _, _, _, err := f.service.ReissueIssuance(ctx, request, origin); !errors.Is(err, ErrNotReissuable) {
+		t.Errorf("Expected ErrNotReissuable, got %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package reward
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/enums"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// MaxRetryAttempts is the most attempts a retry policy may allow
+const MaxRetryAttempts = 50
+
+var (
+	// ErrNotRetryable is returned when retrying an issuance that hasn't failed
+	ErrNotRetryable = errors.New("only failed issuances can be retried")
+
+	// ErrRetryBudget is returned when none of the campaign's budgets can
+	// cover a retried issuance
+	ErrRetryBudget = errors.New("no campaign budget can cover the issuance")
+
+	// ErrInvalidRetryPolicy is returned for an unknown reward type or an
+	// attempt count out of range
+	ErrInvalidRetryPolicy = fmt.Errorf("retry attempts must be between 1 and %d for a known reward type", MaxRetryAttempts)
+)
+
+// RetryPolicy is how the issuance processor retries a reward type's
+// transient handler failures. After MaxAttempts the failure is treated as
+// permanent: the issuance is marked failed and its budget released.
+type RetryPolicy struct {
+	MaxAttempts int32
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy applies to reward types without a policy of their own
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   30 * time.Second,
+	MaxDelay:    time.Hour,
+}
+
+// defaultRetryPolicies give rewards fulfilled by third parties longer to
+// recover from an outage
+var defaultRetryPolicies = map[string]RetryPolicy{
+	"external_voucher": {MaxAttempts: 10, BaseDelay: time.Minute, MaxDelay: 2 * time.Hour},
+	"webhook_custom":   {MaxAttempts: 8, BaseDelay: time.Minute, MaxDelay: time.Hour},
+}
+
+// Delay is the exponential backoff after the given number of failed
+// attempts: BaseDelay, then doubling up to MaxDelay
+func (p RetryPolicy) Delay(attempts int32) time.Duration {
+	delay := p.BaseDelay
+	for i := int32(1); i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// RetryPolicy returns the retry policy for a reward type
+func (s *Service) RetryPolicy(rewardType string) RetryPolicy {
+	if policy, ok := s.retryPolicies[rewardType]; ok {
+		return policy
+	}
+	return DefaultRetryPolicy
+}
+
+// SetRetryAttempts sets how many attempts the processor makes at issuing a
+// reward type before marking the issuance failed
+func (s *Service) SetRetryAttempts(rewardType string, attempts int32) error {
+	if _, ok := s.handlers[rewardType]; !ok || attempts < 1 || attempts > MaxRetryAttempts {
+		return ErrInvalidRetryPolicy
+	}
+	policy := s.RetryPolicy(rewardType)
+	policy.MaxAttempts = attempts
+	s.retryPolicies[rewardType] = policy
+	return nil
+}
+
+// ParseRetryAttempts parses per reward type attempt counts, as in
+// ISSUANCE_RETRY_ATTEMPTS="external_voucher=12,webhook_custom=6"
+func ParseRetryAttempts(value string) (map[string]int32, error) {
+	attempts := make(map[string]int32)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		rewardType, count, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRetryPolicy, pair)
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(count), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRetryPolicy, pair)
+		}
+		attempts[strings.TrimSpace(rewardType)] = int32(n)
+	}
+	return attempts, nil
+}
+
+// RetryIssuance puts a failed issuance back in the processor's queue. Its
+// budget, released when it failed, is reserved again from the campaign's
+// budgets.
+func (s *Service) RetryIssuance(ctx context.Context, issuanceID, tenantID pgtype.UUID, origin Origin) (db.Issuance, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.Issuance{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	issuance, err := qtx.GetIssuanceByID(ctx, db.GetIssuanceByIDParams{
+		ID:       issuanceID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Issuance{}, ErrIssuanceNotFound
+		}
+		return db.Issuance{}, fmt.Errorf("failed to get issuance: %w", err)
+	}
+	if State(issuance.Status) != StateFailed {
+		return db.Issuance{}, ErrNotRetryable
+	}
+
+	if err := s.rereserveBudget(ctx, tx, qtx, issuance); err != nil {
+		return db.Issuance{}, err
+	}
+
+	if err := TransitionIssuance(ctx, qtx, Transition{
+		IssuanceID: issuanceID,
+		TenantID:   tenantID,
+		From:       StateFailed,
+		To:         StateReserved,
+		Origin:     origin,
+	}); err != nil {
+		return db.Issuance{}, err
+	}
+	if err := qtx.ResetIssuanceProcessing(ctx, db.ResetIssuanceProcessingParams{
+		ID:       issuanceID,
+		TenantID: tenantID,
+	}); err != nil {
+		return db.Issuance{}, fmt.Errorf("failed to reset issuance processing: %w", err)
+	}
+
+	retried, err := qtx.GetIssuanceByID(ctx, db.GetIssuanceByIDParams{
+		ID:       issuanceID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		return db.Issuance{}, fmt.Errorf("failed to get issuance: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.Issuance{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return retried, nil
+}
+
+// rereserveBudget reserves a failed issuance's cost again, unless it has no
+// cost or its reservation was never released
+func (s *Service) rereserveBudget(ctx context.Context, tx pgx.Tx, qtx *db.Queries, issuance db.Issuance) error {
+	if !issuance.CampaignID.Valid || !issuance.CostAmount.Valid || !issuance.Currency.Valid {
+		return nil
+	}
+
+	entries, err := qtx.GetLedgerEntryByRef(ctx, db.GetLedgerEntryByRefParams{
+		TenantID: issuance.TenantID,
+		RefType:  pgtype.Text{String: "issuance", Valid: true},
+		RefID:    issuance.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get issuance ledger entries: %w", err)
+	}
+	// Entries are newest first; nothing was reserved, or it still is.
+	// Overdraft entries only record how much of a reservation went past the
+	// hard cap.
+	var latest enums.LedgerEntryType
+	for _, entry := range entries {
+		if enums.LedgerEntryType(entry.EntryType) != enums.LedgerOverdraft {
+			latest = enums.LedgerEntryType(entry.EntryType)
+			break
+		}
+	}
+	if latest == "" || latest == enums.LedgerReserve {
+		return nil
+	}
+
+	var budgetID pgtype.UUID
+	if err := tx.QueryRow(ctx, "SELECT reserve_campaign_budget($1, $2, $3, $4, $5)",
+		issuance.TenantID, issuance.CampaignID, issuance.CostAmount, issuance.Currency.String, issuance.ID).Scan(&budgetID); err != nil {
+		return fmt.Errorf("reserve_campaign_budget function failed: %w", err)
+	}
+	if !budgetID.Valid {
+		return ErrRetryBudget
+	}
+
+	if err := qtx.SetIssuanceBudget(ctx, db.SetIssuanceBudgetParams{
+		ID:       issuance.ID,
+		TenantID: issuance.TenantID,
+		BudgetID: budgetID,
+	}); err != nil {
+		return fmt.Errorf("failed to record issuance budget: %w", err)
+	}
+	return nil
+}
+
+// releaseFailedBudget releases the budget reserved for an issuance that
+// failed for good, so a poison issuance doesn't hold funds indefinitely
+func (s *Service) releaseFailedBudget(ctx context.Context, tx pgx.Tx, qtx *db.Queries, issuance db.Issuance) error {
+	budgetID, err := IssuanceBudget(ctx, qtx, issuance)
+	if err != nil {
+		return err
+	}
+	if err := s.releaseBudget(ctx, tx, issuance.TenantID, budgetID, issuance.ID, issuance.CostAmount, issuance.Currency); err != nil {
+		return fmt.Errorf("failed to release budget: %w", err)
+	}
+	return nil
+}
+
+// ListFailedIssuances returns a tenant's failed issuances, most recent
+// first, with the error that failed them and the total count
+func (s *Service) ListFailedIssuances(ctx context.Context, tenantID pgtype.UUID, limit, offset int32) ([]db.ListFailedIssuancesRow, int64, error) {
+	rows, err := s.queries.ListFailedIssuances(ctx, db.ListFailedIssuancesParams{
+		TenantID:  tenantID,
+		RowLimit:  limit,
+		RowOffset: offset,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list failed issuances: %w", err)
+	}
+	total, err := s.queries.CountFailedIssuances(ctx, tenantID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count failed issuances: %w", err)
+	}
+	return rows, total, nil
+}
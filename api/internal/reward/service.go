@@ -3,11 +3,18 @@ package reward
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"sync"
+	"time"
 
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/fulfilment"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/logging"
+	"github.com/bmachimbira/loyalty/api/internal/reward/codes"
 	"github.com/bmachimbira/loyalty/api/internal/reward/handlers"
+	"github.com/bmachimbira/loyalty/api/internal/webhooks"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -18,6 +25,12 @@ type Service struct {
 	pool     *pgxpool.Pool
 	queries  *db.Queries
 	handlers map[string]handlers.RewardHandler
+	webhooks *webhooks.DeliveryService
+
+	retryPolicies map[string]RetryPolicy
+
+	templates     TemplateSender
+	notifications sync.WaitGroup
 }
 
 // NewService creates a new reward service with all handlers registered
@@ -26,19 +39,30 @@ func NewService(pool *pgxpool.Pool, queries *db.Queries) *Service {
 		pool:     pool,
 		queries:  queries,
 		handlers: make(map[string]handlers.RewardHandler),
+
+		retryPolicies: make(map[string]RetryPolicy, len(defaultRetryPolicies)),
+	}
+	for rewardType, policy := range defaultRetryPolicies {
+		s.retryPolicies[rewardType] = policy
 	}
 
 	// Register all reward type handlers
-	s.RegisterHandler("discount", &handlers.DiscountHandler{})
+	codeGenerator := codes.NewGenerator(queries)
+	s.RegisterHandler("discount", handlers.NewDiscountHandler(codeGenerator))
 	s.RegisterHandler("voucher_code", handlers.NewVoucherCodeHandler(queries))
 	s.RegisterHandler("external_voucher", handlers.NewExternalVoucherHandler())
 	s.RegisterHandler("points_credit", handlers.NewPointsCreditHandler())
-	s.RegisterHandler("physical_item", handlers.NewPhysicalItemHandler())
+	s.RegisterHandler("physical_item", handlers.NewPhysicalItemHandler(codeGenerator))
 	s.RegisterHandler("webhook_custom", handlers.NewWebhookHandler())
 
 	return s
 }
 
+// SetWebhookService enables webhook notifications for redemptions
+func (s *Service) SetWebhookService(webhooks *webhooks.DeliveryService) {
+	s.webhooks = webhooks
+}
+
 // RegisterHandler registers a reward type handler
 func (s *Service) RegisterHandler(rewardType string, handler handlers.RewardHandler) {
 	s.handlers[rewardType] = handler
@@ -55,12 +79,16 @@ func (s *Service) GetHandler(rewardType string) (handlers.RewardHandler, error)
 
 // ProcessIssuance processes a reserved issuance, moving it from reserved → issued
 // This is the main orchestration function that:
-// 1. Validates the issuance is in reserved state
+// 1. Claims the issuance if it is still reserved and no other worker holds it
 // 2. Gets the appropriate handler for the reward type
 // 3. Calls the handler to process the reward
 // 4. Updates the issuance with the result
 // 5. Transitions to issued state
-func (s *Service) ProcessIssuance(ctx context.Context, issuanceID pgtype.UUID) error {
+// A transient handler failure leaves the issuance reserved to be retried;
+// any other failure marks it failed and returns ErrProcessingFailed.
+func (s *Service) ProcessIssuance(ctx context.Context, tenantID, issuanceID pgtype.UUID) error {
+	logger := logging.FromContext(ctx, nil)
+
 	// Start a transaction for atomic processing
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
@@ -68,45 +96,24 @@ func (s *Service) ProcessIssuance(ctx context.Context, issuanceID pgtype.UUID) e
 	}
 	defer tx.Rollback(ctx)
 
+	// Processing runs outside a tenant request
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenantID.Bytes)); err != nil {
+		return fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
 	txQueries := s.queries.WithTx(tx)
 
-	// Get the issuance with the tenant ID from context
-	// In production, tenant_id would come from the context set by middleware
-	// For now, we'll get it from the issuance itself
-	var issuance db.Issuance
-	err = tx.QueryRow(ctx, `
-		SELECT id, tenant_id, customer_id, campaign_id, reward_id, status,
-		       code, external_ref, currency, cost_amount, face_amount,
-		       issued_at, expires_at, redeemed_at, event_id
-		FROM issuances
-		WHERE id = $1
-	`, issuanceID).Scan(
-		&issuance.ID,
-		&issuance.TenantID,
-		&issuance.CustomerID,
-		&issuance.CampaignID,
-		&issuance.RewardID,
-		&issuance.Status,
-		&issuance.Code,
-		&issuance.ExternalRef,
-		&issuance.Currency,
-		&issuance.CostAmount,
-		&issuance.FaceAmount,
-		&issuance.IssuedAt,
-		&issuance.ExpiresAt,
-		&issuance.RedeemedAt,
-		&issuance.EventID,
-	)
+	issuance, err := txQueries.LockReservedIssuance(ctx, db.LockReservedIssuanceParams{
+		ID:       issuanceID,
+		TenantID: tenantID,
+	})
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrIssuanceBusy
+		}
 		return fmt.Errorf("failed to get issuance: %w", err)
 	}
 
-	// Validate state
-	currentState := State(issuance.Status)
-	if currentState != StateReserved {
-		return fmt.Errorf("invalid state for processing: %s (expected %s)", currentState, StateReserved)
-	}
-
 	// Get the reward details
 	reward, err := txQueries.GetRewardByID(ctx, db.GetRewardByIDParams{
 		ID:       issuance.RewardID,
@@ -120,17 +127,21 @@ func (s *Service) ProcessIssuance(ctx context.Context, issuanceID pgtype.UUID) e
 	handler, err := s.GetHandler(reward.Type)
 	if err != nil {
 		// Mark as failed if handler not found
-		_ = s.updateStateInTx(ctx, tx, issuance.ID, issuance.TenantID, StateReserved, StateFailed)
-		return err
+		return s.failIssuance(ctx, tx, issuance, err)
 	}
 
 	// Process the reward
 	result, err := handler.Process(ctx, &issuance, &reward)
 	if err != nil {
-		// Mark as failed if processing fails
-		log.Printf("Failed to process issuance %s: %v", issuance.ID, err)
-		_ = s.updateStateInTx(ctx, tx, issuance.ID, issuance.TenantID, StateReserved, StateFailed)
-		return fmt.Errorf("reward processing failed: %w", err)
+		logger.Error("failed to process issuance",
+			"issuance_id", issuance.ID,
+			"reward_type", reward.Type,
+			"transient", IsTransient(err),
+			"error", err)
+		if IsTransient(err) {
+			return fmt.Errorf("reward processing failed: %w", err)
+		}
+		return s.failIssuance(ctx, tx, issuance, err)
 	}
 
 	// Update issuance with result
@@ -140,20 +151,54 @@ func (s *Service) ProcessIssuance(ctx context.Context, issuanceID pgtype.UUID) e
 	}
 
 	// Transition to issued state
-	err = s.updateStateInTx(ctx, tx, issuance.ID, issuance.TenantID, StateReserved, StateIssued)
+	err = s.updateStateInTx(ctx, tx, issuance.ID, issuance.TenantID, StateReserved, StateIssued, SystemOrigin)
 	if err != nil {
 		return fmt.Errorf("failed to update state: %w", err)
 	}
 
+	// Physical items are tracked until they are delivered
+	if reward.Type == "physical_item" {
+		if err := fulfilment.Open(ctx, txQueries, issuance, reward, time.Now()); err != nil {
+			return err
+		}
+	}
+
 	// Commit transaction
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	log.Printf("Successfully processed issuance %s: %s -> %s", issuance.ID, StateReserved, StateIssued)
+	logger.Info("issuance processed",
+		"issuance_id", issuance.ID,
+		"from", StateReserved,
+		"to", StateIssued)
 	return nil
 }
 
+// failIssuance marks a claimed issuance failed, releases its budget and
+// commits tx. cause is the processing error, recorded on the issuance and
+// returned wrapped in ErrProcessingFailed.
+func (s *Service) failIssuance(ctx context.Context, tx pgx.Tx, issuance db.Issuance, cause error) error {
+	qtx := s.queries.WithTx(tx)
+	if _, err := qtx.RecordIssuanceProcessFailure(ctx, db.RecordIssuanceProcessFailureParams{
+		ID:               issuance.ID,
+		TenantID:         issuance.TenantID,
+		LastProcessError: pgtype.Text{String: cause.Error(), Valid: true},
+	}); err != nil {
+		return fmt.Errorf("failed to record processing failure: %w", err)
+	}
+	if err := s.updateStateInTx(ctx, tx, issuance.ID, issuance.TenantID, StateReserved, StateFailed, SystemOrigin); err != nil {
+		return fmt.Errorf("failed to mark issuance failed: %w", err)
+	}
+	if err := s.releaseFailedBudget(ctx, tx, qtx, issuance); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return fmt.Errorf("%w: %v", ErrProcessingFailed, cause)
+}
+
 // updateIssuanceWithResult updates the issuance record with processing results
 func (s *Service) updateIssuanceWithResult(ctx context.Context, tx pgx.Tx, issuanceID, tenantID pgtype.UUID, result *handlers.ProcessResult) error {
 	// Prepare code field
@@ -187,7 +232,7 @@ func (s *Service) updateIssuanceWithResult(ctx context.Context, tx pgx.Tx, issua
 }
 
 // updateState transitions an issuance to a new state with validation
-func (s *Service) updateState(ctx context.Context, issuanceID, tenantID pgtype.UUID, fromState, toState State) error {
+func (s *Service) updateState(ctx context.Context, issuanceID, tenantID pgtype.UUID, fromState, toState State, origin Origin) error {
 	// Start transaction
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
@@ -195,7 +240,7 @@ func (s *Service) updateState(ctx context.Context, issuanceID, tenantID pgtype.U
 	}
 	defer tx.Rollback(ctx)
 
-	err = s.updateStateInTx(ctx, tx, issuanceID, tenantID, fromState, toState)
+	err = s.updateStateInTx(ctx, tx, issuanceID, tenantID, fromState, toState, origin)
 	if err != nil {
 		return err
 	}
@@ -204,34 +249,18 @@ func (s *Service) updateState(ctx context.Context, issuanceID, tenantID pgtype.U
 }
 
 // updateStateInTx transitions an issuance to a new state within an existing transaction
-func (s *Service) updateStateInTx(ctx context.Context, tx pgx.Tx, issuanceID, tenantID pgtype.UUID, fromState, toState State) error {
-	// Validate transition
-	if err := fromState.ValidateTransition(toState); err != nil {
-		return err
-	}
-
-	// Update status
-	result, err := tx.Exec(ctx, `
-		UPDATE issuances
-		SET status = $3,
-		    redeemed_at = CASE WHEN $3 = 'redeemed' THEN NOW() ELSE redeemed_at END
-		WHERE id = $1 AND tenant_id = $2 AND status = $4
-	`, issuanceID, tenantID, toState.String(), fromState.String())
-
-	if err != nil {
-		return fmt.Errorf("failed to update status: %w", err)
-	}
-
-	// Check if any rows were affected
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("issuance not found or state mismatch")
-	}
-
-	return nil
+func (s *Service) updateStateInTx(ctx context.Context, tx pgx.Tx, issuanceID, tenantID pgtype.UUID, fromState, toState State, origin Origin) error {
+	return TransitionIssuance(ctx, s.queries.WithTx(tx), Transition{
+		IssuanceID: issuanceID,
+		TenantID:   tenantID,
+		From:       fromState,
+		To:         toState,
+		Origin:     origin,
+	})
 }
 
 // CancelIssuance cancels an issuance and releases its budget
-func (s *Service) CancelIssuance(ctx context.Context, issuanceID, tenantID pgtype.UUID) error {
+func (s *Service) CancelIssuance(ctx context.Context, issuanceID, tenantID pgtype.UUID, origin Origin) error {
 	// Get current issuance
 	issuance, err := s.queries.GetIssuanceByID(ctx, db.GetIssuanceByIDParams{
 		ID:       issuanceID,
@@ -249,7 +278,7 @@ func (s *Service) CancelIssuance(ctx context.Context, issuanceID, tenantID pgtyp
 	}
 
 	// Update state to cancelled
-	return s.updateState(ctx, issuanceID, tenantID, currentState, StateCancelled)
+	return s.updateState(ctx, issuanceID, tenantID, currentState, StateCancelled, origin)
 }
 
 // GetIssuance retrieves an issuance by ID
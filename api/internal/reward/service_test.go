@@ -0,0 +1,222 @@
+package reward
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testFixture is a tenant with a budget-funded campaign, a discount reward
+// and a staff user, for tests that need a database
+type testFixture struct {
+	pool     *pgxpool.Pool
+	queries  *db.Queries
+	service  *Service
+	tenant   db.Tenant
+	budget   db.Budget
+	campaign db.Campaign
+	reward   db.RewardCatalog
+	staff    db.StaffUser
+}
+
+// setupTestFixture connects to DATABASE_URL and creates a fresh tenant,
+// skipping the test when no database is configured
+func setupTestFixture(t *testing.T) *testFixture {
+	t.Helper()
+
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set, skipping integration tests")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	queries := db.New(pool)
+
+	f := &testFixture{pool: pool, queries: queries, service: NewService(pool, queries)}
+
+	f.tenant, err = queries.CreateTenant(ctx, db.CreateTenantParams{
+		Name:        "Rewards " + t.Name(),
+		CountryCode: "ZW",
+		DefaultCcy:  "USD",
+		Theme:       []byte(`{}`),
+	})
+	if err != nil {
+		t.Fatalf("CreateTenant failed: %v", err)
+	}
+
+	f.budget, err = queries.CreateBudget(ctx, db.CreateBudgetParams{
+		TenantID: f.tenant.ID,
+		Name:     "Rewards Budget",
+		Currency: "USD",
+		SoftCap:  testNumeric(t, "900"),
+		HardCap:  testNumeric(t, "1000"),
+		Balance:  testNumeric(t, "0"),
+		Period:   "rolling",
+	})
+	if err != nil {
+		t.Fatalf("CreateBudget failed: %v", err)
+	}
+
+	f.campaign, err = queries.CreateCampaign(ctx, db.CreateCampaignParams{
+		TenantID: f.tenant.ID,
+		Name:     "Rewards Campaign",
+		StartAt:  pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true},
+		BudgetID: f.budget.ID,
+		Status:   "active",
+	})
+	if err != nil {
+		t.Fatalf("CreateCampaign failed: %v", err)
+	}
+
+	f.reward, err = queries.CreateReward(ctx, db.CreateRewardParams{
+		TenantID:  f.tenant.ID,
+		Name:      "$5 Voucher",
+		Type:      "discount",
+		FaceValue: testNumeric(t, "5"),
+		Currency:  pgtype.Text{String: "USD", Valid: true},
+		Inventory: "none",
+		Metadata:  []byte(`{}`),
+		Active:    true,
+	})
+	if err != nil {
+		t.Fatalf("CreateReward failed: %v", err)
+	}
+
+	f.staff, err = queries.CreateStaffUser(ctx, db.CreateStaffUserParams{
+		TenantID: f.tenant.ID,
+		Email:    uuid.NewString() + "@example.com",
+		FullName: "Test Staff",
+		Role:     "admin",
+		PwdHash:  "x",
+	})
+	if err != nil {
+		t.Fatalf("CreateStaffUser failed: %v", err)
+	}
+
+	return f
+}
+
+// newCustomer creates a customer of the fixture's tenant
+func (f *testFixture) newCustomer(t *testing.T) db.Customer {
+	t.Helper()
+	customer, err := f.queries.CreateCustomer(context.Background(), db.CreateCustomerParams{
+		TenantID:    f.tenant.ID,
+		ExternalRef: pgtype.Text{String: uuid.NewString(), Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateCustomer failed: %v", err)
+	}
+	return customer
+}
+
+// reserve reserves the fixture's reward for a new customer against the
+// campaign budget, as the rules engine does
+func (f *testFixture) reserve(t *testing.T) db.Issuance {
+	t.Helper()
+	ctx := context.Background()
+
+	customer := f.newCustomer(t)
+	issuance, err := f.queries.ReserveIssuance(ctx, db.ReserveIssuanceParams{
+		TenantID:   f.tenant.ID,
+		CustomerID: customer.ID,
+		CampaignID: f.campaign.ID,
+		RewardID:   f.reward.ID,
+		Currency:   f.reward.Currency,
+		FaceAmount: f.reward.FaceValue,
+		CostAmount: f.reward.FaceValue,
+	})
+	if err != nil {
+		t.Fatalf("ReserveIssuance failed: %v", err)
+	}
+
+	if err := f.pool.QueryRow(ctx, "SELECT reserve_campaign_budget($1, $2, $3, $4, $5)",
+		f.tenant.ID, f.campaign.ID, issuance.CostAmount, issuance.Currency.String, issuance.ID).Scan(&issuance.BudgetID); err != nil {
+		t.Fatalf("reserve_campaign_budget failed: %v", err)
+	}
+	if err := f.queries.SetIssuanceBudget(ctx, db.SetIssuanceBudgetParams{
+		ID:       issuance.ID,
+		TenantID: issuance.TenantID,
+		BudgetID: issuance.BudgetID,
+	}); err != nil {
+		t.Fatalf("SetIssuanceBudget failed: %v", err)
+	}
+	if err := RecordIssuanceCreated(ctx, f.queries, issuance, SystemOrigin); err != nil {
+		t.Fatalf("RecordIssuanceCreated failed: %v", err)
+	}
+	return issuance
+}
+
+// issue reserves and processes an issuance, giving it a code
+func (f *testFixture) issue(t *testing.T) db.Issuance {
+	t.Helper()
+	issuance := f.reserve(t)
+	if err := f.service.ProcessIssuance(context.Background(), f.tenant.ID, issuance.ID); err != nil {
+		t.Fatalf("ProcessIssuance failed: %v", err)
+	}
+	return f.get(t, issuance.ID)
+}
+
+// redeem issues an issuance and redeems it as the fixture's staff user
+func (f *testFixture) redeem(t *testing.T) db.Issuance {
+	t.Helper()
+	issuance := f.issue(t)
+	origin := Origin{Actor: StaffActor(f.staff.ID), Channel: ChannelAPI}
+	if err := f.service.RedeemIssuance(context.Background(), issuance.ID, f.tenant.ID, issuance.Code.String, Checkout{}, origin); err != nil {
+		t.Fatalf("RedeemIssuance failed: %v", err)
+	}
+	return f.get(t, issuance.ID)
+}
+
+// get reloads an issuance of the fixture's tenant
+func (f *testFixture) get(t *testing.T, issuanceID pgtype.UUID) db.Issuance {
+	t.Helper()
+	issuance, err := f.queries.GetIssuanceByID(context.Background(), db.GetIssuanceByIDParams{
+		ID:       issuanceID,
+		TenantID: f.tenant.ID,
+	})
+	if err != nil {
+		t.Fatalf("GetIssuanceByID failed: %v", err)
+	}
+	return issuance
+}
+
+// ledgerTotal sums an issuance's ledger entries of one type
+func (f *testFixture) ledgerTotal(t *testing.T, issuanceID pgtype.UUID, entryType string) float64 {
+	t.Helper()
+	var total pgtype.Numeric
+	if err := f.pool.QueryRow(context.Background(),
+		"SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE ref_type = 'issuance' AND ref_id = $1 AND entry_type = $2",
+		issuanceID, entryType).Scan(&total); err != nil {
+		t.Fatalf("failed to sum ledger entries: %v", err)
+	}
+	return numericToFloat(t, total)
+}
+
+func testNumeric(t *testing.T, s string) pgtype.Numeric {
+	t.Helper()
+	var n pgtype.Numeric
+	if err := n.Scan(s); err != nil {
+		t.Fatalf("invalid numeric %q: %v", s, err)
+	}
+	return n
+}
+
+func numericToFloat(t *testing.T, n pgtype.Numeric) float64 {
+	t.Helper()
+	value, err := n.Float64Value()
+	if err != nil {
+		t.Fatalf("invalid numeric: %v", err)
+	}
+	return value.Float64
+}
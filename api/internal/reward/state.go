@@ -1,22 +1,29 @@
 package reward
 
-import "fmt"
+import (
+	"fmt"
 
-// State represents the lifecycle state of a reward issuance
+	"github.com/bmachimbira/loyalty/api/internal/enums"
+)
+
+// State represents the lifecycle state of a reward issuance. States are the
+// issuance statuses in the enums package.
 type State string
 
 const (
-	StateReserved  State = "reserved"  // Budget reserved, awaiting processing
-	StateIssued    State = "issued"    // Successfully issued to customer
-	StateRedeemed  State = "redeemed"  // Customer has redeemed the reward
-	StateExpired   State = "expired"   // Reward expired before redemption
-	StateCancelled State = "cancelled" // Manually cancelled
-	StateFailed    State = "failed"    // Processing failed
+	StateReserved  = State(enums.IssuanceReserved)  // Budget reserved, awaiting processing
+	StateIssued    = State(enums.IssuanceIssued)    // Successfully issued to customer
+	StateRedeemed  = State(enums.IssuanceRedeemed)  // Customer has redeemed the reward
+	StateExpired   = State(enums.IssuanceExpired)   // Reward expired before redemption
+	StateCancelled = State(enums.IssuanceCancelled) // Manually cancelled
+	StateFailed    = State(enums.IssuanceFailed)    // Processing failed
 )
 
 // Valid state transitions
 // reserved -> issued, cancelled, failed
 // issued -> redeemed, expired, cancelled
+// failed -> reserved when staff retry the issuance, cancelled when they
+// reissue it
 var transitions = map[State][]State{
 	StateReserved: {StateIssued, StateCancelled, StateFailed},
 	StateIssued:   {StateRedeemed, StateExpired, StateCancelled},
@@ -24,7 +31,7 @@ var transitions = map[State][]State{
 	StateRedeemed:  {},
 	StateExpired:   {},
 	StateCancelled: {},
-	StateFailed:    {},
+	StateFailed:    {StateReserved, StateCancelled},
 }
 
 // CanTransitionTo checks if a state transition is valid
@@ -43,15 +50,11 @@ func (s State) CanTransitionTo(target State) bool {
 
 // IsValid checks if the state is a valid state
 func (s State) IsValid() bool {
-	switch s {
-	case StateReserved, StateIssued, StateRedeemed, StateExpired, StateCancelled, StateFailed:
-		return true
-	default:
-		return false
-	}
+	return enums.IssuanceStatus(s).IsValid()
 }
 
-// IsTerminal returns true if this is a terminal state (no further transitions possible)
+// IsTerminal returns true if this is a terminal state (no further transitions
+// possible). A failed issuance is terminal to the system; only staff can retry it.
 func (s State) IsTerminal() bool {
 	return s == StateRedeemed || s == StateExpired || s == StateCancelled || s == StateFailed
 }
@@ -34,6 +34,12 @@ func TestStateTransitions(t *testing.T) {
 		{"expired to issued", StateExpired, StateIssued, true},
 		{"cancelled to issued", StateCancelled, StateIssued, true},
 		{"failed to issued", StateFailed, StateIssued, true},
+
+		// Staff retry
+		{"failed to reserved", StateFailed, StateReserved, false},
+
+		// Reissued in place of a failed issuance
+		{"failed to cancelled", StateFailed, StateCancelled, false},
 	}
 
 	for _, tt := range tests {
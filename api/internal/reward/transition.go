@@ -0,0 +1,114 @@
+package reward
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Channels through which an issuance's status is changed
+const (
+	ChannelAPI       = "api"
+	ChannelWhatsApp  = "whatsapp"
+	ChannelUSSD      = "ussd"
+	ChannelPortal    = "portal"
+	ChannelPOSImport = "pos_import"
+	ChannelSystem    = "system"
+)
+
+// ActorSystem is the actor for changes made by workers and the rules engine
+const ActorSystem = "system"
+
+// ErrStateMismatch is returned when the issuance is not in the expected state,
+// e.g. because a concurrent change got there first
+var ErrStateMismatch = errors.New("issuance not found or state mismatch")
+
+// Origin identifies who changed an issuance's status and through which channel
+type Origin struct {
+	Actor   string
+	Channel string
+}
+
+// SystemOrigin is the origin of changes made by background workers
+var SystemOrigin = Origin{Actor: ActorSystem, Channel: ChannelSystem}
+
+// StaffActor returns the actor for a staff user
+func StaffActor(id pgtype.UUID) string {
+	return "staff:" + httputil.FormatUUID(id.Bytes)
+}
+
+// CustomerActor returns the actor for a customer acting on their own reward
+func CustomerActor(id pgtype.UUID) string {
+	return "customer:" + httputil.FormatUUID(id.Bytes)
+}
+
+// EventActor returns the actor for a change caused by an event, e.g. a reversal
+func EventActor(id pgtype.UUID) string {
+	return "event:" + httputil.FormatUUID(id.Bytes)
+}
+
+// Transition is a status change of an issuance
+type Transition struct {
+	IssuanceID pgtype.UUID
+	TenantID   pgtype.UUID
+	From       State
+	To         State
+	Origin     Origin
+}
+
+// TransitionIssuance is the single place issuance statuses change. It
+// validates the transition against the state machine, applies it only if the
+// issuance is still in t.From, and records it in the status history. q may
+// be bound to a transaction the caller commits.
+func TransitionIssuance(ctx context.Context, q *db.Queries, t Transition) error {
+	if err := t.From.ValidateTransition(t.To); err != nil {
+		return err
+	}
+
+	rows, err := q.TransitionIssuanceStatus(ctx, db.TransitionIssuanceStatusParams{
+		IssuanceID: t.IssuanceID,
+		TenantID:   t.TenantID,
+		FromStatus: t.From.String(),
+		ToStatus:   t.To.String(),
+		Actor:      t.Origin.Actor,
+		Channel:    t.Origin.Channel,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update status: %w", err)
+	}
+	if rows == 0 {
+		return ErrStateMismatch
+	}
+	return nil
+}
+
+// RecordIssuanceCreated records the initial status of a new issuance in its
+// history
+func RecordIssuanceCreated(ctx context.Context, q *db.Queries, issuance db.Issuance, origin Origin) error {
+	if err := q.RecordIssuanceCreated(ctx, db.RecordIssuanceCreatedParams{
+		TenantID:   issuance.TenantID,
+		IssuanceID: issuance.ID,
+		NewStatus:  issuance.Status,
+		Actor:      origin.Actor,
+		Channel:    origin.Channel,
+	}); err != nil {
+		return fmt.Errorf("failed to record issuance history: %w", err)
+	}
+	return nil
+}
+
+// GetStatusHistory returns an issuance's status changes, oldest first
+func (s *Service) GetStatusHistory(ctx context.Context, issuanceID, tenantID pgtype.UUID) ([]db.IssuanceStatusHistory, error) {
+	history, err := s.queries.ListIssuanceStatusHistory(ctx, db.ListIssuanceStatusHistoryParams{
+		TenantID:   tenantID,
+		IssuanceID: issuanceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issuance history: %w", err)
+	}
+	return history, nil
+}
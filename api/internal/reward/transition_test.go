@@ -0,0 +1,113 @@
+package reward
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestActors(t *testing.T) {
+	id := pgtype.UUID{Bytes: [16]byte{15: 1}, Valid: true}
+
+	if got := StaffActor(id); got != "staff:00000000-0000-0000-0000-000000000001" {
+		t.Errorf("StaffActor() = %q", got)
+	}
+	if got := CustomerActor(id); got != "customer:00000000-0000-0000-0000-000000000001" {
+		t.Errorf("CustomerActor() = %q", got)
+	}
+	if got := EventActor(id); got != "event:00000000-0000-0000-0000-000000000001" {
+		t.Errorf("EventActor() = %q", got)
+	}
+}
+
+func TestTransitionIssuanceRejectsInvalidTransition(t *testing.T) {
+	// Invalid transitions are rejected before touching the database
+	err := TransitionIssuance(context.Background(), nil, Transition{
+		From:   StateRedeemed,
+		To:     StateIssued,
+		Origin: SystemOrigin,
+	})
+	if err == nil {
+		t.Fatal("expected error for redeemed -> issued")
+	}
+}
+
+// statusChange is an expected issuance_status_history row
+type statusChange struct {
+	from, to, actor, channel string
+}
+
+func TestTransitionsRecordHistory(t *testing.T) {
+	f := setupTestFixture(t)
+	ctx := context.Background()
+	staff := Origin{Actor: StaffActor(f.staff.ID), Channel: ChannelAPI}
+
+	assertHistory := func(t *testing.T, issuanceID pgtype.UUID, want []statusChange) {
+		t.Helper()
+		history, err := f.service.GetStatusHistory(ctx, issuanceID, f.tenant.ID)
+		if err != nil {
+			t.Fatalf("GetStatusHistory failed: %v", err)
+		}
+		got := make([]statusChange, len(history))
+		for i, entry := range history {
+			got[i] = statusChange{entry.OldStatus.String, entry.NewStatus, entry.Actor, entry.Channel}
+			if i > 0 && entry.CreatedAt.Time.Before(history[i-1].CreatedAt.Time) {
+				t.Errorf("Expected history oldest first, entry %d is older than entry %d", i, i-1)
+			}
+		}
+		if len(got) != len(want) {
+			t.Fatalf("Expected %d history entries, got %+v", len(want), got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("History entry %d: expected %+v, got %+v", i, want[i], got[i])
+			}
+		}
+	}
+
+	t.Run("redeemed issuance", func(t *testing.T) {
+		issuance := f.redeem(t)
+		assertHistory(t, issuance.ID, []statusChange{
+			{"", "reserved", ActorSystem, ChannelSystem},
+			{"reserved", "issued", ActorSystem, ChannelSystem},
+			{"issued", "redeemed", staff.Actor, ChannelAPI},
+		})
+	})
+
+	t.Run("cancelled issuance", func(t *testing.T) {
+		issuance := f.issue(t)
+		if err := f.service.CancelIssuance(ctx, issuance.ID, f.tenant.ID, staff); err != nil {
+			t.Fatalf("CancelIssuance failed: %v", err)
+		}
+		assertHistory(t, issuance.ID, []statusChange{
+			{"", "reserved", ActorSystem, ChannelSystem},
+			{"reserved", "issued", ActorSystem, ChannelSystem},
+			{"issued", "cancelled", staff.Actor, ChannelAPI},
+		})
+	})
+
+	t.Run("stale transition", func(t *testing.T) {
+		// A transition from a status the issuance already left changes
+		// nothing and records nothing
+		issuance := f.issue(t)
+		err := TransitionIssuance(ctx, f.queries, Transition{
+			IssuanceID: issuance.ID,
+			TenantID:   f.tenant.ID,
+			From:       StateReserved,
+			To:         StateCancelled,
+			Origin:     staff,
+		})
+		if !errors.Is(err, ErrStateMismatch) {
+			t.Fatalf("Expected ErrStateMismatch, got %v", err)
+		}
+		if got := f.get(t, issuance.ID).Status; got != string(StateIssued) {
+			t.Errorf("Expected issuance to stay issued, got %s", got)
+		}
+		assertHistory(t, issuance.ID, []statusChange{
+			{"", "reserved", ActorSystem, ChannelSystem},
+			{"reserved", "issued", ActorSystem, ChannelSystem},
+		})
+	})
+}
@@ -0,0 +1,134 @@
+// Package value computes the face value of rewards valued from the event
+// that triggers them, e.g. 5% of the basket up to USD 10. A reward opts in
+// with a formula in its metadata; rewards without one keep their fixed face
+// value.
+package value
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/rewardtypes"
+)
+
+// DefaultProperty is the event property a formula without one is applied to
+const DefaultProperty = "amount"
+
+var (
+	// ErrInvalidConfig is returned for a value formula that can't be used
+	ErrInvalidConfig = errors.New("invalid value formula")
+
+	// ErrNoValue is returned when an event gives a reward no value: its
+	// property is missing, not a positive number, or too small to be worth a
+	// cent
+	ErrNoValue = errors.New("event gives the reward no value")
+)
+
+// Inputs records how an issuance's value was computed
+type Inputs struct {
+	Property string  `json:"property"`
+	Basis    float64 `json:"basis"`
+	Percent  float64 `json:"percent"`
+	Max      float64 `json:"max,omitempty"`
+	Value    float64 `json:"value"`
+}
+
+// FromMetadata returns the value formula in a reward's metadata, or nil when
+// the reward has a fixed face value. A formula without a property gets
+// DefaultProperty.
+func FromMetadata(metadata []byte) (*rewardtypes.ValueFormula, error) {
+	var meta rewardtypes.ValueMetadata
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &meta); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+	}
+	if meta.ValueFormula == nil {
+		return nil, nil
+	}
+
+	formula := *meta.ValueFormula
+	if formula.Property == "" {
+		formula.Property = DefaultProperty
+	}
+	if err := Validate(formula); err != nil {
+		return nil, err
+	}
+	return &formula, nil
+}
+
+// Validate checks a value formula
+func Validate(formula rewardtypes.ValueFormula) error {
+	if formula.Percent <= 0 || formula.Percent > 100 {
+		return fmt.Errorf("%w: percent must be above 0 and at most 100", ErrInvalidConfig)
+	}
+	if formula.Max < 0 {
+		return fmt.Errorf("%w: max must not be negative", ErrInvalidConfig)
+	}
+	if strings.TrimSpace(formula.Property) == "" {
+		return fmt.Errorf("%w: property must not be blank", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// Compute applies a formula to an event's properties. The value is rounded
+// to the cent and capped at the formula's max.
+func Compute(formula rewardtypes.ValueFormula, properties []byte) (Inputs, error) {
+	basis, err := basisOf(properties, formula.Property)
+	if err != nil {
+		return Inputs{}, err
+	}
+
+	amount := math.Round(basis*formula.Percent) / 100
+	if formula.Max > 0 && amount > formula.Max {
+		amount = formula.Max
+	}
+	if amount <= 0 {
+		return Inputs{}, ErrNoValue
+	}
+
+	return Inputs{
+		Property: formula.Property,
+		Basis:    basis,
+		Percent:  formula.Percent,
+		Max:      formula.Max,
+		Value:    amount,
+	}, nil
+}
+
+// basisOf returns the event property a formula is applied to. Numbers sent
+// as strings, as some POS clients do, are accepted.
+func basisOf(properties []byte, property string) (float64, error) {
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(properties, &props); err != nil {
+		return 0, ErrNoValue
+	}
+	raw, ok := props[property]
+	if !ok {
+		return 0, ErrNoValue
+	}
+
+	var basis float64
+	if err := json.Unmarshal(raw, &basis); err != nil {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return 0, ErrNoValue
+		}
+		if basis, err = strconv.ParseFloat(strings.TrimSpace(s), 64); err != nil {
+			return 0, ErrNoValue
+		}
+	}
+	if basis <= 0 || math.IsNaN(basis) || math.IsInf(basis, 0) {
+		return 0, ErrNoValue
+	}
+	return basis, nil
+}
+
+// Format formats a value for a numeric column
+func Format(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
@@ -0,0 +1,78 @@
+package value
+
+import (
+	"testing"
+
+	"github.com/bmachimbira/loyalty/api/internal/rewardtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromMetadata(t *testing.T) {
+	formula, err := FromMetadata([]byte(`{"terms": "In store only"}`))
+	require.NoError(t, err)
+	assert.Nil(t, formula)
+
+	formula, err = FromMetadata([]byte(`{"value_formula": {"percent": 5, "max": 10}}`))
+	require.NoError(t, err)
+	assert.Equal(t, rewardtypes.ValueFormula{Percent: 5, Property: DefaultProperty, Max: 10}, *formula)
+
+	for _, metadata := range []string{
+		`{"value_formula": {"percent": 0}}`,
+		`{"value_formula": {"percent": 101}}`,
+		`{"value_formula": {"percent": 5, "max": -1}}`,
+		`{"value_formula": {"percent": 5, "property": " "}}`,
+		`{"value_formula": "5%"}`,
+	} {
+		_, err := FromMetadata([]byte(metadata))
+		assert.ErrorIs(t, err, ErrInvalidConfig, metadata)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	formula := rewardtypes.ValueFormula{Percent: 5, Property: "amount", Max: 10}
+
+	tests := []struct {
+		name       string
+		properties string
+		want       float64
+	}{
+		{"percentage", `{"amount": 120}`, 6},
+		{"rounded to the cent", `{"amount": 33.33}`, 1.67},
+		{"capped", `{"amount": 500}`, 10},
+		{"numeric string", `{"amount": "120.00"}`, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputs, err := Compute(formula, []byte(tt.properties))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, inputs.Value)
+			assert.Equal(t, "amount", inputs.Property)
+			assert.Equal(t, float64(10), inputs.Max)
+		})
+	}
+
+	inputs, err := Compute(rewardtypes.ValueFormula{Percent: 10, Property: "total"}, []byte(`{"total": 1000}`))
+	require.NoError(t, err)
+	assert.Equal(t, Inputs{Property: "total", Basis: 1000, Percent: 10, Value: 100}, inputs)
+}
+
+func TestComputeNoValue(t *testing.T) {
+	formula := rewardtypes.ValueFormula{Percent: 5, Property: "amount"}
+
+	for _, properties := range []string{
+		`{}`,
+		`{"amount": "lots"}`,
+		`{"amount": -20}`,
+		`{"amount": 0.05}`,
+		`not json`,
+	} {
+		_, err := Compute(formula, []byte(properties))
+		assert.ErrorIs(t, err, ErrNoValue, properties)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "6.00", Format(6))
+	assert.Equal(t, "1.67", Format(1.67))
+}
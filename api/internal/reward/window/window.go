@@ -0,0 +1,202 @@
+// Package window restricts the days and hours a reward can be redeemed, e.g.
+// an off-peak lunch voucher valid 11:00-14:00 on weekdays. A reward opts in
+// with a window in its metadata, read in the tenant's time zone.
+package window
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/rewardtypes"
+)
+
+var (
+	// ErrInvalidConfig is returned for a redemption window that can't be used
+	ErrInvalidConfig = errors.New("invalid redemption window")
+
+	// ErrOutsideWindow is returned when a reward is redeemed outside its
+	// redemption window
+	ErrOutsideWindow = errors.New("reward can't be redeemed at this time")
+)
+
+// week lists the days in the order they are shown, starting on Monday
+var week = []time.Weekday{
+	time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday,
+}
+
+// Window is a parsed redemption window
+type Window struct {
+	// Days the reward can be redeemed on, every day when empty
+	Days []time.Weekday
+	// Start and End are offsets from midnight; an End of zero is all day
+	Start time.Duration
+	End   time.Duration
+}
+
+// FromMetadata returns the redemption window in a reward's metadata, or nil
+// when the reward can be redeemed at any time
+func FromMetadata(metadata []byte) (*Window, error) {
+	var meta rewardtypes.WindowMetadata
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &meta); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+	}
+	if meta.RedemptionWindow == nil {
+		return nil, nil
+	}
+	return Parse(*meta.RedemptionWindow)
+}
+
+// Parse validates a redemption window. Days are matched by their first three
+// letters, so "mon" and "Monday" are the same day.
+func Parse(config rewardtypes.RedemptionWindow) (*Window, error) {
+	w := &Window{}
+	for _, name := range config.Days {
+		day, ok := parseDay(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown day %q", ErrInvalidConfig, name)
+		}
+		if !w.onDay(day) {
+			w.Days = append(w.Days, day)
+		}
+	}
+	if len(w.Days) == len(week) {
+		w.Days = nil
+	}
+
+	if config.Start == "" && config.End == "" {
+		if len(w.Days) == 0 {
+			return nil, fmt.Errorf("%w: days or start and end are required", ErrInvalidConfig)
+		}
+		return w, nil
+	}
+	var err error
+	if w.Start, err = parseClock(config.Start); err != nil {
+		return nil, fmt.Errorf("%w: start: %v", ErrInvalidConfig, err)
+	}
+	if w.End, err = parseClock(config.End); err != nil {
+		return nil, fmt.Errorf("%w: end: %v", ErrInvalidConfig, err)
+	}
+	if w.End <= w.Start {
+		return nil, fmt.Errorf("%w: end must be after start", ErrInvalidConfig)
+	}
+	return w, nil
+}
+
+// Contains reports whether t falls inside the window in loc
+func (w Window) Contains(t time.Time, loc *time.Location) bool {
+	local := t.In(loc)
+	if len(w.Days) > 0 && !w.onDay(local.Weekday()) {
+		return false
+	}
+	if w.End == 0 {
+		return true
+	}
+	offset := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second
+	return offset >= w.Start && offset < w.End
+}
+
+// String describes the window for customers, e.g. "Mon-Fri 11:00-14:00",
+// "daily 17:00-19:00" or "Sat, Sun"
+func (w Window) String() string {
+	days := "daily"
+	if len(w.Days) > 0 {
+		days = w.formatDays()
+	}
+	if w.End == 0 {
+		return days
+	}
+	return fmt.Sprintf("%s %s-%s", days, formatClock(w.Start), formatClock(w.End))
+}
+
+func (w Window) onDay(day time.Weekday) bool {
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// formatDays lists the window's days from Monday, collapsing three or more
+// consecutive days into a range
+func (w Window) formatDays() string {
+	var parts []string
+	for i := 0; i < len(week); i++ {
+		if !w.onDay(week[i]) {
+			continue
+		}
+		j := i
+		for j+1 < len(week) && w.onDay(week[j+1]) {
+			j++
+		}
+		switch {
+		case j-i >= 2:
+			parts = append(parts, dayName(week[i])+"-"+dayName(week[j]))
+		case j > i:
+			parts = append(parts, dayName(week[i]), dayName(week[j]))
+		default:
+			parts = append(parts, dayName(week[i]))
+		}
+		i = j
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Error is an ErrOutsideWindow carrying the window the reward can be
+// redeemed in
+type Error struct {
+	Window Window
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: redeemable %s", ErrOutsideWindow, e.Window)
+}
+
+func (e *Error) Unwrap() error {
+	return ErrOutsideWindow
+}
+
+// Message is the explanation shown to the customer
+func (e *Error) Message() string {
+	return fmt.Sprintf("This reward can only be redeemed %s.", e.Window)
+}
+
+func parseDay(name string) (time.Weekday, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) < 3 {
+		return 0, false
+	}
+	for _, day := range week {
+		if strings.HasPrefix(strings.ToLower(day.String()), name) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+func dayName(day time.Weekday) string {
+	return day.String()[:3]
+}
+
+// parseClock parses a "15:04" time of day into an offset from midnight;
+// "24:00" is the end of the day
+func parseClock(value string) (time.Duration, error) {
+	if value == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a HH:MM time", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func formatClock(offset time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(offset.Hours()), int(offset.Minutes())%60)
+}
@@ -0,0 +1,94 @@
+package window
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/rewardtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromMetadata(t *testing.T) {
+	w, err := FromMetadata([]byte(`{"terms": "In store only"}`))
+	require.NoError(t, err)
+	assert.Nil(t, w)
+
+	w, err = FromMetadata([]byte(`{"redemption_window": {"days": ["mon", "Tuesday", "wed", "thu", "fri", "mon"], "start": "11:00", "end": "14:00"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, w.Days)
+	assert.Equal(t, 11*time.Hour, w.Start)
+	assert.Equal(t, 14*time.Hour, w.End)
+
+	for _, metadata := range []string{
+		`{"redemption_window": {}}`,
+		`{"redemption_window": {"days": ["funday"]}}`,
+		`{"redemption_window": {"start": "11:00"}}`,
+		`{"redemption_window": {"start": "14:00", "end": "11:00"}}`,
+		`{"redemption_window": {"start": "11am", "end": "2pm"}}`,
+		`{"redemption_window": "lunchtime"}`,
+	} {
+		_, err := FromMetadata([]byte(metadata))
+		assert.ErrorIs(t, err, ErrInvalidConfig, metadata)
+	}
+}
+
+func TestContains(t *testing.T) {
+	harare, err := time.LoadLocation("Africa/Harare")
+	require.NoError(t, err)
+
+	lunch, err := Parse(rewardtypes.RedemptionWindow{
+		Days:  []string{"mon", "tue", "wed", "thu", "fri"},
+		Start: "11:00",
+		End:   "14:00",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		// Tuesday 30 December 2025; Harare is UTC+2
+		{"inside", time.Date(2025, 12, 30, 10, 30, 0, 0, time.UTC), true},
+		{"at start", time.Date(2025, 12, 30, 9, 0, 0, 0, time.UTC), true},
+		{"at end", time.Date(2025, 12, 30, 12, 0, 0, 0, time.UTC), false},
+		{"inside in UTC only", time.Date(2025, 12, 30, 13, 0, 0, 0, time.UTC), false},
+		{"weekend", time.Date(2026, 1, 3, 10, 30, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, lunch.Contains(tt.at, harare))
+		})
+	}
+
+	weekends, err := Parse(rewardtypes.RedemptionWindow{Days: []string{"sat", "sun"}})
+	require.NoError(t, err)
+	assert.True(t, weekends.Contains(time.Date(2026, 1, 4, 23, 59, 0, 0, harare), harare))
+	assert.False(t, weekends.Contains(time.Date(2026, 1, 5, 0, 0, 0, 0, harare), harare))
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		config rewardtypes.RedemptionWindow
+		want   string
+	}{
+		{rewardtypes.RedemptionWindow{Days: []string{"fri", "mon", "tue", "wed", "thu"}, Start: "11:00", End: "14:00"}, "Mon-Fri 11:00-14:00"},
+		{rewardtypes.RedemptionWindow{Start: "17:30", End: "24:00"}, "daily 17:30-24:00"},
+		{rewardtypes.RedemptionWindow{Days: []string{"sat", "sun"}}, "Sat, Sun"},
+		{rewardtypes.RedemptionWindow{Days: []string{"mon", "wed", "thu", "fri", "sun"}}, "Mon, Wed-Fri, Sun"},
+		{rewardtypes.RedemptionWindow{Days: []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}, Start: "06:00", End: "09:00"}, "daily 06:00-09:00"},
+	}
+	for _, tt := range tests {
+		w, err := Parse(tt.config)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, w.String())
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	err := &Error{Window: Window{Days: []time.Weekday{time.Saturday}, Start: 8 * time.Hour, End: 10 * time.Hour}}
+	assert.True(t, errors.Is(err, ErrOutsideWindow))
+	assert.Equal(t, "This reward can only be redeemed Sat 08:00-10:00.", err.Message())
+}
@@ -2,55 +2,93 @@ package rewardcatalog
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/bmachimbira/loyalty/api/internal/catalogcache"
 	"github.com/bmachimbira/loyalty/api/internal/db"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+var (
+	// ErrRewardNotFound is returned when the reward does not exist
+	ErrRewardNotFound = errors.New("reward not found")
+
+	// ErrRewardArchived is returned when an archived reward is activated
+	ErrRewardArchived = errors.New("reward is archived")
+
+	// ErrRewardInUse is returned when archiving a reward that active rules
+	// of active campaigns still issue
+	ErrRewardInUse = errors.New("reward is issued by active rules of active campaigns")
+)
+
 // Service handles reward catalog-related business logic
 type Service struct {
 	queries *db.Queries
+	catalog *catalogcache.Cache
 }
 
-// NewService creates a new reward catalog service
-func NewService(queries *db.Queries) *Service {
+// NewService creates a new reward catalog service. Reads go through catalog,
+// which is invalidated on every mutation.
+func NewService(queries *db.Queries, catalog *catalogcache.Cache) *Service {
 	return &Service{
 		queries: queries,
+		catalog: catalog,
 	}
 }
 
 // CreateReward creates a new reward in the catalog
 func (s *Service) CreateReward(ctx context.Context, params db.CreateRewardParams) (db.RewardCatalog, error) {
-	return s.queries.CreateReward(ctx, params)
+	reward, err := s.queries.CreateReward(ctx, params)
+	if err != nil {
+		return db.RewardCatalog{}, err
+	}
+	s.catalog.InvalidateRewardLists(params.TenantID)
+	return reward, nil
 }
 
 // GetRewardByID retrieves a reward by ID
 func (s *Service) GetRewardByID(ctx context.Context, id, tenantID pgtype.UUID) (db.RewardCatalog, error) {
-	reward, err := s.queries.GetRewardByID(ctx, db.GetRewardByIDParams{
+	reward, err := s.catalog.GetRewardByID(ctx, db.GetRewardByIDParams{
 		ID:       id,
 		TenantID: tenantID,
 	})
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return db.RewardCatalog{}, fmt.Errorf("reward not found")
+			return db.RewardCatalog{}, ErrRewardNotFound
 		}
 		return db.RewardCatalog{}, fmt.Errorf("failed to get reward: %w", err)
 	}
 	return reward, nil
 }
 
-// ListRewards retrieves all rewards for a tenant
-func (s *Service) ListRewards(ctx context.Context, tenantID pgtype.UUID, activeOnly bool) ([]db.RewardCatalog, error) {
+// ListRewards retrieves the rewards for a tenant. Archived rewards are only
+// listed with includeArchived and never with activeOnly; those lists are
+// rare enough not to be cached.
+func (s *Service) ListRewards(ctx context.Context, tenantID pgtype.UUID, activeOnly, includeArchived bool) ([]db.RewardCatalog, error) {
 	if activeOnly {
-		return s.queries.ListActiveRewards(ctx, tenantID)
+		return s.catalog.ListActiveRewards(ctx, tenantID)
+	}
+	if includeArchived {
+		return s.queries.ListRewardsIncludingArchived(ctx, tenantID)
 	}
-	return s.queries.ListRewards(ctx, tenantID)
+	return s.catalog.ListRewards(ctx, tenantID)
 }
 
-// UpdateRewardStatus updates the active status of a reward
+// UpdateRewardStatus updates the active status of a reward. Archived rewards
+// must be restored before they are activated.
 func (s *Service) UpdateRewardStatus(ctx context.Context, id, tenantID pgtype.UUID, active bool) error {
+	if active {
+		reward, err := s.GetRewardByID(ctx, id, tenantID)
+		if err != nil {
+			return err
+		}
+		if reward.ArchivedAt.Valid {
+			return ErrRewardArchived
+		}
+	}
+
 	err := s.queries.UpdateRewardStatus(ctx, db.UpdateRewardStatusParams{
 		ID:       id,
 		TenantID: tenantID,
@@ -62,5 +100,52 @@ func (s *Service) UpdateRewardStatus(ctx context.Context, id, tenantID pgtype.UU
 		}
 		return fmt.Errorf("failed to update reward status: %w", err)
 	}
+	s.catalog.InvalidateReward(tenantID, id)
 	return nil
 }
+
+// ArchiveReward deactivates a reward and hides it from reward lists. Rewards
+// still issued by active rules of active campaigns cannot be archived.
+// Archiving an archived reward is a no-op.
+func (s *Service) ArchiveReward(ctx context.Context, id, tenantID pgtype.UUID) (db.RewardCatalog, error) {
+	reward, err := s.GetRewardByID(ctx, id, tenantID)
+	if err != nil {
+		return db.RewardCatalog{}, err
+	}
+	if reward.ArchivedAt.Valid {
+		return reward, nil
+	}
+
+	live, err := s.queries.CountLiveRulesForReward(ctx, db.CountLiveRulesForRewardParams{
+		TenantID: tenantID,
+		RewardID: id,
+	})
+	if err != nil {
+		return db.RewardCatalog{}, fmt.Errorf("failed to check reward rules: %w", err)
+	}
+	if live > 0 {
+		return db.RewardCatalog{}, ErrRewardInUse
+	}
+
+	if _, err := s.queries.ArchiveReward(ctx, db.ArchiveRewardParams{
+		ID:       id,
+		TenantID: tenantID,
+	}); err != nil {
+		return db.RewardCatalog{}, fmt.Errorf("failed to archive reward: %w", err)
+	}
+	s.catalog.InvalidateReward(tenantID, id)
+	return s.GetRewardByID(ctx, id, tenantID)
+}
+
+// RestoreReward makes an archived reward visible again. The reward stays
+// inactive until it is activated.
+func (s *Service) RestoreReward(ctx context.Context, id, tenantID pgtype.UUID) (db.RewardCatalog, error) {
+	if _, err := s.queries.RestoreReward(ctx, db.RestoreRewardParams{
+		ID:       id,
+		TenantID: tenantID,
+	}); err != nil {
+		return db.RewardCatalog{}, fmt.Errorf("failed to restore reward: %w", err)
+	}
+	s.catalog.InvalidateReward(tenantID, id)
+	return s.GetRewardByID(ctx, id, tenantID)
+}
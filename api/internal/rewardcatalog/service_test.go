@@ -0,0 +1,129 @@
+package rewardcatalog
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/catalogcache"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveReward(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set, skipping integration tests")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	require.NoError(t, err)
+	defer pool.Close()
+	queries := db.New(pool)
+	service := NewService(queries, catalogcache.New(queries, time.Minute))
+
+	tests := []struct {
+		name           string
+		withRule       bool
+		ruleActive     bool
+		campaignStatus string // empty for a rule outside any campaign
+		unknown        bool
+		wantErr        error
+	}{
+		{"unused", false, false, "", false, nil},
+		{"inactive rule", true, false, "active", false, nil},
+		{"active rule of paused campaign", true, true, "paused", false, nil},
+		{"active rule of active campaign", true, true, "active", false, ErrRewardInUse},
+		{"active rule outside campaigns", true, true, "", false, ErrRewardInUse},
+		{"unknown", false, false, "", true, ErrRewardNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant, err := queries.CreateTenant(ctx, db.CreateTenantParams{
+				Name:        "Archive Rewards " + tt.name,
+				CountryCode: "ZW",
+				DefaultCcy:  "USD",
+				Theme:       []byte(`{}`),
+			})
+			require.NoError(t, err)
+
+			reward, err := service.CreateReward(ctx, db.CreateRewardParams{
+				TenantID:  tenant.ID,
+				Name:      "Free Coffee",
+				Type:      "physical_item",
+				Inventory: "none",
+				Metadata:  []byte(`{}`),
+				Active:    true,
+			})
+			require.NoError(t, err)
+
+			if tt.withRule {
+				var campaignID pgtype.UUID
+				if tt.campaignStatus != "" {
+					campaign, err := queries.CreateCampaign(ctx, db.CreateCampaignParams{
+						TenantID: tenant.ID,
+						Name:     "Coffee Club",
+						StartAt:  pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true},
+						Status:   tt.campaignStatus,
+					})
+					require.NoError(t, err)
+					campaignID = campaign.ID
+				}
+				_, err := queries.CreateRule(ctx, db.CreateRuleParams{
+					TenantID:   tenant.ID,
+					CampaignID: campaignID,
+					Name:       "Tenth visit",
+					EventType:  "visit",
+					Conditions: []byte(`{}`),
+					RewardID:   reward.ID,
+					PerUserCap: 1,
+					Active:     tt.ruleActive,
+					Quantity:   1,
+				})
+				require.NoError(t, err)
+			}
+
+			id := reward.ID
+			if tt.unknown {
+				id = pgtype.UUID{Bytes: uuid.New(), Valid: true}
+			}
+
+			archived, err := service.ArchiveReward(ctx, id, tenant.ID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, archived.ArchivedAt.Valid)
+			assert.False(t, archived.Active)
+
+			// Archiving again is a no-op
+			again, err := service.ArchiveReward(ctx, id, tenant.ID)
+			require.NoError(t, err)
+			assert.Equal(t, archived.ArchivedAt, again.ArchivedAt)
+
+			listed, err := service.ListRewards(ctx, tenant.ID, false, false)
+			require.NoError(t, err)
+			assert.Empty(t, listed)
+			listed, err = service.ListRewards(ctx, tenant.ID, false, true)
+			require.NoError(t, err)
+			assert.Len(t, listed, 1)
+
+			assert.ErrorIs(t, service.UpdateRewardStatus(ctx, id, tenant.ID, true), ErrRewardArchived)
+
+			// Restored rewards stay inactive until activated
+			restored, err := service.RestoreReward(ctx, id, tenant.ID)
+			require.NoError(t, err)
+			assert.False(t, restored.ArchivedAt.Valid)
+			assert.False(t, restored.Active)
+			assert.NoError(t, service.UpdateRewardStatus(ctx, id, tenant.ID, true))
+		})
+	}
+}
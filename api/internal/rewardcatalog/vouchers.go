@@ -0,0 +1,85 @@
+package rewardcatalog
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrNotVoucherReward is returned when importing codes for a reward that is
+// not of type voucher_code
+var ErrNotVoucherReward = errors.New("reward must be of type voucher_code")
+
+// VoucherImport is the outcome of importing a voucher code CSV
+type VoucherImport struct {
+	// Total is the number of codes in the file
+	Total int
+	// Imported is the number of codes added; codes already loaded are
+	// skipped
+	Imported int
+}
+
+// ParseVoucherCodes reads voucher codes from the first column of a CSV,
+// skipping blank lines
+func ParseVoucherCodes(r io.Reader) ([]string, error) {
+	reader := csv.NewReader(bufio.NewReader(r))
+
+	var codes []string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(record) == 0 {
+			continue
+		}
+		if code := strings.TrimSpace(record[0]); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return codes, nil
+}
+
+// ImportVoucherCodes parses a voucher code CSV and loads its codes for a
+// voucher_code reward
+func (s *Service) ImportVoucherCodes(ctx context.Context, tenantID, rewardID pgtype.UUID, r io.Reader) (VoucherImport, error) {
+	reward, err := s.GetRewardByID(ctx, rewardID, tenantID)
+	if err != nil {
+		return VoucherImport{}, err
+	}
+	if reward.Type != "voucher_code" {
+		return VoucherImport{}, ErrNotVoucherReward
+	}
+
+	codes, err := ParseVoucherCodes(r)
+	if err != nil {
+		return VoucherImport{}, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+
+	result := VoucherImport{Total: len(codes)}
+	for _, code := range codes {
+		_, err := s.queries.InsertVoucherCode(ctx, db.InsertVoucherCodeParams{
+			TenantID: tenantID,
+			RewardID: rewardID,
+			Code:     code,
+		})
+		if err != nil {
+			// Duplicates and bad codes are skipped so one bad line doesn't
+			// lose the rest of the file
+			continue
+		}
+		result.Imported++
+	}
+	return result, nil
+}
@@ -2,6 +2,62 @@ package rewardtypes
 
 // Metadata types for different reward types
 
+// TermsMetadata holds the customer-facing terms any reward type may carry
+type TermsMetadata struct {
+	Description       string   `json:"description,omitempty"`
+	Terms             string   `json:"terms,omitempty"`
+	Instructions      string   `json:"instructions,omitempty"`
+	StoreRestrictions []string `json:"store_restrictions,omitempty"`
+	MinBasket         float64  `json:"min_basket,omitempty"`
+}
+
+// CodeMetadata holds the issuance code settings any code-generating reward
+// type may carry under the "code" key
+type CodeMetadata struct {
+	Code *CodeConfig `json:"code,omitempty"`
+}
+
+// CodeConfig selects how issuance codes are generated for a reward
+type CodeConfig struct {
+	Strategy string `json:"strategy"`         // "alphanumeric", "numeric" or "checksum"
+	Length   int    `json:"length,omitempty"` // characters before any prefix or check character
+	Prefix   string `json:"prefix,omitempty"` // checksum strategy only
+}
+
+// LocationMetadata holds the stores a reward may be redeemed at, by
+// location code, under the "allowed_locations" key. Unlike the
+// StoreRestrictions shown to customers, these are enforced at redemption.
+type LocationMetadata struct {
+	AllowedLocations []string `json:"allowed_locations,omitempty"`
+}
+
+// WindowMetadata holds the days and hours a reward may be redeemed, in the
+// tenant's time zone, under the "redemption_window" key
+type WindowMetadata struct {
+	RedemptionWindow *RedemptionWindow `json:"redemption_window,omitempty"`
+}
+
+// RedemptionWindow limits redemption to some days of the week and a time of
+// day. No days is every day; no start and end is all day.
+type RedemptionWindow struct {
+	Days  []string `json:"days,omitempty"`  // "mon" to "sun"
+	Start string   `json:"start,omitempty"` // "15:04", inclusive
+	End   string   `json:"end,omitempty"`   // "15:04", exclusive
+}
+
+// ValueMetadata holds the settings of a reward valued from the event that
+// triggers it, under the "value_formula" key
+type ValueMetadata struct {
+	ValueFormula *ValueFormula `json:"value_formula,omitempty"`
+}
+
+// ValueFormula values a reward at a percentage of a numeric event property
+type ValueFormula struct {
+	Percent  float64 `json:"percent"`
+	Property string  `json:"property,omitempty"` // event property, "amount" by default
+	Max      float64 `json:"max,omitempty"`      // cap on the value, 0 for none
+}
+
 type DiscountMetadata struct {
 	DiscountType string  `json:"discount_type"` // "amount" or "percent"
 	Amount       float64 `json:"amount"`
@@ -17,7 +73,8 @@ type ExternalVoucherMetadata struct {
 type PhysicalItemMetadata struct {
 	ItemName         string   `json:"item_name"`
 	PickupLocations  []string `json:"pickup_locations,omitempty"`
-	CollectionPeriod int      `json:"collection_period"` // days
+	CollectionPeriod int      `json:"collection_period"`       // days
+	DeliveryDays     int      `json:"delivery_days,omitempty"` // days to deliver before escalating
 }
 
 type WebhookMetadata struct {
@@ -0,0 +1,144 @@
+package rule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/rules"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// MaxRewardQuantity is the most units of one reward a trigger issues
+	MaxRewardQuantity = 100
+	// MaxAdditionalRewards is the most rewards a rule issues on top of its
+	// own reward
+	MaxAdditionalRewards = 10
+)
+
+var (
+	// ErrInvalidRewardActions is returned for a quantity or additional
+	// rewards out of range
+	ErrInvalidRewardActions = fmt.Errorf("quantities must be between 1 and %d, with at most %d additional rewards",
+		MaxRewardQuantity, MaxAdditionalRewards)
+
+	// ErrRewardNotFound is returned when a rule's reward does not exist
+	ErrRewardNotFound = errors.New("reward not found")
+)
+
+// DefaultQuantity defaults an omitted reward quantity to 1. Quantities out
+// of range become 0, which ValidateActions rejects.
+func DefaultQuantity(quantity int) int32 {
+	switch {
+	case quantity == 0:
+		return 1
+	case quantity < 0 || quantity > MaxRewardQuantity:
+		return 0
+	}
+	return int32(quantity)
+}
+
+// ValidateActions checks a rule's quantity and additional rewards
+func ValidateActions(quantity int32, additional []rules.RewardAction) error {
+	if quantity < 1 || quantity > MaxRewardQuantity || len(additional) > MaxAdditionalRewards {
+		return ErrInvalidRewardActions
+	}
+	for _, a := range additional {
+		if !a.RewardID.Valid || a.Quantity < 1 || a.Quantity > MaxRewardQuantity {
+			return ErrInvalidRewardActions
+		}
+	}
+	return nil
+}
+
+// SetAdditionalRewards replaces the rewards a rule issues on top of its own
+// reward. q should be bound to the transaction the rule is written in.
+func SetAdditionalRewards(ctx context.Context, q *db.Queries, tenantID, ruleID pgtype.UUID, additional []rules.RewardAction) error {
+	if err := q.DeleteRuleRewards(ctx, db.DeleteRuleRewardsParams{
+		TenantID: tenantID,
+		RuleID:   ruleID,
+	}); err != nil {
+		return fmt.Errorf("failed to clear rule rewards: %w", err)
+	}
+
+	for i, a := range additional {
+		if _, err := q.CreateRuleReward(ctx, db.CreateRuleRewardParams{
+			TenantID: tenantID,
+			RuleID:   ruleID,
+			RewardID: a.RewardID,
+			Quantity: a.Quantity,
+			Position: int32(i),
+		}); err != nil {
+			return fmt.Errorf("failed to add rule reward: %w", err)
+		}
+	}
+	return nil
+}
+
+// CreateRule creates a rule with the rewards it issues on top of its own.
+// Every reward must be one of the tenant's.
+func (s *Service) CreateRule(ctx context.Context, params db.CreateRuleParams, additional []rules.RewardAction) (db.Rule, error) {
+	if err := ValidateActions(params.Quantity, additional); err != nil {
+		return db.Rule{}, err
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.Rule{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	rewardIDs := []pgtype.UUID{params.RewardID}
+	for _, a := range additional {
+		rewardIDs = append(rewardIDs, a.RewardID)
+	}
+	for _, id := range rewardIDs {
+		if _, err := qtx.GetRewardByID(ctx, db.GetRewardByIDParams{
+			ID:       id,
+			TenantID: params.TenantID,
+		}); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return db.Rule{}, ErrRewardNotFound
+			}
+			return db.Rule{}, fmt.Errorf("failed to get reward: %w", err)
+		}
+	}
+
+	created, err := qtx.CreateRule(ctx, params)
+	if err != nil {
+		return db.Rule{}, fmt.Errorf("failed to create rule: %w", err)
+	}
+	if err := SetAdditionalRewards(ctx, qtx, params.TenantID, created.ID, additional); err != nil {
+		return db.Rule{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.Rule{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return created, nil
+}
+
+// AdditionalRewards returns the rewards each of the rules issues on top of
+// its own, by rule
+func (s *Service) AdditionalRewards(ctx context.Context, tenantID pgtype.UUID, ruleIDs []pgtype.UUID) (map[pgtype.UUID][]db.RuleReward, error) {
+	additional := make(map[pgtype.UUID][]db.RuleReward)
+	if len(ruleIDs) == 0 {
+		return additional, nil
+	}
+
+	rows, err := s.queries.ListRuleRewardsForRules(ctx, db.ListRuleRewardsForRulesParams{
+		TenantID: tenantID,
+		RuleIds:  ruleIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rule rewards: %w", err)
+	}
+	for _, row := range rows {
+		additional[row.RuleID] = append(additional[row.RuleID], row)
+	}
+	return additional, nil
+}
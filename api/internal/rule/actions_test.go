@@ -0,0 +1,34 @@
+package rule
+
+import (
+	"testing"
+
+	"github.com/bmachimbira/loyalty/api/internal/rules"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultQuantity(t *testing.T) {
+	assert.Equal(t, int32(1), DefaultQuantity(0))
+	assert.Equal(t, int32(3), DefaultQuantity(3))
+	assert.Equal(t, int32(0), DefaultQuantity(-1))
+	assert.Equal(t, int32(0), DefaultQuantity(1<<32+1))
+}
+
+func TestValidateActions(t *testing.T) {
+	points := rules.RewardAction{RewardID: pgtype.UUID{Bytes: [16]byte{1}, Valid: true}, Quantity: 1}
+
+	assert.NoError(t, ValidateActions(1, nil))
+	assert.NoError(t, ValidateActions(MaxRewardQuantity, []rules.RewardAction{points}))
+
+	assert.ErrorIs(t, ValidateActions(0, nil), ErrInvalidRewardActions)
+	assert.ErrorIs(t, ValidateActions(MaxRewardQuantity+1, nil), ErrInvalidRewardActions)
+	assert.ErrorIs(t, ValidateActions(1, []rules.RewardAction{{Quantity: 1}}), ErrInvalidRewardActions)
+	assert.ErrorIs(t, ValidateActions(1, []rules.RewardAction{{RewardID: points.RewardID}}), ErrInvalidRewardActions)
+
+	tooMany := make([]rules.RewardAction, MaxAdditionalRewards+1)
+	for i := range tooMany {
+		tooMany[i] = points
+	}
+	assert.ErrorIs(t, ValidateActions(1, tooMany), ErrInvalidRewardActions)
+}
@@ -0,0 +1,125 @@
+package rule
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveRule(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set, skipping integration tests")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	require.NoError(t, err)
+	defer pool.Close()
+	queries := db.New(pool)
+	service := NewService(pool, queries)
+
+	tests := []struct {
+		name           string
+		active         bool
+		campaignStatus string // empty for a rule outside any campaign
+		unknown        bool
+		wantErr        error
+	}{
+		{"inactive rule of active campaign", false, "active", false, nil},
+		{"active rule of paused campaign", true, "paused", false, nil},
+		{"active rule outside campaigns", true, "", false, nil},
+		{"active rule of active campaign", true, "active", false, ErrRuleInActiveCampaign},
+		{"unknown", false, "", true, ErrRuleNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant, err := queries.CreateTenant(ctx, db.CreateTenantParams{
+				Name:        "Archive Rules " + tt.name,
+				CountryCode: "ZW",
+				DefaultCcy:  "USD",
+				Theme:       []byte(`{}`),
+			})
+			require.NoError(t, err)
+
+			reward, err := queries.CreateReward(ctx, db.CreateRewardParams{
+				TenantID:  tenant.ID,
+				Name:      "Free Coffee",
+				Type:      "physical_item",
+				Inventory: "none",
+				Metadata:  []byte(`{}`),
+				Active:    true,
+			})
+			require.NoError(t, err)
+
+			var campaignID pgtype.UUID
+			if tt.campaignStatus != "" {
+				campaign, err := queries.CreateCampaign(ctx, db.CreateCampaignParams{
+					TenantID: tenant.ID,
+					Name:     "Coffee Club",
+					StartAt:  pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true},
+					Status:   tt.campaignStatus,
+				})
+				require.NoError(t, err)
+				campaignID = campaign.ID
+			}
+
+			rule, err := queries.CreateRule(ctx, db.CreateRuleParams{
+				TenantID:   tenant.ID,
+				CampaignID: campaignID,
+				Name:       "Tenth visit",
+				EventType:  "visit",
+				Conditions: []byte(`{}`),
+				RewardID:   reward.ID,
+				PerUserCap: 1,
+				Active:     tt.active,
+				Quantity:   1,
+			})
+			require.NoError(t, err)
+
+			id := rule.ID
+			if tt.unknown {
+				id = pgtype.UUID{Bytes: uuid.New(), Valid: true}
+			}
+
+			archived, err := service.ArchiveRule(ctx, id, tenant.ID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, archived.ArchivedAt.Valid)
+			assert.False(t, archived.Active)
+
+			// Archiving again is a no-op
+			again, err := service.ArchiveRule(ctx, id, tenant.ID)
+			require.NoError(t, err)
+			assert.Equal(t, archived.ArchivedAt, again.ArchivedAt)
+
+			listed, err := service.ListRules(ctx, tenant.ID, false, false)
+			require.NoError(t, err)
+			assert.Empty(t, listed)
+			listed, err = service.ListRules(ctx, tenant.ID, false, true)
+			require.NoError(t, err)
+			assert.Len(t, listed, 1)
+
+			assert.ErrorIs(t, service.UpdateRuleStatus(ctx, id, tenant.ID, true), ErrRuleArchived)
+
+			// Restored rules stay inactive until activated
+			restored, err := service.RestoreRule(ctx, id, tenant.ID)
+			require.NoError(t, err)
+			assert.False(t, restored.ArchivedAt.Valid)
+			assert.False(t, restored.Active)
+			assert.NoError(t, service.UpdateRuleStatus(ctx, id, tenant.ID, true))
+		})
+	}
+}
@@ -0,0 +1,359 @@
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/reward/value"
+	"github.com/bmachimbira/loyalty/api/internal/rewardtypes"
+	"github.com/bmachimbira/loyalty/api/internal/rules"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Backtest limits
+const (
+	// DefaultBacktestDays is the window backtested when none is given
+	DefaultBacktestDays = 30
+	// MaxBacktestDays is the longest window that can be backtested
+	MaxBacktestDays = 90
+	// MaxBacktestEvents bounds the events one backtest evaluates; a window
+	// with more is truncated to its oldest events
+	MaxBacktestEvents = 50000
+)
+
+// Cap skip reasons, in the order caps are checked
+const (
+	skipPerUserCap = "per_user_cap"
+	skipGlobalCap  = "global_cap"
+	skipCooldown   = "cooldown"
+)
+
+// ErrInvalidBacktestDays is returned for a window outside 1 to MaxBacktestDays
+var ErrInvalidBacktestDays = fmt.Errorf("days must be between 1 and %d", MaxBacktestDays)
+
+// BacktestParams selects what a backtest evaluates
+type BacktestParams struct {
+	// Days is how many days back from now to replay; 0 uses
+	// DefaultBacktestDays
+	Days int
+	// Conditions replace the rule's saved conditions, to try a change
+	// before saving it
+	Conditions json.RawMessage
+}
+
+// BacktestResult is what a rule would have issued over a window of past
+// events
+type BacktestResult struct {
+	Since time.Time
+	Until time.Time
+	// EventsEvaluated is the number of the rule's events in the window
+	EventsEvaluated int
+	// Truncated is set when the window had more than MaxBacktestEvents
+	// events, so the counts and cost are a lower bound
+	Truncated bool
+	// Matched is the number of events the conditions matched
+	Matched int
+	// EvaluationErrors is the number of events the conditions failed on
+	EvaluationErrors int
+	// SkippedExcluded is the number of matched events whose customer is
+	// excluded from the rule's campaign
+	SkippedExcluded int
+	// Matched events the rule's caps would have skipped, by cap
+	SkippedPerUserCap int
+	SkippedGlobalCap  int
+	SkippedCooldown   int
+	// SkippedNoValue is the number of matched events that gave a reward
+	// valued from the event no value
+	SkippedNoValue int
+	// Triggered is the number of matched events the caps let through
+	Triggered int
+	// Issuances is the number of rewards the rule would have issued; each
+	// trigger issues its whole reward set
+	Issuances int
+	// Customers is the number of distinct customers rewarded
+	Customers int
+	// Currency, UnitCost and EstimatedCost are the currency of the rule's
+	// reward, the average cost of the rewards one trigger issues, and the
+	// total cost of the triggers. Rewards valued from the event are costed
+	// per event; without triggers UnitCost only covers fixed-value rewards.
+	Currency      string
+	UnitCost      float64
+	EstimatedCost float64
+	// Projected30DayCost scales the estimated cost to a 30 day period
+	Projected30DayCost float64
+}
+
+// Backtester evaluates rules against historical events without issuing
+// anything
+type Backtester struct {
+	queries   *db.Queries
+	evaluator *rules.Evaluator
+}
+
+// NewBacktester creates a new backtester
+func NewBacktester(pool *pgxpool.Pool, queries *db.Queries) *Backtester {
+	return &Backtester{
+		queries:   queries,
+		evaluator: rules.NewEvaluator(rules.NewCustomOperators(pool)),
+	}
+}
+
+// Backtest replays the rule's events of the last params.Days days through
+// its conditions, its campaign's exclusions and its caps and estimates the
+// cost of what it would have issued. The rule need not be active. Caps are
+// simulated from zero over the window, counting only this rule's issuances,
+// and budgets are not checked, so the estimate is the demand the rule would
+// put on its budget. Exclusions, custom operators that look at a customer's
+// history and customer attributes other than enrolled_days are applied as
+// of now rather than as of each event.
+func (b *Backtester) Backtest(ctx context.Context, tenantID, ruleID pgtype.UUID, params BacktestParams, now time.Time) (*BacktestResult, error) {
+	days := params.Days
+	if days == 0 {
+		days = DefaultBacktestDays
+	}
+	if days < 1 || days > MaxBacktestDays {
+		return nil, ErrInvalidBacktestDays
+	}
+
+	rule, err := b.queries.GetRuleByID(ctx, db.GetRuleByIDParams{
+		ID:       ruleID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRuleNotFound
+		}
+		return nil, fmt.Errorf("failed to get rule: %w", err)
+	}
+	if len(params.Conditions) > 0 {
+		rule.Conditions = params.Conditions
+	}
+
+	result := &BacktestResult{
+		Since:    now.AddDate(0, 0, -days),
+		Until:    now,
+		Currency: "USD",
+	}
+
+	actions, err := rules.RuleActions(ctx, b.queries, rule)
+	if err != nil {
+		return nil, err
+	}
+	priced := make([]pricedAction, len(actions))
+	perTrigger := 0
+	for i, action := range actions {
+		rewardItem, err := b.queries.GetRewardByID(ctx, db.GetRewardByIDParams{
+			TenantID: tenantID,
+			ID:       action.RewardID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get reward: %w", err)
+		}
+		if i == 0 && rewardItem.Currency.Valid {
+			result.Currency = rewardItem.Currency.String
+		}
+		priced[i].quantity = action.Quantity
+		if priced[i].formula, err = value.FromMetadata(rewardItem.Metadata); err != nil {
+			return nil, fmt.Errorf("reward %s: %w", rewardItem.Name, err)
+		}
+		if v, err := rewardItem.FaceValue.Float64Value(); err == nil && v.Valid {
+			priced[i].faceValue = v.Float64
+		}
+		perTrigger += int(action.Quantity)
+	}
+
+	events, err := b.queries.ListEventsForBacktest(ctx, db.ListEventsForBacktestParams{
+		TenantID:  tenantID,
+		EventType: rule.EventType,
+		Since:     pgtype.Timestamptz{Time: result.Since, Valid: true},
+		Until:     pgtype.Timestamptz{Time: result.Until, Valid: true},
+		MaxEvents: MaxBacktestEvents + 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+	if len(events) > MaxBacktestEvents {
+		events = events[:MaxBacktestEvents]
+		result.Truncated = true
+	}
+	result.EventsEvaluated = len(events)
+
+	excluded := make(map[pgtype.UUID]bool)
+	if rule.CampaignID.Valid {
+		customerIDs, err := b.queries.ListCampaignExcludedCustomerIDs(ctx, db.ListCampaignExcludedCustomerIDsParams{
+			TenantID:   tenantID,
+			CampaignID: rule.CampaignID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list campaign exclusions: %w", err)
+		}
+		for _, id := range customerIDs {
+			excluded[id] = true
+		}
+	}
+
+	// Customers are looked up once each, and only if the conditions read them
+	readsCustomer := rules.ReadsCustomer(rule.Conditions)
+	customers := make(map[pgtype.UUID]db.GetCustomerRuleAttributesRow)
+
+	caps := newCapSimulator(rule)
+	for _, event := range events {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		data, err := rules.EventData(event)
+		if err != nil {
+			result.EvaluationErrors++
+			continue
+		}
+		if readsCustomer && event.CustomerID.Valid {
+			attributes, ok := customers[event.CustomerID]
+			if !ok {
+				attributes, err = b.queries.GetCustomerRuleAttributes(ctx, db.GetCustomerRuleAttributesParams{
+					TenantID: tenantID,
+					ID:       event.CustomerID,
+				})
+				if err != nil {
+					return nil, fmt.Errorf("failed to get customer attributes: %w", err)
+				}
+				customers[event.CustomerID] = attributes
+			}
+			data["customer"] = rules.CustomerData(attributes, event.OccurredAt.Time)
+		}
+		matched, err := b.evaluator.Evaluate(ctx, rule.Conditions, data)
+		if err != nil {
+			result.EvaluationErrors++
+			continue
+		}
+		if !matched {
+			continue
+		}
+		result.Matched++
+
+		if excluded[event.CustomerID] {
+			result.SkippedExcluded++
+			continue
+		}
+
+		switch caps.check(event.CustomerID, event.OccurredAt.Time) {
+		case skipPerUserCap:
+			result.SkippedPerUserCap++
+		case skipGlobalCap:
+			result.SkippedGlobalCap++
+		case skipCooldown:
+			result.SkippedCooldown++
+		default:
+			cost, err := triggerCost(priced, event.Properties)
+			if err != nil {
+				result.SkippedNoValue++
+				continue
+			}
+			caps.record(event.CustomerID, event.OccurredAt.Time)
+			result.Triggered++
+			result.EstimatedCost += cost
+		}
+	}
+
+	result.Issuances = result.Triggered * perTrigger
+	result.Customers = caps.customers()
+	if result.Triggered > 0 {
+		result.UnitCost = httputil.RoundCents(result.EstimatedCost / float64(result.Triggered))
+	} else {
+		result.UnitCost = httputil.RoundCents(fixedCost(priced))
+	}
+	result.Projected30DayCost = httputil.RoundCents(result.EstimatedCost * 30 / float64(days))
+	result.EstimatedCost = httputil.RoundCents(result.EstimatedCost)
+	return result, nil
+}
+
+// pricedAction is a reward of a rule's action set with what it costs: its
+// face value, or for rewards valued from the event its value formula
+type pricedAction struct {
+	faceValue float64
+	formula   *rewardtypes.ValueFormula
+	quantity  int32
+}
+
+// triggerCost returns the cost of the rewards one trigger issues for an
+// event with the given properties. It returns value.ErrNoValue if the event
+// gives a reward no value, in which case the engine issues nothing.
+func triggerCost(actions []pricedAction, properties []byte) (float64, error) {
+	var cost float64
+	for _, a := range actions {
+		unit := a.faceValue
+		if a.formula != nil {
+			inputs, err := value.Compute(*a.formula, properties)
+			if err != nil {
+				return 0, err
+			}
+			unit = inputs.Value
+		}
+		cost += unit * float64(a.quantity)
+	}
+	return cost, nil
+}
+
+// fixedCost returns the cost of the rewards at a fixed face value one
+// trigger issues
+func fixedCost(actions []pricedAction) float64 {
+	var cost float64
+	for _, a := range actions {
+		if a.formula == nil {
+			cost += a.faceValue * float64(a.quantity)
+		}
+	}
+	return cost
+}
+
+// capSimulator applies a rule's caps to simulated triggers in event order
+type capSimulator struct {
+	rule    db.Rule
+	global  int
+	perUser map[pgtype.UUID]int
+	last    map[pgtype.UUID]time.Time
+}
+
+func newCapSimulator(rule db.Rule) *capSimulator {
+	return &capSimulator{
+		rule:    rule,
+		perUser: make(map[pgtype.UUID]int),
+		last:    make(map[pgtype.UUID]time.Time),
+	}
+}
+
+// check returns the cap that would skip a trigger for the customer at the
+// given time, or "" if none would
+func (s *capSimulator) check(customerID pgtype.UUID, at time.Time) string {
+	if s.rule.PerUserCap > 0 && s.perUser[customerID] >= int(s.rule.PerUserCap) {
+		return skipPerUserCap
+	}
+	if s.rule.GlobalCap.Valid && s.rule.GlobalCap.Int32 > 0 && s.global >= int(s.rule.GlobalCap.Int32) {
+		return skipGlobalCap
+	}
+	if s.rule.CoolDownSec > 0 {
+		if last, ok := s.last[customerID]; ok && at.Sub(last) < time.Duration(s.rule.CoolDownSec)*time.Second {
+			return skipCooldown
+		}
+	}
+	return ""
+}
+
+// record counts a trigger for the customer at the given time
+func (s *capSimulator) record(customerID pgtype.UUID, at time.Time) {
+	s.global++
+	s.perUser[customerID]++
+	s.last[customerID] = at
+}
+
+// customers returns the number of distinct customers issued to
+func (s *capSimulator) customers() int {
+	return len(s.perUser)
+}
@@ -0,0 +1,80 @@
+package rule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/reward/value"
+	"github.com/bmachimbira/loyalty/api/internal/rewardtypes"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func customer(b byte) pgtype.UUID {
+	return pgtype.UUID{Bytes: [16]byte{b}, Valid: true}
+}
+
+func TestCapSimulatorPerUserCap(t *testing.T) {
+	caps := newCapSimulator(db.Rule{PerUserCap: 2})
+	at := time.Date(2025, 12, 1, 9, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		assert.Empty(t, caps.check(customer(1), at))
+		caps.record(customer(1), at)
+	}
+	assert.Equal(t, skipPerUserCap, caps.check(customer(1), at))
+	assert.Empty(t, caps.check(customer(2), at))
+	assert.Equal(t, 1, caps.customers())
+}
+
+func TestCapSimulatorGlobalCap(t *testing.T) {
+	caps := newCapSimulator(db.Rule{GlobalCap: pgtype.Int4{Int32: 2, Valid: true}})
+	at := time.Date(2025, 12, 1, 9, 0, 0, 0, time.UTC)
+
+	caps.record(customer(1), at)
+	caps.record(customer(2), at)
+	assert.Equal(t, skipGlobalCap, caps.check(customer(3), at))
+	assert.Equal(t, 2, caps.customers())
+}
+
+func TestCapSimulatorCooldown(t *testing.T) {
+	caps := newCapSimulator(db.Rule{CoolDownSec: 3600})
+	at := time.Date(2025, 12, 1, 9, 0, 0, 0, time.UTC)
+
+	caps.record(customer(1), at)
+	assert.Equal(t, skipCooldown, caps.check(customer(1), at.Add(59*time.Minute)))
+	assert.Empty(t, caps.check(customer(1), at.Add(time.Hour)))
+	assert.Empty(t, caps.check(customer(2), at))
+}
+
+func TestCapSimulatorNoCaps(t *testing.T) {
+	caps := newCapSimulator(db.Rule{})
+	at := time.Date(2025, 12, 1, 9, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 10; i++ {
+		assert.Empty(t, caps.check(customer(1), at))
+		caps.record(customer(1), at)
+	}
+}
+
+func TestTriggerCost(t *testing.T) {
+	actions := []pricedAction{
+		{faceValue: 2, quantity: 2},
+		{formula: &rewardtypes.ValueFormula{Percent: 5, Property: "amount", Max: 10}, quantity: 1},
+	}
+
+	cost, err := triggerCost(actions, []byte(`{"amount": 120}`))
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, cost)
+
+	cost, err = triggerCost(actions, []byte(`{"amount": 1000}`))
+	require.NoError(t, err)
+	assert.Equal(t, 14.0, cost)
+
+	_, err = triggerCost(actions, []byte(`{}`))
+	assert.ErrorIs(t, err, value.ErrNoValue)
+
+	assert.Equal(t, 4.0, fixedCost(actions))
+}
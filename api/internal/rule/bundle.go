@@ -0,0 +1,400 @@
+package rule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/rules"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gopkg.in/yaml.v3"
+)
+
+// BundleVersion is the version of the bundle format written by Export
+const BundleVersion = 1
+
+// Bundle formats
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+var (
+	// ErrCampaignNotFound is returned when the campaign does not exist
+	ErrCampaignNotFound = errors.New("campaign not found")
+
+	// ErrInvalidBundle is returned for a bundle that cannot be imported
+	ErrInvalidBundle = errors.New("invalid rule bundle")
+
+	// ErrUnknownRewards is returned when a bundle references rewards the
+	// tenant does not have
+	ErrUnknownRewards = errors.New("bundle references unknown rewards")
+
+	// ErrRuleNamesTaken is returned when the campaign already has rules with
+	// names in the bundle
+	ErrRuleNamesTaken = errors.New("campaign already has rules named")
+)
+
+// Bundle is a portable copy of a campaign's rules. Rewards are referenced
+// by name, so a bundle exported from one tenant can be imported into another
+// that has rewards of the same names.
+type Bundle struct {
+	Version  int          `json:"version" yaml:"version"`
+	Campaign string       `json:"campaign" yaml:"campaign"`
+	Rules    []BundleRule `json:"rules" yaml:"rules"`
+}
+
+// BundleRule is a rule in a bundle. Each trigger issues Quantity of Reward
+// and the AdditionalRewards; an omitted quantity means 1.
+type BundleRule struct {
+	Name              string                 `json:"name" yaml:"name"`
+	EventType         string                 `json:"event_type" yaml:"event_type"`
+	Conditions        map[string]interface{} `json:"conditions" yaml:"conditions"`
+	Reward            string                 `json:"reward" yaml:"reward"`
+	Quantity          int                    `json:"quantity,omitempty" yaml:"quantity,omitempty"`
+	AdditionalRewards []BundleReward         `json:"additional_rewards,omitempty" yaml:"additional_rewards,omitempty"`
+	PerUserCap        int                    `json:"per_user_cap" yaml:"per_user_cap"`
+	GlobalCap         *int                   `json:"global_cap,omitempty" yaml:"global_cap,omitempty"`
+	CoolDownSec       int                    `json:"cool_down_sec" yaml:"cool_down_sec"`
+	Active            bool                   `json:"active" yaml:"active"`
+}
+
+// BundleReward is a reward a bundle rule issues on top of its own
+type BundleReward struct {
+	Reward   string `json:"reward" yaml:"reward"`
+	Quantity int    `json:"quantity,omitempty" yaml:"quantity,omitempty"`
+}
+
+// RewardQuantity returns how many of its reward the rule issues per trigger
+func (r BundleRule) RewardQuantity() int32 {
+	return DefaultQuantity(r.Quantity)
+}
+
+// RewardNames returns the names of every reward the rule issues
+func (r BundleRule) RewardNames() []string {
+	names := []string{r.Reward}
+	for _, a := range r.AdditionalRewards {
+		names = append(names, a.Reward)
+	}
+	return names
+}
+
+// Actions returns the rule's additional rewards, resolving reward names
+// with ids
+func (r BundleRule) Actions(ids map[string]pgtype.UUID) []rules.RewardAction {
+	actions := make([]rules.RewardAction, len(r.AdditionalRewards))
+	for i, a := range r.AdditionalRewards {
+		actions[i] = rules.RewardAction{RewardID: ids[a.Reward], Quantity: DefaultQuantity(a.Quantity)}
+	}
+	return actions
+}
+
+// Validate checks that a bundle can be imported
+func (b *Bundle) Validate() error {
+	if b.Version != BundleVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidBundle, b.Version)
+	}
+	if len(b.Rules) == 0 {
+		return fmt.Errorf("%w: no rules", ErrInvalidBundle)
+	}
+
+	seen := make(map[string]bool, len(b.Rules))
+	for i, r := range b.Rules {
+		switch {
+		case strings.TrimSpace(r.Name) == "":
+			return fmt.Errorf("%w: rule %d has no name", ErrInvalidBundle, i+1)
+		case seen[r.Name]:
+			return fmt.Errorf("%w: rule %q appears twice", ErrInvalidBundle, r.Name)
+		case r.EventType == "":
+			return fmt.Errorf("%w: rule %q has no event_type", ErrInvalidBundle, r.Name)
+		case r.Conditions == nil:
+			return fmt.Errorf("%w: rule %q has no conditions", ErrInvalidBundle, r.Name)
+		case r.Reward == "":
+			return fmt.Errorf("%w: rule %q has no reward", ErrInvalidBundle, r.Name)
+		case r.PerUserCap < 0 || r.CoolDownSec < 0 || (r.GlobalCap != nil && *r.GlobalCap < 0):
+			return fmt.Errorf("%w: rule %q has a negative cap or cooldown", ErrInvalidBundle, r.Name)
+		case !validBundleActions(r):
+			return fmt.Errorf("%w: rule %q: %v", ErrInvalidBundle, r.Name, ErrInvalidRewardActions)
+		}
+		seen[r.Name] = true
+	}
+	return nil
+}
+
+// validBundleActions reports whether a bundle rule's quantities and
+// additional rewards are in range
+func validBundleActions(r BundleRule) bool {
+	if r.Quantity < 0 || r.Quantity > MaxRewardQuantity || len(r.AdditionalRewards) > MaxAdditionalRewards {
+		return false
+	}
+	for _, a := range r.AdditionalRewards {
+		if a.Reward == "" || a.Quantity < 0 || a.Quantity > MaxRewardQuantity {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalBundle encodes a bundle in the given format
+func MarshalBundle(b *Bundle, format string) ([]byte, error) {
+	switch format {
+	case FormatYAML:
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(b); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case FormatJSON:
+		return json.MarshalIndent(b, "", "  ")
+	default:
+		return nil, fmt.Errorf("unsupported bundle format %q", format)
+	}
+}
+
+// UnmarshalBundle decodes a bundle in the given format
+func UnmarshalBundle(data []byte, format string) (*Bundle, error) {
+	var b Bundle
+	var err error
+	switch format {
+	case FormatYAML:
+		err = yaml.Unmarshal(data, &b)
+	case FormatJSON:
+		err = json.Unmarshal(data, &b)
+	default:
+		return nil, fmt.Errorf("unsupported bundle format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	return &b, nil
+}
+
+// Bundler exports and imports campaigns' rules as bundles
+type Bundler struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+}
+
+// NewBundler creates a new bundler
+func NewBundler(pool *pgxpool.Pool, queries *db.Queries) *Bundler {
+	return &Bundler{pool: pool, queries: queries}
+}
+
+// Export bundles the campaign's rules, leaving out archived rules
+func (b *Bundler) Export(ctx context.Context, tenantID, campaignID pgtype.UUID) (*Bundle, error) {
+	campaign, err := b.queries.GetCampaignByID(ctx, db.GetCampaignByIDParams{
+		ID:       campaignID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCampaignNotFound
+		}
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+
+	campaignRules, err := b.queries.ListRulesByCampaign(ctx, db.ListRulesByCampaignParams{
+		TenantID:   tenantID,
+		CampaignID: campaignID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaign rules: %w", err)
+	}
+
+	bundle := &Bundle{
+		Version:  BundleVersion,
+		Campaign: campaign.Name,
+		Rules:    make([]BundleRule, 0, len(campaignRules)),
+	}
+	rewardNames := make(map[pgtype.UUID]string)
+	rewardName := func(id pgtype.UUID) (string, error) {
+		if name, ok := rewardNames[id]; ok {
+			return name, nil
+		}
+		rewardItem, err := b.queries.GetRewardByID(ctx, db.GetRewardByIDParams{
+			ID:       id,
+			TenantID: tenantID,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to get reward: %w", err)
+		}
+		rewardNames[id] = rewardItem.Name
+		return rewardItem.Name, nil
+	}
+
+	for _, r := range campaignRules {
+		name, err := rewardName(r.RewardID)
+		if err != nil {
+			return nil, err
+		}
+
+		var conditions map[string]interface{}
+		if err := json.Unmarshal(r.Conditions, &conditions); err != nil {
+			return nil, fmt.Errorf("failed to decode conditions of rule %q: %w", r.Name, err)
+		}
+
+		bundleRule := BundleRule{
+			Name:        r.Name,
+			EventType:   r.EventType,
+			Conditions:  conditions,
+			Reward:      name,
+			Quantity:    int(r.Quantity),
+			PerUserCap:  int(r.PerUserCap),
+			CoolDownSec: int(r.CoolDownSec),
+			Active:      r.Active,
+		}
+		if r.GlobalCap.Valid {
+			globalCap := int(r.GlobalCap.Int32)
+			bundleRule.GlobalCap = &globalCap
+		}
+
+		additional, err := rules.RuleActions(ctx, b.queries, r)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range additional[1:] {
+			name, err := rewardName(a.RewardID)
+			if err != nil {
+				return nil, err
+			}
+			bundleRule.AdditionalRewards = append(bundleRule.AdditionalRewards, BundleReward{
+				Reward:   name,
+				Quantity: int(a.Quantity),
+			})
+		}
+		bundle.Rules = append(bundle.Rules, bundleRule)
+	}
+	return bundle, nil
+}
+
+// Import creates the bundle's rules in the campaign, all or none. Rewards are
+// matched by name. Rules keep the bundle's active flag unless inactive is
+// set, e.g. for tenants that require approval. The campaign may not already
+// have rules with the bundle's names.
+func (b *Bundler) Import(ctx context.Context, tenantID, campaignID pgtype.UUID, bundle *Bundle, inactive bool) ([]db.Rule, error) {
+	if err := bundle.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := b.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := b.queries.WithTx(tx)
+	if _, err := qtx.GetCampaignByID(ctx, db.GetCampaignByIDParams{
+		ID:       campaignID,
+		TenantID: tenantID,
+	}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCampaignNotFound
+		}
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+
+	existing, err := qtx.ListRulesByCampaign(ctx, db.ListRulesByCampaignParams{
+		TenantID:   tenantID,
+		CampaignID: campaignID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaign rules: %w", err)
+	}
+	if taken := takenNames(bundle, existing); len(taken) > 0 {
+		return nil, fmt.Errorf("%w %s", ErrRuleNamesTaken, strings.Join(taken, ", "))
+	}
+
+	rewardIDs := make(map[string]pgtype.UUID)
+	var unknown []string
+	for _, r := range bundle.Rules {
+		for _, name := range r.RewardNames() {
+			if _, ok := rewardIDs[name]; ok {
+				continue
+			}
+			rewardItem, err := qtx.GetRewardByName(ctx, db.GetRewardByNameParams{
+				TenantID: tenantID,
+				Name:     name,
+			})
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					unknown = append(unknown, name)
+					rewardIDs[name] = pgtype.UUID{}
+					continue
+				}
+				return nil, fmt.Errorf("failed to get reward: %w", err)
+			}
+			rewardIDs[name] = rewardItem.ID
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRewards, strings.Join(unknown, ", "))
+	}
+
+	created := make([]db.Rule, 0, len(bundle.Rules))
+	for _, r := range bundle.Rules {
+		conditions, err := json.Marshal(r.Conditions)
+		if err != nil {
+			return nil, fmt.Errorf("%w: rule %q has invalid conditions", ErrInvalidBundle, r.Name)
+		}
+
+		var globalCap pgtype.Int4
+		if r.GlobalCap != nil {
+			globalCap = pgtype.Int4{Int32: int32(*r.GlobalCap), Valid: true}
+		}
+
+		rule, err := qtx.CreateRule(ctx, db.CreateRuleParams{
+			TenantID:    tenantID,
+			CampaignID:  campaignID,
+			Name:        r.Name,
+			EventType:   r.EventType,
+			Conditions:  conditions,
+			RewardID:    rewardIDs[r.Reward],
+			PerUserCap:  int32(r.PerUserCap),
+			GlobalCap:   globalCap,
+			CoolDownSec: int32(r.CoolDownSec),
+			Active:      r.Active && !inactive,
+			Quantity:    r.RewardQuantity(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create rule %q: %w", r.Name, err)
+		}
+		if err := SetAdditionalRewards(ctx, qtx, tenantID, rule.ID, r.Actions(rewardIDs)); err != nil {
+			return nil, err
+		}
+		created = append(created, rule)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return created, nil
+}
+
+// takenNames returns the bundle's rule names the campaign already uses
+func takenNames(bundle *Bundle, existing []db.Rule) []string {
+	names := make(map[string]bool, len(existing))
+	for _, r := range existing {
+		names[r.Name] = true
+	}
+
+	var taken []string
+	for _, r := range bundle.Rules {
+		if names[r.Name] {
+			taken = append(taken, r.Name)
+		}
+	}
+	return taken
+}
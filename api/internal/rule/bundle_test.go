@@ -0,0 +1,132 @@
+package rule
+
+import (
+	"testing"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/rules"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBundle() *Bundle {
+	globalCap := 500
+	return &Bundle{
+		Version:  BundleVersion,
+		Campaign: "Summer",
+		Rules: []BundleRule{
+			{
+				Name:       "Big basket",
+				EventType:  "purchase",
+				Conditions: map[string]interface{}{">=": []interface{}{map[string]interface{}{"var": "amount"}, 50}},
+				Reward:     "USD 5 voucher",
+				PerUserCap: 1,
+				GlobalCap:  &globalCap,
+				Active:     true,
+			},
+		},
+	}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			data, err := MarshalBundle(testBundle(), format)
+			require.NoError(t, err)
+
+			decoded, err := UnmarshalBundle(data, format)
+			require.NoError(t, err)
+			require.NoError(t, decoded.Validate())
+
+			assert.Equal(t, "Summer", decoded.Campaign)
+			require.Len(t, decoded.Rules, 1)
+			assert.Equal(t, "USD 5 voucher", decoded.Rules[0].Reward)
+			assert.Equal(t, 500, *decoded.Rules[0].GlobalCap)
+			assert.Contains(t, decoded.Rules[0].Conditions, ">=")
+		})
+	}
+}
+
+func TestUnmarshalBundleYAML(t *testing.T) {
+	data := []byte(`
+version: 1
+campaign: Summer
+rules:
+  - name: Any purchase
+    event_type: purchase
+    conditions: {}
+    reward: Free coffee
+    per_user_cap: 2
+    cool_down_sec: 3600
+    active: false
+`)
+
+	bundle, err := UnmarshalBundle(data, FormatYAML)
+	require.NoError(t, err)
+	require.NoError(t, bundle.Validate())
+	assert.Equal(t, 2, bundle.Rules[0].PerUserCap)
+	assert.Equal(t, 3600, bundle.Rules[0].CoolDownSec)
+	assert.Nil(t, bundle.Rules[0].GlobalCap)
+}
+
+func TestUnmarshalBundleInvalid(t *testing.T) {
+	_, err := UnmarshalBundle([]byte("{"), FormatJSON)
+	assert.ErrorIs(t, err, ErrInvalidBundle)
+
+	_, err = UnmarshalBundle([]byte("{}"), "xml")
+	assert.Error(t, err)
+}
+
+func TestBundleValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*Bundle)
+	}{
+		{"unsupported version", func(b *Bundle) { b.Version = 2 }},
+		{"no rules", func(b *Bundle) { b.Rules = nil }},
+		{"missing name", func(b *Bundle) { b.Rules[0].Name = " " }},
+		{"duplicate name", func(b *Bundle) { b.Rules = append(b.Rules, b.Rules[0]) }},
+		{"missing event type", func(b *Bundle) { b.Rules[0].EventType = "" }},
+		{"missing conditions", func(b *Bundle) { b.Rules[0].Conditions = nil }},
+		{"missing reward", func(b *Bundle) { b.Rules[0].Reward = "" }},
+		{"negative cap", func(b *Bundle) { b.Rules[0].PerUserCap = -1 }},
+		{"quantity too large", func(b *Bundle) { b.Rules[0].Quantity = MaxRewardQuantity + 1 }},
+		{"additional reward without name", func(b *Bundle) {
+			b.Rules[0].AdditionalRewards = []BundleReward{{Quantity: 2}}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle := testBundle()
+			tt.mutate(bundle)
+			assert.ErrorIs(t, bundle.Validate(), ErrInvalidBundle)
+		})
+	}
+}
+
+func TestBundleRuleActions(t *testing.T) {
+	voucher := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	points := pgtype.UUID{Bytes: [16]byte{2}, Valid: true}
+	r := BundleRule{
+		Reward: "USD 5 voucher",
+		AdditionalRewards: []BundleReward{
+			{Reward: "100 points"},
+			{Reward: "USD 5 voucher", Quantity: 2},
+		},
+	}
+
+	assert.Equal(t, int32(1), r.RewardQuantity())
+	assert.Equal(t, []string{"USD 5 voucher", "100 points", "USD 5 voucher"}, r.RewardNames())
+	assert.Equal(t, []rules.RewardAction{
+		{RewardID: points, Quantity: 1},
+		{RewardID: voucher, Quantity: 2},
+	}, r.Actions(map[string]pgtype.UUID{"USD 5 voucher": voucher, "100 points": points}))
+}
+
+func TestTakenNames(t *testing.T) {
+	existing := []db.Rule{{Name: "Big basket"}, {Name: "Other"}}
+	assert.Equal(t, []string{"Big basket"}, takenNames(testBundle(), existing))
+	assert.Empty(t, takenNames(testBundle(), nil))
+}
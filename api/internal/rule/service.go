@@ -2,30 +2,41 @@ package rule
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/bmachimbira/loyalty/api/internal/db"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrRuleNotFound is returned when the rule does not exist
+	ErrRuleNotFound = errors.New("rule not found")
+
+	// ErrRuleArchived is returned when an archived rule is activated
+	ErrRuleArchived = errors.New("rule is archived")
+
+	// ErrRuleInActiveCampaign is returned when archiving an active rule of an
+	// active campaign
+	ErrRuleInActiveCampaign = errors.New("rule is live in an active campaign; deactivate it first")
 )
 
 // Service handles rule-related business logic
 type Service struct {
+	pool    *pgxpool.Pool
 	queries *db.Queries
 }
 
 // NewService creates a new rule service
-func NewService(queries *db.Queries) *Service {
+func NewService(pool *pgxpool.Pool, queries *db.Queries) *Service {
 	return &Service{
+		pool:    pool,
 		queries: queries,
 	}
 }
 
-// CreateRule creates a new rule
-func (s *Service) CreateRule(ctx context.Context, params db.CreateRuleParams) (db.Rule, error) {
-	return s.queries.CreateRule(ctx, params)
-}
-
 // GetRuleByID retrieves a rule by ID
 func (s *Service) GetRuleByID(ctx context.Context, id, tenantID pgtype.UUID) (db.Rule, error) {
 	rule, err := s.queries.GetRuleByID(ctx, db.GetRuleByIDParams{
@@ -34,25 +45,38 @@ func (s *Service) GetRuleByID(ctx context.Context, id, tenantID pgtype.UUID) (db
 	})
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return db.Rule{}, fmt.Errorf("rule not found")
+			return db.Rule{}, ErrRuleNotFound
 		}
 		return db.Rule{}, fmt.Errorf("failed to get rule: %w", err)
 	}
 	return rule, nil
 }
 
-// ListRules retrieves all rules for a tenant
-func (s *Service) ListRules(ctx context.Context, tenantID pgtype.UUID, activeOnly bool) ([]db.Rule, error) {
+// ListRules retrieves the rules for a tenant. Archived rules are only listed
+// with includeArchived and never with activeOnly.
+func (s *Service) ListRules(ctx context.Context, tenantID pgtype.UUID, activeOnly, includeArchived bool) ([]db.Rule, error) {
 	if activeOnly {
 		return s.queries.ListActiveRules(ctx, tenantID)
 	}
-	// If we need all rules, we'd need a ListAllRules query
-	// For now, just return active rules
-	return s.queries.ListActiveRules(ctx, tenantID)
+	return s.queries.ListRules(ctx, db.ListRulesParams{
+		TenantID:        tenantID,
+		IncludeArchived: includeArchived,
+	})
 }
 
-// UpdateRuleStatus updates the active status of a rule
+// UpdateRuleStatus updates the active status of a rule. Archived rules must
+// be restored before they are activated.
 func (s *Service) UpdateRuleStatus(ctx context.Context, id, tenantID pgtype.UUID, active bool) error {
+	if active {
+		rule, err := s.GetRuleByID(ctx, id, tenantID)
+		if err != nil {
+			return err
+		}
+		if rule.ArchivedAt.Valid {
+			return ErrRuleArchived
+		}
+	}
+
 	err := s.queries.UpdateRuleStatus(ctx, db.UpdateRuleStatusParams{
 		ID:       id,
 		TenantID: tenantID,
@@ -67,7 +91,53 @@ func (s *Service) UpdateRuleStatus(ctx context.Context, id, tenantID pgtype.UUID
 	return nil
 }
 
-// DeactivateRule deactivates a rule (soft delete)
+// DeactivateRule deactivates a rule without archiving it
 func (s *Service) DeactivateRule(ctx context.Context, id, tenantID pgtype.UUID) error {
 	return s.UpdateRuleStatus(ctx, id, tenantID, false)
 }
+
+// ArchiveRule deactivates a rule and hides it from rule lists. Rules that
+// are live in an active campaign must be deactivated first. Archiving an
+// archived rule is a no-op.
+func (s *Service) ArchiveRule(ctx context.Context, id, tenantID pgtype.UUID) (db.Rule, error) {
+	rule, err := s.GetRuleByID(ctx, id, tenantID)
+	if err != nil {
+		return db.Rule{}, err
+	}
+	if rule.ArchivedAt.Valid {
+		return rule, nil
+	}
+
+	if rule.Active && rule.CampaignID.Valid {
+		campaign, err := s.queries.GetCampaignByID(ctx, db.GetCampaignByIDParams{
+			ID:       rule.CampaignID,
+			TenantID: tenantID,
+		})
+		if err != nil {
+			return db.Rule{}, fmt.Errorf("failed to get campaign: %w", err)
+		}
+		if campaign.Status == "active" && !campaign.ArchivedAt.Valid {
+			return db.Rule{}, ErrRuleInActiveCampaign
+		}
+	}
+
+	if _, err := s.queries.ArchiveRule(ctx, db.ArchiveRuleParams{
+		ID:       id,
+		TenantID: tenantID,
+	}); err != nil {
+		return db.Rule{}, fmt.Errorf("failed to archive rule: %w", err)
+	}
+	return s.GetRuleByID(ctx, id, tenantID)
+}
+
+// RestoreRule makes an archived rule visible again. The rule stays inactive
+// until it is activated.
+func (s *Service) RestoreRule(ctx context.Context, id, tenantID pgtype.UUID) (db.Rule, error) {
+	if _, err := s.queries.RestoreRule(ctx, db.RestoreRuleParams{
+		ID:       id,
+		TenantID: tenantID,
+	}); err != nil {
+		return db.Rule{}, fmt.Errorf("failed to restore rule: %w", err)
+	}
+	return s.GetRuleByID(ctx, id, tenantID)
+}
@@ -0,0 +1,35 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// RewardAction is one reward of a rule's action set and how many units of
+// it each trigger issues
+type RewardAction struct {
+	RewardID pgtype.UUID
+	Quantity int32
+}
+
+// RuleActions returns what one trigger of the rule issues: its own reward,
+// then its additional rewards in order
+func RuleActions(ctx context.Context, q *db.Queries, rule db.Rule) ([]RewardAction, error) {
+	additional, err := q.ListRuleRewards(ctx, db.ListRuleRewardsParams{
+		TenantID: rule.TenantID,
+		RuleID:   rule.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rule rewards: %w", err)
+	}
+
+	actions := make([]RewardAction, 0, 1+len(additional))
+	actions = append(actions, RewardAction{RewardID: rule.RewardID, Quantity: max(rule.Quantity, 1)})
+	for _, a := range additional {
+		actions = append(actions, RewardAction{RewardID: a.RewardID, Quantity: a.Quantity})
+	}
+	return actions, nil
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"testing"
 	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
 )
 
 // BenchmarkSimpleRule benchmarks a simple comparison rule
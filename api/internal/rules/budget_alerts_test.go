@@ -0,0 +1,154 @@
+package rules
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmachimbira/loyalty/api/internal/budget"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/logging"
+)
+
+// recordingNotifier collects the budget alerts delivered to it
+type recordingNotifier struct {
+	mu     sync.Mutex
+	alerts []budget.AlertType
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, destination string, alert budget.Alert) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.alerts = append(n.alerts, alert.Type)
+	return nil
+}
+
+func numeric(t *testing.T, s string) pgtype.Numeric {
+	t.Helper()
+	var n pgtype.Numeric
+	require.NoError(t, n.Scan(s))
+	return n
+}
+
+// TestProcessEventBudgetAlerts checks that issuing through the engine raises
+// the alerts of the budget it reserves from
+func TestProcessEventBudgetAlerts(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set, skipping integration tests")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	require.NoError(t, err)
+	defer pool.Close()
+	queries := db.New(pool)
+
+	tests := []struct {
+		name    string
+		softCap string
+		hardCap string
+		want    []budget.AlertType
+	}{
+		{"below thresholds", "50", "100", nil},
+		{"soft cap exceeded", "10", "100", []budget.AlertType{budget.AlertTypeSoftCap}},
+		{"hard cap approaching", "10", "20", []budget.AlertType{budget.AlertTypeSoftCap, budget.AlertTypeHardCap}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant, err := queries.CreateTenant(ctx, db.CreateTenantParams{
+				Name:        "Budget Alerts " + tt.name,
+				CountryCode: "ZW",
+				DefaultCcy:  "USD",
+				Theme:       []byte(`{}`),
+			})
+			require.NoError(t, err)
+
+			customer, err := queries.CreateCustomer(ctx, db.CreateCustomerParams{
+				TenantID:    tenant.ID,
+				ExternalRef: pgtype.Text{String: "alerts-customer", Valid: true},
+			})
+			require.NoError(t, err)
+
+			testBudget, err := queries.CreateBudget(ctx, db.CreateBudgetParams{
+				TenantID:        tenant.ID,
+				Name:            "Alerts Budget",
+				Currency:        "USD",
+				SoftCap:         numeric(t, tt.softCap),
+				HardCap:         numeric(t, tt.hardCap),
+				Balance:         numeric(t, "0"),
+				Period:          "rolling",
+				AlertWebhookUrl: pgtype.Text{String: "https://alerts.example.com", Valid: true},
+			})
+			require.NoError(t, err)
+
+			campaign, err := queries.CreateCampaign(ctx, db.CreateCampaignParams{
+				TenantID: tenant.ID,
+				Name:     "Alerts Campaign",
+				StartAt:  pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true},
+				BudgetID: testBudget.ID,
+				Status:   "active",
+			})
+			require.NoError(t, err)
+
+			rewardItem, err := queries.CreateReward(ctx, db.CreateRewardParams{
+				TenantID:  tenant.ID,
+				Name:      "$20 Voucher",
+				Type:      "discount",
+				FaceValue: numeric(t, "20"),
+				Currency:  pgtype.Text{String: "USD", Valid: true},
+				Inventory: "none",
+				Metadata:  []byte(`{}`),
+				Active:    true,
+			})
+			require.NoError(t, err)
+
+			_, err = queries.CreateRule(ctx, db.CreateRuleParams{
+				TenantID:   tenant.ID,
+				CampaignID: campaign.ID,
+				Name:       "Every purchase",
+				EventType:  "purchase",
+				Conditions: []byte(`{">=": [{"var": "amount"}, 1]}`),
+				RewardID:   rewardItem.ID,
+				PerUserCap: 10,
+				Active:     true,
+				Quantity:   1,
+			})
+			require.NoError(t, err)
+
+			event, err := queries.InsertEvent(ctx, db.InsertEventParams{
+				TenantID:       tenant.ID,
+				CustomerID:     customer.ID,
+				EventType:      "purchase",
+				Properties:     []byte(`{"amount": 25, "currency": "USD"}`),
+				OccurredAt:     pgtype.Timestamptz{Time: time.Now(), Valid: true},
+				Source:         "api",
+				IdempotencyKey: "budget-alerts-" + tt.name,
+			})
+			require.NoError(t, err)
+
+			notifier := &recordingNotifier{}
+			alerts := budget.NewService(pool, queries, nil)
+			alerts.RegisterAlertNotifier(budget.AlertChannelWebhook, notifier)
+
+			engine := NewEngine(pool, logging.New())
+			engine.SetBudgetAlerter(alerts)
+
+			issuances, err := engine.ProcessEvent(ctx, event)
+			require.NoError(t, err)
+			require.Len(t, issuances, 1)
+			assert.Equal(t, testBudget.ID, issuances[0].BudgetID)
+
+			alerts.WaitForAlerts()
+			assert.ElementsMatch(t, tt.want, notifier.alerts)
+		})
+	}
+}
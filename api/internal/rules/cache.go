@@ -102,3 +102,74 @@ func (c *RuleCache) cleanupExpired() {
 		c.mu.Unlock()
 	}
 }
+
+// customerCacheTTL is how long a customer's attributes are reused across
+// their events. A burst of events from one customer costs one lookup, at
+// the price of counts such as total_visits lagging by up to this long.
+const customerCacheTTL = 30 * time.Second
+
+// customerCache is a thread-safe in-memory cache of customers' rule
+// attributes, keyed by tenant and customer
+type customerCache struct {
+	mu    sync.RWMutex
+	items map[string]*customerCacheItem
+	ttl   time.Duration
+}
+
+// customerCacheItem represents a cached customer
+type customerCacheItem struct {
+	attributes db.GetCustomerRuleAttributesRow
+	expiresAt  time.Time
+}
+
+// newCustomerCache creates a new customer cache with the specified TTL
+func newCustomerCache(ttl time.Duration) *customerCache {
+	cache := &customerCache{
+		items: make(map[string]*customerCacheItem),
+		ttl:   ttl,
+	}
+
+	go cache.cleanupExpired()
+
+	return cache
+}
+
+// get retrieves a customer's attributes if cached and not expired
+func (c *customerCache) get(key string) (db.GetCustomerRuleAttributesRow, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	item, ok := c.items[key]
+	if !ok || time.Now().After(item.expiresAt) {
+		return db.GetCustomerRuleAttributesRow{}, false
+	}
+	return item.attributes, true
+}
+
+// set stores a customer's attributes with the configured TTL
+func (c *customerCache) set(key string, attributes db.GetCustomerRuleAttributesRow) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items[key] = &customerCacheItem{
+		attributes: attributes,
+		expiresAt:  time.Now().Add(c.ttl),
+	}
+}
+
+// cleanupExpired periodically removes expired entries from the cache
+func (c *customerCache) cleanupExpired() {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		c.mu.Lock()
+		now := time.Now()
+		for key, item := range c.items {
+			if now.After(item.expiresAt) {
+				delete(c.items, key)
+			}
+		}
+		c.mu.Unlock()
+	}
+}
@@ -5,15 +5,58 @@ import (
 	"fmt"
 
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+// capQuerier runs cap queries on the pool for the cheap pre-check, or on the
+// issuance transaction once its cap locks are held
+type capQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// capScope returns the ID issuances are counted under for a rule's caps. The
+// cap functions count per campaign, so rules sharing a campaign share their
+// caps; rules without a campaign are counted on their own.
+func capScope(rule db.Rule) pgtype.UUID {
+	if rule.CampaignID.Valid {
+		return rule.CampaignID
+	}
+	return rule.ID
+}
+
+// capLockKeys returns the advisory lock keys serialising issuances that
+// count against the same caps, in the order they must be acquired. The
+// customer lock always covers per-user caps, cooldowns and duplicate event
+// checks; the scope lock is only taken for global caps, which every
+// customer's issuance counts against.
+func capLockKeys(rule db.Rule, event db.Event) []int64 {
+	scope := capScope(rule)
+	keys := make([]int64, 0, 2)
+	if rule.GlobalCap.Valid && rule.GlobalCap.Int32 > 0 {
+		keys = append(keys, hashLock([]byte("global-cap"), event.TenantID.Bytes[:], scope.Bytes[:]))
+	}
+	keys = append(keys, hashLock(event.TenantID.Bytes[:], scope.Bytes[:], event.CustomerID.Bytes[:]))
+	return keys
+}
+
+// lockCaps acquires the rule's cap locks for the rest of tx. Keys are always
+// taken in capLockKeys order so concurrent issuances cannot deadlock.
+func lockCaps(ctx context.Context, tx pgx.Tx, rule db.Rule, event db.Event) error {
+	for _, key := range capLockKeys(rule, event) {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", key); err != nil {
+			return fmt.Errorf("failed to acquire advisory lock: %w", err)
+		}
+	}
+	return nil
+}
+
 // checkCaps verifies all cap constraints for a rule before issuance
 // Returns true if all checks pass, false otherwise
-func (e *Engine) checkCaps(ctx context.Context, rule db.Rule, event db.Event) (bool, error) {
+func (e *Engine) checkCaps(ctx context.Context, q capQuerier, rule db.Rule, event db.Event) (bool, error) {
 	// Check per-user cap
 	if rule.PerUserCap > 0 {
-		passed, err := e.checkPerUserCap(ctx, rule, event)
+		passed, err := e.checkPerUserCap(ctx, q, rule, event)
 		if err != nil {
 			return false, fmt.Errorf("per-user cap check failed: %w", err)
 		}
@@ -24,7 +67,7 @@ func (e *Engine) checkCaps(ctx context.Context, rule db.Rule, event db.Event) (b
 
 	// Check global cap
 	if rule.GlobalCap.Valid && rule.GlobalCap.Int32 > 0 {
-		passed, err := e.checkGlobalCap(ctx, rule, event)
+		passed, err := e.checkGlobalCap(ctx, q, rule, event)
 		if err != nil {
 			return false, fmt.Errorf("global cap check failed: %w", err)
 		}
@@ -35,7 +78,7 @@ func (e *Engine) checkCaps(ctx context.Context, rule db.Rule, event db.Event) (b
 
 	// Check cooldown period
 	if rule.CoolDownSec > 0 {
-		passed, err := e.checkCooldown(ctx, rule, event)
+		passed, err := e.checkCooldown(ctx, q, rule, event)
 		if err != nil {
 			return false, fmt.Errorf("cooldown check failed: %w", err)
 		}
@@ -48,12 +91,12 @@ func (e *Engine) checkCaps(ctx context.Context, rule db.Rule, event db.Event) (b
 }
 
 // checkPerUserCap verifies the per-user issuance cap
-func (e *Engine) checkPerUserCap(ctx context.Context, rule db.Rule, event db.Event) (bool, error) {
+func (e *Engine) checkPerUserCap(ctx context.Context, q capQuerier, rule db.Rule, event db.Event) (bool, error) {
 	// Call database function to get customer issuance count
 	query := `SELECT get_customer_rule_issuance_count($1, $2, $3)`
 
 	var count int64
-	err := e.pool.QueryRow(ctx, query, event.TenantID, event.CustomerID, rule.ID).Scan(&count)
+	err := q.QueryRow(ctx, query, event.TenantID, event.CustomerID, rule.ID).Scan(&count)
 	if err != nil {
 		return false, fmt.Errorf("failed to get customer issuance count: %w", err)
 	}
@@ -63,12 +106,12 @@ func (e *Engine) checkPerUserCap(ctx context.Context, rule db.Rule, event db.Eve
 }
 
 // checkGlobalCap verifies the global issuance cap for the rule
-func (e *Engine) checkGlobalCap(ctx context.Context, rule db.Rule, event db.Event) (bool, error) {
+func (e *Engine) checkGlobalCap(ctx context.Context, q capQuerier, rule db.Rule, event db.Event) (bool, error) {
 	// Call database function to get global issuance count
 	query := `SELECT get_rule_global_issuance_count($1, $2)`
 
 	var count int64
-	err := e.pool.QueryRow(ctx, query, event.TenantID, rule.ID).Scan(&count)
+	err := q.QueryRow(ctx, query, event.TenantID, rule.ID).Scan(&count)
 	if err != nil {
 		return false, fmt.Errorf("failed to get global issuance count: %w", err)
 	}
@@ -78,12 +121,12 @@ func (e *Engine) checkGlobalCap(ctx context.Context, rule db.Rule, event db.Even
 }
 
 // checkCooldown verifies the customer is not within the cooldown period
-func (e *Engine) checkCooldown(ctx context.Context, rule db.Rule, event db.Event) (bool, error) {
+func (e *Engine) checkCooldown(ctx context.Context, q capQuerier, rule db.Rule, event db.Event) (bool, error) {
 	// Call database function to check if within cooldown
 	query := `SELECT is_within_cooldown($1, $2, $3, $4)`
 
 	var withinCooldown bool
-	err := e.pool.QueryRow(ctx, query, event.TenantID, event.CustomerID, rule.ID, rule.CoolDownSec).Scan(&withinCooldown)
+	err := q.QueryRow(ctx, query, event.TenantID, event.CustomerID, rule.ID, rule.CoolDownSec).Scan(&withinCooldown)
 	if err != nil {
 		return false, fmt.Errorf("failed to check cooldown: %w", err)
 	}
@@ -94,7 +137,7 @@ func (e *Engine) checkCooldown(ctx context.Context, rule db.Rule, event db.Event
 
 // getCampaignByID retrieves campaign details
 func (e *Engine) getCampaignByID(ctx context.Context, tenantID, campaignID pgtype.UUID) (*db.Campaign, error) {
-	campaign, err := e.queries.GetCampaignByID(ctx, db.GetCampaignByIDParams{
+	campaign, err := e.catalog.GetCampaignByID(ctx, db.GetCampaignByIDParams{
 		TenantID: tenantID,
 		ID:       campaignID,
 	})
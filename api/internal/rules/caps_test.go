@@ -0,0 +1,192 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/logging"
+)
+
+// TestConcurrentCaps checks that caps hold when many events are processed
+// at once, which needs the cap checks serialised by the advisory locks
+func TestConcurrentCaps(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set, skipping integration tests")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	require.NoError(t, err)
+	defer pool.Close()
+	queries := db.New(pool)
+	engine := NewEngine(pool, logging.New())
+
+	// setup creates a tenant with a campaign funded well beyond the caps and
+	// a reward for its rules to issue
+	setup := func(t *testing.T) (db.Tenant, db.Campaign, db.RewardCatalog) {
+		tenant, err := queries.CreateTenant(ctx, db.CreateTenantParams{
+			Name:        "Concurrent Caps",
+			CountryCode: "ZW",
+			DefaultCcy:  "USD",
+			Theme:       []byte(`{}`),
+		})
+		require.NoError(t, err)
+
+		budget, err := queries.CreateBudget(ctx, db.CreateBudgetParams{
+			TenantID: tenant.ID,
+			Name:     "Stress Budget",
+			Currency: "USD",
+			SoftCap:  numeric(t, "10000"),
+			HardCap:  numeric(t, "10000"),
+			Balance:  numeric(t, "0"),
+			Period:   "rolling",
+		})
+		require.NoError(t, err)
+
+		campaign, err := queries.CreateCampaign(ctx, db.CreateCampaignParams{
+			TenantID: tenant.ID,
+			Name:     "Stress Campaign",
+			StartAt:  pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true},
+			BudgetID: budget.ID,
+			Status:   "active",
+		})
+		require.NoError(t, err)
+
+		rewardItem, err := queries.CreateReward(ctx, db.CreateRewardParams{
+			TenantID:  tenant.ID,
+			Name:      "$5 Voucher",
+			Type:      "discount",
+			FaceValue: numeric(t, "5"),
+			Currency:  pgtype.Text{String: "USD", Valid: true},
+			Inventory: "none",
+			Metadata:  []byte(`{}`),
+			Active:    true,
+		})
+		require.NoError(t, err)
+
+		return tenant, campaign, rewardItem
+	}
+	newRule := func(t *testing.T, tenantID, campaignID, rewardID pgtype.UUID, name string, perUserCap int32, globalCap pgtype.Int4) {
+		_, err := queries.CreateRule(ctx, db.CreateRuleParams{
+			TenantID:   tenantID,
+			CampaignID: campaignID,
+			Name:       name,
+			EventType:  "purchase",
+			Conditions: []byte(`{">=": [{"var": "amount"}, 1]}`),
+			RewardID:   rewardID,
+			PerUserCap: perUserCap,
+			GlobalCap:  globalCap,
+			Active:     true,
+			Quantity:   1,
+		})
+		require.NoError(t, err)
+	}
+	newCustomer := func(t *testing.T, tenantID pgtype.UUID) pgtype.UUID {
+		customer, err := queries.CreateCustomer(ctx, db.CreateCustomerParams{
+			TenantID:    tenantID,
+			ExternalRef: pgtype.Text{String: uuid.NewString(), Valid: true},
+		})
+		require.NoError(t, err)
+		return customer.ID
+	}
+	newEvent := func(t *testing.T, tenantID, customerID pgtype.UUID) db.Event {
+		event, err := queries.InsertEvent(ctx, db.InsertEventParams{
+			TenantID:       tenantID,
+			CustomerID:     customerID,
+			EventType:      "purchase",
+			Properties:     []byte(`{"amount": 25, "currency": "USD"}`),
+			OccurredAt:     pgtype.Timestamptz{Time: time.Now(), Valid: true},
+			Source:         "api",
+			IdempotencyKey: uuid.NewString(),
+		})
+		require.NoError(t, err)
+		return event
+	}
+
+	t.Run("per-user cap", func(t *testing.T) {
+		tenant, campaign, rewardItem := setup(t)
+		newRule(t, tenant.ID, campaign.ID, rewardItem.ID, "Per-user cap", 5, pgtype.Int4{})
+
+		// 100 events from the same customer
+		customerID := newCustomer(t, tenant.ID)
+		events := make([]db.Event, 100)
+		for i := range events {
+			events[i] = newEvent(t, tenant.ID, customerID)
+		}
+
+		assert.Equal(t, 5, processConcurrently(t, engine, events), "Expected exactly 5 issuances due to per-user cap")
+	})
+
+	t.Run("global cap", func(t *testing.T) {
+		tenant, campaign, rewardItem := setup(t)
+		newRule(t, tenant.ID, campaign.ID, rewardItem.ID, "Global cap", 100, pgtype.Int4{Int32: 10, Valid: true})
+
+		// 100 events from different customers, so only the global cap
+		// limits issuance
+		events := make([]db.Event, 100)
+		for i := range events {
+			events[i] = newEvent(t, tenant.ID, newCustomer(t, tenant.ID))
+		}
+
+		assert.Equal(t, 10, processConcurrently(t, engine, events), "Expected exactly 10 issuances due to global cap")
+	})
+
+	t.Run("campaign cap", func(t *testing.T) {
+		tenant, campaign, rewardItem := setup(t)
+		// Global caps count the campaign's issuances, so both rules
+		// together must stop at 10
+		for i := 0; i < 2; i++ {
+			newRule(t, tenant.ID, campaign.ID, rewardItem.ID, fmt.Sprintf("Campaign cap rule %d", i), 100, pgtype.Int4{Int32: 10, Valid: true})
+		}
+
+		events := make([]db.Event, 100)
+		for i := range events {
+			events[i] = newEvent(t, tenant.ID, newCustomer(t, tenant.ID))
+		}
+
+		assert.Equal(t, 10, processConcurrently(t, engine, events), "Expected exactly 10 issuances across the campaign's rules")
+	})
+}
+
+// processConcurrently processes all events at once and returns the number of
+// issuances created
+func processConcurrently(t *testing.T, engine *Engine, events []db.Event) int {
+	ctx := context.Background()
+	results := make(chan []db.Issuance, len(events))
+	errors := make(chan error, len(events))
+
+	for _, event := range events {
+		go func(event db.Event) {
+			issuances, err := engine.ProcessEvent(ctx, event)
+			if err != nil {
+				errors <- err
+				return
+			}
+			results <- issuances
+		}(event)
+	}
+
+	totalIssuances := 0
+	for range events {
+		select {
+		case err := <-errors:
+			require.NoError(t, err)
+		case issuances := <-results:
+			totalIssuances += len(issuances)
+		case <-time.After(30 * time.Second):
+			t.Fatal("Test timed out")
+		}
+	}
+	return totalIssuances
+}
@@ -2,8 +2,11 @@ package rules
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/bmachimbira/loyalty/api/internal/timezone"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -49,6 +52,44 @@ func (c *CustomOperators) NthEventInPeriod(
 	return count == int64(n), nil
 }
 
+// EventBetween checks if the customer has an event of a type that occurred
+// from from to to inclusive, other than at except
+func (c *CustomOperators) EventBetween(
+	ctx context.Context,
+	tenantID, customerID string,
+	eventType string,
+	from, to, except time.Time,
+) (bool, error) {
+	var tenantUUID, customerUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		return false, err
+	}
+	if err := customerUUID.Scan(customerID); err != nil {
+		return false, err
+	}
+
+	// Served by idx_events_tenant_customer_type_occurred
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM events
+			WHERE tenant_id = $1
+			  AND customer_id = $2
+			  AND event_type = $3
+			  AND occurred_at BETWEEN $4 AND $5
+			  AND occurred_at <> $6
+		)
+	`
+
+	var found bool
+	err := c.pool.QueryRow(ctx, query, tenantUUID, customerUUID, eventType, from, to, except).Scan(&found)
+	if err != nil {
+		return false, err
+	}
+
+	return found, nil
+}
+
 // DistinctVisitDays counts the number of distinct days with visit events
 func (c *CustomOperators) DistinctVisitDays(
 	ctx context.Context,
@@ -76,6 +117,141 @@ func (c *CustomOperators) DistinctVisitDays(
 	return int(count), nil
 }
 
+// LocationRegion returns the region of a tenant's location, or "" if the
+// location has no region
+func (c *CustomOperators) LocationRegion(ctx context.Context, tenantID, locationID string) (string, error) {
+	var tenantUUID, locationUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		return "", err
+	}
+	if err := locationUUID.Scan(locationID); err != nil {
+		return "", err
+	}
+
+	query := `
+		SELECT COALESCE(trim(region), '')
+		FROM locations
+		WHERE tenant_id = $1
+		  AND id = $2
+	`
+
+	var region string
+	err := c.pool.QueryRow(ctx, query, tenantUUID, locationUUID).Scan(&region)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return region, nil
+}
+
+// SKUInCategory checks if any of the SKUs is an active product in one of the
+// categories. Categories must be lowercase.
+func (c *CustomOperators) SKUInCategory(ctx context.Context, tenantID string, skus, categories []string) (bool, error) {
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		return false, err
+	}
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM products
+			WHERE tenant_id = $1
+			  AND sku = ANY($2)
+			  AND lower(category) = ANY($3)
+			  AND active = true
+		)
+	`
+
+	var found bool
+	err := c.pool.QueryRow(ctx, query, tenantUUID, skus, categories).Scan(&found)
+	if err != nil {
+		return false, err
+	}
+
+	return found, nil
+}
+
+// InWelcomeWindow checks if at is within the bonus window of the customer's
+// welcome series. Customers enrolled while the series was off have none.
+func (c *CustomOperators) InWelcomeWindow(ctx context.Context, tenantID, customerID string, at time.Time) (bool, error) {
+	var tenantUUID, customerUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		return false, err
+	}
+	if err := customerUUID.Scan(customerID); err != nil {
+		return false, err
+	}
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM welcome_series
+			WHERE tenant_id = $1
+			  AND customer_id = $2
+			  AND enrolled_at <= $3
+			  AND bonus_ends_at > $3
+		)
+	`
+
+	var found bool
+	err := c.pool.QueryRow(ctx, query, tenantUUID, customerUUID, at).Scan(&found)
+	if err != nil {
+		return false, err
+	}
+
+	return found, nil
+}
+
+// LocationCoordinates returns the coordinates of a tenant's location, looked
+// up by ID or code. ok is false if the location doesn't exist or has no
+// coordinates.
+func (c *CustomOperators) LocationCoordinates(ctx context.Context, tenantID, location string) (lat, lng float64, ok bool, err error) {
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		return 0, 0, false, err
+	}
+
+	query := `
+		SELECT latitude::float8, longitude::float8
+		FROM locations
+		WHERE tenant_id = $1
+		  AND (id::text = $2 OR code = $2)
+		  AND latitude IS NOT NULL
+		  AND longitude IS NOT NULL
+		LIMIT 1
+	`
+
+	err = c.pool.QueryRow(ctx, query, tenantUUID, location).Scan(&lat, &lng)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, 0, false, nil
+		}
+		return 0, 0, false, err
+	}
+
+	return lat, lng, true, nil
+}
+
+// TenantLocation returns the time zone a tenant's local times are read in
+func (c *CustomOperators) TenantLocation(ctx context.Context, tenantID string) (*time.Location, error) {
+	var tenantUUID pgtype.UUID
+	if err := tenantUUID.Scan(tenantID); err != nil {
+		return nil, err
+	}
+
+	var zone string
+	err := c.pool.QueryRow(ctx, `SELECT timezone FROM tenants WHERE id = $1`, tenantUUID).Scan(&zone)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	return timezone.Load(zone), nil
+}
+
 // countEventsSince counts events of a type since a cutoff date
 func (c *CustomOperators) countEventsSince(
 	ctx context.Context,
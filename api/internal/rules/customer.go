@@ -0,0 +1,73 @@
+package rules
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+)
+
+// ReadsCustomer reports whether conditions read the customer object, so
+// the lookup can be skipped when they don't
+func ReadsCustomer(conditions []byte) bool {
+	return bytes.Contains(conditions, []byte(`"customer.`)) || bytes.Contains(conditions, []byte(`"customer"`))
+}
+
+// usesCustomer reports whether any of the rules' conditions read the
+// customer object
+func usesCustomer(rules []db.Rule) bool {
+	for _, rule := range rules {
+		if ReadsCustomer(rule.Conditions) {
+			return true
+		}
+	}
+	return false
+}
+
+// CustomerData builds the customer object rule conditions read as
+// customer.*: status, enrolled_at, enrolled_days (whole days from enrollment
+// to the event), flagged and total_visits
+func CustomerData(attributes db.GetCustomerRuleAttributesRow, occurredAt time.Time) map[string]interface{} {
+	enrolledDays := 0
+	if attributes.CreatedAt.Valid && occurredAt.After(attributes.CreatedAt.Time) {
+		enrolledDays = int(occurredAt.Sub(attributes.CreatedAt.Time).Hours() / 24)
+	}
+	return map[string]interface{}{
+		"status":        attributes.Status,
+		"enrolled_at":   attributes.CreatedAt.Time.UTC().Format(time.RFC3339),
+		"enrolled_days": enrolledDays,
+		"flagged":       attributes.FlaggedAt.Valid,
+		"total_visits":  attributes.TotalVisits,
+	}
+}
+
+// addCustomerData adds the event's customer object to data, from the cache
+// when it was looked up recently
+func (e *Engine) addCustomerData(ctx context.Context, event db.Event, data map[string]interface{}) error {
+	if !event.CustomerID.Valid {
+		return nil
+	}
+
+	key := uuidToString(event.TenantID) + ":" + uuidToString(event.CustomerID)
+	attributes, ok := e.customers.get(key)
+	if !ok {
+		var err error
+		attributes, err = e.queries.GetCustomerRuleAttributes(ctx, db.GetCustomerRuleAttributesParams{
+			TenantID: event.TenantID,
+			ID:       event.CustomerID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get customer attributes: %w", err)
+		}
+		e.customers.set(key, attributes)
+	}
+
+	occurredAt := time.Now()
+	if event.OccurredAt.Valid {
+		occurredAt = event.OccurredAt.Time
+	}
+	data["customer"] = CustomerData(attributes, occurredAt)
+	return nil
+}
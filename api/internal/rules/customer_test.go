@@ -0,0 +1,69 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestCustomerData(t *testing.T) {
+	enrolled := time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)
+	attributes := db.GetCustomerRuleAttributesRow{
+		Status:      "active",
+		CreatedAt:   pgtype.Timestamptz{Time: enrolled, Valid: true},
+		TotalVisits: 12,
+	}
+
+	customer := CustomerData(attributes, enrolled.Add(45*24*time.Hour+time.Hour))
+	if customer["enrolled_days"] != 45 {
+		t.Errorf("enrolled_days = %v, want 45", customer["enrolled_days"])
+	}
+	if customer["enrolled_at"] != "2025-11-01T12:00:00Z" {
+		t.Errorf("enrolled_at = %v", customer["enrolled_at"])
+	}
+	if customer["flagged"] != false {
+		t.Errorf("flagged = %v, want false", customer["flagged"])
+	}
+
+	// Conditions read the customer object like any other data
+	e := NewEvaluator(nil)
+	logic := json.RawMessage(`{"and": [
+		{">": [{"var": "customer.enrolled_days"}, 30]},
+		{">=": [{"var": "customer.total_visits"}, 10]},
+		{"==": [{"var": "customer.status"}, "active"]}
+	]}`)
+	result, err := e.Evaluate(context.Background(), logic, map[string]interface{}{"customer": customer})
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if !result {
+		t.Error("Evaluate() should match a customer enrolled 45 days ago with 12 visits")
+	}
+
+	// Events from before enrollment, such as imported history, are day 0
+	customer = CustomerData(attributes, enrolled.Add(-time.Hour))
+	if customer["enrolled_days"] != 0 {
+		t.Errorf("enrolled_days = %v, want 0", customer["enrolled_days"])
+	}
+}
+
+func TestReadsCustomer(t *testing.T) {
+	tests := []struct {
+		conditions string
+		want       bool
+	}{
+		{`{">": [{"var": "customer.enrolled_days"}, 30]}`, true},
+		{`{"!!": [{"var": "customer"}]}`, true},
+		{`{"==": [{"var": "customer_id"}, "abc"]}`, false},
+		{`{">=": [{"var": "amount"}, 20]}`, false},
+	}
+	for _, tt := range tests {
+		if got := ReadsCustomer([]byte(tt.conditions)); got != tt.want {
+			t.Errorf("ReadsCustomer(%s) = %v, want %v", tt.conditions, got, tt.want)
+		}
+	}
+}
@@ -3,11 +3,15 @@ package rules
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/bmachimbira/loyalty/api/internal/catalogcache"
 	"github.com/bmachimbira/loyalty/api/internal/db"
 	"github.com/bmachimbira/loyalty/api/internal/logging"
+	"github.com/bmachimbira/loyalty/api/internal/metering"
+	"github.com/bmachimbira/loyalty/api/internal/reward/value"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -18,9 +22,35 @@ type Engine struct {
 	queries   *db.Queries
 	evaluator *Evaluator
 	cache     *RuleCache
+	customers *customerCache
+	catalog   *catalogcache.Cache
+	meter     *metering.Meter
+	tracker   ChallengeTracker
+	draws     DrawEntrant
+	alerter   BudgetAlerter
 	logger    *logging.Logger
 }
 
+// ChallengeTracker records customers' progress on challenges as their events
+// are processed. Track returns the rewards granted for challenges the event
+// completed.
+type ChallengeTracker interface {
+	Track(ctx context.Context, event db.Event) ([]db.Issuance, error)
+}
+
+// DrawEntrant enters the customers of processed events into the prize draws
+// the events qualify for
+type DrawEntrant interface {
+	Enter(ctx context.Context, event db.Event) error
+}
+
+// BudgetAlerter alerts on budgets issuances reserved from that crossed their
+// soft or hard cap thresholds or went into overdraft. AlertReservation must
+// not block event processing.
+type BudgetAlerter interface {
+	AlertReservation(tenantID, budgetID pgtype.UUID)
+}
+
 // NewEngine creates a new rules engine
 func NewEngine(pool *pgxpool.Pool, logger *logging.Logger) *Engine {
 	queries := db.New(pool)
@@ -33,118 +63,381 @@ func NewEngine(pool *pgxpool.Pool, logger *logging.Logger) *Engine {
 		queries:   queries,
 		evaluator: evaluator,
 		cache:     cache,
+		customers: newCustomerCache(customerCacheTTL),
+		catalog:   catalogcache.New(queries, 5*time.Minute),
 		logger:    logger,
 	}
 }
 
-// ProcessEvent evaluates all matching rules for an event and issues rewards
+// SetCatalogCache replaces the engine's catalog cache so campaign lookups
+// share invalidation with the catalog and campaign APIs
+func (e *Engine) SetCatalogCache(catalog *catalogcache.Cache) {
+	e.catalog = catalog
+}
+
+// SetMeter meters created issuances for tenant billing
+func (e *Engine) SetMeter(meter *metering.Meter) {
+	e.meter = meter
+}
+
+// SetChallengeTracker tracks challenge progress of processed events
+func (e *Engine) SetChallengeTracker(tracker ChallengeTracker) {
+	e.tracker = tracker
+}
+
+// SetDrawEntrant enters processed events into prize draws
+func (e *Engine) SetDrawEntrant(draws DrawEntrant) {
+	e.draws = draws
+}
+
+// SetBudgetAlerter alerts on the budgets issuances reserved from
+func (e *Engine) SetBudgetAlerter(alerter BudgetAlerter) {
+	e.alerter = alerter
+}
+
+// ProcessResult is the outcome of processing an event
+type ProcessResult struct {
+	// Issuances are the event's issuances, including those returned as they
+	// are for rules an earlier processing of the event already triggered
+	Issuances []db.Issuance
+	// Created counts the issuances this processing created
+	Created int
+	// Trace is how each rule matching the event's type was handled
+	Trace []RuleTrace
+}
+
+// RuleOutcome is how a rule was handled for an event
+type RuleOutcome string
+
+// Rule outcomes recorded in an event's evaluation trace
+const (
+	OutcomeAlreadyTriggered RuleOutcome = "already_triggered"
+	OutcomeEvaluationError  RuleOutcome = "evaluation_error"
+	OutcomeNotMatched       RuleOutcome = "not_matched"
+	OutcomeExcluded         RuleOutcome = "excluded"
+	OutcomeCapExceeded      RuleOutcome = "cap_exceeded"
+	OutcomePacingExceeded   RuleOutcome = "pacing_exceeded"
+	OutcomeNoValue          RuleOutcome = "no_value"
+	OutcomeIssueError       RuleOutcome = "issue_error"
+	OutcomeIssued           RuleOutcome = "issued"
+)
+
+// RuleTrace records a rule's outcome in an event's evaluation
+type RuleTrace struct {
+	RuleID     pgtype.UUID
+	CampaignID pgtype.UUID
+	Outcome    RuleOutcome
+}
+
+// Excluded returns the rules the event's customer was excluded from by the
+// rules' campaigns
+func (r ProcessResult) Excluded() []RuleTrace {
+	var excluded []RuleTrace
+	for _, trace := range r.Trace {
+		if trace.Outcome == OutcomeExcluded {
+			excluded = append(excluded, trace)
+		}
+	}
+	return excluded
+}
+
+// ProcessEvent evaluates all matching rules for an event and issues rewards,
+// then tracks the event's challenge progress and enters it into draws
 func (e *Engine) ProcessEvent(ctx context.Context, event db.Event) ([]db.Issuance, error) {
+	result, err := e.Process(ctx, event)
+	return result.Issuances, err
+}
+
+// Process processes an event like ProcessEvent, also reporting how many of
+// its issuances it created, e.g. for replays to tell new rewards from ones
+// the event was already given
+func (e *Engine) Process(ctx context.Context, event db.Event) (ProcessResult, error) {
+	result, err := e.processRules(ctx, event)
+	if err != nil || event.EventType == EventTypeReversal {
+		return result, err
+	}
+
+	// Tracking and draw entry are idempotent per event, so a failed event
+	// can be retried. Challenges only complete, and grant rewards, the
+	// first time an event counts towards them.
+	if e.tracker != nil {
+		completed, err := e.tracker.Track(ctx, event)
+		if err != nil {
+			return result, fmt.Errorf("failed to track challenges: %w", err)
+		}
+		result.Issuances = append(result.Issuances, completed...)
+		result.Created += len(completed)
+	}
+	if e.draws != nil {
+		if err := e.draws.Enter(ctx, event); err != nil {
+			return result, fmt.Errorf("failed to enter draws: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// processRules evaluates all matching rules for an event and issues rewards.
+// It returns the event's issuances, how many of them it created and each
+// rule's outcome.
+func (e *Engine) processRules(ctx context.Context, event db.Event) (ProcessResult, error) {
 	startTime := time.Now()
+	logger := e.logger.WithContext(ctx)
+
+	// Reversals undo the rewards of an earlier event instead of running rules
+	if event.EventType == EventTypeReversal {
+		reversed, err := e.reverseEvent(ctx, event)
+		return ProcessResult{Issuances: reversed}, err
+	}
 
 	// Get active rules for this event type
 	rules, err := e.getMatchingRules(ctx, event)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get matching rules: %w", err)
+		return ProcessResult{}, fmt.Errorf("failed to get matching rules: %w", err)
+	}
+
+	// A promo code grants only its batch's rule
+	if event.EventType == EventTypePromoCode {
+		rules = promoRules(event, rules)
 	}
 
 	if len(rules) == 0 {
-		e.logger.Debug("no matching rules for event",
+		logger.Debug("no matching rules for event",
 			"event_id", event.ID,
 			"event_type", event.EventType,
 		)
-		return []db.Issuance{}, nil
+		return ProcessResult{Issuances: []db.Issuance{}}, nil
 	}
 
-	e.logger.Info("evaluating rules for event",
+	logger.Info("evaluating rules for event",
 		"event_id", event.ID,
 		"event_type", event.EventType,
 		"rules_count", len(rules),
 	)
 
+	// The data conditions are evaluated against is built once for all the
+	// rules, with the customer object only when a rule reads it
+	data, err := EventData(event)
+	if err != nil {
+		logger.Warn("failed to build event data",
+			"event_id", event.ID,
+			"error", err,
+		)
+		return ProcessResult{Issuances: []db.Issuance{}}, nil
+	}
+	if usesCustomer(rules) {
+		if err := e.addCustomerData(ctx, event, data); err != nil {
+			return ProcessResult{}, err
+		}
+	}
+
+	// Rules the event already triggered return their issuances as they are,
+	// without being evaluated or checked against caps again
+	earlier, err := e.earlierTriggers(ctx, event)
+	if err != nil {
+		return ProcessResult{}, err
+	}
+
 	var issuances []db.Issuance
+	created := 0
+	trace := make([]RuleTrace, 0, len(rules))
 
 	// Evaluate each rule
 	for _, rule := range rules {
 		ruleStartTime := time.Now()
+		record := func(outcome RuleOutcome) {
+			trace = append(trace, RuleTrace{RuleID: rule.ID, CampaignID: rule.CampaignID, Outcome: outcome})
+		}
 
-		triggered, err := e.evaluateRule(ctx, rule, event)
+		if existing, ok := earlier[uuidToString(rule.ID)]; ok {
+			logger.Info("rule already triggered by event",
+				"rule_id", rule.ID,
+				"event_id", event.ID,
+				"issuances_count", len(existing),
+			)
+			record(OutcomeAlreadyTriggered)
+			issuances = append(issuances, existing...)
+			continue
+		}
+
+		triggered, err := e.evaluateRule(ctx, rule, data)
 		if err != nil {
-			e.logger.Warn("rule evaluation error",
+			logger.Warn("rule evaluation error",
 				"rule_id", rule.ID,
 				"rule_name", rule.Name,
 				"error", err,
 			)
+			record(OutcomeEvaluationError)
 			continue
 		}
 
 		if !triggered {
-			e.logger.Debug("rule not triggered",
+			logger.Debug("rule not triggered",
 				"rule_id", rule.ID,
 				"rule_name", rule.Name,
 			)
+			record(OutcomeNotMatched)
 			continue
 		}
 
-		e.logger.Info("rule triggered",
+		logger.Info("rule triggered",
 			"rule_id", rule.ID,
 			"rule_name", rule.Name,
 		)
 
+		// Skip campaigns the customer is excluded from
+		excluded, err := e.customerExcluded(ctx, rule, event)
+		if err != nil {
+			logger.Warn("exclusion check error",
+				"rule_id", rule.ID,
+				"error", err,
+			)
+			record(OutcomeEvaluationError)
+			continue
+		}
+		if excluded {
+			logger.Info("customer excluded from campaign",
+				"rule_id", rule.ID,
+				"rule_name", rule.Name,
+				"campaign_id", rule.CampaignID,
+				"customer_id", event.CustomerID,
+			)
+			record(OutcomeExcluded)
+			continue
+		}
+
 		// Check caps
-		passed, err := e.checkCaps(ctx, rule, event)
+		passed, err := e.checkCaps(ctx, e.pool, rule, event)
 		if err != nil {
-			e.logger.Warn("cap check error",
+			logger.Warn("cap check error",
 				"rule_id", rule.ID,
 				"error", err,
 			)
+			record(OutcomeEvaluationError)
 			continue
 		}
 
 		if !passed {
-			e.logger.Info("rule caps exceeded",
+			logger.Info("rule caps exceeded",
 				"rule_id", rule.ID,
 				"rule_name", rule.Name,
 			)
+			record(OutcomeCapExceeded)
 			continue
 		}
 
-		// Issue reward
-		issuance, err := e.issueReward(ctx, rule, event)
+		// Issue the rule's rewards
+		issued, err := e.issueRewards(ctx, rule, event)
+		if errors.Is(err, ErrAlreadyIssued) {
+			logger.Info("rule already triggered by event",
+				"rule_id", rule.ID,
+				"event_id", event.ID,
+				"issuances_count", len(issued),
+			)
+			record(OutcomeAlreadyTriggered)
+			issuances = append(issuances, issued...)
+			continue
+		}
+		if errors.Is(err, ErrCapExceeded) {
+			logger.Info("rule caps exceeded",
+				"rule_id", rule.ID,
+				"rule_name", rule.Name,
+			)
+			record(OutcomeCapExceeded)
+			continue
+		}
+		if errors.Is(err, ErrPacingExceeded) {
+			logger.Info("campaign spend limit reached",
+				"rule_id", rule.ID,
+				"campaign_id", rule.CampaignID,
+			)
+			record(OutcomePacingExceeded)
+			continue
+		}
+		if errors.Is(err, value.ErrNoValue) {
+			logger.Info("event gives the rule's reward no value",
+				"rule_id", rule.ID,
+				"event_id", event.ID,
+			)
+			record(OutcomeNoValue)
+			continue
+		}
 		if err != nil {
-			e.logger.Error("reward issuance error",
+			logger.Error("reward issuance error",
 				"rule_id", rule.ID,
 				"error", err,
 			)
+			record(OutcomeIssueError)
 			continue
 		}
 
-		e.logger.Info("reward issued",
+		logger.Info("rewards issued",
 			"rule_id", rule.ID,
-			"issuance_id", issuance.ID,
+			"issuances_count", len(issued),
 			"customer_id", event.CustomerID,
 			"duration_ms", time.Since(ruleStartTime).Milliseconds(),
 		)
 
-		issuances = append(issuances, *issuance)
+		record(OutcomeIssued)
+		issuances = append(issuances, issued...)
+		created += len(issued)
 	}
 
-	e.logger.Info("event processing completed",
+	logger.Info("event processing completed",
 		"event_id", event.ID,
 		"issuances_count", len(issuances),
 		"duration_ms", time.Since(startTime).Milliseconds(),
 	)
+	e.meter.Add(event.TenantID, metering.MetricIssuancesCreated, int64(created))
+
+	return ProcessResult{Issuances: issuances, Created: created, Trace: trace}, nil
+}
+
+// earlierTriggers returns the issuances of the rules the event already
+// triggered, by rule id
+func (e *Engine) earlierTriggers(ctx context.Context, event db.Event) (map[string][]db.Issuance, error) {
+	issuances, err := e.queries.ListEventTriggerIssuances(ctx, db.ListEventTriggerIssuancesParams{
+		TenantID: event.TenantID,
+		EventID:  event.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list earlier triggers: %w", err)
+	}
 
-	return issuances, nil
+	earlier := make(map[string][]db.Issuance)
+	for _, issuance := range issuances {
+		key := uuidToString(issuance.RuleID)
+		earlier[key] = append(earlier[key], issuance)
+	}
+	return earlier, nil
+}
+
+// customerExcluded reports whether the event's customer is on the exclusion
+// list of the rule's campaign
+func (e *Engine) customerExcluded(ctx context.Context, rule db.Rule, event db.Event) (bool, error) {
+	if !rule.CampaignID.Valid {
+		return false, nil
+	}
+	excluded, err := e.queries.IsCustomerExcludedFromCampaign(ctx, db.IsCustomerExcludedFromCampaignParams{
+		TenantID:   event.TenantID,
+		CampaignID: rule.CampaignID,
+		CustomerID: event.CustomerID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check campaign exclusions: %w", err)
+	}
+	return excluded, nil
 }
 
 // getMatchingRules retrieves active rules for an event type (with caching)
 func (e *Engine) getMatchingRules(ctx context.Context, event db.Event) ([]db.Rule, error) {
+	logger := e.logger.WithContext(ctx)
+
 	// Generate cache key
 	cacheKey := fmt.Sprintf("%s:%s", uuidToString(event.TenantID), event.EventType)
 
 	// Check cache first
 	if cachedRules, found := e.cache.Get(cacheKey); found {
-		e.logger.Debug("cache hit for rules", "cache_key", cacheKey)
+		logger.Debug("cache hit for rules", "cache_key", cacheKey)
 		return cachedRules, nil
 	}
 
@@ -159,13 +452,25 @@ func (e *Engine) getMatchingRules(ctx context.Context, event db.Event) ([]db.Rul
 
 	// Cache the results
 	e.cache.Set(cacheKey, rules)
-	e.logger.Debug("cached rules", "cache_key", cacheKey, "count", len(rules))
+	logger.Debug("cached rules", "cache_key", cacheKey, "count", len(rules))
 
 	return rules, nil
 }
 
-// evaluateRule evaluates a single rule against an event
-func (e *Engine) evaluateRule(ctx context.Context, rule db.Rule, event db.Event) (bool, error) {
+// evaluateRule evaluates a single rule against an event's data
+func (e *Engine) evaluateRule(ctx context.Context, rule db.Rule, data map[string]interface{}) (bool, error) {
+	// Evaluate the rule conditions
+	result, err := e.evaluator.Evaluate(ctx, rule.Conditions, data)
+	if err != nil {
+		return false, fmt.Errorf("evaluation failed: %w", err)
+	}
+
+	return result, nil
+}
+
+// EventData builds the data JSON Logic conditions are evaluated against for
+// an event
+func EventData(event db.Event) (map[string]interface{}, error) {
 	// Build data context for JsonLogic evaluation
 	data := make(map[string]interface{})
 
@@ -183,7 +488,7 @@ func (e *Engine) evaluateRule(ctx context.Context, rule db.Rule, event db.Event)
 	if len(event.Properties) > 0 {
 		var properties map[string]interface{}
 		if err := json.Unmarshal(event.Properties, &properties); err != nil {
-			return false, fmt.Errorf("failed to parse event properties: %w", err)
+			return nil, fmt.Errorf("failed to parse event properties: %w", err)
 		}
 		data["properties"] = properties
 
@@ -193,13 +498,18 @@ func (e *Engine) evaluateRule(ctx context.Context, rule db.Rule, event db.Event)
 		}
 	}
 
-	// Evaluate the rule conditions
-	result, err := e.evaluator.Evaluate(ctx, rule.Conditions, data)
-	if err != nil {
-		return false, fmt.Errorf("evaluation failed: %w", err)
+	// The validated location takes precedence over any location_id property
+	if event.LocationID.Valid {
+		data["location_id"] = uuidToString(event.LocationID)
 	}
 
-	return result, nil
+	// Coordinates the event was sent with, for within_radius
+	if event.Latitude.Valid && event.Longitude.Valid {
+		data["latitude"] = event.Latitude.Float64
+		data["longitude"] = event.Longitude.Float64
+	}
+
+	return data, nil
 }
 
 // InvalidateCache clears the entire rule cache
@@ -0,0 +1,141 @@
+package rules
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/logging"
+)
+
+// TestProcessExcludedCustomer checks that a customer on a campaign's
+// exclusion list is not issued the campaign's rewards, and that the
+// exclusion is recorded in the evaluation trace
+func TestProcessExcludedCustomer(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set, skipping integration tests")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	require.NoError(t, err)
+	defer pool.Close()
+	queries := db.New(pool)
+
+	tenant, err := queries.CreateTenant(ctx, db.CreateTenantParams{
+		Name:        "Process Excluded",
+		CountryCode: "ZW",
+		DefaultCcy:  "USD",
+		Theme:       []byte(`{}`),
+	})
+	require.NoError(t, err)
+
+	budget, err := queries.CreateBudget(ctx, db.CreateBudgetParams{
+		TenantID: tenant.ID,
+		Name:     "Exclusions Budget",
+		Currency: "USD",
+		SoftCap:  numeric(t, "1000"),
+		HardCap:  numeric(t, "1000"),
+		Balance:  numeric(t, "0"),
+		Period:   "rolling",
+	})
+	require.NoError(t, err)
+
+	campaign, err := queries.CreateCampaign(ctx, db.CreateCampaignParams{
+		TenantID: tenant.ID,
+		Name:     "Exclusions Campaign",
+		StartAt:  pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true},
+		BudgetID: budget.ID,
+		Status:   "active",
+	})
+	require.NoError(t, err)
+
+	rewardItem, err := queries.CreateReward(ctx, db.CreateRewardParams{
+		TenantID:  tenant.ID,
+		Name:      "$5 Voucher",
+		Type:      "discount",
+		FaceValue: numeric(t, "5"),
+		Currency:  pgtype.Text{String: "USD", Valid: true},
+		Inventory: "none",
+		Metadata:  []byte(`{}`),
+		Active:    true,
+	})
+	require.NoError(t, err)
+
+	rule, err := queries.CreateRule(ctx, db.CreateRuleParams{
+		TenantID:   tenant.ID,
+		CampaignID: campaign.ID,
+		Name:       "Every purchase",
+		EventType:  "purchase",
+		Conditions: []byte(`{">=": [{"var": "amount"}, 1]}`),
+		RewardID:   rewardItem.ID,
+		PerUserCap: 10,
+		Active:     true,
+		Quantity:   1,
+	})
+	require.NoError(t, err)
+
+	engine := NewEngine(pool, logging.New())
+
+	tests := []struct {
+		name     string
+		excluded bool
+		want     RuleOutcome
+	}{
+		{"excluded customer", true, OutcomeExcluded},
+		{"other customer", false, OutcomeIssued},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customer, err := queries.CreateCustomer(ctx, db.CreateCustomerParams{
+				TenantID:    tenant.ID,
+				ExternalRef: pgtype.Text{String: uuid.NewString(), Valid: true},
+			})
+			require.NoError(t, err)
+			if tt.excluded {
+				require.NoError(t, queries.AddCampaignExclusion(ctx, db.AddCampaignExclusionParams{
+					TenantID:   tenant.ID,
+					CampaignID: campaign.ID,
+					CustomerID: customer.ID,
+					Reason:     pgtype.Text{String: "staff", Valid: true},
+				}))
+			}
+
+			event, err := queries.InsertEvent(ctx, db.InsertEventParams{
+				TenantID:       tenant.ID,
+				CustomerID:     customer.ID,
+				EventType:      "purchase",
+				Properties:     []byte(`{"amount": 25, "currency": "USD"}`),
+				OccurredAt:     pgtype.Timestamptz{Time: time.Now(), Valid: true},
+				Source:         "api",
+				IdempotencyKey: uuid.NewString(),
+			})
+			require.NoError(t, err)
+
+			result, err := engine.Process(ctx, event)
+			require.NoError(t, err)
+			require.Len(t, result.Trace, 1)
+			assert.Equal(t, rule.ID, result.Trace[0].RuleID)
+			assert.Equal(t, campaign.ID, result.Trace[0].CampaignID)
+			assert.Equal(t, tt.want, result.Trace[0].Outcome)
+
+			if tt.excluded {
+				assert.Empty(t, result.Issuances)
+				assert.Zero(t, result.Created)
+				assert.Len(t, result.Excluded(), 1)
+				return
+			}
+			assert.Len(t, result.Issuances, 1)
+			assert.Empty(t, result.Excluded())
+		})
+	}
+}
@@ -2,17 +2,34 @@ package rules
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"hash/fnv"
 
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/reward"
+	"github.com/bmachimbira/loyalty/api/internal/reward/value"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-// issueReward creates a new issuance for a triggered rule
+var (
+	// ErrAlreadyIssued is returned, with the existing issuances, when the
+	// event already triggered the rule
+	ErrAlreadyIssued = errors.New("reward already issued for event")
+
+	// ErrCapExceeded is returned when a concurrent issuance reached the
+	// rule's caps after the engine's pre-check passed
+	ErrCapExceeded = errors.New("rule caps exceeded")
+)
+
+// issueRewards creates the issuances of a triggered rule: each reward of its
+// action set, as many times as its quantity. The set is issued in one
+// transaction, so a trigger issues all of it or nothing; in particular the
+// whole set's cost must fit the campaign's budgets and spend limits.
 // Uses PostgreSQL advisory locks to prevent race conditions
-func (e *Engine) issueReward(ctx context.Context, rule db.Rule, event db.Event) (*db.Issuance, error) {
+func (e *Engine) issueRewards(ctx context.Context, rule db.Rule, event db.Event) ([]db.Issuance, error) {
 	// Start transaction
 	tx, err := e.pool.Begin(ctx)
 	if err != nil {
@@ -23,109 +40,243 @@ func (e *Engine) issueReward(ctx context.Context, rule db.Rule, event db.Event)
 	// Create queries with transaction
 	qtx := e.queries.WithTx(tx)
 
-	// Acquire advisory lock on (tenant_id, rule_id, customer_id)
-	// This prevents concurrent issuances for the same rule and customer
-	lockKey := hashLock(event.TenantID.Bytes[:], rule.ID.Bytes[:], event.CustomerID.Bytes[:])
-	_, err = tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", lockKey)
+	// Claim the (event, rule) trigger first, so reprocessing the event
+	// (e.g. a dead letter retry or a replay) returns what it issued before
+	// instead of issuing again. A concurrent attempt waits here until this
+	// transaction ends and then sees its issuances.
+	claimed, err := qtx.ClaimRuleTrigger(ctx, db.ClaimRuleTriggerParams{
+		EventID:  event.ID,
+		RuleID:   rule.ID,
+		TenantID: event.TenantID,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to acquire advisory lock: %w", err)
+		return nil, fmt.Errorf("failed to claim rule trigger: %w", err)
+	}
+	if claimed == 0 {
+		existing, err := triggerIssuances(ctx, qtx, rule, event)
+		if err != nil {
+			return nil, err
+		}
+		return existing, ErrAlreadyIssued
 	}
 
-	// Re-check caps inside transaction (protection against race conditions)
-	passed, err := e.checkCaps(ctx, rule, event)
-	if err != nil {
-		return nil, fmt.Errorf("cap check failed in transaction: %w", err)
+	// Serialise issuances counting against the same caps until commit, so
+	// the cap re-check below sees every issuance committed before it
+	if err := lockCaps(ctx, tx, rule, event); err != nil {
+		return nil, err
 	}
-	if !passed {
-		return nil, fmt.Errorf("cap check failed: limits exceeded")
+
+	actions, err := RuleActions(ctx, qtx, rule)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get reward details
-	reward, err := qtx.GetRewardByID(ctx, db.GetRewardByIDParams{
-		TenantID: event.TenantID,
-		ID:       rule.RewardID,
-	})
+	// Re-check caps inside the transaction now the cap locks are held
+	passed, err := e.checkCaps(ctx, tx, rule, event)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get reward: %w", err)
+		return nil, fmt.Errorf("cap check failed in transaction: %w", err)
+	}
+	if !passed {
+		return nil, ErrCapExceeded
 	}
 
-	// Check and reserve budget if campaign has one
+	var campaign *db.Campaign
 	if rule.CampaignID.Valid {
-		campaign, err := qtx.GetCampaignByID(ctx, db.GetCampaignByIDParams{
+		c, err := qtx.GetCampaignByID(ctx, db.GetCampaignByIDParams{
 			TenantID: event.TenantID,
 			ID:       rule.CampaignID,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to get campaign: %w", err)
 		}
+		campaign = &c
+	}
 
-		// If campaign has a budget, reserve funds
-		if campaign.BudgetID.Valid {
-			success, err := e.reserveBudget(ctx, tx, campaign.BudgetID, event.TenantID, reward.FaceValue)
+	var issuances []db.Issuance
+	for _, action := range actions {
+		// Get reward details
+		rewardItem, err := qtx.GetRewardByID(ctx, db.GetRewardByIDParams{
+			TenantID: event.TenantID,
+			ID:       action.RewardID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get reward: %w", err)
+		}
+
+		for i := int32(0); i < action.Quantity; i++ {
+			issuance, err := e.reserveIssuance(ctx, tx, rule, event, campaign, rewardItem)
 			if err != nil {
-				return nil, fmt.Errorf("failed to reserve budget: %w", err)
-			}
-			if !success {
-				return nil, fmt.Errorf("budget capacity exceeded")
+				return nil, err
 			}
+			issuances = append(issuances, issuance)
+		}
+	}
+
+	// Spend limits are checked with the trigger's issuances reserved, so
+	// their cost counts towards the day and week
+	if err := e.checkPacing(ctx, tx, campaign); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	e.alertBudgets(issuances)
+
+	// The issuance processor moves the issuances from 'reserved' to 'issued'
+
+	return issuances, nil
+}
+
+// triggerIssuances returns the issuances the event's earlier trigger of the
+// rule created
+func triggerIssuances(ctx context.Context, q *db.Queries, rule db.Rule, event db.Event) ([]db.Issuance, error) {
+	issuances, err := q.ListEventTriggerIssuances(ctx, db.ListEventTriggerIssuancesParams{
+		TenantID: event.TenantID,
+		EventID:  event.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list existing issuances: %w", err)
+	}
+
+	existing := []db.Issuance{}
+	for _, issuance := range issuances {
+		if issuance.RuleID == rule.ID {
+			existing = append(existing, issuance)
 		}
 	}
+	return existing, nil
+}
+
+// reserveIssuance creates one issuance of a reward in 'reserved' state and
+// reserves its cost from the campaign's budgets, if it has any. Rewards with
+// a value formula are valued from the event, and the issuance records the
+// formula's inputs.
+func (e *Engine) reserveIssuance(ctx context.Context, tx pgx.Tx, rule db.Rule, event db.Event, campaign *db.Campaign, rewardItem db.RewardCatalog) (db.Issuance, error) {
+	qtx := e.queries.WithTx(tx)
 
-	// Create issuance in 'reserved' state
 	var currency pgtype.Text
-	if reward.Currency.Valid {
-		currency = reward.Currency
+	if rewardItem.Currency.Valid {
+		currency = rewardItem.Currency
 	} else {
 		currency.String = "USD"
 		currency.Valid = true
 	}
 
+	amount, valueInputs, err := rewardValue(rewardItem, event)
+	if err != nil {
+		return db.Issuance{}, err
+	}
+
 	issuance, err := qtx.ReserveIssuance(ctx, db.ReserveIssuanceParams{
-		TenantID:   event.TenantID,
-		CustomerID: event.CustomerID,
-		CampaignID: rule.CampaignID,
-		RewardID:   reward.ID,
-		Currency:   currency,
-		FaceAmount: reward.FaceValue,
-		CostAmount: reward.FaceValue,
+		TenantID:    event.TenantID,
+		CustomerID:  event.CustomerID,
+		CampaignID:  rule.CampaignID,
+		RewardID:    rewardItem.ID,
+		Currency:    currency,
+		FaceAmount:  amount,
+		CostAmount:  amount,
+		EventID:     event.ID,
+		RuleID:      rule.ID,
+		ValueInputs: valueInputs,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create issuance: %w", err)
+		return db.Issuance{}, fmt.Errorf("failed to create issuance: %w", err)
 	}
 
-	// Update issuance with event_id
-	// Note: The ReserveIssuance query doesn't include event_id, so we'll skip this for now
-	// In a production system, you might want to add event_id to the issuance
-
-	// Commit transaction
-	if err := tx.Commit(ctx); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	// Reserve funds from the campaign's budget or, once it is at its hard
+	// cap, from the campaign's fallback budgets. The issuance is created
+	// first so the ledger entry references it; the transaction rolls both
+	// back if the budgets are exhausted.
+	if campaign != nil && campaign.BudgetID.Valid {
+		budgetID, err := e.reserveBudget(ctx, tx, campaign.ID, event.TenantID, issuance)
+		if err != nil {
+			return db.Issuance{}, fmt.Errorf("failed to reserve budget: %w", err)
+		}
+		if !budgetID.Valid {
+			return db.Issuance{}, fmt.Errorf("budget capacity exceeded")
+		}
+		if err := qtx.SetIssuanceBudget(ctx, db.SetIssuanceBudgetParams{
+			ID:       issuance.ID,
+			TenantID: issuance.TenantID,
+			BudgetID: budgetID,
+		}); err != nil {
+			return db.Issuance{}, fmt.Errorf("failed to record issuance budget: %w", err)
+		}
+		issuance.BudgetID = budgetID
 	}
 
-	// Note: In a full implementation, you would trigger async processing here
-	// to transition the issuance from 'reserved' to 'issued' state
-	// For now, we'll return the reserved issuance
+	if err := reward.RecordIssuanceCreated(ctx, qtx, issuance, reward.Origin{
+		Actor:   reward.EventActor(event.ID),
+		Channel: reward.ChannelSystem,
+	}); err != nil {
+		return db.Issuance{}, err
+	}
 
-	return &issuance, nil
+	return issuance, nil
 }
 
-// reserveBudget reserves budget for an issuance
-func (e *Engine) reserveBudget(ctx context.Context, tx pgx.Tx, budgetID, tenantID pgtype.UUID, amount pgtype.Numeric) (bool, error) {
-	// Call database function to reserve budget
-	query := `SELECT reserve_budget($1, $2, $3, $4, $5)`
+// rewardValue returns the value of an issuance of the reward for the event:
+// its face value, or for rewards with a value formula the computed value and
+// the encoded formula inputs
+func rewardValue(rewardItem db.RewardCatalog, event db.Event) (pgtype.Numeric, []byte, error) {
+	formula, err := value.FromMetadata(rewardItem.Metadata)
+	if err != nil {
+		return pgtype.Numeric{}, nil, fmt.Errorf("reward %s: %w", rewardItem.Name, err)
+	}
+	if formula == nil {
+		return rewardItem.FaceValue, nil, nil
+	}
+
+	inputs, err := value.Compute(*formula, event.Properties)
+	if err != nil {
+		return pgtype.Numeric{}, nil, err
+	}
+	encoded, err := json.Marshal(inputs)
+	if err != nil {
+		return pgtype.Numeric{}, nil, fmt.Errorf("failed to encode value inputs: %w", err)
+	}
 
-	// We need to generate a temporary issuance ID for the reference
-	// In a real implementation, this would be the actual issuance ID
-	var tempRefID pgtype.UUID
-	tempRefID.Scan("00000000-0000-0000-0000-000000000000")
+	var amount pgtype.Numeric
+	if err := amount.Scan(value.Format(inputs.Value)); err != nil {
+		return pgtype.Numeric{}, nil, fmt.Errorf("invalid reward value: %w", err)
+	}
+	return amount, encoded, nil
+}
 
-	var success bool
-	err := tx.QueryRow(ctx, query, tenantID, budgetID, amount, "USD", tempRefID).Scan(&success)
+// reserveBudget reserves an issuance's cost from its campaign's primary
+// budget, falling back to the campaign's fallback budgets in order. Only when
+// every budget is at its hard cap does it draw on their overdrafts, in the
+// same order. It returns the budget reserved from, or an invalid UUID when
+// every budget's overdraft is exhausted too.
+func (e *Engine) reserveBudget(ctx context.Context, tx pgx.Tx, campaignID, tenantID pgtype.UUID, issuance db.Issuance) (pgtype.UUID, error) {
+	var budgetID pgtype.UUID
+	err := tx.QueryRow(ctx, "SELECT reserve_campaign_budget($1, $2, $3, $4, $5)",
+		tenantID, campaignID, issuance.CostAmount, issuance.Currency.String, issuance.ID).Scan(&budgetID)
 	if err != nil {
-		return false, fmt.Errorf("reserve_budget function failed: %w", err)
+		return pgtype.UUID{}, fmt.Errorf("reserve_campaign_budget function failed: %w", err)
 	}
 
-	return success, nil
+	return budgetID, nil
+}
+
+// alertBudgets has the budget alerter check each budget the committed
+// issuances reserved from, since a reservation may have crossed its alert
+// thresholds or drawn on its overdraft
+func (e *Engine) alertBudgets(issuances []db.Issuance) {
+	if e.alerter == nil {
+		return
+	}
+	seen := make(map[pgtype.UUID]bool)
+	for _, issuance := range issuances {
+		if !issuance.BudgetID.Valid || seen[issuance.BudgetID] {
+			continue
+		}
+		seen[issuance.BudgetID] = true
+		e.alerter.AlertReservation(issuance.TenantID, issuance.BudgetID)
+	}
 }
 
 // hashLock generates a consistent int64 hash for advisory locking
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 )
 
@@ -111,6 +113,22 @@ func (e *Evaluator) applyOperator(ctx context.Context, op string, args interface
 		return e.opNthEventInPeriod(ctx, args, data)
 	case "distinct_visit_days":
 		return e.opDistinctVisitDays(ctx, args, data)
+	case "in_region":
+		return e.opInRegion(ctx, args, data)
+	case "within_radius":
+		return e.opWithinRadius(ctx, args, data)
+	case "hour_between":
+		return e.opHourBetween(ctx, args, data)
+	case "day_of_week_in":
+		return e.opDayOfWeekIn(ctx, args, data)
+	case "sku_in_category":
+		return e.opSKUInCategory(ctx, args, data)
+	case "in_welcome_window":
+		return e.opInWelcomeWindow(ctx, args, data)
+	case "happened_after":
+		return e.opHappenedNear(ctx, "happened_after", args, data)
+	case "happened_before":
+		return e.opHappenedNear(ctx, "happened_before", args, data)
 	default:
 		return nil, fmt.Errorf("unknown operator: %s", op)
 	}
@@ -338,7 +356,6 @@ func getPath(data map[string]interface{}, path string) interface{} {
 		return data
 	}
 
-	// Simple implementation - no dot notation for now
 	val, ok := data[path]
 	if !ok {
 		// Try to get nested properties
@@ -347,6 +364,13 @@ func getPath(data map[string]interface{}, path string) interface{} {
 				return v
 			}
 		}
+
+		// Dotted paths such as customer.status walk nested objects
+		if head, rest, dotted := strings.Cut(path, "."); dotted {
+			if nested, ok := data[head].(map[string]interface{}); ok {
+				return getPath(nested, rest)
+			}
+		}
 		return nil
 	}
 	return val
@@ -489,6 +513,49 @@ func (e *Evaluator) opWithinDays(ctx context.Context, args interface{}, data map
 	return t.After(cutoff), nil
 }
 
+// opHappenedNear checks if the customer had an event of another type within
+// a number of days of this one: before it for happened_after (this event
+// happened after that one), after it for happened_before. Events at the
+// same instant as this one don't count, so this event never matches itself.
+func (e *Evaluator) opHappenedNear(ctx context.Context, op string, args interface{}, data map[string]interface{}) (interface{}, error) {
+	if e.customOps == nil {
+		return nil, fmt.Errorf("%s requires custom operators", op)
+	}
+
+	operands, err := e.evaluateArgs(ctx, args, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(operands) != 2 {
+		return nil, fmt.Errorf("%s requires 2 operands: event_type, days", op)
+	}
+
+	eventType := strings.TrimSpace(toString(operands[0]))
+	if eventType == "" {
+		return nil, fmt.Errorf("%s: event_type must not be empty", op)
+	}
+	days, ok := toNumber(operands[1])
+	if !ok || days <= 0 {
+		return nil, fmt.Errorf("%s: days must be a positive number", op)
+	}
+
+	// Events without a customer or time have nothing to correlate with
+	occurredAt, ok := data["occurred_at"].(time.Time)
+	customerID, _ := data["customer_id"].(string)
+	if !ok || customerID == "" {
+		return false, nil
+	}
+
+	window := time.Duration(days * float64(24*time.Hour))
+	from, to := occurredAt.Add(-window), occurredAt
+	if op == "happened_before" {
+		from, to = occurredAt, occurredAt.Add(window)
+	}
+
+	tenantID, _ := data["tenant_id"].(string)
+	return e.customOps.EventBetween(ctx, tenantID, customerID, eventType, from, to, occurredAt)
+}
+
 // opNthEventInPeriod checks if this is the Nth event in a period
 func (e *Evaluator) opNthEventInPeriod(ctx context.Context, args interface{}, data map[string]interface{}) (interface{}, error) {
 	if e.customOps == nil {
@@ -549,3 +616,256 @@ func (e *Evaluator) opDistinctVisitDays(ctx context.Context, args interface{}, d
 	}
 	return float64(count), nil
 }
+
+// opInRegion checks if the event's location is in one of the given regions
+func (e *Evaluator) opInRegion(ctx context.Context, args interface{}, data map[string]interface{}) (interface{}, error) {
+	if e.customOps == nil {
+		return nil, fmt.Errorf("in_region requires custom operators")
+	}
+
+	operands, err := e.evaluateArgs(ctx, args, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(operands) < 1 {
+		return nil, fmt.Errorf("in_region requires at least 1 operand: region")
+	}
+
+	// Events without a location are never in a region
+	locationID, _ := data["location_id"].(string)
+	if locationID == "" {
+		return false, nil
+	}
+
+	tenantID, _ := data["tenant_id"].(string)
+	region, err := e.customOps.LocationRegion(ctx, tenantID, locationID)
+	if err != nil {
+		return nil, err
+	}
+	if region == "" {
+		return false, nil
+	}
+
+	for _, operand := range operands {
+		if strings.EqualFold(strings.TrimSpace(toString(operand)), region) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// opSKUInCategory checks if a SKU, or any SKU in a list, belongs to one of the
+// given product categories. List items may be SKU strings or objects with a
+// "sku" field, so basket line items can be passed directly.
+func (e *Evaluator) opSKUInCategory(ctx context.Context, args interface{}, data map[string]interface{}) (interface{}, error) {
+	if e.customOps == nil {
+		return nil, fmt.Errorf("sku_in_category requires custom operators")
+	}
+
+	operands, err := e.evaluateArgs(ctx, args, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(operands) < 2 {
+		return nil, fmt.Errorf("sku_in_category requires at least 2 operands: sku, category")
+	}
+
+	var skus []string
+	switch v := operands[0].(type) {
+	case []interface{}:
+		for _, item := range v {
+			if obj, ok := item.(map[string]interface{}); ok {
+				item = obj["sku"]
+			}
+			if sku := strings.TrimSpace(toString(item)); sku != "" {
+				skus = append(skus, sku)
+			}
+		}
+	default:
+		if sku := strings.TrimSpace(toString(v)); sku != "" {
+			skus = append(skus, sku)
+		}
+	}
+	if len(skus) == 0 {
+		return false, nil
+	}
+
+	categories := make([]string, 0, len(operands)-1)
+	for _, operand := range operands[1:] {
+		categories = append(categories, strings.ToLower(strings.TrimSpace(toString(operand))))
+	}
+
+	tenantID, _ := data["tenant_id"].(string)
+	return e.customOps.SKUInCategory(ctx, tenantID, skus, categories)
+}
+
+// opInWelcomeWindow checks if the event occurred within the bonus window of
+// the customer's welcome series. It takes no operands.
+func (e *Evaluator) opInWelcomeWindow(ctx context.Context, args interface{}, data map[string]interface{}) (interface{}, error) {
+	if e.customOps == nil {
+		return nil, fmt.Errorf("in_welcome_window requires custom operators")
+	}
+
+	// Events without a customer or time were never in a window
+	occurredAt, ok := data["occurred_at"].(time.Time)
+	customerID, _ := data["customer_id"].(string)
+	if !ok || customerID == "" {
+		return false, nil
+	}
+
+	tenantID, _ := data["tenant_id"].(string)
+	return e.customOps.InWelcomeWindow(ctx, tenantID, customerID, occurredAt)
+}
+
+// earthRadiusMeters is the mean radius of the Earth used for distances
+const earthRadiusMeters = 6371000.0
+
+// opWithinRadius checks if the event's coordinates are within a radius (in
+// metres) of one of the tenant's locations, given by ID or code
+func (e *Evaluator) opWithinRadius(ctx context.Context, args interface{}, data map[string]interface{}) (interface{}, error) {
+	if e.customOps == nil {
+		return nil, fmt.Errorf("within_radius requires custom operators")
+	}
+
+	operands, err := e.evaluateArgs(ctx, args, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(operands) != 2 {
+		return nil, fmt.Errorf("within_radius requires 2 operands: location, radius_meters")
+	}
+
+	location := strings.TrimSpace(toString(operands[0]))
+	radius, ok := toNumber(operands[1])
+	if !ok {
+		return nil, fmt.Errorf("within_radius radius must be a number")
+	}
+	if location == "" || radius < 0 {
+		return false, nil
+	}
+
+	// Events without coordinates are never within a radius
+	lat, latOK := data["latitude"].(float64)
+	lng, lngOK := data["longitude"].(float64)
+	if !latOK || !lngOK {
+		return false, nil
+	}
+
+	tenantID, _ := data["tenant_id"].(string)
+	storeLat, storeLng, ok, err := e.customOps.LocationCoordinates(ctx, tenantID, location)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return false, nil
+	}
+
+	return haversineMeters(lat, lng, storeLat, storeLng) <= radius, nil
+}
+
+// haversineMeters returns the great-circle distance between two points in
+// metres
+func haversineMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+// weekdays maps day names and their three letter abbreviations to weekdays
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// localOccurredAt returns when the event occurred in the tenant's time zone
+func (e *Evaluator) localOccurredAt(ctx context.Context, op string, data map[string]interface{}) (time.Time, bool, error) {
+	if e.customOps == nil {
+		return time.Time{}, false, fmt.Errorf("%s requires custom operators", op)
+	}
+
+	occurredAt, ok := data["occurred_at"].(time.Time)
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	tenantID, _ := data["tenant_id"].(string)
+	loc, err := e.customOps.TenantLocation(ctx, tenantID)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return occurredAt.In(loc), true, nil
+}
+
+// opHourBetween checks if the event occurred from the start hour up to (but
+// not including) the end hour, in the tenant's time zone. A start after the
+// end wraps past midnight, e.g. [22, 2] is 22:00 to 01:59.
+func (e *Evaluator) opHourBetween(ctx context.Context, args interface{}, data map[string]interface{}) (interface{}, error) {
+	operands, err := e.evaluateArgs(ctx, args, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(operands) != 2 {
+		return nil, fmt.Errorf("hour_between requires 2 operands: start_hour, end_hour")
+	}
+
+	start, ok1 := toNumber(operands[0])
+	end, ok2 := toNumber(operands[1])
+	if !ok1 || !ok2 || start < 0 || start > 24 || end < 0 || end > 24 {
+		return nil, fmt.Errorf("hour_between hours must be numbers from 0 to 24")
+	}
+
+	local, ok, err := e.localOccurredAt(ctx, "hour_between", data)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	hour := float64(local.Hour())
+	if start <= end {
+		return hour >= start && hour < end, nil
+	}
+	return hour >= start || hour < end, nil
+}
+
+// opDayOfWeekIn checks if the event occurred on one of the given days of the
+// week, in the tenant's time zone. Days are names ("saturday"), three letter
+// abbreviations ("sat") or numbers from 0 (Sunday) to 6.
+func (e *Evaluator) opDayOfWeekIn(ctx context.Context, args interface{}, data map[string]interface{}) (interface{}, error) {
+	operands, err := e.evaluateArgs(ctx, args, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(operands) < 1 {
+		return nil, fmt.Errorf("day_of_week_in requires at least 1 operand: day")
+	}
+
+	days := make(map[time.Weekday]bool, len(operands))
+	for _, operand := range operands {
+		if n, ok := operand.(float64); ok {
+			if n < 0 || n > 6 || n != float64(int(n)) {
+				return nil, fmt.Errorf("day_of_week_in day must be from 0 (Sunday) to 6: %v", n)
+			}
+			days[time.Weekday(n)] = true
+			continue
+		}
+		day, ok := weekdays[strings.ToLower(strings.TrimSpace(toString(operand)))]
+		if !ok {
+			return nil, fmt.Errorf("day_of_week_in unknown day: %v", operand)
+		}
+		days[day] = true
+	}
+
+	local, ok, err := e.localOccurredAt(ctx, "day_of_week_in", data)
+	if err != nil || !ok {
+		return false, err
+	}
+	return days[local.Weekday()], nil
+}
@@ -3,8 +3,15 @@ package rules
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"os"
 	"testing"
 	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
 )
 
 func TestEvaluator_SimpleComparison(t *testing.T) {
@@ -386,6 +393,241 @@ func TestEvaluator_WithinDays(t *testing.T) {
 	}
 }
 
+func TestEvaluator_InRegion(t *testing.T) {
+	ctx := context.Background()
+	logic := json.RawMessage(`{"in_region": ["Harare"]}`)
+
+	// Without custom operators the region cannot be looked up
+	if _, err := NewEvaluator(nil).Evaluate(ctx, logic, map[string]interface{}{}); err == nil {
+		t.Error("Evaluate() should return error without custom operators")
+	}
+
+	// Events without a location never match, so no lookup is needed
+	e := NewEvaluator(&CustomOperators{})
+	result, err := e.Evaluate(ctx, logic, map[string]interface{}{
+		"tenant_id": "00000000-0000-0000-0000-000000000001",
+	})
+	if err != nil {
+		t.Errorf("Evaluate() error = %v", err)
+		return
+	}
+	if result {
+		t.Error("Evaluate() should return false for an event without a location")
+	}
+}
+
+func TestEvaluator_SKUInCategory(t *testing.T) {
+	ctx := context.Background()
+	logic := json.RawMessage(`{"sku_in_category": [{"var": "items"}, "beverages"]}`)
+
+	if _, err := NewEvaluator(nil).Evaluate(ctx, logic, map[string]interface{}{}); err == nil {
+		t.Error("Evaluate() should return error without custom operators")
+	}
+
+	// Baskets without SKUs never match, so no lookup is needed
+	e := NewEvaluator(&CustomOperators{})
+	result, err := e.Evaluate(ctx, logic, map[string]interface{}{
+		"tenant_id": "00000000-0000-0000-0000-000000000001",
+		"items":     []interface{}{map[string]interface{}{"qty": 1.0}},
+	})
+	if err != nil {
+		t.Errorf("Evaluate() error = %v", err)
+		return
+	}
+	if result {
+		t.Error("Evaluate() should return false for a basket without SKUs")
+	}
+}
+
+func TestEvaluator_InWelcomeWindow(t *testing.T) {
+	ctx := context.Background()
+	logic := json.RawMessage(`{"in_welcome_window": []}`)
+
+	if _, err := NewEvaluator(nil).Evaluate(ctx, logic, map[string]interface{}{}); err == nil {
+		t.Error("Evaluate() should return error without custom operators")
+	}
+
+	// Events without a customer never match, so no lookup is needed
+	e := NewEvaluator(&CustomOperators{})
+	result, err := e.Evaluate(ctx, logic, map[string]interface{}{
+		"tenant_id":   "00000000-0000-0000-0000-000000000001",
+		"occurred_at": time.Now(),
+	})
+	if err != nil {
+		t.Errorf("Evaluate() error = %v", err)
+		return
+	}
+	if result {
+		t.Error("Evaluate() should return false for an event without a customer")
+	}
+}
+
+func TestEvaluator_WithinRadius(t *testing.T) {
+	ctx := context.Background()
+	logic := json.RawMessage(`{"within_radius": ["HRE01", 500]}`)
+
+	if _, err := NewEvaluator(nil).Evaluate(ctx, logic, map[string]interface{}{}); err == nil {
+		t.Error("Evaluate() should return error without custom operators")
+	}
+
+	// Each of these is decided before looking the location up
+	e := NewEvaluator(&CustomOperators{})
+	tests := []struct {
+		name    string
+		logic   string
+		data    map[string]interface{}
+		wantErr bool
+	}{
+		{"no coordinates", `{"within_radius": ["HRE01", 500]}`, map[string]interface{}{}, false},
+		{"latitude only", `{"within_radius": ["HRE01", 500]}`, map[string]interface{}{"latitude": -17.8}, false},
+		{"no location", `{"within_radius": ["", 500]}`, map[string]interface{}{"latitude": -17.8, "longitude": 31.0}, false},
+		{"negative radius", `{"within_radius": ["HRE01", -1]}`, map[string]interface{}{"latitude": -17.8, "longitude": 31.0}, false},
+		{"radius not a number", `{"within_radius": ["HRE01", "near"]}`, map[string]interface{}{}, true},
+		{"missing radius", `{"within_radius": ["HRE01"]}`, map[string]interface{}{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := e.Evaluate(ctx, json.RawMessage(tt.logic), tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result {
+				t.Error("Evaluate() should return false")
+			}
+		})
+	}
+}
+
+func TestEvaluator_WithinRadiusLocation(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set, skipping integration tests")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		t.Fatalf("pgxpool.New() error = %v", err)
+	}
+	defer pool.Close()
+	queries := db.New(pool)
+
+	tenant, err := queries.CreateTenant(ctx, db.CreateTenantParams{
+		Name:        "Geofence",
+		CountryCode: "ZW",
+		DefaultCcy:  "USD",
+		Theme:       []byte(`{}`),
+	})
+	if err != nil {
+		t.Fatalf("CreateTenant() error = %v", err)
+	}
+
+	coordinate := func(s string) pgtype.Numeric {
+		var n pgtype.Numeric
+		if err := n.Scan(s); err != nil {
+			t.Fatalf("Scan(%q) error = %v", s, err)
+		}
+		return n
+	}
+	store, err := queries.CreateLocation(ctx, db.CreateLocationParams{
+		TenantID:  tenant.ID,
+		Name:      "Sam Levy's Village",
+		Code:      "HRE01",
+		Latitude:  coordinate("-17.757800"),
+		Longitude: coordinate("31.085600"),
+		Active:    true,
+	})
+	if err != nil {
+		t.Fatalf("CreateLocation() error = %v", err)
+	}
+	if _, err := queries.CreateLocation(ctx, db.CreateLocationParams{
+		TenantID: tenant.ID,
+		Name:     "Online",
+		Code:     "WEB",
+		Active:   true,
+	}); err != nil {
+		t.Fatalf("CreateLocation() error = %v", err)
+	}
+
+	e := NewEvaluator(NewCustomOperators(pool))
+	tests := []struct {
+		name     string
+		location string
+		lat, lng float64
+		radius   int
+		want     bool
+	}{
+		{"at the store by code", "HRE01", -17.7578, 31.0856, 100, true},
+		{"at the store by ID", uuidToString(store.ID), -17.7578, 31.0856, 100, true},
+		{"nearby within radius", "HRE01", -17.7600, 31.0856, 500, true},
+		{"nearby outside radius", "HRE01", -17.7600, 31.0856, 100, false},
+		{"other city", "HRE01", -20.1325, 28.6265, 5000, false},
+		{"location without coordinates", "WEB", -17.7578, 31.0856, 100, false},
+		{"unknown location", "BYO01", -17.7578, 31.0856, 100, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logic := json.RawMessage(fmt.Sprintf(`{"within_radius": [%q, %d]}`, tt.location, tt.radius))
+			result, err := e.Evaluate(ctx, logic, map[string]interface{}{
+				"tenant_id": uuidToString(tenant.ID),
+				"latitude":  tt.lat,
+				"longitude": tt.lng,
+			})
+			if err != nil {
+				t.Fatalf("Evaluate() error = %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("Evaluate() = %v, want %v", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestHaversineMeters(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lng1, lat2, lng2 float64
+		want                   float64
+	}{
+		{"same point", -17.8292, 31.0522, -17.8292, 31.0522, 0},
+		{"one degree of latitude", 0, 0, 1, 0, 111195},
+		{"Harare to Bulawayo", -17.8292, 31.0522, -20.1325, 28.6265, 365000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := haversineMeters(tt.lat1, tt.lng1, tt.lat2, tt.lng2)
+			// Within 1% of the expected distance
+			if diff := got - tt.want; diff > tt.want/100+1 || diff < -tt.want/100-1 {
+				t.Errorf("haversineMeters() = %.0f, want about %.0f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventDataCoordinates(t *testing.T) {
+	data, err := EventData(db.Event{})
+	if err != nil {
+		t.Fatalf("EventData() error = %v", err)
+	}
+	if _, ok := data["latitude"]; ok {
+		t.Error("EventData() should not set latitude for an event without coordinates")
+	}
+
+	data, err = EventData(db.Event{
+		Latitude:  pgtype.Float8{Float64: -17.8292, Valid: true},
+		Longitude: pgtype.Float8{Float64: 31.0522, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("EventData() error = %v", err)
+	}
+	if data["latitude"] != -17.8292 || data["longitude"] != 31.0522 {
+		t.Errorf("EventData() coordinates = %v, %v", data["latitude"], data["longitude"])
+	}
+}
+
 func TestEvaluator_EmptyLogic(t *testing.T) {
 	e := NewEvaluator(nil)
 	ctx := context.Background()
@@ -443,3 +685,37 @@ func BenchmarkEvaluator_ComplexCondition(b *testing.B) {
 		_, _ = e.Evaluate(ctx, logic, data)
 	}
 }
+
+func TestEvaluator_HappenedNear(t *testing.T) {
+	ctx := context.Background()
+	e := NewEvaluator(&CustomOperators{})
+	data := map[string]interface{}{
+		"tenant_id":   "00000000-0000-0000-0000-000000000001",
+		"occurred_at": time.Now(),
+	}
+
+	for _, logic := range []string{
+		`{"happened_after": ["signup"]}`,
+		`{"happened_after": ["", 7]}`,
+		`{"happened_before": ["signup", 0]}`,
+		`{"happened_before": ["signup", "soon"]}`,
+	} {
+		if _, err := e.Evaluate(ctx, json.RawMessage(logic), data); err == nil {
+			t.Errorf("Evaluate(%s) should return error", logic)
+		}
+	}
+
+	if _, err := NewEvaluator(nil).Evaluate(ctx, json.RawMessage(`{"happened_after": ["signup", 7]}`), data); err == nil {
+		t.Error("Evaluate() should return error without custom operators")
+	}
+
+	// Events without a customer never match, so no lookup is needed
+	result, err := e.Evaluate(ctx, json.RawMessage(`{"happened_after": ["signup", 7]}`), data)
+	if err != nil {
+		t.Errorf("Evaluate() error = %v", err)
+		return
+	}
+	if result {
+		t.Error("Evaluate() should return false for an event without a customer")
+	}
+}
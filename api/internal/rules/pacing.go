@@ -0,0 +1,40 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/campaign"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrPacingExceeded is returned when a trigger's issuances would take their
+// campaign past its daily or weekly spend limit
+var ErrPacingExceeded = errors.New("campaign spend limit reached")
+
+// checkPacing checks a campaign's spend in the current day and week,
+// including the issuances just reserved in tx, is within its limits. The
+// pacing lock serialises the campaign's issuances until tx ends, so
+// concurrent triggers can't both take the last of a period's spend.
+func (e *Engine) checkPacing(ctx context.Context, tx pgx.Tx, c *db.Campaign) error {
+	if c == nil || !campaign.HasPacing(*c) {
+		return nil
+	}
+
+	key := hashLock([]byte("pacing"), c.TenantID.Bytes[:], c.ID.Bytes[:])
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", key); err != nil {
+		return fmt.Errorf("failed to acquire advisory lock: %w", err)
+	}
+
+	pacing, err := campaign.CampaignPacing(ctx, e.queries.WithTx(tx), *c, time.Now())
+	if err != nil {
+		return err
+	}
+	if pacing.Exceeded() {
+		return ErrPacingExceeded
+	}
+	return nil
+}
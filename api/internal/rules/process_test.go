@@ -0,0 +1,98 @@
+package rules
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/logging"
+)
+
+// TestProcessReportsCreated checks that reprocessing an event, as a replay
+// does, reports the issuances it already had as not created
+func TestProcessReportsCreated(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set, skipping integration tests")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	require.NoError(t, err)
+	defer pool.Close()
+	queries := db.New(pool)
+
+	tenant, err := queries.CreateTenant(ctx, db.CreateTenantParams{
+		Name:        "Process Created",
+		CountryCode: "ZW",
+		DefaultCcy:  "USD",
+		Theme:       []byte(`{}`),
+	})
+	require.NoError(t, err)
+
+	customer, err := queries.CreateCustomer(ctx, db.CreateCustomerParams{
+		TenantID:    tenant.ID,
+		ExternalRef: pgtype.Text{String: "process-customer", Valid: true},
+	})
+	require.NoError(t, err)
+
+	rewardItem, err := queries.CreateReward(ctx, db.CreateRewardParams{
+		TenantID:  tenant.ID,
+		Name:      "$5 Voucher",
+		Type:      "discount",
+		FaceValue: numeric(t, "5"),
+		Currency:  pgtype.Text{String: "USD", Valid: true},
+		Inventory: "none",
+		Metadata:  []byte(`{}`),
+		Active:    true,
+	})
+	require.NoError(t, err)
+
+	_, err = queries.CreateRule(ctx, db.CreateRuleParams{
+		TenantID:   tenant.ID,
+		Name:       "Every purchase",
+		EventType:  "purchase",
+		Conditions: []byte(`{">=": [{"var": "amount"}, 1]}`),
+		RewardID:   rewardItem.ID,
+		PerUserCap: 10,
+		Active:     true,
+		Quantity:   1,
+	})
+	require.NoError(t, err)
+
+	event, err := queries.InsertEvent(ctx, db.InsertEventParams{
+		TenantID:       tenant.ID,
+		CustomerID:     customer.ID,
+		EventType:      "purchase",
+		Properties:     []byte(`{"amount": 25, "currency": "USD"}`),
+		OccurredAt:     pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Source:         "api",
+		IdempotencyKey: "process-created",
+	})
+	require.NoError(t, err)
+
+	engine := NewEngine(pool, logging.New())
+
+	tests := []struct {
+		name    string
+		created int
+	}{
+		{"first processing", 1},
+		{"replay", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := engine.Process(ctx, event)
+			require.NoError(t, err)
+			assert.Len(t, result.Issuances, 1)
+			assert.Equal(t, tt.created, result.Created)
+		})
+	}
+}
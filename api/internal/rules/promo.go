@@ -0,0 +1,39 @@
+package rules
+
+import (
+	"encoding/json"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+)
+
+// EventTypePromoCode is the built-in event type emitted when a customer
+// enters a valid promo code. Its properties name the promo batch, the code
+// and the batch's rule_id.
+const EventTypePromoCode = "promo_code"
+
+// PromoSource is the event source of promo_code events emitted by the promo
+// service. Promo rules only run for events from this source, so a promo_code
+// event from anywhere else can't grant a promo reward.
+const PromoSource = "promo"
+
+// promoRules narrows the rules matched by a promo_code event to the rule of
+// the code's batch
+func promoRules(event db.Event, rules []db.Rule) []db.Rule {
+	if event.Source != PromoSource {
+		return nil
+	}
+
+	var properties struct {
+		RuleID string `json:"rule_id"`
+	}
+	if err := json.Unmarshal(event.Properties, &properties); err != nil || properties.RuleID == "" {
+		return nil
+	}
+
+	for _, rule := range rules {
+		if uuidToString(rule.ID) == properties.RuleID {
+			return []db.Rule{rule}
+		}
+	}
+	return nil
+}
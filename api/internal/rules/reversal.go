@@ -0,0 +1,272 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/enums"
+	"github.com/bmachimbira/loyalty/api/internal/reward"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// EventTypeReversal is the built-in event type that undoes the rewards of an
+// earlier event, e.g. a refunded purchase. Its properties name the original
+// event by original_event_id or original_idempotency_key, with an optional
+// reason.
+const EventTypeReversal = "reversal"
+
+// Reversal actions recorded per issuance of the original event
+const (
+	ReversalActionCancelled       = "cancelled"
+	ReversalActionAlreadyRedeemed = "already_redeemed"
+	ReversalActionSkipped         = "skipped"
+)
+
+var (
+	// ErrReversalTargetRequired is returned when a reversal names no original event
+	ErrReversalTargetRequired = errors.New("reversal requires original_event_id or original_idempotency_key")
+
+	// ErrOriginalEventNotFound is returned when the reversed event doesn't exist
+	ErrOriginalEventNotFound = errors.New("original event not found")
+
+	// ErrReversalOfReversal is returned when a reversal targets another reversal
+	ErrReversalOfReversal = errors.New("a reversal cannot be reversed")
+
+	// ErrReversalCustomerMismatch is returned when the reversal and original
+	// event belong to different customers
+	ErrReversalCustomerMismatch = errors.New("reversal customer does not match the original event")
+
+	// ErrAlreadyReversed is returned when the original event was reversed by
+	// another reversal event
+	ErrAlreadyReversed = errors.New("event already reversed")
+
+	// ErrReversalNotFound is returned when an event has no reversal
+	ErrReversalNotFound = errors.New("reversal not found")
+)
+
+// reversalProperties are the properties of a reversal event
+type reversalProperties struct {
+	OriginalEventID        string `json:"original_event_id"`
+	OriginalIdempotencyKey string `json:"original_idempotency_key"`
+	Reason                 string `json:"reason"`
+}
+
+// Reversal is a reversal and what it did to each issuance of the original
+// event
+type Reversal struct {
+	db.EventReversal
+	Issuances []db.EventReversalIssuance
+}
+
+// FindReversalTarget resolves the event a reversal's properties refer to and
+// checks it can be reversed on behalf of customerID
+func (e *Engine) FindReversalTarget(ctx context.Context, tenantID, customerID pgtype.UUID, properties []byte) (db.Event, error) {
+	return findReversalTarget(ctx, e.queries, tenantID, customerID, properties)
+}
+
+func findReversalTarget(ctx context.Context, q *db.Queries, tenantID, customerID pgtype.UUID, properties []byte) (db.Event, error) {
+	var props reversalProperties
+	if len(properties) > 0 {
+		if err := json.Unmarshal(properties, &props); err != nil {
+			return db.Event{}, ErrReversalTargetRequired
+		}
+	}
+
+	var original db.Event
+	var err error
+	switch {
+	case props.OriginalEventID != "":
+		var id pgtype.UUID
+		if id.Scan(props.OriginalEventID) != nil {
+			return db.Event{}, ErrOriginalEventNotFound
+		}
+		original, err = q.GetEventByID(ctx, db.GetEventByIDParams{ID: id, TenantID: tenantID})
+	case props.OriginalIdempotencyKey != "":
+		original, err = q.GetEventByIdemKey(ctx, db.GetEventByIdemKeyParams{
+			TenantID:       tenantID,
+			IdempotencyKey: props.OriginalIdempotencyKey,
+		})
+	default:
+		return db.Event{}, ErrReversalTargetRequired
+	}
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Event{}, ErrOriginalEventNotFound
+		}
+		return db.Event{}, fmt.Errorf("failed to get original event: %w", err)
+	}
+
+	if original.EventType == EventTypeReversal {
+		return db.Event{}, ErrReversalOfReversal
+	}
+	if original.CustomerID != customerID {
+		return db.Event{}, ErrReversalCustomerMismatch
+	}
+	return original, nil
+}
+
+// reverseEvent processes a reversal event: every unredeemed issuance of the
+// original event is cancelled and its budget released, and the outcome for
+// each issuance is recorded. Reprocessing the same reversal event is a
+// no-op. It returns the original event's issuances in their new state.
+func (e *Engine) reverseEvent(ctx context.Context, event db.Event) ([]db.Issuance, error) {
+	logger := e.logger.WithContext(ctx)
+
+	tx, err := e.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := e.queries.WithTx(tx)
+
+	original, err := findReversalTarget(ctx, qtx, event.TenantID, event.CustomerID, event.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	var props reversalProperties
+	json.Unmarshal(event.Properties, &props)
+
+	reversal, err := qtx.CreateEventReversal(ctx, db.CreateEventReversalParams{
+		TenantID:        event.TenantID,
+		ReversalEventID: event.ID,
+		OriginalEventID: original.ID,
+		Reason:          pgtype.Text{String: props.Reason, Valid: props.Reason != ""},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		existing, err := qtx.GetEventReversal(ctx, db.GetEventReversalParams{TenantID: event.TenantID, EventID: original.ID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get existing reversal: %w", err)
+		}
+		if existing.ReversalEventID != event.ID {
+			return nil, ErrAlreadyReversed
+		}
+		return qtx.ListEventIssuancesForUpdate(ctx, db.ListEventIssuancesForUpdateParams{
+			TenantID: event.TenantID,
+			EventID:  original.ID,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record reversal: %w", err)
+	}
+
+	issuances, err := qtx.ListEventIssuancesForUpdate(ctx, db.ListEventIssuancesForUpdateParams{
+		TenantID: event.TenantID,
+		EventID:  original.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get original issuances: %w", err)
+	}
+
+	for i, issuance := range issuances {
+		item := db.AddEventReversalIssuanceParams{
+			ReversalID:     reversal.ID,
+			TenantID:       event.TenantID,
+			IssuanceID:     issuance.ID,
+			PreviousStatus: issuance.Status,
+		}
+
+		switch enums.IssuanceStatus(issuance.Status) {
+		case enums.IssuanceReserved, enums.IssuanceIssued:
+			if err := reward.TransitionIssuance(ctx, qtx, reward.Transition{
+				IssuanceID: issuance.ID,
+				TenantID:   issuance.TenantID,
+				From:       reward.State(issuance.Status),
+				To:         reward.StateCancelled,
+				Origin:     reward.Origin{Actor: reward.EventActor(event.ID), Channel: reward.ChannelSystem},
+			}); err != nil {
+				return nil, fmt.Errorf("failed to cancel issuance: %w", err)
+			}
+			released, err := e.releaseIssuanceBudget(ctx, tx, qtx, issuance)
+			if err != nil {
+				return nil, err
+			}
+			item.Action = ReversalActionCancelled
+			if released {
+				item.ReleasedAmount = issuance.CostAmount
+				item.Currency = issuance.Currency
+			}
+			issuances[i].Status = string(enums.IssuanceCancelled)
+		case enums.IssuanceRedeemed:
+			item.Action = ReversalActionAlreadyRedeemed
+		default:
+			item.Action = ReversalActionSkipped
+		}
+
+		if err := qtx.AddEventReversalIssuance(ctx, item); err != nil {
+			return nil, fmt.Errorf("failed to record reversed issuance: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Info("event reversed",
+		"reversal_event_id", event.ID,
+		"original_event_id", original.ID,
+		"issuances_count", len(issuances),
+	)
+	return issuances, nil
+}
+
+// releaseIssuanceBudget returns a cancelled issuance's cost to the budget it
+// was reserved from. It reports false when there was nothing to release: no
+// budget, no cost, or the reservation was already released or charged.
+func (e *Engine) releaseIssuanceBudget(ctx context.Context, tx pgx.Tx, qtx *db.Queries, issuance db.Issuance) (bool, error) {
+	if !issuance.CampaignID.Valid || !issuance.CostAmount.Valid || !issuance.Currency.Valid {
+		return false, nil
+	}
+
+	budgetID, err := reward.IssuanceBudget(ctx, qtx, issuance)
+	if err != nil || !budgetID.Valid {
+		return false, err
+	}
+
+	entries, err := qtx.GetLedgerEntryByRef(ctx, db.GetLedgerEntryByRefParams{
+		TenantID: issuance.TenantID,
+		RefType:  pgtype.Text{String: "issuance", Valid: true},
+		RefID:    issuance.ID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check ledger entries: %w", err)
+	}
+	for _, entry := range entries {
+		switch enums.LedgerEntryType(entry.EntryType) {
+		case enums.LedgerRelease, enums.LedgerCharge:
+			return false, nil
+		}
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT release_budget($1, $2, $3, $4, $5)",
+		issuance.TenantID, budgetID, issuance.CostAmount, issuance.Currency.String, issuance.ID); err != nil {
+		return false, fmt.Errorf("release_budget function failed: %w", err)
+	}
+	return true, nil
+}
+
+// GetReversal returns the reversal of an event, looked up by the original or
+// the reversal event ID
+func (e *Engine) GetReversal(ctx context.Context, tenantID, eventID pgtype.UUID) (*Reversal, error) {
+	reversal, err := e.queries.GetEventReversal(ctx, db.GetEventReversalParams{TenantID: tenantID, EventID: eventID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrReversalNotFound
+		}
+		return nil, fmt.Errorf("failed to get reversal: %w", err)
+	}
+
+	items, err := e.queries.ListEventReversalIssuances(ctx, db.ListEventReversalIssuancesParams{
+		ReversalID: reversal.ID,
+		TenantID:   tenantID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reversed issuances: %w", err)
+	}
+	return &Reversal{EventReversal: reversal, Issuances: items}, nil
+}
@@ -0,0 +1,183 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/logging"
+)
+
+func TestFindReversalTargetProperties(t *testing.T) {
+	// Malformed targets are rejected before looking the event up
+	tests := []struct {
+		name       string
+		properties string
+		wantErr    error
+	}{
+		{"no properties", ``, ErrReversalTargetRequired},
+		{"no target", `{"reason": "refund"}`, ErrReversalTargetRequired},
+		{"not JSON", `refund`, ErrReversalTargetRequired},
+		{"malformed event ID", `{"original_event_id": "not-a-uuid"}`, ErrOriginalEventNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := findReversalTarget(context.Background(), nil, pgtype.UUID{}, pgtype.UUID{}, []byte(tt.properties))
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+// TestReverseEvent checks what reversing a purchase does to its reward
+func TestReverseEvent(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set, skipping integration tests")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	require.NoError(t, err)
+	defer pool.Close()
+	queries := db.New(pool)
+
+	tenant, err := queries.CreateTenant(ctx, db.CreateTenantParams{
+		Name:        "Reversals",
+		CountryCode: "ZW",
+		DefaultCcy:  "USD",
+		Theme:       []byte(`{}`),
+	})
+	require.NoError(t, err)
+
+	rewardItem, err := queries.CreateReward(ctx, db.CreateRewardParams{
+		TenantID:  tenant.ID,
+		Name:      "$5 Voucher",
+		Type:      "discount",
+		FaceValue: numeric(t, "5"),
+		Currency:  pgtype.Text{String: "USD", Valid: true},
+		Inventory: "none",
+		Metadata:  []byte(`{}`),
+		Active:    true,
+	})
+	require.NoError(t, err)
+
+	_, err = queries.CreateRule(ctx, db.CreateRuleParams{
+		TenantID:   tenant.ID,
+		Name:       "Every purchase",
+		EventType:  "purchase",
+		Conditions: []byte(`{">=": [{"var": "amount"}, 1]}`),
+		RewardID:   rewardItem.ID,
+		PerUserCap: 100,
+		Active:     true,
+		Quantity:   1,
+	})
+	require.NoError(t, err)
+
+	engine := NewEngine(pool, logging.New())
+
+	newCustomer := func(t *testing.T) pgtype.UUID {
+		customer, err := queries.CreateCustomer(ctx, db.CreateCustomerParams{
+			TenantID:    tenant.ID,
+			ExternalRef: pgtype.Text{String: uuid.NewString(), Valid: true},
+		})
+		require.NoError(t, err)
+		return customer.ID
+	}
+	newEvent := func(t *testing.T, customerID pgtype.UUID, eventType, properties string) db.Event {
+		event, err := queries.InsertEvent(ctx, db.InsertEventParams{
+			TenantID:       tenant.ID,
+			CustomerID:     customerID,
+			EventType:      eventType,
+			Properties:     []byte(properties),
+			OccurredAt:     pgtype.Timestamptz{Time: time.Now(), Valid: true},
+			Source:         "api",
+			IdempotencyKey: uuid.NewString(),
+		})
+		require.NoError(t, err)
+		return event
+	}
+
+	tests := []struct {
+		name       string
+		redeemed   bool
+		wantStatus string
+		wantAction string
+	}{
+		{"unredeemed reward is cancelled", false, "cancelled", ReversalActionCancelled},
+		{"redeemed reward is kept", true, "redeemed", ReversalActionAlreadyRedeemed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customerID := newCustomer(t)
+			purchase := newEvent(t, customerID, "purchase", `{"amount": 25, "currency": "USD"}`)
+			issued, err := engine.ProcessEvent(ctx, purchase)
+			require.NoError(t, err)
+			require.Len(t, issued, 1)
+
+			if tt.redeemed {
+				_, err := pool.Exec(ctx, "UPDATE issuances SET status = 'redeemed', redeemed_at = now() WHERE id = $1", issued[0].ID)
+				require.NoError(t, err)
+			}
+
+			target := fmt.Sprintf(`{"original_event_id": %q, "reason": "refund"}`, uuid.UUID(purchase.ID.Bytes).String())
+			reversal := newEvent(t, customerID, EventTypeReversal, target)
+
+			reversed, err := engine.ProcessEvent(ctx, reversal)
+			require.NoError(t, err)
+			require.Len(t, reversed, 1)
+			assert.Equal(t, tt.wantStatus, reversed[0].Status)
+
+			// Reprocessing the same reversal changes nothing
+			again, err := engine.ProcessEvent(ctx, reversal)
+			require.NoError(t, err)
+			require.Len(t, again, 1)
+			assert.Equal(t, tt.wantStatus, again[0].Status)
+
+			record, err := engine.GetReversal(ctx, tenant.ID, purchase.ID)
+			require.NoError(t, err)
+			assert.Equal(t, reversal.ID, record.ReversalEventID)
+			assert.Equal(t, "refund", record.Reason.String)
+			require.Len(t, record.Issuances, 1)
+			assert.Equal(t, tt.wantAction, record.Issuances[0].Action)
+
+			// A second reversal of the same purchase is refused
+			_, err = engine.ProcessEvent(ctx, newEvent(t, customerID, EventTypeReversal, target))
+			assert.ErrorIs(t, err, ErrAlreadyReversed)
+		})
+	}
+
+	t.Run("refused targets", func(t *testing.T) {
+		customerID := newCustomer(t)
+		purchase := newEvent(t, customerID, "purchase", `{"amount": 25, "currency": "USD"}`)
+		reversal := newEvent(t, customerID, EventTypeReversal,
+			fmt.Sprintf(`{"original_idempotency_key": %q}`, purchase.IdempotencyKey))
+
+		refused := []struct {
+			name       string
+			customerID pgtype.UUID
+			properties string
+			wantErr    error
+		}{
+			{"other customer", newCustomer(t), fmt.Sprintf(`{"original_idempotency_key": %q}`, purchase.IdempotencyKey), ErrReversalCustomerMismatch},
+			{"reversal of a reversal", customerID, fmt.Sprintf(`{"original_idempotency_key": %q}`, reversal.IdempotencyKey), ErrReversalOfReversal},
+			{"unknown event", customerID, fmt.Sprintf(`{"original_event_id": %q}`, uuid.NewString()), ErrOriginalEventNotFound},
+		}
+		for _, tt := range refused {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := engine.FindReversalTarget(ctx, tenant.ID, tt.customerID, []byte(tt.properties))
+				assert.ErrorIs(t, err, tt.wantErr)
+			})
+		}
+	})
+}
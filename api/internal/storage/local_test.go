@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLocalStorePutGet(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore() error = %v", err)
+	}
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "tenant/voucher_codes/1/codes.csv", []byte("ABC123\n"), "text/csv"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	got, err := store.Get(ctx, "tenant/voucher_codes/1/codes.csv")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if string(got) != "ABC123\n" {
+		t.Errorf("Get() = %q, want %q", got, "ABC123\n")
+	}
+
+	// Putting again replaces the object
+	if err := store.Put(ctx, "tenant/voucher_codes/1/codes.csv", []byte("XYZ789\n"), "text/csv"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	got, err = store.Get(ctx, "tenant/voucher_codes/1/codes.csv")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if string(got) != "XYZ789\n" {
+		t.Errorf("Get() = %q, want %q", got, "XYZ789\n")
+	}
+
+	if _, err := store.Get(ctx, "tenant/voucher_codes/2/codes.csv"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Get() of missing object error = %v, want %v", err, ErrObjectNotFound)
+	}
+}
+
+func TestLocalStoreRejectsInvalidKeys(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore() error = %v", err)
+	}
+	ctx := context.Background()
+
+	for _, key := range []string{"", "/etc/passwd", "../outside", "tenant/../../outside", "tenant//codes.csv", "tenant/./codes.csv", `tenant\codes.csv`} {
+		if err := store.Put(ctx, key, []byte("x"), "text/plain"); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q) error = %v, want %v", key, err, ErrInvalidKey)
+		}
+		if _, err := store.Get(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Get(%q) error = %v, want %v", key, err, ErrInvalidKey)
+		}
+	}
+}
+
+func TestObjectKey(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"codes.csv", "t/voucher_codes/u/codes.csv"},
+		{"my codes (1).csv", "t/voucher_codes/u/my_codes__1_.csv"},
+		{"../../etc/passwd", "t/voucher_codes/u/passwd"},
+		{"..", "t/voucher_codes/u/file"},
+		{"", "t/voucher_codes/u/file"},
+	}
+
+	for _, tt := range tests {
+		got := objectKey("t", KindVoucherCodes, "u", tt.filename)
+		if got != tt.want {
+			t.Errorf("objectKey(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+		if err := validateKey(got); err != nil {
+			t.Errorf("objectKey(%q) = %q is not a valid key", tt.filename, got)
+		}
+	}
+}
+
+func TestNewStore(t *testing.T) {
+	store, err := NewStore(Config{})
+	if err != nil || store != nil {
+		t.Errorf("NewStore() with no backend = %v, %v, want nil, nil", store, err)
+	}
+	if _, err := NewStore(Config{Backend: "ftp"}); err == nil {
+		t.Error("NewStore() with unknown backend succeeded")
+	}
+	if _, err := NewStore(Config{Backend: "local"}); err == nil {
+		t.Error("NewStore() of local store without a directory succeeded")
+	}
+	if _, err := NewStore(Config{Backend: "s3"}); err == nil {
+		t.Error("NewStore() of s3 store without a bucket succeeded")
+	}
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bmachimbira/loyalty/api/internal/export"
+)
+
+// S3Store keeps objects in a bucket of an S3-compatible store
+type S3Store struct {
+	uploader *export.S3Uploader
+	bucket   string
+}
+
+// NewS3Store creates a store that keeps objects in bucket
+func NewS3Store(uploader *export.S3Uploader, bucket string) *S3Store {
+	return &S3Store{
+		uploader: uploader,
+		bucket:   bucket,
+	}
+}
+
+// Put uploads body to key, replacing any existing object
+func (s *S3Store) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	return s.uploader.Put(ctx, s.bucket, key, body, contentType)
+}
+
+// Get downloads the object at key
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	body, err := s.uploader.Get(ctx, s.bucket, key)
+	if errors.Is(err, export.ErrObjectNotFound) {
+		return nil, ErrObjectNotFound
+	}
+	return body, err
+}
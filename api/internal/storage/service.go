@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// KindVoucherCodes is the kind of a voucher code CSV uploaded for a
+// voucher_code reward
+const KindVoucherCodes = "voucher_codes"
+
+var (
+	// ErrStorageDisabled is returned when no object store is configured
+	ErrStorageDisabled = errors.New("upload storage is not configured")
+
+	// ErrUploadNotFound is returned when the upload does not exist
+	ErrUploadNotFound = errors.New("upload not found")
+)
+
+// SaveParams describes an uploaded file to keep
+type SaveParams struct {
+	TenantID    pgtype.UUID
+	Kind        string
+	Filename    string
+	ContentType string
+	Body        []byte
+	// EntityType and EntityID link the upload to what it changed, e.g.
+	// "reward" and the voucher reward's ID
+	EntityType string
+	EntityID   pgtype.UUID
+	UploadedBy pgtype.UUID
+}
+
+// Service keeps uploaded files in the object store and records what was
+// uploaded, by whom and for what
+type Service struct {
+	queries *db.Queries
+	store   Store
+}
+
+// NewService creates an upload service. store may be nil, in which case
+// nothing is kept and Save returns ErrStorageDisabled.
+func NewService(queries *db.Queries, store Store) *Service {
+	return &Service{
+		queries: queries,
+		store:   store,
+	}
+}
+
+// Enabled reports whether an object store is configured
+func (s *Service) Enabled() bool {
+	return s.store != nil
+}
+
+// Save writes an uploaded file to the store and records its metadata
+func (s *Service) Save(ctx context.Context, params SaveParams) (db.Upload, error) {
+	if s.store == nil {
+		return db.Upload{}, ErrStorageDisabled
+	}
+
+	sum := sha256.Sum256(params.Body)
+	key := objectKey(httputil.FormatUUID(params.TenantID.Bytes), params.Kind, uuid.New().String(), params.Filename)
+	contentType := params.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	if err := s.store.Put(ctx, key, params.Body, contentType); err != nil {
+		return db.Upload{}, fmt.Errorf("failed to store upload: %w", err)
+	}
+
+	// A file stored without its row is only wasted space, so the store is
+	// written first
+	upload, err := s.queries.CreateUpload(ctx, db.CreateUploadParams{
+		TenantID:    params.TenantID,
+		Kind:        params.Kind,
+		Filename:    params.Filename,
+		ContentType: contentType,
+		SizeBytes:   int64(len(params.Body)),
+		Sha256:      hex.EncodeToString(sum[:]),
+		StorageKey:  key,
+		EntityType:  pgtype.Text{String: params.EntityType, Valid: params.EntityType != ""},
+		EntityID:    params.EntityID,
+		UploadedBy:  params.UploadedBy,
+	})
+	if err != nil {
+		return db.Upload{}, fmt.Errorf("failed to record upload: %w", err)
+	}
+	return upload, nil
+}
+
+// Get retrieves an upload's metadata
+func (s *Service) Get(ctx context.Context, tenantID, id pgtype.UUID) (db.Upload, error) {
+	upload, err := s.queries.GetUpload(ctx, db.GetUploadParams{
+		TenantID: tenantID,
+		ID:       id,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Upload{}, ErrUploadNotFound
+		}
+		return db.Upload{}, fmt.Errorf("failed to get upload: %w", err)
+	}
+	return upload, nil
+}
+
+// Open retrieves an upload and its contents
+func (s *Service) Open(ctx context.Context, tenantID, id pgtype.UUID) (db.Upload, []byte, error) {
+	if s.store == nil {
+		return db.Upload{}, nil, ErrStorageDisabled
+	}
+
+	upload, err := s.Get(ctx, tenantID, id)
+	if err != nil {
+		return db.Upload{}, nil, err
+	}
+
+	body, err := s.store.Get(ctx, upload.StorageKey)
+	if err != nil {
+		return db.Upload{}, nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	return upload, body, nil
+}
+
+// ListFilter narrows the uploads listed; empty fields match everything
+type ListFilter struct {
+	Kind       string
+	EntityType string
+	EntityID   pgtype.UUID
+}
+
+// List returns a page of a tenant's uploads, newest first, and how many
+// match the filter
+func (s *Service) List(ctx context.Context, tenantID pgtype.UUID, filter ListFilter, limit, offset string) ([]db.Upload, int64, error) {
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil || limitInt < 1 {
+		limitInt = 50
+	}
+	if limitInt > 100 {
+		limitInt = 100
+	}
+
+	offsetInt, err := strconv.Atoi(offset)
+	if err != nil || offsetInt < 0 {
+		offsetInt = 0
+	}
+
+	kind := pgtype.Text{String: filter.Kind, Valid: filter.Kind != ""}
+	entityType := pgtype.Text{String: filter.EntityType, Valid: filter.EntityType != ""}
+
+	uploads, err := s.queries.ListUploads(ctx, db.ListUploadsParams{
+		TenantID:   tenantID,
+		Kind:       kind,
+		EntityType: entityType,
+		EntityID:   filter.EntityID,
+		RowLimit:   int32(limitInt),
+		RowOffset:  int32(offsetInt),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list uploads: %w", err)
+	}
+
+	total, err := s.queries.CountUploads(ctx, db.CountUploadsParams{
+		TenantID:   tenantID,
+		Kind:       kind,
+		EntityType: entityType,
+		EntityID:   filter.EntityID,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count uploads: %w", err)
+	}
+
+	return uploads, total, nil
+}
+
+// objectKey builds the key an upload is stored under,
+// <tenant>/<kind>/<upload>/<filename>. The filename is reduced to safe
+// characters; the original is kept in the upload's row.
+func objectKey(tenantID, kind, id, filename string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, path.Base(filename))
+	if strings.Trim(name, ".") == "" {
+		name = "file"
+	}
+	return tenantID + "/" + kind + "/" + id + "/" + name
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/export"
+)
+
+var (
+	// ErrObjectNotFound is returned when reading an object that isn't in the
+	// store
+	ErrObjectNotFound = errors.New("object not found")
+
+	// ErrInvalidKey is returned for keys that are empty, absolute or would
+	// escape the store
+	ErrInvalidKey = errors.New("invalid storage key")
+)
+
+// Store persists uploaded files under slash-separated keys
+type Store interface {
+	Put(ctx context.Context, key string, body []byte, contentType string) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// Config selects and configures the object store uploads are kept in
+type Config struct {
+	// Backend is "local", "s3", or empty for no store
+	Backend string
+	// Dir is the root directory of the local store
+	Dir string
+	// Bucket is the S3 bucket uploads are kept in
+	Bucket string
+	S3     export.S3Config
+}
+
+// NewStore creates the store config selects. It returns nil when no backend
+// is configured, in which case uploads are processed but not kept.
+func NewStore(config Config) (Store, error) {
+	switch config.Backend {
+	case "":
+		return nil, nil
+	case "local":
+		store, err := NewLocalStore(config.Dir)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case "s3":
+		if config.Bucket == "" {
+			return nil, fmt.Errorf("s3 upload storage requires a bucket")
+		}
+		uploader, err := export.NewS3Uploader(config.S3)
+		if err != nil {
+			return nil, err
+		}
+		return NewS3Store(uploader, config.Bucket), nil
+	default:
+		return nil, fmt.Errorf("unknown upload storage backend: %s", config.Backend)
+	}
+}
+
+// validateKey rejects keys that are empty, absolute or contain empty, "." or
+// ".." segments
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package supplier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	// ErrSupplierNotFound is returned when the supplier does not exist for the tenant
+	ErrSupplierNotFound = errors.New("supplier not found")
+
+	// ErrSupplierExists is returned when another supplier already uses the name
+	ErrSupplierExists = errors.New("supplier name already exists")
+
+	// ErrSupplierInactive is returned when a reward references a deactivated supplier
+	ErrSupplierInactive = errors.New("supplier is inactive")
+
+	// ErrSupplierInUse is returned when deactivating a supplier that active
+	// rewards are still fulfilled by
+	ErrSupplierInUse = errors.New("supplier has active rewards")
+)
+
+// secretKeys are substrings of integration keys that look like credentials.
+// Credentials are configured in the environment, so they never reach the
+// database or API responses.
+var secretKeys = []string{"secret", "password", "token", "api_key", "apikey", "private_key"}
+
+// Params contains the fields of a supplier
+type Params struct {
+	TenantID     pgtype.UUID
+	Name         string
+	ContactName  string
+	ContactEmail string
+	ContactPhone string
+	Integration  map[string]interface{}
+	Active       bool
+}
+
+// Validate validates the supplier parameters
+func (p Params) Validate() error {
+	if !p.TenantID.Valid {
+		return errors.New("tenant_id is required")
+	}
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("name is required")
+	}
+	if email := strings.TrimSpace(p.ContactEmail); email != "" {
+		if _, err := mail.ParseAddress(email); err != nil {
+			return errors.New("contact_email is not a valid email address")
+		}
+	}
+	for key := range p.Integration {
+		lower := strings.ToLower(key)
+		for _, secret := range secretKeys {
+			if strings.Contains(lower, secret) {
+				return fmt.Errorf("integration must not contain credentials (%s); configure them in the environment", key)
+			}
+		}
+	}
+	return nil
+}
+
+// Performance is how a supplier fulfilled the issuances reserved in a period.
+// SuccessRate is the share of settled issuances (fulfilled or failed) that
+// were fulfilled; pending issuances are still being processed.
+type Performance struct {
+	SupplierID        pgtype.UUID
+	Name              string
+	Total             int64
+	Fulfilled         int64
+	Failed            int64
+	Pending           int64
+	SuccessRate       float64
+	AvgLatencySeconds float64
+	P95LatencySeconds float64
+}
+
+// Service handles supplier business logic
+type Service struct {
+	queries *db.Queries
+}
+
+// NewService creates a new supplier service
+func NewService(queries *db.Queries) *Service {
+	return &Service{queries: queries}
+}
+
+// Create creates a new supplier
+func (s *Service) Create(ctx context.Context, params Params) (db.Supplier, error) {
+	if err := params.Validate(); err != nil {
+		return db.Supplier{}, err
+	}
+
+	integration, err := integrationJSON(params.Integration)
+	if err != nil {
+		return db.Supplier{}, err
+	}
+
+	created, err := s.queries.CreateSupplier(ctx, db.CreateSupplierParams{
+		TenantID:     params.TenantID,
+		Name:         strings.TrimSpace(params.Name),
+		ContactName:  optionalText(params.ContactName),
+		ContactEmail: optionalText(params.ContactEmail),
+		ContactPhone: optionalText(params.ContactPhone),
+		Integration:  integration,
+		Active:       params.Active,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return db.Supplier{}, ErrSupplierExists
+		}
+		return db.Supplier{}, fmt.Errorf("failed to create supplier: %w", err)
+	}
+
+	return created, nil
+}
+
+// Get retrieves a supplier by ID
+func (s *Service) Get(ctx context.Context, tenantID, id pgtype.UUID) (db.Supplier, error) {
+	supplier, err := s.queries.GetSupplierByID(ctx, db.GetSupplierByIDParams{
+		ID:       id,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Supplier{}, ErrSupplierNotFound
+		}
+		return db.Supplier{}, fmt.Errorf("failed to get supplier: %w", err)
+	}
+	return supplier, nil
+}
+
+// List lists the tenant's suppliers by name
+func (s *Service) List(ctx context.Context, tenantID pgtype.UUID, activeOnly bool) ([]db.Supplier, error) {
+	suppliers, err := s.queries.ListSuppliers(ctx, db.ListSuppliersParams{
+		TenantID:   tenantID,
+		ActiveOnly: activeOnly,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list suppliers: %w", err)
+	}
+	return suppliers, nil
+}
+
+// Update replaces the fields of a supplier
+func (s *Service) Update(ctx context.Context, id pgtype.UUID, params Params) (db.Supplier, error) {
+	if err := params.Validate(); err != nil {
+		return db.Supplier{}, err
+	}
+
+	integration, err := integrationJSON(params.Integration)
+	if err != nil {
+		return db.Supplier{}, err
+	}
+
+	updated, err := s.queries.UpdateSupplier(ctx, db.UpdateSupplierParams{
+		ID:           id,
+		TenantID:     params.TenantID,
+		Name:         strings.TrimSpace(params.Name),
+		ContactName:  optionalText(params.ContactName),
+		ContactEmail: optionalText(params.ContactEmail),
+		ContactPhone: optionalText(params.ContactPhone),
+		Integration:  integration,
+		Active:       params.Active,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Supplier{}, ErrSupplierNotFound
+		}
+		if isUniqueViolation(err) {
+			return db.Supplier{}, ErrSupplierExists
+		}
+		return db.Supplier{}, fmt.Errorf("failed to update supplier: %w", err)
+	}
+	return updated, nil
+}
+
+// Deactivate stops a supplier from being linked to rewards. Suppliers are
+// never deleted so historical issuances keep their attribution, and one can't
+// be deactivated while active rewards still use it.
+func (s *Service) Deactivate(ctx context.Context, tenantID, id pgtype.UUID) error {
+	if _, err := s.Get(ctx, tenantID, id); err != nil {
+		return err
+	}
+
+	rewards, err := s.queries.CountSupplierRewards(ctx, db.CountSupplierRewardsParams{
+		TenantID:   tenantID,
+		SupplierID: id,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count supplier rewards: %w", err)
+	}
+	if rewards > 0 {
+		return ErrSupplierInUse
+	}
+
+	if err := s.queries.DeactivateSupplier(ctx, db.DeactivateSupplierParams{
+		ID:       id,
+		TenantID: tenantID,
+	}); err != nil {
+		return fmt.Errorf("failed to deactivate supplier: %w", err)
+	}
+	return nil
+}
+
+// ValidateSupplier checks that a supplier exists and is active for the tenant
+func (s *Service) ValidateSupplier(ctx context.Context, tenantID, id pgtype.UUID) error {
+	supplier, err := s.Get(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if !supplier.Active {
+		return ErrSupplierInactive
+	}
+	return nil
+}
+
+// Performance reports each supplier's fulfilment of the issuances reserved in
+// [from, to). supplierID optionally limits the report to one supplier.
+func (s *Service) Performance(ctx context.Context, tenantID, supplierID pgtype.UUID, from, to time.Time) ([]Performance, error) {
+	rows, err := s.queries.SupplierPerformance(ctx, db.SupplierPerformanceParams{
+		TenantID:   tenantID,
+		SupplierID: supplierID,
+		FromTime:   pgtype.Timestamptz{Time: from, Valid: true},
+		ToTime:     pgtype.Timestamptz{Time: to, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to report supplier performance: %w", err)
+	}
+
+	report := make([]Performance, len(rows))
+	for i, row := range rows {
+		report[i] = Performance{
+			SupplierID:        row.SupplierID,
+			Name:              row.SupplierName,
+			Total:             row.Total,
+			Fulfilled:         row.Fulfilled,
+			Failed:            row.Failed,
+			Pending:           row.Pending,
+			SuccessRate:       successRate(row.Fulfilled, row.Failed),
+			AvgLatencySeconds: row.AvgLatencySeconds,
+			P95LatencySeconds: row.P95LatencySeconds,
+		}
+	}
+	return report, nil
+}
+
+// successRate is the share of settled issuances that were fulfilled, 0 when
+// none have settled
+func successRate(fulfilled, failed int64) float64 {
+	if fulfilled+failed == 0 {
+		return 0
+	}
+	return float64(fulfilled) / float64(fulfilled+failed)
+}
+
+func integrationJSON(integration map[string]interface{}) ([]byte, error) {
+	if integration == nil {
+		return []byte("{}"), nil
+	}
+	data, err := json.Marshal(integration)
+	if err != nil {
+		return nil, fmt.Errorf("invalid integration config: %w", err)
+	}
+	return data, nil
+}
+
+func optionalText(value string) pgtype.Text {
+	value = strings.TrimSpace(value)
+	return pgtype.Text{String: value, Valid: value != ""}
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,52 @@
+package supplier
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestParamsValidate(t *testing.T) {
+	tenantID := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+
+	tests := []struct {
+		name    string
+		params  Params
+		wantErr bool
+	}{
+		{"valid", Params{TenantID: tenantID, Name: "Airtime Co", ContactEmail: "ops@airtime.example"}, false},
+		{"integration settings", Params{TenantID: tenantID, Name: "Airtime Co", Integration: map[string]interface{}{"connector": "airtime", "base_url": "https://api.airtime.example"}}, false},
+		{"missing tenant", Params{Name: "Airtime Co"}, true},
+		{"missing name", Params{TenantID: tenantID, Name: "  "}, true},
+		{"bad email", Params{TenantID: tenantID, Name: "Airtime Co", ContactEmail: "ops"}, true},
+		{"api key", Params{TenantID: tenantID, Name: "Airtime Co", Integration: map[string]interface{}{"API_KEY": "abc"}}, true},
+		{"client secret", Params{TenantID: tenantID, Name: "Airtime Co", Integration: map[string]interface{}{"client_secret": "abc"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSuccessRate(t *testing.T) {
+	tests := []struct {
+		fulfilled, failed int64
+		want              float64
+	}{
+		{0, 0, 0},
+		{9, 1, 0.9},
+		{0, 3, 0},
+		{4, 0, 1},
+	}
+
+	for _, tt := range tests {
+		if got := successRate(tt.fulfilled, tt.failed); got != tt.want {
+			t.Errorf("successRate(%d, %d) = %v, want %v", tt.fulfilled, tt.failed, got, tt.want)
+		}
+	}
+}
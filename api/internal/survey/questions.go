@@ -0,0 +1,210 @@
+package survey
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Question types
+const (
+	QuestionNPS    = "nps"    // 0-10 likelihood to recommend
+	QuestionRating = "rating" // 1-5 stars
+	QuestionChoice = "choice" // one of Options
+	QuestionText   = "text"   // free text
+)
+
+// MaxQuestions keeps surveys short enough to answer over WhatsApp
+const MaxQuestions = 10
+
+// Question is a single survey question
+type Question struct {
+	ID      string   `json:"id"`
+	Type    string   `json:"type"`
+	Text    string   `json:"text"`
+	Options []string `json:"options,omitempty"`
+}
+
+// Validate validates the question
+func (q Question) Validate() error {
+	if strings.TrimSpace(q.ID) == "" {
+		return errors.New("question id is required")
+	}
+	if strings.TrimSpace(q.Text) == "" {
+		return fmt.Errorf("question %q: text is required", q.ID)
+	}
+	switch q.Type {
+	case QuestionNPS, QuestionRating, QuestionText:
+		if len(q.Options) > 0 {
+			return fmt.Errorf("question %q: options are only allowed for choice questions", q.ID)
+		}
+	case QuestionChoice:
+		if len(q.Options) < 2 {
+			return fmt.Errorf("question %q: choice questions need at least 2 options", q.ID)
+		}
+	default:
+		return fmt.Errorf("question %q: unknown type %q", q.ID, q.Type)
+	}
+	return nil
+}
+
+// ValidateQuestions validates an ordered list of questions
+func ValidateQuestions(questions []Question) error {
+	if len(questions) == 0 {
+		return errors.New("at least one question is required")
+	}
+	if len(questions) > MaxQuestions {
+		return fmt.Errorf("surveys can have at most %d questions", MaxQuestions)
+	}
+	seen := make(map[string]bool, len(questions))
+	for _, q := range questions {
+		if err := q.Validate(); err != nil {
+			return err
+		}
+		if seen[q.ID] {
+			return fmt.Errorf("duplicate question id %q", q.ID)
+		}
+		seen[q.ID] = true
+	}
+	return nil
+}
+
+// Prompt renders the question as a chat message
+func (q Question) Prompt() string {
+	var b strings.Builder
+	b.WriteString(q.Text)
+	switch q.Type {
+	case QuestionNPS:
+		b.WriteString("\n\nReply with a number from 0 (not likely) to 10 (very likely).")
+	case QuestionRating:
+		b.WriteString("\n\nReply with a number from 1 to 5.")
+	case QuestionChoice:
+		b.WriteString("\n")
+		for i, option := range q.Options {
+			fmt.Fprintf(&b, "\n%d. %s", i+1, option)
+		}
+		b.WriteString("\n\nReply with the number of your answer.")
+	}
+	return b.String()
+}
+
+// ParseAnswer validates a reply to the question and returns the value to
+// store: a number for nps and rating, the chosen option for choice, and the
+// trimmed text for text questions.
+func (q Question) ParseAnswer(reply string) (interface{}, error) {
+	reply = strings.TrimSpace(reply)
+	if reply == "" {
+		return nil, errors.New("please send an answer")
+	}
+
+	switch q.Type {
+	case QuestionNPS:
+		n, err := strconv.Atoi(reply)
+		if err != nil || n < 0 || n > 10 {
+			return nil, errors.New("please reply with a number from 0 to 10")
+		}
+		return n, nil
+	case QuestionRating:
+		n, err := strconv.Atoi(reply)
+		if err != nil || n < 1 || n > 5 {
+			return nil, errors.New("please reply with a number from 1 to 5")
+		}
+		return n, nil
+	case QuestionChoice:
+		if n, err := strconv.Atoi(reply); err == nil && n >= 1 && n <= len(q.Options) {
+			return q.Options[n-1], nil
+		}
+		for _, option := range q.Options {
+			if strings.EqualFold(option, reply) {
+				return option, nil
+			}
+		}
+		return nil, fmt.Errorf("please reply with a number from 1 to %d", len(q.Options))
+	default:
+		if len(reply) > 1000 {
+			reply = reply[:1000]
+		}
+		return reply, nil
+	}
+}
+
+// QuestionResult aggregates the answers to one question
+type QuestionResult struct {
+	ID      string         `json:"id"`
+	Type    string         `json:"type"`
+	Text    string         `json:"text"`
+	Answers int            `json:"answers"`
+	Average *float64       `json:"average,omitempty"`
+	NPS     *NPSResult     `json:"nps,omitempty"`
+	Choices map[string]int `json:"choices,omitempty"`
+}
+
+// NPSResult is the Net Promoter Score breakdown of an nps question
+type NPSResult struct {
+	Promoters  int     `json:"promoters"`
+	Passives   int     `json:"passives"`
+	Detractors int     `json:"detractors"`
+	Score      float64 `json:"score"`
+}
+
+// Aggregate summarises completed answers per question. Free text answers
+// are only counted.
+func Aggregate(questions []Question, answers []map[string]interface{}) []QuestionResult {
+	results := make([]QuestionResult, len(questions))
+	for i, q := range questions {
+		result := QuestionResult{ID: q.ID, Type: q.Type, Text: q.Text}
+		var sum float64
+		var nps NPSResult
+		if q.Type == QuestionChoice {
+			result.Choices = make(map[string]int, len(q.Options))
+			for _, option := range q.Options {
+				result.Choices[option] = 0
+			}
+		}
+
+		for _, answer := range answers {
+			value, ok := answer[q.ID]
+			if !ok {
+				continue
+			}
+			result.Answers++
+
+			switch q.Type {
+			case QuestionNPS, QuestionRating:
+				n, ok := value.(float64)
+				if !ok {
+					continue
+				}
+				sum += n
+				if q.Type == QuestionNPS {
+					switch {
+					case n >= 9:
+						nps.Promoters++
+					case n >= 7:
+						nps.Passives++
+					default:
+						nps.Detractors++
+					}
+				}
+			case QuestionChoice:
+				if option, ok := value.(string); ok {
+					result.Choices[option]++
+				}
+			}
+		}
+
+		if result.Answers > 0 && (q.Type == QuestionNPS || q.Type == QuestionRating) {
+			average := sum / float64(result.Answers)
+			result.Average = &average
+		}
+		if q.Type == QuestionNPS {
+			if result.Answers > 0 {
+				nps.Score = float64(nps.Promoters-nps.Detractors) * 100 / float64(result.Answers)
+			}
+			result.NPS = &nps
+		}
+		results[i] = result
+	}
+	return results
+}
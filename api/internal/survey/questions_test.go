@@ -0,0 +1,82 @@
+package survey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateQuestions(t *testing.T) {
+	valid := []Question{
+		{ID: "nps", Type: QuestionNPS, Text: "How likely are you to recommend us?"},
+		{ID: "visit", Type: QuestionChoice, Text: "What did you buy?", Options: []string{"Groceries", "Fuel"}},
+	}
+	assert.NoError(t, ValidateQuestions(valid))
+
+	assert.Error(t, ValidateQuestions(nil))
+	assert.Error(t, ValidateQuestions([]Question{{ID: "a", Type: "stars", Text: "?"}}))
+	assert.Error(t, ValidateQuestions([]Question{{ID: "a", Type: QuestionChoice, Text: "?", Options: []string{"only"}}}))
+	assert.Error(t, ValidateQuestions([]Question{
+		{ID: "a", Type: QuestionText, Text: "?"},
+		{ID: "a", Type: QuestionText, Text: "?"},
+	}))
+}
+
+func TestParseAnswer(t *testing.T) {
+	nps := Question{ID: "nps", Type: QuestionNPS, Text: "?"}
+	value, err := nps.ParseAnswer(" 9 ")
+	require.NoError(t, err)
+	assert.Equal(t, 9, value)
+	_, err = nps.ParseAnswer("11")
+	assert.Error(t, err)
+
+	rating := Question{ID: "r", Type: QuestionRating, Text: "?"}
+	_, err = rating.ParseAnswer("0")
+	assert.Error(t, err)
+
+	choice := Question{ID: "c", Type: QuestionChoice, Text: "?", Options: []string{"Yes", "No"}}
+	value, err = choice.ParseAnswer("2")
+	require.NoError(t, err)
+	assert.Equal(t, "No", value)
+	value, err = choice.ParseAnswer("yes")
+	require.NoError(t, err)
+	assert.Equal(t, "Yes", value)
+	_, err = choice.ParseAnswer("3")
+	assert.Error(t, err)
+
+	text := Question{ID: "t", Type: QuestionText, Text: "?"}
+	_, err = text.ParseAnswer("   ")
+	assert.Error(t, err)
+}
+
+func TestAggregate(t *testing.T) {
+	questions := []Question{
+		{ID: "nps", Type: QuestionNPS, Text: "?"},
+		{ID: "c", Type: QuestionChoice, Text: "?", Options: []string{"Yes", "No"}},
+		{ID: "t", Type: QuestionText, Text: "?"},
+	}
+	// Answers as decoded from JSON
+	answers := []map[string]interface{}{
+		{"nps": 10.0, "c": "Yes", "t": "Great"},
+		{"nps": 9.0, "c": "Yes"},
+		{"nps": 7.0, "c": "No"},
+		{"nps": 2.0},
+	}
+
+	results := Aggregate(questions, answers)
+	require.Len(t, results, 3)
+
+	require.NotNil(t, results[0].NPS)
+	assert.Equal(t, 4, results[0].Answers)
+	assert.Equal(t, 2, results[0].NPS.Promoters)
+	assert.Equal(t, 1, results[0].NPS.Passives)
+	assert.Equal(t, 1, results[0].NPS.Detractors)
+	assert.InDelta(t, 25.0, results[0].NPS.Score, 0.001)
+	require.NotNil(t, results[0].Average)
+	assert.InDelta(t, 7.0, *results[0].Average, 0.001)
+
+	assert.Equal(t, map[string]int{"Yes": 2, "No": 1}, results[1].Choices)
+	assert.Equal(t, 1, results[2].Answers)
+	assert.Nil(t, results[2].Average)
+}
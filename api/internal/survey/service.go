@@ -0,0 +1,544 @@
+package survey
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/deadletter"
+	"github.com/bmachimbira/loyalty/api/internal/featureflag"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Survey triggers
+const (
+	TriggerPostRedemption = "post_redemption"
+	TriggerManual         = "manual"
+)
+
+// Survey response statuses
+const (
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+	StatusAbandoned  = "abandoned"
+)
+
+// EventTypeSurveyCompleted is emitted when a customer answers every question
+const EventTypeSurveyCompleted = "survey_completed"
+
+// triggerTimeout bounds a background post-redemption trigger
+const triggerTimeout = 30 * time.Second
+
+var (
+	// ErrSurveyNotFound is returned when the survey does not exist
+	ErrSurveyNotFound = errors.New("survey not found")
+
+	// ErrSurveyInactive is returned when sending an inactive survey
+	ErrSurveyInactive = errors.New("survey is inactive")
+
+	// ErrResponseNotFound is returned when the survey response does not exist
+	ErrResponseNotFound = errors.New("survey response not found")
+
+	// ErrResponseClosed is returned when answering a completed or abandoned survey
+	ErrResponseClosed = errors.New("survey response is no longer in progress")
+
+	// ErrNoDeliverer is returned when no channel is configured to send surveys
+	ErrNoDeliverer = errors.New("no survey delivery channel configured")
+
+	// ErrCustomerNotFound is returned when sending a survey to an unknown customer
+	ErrCustomerNotFound = errors.New("customer not found")
+
+	// ErrAlreadySent is returned when a redemption already triggered the survey
+	ErrAlreadySent = errors.New("survey already sent for this redemption")
+
+	// ErrSurveysDisabled is returned when the tenant has turned surveys off
+	// for WhatsApp
+	ErrSurveysDisabled = errors.New("surveys are disabled for the tenant")
+)
+
+// Deliverer sends survey questions to a customer over a messaging channel
+// and routes the customer's replies back to Answer
+type Deliverer interface {
+	DeliverSurveyQuestion(ctx context.Context, tenantID, customerID, responseID pgtype.UUID, prompt string) error
+}
+
+// Params contains the fields of a survey
+type Params struct {
+	TenantID  pgtype.UUID
+	Name      string
+	Trigger   string
+	Questions []Question
+	Active    bool
+}
+
+// Validate validates the survey parameters
+func (p Params) Validate() error {
+	if !p.TenantID.Valid {
+		return errors.New("tenant_id is required")
+	}
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("name is required")
+	}
+	if p.Trigger != TriggerPostRedemption && p.Trigger != TriggerManual {
+		return fmt.Errorf("trigger must be %s or %s", TriggerPostRedemption, TriggerManual)
+	}
+	return ValidateQuestions(p.Questions)
+}
+
+// AnswerResult is the outcome of answering the current question
+type AnswerResult struct {
+	// Invalid is set when the reply was not a valid answer; the same
+	// question should be asked again
+	Invalid string
+	// Next is the prompt for the next question, empty when completed
+	Next      string
+	Completed bool
+	// Issuances are rewards granted by rules for the survey_completed event
+	Issuances []db.Issuance
+}
+
+// Results summarises the responses to a survey
+type Results struct {
+	SurveyID  pgtype.UUID
+	Started   int64
+	Completed int64
+	Abandoned int64
+	Questions []QuestionResult
+}
+
+// Service manages surveys and customer responses
+type Service struct {
+	pool        *pgxpool.Pool
+	queries     *db.Queries
+	processor   deadletter.EventProcessor
+	deadLetters *deadletter.Service
+	deliverer   Deliverer
+	features    *featureflag.Service
+	logger      *slog.Logger
+	triggers    sync.WaitGroup
+}
+
+// NewService creates a new survey service. Completed surveys emit a
+// survey_completed event that is run through processor.
+func NewService(pool *pgxpool.Pool, queries *db.Queries, processor deadletter.EventProcessor, logger *slog.Logger) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Service{
+		pool:        pool,
+		queries:     queries,
+		processor:   processor,
+		deadLetters: deadletter.NewService(queries, processor),
+		logger:      logger,
+	}
+}
+
+// SetDeliverer sets the channel used to send survey questions
+func (s *Service) SetDeliverer(deliverer Deliverer) {
+	s.deliverer = deliverer
+}
+
+// SetFeatureFlags lets tenants turn surveys off. Surveys are answered over
+// WhatsApp, so the WhatsApp flag applies.
+func (s *Service) SetFeatureFlags(features *featureflag.Service) {
+	s.features = features
+}
+
+// Create creates a new survey
+func (s *Service) Create(ctx context.Context, params Params) (db.Survey, error) {
+	if err := params.Validate(); err != nil {
+		return db.Survey{}, err
+	}
+
+	questions, err := json.Marshal(params.Questions)
+	if err != nil {
+		return db.Survey{}, fmt.Errorf("failed to marshal questions: %w", err)
+	}
+
+	survey, err := s.queries.CreateSurvey(ctx, db.CreateSurveyParams{
+		TenantID:  params.TenantID,
+		Name:      strings.TrimSpace(params.Name),
+		Trigger:   params.Trigger,
+		Questions: questions,
+		Active:    params.Active,
+	})
+	if err != nil {
+		return db.Survey{}, fmt.Errorf("failed to create survey: %w", err)
+	}
+	return survey, nil
+}
+
+// Get retrieves a survey by ID
+func (s *Service) Get(ctx context.Context, tenantID, surveyID pgtype.UUID) (db.Survey, error) {
+	survey, err := s.queries.GetSurveyByID(ctx, db.GetSurveyByIDParams{
+		ID:       surveyID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Survey{}, ErrSurveyNotFound
+		}
+		return db.Survey{}, fmt.Errorf("failed to get survey: %w", err)
+	}
+	return survey, nil
+}
+
+// List lists the tenant's surveys
+func (s *Service) List(ctx context.Context, tenantID pgtype.UUID) ([]db.Survey, error) {
+	surveys, err := s.queries.ListSurveys(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list surveys: %w", err)
+	}
+	return surveys, nil
+}
+
+// Update replaces the fields of a survey. Responses already in progress
+// keep answering by question index, so editing questions mid-flight only
+// affects later questions.
+func (s *Service) Update(ctx context.Context, surveyID pgtype.UUID, params Params) (db.Survey, error) {
+	if err := params.Validate(); err != nil {
+		return db.Survey{}, err
+	}
+
+	questions, err := json.Marshal(params.Questions)
+	if err != nil {
+		return db.Survey{}, fmt.Errorf("failed to marshal questions: %w", err)
+	}
+
+	survey, err := s.queries.UpdateSurvey(ctx, db.UpdateSurveyParams{
+		ID:        surveyID,
+		TenantID:  params.TenantID,
+		Name:      strings.TrimSpace(params.Name),
+		Trigger:   params.Trigger,
+		Questions: questions,
+		Active:    params.Active,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Survey{}, ErrSurveyNotFound
+		}
+		return db.Survey{}, fmt.Errorf("failed to update survey: %w", err)
+	}
+	return survey, nil
+}
+
+// ParseQuestions decodes the stored questions of a survey
+func ParseQuestions(raw []byte) []Question {
+	questions := []Question{}
+	if len(raw) > 0 {
+		json.Unmarshal(raw, &questions)
+	}
+	return questions
+}
+
+// Send starts a survey for a customer and delivers the first question.
+// issuanceID links the response to the redemption that triggered it and
+// may be invalid for manually sent surveys.
+func (s *Service) Send(ctx context.Context, survey db.Survey, customerID, issuanceID pgtype.UUID) (db.SurveyResponse, error) {
+	if s.deliverer == nil {
+		return db.SurveyResponse{}, ErrNoDeliverer
+	}
+	if !survey.Active {
+		return db.SurveyResponse{}, ErrSurveyInactive
+	}
+	if s.features != nil && !s.features.Enabled(ctx, survey.TenantID, featureflag.ChannelWhatsApp, featureflag.FeatureSurveys) {
+		return db.SurveyResponse{}, ErrSurveysDisabled
+	}
+
+	questions := ParseQuestions(survey.Questions)
+	if len(questions) == 0 {
+		return db.SurveyResponse{}, errors.New("survey has no questions")
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.SurveyResponse{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	// A customer answers one survey at a time over chat
+	if err := qtx.AbandonOpenSurveyResponses(ctx, db.AbandonOpenSurveyResponsesParams{
+		TenantID:   survey.TenantID,
+		CustomerID: customerID,
+	}); err != nil {
+		return db.SurveyResponse{}, fmt.Errorf("failed to abandon open survey responses: %w", err)
+	}
+
+	response, err := qtx.CreateSurveyResponse(ctx, db.CreateSurveyResponseParams{
+		TenantID:   survey.TenantID,
+		SurveyID:   survey.ID,
+		CustomerID: customerID,
+		IssuanceID: issuanceID,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case "23505":
+				return db.SurveyResponse{}, ErrAlreadySent
+			case "23503":
+				return db.SurveyResponse{}, ErrCustomerNotFound
+			}
+		}
+		return db.SurveyResponse{}, fmt.Errorf("failed to create survey response: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.SurveyResponse{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if err := s.deliverer.DeliverSurveyQuestion(ctx, survey.TenantID, customerID, response.ID, questions[0].Prompt()); err != nil {
+		return response, fmt.Errorf("failed to deliver survey: %w", err)
+	}
+
+	return response, nil
+}
+
+// TriggerAfterRedemption sends the tenant's active post-redemption survey,
+// if any, in the background so redemption is not slowed down by delivery
+func (s *Service) TriggerAfterRedemption(tenantID, customerID, issuanceID pgtype.UUID) {
+	if s.deliverer == nil {
+		return
+	}
+
+	s.triggers.Add(1)
+	go func() {
+		defer s.triggers.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), triggerTimeout)
+		defer cancel()
+
+		survey, err := s.queries.GetActiveSurveyByTrigger(ctx, db.GetActiveSurveyByTriggerParams{
+			TenantID: tenantID,
+			Trigger:  TriggerPostRedemption,
+		})
+		if err != nil {
+			if !errors.Is(err, pgx.ErrNoRows) {
+				s.logger.Error("failed to get post-redemption survey", "tenant_id", tenantID, "error", err)
+			}
+			return
+		}
+
+		if _, err := s.Send(ctx, survey, customerID, issuanceID); err != nil && !errors.Is(err, ErrAlreadySent) && !errors.Is(err, ErrSurveysDisabled) {
+			s.logger.Error("failed to send post-redemption survey",
+				"survey_id", survey.ID,
+				"issuance_id", issuanceID,
+				"error", err,
+			)
+		}
+	}()
+}
+
+// WaitForTriggers blocks until in-flight background survey triggers finish
+func (s *Service) WaitForTriggers() {
+	s.triggers.Wait()
+}
+
+// Answer records a reply to the current question of a survey response.
+// When the last question is answered the response is completed and a
+// survey_completed event is emitted so rules can grant a reward.
+func (s *Service) Answer(ctx context.Context, tenantID, responseID pgtype.UUID, reply string) (*AnswerResult, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	response, err := qtx.GetSurveyResponseForUpdate(ctx, db.GetSurveyResponseForUpdateParams{
+		ID:       responseID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrResponseNotFound
+		}
+		return nil, fmt.Errorf("failed to get survey response: %w", err)
+	}
+	if response.Status != StatusInProgress {
+		return nil, ErrResponseClosed
+	}
+
+	survey, err := qtx.GetSurveyByID(ctx, db.GetSurveyByIDParams{
+		ID:       response.SurveyID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get survey: %w", err)
+	}
+
+	questions := ParseQuestions(survey.Questions)
+	index := int(response.CurrentQuestion)
+	if index >= len(questions) {
+		return nil, ErrResponseClosed
+	}
+
+	question := questions[index]
+	value, err := question.ParseAnswer(reply)
+	if err != nil {
+		return &AnswerResult{Invalid: err.Error(), Next: question.Prompt()}, nil
+	}
+
+	answers := make(map[string]interface{})
+	if len(response.Answers) > 0 {
+		if err := json.Unmarshal(response.Answers, &answers); err != nil {
+			return nil, fmt.Errorf("failed to parse answers: %w", err)
+		}
+	}
+	answers[question.ID] = value
+
+	answersJSON, err := json.Marshal(answers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal answers: %w", err)
+	}
+
+	if err := qtx.RecordSurveyAnswer(ctx, db.RecordSurveyAnswerParams{
+		ID:              response.ID,
+		TenantID:        tenantID,
+		Answers:         answersJSON,
+		CurrentQuestion: int32(index + 1),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to record answer: %w", err)
+	}
+
+	if index+1 < len(questions) {
+		if err := tx.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return &AnswerResult{Next: questions[index+1].Prompt()}, nil
+	}
+
+	event, err := s.insertCompletedEvent(ctx, qtx, response, answers, questions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := qtx.CompleteSurveyResponse(ctx, db.CompleteSurveyResponseParams{
+		ID:       response.ID,
+		TenantID: tenantID,
+		EventID:  event.ID,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to complete survey response: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result := &AnswerResult{Completed: true}
+
+	issuances, err := s.processor.ProcessEvent(ctx, event)
+	if err != nil {
+		// The survey is complete; park the event so the reward can be retried
+		s.logger.Error("rules engine processing failed",
+			"event_id", event.ID,
+			"survey_response_id", response.ID,
+			"error", err,
+		)
+		if _, dlqErr := s.deadLetters.Record(ctx, event, err); dlqErr != nil {
+			s.logger.Error("failed to record dead letter", "event_id", event.ID, "error", dlqErr)
+		}
+		return result, nil
+	}
+	result.Issuances = issuances
+
+	return result, nil
+}
+
+// insertCompletedEvent records the survey_completed event for a response.
+// The NPS score, if the survey asks for one, is included so rules can
+// target promoters or detractors.
+func (s *Service) insertCompletedEvent(ctx context.Context, qtx *db.Queries, response db.SurveyResponse, answers map[string]interface{}, questions []Question) (db.Event, error) {
+	properties := map[string]interface{}{
+		"survey_id":          httputil.FormatUUID(response.SurveyID.Bytes),
+		"survey_response_id": httputil.FormatUUID(response.ID.Bytes),
+	}
+	if response.IssuanceID.Valid {
+		properties["issuance_id"] = httputil.FormatUUID(response.IssuanceID.Bytes)
+	}
+	for _, q := range questions {
+		if q.Type == QuestionNPS {
+			if score, ok := answers[q.ID]; ok {
+				properties["nps_score"] = score
+				break
+			}
+		}
+	}
+
+	propertiesJSON, err := json.Marshal(properties)
+	if err != nil {
+		return db.Event{}, fmt.Errorf("failed to marshal event properties: %w", err)
+	}
+
+	event, err := qtx.InsertEvent(ctx, db.InsertEventParams{
+		TenantID:       response.TenantID,
+		CustomerID:     response.CustomerID,
+		EventType:      EventTypeSurveyCompleted,
+		Properties:     propertiesJSON,
+		OccurredAt:     pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Source:         "survey",
+		IdempotencyKey: "survey:" + httputil.FormatUUID(response.ID.Bytes),
+	})
+	if err != nil {
+		return db.Event{}, fmt.Errorf("failed to create event: %w", err)
+	}
+	return event, nil
+}
+
+// Results aggregates the responses to a survey
+func (s *Service) Results(ctx context.Context, tenantID, surveyID pgtype.UUID) (*Results, error) {
+	survey, err := s.Get(ctx, tenantID, surveyID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.queries.CountSurveyResponsesByStatus(ctx, db.CountSurveyResponsesByStatusParams{
+		SurveyID: surveyID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count survey responses: %w", err)
+	}
+
+	results := &Results{SurveyID: survey.ID}
+	for _, count := range counts {
+		results.Started += count.Count
+		switch count.Status {
+		case StatusCompleted:
+			results.Completed = count.Count
+		case StatusAbandoned:
+			results.Abandoned = count.Count
+		}
+	}
+
+	rawAnswers, err := s.queries.ListCompletedSurveyAnswers(ctx, db.ListCompletedSurveyAnswersParams{
+		SurveyID: surveyID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list survey answers: %w", err)
+	}
+
+	answers := make([]map[string]interface{}, 0, len(rawAnswers))
+	for _, raw := range rawAnswers {
+		var answer map[string]interface{}
+		if err := json.Unmarshal(raw, &answer); err == nil {
+			answers = append(answers, answer)
+		}
+	}
+	results.Questions = Aggregate(ParseQuestions(survey.Questions), answers)
+
+	return results, nil
+}
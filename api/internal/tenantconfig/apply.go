@@ -0,0 +1,784 @@
+package tenantconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/approval"
+	"github.com/bmachimbira/loyalty/api/internal/catalogcache"
+	"github.com/bmachimbira/loyalty/api/internal/currency"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/reward/basket"
+	"github.com/bmachimbira/loyalty/api/internal/reward/codes"
+	"github.com/bmachimbira/loyalty/api/internal/reward/locations"
+	"github.com/bmachimbira/loyalty/api/internal/reward/value"
+	"github.com/bmachimbira/loyalty/api/internal/reward/window"
+	"github.com/bmachimbira/loyalty/api/internal/rule"
+	"github.com/bmachimbira/loyalty/api/internal/rules"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrAmbiguousName is returned when the tenant has more than one
+	// resource with a name the document uses
+	ErrAmbiguousName = errors.New("name matches more than one existing resource")
+
+	// ErrUnknownReferences is returned when a document references budgets or
+	// rewards that neither it nor the tenant has
+	ErrUnknownReferences = errors.New("document references unknown resources")
+
+	// ErrImmutableChange is returned when a document changes a field that
+	// cannot change once the resource exists
+	ErrImmutableChange = errors.New("field cannot be changed")
+
+	// ErrArchivedReward is returned when a document declares a reward whose
+	// name is taken by an archived reward
+	ErrArchivedReward = errors.New("reward is archived")
+
+	// ErrApprovalRequired is returned when a tenant that requires approval
+	// changes a campaign that is not a draft
+	ErrApprovalRequired = errors.New("campaign changes must be submitted for approval")
+)
+
+// Resource kinds
+const (
+	KindBudget   = "budget"
+	KindReward   = "reward"
+	KindCampaign = "campaign"
+	KindRule     = "rule"
+)
+
+// Change actions
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionNone   = "none"
+)
+
+// Change is a planned change to one resource
+type Change struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Campaign is the campaign of a rule
+	Campaign string `json:"campaign,omitempty"`
+	Action   string `json:"action"`
+	// Fields are the fields an update changes
+	Fields []string `json:"fields,omitempty"`
+}
+
+// Plan is the set of changes applying a document makes
+type Plan struct {
+	Changes []Change `json:"changes"`
+	// Applied is set once the changes have been made
+	Applied bool `json:"applied"`
+}
+
+// Count returns the number of changes with the given action
+func (p *Plan) Count(action string) int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Options control how a document is applied
+type Options struct {
+	// Apply makes the changes. Without it the changes are planned, checked
+	// against the database and rolled back.
+	Apply bool
+	// RequireApproval is set for tenants that require approval. New campaigns
+	// are created as drafts with inactive rules, rules are never activated,
+	// and campaigns that are not drafts cannot be changed.
+	RequireApproval bool
+}
+
+// Applier applies documents to tenants
+type Applier struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+	catalog *catalogcache.Cache
+}
+
+// NewApplier creates a new applier. Changed rewards and campaigns are
+// invalidated in catalog.
+func NewApplier(pool *pgxpool.Pool, queries *db.Queries, catalog *catalogcache.Cache) *Applier {
+	return &Applier{
+		pool:    pool,
+		queries: queries,
+		catalog: catalog,
+	}
+}
+
+// Apply plans the document's changes to the tenant and, with opts.Apply,
+// makes them, all or none. Budgets are applied first, then rewards, then
+// campaigns and their rules.
+func (a *Applier) Apply(ctx context.Context, tenantID pgtype.UUID, doc *Document, opts Options) (*Plan, error) {
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := a.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenantID.Bytes)); err != nil {
+		return nil, fmt.Errorf("failed to set tenant: %w", err)
+	}
+
+	qtx := a.queries.WithTx(tx)
+	run := &applyRun{
+		tenantID:   tenantID,
+		queries:    qtx,
+		currencies: currency.NewChecker(qtx),
+		opts:       opts,
+		plan:       &Plan{Changes: []Change{}},
+		budgetIDs:  make(map[string]pgtype.UUID),
+		rewardIDs:  make(map[string]pgtype.UUID),
+	}
+
+	if err := run.resolveReferences(ctx, doc); err != nil {
+		return nil, err
+	}
+	for _, spec := range doc.Budgets {
+		if err := run.applyBudget(ctx, spec); err != nil {
+			return nil, err
+		}
+	}
+	for _, spec := range doc.Rewards {
+		if err := run.applyReward(ctx, spec); err != nil {
+			return nil, err
+		}
+	}
+	for _, spec := range doc.Campaigns {
+		if err := run.applyCampaign(ctx, spec); err != nil {
+			return nil, err
+		}
+	}
+
+	if !opts.Apply {
+		return run.plan, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	run.plan.Applied = true
+
+	a.catalog.InvalidateRewardLists(tenantID)
+	for _, id := range run.changedRewards {
+		a.catalog.InvalidateReward(tenantID, id)
+	}
+	for _, id := range run.changedCampaigns {
+		a.catalog.InvalidateCampaign(tenantID, id)
+	}
+	return run.plan, nil
+}
+
+// applyRun is the state of applying one document
+type applyRun struct {
+	tenantID   pgtype.UUID
+	queries    *db.Queries
+	currencies *currency.Checker
+	opts       Options
+	plan       *Plan
+
+	// budgetIDs and rewardIDs resolve names to IDs. Resources the document
+	// creates are added as they are created.
+	budgetIDs map[string]pgtype.UUID
+	rewardIDs map[string]pgtype.UUID
+
+	changedRewards   []pgtype.UUID
+	changedCampaigns []pgtype.UUID
+}
+
+// resolveReferences checks that every budget and reward the document's
+// campaigns reference is declared by the document or exists
+func (r *applyRun) resolveReferences(ctx context.Context, doc *Document) error {
+	declaredBudgets := make(map[string]bool, len(doc.Budgets))
+	for _, b := range doc.Budgets {
+		declaredBudgets[b.Name] = true
+	}
+	declaredRewards := make(map[string]bool, len(doc.Rewards))
+	for _, rw := range doc.Rewards {
+		declaredRewards[rw.Name] = true
+	}
+
+	var unknown []string
+	for _, c := range doc.Campaigns {
+		if c.Budget != "" && !declaredBudgets[c.Budget] {
+			if _, ok := r.budgetIDs[c.Budget]; !ok {
+				budget, err := r.findBudget(ctx, c.Budget)
+				if err != nil {
+					return err
+				}
+				if budget == nil {
+					unknown = append(unknown, "budget "+strconv.Quote(c.Budget))
+					r.budgetIDs[c.Budget] = pgtype.UUID{}
+				} else {
+					r.budgetIDs[c.Budget] = budget.ID
+				}
+			}
+		}
+		for _, rl := range c.Rules {
+			for _, name := range rl.RewardNames() {
+				if declaredRewards[name] {
+					continue
+				}
+				if _, ok := r.rewardIDs[name]; ok {
+					continue
+				}
+				rewardItem, err := r.queries.GetRewardByName(ctx, db.GetRewardByNameParams{
+					TenantID: r.tenantID,
+					Name:     name,
+				})
+				if err != nil {
+					if !errors.Is(err, pgx.ErrNoRows) {
+						return fmt.Errorf("failed to get reward: %w", err)
+					}
+					unknown = append(unknown, "reward "+strconv.Quote(name))
+					r.rewardIDs[name] = pgtype.UUID{}
+					continue
+				}
+				r.rewardIDs[name] = rewardItem.ID
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("%w: %s", ErrUnknownReferences, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// findBudget returns the tenant's budget with the given name, or nil
+func (r *applyRun) findBudget(ctx context.Context, name string) (*db.Budget, error) {
+	budgets, err := r.queries.ListBudgetsByName(ctx, db.ListBudgetsByNameParams{
+		TenantID: r.tenantID,
+		Name:     name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list budgets: %w", err)
+	}
+	switch len(budgets) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &budgets[0], nil
+	default:
+		return nil, fmt.Errorf("%w: budget %q", ErrAmbiguousName, name)
+	}
+}
+
+// applyBudget creates or updates a budget
+func (r *applyRun) applyBudget(ctx context.Context, spec BudgetSpec) error {
+	if err := r.currencies.Check(ctx, r.tenantID, spec.Currency); err != nil {
+		return fmt.Errorf("%w: budget %q: %v", ErrInvalidDocument, spec.Name, err)
+	}
+
+	var softCap, hardCap pgtype.Numeric
+	if err := softCap.Scan(formatAmount(spec.SoftCap)); err != nil {
+		return fmt.Errorf("%w: budget %q has an invalid soft_cap", ErrInvalidDocument, spec.Name)
+	}
+	if err := hardCap.Scan(formatAmount(spec.HardCap)); err != nil {
+		return fmt.Errorf("%w: budget %q has an invalid hard_cap", ErrInvalidDocument, spec.Name)
+	}
+
+	existing, err := r.findBudget(ctx, spec.Name)
+	if err != nil {
+		return err
+	}
+	change := Change{Kind: KindBudget, Name: spec.Name}
+
+	if existing == nil {
+		var balance pgtype.Numeric
+		if err := balance.Scan("0"); err != nil {
+			return fmt.Errorf("failed to initialize balance: %w", err)
+		}
+		created, err := r.queries.CreateBudget(ctx, db.CreateBudgetParams{
+			TenantID: r.tenantID,
+			Name:     spec.Name,
+			Currency: spec.Currency,
+			SoftCap:  softCap,
+			HardCap:  hardCap,
+			Balance:  balance,
+			Period:   spec.Period,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create budget %q: %w", spec.Name, err)
+		}
+		r.budgetIDs[spec.Name] = created.ID
+		change.Action = ActionCreate
+		r.plan.Changes = append(r.plan.Changes, change)
+		return nil
+	}
+
+	r.budgetIDs[spec.Name] = existing.ID
+	fields, err := budgetChanges(spec, *existing)
+	if err != nil {
+		return err
+	}
+	change.Action = ActionNone
+	if len(fields) > 0 {
+		if _, err := r.queries.UpdateBudgetLimits(ctx, db.UpdateBudgetLimitsParams{
+			ID:       existing.ID,
+			TenantID: r.tenantID,
+			SoftCap:  softCap,
+			HardCap:  hardCap,
+			Period:   spec.Period,
+		}); err != nil {
+			return fmt.Errorf("failed to update budget %q: %w", spec.Name, err)
+		}
+		change.Action = ActionUpdate
+		change.Fields = fields
+	}
+	r.plan.Changes = append(r.plan.Changes, change)
+	return nil
+}
+
+// applyReward creates or updates a reward
+func (r *applyRun) applyReward(ctx context.Context, spec RewardSpec) error {
+	var faceValue pgtype.Numeric
+	if spec.FaceValue != nil {
+		if err := faceValue.Scan(formatAmount(*spec.FaceValue)); err != nil {
+			return fmt.Errorf("%w: reward %q has an invalid face_value", ErrInvalidDocument, spec.Name)
+		}
+	}
+
+	var currencyCode pgtype.Text
+	if spec.Currency != "" {
+		if err := r.currencies.Check(ctx, r.tenantID, spec.Currency); err != nil {
+			return fmt.Errorf("%w: reward %q: %v", ErrInvalidDocument, spec.Name, err)
+		}
+		currencyCode = pgtype.Text{String: spec.Currency, Valid: true}
+	}
+
+	metadata := []byte("{}")
+	if spec.Metadata != nil {
+		var err error
+		metadata, err = json.Marshal(spec.Metadata)
+		if err != nil {
+			return fmt.Errorf("%w: reward %q has invalid metadata", ErrInvalidDocument, spec.Name)
+		}
+	}
+	if _, err := codes.FromMetadata(metadata, codes.DefaultAlphanumeric); err != nil {
+		return fmt.Errorf("%w: reward %q: %v", ErrInvalidDocument, spec.Name, err)
+	}
+	if _, err := value.FromMetadata(metadata); err != nil {
+		return fmt.Errorf("%w: reward %q: %v", ErrInvalidDocument, spec.Name, err)
+	}
+	if _, err := basket.FromMetadata(metadata); err != nil {
+		return fmt.Errorf("%w: reward %q: %v", ErrInvalidDocument, spec.Name, err)
+	}
+	if _, err := locations.FromMetadata(metadata); err != nil {
+		return fmt.Errorf("%w: reward %q: %v", ErrInvalidDocument, spec.Name, err)
+	}
+	if _, err := window.FromMetadata(metadata); err != nil {
+		return fmt.Errorf("%w: reward %q: %v", ErrInvalidDocument, spec.Name, err)
+	}
+
+	change := Change{Kind: KindReward, Name: spec.Name}
+	existing, err := r.queries.GetRewardByName(ctx, db.GetRewardByNameParams{
+		TenantID: r.tenantID,
+		Name:     spec.Name,
+	})
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to get reward: %w", err)
+		}
+		created, err := r.queries.CreateReward(ctx, db.CreateRewardParams{
+			TenantID:  r.tenantID,
+			Name:      spec.Name,
+			Type:      spec.Type,
+			FaceValue: faceValue,
+			Currency:  currencyCode,
+			Inventory: spec.Inventory,
+			Metadata:  metadata,
+			Active:    spec.Active,
+		})
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				return fmt.Errorf("%w: restore %q before applying", ErrArchivedReward, spec.Name)
+			}
+			return fmt.Errorf("failed to create reward %q: %w", spec.Name, err)
+		}
+		r.rewardIDs[spec.Name] = created.ID
+		change.Action = ActionCreate
+		r.plan.Changes = append(r.plan.Changes, change)
+		return nil
+	}
+
+	r.rewardIDs[spec.Name] = existing.ID
+	fields, err := rewardChanges(spec, metadata, existing)
+	if err != nil {
+		return err
+	}
+	change.Action = ActionNone
+	if len(fields) > 0 {
+		if _, err := r.queries.UpdateRewardDefinition(ctx, db.UpdateRewardDefinitionParams{
+			ID:        existing.ID,
+			TenantID:  r.tenantID,
+			FaceValue: faceValue,
+			Currency:  currencyCode,
+			Metadata:  metadata,
+			Active:    spec.Active,
+		}); err != nil {
+			return fmt.Errorf("failed to update reward %q: %w", spec.Name, err)
+		}
+		r.changedRewards = append(r.changedRewards, existing.ID)
+		change.Action = ActionUpdate
+		change.Fields = fields
+	}
+	r.plan.Changes = append(r.plan.Changes, change)
+	return nil
+}
+
+// applyCampaign creates or updates a campaign, then its rules
+func (r *applyRun) applyCampaign(ctx context.Context, spec CampaignSpec) error {
+	var budgetID pgtype.UUID
+	if spec.Budget != "" {
+		budgetID = r.budgetIDs[spec.Budget]
+	}
+	startAt := timestamptz(spec.StartAt)
+	endAt := timestamptz(spec.EndAt)
+
+	campaigns, err := r.queries.ListCampaignsByName(ctx, db.ListCampaignsByNameParams{
+		TenantID: r.tenantID,
+		Name:     spec.Name,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list campaigns: %w", err)
+	}
+	if len(campaigns) > 1 {
+		return fmt.Errorf("%w: campaign %q", ErrAmbiguousName, spec.Name)
+	}
+
+	change := Change{Kind: KindCampaign, Name: spec.Name}
+	if len(campaigns) == 0 {
+		status := spec.Status
+		if r.opts.RequireApproval {
+			status = approval.CampaignDraft
+		}
+		created, err := r.queries.CreateCampaign(ctx, db.CreateCampaignParams{
+			TenantID: r.tenantID,
+			Name:     spec.Name,
+			StartAt:  startAt,
+			EndAt:    endAt,
+			BudgetID: budgetID,
+			Status:   status,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create campaign %q: %w", spec.Name, err)
+		}
+		change.Action = ActionCreate
+		r.plan.Changes = append(r.plan.Changes, change)
+		return r.applyRules(ctx, spec, created, nil)
+	}
+
+	existing := campaigns[0]
+	status := spec.Status
+	if r.opts.RequireApproval && existing.Status == approval.CampaignDraft {
+		// Drafts are edited freely but only go live through approval
+		status = existing.Status
+	}
+	fields := campaignChanges(spec, startAt, endAt, budgetID, status, existing)
+	change.Action = ActionNone
+	if len(fields) > 0 {
+		if err := r.checkEditable(existing); err != nil {
+			return err
+		}
+		if err := r.queries.UpdateCampaign(ctx, db.UpdateCampaignParams{
+			ID:       existing.ID,
+			TenantID: r.tenantID,
+			Name:     existing.Name,
+			StartAt:  startAt,
+			EndAt:    endAt,
+			BudgetID: budgetID,
+			Status:   status,
+		}); err != nil {
+			return fmt.Errorf("failed to update campaign %q: %w", spec.Name, err)
+		}
+		r.changedCampaigns = append(r.changedCampaigns, existing.ID)
+		change.Action = ActionUpdate
+		change.Fields = fields
+	}
+	r.plan.Changes = append(r.plan.Changes, change)
+
+	rules, err := r.queries.ListRulesByCampaign(ctx, db.ListRulesByCampaignParams{
+		TenantID:   r.tenantID,
+		CampaignID: existing.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list campaign rules: %w", err)
+	}
+	return r.applyRules(ctx, spec, existing, rules)
+}
+
+// applyRules creates or updates the rules of a campaign. existing are the
+// campaign's current rules.
+func (r *applyRun) applyRules(ctx context.Context, spec CampaignSpec, campaign db.Campaign, existing []db.Rule) error {
+	byName := make(map[string]*db.Rule, len(existing))
+	for i := range existing {
+		if _, ok := byName[existing[i].Name]; ok {
+			return fmt.Errorf("%w: rule %q of campaign %q", ErrAmbiguousName, existing[i].Name, campaign.Name)
+		}
+		byName[existing[i].Name] = &existing[i]
+	}
+
+	for _, rs := range spec.Rules {
+		conditions, err := json.Marshal(rs.Conditions)
+		if err != nil {
+			return fmt.Errorf("%w: rule %q has invalid conditions", ErrInvalidDocument, rs.Name)
+		}
+		var globalCap pgtype.Int4
+		if rs.GlobalCap != nil {
+			globalCap = pgtype.Int4{Int32: int32(*rs.GlobalCap), Valid: true}
+		}
+		rewardID := r.rewardIDs[rs.Reward]
+		additional := rs.Actions(r.rewardIDs)
+
+		change := Change{Kind: KindRule, Name: rs.Name, Campaign: campaign.Name}
+		current, ok := byName[rs.Name]
+		if !ok {
+			if err := r.checkEditable(campaign); err != nil {
+				return err
+			}
+			created, err := r.queries.CreateRule(ctx, db.CreateRuleParams{
+				TenantID:    r.tenantID,
+				CampaignID:  campaign.ID,
+				Name:        rs.Name,
+				EventType:   rs.EventType,
+				Conditions:  conditions,
+				RewardID:    rewardID,
+				PerUserCap:  int32(rs.PerUserCap),
+				GlobalCap:   globalCap,
+				CoolDownSec: int32(rs.CoolDownSec),
+				Active:      rs.Active && !r.opts.RequireApproval,
+				Quantity:    rs.RewardQuantity(),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create rule %q: %w", rs.Name, err)
+			}
+			if err := rule.SetAdditionalRewards(ctx, r.queries, r.tenantID, created.ID, additional); err != nil {
+				return err
+			}
+			change.Action = ActionCreate
+			r.plan.Changes = append(r.plan.Changes, change)
+			continue
+		}
+
+		active := rs.Active
+		if r.opts.RequireApproval {
+			// Rules are only activated through approval
+			active = rs.Active && current.Active
+		}
+		fields, err := ruleChanges(rs, rewardID, active, *current)
+		if err != nil {
+			return err
+		}
+		currentActions, err := rules.RuleActions(ctx, r.queries, *current)
+		if err != nil {
+			return err
+		}
+		additionalChanged := !slices.Equal(additional, currentActions[1:])
+		if additionalChanged {
+			fields = append(fields, "additional_rewards")
+		}
+		change.Action = ActionNone
+		if len(fields) > 0 {
+			if err := r.checkEditable(campaign); err != nil {
+				return err
+			}
+			if _, err := r.queries.UpdateRuleDefinition(ctx, db.UpdateRuleDefinitionParams{
+				ID:          current.ID,
+				TenantID:    r.tenantID,
+				EventType:   rs.EventType,
+				Conditions:  conditions,
+				RewardID:    rewardID,
+				PerUserCap:  int32(rs.PerUserCap),
+				GlobalCap:   globalCap,
+				CoolDownSec: int32(rs.CoolDownSec),
+				Active:      active,
+				Quantity:    rs.RewardQuantity(),
+			}); err != nil {
+				return fmt.Errorf("failed to update rule %q: %w", rs.Name, err)
+			}
+			if additionalChanged {
+				if err := rule.SetAdditionalRewards(ctx, r.queries, r.tenantID, current.ID, additional); err != nil {
+					return err
+				}
+			}
+			change.Action = ActionUpdate
+			change.Fields = fields
+		}
+		r.plan.Changes = append(r.plan.Changes, change)
+	}
+	return nil
+}
+
+// checkEditable returns ErrApprovalRequired for campaigns that may only be
+// changed through approval
+func (r *applyRun) checkEditable(campaign db.Campaign) error {
+	if r.opts.RequireApproval && campaign.Status != approval.CampaignDraft {
+		return fmt.Errorf("%w: %q", ErrApprovalRequired, campaign.Name)
+	}
+	return nil
+}
+
+// budgetChanges returns the fields of a budget a spec changes
+func budgetChanges(spec BudgetSpec, budget db.Budget) ([]string, error) {
+	if spec.Currency != budget.Currency {
+		return nil, fmt.Errorf("%w: budget %q currency", ErrImmutableChange, spec.Name)
+	}
+
+	var fields []string
+	if !amountEqual(spec.SoftCap, budget.SoftCap) {
+		fields = append(fields, "soft_cap")
+	}
+	if !amountEqual(spec.HardCap, budget.HardCap) {
+		fields = append(fields, "hard_cap")
+	}
+	if spec.Period != budget.Period {
+		fields = append(fields, "period")
+	}
+	return fields, nil
+}
+
+// rewardChanges returns the fields of a reward a spec changes. metadata is
+// the spec's encoded metadata.
+func rewardChanges(spec RewardSpec, metadata []byte, reward db.RewardCatalog) ([]string, error) {
+	if spec.Type != reward.Type {
+		return nil, fmt.Errorf("%w: reward %q type", ErrImmutableChange, spec.Name)
+	}
+	if spec.Inventory != reward.Inventory {
+		return nil, fmt.Errorf("%w: reward %q inventory", ErrImmutableChange, spec.Name)
+	}
+
+	var fields []string
+	if spec.FaceValue == nil && reward.FaceValue.Valid ||
+		spec.FaceValue != nil && !amountEqual(*spec.FaceValue, reward.FaceValue) {
+		fields = append(fields, "face_value")
+	}
+	if spec.Currency != reward.Currency.String {
+		fields = append(fields, "currency")
+	}
+	if !jsonEqual(metadata, reward.Metadata) {
+		fields = append(fields, "metadata")
+	}
+	if spec.Active != reward.Active {
+		fields = append(fields, "active")
+	}
+	return fields, nil
+}
+
+// campaignChanges returns the fields of a campaign a spec changes, given the
+// spec's resolved dates, budget and status
+func campaignChanges(spec CampaignSpec, startAt, endAt pgtype.Timestamptz, budgetID pgtype.UUID, status string, campaign db.Campaign) []string {
+	var fields []string
+	if !timestampEqual(startAt, campaign.StartAt) {
+		fields = append(fields, "start_at")
+	}
+	if !timestampEqual(endAt, campaign.EndAt) {
+		fields = append(fields, "end_at")
+	}
+	if budgetID != campaign.BudgetID {
+		fields = append(fields, "budget")
+	}
+	if status != campaign.Status {
+		fields = append(fields, "status")
+	}
+	return fields
+}
+
+// ruleChanges returns the fields of a rule a spec changes, given the spec's
+// resolved reward and active flag
+func ruleChanges(spec rule.BundleRule, rewardID pgtype.UUID, active bool, current db.Rule) ([]string, error) {
+	conditions, err := json.Marshal(spec.Conditions)
+	if err != nil {
+		return nil, fmt.Errorf("%w: rule %q has invalid conditions", ErrInvalidDocument, spec.Name)
+	}
+
+	var fields []string
+	if spec.EventType != current.EventType {
+		fields = append(fields, "event_type")
+	}
+	if !jsonEqual(conditions, current.Conditions) {
+		fields = append(fields, "conditions")
+	}
+	if rewardID != current.RewardID {
+		fields = append(fields, "reward")
+	}
+	if spec.RewardQuantity() != current.Quantity {
+		fields = append(fields, "quantity")
+	}
+	if int32(spec.PerUserCap) != current.PerUserCap {
+		fields = append(fields, "per_user_cap")
+	}
+	if spec.GlobalCap == nil && current.GlobalCap.Valid ||
+		spec.GlobalCap != nil && (!current.GlobalCap.Valid || int32(*spec.GlobalCap) != current.GlobalCap.Int32) {
+		fields = append(fields, "global_cap")
+	}
+	if int32(spec.CoolDownSec) != current.CoolDownSec {
+		fields = append(fields, "cool_down_sec")
+	}
+	if active != current.Active {
+		fields = append(fields, "active")
+	}
+	return fields, nil
+}
+
+// formatAmount formats an amount for a numeric column
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// amountEqual reports whether an amount equals a numeric to the cent
+func amountEqual(amount float64, n pgtype.Numeric) bool {
+	v, err := n.Float64Value()
+	if err != nil || !v.Valid {
+		return false
+	}
+	return formatAmount(amount) == formatAmount(v.Float64)
+}
+
+// jsonEqual reports whether two JSON documents are equal, ignoring key order
+// and formatting
+func jsonEqual(a, b []byte) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// timestamptz converts an optional time to a timestamp
+func timestamptz(t *time.Time) pgtype.Timestamptz {
+	if t == nil {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: *t, Valid: true}
+}
+
+// timestampEqual reports whether two timestamps are the same instant
+func timestampEqual(a, b pgtype.Timestamptz) bool {
+	if a.Valid != b.Valid {
+		return false
+	}
+	return !a.Valid || a.Time.Equal(b.Time)
+}
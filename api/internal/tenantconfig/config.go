@@ -0,0 +1,172 @@
+// Package tenantconfig applies declarative tenant configuration.
+//
+// A Document describes the budgets, rewards and campaigns (with their rules)
+// a tenant should have. Resources are matched to the tenant's existing ones
+// by name: missing resources are created and differing ones updated, while
+// resources the document does not mention are left untouched. Applying is
+// all or none, and a plan previews the changes without making them.
+package tenantconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/rule"
+	"gopkg.in/yaml.v3"
+)
+
+// DocumentVersion is the version of the document format
+const DocumentVersion = 1
+
+// Budget periods
+const (
+	PeriodRolling = "rolling"
+	PeriodMonthly = "monthly"
+)
+
+// ErrInvalidDocument is returned for a document that cannot be applied
+var ErrInvalidDocument = errors.New("invalid tenant configuration")
+
+// Document is a tenant's declared configuration
+type Document struct {
+	Version   int            `json:"version" yaml:"version"`
+	Budgets   []BudgetSpec   `json:"budgets,omitempty" yaml:"budgets,omitempty"`
+	Rewards   []RewardSpec   `json:"rewards,omitempty" yaml:"rewards,omitempty"`
+	Campaigns []CampaignSpec `json:"campaigns,omitempty" yaml:"campaigns,omitempty"`
+}
+
+// BudgetSpec declares a budget. Alert settings are not managed by documents.
+type BudgetSpec struct {
+	Name     string  `json:"name" yaml:"name"`
+	Currency string  `json:"currency" yaml:"currency"`
+	SoftCap  float64 `json:"soft_cap" yaml:"soft_cap"`
+	HardCap  float64 `json:"hard_cap" yaml:"hard_cap"`
+	// Period defaults to rolling
+	Period string `json:"period,omitempty" yaml:"period,omitempty"`
+}
+
+// RewardSpec declares a reward. A reward's type and inventory cannot change
+// once it exists.
+type RewardSpec struct {
+	Name      string                 `json:"name" yaml:"name"`
+	Type      string                 `json:"type" yaml:"type"`
+	FaceValue *float64               `json:"face_value,omitempty" yaml:"face_value,omitempty"`
+	Currency  string                 `json:"currency,omitempty" yaml:"currency,omitempty"`
+	Inventory string                 `json:"inventory" yaml:"inventory"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Active    bool                   `json:"active" yaml:"active"`
+}
+
+// CampaignSpec declares a campaign and its rules. Budget and rule rewards
+// are referenced by name, and may be declared in the same document.
+type CampaignSpec struct {
+	Name    string     `json:"name" yaml:"name"`
+	StartAt *time.Time `json:"start_at,omitempty" yaml:"start_at,omitempty"`
+	EndAt   *time.Time `json:"end_at,omitempty" yaml:"end_at,omitempty"`
+	Budget  string     `json:"budget,omitempty" yaml:"budget,omitempty"`
+	// Status defaults to active
+	Status string            `json:"status,omitempty" yaml:"status,omitempty"`
+	Rules  []rule.BundleRule `json:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+// Unmarshal decodes a document in the given format, rule.FormatJSON or
+// rule.FormatYAML
+func Unmarshal(data []byte, format string) (*Document, error) {
+	var d Document
+	var err error
+	switch format {
+	case rule.FormatYAML:
+		err = yaml.Unmarshal(data, &d)
+	case rule.FormatJSON:
+		err = json.Unmarshal(data, &d)
+	default:
+		return nil, fmt.Errorf("unsupported document format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	return &d, nil
+}
+
+// Validate checks a document and fills in defaults
+func (d *Document) Validate() error {
+	if d.Version != DocumentVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidDocument, d.Version)
+	}
+
+	budgets := make(map[string]bool, len(d.Budgets))
+	for i := range d.Budgets {
+		b := &d.Budgets[i]
+		if b.Period == "" {
+			b.Period = PeriodRolling
+		}
+		switch {
+		case strings.TrimSpace(b.Name) == "":
+			return fmt.Errorf("%w: budget %d has no name", ErrInvalidDocument, i+1)
+		case budgets[b.Name]:
+			return fmt.Errorf("%w: budget %q appears twice", ErrInvalidDocument, b.Name)
+		case b.Currency == "":
+			return fmt.Errorf("%w: budget %q has no currency", ErrInvalidDocument, b.Name)
+		case b.HardCap <= 0:
+			return fmt.Errorf("%w: budget %q must have a positive hard_cap", ErrInvalidDocument, b.Name)
+		case b.SoftCap < 0 || b.SoftCap > b.HardCap:
+			return fmt.Errorf("%w: budget %q soft_cap must be between 0 and hard_cap", ErrInvalidDocument, b.Name)
+		case b.Period != PeriodRolling && b.Period != PeriodMonthly:
+			return fmt.Errorf("%w: budget %q period must be rolling or monthly", ErrInvalidDocument, b.Name)
+		}
+		budgets[b.Name] = true
+	}
+
+	rewards := make(map[string]bool, len(d.Rewards))
+	for i, r := range d.Rewards {
+		if strings.TrimSpace(r.Name) == "" {
+			return fmt.Errorf("%w: reward %d has no name", ErrInvalidDocument, i+1)
+		}
+		if rewards[r.Name] {
+			return fmt.Errorf("%w: reward %q appears twice", ErrInvalidDocument, r.Name)
+		}
+		if err := httputil.ValidateRewardType(r.Type); err != nil {
+			return fmt.Errorf("%w: reward %q: %v", ErrInvalidDocument, r.Name, err)
+		}
+		if err := httputil.ValidateInventoryType(r.Inventory); err != nil {
+			return fmt.Errorf("%w: reward %q: %v", ErrInvalidDocument, r.Name, err)
+		}
+		rewards[r.Name] = true
+	}
+
+	campaigns := make(map[string]bool, len(d.Campaigns))
+	for i := range d.Campaigns {
+		c := &d.Campaigns[i]
+		if c.Status == "" {
+			c.Status = "active"
+		}
+		switch {
+		case strings.TrimSpace(c.Name) == "":
+			return fmt.Errorf("%w: campaign %d has no name", ErrInvalidDocument, i+1)
+		case campaigns[c.Name]:
+			return fmt.Errorf("%w: campaign %q appears twice", ErrInvalidDocument, c.Name)
+		case !validCampaignStatus(c.Status):
+			return fmt.Errorf("%w: campaign %q status must be draft, active, paused, or completed", ErrInvalidDocument, c.Name)
+		case c.StartAt != nil && c.EndAt != nil && !c.EndAt.After(*c.StartAt):
+			return fmt.Errorf("%w: campaign %q must end after it starts", ErrInvalidDocument, c.Name)
+		}
+		if len(c.Rules) > 0 {
+			bundle := rule.Bundle{Version: rule.BundleVersion, Campaign: c.Name, Rules: c.Rules}
+			if err := bundle.Validate(); err != nil {
+				return fmt.Errorf("%w: campaign %q: %v", ErrInvalidDocument, c.Name, err)
+			}
+		}
+		campaigns[c.Name] = true
+	}
+	return nil
+}
+
+// validCampaignStatus reports whether status is a status campaigns may be
+// given
+func validCampaignStatus(status string) bool {
+	return status == "draft" || status == "active" || status == "paused" || status == "completed"
+}
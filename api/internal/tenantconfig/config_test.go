@@ -0,0 +1,191 @@
+package tenantconfig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/rule"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDocument = `
+version: 1
+budgets:
+  - name: Main
+    currency: USD
+    soft_cap: 800
+    hard_cap: 1000
+rewards:
+  - name: Free coffee
+    type: voucher_code
+    inventory: none
+    active: true
+campaigns:
+  - name: Summer
+    start_at: 2025-12-01T00:00:00Z
+    end_at: 2026-03-01T00:00:00Z
+    budget: Main
+    rules:
+      - name: Any purchase
+        event_type: purchase
+        conditions: {}
+        reward: Free coffee
+        per_user_cap: 1
+        active: true
+`
+
+func numeric(s string) pgtype.Numeric {
+	var n pgtype.Numeric
+	if err := n.Scan(s); err != nil {
+		panic(err)
+	}
+	return n
+}
+
+func TestUnmarshalDocument(t *testing.T) {
+	doc, err := Unmarshal([]byte(testDocument), rule.FormatYAML)
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate())
+
+	require.Len(t, doc.Budgets, 1)
+	assert.Equal(t, PeriodRolling, doc.Budgets[0].Period)
+	require.Len(t, doc.Campaigns, 1)
+	assert.Equal(t, "active", doc.Campaigns[0].Status)
+	assert.Equal(t, time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC), *doc.Campaigns[0].StartAt)
+	assert.Equal(t, "Free coffee", doc.Campaigns[0].Rules[0].Reward)
+
+	_, err = Unmarshal([]byte("{"), rule.FormatJSON)
+	assert.ErrorIs(t, err, ErrInvalidDocument)
+}
+
+func TestDocumentValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*Document)
+	}{
+		{"unsupported version", func(d *Document) { d.Version = 2 }},
+		{"duplicate budget", func(d *Document) { d.Budgets = append(d.Budgets, d.Budgets[0]) }},
+		{"soft cap above hard cap", func(d *Document) { d.Budgets[0].SoftCap = 2000 }},
+		{"invalid period", func(d *Document) { d.Budgets[0].Period = "weekly" }},
+		{"invalid reward type", func(d *Document) { d.Rewards[0].Type = "cash" }},
+		{"invalid campaign status", func(d *Document) { d.Campaigns[0].Status = "live" }},
+		{"ends before start", func(d *Document) { d.Campaigns[0].EndAt = d.Campaigns[0].StartAt }},
+		{"invalid rule", func(d *Document) { d.Campaigns[0].Rules[0].EventType = "" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Unmarshal([]byte(testDocument), rule.FormatYAML)
+			require.NoError(t, err)
+			tt.mutate(doc)
+			assert.ErrorIs(t, doc.Validate(), ErrInvalidDocument)
+		})
+	}
+}
+
+func TestBudgetChanges(t *testing.T) {
+	budget := db.Budget{Currency: "USD", SoftCap: numeric("800.00"), HardCap: numeric("1000.00"), Period: PeriodRolling}
+	spec := BudgetSpec{Name: "Main", Currency: "USD", SoftCap: 800, HardCap: 1000, Period: PeriodRolling}
+
+	fields, err := budgetChanges(spec, budget)
+	require.NoError(t, err)
+	assert.Empty(t, fields)
+
+	spec.HardCap = 1500
+	spec.Period = PeriodMonthly
+	fields, err = budgetChanges(spec, budget)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hard_cap", "period"}, fields)
+
+	spec.Currency = "ZWG"
+	_, err = budgetChanges(spec, budget)
+	assert.ErrorIs(t, err, ErrImmutableChange)
+}
+
+func TestRewardChanges(t *testing.T) {
+	reward := db.RewardCatalog{Type: "voucher_code", Inventory: "none", Metadata: []byte(`{"b": 1, "a": 2}`), Active: true}
+	spec := RewardSpec{Name: "Free coffee", Type: "voucher_code", Inventory: "none", Active: true}
+
+	fields, err := rewardChanges(spec, []byte(`{"a":2,"b":1}`), reward)
+	require.NoError(t, err)
+	assert.Empty(t, fields)
+
+	faceValue := 5.0
+	spec.FaceValue = &faceValue
+	spec.Active = false
+	fields, err = rewardChanges(spec, []byte(`{}`), reward)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"face_value", "metadata", "active"}, fields)
+
+	spec.Inventory = "pool"
+	_, err = rewardChanges(spec, []byte(`{}`), reward)
+	assert.ErrorIs(t, err, ErrImmutableChange)
+}
+
+func TestCampaignChanges(t *testing.T) {
+	start := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	campaign := db.Campaign{StartAt: pgtype.Timestamptz{Time: start, Valid: true}, Status: "active"}
+
+	fields := campaignChanges(CampaignSpec{}, timestamptz(&start), pgtype.Timestamptz{}, pgtype.UUID{}, "active", campaign)
+	assert.Empty(t, fields)
+
+	budgetID := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	fields = campaignChanges(CampaignSpec{}, pgtype.Timestamptz{}, pgtype.Timestamptz{}, budgetID, "paused", campaign)
+	assert.Equal(t, []string{"start_at", "budget", "status"}, fields)
+}
+
+func TestRuleChanges(t *testing.T) {
+	rewardID := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	current := db.Rule{
+		EventType:  "purchase",
+		Conditions: []byte(`{">=": [{"var": "amount"}, 50]}`),
+		RewardID:   rewardID,
+		Quantity:   1,
+		PerUserCap: 1,
+		Active:     true,
+	}
+	spec := rule.BundleRule{
+		Name:       "Big basket",
+		EventType:  "purchase",
+		Conditions: map[string]interface{}{">=": []interface{}{map[string]interface{}{"var": "amount"}, 50}},
+		Reward:     "Free coffee",
+		PerUserCap: 1,
+		Active:     true,
+	}
+
+	fields, err := ruleChanges(spec, rewardID, true, current)
+	require.NoError(t, err)
+	assert.Empty(t, fields)
+
+	globalCap := 100
+	spec.GlobalCap = &globalCap
+	spec.Conditions = map[string]interface{}{}
+	fields, err = ruleChanges(spec, pgtype.UUID{}, false, current)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"conditions", "reward", "global_cap", "active"}, fields)
+
+	spec.Quantity = 2
+	fields, err = ruleChanges(spec, rewardID, true, current)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"conditions", "quantity", "global_cap"}, fields)
+}
+
+func TestAmountEqual(t *testing.T) {
+	assert.True(t, amountEqual(10, numeric("10.00")))
+	assert.True(t, amountEqual(0.1+0.2, numeric("0.30")))
+	assert.False(t, amountEqual(10, numeric("10.01")))
+	assert.False(t, amountEqual(0, pgtype.Numeric{}))
+}
+
+func TestPlanCount(t *testing.T) {
+	plan := Plan{Changes: []Change{
+		{Kind: KindBudget, Action: ActionCreate},
+		{Kind: KindReward, Action: ActionNone},
+		{Kind: KindRule, Action: ActionCreate},
+	}}
+	assert.Equal(t, 2, plan.Count(ActionCreate))
+	assert.Zero(t, plan.Count(ActionUpdate))
+}
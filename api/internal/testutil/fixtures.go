@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/enums"
 )
 
 // UUIDFromString converts a string UUID to pgtype.UUID
@@ -459,7 +460,7 @@ func CreateTestIssuance(t *testing.T, queries *db.Queries, tenantID, customerID,
 		CampaignID: campaignID,
 		RewardID:   rewardID,
 		EventID:    eventID,
-		Status:     "reserved",
+		Status:     string(enums.IssuanceReserved),
 		Currency:   TextFromString("USD"),
 		CostAmount: NumericFromFloat(t, 10.0),
 		FaceAmount: NumericFromFloat(t, 10.0),
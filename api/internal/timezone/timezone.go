@@ -0,0 +1,99 @@
+// Package timezone resolves the IANA time zone a tenant trades in
+// (tenants.timezone), which local times such as happy hours, report days and
+// challenge periods are read in.
+package timezone
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	// Embed the zone database so zones load in minimal containers without
+	// /usr/share/zoneinfo
+	_ "time/tzdata"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Default is the time zone of tenants that have not set one
+const Default = "Africa/Harare"
+
+// dateLayout is the layout of dates given without a time of day
+const dateLayout = "2006-01-02"
+
+// Valid reports whether name is an IANA time zone. "Local" is rejected since
+// it depends on the server.
+func Valid(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// Load returns the time zone called name, falling back to Default for an
+// unknown or empty name
+func Load(name string) *time.Location {
+	if name != "" && name != "Local" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	loc, err := time.LoadLocation(Default)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// StartOfDay returns midnight of t's day in loc
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// ParseStart parses an RFC 3339 time, or a date (YYYY-MM-DD) as the start of
+// that day in loc
+func ParseStart(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation(dateLayout, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be an RFC 3339 time or a YYYY-MM-DD date: %q", value)
+	}
+	return day, nil
+}
+
+// ParseEnd parses an RFC 3339 time, or a date (YYYY-MM-DD) as the end of
+// that day in loc, i.e. the start of the next so the whole day is included
+func ParseEnd(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation(dateLayout, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be an RFC 3339 time or a YYYY-MM-DD date: %q", value)
+	}
+	return day.AddDate(0, 0, 1), nil
+}
+
+// Resolver looks up the time zones of tenants
+type Resolver struct {
+	queries *db.Queries
+}
+
+// NewResolver creates a new time zone resolver
+func NewResolver(queries *db.Queries) *Resolver {
+	return &Resolver{queries: queries}
+}
+
+// Location returns the time zone the tenant's local times are read in
+func (r *Resolver) Location(ctx context.Context, tenantID pgtype.UUID) (*time.Location, error) {
+	name, err := r.queries.GetTenantTimezone(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant timezone: %w", err)
+	}
+	return Load(name), nil
+}
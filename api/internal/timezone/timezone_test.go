@@ -0,0 +1,85 @@
+package timezone
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"Africa/Harare", true},
+		{"Africa/Johannesburg", true},
+		{"UTC", true},
+		{"", false},
+		{"Local", false},
+		{"Mars/Olympus_Mons", false},
+	}
+
+	for _, tt := range tests {
+		if got := Valid(tt.name); got != tt.want {
+			t.Errorf("Valid(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	if got := Load("Africa/Johannesburg").String(); got != "Africa/Johannesburg" {
+		t.Errorf("Load(Africa/Johannesburg) = %s", got)
+	}
+	if got := Load("nowhere").String(); got != Default {
+		t.Errorf("Load(nowhere) = %s, want %s", got, Default)
+	}
+
+	// Harare is UTC+2 all year
+	at := time.Date(2025, 12, 1, 22, 30, 0, 0, time.UTC).In(Load(""))
+	if at.Hour() != 0 || at.Day() != 2 {
+		t.Errorf("22:30 UTC in %s = %v, want 00:30 the next day", Default, at)
+	}
+}
+
+func TestStartOfDay(t *testing.T) {
+	harare := Load("Africa/Harare")
+
+	// 23:30 UTC is already the next day in Harare
+	got := StartOfDay(time.Date(2025, 12, 1, 23, 30, 0, 0, time.UTC), harare)
+	want := time.Date(2025, 12, 2, 0, 0, 0, 0, harare)
+	if !got.Equal(want) {
+		t.Errorf("StartOfDay() = %v, want %v", got, want)
+	}
+}
+
+func TestParseStartEnd(t *testing.T) {
+	harare := Load("Africa/Harare")
+
+	start, err := ParseStart("2025-12-24", harare)
+	if err != nil {
+		t.Fatalf("ParseStart() error = %v", err)
+	}
+	if want := time.Date(2025, 12, 23, 22, 0, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("ParseStart(date) = %v, want %v", start.UTC(), want)
+	}
+
+	end, err := ParseEnd("2025-12-24", harare)
+	if err != nil {
+		t.Fatalf("ParseEnd() error = %v", err)
+	}
+	if want := time.Date(2025, 12, 24, 22, 0, 0, 0, time.UTC); !end.Equal(want) {
+		t.Errorf("ParseEnd(date) = %v, want %v", end.UTC(), want)
+	}
+
+	// Full timestamps keep their own offset
+	exact, err := ParseEnd("2025-12-24T18:00:00Z", harare)
+	if err != nil {
+		t.Fatalf("ParseEnd() error = %v", err)
+	}
+	if want := time.Date(2025, 12, 24, 18, 0, 0, 0, time.UTC); !exact.Equal(want) {
+		t.Errorf("ParseEnd(time) = %v, want %v", exact, want)
+	}
+
+	if _, err := ParseStart("24/12/2025", harare); err == nil {
+		t.Error("ParseStart() accepted an invalid date")
+	}
+}
@@ -0,0 +1,273 @@
+package wallet
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"time"
+)
+
+// PKPassContentType is the media type of Apple Wallet passes
+const PKPassContentType = "application/vnd.apple.pkpass"
+
+// AppleConfig configures Apple Wallet pass signing. The pass type
+// certificate and key are issued in the Apple Developer portal; the WWDR
+// certificate is Apple's intermediate that issued it. WebServiceURL, when
+// set, is where wallets fetch updated passes and must end in /public/wallet.
+type AppleConfig struct {
+	PassTypeID    string
+	TeamID        string
+	CertFile      string
+	KeyFile       string
+	WWDRFile      string
+	WebServiceURL string
+}
+
+// AppleSigner builds signed Apple Wallet passes
+type AppleSigner struct {
+	passTypeID    string
+	teamID        string
+	webServiceURL string
+	cert          *x509.Certificate
+	key           crypto.Signer
+	wwdr          *x509.Certificate
+	tokenSecret   []byte
+	now           func() time.Time
+}
+
+// NewAppleSigner loads the pass type certificate and keys. tokenSecret signs
+// the per-pass tokens wallets authenticate pass updates with.
+func NewAppleSigner(config AppleConfig, tokenSecret string) (*AppleSigner, error) {
+	if config.PassTypeID == "" || config.TeamID == "" {
+		return nil, fmt.Errorf("Apple Wallet pass type ID and team ID are required")
+	}
+
+	pair, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pass type certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pass type certificate: %w", err)
+	}
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported pass type key")
+	}
+
+	wwdrPEM, err := os.ReadFile(config.WWDRFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WWDR certificate: %w", err)
+	}
+	wwdrDER := wwdrPEM
+	if block, _ := pem.Decode(wwdrPEM); block != nil {
+		wwdrDER = block.Bytes
+	}
+	wwdr, err := x509.ParseCertificate(wwdrDER)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse WWDR certificate: %w", err)
+	}
+
+	return &AppleSigner{
+		passTypeID:    config.PassTypeID,
+		teamID:        config.TeamID,
+		webServiceURL: config.WebServiceURL,
+		cert:          cert,
+		key:           key,
+		wwdr:          wwdr,
+		tokenSecret:   []byte(tokenSecret),
+		now:           time.Now,
+	}, nil
+}
+
+// PassTypeID returns the pass type identifier passes are signed for
+func (s *AppleSigner) PassTypeID() string {
+	return s.passTypeID
+}
+
+// AuthToken returns the token wallets send to fetch updates of the pass
+// with the serial number
+func (s *AppleSigner) AuthToken(serial string) string {
+	mac := hmac.New(sha256.New, s.tokenSecret)
+	mac.Write([]byte("apple-wallet:" + serial))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ValidAuthToken reports whether token is the update token of the pass
+func (s *AppleSigner) ValidAuthToken(serial, token string) bool {
+	return hmac.Equal([]byte(s.AuthToken(serial)), []byte(token))
+}
+
+// Build returns the signed .pkpass archive of the pass
+func (s *AppleSigner) Build(pass Pass) ([]byte, error) {
+	passJSON, err := json.Marshal(s.passJSON(pass))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode pass: %w", err)
+	}
+
+	files := map[string][]byte{"pass.json": passJSON}
+	for name, size := range map[string]int{"icon.png": 29, "icon@2x.png": 58, "icon@3x.png": 87} {
+		icon, err := iconPNG(pass.Branding.PrimaryColor, size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to draw icon: %w", err)
+		}
+		files[name] = icon
+	}
+
+	// The manifest lists every file's SHA-1, as the pass format requires;
+	// the manifest itself is signed with SHA-256
+	manifest := make(map[string]string, len(files))
+	for name, data := range files {
+		sum := sha1.Sum(data)
+		manifest[name] = hex.EncodeToString(sum[:])
+	}
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	files["manifest.json"] = manifestJSON
+
+	signature, err := signDetached(manifestJSON, s.cert, s.key, []*x509.Certificate{s.wwdr}, s.now())
+	if err != nil {
+		return nil, err
+	}
+	files["signature"] = signature
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"pass.json", "icon.png", "icon@2x.png", "icon@3x.png", "manifest.json", "signature"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write pass archive: %w", err)
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			return nil, fmt.Errorf("failed to write pass archive: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write pass archive: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// applePass is pass.json, see Apple's PassKit Package Format Reference
+type applePass struct {
+	FormatVersion       int            `json:"formatVersion"`
+	PassTypeIdentifier  string         `json:"passTypeIdentifier"`
+	SerialNumber        string         `json:"serialNumber"`
+	TeamIdentifier      string         `json:"teamIdentifier"`
+	OrganizationName    string         `json:"organizationName"`
+	Description         string         `json:"description"`
+	LogoText            string         `json:"logoText,omitempty"`
+	BackgroundColor     string         `json:"backgroundColor"`
+	ForegroundColor     string         `json:"foregroundColor"`
+	LabelColor          string         `json:"labelColor"`
+	WebServiceURL       string         `json:"webServiceURL,omitempty"`
+	AuthenticationToken string         `json:"authenticationToken,omitempty"`
+	ExpirationDate      string         `json:"expirationDate,omitempty"`
+	Voided              bool           `json:"voided,omitempty"`
+	Barcodes            []appleBarcode `json:"barcodes"`
+	Coupon              appleFields    `json:"coupon"`
+}
+
+type appleBarcode struct {
+	Format          string `json:"format"`
+	Message         string `json:"message"`
+	MessageEncoding string `json:"messageEncoding"`
+	AltText         string `json:"altText,omitempty"`
+}
+
+type appleFields struct {
+	PrimaryFields   []appleField `json:"primaryFields,omitempty"`
+	SecondaryFields []appleField `json:"secondaryFields,omitempty"`
+	AuxiliaryFields []appleField `json:"auxiliaryFields,omitempty"`
+	BackFields      []appleField `json:"backFields,omitempty"`
+}
+
+type appleField struct {
+	Key       string `json:"key"`
+	Label     string `json:"label,omitempty"`
+	Value     string `json:"value"`
+	DateStyle string `json:"dateStyle,omitempty"`
+}
+
+func (s *AppleSigner) passJSON(pass Pass) applePass {
+	serial := pass.SerialNumber()
+	p := applePass{
+		FormatVersion:      1,
+		PassTypeIdentifier: s.passTypeID,
+		SerialNumber:       serial,
+		TeamIdentifier:     s.teamID,
+		OrganizationName:   pass.Branding.Name,
+		Description:        pass.RewardName,
+		LogoText:           pass.Branding.Name,
+		BackgroundColor:    pass.Branding.PrimaryColor.CSS(),
+		ForegroundColor:    pass.Branding.ForegroundColor.CSS(),
+		LabelColor:         pass.Branding.ForegroundColor.CSS(),
+		Voided:             pass.Voided(),
+		Barcodes: []appleBarcode{{
+			Format:          "PKBarcodeFormatQR",
+			Message:         pass.Code,
+			MessageEncoding: "iso-8859-1",
+			AltText:         pass.Code,
+		}},
+		Coupon: appleFields{
+			PrimaryFields: []appleField{{Key: "reward", Label: "REWARD", Value: pass.RewardName}},
+		},
+	}
+	if s.webServiceURL != "" {
+		p.WebServiceURL = s.webServiceURL
+		p.AuthenticationToken = s.AuthToken(serial)
+	}
+
+	if pass.Value != "" {
+		p.Coupon.SecondaryFields = append(p.Coupon.SecondaryFields, appleField{Key: "value", Label: "VALUE", Value: pass.Value})
+	}
+	if !pass.ExpiresAt.IsZero() {
+		expires := pass.ExpiresAt.UTC().Format(time.RFC3339)
+		p.ExpirationDate = expires
+		p.Coupon.SecondaryFields = append(p.Coupon.SecondaryFields, appleField{Key: "expires", Label: "EXPIRES", Value: expires, DateStyle: "PKDateStyleMedium"})
+	}
+	p.Coupon.AuxiliaryFields = []appleField{{Key: "code", Label: "CODE", Value: pass.Code}}
+
+	if pass.Description != "" {
+		p.Coupon.BackFields = append(p.Coupon.BackFields, appleField{Key: "description", Label: "About", Value: pass.Description})
+	}
+	if pass.Terms != "" {
+		p.Coupon.BackFields = append(p.Coupon.BackFields, appleField{Key: "terms", Label: "Terms", Value: pass.Terms})
+	}
+
+	return p
+}
+
+// iconPNG draws a square icon in the tenant's color. Apple Wallet requires
+// an icon, and tenants only configure a logo URL.
+func iconPNG(c RGB, size int) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	fill := color.RGBA{R: c.R, G: c.G, B: c.B, A: 255}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			img.Set(x, y, fill)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,164 @@
+package wallet
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCertificate(t *testing.T, name string) (*x509.Certificate, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func testSigner(t *testing.T) *AppleSigner {
+	t.Helper()
+	cert, key := testCertificate(t, "Pass Type ID: pass.com.example.loyalty")
+	wwdr, _ := testCertificate(t, "WWDR")
+	return &AppleSigner{
+		passTypeID:    "pass.com.example.loyalty",
+		teamID:        "TEAM123",
+		webServiceURL: "https://api.example.com/public/wallet",
+		cert:          cert,
+		key:           key,
+		wwdr:          wwdr,
+		tokenSecret:   []byte("secret"),
+		now:           time.Now,
+	}
+}
+
+func testPass() Pass {
+	primary, _ := ParseColor("#202020")
+	return Pass{
+		TenantID:     pgtype.UUID{Bytes: [16]byte{0x12, 15: 0x01}, Valid: true},
+		IssuanceID:   pgtype.UUID{Bytes: [16]byte{0x34, 15: 0x02}, Valid: true},
+		RewardName:   "Free Coffee",
+		Code:         "ABC123",
+		Value:        "USD 2.50",
+		Status:       "issued",
+		ExpiresAt:    time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC),
+		LastModified: time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC),
+		Branding: Branding{
+			Name:            "Acme",
+			PrimaryColor:    primary,
+			ForegroundColor: RGB{255, 255, 255},
+		},
+	}
+}
+
+func TestAppleBuild(t *testing.T) {
+	signer := testSigner(t)
+	pass := testPass()
+
+	archive, err := signer.Build(pass)
+	require.NoError(t, err)
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		files[f.Name] = data
+	}
+	require.Len(t, files, 6)
+
+	var manifest map[string]string
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &manifest))
+	assert.Len(t, manifest, 4)
+	for name, sum := range manifest {
+		expected := sha1.Sum(files[name])
+		assert.Equal(t, hex.EncodeToString(expected[:]), sum, name)
+	}
+
+	var passJSON map[string]any
+	require.NoError(t, json.Unmarshal(files["pass.json"], &passJSON))
+	assert.Equal(t, "pass.com.example.loyalty", passJSON["passTypeIdentifier"])
+	assert.Equal(t, pass.SerialNumber(), passJSON["serialNumber"])
+	assert.Equal(t, "rgb(32,32,32)", passJSON["backgroundColor"])
+	assert.Equal(t, "2026-01-31T00:00:00Z", passJSON["expirationDate"])
+	assert.Equal(t, signer.AuthToken(pass.SerialNumber()), passJSON["authenticationToken"])
+	assert.NotContains(t, passJSON, "voided")
+	barcodes := passJSON["barcodes"].([]any)
+	assert.Equal(t, "ABC123", barcodes[0].(map[string]any)["message"])
+
+	verifySignature(t, files["signature"], files["manifest.json"], signer.cert)
+}
+
+// verifySignature checks a detached PKCS #7 signature made by signDetached
+func verifySignature(t *testing.T, signature, content []byte, cert *x509.Certificate) {
+	t.Helper()
+
+	var ci contentInfo
+	_, err := asn1.Unmarshal(signature, &ci)
+	require.NoError(t, err)
+	assert.True(t, ci.ContentType.Equal(oidSignedData))
+
+	var sd signedData
+	_, err = asn1.Unmarshal(ci.Content.Bytes, &sd)
+	require.NoError(t, err)
+	require.Len(t, sd.SignerInfos, 1)
+	si := sd.SignerInfos[0]
+	assert.Equal(t, cert.SerialNumber, si.IssuerAndSerialNumber.SerialNumber)
+
+	// The message digest attribute must match the content
+	digest := sha256.Sum256(content)
+	encodedDigest, err := asn1.Marshal(digest[:])
+	require.NoError(t, err)
+	assert.True(t, bytes.Contains(si.AuthenticatedAttributes.Bytes, encodedDigest))
+
+	attrsSet, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: si.AuthenticatedAttributes.Bytes})
+	require.NoError(t, err)
+	attrsDigest := sha256.Sum256(attrsSet)
+	require.NoError(t, rsa.VerifyPKCS1v15(cert.PublicKey.(*rsa.PublicKey), crypto.SHA256, attrsDigest[:], si.EncryptedDigest))
+}
+
+func TestAppleBuildVoided(t *testing.T) {
+	signer := testSigner(t)
+	pass := testPass()
+	pass.Status = "redeemed"
+
+	p := signer.passJSON(pass)
+	assert.True(t, p.Voided)
+}
+
+func TestAppleAuthToken(t *testing.T) {
+	signer := testSigner(t)
+	serial := testPass().SerialNumber()
+
+	token := signer.AuthToken(serial)
+	assert.True(t, signer.ValidAuthToken(serial, token))
+	assert.False(t, signer.ValidAuthToken(serial, "nope"))
+	assert.False(t, signer.ValidAuthToken(serial+"x", token))
+}
@@ -0,0 +1,333 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/enums"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	googleSaveURL     = "https://pay.google.com/gp/v/save/"
+	googleObjectsURL  = "https://walletobjects.googleapis.com/walletobjects/v1/genericObject/"
+	googleWalletScope = "https://www.googleapis.com/auth/wallet_object.issuer"
+	googleTokenURL    = "https://oauth2.googleapis.com/token"
+
+	// googleClassSuffix names the generic pass class reward passes belong to
+	googleClassSuffix = "loyalty_reward"
+)
+
+// ErrNotSaved is returned when updating a Google Wallet pass the customer
+// has not saved yet, so there is nothing to update
+var ErrNotSaved = errors.New("pass has not been saved to Google Wallet")
+
+// GoogleConfig configures Google Wallet passes. The issuer ID comes from the
+// Google Pay & Wallet console; the service account must be an issuer user.
+type GoogleConfig struct {
+	IssuerID           string
+	ServiceAccountFile string
+}
+
+// GoogleIssuer creates "Save to Google Wallet" links and updates saved
+// passes
+type GoogleIssuer struct {
+	client   *http.Client
+	issuerID string
+	email    string
+	key      *rsa.PrivateKey
+	now      func() time.Time
+
+	mu          sync.Mutex
+	accessToken string
+	tokenExpiry time.Time
+}
+
+// serviceAccount is the part of a Google service account key file used
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+}
+
+// NewGoogleIssuer loads the service account key
+func NewGoogleIssuer(config GoogleConfig) (*GoogleIssuer, error) {
+	if config.IssuerID == "" {
+		return nil, fmt.Errorf("Google Wallet issuer ID is required")
+	}
+
+	data, err := os.ReadFile(config.ServiceAccountFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account file: %w", err)
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("invalid service account file: %w", err)
+	}
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil || account.ClientEmail == "" {
+		return nil, fmt.Errorf("service account file has no client email or private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service account private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("service account private key must be RSA")
+	}
+
+	return &GoogleIssuer{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		issuerID: config.IssuerID,
+		email:    account.ClientEmail,
+		key:      key,
+		now:      time.Now,
+	}, nil
+}
+
+// googleText is a localized string
+type googleText struct {
+	DefaultValue googleValue `json:"defaultValue"`
+}
+
+type googleValue struct {
+	Language string `json:"language"`
+	Value    string `json:"value"`
+}
+
+type googleObject struct {
+	ID                 string              `json:"id"`
+	ClassID            string              `json:"classId"`
+	State              string              `json:"state"`
+	CardTitle          googleText          `json:"cardTitle"`
+	Header             googleText          `json:"header"`
+	Subheader          *googleText         `json:"subheader,omitempty"`
+	HexBackgroundColor string              `json:"hexBackgroundColor"`
+	Logo               *googleImage        `json:"logo,omitempty"`
+	Barcode            googleBarcode       `json:"barcode"`
+	ValidTimeInterval  *googleTimeInterval `json:"validTimeInterval,omitempty"`
+	TextModulesData    []googleTextModule  `json:"textModulesData,omitempty"`
+}
+
+type googleImage struct {
+	SourceURI struct {
+		URI string `json:"uri"`
+	} `json:"sourceUri"`
+}
+
+type googleBarcode struct {
+	Type          string `json:"type"`
+	Value         string `json:"value"`
+	AlternateText string `json:"alternateText,omitempty"`
+}
+
+type googleTimeInterval struct {
+	End struct {
+		Date string `json:"date"`
+	} `json:"end"`
+}
+
+type googleTextModule struct {
+	ID     string `json:"id"`
+	Header string `json:"header"`
+	Body   string `json:"body"`
+}
+
+func text(s string) googleText {
+	return googleText{DefaultValue: googleValue{Language: "en", Value: s}}
+}
+
+// objectID returns the ID of the pass's Google Wallet object
+func (g *GoogleIssuer) objectID(pass Pass) string {
+	return g.issuerID + "." + pass.SerialNumber()
+}
+
+func (g *GoogleIssuer) object(pass Pass) googleObject {
+	state := "INACTIVE"
+	switch enums.IssuanceStatus(pass.Status) {
+	case enums.IssuanceIssued:
+		state = "ACTIVE"
+	case enums.IssuanceRedeemed:
+		state = "COMPLETED"
+	case enums.IssuanceExpired:
+		state = "EXPIRED"
+	}
+
+	obj := googleObject{
+		ID:                 g.objectID(pass),
+		ClassID:            g.issuerID + "." + googleClassSuffix,
+		State:              state,
+		CardTitle:          text(pass.Branding.Name),
+		Header:             text(pass.RewardName),
+		HexBackgroundColor: pass.Branding.PrimaryColor.Hex(),
+		Barcode: googleBarcode{
+			Type:          "QR_CODE",
+			Value:         pass.Code,
+			AlternateText: pass.Code,
+		},
+	}
+	if pass.Value != "" {
+		subheader := text(pass.Value)
+		obj.Subheader = &subheader
+	}
+	if pass.Branding.LogoURL != "" {
+		obj.Logo = &googleImage{}
+		obj.Logo.SourceURI.URI = pass.Branding.LogoURL
+	}
+	if !pass.ExpiresAt.IsZero() {
+		obj.ValidTimeInterval = &googleTimeInterval{}
+		obj.ValidTimeInterval.End.Date = pass.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	if pass.Description != "" {
+		obj.TextModulesData = append(obj.TextModulesData, googleTextModule{ID: "description", Header: "About", Body: pass.Description})
+	}
+	if pass.Terms != "" {
+		obj.TextModulesData = append(obj.TextModulesData, googleTextModule{ID: "terms", Header: "Terms", Body: pass.Terms})
+	}
+	return obj
+}
+
+// saveClaims are the claims of a "Save to Google Wallet" JWT
+type saveClaims struct {
+	Typ     string      `json:"typ"`
+	Origins []string    `json:"origins"`
+	Payload savePayload `json:"payload"`
+	jwt.RegisteredClaims
+}
+
+type savePayload struct {
+	GenericClasses []map[string]string `json:"genericClasses"`
+	GenericObjects []googleObject      `json:"genericObjects"`
+}
+
+// SaveURL returns a "Save to Google Wallet" link for the pass. The pass's
+// class and object are created when the customer saves it.
+func (g *GoogleIssuer) SaveURL(pass Pass) (string, error) {
+	claims := saveClaims{
+		Typ:     "savetowallet",
+		Origins: []string{},
+		Payload: savePayload{
+			GenericClasses: []map[string]string{{"id": g.issuerID + "." + googleClassSuffix}},
+			GenericObjects: []googleObject{g.object(pass)},
+		},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:   g.email,
+			Audience: jwt.ClaimStrings{"google"},
+			IssuedAt: jwt.NewNumericDate(g.now()),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(g.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign save link: %w", err)
+	}
+	return googleSaveURL + token, nil
+}
+
+// Update replaces a saved pass's object with the pass's current content, so
+// the customer's wallet shows it as redeemed or expired. It returns
+// ErrNotSaved if the customer never saved the pass.
+func (g *GoogleIssuer) Update(ctx context.Context, pass Pass) error {
+	token, err := g.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(g.object(pass))
+	if err != nil {
+		return fmt.Errorf("failed to encode pass: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, googleObjectsURL+url.PathEscape(g.objectID(pass)), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to update Google Wallet pass: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotSaved
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("Google Wallet API error (status %d): %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+// token returns an OAuth access token for the Google Wallet API, exchanging
+// a signed assertion for a new one shortly before the last expires
+func (g *GoogleIssuer) token(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	if g.accessToken != "" && now.Before(g.tokenExpiry.Add(-time.Minute)) {
+		return g.accessToken, nil
+	}
+
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   g.email,
+		"scope": googleWalletScope,
+		"aud":   googleTokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(g.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token request: %w", err)
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, googleTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get Google access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("Google token error (status %d): %s", resp.StatusCode, respBody)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("invalid Google token response: %w", err)
+	}
+
+	g.accessToken = result.AccessToken
+	g.tokenExpiry = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return g.accessToken, nil
+}
@@ -0,0 +1,60 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoogleSaveURL(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	issuer := &GoogleIssuer{
+		issuerID: "3388000000012345678",
+		email:    "wallet@example.iam.gserviceaccount.com",
+		key:      key,
+		now:      time.Now,
+	}
+
+	pass := testPass()
+	link, err := issuer.SaveURL(pass)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(link, googleSaveURL))
+
+	var claims saveClaims
+	_, err = jwt.ParseWithClaims(strings.TrimPrefix(link, googleSaveURL), &claims, func(*jwt.Token) (any, error) {
+		return &key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithAudience("google"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "savetowallet", claims.Typ)
+	assert.Equal(t, issuer.email, claims.Issuer)
+	require.Len(t, claims.Payload.GenericObjects, 1)
+	obj := claims.Payload.GenericObjects[0]
+	assert.Equal(t, "3388000000012345678."+pass.SerialNumber(), obj.ID)
+	assert.Equal(t, "3388000000012345678.loyalty_reward", obj.ClassID)
+	assert.Equal(t, "ACTIVE", obj.State)
+	assert.Equal(t, "ABC123", obj.Barcode.Value)
+	assert.Equal(t, "#202020", obj.HexBackgroundColor)
+	assert.Equal(t, "2026-01-31T00:00:00Z", obj.ValidTimeInterval.End.Date)
+}
+
+func TestGoogleObjectState(t *testing.T) {
+	issuer := &GoogleIssuer{issuerID: "1"}
+	for status, state := range map[string]string{
+		"issued":    "ACTIVE",
+		"redeemed":  "COMPLETED",
+		"expired":   "EXPIRED",
+		"cancelled": "INACTIVE",
+	} {
+		pass := testPass()
+		pass.Status = status
+		assert.Equal(t, state, issuer.object(pass).State, status)
+	}
+}
@@ -0,0 +1,253 @@
+// Package wallet generates Apple Wallet and Google Wallet passes for issued
+// rewards, so customers can keep a reward's redemption code in their phone
+// wallet. Passes carry the code as a barcode, the reward's expiry and the
+// tenant's branding from its theme.
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/catalogcache"
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/enums"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/rewardtypes"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	// ErrPassNotFound is returned when the issuance does not exist for the
+	// tenant
+	ErrPassNotFound = errors.New("issuance not found")
+
+	// ErrNoCode is returned for issuances without a redemption code, which
+	// have nothing to put in a wallet
+	ErrNoCode = errors.New("issuance has no redemption code")
+
+	// ErrNotIssued is returned when creating a pass for an issuance that is
+	// not currently issued
+	ErrNotIssued = errors.New("issuance is not issued")
+
+	// ErrInvalidSerial is returned for a pass serial number not made by
+	// SerialNumber
+	ErrInvalidSerial = errors.New("invalid pass serial number")
+)
+
+// defaultColor is used when a tenant's theme has no usable primary color
+const defaultColor = "#1a73e8"
+
+// Branding is the part of a tenant's theme shown on passes
+type Branding struct {
+	Name            string
+	LogoURL         string
+	PrimaryColor    RGB
+	ForegroundColor RGB
+}
+
+// RGB is a color
+type RGB struct {
+	R, G, B uint8
+}
+
+// Hex formats the color as #rrggbb
+func (c RGB) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// CSS formats the color as rgb(r,g,b), as Apple Wallet expects
+func (c RGB) CSS() string {
+	return fmt.Sprintf("rgb(%d,%d,%d)", c.R, c.G, c.B)
+}
+
+// ParseColor parses a #rrggbb color
+func ParseColor(s string) (RGB, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) != 6 {
+		return RGB{}, fmt.Errorf("color must be #rrggbb")
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return RGB{}, fmt.Errorf("color must be #rrggbb")
+	}
+	return RGB{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v)}, nil
+}
+
+// tenantTheme is the part of a tenant's theme used for branding
+type tenantTheme struct {
+	LogoURL      string `json:"logo_url"`
+	PrimaryColor string `json:"primary_color"`
+}
+
+// BrandingFromTenant reads a tenant's branding from its theme. Missing or
+// invalid settings fall back to defaults. Text is white or black, whichever
+// reads better on the primary color.
+func BrandingFromTenant(tenant db.Tenant) Branding {
+	var theme tenantTheme
+	if len(tenant.Theme) > 0 {
+		_ = json.Unmarshal(tenant.Theme, &theme)
+	}
+
+	primary, err := ParseColor(theme.PrimaryColor)
+	if err != nil {
+		primary, _ = ParseColor(defaultColor)
+	}
+
+	foreground := RGB{255, 255, 255}
+	// Perceived brightness, ITU-R BT.601
+	if 299*int(primary.R)+587*int(primary.G)+114*int(primary.B) > 150_000 {
+		foreground = RGB{0, 0, 0}
+	}
+
+	branding := Branding{
+		Name:            tenant.Name,
+		PrimaryColor:    primary,
+		ForegroundColor: foreground,
+	}
+	// Wallets fetch logos themselves, so only absolute URLs are usable
+	if strings.HasPrefix(theme.LogoURL, "https://") {
+		branding.LogoURL = theme.LogoURL
+	}
+	return branding
+}
+
+// Pass is the content of a wallet pass for an issued reward
+type Pass struct {
+	TenantID     pgtype.UUID
+	IssuanceID   pgtype.UUID
+	CustomerID   pgtype.UUID
+	RewardName   string
+	Description  string
+	Terms        string
+	Code         string
+	Value        string
+	Status       string
+	ExpiresAt    time.Time // zero if the reward does not expire
+	LastModified time.Time
+	Branding     Branding
+}
+
+// SerialNumber identifies the pass to wallets. It carries the tenant so
+// pass updates can be looked up without a tenant in the URL.
+func (p Pass) SerialNumber() string {
+	return httputil.FormatUUID(p.TenantID.Bytes) + "." + httputil.FormatUUID(p.IssuanceID.Bytes)
+}
+
+// Voided reports whether the pass can no longer be used, so wallets show it
+// as such
+func (p Pass) Voided() bool {
+	return enums.IssuanceStatus(p.Status) != enums.IssuanceIssued
+}
+
+// ParseSerialNumber returns the tenant and issuance a serial number refers to
+func ParseSerialNumber(serial string) (tenantID, issuanceID pgtype.UUID, err error) {
+	tenant, issuance, ok := strings.Cut(serial, ".")
+	if !ok || tenantID.Scan(tenant) != nil || issuanceID.Scan(issuance) != nil {
+		return tenantID, issuanceID, ErrInvalidSerial
+	}
+	return tenantID, issuanceID, nil
+}
+
+// Service loads the pass content of issuances
+type Service struct {
+	queries *db.Queries
+	catalog *catalogcache.Cache
+}
+
+// NewService creates a new wallet pass service
+func NewService(queries *db.Queries, catalog *catalogcache.Cache) *Service {
+	return &Service{
+		queries: queries,
+		catalog: catalog,
+	}
+}
+
+// Pass loads the current pass content of an issuance with a redemption code.
+// Passes of redeemed, expired or cancelled issuances are loaded too, so
+// wallets can be told they are void.
+func (s *Service) Pass(ctx context.Context, tenantID, issuanceID pgtype.UUID) (Pass, error) {
+	issuance, err := s.queries.GetIssuanceByID(ctx, db.GetIssuanceByIDParams{
+		ID:       issuanceID,
+		TenantID: tenantID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Pass{}, ErrPassNotFound
+	}
+	if err != nil {
+		return Pass{}, fmt.Errorf("failed to get issuance: %w", err)
+	}
+	if !issuance.Code.Valid || issuance.Code.String == "" {
+		return Pass{}, ErrNoCode
+	}
+
+	reward, err := s.catalog.GetRewardByID(ctx, db.GetRewardByIDParams{
+		ID:       issuance.RewardID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		return Pass{}, fmt.Errorf("failed to get reward: %w", err)
+	}
+
+	tenant, err := s.queries.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return Pass{}, fmt.Errorf("failed to get tenant: %w", err)
+	}
+
+	changedAt, err := s.queries.GetIssuanceLastChange(ctx, db.GetIssuanceLastChangeParams{
+		TenantID:   tenantID,
+		IssuanceID: issuanceID,
+	})
+	if err != nil {
+		return Pass{}, fmt.Errorf("failed to get issuance history: %w", err)
+	}
+
+	var terms rewardtypes.TermsMetadata
+	if len(reward.Metadata) > 0 {
+		// Unknown or malformed metadata just leaves the terms out
+		_ = json.Unmarshal(reward.Metadata, &terms)
+	}
+
+	pass := Pass{
+		TenantID:     tenantID,
+		IssuanceID:   issuanceID,
+		CustomerID:   issuance.CustomerID,
+		RewardName:   reward.Name,
+		Description:  terms.Description,
+		Terms:        terms.Terms,
+		Code:         issuance.Code.String,
+		Status:       issuance.Status,
+		LastModified: changedAt.Time.UTC().Truncate(time.Second),
+		Branding:     BrandingFromTenant(tenant),
+	}
+	if issuance.Currency.Valid && issuance.FaceAmount.Valid {
+		pass.Value = issuance.Currency.String + " " + httputil.FormatNumeric(issuance.FaceAmount)
+	}
+	if issuance.ExpiresAt.Valid {
+		pass.ExpiresAt = issuance.ExpiresAt.Time
+	}
+	if issuance.IssuedAt.Valid && issuance.IssuedAt.Time.After(pass.LastModified) {
+		pass.LastModified = issuance.IssuedAt.Time.UTC().Truncate(time.Second)
+	}
+
+	return pass, nil
+}
+
+// IssuedPass loads the pass of an issuance that can still be redeemed. New
+// passes are not handed out for used or void rewards; it returns
+// ErrNotIssued for those.
+func (s *Service) IssuedPass(ctx context.Context, tenantID, issuanceID pgtype.UUID) (Pass, error) {
+	pass, err := s.Pass(ctx, tenantID, issuanceID)
+	if err != nil {
+		return Pass{}, err
+	}
+	if pass.Voided() {
+		return Pass{}, ErrNotIssued
+	}
+	return pass, nil
+}
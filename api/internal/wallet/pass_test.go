@@ -0,0 +1,61 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSerialNumberRoundTrip(t *testing.T) {
+	pass := Pass{
+		TenantID:   pgtype.UUID{Bytes: [16]byte{0x12, 15: 0x01}, Valid: true},
+		IssuanceID: pgtype.UUID{Bytes: [16]byte{0x34, 15: 0x02}, Valid: true},
+	}
+
+	serial := pass.SerialNumber()
+	assert.Equal(t, "12000000-0000-0000-0000-000000000001.34000000-0000-0000-0000-000000000002", serial)
+
+	tenantID, issuanceID, err := ParseSerialNumber(serial)
+	require.NoError(t, err)
+	assert.Equal(t, pass.TenantID, tenantID)
+	assert.Equal(t, pass.IssuanceID, issuanceID)
+
+	for _, bad := range []string{"", "12000000-0000-0000-0000-000000000001", "a.b"} {
+		_, _, err := ParseSerialNumber(bad)
+		assert.ErrorIs(t, err, ErrInvalidSerial, bad)
+	}
+}
+
+func TestBrandingFromTenant(t *testing.T) {
+	tests := []struct {
+		name       string
+		theme      string
+		primary    string
+		foreground string
+		logo       string
+	}{
+		{"no theme", ``, defaultColor, "#ffffff", ""},
+		{"dark color", `{"primary_color":"#202020","logo_url":"https://cdn.example.com/logo.png"}`, "#202020", "#ffffff", "https://cdn.example.com/logo.png"},
+		{"light color", `{"primary_color":"#ffe066"}`, "#ffe066", "#000000", ""},
+		{"invalid color and insecure logo", `{"primary_color":"blue","logo_url":"http://example.com/logo.png"}`, defaultColor, "#ffffff", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			branding := BrandingFromTenant(db.Tenant{Name: "Acme", Theme: []byte(tt.theme)})
+			assert.Equal(t, "Acme", branding.Name)
+			assert.Equal(t, tt.primary, branding.PrimaryColor.Hex())
+			assert.Equal(t, tt.foreground, branding.ForegroundColor.Hex())
+			assert.Equal(t, tt.logo, branding.LogoURL)
+		})
+	}
+}
+
+func TestPassVoided(t *testing.T) {
+	assert.False(t, Pass{Status: "issued"}.Voided())
+	assert.True(t, Pass{Status: "redeemed"}.Voided())
+	assert.True(t, Pass{Status: "expired"}.Voided())
+}
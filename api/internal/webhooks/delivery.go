@@ -3,7 +3,6 @@ package webhooks
 import (
 	"bytes"
 	"context"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,17 +13,19 @@ import (
 	"github.com/bmachimbira/loyalty/api/internal/db"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // DeliveryService handles webhook delivery with worker pools and retry logic
 type DeliveryService struct {
 	queries     *db.Queries
-	dbConn      *sql.DB
+	pool        *pgxpool.Pool
 	client      *http.Client
 	queue       chan *DeliveryJob
 	workers     int
 	stopChan    chan struct{}
 	logger      *slog.Logger
+	filters     FilterEvaluator
 }
 
 // DeliveryJob represents a webhook delivery job
@@ -36,10 +37,10 @@ type DeliveryJob struct {
 }
 
 // NewDeliveryService creates a new webhook delivery service
-func NewDeliveryService(queries *db.Queries, dbConn *sql.DB, logger *slog.Logger) *DeliveryService {
+func NewDeliveryService(pool *pgxpool.Pool, logger *slog.Logger) *DeliveryService {
 	return &DeliveryService{
-		queries:  queries,
-		dbConn:   dbConn,
+		queries:  db.New(pool),
+		pool:     pool,
 		client:   &http.Client{Timeout: 30 * time.Second},
 		queue:    make(chan *DeliveryJob, 100),
 		workers:  5,
@@ -48,6 +49,12 @@ func NewDeliveryService(queries *db.Queries, dbConn *sql.DB, logger *slog.Logger
 	}
 }
 
+// SetFilterEvaluator sets the evaluator of endpoints' JsonLogic filters.
+// Without one, events aren't delivered to endpoints with a filter.
+func (s *DeliveryService) SetFilterEvaluator(evaluator FilterEvaluator) {
+	s.filters = evaluator
+}
+
 // Start starts the webhook delivery workers
 func (s *DeliveryService) StartWorkers() {
 	for i := 0; i < s.workers; i++ {
@@ -62,22 +69,49 @@ func (s *DeliveryService) Stop() {
 	s.logger.Info("webhook delivery service stopping")
 }
 
-// SendWebhook queues a webhook for async delivery
-func (s *DeliveryService) SendWebhook(ctx context.Context, tenantID uuid.UUID, eventType string, payload EventPayload) error {
-	// Set tenant context for RLS
-	_, err := s.dbConn.ExecContext(ctx, "SET LOCAL app.tenant_id = $1", tenantID)
+// withTenant runs fn in a transaction scoped to the tenant for RLS
+func (s *DeliveryService) withTenant(ctx context.Context, tenantID uuid.UUID, fn func(q *db.Queries) error) error {
+	tx, err := s.pool.Begin(ctx)
 	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", tenantID.String()); err != nil {
 		return fmt.Errorf("failed to set tenant context: %w", err)
 	}
 
+	if err := fn(s.queries.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// SendWebhook queues a webhook for async delivery
+func (s *DeliveryService) SendWebhook(ctx context.Context, tenantID uuid.UUID, eventType string, payload EventPayload) error {
 	// Get all webhooks subscribed to this event
-	webhooks, err := s.queries.GetWebhooksByEvent(ctx, eventType)
+	var webhooks []db.Webhook
+	err := s.withTenant(ctx, tenantID, func(q *db.Queries) error {
+		var err error
+		webhooks, err = q.GetWebhooksByEvent(ctx, eventType)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to get webhooks: %w", err)
 	}
 
-	// Queue delivery for each webhook
+	// Queue delivery for each webhook whose filter the event passes
 	for _, webhook := range webhooks {
+		ok, err := Matches(ctx, s.filters, webhook.Filter, payload)
+		if err != nil {
+			s.logger.Warn("failed to evaluate webhook filter, skipping", "webhook_id", webhook.ID, "event", eventType, "error", err)
+			continue
+		}
+		if !ok {
+			s.logger.Debug("webhook filtered out", "webhook_id", webhook.ID, "event", eventType)
+			continue
+		}
+
 		job := &DeliveryJob{
 			WebhookID: webhook.ID,
 			TenantID:  tenantID,
@@ -113,16 +147,13 @@ func (s *DeliveryService) worker(id int) {
 
 // deliverWebhook delivers a webhook with retry logic
 func (s *DeliveryService) deliverWebhook(ctx context.Context, job *DeliveryJob) {
-	// Set tenant context
-	ctx = context.WithValue(ctx, "tenant_id", job.TenantID)
-	_, err := s.dbConn.ExecContext(ctx, "SET LOCAL app.tenant_id = $1", job.TenantID)
-	if err != nil {
-		s.logger.Error("failed to set tenant context", "error", err)
-		return
-	}
-
 	// Get webhook config
-	webhook, err := s.queries.GetWebhookByID(ctx, job.WebhookID)
+	var webhook db.Webhook
+	err := s.withTenant(ctx, job.TenantID, func(q *db.Queries) error {
+		var err error
+		webhook, err = q.GetWebhookByID(ctx, job.WebhookID)
+		return err
+	})
 	if err != nil {
 		s.logger.Error("failed to get webhook", "webhook_id", job.WebhookID, "error", err)
 		return
@@ -148,14 +179,17 @@ func (s *DeliveryService) deliverWebhook(ctx context.Context, job *DeliveryJob)
 		status, statusCode, respBody, err := s.sendRequest(ctx, webhook.Url, webhook.Secret, job.Event, body)
 
 		// Record delivery attempt
-		_, dbErr := s.queries.InsertWebhookDelivery(ctx, db.InsertWebhookDeliveryParams{
-			WebhookID:    webhook.ID,
-			EventType:    job.Event,
-			Attempt:      int32(attempt),
-			Status:       status,
-			ResponseCode: pgtype.Int4{Int32: int32(statusCode), Valid: statusCode > 0},
-			ResponseBody: pgtype.Text{String: respBody, Valid: respBody != ""},
-			ErrorMessage: pgtype.Text{String: getErrorMessage(err), Valid: err != nil},
+		dbErr := s.withTenant(ctx, job.TenantID, func(q *db.Queries) error {
+			_, err := q.InsertWebhookDelivery(ctx, db.InsertWebhookDeliveryParams{
+				WebhookID:    webhook.ID,
+				EventType:    job.Event,
+				Attempt:      int32(attempt),
+				Status:       status,
+				ResponseCode: pgtype.Int4{Int32: int32(statusCode), Valid: statusCode > 0},
+				ResponseBody: pgtype.Text{String: respBody, Valid: respBody != ""},
+				ErrorMessage: pgtype.Text{String: getErrorMessage(err), Valid: err != nil},
+			})
+			return err
 		})
 
 		if dbErr != nil {
@@ -248,6 +282,12 @@ func (s *DeliveryService) NotifyRewardExpired(ctx context.Context, tenantID uuid
 	return s.SendWebhook(ctx, tenantID, EventRewardExpired, payload)
 }
 
+// NotifyRewardClawedBack sends reward.clawed_back webhook notifications
+func (s *DeliveryService) NotifyRewardClawedBack(ctx context.Context, tenantID uuid.UUID, data RewardClawedBackData) error {
+	payload := NewRewardClawedBackEvent(tenantID, data)
+	return s.SendWebhook(ctx, tenantID, EventRewardClawedBack, payload)
+}
+
 // NotifyCustomerEnrolled sends customer.enrolled webhook notifications
 func (s *DeliveryService) NotifyCustomerEnrolled(ctx context.Context, tenantID uuid.UUID, data CustomerEnrolledData) error {
 	payload := NewCustomerEnrolledEvent(tenantID, data)
@@ -260,6 +300,12 @@ func (s *DeliveryService) NotifyBudgetThreshold(ctx context.Context, tenantID uu
 	return s.SendWebhook(ctx, tenantID, EventBudgetThreshold, payload)
 }
 
+// NotifyFulfilmentOverdue sends fulfilment.overdue webhook notifications
+func (s *DeliveryService) NotifyFulfilmentOverdue(ctx context.Context, tenantID uuid.UUID, data FulfilmentOverdueData) error {
+	payload := NewFulfilmentOverdueEvent(tenantID, data)
+	return s.SendWebhook(ctx, tenantID, EventFulfilmentOverdue, payload)
+}
+
 func getErrorMessage(err error) string {
 	if err == nil {
 		return ""
@@ -0,0 +1,195 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrWebhookNotFound is returned when a tenant has no webhook with the ID
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// Params are the configurable fields of a webhook endpoint
+type Params struct {
+	Name   string
+	URL    string
+	Events []string
+	Filter json.RawMessage
+	Active bool
+}
+
+// Validate checks the endpoint's URL, event subscriptions and filter
+func (p Params) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("name is required")
+	}
+	if err := ValidateURL(p.URL); err != nil {
+		return err
+	}
+	if len(p.Events) == 0 {
+		return errors.New("at least one event is required")
+	}
+	for _, event := range p.Events {
+		if !IsEvent(event) {
+			return fmt.Errorf("unknown event %q; expected one of %s", event, strings.Join(Events, ", "))
+		}
+	}
+	return ValidateFilter(p.Filter)
+}
+
+// filter is the filter to store, nil when there is none
+func (p Params) filter() []byte {
+	if len(p.Filter) == 0 || string(p.Filter) == "null" {
+		return nil
+	}
+	return p.Filter
+}
+
+// Service manages tenants' webhook endpoints
+type Service struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+}
+
+// NewService creates a new webhook endpoint service
+func NewService(pool *pgxpool.Pool) *Service {
+	return &Service{
+		pool:    pool,
+		queries: db.New(pool),
+	}
+}
+
+// withTenant runs fn in a transaction scoped to the tenant, as the webhook
+// queries are filtered by RLS
+func (s *Service) withTenant(ctx context.Context, tenantID pgtype.UUID, fn func(q *db.Queries) error) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenantID.Bytes)); err != nil {
+		return fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	if err := fn(s.queries.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// List returns the tenant's webhook endpoints, newest first
+func (s *Service) List(ctx context.Context, tenantID pgtype.UUID) ([]db.Webhook, error) {
+	var webhooks []db.Webhook
+	err := s.withTenant(ctx, tenantID, func(q *db.Queries) error {
+		var err error
+		webhooks, err = q.ListWebhooks(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// Get returns one of the tenant's webhook endpoints
+func (s *Service) Get(ctx context.Context, tenantID, id pgtype.UUID) (db.Webhook, error) {
+	var webhook db.Webhook
+	err := s.withTenant(ctx, tenantID, func(q *db.Queries) error {
+		var err error
+		webhook, err = q.GetWebhookByID(ctx, id)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Webhook{}, ErrWebhookNotFound
+	}
+	if err != nil {
+		return db.Webhook{}, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// Create adds a webhook endpoint with a new signing secret
+func (s *Service) Create(ctx context.Context, tenantID pgtype.UUID, params Params) (db.Webhook, error) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		return db.Webhook{}, err
+	}
+
+	var webhook db.Webhook
+	err = s.withTenant(ctx, tenantID, func(q *db.Queries) error {
+		var err error
+		webhook, err = q.CreateWebhook(ctx, db.CreateWebhookParams{
+			Name:   params.Name,
+			Url:    params.URL,
+			Events: params.Events,
+			Filter: params.filter(),
+			Secret: secret,
+			Active: params.Active,
+		})
+		return err
+	})
+	if err != nil {
+		return db.Webhook{}, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// Update replaces a webhook endpoint's configuration. Its secret is kept.
+func (s *Service) Update(ctx context.Context, tenantID, id pgtype.UUID, params Params) (db.Webhook, error) {
+	var webhook db.Webhook
+	err := s.withTenant(ctx, tenantID, func(q *db.Queries) error {
+		var err error
+		webhook, err = q.UpdateWebhook(ctx, db.UpdateWebhookParams{
+			ID:     id,
+			Name:   params.Name,
+			Url:    params.URL,
+			Events: params.Events,
+			Filter: params.filter(),
+			Active: params.Active,
+		})
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Webhook{}, ErrWebhookNotFound
+	}
+	if err != nil {
+		return db.Webhook{}, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// Deactivate stops deliveries to a webhook endpoint. Endpoints are kept
+// rather than deleted as their delivery history references them.
+func (s *Service) Deactivate(ctx context.Context, tenantID, id pgtype.UUID) (db.Webhook, error) {
+	webhook, err := s.Get(ctx, tenantID, id)
+	if err != nil {
+		return db.Webhook{}, err
+	}
+	return s.Update(ctx, tenantID, id, Params{
+		Name:   webhook.Name,
+		URL:    webhook.Url,
+		Events: webhook.Events,
+		Filter: webhook.Filter,
+		Active: false,
+	})
+}
+
+// GenerateSecret generates a secret webhook deliveries are signed with
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
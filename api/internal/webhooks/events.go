@@ -12,9 +12,33 @@ const (
 	EventRewardIssued     = "reward.issued"
 	EventRewardRedeemed   = "reward.redeemed"
 	EventRewardExpired    = "reward.expired"
+	EventRewardClawedBack = "reward.clawed_back"
 	EventBudgetThreshold  = "budget.threshold"
+
+	EventFulfilmentOverdue = "fulfilment.overdue"
 )
 
+// Events lists the event types endpoints can subscribe to
+var Events = []string{
+	EventCustomerEnrolled,
+	EventRewardIssued,
+	EventRewardRedeemed,
+	EventRewardExpired,
+	EventRewardClawedBack,
+	EventBudgetThreshold,
+	EventFulfilmentOverdue,
+}
+
+// IsEvent reports whether eventType is one endpoints can subscribe to
+func IsEvent(eventType string) bool {
+	for _, e := range Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
 // EventPayload is the base structure for all webhook events
 type EventPayload struct {
 	Event     string      `json:"event"`
@@ -78,16 +102,47 @@ type RewardExpiredData struct {
 	IssuedAt   string  `json:"issued_at"`
 }
 
+// RewardClawedBackData contains data for reward.clawed_back event. Receivers
+// should reverse any fulfilment of the redeemed reward.
+type RewardClawedBackData struct {
+	ClawbackID   string  `json:"clawback_id"`
+	IssuanceID   string  `json:"issuance_id"`
+	CustomerID   string  `json:"customer_id"`
+	RewardID     string  `json:"reward_id"`
+	Code         string  `json:"code,omitempty"`
+	ExternalRef  string  `json:"external_ref,omitempty"`
+	Amount       float64 `json:"amount,omitempty"`
+	Currency     string  `json:"currency,omitempty"`
+	ReasonCode   string  `json:"reason_code"`
+	Note         string  `json:"note,omitempty"`
+	RedeemedAt   string  `json:"redeemed_at,omitempty"`
+	ClawedBackAt string  `json:"clawed_back_at"`
+}
+
 // BudgetThresholdData contains data for budget.threshold event
 type BudgetThresholdData struct {
 	BudgetID   string  `json:"budget_id"`
 	BudgetName string  `json:"budget_name"`
-	Threshold  string  `json:"threshold"` // "soft_cap" or "hard_cap"
+	Threshold  string  `json:"threshold"` // "soft_cap", "hard_cap" or "overdraft"
 	Balance    float64 `json:"balance"`
 	SoftCap    float64 `json:"soft_cap"`
 	HardCap    float64 `json:"hard_cap"`
 	Currency   string  `json:"currency"`
 	Utilization float64 `json:"utilization"` // Percentage
+	Resolved   bool    `json:"resolved,omitempty"` // Back below the threshold
+}
+
+// FulfilmentOverdueData contains data for fulfilment.overdue event, sent once
+// when a physical reward hasn't been delivered by its due date
+type FulfilmentOverdueData struct {
+	FulfilmentID string `json:"fulfilment_id"`
+	IssuanceID   string `json:"issuance_id"`
+	CustomerID   string `json:"customer_id"`
+	RewardName   string `json:"reward_name"`
+	Status       string `json:"status"`
+	Carrier      string `json:"carrier,omitempty"`
+	TrackingRef  string `json:"tracking_ref,omitempty"`
+	DueAt        string `json:"due_at"`
 }
 
 // NewEventPayload creates a new event payload
@@ -124,3 +179,13 @@ func NewRewardExpiredEvent(tenantID uuid.UUID, data RewardExpiredData) EventPayl
 func NewBudgetThresholdEvent(tenantID uuid.UUID, data BudgetThresholdData) EventPayload {
 	return NewEventPayload(EventBudgetThreshold, tenantID, data)
 }
+
+// NewRewardClawedBackEvent creates a reward.clawed_back event
+func NewRewardClawedBackEvent(tenantID uuid.UUID, data RewardClawedBackData) EventPayload {
+	return NewEventPayload(EventRewardClawedBack, tenantID, data)
+}
+
+// NewFulfilmentOverdueEvent creates a fulfilment.overdue event
+func NewFulfilmentOverdueEvent(tenantID uuid.UUID, data FulfilmentOverdueData) EventPayload {
+	return NewEventPayload(EventFulfilmentOverdue, tenantID, data)
+}
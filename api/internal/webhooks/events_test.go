@@ -106,6 +106,27 @@ func TestNewRewardExpiredEvent(t *testing.T) {
 	assert.Equal(t, data, event.Data)
 }
 
+func TestNewRewardClawedBackEvent(t *testing.T) {
+	tenantID := uuid.New()
+	data := webhooks.RewardClawedBackData{
+		ClawbackID:   "clawback-123",
+		IssuanceID:   "issuance-123",
+		CustomerID:   "customer-123",
+		RewardID:     "reward-123",
+		Amount:       5.00,
+		Currency:     "USD",
+		ReasonCode:   "refund",
+		RedeemedAt:   "2025-11-14T10:00:00Z",
+		ClawedBackAt: "2025-11-20T10:00:00Z",
+	}
+
+	event := webhooks.NewRewardClawedBackEvent(tenantID, data)
+
+	assert.Equal(t, webhooks.EventRewardClawedBack, event.Event)
+	assert.Equal(t, tenantID.String(), event.TenantID)
+	assert.Equal(t, data, event.Data)
+}
+
 func TestNewBudgetThresholdEvent(t *testing.T) {
 	tenantID := uuid.New()
 	data := webhooks.BudgetThresholdData{
@@ -167,5 +188,7 @@ func TestEventTypes_Constants(t *testing.T) {
 	assert.Equal(t, "reward.issued", webhooks.EventRewardIssued)
 	assert.Equal(t, "reward.redeemed", webhooks.EventRewardRedeemed)
 	assert.Equal(t, "reward.expired", webhooks.EventRewardExpired)
+	assert.Equal(t, "reward.clawed_back", webhooks.EventRewardClawedBack)
 	assert.Equal(t, "budget.threshold", webhooks.EventBudgetThreshold)
+	assert.Equal(t, "fulfilment.overdue", webhooks.EventFulfilmentOverdue)
 }
@@ -0,0 +1,56 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidFilter is returned for an endpoint filter that isn't a JsonLogic
+// expression
+var ErrInvalidFilter = errors.New("filter must be a JsonLogic expression object")
+
+// FilterEvaluator evaluates endpoint filters. It is implemented by
+// rules.Evaluator, which can't be imported here as the rules engine sends
+// webhooks.
+type FilterEvaluator interface {
+	Evaluate(ctx context.Context, logic json.RawMessage, data map[string]interface{}) (bool, error)
+}
+
+// ValidateFilter checks that filter is empty or a JsonLogic expression, i.e.
+// a JSON object with a single operator
+func ValidateFilter(filter json.RawMessage) error {
+	if len(filter) == 0 || string(filter) == "null" {
+		return nil
+	}
+	var expr map[string]json.RawMessage
+	if err := json.Unmarshal(filter, &expr); err != nil || len(expr) != 1 {
+		return ErrInvalidFilter
+	}
+	return nil
+}
+
+// Matches reports whether an event passes an endpoint's filter. The filter
+// is evaluated against the event's data, so {"var": "face_amount"} is the
+// face amount of a reward.issued event. Endpoints without a filter receive
+// every event they subscribe to.
+func Matches(ctx context.Context, evaluator FilterEvaluator, filter []byte, payload EventPayload) (bool, error) {
+	if len(filter) == 0 || string(filter) == "null" {
+		return true, nil
+	}
+	if evaluator == nil {
+		return false, errors.New("no filter evaluator configured")
+	}
+
+	b, err := json.Marshal(payload.Data)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal event data: %w", err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return false, fmt.Errorf("failed to read event data: %w", err)
+	}
+
+	return evaluator.Evaluate(ctx, filter, data)
+}
@@ -0,0 +1,79 @@
+package webhooks_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/bmachimbira/loyalty/api/internal/rules"
+	"github.com/bmachimbira/loyalty/api/internal/webhooks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateFilter(t *testing.T) {
+	assert.NoError(t, webhooks.ValidateFilter(nil))
+	assert.NoError(t, webhooks.ValidateFilter(json.RawMessage(`null`)))
+	assert.NoError(t, webhooks.ValidateFilter(json.RawMessage(`{">": [{"var": "face_amount"}, 50]}`)))
+
+	for _, filter := range []string{`[1]`, `{}`, `{"==": [1, 1], "!=": [1, 2]}`, `"face_amount"`, `{`} {
+		assert.ErrorIs(t, webhooks.ValidateFilter(json.RawMessage(filter)), webhooks.ErrInvalidFilter, filter)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	ctx := context.Background()
+	evaluator := rules.NewEvaluator(nil)
+	filter := []byte(`{">": [{"var": "face_amount"}, 50]}`)
+
+	large := webhooks.NewRewardIssuedEvent(uuid.New(), webhooks.RewardIssuedData{IssuanceID: "iss-1", FaceAmount: 75})
+	small := webhooks.NewRewardIssuedEvent(uuid.New(), webhooks.RewardIssuedData{IssuanceID: "iss-2", FaceAmount: 20})
+
+	ok, err := webhooks.Matches(ctx, evaluator, filter, large)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = webhooks.Matches(ctx, evaluator, filter, small)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Endpoints without a filter receive every event
+	ok, err = webhooks.Matches(ctx, nil, nil, small)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = webhooks.Matches(ctx, nil, filter, small)
+	assert.Error(t, err)
+}
+
+func TestParams_Validate(t *testing.T) {
+	valid := webhooks.Params{
+		Name:   "ERP",
+		URL:    "https://erp.example.com/hooks",
+		Events: []string{webhooks.EventRewardIssued},
+		Filter: json.RawMessage(`{">": [{"var": "face_amount"}, 50]}`),
+		Active: true,
+	}
+	assert.NoError(t, valid.Validate())
+
+	noName := valid
+	noName.Name = " "
+	assert.Error(t, noName.Validate())
+
+	badURL := valid
+	badURL.URL = "ftp://erp.example.com"
+	assert.Error(t, badURL.Validate())
+
+	noEvents := valid
+	noEvents.Events = nil
+	assert.Error(t, noEvents.Validate())
+
+	unknownEvent := valid
+	unknownEvent.Events = []string{"reward.sent"}
+	assert.Error(t, unknownEvent.Validate())
+
+	badFilter := valid
+	badFilter.Filter = json.RawMessage(`[]`)
+	assert.ErrorIs(t, badFilter.Validate(), webhooks.ErrInvalidFilter)
+}
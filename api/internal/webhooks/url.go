@@ -0,0 +1,45 @@
+package webhooks
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+)
+
+var (
+	// ErrInvalidURL is returned for webhook URLs that aren't absolute
+	// http(s) URLs
+	ErrInvalidURL = errors.New("url must be an http or https URL")
+
+	// ErrPrivateURL is returned for webhook URLs on a local or private host
+	ErrPrivateURL = errors.New("url must not point at a local or private host")
+)
+
+// ValidateURL checks that a tenant-supplied URL may be posted to: it must be
+// an absolute http(s) URL whose host isn't a local name or a loopback,
+// private, link-local or unspecified address, so tenants can't point the API
+// at internal services
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return ErrInvalidURL
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return ErrInvalidURL
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") ||
+		strings.HasSuffix(host, ".local") || strings.HasSuffix(host, ".internal") {
+		return ErrPrivateURL
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+			ip.IsUnspecified() || ip.IsMulticast() {
+			return ErrPrivateURL
+		}
+	}
+	return nil
+}
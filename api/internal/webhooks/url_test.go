@@ -0,0 +1,38 @@
+package webhooks_test
+
+import (
+	"testing"
+
+	"github.com/bmachimbira/loyalty/api/internal/webhooks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want error
+	}{
+		{"https", "https://hooks.example.com/loyalty", nil},
+		{"http with port", "http://hooks.example.com:8080/alerts", nil},
+		{"public IP", "https://203.0.113.10/alerts", nil},
+		{"no scheme", "hooks.example.com/alerts", webhooks.ErrInvalidURL},
+		{"ftp", "ftp://hooks.example.com", webhooks.ErrInvalidURL},
+		{"no host", "https:///alerts", webhooks.ErrInvalidURL},
+		{"localhost", "http://localhost:8080/alerts", webhooks.ErrPrivateURL},
+		{"localhost trailing dot", "http://LOCALHOST./alerts", webhooks.ErrPrivateURL},
+		{"internal name", "https://billing.internal/alerts", webhooks.ErrPrivateURL},
+		{"loopback", "http://127.0.0.1/alerts", webhooks.ErrPrivateURL},
+		{"private", "http://10.1.2.3/alerts", webhooks.ErrPrivateURL},
+		{"metadata service", "http://169.254.169.254/latest/meta-data", webhooks.ErrPrivateURL},
+		{"unspecified", "http://0.0.0.0/alerts", webhooks.ErrPrivateURL},
+		{"IPv6 loopback", "http://[::1]/alerts", webhooks.ErrPrivateURL},
+		{"IPv6 unique local", "http://[fd00::1]/alerts", webhooks.ErrPrivateURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, webhooks.ValidateURL(tt.url))
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package welcome
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/metering"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TemplateSender sends WhatsApp template messages
+type TemplateSender interface {
+	SendTemplate(ctx context.Context, to, templateName string, params map[string]string) error
+}
+
+// Service steps customers through the welcome series
+type Service struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+	sender  TemplateSender
+	logger  *slog.Logger
+}
+
+// NewService creates a new welcome series service
+func NewService(pool *pgxpool.Pool, queries *db.Queries, logger *slog.Logger) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Service{
+		pool:    pool,
+		queries: queries,
+		logger:  logger,
+	}
+}
+
+// SetSender enables sending tenants' welcome templates over WhatsApp
+func (s *Service) SetSender(sender TemplateSender) {
+	s.sender = sender
+}
+
+// message is a template due to a customer after a sweep
+type message struct {
+	seriesID   pgtype.UUID
+	customerID pgtype.UUID
+	reminder   bool
+	phone      string
+	template   string
+}
+
+// Sweep sends the tenant's due welcome and reminder messages, and completes
+// series whose bonus window has ended, returning how many messages were
+// sent. Messages are recorded as sent before they're sent, so a failure
+// after the commit doesn't send them twice.
+func (s *Service) Sweep(ctx context.Context, tenant db.Tenant, now time.Time) (int, error) {
+	messaging := s.sender != nil
+	nowTS := pgtype.Timestamptz{Time: now, Valid: true}
+
+	var messages []message
+	err := s.withTenant(ctx, tenant.ID, func(qtx *db.Queries) error {
+		// Purchases are recorded first so reminders aren't sent to customers
+		// who have already bought
+		if _, err := qtx.RecordWelcomeFirstPurchases(ctx, tenant.ID); err != nil {
+			return fmt.Errorf("failed to record first purchases: %w", err)
+		}
+
+		rows, err := qtx.ListDueWelcomeSeries(ctx, db.ListDueWelcomeSeriesParams{
+			TenantID: tenant.ID,
+			Now:      nowTS,
+			RowLimit: BatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list due series: %w", err)
+		}
+
+		for _, row := range rows {
+			m, send, err := s.step(ctx, qtx, tenant, row, messaging, now)
+			if err != nil {
+				return err
+			}
+			if send {
+				messages = append(messages, m)
+			}
+		}
+
+		if _, err := qtx.CompleteWelcomeSeries(ctx, db.CompleteWelcomeSeriesParams{
+			Now:      nowTS,
+			TenantID: tenant.ID,
+		}); err != nil {
+			return fmt.Errorf("failed to complete series: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, m := range messages {
+		if s.sendTemplate(ctx, tenant, m) {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// step records the outcome of the series' next message. The welcome comes
+// first; a reminder due at the same time waits for the next sweep. send is
+// set when the template is to be sent once the sweep commits.
+func (s *Service) step(ctx context.Context, qtx *db.Queries, tenant db.Tenant, row db.ListDueWelcomeSeriesRow, messaging bool, now time.Time) (message, bool, error) {
+	m := message{
+		seriesID:   row.ID,
+		customerID: row.CustomerID,
+		reminder:   row.WelcomeStatus.Valid,
+		phone:      row.PhoneE164.String,
+	}
+
+	template := tenant.WelcomeTemplate
+	if m.reminder {
+		template = tenant.WelcomeReminderTemplate
+	}
+
+	status := skipReason(tenant, template, messaging, row.CustomerStatus, m.phone)
+	if m.reminder && row.FirstPurchaseAt.Valid {
+		status = MessagePurchased
+	}
+	if status == "" {
+		granted, err := hasConsent(ctx, qtx, tenant.ID, row.CustomerID)
+		if err != nil {
+			return message{}, false, err
+		}
+		if !granted {
+			status = MessageNoConsent
+		}
+	}
+
+	send := status == ""
+	if send {
+		m.template = template.String
+		status = MessageSent
+	}
+	if err := setStatus(ctx, qtx, tenant.ID, m, status, now); err != nil {
+		return message{}, false, err
+	}
+	return m, send, nil
+}
+
+// hasConsent reports whether the customer has consented to loyalty messages
+// on WhatsApp
+func hasConsent(ctx context.Context, qtx *db.Queries, tenantID, customerID pgtype.UUID) (bool, error) {
+	consent, err := qtx.GetLatestConsent(ctx, db.GetLatestConsentParams{
+		TenantID:   tenantID,
+		CustomerID: customerID,
+		Channel:    "whatsapp",
+		Purpose:    "loyalty",
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get consent: %w", err)
+	}
+	return consent.Granted, nil
+}
+
+// sendTemplate sends a message and records a failure. Sends that fail for a
+// reason worth retrying are queued by the sender and count as sent.
+func (s *Service) sendTemplate(ctx context.Context, tenant db.Tenant, m message) bool {
+	err := s.sender.SendTemplate(metering.WithTenant(ctx, tenant.ID), m.phone, m.template, nil)
+	if err == nil {
+		return true
+	}
+	s.logger.Warn("failed to send welcome series template",
+		"tenant_id", httputil.FormatUUID(tenant.ID.Bytes),
+		"customer_id", httputil.FormatUUID(m.customerID.Bytes),
+		"reminder", m.reminder,
+		"error", err,
+	)
+
+	err = s.withTenant(ctx, tenant.ID, func(qtx *db.Queries) error {
+		return setStatus(ctx, qtx, tenant.ID, m, MessageFailed, time.Time{})
+	})
+	if err != nil {
+		s.logger.Error("failed to record welcome series message status",
+			"series_id", httputil.FormatUUID(m.seriesID.Bytes),
+			"error", err,
+		)
+	}
+	return false
+}
+
+// setStatus records how the series' message went; sentAt is kept for sent
+// messages only
+func setStatus(ctx context.Context, qtx *db.Queries, tenantID pgtype.UUID, m message, status string, sentAt time.Time) error {
+	statusText := pgtype.Text{String: status, Valid: true}
+	sentAtTS := pgtype.Timestamptz{Time: sentAt, Valid: status == MessageSent}
+
+	var err error
+	if m.reminder {
+		err = qtx.SetWelcomeReminderStatus(ctx, db.SetWelcomeReminderStatusParams{
+			ID:             m.seriesID,
+			TenantID:       tenantID,
+			ReminderStatus: statusText,
+			ReminderSentAt: sentAtTS,
+		})
+	} else {
+		err = qtx.SetWelcomeStatus(ctx, db.SetWelcomeStatusParams{
+			ID:            m.seriesID,
+			TenantID:      tenantID,
+			WelcomeStatus: statusText,
+			WelcomeSentAt: sentAtTS,
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record message status: %w", err)
+	}
+	return nil
+}
+
+// SweepAll sweeps every tenant with the series on, or with series still
+// open. A failing tenant is logged and does not stop the others.
+func (s *Service) SweepAll(ctx context.Context, now time.Time) error {
+	tenants, err := s.queries.ListWelcomeSeriesTenants(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	for _, tenant := range tenants {
+		sent, err := s.Sweep(ctx, tenant, now)
+		if err != nil {
+			s.logger.Error("welcome series sweep failed",
+				"tenant_id", httputil.FormatUUID(tenant.ID.Bytes),
+				"error", err)
+			continue
+		}
+		if sent > 0 {
+			s.logger.Info("sent welcome series messages",
+				"tenant_id", httputil.FormatUUID(tenant.ID.Bytes),
+				"messages", sent)
+		}
+	}
+	return nil
+}
+
+// Run sweeps every tenant on a schedule until ctx is cancelled
+func (s *Service) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.SweepAll(ctx, time.Now()); err != nil {
+			s.logger.Error("welcome series sweep failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// withTenant runs fn in a transaction scoped to the tenant. Sweeps run
+// outside a tenant request.
+func (s *Service) withTenant(ctx context.Context, tenantID pgtype.UUID, fn func(qtx *db.Queries) error) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenantID.Bytes)); err != nil {
+		return fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	if err := fn(s.queries.WithTx(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Package welcome runs the welcome series for new customers. Customers
+// enrolled while their tenant has the series on are sent the tenant's
+// welcome template straight away and its reminder template after a few days
+// if they haven't made a purchase, and rules using the in_welcome_window
+// operator apply to them for their first days. The series is scheduled by a
+// database trigger as customers are created (migration 067).
+package welcome
+
+import (
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Statuses recorded against a message of the series
+const (
+	MessageSent      = "sent"
+	MessageFailed    = "failed"
+	MessageNoConsent = "no_consent"
+	MessageNoPhone   = "no_phone"
+	// MessageSkipped is recorded when the tenant has no template for the
+	// message, WhatsApp isn't configured, the series has been turned off or
+	// the customer is no longer active
+	MessageSkipped = "skipped"
+	// MessagePurchased is recorded instead of the reminder when the customer
+	// has already made a purchase
+	MessagePurchased = "purchased"
+)
+
+// Series statuses shown on the customer profile
+const (
+	StatusAwaitingWelcome  = "awaiting_welcome"
+	StatusAwaitingReminder = "awaiting_reminder"
+	StatusBonusWindow      = "bonus_window"
+	StatusCompleted        = "completed"
+)
+
+// BatchSize is the most series of one tenant stepped per sweep; the rest are
+// picked up by the next
+const BatchSize = 500
+
+// Status returns where a customer's series is as of now
+func Status(series db.WelcomeSeries, now time.Time) string {
+	switch {
+	case series.CompletedAt.Valid:
+		return StatusCompleted
+	case !series.WelcomeStatus.Valid:
+		return StatusAwaitingWelcome
+	case !series.ReminderStatus.Valid:
+		return StatusAwaitingReminder
+	case now.Before(series.BonusEndsAt.Time):
+		return StatusBonusWindow
+	default:
+		// Waiting for the next sweep to close it
+		return StatusCompleted
+	}
+}
+
+// skipReason returns the status recorded instead of sending template, or ""
+// when it should be sent, subject to the customer's consent
+func skipReason(tenant db.Tenant, template pgtype.Text, messaging bool, customerStatus, phone string) string {
+	switch {
+	case !tenant.WelcomeSeriesEnabled, !template.Valid, !messaging, customerStatus != "active":
+		return MessageSkipped
+	case phone == "":
+		return MessageNoPhone
+	default:
+		return ""
+	}
+}
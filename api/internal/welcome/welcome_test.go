@@ -0,0 +1,45 @@
+package welcome
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus(t *testing.T) {
+	now := time.Date(2025, 12, 30, 9, 0, 0, 0, time.UTC)
+	sent := pgtype.Text{String: MessageSent, Valid: true}
+	series := db.WelcomeSeries{BonusEndsAt: pgtype.Timestamptz{Time: now.AddDate(0, 0, 7), Valid: true}}
+
+	assert.Equal(t, StatusAwaitingWelcome, Status(series, now))
+
+	series.WelcomeStatus = sent
+	assert.Equal(t, StatusAwaitingReminder, Status(series, now))
+
+	series.ReminderStatus = pgtype.Text{String: MessagePurchased, Valid: true}
+	assert.Equal(t, StatusBonusWindow, Status(series, now))
+
+	// Past the bonus window but not yet closed by a sweep
+	assert.Equal(t, StatusCompleted, Status(series, now.AddDate(0, 0, 8)))
+
+	series.WelcomeStatus = pgtype.Text{}
+	series.CompletedAt = pgtype.Timestamptz{Time: now, Valid: true}
+	assert.Equal(t, StatusCompleted, Status(series, now))
+}
+
+func TestSkipReason(t *testing.T) {
+	tenant := db.Tenant{WelcomeSeriesEnabled: true}
+	template := pgtype.Text{String: "welcome_v1", Valid: true}
+
+	assert.Equal(t, "", skipReason(tenant, template, true, "active", "+263771234567"))
+	assert.Equal(t, MessageNoPhone, skipReason(tenant, template, true, "active", ""))
+	assert.Equal(t, MessageSkipped, skipReason(tenant, pgtype.Text{}, true, "active", "+263771234567"))
+	assert.Equal(t, MessageSkipped, skipReason(tenant, template, false, "active", "+263771234567"))
+	assert.Equal(t, MessageSkipped, skipReason(tenant, template, true, "suspended", "+263771234567"))
+
+	// Series left open when the tenant turned it off send nothing more
+	assert.Equal(t, MessageSkipped, skipReason(db.Tenant{}, template, true, "active", "+263771234567"))
+}
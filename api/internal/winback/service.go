@@ -0,0 +1,293 @@
+package winback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/deadletter"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/bmachimbira/loyalty/api/internal/metering"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TemplateSender sends WhatsApp template messages
+type TemplateSender interface {
+	SendTemplate(ctx context.Context, to, templateName string, params map[string]string) error
+}
+
+// Service finds dormant customers and targets them
+type Service struct {
+	pool        *pgxpool.Pool
+	queries     *db.Queries
+	processor   deadletter.EventProcessor
+	deadLetters *deadletter.Service
+	sender      TemplateSender
+	logger      *slog.Logger
+}
+
+// NewService creates a new win-back service. customer_dormant events are run
+// through processor so rules can grant win-back rewards.
+func NewService(pool *pgxpool.Pool, queries *db.Queries, processor deadletter.EventProcessor, logger *slog.Logger) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Service{
+		pool:        pool,
+		queries:     queries,
+		processor:   processor,
+		deadLetters: deadletter.NewService(queries, processor),
+		logger:      logger,
+	}
+}
+
+// SetSender enables sending tenants' win-back templates over WhatsApp
+func (s *Service) SetSender(sender TemplateSender) {
+	s.sender = sender
+}
+
+// contact is a customer targeted by a sweep
+type contact struct {
+	db.WinbackContact
+	event db.Event
+	phone string
+	// send is set when the customer is due the tenant's template
+	send bool
+}
+
+// Sweep targets the tenant's dormant customers and returns how many were
+// targeted. Events and contacts are recorded together, then the events are
+// run through the rules and the template sent, so a failure after the
+// commit doesn't target the customer twice.
+func (s *Service) Sweep(ctx context.Context, tenant db.Tenant, now time.Time) (int, error) {
+	inactiveSince, contactedSince, ok := Cutoffs(tenant, now)
+	if !ok {
+		return 0, nil
+	}
+	messaging := tenant.WinbackTemplate.Valid && s.sender != nil
+
+	var contacts []contact
+	err := s.withTenant(ctx, tenant.ID, func(qtx *db.Queries) error {
+		customers, err := qtx.ListDormantCustomers(ctx, db.ListDormantCustomersParams{
+			TenantID:       tenant.ID,
+			InactiveSince:  pgtype.Timestamptz{Time: inactiveSince, Valid: true},
+			ContactedSince: pgtype.Timestamptz{Time: contactedSince, Valid: true},
+			RowLimit:       BatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list dormant customers: %w", err)
+		}
+
+		for _, customer := range customers {
+			c, err := s.target(ctx, qtx, tenant, customer, now)
+			if errors.Is(err, pgx.ErrNoRows) {
+				// Another sweep got to this dormant spell first
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if messaging {
+				if err := s.checkMessage(ctx, qtx, &c); err != nil {
+					return err
+				}
+			}
+			contacts = append(contacts, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, c := range contacts {
+		if _, err := s.processor.ProcessEvent(ctx, c.event); err != nil {
+			// The customer is targeted; park the event so the reward can be
+			// retried
+			s.logger.Error("rules engine processing failed",
+				"event_id", c.event.ID,
+				"customer_id", c.CustomerID,
+				"error", err,
+			)
+			if _, dlqErr := s.deadLetters.Record(ctx, c.event, err); dlqErr != nil {
+				s.logger.Error("failed to record dead letter", "event_id", c.event.ID, "error", dlqErr)
+			}
+		}
+		if c.send {
+			s.sendTemplate(ctx, tenant, c)
+		}
+	}
+	return len(contacts), nil
+}
+
+// target records the customer_dormant event and contact for a customer. It
+// returns pgx.ErrNoRows when the dormant spell already has its event.
+func (s *Service) target(ctx context.Context, qtx *db.Queries, tenant db.Tenant, customer db.ListDormantCustomersRow, now time.Time) (contact, error) {
+	properties, err := eventProperties(tenant, customer.LastActivityAt.Time, now)
+	if err != nil {
+		return contact{}, err
+	}
+
+	event, err := qtx.InsertEventIfNew(ctx, db.InsertEventIfNewParams{
+		TenantID:       tenant.ID,
+		CustomerID:     customer.ID,
+		EventType:      EventTypeCustomerDormant,
+		Properties:     properties,
+		OccurredAt:     pgtype.Timestamptz{Time: now, Valid: true},
+		Source:         "winback",
+		IdempotencyKey: idempotencyKey(customer.ID, customer.LastActivityAt.Time),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return contact{}, err
+		}
+		return contact{}, fmt.Errorf("failed to create event: %w", err)
+	}
+
+	wc, err := qtx.CreateWinbackContact(ctx, db.CreateWinbackContactParams{
+		TenantID:       tenant.ID,
+		CustomerID:     customer.ID,
+		EventID:        event.ID,
+		LastActivityAt: customer.LastActivityAt,
+	})
+	if err != nil {
+		return contact{}, fmt.Errorf("failed to record win-back contact: %w", err)
+	}
+	return contact{WinbackContact: wc, event: event, phone: customer.PhoneE164.String}, nil
+}
+
+// checkMessage works out whether the customer can be sent the template:
+// they need a phone number and to have consented to loyalty messages on
+// WhatsApp. Customers who can't are recorded as such.
+func (s *Service) checkMessage(ctx context.Context, qtx *db.Queries, c *contact) error {
+	status := ""
+	if c.phone == "" {
+		status = MessageNoPhone
+	} else {
+		consent, err := qtx.GetLatestConsent(ctx, db.GetLatestConsentParams{
+			TenantID:   c.TenantID,
+			CustomerID: c.CustomerID,
+			Channel:    "whatsapp",
+			Purpose:    "loyalty",
+		})
+		switch {
+		case errors.Is(err, pgx.ErrNoRows), err == nil && !consent.Granted:
+			status = MessageNoConsent
+		case err != nil:
+			return fmt.Errorf("failed to get consent: %w", err)
+		}
+	}
+
+	if status == "" {
+		c.send = true
+		return nil
+	}
+	return setMessageStatus(ctx, qtx, c.WinbackContact, status)
+}
+
+// sendTemplate sends the tenant's template to the customer and records how
+// it went. Sends that fail for a reason worth retrying are queued by the
+// sender and count as sent.
+func (s *Service) sendTemplate(ctx context.Context, tenant db.Tenant, c contact) {
+	status := MessageSent
+	if err := s.sender.SendTemplate(metering.WithTenant(ctx, tenant.ID), c.phone, tenant.WinbackTemplate.String, nil); err != nil {
+		s.logger.Warn("failed to send win-back template",
+			"tenant_id", httputil.FormatUUID(tenant.ID.Bytes),
+			"customer_id", httputil.FormatUUID(c.CustomerID.Bytes),
+			"error", err,
+		)
+		status = MessageFailed
+	}
+
+	err := s.withTenant(ctx, tenant.ID, func(qtx *db.Queries) error {
+		return setMessageStatus(ctx, qtx, c.WinbackContact, status)
+	})
+	if err != nil {
+		s.logger.Error("failed to record win-back message status",
+			"contact_id", httputil.FormatUUID(c.ID.Bytes),
+			"error", err,
+		)
+	}
+}
+
+func setMessageStatus(ctx context.Context, qtx *db.Queries, wc db.WinbackContact, status string) error {
+	if err := qtx.SetWinbackMessageStatus(ctx, db.SetWinbackMessageStatusParams{
+		ID:            wc.ID,
+		TenantID:      wc.TenantID,
+		MessageStatus: pgtype.Text{String: status, Valid: true},
+	}); err != nil {
+		return fmt.Errorf("failed to record message status: %w", err)
+	}
+	return nil
+}
+
+// SweepAll sweeps every tenant with win-back turned on. A failing tenant is
+// logged and does not stop the others.
+func (s *Service) SweepAll(ctx context.Context, now time.Time) error {
+	tenants, err := s.queries.ListWinbackTenants(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	for _, tenant := range tenants {
+		targeted, err := s.Sweep(ctx, tenant, now)
+		if err != nil {
+			s.logger.Error("win-back sweep failed",
+				"tenant_id", httputil.FormatUUID(tenant.ID.Bytes),
+				"error", err)
+			continue
+		}
+		if targeted > 0 {
+			s.logger.Info("targeted dormant customers",
+				"tenant_id", httputil.FormatUUID(tenant.ID.Bytes),
+				"customers", targeted)
+		}
+	}
+	return nil
+}
+
+// Run sweeps every tenant on a schedule until ctx is cancelled
+func (s *Service) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.SweepAll(ctx, time.Now()); err != nil {
+			s.logger.Error("win-back sweep failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// withTenant runs fn in a transaction scoped to the tenant. Sweeps run
+// outside a tenant request.
+func (s *Service) withTenant(ctx context.Context, tenantID pgtype.UUID, fn func(qtx *db.Queries) error) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", httputil.FormatUUID(tenantID.Bytes)); err != nil {
+		return fmt.Errorf("failed to set tenant context: %w", err)
+	}
+
+	if err := fn(s.queries.WithTx(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+// Package winback targets customers who have gone quiet. Each customer with
+// no events for their tenant's dormancy period gets a customer_dormant
+// event, which rules can grant a win-back reward on, and optionally a
+// WhatsApp template. Contacts are recorded so a customer is targeted once
+// per dormant spell and not again within the tenant's cooldown.
+package winback
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/httputil"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// EventTypeCustomerDormant is emitted for each customer targeted
+const EventTypeCustomerDormant = "customer_dormant"
+
+// Message statuses recorded against a contact
+const (
+	MessageSent      = "sent"
+	MessageFailed    = "failed"
+	MessageNoConsent = "no_consent"
+	MessageNoPhone   = "no_phone"
+)
+
+// BatchSize is the most customers of one tenant targeted per sweep; the rest
+// are picked up by the next
+const BatchSize = 500
+
+// Cutoffs returns the times a tenant's win-back sweep works from as of now:
+// customers inactive since before inactiveSince are dormant, and those
+// contacted after contactedSince are suppressed. ok is false when the
+// tenant hasn't turned win-back on.
+func Cutoffs(tenant db.Tenant, now time.Time) (inactiveSince, contactedSince time.Time, ok bool) {
+	if !tenant.WinbackDormantDays.Valid {
+		return time.Time{}, time.Time{}, false
+	}
+	inactiveSince = now.AddDate(0, 0, -int(tenant.WinbackDormantDays.Int32))
+	contactedSince = now.AddDate(0, 0, -int(tenant.WinbackCooldownDays))
+	return inactiveSince, contactedSince, true
+}
+
+// idempotencyKey identifies the customer's dormant spell, so a spell raises
+// one event however many sweeps see it
+func idempotencyKey(customerID pgtype.UUID, lastActivity time.Time) string {
+	return "winback:" + httputil.FormatUUID(customerID.Bytes) + ":" + strconv.FormatInt(lastActivity.Unix(), 10)
+}
+
+// eventProperties describes the dormant spell so rules can target, say,
+// customers gone for more than 90 days
+func eventProperties(tenant db.Tenant, lastActivity, now time.Time) ([]byte, error) {
+	properties, err := json.Marshal(map[string]interface{}{
+		"last_activity_at": lastActivity.UTC().Format(time.RFC3339),
+		"inactive_days":    int(now.Sub(lastActivity).Hours() / 24),
+		"dormant_days":     tenant.WinbackDormantDays.Int32,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event properties: %w", err)
+	}
+	return properties, nil
+}
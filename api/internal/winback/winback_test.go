@@ -0,0 +1,53 @@
+package winback
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCutoffs(t *testing.T) {
+	now := time.Date(2025, 12, 30, 9, 0, 0, 0, time.UTC)
+
+	inactiveSince, contactedSince, ok := Cutoffs(db.Tenant{
+		WinbackDormantDays:  pgtype.Int4{Int32: 60, Valid: true},
+		WinbackCooldownDays: 90,
+	}, now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2025, 10, 31, 9, 0, 0, 0, time.UTC), inactiveSince)
+	assert.Equal(t, time.Date(2025, 10, 1, 9, 0, 0, 0, time.UTC), contactedSince)
+
+	_, _, ok = Cutoffs(db.Tenant{WinbackCooldownDays: 90}, now)
+	assert.False(t, ok)
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	customerID := pgtype.UUID{Bytes: [16]byte{1, 2, 3}, Valid: true}
+	lastActivity := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	key := idempotencyKey(customerID, lastActivity)
+	assert.Equal(t, "winback:01020300-0000-0000-0000-000000000000:1759320000", key)
+
+	// A new dormant spell after further activity is a new event
+	assert.NotEqual(t, key, idempotencyKey(customerID, lastActivity.Add(time.Hour)))
+}
+
+func TestEventProperties(t *testing.T) {
+	tenant := db.Tenant{WinbackDormantDays: pgtype.Int4{Int32: 60, Valid: true}}
+	lastActivity := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	now := time.Date(2025, 12, 30, 9, 0, 0, 0, time.UTC)
+
+	raw, err := eventProperties(tenant, lastActivity, now)
+	require.NoError(t, err)
+
+	var properties map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &properties))
+	assert.Equal(t, "2025-10-01T12:00:00Z", properties["last_activity_at"])
+	assert.Equal(t, float64(89), properties["inactive_days"])
+	assert.Equal(t, float64(60), properties["dormant_days"])
+}
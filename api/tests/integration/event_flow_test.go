@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/enums"
 	"github.com/bmachimbira/loyalty/api/internal/rules"
 	"github.com/bmachimbira/loyalty/api/internal/testutil"
 )
@@ -116,7 +117,7 @@ func TestEventIngestion_EndToEnd(t *testing.T) {
 	// Find reservation entry
 	var foundReservation bool
 	for _, entry := range ledgerEntries {
-		if entry.Type == "reserve" && entry.IssuanceID.Valid {
+		if enums.LedgerEntryType(entry.EntryType) == enums.LedgerReserve && entry.IssuanceID.Valid {
 			foundReservation = true
 			assert.Equal(t, issuance.ID, entry.IssuanceID)
 			break
@@ -133,14 +133,11 @@ func TestIdempotency_ProcessingSameEventTwice(t *testing.T) {
 	issuanceID1 := issuances1[0].ID
 
 	// Process same event second time
-	// The engine should detect the event was already processed
+	// The rule already triggered for the event, so its issuance is returned
 	issuances2, err := engine.ProcessEvent(ctx, event)
 	require.NoError(t, err)
-
-	// Should return existing issuance or empty (depending on implementation)
-	if len(issuances2) > 0 {
-		assert.Equal(t, issuanceID1, issuances2[0].ID, "Should return same issuance")
-	}
+	require.Len(t, issuances2, 1, "Replay should return the existing issuance")
+	assert.Equal(t, issuanceID1, issuances2[0].ID, "Should return same issuance")
 
 	// Verify only one issuance exists in database
 	allIssuances, err := queries.ListIssuances(ctx, db.ListIssuancesParams{
@@ -15,6 +15,7 @@ import (
 
 	"github.com/bmachimbira/loyalty/api/internal/budget"
 	"github.com/bmachimbira/loyalty/api/internal/db"
+	"github.com/bmachimbira/loyalty/api/internal/enums"
 	"github.com/bmachimbira/loyalty/api/internal/rules"
 	"github.com/bmachimbira/loyalty/api/internal/testutil"
 )
@@ -449,7 +450,7 @@ func TestRulesEngine_CompleteFlow(t *testing.T) {
 	// Find the reservation entry
 	var reservationEntry *db.LedgerEntry
 	for _, entry := range ledgerEntries {
-		if entry.Type == "reserve" {
+		if enums.LedgerEntryType(entry.EntryType) == enums.LedgerReserve {
 			reservationEntry = &entry
 			break
 		}
@@ -471,7 +472,7 @@ func TestRulesEngine_CompleteFlow(t *testing.T) {
 
 	var chargeEntry *db.LedgerEntry
 	for _, entry := range ledgerEntries {
-		if entry.Type == "charge" {
+		if enums.LedgerEntryType(entry.EntryType) == enums.LedgerCharge {
 			chargeEntry = &entry
 			break
 		}
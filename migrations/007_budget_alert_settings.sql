@@ -0,0 +1,22 @@
+-- Per-budget alert thresholds and notification routing
+-- Version: 1.0
+-- Date: 2025-11-20
+
+-- =============================================================================
+-- BUDGET ALERT SETTINGS
+-- =============================================================================
+
+-- Thresholds are percentages of the hard cap. NULL means "use the platform
+-- defaults" (80% soft, 95% hard) so existing budgets keep their behaviour.
+ALTER TABLE budgets
+  ADD COLUMN alert_soft_percent    numeric(5,2)
+    CHECK (alert_soft_percent IS NULL OR (alert_soft_percent > 0 AND alert_soft_percent <= 100)),
+  ADD COLUMN alert_hard_percent    numeric(5,2)
+    CHECK (alert_hard_percent IS NULL OR (alert_hard_percent > 0 AND alert_hard_percent <= 100)),
+  ADD COLUMN alert_email           text,
+  ADD COLUMN alert_webhook_url     text,
+  ADD COLUMN alert_whatsapp_number text;
+
+ALTER TABLE budgets
+  ADD CONSTRAINT budgets_alert_percent_order
+  CHECK (alert_soft_percent IS NULL OR alert_hard_percent IS NULL OR alert_soft_percent <= alert_hard_percent);
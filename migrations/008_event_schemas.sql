@@ -0,0 +1,40 @@
+-- Event schema registry
+-- Version: 1.0
+-- Date: 2025-11-21
+
+-- =============================================================================
+-- EVENT SCHEMAS TABLE
+-- =============================================================================
+
+-- One JSON Schema per tenant and event type, used to validate event properties
+-- at ingestion. Strict schemas reject invalid events; non-strict schemas accept
+-- them and record the validation errors on the event.
+CREATE TABLE event_schemas (
+  id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id     uuid NOT NULL REFERENCES tenants(id),
+  event_type    text NOT NULL,
+  schema        jsonb NOT NULL,
+  strict        boolean NOT NULL DEFAULT false,
+  created_at    timestamptz NOT NULL DEFAULT now(),
+  updated_at    timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (tenant_id, event_type)
+);
+
+-- Validation errors for events accepted under a non-strict schema
+ALTER TABLE events ADD COLUMN schema_errors jsonb;
+
+CREATE INDEX idx_events_schema_errors ON events(tenant_id, event_type, occurred_at DESC)
+WHERE schema_errors IS NOT NULL;
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE event_schemas ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_event_schemas
+  ON event_schemas
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE event_schemas FORCE ROW LEVEL SECURITY;
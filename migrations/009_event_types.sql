@@ -0,0 +1,36 @@
+-- Tenant-defined event types
+-- Version: 1.0
+-- Date: 2025-11-21
+
+-- =============================================================================
+-- EVENT TYPES TABLE
+-- =============================================================================
+
+-- Custom event types a tenant can ingest in addition to the built-in ones
+-- (purchase, visit, referral, ...). expected_properties documents the
+-- properties producers should send, e.g.
+--   [{"name": "branch", "type": "string", "required": true, "description": "..."}]
+CREATE TABLE event_types (
+  id                   uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id            uuid NOT NULL REFERENCES tenants(id),
+  name                 text NOT NULL CHECK (name ~ '^[a-z][a-z0-9_]{0,63}$'),
+  description          text,
+  expected_properties  jsonb NOT NULL DEFAULT '[]',
+  active               boolean NOT NULL DEFAULT true,
+  created_at           timestamptz NOT NULL DEFAULT now(),
+  updated_at           timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (tenant_id, name)
+);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE event_types ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_event_types
+  ON event_types
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE event_types FORCE ROW LEVEL SECURITY;
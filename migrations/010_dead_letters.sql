@@ -0,0 +1,42 @@
+-- Dead letter queue for failed rule processing
+-- Version: 1.0
+-- Date: 2025-11-22
+
+-- =============================================================================
+-- DEAD LETTERS TABLE
+-- =============================================================================
+
+-- Events whose rule processing failed. One row per event; repeated failures
+-- bump the attempt counter and keep the latest error.
+CREATE TABLE dead_letters (
+  id               uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id        uuid NOT NULL REFERENCES tenants(id),
+  event_id         uuid NOT NULL REFERENCES events(id),
+  error            text NOT NULL,
+  attempts         int NOT NULL DEFAULT 1,
+  status           text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending','retrying','resolved')),
+  last_attempt_at  timestamptz NOT NULL DEFAULT now(),
+  resolved_at      timestamptz,
+  created_at       timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (event_id)
+);
+
+CREATE INDEX idx_dead_letters_tenant_status ON dead_letters(tenant_id, status, created_at DESC);
+
+-- Link issuances back to the event that triggered them so reprocessing an
+-- event never issues the same reward twice
+CREATE INDEX idx_issuances_event_reward ON issuances(event_id, reward_id)
+WHERE event_id IS NOT NULL;
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE dead_letters ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_dead_letters
+  ON dead_letters
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE dead_letters FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,17 @@
+-- Offline redemption import from POS terminals
+-- Version: 1.0
+-- Date: 2025-11-24
+
+-- =============================================================================
+-- ISSUANCE REDEMPTION DETAILS
+-- =============================================================================
+
+-- Where and by whom an issuance was redeemed, and how the redemption arrived
+ALTER TABLE issuances
+  ADD COLUMN redeemed_store_id  text,
+  ADD COLUMN redeemed_staff_ref text,
+  ADD COLUMN redemption_source  text CHECK (redemption_source IN ('online','pos_import'));
+
+-- Imports look issuances up by code (case-insensitive)
+CREATE INDEX idx_issuances_tenant_code ON issuances(tenant_id, upper(code))
+WHERE code IS NOT NULL;
@@ -0,0 +1,43 @@
+-- Stores and locations
+-- Version: 1.0
+-- Date: 2025-11-25
+
+-- =============================================================================
+-- LOCATIONS TABLE
+-- =============================================================================
+
+-- Physical stores/branches of a tenant. region groups locations so rules can
+-- target e.g. "any store in Harare" without enumerating store codes.
+CREATE TABLE locations (
+  id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id   uuid NOT NULL REFERENCES tenants(id),
+  name        text NOT NULL,
+  code        text NOT NULL,
+  region      text,
+  latitude    numeric(9,6) CHECK (latitude BETWEEN -90 AND 90),
+  longitude   numeric(9,6) CHECK (longitude BETWEEN -180 AND 180),
+  active      boolean NOT NULL DEFAULT true,
+  created_at  timestamptz NOT NULL DEFAULT now(),
+  updated_at  timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (tenant_id, code),
+  CHECK ((latitude IS NULL) = (longitude IS NULL))
+);
+
+CREATE INDEX idx_locations_tenant_region ON locations(tenant_id, lower(region));
+
+-- events.location_id predates this table; enforce it now that locations exist
+ALTER TABLE events
+  ADD CONSTRAINT events_location_id_fkey FOREIGN KEY (location_id) REFERENCES locations(id);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE locations ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_locations
+  ON locations
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE locations FORCE ROW LEVEL SECURITY;
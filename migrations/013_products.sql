@@ -0,0 +1,37 @@
+-- Product catalog references
+-- Version: 1.0
+-- Date: 2025-11-26
+
+-- =============================================================================
+-- PRODUCTS TABLE
+-- =============================================================================
+
+-- Lightweight product references so rules can target a whole category
+-- (e.g. "any beverage SKU") instead of enumerating SKUs in JsonLogic.
+-- This is not a full product catalog; it only maps SKUs to categories.
+CREATE TABLE products (
+  id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id   uuid NOT NULL REFERENCES tenants(id),
+  sku         text NOT NULL,
+  name        text,
+  category    text NOT NULL,
+  active      boolean NOT NULL DEFAULT true,
+  created_at  timestamptz NOT NULL DEFAULT now(),
+  updated_at  timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (tenant_id, sku)
+);
+
+CREATE INDEX idx_products_tenant_category ON products(tenant_id, lower(category));
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE products ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_products
+  ON products
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE products FORCE ROW LEVEL SECURITY;
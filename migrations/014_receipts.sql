@@ -0,0 +1,67 @@
+-- Receipt ingestion
+-- Version: 1.0
+-- Date: 2025-11-27
+
+-- =============================================================================
+-- RECEIPTS TABLE
+-- =============================================================================
+
+-- Receipt images submitted by customers of tenants without a POS integration.
+-- OCR output is stored for staff review; approving a receipt creates a
+-- purchase event that is fed to the rules engine.
+CREATE TABLE receipts (
+  id                uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id         uuid NOT NULL REFERENCES tenants(id),
+  customer_id       uuid NOT NULL REFERENCES customers(id),
+  source            text NOT NULL CHECK (source IN ('upload','whatsapp')),
+  status            text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending','approved','rejected')),
+  mime_type         text NOT NULL,
+  image_sha256      text NOT NULL,
+  ocr_provider      text,
+  ocr_text          text,
+  ocr_error         text,
+  amount            numeric(18,2),
+  currency          text CHECK (currency IN ('ZWG','USD')),
+  receipt_date      date,
+  store_name        text,
+  location_id       uuid REFERENCES locations(id),
+  event_id          uuid REFERENCES events(id),
+  reviewed_by       uuid REFERENCES staff_users(id),
+  reviewed_at       timestamptz,
+  rejection_reason  text,
+  created_at        timestamptz NOT NULL DEFAULT now(),
+  -- The same image cannot be submitted twice
+  UNIQUE (tenant_id, image_sha256)
+);
+
+CREATE INDEX idx_receipts_tenant_status ON receipts(tenant_id, status, created_at);
+CREATE INDEX idx_receipts_customer ON receipts(tenant_id, customer_id);
+
+-- Images are kept out of the receipts table so listing receipts stays cheap
+CREATE TABLE receipt_images (
+  receipt_id  uuid PRIMARY KEY REFERENCES receipts(id) ON DELETE CASCADE,
+  tenant_id   uuid NOT NULL REFERENCES tenants(id),
+  image       bytea NOT NULL
+);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE receipts ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_receipts
+  ON receipts
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE receipts FORCE ROW LEVEL SECURITY;
+
+ALTER TABLE receipt_images ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_receipt_images
+  ON receipt_images
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE receipt_images FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,70 @@
+-- Surveys and NPS
+-- Version: 1.0
+-- Date: 2025-11-28
+
+-- =============================================================================
+-- SURVEYS TABLE
+-- =============================================================================
+
+-- Short question flows sent to customers over WhatsApp. questions is an
+-- ordered array of {id, type, text, options} where type is one of
+-- nps, rating, choice or text.
+CREATE TABLE surveys (
+  id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id   uuid NOT NULL REFERENCES tenants(id),
+  name        text NOT NULL,
+  trigger     text NOT NULL CHECK (trigger IN ('post_redemption','manual')),
+  questions   jsonb NOT NULL,
+  active      boolean NOT NULL DEFAULT true,
+  created_at  timestamptz NOT NULL DEFAULT now(),
+  updated_at  timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_surveys_tenant_trigger ON surveys(tenant_id, trigger) WHERE active;
+
+-- =============================================================================
+-- SURVEY RESPONSES TABLE
+-- =============================================================================
+
+-- One row per survey sent to a customer. answers maps question id to the
+-- customer's answer; current_question is the index of the next question.
+CREATE TABLE survey_responses (
+  id                uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id         uuid NOT NULL REFERENCES tenants(id),
+  survey_id         uuid NOT NULL REFERENCES surveys(id),
+  customer_id       uuid NOT NULL REFERENCES customers(id),
+  issuance_id       uuid REFERENCES issuances(id),
+  status            text NOT NULL DEFAULT 'in_progress' CHECK (status IN ('in_progress','completed','abandoned')),
+  answers           jsonb NOT NULL DEFAULT '{}'::jsonb,
+  current_question  int NOT NULL DEFAULT 0,
+  event_id          uuid REFERENCES events(id),
+  started_at        timestamptz NOT NULL DEFAULT now(),
+  completed_at      timestamptz,
+  -- A redemption triggers a survey at most once
+  UNIQUE (survey_id, issuance_id)
+);
+
+CREATE INDEX idx_survey_responses_survey_status ON survey_responses(survey_id, status);
+CREATE INDEX idx_survey_responses_customer ON survey_responses(tenant_id, customer_id, status);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE surveys ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_surveys
+  ON surveys
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE surveys FORCE ROW LEVEL SECURITY;
+
+ALTER TABLE survey_responses ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_survey_responses
+  ON survey_responses
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE survey_responses FORCE ROW LEVEL SECURITY;
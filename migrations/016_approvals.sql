@@ -0,0 +1,61 @@
+-- Maker-checker approvals for campaigns and rules
+-- Version: 1.0
+-- Date: 2025-11-29
+
+-- =============================================================================
+-- TENANT SETTING & MANAGER ROLE
+-- =============================================================================
+
+-- Regulated tenants require a second person to approve campaign and rule
+-- changes before they take effect. Enabled with `loyaltyctl set-approval`.
+ALTER TABLE tenants
+  ADD COLUMN require_approval boolean NOT NULL DEFAULT false;
+
+-- Managers approve changes made by other staff
+ALTER TABLE staff_users DROP CONSTRAINT staff_users_role_check;
+ALTER TABLE staff_users
+  ADD CONSTRAINT staff_users_role_check
+  CHECK (role IN ('owner','admin','manager','staff','viewer'));
+
+-- =============================================================================
+-- APPROVALS TABLE
+-- =============================================================================
+
+-- A change awaiting (or having received) a decision. 'activate' moves a draft
+-- campaign or inactive rule live; 'update' applies the proposed campaign
+-- fields in payload to a campaign that is already live.
+CREATE TABLE approvals (
+  id                uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id         uuid NOT NULL REFERENCES tenants(id),
+  entity_type       text NOT NULL CHECK (entity_type IN ('campaign','rule')),
+  entity_id         uuid NOT NULL,
+  action            text NOT NULL CHECK (action IN ('activate','update')),
+  payload           jsonb NOT NULL DEFAULT '{}',
+  status            text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending','approved','rejected')),
+  requested_by      uuid NOT NULL REFERENCES staff_users(id),
+  request_comment   text NOT NULL,
+  decided_by        uuid REFERENCES staff_users(id),
+  decision_comment  text,
+  created_at        timestamptz NOT NULL DEFAULT now(),
+  decided_at        timestamptz
+);
+
+CREATE INDEX idx_approvals_tenant_status ON approvals(tenant_id, status, created_at DESC);
+
+-- At most one open change per campaign or rule
+CREATE UNIQUE INDEX idx_approvals_pending_entity
+  ON approvals(tenant_id, entity_type, entity_id)
+  WHERE status = 'pending';
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE approvals ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_approvals
+  ON approvals
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE approvals FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,98 @@
+-- Budget period close statements
+-- Version: 1.0
+-- Date: 2025-11-30
+
+-- =============================================================================
+-- BUDGET STATEMENTS TABLE
+-- =============================================================================
+
+-- An immutable snapshot of a budget over a closed period. Opening balance is
+-- the previous statement's closing balance; totals holds the count and sum of
+-- ledger entries per entry type within [period_start, period_end).
+--
+-- ledger_discrepancy compares the closing balance with the balance recomputed
+-- from the whole ledger up to period_end; balance_discrepancy compares the
+-- budget's stored balance with the ledger at close time.
+CREATE TABLE budget_statements (
+  id                   uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id            uuid NOT NULL REFERENCES tenants(id),
+  budget_id            uuid NOT NULL REFERENCES budgets(id),
+  currency             text NOT NULL CHECK (currency IN ('ZWG','USD')),
+  period_start         timestamptz NOT NULL,
+  period_end           timestamptz NOT NULL,
+  opening_balance      numeric(18,2) NOT NULL,
+  closing_balance      numeric(18,2) NOT NULL,
+  totals               jsonb NOT NULL DEFAULT '{}',
+  entry_count          bigint NOT NULL DEFAULT 0,
+  ledger_discrepancy   numeric(18,2) NOT NULL DEFAULT 0,
+  balance_discrepancy  numeric(18,2) NOT NULL DEFAULT 0,
+  closed_by            uuid REFERENCES staff_users(id),
+  created_at           timestamptz NOT NULL DEFAULT now(),
+  CHECK (period_end > period_start),
+  UNIQUE (budget_id, period_start)
+);
+
+CREATE INDEX idx_budget_statements_budget ON budget_statements(tenant_id, budget_id, period_end DESC);
+
+-- =============================================================================
+-- IMMUTABILITY
+-- =============================================================================
+
+-- Statements are never changed once written
+CREATE OR REPLACE FUNCTION prevent_budget_statement_mutation()
+RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'budget statements are immutable'
+    USING ERRCODE = 'check_violation';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER budget_statements_immutable
+  BEFORE UPDATE OR DELETE ON budget_statements
+  FOR EACH ROW EXECUTE FUNCTION prevent_budget_statement_mutation();
+
+-- Ledger entries in a closed period cannot be changed, removed or back-dated
+-- into it. Corrections are posted as new entries in the open period.
+CREATE OR REPLACE FUNCTION prevent_closed_ledger_mutation()
+RETURNS trigger AS $$
+DECLARE
+  v_row ledger_entries%ROWTYPE;
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    v_row := NEW;
+  ELSE
+    v_row := OLD;
+  END IF;
+
+  IF EXISTS (
+    SELECT 1 FROM budget_statements
+    WHERE budget_id = v_row.budget_id
+      AND period_end > v_row.created_at
+  ) THEN
+    RAISE EXCEPTION 'ledger entry % falls in a closed budget period', v_row.id
+      USING ERRCODE = 'check_violation';
+  END IF;
+
+  IF TG_OP = 'DELETE' THEN
+    RETURN OLD;
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER ledger_entries_closed_period
+  BEFORE INSERT OR UPDATE OR DELETE ON ledger_entries
+  FOR EACH ROW EXECUTE FUNCTION prevent_closed_ledger_mutation();
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE budget_statements ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_budget_statements
+  ON budget_statements
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE budget_statements FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,116 @@
+-- Reward liability ledger
+-- Version: 1.0
+-- Date: 2025-12-01
+
+-- =============================================================================
+-- LIABILITY ENTRIES TABLE
+-- =============================================================================
+
+-- Double-entry ledger for rewards owed to customers. Every movement writes a
+-- debit and a credit row sharing txn_id, so each transaction balances:
+--
+--   issue   Dr promotion_expense      Cr reward_liability
+--   redeem  Dr reward_liability       Cr redemption_settlement
+--   expire  Dr reward_liability       Cr breakage_income
+--   cancel  Dr reward_liability       Cr promotion_expense
+--
+-- The credit balance of reward_liability is the face value of outstanding
+-- (issued, unredeemed) rewards.
+CREATE TABLE liability_entries (
+  id           bigserial PRIMARY KEY,
+  txn_id       uuid NOT NULL,
+  tenant_id    uuid NOT NULL REFERENCES tenants(id),
+  issuance_id  uuid NOT NULL REFERENCES issuances(id),
+  reward_id    uuid NOT NULL REFERENCES reward_catalog(id),
+  entry_type   text NOT NULL CHECK (entry_type IN ('issue','redeem','expire','cancel')),
+  account      text NOT NULL CHECK (account IN
+                 ('reward_liability','promotion_expense','redemption_settlement','breakage_income')),
+  currency     text NOT NULL CHECK (currency IN ('ZWG','USD')),
+  debit        numeric(18,2) NOT NULL DEFAULT 0 CHECK (debit >= 0),
+  credit       numeric(18,2) NOT NULL DEFAULT 0 CHECK (credit >= 0),
+  created_at   timestamptz NOT NULL DEFAULT now(),
+  CHECK ((debit = 0) <> (credit = 0))
+);
+
+CREATE INDEX idx_liability_entries_tenant_account ON liability_entries(tenant_id, account, created_at);
+CREATE INDEX idx_liability_entries_issuance ON liability_entries(issuance_id);
+
+-- =============================================================================
+-- POSTING FROM ISSUANCE STATUS CHANGES
+-- =============================================================================
+
+-- Posts a balanced pair of entries for an issuance
+CREATE OR REPLACE FUNCTION post_liability_entry(
+  p_issuance issuances,
+  p_entry_type text,
+  p_debit_account text,
+  p_credit_account text
+) RETURNS void AS $$
+DECLARE
+  v_amount numeric;
+  v_currency text;
+  v_txn_id uuid := gen_random_uuid();
+BEGIN
+  SELECT COALESCE(p_issuance.face_amount, rc.face_value),
+         COALESCE(p_issuance.currency, rc.currency)
+  INTO v_amount, v_currency
+  FROM reward_catalog rc
+  WHERE rc.id = p_issuance.reward_id;
+
+  -- Rewards without a face value carry no monetary liability
+  IF v_amount IS NULL OR v_amount <= 0 OR v_currency IS NULL THEN
+    RETURN;
+  END IF;
+
+  INSERT INTO liability_entries
+    (txn_id, tenant_id, issuance_id, reward_id, entry_type, account, currency, debit, credit)
+  VALUES
+    (v_txn_id, p_issuance.tenant_id, p_issuance.id, p_issuance.reward_id, p_entry_type, p_debit_account, v_currency, v_amount, 0),
+    (v_txn_id, p_issuance.tenant_id, p_issuance.id, p_issuance.reward_id, p_entry_type, p_credit_account, v_currency, 0, v_amount);
+END;
+$$ LANGUAGE plpgsql;
+
+-- Issuances change status from several code paths (reward service, expiry
+-- worker, channels, imports), so postings hang off the table itself
+CREATE OR REPLACE FUNCTION post_issuance_liability()
+RETURNS trigger AS $$
+BEGIN
+  IF NEW.status = 'issued' AND (TG_OP = 'INSERT' OR OLD.status <> 'issued') THEN
+    PERFORM post_liability_entry(NEW, 'issue', 'promotion_expense', 'reward_liability');
+  ELSIF TG_OP = 'UPDATE' AND OLD.status = 'issued' THEN
+    CASE NEW.status
+      WHEN 'redeemed' THEN
+        PERFORM post_liability_entry(NEW, 'redeem', 'reward_liability', 'redemption_settlement');
+      WHEN 'expired' THEN
+        PERFORM post_liability_entry(NEW, 'expire', 'reward_liability', 'breakage_income');
+      WHEN 'cancelled' THEN
+        PERFORM post_liability_entry(NEW, 'cancel', 'reward_liability', 'promotion_expense');
+      ELSE
+        NULL;
+    END CASE;
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER issuances_liability
+  AFTER INSERT OR UPDATE OF status ON issuances
+  FOR EACH ROW EXECUTE FUNCTION post_issuance_liability();
+
+-- Open the liability for rewards already outstanding
+SELECT post_liability_entry(i, 'issue', 'promotion_expense', 'reward_liability')
+FROM issuances i
+WHERE i.status = 'issued';
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE liability_entries ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_liability_entries
+  ON liability_entries
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE liability_entries FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,25 @@
+-- Time-series analytics indexes
+-- Version: 1.0
+-- Date: 2025-12-02
+
+-- =============================================================================
+-- TIME-SERIES INDEXES
+-- =============================================================================
+
+-- Tenant-wide issuance series bucketed by issue time
+CREATE INDEX idx_issuances_tenant_issued ON issuances(tenant_id, issued_at)
+WHERE issued_at IS NOT NULL;
+
+-- Tenant-wide redemption series bucketed by redemption time
+CREATE INDEX idx_issuances_tenant_redeemed ON issuances(tenant_id, redeemed_at)
+WHERE redeemed_at IS NOT NULL;
+
+-- Campaign series for issuances and redemptions
+CREATE INDEX idx_issuances_tenant_campaign_issued ON issuances(tenant_id, campaign_id, issued_at)
+WHERE campaign_id IS NOT NULL AND issued_at IS NOT NULL;
+
+CREATE INDEX idx_issuances_tenant_campaign_redeemed ON issuances(tenant_id, campaign_id, redeemed_at)
+WHERE campaign_id IS NOT NULL AND redeemed_at IS NOT NULL;
+
+-- Tenant-wide event series across all event types
+CREATE INDEX idx_events_tenant_occurred ON events(tenant_id, occurred_at);
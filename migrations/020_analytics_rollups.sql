@@ -0,0 +1,92 @@
+-- Daily analytics rollups
+-- Version: 1.0
+-- Date: 2025-12-03
+
+-- =============================================================================
+-- ROLLUP TABLES
+-- =============================================================================
+
+-- Rollups are bucketed by UTC day and rebuilt a whole day at a time by the
+-- rollup worker, so they carry no unique constraints beyond the day grain.
+
+-- Events per day and type
+CREATE TABLE events_daily (
+  tenant_id         uuid NOT NULL REFERENCES tenants(id),
+  day               date NOT NULL,
+  event_type        text NOT NULL,
+  event_count       bigint NOT NULL DEFAULT 0,
+  unique_customers  bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY (tenant_id, day, event_type)
+);
+
+-- Issuances per day, campaign and currency. Issued figures are bucketed by
+-- issued_at and redeemed figures by redeemed_at.
+CREATE TABLE issuances_daily (
+  tenant_id       uuid NOT NULL REFERENCES tenants(id),
+  day             date NOT NULL,
+  campaign_id     uuid REFERENCES campaigns(id),
+  currency        text NOT NULL DEFAULT '',
+  issued_count    bigint NOT NULL DEFAULT 0,
+  issued_cost     numeric(18,2) NOT NULL DEFAULT 0,
+  redeemed_count  bigint NOT NULL DEFAULT 0,
+  redeemed_face   numeric(18,2) NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_issuances_daily_tenant_day ON issuances_daily(tenant_id, day);
+CREATE INDEX idx_issuances_daily_campaign_day ON issuances_daily(tenant_id, campaign_id, day)
+WHERE campaign_id IS NOT NULL;
+
+-- Budget ledger movements per day
+CREATE TABLE budget_spend_daily (
+  tenant_id    uuid NOT NULL REFERENCES tenants(id),
+  budget_id    uuid NOT NULL REFERENCES budgets(id),
+  day          date NOT NULL,
+  currency     text NOT NULL CHECK (currency IN ('ZWG','USD')),
+  funded       numeric(18,2) NOT NULL DEFAULT 0,
+  reserved     numeric(18,2) NOT NULL DEFAULT 0,
+  charged      numeric(18,2) NOT NULL DEFAULT 0,
+  released     numeric(18,2) NOT NULL DEFAULT 0,
+  entry_count  bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY (tenant_id, budget_id, day)
+);
+
+-- Rollup progress per tenant. refreshed_at is the freshness reported to
+-- clients: activity after it is not yet in the rollups.
+CREATE TABLE analytics_rollup_state (
+  tenant_id     uuid PRIMARY KEY REFERENCES tenants(id),
+  refreshed_at  timestamptz NOT NULL
+);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE events_daily ENABLE ROW LEVEL SECURITY;
+ALTER TABLE issuances_daily ENABLE ROW LEVEL SECURITY;
+ALTER TABLE budget_spend_daily ENABLE ROW LEVEL SECURITY;
+ALTER TABLE analytics_rollup_state ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_events_daily
+  ON events_daily
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+CREATE POLICY tenant_isolation_issuances_daily
+  ON issuances_daily
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+CREATE POLICY tenant_isolation_budget_spend_daily
+  ON budget_spend_daily
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+CREATE POLICY tenant_isolation_analytics_rollup_state
+  ON analytics_rollup_state
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE events_daily FORCE ROW LEVEL SECURITY;
+ALTER TABLE issuances_daily FORCE ROW LEVEL SECURITY;
+ALTER TABLE budget_spend_daily FORCE ROW LEVEL SECURITY;
+ALTER TABLE analytics_rollup_state FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,48 @@
+-- Data exports to object storage
+-- Version: 1.0
+-- Date: 2025-12-04
+
+-- =============================================================================
+-- TENANT SETTINGS
+-- =============================================================================
+
+-- Tenants with an export bucket get nightly CSV snapshots written to it.
+-- Configured with `loyaltyctl set-export`.
+ALTER TABLE tenants
+  ADD COLUMN export_bucket text,
+  ADD COLUMN export_prefix text NOT NULL DEFAULT '';
+
+-- =============================================================================
+-- DATA EXPORTS TABLE
+-- =============================================================================
+
+-- One completed export file. customers is a full snapshot taken at export
+-- time; events, issuances and ledger hold the rows of snapshot_date (UTC).
+CREATE TABLE data_exports (
+  id             uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id      uuid NOT NULL REFERENCES tenants(id),
+  dataset        text NOT NULL CHECK (dataset IN ('customers','events','issuances','ledger')),
+  snapshot_date  date NOT NULL,
+  format         text NOT NULL DEFAULT 'csv' CHECK (format IN ('csv')),
+  bucket         text NOT NULL,
+  object_key     text NOT NULL,
+  row_count      bigint NOT NULL,
+  size_bytes     bigint NOT NULL,
+  created_at     timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (tenant_id, dataset, snapshot_date)
+);
+
+CREATE INDEX idx_data_exports_tenant ON data_exports(tenant_id, snapshot_date DESC);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE data_exports ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_data_exports
+  ON data_exports
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE data_exports FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,130 @@
+-- Domain event outbox for message bus publication
+-- Version: 1.0
+-- Date: 2025-12-05
+
+-- =============================================================================
+-- TENANT SETTINGS
+-- =============================================================================
+
+-- Tenants with the event bus enabled have their domain events written to the
+-- outbox and published. Configured with `loyaltyctl set-event-bus`.
+ALTER TABLE tenants
+  ADD COLUMN event_bus_enabled boolean NOT NULL DEFAULT false;
+
+-- =============================================================================
+-- OUTBOX TABLE
+-- =============================================================================
+
+-- Domain events awaiting publication. Rows are written in the same
+-- transaction as the change they describe, so an event is published if and
+-- only if the change commits. The relay publishes in id order and retries
+-- with backoff; consumers de-duplicate on message_id.
+CREATE TABLE outbox_events (
+  id               bigserial PRIMARY KEY,
+  message_id       uuid NOT NULL DEFAULT gen_random_uuid(),
+  tenant_id        uuid NOT NULL REFERENCES tenants(id),
+  event_type       text NOT NULL CHECK (event_type IN
+                     ('event.ingested','issuance.created','issuance.redeemed','budget.alert')),
+  aggregate_id     uuid NOT NULL,
+  payload          jsonb NOT NULL,
+  created_at       timestamptz NOT NULL DEFAULT now(),
+  attempts         int NOT NULL DEFAULT 0,
+  next_attempt_at  timestamptz NOT NULL DEFAULT now(),
+  last_error       text,
+  published_at     timestamptz
+);
+
+CREATE INDEX idx_outbox_events_pending ON outbox_events(tenant_id, id)
+WHERE published_at IS NULL;
+CREATE INDEX idx_outbox_events_published ON outbox_events(published_at)
+WHERE published_at IS NOT NULL;
+
+-- =============================================================================
+-- WRITING DOMAIN EVENTS
+-- =============================================================================
+
+-- Adds an event to the outbox if the tenant has the event bus enabled
+CREATE OR REPLACE FUNCTION enqueue_outbox_event(
+  p_tenant_id uuid,
+  p_event_type text,
+  p_aggregate_id uuid,
+  p_payload jsonb
+) RETURNS void AS $$
+BEGIN
+  INSERT INTO outbox_events (tenant_id, event_type, aggregate_id, payload)
+  SELECT p_tenant_id, p_event_type, p_aggregate_id, p_payload
+  FROM tenants t
+  WHERE t.id = p_tenant_id AND t.event_bus_enabled;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Events and issuances are written from several code paths (API, channels,
+-- receipts, surveys, imports), so like the liability postings the outbox
+-- hangs off the tables themselves
+CREATE OR REPLACE FUNCTION outbox_event_ingested()
+RETURNS trigger AS $$
+BEGIN
+  PERFORM enqueue_outbox_event(NEW.tenant_id, 'event.ingested', NEW.id, jsonb_build_object(
+    'event_id', NEW.id,
+    'customer_id', NEW.customer_id,
+    'event_type', NEW.event_type,
+    'properties', NEW.properties,
+    'occurred_at', NEW.occurred_at,
+    'source', NEW.source,
+    'location_id', NEW.location_id
+  ));
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER events_outbox
+  AFTER INSERT ON events
+  FOR EACH ROW EXECUTE FUNCTION outbox_event_ingested();
+
+CREATE OR REPLACE FUNCTION outbox_issuance_changed()
+RETURNS trigger AS $$
+DECLARE
+  v_payload jsonb := jsonb_build_object(
+    'issuance_id', NEW.id,
+    'customer_id', NEW.customer_id,
+    'campaign_id', NEW.campaign_id,
+    'reward_id', NEW.reward_id,
+    'event_id', NEW.event_id,
+    'status', NEW.status,
+    'currency', NEW.currency,
+    'face_amount', NEW.face_amount,
+    'cost_amount', NEW.cost_amount,
+    'issued_at', NEW.issued_at,
+    'expires_at', NEW.expires_at,
+    'redeemed_at', NEW.redeemed_at
+  );
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    PERFORM enqueue_outbox_event(NEW.tenant_id, 'issuance.created', NEW.id, v_payload);
+  ELSIF NEW.status = 'redeemed' AND OLD.status <> 'redeemed' THEN
+    PERFORM enqueue_outbox_event(NEW.tenant_id, 'issuance.redeemed', NEW.id, v_payload || jsonb_build_object(
+      'redemption_source', NEW.redemption_source,
+      'redeemed_store_id', NEW.redeemed_store_id,
+      'redeemed_staff_ref', NEW.redeemed_staff_ref
+    ));
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER issuances_outbox
+  AFTER INSERT OR UPDATE OF status ON issuances
+  FOR EACH ROW EXECUTE FUNCTION outbox_issuance_changed();
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE outbox_events ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_outbox_events
+  ON outbox_events
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE outbox_events FORCE ROW LEVEL SECURITY;
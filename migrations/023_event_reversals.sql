@@ -0,0 +1,55 @@
+-- Event reversals for refunded purchases
+-- Version: 1.0
+-- Date: 2025-12-06
+
+-- =============================================================================
+-- EVENT REVERSALS
+-- =============================================================================
+
+-- A reversal event ("reversal" type) undoes the rewards of an earlier event.
+-- Each original event can be reversed once.
+CREATE TABLE event_reversals (
+  id                 uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id          uuid NOT NULL REFERENCES tenants(id),
+  reversal_event_id  uuid NOT NULL REFERENCES events(id),
+  original_event_id  uuid NOT NULL REFERENCES events(id),
+  reason             text,
+  created_at         timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (tenant_id, original_event_id),
+  UNIQUE (reversal_event_id)
+);
+
+-- What the reversal did to each issuance of the original event:
+--   cancelled         unredeemed; cancelled and its budget released
+--   already_redeemed  redeemed before the reversal; left as is
+--   skipped           already expired, cancelled or failed
+CREATE TABLE event_reversal_issuances (
+  reversal_id      uuid NOT NULL REFERENCES event_reversals(id),
+  tenant_id        uuid NOT NULL REFERENCES tenants(id),
+  issuance_id      uuid NOT NULL REFERENCES issuances(id),
+  previous_status  text NOT NULL,
+  action           text NOT NULL CHECK (action IN ('cancelled','already_redeemed','skipped')),
+  released_amount  numeric(18,2),
+  currency         text,
+  PRIMARY KEY (reversal_id, issuance_id)
+);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE event_reversals ENABLE ROW LEVEL SECURITY;
+ALTER TABLE event_reversal_issuances ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_event_reversals
+  ON event_reversals
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+CREATE POLICY tenant_isolation_event_reversal_issuances
+  ON event_reversal_issuances
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE event_reversals FORCE ROW LEVEL SECURITY;
+ALTER TABLE event_reversal_issuances FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,76 @@
+-- Claw-back of redeemed rewards
+-- Version: 1.0
+-- Date: 2025-12-07
+
+-- =============================================================================
+-- CUSTOMER FLAGS
+-- =============================================================================
+
+-- A flagged customer had a reward clawed back and may warrant review
+ALTER TABLE customers
+  ADD COLUMN flagged_at  timestamptz,
+  ADD COLUMN flag_reason text;
+
+CREATE INDEX idx_customers_flagged ON customers(tenant_id, flagged_at DESC) WHERE flagged_at IS NOT NULL;
+
+-- =============================================================================
+-- ISSUANCE CLAWBACKS
+-- =============================================================================
+
+-- A redeemed issuance clawed back after a refund or fraud. The issuance
+-- stays redeemed; the clawback is recorded once per issuance.
+CREATE TABLE issuance_clawbacks (
+  id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id    uuid NOT NULL REFERENCES tenants(id),
+  issuance_id  uuid NOT NULL REFERENCES issuances(id),
+  customer_id  uuid NOT NULL REFERENCES customers(id),
+  reason_code  text NOT NULL CHECK (reason_code IN ('fraud','refund','chargeback','duplicate','other')),
+  note         text,
+  budget_id    uuid REFERENCES budgets(id),
+  amount       numeric(18,2),
+  currency     text,
+  created_by   uuid REFERENCES staff_users(id),
+  created_at   timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (issuance_id)
+);
+
+CREATE INDEX idx_issuance_clawbacks_customer ON issuance_clawbacks(tenant_id, customer_id, created_at DESC);
+
+-- =============================================================================
+-- BUDGET FUNCTIONS
+-- =============================================================================
+
+-- Function to credit back the charge of a clawed back issuance
+CREATE OR REPLACE FUNCTION reverse_budget(
+  p_tenant_id uuid,
+  p_budget_id uuid,
+  p_amount numeric,
+  p_currency text,
+  p_ref_id uuid
+) RETURNS boolean AS $$
+BEGIN
+  -- Decrease balance (return funds)
+  UPDATE budgets
+  SET balance = balance - p_amount
+  WHERE id = p_budget_id AND tenant_id = p_tenant_id;
+
+  -- Insert ledger entry
+  INSERT INTO ledger_entries (tenant_id, budget_id, entry_type, currency, amount, ref_type, ref_id)
+  VALUES (p_tenant_id, p_budget_id, 'reverse', p_currency, -p_amount, 'issuance', p_ref_id);
+
+  RETURN true;
+END;
+$$ LANGUAGE plpgsql;
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE issuance_clawbacks ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_issuance_clawbacks
+  ON issuance_clawbacks
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE issuance_clawbacks FORCE ROW LEVEL SECURITY;
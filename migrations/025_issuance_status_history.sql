@@ -0,0 +1,104 @@
+-- Issuance status history
+-- Version: 1.0
+-- Date: 2025-12-08
+
+-- =============================================================================
+-- ISSUANCE STATUS HISTORY
+-- =============================================================================
+
+-- Every status change of an issuance, including its creation (old_status
+-- NULL). actor is "staff:<id>", "customer:<id>", "event:<id>" or "system";
+-- channel is where the change was made (api, whatsapp, ussd, pos_import,
+-- system).
+CREATE TABLE issuance_status_history (
+  id           bigserial PRIMARY KEY,
+  tenant_id    uuid NOT NULL REFERENCES tenants(id),
+  issuance_id  uuid NOT NULL REFERENCES issuances(id),
+  old_status   text,
+  new_status   text NOT NULL,
+  actor        text NOT NULL,
+  channel      text NOT NULL,
+  created_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_issuance_status_history_issuance ON issuance_status_history(tenant_id, issuance_id, id);
+
+-- Backfill the creation of existing issuances
+INSERT INTO issuance_status_history (tenant_id, issuance_id, old_status, new_status, actor, channel, created_at)
+SELECT tenant_id, id, NULL, 'reserved', 'system', 'system', COALESCE(issued_at, now())
+FROM issuances;
+
+-- =============================================================================
+-- EXPIRY HELPER FUNCTIONS
+-- =============================================================================
+
+-- Function to expire old issuances (for cron/worker), now recording history
+CREATE OR REPLACE FUNCTION expire_old_issuances()
+RETURNS TABLE(
+  expired_count bigint,
+  budget_released numeric
+) AS $$
+DECLARE
+  v_expired_count bigint := 0;
+  v_budget_released numeric := 0;
+  v_rec record;
+BEGIN
+  -- Find and update expired issuances
+  FOR v_rec IN
+    SELECT id, tenant_id, campaign_id, cost_amount, currency, status
+    FROM issuances
+    WHERE status IN ('issued', 'reserved')
+      AND expires_at IS NOT NULL
+      AND expires_at < now()
+    FOR UPDATE SKIP LOCKED
+  LOOP
+    -- Update status to expired
+    UPDATE issuances
+    SET status = 'expired'
+    WHERE id = v_rec.id;
+
+    INSERT INTO issuance_status_history (tenant_id, issuance_id, old_status, new_status, actor, channel)
+    VALUES (v_rec.tenant_id, v_rec.id, v_rec.status, 'expired', 'system', 'system');
+
+    -- Release budget if there's a campaign with budget
+    IF v_rec.campaign_id IS NOT NULL AND v_rec.cost_amount IS NOT NULL THEN
+      DECLARE
+        v_budget_id uuid;
+      BEGIN
+        SELECT budget_id INTO v_budget_id
+        FROM campaigns
+        WHERE id = v_rec.campaign_id;
+
+        IF v_budget_id IS NOT NULL THEN
+          PERFORM release_budget(
+            v_rec.tenant_id,
+            v_budget_id,
+            v_rec.cost_amount,
+            v_rec.currency,
+            v_rec.id
+          );
+
+          v_budget_released := v_budget_released + v_rec.cost_amount;
+        END IF;
+      END;
+    END IF;
+
+    v_expired_count := v_expired_count + 1;
+  END LOOP;
+
+  RETURN QUERY SELECT v_expired_count, v_budget_released;
+END;
+$$ LANGUAGE plpgsql;
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE issuance_status_history ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_issuance_status_history
+  ON issuance_status_history
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE issuance_status_history FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,22 @@
+-- Budget spend attribution
+-- Version: 1.0
+-- Date: 2025-12-09
+
+-- =============================================================================
+-- ISSUANCE RULE
+-- =============================================================================
+
+-- The rule that issued the reward. Ledger entries reference their issuance,
+-- so spend can be attributed to its campaign, rule and reward. Issuances
+-- created before this migration have no rule.
+ALTER TABLE issuances ADD COLUMN rule_id uuid REFERENCES rules(id);
+
+CREATE INDEX idx_issuances_tenant_rule ON issuances(tenant_id, rule_id)
+WHERE rule_id IS NOT NULL;
+
+-- =============================================================================
+-- LEDGER ISSUANCE LOOKUP
+-- =============================================================================
+
+CREATE INDEX idx_ledger_entries_issuance ON ledger_entries(tenant_id, budget_id, ref_id)
+WHERE ref_type = 'issuance';
@@ -0,0 +1,158 @@
+-- Campaign fallback budgets
+-- Version: 1.0
+-- Date: 2025-12-10
+
+-- =============================================================================
+-- CAMPAIGN BUDGETS
+-- =============================================================================
+
+-- Ordered budgets a campaign falls back to when its primary budget
+-- (campaigns.budget_id) has reached its hard cap. Lower positions are tried
+-- first.
+CREATE TABLE campaign_budgets (
+  id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id    uuid NOT NULL REFERENCES tenants(id),
+  campaign_id  uuid NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+  budget_id    uuid NOT NULL REFERENCES budgets(id),
+  position     int NOT NULL,
+  created_at   timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (campaign_id, budget_id),
+  UNIQUE (campaign_id, position)
+);
+
+CREATE INDEX idx_campaign_budgets_tenant_campaign ON campaign_budgets(tenant_id, campaign_id, position);
+
+-- =============================================================================
+-- FUNDING BUDGET
+-- =============================================================================
+
+-- The budget an issuance's cost was reserved from. Charges, releases and
+-- reversals go back to this budget rather than the campaign's primary.
+ALTER TABLE issuances ADD COLUMN budget_id uuid REFERENCES budgets(id);
+
+UPDATE issuances i
+SET budget_id = c.budget_id
+FROM campaigns c
+WHERE c.id = i.campaign_id
+  AND c.budget_id IS NOT NULL
+  AND i.cost_amount IS NOT NULL;
+
+-- The primary budget a reservation fell back from, NULL when the primary
+-- budget was used
+ALTER TABLE ledger_entries ADD COLUMN fallback_from uuid REFERENCES budgets(id);
+
+-- =============================================================================
+-- BUDGET FUNCTIONS
+-- =============================================================================
+
+-- Reserve an issuance's cost from the campaign's primary budget, falling back
+-- to its campaign_budgets in order. Returns the budget reserved from, or NULL
+-- when every budget is at its hard cap.
+CREATE OR REPLACE FUNCTION reserve_campaign_budget(
+  p_tenant_id uuid,
+  p_campaign_id uuid,
+  p_amount numeric,
+  p_currency text,
+  p_ref_id uuid
+) RETURNS uuid AS $$
+DECLARE
+  v_primary uuid;
+  v_budget uuid;
+BEGIN
+  SELECT budget_id INTO v_primary
+  FROM campaigns
+  WHERE id = p_campaign_id AND tenant_id = p_tenant_id;
+
+  FOR v_budget IN
+    SELECT b.budget_id
+    FROM (
+      SELECT v_primary AS budget_id, -1 AS position
+      WHERE v_primary IS NOT NULL
+      UNION ALL
+      SELECT cb.budget_id, cb.position
+      FROM campaign_budgets cb
+      WHERE cb.tenant_id = p_tenant_id AND cb.campaign_id = p_campaign_id
+    ) b
+    ORDER BY b.position
+  LOOP
+    IF reserve_budget(p_tenant_id, v_budget, p_amount, p_currency, p_ref_id) THEN
+      IF v_budget IS DISTINCT FROM v_primary THEN
+        UPDATE ledger_entries
+        SET fallback_from = v_primary
+        WHERE id = (
+          SELECT max(id) FROM ledger_entries
+          WHERE tenant_id = p_tenant_id AND budget_id = v_budget
+            AND entry_type = 'reserve' AND ref_id = p_ref_id
+        );
+      END IF;
+      RETURN v_budget;
+    END IF;
+  END LOOP;
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Function to expire old issuances (for cron/worker), releasing each
+-- issuance's cost to the budget it was reserved from
+CREATE OR REPLACE FUNCTION expire_old_issuances()
+RETURNS TABLE(
+  expired_count bigint,
+  budget_released numeric
+) AS $$
+DECLARE
+  v_expired_count bigint := 0;
+  v_budget_released numeric := 0;
+  v_rec record;
+BEGIN
+  -- Find and update expired issuances
+  FOR v_rec IN
+    SELECT i.id, i.tenant_id, i.cost_amount, i.currency, i.status,
+           COALESCE(i.budget_id, c.budget_id) AS budget_id
+    FROM issuances i
+    LEFT JOIN campaigns c ON c.id = i.campaign_id
+    WHERE i.status IN ('issued', 'reserved')
+      AND i.expires_at IS NOT NULL
+      AND i.expires_at < now()
+    FOR UPDATE OF i SKIP LOCKED
+  LOOP
+    -- Update status to expired
+    UPDATE issuances
+    SET status = 'expired'
+    WHERE id = v_rec.id;
+
+    INSERT INTO issuance_status_history (tenant_id, issuance_id, old_status, new_status, actor, channel)
+    VALUES (v_rec.tenant_id, v_rec.id, v_rec.status, 'expired', 'system', 'system');
+
+    -- Release budget if the issuance was funded by one
+    IF v_rec.budget_id IS NOT NULL AND v_rec.cost_amount IS NOT NULL THEN
+      PERFORM release_budget(
+        v_rec.tenant_id,
+        v_rec.budget_id,
+        v_rec.cost_amount,
+        v_rec.currency,
+        v_rec.id
+      );
+
+      v_budget_released := v_budget_released + v_rec.cost_amount;
+    END IF;
+
+    v_expired_count := v_expired_count + 1;
+  END LOOP;
+
+  RETURN QUERY SELECT v_expired_count, v_budget_released;
+END;
+$$ LANGUAGE plpgsql;
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE campaign_budgets ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_campaign_budgets
+  ON campaign_budgets
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE campaign_budgets FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,21 @@
+-- Reservation ageing
+-- Version: 1.0
+-- Date: 2025-12-11
+
+-- =============================================================================
+-- TENANT SETTINGS
+-- =============================================================================
+
+-- How long an issuance may stay reserved before its budget reservation is
+-- released and the issuance cancelled. NULL keeps reservations until they are
+-- processed, which was the behaviour before this setting existed.
+ALTER TABLE tenants
+  ADD COLUMN max_reservation_age_hours int CHECK (max_reservation_age_hours > 0);
+
+-- =============================================================================
+-- INDEXES
+-- =============================================================================
+
+-- Stale reservations are found per tenant, oldest first
+CREATE INDEX idx_issuances_tenant_reserved ON issuances(tenant_id, issued_at)
+  WHERE status = 'reserved';
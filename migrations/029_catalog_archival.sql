@@ -0,0 +1,21 @@
+-- Catalog archival
+-- Version: 1.0
+-- Date: 2025-12-12
+
+-- =============================================================================
+-- ARCHIVED AT
+-- =============================================================================
+
+-- Archived rules, rewards and campaigns are kept for history and reporting
+-- but hidden from list endpoints unless include_archived is set. Archiving a
+-- rule or reward also deactivates it; restoring clears archived_at only.
+ALTER TABLE rules ADD COLUMN archived_at timestamptz;
+ALTER TABLE reward_catalog ADD COLUMN archived_at timestamptz;
+ALTER TABLE campaigns ADD COLUMN archived_at timestamptz;
+
+-- =============================================================================
+-- INDEXES
+-- =============================================================================
+
+-- Rewards are checked for live rules before they are archived
+CREATE INDEX idx_rules_tenant_reward ON rules(tenant_id, reward_id) WHERE active = true;
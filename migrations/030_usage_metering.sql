@@ -0,0 +1,34 @@
+-- Usage metering
+-- Version: 1.0
+-- Date: 2025-12-13
+
+-- =============================================================================
+-- USAGE RECORDS
+-- =============================================================================
+
+-- Billable operations per tenant, per calendar month (UTC). The API buffers
+-- counts in memory and adds them here periodically.
+CREATE TABLE usage_records (
+  tenant_id   uuid NOT NULL REFERENCES tenants(id),
+  period      date NOT NULL CHECK (extract(day FROM period) = 1),
+  metric      text NOT NULL,
+  quantity    bigint NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+  updated_at  timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (tenant_id, period, metric)
+);
+
+-- Invoicing exports read one period across all tenants
+CREATE INDEX idx_usage_records_period ON usage_records(period);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE usage_records ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_usage_records
+  ON usage_records
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE usage_records FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,56 @@
+-- WhatsApp outbound message queue
+-- Version: 1.0
+-- Date: 2025-12-14
+
+-- =============================================================================
+-- TENANT SETTINGS
+-- =============================================================================
+
+-- Most queued WhatsApp messages dispatched per tenant per minute, so one
+-- tenant's backlog cannot exhaust the platform's Meta messaging limits.
+-- Configured with `loyaltyctl set-whatsapp-rate`.
+ALTER TABLE tenants
+  ADD COLUMN whatsapp_rate_per_minute int NOT NULL DEFAULT 60
+    CHECK (whatsapp_rate_per_minute > 0);
+
+-- =============================================================================
+-- OUTBOUND MESSAGES
+-- =============================================================================
+
+-- WhatsApp messages that could not be sent immediately. The dispatcher
+-- retries pending messages with exponential backoff; messages that fail
+-- permanently or run out of attempts become dead and stay until retried by
+-- hand. Sent messages are purged after a week.
+CREATE TABLE outbound_messages (
+  id               bigserial PRIMARY KEY,
+  tenant_id        uuid NOT NULL REFERENCES tenants(id),
+  recipient        text NOT NULL,
+  payload          jsonb NOT NULL,
+  status           text NOT NULL DEFAULT 'pending'
+                     CHECK (status IN ('pending','sent','dead')),
+  attempts         int NOT NULL DEFAULT 0,
+  next_attempt_at  timestamptz NOT NULL DEFAULT now(),
+  last_error       text,
+  created_at       timestamptz NOT NULL DEFAULT now(),
+  sent_at          timestamptz,
+  dead_at          timestamptz
+);
+
+CREATE INDEX idx_outbound_messages_pending ON outbound_messages(tenant_id, next_attempt_at)
+WHERE status = 'pending';
+CREATE INDEX idx_outbound_messages_sent ON outbound_messages(tenant_id, sent_at)
+WHERE status = 'sent';
+CREATE INDEX idx_outbound_messages_status ON outbound_messages(tenant_id, status, created_at DESC);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE outbound_messages ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_outbound_messages
+  ON outbound_messages
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE outbound_messages FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,38 @@
+-- Customer portal sign-in codes
+-- Version: 1.0
+-- Date: 2025-12-15
+
+-- =============================================================================
+-- CUSTOMER OTPS
+-- =============================================================================
+
+-- One-time codes customers sign in to the hosted portal and web widgets with.
+-- Codes are stored hashed, expire after a few minutes and are locked after
+-- too many wrong guesses; a verified code is consumed and exchanged for a
+-- short-lived customer token.
+CREATE TABLE customer_otps (
+  id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id    uuid NOT NULL REFERENCES tenants(id),
+  customer_id  uuid NOT NULL REFERENCES customers(id),
+  channel      text NOT NULL,
+  code_hash    text NOT NULL,
+  attempts     int NOT NULL DEFAULT 0,
+  expires_at   timestamptz NOT NULL,
+  consumed_at  timestamptz,
+  created_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_customer_otps_customer ON customer_otps(tenant_id, customer_id, created_at DESC);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE customer_otps ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_customer_otps
+  ON customer_otps
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE customer_otps FORCE ROW LEVEL SECURITY;
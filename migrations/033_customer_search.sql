@@ -0,0 +1,23 @@
+-- Customer search
+-- Version: 1.0
+-- Date: 2025-12-16
+
+-- Trigram matching for call-center agents who only have part of a
+-- customer's phone number, reference or name
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- =============================================================================
+-- CUSTOMER NAMES
+-- =============================================================================
+
+ALTER TABLE customers
+  ADD COLUMN name text;
+
+-- =============================================================================
+-- SEARCH INDEXES
+-- =============================================================================
+
+-- Trigram indexes serve both substring (LIKE '%...%') and fuzzy matches
+CREATE INDEX idx_customers_phone_trgm ON customers USING gin (phone_e164 gin_trgm_ops);
+CREATE INDEX idx_customers_external_ref_trgm ON customers USING gin (external_ref gin_trgm_ops);
+CREATE INDEX idx_customers_name_trgm ON customers USING gin (name gin_trgm_ops);
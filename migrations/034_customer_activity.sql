@@ -0,0 +1,14 @@
+-- Customer activity feed
+-- Version: 1.0
+-- Date: 2025-12-17
+
+-- =============================================================================
+-- ACTIVITY INDEXES
+-- =============================================================================
+
+-- The activity feed reads a customer's events, consents and messages newest
+-- first
+CREATE INDEX idx_events_tenant_customer_occurred ON events(tenant_id, customer_id, occurred_at DESC)
+WHERE customer_id IS NOT NULL;
+CREATE INDEX idx_consents_tenant_customer ON consents(tenant_id, customer_id, occurred_at DESC);
+CREATE INDEX idx_outbound_messages_recipient ON outbound_messages(tenant_id, recipient, created_at DESC);
@@ -0,0 +1,14 @@
+-- Tenant phone region
+-- Version: 1.0
+-- Date: 2025-12-18
+
+-- =============================================================================
+-- TENANT SETTINGS
+-- =============================================================================
+
+-- The region (ISO 3166-1 alpha-2) phone numbers without a country code are
+-- read in, e.g. 077 123 4567 is +263771234567 in ZW. Configured with
+-- `loyaltyctl set-phone-region`.
+ALTER TABLE tenants
+  ADD COLUMN phone_region text NOT NULL DEFAULT 'ZW'
+    CHECK (phone_region ~ '^[A-Z]{2}$');
@@ -0,0 +1,208 @@
+-- Ledger immutability and hash chain
+-- Version: 1.0
+-- Date: 2025-12-19
+
+-- =============================================================================
+-- APPEND-ONLY LEDGER
+-- =============================================================================
+
+-- Ledger entries are never changed or removed; corrections are posted as new
+-- entries. Table owners and superusers keep their privileges whatever is
+-- revoked, so a trigger enforces this for them too.
+REVOKE UPDATE, DELETE, TRUNCATE ON ledger_entries FROM PUBLIC;
+
+CREATE OR REPLACE FUNCTION prevent_ledger_mutation()
+RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'ledger entries are append-only'
+    USING ERRCODE = 'check_violation';
+END;
+$$ LANGUAGE plpgsql;
+
+-- =============================================================================
+-- HASH CHAIN
+-- =============================================================================
+
+-- Each entry carries the hash of the budget's previous entry and its own hash
+-- over its fields and that previous hash, so changing or removing an entry
+-- breaks the chain from there on. The first entry of a budget has an empty
+-- prev_hash. budget.EntryHash computes the same hash for verification.
+ALTER TABLE ledger_entries
+  ADD COLUMN prev_hash  text,
+  ADD COLUMN entry_hash text;
+
+-- The hashed fields of an entry, in a fixed order. Times are in microseconds
+-- since the epoch so they read the same in any time zone.
+CREATE OR REPLACE FUNCTION ledger_entry_hash(e ledger_entries, p_prev_hash text)
+RETURNS text AS $$
+  SELECT encode(sha256(convert_to(concat_ws('|',
+    e.id::text,
+    e.tenant_id::text,
+    e.budget_id::text,
+    e.entry_type,
+    e.currency,
+    e.amount::text,
+    COALESCE(e.ref_type, ''),
+    COALESCE(e.ref_id::text, ''),
+    COALESCE(e.fallback_from::text, ''),
+    (extract(epoch FROM e.created_at) * 1000000)::bigint::text,
+    p_prev_hash
+  ), 'UTF8')), 'hex');
+$$ LANGUAGE sql IMMUTABLE;
+
+-- Backfill the chain of existing entries in id order. The closed period
+-- guard is suspended as it rejects any update of closed entries.
+ALTER TABLE ledger_entries DISABLE TRIGGER ledger_entries_closed_period;
+
+DO $$
+DECLARE
+  v_entry ledger_entries%ROWTYPE;
+  v_budget uuid;
+  v_prev text;
+BEGIN
+  FOR v_entry IN SELECT * FROM ledger_entries ORDER BY budget_id, id LOOP
+    IF v_budget IS DISTINCT FROM v_entry.budget_id THEN
+      v_budget := v_entry.budget_id;
+      v_prev := '';
+    END IF;
+    UPDATE ledger_entries
+    SET prev_hash = v_prev, entry_hash = ledger_entry_hash(v_entry, v_prev)
+    WHERE id = v_entry.id;
+    v_prev := ledger_entry_hash(v_entry, v_prev);
+  END LOOP;
+END;
+$$;
+
+ALTER TABLE ledger_entries ENABLE TRIGGER ledger_entries_closed_period;
+
+ALTER TABLE ledger_entries
+  ALTER COLUMN prev_hash SET NOT NULL,
+  ALTER COLUMN entry_hash SET NOT NULL;
+
+-- Chains entries as they are inserted. Inserts into a budget are serialized
+-- and take their id under the lock, so ids follow the chain.
+CREATE OR REPLACE FUNCTION chain_ledger_entry()
+RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_advisory_xact_lock(hashtextextended('ledger_chain:' || NEW.budget_id::text, 0));
+
+  NEW.id := nextval(pg_get_serial_sequence('ledger_entries', 'id'));
+  SELECT COALESCE(
+    (SELECT entry_hash FROM ledger_entries
+     WHERE budget_id = NEW.budget_id
+     ORDER BY id DESC
+     LIMIT 1),
+    '') INTO NEW.prev_hash;
+  NEW.entry_hash := ledger_entry_hash(NEW, NEW.prev_hash);
+
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER ledger_entries_hash_chain
+  BEFORE INSERT ON ledger_entries
+  FOR EACH ROW EXECUTE FUNCTION chain_ledger_entry();
+
+CREATE TRIGGER ledger_entries_append_only
+  BEFORE UPDATE OR DELETE ON ledger_entries
+  FOR EACH ROW EXECUTE FUNCTION prevent_ledger_mutation();
+
+CREATE INDEX idx_ledger_entries_chain ON ledger_entries(budget_id, id);
+
+-- =============================================================================
+-- BUDGET FUNCTIONS
+-- =============================================================================
+
+-- Reserve from a budget, recording the primary budget it fell back from.
+-- reserve_campaign_budget used to set fallback_from after the insert, which
+-- the append-only ledger no longer allows.
+CREATE OR REPLACE FUNCTION reserve_budget_from(
+  p_tenant_id uuid,
+  p_budget_id uuid,
+  p_amount numeric,
+  p_currency text,
+  p_ref_id uuid,
+  p_fallback_from uuid
+) RETURNS boolean AS $$
+DECLARE
+  v_balance numeric;
+  v_hard_cap numeric;
+BEGIN
+  -- Lock the budget row for update
+  SELECT balance, hard_cap
+  INTO v_balance, v_hard_cap
+  FROM budgets
+  WHERE id = p_budget_id AND tenant_id = p_tenant_id
+  FOR UPDATE;
+
+  -- Check if budget exists
+  IF NOT FOUND THEN
+    RAISE EXCEPTION 'Budget not found: %', p_budget_id;
+  END IF;
+
+  -- Check capacity
+  IF (v_balance + p_amount) > v_hard_cap THEN
+    RETURN false;
+  END IF;
+
+  -- Update balance
+  UPDATE budgets
+  SET balance = balance + p_amount
+  WHERE id = p_budget_id AND tenant_id = p_tenant_id;
+
+  -- Insert ledger entry
+  INSERT INTO ledger_entries (tenant_id, budget_id, entry_type, currency, amount, ref_type, ref_id, fallback_from)
+  VALUES (p_tenant_id, p_budget_id, 'reserve', p_currency, p_amount, 'issuance', p_ref_id, p_fallback_from);
+
+  RETURN true;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION reserve_budget(
+  p_tenant_id uuid,
+  p_budget_id uuid,
+  p_amount numeric,
+  p_currency text,
+  p_ref_id uuid
+) RETURNS boolean AS $$
+BEGIN
+  RETURN reserve_budget_from(p_tenant_id, p_budget_id, p_amount, p_currency, p_ref_id, NULL);
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION reserve_campaign_budget(
+  p_tenant_id uuid,
+  p_campaign_id uuid,
+  p_amount numeric,
+  p_currency text,
+  p_ref_id uuid
+) RETURNS uuid AS $$
+DECLARE
+  v_primary uuid;
+  v_budget uuid;
+BEGIN
+  SELECT budget_id INTO v_primary
+  FROM campaigns
+  WHERE id = p_campaign_id AND tenant_id = p_tenant_id;
+
+  FOR v_budget IN
+    SELECT b.budget_id
+    FROM (
+      SELECT v_primary AS budget_id, -1 AS position
+      WHERE v_primary IS NOT NULL
+      UNION ALL
+      SELECT cb.budget_id, cb.position
+      FROM campaign_budgets cb
+      WHERE cb.tenant_id = p_tenant_id AND cb.campaign_id = p_campaign_id
+    ) b
+    ORDER BY b.position
+  LOOP
+    IF reserve_budget_from(p_tenant_id, v_budget, p_amount, p_currency, p_ref_id,
+         CASE WHEN v_budget IS DISTINCT FROM v_primary THEN v_primary END) THEN
+      RETURN v_budget;
+    END IF;
+  END LOOP;
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,57 @@
+-- Currencies beyond USD and ZWG
+-- Version: 1.0
+-- Date: 2025-12-20
+
+-- =============================================================================
+-- CURRENCY COLUMNS
+-- =============================================================================
+
+-- Currencies were limited to ZWG and USD. Any ISO 4217 code is now accepted;
+-- the application checks codes against the ISO 4217 table and the tenant's
+-- allowed currencies, so the database only checks the format.
+ALTER TABLE tenants DROP CONSTRAINT tenants_default_ccy_check;
+ALTER TABLE tenants ADD CONSTRAINT tenants_default_ccy_check
+  CHECK (default_ccy ~ '^[A-Z]{3}$');
+
+ALTER TABLE budgets DROP CONSTRAINT budgets_currency_check;
+ALTER TABLE budgets ADD CONSTRAINT budgets_currency_check
+  CHECK (currency ~ '^[A-Z]{3}$');
+
+ALTER TABLE ledger_entries DROP CONSTRAINT ledger_entries_currency_check;
+ALTER TABLE ledger_entries ADD CONSTRAINT ledger_entries_currency_check
+  CHECK (currency ~ '^[A-Z]{3}$');
+
+ALTER TABLE reward_catalog DROP CONSTRAINT reward_catalog_currency_check;
+ALTER TABLE reward_catalog ADD CONSTRAINT reward_catalog_currency_check
+  CHECK (currency ~ '^[A-Z]{3}$');
+
+ALTER TABLE issuances DROP CONSTRAINT issuances_currency_check;
+ALTER TABLE issuances ADD CONSTRAINT issuances_currency_check
+  CHECK (currency ~ '^[A-Z]{3}$');
+
+ALTER TABLE receipts DROP CONSTRAINT receipts_currency_check;
+ALTER TABLE receipts ADD CONSTRAINT receipts_currency_check
+  CHECK (currency ~ '^[A-Z]{3}$');
+
+ALTER TABLE budget_statements DROP CONSTRAINT budget_statements_currency_check;
+ALTER TABLE budget_statements ADD CONSTRAINT budget_statements_currency_check
+  CHECK (currency ~ '^[A-Z]{3}$');
+
+ALTER TABLE liability_entries DROP CONSTRAINT liability_entries_currency_check;
+ALTER TABLE liability_entries ADD CONSTRAINT liability_entries_currency_check
+  CHECK (currency ~ '^[A-Z]{3}$');
+
+ALTER TABLE budget_spend_daily DROP CONSTRAINT budget_spend_daily_currency_check;
+ALTER TABLE budget_spend_daily ADD CONSTRAINT budget_spend_daily_currency_check
+  CHECK (currency ~ '^[A-Z]{3}$');
+
+-- =============================================================================
+-- TENANT CURRENCIES
+-- =============================================================================
+
+-- Currencies a tenant may create budgets and rewards in besides its default
+-- currency. Existing tenants keep both currencies they could use before.
+ALTER TABLE tenants
+  ADD COLUMN allowed_currencies text[] NOT NULL DEFAULT '{}';
+
+UPDATE tenants SET allowed_currencies = ARRAY['ZWG', 'USD'];
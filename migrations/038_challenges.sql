@@ -0,0 +1,101 @@
+-- Challenges and streaks
+-- Version: 1.0
+-- Date: 2025-12-21
+
+-- =============================================================================
+-- CHALLENGES TABLE
+-- =============================================================================
+
+-- Goals customers work towards with their events. A count challenge is met
+-- by target qualifying events within one period ("visit 3 times this
+-- week"); a streak by qualifying events in target consecutive periods
+-- ("shop every week for 4 weeks"). criteria is JSON Logic over the event, as
+-- in rule conditions; without it every event of the type qualifies.
+-- Completing a challenge emits a challenge_completed event rules can reward.
+CREATE TABLE challenges (
+  id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id    uuid NOT NULL REFERENCES tenants(id),
+  name         text NOT NULL,
+  description  text NOT NULL DEFAULT '',
+  kind         text NOT NULL CHECK (kind IN ('count','streak')),
+  event_type   text NOT NULL,
+  criteria     jsonb,
+  target       int NOT NULL CHECK (target > 0),
+  period       text NOT NULL CHECK (period IN ('day','week','month','none')),
+  starts_at    timestamptz,
+  ends_at      timestamptz,
+  active       boolean NOT NULL DEFAULT true,
+  created_at   timestamptz NOT NULL DEFAULT now(),
+  updated_at   timestamptz NOT NULL DEFAULT now(),
+  -- A streak counts consecutive periods, so it needs a period
+  CHECK (kind = 'count' OR period <> 'none'),
+  CHECK (ends_at IS NULL OR starts_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX idx_challenges_tenant_event ON challenges(tenant_id, event_type) WHERE active;
+
+-- =============================================================================
+-- CHALLENGE PROGRESS TABLE
+-- =============================================================================
+
+-- A customer's progress on one attempt at a challenge. For count challenges
+-- there is one row per period; period_start is the period counted. For
+-- streaks there is one row per streak; period_start is its first period and
+-- last_period the latest period with a qualifying event.
+CREATE TABLE challenge_progress (
+  id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id     uuid NOT NULL REFERENCES tenants(id),
+  challenge_id  uuid NOT NULL REFERENCES challenges(id),
+  customer_id   uuid NOT NULL REFERENCES customers(id),
+  period_start  timestamptz NOT NULL,
+  last_period   timestamptz NOT NULL,
+  progress      int NOT NULL DEFAULT 0,
+  completed_at  timestamptz,
+  event_id      uuid REFERENCES events(id),
+  created_at    timestamptz NOT NULL DEFAULT now(),
+  updated_at    timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (challenge_id, customer_id, period_start)
+);
+
+CREATE INDEX idx_challenge_progress_customer ON challenge_progress(tenant_id, customer_id, challenge_id, period_start DESC);
+
+-- Events already counted towards a challenge, so reprocessing an event does
+-- not count it twice
+CREATE TABLE challenge_events (
+  challenge_id  uuid NOT NULL REFERENCES challenges(id),
+  event_id      uuid NOT NULL REFERENCES events(id),
+  tenant_id     uuid NOT NULL REFERENCES tenants(id),
+  created_at    timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (challenge_id, event_id)
+);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE challenges ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_challenges
+  ON challenges
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE challenges FORCE ROW LEVEL SECURITY;
+
+ALTER TABLE challenge_progress ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_challenge_progress
+  ON challenge_progress
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE challenge_progress FORCE ROW LEVEL SECURITY;
+
+ALTER TABLE challenge_events ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_challenge_events
+  ON challenge_events
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE challenge_events FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,47 @@
+-- Campaign leaderboards
+-- Version: 1.0
+-- Date: 2025-12-22
+
+-- =============================================================================
+-- CAMPAIGN LEADERBOARD SETTINGS
+-- =============================================================================
+
+-- Leaderboards are opt-in per campaign: leaderboard_metric is what customers
+-- are ranked by, or NULL for no leaderboard.
+--   points  face value of the campaign's points_credit rewards
+--   spend   purchase amounts during the campaign
+--   visits  visit events during the campaign
+ALTER TABLE campaigns
+  ADD COLUMN leaderboard_metric text
+    CHECK (leaderboard_metric IN ('points','spend','visits'));
+
+-- =============================================================================
+-- LEADERBOARD ENTRIES TABLE
+-- =============================================================================
+
+-- The cached ranking of a campaign's leaderboard, rebuilt by the leaderboard
+-- worker. Customers tied on score share a rank.
+CREATE TABLE leaderboard_entries (
+  tenant_id    uuid NOT NULL REFERENCES tenants(id),
+  campaign_id  uuid NOT NULL REFERENCES campaigns(id),
+  customer_id  uuid NOT NULL REFERENCES customers(id),
+  rank         int NOT NULL,
+  score        numeric(18,2) NOT NULL,
+  computed_at  timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (campaign_id, customer_id)
+);
+
+CREATE INDEX idx_leaderboard_entries_rank ON leaderboard_entries(tenant_id, campaign_id, rank);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE leaderboard_entries ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_leaderboard_entries
+  ON leaderboard_entries
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE leaderboard_entries FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,101 @@
+-- Prize draws
+-- Version: 1.0
+-- Date: 2025-12-23
+
+-- =============================================================================
+-- DRAWS TABLE
+-- =============================================================================
+
+-- A prize draw for a campaign. While the draw is open, each qualifying event
+-- (event_type, matching criteria, during the campaign) enters its customer
+-- with entries_per_event tickets. The draw picks winners customers at
+-- random, weighted by tickets, either at draw_at or when triggered by staff.
+-- Each winner gets a draw_won event; prizes are granted by rules on it.
+--
+-- seed is the random seed the winners were picked with and entries_hash a
+-- SHA-256 over the entries, so a draw can be re-run and checked.
+CREATE TABLE draws (
+  id                 uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id          uuid NOT NULL REFERENCES tenants(id),
+  campaign_id        uuid NOT NULL REFERENCES campaigns(id),
+  name               text NOT NULL,
+  event_type         text NOT NULL,
+  criteria           jsonb,
+  entries_per_event  int NOT NULL DEFAULT 1 CHECK (entries_per_event > 0),
+  winners            int NOT NULL CHECK (winners > 0),
+  draw_at            timestamptz,
+  status             text NOT NULL DEFAULT 'open' CHECK (status IN ('open','drawn','cancelled')),
+  seed               text,
+  entries_hash       text,
+  entry_count        int,
+  drawn_at           timestamptz,
+  created_at         timestamptz NOT NULL DEFAULT now(),
+  updated_at         timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_draws_tenant_event ON draws(tenant_id, event_type) WHERE status = 'open';
+CREATE INDEX idx_draws_due ON draws(tenant_id, draw_at) WHERE status = 'open' AND draw_at IS NOT NULL;
+
+-- =============================================================================
+-- DRAW ENTRIES TABLE
+-- =============================================================================
+
+-- One row per qualifying event, so reprocessing an event does not enter it
+-- twice
+CREATE TABLE draw_entries (
+  id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id    uuid NOT NULL REFERENCES tenants(id),
+  draw_id      uuid NOT NULL REFERENCES draws(id),
+  customer_id  uuid NOT NULL REFERENCES customers(id),
+  event_id     uuid NOT NULL REFERENCES events(id),
+  tickets      int NOT NULL CHECK (tickets > 0),
+  created_at   timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (draw_id, event_id)
+);
+
+-- =============================================================================
+-- DRAW WINNERS TABLE
+-- =============================================================================
+
+-- A customer wins at most once per draw. event_id is their draw_won event.
+CREATE TABLE draw_winners (
+  draw_id      uuid NOT NULL REFERENCES draws(id),
+  tenant_id    uuid NOT NULL REFERENCES tenants(id),
+  position     int NOT NULL CHECK (position > 0),
+  customer_id  uuid NOT NULL REFERENCES customers(id),
+  event_id     uuid NOT NULL REFERENCES events(id),
+  created_at   timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (draw_id, position),
+  UNIQUE (draw_id, customer_id)
+);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE draws ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_draws
+  ON draws
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE draws FORCE ROW LEVEL SECURITY;
+
+ALTER TABLE draw_entries ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_draw_entries
+  ON draw_entries
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE draw_entries FORCE ROW LEVEL SECURITY;
+
+ALTER TABLE draw_winners ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_draw_winners
+  ON draw_winners
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE draw_winners FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,125 @@
+-- Customer-entered promo codes
+-- Version: 1.0
+-- Date: 2025-12-24
+
+-- =============================================================================
+-- PROMO BATCHES TABLE
+-- =============================================================================
+
+-- A batch of promo codes tied to a rule. A unique batch has generated codes
+-- that can each be used once; a shared batch has one code, e.g. SUMMER25,
+-- that can be used up to max_uses times in total (unlimited when NULL).
+-- Either way a customer can use a batch's codes per_customer_limit times.
+-- An accepted code emits a promo_code event that runs only the batch's rule,
+-- so its conditions, caps and budget still apply.
+CREATE TABLE promo_batches (
+  id                  uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id           uuid NOT NULL REFERENCES tenants(id),
+  name                text NOT NULL,
+  rule_id             uuid NOT NULL REFERENCES rules(id),
+  kind                text NOT NULL CHECK (kind IN ('unique','shared')),
+  max_uses            int CHECK (max_uses > 0),
+  per_customer_limit  int NOT NULL DEFAULT 1 CHECK (per_customer_limit > 0),
+  starts_at           timestamptz,
+  ends_at             timestamptz,
+  active              boolean NOT NULL DEFAULT true,
+  created_at          timestamptz NOT NULL DEFAULT now(),
+  updated_at          timestamptz NOT NULL DEFAULT now(),
+  CHECK (kind = 'shared' OR max_uses IS NULL),
+  CHECK (ends_at IS NULL OR starts_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX idx_promo_batches_tenant ON promo_batches(tenant_id, created_at DESC);
+
+-- =============================================================================
+-- PROMO CODES TABLE
+-- =============================================================================
+
+-- Codes are unique per tenant across batches, so a submitted code finds its
+-- batch
+CREATE TABLE promo_codes (
+  id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id   uuid NOT NULL REFERENCES tenants(id),
+  batch_id    uuid NOT NULL REFERENCES promo_batches(id),
+  code        text NOT NULL,
+  uses        int NOT NULL DEFAULT 0,
+  created_at  timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (tenant_id, code)
+);
+
+CREATE INDEX idx_promo_codes_batch ON promo_codes(batch_id, code);
+
+-- =============================================================================
+-- PROMO REDEMPTIONS TABLE
+-- =============================================================================
+
+CREATE TABLE promo_redemptions (
+  id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id    uuid NOT NULL REFERENCES tenants(id),
+  batch_id     uuid NOT NULL REFERENCES promo_batches(id),
+  code_id      uuid NOT NULL REFERENCES promo_codes(id),
+  customer_id  uuid NOT NULL REFERENCES customers(id),
+  event_id     uuid NOT NULL REFERENCES events(id),
+  channel      text NOT NULL,
+  created_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_promo_redemptions_customer ON promo_redemptions(tenant_id, batch_id, customer_id);
+
+-- =============================================================================
+-- PROMO ATTEMPTS TABLE
+-- =============================================================================
+
+-- Every code a customer submits, so repeated failures can be throttled
+-- before codes can be guessed
+CREATE TABLE promo_attempts (
+  id           bigserial PRIMARY KEY,
+  tenant_id    uuid NOT NULL REFERENCES tenants(id),
+  customer_id  uuid NOT NULL REFERENCES customers(id),
+  code         text NOT NULL,
+  channel      text NOT NULL,
+  succeeded    boolean NOT NULL,
+  created_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_promo_attempts_customer ON promo_attempts(tenant_id, customer_id, created_at DESC);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE promo_batches ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_promo_batches
+  ON promo_batches
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE promo_batches FORCE ROW LEVEL SECURITY;
+
+ALTER TABLE promo_codes ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_promo_codes
+  ON promo_codes
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE promo_codes FORCE ROW LEVEL SECURITY;
+
+ALTER TABLE promo_redemptions ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_promo_redemptions
+  ON promo_redemptions
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE promo_redemptions FORCE ROW LEVEL SECURITY;
+
+ALTER TABLE promo_attempts ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_promo_attempts
+  ON promo_attempts
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE promo_attempts FORCE ROW LEVEL SECURITY;
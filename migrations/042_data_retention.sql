@@ -0,0 +1,76 @@
+-- Data retention
+-- Version: 1.0
+-- Date: 2025-12-25
+
+-- =============================================================================
+-- TENANT SETTINGS
+-- =============================================================================
+
+-- How many months a tenant's data is kept before the purge worker removes it.
+-- NULL keeps the data forever, which was the behaviour before these settings
+-- existed. Configured with `loyaltyctl set-retention`.
+--
+--   event_retention_months     events, by occurred_at
+--   message_retention_months   sent and dead outbound messages and channel
+--                              sessions, by last activity
+--   issuance_retention_months  expired issuances, by expires_at
+ALTER TABLE tenants
+  ADD COLUMN event_retention_months int CHECK (event_retention_months > 0),
+  ADD COLUMN message_retention_months int CHECK (message_retention_months > 0),
+  ADD COLUMN issuance_retention_months int CHECK (issuance_retention_months > 0);
+
+-- =============================================================================
+-- ANONYMIZED ROWS
+-- =============================================================================
+
+-- Events referenced by issuances, reversals, draws and the like cannot be
+-- deleted, and issuances back the liability ledger, so rows past retention
+-- that must stay are anonymized instead and marked so they are not counted
+-- again.
+ALTER TABLE events
+  ADD COLUMN anonymized_at timestamptz;
+
+ALTER TABLE issuances
+  ADD COLUMN anonymized_at timestamptz;
+
+CREATE INDEX idx_events_tenant_occurred ON events(tenant_id, occurred_at)
+  WHERE anonymized_at IS NULL;
+CREATE INDEX idx_issuances_tenant_expired ON issuances(tenant_id, expires_at)
+  WHERE status = 'expired' AND anonymized_at IS NULL;
+
+-- =============================================================================
+-- PURGE RUNS TABLE
+-- =============================================================================
+
+-- One row per tenant per purge, including dry runs, which report what a purge
+-- would remove without removing it. A cutoff is NULL when the tenant keeps
+-- that kind of data forever.
+CREATE TABLE purge_runs (
+  id                    uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id             uuid NOT NULL REFERENCES tenants(id),
+  dry_run               boolean NOT NULL,
+  event_cutoff          timestamptz,
+  message_cutoff        timestamptz,
+  issuance_cutoff       timestamptz,
+  events_deleted        bigint NOT NULL DEFAULT 0,
+  events_anonymized     bigint NOT NULL DEFAULT 0,
+  messages_deleted      bigint NOT NULL DEFAULT 0,
+  sessions_deleted      bigint NOT NULL DEFAULT 0,
+  issuances_anonymized  bigint NOT NULL DEFAULT 0,
+  created_at            timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_purge_runs_tenant ON purge_runs(tenant_id, created_at DESC);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE purge_runs ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_purge_runs
+  ON purge_runs
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE purge_runs FORCE ROW LEVEL SECURITY;
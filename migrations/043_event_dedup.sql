@@ -0,0 +1,62 @@
+-- Event content deduplication
+-- Version: 1.0
+-- Date: 2025-12-26
+
+-- =============================================================================
+-- TENANT SETTINGS
+-- =============================================================================
+
+-- Some POS clients regenerate idempotency keys when they retry, so the same
+-- event arrives twice under different keys. Tenants may opt in to catching
+-- these by content: an event is a near-duplicate of an earlier event of the
+-- same customer, type and properties that occurred within the window.
+-- Configured with `loyaltyctl set-event-dedup`.
+--
+--   event_dedup_mode            NULL (off), 'flag' to accept near-duplicates
+--                               but mark them, or 'suppress' to drop them
+--   event_dedup_window_seconds  how far apart occurred_at may be
+ALTER TABLE tenants
+  ADD COLUMN event_dedup_mode text CHECK (event_dedup_mode IN ('flag', 'suppress')),
+  ADD COLUMN event_dedup_window_seconds int NOT NULL DEFAULT 300
+    CHECK (event_dedup_window_seconds > 0);
+
+-- =============================================================================
+-- EVENT CONTENT HASHES
+-- =============================================================================
+
+-- content_hash is only set for API events of tenants with deduplication on.
+-- duplicate_of points flagged near-duplicates at the event they repeat.
+ALTER TABLE events
+  ADD COLUMN content_hash text,
+  ADD COLUMN duplicate_of uuid REFERENCES events(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_events_content_hash ON events(tenant_id, content_hash, occurred_at)
+  WHERE content_hash IS NOT NULL;
+
+-- =============================================================================
+-- DEDUPLICATION STATS TABLE
+-- =============================================================================
+
+-- Near-duplicates caught per tenant, day and event type. Suppressed events
+-- are never stored, so these counts are the only record of them.
+CREATE TABLE event_dedup_stats (
+  tenant_id   uuid NOT NULL REFERENCES tenants(id),
+  day         date NOT NULL,
+  event_type  text NOT NULL,
+  flagged     bigint NOT NULL DEFAULT 0,
+  suppressed  bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY (tenant_id, day, event_type)
+);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE event_dedup_stats ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_event_dedup_stats
+  ON event_dedup_stats
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE event_dedup_stats FORCE ROW LEVEL SECURITY;
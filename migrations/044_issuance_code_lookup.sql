@@ -0,0 +1,14 @@
+-- Issuance code lookup
+-- Version: 1.0
+-- Date: 2025-12-26
+
+-- =============================================================================
+-- INDEXES
+-- =============================================================================
+
+-- Cashiers look issuances up by the code the customer shows them. Exact,
+-- case-insensitive lookups use idx_issuances_tenant_code (011). Fuzzy lookups
+-- also ignore spaces and dashes, so "abc-123" and "ABC 123" find ABC123.
+CREATE INDEX idx_issuances_tenant_code_normalized
+  ON issuances(tenant_id, translate(upper(code), ' -', ''))
+  WHERE code IS NOT NULL;
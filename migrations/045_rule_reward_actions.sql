@@ -0,0 +1,99 @@
+-- Rule reward quantities and multi-reward actions
+-- Version: 1.0
+-- Date: 2025-12-27
+
+-- =============================================================================
+-- RULE ACTIONS
+-- =============================================================================
+
+-- How many units of its reward one trigger of the rule issues
+ALTER TABLE rules
+  ADD COLUMN quantity int NOT NULL DEFAULT 1 CHECK (quantity BETWEEN 1 AND 100);
+
+-- Rewards a rule issues on each trigger on top of its own reward (e.g. a
+-- voucher and points). A trigger issues the whole set or nothing; the
+-- issuances are reserved against the campaign's budget in one transaction.
+CREATE TABLE rule_rewards (
+  id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id   uuid NOT NULL REFERENCES tenants(id),
+  rule_id     uuid NOT NULL REFERENCES rules(id) ON DELETE CASCADE,
+  reward_id   uuid NOT NULL REFERENCES reward_catalog(id),
+  quantity    int NOT NULL DEFAULT 1 CHECK (quantity BETWEEN 1 AND 100),
+  position    int NOT NULL,
+  created_at  timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (rule_id, position)
+);
+
+CREATE INDEX idx_rule_rewards_tenant_rule ON rule_rewards(tenant_id, rule_id, position);
+
+-- =============================================================================
+-- CAP FUNCTIONS
+-- =============================================================================
+
+-- Caps limit how often a rule triggers, not how many issuances a trigger
+-- creates, so the issuances of one event count once. Issuances without an
+-- event (manual grants) still count individually.
+CREATE OR REPLACE FUNCTION get_customer_rule_issuance_count(
+  p_tenant_id uuid,
+  p_customer_id uuid,
+  p_rule_id uuid
+) RETURNS bigint AS $$
+DECLARE
+  v_campaign_id uuid;
+BEGIN
+  SELECT campaign_id INTO v_campaign_id
+  FROM rules
+  WHERE id = p_rule_id AND tenant_id = p_tenant_id;
+
+  IF NOT FOUND THEN
+    RETURN 0;
+  END IF;
+
+  RETURN (
+    SELECT COUNT(DISTINCT COALESCE(event_id, id))
+    FROM issuances
+    WHERE tenant_id = p_tenant_id
+      AND customer_id = p_customer_id
+      AND campaign_id = v_campaign_id
+      AND status IN ('reserved', 'issued', 'redeemed')
+  );
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+CREATE OR REPLACE FUNCTION get_rule_global_issuance_count(
+  p_tenant_id uuid,
+  p_rule_id uuid
+) RETURNS bigint AS $$
+DECLARE
+  v_campaign_id uuid;
+BEGIN
+  SELECT campaign_id INTO v_campaign_id
+  FROM rules
+  WHERE id = p_rule_id AND tenant_id = p_tenant_id;
+
+  IF NOT FOUND THEN
+    RETURN 0;
+  END IF;
+
+  RETURN (
+    SELECT COUNT(DISTINCT COALESCE(event_id, id))
+    FROM issuances
+    WHERE tenant_id = p_tenant_id
+      AND campaign_id = v_campaign_id
+      AND status IN ('reserved', 'issued', 'redeemed')
+  );
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE rule_rewards ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_rule_rewards
+  ON rule_rewards
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE rule_rewards FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,15 @@
+-- Rewards valued from the triggering event
+-- Version: 1.0
+-- Date: 2025-12-27
+
+-- =============================================================================
+-- ISSUANCE VALUE INPUTS
+-- =============================================================================
+
+-- Rewards may be valued from the event that triggers them instead of their
+-- fixed face_value, with a "value_formula" in their metadata (e.g. 5% of the
+-- amount, at most 10). The engine reserves the computed value and records
+-- what it was computed from, e.g.
+--   {"property": "amount", "basis": 120, "percent": 5, "max": 10, "value": 6}
+-- NULL for issuances at a fixed face value.
+ALTER TABLE issuances ADD COLUMN value_inputs jsonb;
@@ -0,0 +1,300 @@
+-- Budget commitments for scheduled campaigns
+-- Version: 1.0
+-- Date: 2025-12-28
+
+-- =============================================================================
+-- LEDGER ENTRY TYPES
+-- =============================================================================
+
+-- 'commit' entries ring-fence part of a budget for a campaign before it
+-- issues anything. A commitment posts a positive 'commit' entry; converting
+-- it into reservations or releasing it posts negative ones, so the 'commit'
+-- entries of a budget sum to its outstanding commitments.
+ALTER TABLE ledger_entries DROP CONSTRAINT ledger_entries_entry_type_check;
+ALTER TABLE ledger_entries ADD CONSTRAINT ledger_entries_entry_type_check
+  CHECK (entry_type IN ('fund','reserve','release','charge','expire','reverse','commit'));
+
+-- =============================================================================
+-- BUDGET COMMITMENTS
+-- =============================================================================
+
+-- An amount of a budget earmarked for a campaign. Committed funds count
+-- towards the budget's balance like reservations, so other campaigns can't
+-- spend them. The campaign's reservations against the budget draw the
+-- commitment down (status 'converted' once fully drawn); cancelling the
+-- campaign releases what is left (status 'released').
+CREATE TABLE budget_commitments (
+  id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id    uuid NOT NULL REFERENCES tenants(id),
+  budget_id    uuid NOT NULL REFERENCES budgets(id),
+  campaign_id  uuid NOT NULL REFERENCES campaigns(id),
+  currency     text NOT NULL,
+  amount       numeric(18,2) NOT NULL CHECK (amount > 0),
+  remaining    numeric(18,2) NOT NULL CHECK (remaining >= 0 AND remaining <= amount),
+  status       text NOT NULL DEFAULT 'open' CHECK (status IN ('open','converted','released')),
+  note         text,
+  created_by   uuid REFERENCES staff_users(id),
+  created_at   timestamptz NOT NULL DEFAULT now(),
+  released_by  uuid REFERENCES staff_users(id),
+  released_at  timestamptz
+);
+
+CREATE INDEX idx_budget_commitments_tenant_budget ON budget_commitments(tenant_id, budget_id, created_at DESC);
+CREATE INDEX idx_budget_commitments_open ON budget_commitments(tenant_id, budget_id, campaign_id)
+  WHERE status = 'open';
+
+-- =============================================================================
+-- BUDGET FUNCTIONS
+-- =============================================================================
+
+-- Ring-fence a commitment's amount against its budget. Returns false when
+-- the budget's hard cap can't take it.
+CREATE OR REPLACE FUNCTION commit_budget(
+  p_tenant_id uuid,
+  p_commitment_id uuid
+) RETURNS boolean AS $$
+DECLARE
+  v_commitment budget_commitments%ROWTYPE;
+  v_balance numeric;
+  v_hard_cap numeric;
+BEGIN
+  SELECT * INTO v_commitment
+  FROM budget_commitments
+  WHERE id = p_commitment_id AND tenant_id = p_tenant_id;
+
+  IF NOT FOUND THEN
+    RAISE EXCEPTION 'Budget commitment not found: %', p_commitment_id;
+  END IF;
+
+  SELECT balance, hard_cap
+  INTO v_balance, v_hard_cap
+  FROM budgets
+  WHERE id = v_commitment.budget_id AND tenant_id = p_tenant_id
+  FOR UPDATE;
+
+  IF (v_balance + v_commitment.amount) > v_hard_cap THEN
+    RETURN false;
+  END IF;
+
+  UPDATE budgets
+  SET balance = balance + v_commitment.amount
+  WHERE id = v_commitment.budget_id AND tenant_id = p_tenant_id;
+
+  INSERT INTO ledger_entries (tenant_id, budget_id, entry_type, currency, amount, ref_type, ref_id)
+  VALUES (p_tenant_id, v_commitment.budget_id, 'commit', v_commitment.currency, v_commitment.amount,
+          'commitment', p_commitment_id);
+
+  RETURN true;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Release what is left of an open commitment back to its budget. A released
+-- commitment keeps its remaining amount as the amount released. Returns the
+-- amount released, or NULL when the commitment isn't open.
+CREATE OR REPLACE FUNCTION release_budget_commitment(
+  p_tenant_id uuid,
+  p_commitment_id uuid,
+  p_released_by uuid
+) RETURNS numeric AS $$
+DECLARE
+  v_budget_id uuid;
+  v_commitment budget_commitments%ROWTYPE;
+BEGIN
+  SELECT budget_id INTO v_budget_id
+  FROM budget_commitments
+  WHERE id = p_commitment_id AND tenant_id = p_tenant_id;
+
+  IF NOT FOUND THEN
+    RETURN NULL;
+  END IF;
+
+  -- Lock the budget before the commitment, in the order reservations do
+  PERFORM 1 FROM budgets
+  WHERE id = v_budget_id AND tenant_id = p_tenant_id
+  FOR UPDATE;
+
+  SELECT * INTO v_commitment
+  FROM budget_commitments
+  WHERE id = p_commitment_id AND tenant_id = p_tenant_id AND status = 'open'
+  FOR UPDATE;
+
+  IF NOT FOUND THEN
+    RETURN NULL;
+  END IF;
+
+  UPDATE budgets
+  SET balance = balance - v_commitment.remaining
+  WHERE id = v_budget_id AND tenant_id = p_tenant_id;
+
+  IF v_commitment.remaining > 0 THEN
+    INSERT INTO ledger_entries (tenant_id, budget_id, entry_type, currency, amount, ref_type, ref_id)
+    VALUES (p_tenant_id, v_budget_id, 'commit', v_commitment.currency, -v_commitment.remaining,
+            'commitment', p_commitment_id);
+  END IF;
+
+  UPDATE budget_commitments
+  SET status = 'released',
+      released_by = p_released_by,
+      released_at = now()
+  WHERE id = p_commitment_id;
+
+  RETURN v_commitment.remaining;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Reserve from a budget for one of a campaign's issuances, drawing first on
+-- the campaign's open commitments against the budget. The drawn part is
+-- already counted in the balance, so only the rest needs capacity.
+CREATE OR REPLACE FUNCTION reserve_committed_budget(
+  p_tenant_id uuid,
+  p_budget_id uuid,
+  p_campaign_id uuid,
+  p_amount numeric,
+  p_currency text,
+  p_ref_id uuid,
+  p_fallback_from uuid
+) RETURNS boolean AS $$
+DECLARE
+  v_balance numeric;
+  v_hard_cap numeric;
+  v_committed numeric;
+  v_needed numeric;
+  v_draw numeric;
+  v_commitment record;
+BEGIN
+  SELECT balance, hard_cap
+  INTO v_balance, v_hard_cap
+  FROM budgets
+  WHERE id = p_budget_id AND tenant_id = p_tenant_id
+  FOR UPDATE;
+
+  IF NOT FOUND THEN
+    RAISE EXCEPTION 'Budget not found: %', p_budget_id;
+  END IF;
+
+  PERFORM 1 FROM budget_commitments
+  WHERE tenant_id = p_tenant_id AND budget_id = p_budget_id
+    AND campaign_id = p_campaign_id AND status = 'open' AND currency = p_currency
+  FOR UPDATE;
+
+  SELECT COALESCE(SUM(remaining), 0) INTO v_committed
+  FROM budget_commitments
+  WHERE tenant_id = p_tenant_id AND budget_id = p_budget_id
+    AND campaign_id = p_campaign_id AND status = 'open' AND currency = p_currency;
+
+  v_needed := LEAST(v_committed, p_amount);
+
+  IF (v_balance + p_amount - v_needed) > v_hard_cap THEN
+    RETURN false;
+  END IF;
+
+  -- Draw the oldest commitments first
+  FOR v_commitment IN
+    SELECT id, remaining
+    FROM budget_commitments
+    WHERE tenant_id = p_tenant_id AND budget_id = p_budget_id
+      AND campaign_id = p_campaign_id AND status = 'open' AND currency = p_currency
+    ORDER BY created_at, id
+  LOOP
+    EXIT WHEN v_needed <= 0;
+    v_draw := LEAST(v_commitment.remaining, v_needed);
+
+    UPDATE budget_commitments
+    SET remaining = remaining - v_draw,
+        status = CASE WHEN remaining - v_draw = 0 THEN 'converted' ELSE status END
+    WHERE id = v_commitment.id;
+
+    INSERT INTO ledger_entries (tenant_id, budget_id, entry_type, currency, amount, ref_type, ref_id)
+    VALUES (p_tenant_id, p_budget_id, 'commit', p_currency, -v_draw, 'commitment', v_commitment.id);
+
+    v_needed := v_needed - v_draw;
+  END LOOP;
+
+  UPDATE budgets
+  SET balance = balance + p_amount - LEAST(v_committed, p_amount)
+  WHERE id = p_budget_id AND tenant_id = p_tenant_id;
+
+  INSERT INTO ledger_entries (tenant_id, budget_id, entry_type, currency, amount, ref_type, ref_id, fallback_from)
+  VALUES (p_tenant_id, p_budget_id, 'reserve', p_currency, p_amount, 'issuance', p_ref_id, p_fallback_from);
+
+  RETURN true;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION reserve_campaign_budget(
+  p_tenant_id uuid,
+  p_campaign_id uuid,
+  p_amount numeric,
+  p_currency text,
+  p_ref_id uuid
+) RETURNS uuid AS $$
+DECLARE
+  v_primary uuid;
+  v_budget uuid;
+BEGIN
+  SELECT budget_id INTO v_primary
+  FROM campaigns
+  WHERE id = p_campaign_id AND tenant_id = p_tenant_id;
+
+  FOR v_budget IN
+    SELECT b.budget_id
+    FROM (
+      SELECT v_primary AS budget_id, -1 AS position
+      WHERE v_primary IS NOT NULL
+      UNION ALL
+      SELECT cb.budget_id, cb.position
+      FROM campaign_budgets cb
+      WHERE cb.tenant_id = p_tenant_id AND cb.campaign_id = p_campaign_id
+    ) b
+    ORDER BY b.position
+  LOOP
+    IF reserve_committed_budget(p_tenant_id, v_budget, p_campaign_id, p_amount, p_currency, p_ref_id,
+         CASE WHEN v_budget IS DISTINCT FROM v_primary THEN v_primary END) THEN
+      RETURN v_budget;
+    END IF;
+  END LOOP;
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Commitments count towards the balance
+CREATE OR REPLACE FUNCTION reconcile_budget(
+  p_budget_id uuid
+) RETURNS TABLE (
+  current_balance numeric,
+  calculated_balance numeric,
+  discrepancy numeric
+) AS $$
+DECLARE
+  v_current_balance numeric;
+  v_calculated_balance numeric;
+BEGIN
+  SELECT balance INTO v_current_balance
+  FROM budgets
+  WHERE id = p_budget_id;
+
+  SELECT COALESCE(SUM(amount) FILTER (WHERE entry_type IN ('fund', 'reserve', 'release', 'commit')), 0)
+  INTO v_calculated_balance
+  FROM ledger_entries
+  WHERE budget_id = p_budget_id;
+
+  RETURN QUERY SELECT
+    v_current_balance,
+    v_calculated_balance,
+    v_current_balance - v_calculated_balance as discrepancy;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE budget_commitments ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_budget_commitments
+  ON budget_commitments
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE budget_commitments FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,20 @@
+-- Background processing of reserved issuances
+-- Version: 1.0
+-- Date: 2025-12-28
+
+-- =============================================================================
+-- ISSUANCE PROCESSING
+-- =============================================================================
+
+-- The issuance processor moves reserved issuances to issued through their
+-- reward type's handler. A transient handler failure (a supplier timeout, an
+-- open circuit) leaves the issuance reserved and schedules another attempt
+-- at next_process_at; last_process_error is kept for support.
+ALTER TABLE issuances
+  ADD COLUMN process_attempts int NOT NULL DEFAULT 0,
+  ADD COLUMN next_process_at timestamptz,
+  ADD COLUMN last_process_error text;
+
+-- The processor's queue: a tenant's reserved issuances, oldest first
+CREATE INDEX idx_issuances_processing ON issuances(tenant_id, issued_at)
+  WHERE status = 'reserved';
@@ -0,0 +1,47 @@
+-- Suppliers
+-- Version: 1.0
+-- Date: 2025-12-29
+
+-- =============================================================================
+-- SUPPLIERS TABLE
+-- =============================================================================
+
+-- Third parties that fulfil a tenant's rewards, e.g. an airtime aggregator or
+-- a voucher provider. integration holds the connector settings used to issue
+-- through the supplier (connector name, endpoint, product mapping); secrets
+-- belong in the environment, not here.
+CREATE TABLE suppliers (
+  id             uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id      uuid NOT NULL REFERENCES tenants(id),
+  name           text NOT NULL,
+  contact_name   text,
+  contact_email  text,
+  contact_phone  text,
+  integration    jsonb NOT NULL DEFAULT '{}'::jsonb,
+  active         boolean NOT NULL DEFAULT true,
+  created_at     timestamptz NOT NULL DEFAULT now(),
+  updated_at     timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (tenant_id, name)
+);
+
+-- reward_catalog.supplier_id predates this table. The constraint is added NOT
+-- VALID so rewards created before suppliers existed keep their supplier_id;
+-- new and updated rewards must reference a supplier.
+ALTER TABLE reward_catalog
+  ADD CONSTRAINT reward_catalog_supplier_id_fkey FOREIGN KEY (supplier_id) REFERENCES suppliers(id) NOT VALID;
+
+CREATE INDEX idx_reward_catalog_supplier ON reward_catalog(tenant_id, supplier_id)
+  WHERE supplier_id IS NOT NULL;
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE suppliers ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_suppliers
+  ON suppliers
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE suppliers FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,48 @@
+-- Physical item fulfilment
+-- Version: 1.0
+-- Date: 2025-12-29
+
+-- =============================================================================
+-- FULFILMENTS TABLE
+-- =============================================================================
+
+-- Delivery of an issued physical_item reward, opened when the issuance is
+-- issued. Status only moves forward: pending -> picked -> dispatched ->
+-- delivered; each step's time is kept. A fulfilment not delivered by due_at
+-- is escalated once, recorded in escalated_at.
+CREATE TABLE fulfilments (
+  id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id     uuid NOT NULL REFERENCES tenants(id),
+  issuance_id   uuid NOT NULL UNIQUE REFERENCES issuances(id),
+  customer_id   uuid NOT NULL REFERENCES customers(id),
+  status        text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending','picked','dispatched','delivered')),
+  carrier       text,
+  tracking_ref  text,
+  note          text,
+  due_at        timestamptz NOT NULL,
+  picked_at     timestamptz,
+  dispatched_at timestamptz,
+  delivered_at  timestamptz,
+  escalated_at  timestamptz,
+  updated_by    uuid REFERENCES staff_users(id),
+  created_at    timestamptz NOT NULL DEFAULT now(),
+  updated_at    timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_fulfilments_tenant_status ON fulfilments(tenant_id, status, due_at);
+CREATE INDEX idx_fulfilments_tenant_customer ON fulfilments(tenant_id, customer_id, created_at DESC);
+CREATE INDEX idx_fulfilments_overdue ON fulfilments(tenant_id, due_at)
+  WHERE status <> 'delivered' AND escalated_at IS NULL;
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE fulfilments ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_fulfilments
+  ON fulfilments
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE fulfilments FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,15 @@
+-- Event coordinates for geo-fenced rules
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- EVENT COORDINATES
+-- =============================================================================
+
+-- Where the customer was when the event happened, e.g. a check-in from the
+-- mobile app. Rules compare it against the tenant's store coordinates with the
+-- within_radius operator.
+ALTER TABLE events
+  ADD COLUMN latitude  double precision CHECK (latitude BETWEEN -90 AND 90),
+  ADD COLUMN longitude double precision CHECK (longitude BETWEEN -180 AND 180),
+  ADD CONSTRAINT events_coordinates_pair CHECK ((latitude IS NULL) = (longitude IS NULL));
@@ -0,0 +1,13 @@
+-- Tenant timezone
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- TENANT SETTINGS
+-- =============================================================================
+
+-- The IANA time zone the tenant trades in, e.g. Africa/Harare. Time-of-day
+-- and day-of-week rules are evaluated in it. Configured with
+-- `loyaltyctl set-timezone`.
+ALTER TABLE tenants
+  ADD COLUMN timezone text NOT NULL DEFAULT 'Africa/Harare';
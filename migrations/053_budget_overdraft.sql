@@ -0,0 +1,244 @@
+-- Budget overdraft
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- BUDGET SETTINGS
+-- =============================================================================
+
+-- How far reservations for triggered rewards may take a budget past its hard
+-- cap. 0 (the default) keeps the hard cap strict. Commitments never use the
+-- overdraft.
+ALTER TABLE budgets
+  ADD COLUMN overdraft_limit numeric(18,2) NOT NULL DEFAULT 0 CHECK (overdraft_limit >= 0);
+
+-- =============================================================================
+-- LEDGER ENTRY TYPES
+-- =============================================================================
+
+-- 'overdraft' entries record the part of a reservation that went past the
+-- budget's hard cap. The reservation itself is posted as usual, so overdraft
+-- entries don't count towards the balance; they sum to the overdraft a budget
+-- has drawn.
+ALTER TABLE ledger_entries DROP CONSTRAINT ledger_entries_entry_type_check;
+ALTER TABLE ledger_entries ADD CONSTRAINT ledger_entries_entry_type_check
+  CHECK (entry_type IN ('fund','reserve','release','charge','expire','reverse','commit','overdraft'));
+
+-- =============================================================================
+-- BUDGET FUNCTIONS
+-- =============================================================================
+
+DROP FUNCTION reserve_budget_from(uuid, uuid, numeric, text, uuid, uuid);
+DROP FUNCTION reserve_committed_budget(uuid, uuid, uuid, numeric, text, uuid, uuid);
+
+-- Record the part of a reservation past the hard cap, if any
+CREATE OR REPLACE FUNCTION record_budget_overdraft(
+  p_tenant_id uuid,
+  p_budget_id uuid,
+  p_balance numeric,
+  p_added numeric,
+  p_hard_cap numeric,
+  p_currency text,
+  p_ref_id uuid
+) RETURNS void AS $$
+DECLARE
+  v_over numeric;
+BEGIN
+  v_over := LEAST(p_added, p_balance + p_added - p_hard_cap);
+  IF v_over > 0 THEN
+    INSERT INTO ledger_entries (tenant_id, budget_id, entry_type, currency, amount, ref_type, ref_id)
+    VALUES (p_tenant_id, p_budget_id, 'overdraft', p_currency, v_over, 'issuance', p_ref_id);
+  END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Reserve from a budget, recording the primary budget it fell back from.
+-- With p_allow_overdraft the budget may go past its hard cap by up to its
+-- overdraft limit.
+CREATE OR REPLACE FUNCTION reserve_budget_from(
+  p_tenant_id uuid,
+  p_budget_id uuid,
+  p_amount numeric,
+  p_currency text,
+  p_ref_id uuid,
+  p_fallback_from uuid,
+  p_allow_overdraft boolean
+) RETURNS boolean AS $$
+DECLARE
+  v_balance numeric;
+  v_hard_cap numeric;
+  v_limit numeric;
+BEGIN
+  -- Lock the budget row for update
+  SELECT balance, hard_cap, CASE WHEN p_allow_overdraft THEN hard_cap + overdraft_limit ELSE hard_cap END
+  INTO v_balance, v_hard_cap, v_limit
+  FROM budgets
+  WHERE id = p_budget_id AND tenant_id = p_tenant_id
+  FOR UPDATE;
+
+  -- Check if budget exists
+  IF NOT FOUND THEN
+    RAISE EXCEPTION 'Budget not found: %', p_budget_id;
+  END IF;
+
+  -- Check capacity
+  IF (v_balance + p_amount) > v_limit THEN
+    RETURN false;
+  END IF;
+
+  -- Update balance
+  UPDATE budgets
+  SET balance = balance + p_amount
+  WHERE id = p_budget_id AND tenant_id = p_tenant_id;
+
+  -- Insert ledger entry
+  INSERT INTO ledger_entries (tenant_id, budget_id, entry_type, currency, amount, ref_type, ref_id, fallback_from)
+  VALUES (p_tenant_id, p_budget_id, 'reserve', p_currency, p_amount, 'issuance', p_ref_id, p_fallback_from);
+
+  PERFORM record_budget_overdraft(p_tenant_id, p_budget_id, v_balance, p_amount, v_hard_cap, p_currency, p_ref_id);
+
+  RETURN true;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Reservations made directly against a budget are for rewards already
+-- triggered, so they may use its overdraft
+CREATE OR REPLACE FUNCTION reserve_budget(
+  p_tenant_id uuid,
+  p_budget_id uuid,
+  p_amount numeric,
+  p_currency text,
+  p_ref_id uuid
+) RETURNS boolean AS $$
+BEGIN
+  RETURN reserve_budget_from(p_tenant_id, p_budget_id, p_amount, p_currency, p_ref_id, NULL, true);
+END;
+$$ LANGUAGE plpgsql;
+
+-- Reserve from a budget for one of a campaign's issuances, drawing first on
+-- the campaign's open commitments against the budget. The drawn part is
+-- already counted in the balance, so only the rest needs capacity. With
+-- p_allow_overdraft the rest may use the budget's overdraft.
+CREATE OR REPLACE FUNCTION reserve_committed_budget(
+  p_tenant_id uuid,
+  p_budget_id uuid,
+  p_campaign_id uuid,
+  p_amount numeric,
+  p_currency text,
+  p_ref_id uuid,
+  p_fallback_from uuid,
+  p_allow_overdraft boolean
+) RETURNS boolean AS $$
+DECLARE
+  v_balance numeric;
+  v_hard_cap numeric;
+  v_limit numeric;
+  v_committed numeric;
+  v_needed numeric;
+  v_draw numeric;
+  v_commitment record;
+BEGIN
+  SELECT balance, hard_cap, CASE WHEN p_allow_overdraft THEN hard_cap + overdraft_limit ELSE hard_cap END
+  INTO v_balance, v_hard_cap, v_limit
+  FROM budgets
+  WHERE id = p_budget_id AND tenant_id = p_tenant_id
+  FOR UPDATE;
+
+  IF NOT FOUND THEN
+    RAISE EXCEPTION 'Budget not found: %', p_budget_id;
+  END IF;
+
+  PERFORM 1 FROM budget_commitments
+  WHERE tenant_id = p_tenant_id AND budget_id = p_budget_id
+    AND campaign_id = p_campaign_id AND status = 'open' AND currency = p_currency
+  FOR UPDATE;
+
+  SELECT COALESCE(SUM(remaining), 0) INTO v_committed
+  FROM budget_commitments
+  WHERE tenant_id = p_tenant_id AND budget_id = p_budget_id
+    AND campaign_id = p_campaign_id AND status = 'open' AND currency = p_currency;
+
+  v_needed := LEAST(v_committed, p_amount);
+
+  IF (v_balance + p_amount - v_needed) > v_limit THEN
+    RETURN false;
+  END IF;
+
+  -- Draw the oldest commitments first
+  FOR v_commitment IN
+    SELECT id, remaining
+    FROM budget_commitments
+    WHERE tenant_id = p_tenant_id AND budget_id = p_budget_id
+      AND campaign_id = p_campaign_id AND status = 'open' AND currency = p_currency
+    ORDER BY created_at, id
+  LOOP
+    EXIT WHEN v_needed <= 0;
+    v_draw := LEAST(v_commitment.remaining, v_needed);
+
+    UPDATE budget_commitments
+    SET remaining = remaining - v_draw,
+        status = CASE WHEN remaining - v_draw = 0 THEN 'converted' ELSE status END
+    WHERE id = v_commitment.id;
+
+    INSERT INTO ledger_entries (tenant_id, budget_id, entry_type, currency, amount, ref_type, ref_id)
+    VALUES (p_tenant_id, p_budget_id, 'commit', p_currency, -v_draw, 'commitment', v_commitment.id);
+
+    v_needed := v_needed - v_draw;
+  END LOOP;
+
+  UPDATE budgets
+  SET balance = balance + p_amount - LEAST(v_committed, p_amount)
+  WHERE id = p_budget_id AND tenant_id = p_tenant_id;
+
+  INSERT INTO ledger_entries (tenant_id, budget_id, entry_type, currency, amount, ref_type, ref_id, fallback_from)
+  VALUES (p_tenant_id, p_budget_id, 'reserve', p_currency, p_amount, 'issuance', p_ref_id, p_fallback_from);
+
+  PERFORM record_budget_overdraft(p_tenant_id, p_budget_id, v_balance,
+    p_amount - LEAST(v_committed, p_amount), v_hard_cap, p_currency, p_ref_id);
+
+  RETURN true;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Try the campaign's budgets in order within their hard caps first, and only
+-- then their overdrafts, so a fallback budget with room is preferred to
+-- overdrawing the primary one
+CREATE OR REPLACE FUNCTION reserve_campaign_budget(
+  p_tenant_id uuid,
+  p_campaign_id uuid,
+  p_amount numeric,
+  p_currency text,
+  p_ref_id uuid
+) RETURNS uuid AS $$
+DECLARE
+  v_primary uuid;
+  v_budget uuid;
+  v_overdraft boolean;
+BEGIN
+  SELECT budget_id INTO v_primary
+  FROM campaigns
+  WHERE id = p_campaign_id AND tenant_id = p_tenant_id;
+
+  FOREACH v_overdraft IN ARRAY ARRAY[false, true] LOOP
+    FOR v_budget IN
+      SELECT b.budget_id
+      FROM (
+        SELECT v_primary AS budget_id, -1 AS position
+        WHERE v_primary IS NOT NULL
+        UNION ALL
+        SELECT cb.budget_id, cb.position
+        FROM campaign_budgets cb
+        WHERE cb.tenant_id = p_tenant_id AND cb.campaign_id = p_campaign_id
+      ) b
+      ORDER BY b.position
+    LOOP
+      IF reserve_committed_budget(p_tenant_id, v_budget, p_campaign_id, p_amount, p_currency, p_ref_id,
+           CASE WHEN v_budget IS DISTINCT FROM v_primary THEN v_primary END, v_overdraft) THEN
+        RETURN v_budget;
+      END IF;
+    END LOOP;
+  END LOOP;
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
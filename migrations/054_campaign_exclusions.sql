@@ -0,0 +1,35 @@
+-- Campaign exclusions
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- CAMPAIGN EXCLUSIONS TABLE
+-- =============================================================================
+
+-- Customers a campaign must not reward, e.g. staff or customers already
+-- compensated another way. The rules engine skips a campaign's rules for an
+-- excluded customer even when they trigger.
+CREATE TABLE campaign_exclusions (
+  id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id   uuid NOT NULL REFERENCES tenants(id),
+  campaign_id uuid NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+  customer_id uuid NOT NULL REFERENCES customers(id),
+  reason      text,
+  created_at  timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (campaign_id, customer_id)
+);
+
+CREATE INDEX idx_campaign_exclusions_tenant_campaign ON campaign_exclusions(tenant_id, campaign_id, created_at DESC);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE campaign_exclusions ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_campaign_exclusions
+  ON campaign_exclusions
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE campaign_exclusions FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,34 @@
+-- Feature flags
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- FEATURE FLAGS TABLE
+-- =============================================================================
+
+-- Per-tenant, per-channel overrides of customer-facing features such as
+-- referrals or surveys, so a feature can be rolled out tenant by tenant
+-- without a deployment. Features without a row use their built-in default.
+CREATE TABLE feature_flags (
+  id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id  uuid NOT NULL REFERENCES tenants(id),
+  channel    text NOT NULL CHECK (channel IN ('whatsapp', 'ussd')),
+  feature    text NOT NULL,
+  enabled    boolean NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (tenant_id, channel, feature)
+);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE feature_flags ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_feature_flags
+  ON feature_flags
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE feature_flags FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,38 @@
+-- Customer impersonations
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- CUSTOMER IMPERSONATIONS TABLE
+-- =============================================================================
+
+-- Support staff see the customer portal as a customer sees it with a
+-- read-only, time-limited customer token. Each impersonation records who
+-- started it and why; it can be ended before it expires, after which its
+-- token is rejected. Starting, ending and every request made with the token
+-- are written to audit_logs.
+CREATE TABLE customer_impersonations (
+  id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id     uuid NOT NULL REFERENCES tenants(id),
+  customer_id   uuid NOT NULL REFERENCES customers(id),
+  staff_user_id uuid NOT NULL REFERENCES staff_users(id),
+  reason        text NOT NULL CHECK (length(trim(reason)) > 0),
+  expires_at    timestamptz NOT NULL,
+  ended_at      timestamptz,
+  created_at    timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_customer_impersonations_customer ON customer_impersonations(tenant_id, customer_id, created_at DESC);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE customer_impersonations ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_customer_impersonations
+  ON customer_impersonations
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE customer_impersonations FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,42 @@
+-- Uploads
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- UPLOADS TABLE
+-- =============================================================================
+
+-- Files staff upload, such as voucher code CSVs, kept in the configured
+-- object store (local disk or S3) so what was uploaded can be audited and
+-- processed again. storage_key locates the file in the store; entity_type
+-- and entity_id link it to what it changed, e.g. the voucher reward.
+CREATE TABLE uploads (
+  id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id    uuid NOT NULL REFERENCES tenants(id),
+  kind         text NOT NULL,
+  filename     text NOT NULL,
+  content_type text NOT NULL,
+  size_bytes   bigint NOT NULL CHECK (size_bytes >= 0),
+  sha256       text NOT NULL,
+  storage_key  text NOT NULL UNIQUE,
+  entity_type  text,
+  entity_id    uuid,
+  uploaded_by  uuid REFERENCES staff_users(id),
+  created_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_uploads_tenant_created ON uploads(tenant_id, created_at DESC);
+CREATE INDEX idx_uploads_entity ON uploads(tenant_id, entity_type, entity_id) WHERE entity_id IS NOT NULL;
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE uploads ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_uploads
+  ON uploads
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE uploads FORCE ROW LEVEL SECURITY;
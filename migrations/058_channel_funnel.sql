@@ -0,0 +1,43 @@
+-- Channel Funnel Sessions
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- CHANNEL FUNNEL SESSIONS TABLE
+-- =============================================================================
+
+-- One row per customer session on a channel, recording how far through the
+-- flow it got: sessions start at the main menu, reach a menu by choosing an
+-- option, then may attempt and complete a redemption. last_step is the menu
+-- the session was last on, which for sessions the customer abandoned is
+-- where they dropped off. ended_at is set when the channel ended the
+-- session itself rather than the customer walking away.
+CREATE TABLE channel_funnel_sessions (
+  id                      uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id               uuid NOT NULL REFERENCES tenants(id),
+  channel                 text NOT NULL CHECK (channel IN ('ussd')),
+  session_ref             text NOT NULL,
+  last_step               text NOT NULL,
+  menu_reached_at         timestamptz,
+  redemption_attempted_at timestamptz,
+  redemption_completed_at timestamptz,
+  ended_at                timestamptz,
+  started_at              timestamptz NOT NULL DEFAULT now(),
+  updated_at              timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (tenant_id, channel, session_ref)
+);
+
+CREATE INDEX idx_channel_funnel_sessions_started ON channel_funnel_sessions(tenant_id, channel, started_at);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE channel_funnel_sessions ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_channel_funnel_sessions
+  ON channel_funnel_sessions
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE channel_funnel_sessions FORCE ROW LEVEL SECURITY;
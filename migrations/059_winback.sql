@@ -0,0 +1,52 @@
+-- Win-back automation
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- TENANT SETTINGS
+-- =============================================================================
+
+-- Customers with no events for winback_dormant_days get a customer_dormant
+-- event, which rules can grant a win-back reward on, and the
+-- winback_template WhatsApp template if one is set and they have consented.
+-- A customer is targeted once per dormant spell and at most once every
+-- winback_cooldown_days. NULL winback_dormant_days turns the automation off.
+-- Configured with `loyaltyctl set-winback`.
+ALTER TABLE tenants
+  ADD COLUMN winback_dormant_days int CHECK (winback_dormant_days > 0),
+  ADD COLUMN winback_cooldown_days int NOT NULL DEFAULT 90 CHECK (winback_cooldown_days > 0),
+  ADD COLUMN winback_template text;
+
+-- =============================================================================
+-- WIN-BACK CONTACTS TABLE
+-- =============================================================================
+
+-- One row per customer targeted, which is what suppresses targeting them
+-- again. event_id is their customer_dormant event and last_activity_at the
+-- last event before it. message_status is how the WhatsApp template went:
+-- sent, failed, no_consent or no_phone, and NULL when none was due because
+-- the tenant has no template or WhatsApp isn't configured.
+CREATE TABLE winback_contacts (
+  id                uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id         uuid NOT NULL REFERENCES tenants(id),
+  customer_id       uuid NOT NULL REFERENCES customers(id),
+  event_id          uuid NOT NULL UNIQUE REFERENCES events(id),
+  last_activity_at  timestamptz NOT NULL,
+  message_status    text CHECK (message_status IN ('sent', 'failed', 'no_consent', 'no_phone')),
+  created_at        timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_winback_contacts_customer ON winback_contacts(tenant_id, customer_id, created_at DESC);
+CREATE INDEX idx_winback_contacts_created ON winback_contacts(tenant_id, created_at DESC);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY
+-- =============================================================================
+
+ALTER TABLE winback_contacts ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_winback_contacts ON winback_contacts
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE winback_contacts FORCE ROW LEVEL SECURITY;
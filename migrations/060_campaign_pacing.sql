@@ -0,0 +1,15 @@
+-- Campaign budget pacing
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- CAMPAIGN SETTINGS
+-- =============================================================================
+
+-- The most a campaign may spend, in cost of the issuances its rules reserve,
+-- per day and per week in the tenant's time zone; weeks start on Monday.
+-- Rules stop issuing once a trigger would take the campaign past either
+-- limit and resume in the next period. NULL leaves the period unlimited.
+ALTER TABLE campaigns
+  ADD COLUMN max_spend_per_day numeric(18,2) CHECK (max_spend_per_day > 0),
+  ADD COLUMN max_spend_per_week numeric(18,2) CHECK (max_spend_per_week > 0);
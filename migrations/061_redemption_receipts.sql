@@ -0,0 +1,50 @@
+-- Redemption receipts
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- REDEMPTION RECEIPTS TABLE
+-- =============================================================================
+
+-- One row per redeemed issuance, written in the redemption's transaction, so
+-- disputes can be settled from what was redeemed, where and by whom. The
+-- reward's name and value are copied as they were at redemption. staff_ref
+-- is the POS staff reference of imported redemptions.
+-- confirmation_status is how the confirmation to the customer went: sent,
+-- failed, no_consent or no_phone for a WhatsApp message, in_channel when the
+-- customer redeemed through a channel that confirmed it in its reply, and
+-- NULL while pending, when WhatsApp isn't configured or for redemptions
+-- imported from a POS, which are synced after the fact.
+CREATE TABLE redemption_receipts (
+  id                   uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id            uuid NOT NULL REFERENCES tenants(id),
+  issuance_id          uuid NOT NULL UNIQUE REFERENCES issuances(id),
+  customer_id          uuid NOT NULL REFERENCES customers(id),
+  reward_id            uuid NOT NULL REFERENCES reward_catalog(id),
+  reward_name          text NOT NULL,
+  value                numeric(18,2),
+  currency             text,
+  store_id             text,
+  staff_user_id        uuid REFERENCES staff_users(id),
+  staff_ref            text,
+  redeemed_by          text NOT NULL,
+  channel              text NOT NULL,
+  redeemed_at          timestamptz NOT NULL,
+  confirmation_status  text CHECK (confirmation_status IN ('sent', 'failed', 'no_consent', 'no_phone', 'in_channel')),
+  confirmed_at         timestamptz,
+  created_at           timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_redemption_receipts_customer ON redemption_receipts(tenant_id, customer_id, redeemed_at DESC);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY
+-- =============================================================================
+
+ALTER TABLE redemption_receipts ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_redemption_receipts ON redemption_receipts
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE redemption_receipts FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,37 @@
+-- Staff notes
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- STAFF NOTES TABLE
+-- =============================================================================
+
+-- Internal notes staff attach to a customer, or to one of their issuances,
+-- to keep support interactions traceable. Notes are only served by the staff
+-- API and are never shown to customers. issuance_id is NULL for a note on the
+-- customer. Notes are append-only.
+CREATE TABLE staff_notes (
+  id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id    uuid NOT NULL REFERENCES tenants(id),
+  customer_id  uuid NOT NULL REFERENCES customers(id),
+  issuance_id  uuid REFERENCES issuances(id),
+  author_id    uuid NOT NULL REFERENCES staff_users(id),
+  body         text NOT NULL CHECK (length(body) BETWEEN 1 AND 2000),
+  created_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_staff_notes_customer ON staff_notes(tenant_id, customer_id, created_at DESC);
+CREATE INDEX idx_staff_notes_issuance ON staff_notes(tenant_id, issuance_id, created_at DESC)
+  WHERE issuance_id IS NOT NULL;
+
+-- =============================================================================
+-- ROW LEVEL SECURITY
+-- =============================================================================
+
+ALTER TABLE staff_notes ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_staff_notes ON staff_notes
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE staff_notes FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,38 @@
+-- Reissue of failed or lost rewards
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- ISSUANCE REISSUES
+-- =============================================================================
+
+-- A failed or issued issuance replaced by support with a new one carrying a
+-- fresh code. The original is cancelled and its budget released; the
+-- replacement reserves its own. An issuance is reissued at most once, but a
+-- replacement can itself be reissued.
+CREATE TABLE issuance_reissues (
+  id              uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id       uuid NOT NULL REFERENCES tenants(id),
+  original_id     uuid NOT NULL UNIQUE REFERENCES issuances(id),
+  replacement_id  uuid NOT NULL UNIQUE REFERENCES issuances(id),
+  customer_id     uuid NOT NULL REFERENCES customers(id),
+  reason_code     text NOT NULL CHECK (reason_code IN ('failed','lost','not_received','other')),
+  note            text,
+  created_by      uuid REFERENCES staff_users(id),
+  created_at      timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_issuance_reissues_customer ON issuance_reissues(tenant_id, customer_id, created_at DESC);
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE issuance_reissues ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_issuance_reissues
+  ON issuance_reissues
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE issuance_reissues FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,25 @@
+-- PII encryption
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- CUSTOMER PHONE BLIND INDEX
+-- =============================================================================
+
+-- Phone numbers are encrypted by the application once PII_KEYS_JSON is
+-- configured (see internal/pii), so phone_e164 holds either a plaintext
+-- number or "enc:<kid>:<ciphertext>". Encrypted numbers can't be compared,
+-- so customers are looked up, and kept unique, by phone_bidx: the number's
+-- HMAC under the key it was encrypted with. Numbers stored before then are
+-- indexed by their SHA-256 digest until loyaltyctl rotate-pii encrypts them.
+ALTER TABLE customers ADD COLUMN phone_bidx bytea;
+
+UPDATE customers
+SET phone_bidx = sha256(convert_to(phone_e164, 'UTF8'))
+WHERE phone_e164 IS NOT NULL;
+
+CREATE UNIQUE INDEX idx_customers_phone_bidx ON customers(tenant_id, phone_bidx);
+
+-- The number itself is no longer unique or looked up once it's encrypted
+ALTER TABLE customers DROP CONSTRAINT customers_tenant_id_phone_e164_key;
+DROP INDEX idx_customers_phone;
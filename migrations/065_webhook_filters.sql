@@ -0,0 +1,12 @@
+-- Webhook filters
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- WEBHOOK FILTERS
+-- =============================================================================
+
+-- An endpoint receives the event types in its events, optionally narrowed by
+-- a JsonLogic filter evaluated against each event's data, e.g.
+-- {">": [{"var": "face_amount"}, 50]}. NULL delivers every subscribed event.
+ALTER TABLE webhooks ADD COLUMN filter jsonb;
@@ -0,0 +1,114 @@
+-- Change feed for polling integrations
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- ENTITY CHANGES
+-- =============================================================================
+
+-- A record of each change to a customer or issuance, and of each redemption,
+-- read in order by GET /v1/tenants/:tid/changes so no-code tools (Zapier,
+-- Make) can poll for what changed since their last cursor. Rows are written
+-- by triggers in the same transaction as the change.
+--
+-- Ids are assigned before commit, so a smaller id can commit after a larger
+-- one. The feed is ordered by (txid, id) instead and only returns rows of
+-- transactions older than every running one, whose rows are all final.
+CREATE TABLE entity_changes (
+  id           bigserial PRIMARY KEY,
+  tenant_id    uuid NOT NULL REFERENCES tenants(id),
+  entity_type  text NOT NULL CHECK (entity_type IN ('customer','issuance','redemption')),
+  entity_id    uuid NOT NULL,
+  operation    text NOT NULL CHECK (operation IN ('created','updated')),
+  data         jsonb NOT NULL,
+  txid         bigint NOT NULL DEFAULT pg_current_xact_id()::text::bigint,
+  changed_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_entity_changes_feed ON entity_changes(tenant_id, txid, id);
+CREATE INDEX idx_entity_changes_changed ON entity_changes(tenant_id, changed_at);
+
+-- =============================================================================
+-- WRITING CHANGES
+-- =============================================================================
+
+-- Customer phone numbers are copied as stored, encrypted or not; the API
+-- decrypts them. Rotating PII keys doesn't change a customer, so phone
+-- updates aren't recorded.
+CREATE OR REPLACE FUNCTION record_customer_change()
+RETURNS trigger AS $$
+BEGIN
+  INSERT INTO entity_changes (tenant_id, entity_type, entity_id, operation, data)
+  VALUES (NEW.tenant_id, 'customer', NEW.id,
+    CASE WHEN TG_OP = 'INSERT' THEN 'created' ELSE 'updated' END,
+    jsonb_build_object(
+      'customer_id', NEW.id,
+      'phone_e164', NEW.phone_e164,
+      'external_ref', NEW.external_ref,
+      'name', NEW.name,
+      'status', NEW.status,
+      'flagged_at', NEW.flagged_at,
+      'created_at', NEW.created_at
+    ));
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER customers_changes
+  AFTER INSERT OR UPDATE OF external_ref, name, status, flagged_at ON customers
+  FOR EACH ROW EXECUTE FUNCTION record_customer_change();
+
+-- Issuances are recorded when created and when their status changes; moving
+-- to redeemed also records a redemption
+CREATE OR REPLACE FUNCTION record_issuance_change()
+RETURNS trigger AS $$
+DECLARE
+  v_data jsonb := jsonb_build_object(
+    'issuance_id', NEW.id,
+    'customer_id', NEW.customer_id,
+    'campaign_id', NEW.campaign_id,
+    'reward_id', NEW.reward_id,
+    'status', NEW.status,
+    'currency', NEW.currency,
+    'face_amount', NEW.face_amount,
+    'issued_at', NEW.issued_at,
+    'expires_at', NEW.expires_at,
+    'redeemed_at', NEW.redeemed_at
+  );
+BEGIN
+  IF TG_OP = 'UPDATE' AND NEW.status = OLD.status THEN
+    RETURN NEW;
+  END IF;
+
+  INSERT INTO entity_changes (tenant_id, entity_type, entity_id, operation, data)
+  VALUES (NEW.tenant_id, 'issuance', NEW.id,
+    CASE WHEN TG_OP = 'INSERT' THEN 'created' ELSE 'updated' END, v_data);
+
+  IF NEW.status = 'redeemed' AND (TG_OP = 'INSERT' OR OLD.status <> 'redeemed') THEN
+    INSERT INTO entity_changes (tenant_id, entity_type, entity_id, operation, data)
+    VALUES (NEW.tenant_id, 'redemption', NEW.id, 'created', v_data || jsonb_build_object(
+      'redemption_source', NEW.redemption_source,
+      'redeemed_store_id', NEW.redeemed_store_id,
+      'redeemed_staff_ref', NEW.redeemed_staff_ref
+    ));
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER issuances_changes
+  AFTER INSERT OR UPDATE OF status ON issuances
+  FOR EACH ROW EXECUTE FUNCTION record_issuance_change();
+
+-- =============================================================================
+-- ROW LEVEL SECURITY (RLS)
+-- =============================================================================
+
+ALTER TABLE entity_changes ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_entity_changes
+  ON entity_changes
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE entity_changes FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,94 @@
+-- Welcome series automation
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- TENANT SETTINGS
+-- =============================================================================
+
+-- With welcome_series_enabled, each customer enrolled gets a welcome series:
+-- the welcome_template WhatsApp template straight away, the
+-- welcome_reminder_template after welcome_reminder_days if they haven't made
+-- a purchase by then, and welcome_bonus_days in which rules using the
+-- in_welcome_window operator (the first_purchase_bonus campaign template)
+-- apply. Either template may be unset to skip that message. Configured with
+-- `loyaltyctl set-welcome-series`.
+ALTER TABLE tenants
+  ADD COLUMN welcome_series_enabled boolean NOT NULL DEFAULT false,
+  ADD COLUMN welcome_template text,
+  ADD COLUMN welcome_reminder_template text,
+  ADD COLUMN welcome_reminder_days int NOT NULL DEFAULT 3 CHECK (welcome_reminder_days > 0),
+  ADD COLUMN welcome_bonus_days int NOT NULL DEFAULT 14 CHECK (welcome_bonus_days > 0);
+
+-- =============================================================================
+-- WELCOME SERIES TABLE
+-- =============================================================================
+
+-- One row per customer enrolled while their tenant had the series on. The
+-- schedule is fixed at enrollment, so changing the tenant's settings only
+-- affects customers enrolled afterwards. welcome_status and reminder_status
+-- are NULL until the step is done, then how it went: sent, failed,
+-- no_consent, no_phone, skipped when there's no template or WhatsApp isn't
+-- configured, or, for the reminder, purchased when the customer had already
+-- made a purchase. The series is completed once both messages are done and
+-- the bonus window has ended.
+CREATE TABLE welcome_series (
+  id                 uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id          uuid NOT NULL REFERENCES tenants(id),
+  customer_id        uuid NOT NULL REFERENCES customers(id),
+  enrolled_at        timestamptz NOT NULL,
+  reminder_due_at    timestamptz NOT NULL,
+  bonus_ends_at      timestamptz NOT NULL,
+  welcome_status     text CHECK (welcome_status IN ('sent', 'failed', 'no_consent', 'no_phone', 'skipped')),
+  welcome_sent_at    timestamptz,
+  reminder_status    text CHECK (reminder_status IN ('sent', 'failed', 'no_consent', 'no_phone', 'skipped', 'purchased')),
+  reminder_sent_at   timestamptz,
+  first_purchase_at  timestamptz,
+  completed_at       timestamptz,
+  created_at         timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (tenant_id, customer_id)
+);
+
+CREATE INDEX idx_welcome_series_open ON welcome_series(tenant_id, enrolled_at)
+  WHERE completed_at IS NULL;
+
+-- =============================================================================
+-- ENROLLMENT
+-- =============================================================================
+
+-- Customers are enrolled as they are created, however they are created
+-- (API, USSD, WhatsApp or imports)
+CREATE OR REPLACE FUNCTION enroll_welcome_series()
+RETURNS trigger AS $$
+DECLARE
+  v_tenant tenants%ROWTYPE;
+BEGIN
+  SELECT * INTO v_tenant FROM tenants WHERE id = NEW.tenant_id;
+  IF NOT v_tenant.welcome_series_enabled THEN
+    RETURN NEW;
+  END IF;
+
+  INSERT INTO welcome_series (tenant_id, customer_id, enrolled_at, reminder_due_at, bonus_ends_at)
+  VALUES (NEW.tenant_id, NEW.id, NEW.created_at,
+    NEW.created_at + make_interval(days => v_tenant.welcome_reminder_days),
+    NEW.created_at + make_interval(days => v_tenant.welcome_bonus_days))
+  ON CONFLICT (tenant_id, customer_id) DO NOTHING;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER customers_welcome_series
+  AFTER INSERT ON customers
+  FOR EACH ROW EXECUTE FUNCTION enroll_welcome_series();
+
+-- =============================================================================
+-- ROW LEVEL SECURITY
+-- =============================================================================
+
+ALTER TABLE welcome_series ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_welcome_series ON welcome_series
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE welcome_series FORCE ROW LEVEL SECURITY;
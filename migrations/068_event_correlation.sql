@@ -0,0 +1,15 @@
+-- Event correlation index
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- CORRELATION INDEX
+-- =============================================================================
+
+-- The happened_after and happened_before rule operators look for another
+-- event of a given type of the customer near the one being processed. The
+-- same index serves nth_event_in_period's and customer.total_visits' counts
+-- of a customer's events of one type.
+CREATE INDEX idx_events_tenant_customer_type_occurred
+  ON events(tenant_id, customer_id, event_type, occurred_at)
+  WHERE customer_id IS NOT NULL;
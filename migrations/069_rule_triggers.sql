@@ -0,0 +1,46 @@
+-- Idempotent rule triggers
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- RULE TRIGGERS TABLE
+-- =============================================================================
+
+-- One row per rule an event triggered, inserted in the transaction that
+-- creates the trigger's issuances. The primary key makes issuing idempotent
+-- per (event, rule): replaying an event (dead letter retries, replay-events)
+-- returns the trigger's existing issuances instead of issuing again, and of
+-- two concurrent attempts the second waits for the first and sees its
+-- issuances. A constraint on issuances themselves wouldn't do, as a trigger
+-- issues one row per reward and quantity of the rule's action set and
+-- reissues copy the event and rule of the issuance they replace.
+CREATE TABLE rule_triggers (
+  event_id    uuid NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+  rule_id     uuid NOT NULL REFERENCES rules(id) ON DELETE CASCADE,
+  tenant_id   uuid NOT NULL REFERENCES tenants(id),
+  created_at  timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (event_id, rule_id)
+);
+
+-- Triggers of issuances made before this migration
+INSERT INTO rule_triggers (event_id, rule_id, tenant_id, created_at)
+SELECT event_id, rule_id, tenant_id, COALESCE(MIN(issued_at), now())
+FROM issuances
+WHERE event_id IS NOT NULL AND rule_id IS NOT NULL
+GROUP BY event_id, rule_id, tenant_id
+ON CONFLICT DO NOTHING;
+
+CREATE INDEX idx_issuances_event_rule ON issuances(event_id, rule_id)
+  WHERE event_id IS NOT NULL;
+
+-- =============================================================================
+-- ROW LEVEL SECURITY
+-- =============================================================================
+
+ALTER TABLE rule_triggers ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_rule_triggers ON rule_triggers
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE rule_triggers FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,48 @@
+-- Budget alert deduplication
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- BUDGET ALERT SETTINGS
+-- =============================================================================
+
+-- How long an alert that stays active waits before it is sent again. NULL
+-- means the platform default (60 minutes).
+ALTER TABLE budgets
+  ADD COLUMN alert_repeat_minutes integer
+    CHECK (alert_repeat_minutes IS NULL OR alert_repeat_minutes > 0);
+
+-- =============================================================================
+-- BUDGET ALERT STATES TABLE
+-- =============================================================================
+
+-- One row per budget and threshold alert type. An alert is active from the
+-- time it is triggered until utilization drops back below its threshold,
+-- when resolved_at is set and a resolved notification is sent. While active
+-- it is sent again only once last_alerted_at is older than the budget's
+-- repeat interval, however many reservations cross the threshold.
+CREATE TABLE budget_alert_states (
+  budget_id        uuid NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+  alert_type       text NOT NULL,
+  tenant_id        uuid NOT NULL REFERENCES tenants(id),
+  triggered_at     timestamptz NOT NULL,
+  last_alerted_at  timestamptz NOT NULL,
+  alert_count      integer NOT NULL DEFAULT 1,
+  resolved_at      timestamptz,
+  PRIMARY KEY (budget_id, alert_type)
+);
+
+CREATE INDEX idx_budget_alert_states_active ON budget_alert_states(budget_id)
+  WHERE resolved_at IS NULL;
+
+-- =============================================================================
+-- ROW LEVEL SECURITY
+-- =============================================================================
+
+ALTER TABLE budget_alert_states ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_budget_alert_states ON budget_alert_states
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE budget_alert_states FORCE ROW LEVEL SECURITY;
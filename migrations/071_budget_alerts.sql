@@ -0,0 +1,45 @@
+-- Budget alert history and acknowledgement
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- BUDGET ALERTS TABLE
+-- =============================================================================
+
+-- Every budget alert sent, so ops teams can track which incidents were
+-- handled. An alert is acknowledged once, by a staff user with an optional
+-- note on what was done about it.
+CREATE TABLE budget_alerts (
+  id                   uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id            uuid NOT NULL REFERENCES tenants(id),
+  budget_id            uuid NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+  alert_type           text NOT NULL,
+  level                text NOT NULL CHECK (level IN ('info','warning','critical')),
+  message              text NOT NULL,
+  currency             text NOT NULL,
+  balance              numeric(18,2) NOT NULL,
+  utilization          numeric(18,2) NOT NULL,
+  created_at           timestamptz NOT NULL DEFAULT now(),
+  acknowledged_at      timestamptz,
+  acknowledged_by      uuid REFERENCES staff_users(id),
+  acknowledgement_note text
+);
+
+CREATE INDEX idx_budget_alerts_tenant ON budget_alerts(tenant_id, created_at DESC);
+CREATE INDEX idx_budget_alerts_budget ON budget_alerts(budget_id, created_at DESC);
+
+-- The dashboard lists unacknowledged critical alerts
+CREATE INDEX idx_budget_alerts_unacknowledged ON budget_alerts(tenant_id, level, created_at DESC)
+  WHERE acknowledged_at IS NULL;
+
+-- =============================================================================
+-- ROW LEVEL SECURITY
+-- =============================================================================
+
+ALTER TABLE budget_alerts ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation_budget_alerts ON budget_alerts
+  USING (tenant_id = current_setting('app.tenant_id', true)::uuid)
+  WITH CHECK (tenant_id = current_setting('app.tenant_id', true)::uuid);
+
+ALTER TABLE budget_alerts FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,19 @@
+-- Tenant branding for channel messages
+-- Version: 1.0
+-- Date: 2025-12-30
+
+-- =============================================================================
+-- TENANT SETTINGS
+-- =============================================================================
+
+-- How the tenant's programme introduces itself on WhatsApp and USSD:
+-- program_name replaces the generic "Loyalty Program" in welcome, menu and
+-- help text, brand_emoji is shown with it on WhatsApp, support_phone is the
+-- number customers are told to call for help and message_footer is appended
+-- to WhatsApp welcome and help messages. Any of them may be unset to use the
+-- defaults. Configured with `loyaltyctl set-branding`.
+ALTER TABLE tenants
+  ADD COLUMN program_name text CHECK (length(program_name) BETWEEN 1 AND 60),
+  ADD COLUMN brand_emoji text CHECK (length(brand_emoji) BETWEEN 1 AND 16),
+  ADD COLUMN support_phone text CHECK (length(support_phone) BETWEEN 1 AND 32),
+  ADD COLUMN message_footer text CHECK (length(message_footer) BETWEEN 1 AND 200);
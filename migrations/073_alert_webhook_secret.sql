@@ -0,0 +1,15 @@
+-- Signed budget alert webhooks
+-- Version: 1.0
+-- Date: 2025-12-31
+
+-- =============================================================================
+-- TENANT SETTINGS
+-- =============================================================================
+
+-- Budget alert webhooks are signed with the tenant's alert webhook secret,
+-- as HMAC-SHA256 of the body in the X-Signature header, the same way webhook
+-- endpoint deliveries are. Every tenant gets a random secret; operators
+-- rotate it, and read the new one, with `loyaltyctl rotate-alert-secret`.
+ALTER TABLE tenants
+  ADD COLUMN alert_webhook_secret text NOT NULL
+    DEFAULT 'whsec_' || replace(gen_random_uuid()::text, '-', '') || replace(gen_random_uuid()::text, '-', '');
@@ -156,3 +156,48 @@ WHERE r.tenant_id = $1
   AND r.active = true
 GROUP BY r.id, r.name, r.event_type, r.per_user_cap, r.global_cap
 ORDER BY issuance_count DESC;
+
+-- name: GetIssuanceTimeseries :many
+-- Issuances bucketed by issue time. Interval is a date_trunc field.
+SELECT
+  date_trunc(sqlc.arg(bucket_interval)::text, issued_at, sqlc.arg(timezone)::text)::timestamptz AS bucket,
+  COALESCE(currency, '')::text AS currency,
+  COUNT(*) AS count,
+  COALESCE(SUM(cost_amount), 0)::numeric AS amount
+FROM issuances
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND issued_at >= sqlc.arg(from_time)
+  AND issued_at < sqlc.arg(to_time)
+  AND (sqlc.narg(campaign_id)::uuid IS NULL OR campaign_id = sqlc.narg(campaign_id))
+GROUP BY 1, 2
+ORDER BY 1, 2;
+
+-- name: GetRedemptionTimeseries :many
+-- Redemptions bucketed by redemption time. Interval is a date_trunc field.
+SELECT
+  date_trunc(sqlc.arg(bucket_interval)::text, redeemed_at, sqlc.arg(timezone)::text)::timestamptz AS bucket,
+  COALESCE(currency, '')::text AS currency,
+  COUNT(*) AS count,
+  COALESCE(SUM(face_amount), 0)::numeric AS amount
+FROM issuances
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND status = 'redeemed'
+  AND redeemed_at >= sqlc.arg(from_time)
+  AND redeemed_at < sqlc.arg(to_time)
+  AND (sqlc.narg(campaign_id)::uuid IS NULL OR campaign_id = sqlc.narg(campaign_id))
+GROUP BY 1, 2
+ORDER BY 1, 2;
+
+-- name: GetEventTimeseries :many
+-- Events bucketed by occurrence time. Interval is a date_trunc field.
+SELECT
+  date_trunc(sqlc.arg(bucket_interval)::text, occurred_at, sqlc.arg(timezone)::text)::timestamptz AS bucket,
+  COUNT(*) AS count,
+  COUNT(DISTINCT customer_id) AS unique_customers
+FROM events
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND occurred_at >= sqlc.arg(from_time)
+  AND occurred_at < sqlc.arg(to_time)
+  AND (sqlc.narg(event_type)::text IS NULL OR event_type = sqlc.narg(event_type))
+GROUP BY 1
+ORDER BY 1;
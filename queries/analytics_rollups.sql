@@ -0,0 +1,151 @@
+-- Analytics rollup queries
+-- sqlc query file for the daily rollup tables
+
+-- name: GetRollupState :one
+SELECT * FROM analytics_rollup_state
+WHERE tenant_id = $1;
+
+-- name: UpsertRollupState :exec
+INSERT INTO analytics_rollup_state (tenant_id, refreshed_at)
+VALUES ($1, $2)
+ON CONFLICT (tenant_id) DO UPDATE SET refreshed_at = EXCLUDED.refreshed_at;
+
+-- name: ResetRollupState :exec
+-- Forget the tenant's last refresh so the next run rebuilds every day, e.g.
+-- after its time zone changes
+DELETE FROM analytics_rollup_state
+WHERE tenant_id = $1;
+
+-- name: GetEarliestEventSince :one
+-- Earliest occurrence among events recorded since a time, so back-dated
+-- events reopen the days they fall in
+SELECT MIN(occurred_at)::timestamptz AS earliest
+FROM events
+WHERE tenant_id = $1
+  AND created_at >= $2;
+
+-- name: DeleteEventsDaily :exec
+DELETE FROM events_daily
+WHERE tenant_id = sqlc.arg(tenant_id) AND day >= sqlc.arg(from_day)::date;
+
+-- name: RefreshEventsDaily :exec
+INSERT INTO events_daily (tenant_id, day, event_type, event_count, unique_customers)
+SELECT
+  ev.tenant_id,
+  (ev.occurred_at AT TIME ZONE sqlc.arg(timezone)::text)::date,
+  ev.event_type,
+  COUNT(*),
+  COUNT(DISTINCT ev.customer_id)
+FROM events ev
+WHERE ev.tenant_id = sqlc.arg(tenant_id)
+  AND ev.occurred_at >= (sqlc.arg(from_day)::date)::timestamp AT TIME ZONE sqlc.arg(timezone)::text
+GROUP BY 1, 2, 3;
+
+-- name: DeleteIssuancesDaily :exec
+DELETE FROM issuances_daily
+WHERE tenant_id = sqlc.arg(tenant_id) AND day >= sqlc.arg(from_day)::date;
+
+-- name: RefreshIssuancesDaily :exec
+INSERT INTO issuances_daily
+  (tenant_id, day, campaign_id, currency, issued_count, issued_cost, redeemed_count, redeemed_face)
+SELECT m.tenant_id, m.day, m.campaign_id, m.currency,
+       SUM(m.issued_count), SUM(m.issued_cost), SUM(m.redeemed_count), SUM(m.redeemed_face)
+FROM (
+  SELECT i.tenant_id, (i.issued_at AT TIME ZONE sqlc.arg(timezone)::text)::date AS day, i.campaign_id,
+         COALESCE(i.currency, '') AS currency,
+         1 AS issued_count, COALESCE(i.cost_amount, 0) AS issued_cost,
+         0 AS redeemed_count, 0 AS redeemed_face
+  FROM issuances i
+  WHERE i.tenant_id = sqlc.arg(tenant_id)
+    AND i.issued_at >= (sqlc.arg(from_day)::date)::timestamp AT TIME ZONE sqlc.arg(timezone)::text
+  UNION ALL
+  SELECT r.tenant_id, (r.redeemed_at AT TIME ZONE sqlc.arg(timezone)::text)::date, r.campaign_id,
+         COALESCE(r.currency, ''),
+         0, 0,
+         1, COALESCE(r.face_amount, 0)
+  FROM issuances r
+  WHERE r.tenant_id = sqlc.arg(tenant_id)
+    AND r.status = 'redeemed'
+    AND r.redeemed_at >= (sqlc.arg(from_day)::date)::timestamp AT TIME ZONE sqlc.arg(timezone)::text
+) m
+GROUP BY m.tenant_id, m.day, m.campaign_id, m.currency;
+
+-- name: DeleteBudgetSpendDaily :exec
+DELETE FROM budget_spend_daily
+WHERE tenant_id = sqlc.arg(tenant_id) AND day >= sqlc.arg(from_day)::date;
+
+-- name: RefreshBudgetSpendDaily :exec
+INSERT INTO budget_spend_daily
+  (tenant_id, budget_id, day, currency, funded, reserved, charged, released, entry_count)
+SELECT
+  l.tenant_id,
+  l.budget_id,
+  (l.created_at AT TIME ZONE sqlc.arg(timezone)::text)::date,
+  l.currency,
+  COALESCE(SUM(l.amount) FILTER (WHERE l.entry_type = 'fund'), 0),
+  COALESCE(SUM(l.amount) FILTER (WHERE l.entry_type = 'reserve'), 0),
+  COALESCE(SUM(l.amount) FILTER (WHERE l.entry_type = 'charge'), 0),
+  COALESCE(SUM(l.amount) FILTER (WHERE l.entry_type = 'release'), 0),
+  COUNT(*)
+FROM ledger_entries l
+WHERE l.tenant_id = sqlc.arg(tenant_id)
+  AND l.created_at >= (sqlc.arg(from_day)::date)::timestamp AT TIME ZONE sqlc.arg(timezone)::text
+GROUP BY 1, 2, 3, 4;
+
+-- name: GetDailyTotals :one
+-- Dashboard counts for one day from the rollups
+SELECT
+  (SELECT COALESCE(SUM(event_count), 0) FROM events_daily e
+   WHERE e.tenant_id = sqlc.arg(tenant_id) AND e.day = sqlc.arg(day)::date)::bigint AS events,
+  (SELECT COALESCE(SUM(issued_count), 0) FROM issuances_daily i
+   WHERE i.tenant_id = sqlc.arg(tenant_id) AND i.day = sqlc.arg(day)::date)::bigint AS issued,
+  (SELECT COALESCE(SUM(redeemed_count), 0) FROM issuances_daily i
+   WHERE i.tenant_id = sqlc.arg(tenant_id) AND i.day = sqlc.arg(day)::date)::bigint AS redeemed;
+
+-- name: CountActiveCustomers :one
+SELECT COUNT(*) FROM customers
+WHERE tenant_id = $1 AND status = 'active';
+
+-- name: GetIssuanceRollupTimeseries :many
+SELECT
+  (date_trunc(sqlc.arg(bucket_interval)::text, day::timestamp) AT TIME ZONE sqlc.arg(timezone)::text)::timestamptz AS bucket,
+  currency,
+  SUM(issued_count)::bigint AS count,
+  SUM(issued_cost)::numeric AS amount
+FROM issuances_daily
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND day >= sqlc.arg(from_day)::date
+  AND day < sqlc.arg(to_day)::date
+  AND (sqlc.narg(campaign_id)::uuid IS NULL OR campaign_id = sqlc.narg(campaign_id))
+GROUP BY 1, 2
+HAVING SUM(issued_count) > 0
+ORDER BY 1, 2;
+
+-- name: GetRedemptionRollupTimeseries :many
+SELECT
+  (date_trunc(sqlc.arg(bucket_interval)::text, day::timestamp) AT TIME ZONE sqlc.arg(timezone)::text)::timestamptz AS bucket,
+  currency,
+  SUM(redeemed_count)::bigint AS count,
+  SUM(redeemed_face)::numeric AS amount
+FROM issuances_daily
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND day >= sqlc.arg(from_day)::date
+  AND day < sqlc.arg(to_day)::date
+  AND (sqlc.narg(campaign_id)::uuid IS NULL OR campaign_id = sqlc.narg(campaign_id))
+GROUP BY 1, 2
+HAVING SUM(redeemed_count) > 0
+ORDER BY 1, 2;
+
+-- name: GetEventRollupTimeseries :many
+-- Daily only: unique customers can't be summed across days
+SELECT
+  (day::timestamp AT TIME ZONE sqlc.arg(timezone)::text)::timestamptz AS bucket,
+  SUM(event_count)::bigint AS count,
+  SUM(unique_customers)::bigint AS unique_customers
+FROM events_daily
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND day >= sqlc.arg(from_day)::date
+  AND day < sqlc.arg(to_day)::date
+  AND (sqlc.narg(event_type)::text IS NULL OR event_type = sqlc.narg(event_type))
+GROUP BY 1
+ORDER BY 1;
@@ -0,0 +1,39 @@
+-- Approval queries
+-- sqlc query file for maker-checker approvals
+
+-- name: CreateApproval :one
+INSERT INTO approvals (tenant_id, entity_type, entity_id, action, payload, requested_by, request_comment)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetApprovalByID :one
+SELECT * FROM approvals
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: GetApprovalForUpdate :one
+SELECT * FROM approvals
+WHERE id = $1 AND tenant_id = $2
+FOR UPDATE;
+
+-- name: ListApprovals :many
+SELECT * FROM approvals
+WHERE tenant_id = $1
+  AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status'))
+  AND (sqlc.narg('entity_type')::text IS NULL OR entity_type = sqlc.narg('entity_type'))
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountApprovals :one
+SELECT COUNT(*) FROM approvals
+WHERE tenant_id = $1
+  AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status'))
+  AND (sqlc.narg('entity_type')::text IS NULL OR entity_type = sqlc.narg('entity_type'));
+
+-- name: DecideApproval :one
+UPDATE approvals
+SET status = $3,
+    decided_by = $4,
+    decision_comment = $5,
+    decided_at = now()
+WHERE id = $1 AND tenant_id = $2 AND status = 'pending'
+RETURNING *;
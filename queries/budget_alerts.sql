@@ -0,0 +1,75 @@
+-- Budget alert state queries
+-- sqlc query file for deduplicating, recording and acknowledging budget alerts
+
+-- name: ClaimBudgetAlert :execrows
+-- Records the budget's alert as sent now. No row is updated while the alert
+-- is active and was sent within the repeat interval, in which case it must
+-- not be sent again. A resolved alert is triggered afresh.
+INSERT INTO budget_alert_states (budget_id, alert_type, tenant_id, triggered_at, last_alerted_at)
+VALUES (sqlc.arg(budget_id), sqlc.arg(alert_type), sqlc.arg(tenant_id), sqlc.arg(now), sqlc.arg(now))
+ON CONFLICT (budget_id, alert_type) DO UPDATE
+SET triggered_at = CASE WHEN budget_alert_states.resolved_at IS NULL
+                        THEN budget_alert_states.triggered_at ELSE EXCLUDED.triggered_at END,
+    alert_count = CASE WHEN budget_alert_states.resolved_at IS NULL
+                       THEN budget_alert_states.alert_count + 1 ELSE 1 END,
+    last_alerted_at = EXCLUDED.last_alerted_at,
+    resolved_at = NULL
+WHERE budget_alert_states.resolved_at IS NOT NULL
+   OR budget_alert_states.last_alerted_at <= EXCLUDED.last_alerted_at - make_interval(mins => sqlc.arg(repeat_minutes)::int);
+
+-- name: ResolveBudgetAlert :execrows
+-- Resolves the budget's alert. No row is updated when it wasn't active.
+UPDATE budget_alert_states
+SET resolved_at = $1
+WHERE tenant_id = $2 AND budget_id = $3 AND alert_type = $4
+  AND resolved_at IS NULL;
+
+-- name: ListActiveBudgetAlerts :many
+-- A tenant's active alerts, for the sweep that resolves them
+SELECT * FROM budget_alert_states
+WHERE tenant_id = $1 AND resolved_at IS NULL
+ORDER BY budget_id, alert_type;
+
+-- name: CreateBudgetAlert :one
+-- Records an alert as sent. Notices that need no action are recorded
+-- acknowledged.
+INSERT INTO budget_alerts (tenant_id, budget_id, alert_type, level, message, currency, balance, utilization, acknowledged_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetBudgetAlert :one
+SELECT * FROM budget_alerts
+WHERE tenant_id = $1 AND id = $2;
+
+-- name: ListBudgetAlerts :many
+-- A tenant's alerts, newest first, optionally for one budget, with one level
+-- or by whether they were acknowledged
+SELECT * FROM budget_alerts
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND (sqlc.narg(budget_id)::uuid IS NULL OR budget_id = sqlc.narg(budget_id))
+  AND (sqlc.narg(level)::text IS NULL OR level = sqlc.narg(level))
+  AND (sqlc.narg(acknowledged)::boolean IS NULL OR (acknowledged_at IS NOT NULL) = sqlc.narg(acknowledged))
+ORDER BY created_at DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountBudgetAlerts :one
+-- Counts the alerts ListBudgetAlerts pages through
+SELECT COUNT(*) FROM budget_alerts
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND (sqlc.narg(budget_id)::uuid IS NULL OR budget_id = sqlc.narg(budget_id))
+  AND (sqlc.narg(level)::text IS NULL OR level = sqlc.narg(level))
+  AND (sqlc.narg(acknowledged)::boolean IS NULL OR (acknowledged_at IS NOT NULL) = sqlc.narg(acknowledged));
+
+-- name: CountUnacknowledgedBudgetAlerts :one
+SELECT COUNT(*) FROM budget_alerts
+WHERE tenant_id = $1 AND level = $2 AND acknowledged_at IS NULL;
+
+-- name: AcknowledgeBudgetAlert :one
+-- Acknowledges an alert. No row is returned when it was already acknowledged.
+UPDATE budget_alerts
+SET acknowledged_at = now(),
+    acknowledged_by = sqlc.narg(acknowledged_by),
+    acknowledgement_note = sqlc.narg(note)
+WHERE tenant_id = sqlc.arg(tenant_id) AND id = sqlc.arg(id)
+  AND acknowledged_at IS NULL
+RETURNING *;
@@ -0,0 +1,41 @@
+-- Budget commitment queries
+-- sqlc query file for amounts of a budget earmarked for campaigns.
+-- commit_budget and release_budget_commitment post their ledger entries.
+
+-- name: CreateBudgetCommitment :one
+INSERT INTO budget_commitments (tenant_id, budget_id, campaign_id, currency, amount, remaining, note, created_by)
+VALUES (sqlc.arg(tenant_id), sqlc.arg(budget_id), sqlc.arg(campaign_id), sqlc.arg(currency),
+        sqlc.arg(amount), sqlc.arg(amount), sqlc.narg(note), sqlc.narg(created_by))
+RETURNING *;
+
+-- name: GetBudgetCommitment :one
+SELECT * FROM budget_commitments
+WHERE tenant_id = $1 AND budget_id = $2 AND id = $3;
+
+-- name: ListBudgetCommitments :many
+-- A budget's commitments, newest first, optionally with one status
+SELECT * FROM budget_commitments
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND budget_id = sqlc.arg(budget_id)
+  AND (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status))
+ORDER BY created_at DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountBudgetCommitments :one
+-- Counts the commitments ListBudgetCommitments pages through
+SELECT COUNT(*) FROM budget_commitments
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND budget_id = sqlc.arg(budget_id)
+  AND (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status));
+
+-- name: CampaignDrawsFromBudget :one
+-- Whether a campaign reserves from a budget, as its primary or a fallback
+SELECT EXISTS (
+  SELECT 1 FROM campaigns c
+  WHERE c.tenant_id = sqlc.arg(tenant_id) AND c.id = sqlc.arg(campaign_id)
+    AND c.budget_id = sqlc.arg(budget_id)
+) OR EXISTS (
+  SELECT 1 FROM campaign_budgets cb
+  WHERE cb.tenant_id = sqlc.arg(tenant_id) AND cb.campaign_id = sqlc.arg(campaign_id)
+    AND cb.budget_id = sqlc.arg(budget_id)
+) AS draws;
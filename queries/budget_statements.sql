@@ -0,0 +1,36 @@
+-- Budget statement queries
+-- sqlc query file for budget period close statements
+
+-- name: CreateBudgetStatement :one
+INSERT INTO budget_statements (
+  tenant_id, budget_id, currency, period_start, period_end,
+  opening_balance, closing_balance, totals, entry_count,
+  ledger_discrepancy, balance_discrepancy, closed_by
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+RETURNING *;
+
+-- name: GetBudgetStatement :one
+SELECT * FROM budget_statements
+WHERE id = $1 AND tenant_id = $2 AND budget_id = $3;
+
+-- name: GetLatestBudgetStatement :one
+SELECT * FROM budget_statements
+WHERE tenant_id = $1 AND budget_id = $2
+ORDER BY period_end DESC
+LIMIT 1;
+
+-- name: ListBudgetStatements :many
+SELECT * FROM budget_statements
+WHERE tenant_id = $1 AND budget_id = $2
+ORDER BY period_end DESC
+LIMIT $3 OFFSET $4;
+
+-- name: CountBudgetStatements :one
+SELECT COUNT(*) FROM budget_statements
+WHERE tenant_id = $1 AND budget_id = $2;
+
+-- name: ListPeriodicBudgets :many
+-- Budgets across all tenants whose periods close automatically
+SELECT * FROM budgets
+WHERE period <> 'rolling'
+ORDER BY created_at;
@@ -1,6 +1,10 @@
 -- name: CreateBudget :one
-INSERT INTO budgets (tenant_id, name, currency, soft_cap, hard_cap, balance, period)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
+INSERT INTO budgets (
+  tenant_id, name, currency, soft_cap, hard_cap, balance, period,
+  alert_soft_percent, alert_hard_percent, alert_email, alert_webhook_url, alert_whatsapp_number,
+  alert_repeat_minutes
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 RETURNING *;
 
 -- name: GetBudgetByID :one
@@ -12,6 +16,36 @@ SELECT * FROM budgets
 WHERE tenant_id = $1
 ORDER BY created_at DESC;
 
+-- name: ListBudgetsByName :many
+SELECT * FROM budgets
+WHERE tenant_id = $1 AND name = $2
+ORDER BY created_at;
+
+-- name: UpdateBudgetLimits :one
+UPDATE budgets
+SET soft_cap = $3,
+    hard_cap = $4,
+    period = $5
+WHERE id = $1 AND tenant_id = $2
+RETURNING *;
+
+-- name: UpdateBudgetAlertSettings :one
+UPDATE budgets
+SET alert_soft_percent = $3,
+    alert_hard_percent = $4,
+    alert_email = $5,
+    alert_webhook_url = $6,
+    alert_whatsapp_number = $7,
+    alert_repeat_minutes = $8
+WHERE id = $1 AND tenant_id = $2
+RETURNING *;
+
+-- name: UpdateBudgetOverdraft :one
+UPDATE budgets
+SET overdraft_limit = $3
+WHERE id = $1 AND tenant_id = $2
+RETURNING *;
+
 -- name: UpdateBudgetBalance :exec
 UPDATE budgets
 SET balance = balance + $3
@@ -0,0 +1,17 @@
+-- Campaign fallback budget queries
+-- sqlc query file for the ordered budgets a campaign falls back to
+
+-- name: ListCampaignFallbackBudgets :many
+SELECT cb.budget_id, cb.position, b.name AS budget_name, b.currency, b.balance, b.hard_cap
+FROM campaign_budgets cb
+JOIN budgets b ON b.id = cb.budget_id
+WHERE cb.tenant_id = $1 AND cb.campaign_id = $2
+ORDER BY cb.position;
+
+-- name: DeleteCampaignFallbackBudgets :exec
+DELETE FROM campaign_budgets
+WHERE tenant_id = $1 AND campaign_id = $2;
+
+-- name: AddCampaignFallbackBudget :exec
+INSERT INTO campaign_budgets (tenant_id, campaign_id, budget_id, position)
+VALUES ($1, $2, $3, $4);
@@ -0,0 +1,33 @@
+-- Campaign exclusion queries
+-- sqlc query file for the customers a campaign must not reward
+
+-- name: AddCampaignExclusion :exec
+INSERT INTO campaign_exclusions (tenant_id, campaign_id, customer_id, reason)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (campaign_id, customer_id) DO UPDATE SET reason = EXCLUDED.reason;
+
+-- name: ListCampaignExclusions :many
+SELECT ce.customer_id, ce.reason, ce.created_at, c.phone_e164, c.external_ref
+FROM campaign_exclusions ce
+JOIN customers c ON c.id = ce.customer_id
+WHERE ce.tenant_id = $1 AND ce.campaign_id = $2
+ORDER BY ce.created_at DESC, ce.customer_id
+LIMIT $3 OFFSET $4;
+
+-- name: CountCampaignExclusions :one
+SELECT COUNT(*) FROM campaign_exclusions
+WHERE tenant_id = $1 AND campaign_id = $2;
+
+-- name: ListCampaignExcludedCustomerIDs :many
+SELECT customer_id FROM campaign_exclusions
+WHERE tenant_id = $1 AND campaign_id = $2;
+
+-- name: DeleteCampaignExclusion :execrows
+DELETE FROM campaign_exclusions
+WHERE tenant_id = $1 AND campaign_id = $2 AND customer_id = $3;
+
+-- name: IsCustomerExcludedFromCampaign :one
+SELECT EXISTS (
+  SELECT 1 FROM campaign_exclusions
+  WHERE tenant_id = $1 AND campaign_id = $2 AND customer_id = $3
+);
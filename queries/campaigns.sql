@@ -13,13 +13,20 @@ WHERE id = $1 AND tenant_id = $2;
 -- name: ListCampaigns :many
 SELECT * FROM campaigns
 WHERE tenant_id = $1
+  AND (sqlc.arg(include_archived)::boolean OR archived_at IS NULL)
 ORDER BY name
 LIMIT $2 OFFSET $3;
 
+-- name: ListCampaignsByName :many
+SELECT * FROM campaigns
+WHERE tenant_id = $1 AND name = $2 AND archived_at IS NULL
+ORDER BY id;
+
 -- name: ListActiveCampaigns :many
 SELECT * FROM campaigns
 WHERE tenant_id = $1
   AND status = 'active'
+  AND archived_at IS NULL
   AND (start_at IS NULL OR start_at <= NOW())
   AND (end_at IS NULL OR end_at >= NOW())
 ORDER BY name;
@@ -46,9 +53,97 @@ ORDER BY name;
 -- name: GetCampaignsByStatus :many
 SELECT * FROM campaigns
 WHERE tenant_id = $1 AND status = $2
+  AND (sqlc.arg(include_archived)::boolean OR archived_at IS NULL)
 ORDER BY name
 LIMIT $3 OFFSET $4;
 
 -- name: DeleteCampaign :exec
 DELETE FROM campaigns
 WHERE id = $1 AND tenant_id = $2;
+
+-- name: GetCampaignForUpdate :one
+SELECT * FROM campaigns
+WHERE id = $1 AND tenant_id = $2
+FOR UPDATE;
+
+-- name: ArchiveCampaign :execrows
+UPDATE campaigns
+SET archived_at = now()
+WHERE id = $1 AND tenant_id = $2 AND archived_at IS NULL;
+
+-- name: RestoreCampaign :execrows
+UPDATE campaigns
+SET archived_at = NULL
+WHERE id = $1 AND tenant_id = $2 AND archived_at IS NOT NULL;
+
+-- name: SetCampaignPacing :one
+UPDATE campaigns
+SET max_spend_per_day = sqlc.narg(max_spend_per_day),
+    max_spend_per_week = sqlc.narg(max_spend_per_week)
+WHERE id = sqlc.arg(id) AND tenant_id = sqlc.arg(tenant_id)
+RETURNING *;
+
+-- name: GetCampaignPacingSpend :one
+-- Cost of the campaign's issuances reserved since the start of the day and
+-- of the week; cancelled and failed issuances don't count
+SELECT
+  COALESCE(SUM(cost_amount) FILTER (WHERE issued_at >= sqlc.arg(day_start)), 0)::numeric AS day_spend,
+  COALESCE(SUM(cost_amount), 0)::numeric AS week_spend
+FROM issuances
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND campaign_id = sqlc.arg(campaign_id)
+  AND issued_at >= sqlc.arg(week_start)
+  AND status NOT IN ('cancelled', 'failed');
+
+-- name: GetCampaignStats :one
+-- Issuances, redemptions and spend of a campaign over its lifetime;
+-- cancelled and failed issuances don't count
+SELECT
+  COUNT(*) FILTER (WHERE status NOT IN ('cancelled', 'failed')) AS issued,
+  COUNT(*) FILTER (WHERE status = 'redeemed') AS redeemed,
+  COALESCE(SUM(cost_amount) FILTER (WHERE status NOT IN ('cancelled', 'failed')), 0)::numeric AS spend
+FROM issuances
+WHERE tenant_id = $1 AND campaign_id = $2;
+
+-- name: ListCampaignBudgetPerformance :many
+-- Running campaigns with a budget, with their budget's cap and spend and
+-- their lifetime issuances and redemptions; cancelled and failed issuances
+-- don't count
+SELECT
+  c.id AS campaign_id,
+  c.name AS campaign_name,
+  b.id AS budget_id,
+  b.name AS budget_name,
+  b.currency,
+  b.hard_cap,
+  b.balance,
+  COUNT(i.id) FILTER (WHERE i.status NOT IN ('cancelled', 'failed')) AS issued,
+  COUNT(i.id) FILTER (WHERE i.status = 'redeemed') AS redeemed
+FROM campaigns c
+JOIN budgets b ON b.id = c.budget_id
+LEFT JOIN issuances i ON i.campaign_id = c.id AND i.tenant_id = c.tenant_id
+WHERE c.tenant_id = $1
+  AND c.status = 'active'
+  AND c.archived_at IS NULL
+  AND (c.end_at IS NULL OR c.end_at >= NOW())
+GROUP BY c.id, b.id
+ORDER BY c.name;
+
+-- name: ListCampaignTopRewards :many
+-- The campaign's most issued rewards, with their redemptions and cost;
+-- cancelled and failed issuances don't count
+SELECT
+  r.id AS reward_id,
+  r.name AS reward_name,
+  r.type AS reward_type,
+  COUNT(i.id) AS issued,
+  COUNT(i.id) FILTER (WHERE i.status = 'redeemed') AS redeemed,
+  COALESCE(SUM(i.cost_amount), 0)::numeric AS cost
+FROM issuances i
+JOIN reward_catalog r ON r.id = i.reward_id
+WHERE i.tenant_id = sqlc.arg(tenant_id)
+  AND i.campaign_id = sqlc.arg(campaign_id)
+  AND i.status NOT IN ('cancelled', 'failed')
+GROUP BY r.id, r.name, r.type
+ORDER BY issued DESC, r.name
+LIMIT sqlc.arg(row_limit);
@@ -0,0 +1,86 @@
+-- Challenge queries
+
+-- name: CreateChallenge :one
+INSERT INTO challenges (tenant_id, name, description, kind, event_type, criteria, target, period, starts_at, ends_at, active)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING *;
+
+-- name: GetChallengeByID :one
+SELECT * FROM challenges
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: ListChallenges :many
+SELECT * FROM challenges
+WHERE tenant_id = $1
+ORDER BY created_at DESC;
+
+-- name: ListActiveChallenges :many
+SELECT * FROM challenges
+WHERE tenant_id = $1 AND active = true
+ORDER BY created_at;
+
+-- name: UpdateChallenge :one
+UPDATE challenges
+SET name = $3,
+    description = $4,
+    criteria = $5,
+    target = $6,
+    starts_at = $7,
+    ends_at = $8,
+    active = $9,
+    updated_at = now()
+WHERE id = $1 AND tenant_id = $2
+RETURNING *;
+
+-- name: ListActiveChallengesForEvent :many
+SELECT * FROM challenges
+WHERE tenant_id = $1 AND event_type = $2 AND active = true;
+
+-- name: RecordChallengeEvent :execrows
+-- Records that an event counted towards a challenge; no rows when it
+-- already had
+INSERT INTO challenge_events (challenge_id, event_id, tenant_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (challenge_id, event_id) DO NOTHING;
+
+-- name: LockChallengeProgress :exec
+-- Serializes progress updates of one customer on one challenge until the
+-- transaction ends
+SELECT pg_advisory_xact_lock(hashtextextended('challenge:' || (sqlc.arg(challenge_id)::uuid)::text || ':' || (sqlc.arg(customer_id)::uuid)::text, 0));
+
+-- name: GetChallengeProgress :one
+SELECT * FROM challenge_progress
+WHERE tenant_id = $1 AND challenge_id = $2 AND customer_id = $3 AND period_start = $4;
+
+-- name: GetLatestChallengeProgress :one
+SELECT * FROM challenge_progress
+WHERE tenant_id = $1 AND challenge_id = $2 AND customer_id = $3
+ORDER BY period_start DESC
+LIMIT 1;
+
+-- name: InsertChallengeProgress :one
+INSERT INTO challenge_progress (tenant_id, challenge_id, customer_id, period_start, last_period, progress)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: UpdateChallengeProgress :one
+UPDATE challenge_progress
+SET last_period = $3,
+    progress = $4,
+    updated_at = now()
+WHERE id = $1 AND tenant_id = $2
+RETURNING *;
+
+-- name: CompleteChallengeProgress :exec
+UPDATE challenge_progress
+SET completed_at = now(),
+    event_id = $3,
+    updated_at = now()
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: ListLatestChallengeProgressForCustomer :many
+-- Each challenge's latest progress row for a customer
+SELECT DISTINCT ON (challenge_id) *
+FROM challenge_progress
+WHERE tenant_id = $1 AND customer_id = $2
+ORDER BY challenge_id, period_start DESC;
@@ -0,0 +1,53 @@
+-- Channel funnel queries
+-- sqlc query file for channel session funnel analytics
+
+-- name: RecordFunnelStep :exec
+-- Starts the session on its first step. Later steps move last_step on and
+-- stamp each funnel stage the first time it is reached.
+INSERT INTO channel_funnel_sessions (
+  tenant_id, channel, session_ref, last_step,
+  menu_reached_at, redemption_attempted_at, redemption_completed_at, ended_at
+)
+VALUES (
+  sqlc.arg(tenant_id), sqlc.arg(channel), sqlc.arg(session_ref), sqlc.arg(last_step),
+  CASE WHEN sqlc.arg(menu_reached)::boolean THEN now() END,
+  CASE WHEN sqlc.arg(redemption_attempted)::boolean THEN now() END,
+  CASE WHEN sqlc.arg(redemption_completed)::boolean THEN now() END,
+  CASE WHEN sqlc.arg(ended)::boolean THEN now() END
+)
+ON CONFLICT (tenant_id, channel, session_ref) DO UPDATE SET
+  last_step = EXCLUDED.last_step,
+  menu_reached_at = COALESCE(channel_funnel_sessions.menu_reached_at, EXCLUDED.menu_reached_at),
+  redemption_attempted_at = COALESCE(channel_funnel_sessions.redemption_attempted_at, EXCLUDED.redemption_attempted_at),
+  redemption_completed_at = COALESCE(channel_funnel_sessions.redemption_completed_at, EXCLUDED.redemption_completed_at),
+  ended_at = COALESCE(channel_funnel_sessions.ended_at, EXCLUDED.ended_at),
+  updated_at = now();
+
+-- name: GetChannelFunnel :one
+-- How many of the sessions started in a period reached each funnel stage,
+-- and how many were abandoned: neither ended by the channel nor active
+-- since abandoned_before
+SELECT
+  COUNT(*)::bigint AS sessions_started,
+  COUNT(menu_reached_at)::bigint AS menu_reached,
+  COUNT(redemption_attempted_at)::bigint AS redemption_attempted,
+  COUNT(redemption_completed_at)::bigint AS redemption_completed,
+  (COUNT(*) FILTER (WHERE ended_at IS NULL AND updated_at < sqlc.arg(abandoned_before)))::bigint AS abandoned
+FROM channel_funnel_sessions
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND channel = sqlc.arg(channel)
+  AND started_at >= sqlc.arg(started_from)
+  AND started_at < sqlc.arg(started_to);
+
+-- name: ListChannelFunnelDropOffs :many
+-- The steps abandoned sessions were last on, most common first
+SELECT last_step, COUNT(*)::bigint AS sessions
+FROM channel_funnel_sessions
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND channel = sqlc.arg(channel)
+  AND started_at >= sqlc.arg(started_from)
+  AND started_at < sqlc.arg(started_to)
+  AND ended_at IS NULL
+  AND updated_at < sqlc.arg(abandoned_before)
+GROUP BY last_step
+ORDER BY sessions DESC, last_step;
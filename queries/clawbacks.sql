@@ -0,0 +1,18 @@
+-- Issuance clawback queries
+-- sqlc query file for clawbacks of redeemed rewards
+
+-- name: CreateIssuanceClawback :one
+INSERT INTO issuance_clawbacks
+  (tenant_id, issuance_id, customer_id, reason_code, note, budget_id, amount, currency, created_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (issuance_id) DO NOTHING
+RETURNING *;
+
+-- name: GetIssuanceClawback :one
+SELECT * FROM issuance_clawbacks
+WHERE tenant_id = $1 AND issuance_id = $2;
+
+-- name: GetIssuanceForUpdate :one
+SELECT * FROM issuances
+WHERE id = $1 AND tenant_id = $2
+FOR UPDATE;
@@ -0,0 +1,96 @@
+-- name: ListCustomerActivity :many
+-- A customer's events, issuance status changes, queued WhatsApp messages and
+-- consent changes, newest first. Redemptions are the status changes to
+-- redeemed. kinds, when set, limits the feed to those kinds of activity.
+SELECT kind, ref_id, occurred_at, summary, details
+FROM (
+  SELECT 'event'::text AS kind,
+    e.id::text AS ref_id,
+    e.occurred_at,
+    e.event_type AS summary,
+    jsonb_build_object('source', e.source, 'properties', e.properties) AS details
+  FROM events e
+  WHERE e.tenant_id = sqlc.arg(tenant_id) AND e.customer_id = sqlc.arg(customer_id)
+
+  UNION ALL
+
+  SELECT CASE WHEN h.new_status = 'redeemed' THEN 'redemption' ELSE 'issuance' END,
+    h.issuance_id::text,
+    h.created_at,
+    h.new_status,
+    jsonb_build_object(
+      'old_status', h.old_status,
+      'actor', h.actor,
+      'channel', h.channel,
+      'reward_id', i.reward_id,
+      'reward_name', r.name,
+      'campaign_id', i.campaign_id
+    )
+  FROM issuance_status_history h
+  JOIN issuances i ON i.id = h.issuance_id AND i.tenant_id = h.tenant_id
+  JOIN reward_catalog r ON r.id = i.reward_id
+  WHERE h.tenant_id = sqlc.arg(tenant_id) AND i.customer_id = sqlc.arg(customer_id)
+
+  UNION ALL
+
+  -- WhatsApp addresses recipients by their number without the leading +.
+  -- phone is the customer's decrypted number, as stored numbers may be
+  -- encrypted.
+  SELECT 'message',
+    m.id::text,
+    m.created_at,
+    m.status,
+    jsonb_build_object(
+      'channel', 'whatsapp',
+      'attempts', m.attempts,
+      'last_error', m.last_error,
+      'sent_at', m.sent_at
+    )
+  FROM outbound_messages m
+  WHERE m.tenant_id = sqlc.arg(tenant_id)
+    AND m.recipient IN (sqlc.arg(phone)::text, ltrim(sqlc.arg(phone)::text, '+'))
+
+  UNION ALL
+
+  SELECT 'consent',
+    cs.id::text,
+    cs.occurred_at,
+    CASE WHEN cs.granted THEN 'granted' ELSE 'withdrawn' END,
+    jsonb_build_object('channel', cs.channel, 'purpose', cs.purpose)
+  FROM consents cs
+  WHERE cs.tenant_id = sqlc.arg(tenant_id) AND cs.customer_id = sqlc.arg(customer_id)
+) activity
+WHERE sqlc.narg(kinds)::text[] IS NULL OR kind = ANY(sqlc.narg(kinds)::text[])
+ORDER BY occurred_at DESC, kind, ref_id
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountCustomerActivity :one
+-- Counts the activity ListCustomerActivity pages through
+SELECT COUNT(*)
+FROM (
+  SELECT 'event'::text AS kind
+  FROM events e
+  WHERE e.tenant_id = sqlc.arg(tenant_id) AND e.customer_id = sqlc.arg(customer_id)
+
+  UNION ALL
+
+  SELECT CASE WHEN h.new_status = 'redeemed' THEN 'redemption' ELSE 'issuance' END
+  FROM issuance_status_history h
+  JOIN issuances i ON i.id = h.issuance_id AND i.tenant_id = h.tenant_id
+  JOIN reward_catalog r ON r.id = i.reward_id
+  WHERE h.tenant_id = sqlc.arg(tenant_id) AND i.customer_id = sqlc.arg(customer_id)
+
+  UNION ALL
+
+  SELECT 'message'
+  FROM outbound_messages m
+  WHERE m.tenant_id = sqlc.arg(tenant_id)
+    AND m.recipient IN (sqlc.arg(phone)::text, ltrim(sqlc.arg(phone)::text, '+'))
+
+  UNION ALL
+
+  SELECT 'consent'
+  FROM consents cs
+  WHERE cs.tenant_id = sqlc.arg(tenant_id) AND cs.customer_id = sqlc.arg(customer_id)
+) activity
+WHERE sqlc.narg(kinds)::text[] IS NULL OR kind = ANY(sqlc.narg(kinds)::text[]);
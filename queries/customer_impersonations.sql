@@ -0,0 +1,29 @@
+-- Customer impersonation queries
+-- sqlc query file for staff impersonating customers in the portal
+
+-- name: CreateCustomerImpersonation :one
+INSERT INTO customer_impersonations (tenant_id, customer_id, staff_user_id, reason, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetActiveCustomerImpersonation :one
+SELECT * FROM customer_impersonations
+WHERE tenant_id = $1 AND id = $2
+  AND ended_at IS NULL AND expires_at > now();
+
+-- name: EndCustomerImpersonation :one
+UPDATE customer_impersonations
+SET ended_at = now()
+WHERE tenant_id = $1 AND id = $2
+  AND ended_at IS NULL AND expires_at > now()
+RETURNING *;
+
+-- name: ListCustomerImpersonations :many
+SELECT * FROM customer_impersonations
+WHERE tenant_id = $1 AND customer_id = $2
+ORDER BY created_at DESC
+LIMIT $3 OFFSET $4;
+
+-- name: CountCustomerImpersonations :one
+SELECT COUNT(*) FROM customer_impersonations
+WHERE tenant_id = $1 AND customer_id = $2;
@@ -0,0 +1,31 @@
+-- Customer OTP queries
+-- sqlc query file for customer portal sign-in codes
+
+-- name: CreateCustomerOTP :one
+INSERT INTO customer_otps (tenant_id, customer_id, channel, code_hash, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: CountCustomerOTPsSince :one
+SELECT COUNT(*) FROM customer_otps
+WHERE tenant_id = $1
+  AND customer_id = $2
+  AND created_at >= sqlc.arg(since);
+
+-- name: GetLatestCustomerOTP :one
+-- The newest code replaces any sent before it
+SELECT * FROM customer_otps
+WHERE tenant_id = $1 AND customer_id = $2
+ORDER BY created_at DESC
+LIMIT 1
+FOR UPDATE;
+
+-- name: IncrementCustomerOTPAttempts :exec
+UPDATE customer_otps
+SET attempts = attempts + 1
+WHERE id = $1;
+
+-- name: ConsumeCustomerOTP :execrows
+UPDATE customer_otps
+SET consumed_at = now()
+WHERE id = $1 AND consumed_at IS NULL;
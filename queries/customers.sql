@@ -1,27 +1,120 @@
 -- name: CreateCustomer :one
-INSERT INTO customers (tenant_id, phone_e164, external_ref, status)
-VALUES ($1, $2, $3, 'active')
+INSERT INTO customers (tenant_id, phone_e164, phone_bidx, external_ref, name, status)
+VALUES ($1, $2, $3, $4, $5, 'active')
 RETURNING *;
 
+-- name: UpsertCustomerByPhone :one
+-- Creates the customer with the phone number, or updates the existing one's
+-- external reference and name when given. The customer is matched by the
+-- number's blind index under the current PII key, so customers indexed
+-- under an older key must be reindexed first. created is true when the
+-- customer was created.
+INSERT INTO customers (tenant_id, phone_e164, phone_bidx, external_ref, name, status)
+VALUES (sqlc.arg(tenant_id), sqlc.arg(phone_e164), sqlc.arg(phone_bidx), sqlc.narg(external_ref), sqlc.narg(name), 'active')
+ON CONFLICT (tenant_id, phone_bidx) DO UPDATE
+SET external_ref = COALESCE(EXCLUDED.external_ref, customers.external_ref),
+    name = COALESCE(EXCLUDED.name, customers.name)
+RETURNING sqlc.embed(customers), (xmax = 0)::boolean AS created;
+
+-- name: UpsertCustomerByExternalRef :one
+-- Creates the customer with the external reference or updates the existing
+-- one's name. created is true when the customer was created.
+INSERT INTO customers (tenant_id, external_ref, name, status)
+VALUES (sqlc.arg(tenant_id), sqlc.arg(external_ref), sqlc.narg(name), 'active')
+ON CONFLICT (tenant_id, external_ref) DO UPDATE
+SET name = COALESCE(EXCLUDED.name, customers.name)
+RETURNING sqlc.embed(customers), (xmax = 0)::boolean AS created;
+
 -- name: GetCustomerByID :one
 SELECT * FROM customers
 WHERE id = $1 AND tenant_id = $2;
 
 -- name: GetCustomerByPhone :one
+-- Finds the customer by the blind indexes of their phone number under every
+-- PII key, so they're found whichever key their number was encrypted with.
 SELECT * FROM customers
-WHERE tenant_id = $1 AND phone_e164 = $2;
+WHERE tenant_id = sqlc.arg(tenant_id) AND phone_bidx = ANY(sqlc.arg(phone_bidxs)::bytea[]);
+
+-- name: ReindexCustomerPhone :exec
+-- Moves the customer whose phone number is indexed under an older PII key,
+-- or isn't encrypted yet, onto the current key.
+UPDATE customers
+SET phone_e164 = sqlc.arg(phone_e164), phone_bidx = sqlc.arg(phone_bidx)
+WHERE tenant_id = sqlc.arg(tenant_id) AND phone_bidx = ANY(sqlc.arg(previous_bidxs)::bytea[]);
+
+-- name: ListCustomersToRotate :many
+-- Customers with a phone number that isn't encrypted under the current PII
+-- key, whose values start with current_prefix.
+SELECT id, phone_e164 FROM customers
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND phone_e164 IS NOT NULL
+  AND NOT starts_with(phone_e164, sqlc.arg(current_prefix)::text)
+ORDER BY id
+LIMIT sqlc.arg(row_limit);
+
+-- name: SetCustomerPhone :exec
+UPDATE customers
+SET phone_e164 = $3, phone_bidx = $4
+WHERE id = $1 AND tenant_id = $2;
 
 -- name: GetCustomerByExternalRef :one
 SELECT * FROM customers
 WHERE tenant_id = $1 AND external_ref = $2;
 
+-- name: GetCustomerRuleAttributes :one
+-- The customer attributes rule conditions can use, in one round trip
+SELECT c.status, c.created_at, c.flagged_at,
+  (SELECT COUNT(*) FROM events ev
+   WHERE ev.tenant_id = c.tenant_id
+     AND ev.customer_id = c.id
+     AND ev.event_type = 'visit') AS total_visits
+FROM customers c
+WHERE c.tenant_id = $1 AND c.id = $2;
+
 -- name: ListCustomers :many
 SELECT * FROM customers
 WHERE tenant_id = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3;
 
+-- name: SearchCustomers :many
+-- Customers whose phone number, external reference or name contains the
+-- query or resembles it, best matches first: exact matches, then substring
+-- matches, each ranked by trigram similarity. Encrypted phone numbers only
+-- match exactly, by their blind indexes.
+SELECT sqlc.embed(customers),
+  ((CASE
+      WHEN phone_bidx = ANY(sqlc.narg(phone_bidxs)::bytea[])
+        OR lower(external_ref) = lower(sqlc.arg(query)::text)
+        OR lower(name) = lower(sqlc.arg(query)::text) THEN 2
+      WHEN (phone_e164 NOT LIKE 'enc:%' AND phone_e164 LIKE sqlc.narg(phone_pattern)::text)
+        OR external_ref ILIKE sqlc.arg(pattern)::text
+        OR name ILIKE sqlc.arg(pattern)::text THEN 1
+      ELSE 0
+    END)
+    + COALESCE(GREATEST(
+        word_similarity(sqlc.arg(query)::text, name),
+        word_similarity(sqlc.arg(query)::text, external_ref)
+      ), 0))::real AS score
+FROM customers
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND (
+    phone_bidx = ANY(sqlc.narg(phone_bidxs)::bytea[])
+    OR (phone_e164 NOT LIKE 'enc:%' AND phone_e164 LIKE sqlc.narg(phone_pattern)::text)
+    OR external_ref ILIKE sqlc.arg(pattern)::text
+    OR name ILIKE sqlc.arg(pattern)::text
+    OR sqlc.arg(query)::text <% name
+    OR sqlc.arg(query)::text <% external_ref
+  )
+ORDER BY score DESC, created_at DESC
+LIMIT sqlc.arg(row_limit);
+
 -- name: UpdateCustomerStatus :exec
 UPDATE customers
 SET status = $3
 WHERE id = $1 AND tenant_id = $2;
+
+-- name: FlagCustomer :exec
+UPDATE customers
+SET flagged_at = COALESCE(flagged_at, now()), flag_reason = $3
+WHERE id = $1 AND tenant_id = $2;
@@ -0,0 +1,67 @@
+-- Data export queries
+-- sqlc query file for exported snapshot files
+
+-- name: CreateDataExport :one
+INSERT INTO data_exports
+  (tenant_id, dataset, snapshot_date, format, bucket, object_key, row_count, size_bytes)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetDataExport :one
+SELECT * FROM data_exports
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: DataExportExists :one
+SELECT EXISTS (
+  SELECT 1 FROM data_exports
+  WHERE tenant_id = $1 AND dataset = $2 AND snapshot_date = $3
+);
+
+-- name: ListDataExports :many
+SELECT * FROM data_exports
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND (sqlc.narg(dataset)::text IS NULL OR dataset = sqlc.narg(dataset))
+ORDER BY snapshot_date DESC, dataset
+LIMIT sqlc.arg(limit_count) OFFSET sqlc.arg(offset_count);
+
+-- name: CountDataExports :one
+SELECT COUNT(*) FROM data_exports
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND (sqlc.narg(dataset)::text IS NULL OR dataset = sqlc.narg(dataset));
+
+-- name: ListExportTenants :many
+SELECT * FROM tenants
+WHERE export_bucket IS NOT NULL
+ORDER BY created_at;
+
+-- name: ExportCustomers :many
+SELECT id, phone_e164, external_ref, status, created_at
+FROM customers
+WHERE tenant_id = $1
+ORDER BY created_at, id;
+
+-- name: ExportEvents :many
+SELECT id, customer_id, event_type, properties, occurred_at, source, location_id, created_at
+FROM events
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND occurred_at >= sqlc.arg(from_time)
+  AND occurred_at < sqlc.arg(to_time)
+ORDER BY occurred_at, id;
+
+-- name: ExportIssuances :many
+SELECT id, customer_id, campaign_id, reward_id, event_id, status, currency,
+       cost_amount, face_amount, issued_at, expires_at, redeemed_at,
+       redemption_source
+FROM issuances
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND issued_at >= sqlc.arg(from_time)
+  AND issued_at < sqlc.arg(to_time)
+ORDER BY issued_at, id;
+
+-- name: ExportLedgerEntries :many
+SELECT id, budget_id, entry_type, currency, amount, ref_type, ref_id, created_at
+FROM ledger_entries
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND created_at >= sqlc.arg(from_time)
+  AND created_at < sqlc.arg(to_time)
+ORDER BY created_at, id;
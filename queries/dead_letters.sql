@@ -0,0 +1,47 @@
+-- Dead letter queue queries
+
+-- name: RecordDeadLetter :one
+INSERT INTO dead_letters (tenant_id, event_id, error)
+VALUES ($1, $2, $3)
+ON CONFLICT (event_id)
+DO UPDATE SET error = EXCLUDED.error,
+              attempts = dead_letters.attempts + 1,
+              status = 'pending',
+              last_attempt_at = now(),
+              resolved_at = NULL
+RETURNING *;
+
+-- name: GetDeadLetterByID :one
+SELECT * FROM dead_letters
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: ListDeadLetters :many
+SELECT * FROM dead_letters
+WHERE tenant_id = $1
+  AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status')::text)
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountDeadLetters :one
+SELECT COUNT(*) FROM dead_letters
+WHERE tenant_id = $1
+  AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status')::text);
+
+-- name: ClaimDeadLetterForRetry :one
+UPDATE dead_letters
+SET status = 'retrying', last_attempt_at = now()
+WHERE id = $1 AND tenant_id = $2 AND status = 'pending'
+RETURNING *;
+
+-- name: ResolveDeadLetter :exec
+UPDATE dead_letters
+SET status = 'resolved', resolved_at = now()
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: FailDeadLetterRetry :exec
+UPDATE dead_letters
+SET status = 'pending',
+    error = $3,
+    attempts = attempts + 1,
+    last_attempt_at = now()
+WHERE id = $1 AND tenant_id = $2;
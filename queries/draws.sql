@@ -0,0 +1,104 @@
+-- Prize draw queries
+
+-- name: CreateDraw :one
+INSERT INTO draws (tenant_id, campaign_id, name, event_type, criteria, entries_per_event, winners, draw_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetDrawByID :one
+SELECT * FROM draws
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: GetDrawForUpdate :one
+SELECT * FROM draws
+WHERE id = $1 AND tenant_id = $2
+FOR UPDATE;
+
+-- name: ListDraws :many
+SELECT * FROM draws
+WHERE tenant_id = $1
+  AND (sqlc.narg(campaign_id)::uuid IS NULL OR campaign_id = sqlc.narg(campaign_id))
+ORDER BY created_at DESC;
+
+-- name: ListOpenDrawsForEvent :many
+-- Open draws an event of the type can enter: the campaign must be running
+-- and the draw not yet due
+SELECT d.* FROM draws d
+JOIN campaigns c ON c.id = d.campaign_id
+WHERE d.tenant_id = sqlc.arg(tenant_id)
+  AND d.event_type = sqlc.arg(event_type)
+  AND d.status = 'open'
+  AND (d.draw_at IS NULL OR sqlc.arg(occurred_at)::timestamptz < d.draw_at)
+  AND c.status = 'active'
+  AND c.archived_at IS NULL
+  AND (c.start_at IS NULL OR sqlc.arg(occurred_at)::timestamptz >= c.start_at)
+  AND (c.end_at IS NULL OR sqlc.arg(occurred_at)::timestamptz < c.end_at);
+
+-- name: ListDueDraws :many
+SELECT * FROM draws
+WHERE tenant_id = $1
+  AND status = 'open'
+  AND draw_at IS NOT NULL
+  AND draw_at <= sqlc.arg(now)::timestamptz
+ORDER BY draw_at;
+
+-- name: LockOpenDraw :one
+-- Holds off the draw until the transaction ends; no rows once it is drawn
+SELECT id FROM draws
+WHERE id = $1 AND tenant_id = $2 AND status = 'open'
+FOR SHARE;
+
+-- name: InsertDrawEntry :execrows
+-- Enters an event into a draw; no rows when it already was
+INSERT INTO draw_entries (tenant_id, draw_id, customer_id, event_id, tickets)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (draw_id, event_id) DO NOTHING;
+
+-- name: ListDrawEntries :many
+-- Entries in the order winners are picked from
+SELECT customer_id, event_id, tickets FROM draw_entries
+WHERE tenant_id = $1 AND draw_id = $2
+ORDER BY event_id;
+
+-- name: CountDrawEntries :one
+SELECT COUNT(*)::int AS entries,
+       COALESCE(SUM(tickets), 0)::int AS tickets,
+       COUNT(DISTINCT customer_id)::int AS customers
+FROM draw_entries
+WHERE tenant_id = $1 AND draw_id = $2;
+
+-- name: InsertDrawWinner :exec
+INSERT INTO draw_winners (draw_id, tenant_id, position, customer_id, event_id)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: CompleteDraw :one
+UPDATE draws
+SET status = 'drawn',
+    seed = $3,
+    entries_hash = $4,
+    entry_count = $5,
+    drawn_at = $6,
+    updated_at = now()
+WHERE id = $1 AND tenant_id = $2 AND status = 'open'
+RETURNING *;
+
+-- name: CancelDraw :one
+UPDATE draws
+SET status = 'cancelled',
+    updated_at = now()
+WHERE id = $1 AND tenant_id = $2 AND status = 'open'
+RETURNING *;
+
+-- name: ListDrawWinners :many
+-- Winners with the prizes issued for their draw_won events; a winner has a
+-- row per prize, or one row without a prize
+SELECT w.position, w.customer_id, w.event_id,
+       cu.phone_e164, cu.external_ref,
+       i.id AS issuance_id, i.status AS issuance_status,
+       r.name AS reward_name, i.face_amount, i.currency
+FROM draw_winners w
+JOIN customers cu ON cu.id = w.customer_id
+LEFT JOIN issuances i ON i.event_id = w.event_id AND i.tenant_id = w.tenant_id
+LEFT JOIN reward_catalog r ON r.id = i.reward_id
+WHERE w.tenant_id = $1 AND w.draw_id = $2
+ORDER BY w.position, i.issued_at;
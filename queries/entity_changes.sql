@@ -0,0 +1,19 @@
+-- Entity change queries
+-- sqlc query file for the change feed polled by integrations
+
+-- name: ListEntityChanges :many
+-- Changes after the cursor, in feed order. Only changes of transactions
+-- older than every running transaction are listed: they are all committed
+-- or rolled back, so no change can later appear before the last one listed.
+SELECT * FROM entity_changes
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND (txid, id) > (sqlc.arg(after_txid)::bigint, sqlc.arg(after_id)::bigint)
+  AND txid < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+  AND entity_type = ANY(sqlc.arg(entity_types)::text[])
+ORDER BY txid, id
+LIMIT sqlc.arg(row_limit);
+
+-- name: DeleteEntityChangesBefore :execrows
+DELETE FROM entity_changes
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND changed_at < sqlc.arg(before);
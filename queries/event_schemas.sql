@@ -0,0 +1,21 @@
+-- Event schema registry queries
+
+-- name: UpsertEventSchema :one
+INSERT INTO event_schemas (tenant_id, event_type, schema, strict)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (tenant_id, event_type)
+DO UPDATE SET schema = EXCLUDED.schema, strict = EXCLUDED.strict, updated_at = now()
+RETURNING *;
+
+-- name: GetEventSchema :one
+SELECT * FROM event_schemas
+WHERE tenant_id = $1 AND event_type = $2;
+
+-- name: ListEventSchemas :many
+SELECT * FROM event_schemas
+WHERE tenant_id = $1
+ORDER BY event_type;
+
+-- name: DeleteEventSchema :exec
+DELETE FROM event_schemas
+WHERE tenant_id = $1 AND event_type = $2;
@@ -0,0 +1,29 @@
+-- Tenant-defined event type queries
+
+-- name: CreateEventType :one
+INSERT INTO event_types (tenant_id, name, description, expected_properties, active)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetEventTypeByName :one
+SELECT * FROM event_types
+WHERE tenant_id = $1 AND name = $2;
+
+-- name: ListEventTypes :many
+SELECT * FROM event_types
+WHERE tenant_id = $1
+ORDER BY name;
+
+-- name: UpdateEventType :one
+UPDATE event_types
+SET description = $3,
+    expected_properties = $4,
+    active = $5,
+    updated_at = now()
+WHERE tenant_id = $1 AND name = $2
+RETURNING *;
+
+-- name: DeactivateEventType :exec
+UPDATE event_types
+SET active = false, updated_at = now()
+WHERE tenant_id = $1 AND name = $2;
@@ -1,8 +1,19 @@
 -- name: InsertEvent :one
-INSERT INTO events (tenant_id, customer_id, event_type, properties, occurred_at, source, idempotency_key)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
+INSERT INTO events (tenant_id, customer_id, event_type, properties, occurred_at, source, idempotency_key, schema_errors, location_id, content_hash, duplicate_of, latitude, longitude)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 RETURNING *;
 
+-- name: InsertEventIfNew :one
+-- Inserts an event unless the tenant already has one with its idempotency
+-- key; no rows when it does
+INSERT INTO events (tenant_id, customer_id, event_type, properties, occurred_at, source, idempotency_key, schema_errors, location_id, content_hash, duplicate_of, latitude, longitude)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+ON CONFLICT (tenant_id, idempotency_key) DO NOTHING
+RETURNING *;
+
+-- name: GetEventByID :one
+SELECT * FROM events WHERE id = $1 AND tenant_id = $2;
+
 -- name: GetEventByIdemKey :one
 SELECT * FROM events WHERE tenant_id = $1 AND idempotency_key = $2;
 
@@ -15,3 +26,37 @@ SELECT * FROM events
 WHERE tenant_id = $1 AND customer_id = $2
 ORDER BY occurred_at DESC
 LIMIT $3 OFFSET $4;
+
+-- name: ListEventsByTenantRange :many
+SELECT * FROM events
+WHERE tenant_id = $1
+  AND occurred_at >= sqlc.arg('since')
+  AND occurred_at < sqlc.arg('until')
+ORDER BY occurred_at ASC;
+
+-- name: FindNearDuplicateEvent :one
+-- The earliest event with the same content that occurred within the window
+-- of occurred_at. Flagged duplicates are skipped so they all point at the
+-- original.
+SELECT * FROM events
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND content_hash = sqlc.arg(content_hash)
+  AND duplicate_of IS NULL
+  AND occurred_at >= sqlc.arg(occurred_at)::timestamptz - make_interval(secs => sqlc.arg(window_seconds)::int)
+  AND occurred_at <= sqlc.arg(occurred_at)::timestamptz + make_interval(secs => sqlc.arg(window_seconds)::int)
+ORDER BY occurred_at, id
+LIMIT 1;
+
+-- name: IncrementEventDedupStats :exec
+INSERT INTO event_dedup_stats (tenant_id, day, event_type, flagged, suppressed)
+VALUES (sqlc.arg(tenant_id), sqlc.arg(day), sqlc.arg(event_type), sqlc.arg(flagged), sqlc.arg(suppressed))
+ON CONFLICT (tenant_id, day, event_type) DO UPDATE
+SET flagged = event_dedup_stats.flagged + EXCLUDED.flagged,
+    suppressed = event_dedup_stats.suppressed + EXCLUDED.suppressed;
+
+-- name: ListEventDedupStats :many
+SELECT * FROM event_dedup_stats
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND day >= sqlc.arg(from_day)
+  AND day <= sqlc.arg(to_day)
+ORDER BY day, event_type;
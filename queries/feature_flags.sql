@@ -0,0 +1,22 @@
+-- Feature flag queries
+-- sqlc query file for per-tenant, per-channel feature overrides
+
+-- name: GetFeatureFlag :one
+SELECT enabled FROM feature_flags
+WHERE tenant_id = $1 AND channel = $2 AND feature = $3;
+
+-- name: ListFeatureFlags :many
+SELECT * FROM feature_flags
+WHERE tenant_id = $1
+ORDER BY channel, feature;
+
+-- name: SetFeatureFlag :one
+INSERT INTO feature_flags (tenant_id, channel, feature, enabled)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (tenant_id, channel, feature)
+DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = now()
+RETURNING *;
+
+-- name: DeleteFeatureFlag :execrows
+DELETE FROM feature_flags
+WHERE tenant_id = $1 AND channel = $2 AND feature = $3;
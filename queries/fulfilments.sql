@@ -0,0 +1,74 @@
+-- Fulfilment queries
+
+-- name: CreateFulfilment :one
+INSERT INTO fulfilments (tenant_id, issuance_id, customer_id, due_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (issuance_id) DO NOTHING
+RETURNING *;
+
+-- name: GetFulfilment :one
+SELECT * FROM fulfilments
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: ListFulfilments :many
+-- Open fulfilments due soonest first, delivered ones after. overdue limits
+-- them to undelivered fulfilments past their due date.
+SELECT * FROM fulfilments
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status)::text)
+  AND (NOT sqlc.arg(overdue)::boolean OR (status <> 'delivered' AND due_at <= sqlc.arg(now)::timestamptz))
+ORDER BY status = 'delivered', due_at, id
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountFulfilments :one
+SELECT COUNT(*) FROM fulfilments
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status)::text)
+  AND (NOT sqlc.arg(overdue)::boolean OR (status <> 'delivered' AND due_at <= sqlc.arg(now)::timestamptz));
+
+-- name: AdvanceFulfilment :one
+-- Moves a fulfilment from from_status to status, stamping the time of each
+-- step reached. Carrier, tracking ref and note are kept unless given. No row
+-- when the fulfilment has moved on in the meantime.
+UPDATE fulfilments
+SET status = sqlc.arg(status),
+    carrier = COALESCE(sqlc.narg(carrier), carrier),
+    tracking_ref = COALESCE(sqlc.narg(tracking_ref), tracking_ref),
+    note = COALESCE(sqlc.narg(note), note),
+    picked_at = COALESCE(picked_at, now()),
+    dispatched_at = CASE WHEN sqlc.arg(status)::text IN ('dispatched', 'delivered') THEN COALESCE(dispatched_at, now()) END,
+    delivered_at = CASE WHEN sqlc.arg(status)::text = 'delivered' THEN now() END,
+    updated_by = sqlc.narg(updated_by),
+    updated_at = now()
+WHERE id = sqlc.arg(id) AND tenant_id = sqlc.arg(tenant_id)
+  AND status = sqlc.arg(from_status)
+RETURNING *;
+
+-- name: ListCustomerFulfilments :many
+-- A customer's most recent fulfilments with the reward being delivered
+SELECT sqlc.embed(f), r.name AS reward_name
+FROM fulfilments f
+JOIN issuances i ON i.id = f.issuance_id AND i.tenant_id = f.tenant_id
+JOIN reward_catalog r ON r.id = i.reward_id AND r.tenant_id = i.tenant_id
+WHERE f.tenant_id = sqlc.arg(tenant_id) AND f.customer_id = sqlc.arg(customer_id)
+ORDER BY f.created_at DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListOverdueFulfilments :many
+-- Undelivered fulfilments past their due date that haven't been escalated
+SELECT sqlc.embed(f), r.name AS reward_name
+FROM fulfilments f
+JOIN issuances i ON i.id = f.issuance_id AND i.tenant_id = f.tenant_id
+JOIN reward_catalog r ON r.id = i.reward_id AND r.tenant_id = i.tenant_id
+WHERE f.tenant_id = sqlc.arg(tenant_id)
+  AND f.status <> 'delivered'
+  AND f.escalated_at IS NULL
+  AND f.due_at <= sqlc.arg(now)::timestamptz
+ORDER BY f.due_at
+LIMIT sqlc.arg(row_limit)
+FOR UPDATE OF f SKIP LOCKED;
+
+-- name: MarkFulfilmentEscalated :exec
+UPDATE fulfilments
+SET escalated_at = now()
+WHERE id = $1 AND tenant_id = $2;
@@ -0,0 +1,33 @@
+-- Issuance status history queries
+-- sqlc query file for issuance status transitions and their history
+
+-- name: TransitionIssuanceStatus :execrows
+-- Moves an issuance from one status to another and records the change in
+-- one statement. No row is affected when the issuance isn't in from_status.
+WITH updated AS (
+  UPDATE issuances
+  SET status = sqlc.arg(to_status)::text,
+      redeemed_at = CASE WHEN sqlc.arg(to_status)::text = 'redeemed' THEN now() ELSE redeemed_at END
+  WHERE issuances.id = sqlc.arg(issuance_id)
+    AND issuances.tenant_id = sqlc.arg(tenant_id)
+    AND issuances.status = sqlc.arg(from_status)::text
+  RETURNING issuances.id, issuances.tenant_id
+)
+INSERT INTO issuance_status_history (tenant_id, issuance_id, old_status, new_status, actor, channel)
+SELECT u.tenant_id, u.id, sqlc.arg(from_status)::text, sqlc.arg(to_status)::text, sqlc.arg(actor), sqlc.arg(channel)
+FROM updated u;
+
+-- name: RecordIssuanceCreated :exec
+INSERT INTO issuance_status_history (tenant_id, issuance_id, old_status, new_status, actor, channel)
+VALUES ($1, $2, NULL, $3, $4, $5);
+
+-- name: ListIssuanceStatusHistory :many
+SELECT * FROM issuance_status_history
+WHERE tenant_id = $1 AND issuance_id = $2
+ORDER BY id;
+
+-- name: GetIssuanceLastChange :one
+-- When the issuance's status last changed, used as its last-modified time
+SELECT COALESCE(MAX(created_at), 'epoch'::timestamptz)::timestamptz AS changed_at
+FROM issuance_status_history
+WHERE tenant_id = $1 AND issuance_id = $2;
@@ -0,0 +1,17 @@
+-- Issuance reissue queries
+-- sqlc query file for replacements of failed or lost rewards
+
+-- name: CreateIssuanceReissue :one
+INSERT INTO issuance_reissues
+  (tenant_id, original_id, replacement_id, customer_id, reason_code, note, created_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (original_id) DO NOTHING
+RETURNING *;
+
+-- name: GetIssuanceReissueByOriginal :one
+SELECT * FROM issuance_reissues
+WHERE tenant_id = $1 AND original_id = $2;
+
+-- name: GetIssuanceReissueByReplacement :one
+SELECT * FROM issuance_reissues
+WHERE tenant_id = $1 AND replacement_id = $2;
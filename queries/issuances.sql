@@ -1,14 +1,8 @@
 -- name: ReserveIssuance :one
-INSERT INTO issuances (tenant_id, customer_id, campaign_id, reward_id, status, currency, face_amount, cost_amount, issued_at)
-VALUES ($1, $2, $3, $4, 'reserved', $5, $6, $7, now())
+INSERT INTO issuances (tenant_id, customer_id, campaign_id, reward_id, status, currency, face_amount, cost_amount, issued_at, event_id, rule_id, value_inputs)
+VALUES ($1, $2, $3, $4, 'reserved', $5, $6, $7, now(), $8, $9, $10)
 RETURNING *;
 
--- name: UpdateIssuanceStatus :exec
-UPDATE issuances
-SET status = $4,
-    redeemed_at = CASE WHEN $4 = 'redeemed' THEN now() ELSE redeemed_at END
-WHERE id = $1 AND tenant_id = $2 AND status = $3;
-
 -- name: GetIssuanceByID :one
 SELECT * FROM issuances
 WHERE id = $1 AND tenant_id = $2;
@@ -24,6 +18,27 @@ SELECT * FROM issuances
 WHERE tenant_id = $1 AND customer_id = $2 AND status IN ('issued', 'reserved')
 ORDER BY issued_at DESC;
 
+-- name: GetCustomerPointsBalance :one
+-- Points credit rewards are credited when issued
+SELECT COALESCE(SUM((r.metadata->>'points_amount')::bigint), 0)::bigint AS points
+FROM issuances i
+JOIN reward_catalog r ON r.id = i.reward_id AND r.tenant_id = i.tenant_id
+WHERE i.tenant_id = $1
+  AND i.customer_id = $2
+  AND r.type = 'points_credit'
+  AND i.status IN ('issued', 'redeemed');
+
+-- name: SetIssuanceBudget :exec
+UPDATE issuances
+SET budget_id = $3
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: IssuanceCodeExists :one
+SELECT EXISTS (
+  SELECT 1 FROM issuances
+  WHERE tenant_id = $1 AND code = $2
+) AS taken;
+
 -- name: UpdateIssuanceDetails :exec
 UPDATE issuances
 SET code = $3,
@@ -38,3 +53,113 @@ WHERE status = 'issued'
   AND expires_at IS NOT NULL
   AND expires_at < NOW()
 FOR UPDATE SKIP LOCKED;
+
+-- name: ListStaleReservations :many
+SELECT * FROM issuances
+WHERE tenant_id = $1
+  AND status = 'reserved'
+  AND issued_at < sqlc.arg(reserved_before)::timestamptz
+ORDER BY issued_at
+LIMIT $2
+FOR UPDATE SKIP LOCKED;
+
+-- name: GetIssuanceByCode :one
+-- Case-insensitive code lookup with the issuance's reward and customer;
+-- code must be upper case
+SELECT sqlc.embed(i),
+       r.name AS reward_name,
+       r.type AS reward_type,
+       c.name AS customer_name,
+       c.phone_e164 AS customer_phone,
+       c.external_ref AS customer_external_ref,
+       c.status AS customer_status
+FROM issuances i
+JOIN reward_catalog r ON r.id = i.reward_id
+JOIN customers c ON c.id = i.customer_id
+WHERE i.tenant_id = sqlc.arg(tenant_id)
+  AND upper(i.code) = sqlc.arg(code)::text
+ORDER BY i.issued_at DESC
+LIMIT 1;
+
+-- name: GetIssuanceByNormalizedCode :one
+-- Like GetIssuanceByCode, ignoring spaces and dashes; code must be upper
+-- case without spaces or dashes
+SELECT sqlc.embed(i),
+       r.name AS reward_name,
+       r.type AS reward_type,
+       c.name AS customer_name,
+       c.phone_e164 AS customer_phone,
+       c.external_ref AS customer_external_ref,
+       c.status AS customer_status
+FROM issuances i
+JOIN reward_catalog r ON r.id = i.reward_id
+JOIN customers c ON c.id = i.customer_id
+WHERE i.tenant_id = sqlc.arg(tenant_id)
+  AND translate(upper(i.code), ' -', '') = sqlc.arg(code)::text
+ORDER BY i.issued_at DESC
+LIMIT 1;
+
+-- name: GetCustomerIssuanceByCode :one
+-- Case-insensitive lookup of one of a customer's issuances by code; code must
+-- be upper case. Active issuances win over spent ones with the same code.
+SELECT * FROM issuances
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND customer_id = sqlc.arg(customer_id)
+  AND upper(code) = sqlc.arg(code)::text
+ORDER BY status IN ('issued', 'reserved') DESC, issued_at DESC
+LIMIT 1;
+
+-- name: ListProcessableIssuances :many
+-- A tenant's reserved issuances due for processing, oldest first. The
+-- processor locks each one with LockReservedIssuance as it processes it.
+SELECT id FROM issuances
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND status = 'reserved'
+  AND (next_process_at IS NULL OR next_process_at <= sqlc.arg(now)::timestamptz)
+ORDER BY issued_at
+LIMIT sqlc.arg(row_limit);
+
+-- name: CountProcessableIssuances :one
+SELECT COUNT(*) FROM issuances
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND status = 'reserved'
+  AND (next_process_at IS NULL OR next_process_at <= sqlc.arg(now)::timestamptz);
+
+-- name: LockReservedIssuance :one
+-- Claims a reserved issuance for processing. No row when it was processed
+-- already or another worker holds it.
+SELECT * FROM issuances
+WHERE id = $1 AND tenant_id = $2 AND status = 'reserved'
+FOR UPDATE SKIP LOCKED;
+
+-- name: RecordIssuanceProcessFailure :one
+UPDATE issuances
+SET process_attempts = process_attempts + 1,
+    next_process_at = sqlc.arg(next_process_at),
+    last_process_error = sqlc.arg(last_process_error)
+WHERE id = sqlc.arg(id) AND tenant_id = sqlc.arg(tenant_id)
+RETURNING process_attempts;
+
+-- name: ResetIssuanceProcessing :exec
+UPDATE issuances
+SET process_attempts = 0,
+    next_process_at = NULL,
+    last_process_error = NULL
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: ListFailedIssuances :many
+-- A tenant's failed issuances for review, most recently failed first
+SELECT sqlc.embed(i), r.name AS reward_name, r.type AS reward_type,
+       (SELECT MAX(h.created_at) FROM issuance_status_history h
+        WHERE h.tenant_id = i.tenant_id AND h.issuance_id = i.id
+          AND h.new_status = 'failed')::timestamptz AS failed_at
+FROM issuances i
+JOIN reward_catalog r ON r.id = i.reward_id AND r.tenant_id = i.tenant_id
+WHERE i.tenant_id = sqlc.arg(tenant_id)
+  AND i.status = 'failed'
+ORDER BY failed_at DESC NULLS LAST, i.issued_at DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountFailedIssuances :one
+SELECT COUNT(*) FROM issuances
+WHERE tenant_id = $1 AND status = 'failed';
@@ -0,0 +1,85 @@
+-- Leaderboard queries
+-- sqlc query file for campaign leaderboards
+
+-- name: SetCampaignLeaderboardMetric :one
+UPDATE campaigns
+SET leaderboard_metric = sqlc.narg(leaderboard_metric)
+WHERE id = $1 AND tenant_id = $2
+RETURNING *;
+
+-- name: ListLeaderboardCampaigns :many
+SELECT * FROM campaigns
+WHERE tenant_id = $1
+  AND leaderboard_metric IS NOT NULL
+  AND archived_at IS NULL
+ORDER BY name;
+
+-- name: DeleteLeaderboardEntries :exec
+DELETE FROM leaderboard_entries
+WHERE tenant_id = $1 AND campaign_id = $2;
+
+-- name: RefreshLeaderboardEntries :execrows
+-- Ranks the campaign's customers by its leaderboard metric. Reversed events
+-- and cancelled or failed rewards do not count.
+INSERT INTO leaderboard_entries (tenant_id, campaign_id, customer_id, rank, score, computed_at)
+SELECT c.tenant_id, c.id, s.customer_id,
+       rank() OVER (ORDER BY s.score DESC),
+       s.score,
+       sqlc.arg(computed_at)::timestamptz
+FROM campaigns c
+CROSS JOIN LATERAL (
+  SELECT i.customer_id, SUM(i.face_amount) AS score
+  FROM issuances i
+  JOIN reward_catalog r ON r.id = i.reward_id
+  WHERE c.leaderboard_metric = 'points'
+    AND i.tenant_id = c.tenant_id
+    AND i.campaign_id = c.id
+    AND r.type = 'points_credit'
+    AND i.status IN ('issued','redeemed','expired')
+  GROUP BY i.customer_id
+  UNION ALL
+  SELECT e.customer_id, SUM((e.properties->>'amount')::numeric) AS score
+  FROM events e
+  WHERE c.leaderboard_metric = 'spend'
+    AND e.tenant_id = c.tenant_id
+    AND e.event_type = 'purchase'
+    AND e.customer_id IS NOT NULL
+    AND jsonb_typeof(e.properties->'amount') = 'number'
+    AND (c.start_at IS NULL OR e.occurred_at >= c.start_at)
+    AND (c.end_at IS NULL OR e.occurred_at < c.end_at)
+    AND NOT EXISTS (SELECT 1 FROM event_reversals er WHERE er.original_event_id = e.id)
+  GROUP BY e.customer_id
+  UNION ALL
+  SELECT e.customer_id, COUNT(*)::numeric AS score
+  FROM events e
+  WHERE c.leaderboard_metric = 'visits'
+    AND e.tenant_id = c.tenant_id
+    AND e.event_type = 'visit'
+    AND e.customer_id IS NOT NULL
+    AND (c.start_at IS NULL OR e.occurred_at >= c.start_at)
+    AND (c.end_at IS NULL OR e.occurred_at < c.end_at)
+    AND NOT EXISTS (SELECT 1 FROM event_reversals er WHERE er.original_event_id = e.id)
+  GROUP BY e.customer_id
+) s
+WHERE c.id = sqlc.arg(campaign_id) AND c.tenant_id = sqlc.arg(tenant_id)
+  AND s.score > 0;
+
+-- name: ListLeaderboardEntries :many
+SELECT le.customer_id, le.rank, le.score, le.computed_at,
+       cu.name AS customer_name, cu.phone_e164
+FROM leaderboard_entries le
+JOIN customers cu ON cu.id = le.customer_id
+WHERE le.tenant_id = $1 AND le.campaign_id = $2
+ORDER BY le.rank, le.customer_id
+LIMIT $3 OFFSET $4;
+
+-- name: CountLeaderboardEntries :one
+SELECT COUNT(*) FROM leaderboard_entries
+WHERE tenant_id = $1 AND campaign_id = $2;
+
+-- name: GetLeaderboardEntry :one
+SELECT le.customer_id, le.rank, le.score, le.computed_at,
+       cu.name AS customer_name, cu.phone_e164
+FROM leaderboard_entries le
+JOIN customers cu ON cu.id = le.customer_id
+WHERE le.tenant_id = $1 AND le.campaign_id = $2 AND le.customer_id = $3;
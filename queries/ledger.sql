@@ -49,3 +49,48 @@ WHERE tenant_id = $1
   AND created_at <= $4
 GROUP BY entry_type, currency
 ORDER BY entry_type;
+
+-- name: GetLedgerTotalsForPeriod :many
+-- Entry counts and sums per type within [from, to)
+SELECT
+  entry_type,
+  COUNT(*)::bigint AS entry_count,
+  COALESCE(SUM(amount), 0)::numeric(18,2) AS total_amount
+FROM ledger_entries
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND budget_id = sqlc.arg(budget_id)
+  AND created_at >= sqlc.arg(period_start)
+  AND created_at < sqlc.arg(period_end)
+GROUP BY entry_type
+ORDER BY entry_type;
+
+-- name: GetLedgerBalanceAt :one
+-- Balance derived from the ledger before a point in time, matching reconcile_budget
+SELECT COALESCE(SUM(amount) FILTER (WHERE entry_type IN ('fund','reserve','release','commit')), 0)::numeric(18,2) AS balance
+FROM ledger_entries
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND budget_id = sqlc.arg(budget_id)
+  AND created_at < sqlc.arg(before);
+
+-- name: ListLedgerChain :many
+-- A budget's ledger entries in chain order after an id, with their hashed
+-- fields formatted as ledger_entry_hash formats them
+SELECT
+  id,
+  tenant_id,
+  budget_id,
+  entry_type,
+  currency,
+  amount::text AS amount_text,
+  ref_type,
+  ref_id,
+  fallback_from,
+  (extract(epoch FROM created_at) * 1000000)::bigint AS created_micros,
+  prev_hash,
+  entry_hash
+FROM ledger_entries
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND budget_id = sqlc.arg(budget_id)
+  AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(row_limit);
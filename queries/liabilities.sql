@@ -0,0 +1,22 @@
+-- Liability ledger queries
+-- sqlc query file for the reward liability ledger
+
+-- name: GetLiabilitiesByRewardType :many
+-- Outstanding face value and movements per reward type up to as_of
+SELECT
+  rc.type AS reward_type,
+  le.currency,
+  COALESCE(SUM(le.credit - le.debit) FILTER (WHERE le.account = 'reward_liability'), 0)::numeric(18,2) AS outstanding,
+  COALESCE(SUM(le.credit) FILTER (WHERE le.account = 'reward_liability' AND le.entry_type = 'issue'), 0)::numeric(18,2) AS issued,
+  COALESCE(SUM(le.debit) FILTER (WHERE le.account = 'reward_liability' AND le.entry_type = 'redeem'), 0)::numeric(18,2) AS redeemed,
+  COALESCE(SUM(le.debit) FILTER (WHERE le.account = 'reward_liability' AND le.entry_type = 'expire'), 0)::numeric(18,2) AS expired,
+  COALESCE(SUM(le.debit) FILTER (WHERE le.account = 'reward_liability' AND le.entry_type = 'cancel'), 0)::numeric(18,2) AS cancelled,
+  (COUNT(*) FILTER (WHERE le.account = 'reward_liability' AND le.entry_type = 'issue')
+    - COUNT(*) FILTER (WHERE le.account = 'reward_liability' AND le.entry_type <> 'issue'))::bigint AS outstanding_count
+FROM liability_entries le
+JOIN reward_catalog rc ON rc.id = le.reward_id
+WHERE le.tenant_id = sqlc.arg(tenant_id)
+  AND le.created_at <= sqlc.arg(as_of)
+GROUP BY rc.type, le.currency
+ORDER BY rc.type, le.currency;
+
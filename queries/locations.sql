@@ -0,0 +1,33 @@
+-- Location queries
+
+-- name: CreateLocation :one
+INSERT INTO locations (tenant_id, name, code, region, latitude, longitude, active)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetLocationByID :one
+SELECT * FROM locations
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: ListLocations :many
+SELECT * FROM locations
+WHERE tenant_id = $1
+  AND (sqlc.narg('region')::text IS NULL OR lower(region) = lower(sqlc.narg('region')::text))
+ORDER BY name;
+
+-- name: UpdateLocation :one
+UPDATE locations
+SET name = $3,
+    code = $4,
+    region = $5,
+    latitude = $6,
+    longitude = $7,
+    active = $8,
+    updated_at = now()
+WHERE id = $1 AND tenant_id = $2
+RETURNING *;
+
+-- name: DeactivateLocation :exec
+UPDATE locations
+SET active = false, updated_at = now()
+WHERE id = $1 AND tenant_id = $2;
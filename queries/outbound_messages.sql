@@ -0,0 +1,76 @@
+-- Outbound message queries
+-- sqlc query file for queued WhatsApp messages
+
+-- name: EnqueueOutboundMessage :one
+INSERT INTO outbound_messages (tenant_id, recipient, payload, attempts, next_attempt_at, last_error)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ClaimOutboundMessages :many
+SELECT * FROM outbound_messages
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND status = 'pending'
+  AND next_attempt_at <= sqlc.arg(now)
+ORDER BY id
+LIMIT sqlc.arg(limit_count)
+FOR UPDATE SKIP LOCKED;
+
+-- name: CountOutboundSentSince :one
+SELECT COUNT(*) FROM outbound_messages
+WHERE tenant_id = $1
+  AND status = 'sent'
+  AND sent_at >= sqlc.arg(since);
+
+-- name: MarkOutboundMessageSent :exec
+UPDATE outbound_messages
+SET status = 'sent',
+    attempts = attempts + 1,
+    sent_at = now(),
+    last_error = NULL
+WHERE id = $1;
+
+-- name: MarkOutboundMessageFailed :exec
+UPDATE outbound_messages
+SET attempts = attempts + 1,
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    last_error = sqlc.arg(last_error)
+WHERE id = sqlc.arg(id);
+
+-- name: MarkOutboundMessageDead :exec
+UPDATE outbound_messages
+SET status = 'dead',
+    attempts = attempts + 1,
+    dead_at = now(),
+    last_error = sqlc.arg(last_error)
+WHERE id = sqlc.arg(id);
+
+-- name: DeleteSentOutboundMessages :execrows
+DELETE FROM outbound_messages
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND status = 'sent'
+  AND sent_at < sqlc.arg(before);
+
+-- name: GetOutboundMessageByID :one
+SELECT * FROM outbound_messages
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: ListOutboundMessages :many
+SELECT * FROM outbound_messages
+WHERE tenant_id = $1
+  AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status')::text)
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountOutboundMessages :one
+SELECT COUNT(*) FROM outbound_messages
+WHERE tenant_id = $1
+  AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status')::text);
+
+-- name: RetryOutboundMessage :one
+UPDATE outbound_messages
+SET status = 'pending',
+    attempts = 0,
+    next_attempt_at = now(),
+    dead_at = NULL
+WHERE id = $1 AND tenant_id = $2 AND status = 'dead'
+RETURNING *;
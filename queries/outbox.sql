@@ -0,0 +1,42 @@
+-- Outbox queries
+-- sqlc query file for domain events awaiting message bus publication
+
+-- name: EnqueueOutboxEvent :exec
+SELECT enqueue_outbox_event(
+  sqlc.arg(tenant_id)::uuid,
+  sqlc.arg(event_type)::text,
+  sqlc.arg(aggregate_id)::uuid,
+  sqlc.arg(payload)::jsonb
+);
+
+-- name: ClaimOutboxEvents :many
+SELECT * FROM outbox_events
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND published_at IS NULL
+ORDER BY id
+LIMIT sqlc.arg(limit_count)
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxEventPublished :exec
+UPDATE outbox_events
+SET published_at = now(),
+    attempts = attempts + 1,
+    last_error = NULL
+WHERE id = $1;
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE outbox_events
+SET attempts = attempts + 1,
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    last_error = sqlc.arg(last_error)
+WHERE id = sqlc.arg(id);
+
+-- name: DeletePublishedOutboxEvents :execrows
+DELETE FROM outbox_events
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND published_at < sqlc.arg(before);
+
+-- name: ListEventBusTenants :many
+SELECT * FROM tenants
+WHERE event_bus_enabled
+ORDER BY created_at;
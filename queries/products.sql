@@ -0,0 +1,27 @@
+-- Product queries
+
+-- name: UpsertProduct :one
+INSERT INTO products (tenant_id, sku, name, category, active)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (tenant_id, sku) DO UPDATE
+SET name = EXCLUDED.name,
+    category = EXCLUDED.category,
+    active = EXCLUDED.active,
+    updated_at = now()
+RETURNING *;
+
+-- name: GetProductBySKU :one
+SELECT * FROM products
+WHERE tenant_id = $1 AND sku = $2;
+
+-- name: ListProducts :many
+SELECT * FROM products
+WHERE tenant_id = $1
+  AND (sqlc.narg('category')::text IS NULL OR lower(category) = lower(sqlc.narg('category')::text))
+ORDER BY category, sku
+LIMIT $2 OFFSET $3;
+
+-- name: CountProducts :one
+SELECT COUNT(*) FROM products
+WHERE tenant_id = $1
+  AND (sqlc.narg('category')::text IS NULL OR lower(category) = lower(sqlc.narg('category')::text));
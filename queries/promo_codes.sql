@@ -0,0 +1,77 @@
+-- Promo code queries
+
+-- name: CreatePromoBatch :one
+INSERT INTO promo_batches (tenant_id, name, rule_id, kind, max_uses, per_customer_limit, starts_at, ends_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetPromoBatchByID :one
+SELECT * FROM promo_batches
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: ListPromoBatches :many
+SELECT b.*,
+       (SELECT COUNT(*) FROM promo_codes pc WHERE pc.batch_id = b.id)::int AS code_count,
+       (SELECT COUNT(*) FROM promo_redemptions pr WHERE pr.batch_id = b.id)::int AS redemption_count
+FROM promo_batches b
+WHERE b.tenant_id = $1
+ORDER BY b.created_at DESC;
+
+-- name: SetPromoBatchActive :one
+UPDATE promo_batches
+SET active = $3,
+    updated_at = now()
+WHERE id = $1 AND tenant_id = $2
+RETURNING *;
+
+-- name: InsertPromoCodes :execrows
+-- Inserts codes into a batch, skipping any already taken in the tenant
+INSERT INTO promo_codes (tenant_id, batch_id, code)
+SELECT sqlc.arg(tenant_id), sqlc.arg(batch_id), unnest(sqlc.arg(codes)::text[])
+ON CONFLICT (tenant_id, code) DO NOTHING;
+
+-- name: ListPromoCodes :many
+SELECT * FROM promo_codes
+WHERE tenant_id = $1 AND batch_id = $2
+ORDER BY created_at, code
+LIMIT $3 OFFSET $4;
+
+-- name: CountPromoCodes :one
+SELECT COUNT(*) FROM promo_codes
+WHERE tenant_id = $1 AND batch_id = $2;
+
+-- name: GetPromoCodeForUpdate :one
+SELECT sqlc.embed(promo_codes), sqlc.embed(promo_batches)
+FROM promo_codes
+JOIN promo_batches ON promo_batches.id = promo_codes.batch_id
+WHERE promo_codes.tenant_id = $1 AND promo_codes.code = $2
+FOR UPDATE OF promo_codes;
+
+-- name: IncrementPromoCodeUses :exec
+UPDATE promo_codes
+SET uses = uses + 1
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: CountCustomerPromoRedemptions :one
+SELECT COUNT(*) FROM promo_redemptions
+WHERE tenant_id = $1 AND batch_id = $2 AND customer_id = $3;
+
+-- name: LockCustomerPromoBatch :exec
+-- Serializes one customer's redemptions from a batch until the transaction
+-- ends, so the per-customer limit holds for concurrent submissions
+SELECT pg_advisory_xact_lock(hashtextextended('promo:' || (sqlc.arg(batch_id)::uuid)::text || ':' || (sqlc.arg(customer_id)::uuid)::text, 0));
+
+-- name: InsertPromoRedemption :one
+INSERT INTO promo_redemptions (tenant_id, batch_id, code_id, customer_id, event_id, channel)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: InsertPromoAttempt :exec
+INSERT INTO promo_attempts (tenant_id, customer_id, code, channel, succeeded)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: CountRecentPromoFailures :one
+SELECT COUNT(*) FROM promo_attempts
+WHERE tenant_id = $1 AND customer_id = $2
+  AND NOT succeeded
+  AND created_at > sqlc.arg(since)::timestamptz;
@@ -0,0 +1,65 @@
+-- Receipt queries
+
+-- name: CreateReceipt :one
+INSERT INTO receipts (
+  tenant_id, customer_id, source, mime_type, image_sha256,
+  ocr_provider, ocr_text, ocr_error, amount, currency, receipt_date, store_name
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+)
+RETURNING *;
+
+-- name: CreateReceiptImage :exec
+INSERT INTO receipt_images (receipt_id, tenant_id, image)
+VALUES ($1, $2, $3);
+
+-- name: GetReceiptByID :one
+SELECT * FROM receipts
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: GetReceiptByHash :one
+SELECT * FROM receipts
+WHERE tenant_id = $1 AND image_sha256 = $2;
+
+-- name: GetReceiptForReview :one
+SELECT * FROM receipts
+WHERE id = $1 AND tenant_id = $2
+FOR UPDATE;
+
+-- name: GetReceiptImage :one
+SELECT r.mime_type, i.image
+FROM receipts r
+JOIN receipt_images i ON i.receipt_id = r.id
+WHERE r.id = $1 AND r.tenant_id = $2;
+
+-- name: ListReceipts :many
+SELECT * FROM receipts
+WHERE tenant_id = $1
+  AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status')::text)
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountReceipts :one
+SELECT COUNT(*) FROM receipts
+WHERE tenant_id = $1
+  AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status')::text);
+
+-- name: ApproveReceipt :exec
+UPDATE receipts
+SET status = 'approved',
+    amount = $3,
+    currency = $4,
+    receipt_date = $5,
+    location_id = $6,
+    event_id = $7,
+    reviewed_by = $8,
+    reviewed_at = now()
+WHERE id = $1 AND tenant_id = $2 AND status = 'pending';
+
+-- name: RejectReceipt :execrows
+UPDATE receipts
+SET status = 'rejected',
+    rejection_reason = $3,
+    reviewed_by = $4,
+    reviewed_at = now()
+WHERE id = $1 AND tenant_id = $2 AND status = 'pending';
@@ -0,0 +1,35 @@
+-- Redemption receipt queries
+-- sqlc query file for the receipts of redeemed issuances
+
+-- name: CreateRedemptionReceipt :one
+-- Records the receipt of a redeemed issuance from its redemption details and
+-- its reward's current name
+INSERT INTO redemption_receipts (
+  tenant_id, issuance_id, customer_id, reward_id, reward_name, value, currency,
+  store_id, staff_user_id, staff_ref, redeemed_by, channel, redeemed_at
+)
+SELECT i.tenant_id, i.id, i.customer_id, i.reward_id, r.name, i.face_amount, i.currency,
+       i.redeemed_store_id, sqlc.narg(staff_user_id)::uuid, i.redeemed_staff_ref,
+       sqlc.arg(redeemed_by)::text, sqlc.arg(channel)::text, i.redeemed_at
+FROM issuances i
+JOIN reward_catalog r ON r.id = i.reward_id AND r.tenant_id = i.tenant_id
+WHERE i.id = sqlc.arg(issuance_id)
+  AND i.tenant_id = sqlc.arg(tenant_id)
+  AND i.status = 'redeemed'
+RETURNING *;
+
+-- name: SetRedemptionReceiptConfirmation :exec
+UPDATE redemption_receipts
+SET confirmation_status = sqlc.arg(confirmation_status)::text,
+    confirmed_at = now()
+WHERE tenant_id = sqlc.arg(tenant_id) AND id = sqlc.arg(id);
+
+-- name: ListCustomerRedemptionReceipts :many
+SELECT * FROM redemption_receipts
+WHERE tenant_id = $1 AND customer_id = $2
+ORDER BY redeemed_at DESC
+LIMIT $3 OFFSET $4;
+
+-- name: CountCustomerRedemptionReceipts :one
+SELECT COUNT(*) FROM redemption_receipts
+WHERE tenant_id = $1 AND customer_id = $2;
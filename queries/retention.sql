@@ -0,0 +1,96 @@
+-- Data retention queries
+-- sqlc query file for retention settings and purges
+
+-- name: UpdateTenantRetention :exec
+UPDATE tenants
+SET event_retention_months = sqlc.narg(event_retention_months),
+    message_retention_months = sqlc.narg(message_retention_months),
+    issuance_retention_months = sqlc.narg(issuance_retention_months)
+WHERE id = sqlc.arg(id);
+
+-- name: ListRetentionTenants :many
+SELECT * FROM tenants
+WHERE event_retention_months IS NOT NULL
+   OR message_retention_months IS NOT NULL
+   OR issuance_retention_months IS NOT NULL
+ORDER BY created_at;
+
+-- Events past retention that nothing references are deleted outright
+-- name: DeleteExpiredEvents :execrows
+DELETE FROM events ev
+WHERE ev.tenant_id = sqlc.arg(tenant_id)
+  AND ev.occurred_at < sqlc.arg(before)
+  AND NOT EXISTS (SELECT 1 FROM issuances i WHERE i.event_id = ev.id)
+  AND NOT EXISTS (SELECT 1 FROM dead_letters dl WHERE dl.event_id = ev.id)
+  AND NOT EXISTS (SELECT 1 FROM receipts r WHERE r.event_id = ev.id)
+  AND NOT EXISTS (SELECT 1 FROM survey_responses sr WHERE sr.event_id = ev.id)
+  AND NOT EXISTS (
+    SELECT 1 FROM event_reversals er
+    WHERE er.reversal_event_id = ev.id OR er.original_event_id = ev.id
+  )
+  AND NOT EXISTS (SELECT 1 FROM challenge_progress cp WHERE cp.event_id = ev.id)
+  AND NOT EXISTS (SELECT 1 FROM challenge_events ce WHERE ce.event_id = ev.id)
+  AND NOT EXISTS (SELECT 1 FROM draw_entries de WHERE de.event_id = ev.id)
+  AND NOT EXISTS (SELECT 1 FROM draw_winners dw WHERE dw.event_id = ev.id)
+  AND NOT EXISTS (SELECT 1 FROM promo_redemptions pr WHERE pr.event_id = ev.id)
+  AND NOT EXISTS (SELECT 1 FROM winback_contacts wc WHERE wc.event_id = ev.id);
+
+-- Events past retention that are still referenced keep their type, customer
+-- and time but lose their properties
+-- name: AnonymizeExpiredEvents :execrows
+UPDATE events
+SET properties = '{}',
+    schema_errors = NULL,
+    idempotency_key = 'anonymized:' || id::text,
+    content_hash = NULL,
+    anonymized_at = now()
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND occurred_at < sqlc.arg(before)
+  AND anonymized_at IS NULL;
+
+-- name: DeleteExpiredOutboundMessages :execrows
+DELETE FROM outbound_messages
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND status IN ('sent', 'dead')
+  AND created_at < sqlc.arg(before);
+
+-- name: DeleteExpiredWASessions :execrows
+DELETE FROM wa_sessions
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND last_msg_at < sqlc.arg(before);
+
+-- name: DeleteExpiredUSSDSessions :execrows
+DELETE FROM ussd_sessions
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND last_input_at < sqlc.arg(before);
+
+-- Expired issuances stay for the liability ledger but lose their codes
+-- name: AnonymizeExpiredIssuances :execrows
+UPDATE issuances
+SET code = NULL,
+    external_ref = NULL,
+    anonymized_at = now()
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND status = 'expired'
+  AND expires_at < sqlc.arg(before)
+  AND anonymized_at IS NULL;
+
+-- name: InsertPurgeRun :one
+INSERT INTO purge_runs (
+  tenant_id, dry_run, event_cutoff, message_cutoff, issuance_cutoff,
+  events_deleted, events_anonymized, messages_deleted, sessions_deleted,
+  issuances_anonymized
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+RETURNING *;
+
+-- name: ListPurgeRuns :many
+SELECT * FROM purge_runs
+WHERE tenant_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountPurgeRuns :one
+SELECT COUNT(*) FROM purge_runs
+WHERE tenant_id = $1;
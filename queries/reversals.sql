@@ -0,0 +1,31 @@
+-- Event reversal queries
+-- sqlc query file for reversals of refunded events
+
+-- name: CreateEventReversal :one
+INSERT INTO event_reversals (tenant_id, reversal_event_id, original_event_id, reason)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (tenant_id, original_event_id) DO NOTHING
+RETURNING *;
+
+-- name: GetEventReversal :one
+-- Finds the reversal of an event, looked up by either the original or the
+-- reversal event ID
+SELECT * FROM event_reversals
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND (original_event_id = sqlc.arg(event_id) OR reversal_event_id = sqlc.arg(event_id));
+
+-- name: AddEventReversalIssuance :exec
+INSERT INTO event_reversal_issuances
+  (reversal_id, tenant_id, issuance_id, previous_status, action, released_amount, currency)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListEventReversalIssuances :many
+SELECT * FROM event_reversal_issuances
+WHERE reversal_id = $1 AND tenant_id = $2
+ORDER BY issuance_id;
+
+-- name: ListEventIssuancesForUpdate :many
+SELECT * FROM issuances
+WHERE tenant_id = $1 AND event_id = $2
+ORDER BY issued_at, id
+FOR UPDATE;
@@ -7,17 +7,57 @@ RETURNING *;
 SELECT * FROM reward_catalog
 WHERE id = $1 AND tenant_id = $2;
 
+-- name: GetRewardByName :one
+SELECT * FROM reward_catalog
+WHERE tenant_id = $1 AND name = $2 AND archived_at IS NULL;
+
 -- name: ListRewards :many
 SELECT * FROM reward_catalog
-WHERE tenant_id = $1
+WHERE tenant_id = $1 AND archived_at IS NULL
 ORDER BY name;
 
 -- name: ListActiveRewards :many
 SELECT * FROM reward_catalog
-WHERE tenant_id = $1 AND active = true
+WHERE tenant_id = $1 AND active = true AND archived_at IS NULL
+ORDER BY name;
+
+-- name: ListRewardsIncludingArchived :many
+SELECT * FROM reward_catalog
+WHERE tenant_id = $1
 ORDER BY name;
 
 -- name: UpdateRewardStatus :exec
 UPDATE reward_catalog
 SET active = $3
 WHERE id = $1 AND tenant_id = $2;
+
+-- name: UpdateRewardDefinition :one
+UPDATE reward_catalog
+SET face_value = $3,
+    currency = $4,
+    metadata = $5,
+    active = $6
+WHERE id = $1 AND tenant_id = $2
+RETURNING *;
+
+-- name: CountLiveRulesForReward :one
+-- Active rules issuing the reward that are not in an inactive or archived
+-- campaign
+SELECT count(*) FROM rules r
+LEFT JOIN campaigns c ON c.id = r.campaign_id
+WHERE r.tenant_id = $1
+  AND r.reward_id = $2
+  AND r.active = true
+  AND r.archived_at IS NULL
+  AND (r.campaign_id IS NULL OR (c.status = 'active' AND c.archived_at IS NULL));
+
+-- name: ArchiveReward :execrows
+UPDATE reward_catalog
+SET archived_at = now(),
+    active = false
+WHERE id = $1 AND tenant_id = $2 AND archived_at IS NULL;
+
+-- name: RestoreReward :execrows
+UPDATE reward_catalog
+SET archived_at = NULL
+WHERE id = $1 AND tenant_id = $2 AND archived_at IS NOT NULL;
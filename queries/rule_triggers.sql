@@ -0,0 +1,14 @@
+-- name: ClaimRuleTrigger :execrows
+-- Records that the event triggered the rule. No row is inserted when the
+-- trigger was already claimed, by an earlier run or a concurrent one, in
+-- which case the rule must not issue again.
+INSERT INTO rule_triggers (event_id, rule_id, tenant_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (event_id, rule_id) DO NOTHING;
+
+-- name: ListEventTriggerIssuances :many
+-- The issuances of the rules the event has already triggered
+SELECT i.* FROM issuances i
+JOIN rule_triggers rt ON rt.event_id = i.event_id AND rt.rule_id = i.rule_id
+WHERE i.tenant_id = $1 AND i.event_id = $2
+ORDER BY i.issued_at, i.id;
@@ -1,6 +1,6 @@
 -- name: CreateRule :one
-INSERT INTO rules (tenant_id, campaign_id, name, event_type, conditions, reward_id, per_user_cap, global_cap, cool_down_sec, active)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+INSERT INTO rules (tenant_id, campaign_id, name, event_type, conditions, reward_id, per_user_cap, global_cap, cool_down_sec, active, quantity)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 RETURNING *;
 
 -- name: GetRuleByID :one
@@ -9,10 +9,88 @@ WHERE id = $1 AND tenant_id = $2;
 
 -- name: ListActiveRules :many
 SELECT * FROM rules
-WHERE tenant_id = $1 AND active = true
+WHERE tenant_id = $1 AND active = true AND archived_at IS NULL
+ORDER BY name;
+
+-- name: ListRules :many
+SELECT * FROM rules
+WHERE tenant_id = $1
+  AND (sqlc.arg(include_archived)::boolean OR archived_at IS NULL)
 ORDER BY name;
 
 -- name: UpdateRuleStatus :exec
 UPDATE rules
 SET active = $3
 WHERE id = $1 AND tenant_id = $2;
+
+-- name: UpdateRuleDefinition :one
+UPDATE rules
+SET event_type = $3,
+    conditions = $4,
+    reward_id = $5,
+    per_user_cap = $6,
+    global_cap = $7,
+    cool_down_sec = $8,
+    active = $9,
+    quantity = $10
+WHERE id = $1 AND tenant_id = $2
+RETURNING *;
+
+-- name: ListRulesByCampaign :many
+SELECT * FROM rules
+WHERE tenant_id = $1 AND campaign_id = $2 AND archived_at IS NULL
+ORDER BY name;
+
+-- name: GetRuleForUpdate :one
+SELECT * FROM rules
+WHERE id = $1 AND tenant_id = $2
+FOR UPDATE;
+
+-- name: ArchiveRule :execrows
+UPDATE rules
+SET archived_at = now(),
+    active = false
+WHERE id = $1 AND tenant_id = $2 AND archived_at IS NULL;
+
+-- name: RestoreRule :execrows
+UPDATE rules
+SET archived_at = NULL
+WHERE id = $1 AND tenant_id = $2 AND archived_at IS NOT NULL;
+
+-- name: ListRuleRewards :many
+-- The rewards a rule issues on top of its own reward, in order
+SELECT * FROM rule_rewards
+WHERE tenant_id = $1 AND rule_id = $2
+ORDER BY position;
+
+-- name: ListRuleRewardsForRules :many
+SELECT * FROM rule_rewards
+WHERE tenant_id = $1 AND rule_id = ANY(sqlc.arg(rule_ids)::uuid[])
+ORDER BY rule_id, position;
+
+-- name: CreateRuleReward :one
+INSERT INTO rule_rewards (tenant_id, rule_id, reward_id, quantity, position)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: DeleteRuleRewards :exec
+DELETE FROM rule_rewards
+WHERE tenant_id = $1 AND rule_id = $2;
+
+-- name: CopyRuleRewards :exec
+INSERT INTO rule_rewards (tenant_id, rule_id, reward_id, quantity, position)
+SELECT rr.tenant_id, sqlc.arg(to_rule_id)::uuid, rr.reward_id, rr.quantity, rr.position
+FROM rule_rewards rr
+WHERE rr.tenant_id = sqlc.arg(tenant_id) AND rr.rule_id = sqlc.arg(from_rule_id);
+
+-- name: ListEventsForBacktest :many
+-- A rule's candidate events in a window, oldest first, leaving out events
+-- that were since reversed
+SELECT e.* FROM events e
+WHERE e.tenant_id = $1
+  AND e.event_type = $2
+  AND e.occurred_at >= sqlc.arg(since)::timestamptz
+  AND e.occurred_at < sqlc.arg(until)::timestamptz
+  AND NOT EXISTS (SELECT 1 FROM event_reversals er WHERE er.original_event_id = e.id)
+ORDER BY e.occurred_at, e.id
+LIMIT sqlc.arg(max_events);
@@ -0,0 +1,69 @@
+-- Budget spend attribution queries
+-- sqlc query file for breaking down budget ledger spend
+
+-- Each query sums a budget's issuance ledger entries per group. Entries whose
+-- issuance is unknown (e.g. reservations recorded before issuances were
+-- referenced) are grouped under a NULL id.
+
+-- name: GetBudgetSpendByCampaign :many
+SELECT
+  i.campaign_id AS group_id,
+  c.name AS group_name,
+  COALESCE(SUM(le.amount) FILTER (WHERE le.entry_type = 'reserve'), 0)::numeric(18,2) AS reserved,
+  COALESCE(-SUM(le.amount) FILTER (WHERE le.entry_type = 'release'), 0)::numeric(18,2) AS released,
+  COALESCE(SUM(le.amount) FILTER (WHERE le.entry_type = 'charge'), 0)::numeric(18,2) AS charged,
+  COALESCE(-SUM(le.amount) FILTER (WHERE le.entry_type = 'reverse'), 0)::numeric(18,2) AS reversed,
+  COALESCE(SUM(le.amount) FILTER (WHERE le.entry_type IN ('reserve', 'release', 'reverse')), 0)::numeric(18,2) AS net,
+  COUNT(DISTINCT i.id)::bigint AS issuance_count
+FROM ledger_entries le
+LEFT JOIN issuances i ON i.id = le.ref_id AND i.tenant_id = le.tenant_id
+LEFT JOIN campaigns c ON c.id = i.campaign_id
+WHERE le.tenant_id = sqlc.arg(tenant_id)
+  AND le.budget_id = sqlc.arg(budget_id)
+  AND le.ref_type = 'issuance'
+  AND (sqlc.narg(from_time)::timestamptz IS NULL OR le.created_at >= sqlc.narg(from_time))
+  AND (sqlc.narg(to_time)::timestamptz IS NULL OR le.created_at < sqlc.narg(to_time))
+GROUP BY i.campaign_id, c.name
+ORDER BY net DESC;
+
+-- name: GetBudgetSpendByRule :many
+SELECT
+  i.rule_id AS group_id,
+  r.name AS group_name,
+  COALESCE(SUM(le.amount) FILTER (WHERE le.entry_type = 'reserve'), 0)::numeric(18,2) AS reserved,
+  COALESCE(-SUM(le.amount) FILTER (WHERE le.entry_type = 'release'), 0)::numeric(18,2) AS released,
+  COALESCE(SUM(le.amount) FILTER (WHERE le.entry_type = 'charge'), 0)::numeric(18,2) AS charged,
+  COALESCE(-SUM(le.amount) FILTER (WHERE le.entry_type = 'reverse'), 0)::numeric(18,2) AS reversed,
+  COALESCE(SUM(le.amount) FILTER (WHERE le.entry_type IN ('reserve', 'release', 'reverse')), 0)::numeric(18,2) AS net,
+  COUNT(DISTINCT i.id)::bigint AS issuance_count
+FROM ledger_entries le
+LEFT JOIN issuances i ON i.id = le.ref_id AND i.tenant_id = le.tenant_id
+LEFT JOIN rules r ON r.id = i.rule_id
+WHERE le.tenant_id = sqlc.arg(tenant_id)
+  AND le.budget_id = sqlc.arg(budget_id)
+  AND le.ref_type = 'issuance'
+  AND (sqlc.narg(from_time)::timestamptz IS NULL OR le.created_at >= sqlc.narg(from_time))
+  AND (sqlc.narg(to_time)::timestamptz IS NULL OR le.created_at < sqlc.narg(to_time))
+GROUP BY i.rule_id, r.name
+ORDER BY net DESC;
+
+-- name: GetBudgetSpendByReward :many
+SELECT
+  i.reward_id AS group_id,
+  rc.name AS group_name,
+  COALESCE(SUM(le.amount) FILTER (WHERE le.entry_type = 'reserve'), 0)::numeric(18,2) AS reserved,
+  COALESCE(-SUM(le.amount) FILTER (WHERE le.entry_type = 'release'), 0)::numeric(18,2) AS released,
+  COALESCE(SUM(le.amount) FILTER (WHERE le.entry_type = 'charge'), 0)::numeric(18,2) AS charged,
+  COALESCE(-SUM(le.amount) FILTER (WHERE le.entry_type = 'reverse'), 0)::numeric(18,2) AS reversed,
+  COALESCE(SUM(le.amount) FILTER (WHERE le.entry_type IN ('reserve', 'release', 'reverse')), 0)::numeric(18,2) AS net,
+  COUNT(DISTINCT i.id)::bigint AS issuance_count
+FROM ledger_entries le
+LEFT JOIN issuances i ON i.id = le.ref_id AND i.tenant_id = le.tenant_id
+LEFT JOIN reward_catalog rc ON rc.id = i.reward_id
+WHERE le.tenant_id = sqlc.arg(tenant_id)
+  AND le.budget_id = sqlc.arg(budget_id)
+  AND le.ref_type = 'issuance'
+  AND (sqlc.narg(from_time)::timestamptz IS NULL OR le.created_at >= sqlc.narg(from_time))
+  AND (sqlc.narg(to_time)::timestamptz IS NULL OR le.created_at < sqlc.narg(to_time))
+GROUP BY i.reward_id, rc.name
+ORDER BY net DESC;
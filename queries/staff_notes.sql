@@ -0,0 +1,28 @@
+-- Staff note queries
+-- sqlc query file for internal notes on customers and issuances
+
+-- name: CreateStaffNote :one
+INSERT INTO staff_notes (tenant_id, customer_id, issuance_id, author_id, body)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ListCustomerStaffNotes :many
+-- A customer's notes, including those on their issuances, with their authors
+SELECT n.*, s.full_name AS author_name, s.email AS author_email
+FROM staff_notes n
+JOIN staff_users s ON s.id = n.author_id
+WHERE n.tenant_id = $1 AND n.customer_id = $2
+ORDER BY n.created_at DESC
+LIMIT $3 OFFSET $4;
+
+-- name: CountCustomerStaffNotes :one
+SELECT COUNT(*) FROM staff_notes
+WHERE tenant_id = $1 AND customer_id = $2;
+
+-- name: ListIssuanceStaffNotes :many
+-- An issuance's notes with their authors
+SELECT n.*, s.full_name AS author_name, s.email AS author_email
+FROM staff_notes n
+JOIN staff_users s ON s.id = n.author_id
+WHERE n.tenant_id = $1 AND n.issuance_id = $2
+ORDER BY n.created_at DESC;
@@ -42,3 +42,8 @@ WHERE id = $1 AND tenant_id = $2;
 -- name: DeleteStaffUser :exec
 DELETE FROM staff_users
 WHERE id = $1 AND tenant_id = $2;
+
+-- name: ListApprovers :many
+SELECT * FROM staff_users
+WHERE tenant_id = $1 AND role IN ('owner', 'manager')
+ORDER BY created_at;
@@ -0,0 +1,64 @@
+-- Supplier queries
+
+-- name: CreateSupplier :one
+INSERT INTO suppliers (tenant_id, name, contact_name, contact_email, contact_phone, integration, active)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetSupplierByID :one
+SELECT * FROM suppliers
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: ListSuppliers :many
+SELECT * FROM suppliers
+WHERE tenant_id = $1
+  AND (NOT sqlc.arg(active_only)::boolean OR active)
+ORDER BY name;
+
+-- name: UpdateSupplier :one
+UPDATE suppliers
+SET name = $3,
+    contact_name = $4,
+    contact_email = $5,
+    contact_phone = $6,
+    integration = $7,
+    active = $8,
+    updated_at = now()
+WHERE id = $1 AND tenant_id = $2
+RETURNING *;
+
+-- name: DeactivateSupplier :exec
+UPDATE suppliers
+SET active = false, updated_at = now()
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: CountSupplierRewards :one
+-- Active rewards fulfilled by a supplier
+SELECT COUNT(*) FROM reward_catalog
+WHERE tenant_id = $1 AND supplier_id = $2 AND active AND archived_at IS NULL;
+
+-- name: SupplierPerformance :many
+-- Per supplier outcomes of the issuances reserved in a period. Fulfilled
+-- issuances reached 'issued'; latency is from reservation to issue.
+SELECT s.id AS supplier_id,
+       s.name AS supplier_name,
+       COUNT(i.id) AS total,
+       COUNT(f.issued_at) AS fulfilled,
+       COUNT(i.id) FILTER (WHERE i.status = 'failed') AS failed,
+       COUNT(i.id) FILTER (WHERE i.status = 'reserved') AS pending,
+       COALESCE(AVG(EXTRACT(EPOCH FROM f.issued_at - i.issued_at)), 0)::float8 AS avg_latency_seconds,
+       COALESCE(PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM f.issued_at - i.issued_at)), 0)::float8 AS p95_latency_seconds
+FROM suppliers s
+LEFT JOIN reward_catalog r ON r.tenant_id = s.tenant_id AND r.supplier_id = s.id
+LEFT JOIN issuances i ON i.tenant_id = r.tenant_id AND i.reward_id = r.id
+  AND i.issued_at >= sqlc.arg(from_time)::timestamptz
+  AND i.issued_at < sqlc.arg(to_time)::timestamptz
+LEFT JOIN LATERAL (
+  SELECT MIN(h.created_at) AS issued_at
+  FROM issuance_status_history h
+  WHERE h.tenant_id = i.tenant_id AND h.issuance_id = i.id AND h.new_status = 'issued'
+) f ON true
+WHERE s.tenant_id = sqlc.arg(tenant_id)
+  AND (sqlc.narg(supplier_id)::uuid IS NULL OR s.id = sqlc.narg(supplier_id)::uuid)
+GROUP BY s.id, s.name
+ORDER BY s.name;
@@ -0,0 +1,69 @@
+-- Survey queries
+
+-- name: CreateSurvey :one
+INSERT INTO surveys (tenant_id, name, trigger, questions, active)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetSurveyByID :one
+SELECT * FROM surveys
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: ListSurveys :many
+SELECT * FROM surveys
+WHERE tenant_id = $1
+ORDER BY created_at DESC;
+
+-- name: UpdateSurvey :one
+UPDATE surveys
+SET name = $3,
+    trigger = $4,
+    questions = $5,
+    active = $6,
+    updated_at = now()
+WHERE id = $1 AND tenant_id = $2
+RETURNING *;
+
+-- name: GetActiveSurveyByTrigger :one
+SELECT * FROM surveys
+WHERE tenant_id = $1 AND trigger = $2 AND active = true
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: CreateSurveyResponse :one
+INSERT INTO survey_responses (tenant_id, survey_id, customer_id, issuance_id)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: AbandonOpenSurveyResponses :exec
+UPDATE survey_responses
+SET status = 'abandoned'
+WHERE tenant_id = $1 AND customer_id = $2 AND status = 'in_progress';
+
+-- name: GetSurveyResponseForUpdate :one
+SELECT * FROM survey_responses
+WHERE id = $1 AND tenant_id = $2
+FOR UPDATE;
+
+-- name: RecordSurveyAnswer :exec
+UPDATE survey_responses
+SET answers = $3,
+    current_question = $4
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: CompleteSurveyResponse :exec
+UPDATE survey_responses
+SET status = 'completed',
+    event_id = $3,
+    completed_at = now()
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: ListCompletedSurveyAnswers :many
+SELECT answers FROM survey_responses
+WHERE survey_id = $1 AND tenant_id = $2 AND status = 'completed';
+
+-- name: CountSurveyResponsesByStatus :many
+SELECT status, COUNT(*) AS count
+FROM survey_responses
+WHERE survey_id = $1 AND tenant_id = $2
+GROUP BY status;
@@ -0,0 +1,35 @@
+-- Tenant health queries
+-- sqlc query file for the platform operators' tenant health report
+
+-- name: GetTenantHealth :one
+-- Counts a tenant's activity and failures since a point in time, and its
+-- current backlogs and budgets over their caps
+SELECT
+  (SELECT COUNT(*) FROM events e
+   WHERE e.tenant_id = sqlc.arg(tenant_id) AND e.created_at >= sqlc.arg(since))::bigint AS events,
+  (SELECT COUNT(*) FROM dead_letters dl
+   WHERE dl.tenant_id = sqlc.arg(tenant_id) AND dl.created_at >= sqlc.arg(since))::bigint AS dead_letters,
+  (SELECT COUNT(*) FROM issuance_status_history h
+   WHERE h.tenant_id = sqlc.arg(tenant_id) AND h.new_status = 'failed' AND h.created_at >= sqlc.arg(since))::bigint AS failed_issuances,
+  (SELECT COUNT(*) FROM dead_letters dl
+   WHERE dl.tenant_id = sqlc.arg(tenant_id) AND dl.status IN ('pending', 'retrying'))::bigint AS dead_letter_backlog,
+  (SELECT COUNT(*) FROM issuances i
+   WHERE i.tenant_id = sqlc.arg(tenant_id) AND i.status = 'reserved')::bigint AS reserved_issuances,
+  (SELECT COUNT(*) FROM outbound_messages om
+   WHERE om.tenant_id = sqlc.arg(tenant_id) AND om.status = 'pending')::bigint AS message_backlog,
+  (SELECT COUNT(*) FROM outbox_events oe
+   WHERE oe.tenant_id = sqlc.arg(tenant_id) AND oe.published_at IS NULL)::bigint AS outbox_backlog,
+  (SELECT COUNT(*) FROM webhook_deliveries wd
+   WHERE wd.tenant_id = sqlc.arg(tenant_id) AND wd.status = 'pending')::bigint AS webhook_backlog,
+  (SELECT COUNT(*) FROM budgets b
+   WHERE b.tenant_id = sqlc.arg(tenant_id) AND b.soft_cap > 0 AND b.balance > b.soft_cap)::bigint AS budgets_over_soft_cap,
+  (SELECT COUNT(*) FROM budgets b
+   WHERE b.tenant_id = sqlc.arg(tenant_id) AND b.hard_cap > 0 AND b.balance >= b.hard_cap)::bigint AS budgets_at_hard_cap,
+  (SELECT COUNT(*) FROM webhook_deliveries wd
+   WHERE wd.tenant_id = sqlc.arg(tenant_id) AND wd.status = 'success' AND wd.created_at >= sqlc.arg(since))::bigint AS webhooks_delivered,
+  (SELECT COUNT(*) FROM webhook_deliveries wd
+   WHERE wd.tenant_id = sqlc.arg(tenant_id) AND wd.status = 'failed' AND wd.created_at >= sqlc.arg(since))::bigint AS webhooks_failed,
+  (SELECT COUNT(*) FROM outbound_messages om
+   WHERE om.tenant_id = sqlc.arg(tenant_id) AND om.status = 'sent' AND om.sent_at >= sqlc.arg(since))::bigint AS messages_sent,
+  (SELECT COUNT(*) FROM outbound_messages om
+   WHERE om.tenant_id = sqlc.arg(tenant_id) AND om.status = 'dead' AND om.dead_at >= sqlc.arg(since))::bigint AS messages_dead;
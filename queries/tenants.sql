@@ -27,3 +27,84 @@ WHERE id = $1;
 UPDATE tenants
 SET default_ccy = $2
 WHERE id = $1;
+
+-- name: UpdateTenantRequireApproval :exec
+UPDATE tenants
+SET require_approval = $2
+WHERE id = $1;
+
+-- name: UpdateTenantExport :exec
+UPDATE tenants
+SET export_bucket = $2,
+    export_prefix = $3
+WHERE id = $1;
+
+-- name: UpdateTenantEventBus :exec
+UPDATE tenants
+SET event_bus_enabled = $2
+WHERE id = $1;
+
+-- name: UpdateTenantMaxReservationAge :exec
+UPDATE tenants
+SET max_reservation_age_hours = $2
+WHERE id = $1;
+
+-- name: UpdateTenantWhatsAppRate :exec
+UPDATE tenants
+SET whatsapp_rate_per_minute = $2
+WHERE id = $1;
+
+-- name: UpdateTenantPhoneRegion :exec
+UPDATE tenants
+SET phone_region = $2
+WHERE id = $1;
+
+-- name: UpdateTenantEventDedup :exec
+UPDATE tenants
+SET event_dedup_mode = sqlc.narg(mode),
+    event_dedup_window_seconds = sqlc.arg(window_seconds)
+WHERE id = sqlc.arg(id);
+
+-- name: GetTenantPhoneRegion :one
+SELECT phone_region FROM tenants
+WHERE id = $1;
+
+-- name: GetTenantTimezone :one
+SELECT timezone FROM tenants
+WHERE id = $1;
+
+-- name: UpdateTenantTimezone :exec
+UPDATE tenants
+SET timezone = $2
+WHERE id = $1;
+
+-- name: UpdateTenantBranding :exec
+UPDATE tenants
+SET program_name = sqlc.narg(program_name),
+    brand_emoji = sqlc.narg(brand_emoji),
+    support_phone = sqlc.narg(support_phone),
+    message_footer = sqlc.narg(message_footer)
+WHERE id = sqlc.arg(id);
+
+-- name: UpdateTenantAllowedCurrencies :exec
+UPDATE tenants
+SET allowed_currencies = $2
+WHERE id = $1;
+
+-- name: GetTenantCurrencies :one
+SELECT default_ccy, allowed_currencies FROM tenants
+WHERE id = $1;
+
+-- name: ListReservationAgeingTenants :many
+SELECT * FROM tenants
+WHERE max_reservation_age_hours IS NOT NULL
+ORDER BY created_at;
+
+-- name: GetTenantAlertWebhookSecret :one
+SELECT alert_webhook_secret FROM tenants
+WHERE id = $1;
+
+-- name: UpdateTenantAlertWebhookSecret :exec
+UPDATE tenants
+SET alert_webhook_secret = $2
+WHERE id = $1;
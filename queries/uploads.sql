@@ -0,0 +1,29 @@
+-- Upload queries
+-- sqlc query file for uploaded files kept in object storage
+
+-- name: CreateUpload :one
+INSERT INTO uploads (tenant_id, kind, filename, content_type, size_bytes, sha256, storage_key, entity_type, entity_id, uploaded_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: GetUpload :one
+SELECT * FROM uploads
+WHERE tenant_id = $1 AND id = $2;
+
+-- name: ListUploads :many
+-- A tenant's uploads, newest first. kind, entity_type and entity_id narrow
+-- the list when set.
+SELECT * FROM uploads
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND (sqlc.narg(kind)::text IS NULL OR kind = sqlc.narg(kind))
+  AND (sqlc.narg(entity_type)::text IS NULL OR entity_type = sqlc.narg(entity_type))
+  AND (sqlc.narg(entity_id)::uuid IS NULL OR entity_id = sqlc.narg(entity_id))
+ORDER BY created_at DESC, id
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountUploads :one
+SELECT COUNT(*) FROM uploads
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND (sqlc.narg(kind)::text IS NULL OR kind = sqlc.narg(kind))
+  AND (sqlc.narg(entity_type)::text IS NULL OR entity_type = sqlc.narg(entity_type))
+  AND (sqlc.narg(entity_id)::uuid IS NULL OR entity_id = sqlc.narg(entity_id));
@@ -0,0 +1,24 @@
+-- Usage metering queries
+-- sqlc query file for tenant usage records
+
+-- name: AddUsage :exec
+INSERT INTO usage_records (tenant_id, period, metric, quantity)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (tenant_id, period, metric) DO UPDATE
+SET quantity = usage_records.quantity + EXCLUDED.quantity,
+    updated_at = now();
+
+-- name: ListTenantUsage :many
+SELECT * FROM usage_records
+WHERE tenant_id = $1
+  AND period >= sqlc.arg(from_period)::date
+  AND period <= sqlc.arg(to_period)::date
+ORDER BY period, metric;
+
+-- name: ListUsageForPeriod :many
+-- Every tenant's usage for one month, for invoicing
+SELECT u.tenant_id, t.name AS tenant_name, u.metric, u.quantity
+FROM usage_records u
+JOIN tenants t ON t.id = u.tenant_id
+WHERE u.period = $1
+ORDER BY t.name, u.tenant_id, u.metric;
@@ -44,3 +44,17 @@ SELECT * FROM ussd_sessions
 WHERE tenant_id = $1
 ORDER BY last_input_at DESC
 LIMIT $2 OFFSET $3;
+
+-- name: ListUSSDSessionsToRotate :many
+-- Sessions whose phone number isn't encrypted under the current PII key,
+-- whose values start with current_prefix.
+SELECT id, phone_e164 FROM ussd_sessions
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND NOT starts_with(phone_e164, sqlc.arg(current_prefix)::text)
+ORDER BY id
+LIMIT sqlc.arg(row_limit);
+
+-- name: SetUSSDSessionPhone :exec
+UPDATE ussd_sessions
+SET phone_e164 = $3
+WHERE id = $1 AND tenant_id = $2;
@@ -13,13 +13,13 @@ WHERE tenant_id = current_setting('app.tenant_id', true)::uuid
 ORDER BY created_at DESC;
 
 -- name: CreateWebhook :one
-INSERT INTO webhooks (tenant_id, name, url, events, secret, active)
-VALUES (current_setting('app.tenant_id', true)::uuid, $1, $2, $3, $4, $5)
+INSERT INTO webhooks (tenant_id, name, url, events, filter, secret, active)
+VALUES (current_setting('app.tenant_id', true)::uuid, $1, $2, $3, $4, $5, $6)
 RETURNING *;
 
 -- name: UpdateWebhook :one
 UPDATE webhooks
-SET name = $2, url = $3, events = $4, active = $5
+SET name = $2, url = $3, events = $4, filter = $5, active = $6
 WHERE id = $1 AND tenant_id = current_setting('app.tenant_id', true)::uuid
 RETURNING *;
 
@@ -0,0 +1,73 @@
+-- Welcome series queries
+-- sqlc query file for the welcome series sent to new customers
+
+-- name: UpdateTenantWelcomeSeries :exec
+UPDATE tenants
+SET welcome_series_enabled = sqlc.arg(welcome_series_enabled),
+    welcome_template = sqlc.narg(welcome_template),
+    welcome_reminder_template = sqlc.narg(welcome_reminder_template),
+    welcome_reminder_days = sqlc.arg(welcome_reminder_days),
+    welcome_bonus_days = sqlc.arg(welcome_bonus_days)
+WHERE id = sqlc.arg(id);
+
+-- name: ListWelcomeSeriesTenants :many
+-- Tenants with the series on, or with series still open from when it was
+SELECT * FROM tenants t
+WHERE t.welcome_series_enabled
+   OR EXISTS (SELECT 1 FROM welcome_series ws WHERE ws.tenant_id = t.id AND ws.completed_at IS NULL)
+ORDER BY t.created_at;
+
+-- name: GetCustomerWelcomeSeries :one
+SELECT * FROM welcome_series
+WHERE tenant_id = $1 AND customer_id = $2;
+
+-- name: RecordWelcomeFirstPurchases :execrows
+-- Records the first purchase since enrolling of customers in an open series
+UPDATE welcome_series ws
+SET first_purchase_at = p.first_purchase_at
+FROM (
+  SELECT s.id, MIN(ev.occurred_at)::timestamptz AS first_purchase_at
+  FROM welcome_series s
+  JOIN events ev ON ev.tenant_id = s.tenant_id AND ev.customer_id = s.customer_id
+  WHERE s.tenant_id = sqlc.arg(tenant_id)
+    AND s.completed_at IS NULL
+    AND s.first_purchase_at IS NULL
+    AND ev.event_type = 'purchase'
+    AND ev.occurred_at >= s.enrolled_at
+  GROUP BY s.id
+) p
+WHERE ws.id = p.id;
+
+-- name: ListDueWelcomeSeries :many
+-- Open series with a message due: the welcome, or the reminder once its day
+-- has come
+SELECT ws.id, ws.customer_id, ws.reminder_due_at, ws.welcome_status, ws.reminder_status,
+       ws.first_purchase_at, c.phone_e164, c.status AS customer_status
+FROM welcome_series ws
+JOIN customers c ON c.id = ws.customer_id AND c.tenant_id = ws.tenant_id
+WHERE ws.tenant_id = sqlc.arg(tenant_id)
+  AND ws.completed_at IS NULL
+  AND (ws.welcome_status IS NULL
+       OR (ws.reminder_status IS NULL AND ws.reminder_due_at <= sqlc.arg(now)))
+ORDER BY ws.enrolled_at, ws.id
+LIMIT sqlc.arg(row_limit);
+
+-- name: SetWelcomeStatus :exec
+UPDATE welcome_series
+SET welcome_status = $3, welcome_sent_at = $4
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: SetWelcomeReminderStatus :exec
+UPDATE welcome_series
+SET reminder_status = $3, reminder_sent_at = $4
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: CompleteWelcomeSeries :execrows
+-- Completes series whose messages are done and whose bonus window has ended
+UPDATE welcome_series
+SET completed_at = sqlc.arg(now)
+WHERE tenant_id = sqlc.arg(tenant_id)
+  AND completed_at IS NULL
+  AND welcome_status IS NOT NULL
+  AND reminder_status IS NOT NULL
+  AND bonus_ends_at <= sqlc.arg(now);
@@ -0,0 +1,52 @@
+-- Win-back queries
+-- sqlc query file for inactive-customer win-back
+
+-- name: UpdateTenantWinback :exec
+UPDATE tenants
+SET winback_dormant_days = sqlc.narg(winback_dormant_days),
+    winback_cooldown_days = sqlc.arg(winback_cooldown_days),
+    winback_template = sqlc.narg(winback_template)
+WHERE id = sqlc.arg(id);
+
+-- name: ListWinbackTenants :many
+SELECT * FROM tenants
+WHERE winback_dormant_days IS NOT NULL
+ORDER BY created_at;
+
+-- name: ListDormantCustomers :many
+-- Active customers whose last event, or sign-up if they have none, is before
+-- inactive_since. customer_dormant events aren't activity. Customers already
+-- contacted since their last activity, or since contacted_since, are
+-- suppressed.
+SELECT c.id, c.phone_e164, activity.last_activity_at
+FROM customers c
+CROSS JOIN LATERAL (
+  SELECT COALESCE(
+    (SELECT MAX(ev.occurred_at) FROM events ev
+     WHERE ev.tenant_id = c.tenant_id
+       AND ev.customer_id = c.id
+       AND ev.event_type <> 'customer_dormant'),
+    c.created_at
+  )::timestamptz AS last_activity_at
+) activity
+WHERE c.tenant_id = sqlc.arg(tenant_id)
+  AND c.status = 'active'
+  AND activity.last_activity_at < sqlc.arg(inactive_since)
+  AND NOT EXISTS (
+    SELECT 1 FROM winback_contacts wc
+    WHERE wc.tenant_id = c.tenant_id
+      AND wc.customer_id = c.id
+      AND (wc.created_at > activity.last_activity_at OR wc.created_at > sqlc.arg(contacted_since))
+  )
+ORDER BY activity.last_activity_at, c.id
+LIMIT sqlc.arg(row_limit);
+
+-- name: CreateWinbackContact :one
+INSERT INTO winback_contacts (tenant_id, customer_id, event_id, last_activity_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: SetWinbackMessageStatus :exec
+UPDATE winback_contacts
+SET message_status = $3
+WHERE id = $1 AND tenant_id = $2;
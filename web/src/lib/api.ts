@@ -17,6 +17,7 @@ import {
   LoginResponse,
   DashboardStats,
   PaginatedResponse,
+  Envelope,
 } from './types';
 
 const API_BASE = import.meta.env.VITE_API_URL || '/v1';
@@ -95,9 +96,9 @@ export class APIClient {
       let errorDetails;
 
       try {
-        const errorData = await response.json();
-        errorMessage = errorData.error || errorMessage;
-        errorDetails = errorData.details;
+        const body = await response.json();
+        errorMessage = body.error?.message || errorMessage;
+        errorDetails = body.error?.details;
       } catch {
         // If response is not JSON, use status text
       }
@@ -105,7 +106,17 @@ export class APIClient {
       throw new APIError(errorMessage, response.status, errorDetails);
     }
 
-    return response.json();
+    const body: Envelope<any> = await response.json();
+    // List responses carry their pagination in meta
+    if (body.meta?.total !== undefined) {
+      return {
+        data: body.data,
+        total: body.meta.total,
+        limit: body.meta.limit ?? 0,
+        offset: body.meta.offset ?? 0,
+      } as T;
+    }
+    return body.data as T;
   }
 
   // Auth endpoints
@@ -239,8 +250,8 @@ export class APIClient {
   ledger = {
     list: async (budgetId?: string) => {
       const query = budgetId ? `?budget_id=${budgetId}` : '';
-      const response = await this.request<{ entries: LedgerEntry[] }>(`/tenants/${this.getTenantId()}/ledger${query}`);
-      return response.entries;
+      const response = await this.request<PaginatedResponse<LedgerEntry>>(`/tenants/${this.getTenantId()}/ledger${query}`);
+      return response.data;
     },
   };
 
@@ -248,8 +259,8 @@ export class APIClient {
   issuances = {
     list: async (customerId?: string) => {
       const query = customerId ? `?customer_id=${customerId}` : '';
-      const response = await this.request<{ issuances: Issuance[] }>(`/tenants/${this.getTenantId()}/issuances${query}`);
-      return response.issuances;
+      const response = await this.request<PaginatedResponse<Issuance>>(`/tenants/${this.getTenantId()}/issuances${query}`);
+      return response.data;
     },
     get: (id: string) =>
       this.request<Issuance>(`/tenants/${this.getTenantId()}/issuances/${id}`),
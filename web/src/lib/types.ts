@@ -204,11 +204,21 @@ export interface DashboardStats {
 export interface PaginatedResponse<T> {
   data: T[];
   total: number;
-  page: number;
   limit: number;
-}
-
-export interface APIError {
-  error: string;
-  details?: any;
+  offset: number;
+}
+
+export interface Envelope<T> {
+  data?: T;
+  meta: {
+    request_id?: string;
+    total?: number;
+    limit?: number;
+    offset?: number;
+  };
+  error?: {
+    code: string;
+    message: string;
+    details?: any;
+  };
 }